@@ -8,39 +8,94 @@
 // @BasePath        /
 //
 // @schemes         http
+//
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 "Bearer <token>"; the admin endpoints require API_ADMIN_TOKEN
 package main
 
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/api"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/environment"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/baseline"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/doctor"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/envelope"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/export"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/fleetstatus"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/maintenance"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/predict"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/replay"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/scheduler"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/slo"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/summary"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/usage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
 
 	_ "github.com/cisco/gpu-telemetry-pipeline/docs"
 )
 
 func main() {
-	// Setup logging
-	logger := log.New(os.Stdout, "[API] ", log.LstdFlags|log.Lmicroseconds)
+	// Setup logging, keeping recent lines for support reports
+	recentLogs := support.NewLogs(support.DefaultLogLines)
+	logger := log.New(io.MultiWriter(os.Stdout, recentLogs), "[API] ", log.LstdFlags|log.Lmicroseconds)
+
+	// Map deprecated settings to their replacements before loading configuration
+	for _, w := range config.Migrate(config.ComponentAPI) {
+		logger.Printf("Warning: %s", w)
+	}
 
 	// Load configuration from environment variables
 	cfg := config.DefaultAPIConfig()
+	influxCfg := storage.DefaultInfluxDBConfig()
+
+	// "doctor" runs the full pre-flight report, including dependency probes, and exits
+	checks := doctor.APIChecks(cfg, influxCfg)
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctor.Main(os.Stdout, checks))
+	}
 
 	logger.Printf("Starting API Gateway...")
+	logger.Printf("Build: %s", buildinfo.Get("api"))
 	logger.Printf("  Host: %s", cfg.Host)
 	logger.Printf("  Port: %d", cfg.Port)
+	logger.Printf("  Latest Cache: %s", cfg.CacheSource)
+	logger.Printf("  Admin Endpoints: %s", map[bool]string{true: "enabled", false: "disabled (API_ADMIN_TOKEN not set)"}[cfg.AdminToken != ""])
+	logger.Printf("  Config Bundles: %s", map[bool]string{true: "enabled", false: "disabled (API_BUNDLE_SIGNING_KEY not set)"}[cfg.BundleKey != ""])
 
-	// Create InfluxDB storage from environment variables
-	influxCfg := storage.DefaultInfluxDBConfig()
+	logger.Println("Running pre-flight checks...")
+	if err := doctor.Preflight(logger, checks); err != nil {
+		logger.Fatalf("Pre-flight checks failed: %v", err)
+	}
+
+	// Connect to InfluxDB storage configured from environment variables
 	logger.Printf("Connecting to InfluxDB at %s (org=%s, bucket=%s)", influxCfg.URL, influxCfg.Org, influxCfg.Bucket)
+	logger.Printf("InfluxDB schema: %s", influxCfg.Schema)
 
 	store, err := storage.NewInfluxDBStorage(influxCfg)
 	if err != nil {
@@ -49,10 +104,183 @@ func main() {
 	logger.Printf("Connected to InfluxDB")
 	defer store.Close()
 
+	// Let admins change the log level and log Flux queries without a restart
+	level, _ := logging.ParseLevel(cfg.LogLevel) // checked by the pre-flight checks
+	logs := logging.New(logger, level)
+	queryLog := logs.Toggle("flux.queries", "Log the Flux text and duration of every query")
+	store.SetQueryLog(queryLog)
+	logger.Printf("  Log Level: %s", level)
+
+	// Keep alert state, and with Redis the leader leases, in the state store
+	state, err := statestore.Open(cfg.State)
+	if err != nil {
+		logger.Fatalf("Failed to open state store: %v", err)
+	}
+	if state != nil {
+		logger.Printf("  State Store: %s", cfg.State.Backend)
+		defer state.Close()
+	}
+
+	// Open the batch payloads the streamer encrypted, for the MQ cache and re-ingestion
+	keys, err := envelope.New(context.Background(), cfg.Encryption)
+	if err != nil {
+		logger.Fatalf("Failed to load encryption keys: %v", err)
+	}
+
+	// Keep the latest values in memory for snapshot and health reads
+	cacheCtx, stopCache := context.WithCancel(context.Background())
+	defer stopCache()
+	latest := startLatestCache(cacheCtx, cfg, store, influxCfg.Schema.Aliases, keys, logger)
+
+	// Deliver export-completed events to webhooks when configured
+	hostname, _ := os.Hostname()
+	events, err := notify.NewDispatcher(cfg.Webhooks, "api-"+hostname, logger)
+	if err != nil {
+		logger.Fatalf("Invalid webhook configuration: %v", err)
+	}
+	if events != nil {
+		logger.Printf("  Webhooks: %d endpoint(s)", len(cfg.Webhooks.URLs))
+		go events.Run(cacheCtx)
+	}
+
+	// Run saved queries on their schedules (on the elected replica only)
+	if cfg.Scheduler.Enabled {
+		startScheduler(cacheCtx, cfg, store, state, events, logger)
+	}
+
+	// Learn per-model baselines for alert thresholds
+	var baselines *baseline.Profiler
+	if cfg.Baselines.Enabled {
+		baselines = baseline.NewProfiler(store, cfg.Baselines, logger)
+		logger.Printf("  Baselines: %v over %v, refreshed every %v", cfg.Baselines.Metrics, cfg.Baselines.Window, cfg.Baselines.Refresh)
+		go baselines.Run(cacheCtx)
+	}
+
+	// Summarize stored telemetry for /api/v1/stats
+	var summarizer *summary.Summarizer
+	if cfg.Summary.Enabled {
+		summarizer = summary.New(store, cfg.Summary, logger)
+		logger.Printf("  Fleet summary: refreshed every %v, listing %d days", cfg.Summary.Refresh, cfg.Summary.Days)
+		go summarizer.Run(cacheCtx)
+	}
+
+	// Evaluate alert rules and notify (on the elected replica only)
+	var alerts *alert.Evaluator
+	var alertRules *alert.RuleSet
+	if cfg.Alerts.Enabled {
+		alerts, alertRules = startAlerts(cacheCtx, cfg, store, state, latest, baselines, events, logger)
+	}
+
+	// Keep each GPU's current status materialized for the fleet overview
+	if cfg.Status.Enabled {
+		startStatus(cacheCtx, cfg, store, state, latest, alerts, logger)
+	}
+
+	// Evaluate the ingest-latency objective from batch lineage
+	var sloTracker *slo.Tracker
+	if cfg.SLO.Enabled {
+		sloTracker = startSLO(cacheCtx, cfg, store, state, events, logger)
+	}
+
+	// Score GPU failure risk from recent telemetry
+	if cfg.Predict.Enabled {
+		startPredict(cacheCtx, cfg, store, state, logger)
+	}
+
+	// Re-ingestion is an admin operation, so only connect for it when admin endpoints are enabled
+	var replayer *replay.Replayer
+	if cfg.AdminToken != "" {
+		replayer = startReplayer(cacheCtx, cfg, store, keys, logger)
+	}
+
+	// Run large exports in the background, keeping artifacts on local disk
+	var exports *export.Store
+	if cfg.Exports.Dir != "" {
+		exports, err = export.Open(store, cfg.Exports, logger)
+		if err != nil {
+			logger.Fatalf("Failed to open export store: %v", err)
+		}
+		logger.Printf("  Exports: %s (ttl=%v, quota=%d MiB)", cfg.Exports.Dir, cfg.Exports.TTL, cfg.Exports.QuotaBytes>>20)
+		go exports.Run(cacheCtx)
+	}
+
+	// Identify tenants by their tokens and meter their usage
+	authenticator := auth.New(cfg.AdminToken)
+	for tenant, token := range cfg.TenantTokens {
+		authenticator.AddTenant(tenant, token)
+	}
+	meter, err := usage.New(cfg.Usage, logger)
+	if err != nil {
+		logger.Fatalf("Failed to open usage meter: %v", err)
+	}
+	logger.Printf("  Usage: %d tenant(s), quota requests=%d rows=%d export=%d MiB (0 is unlimited)",
+		len(cfg.TenantTokens), cfg.Usage.QuotaRequests, cfg.Usage.QuotaRows, cfg.Usage.QuotaExportBytes>>20)
+	go meter.Run(cacheCtx)
+
+	// Refuse writes while storage is upgraded; admins switch it at runtime
+	maintenanceMode := maintenance.NewMode(cfg.Maintenance, cfg.MaintenanceMessage)
+	if cfg.Maintenance {
+		logger.Printf("Maintenance mode on: %s", maintenanceMode.State().Message)
+	}
+
+	// Serve further data sets, each from its own bucket, to requests selecting them
+	environments := environment.NewSet(cfg.DefaultEnvironment, store)
+	for _, e := range cfg.Environments {
+		envCfg := influxCfg
+		envCfg.URL, envCfg.Org, envCfg.Bucket, envCfg.Token = e.URL, e.Org, e.Bucket, e.Token
+		envStore, err := storage.NewInfluxDBStorage(envCfg)
+		if err != nil {
+			logger.Fatalf("Failed to connect to InfluxDB for environment %s: %v", e.Name, err)
+		}
+		envStore.SetQueryLog(queryLog)
+		defer envStore.Close()
+		environments.Add(&environment.Environment{Name: e.Name, Store: envStore, Tenants: e.Tenants})
+		logger.Printf("  Environment %s: %s (org=%s, bucket=%s, tenants=%s)", e.Name, e.URL, e.Org, e.Bucket,
+			map[bool]string{true: strings.Join(e.Tenants, ","), false: "any"}[len(e.Tenants) > 0])
+	}
+
+	// Announce this replica and report the pipeline's wiring from the MQ server's heartbeats
+	var pipelineTopology *topology.Source
+	if cfg.MQ.HeartbeatInterval > 0 {
+		buckets := []topology.Storage{topology.InfluxDB(influxCfg, models.RelationReads)}
+		for _, e := range cfg.Environments {
+			buckets = append(buckets, topology.Storage{Backend: "influxdb", URL: e.URL, Org: e.Org, Bucket: e.Bucket, Access: models.RelationReads})
+		}
+		pipelineTopology = startTopology(cacheCtx, cfg, buckets, logger)
+	}
+
+	// Collect this replica's support report for admins
+	supportSource := support.NewSource("api", map[string]any{"api": cfg, "influxdb": influxCfg}, recentLogs)
+	if latest != nil {
+		supportSource.AddStats("cache", func() any { return latest.Status() })
+	}
+	supportSource.AddStats("maintenance", func() any { return maintenanceMode.State() })
+	supportSource.AddStats("usage", func() any { return meter.List(meter.Month()) })
+
 	// Create router
 	routerConfig := api.RouterConfig{
-		DefaultLimit: cfg.DefaultLimit,
-		MaxLimit:     cfg.MaxLimit,
+		DefaultLimit:  cfg.DefaultLimit,
+		MaxLimit:      cfg.MaxLimit,
+		LatestCache:   latest,
+		Webhooks:      events,
+		Replayer:      replayer,
+		Alerts:        alerts,
+		AlertRules:    alertRules,
+		Baselines:     baselines,
+		SLO:           sloTracker,
+		Topology:      pipelineTopology,
+		Summary:       summarizer,
+		Exports:       exports,
+		Usage:         meter,
+		Auth:          authenticator,
+		Dashboard:     cfg.Dashboard,
+		Logging:       logs,
+		Maintenance:   maintenanceMode,
+		BundleKey:     cfg.BundleKey,
+		MetricAliases: influxCfg.Schema.Aliases,
+		Environments:  environments,
+		Support:       supportSource,
+		Deliveries:    scheduler.Allowlist(cfg.Scheduler),
 	}
 	router := api.NewRouter(store, routerConfig)
 
@@ -87,6 +315,305 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Printf("Error during shutdown: %v", err)
 	}
+	if err := meter.Save(); err != nil {
+		logger.Printf("Failed to save usage: %v", err)
+	}
 
 	logger.Println("API server stopped")
 }
+
+// startLatestCache creates the latest-values cache and starts feeding it from
+// the configured source. It returns nil when the cache is disabled.
+func startLatestCache(ctx context.Context, cfg config.APIConfig, store storage.ReadStorage, aliases models.MetricAliases, keys *envelope.Keyring, logger *log.Logger) *cache.Latest {
+	switch cfg.CacheSource {
+	case cache.SourceStorage:
+		latest := cache.NewLatest(cache.SourceStorage)
+		go latest.RunStorageRefresh(ctx, store, cfg.CacheRefreshInterval, cfg.CacheWindow, logger)
+		return latest
+
+	case cache.SourceMQ:
+		latest := cache.NewLatest(cache.SourceMQ)
+
+		// Seed from storage so the snapshot is complete before new batches arrive
+		if err := latest.RefreshFromStorage(ctx, store, cfg.CacheWindow); err != nil {
+			logger.Printf("Latest cache seed failed: %v", err)
+		}
+
+		client := mq.NewClient(mq.ClientConfig{
+			Host:          cfg.MQ.Host,
+			Port:          cfg.MQ.Port,
+			Failover:      cfg.MQ.Failover,
+			WireFormat:    cfg.MQ.WireFormat,
+			Timeout:       10 * time.Second,
+			AutoReconnect: true,
+		})
+		if err := client.ConnectContext(ctx); err != nil {
+			logger.Fatalf("Failed to connect to MQ server for latest cache: %v", err)
+		}
+		go func() {
+			<-ctx.Done()
+			client.Close()
+		}()
+
+		// Each API replica needs its own subscription to see every batch
+		hostname, _ := os.Hostname()
+		if err := latest.SubscribeMQ(ctx, client, "api-cache-"+hostname, aliases, keys); err != nil {
+			logger.Fatalf("Failed to subscribe latest cache: %v", err)
+		}
+		return latest
+
+	default:
+		return nil
+	}
+}
+
+// startScheduler starts the saved-query scheduler. With leader election on,
+// replicas campaign for a lease and only the holder runs schedules.
+func startScheduler(ctx context.Context, cfg config.APIConfig, store storage.ReadStorage, state statestore.Store, events *notify.Dispatcher, logger *log.Logger) {
+	savedQueries, ok := store.(storage.SavedQueryStore)
+	if !ok {
+		logger.Fatalf("Scheduler enabled but storage backend does not support saved queries")
+	}
+
+	var l leader.Leader = leader.Always{}
+	if cfg.Scheduler.LeaderElection {
+		l = electLeader(ctx, cfg, state, "api-scheduler", cfg.Scheduler.LeaseTTL, logger)
+	}
+
+	deliverers, err := scheduler.Deliverers(cfg.Scheduler)
+	if err != nil {
+		logger.Fatalf("Failed to set up report delivery: %v", err)
+	}
+	targets := make([]string, 0, len(deliverers))
+	for target := range deliverers {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	logger.Printf("Scheduler enabled (tick=%v, leader election=%t, delivery=%s)",
+		cfg.Scheduler.Tick, cfg.Scheduler.LeaderElection, strings.Join(targets, ","))
+
+	s := scheduler.New(savedQueries, store, l, deliverers, events, cfg.Scheduler.Tick, cfg.Scheduler.DeliveryTimeout, logger)
+	go s.Run(ctx)
+}
+
+// startStatus starts materializing GPU statuses. With alerting on they are
+// written by the replica evaluating rules, the only one knowing the alert
+// state; otherwise replicas campaign for their own lease when leader
+// election is on.
+func startStatus(ctx context.Context, cfg config.APIConfig, store storage.ReadStorage, state statestore.Store, latest *cache.Latest, alerts *alert.Evaluator, logger *log.Logger) {
+	statuses, ok := store.(storage.GPUStatusStore)
+	if !ok {
+		logger.Fatalf("GPU statuses enabled but storage backend does not support them")
+	}
+
+	var source fleetstatus.AlertSource
+	var l leader.Leader = leader.Always{}
+	if alerts != nil {
+		source = alerts
+	} else if cfg.Status.LeaderElection {
+		l = electLeader(ctx, cfg, state, "api-status", cfg.Status.LeaseTTL, logger)
+	}
+
+	logger.Printf("GPU statuses enabled (interval=%v, metrics=%v, stale after=%v)",
+		cfg.Status.Interval, cfg.Status.Metrics, cfg.Status.StaleAfter)
+	go fleetstatus.New(statuses, latest, source, l, cfg.Status, logger).Run(ctx)
+}
+
+// startSLO starts evaluating the ingest-latency objective. Every replica
+// evaluates and serves it; with leader election on, only the replica
+// holding the lease raises burn-rate events.
+func startSLO(ctx context.Context, cfg config.APIConfig, store *storage.InfluxDBStorage, state statestore.Store, events *notify.Dispatcher, logger *log.Logger) *slo.Tracker {
+	var l leader.Leader = leader.Always{}
+	if cfg.SLO.LeaderElection {
+		l = electLeader(ctx, cfg, state, "api-slo", cfg.SLO.LeaseTTL, logger)
+	}
+
+	logger.Printf("SLO enabled (%.4g%% of metrics stored within %v over %v, burn rate %.4g over %v and %v)",
+		cfg.SLO.Objective*100, cfg.SLO.Latency, cfg.SLO.Window, cfg.SLO.BurnRate, cfg.SLO.ShortWindow, cfg.SLO.LongWindow)
+	tracker := slo.New(store, cfg.SLO, l, events, logger)
+	go tracker.Run(ctx)
+	return tracker
+}
+
+// startPredict starts scoring GPU failure risk with the configured model,
+// loading its parameters from the model file when one is set. With leader
+// election on, only the replica holding the lease scores.
+func startPredict(ctx context.Context, cfg config.APIConfig, store storage.ReadStorage, state statestore.Store, logger *log.Logger) {
+	reader, ok := store.(storage.SeriesReader)
+	predictions, ok2 := store.(storage.PredictionStore)
+	if !ok || !ok2 {
+		logger.Fatalf("Failure prediction enabled but storage backend does not support it")
+	}
+
+	var params []byte
+	if cfg.Predict.ModelFile != "" {
+		var err error
+		if params, err = os.ReadFile(cfg.Predict.ModelFile); err != nil {
+			logger.Fatalf("Failed to read prediction model file: %v", err)
+		}
+	}
+	model, err := predict.New(cfg.Predict.Model, params)
+	if err != nil {
+		logger.Fatalf("Invalid prediction model: %v", err)
+	}
+
+	var l leader.Leader = leader.Always{}
+	if cfg.Predict.LeaderElection {
+		l = electLeader(ctx, cfg, state, "api-predict", cfg.Predict.LeaseTTL, logger)
+	}
+
+	logger.Printf("Failure prediction enabled (model=%s, interval=%v, window=%v, samples=%d)",
+		cfg.Predict.Model, cfg.Predict.Interval, cfg.Predict.Window, cfg.Predict.Samples)
+	go predict.NewPredictor(reader, predictions, model, cfg.Predict.Model, l, cfg.Predict, logger).Run(ctx)
+}
+
+// startAlerts starts the alert evaluator and its notification router, and
+// returns them with the rule set evaluated: the rules file plus rules stored
+// through the API. With leader election on, replicas campaign for their own
+// lease, so alerting and scheduling can run on different replicas.
+func startAlerts(ctx context.Context, cfg config.APIConfig, store storage.ReadStorage, state statestore.Store, latest *cache.Latest,
+	baselines *baseline.Profiler, events *notify.Dispatcher, logger *log.Logger) (*alert.Evaluator, *alert.RuleSet) {
+	var rules alert.StaticRules
+	if cfg.Alerts.RulesFile != "" {
+		var err error
+		if rules, err = alert.LoadRulesFile(cfg.Alerts.RulesFile); err != nil {
+			logger.Fatalf("Failed to load alert rules: %v", err)
+		}
+	}
+
+	notifiers, err := alert.NewNotifiers(cfg.Alerts.Notifiers, cfg.Scheduler)
+	if err != nil {
+		logger.Fatalf("Invalid alert notifier configuration: %v", err)
+	}
+	router := alert.NewRouter(cfg.Alerts, notifiers, logger)
+
+	// Stored rules are read on every evaluation, so API changes apply without a restart
+	var stored alert.RuleLister
+	if s, ok := store.(storage.AlertRuleStore); ok {
+		stored = s
+	}
+	ruleSet := alert.NewRuleSet(rules, stored, logger)
+
+	// Silences need a backend that stores them; without one nothing is silenced
+	var silences alert.SilenceSource
+	if s, ok := store.(storage.SilenceStore); ok {
+		silences = s
+	}
+
+	var l leader.Leader = leader.Always{}
+	if cfg.Alerts.LeaderElection {
+		l = electLeader(ctx, cfg, state, "api-alerts", cfg.Alerts.LeaseTTL, logger)
+	}
+
+	logger.Printf("Alerting enabled (%d file rules, %d notifiers, interval=%v, leader election=%t)",
+		len(rules), len(notifiers), cfg.Alerts.EvalInterval, cfg.Alerts.LeaderElection)
+
+	evaluator := alert.NewEvaluator(ruleSet, latest, silences, router, l, events, cfg.Alerts.EvalInterval, logger)
+	// Maintenance windows are annotations, so they need a backend that stores those
+	if s, ok := store.(storage.AnnotationStore); ok && len(cfg.Alerts.MaintenanceKinds) > 0 {
+		evaluator.SetMaintenance(s, cfg.Alerts.MaintenanceKinds)
+	}
+	if baselines != nil {
+		evaluator.SetBaselines(baselines)
+	}
+	if state != nil {
+		evaluator.SetStateStore(state)
+	}
+	for i := range rules {
+		if err := evaluator.CheckRule(&rules[i]); err != nil {
+			logger.Fatalf("Invalid alert rules: %v", err)
+		}
+	}
+	go router.Run(ctx)
+	go evaluator.Run(ctx)
+	return evaluator, ruleSet
+}
+
+// electLeader campaigns for the named lease until ctx is done: in the state
+// store when it is Redis, shared by every replica, and otherwise on the MQ
+// server.
+func electLeader(ctx context.Context, cfg config.APIConfig, state statestore.Store, lease string, ttl time.Duration, logger *log.Logger) *leader.Elector {
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	if state != nil && cfg.State.Backend == statestore.BackendRedis {
+		elector := leader.NewElector(leader.StoreLeases{Store: state}, lease, holder, ttl, logger)
+		go elector.Run(ctx)
+		return elector
+	}
+
+	client := mq.NewClient(mq.ClientConfig{
+		Host:          cfg.MQ.Host,
+		Port:          cfg.MQ.Port,
+		Failover:      cfg.MQ.Failover,
+		WireFormat:    cfg.MQ.WireFormat,
+		Timeout:       10 * time.Second,
+		AutoReconnect: true,
+	})
+	if err := client.ConnectContext(ctx); err != nil {
+		logger.Fatalf("Failed to connect to MQ server for %s leader election: %v", lease, err)
+	}
+	go func() {
+		<-ctx.Done()
+		client.Close()
+	}()
+
+	elector := leader.NewElector(client, lease, holder, ttl, logger)
+	go elector.Run(ctx)
+	return elector
+}
+
+// startReplayer connects to the MQ server for batch re-ingestion. It returns
+// nil, leaving re-ingestion unavailable, if the MQ server cannot be reached.
+func startReplayer(ctx context.Context, cfg config.APIConfig, store *storage.InfluxDBStorage, keys *envelope.Keyring, logger *log.Logger) *replay.Replayer {
+	client := mq.NewClient(mq.ClientConfig{
+		Host:          cfg.MQ.Host,
+		Port:          cfg.MQ.Port,
+		Failover:      cfg.MQ.Failover,
+		WireFormat:    cfg.MQ.WireFormat,
+		Timeout:       10 * time.Second,
+		AutoReconnect: true,
+	})
+	if err := client.ConnectContext(ctx); err != nil {
+		logger.Printf("Re-ingestion unavailable, could not connect to MQ server: %v", err)
+		return nil
+	}
+	go func() {
+		<-ctx.Done()
+		client.Close()
+	}()
+
+	r := replay.New(store, client, cfg.MaxLimit, logger)
+	r.SetKeyring(keys)
+	return r
+}
+
+// startTopology connects to the MQ server to announce this replica, reading
+// buckets, and to list the components the server has heard from. It returns
+// nil, leaving the topology unavailable, if the MQ server cannot be reached.
+func startTopology(ctx context.Context, cfg config.APIConfig, buckets []topology.Storage, logger *log.Logger) *topology.Source {
+	client := mq.NewClient(mq.ClientConfig{
+		Host:          cfg.MQ.Host,
+		Port:          cfg.MQ.Port,
+		Failover:      cfg.MQ.Failover,
+		WireFormat:    cfg.MQ.WireFormat,
+		Timeout:       10 * time.Second,
+		AutoReconnect: true,
+	})
+	if err := client.ConnectContext(ctx); err != nil {
+		logger.Printf("Pipeline topology unavailable, could not connect to MQ server: %v", err)
+		return nil
+	}
+	go func() {
+		<-ctx.Done()
+		client.Close()
+	}()
+
+	var mqUse []string
+	if cfg.CacheSource == cache.SourceMQ {
+		mqUse = append(mqUse, models.RelationConsumes)
+	}
+	hostname, _ := os.Hostname()
+	hb := topology.Heartbeat("api", fmt.Sprintf("%s-%d", hostname, os.Getpid()), mqUse, buckets...)
+	go topology.Announce(ctx, client, hb, cfg.MQ.HeartbeatInterval, logger)
+	return topology.New(client)
+}
@@ -2,39 +2,134 @@
 //
 // This component subscribes to the message queue, processes incoming
 // telemetry batches, and stores them in the configured storage backend.
+// With COLLECTOR_SOURCE=kafka it consumes a Kafka topic instead, decoding
+// JSON batches, OTLP protobuf or line protocol into the same chain. Stored
+// metrics can also be forwarded to external observability systems.
 package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cardinality"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/doctor"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/envelope"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/forward"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/hostagg"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/ingeststats"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/kafka"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/lateness"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/maintenance"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/signing"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/skew"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
 )
 
 func main() {
-	// Setup logging
-	logger := log.New(os.Stdout, "[COLLECTOR] ", log.LstdFlags|log.Lmicroseconds)
+	// Setup logging, keeping recent lines for support reports
+	recentLogs := support.NewLogs(support.DefaultLogLines)
+	logger := log.New(io.MultiWriter(os.Stdout, recentLogs), "[COLLECTOR] ", log.LstdFlags|log.Lmicroseconds)
+
+	// Map deprecated settings to their replacements before loading configuration
+	for _, w := range config.Migrate(config.ComponentCollector) {
+		logger.Printf("Warning: %s", w)
+	}
 
 	// Load configuration from environment variables
 	cfg := config.DefaultCollectorConfig()
 
+	// "doctor" runs the full pre-flight report, including dependency probes, and exits
+	checks := doctor.CollectorChecks(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctor.Main(os.Stdout, checks))
+	}
+
 	logger.Printf("Starting Telemetry Collector...")
+	logger.Printf("Build: %s", buildinfo.Get("collector"))
 	logger.Printf("  Instance ID: %s", cfg.InstanceID)
-	logger.Printf("  MQ Server: %s:%d", cfg.MQ.Host, cfg.MQ.Port)
-	logger.Printf("  Retention Period: %v", cfg.RetentionPeriod)
+	if cfg.Source == "kafka" {
+		logger.Printf("  Kafka Brokers: %s", strings.Join(cfg.Kafka.Brokers, ","))
+		logger.Printf("  Kafka Topic: %s (group %s, format %s)", cfg.Kafka.Topic, cfg.Kafka.GroupID, cfg.Kafka.Format)
+		logger.Printf("  Start Offset: %s", cfg.Kafka.StartOffset)
+		if cfg.Kafka.TLS || cfg.Kafka.SASLMechanism != "" {
+			logger.Printf("  Kafka Security: tls=%v sasl=%s", cfg.Kafka.TLS, cfg.Kafka.SASLMechanism)
+		}
+	} else {
+		logger.Printf("  MQ Server: %s:%d", cfg.MQ.Host, cfg.MQ.Port)
+		logger.Printf("  Start Offset: %s", cfg.StartOffset)
+		logger.Printf("  Commit Interval: %v", cfg.CommitInterval)
+		if cfg.SubscribeFilter != "" {
+			logger.Printf("  Subscribe Filter: %s", cfg.SubscribeFilter)
+		}
+		if len(cfg.SubscribePartitions) > 0 {
+			logger.Printf("  Partitions: %v", cfg.SubscribePartitions)
+		}
+		if cfg.Group != "" {
+			logger.Printf("  Consumer Group: %s", cfg.Group)
+		}
+		if cfg.Workers > 0 {
+			logger.Printf("  Workers: %d batches at once", cfg.Workers)
+		}
+	}
+	logger.Printf("  Retention Period: %v (collector expiry: %v)", cfg.RetentionPeriod, cfg.ExpireTelemetry)
+	if cfg.ReadOnly {
+		logger.Printf("  Read-only: not consuming until switched off at /read-only")
+	}
+	if cfg.Cardinality.Budget > 0 {
+		logger.Printf("  Cardinality Budget: %d series per %v (drop=%v)", cfg.Cardinality.Budget, cfg.Cardinality.Window, cfg.Cardinality.Drop)
+	}
+	logger.Printf("  Late Data: older than %v is %s", cfg.LateData.Threshold, lateAction(cfg.LateData))
+	if cfg.Source != "kafka" {
+		logger.Printf("  Clock Skew: batches more than %v off the MQ server's clock are %s", cfg.ClockSkew.Threshold, skewAction(cfg.ClockSkew.Policy))
+	}
+	for _, a := range cfg.HostAggregates {
+		logger.Printf("  Host Aggregate: %s of %s as %s", a.Func, a.Metric, hostagg.Name(a))
+	}
+	if len(cfg.Webhooks.URLs) > 0 {
+		logger.Printf("  Webhooks: %d endpoint(s), silence after %v, lag threshold %d",
+			len(cfg.Webhooks.URLs), cfg.Webhooks.SilenceAfter, cfg.Webhooks.LagThreshold)
+	}
+	if cfg.IngestStatsInterval > 0 {
+		logger.Printf("  Ingest Stats: written every %v", cfg.IngestStatsInterval)
+	}
+	for _, sink := range cfg.Forward {
+		logger.Printf("  Forward: %s (%s) every %v or %d metrics", sink.Name, sink.Type, sink.FlushInterval, sink.BatchSize)
+	}
+
+	logger.Println("Running pre-flight checks...")
+	if err := doctor.Preflight(logger, checks); err != nil {
+		logger.Fatalf("Pre-flight checks failed: %v", err)
+	}
 
 	// Create InfluxDB storage backend from environment variables
 	influxCfg := storage.DefaultInfluxDBConfig()
 	logger.Printf("Connecting to InfluxDB at %s (org=%s, bucket=%s)", influxCfg.URL, influxCfg.Org, influxCfg.Bucket)
+	logger.Printf("InfluxDB schema: %s", influxCfg.Schema)
+	if len(cfg.HostAggregates) > 0 && influxCfg.Schema.Tags != nil && !slices.Contains(influxCfg.Schema.Tags, "hostname") {
+		logger.Fatalf("Host aggregates need the hostname tag in the InfluxDB schema")
+	}
 
 	store, err := storage.NewInfluxDBWriteStorage(influxCfg)
 	if err != nil {
@@ -42,23 +137,10 @@ func main() {
 	}
 	logger.Printf("Connected to InfluxDB")
 	defer store.Close()
-
-	// Create MQ client
-	client := mq.NewClient(mq.ClientConfig{
-		Host:          cfg.MQ.Host,
-		Port:          cfg.MQ.Port,
-		Timeout:       10 * time.Second,
-		AutoReconnect: true,
-	})
-
-	// Connect to MQ server
-	logger.Println("Connecting to MQ server...")
-	if err := client.Connect(); err != nil {
-		logger.Fatalf("Failed to connect to MQ server: %v", err)
+	store.SetExpiry(cfg.ExpireTelemetry)
+	if cfg.LateData.Policy == config.LatePolicyReroute {
+		store.SetLateBucket(cfg.LateData.Bucket)
 	}
-	defer client.Close()
-
-	logger.Println("Connected to MQ server")
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -66,10 +148,174 @@ func main() {
 
 	// Create collector
 	collector := &Collector{
-		client: client,
 		store:  store,
 		cfg:    cfg,
 		logger: logger,
+		clock:  clock.Real,
+	}
+	collector.readOnly = maintenance.NewSwitch(cfg.ReadOnly)
+	collector.guard = cardinality.NewGuard(cfg.Cardinality, influxCfg.Schema, logger, collector.clock.Now())
+	collector.late = lateness.NewTracker(cfg.LateData)
+	collector.skew = skew.New(cfg.ClockSkew)
+	collector.hostAgg = hostagg.New(cfg.HostAggregates)
+	collector.aliases = influxCfg.Schema.Aliases
+	if cfg.IngestStatsInterval > 0 {
+		collector.ingest = ingeststats.New()
+	}
+
+	if cfg.Source == "kafka" {
+		decode, err := kafka.NewDecoder(cfg.Kafka.Format)
+		if err != nil {
+			logger.Fatalf("Invalid Kafka configuration: %v", err)
+		}
+		collector.decode = decode
+		var tlsConfig *tls.Config
+		if cfg.Kafka.TLS {
+			if tlsConfig, err = kafka.LoadTLS(cfg.Kafka.TLSCAFile); err != nil {
+				logger.Fatalf("Invalid Kafka configuration: %v", err)
+			}
+		}
+		collector.consumer = kafka.NewConsumer(kafka.Config{
+			Brokers:        cfg.Kafka.Brokers,
+			Topic:          cfg.Kafka.Topic,
+			GroupID:        cfg.Kafka.GroupID,
+			ClientID:       cfg.InstanceID,
+			StartOffset:    cfg.Kafka.StartOffset,
+			MaxWait:        cfg.Kafka.MaxWait,
+			MaxBytes:       int32(cfg.Kafka.FetchMaxBytes),
+			CommitInterval: cfg.Kafka.CommitInterval,
+			Timeout:        10 * time.Second,
+			Retry:          retry.FromConfig("collector-kafka", cfg.Kafka.Retry),
+			TLS:            tlsConfig,
+			SASL: kafka.SASL{
+				Mechanism: strings.ToUpper(cfg.Kafka.SASLMechanism),
+				Username:  cfg.Kafka.SASLUsername,
+				Password:  cfg.Kafka.SASLPassword,
+			},
+		}, logger)
+	} else {
+		// Create MQ client
+		reconnect := retry.FromConfig("collector-mq-reconnect", cfg.MQ.Reconnect)
+		reconnect.OnRetry = func(attempt int, err error, wait time.Duration) {
+			logger.Printf("MQ reconnect attempt %d failed, retrying in %v: %v", attempt, wait, err)
+		}
+		client := mq.NewClient(mq.ClientConfig{
+			Host:            cfg.MQ.Host,
+			Port:            cfg.MQ.Port,
+			Failover:        cfg.MQ.Failover,
+			WireFormat:      cfg.MQ.WireFormat,
+			Timeout:         10 * time.Second,
+			AutoReconnect:   true,
+			ReconnectPolicy: &reconnect,
+			ManualCommit:    true,
+			Workers:         cfg.Workers,
+		})
+
+		// Connect to MQ server
+		logger.Println("Connecting to MQ server...")
+		if err := client.Connect(); err != nil {
+			logger.Fatalf("Failed to connect to MQ server: %v", err)
+		}
+		defer client.Close()
+
+		logger.Println("Connected to MQ server")
+		collector.client = client
+
+		// Open payloads the streamer encrypted
+		keys, err := envelope.New(ctx, cfg.Encryption)
+		if err != nil {
+			logger.Fatalf("Failed to load encryption keys: %v", err)
+		}
+		if keys != nil {
+			logger.Printf("Payload encryption keys loaded (required=%v)", cfg.Encryption.Required)
+		}
+		collector.keys = keys
+
+		// Verify batches against the producer key registry
+		producers, err := signing.NewRegistry(cfg.Verification)
+		if err != nil {
+			logger.Fatalf("Failed to load producer keys: %v", err)
+		}
+		if producers != nil {
+			logger.Printf("Verifying batch signatures of %d producers (policy=%s)", producers.Len(), cfg.Verification.Policy)
+		}
+		collector.producers = producers
+	}
+
+	collector.storeRetry = retry.FromConfig("collector-store", cfg.StoreRetry)
+	collector.storeRetry.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("Store attempt %d failed, retrying in %v: %v", attempt, wait, err)
+	}
+
+	// Raise GPU discovery/silence and lag events when webhooks are configured
+	events, err := notify.NewDispatcher(cfg.Webhooks, cfg.InstanceID, logger)
+	if err != nil {
+		logger.Fatalf("Invalid webhook configuration: %v", err)
+	}
+	if events != nil {
+		go events.Run(ctx)
+		collector.gpus = notify.NewGPUTracker(events, cfg.Webhooks.SilenceAfter, collector.clock.Now())
+		collector.lagMonitor = notify.NewLagMonitor(events, cfg.Webhooks.LagThreshold)
+	}
+
+	// Push stored metrics on to external sinks; the last queued metrics are
+	// sent after consumption stops
+	forwarder, err := forward.New(cfg.Forward, logger)
+	if err != nil {
+		logger.Fatalf("Invalid forward configuration: %v", err)
+	}
+	collector.forwarder = forwarder
+	forwardDone := make(chan struct{})
+	go func() {
+		defer close(forwardDone)
+		forwarder.Run(ctx)
+	}()
+
+	// Collect the support report served with health
+	collector.support = support.NewSource("collector", cfg, recentLogs)
+	collector.support.AddStats("consumer", func() any {
+		return map[string]int64{
+			"batches_processed": atomic.LoadInt64(&collector.batchesProcessed),
+			"metrics_stored":    atomic.LoadInt64(&collector.metricsStored),
+			"lag":               atomic.LoadInt64(&collector.lag),
+		}
+	})
+	collector.support.AddStats("cardinality", func() any { return collector.guard.Report(collector.clock.Now()) })
+	collector.support.AddStats("late_data", func() any { return collector.late.Stats(collector.clock.Now()) })
+	collector.support.AddStats("clock_skew", func() any { return collector.skew.Stats() })
+	if s, ok := collector.store.(interface{ Stats() storage.StorageStats }); ok {
+		collector.support.AddStats("storage", func() any { return s.Stats() })
+	}
+	if forwarder != nil {
+		collector.support.AddStats("forward", func() any { return forwarder.Stats() })
+	}
+
+	// Announce this collector for the pipeline topology
+	if collector.client != nil && cfg.MQ.HeartbeatInterval > 0 {
+		hb := topology.Heartbeat("collector", cfg.InstanceID, []string{models.RelationConsumes},
+			topology.InfluxDB(influxCfg, models.RelationWrites))
+		if cfg.Group != "" {
+			hb.Attributes["group"] = cfg.Group
+		}
+		if cfg.SubscribeFilter != "" {
+			hb.Attributes["filter"] = cfg.SubscribeFilter
+		}
+		go topology.Announce(ctx, collector.client, hb, cfg.MQ.HeartbeatInterval, logger)
+	}
+
+	// Serve health, build info and the support report
+	if cfg.HealthPort != 0 {
+		health := &http.Server{
+			Addr:    net.JoinHostPort(cfg.HealthHost, strconv.Itoa(cfg.HealthPort)),
+			Handler: collector.healthHandler(),
+		}
+		go func() {
+			if err := health.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Printf("Health endpoint stopped: %v", err)
+			}
+		}()
+		defer health.Close()
+		logger.Printf("Health endpoint listening on %s", health.Addr)
 	}
 
 	// Handle shutdown signals
@@ -86,83 +332,497 @@ func main() {
 	if err := collector.Run(ctx); err != nil && ctx.Err() == nil {
 		logger.Fatalf("Collector error: %v", err)
 	}
+	cancel()
+	<-forwardDone
+	collector.flushIngestStats()
 
 	logger.Printf("Collector stopped. Total batches processed: %d, Total metrics stored: %d",
 		collector.batchesProcessed, collector.metricsStored)
+	collector.logForwardStats()
 }
 
 // Collector handles message consumption and storage.
 type Collector struct {
-	client           *mq.Client
+	client           *mq.Client      // nil when consuming from Kafka
+	consumer         *kafka.Consumer // nil when consuming from the MQ
+	decode           kafka.Decoder
 	store            storage.Storage
 	cfg              config.CollectorConfig
 	logger           *log.Logger
+	clock            clock.Clock // Paces retention cleanup and the other loops
 	batchesProcessed int64
 	metricsStored    int64
+	lag              int64 // Latest consumer lag reported by the MQ server or Kafka consumer
+	storeRetry       retry.Policy
+	gpus             *notify.GPUTracker    // nil when webhooks are disabled
+	lagMonitor       *notify.LagMonitor    // nil when webhooks are disabled
+	forwarder        *forward.Forwarder    // nil when no forward sinks are configured
+	guard            *cardinality.Guard    // Counts series and enforces the cardinality budget
+	late             *lateness.Tracker     // Applies the late-data policy and tracks the watermark
+	skew             *skew.Monitor         // Applies the clock-skew policy to MQ batches
+	hostAgg          *hostagg.Aggregator   // Computes host-level aggregates of each batch
+	aliases          models.MetricAliases  // Renames aliased metrics to their canonical names
+	readOnly         *maintenance.Switch   // Pauses consumption while on
+	inFlight         int64                 // Batches being handled
+	support          *support.Source       // Serves the support report on the health port
+	ingest           *ingeststats.Recorder // Counts ingest per streamer; nil when disabled
+	keys             *envelope.Keyring     // Opens encrypted MQ payloads; nil when none are configured
+	producers        *signing.Registry     // Verifies MQ batch signatures; nil when no registry is configured
 }
 
-// Run starts the collector.
+// Run starts the collector. While read-only mode is on it consumes
+// nothing; switching it on stops consumption, lets the batches in hand be
+// stored and commits the position, and switching it off resumes from there.
+// The MQ subscription is paused rather than dropped, keeping its position
+// on the server; a Kafka consumer leaves the group and rejoins.
 func (c *Collector) Run(ctx context.Context) error {
-	// Subscribe to the queue starting from latest (new messages only)
-	// Use OffsetEarliest to replay all available messages from the beginning
-	err := c.client.Subscribe(ctx, c.cfg.InstanceID, mq.OffsetLatest, c.handleMessage)
-	if err != nil {
-		return err
+	c.startLoops(ctx)
+	if c.consumer != nil {
+		go c.kafkaLagLoop(ctx)
+	} else {
+		go c.lagLoop(ctx)
 	}
 
-	c.logger.Println("Subscribed to message queue")
+	resumed := false
+	for {
+		readOnly, changed := c.readOnly.Get()
+		if readOnly {
+			c.logger.Println("Read-only mode on: not consuming")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-changed:
+				continue
+			}
+		}
+		if resumed {
+			c.logger.Println("Read-only mode off: resuming consumption")
+		}
+		if c.consumer == nil {
+			return c.runMQ(ctx)
+		}
+
+		consumeCtx, stop := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-changed:
+				stop()
+			case <-consumeCtx.Done():
+			}
+		}()
+		err := c.runKafka(consumeCtx)
+		stop()
+		if err != nil || ctx.Err() != nil {
+			return err
+		}
+
+		c.drain(ctx)
+		c.logger.Printf("Read-only mode on: drained after %d batches", atomic.LoadInt64(&c.batchesProcessed))
+		resumed = true
+	}
+}
+
+// drain waits until the batches being handled are stored or ctx is done.
+func (c *Collector) drain(ctx context.Context) {
+	for atomic.LoadInt64(&c.inFlight) > 0 && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// healthHandler serves the health endpoint with consumption counters, the
+// cardinality report, late-data counters and watermark, the clock skew of
+// each streamer, the read-only switch, the build info and the support report.
+func (c *Collector) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		readOnly, _ := c.readOnly.Get()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":            "healthy",
+			"batches_processed": atomic.LoadInt64(&c.batchesProcessed),
+			"metrics_stored":    atomic.LoadInt64(&c.metricsStored),
+			"lag":               atomic.LoadInt64(&c.lag),
+			"read_only":         readOnly,
+			"watermark":         c.late.Stats(c.clock.Now()).Watermark,
+		})
+	})
+	mux.HandleFunc("/read-only", c.serveReadOnly)
+	mux.HandleFunc("/cardinality", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.guard.Report(c.clock.Now()))
+	})
+	mux.HandleFunc("/late-data", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.late.Stats(c.clock.Now()))
+	})
+	mux.HandleFunc("/clock-skew", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.skew.Stats())
+	})
+	mux.HandleFunc("/version", buildinfo.Handler("collector"))
+	if c.support != nil {
+		mux.HandleFunc("/debug/support", c.support.Handler())
+	}
+	return mux
+}
+
+// serveReadOnly reports read-only mode on GET and switches it on PUT with
+// a body like {"read_only": true}.
+func (c *Collector) serveReadOnly(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			ReadOnly *bool `json:"read_only"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ReadOnly == nil {
+			http.Error(w, `body must be {"read_only": true|false}`, http.StatusBadRequest)
+			return
+		}
+		if c.readOnly.Set(*req.ReadOnly) {
+			c.logger.Printf("Read-only mode switched %s from %s", onOff(*req.ReadOnly), r.RemoteAddr)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	readOnly, _ := c.readOnly.Get()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"read_only":       readOnly,
+		"since":           c.readOnly.Since(),
+		"batches_in_hand": atomic.LoadInt64(&c.inFlight),
+	})
+}
 
+// lateAction describes what happens to late metrics under cfg's policy.
+func lateAction(cfg config.LateDataConfig) string {
+	switch cfg.Policy {
+	case config.LatePolicyTag:
+		return "stored with late=true"
+	case config.LatePolicyReroute:
+		return "stored in bucket " + cfg.Bucket
+	case config.LatePolicyDrop:
+		return "dropped"
+	}
+	return "stored as usual"
+}
+
+// skewAction describes what happens to skewed batches under policy.
+func skewAction(policy string) string {
+	switch policy {
+	case config.SkewPolicyCorrect:
+		return "shifted onto the server's clock"
+	case config.SkewPolicyFlag:
+		return "stored with skewed=true"
+	case config.SkewPolicyReject:
+		return "rejected"
+	}
+	return "stored as usual"
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// startLoops starts the background work shared by both sources.
+func (c *Collector) startLoops(ctx context.Context) {
 	// Start cleanup goroutine
 	go c.cleanupLoop(ctx)
 
 	// Start stats reporter
 	go c.statsLoop(ctx)
 
-	// Wait for shutdown
-	<-ctx.Done()
+	// Watch for GPUs that stop reporting
+	if c.gpus != nil {
+		go c.silenceLoop(ctx)
+	}
+
+	// Write the ingest statistics
+	if c.ingest != nil {
+		go c.ingestStatsLoop(ctx)
+	}
+}
+
+// runMQ consumes from the pipeline's MQ until ctx is done, pausing the
+// subscription while read-only mode is on.
+func (c *Collector) runMQ(ctx context.Context) error {
+	// Subscribe from the configured position: latest (new messages only) by default,
+	// earliest to replay everything, or committed to resume where we stopped
+	startOffset, err := mq.ParseOffset(c.cfg.StartOffset)
+	if err != nil {
+		return err
+	}
+
+	if c.cfg.Group != "" {
+		err = c.client.SubscribeGroup(ctx, c.cfg.Group, c.cfg.InstanceID, startOffset, c.cfg.SubscribeFilter, c.handleMessage)
+	} else {
+		err = c.client.SubscribePartitions(ctx, c.cfg.InstanceID, startOffset, c.cfg.SubscribeFilter, c.cfg.SubscribePartitions, c.handleMessage)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.logger.Println("Subscribed to message queue")
+	if info, err := c.client.GetOffset(ctx, c.cfg.InstanceID); err == nil {
+		c.logger.Printf("Consuming from offset %d (committed=%d, latest=%d)", info.Current, info.Committed, info.Latest)
+	}
+
+	// Commit what was stored until shutdown or read-only mode, so a restart
+	// or reconnect redelivers only batches that failed or were in flight
+	ticker := c.clock.NewTicker(c.cfg.CommitInterval)
+	defer ticker.Stop()
+	_, changed := c.readOnly.Get()
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C():
+			if _, err := c.commitPosition(ctx); err != nil && ctx.Err() == nil {
+				c.logger.Printf("Offset commit failed: %v", err)
+			}
+		case <-changed:
+			var readOnly bool
+			readOnly, changed = c.readOnly.Get()
+			c.pauseMQ(ctx, readOnly)
+		}
+	}
+
+	// Commit our position so a restart with start offset "committed" resumes here
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if offset, err := c.commitPosition(shutdownCtx); err != nil {
+		c.logger.Printf("Offset commit failed: %v", err)
+	} else if offset >= 0 {
+		c.logger.Printf("Committed offset %d", offset)
+	}
 
 	// Unsubscribe
-	c.client.Unsubscribe(c.cfg.InstanceID)
+	c.client.Unsubscribe(shutdownCtx, c.cfg.InstanceID)
 
 	return nil
 }
 
+// pauseMQ pauses the subscription when read-only mode is switched on,
+// storing the batches in hand and committing the position, and resumes it
+// when it is switched off.
+func (c *Collector) pauseMQ(ctx context.Context, readOnly bool) {
+	if !readOnly {
+		if err := c.client.ResumeSubscriber(ctx, c.cfg.InstanceID); err != nil {
+			c.logger.Printf("Failed to resume subscription: %v", err)
+			return
+		}
+		c.logger.Println("Read-only mode off: resuming consumption")
+		return
+	}
+
+	if err := c.client.PauseSubscriber(ctx, c.cfg.InstanceID); err != nil {
+		c.logger.Printf("Failed to pause subscription: %v", err)
+		return
+	}
+	c.logger.Println("Read-only mode on: not consuming")
+	c.drain(ctx)
+	if _, err := c.commitPosition(ctx); err != nil && ctx.Err() == nil {
+		c.logger.Printf("Offset commit failed: %v", err)
+	}
+	c.logger.Printf("Read-only mode on: drained after %d batches", atomic.LoadInt64(&c.batchesProcessed))
+}
+
+// runKafka consumes the Kafka topic until ctx is done. The consumer commits
+// its offsets under the group ID, so a restart resumes where it stopped.
+func (c *Collector) runKafka(ctx context.Context) error {
+	c.logger.Printf("Consuming Kafka topic %s", c.cfg.Kafka.Topic)
+	return c.consumer.Run(ctx, c.handleRecord)
+}
+
+// commitPosition commits the MQ offset up to which every batch was stored,
+// returning the committed offset, or -1 before the first batch.
+func (c *Collector) commitPosition(ctx context.Context) (mq.Offset, error) {
+	offset, _, err := c.client.CommitProcessed(ctx, c.cfg.InstanceID)
+	return offset, err
+}
+
 // handleMessage processes incoming messages.
 func (c *Collector) handleMessage(ctx context.Context, msg *mq.Message) error {
-	// Parse batch
+	// Quick-skip empty batches using the streamer's metadata stamp
+	if msg.Metadata[mq.MetaRecordCount] == "0" {
+		return nil
+	}
+
+	receivedAt := c.clock.Now()
+
+	// Parse batch. A failed message is delivered again, so undecodable
+	// ones are dropped rather than redelivered for good.
+	payload, err := c.keys.Open(msg.Payload, msg.Metadata)
+	if err != nil {
+		c.logger.Printf("Dropping batch at offset %d: %v", msg.Offset, err)
+		c.ingest.Rejected(models.IngestUnknownSource, receivedAt)
+		return nil
+	}
 	var batch models.MetricBatch
-	if err := json.Unmarshal(msg.Payload, &batch); err != nil {
-		c.logger.Printf("Error unmarshaling batch: %v", err)
-		return err
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		c.logger.Printf("Dropping undecodable batch at offset %d: %v", msg.Offset, err)
+		c.ingest.Rejected(models.IngestUnknownSource, receivedAt)
+		return nil
+	}
+
+	// A batch not signed by the producer it names may be spoofed
+	signer, unverified := "", ""
+	if err := c.producers.Verify(payload, msg.Metadata, batch.Source); err != nil {
+		if c.cfg.Verification.Policy != config.SignaturePolicyFlag {
+			c.logger.Printf("Rejecting batch %s at offset %d: %v", batch.BatchID, msg.Offset, err)
+			c.ingest.Rejected(models.IngestUnknownSource, receivedAt)
+			return nil
+		}
+		c.logger.Printf("Storing unverified batch %s at offset %d: %v", batch.BatchID, msg.Offset, err)
+		unverified = err.Error()
+	} else if c.producers != nil {
+		signer = batch.Source
 	}
 
-	// Store metrics
+	// Skew is measured against when the MQ server first received the batch,
+	// which a replay carries in its metadata
+	publishedAt := msg.Timestamp
+	if v := msg.Metadata[mq.MetaPublishedAt]; v != "" {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			publishedAt = t
+		}
+	}
+	if !c.skew.Check(&batch, publishedAt) {
+		c.logger.Printf("Rejecting batch %s from %s: collected at %s, %v off the MQ server's clock",
+			batch.BatchID, batch.Source, batch.CollectedAt.Format(time.RFC3339), publishedAt.Sub(batch.CollectedAt))
+		c.ingest.Rejected(batch.Source, receivedAt)
+		return nil
+	}
+
+	return c.processBatch(ctx, &batch, len(msg.Payload), 0, func(lineage *models.BatchLineage) {
+		lineage.MQOffset = int64(msg.Offset)
+		lineage.PublishedAt = msg.Timestamp
+		lineage.ReceivedAt = receivedAt
+		lineage.Replayed = msg.Metadata[mq.MetaReplayOf] != ""
+		lineage.Signer = signer
+		lineage.Unverified = unverified
+	})
+}
+
+// handleRecord processes a Kafka record. Records that cannot be decoded
+// are dropped: redelivering them would stall the partition for good.
+func (c *Collector) handleRecord(ctx context.Context, msg kafka.Message) error {
+	receivedAt := c.clock.Now()
+
+	decoded, err := c.decode(msg)
+	if err != nil {
+		c.logger.Printf("Dropping Kafka record %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		c.ingest.Rejected(models.IngestUnknownSource, receivedAt)
+		return nil
+	}
+	if decoded.Dropped > 0 {
+		c.logger.Printf("Kafka record %s/%d@%d: dropped %s",
+			msg.Topic, msg.Partition, msg.Offset, strings.Join(decoded.Reasons, ", "))
+	}
+	if len(decoded.Batch.Metrics) == 0 {
+		c.ingest.Dropped(decoded.Batch.Source, decoded.Dropped, receivedAt)
+		return nil
+	}
+
+	return c.processBatch(ctx, &decoded.Batch, len(msg.Value), decoded.Dropped, func(lineage *models.BatchLineage) {
+		lineage.Kafka = &models.KafkaPosition{Topic: msg.Topic, Partition: msg.Partition, Offset: msg.Offset}
+		lineage.PublishedAt = msg.Time
+		lineage.ReceivedAt = receivedAt
+	})
+}
+
+// processBatch stores a batch and records its lineage; origin fills in
+// where the batch was consumed from. Both sources share it. size is the
+// batch's size as received and undecoded the metrics its decoder dropped,
+// both counted in the ingest statistics once the batch is stored.
+func (c *Collector) processBatch(ctx context.Context, batch *models.MetricBatch, size, undecoded int, origin func(*models.BatchLineage)) error {
+	atomic.AddInt64(&c.inFlight, 1)
+	defer atomic.AddInt64(&c.inFlight, -1)
+
+	// Store metrics under their canonical names, each stamped with its batch
+	// for lineage
 	metrics := make([]*models.GPUMetric, len(batch.Metrics))
 	for i := range batch.Metrics {
+		batch.Metrics[i].MetricName = c.aliases.Canonical(batch.Metrics[i].MetricName)
+		batch.Metrics[i].BatchID = batch.BatchID
 		metrics[i] = &batch.Metrics[i]
 	}
 
-	if err := c.store.StoreBatch(ctx, metrics); err != nil {
-		c.logger.Printf("Error storing batch: %v", err)
-		return err
+	// Late metrics are handled by the late-data policy, host aggregates are
+	// added, and metrics for new series past the cardinality budget may be
+	// dropped
+	now := c.clock.Now()
+	onTime, late := c.late.Sort(metrics, now)
+	dropped := undecoded + len(metrics) - len(onTime) - len(late)
+	if aggregates := c.hostAgg.Aggregate(onTime); len(aggregates) > 0 {
+		onTime = append(onTime[:len(onTime):len(onTime)], aggregates...)
+	}
+	stored := c.guard.Filter(onTime, now)
+	dropped += len(onTime) - len(stored)
+	if len(stored) > 0 {
+		err := c.storeRetry.Do(ctx, func(ctx context.Context) error {
+			return c.store.StoreBatch(ctx, stored)
+		})
+		if err != nil {
+			c.logger.Printf("Error storing batch: %v", err)
+			return err
+		}
+	}
+	if len(late) > 0 {
+		err := c.storeRetry.Do(ctx, func(ctx context.Context) error {
+			return c.store.(storage.LateStore).StoreLate(ctx, late)
+		})
+		if err != nil {
+			c.logger.Printf("Error storing late metrics: %v", err)
+			return err
+		}
 	}
 
 	atomic.AddInt64(&c.batchesProcessed, 1)
-	atomic.AddInt64(&c.metricsStored, int64(len(metrics)))
+	atomic.AddInt64(&c.metricsStored, int64(len(stored)))
+	c.ingest.Batch(batch.Source, len(batch.Metrics), size, now)
+	c.ingest.Dropped(batch.Source, dropped, now)
+	c.recordLineage(ctx, batch, origin)
+	if c.gpus != nil {
+		c.gpus.Observe(metrics, c.clock.Now())
+	}
+	c.forwarder.Forward(batch.Metrics)
 
 	return nil
 }
 
+// recordLineage stores where a batch came from. The metrics are already
+// stored, so a failure is logged rather than causing the batch to be retried.
+func (c *Collector) recordLineage(ctx context.Context, batch *models.MetricBatch, origin func(*models.BatchLineage)) {
+	recorder, ok := c.store.(storage.LineageRecorder)
+	if !ok || batch.BatchID == "" {
+		return
+	}
+
+	lineage := models.NewBatchLineage(batch)
+	lineage.Collector = c.cfg.InstanceID
+	origin(lineage)
+	lineage.StoredAt = c.clock.Now()
+	if err := recorder.RecordBatch(ctx, lineage); err != nil {
+		c.logger.Printf("Could not record lineage of batch %s: %v", batch.BatchID, err)
+	}
+}
+
 // cleanupLoop periodically removes old data.
 func (c *Collector) cleanupLoop(ctx context.Context) {
-	ticker := time.NewTicker(time.Hour)
+	ticker := c.clock.NewTicker(time.Hour)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			removed, err := c.store.Cleanup(ctx, c.cfg.RetentionPeriod)
 			if err != nil {
 				c.logger.Printf("Cleanup error: %v", err)
@@ -175,20 +835,145 @@ func (c *Collector) cleanupLoop(ctx context.Context) {
 
 // statsLoop periodically logs statistics.
 func (c *Collector) statsLoop(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := c.clock.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			stats := c.store.Stats()
-			c.logger.Printf("Stats: batches=%d, metrics_stored=%d, total_metrics=%d, gpus=%d",
+			c.logger.Printf("Stats: batches=%d, metrics_stored=%d, total_metrics=%d, gpus=%d, lag=%d",
 				atomic.LoadInt64(&c.batchesProcessed),
 				atomic.LoadInt64(&c.metricsStored),
 				stats.TotalMetrics,
-				stats.TotalGPUs)
+				stats.TotalGPUs,
+				atomic.LoadInt64(&c.lag))
+			card := c.guard.Report(c.clock.Now())
+			c.logger.Printf("Cardinality: series=%d (budget %d), created=%d, dropped=%d since %s",
+				card.Series, card.Budget, card.Created, card.Dropped, card.Start.Format(time.RFC3339))
+			late := c.late.Stats(c.clock.Now())
+			c.logger.Printf("Late data: late=%d (policy %s), tagged=%d, rerouted=%d, dropped=%d, watermark=%s",
+				late.Late, late.Policy, late.Tagged, late.Rerouted, late.Dropped, late.Watermark.Format(time.RFC3339))
+			for _, st := range c.skew.Stats().Sources {
+				c.logger.Printf("Clock skew %s: batches=%d, skewed=%d, last=%.1fs, max=%.1fs",
+					st.Source, st.Batches, st.Skewed, st.LastSkewSeconds, st.MaxSkewSeconds)
+			}
+			for _, st := range retry.Snapshot() {
+				c.logger.Printf("Retry %s: attempts=%d, retries=%d, failures=%d", st.Name, st.Attempts, st.Retries, st.Failures)
+			}
+			c.logForwardStats()
+		}
+	}
+}
+
+// logForwardStats logs the counters of each forward sink.
+func (c *Collector) logForwardStats() {
+	for _, st := range c.forwarder.Stats() {
+		c.logger.Printf("Forward %s: forwarded=%d, filtered=%d, dropped=%d, failed=%d, queued=%d",
+			st.Name, st.Forwarded, st.Filtered, st.Dropped, st.Failed, st.Queued)
+	}
+}
+
+// lagLoop records this collector's lag from periodic MQ server stats.
+func (c *Collector) lagLoop(ctx context.Context) {
+	updates, err := c.client.WatchStats(ctx, 10*time.Second)
+	if err != nil {
+		c.logger.Printf("Lag monitoring unavailable: %v", err)
+		return
+	}
+
+	// A group member lags as far as the position it shares with its group
+	id := c.cfg.InstanceID
+	if c.cfg.Group != "" {
+		id = c.cfg.Group
+	}
+	for stats := range updates {
+		for _, sub := range stats.Subscribers {
+			if sub.ID == id {
+				atomic.StoreInt64(&c.lag, sub.Lag)
+				if c.lagMonitor != nil {
+					c.lagMonitor.Observe(sub.ID, sub.Lag)
+				}
+			}
+		}
+	}
+}
+
+// kafkaLagLoop records how far the Kafka consumer is behind the topic.
+func (c *Collector) kafkaLagLoop(ctx context.Context) {
+	ticker := c.clock.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			lag := c.consumer.Lag()
+			atomic.StoreInt64(&c.lag, lag)
+			if c.lagMonitor != nil {
+				c.lagMonitor.Observe(c.cfg.InstanceID, lag)
+			}
+		}
+	}
+}
+
+// ingestStatsLoop periodically writes the ingest statistics counted since
+// the previous write.
+func (c *Collector) ingestStatsLoop(ctx context.Context) {
+	ticker := c.clock.NewTicker(c.cfg.IngestStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			c.writeIngestStats(ctx)
+		}
+	}
+}
+
+// flushIngestStats writes the last ingest statistics at shutdown.
+func (c *Collector) flushIngestStats() {
+	if c.ingest == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c.writeIngestStats(ctx)
+}
+
+// writeIngestStats writes the counts taken from the recorder. Counts that
+// could not be written are kept for the next write.
+func (c *Collector) writeIngestStats(ctx context.Context) {
+	recorder, ok := c.store.(storage.IngestStatsRecorder)
+	if !ok {
+		return
+	}
+	stats := c.ingest.Take()
+	if len(stats) == 0 {
+		return
+	}
+	if err := recorder.WriteIngestStats(ctx, c.cfg.InstanceID, stats, c.clock.Now()); err != nil {
+		c.logger.Printf("Could not write ingest stats: %v", err)
+		c.ingest.Restore(stats)
+	}
+}
+
+// silenceLoop periodically raises events for GPUs that stopped reporting.
+func (c *Collector) silenceLoop(ctx context.Context) {
+	ticker := c.clock.NewTicker(c.cfg.Webhooks.SilenceAfter / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			c.gpus.CheckSilent(now)
 		}
 	}
 }
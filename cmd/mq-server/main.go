@@ -6,23 +6,48 @@ package main
 
 import (
 	"context"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/doctor"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/schemas"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
 )
 
 func main() {
-	// Setup logging
-	logger := log.New(os.Stdout, "[MQ-SERVER] ", log.LstdFlags|log.Lmicroseconds)
+	// Setup logging, keeping recent lines for support reports
+	recentLogs := support.NewLogs(support.DefaultLogLines)
+	logger := log.New(io.MultiWriter(os.Stdout, recentLogs), "[MQ-SERVER] ", log.LstdFlags|log.Lmicroseconds)
+
+	// Map deprecated settings to their replacements before loading configuration
+	for _, w := range config.Migrate(config.ComponentMQServer) {
+		logger.Printf("Warning: %s", w)
+	}
 
 	// Load configuration from environment variables
 	cfg := config.DefaultMQServerConfig()
 
+	// "doctor" runs the full pre-flight report, including dependency probes, and exits
+	checks := doctor.MQServerChecks(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctor.Main(os.Stdout, checks))
+	}
+
+	logger.Println("Running pre-flight checks...")
+	if err := doctor.Preflight(logger, checks); err != nil {
+		logger.Fatalf("Pre-flight checks failed: %v", err)
+	}
+
 	// Create server config
 	serverCfg := mq.ServerConfig{
 		TCPHost:  cfg.TCPHost,
@@ -34,22 +59,152 @@ func main() {
 			BufferSize:     cfg.Queue.BufferSize,
 			MaxRetries:     cfg.Queue.MaxRetries,
 			RetryDelay:     cfg.Queue.RetryDelay,
+			ProbeInterval:  cfg.Queue.ProbeInterval,
+			DataDir:        cfg.Queue.DataDir,
+			FsyncPolicy:    cfg.Queue.FsyncPolicy,
+			FsyncInterval:  cfg.Queue.FsyncInterval,
+			SegmentBytes:   int64(cfg.Queue.SegmentBytes),
+			MemoryBytes:    int64(cfg.Queue.MemoryBytes),
+
+			RetryMultiplier: cfg.Queue.RetryMultiplier,
+			RetryMaxDelay:   cfg.Queue.RetryMaxDelay,
+			RetryJitter:     cfg.Queue.RetryJitter,
+
+			RetentionMessages: cfg.Queue.RetentionMessages,
+			RetentionBytes:    int64(cfg.Queue.RetentionBytes),
+			RetentionAge:      cfg.Queue.RetentionAge,
+			RetentionInterval: cfg.Queue.RetentionInterval,
+			MaxMessages:       cfg.Queue.MaxMessages,
+			MaxBytes:          int64(cfg.Queue.MaxBytes),
+			OverflowPolicy:    cfg.Queue.OverflowPolicy,
+			Partitions:        cfg.Queue.Partitions,
+			DedupWindow:       cfg.Queue.DedupWindow,
+			DedupMaxKeys:      cfg.Queue.DedupMaxKeys,
+			MaxDeadLetters:    cfg.Queue.MaxDeadLetters,
+
+			SubscriberMaxPendingBytes: int64(cfg.Queue.SubscriberMaxPendingBytes),
+			SubscriberOverflowPolicy:  cfg.Queue.SubscriberOverflowPolicy,
+
+			AutoCommitInterval: cfg.Queue.AutoCommitInterval,
+			AckTimeout:         cfg.Queue.AckTimeout,
+		},
+		PublishLimits: mq.PublishLimits{
+			ConnMessages:   cfg.PublishLimits.ConnMessages,
+			ConnBytes:      cfg.PublishLimits.ConnBytes,
+			GlobalMessages: cfg.PublishLimits.GlobalMessages,
+			GlobalBytes:    cfg.PublishLimits.GlobalBytes,
+		},
+		Quotas: mq.ClientQuotas{
+			MaxInFlight:      cfg.Quotas.MaxInFlight,
+			MaxPublishBytes:  cfg.Quotas.MaxPublishBytes,
+			MaxSubscriptions: cfg.Quotas.MaxSubscriptions,
 		},
+		ReplicaOf:    cfg.ReplicaOf,
+		PromoteAfter: cfg.PromoteAfter,
+
+		WebSocketOrigins: cfg.WebSocketOrigins,
 	}
 
 	// Create and start server
 	server := mq.NewServer(serverCfg, logger)
+	if cfg.Debug {
+		protocol, _ := schemas.Get("protocol-message")
+		batch, _ := schemas.Get("metric-batch")
+		server.SetValidation(protocol.Schema, batch.Schema)
+	}
+
+	// Let admins change the log level and dump frames without a restart
+	level, _ := logging.ParseLevel(cfg.LogLevel) // checked by the pre-flight checks
+	server.SetLogging(logging.New(logger, level), auth.New(cfg.AdminToken))
+	server.SetSupport(support.NewSource("mq-server", cfg, recentLogs))
 
 	logger.Printf("Starting MQ Server...")
+	logger.Printf("Build: %s", buildinfo.Get("mq-server"))
 	logger.Printf("  TCP: %s:%d", serverCfg.TCPHost, serverCfg.TCPPort)
 	logger.Printf("  HTTP: %s:%d", serverCfg.HTTPHost, serverCfg.HTTPPort)
 	logger.Printf("  Buffer Size: %d", serverCfg.Queue.BufferSize)
+	if serverCfg.Queue.ProbeInterval > 0 {
+		logger.Printf("  Latency Probes: every %v (see /stats)", serverCfg.Queue.ProbeInterval)
+	}
+	if serverCfg.Queue.DataDir != "" {
+		logger.Printf("  Data Dir: %s (fsync %s)", serverCfg.Queue.DataDir, serverCfg.Queue.FsyncPolicy)
+		if m := serverCfg.Queue.MemoryBytes; m > 0 {
+			logger.Printf("  Memory Budget: %d bytes, older segments spilled to disk past it", m)
+		}
+	} else {
+		logger.Printf("  Data Dir: none, the log is lost on restart")
+	}
+	if q := serverCfg.Queue; q.RetentionMessages > 0 || q.RetentionBytes > 0 || q.RetentionAge > 0 {
+		logger.Printf("  Retention: %d messages, %d bytes, %v (0 = unlimited; trimmed every %v)",
+			q.RetentionMessages, q.RetentionBytes, q.RetentionAge, q.RetentionInterval)
+	} else {
+		logger.Printf("  Retention: unlimited, the log grows until restart")
+	}
+	if q := serverCfg.Queue; q.MaxMessages > 0 || q.MaxBytes > 0 {
+		logger.Printf("  Max Log Size: %d messages, %d bytes (0 = unbounded; %s on overflow)",
+			q.MaxMessages, q.MaxBytes, q.OverflowPolicy)
+	}
+	if l := serverCfg.PublishLimits; l != (mq.PublishLimits{}) {
+		logger.Printf("  Publish Limits: %g msg/s and %g B/s per connection, %g msg/s and %g B/s in total (0 = unlimited)",
+			l.ConnMessages, l.ConnBytes, l.GlobalMessages, l.GlobalBytes)
+	}
+	if q := serverCfg.Quotas; q != (mq.ClientQuotas{}) {
+		logger.Printf("  Client Quotas: %d in flight per subscription, %d byte publishes, %d subscriptions (0 = unlimited)",
+			q.MaxInFlight, q.MaxPublishBytes, q.MaxSubscriptions)
+	}
+	if serverCfg.Queue.Partitions > 1 {
+		logger.Printf("  Partitions: %d, routed by the %s metadata key", serverCfg.Queue.Partitions, mq.MetaPartitionKey)
+	}
+	if q := serverCfg.Queue; q.DedupWindow > 0 {
+		logger.Printf("  Deduplication: %s keys remembered for %v, up to %d (0 = unlimited)", mq.MetaIdempotencyKey, q.DedupWindow, q.DedupMaxKeys)
+	} else {
+		logger.Printf("  Deduplication: disabled, retried publishes may be appended twice")
+	}
+	if q := serverCfg.Queue; q.SubscriberMaxPendingBytes > 0 {
+		logger.Printf("  Subscriber Budget: %d pending bytes each (%s on overflow)", q.SubscriberMaxPendingBytes, q.SubscriberOverflowPolicy)
+	} else {
+		logger.Printf("  Subscriber Budget: unbounded, a slow consumer may hold any number of unacked messages")
+	}
+	if serverCfg.Queue.AutoCommitInterval > 0 {
+		logger.Printf("  Auto Commit: every %v", serverCfg.Queue.AutoCommitInterval)
+	} else {
+		logger.Printf("  Auto Commit: disabled, consumers commit their own offsets")
+	}
+	if q := serverCfg.Queue; q.AckTimeout > 0 {
+		logger.Printf("  Acks: unacked after %v or nacked messages redelivered up to %d times, backing off from %v", q.AckTimeout, q.MaxRetries, q.RetryDelay)
+	} else {
+		logger.Printf("  Acks: not tracked, nothing is redelivered")
+	}
+	logger.Printf("  Dead Letters: %d kept of the messages subscribers gave up on", serverCfg.Queue.MaxDeadLetters)
+	if origins := serverCfg.WebSocketOrigins; len(origins) > 0 {
+		logger.Printf("  WebSocket Origins: %s", strings.Join(origins, ", "))
+	} else {
+		logger.Printf("  WebSocket Origins: the server's own only")
+	}
+	switch {
+	case serverCfg.ReplicaOf == "":
+	case serverCfg.PromoteAfter > 0:
+		logger.Printf("  Standby: replicating %s, promoted after %v without it or by POST /admin/promote", serverCfg.ReplicaOf, serverCfg.PromoteAfter)
+	default:
+		logger.Printf("  Standby: replicating %s until promoted by POST /admin/promote", serverCfg.ReplicaOf)
+	}
+	logger.Printf("  Log Level: %s (admin endpoint %s)", level,
+		map[bool]string{true: "enabled", false: "disabled, MQ_ADMIN_TOKEN not set"}[cfg.AdminToken != ""])
+	if cfg.Debug {
+		logger.Printf("  Debug: validating frames and payloads against published schemas")
+	}
 
 	if err := server.Start(); err != nil {
 		logger.Fatalf("Failed to start server: %v", err)
 	}
 
 	logger.Println("MQ Server started successfully")
+	if wal := server.GetQueue().GetStats().WAL; wal != nil {
+		logger.Printf("Recovered %d messages in %d segments from %s", wal.Recovered, wal.Segments, wal.Dir)
+		if wal.TruncatedBytes > 0 {
+			logger.Printf("Truncated a torn write of %d bytes at the end of the log", wal.TruncatedBytes)
+		}
+	}
 
 	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
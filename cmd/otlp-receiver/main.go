@@ -0,0 +1,160 @@
+// OTLP Receiver - Accepts OpenTelemetry metrics and publishes them to MQ
+//
+// This component lets otel-collector agents on GPU nodes export metrics to
+// the pipeline natively over OTLP/gRPC or OTLP/HTTP. Each export is mapped to
+// GPU metrics and published as one batch, like a streamer batch.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/doctor"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/otlp"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
+)
+
+func main() {
+	// Setup logging, keeping recent lines for support reports
+	recentLogs := support.NewLogs(support.DefaultLogLines)
+	logger := log.New(io.MultiWriter(os.Stdout, recentLogs), "[OTLP-RECEIVER] ", log.LstdFlags|log.Lmicroseconds)
+
+	// Map deprecated settings to their replacements before loading configuration
+	for _, w := range config.Migrate(config.ComponentOTLPReceiver) {
+		logger.Printf("Warning: %s", w)
+	}
+
+	// Load configuration from environment variables
+	cfg := config.DefaultOTLPReceiverConfig()
+
+	// "doctor" runs the full pre-flight report, including dependency probes, and exits
+	checks := doctor.OTLPReceiverChecks(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctor.Main(os.Stdout, checks))
+	}
+
+	logger.Printf("Starting OTLP Receiver...")
+	logger.Printf("Build: %s", buildinfo.Get("otlp-receiver"))
+	logger.Printf("  Instance ID: %s", cfg.InstanceID)
+	logger.Printf("  OTLP/gRPC: %s:%d", cfg.Host, cfg.GRPCPort)
+	logger.Printf("  OTLP/HTTP: %s:%d%s", cfg.Host, cfg.HTTPPort, otlp.HTTPPath)
+	logger.Printf("  MQ Server: %s:%d", cfg.MQ.Host, cfg.MQ.Port)
+
+	logger.Println("Running pre-flight checks...")
+	if err := doctor.Preflight(logger, checks); err != nil {
+		logger.Fatalf("Pre-flight checks failed: %v", err)
+	}
+
+	// Create MQ client
+	reconnect := retry.FromConfig("otlp-mq-reconnect", cfg.MQ.Reconnect)
+	reconnect.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("MQ reconnect attempt %d failed, retrying in %v: %v", attempt, wait, err)
+	}
+	client := mq.NewClient(mq.ClientConfig{
+		Host:            cfg.MQ.Host,
+		Port:            cfg.MQ.Port,
+		Failover:        cfg.MQ.Failover,
+		WireFormat:      cfg.MQ.WireFormat,
+		Timeout:         10 * time.Second,
+		AutoReconnect:   true,
+		ReconnectPolicy: &reconnect,
+	})
+
+	logger.Println("Connecting to MQ server...")
+	if err := client.Connect(); err != nil {
+		logger.Fatalf("Failed to connect to MQ server: %v", err)
+	}
+	defer client.Close()
+	logger.Println("Connected to MQ server")
+
+	publishRetry := retry.FromConfig("otlp-publish", cfg.PublishRetry)
+	publishRetry.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("Publish attempt %d failed, retrying in %v: %v", attempt, wait, err)
+	}
+	receiver := otlp.New(client, otlp.Config{
+		InstanceID:      cfg.InstanceID,
+		MaxRequestBytes: int64(cfg.MaxRequestBytes),
+		PublishTimeout:  cfg.MQ.PublishTimeout,
+		PublishRetry:    publishRetry,
+	}, logger)
+
+	// Serve the support report beside OTLP/HTTP
+	supportSource := support.NewSource("otlp-receiver", cfg, recentLogs)
+	supportSource.AddStats("receiver", func() any { return receiver.Stats() })
+	httpMux := http.NewServeMux()
+	httpMux.Handle("/", receiver.HTTPHandler())
+	httpMux.HandleFunc("/debug/support", supportSource.Handler())
+
+	grpcAddr := fmt.Sprintf("%s:%d", cfg.Host, cfg.GRPCPort)
+	grpcListener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		logger.Fatalf("Failed to listen on %s: %v", grpcAddr, err)
+	}
+	grpcServer := receiver.GRPCServer()
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Fatalf("Server error on %s: %v", grpcAddr, err)
+		}
+	}()
+
+	httpServer := &http.Server{Addr: fmt.Sprintf("%s:%d", cfg.Host, cfg.HTTPPort), Handler: httpMux}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("Server error on %s: %v", httpServer.Addr, err)
+		}
+	}()
+
+	// Announce this receiver for the pipeline topology
+	announceCtx, stopAnnouncing := context.WithCancel(context.Background())
+	defer stopAnnouncing()
+	if cfg.MQ.HeartbeatInterval > 0 {
+		hb := topology.Heartbeat("otlp-receiver", cfg.InstanceID, []string{models.RelationPublishes})
+		go topology.Announce(announceCtx, client, hb, cfg.MQ.HeartbeatInterval, logger)
+	}
+
+	logger.Println("OTLP Receiver started successfully")
+
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-sigChan
+	logger.Printf("Received signal %v, shutting down...", sig)
+
+	// Graceful shutdown with timeout; in-flight exports finish publishing
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Printf("Error during shutdown of %s: %v", httpServer.Addr, err)
+	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		logger.Printf("Error during shutdown of %s: %v", grpcAddr, ctx.Err())
+		grpcServer.Stop()
+	}
+
+	st := receiver.Stats()
+	logger.Printf("OTLP Receiver stopped. Requests: %d, Batches: %d, Metrics: %d, Rejected points: %d, Publish failures: %d",
+		st.Requests, st.Batches, st.Metrics, st.Rejected, st.PublishFailure)
+}
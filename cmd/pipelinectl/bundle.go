@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func init() {
+	register("bundle", "Export or import a signed configuration bundle (export|import)", runBundle)
+}
+
+func runBundle(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: pipelinectl bundle export [-o FILE] | import -f FILE [-dry-run]")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("bundle "+action, flag.ExitOnError)
+	apiURL := fs.String("api-url", "http://localhost:8080", "API base URL")
+	token := fs.String("token", os.Getenv("API_ADMIN_TOKEN"), "Admin bearer token (default $API_ADMIN_TOKEN)")
+	timeout := fs.Duration("timeout", 30*time.Second, "Request timeout")
+	out := fs.String("o", "-", "File to write the exported bundle to (export; - for stdout)")
+	in := fs.String("f", "", "Bundle file to import (import; - for stdin)")
+	dryRun := fs.Bool("dry-run", false, "Report the changes without making them (import)")
+	fs.Parse(args[1:])
+
+	client := &http.Client{Timeout: *timeout}
+	url := strings.TrimRight(*apiURL, "/") + "/api/v1/admin/bundle"
+
+	switch action {
+	case "export":
+		body, err := callAPI(client, http.MethodGet, url, *token, nil)
+		if err != nil {
+			return err
+		}
+		if *out == "-" {
+			_, err = os.Stdout.Write(body)
+			return err
+		}
+		return os.WriteFile(*out, body, 0o600)
+
+	case "import":
+		var data []byte
+		var err error
+		switch *in {
+		case "":
+			return errors.New("-f is required")
+		case "-":
+			data, err = io.ReadAll(os.Stdin)
+		default:
+			data, err = os.ReadFile(*in)
+		}
+		if err != nil {
+			return err
+		}
+		if *dryRun {
+			url += "?dry_run=true"
+		}
+		body, err := callAPI(client, http.MethodPost, url, *token, data)
+		if err != nil {
+			return err
+		}
+		var result models.BundleImportResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+		printImport(&result)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown action %q (want export or import)", action)
+	}
+}
+
+// callAPI sends an admin request and returns the response body, or the
+// API's error message for a non-2xx status.
+func callAPI(client *http.Client, method, url, token string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, apiErr.Message)
+		}
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return data, nil
+}
+
+// printImport summarizes what an import changed.
+func printImport(result *models.BundleImportResult) {
+	if result.DryRun {
+		fmt.Println("Dry run: nothing was changed")
+	}
+	if result.Retention != "" {
+		fmt.Printf("Retention:      %s -> %s\n", result.PreviousRetention, result.Retention)
+	} else {
+		fmt.Println("Retention:      unchanged")
+	}
+	for _, kind := range []struct {
+		name    string
+		changes models.BundleChanges
+	}{{"Alert rules", result.AlertRules}, {"Saved queries", result.SavedQueries}} {
+		fmt.Printf("%-15s %d created, %d updated, %d unchanged\n", kind.name+":",
+			len(kind.changes.Created), len(kind.changes.Updated), len(kind.changes.Unchanged))
+		for _, name := range kind.changes.Created {
+			fmt.Printf("  + %s\n", name)
+		}
+		for _, name := range kind.changes.Updated {
+			fmt.Printf("  ~ %s\n", name)
+		}
+	}
+}
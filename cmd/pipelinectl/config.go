@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+func init() {
+	register("config", "Migrate env files off deprecated settings (migrate)", runConfig)
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "migrate" {
+		return errors.New("usage: pipelinectl config migrate [-component NAME] [-w] FILE...")
+	}
+
+	fs := flag.NewFlagSet("config migrate", flag.ExitOnError)
+	component := fs.String("component", "", "Component the files configure ("+strings.Join(config.Components, ", ")+"); empty checks every component's settings")
+	write := fs.Bool("w", false, "Rewrite the files in place instead of printing them")
+	fs.Parse(args[1:])
+
+	if *component != "" && !knownComponent(*component) {
+		return fmt.Errorf("unknown component %q (expected one of %s)", *component, strings.Join(config.Components, ", "))
+	}
+	if fs.NArg() == 0 {
+		return errors.New("at least one env file is required")
+	}
+
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		migrated, warnings, err := config.MigrateEnvFile(*component, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, w)
+		}
+
+		if !*write {
+			os.Stdout.Write(migrated)
+			continue
+		}
+		if len(warnings) == 0 {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, migrated, info.Mode().Perm()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s: rewritten\n", path)
+	}
+	return nil
+}
+
+// knownComponent reports whether name is a pipeline component.
+func knownComponent(name string) bool {
+	for _, c := range config.Components {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func init() {
+	register("dead-letters", "List the messages MQ subscribers gave up on", runDeadLetters)
+}
+
+func runDeadLetters(args []string) error {
+	fs := flag.NewFlagSet("dead-letters", flag.ExitOnError)
+	host, port, timeout := mqFlags(fs)
+	payloads := fs.Bool("payloads", false, "Print each message's payload too")
+	fs.Parse(args)
+
+	client, err := connectMQ(*host, *port, *timeout)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	letters, err := client.DeadLetters(ctx)
+	if err != nil {
+		return err
+	}
+	if len(letters) == 0 {
+		fmt.Println("No dead letters")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AT\tSUBSCRIBER\tOFFSET\tMESSAGE ID\tREASON")
+	for _, l := range letters {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", l.At.Format(time.RFC3339), l.Subscriber, l.Message.Offset, l.Message.ID, l.Reason)
+		if *payloads {
+			fmt.Fprintf(w, "\t%s\n", l.Message.Payload)
+		}
+	}
+	return w.Flush()
+}
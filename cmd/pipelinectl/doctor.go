@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/doctor"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+func init() {
+	register("doctor", "Check pipeline configuration and probe dependencies", runDoctor)
+}
+
+func runDoctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	component := fs.String("component", "all", "Component to check: all, streamer, collector, api, mq-server, otlp-receiver")
+	skipProbes := fs.Bool("skip-probes", false, "Only check configuration, do not contact dependencies")
+	fs.Parse(args)
+
+	// Deprecated settings are mapped to their replacements as the components
+	// do at startup, and reported as warnings
+	deprecated := make(map[string][]config.Warning)
+	for _, name := range config.Components {
+		if *component == "all" || *component == name {
+			deprecated[name] = config.Migrate(name)
+		}
+	}
+
+	// Configuration is read from the same environment variables the components use
+	mqServerCfg := config.DefaultMQServerConfig()
+	apiCfg := config.DefaultAPIConfig()
+	otlpCfg := config.DefaultOTLPReceiverConfig()
+
+	components := []struct {
+		name   string
+		checks []doctor.Check
+	}{
+		{"mq-server", doctor.MQServerChecks(mqServerCfg)},
+		{"streamer", doctor.StreamerChecks(config.DefaultStreamerConfig())},
+		{"collector", doctor.CollectorChecks(config.DefaultCollectorConfig())},
+		{"api", doctor.APIChecks(apiCfg, storage.DefaultInfluxDBConfig())},
+		{"otlp-receiver", doctor.OTLPReceiverChecks(otlpCfg)},
+	}
+
+	var checks []doctor.Check
+	for _, c := range components {
+		if *component == "all" || *component == c.name {
+			warnings := deprecated[c.name]
+			c.checks = append(c.checks, doctor.Check{Name: "deprecated settings", Run: func(ctx context.Context) error {
+				errs := make([]error, len(warnings))
+				for i, w := range warnings {
+					errs[i] = errors.New(w.String())
+				}
+				return doctor.Warn(errors.Join(errs...))
+			}})
+			checks = append(checks, doctor.Prefix(c.name+": ", c.checks)...)
+		}
+	}
+	if len(checks) == 0 {
+		return fmt.Errorf("unknown component %q", *component)
+	}
+
+	if *component == "all" {
+		// Components usually run on separate hosts, so a shared port is only a warning
+		checks = append(checks, doctor.Check{Name: "port conflicts", Run: func(ctx context.Context) error {
+			return doctor.Warn(config.CheckPortConflicts(map[string]int{
+				"mq.tcp_port":    mqServerCfg.TCPPort,
+				"mq.http_port":   mqServerCfg.HTTPPort,
+				"api.port":       apiCfg.Port,
+				"otlp.grpc_port": otlpCfg.GRPCPort,
+				"otlp.http_port": otlpCfg.HTTPPort,
+			}))
+		}})
+	}
+	if *skipProbes {
+		checks = doctor.Static(checks)
+	}
+
+	if doctor.Main(os.Stdout, checks) != 0 {
+		return errors.New("one or more checks failed")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// pipelinectl - Command-line control tool for the telemetry pipeline
+//
+// This tool talks to running pipeline components (currently the MQ server)
+// to inspect and operate the deployment.
+//
+// Usage:
+//
+//	pipelinectl <command> [flags]
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command is a pipelinectl subcommand.
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+// commands holds every registered subcommand, keyed by name.
+var commands = map[string]command{}
+
+// register adds a subcommand; called from init in each command file.
+func register(name, summary string, run func(args []string) error) {
+	commands[name] = command{summary: summary, run: run}
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "pipelinectl %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the list of available commands.
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: pipelinectl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", name, commands[name].summary)
+	}
+}
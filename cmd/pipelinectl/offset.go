@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+)
+
+func init() {
+	register("offset", "Inspect or move a subscriber's log position (get|seek|commit)", runOffset)
+}
+
+func runOffset(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: pipelinectl offset get|seek|commit -subscriber ID [-to OFFSET | -at TIME]")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("offset "+action, flag.ExitOnError)
+	host, port, timeout := mqFlags(fs)
+	subscriber := fs.String("subscriber", "", "Subscriber ID (e.g., collector-1)")
+	to := fs.String("to", "", "Target offset: earliest, latest, or a number (seek/commit)")
+	at := fs.String("at", "", "Seek to the first message received at or after this RFC3339 time (seek)")
+	fs.Parse(args[1:])
+
+	if *subscriber == "" {
+		return errors.New("-subscriber is required")
+	}
+
+	client, err := connectMQ(*host, *port, *timeout)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	switch action {
+	case "get":
+		info, err := client.GetOffset(ctx, *subscriber)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Subscriber:  %s (active=%v)\n", info.SubscriberID, info.Active)
+		fmt.Printf("Current:     %d\n", info.Current)
+		fmt.Printf("Committed:   %d\n", info.Committed)
+		fmt.Printf("Log range:   %d..%d\n", info.Oldest, info.Latest)
+		fmt.Printf("Lag:         %d\n", info.Lag)
+		if info.Filter != "" {
+			fmt.Printf("Filter:      %s\n", info.Filter)
+		}
+		return nil
+
+	case "seek", "commit":
+		if *at != "" {
+			if action != "seek" || *to != "" {
+				return errors.New("-at is only for seek, instead of -to")
+			}
+			ts, err := time.Parse(time.RFC3339, *at)
+			if err != nil {
+				return fmt.Errorf("-at must be RFC3339 (e.g., 2024-01-02T14:00:00Z): %w", err)
+			}
+			offset, err := client.SeekToTimestamp(ctx, *subscriber, ts)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("seek %s to %s: offset %d\n", *subscriber, *at, offset)
+			return nil
+		}
+		if *to == "" {
+			return errors.New("-to (or -at for seek) is required")
+		}
+		offset, err := mq.ParseOffset(*to)
+		if err != nil {
+			return err
+		}
+		if action == "seek" {
+			err = client.SeekOffset(ctx, *subscriber, offset)
+		} else {
+			err = client.CommitOffset(ctx, *subscriber, offset)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s to %s: ok\n", action, *subscriber, *to)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown offset action %q", action)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+func init() {
+	register("stats", "Show MQ queue statistics and subscriber lag", runStats)
+}
+
+// mqFlags registers the MQ connection flags shared by commands that talk to the MQ server.
+func mqFlags(fs *flag.FlagSet) (host *string, port *int, timeout *time.Duration) {
+	defaults := config.DefaultMQClientConfig()
+	host = fs.String("mq-host", defaults.Host, "MQ server host")
+	port = fs.Int("mq-port", defaults.Port, "MQ server TCP port")
+	timeout = fs.Duration("timeout", 10*time.Second, "Request timeout")
+	return host, port, timeout
+}
+
+// connectMQ dials the MQ server using the values parsed from mqFlags.
+func connectMQ(host string, port int, timeout time.Duration) (*mq.Client, error) {
+	client := mq.NewClient(mq.ClientConfig{
+		Host:          host,
+		Port:          port,
+		Timeout:       timeout,
+		AutoReconnect: true,
+	})
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// signalContext returns a context cancelled on SIGINT/SIGTERM.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	host, port, timeout := mqFlags(fs)
+	watch := fs.Bool("watch", false, "Keep printing stats pushed by the server")
+	interval := fs.Duration("interval", 5*time.Second, "Push interval when -watch is set")
+	fs.Parse(args)
+
+	client, err := connectMQ(*host, *port, *timeout)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	if !*watch {
+		stats, err := client.GetStats(ctx)
+		if err != nil {
+			return err
+		}
+		printStats(stats)
+		return nil
+	}
+
+	updates, err := client.WatchStats(ctx, *interval)
+	if err != nil {
+		return err
+	}
+	for stats := range updates {
+		fmt.Printf("--- %s ---\n", time.Now().Format(time.RFC3339))
+		printStats(stats)
+	}
+	return nil
+}
+
+// printStats writes a queue stats snapshot as a human-readable table.
+func printStats(stats mq.QueueStats) {
+	fmt.Printf("Total messages:  %d\n", stats.TotalMessages)
+	fmt.Printf("Offsets:         %d..%d\n", stats.OldestOffset, stats.LatestOffset)
+	fmt.Printf("Retained:        %d bytes (%d messages trimmed)\n", stats.RetainedBytes, stats.TrimmedMessages)
+	if s := stats.Spill; s != nil {
+		fmt.Printf("Spill:           %d of %d bytes in memory, %d messages on disk only (%d segments paged in)\n",
+			s.ResidentBytes, s.MemoryBytes, s.SpilledMessages, s.PageIns)
+		if s.PageError != "" {
+			fmt.Printf("Spill error:     %s\n", s.PageError)
+		}
+	}
+	fmt.Printf("Throughput:      %.1f/s published, %.1f/s delivered over the last minute (%d delivered)\n",
+		stats.PublishRate, stats.DeliverRate, stats.DeliveredMessages)
+	if stats.RejectedMessages > 0 || stats.DroppedMessages > 0 {
+		fmt.Printf("Overflow:        %d rejected, %d dropped\n", stats.RejectedMessages, stats.DroppedMessages)
+	}
+	if stats.DuplicateMessages > 0 {
+		fmt.Printf("Duplicates:      %d publishes dropped\n", stats.DuplicateMessages)
+	}
+	if stats.ThrottledPublishes > 0 {
+		fmt.Printf("Throttled:       %d publishes over the rate limit\n", stats.ThrottledPublishes)
+	}
+	if control, alert := stats.Priorities[mq.PriorityControl], stats.Priorities[mq.PriorityAlert]; control.Published > 0 || alert.Published > 0 {
+		fmt.Printf("Priorities:      %d control, %d alert (%d and %d delivered ahead of a backlog)\n",
+			control.Published, alert.Published, control.Expedited, alert.Expedited)
+	}
+	if stats.ScheduledMessages > 0 {
+		fmt.Printf("Scheduled:       %d messages not yet due\n", stats.ScheduledMessages)
+	}
+	fmt.Printf("Subscribers:     %d\n", stats.SubscriberCount)
+	if stats.EvictedSubscribers > 0 {
+		fmt.Printf("Evicted:         %d subscribers over their pending byte budget\n", stats.EvictedSubscribers)
+	}
+	if stats.DeadLetters > 0 {
+		fmt.Printf("Dead letters:    %d given up on, %d kept (pipelinectl dead-letters)\n", stats.DeadLetters, stats.DeadLettersKept)
+	}
+	if r := stats.Replication; r != nil {
+		switch {
+		case !r.Standby:
+			fmt.Printf("Replication:     promoted from a standby of %s at offset %d, epoch %d\n", r.Primary, r.Next, stats.Epoch)
+		case r.Connected:
+			fmt.Printf("Replication:     standby of %s, %d messages behind\n", r.Primary, r.Lag)
+		default:
+			fmt.Printf("Replication:     standby of %s, disconnected: %s\n", r.Primary, r.Error)
+		}
+	}
+	if p := stats.Probes; p != nil {
+		fmt.Printf("Probe latency:   last %.2fms, p50 %.2fms, p99 %.2fms, max %.2fms (%d/%d delivered, every %s)\n",
+			p.LastMs, p.P50Ms, p.P99Ms, p.MaxMs, p.Received, p.Sent, p.Interval)
+	}
+
+	printClients(stats.Clients)
+
+	if len(stats.Subscribers) == 0 {
+		return
+	}
+
+	subs := stats.Subscribers
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUBSCRIBER\tOFFSET\tLAG\tDELIVERED\tERRORS\tTRIMMED\tPENDING\tPARTITIONS")
+	for _, sub := range subs {
+		offset := strconv.FormatInt(int64(sub.CurrentOffset), 10)
+		if sub.Suspended {
+			offset += " (paused)"
+		}
+		pending := strconv.FormatInt(sub.PendingBytes, 10)
+		if sub.Paused {
+			pending += " (paused)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", sub.ID, offset, sub.Lag, sub.Delivered, sub.HandlerErrors, sub.Trimmed, pending, partitionsOf(sub))
+	}
+	tw.Flush()
+}
+
+// printClients writes the connected clients' traffic as a table.
+func printClients(clients []mq.ClientInfo) {
+	if len(clients) == 0 {
+		return
+	}
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CLIENT	PUBLISHED	DELIVERED	SUBSCRIPTIONS	WATCHES	REFUSED")
+	for _, c := range clients {
+		fmt.Fprintf(tw, "%s	%d (%d bytes)	%d (%d bytes)	%s	%d	%d\n", c.Addr,
+			c.PublishedMessages, c.PublishedBytes, c.DeliveredMessages, c.DeliveredBytes,
+			strings.Join(c.Subscriptions, ","), c.Watches, c.Refused)
+	}
+	tw.Flush()
+}
+
+// partitionsOf describes the partitions a subscriber consumes, or, for a
+// consumer group, those assigned to each member.
+func partitionsOf(sub mq.SubscriberInfo) string {
+	join := func(partitions []int) string {
+		s := make([]string, len(partitions))
+		for i, p := range partitions {
+			s[i] = strconv.Itoa(p)
+		}
+		return strings.Join(s, ",")
+	}
+	if sub.Members != nil {
+		members := make([]string, 0, len(sub.Members))
+		for id, partitions := range sub.Members {
+			if len(partitions) == 0 {
+				members = append(members, id+"=standby")
+			} else {
+				members = append(members, id+"="+join(partitions))
+			}
+		}
+		sort.Strings(members)
+		return "group " + strings.Join(members, " ")
+	}
+	if len(sub.Partitions) == 0 {
+		return "all"
+	}
+	return join(sub.Partitions)
+}
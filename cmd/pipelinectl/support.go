@@ -0,0 +1,221 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
+)
+
+func init() {
+	register("support", "Collect a support bundle from every component into a tarball", runSupport)
+}
+
+// supportTarget is one component instance whose support report is fetched.
+type supportTarget struct {
+	component string
+	url       string // the report's full URL
+	token     string
+}
+
+// supportResult is a target's report, or why it could not be read.
+type supportResult struct {
+	target supportTarget
+	report support.Report
+	err    error
+}
+
+// supportManifest lists what a bundle holds; it is the bundle's first file.
+type supportManifest struct {
+	Build       buildinfo.Info   `json:"build"`
+	CollectedAt time.Time        `json:"collected_at"`
+	Components  []supportSummary `json:"components"`
+}
+
+// supportSummary records where one report came from, and its directory in
+// the bundle or the error that kept it out.
+type supportSummary struct {
+	Component string `json:"component"`
+	URL       string `json:"url"`
+	Dir       string `json:"dir,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func runSupport(args []string) error {
+	fs := flag.NewFlagSet("support", flag.ExitOnError)
+	urls := map[string]*string{
+		"api":           fs.String("api-url", "http://localhost:8080", "API base URLs, comma-separated for replicas"),
+		"mq-server":     fs.String("mq-url", "http://localhost:9001", "MQ server HTTP base URLs"),
+		"streamer":      fs.String("streamer-url", "http://localhost:8082", "Streamer health endpoint base URLs"),
+		"collector":     fs.String("collector-url", "http://localhost:8083", "Collector health endpoint base URLs"),
+		"otlp-receiver": fs.String("otlp-url", "http://localhost:4318", "OTLP receiver HTTP base URLs"),
+	}
+	apiToken := fs.String("token", os.Getenv("API_ADMIN_TOKEN"), "API admin bearer token (default $API_ADMIN_TOKEN)")
+	mqToken := fs.String("mq-token", os.Getenv("MQ_ADMIN_TOKEN"), "MQ server admin bearer token (default $MQ_ADMIN_TOKEN)")
+	out := fs.String("o", "", "Tarball to write (default support-<time>.tar.gz)")
+	timeout := fs.Duration("timeout", 10*time.Second, "Request timeout per component")
+	fs.Parse(args)
+
+	// The API and MQ server serve reports to admins; the other components
+	// on their internal health ports
+	paths := map[string]string{
+		"api":           "/api/v1/admin/support",
+		"mq-server":     "/admin/support",
+		"streamer":      "/debug/support",
+		"collector":     "/debug/support",
+		"otlp-receiver": "/debug/support",
+	}
+	tokens := map[string]string{"api": *apiToken, "mq-server": *mqToken}
+
+	var targets []supportTarget
+	for component, list := range urls {
+		for _, url := range strings.Split(*list, ",") {
+			if url = strings.TrimRight(strings.TrimSpace(url), "/"); url != "" {
+				targets = append(targets, supportTarget{component: component, url: url + paths[component], token: tokens[component]})
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].component != targets[j].component {
+			return targets[i].component < targets[j].component
+		}
+		return targets[i].url < targets[j].url
+	})
+
+	collectedAt := time.Now().UTC()
+	if *out == "" {
+		*out = "support-" + collectedAt.Format("20060102-150405") + ".tar.gz"
+	}
+	results := querySupport(targets, *timeout)
+	collected, err := writeSupportBundle(*out, collectedAt, results)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tURL\tRESULT")
+	for _, r := range results {
+		result := "collected"
+		if r.err != nil {
+			result = "failed: " + r.err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.target.component, r.target.url, result)
+	}
+	w.Flush()
+
+	if collected == 0 {
+		return errors.New("no component could be reached")
+	}
+	fmt.Printf("Wrote %s with %d of %d reports\n", *out, collected, len(results))
+	return nil
+}
+
+// querySupport fetches every target's report concurrently, returning the
+// results in target order.
+func querySupport(targets []supportTarget, timeout time.Duration) []supportResult {
+	results := make([]supportResult, len(targets))
+	client := &http.Client{Timeout: timeout}
+
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t supportTarget) {
+			defer wg.Done()
+			results[i] = supportResult{target: t}
+			body, err := callAPI(client, http.MethodGet, t.url, t.token, nil)
+			if err == nil {
+				err = json.Unmarshal(body, &results[i].report)
+			}
+			results[i].err = err
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// writeSupportBundle writes a gzipped tarball holding a manifest, and for
+// each report a directory with the report, its logs and its goroutine dump
+// as separate files. It returns how many reports were written.
+func writeSupportBundle(name string, collectedAt time.Time, results []supportResult) (int, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	root := strings.TrimSuffix(path.Base(name), ".tar.gz")
+	add := func(file string, data []byte) error {
+		hdr := &tar.Header{Name: path.Join(root, file), Mode: 0o600, Size: int64(len(data)), ModTime: collectedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	manifest := supportManifest{Build: buildinfo.Get("pipelinectl"), CollectedAt: collectedAt}
+	var files []func() error
+	seen := make(map[string]int)
+	for _, r := range results {
+		summary := supportSummary{Component: r.target.component, URL: r.target.url}
+		if r.err != nil {
+			summary.Error = r.err.Error()
+			manifest.Components = append(manifest.Components, summary)
+			continue
+		}
+		seen[r.target.component]++
+		summary.Dir = fmt.Sprintf("%s-%d", r.target.component, seen[r.target.component])
+		manifest.Components = append(manifest.Components, summary)
+
+		report := r.report
+		logs, goroutines := strings.Join(report.Logs, "\n")+"\n", report.Goroutines
+		report.Logs, report.Goroutines = nil, ""
+		dir := summary.Dir
+		files = append(files, func() error {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			return errors.Join(
+				add(path.Join(dir, "report.json"), data),
+				add(path.Join(dir, "logs.txt"), []byte(logs)),
+				add(path.Join(dir, "goroutines.txt"), []byte(goroutines)),
+			)
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := add("manifest.json", data); err != nil {
+		return 0, err
+	}
+	for _, write := range files {
+		if err := write(); err != nil {
+			return 0, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, err
+	}
+	return len(files), f.Close()
+}
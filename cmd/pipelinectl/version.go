@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+)
+
+func init() {
+	register("version", "Show build info; with --all, of every running component", runVersion)
+}
+
+// versionTarget is one component instance whose /version is queried.
+type versionTarget struct {
+	component string
+	url       string
+}
+
+// versionResult is a target's build info, or why it could not be read.
+type versionResult struct {
+	target versionTarget
+	info   buildinfo.Info
+	err    error
+}
+
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	all := fs.Bool("all", false, "Query the /version endpoint of every component")
+	urls := map[string]*string{
+		"api":           fs.String("api-url", "http://localhost:8080", "API base URLs, comma-separated for replicas"),
+		"mq-server":     fs.String("mq-url", "http://localhost:9001", "MQ server HTTP base URLs"),
+		"streamer":      fs.String("streamer-url", "http://localhost:8082", "Streamer health endpoint base URLs"),
+		"collector":     fs.String("collector-url", "http://localhost:8083", "Collector health endpoint base URLs"),
+		"otlp-receiver": fs.String("otlp-url", "http://localhost:4318", "OTLP receiver HTTP base URLs"),
+	}
+	timeout := fs.Duration("timeout", 5*time.Second, "Request timeout per component")
+	fs.Parse(args)
+
+	self := buildinfo.Get("pipelinectl")
+	if !*all {
+		fmt.Println(self)
+		return nil
+	}
+
+	var targets []versionTarget
+	for component, list := range urls {
+		for _, url := range strings.Split(*list, ",") {
+			if url = strings.TrimRight(strings.TrimSpace(url), "/"); url != "" {
+				targets = append(targets, versionTarget{component: component, url: url})
+			}
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].component != targets[j].component {
+			return targets[i].component < targets[j].component
+		}
+		return targets[i].url < targets[j].url
+	})
+
+	results := queryVersions(targets, *timeout)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tURL\tVERSION\tCOMMIT\tBUILT\tGO")
+	fmt.Fprintf(w, "pipelinectl\t-\t%s\t%s\t%s\t%s\n", self.Version, commitOf(self), self.BuildDate, self.GoVersion)
+
+	builds := make(map[string][]string) // version (commit) -> components running it
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(w, "%s\t%s\tunreachable: %v\t\t\t\n", r.target.component, r.target.url, r.err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.target.component, r.target.url,
+			r.info.Version, commitOf(r.info), r.info.BuildDate, r.info.GoVersion)
+
+		build := r.info.Version
+		if c := commitOf(r.info); c != "" {
+			build += " (" + c + ")"
+		}
+		builds[build] = append(builds[build], r.target.component)
+	}
+	w.Flush()
+
+	if len(builds) > 1 {
+		var mixed []string
+		for build, components := range builds {
+			mixed = append(mixed, fmt.Sprintf("%s on %s", build, strings.Join(components, ", ")))
+		}
+		sort.Strings(mixed)
+		return fmt.Errorf("mixed versions deployed: %s", strings.Join(mixed, "; "))
+	}
+	return nil
+}
+
+// commitOf returns the build's commit, marked when built from a dirty tree.
+func commitOf(info buildinfo.Info) string {
+	if info.Modified {
+		return info.Commit + "+dirty"
+	}
+	return info.Commit
+}
+
+// queryVersions fetches every target's /version concurrently, returning
+// the results in target order.
+func queryVersions(targets []versionTarget, timeout time.Duration) []versionResult {
+	results := make([]versionResult, len(targets))
+	client := &http.Client{Timeout: timeout}
+
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t versionTarget) {
+			defer wg.Done()
+			info, err := fetchVersion(client, t.url+"/version")
+			results[i] = versionResult{target: t, info: info, err: err}
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// fetchVersion reads build info from a /version endpoint.
+func fetchVersion(client *http.Client, url string) (buildinfo.Info, error) {
+	var info buildinfo.Info
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		return info, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return info, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("%s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return info, fmt.Errorf("invalid response: %w", err)
+	}
+	return info, nil
+}
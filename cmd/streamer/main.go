@@ -1,61 +1,162 @@
 // Telemetry Streamer - Reads CSV telemetry data and streams to MQ
 //
-// This component continuously reads GPU telemetry from a CSV file,
+// This component continuously reads GPU telemetry from a CSV file, or
+// from a site-specific source registered with the source package,
 // buffers it locally, and publishes batches to the message queue
-// at configurable intervals.
+// at configurable intervals. It can also accept StatsD or JSON
+// datagrams over UDP from senders that must never block on the pipeline.
+// Its health endpoint reports the MQ offset acknowledged data reaches.
 package main
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/doctor"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/dryrun"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/envelope"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/parser"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/signing"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/source"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/udp"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
 	"github.com/google/uuid"
 )
 
 func main() {
-	// Setup logging
-	logger := log.New(os.Stdout, "[STREAMER] ", log.LstdFlags|log.Lmicroseconds)
+	// Setup logging, keeping recent lines for support reports
+	recentLogs := support.NewLogs(support.DefaultLogLines)
+	logger := log.New(io.MultiWriter(os.Stdout, recentLogs), "[STREAMER] ", log.LstdFlags|log.Lmicroseconds)
+
+	// Map deprecated settings to their replacements before loading configuration
+	for _, w := range config.Migrate(config.ComponentStreamer) {
+		logger.Printf("Warning: %s", w)
+	}
 
 	// Load configuration from environment variables
 	cfg := config.DefaultStreamerConfig()
 
+	// "doctor" runs the full pre-flight report, including dependency probes, and exits
+	checks := doctor.StreamerChecks(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctor.Main(os.Stdout, checks))
+	}
+	// "dry-run" parses and validates the whole input file, reports what
+	// replaying it would publish, and exits without connecting to the MQ
+	if len(os.Args) > 1 && os.Args[1] == "dry-run" {
+		os.Exit(dryrun.Main(os.Stdout, cfg))
+	}
+
 	logger.Printf("Starting Telemetry Streamer...")
+	logger.Printf("Build: %s", buildinfo.Get("streamer"))
 	logger.Printf("  Instance ID: %s", cfg.InstanceID)
-	logger.Printf("  CSV Path: %s", cfg.CSVPath)
+	if cfg.Source == source.FileName {
+		logger.Printf("  Input: %s (format=%s)", cfg.CSVPath, cfg.InputFormat)
+		if len(cfg.CSVColumns) > 0 {
+			logger.Printf("  CSV Columns: %s (metric_name, uuid and value are always parsed)", strings.Join(cfg.CSVColumns, ", "))
+		}
+	} else {
+		logger.Printf("  Source: %s", cfg.Source)
+	}
 	logger.Printf("  Collect Interval: %v", cfg.CollectInterval)
 	logger.Printf("  Publish Interval: %v", cfg.StreamInterval)
 	logger.Printf("  Loop: %v", cfg.Loop)
 	logger.Printf("  MQ Server: %s:%d", cfg.MQ.Host, cfg.MQ.Port)
+	if cfg.UDP.Enabled() {
+		logger.Printf("  UDP Listener: %s:%d (max datagram %d bytes, max buffered %d metrics)",
+			cfg.UDP.Host, cfg.UDP.Port, cfg.UDP.MaxDatagramSize, cfg.UDP.MaxBuffered)
+	}
 
-	// Validate CSV file
-	if err := parser.ValidateCSV(cfg.CSVPath); err != nil {
-		logger.Fatalf("Invalid CSV file: %v", err)
+	logger.Println("Running pre-flight checks...")
+	if err := doctor.Preflight(logger, checks); err != nil {
+		logger.Fatalf("Pre-flight checks failed: %v", err)
+	}
+
+	var mappings []parser.Mapping
+	if cfg.CSVMappingsFile != "" {
+		var err error
+		if mappings, err = parser.LoadMappingsFile(cfg.CSVMappingsFile); err != nil {
+			logger.Fatalf("Failed to load CSV mappings: %v", err)
+		}
+		for _, m := range mappings {
+			logger.Printf("  CSV Mapping: %s (fingerprints %s)", m.Name, strings.Join(m.Fingerprints, ", "))
+		}
+	}
+
+	// Site-specific sources are compiled in or loaded from plugins
+	if err := source.LoadPlugins(cfg.SourcePlugins); err != nil {
+		logger.Fatalf("Failed to load source plugins: %v", err)
+	}
+	if len(cfg.SourcePlugins) > 0 {
+		logger.Printf("  Registered Sources: %s", strings.Join(source.Names(), ", "))
+	}
+	if !slices.Contains(source.Names(), cfg.Source) {
+		logger.Fatalf("Unknown source %q (registered: %s)", cfg.Source, strings.Join(source.Names(), ", "))
 	}
 
 	// Count records for logging
-	recordCount, err := parser.CountRecords(cfg.CSVPath)
+	if cfg.Source == source.FileName && cfg.InputFormat != "none" {
+		recordCount, err := parser.Count(cfg.CSVPath, cfg.InputFormat)
+		if err != nil {
+			logger.Printf("Warning: could not count records: %v", err)
+		} else {
+			logger.Printf("  Total Records: %d", recordCount)
+		}
+	}
+
+	// Seal payloads end to end when encryption keys are configured
+	keys, err := envelope.New(context.Background(), cfg.Encryption)
 	if err != nil {
-		logger.Printf("Warning: could not count records: %v", err)
-	} else {
-		logger.Printf("  Total Records: %d", recordCount)
+		logger.Fatalf("Failed to load encryption keys: %v", err)
+	}
+	if keys != nil {
+		logger.Printf("  Payload Encryption: %s with key %s", envelope.AlgorithmAESGCM, keys.Primary())
+	}
+
+	// Sign batches as this instance when a signing key is configured
+	signer, err := signing.NewSigner(cfg.InstanceID, cfg.Signing)
+	if err != nil {
+		logger.Fatalf("Failed to load signing key: %v", err)
+	}
+	if signer != nil {
+		logger.Printf("  Batch Signing: ed25519, registered as %s", signer.PublicKey())
 	}
 
 	// Create MQ client
+	reconnect := retry.FromConfig("streamer-mq-reconnect", cfg.MQ.Reconnect)
+	reconnect.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("MQ reconnect attempt %d failed, retrying in %v: %v", attempt, wait, err)
+	}
 	client := mq.NewClient(mq.ClientConfig{
-		Host:          cfg.MQ.Host,
-		Port:          cfg.MQ.Port,
-		Timeout:       10 * time.Second,
-		AutoReconnect: true,
+		Host:            cfg.MQ.Host,
+		Port:            cfg.MQ.Port,
+		Failover:        cfg.MQ.Failover,
+		WireFormat:      cfg.MQ.WireFormat,
+		Timeout:         10 * time.Second,
+		AutoReconnect:   true,
+		ReconnectPolicy: &reconnect,
 	})
 
 	// Connect to MQ server
@@ -67,6 +168,26 @@ func main() {
 
 	logger.Println("Connected to MQ server")
 
+	// Resume after the checkpoint of the data the MQ last acknowledged
+	state, err := statestore.Open(cfg.State)
+	if err != nil {
+		logger.Fatalf("Failed to open state store: %v", err)
+	}
+	if state != nil {
+		defer state.Close()
+		logger.Printf("  State Store: %s", cfg.State.Backend)
+		if cfg.SourceCheckpoint == "" {
+			saved, err := state.Get(context.Background(), checkpointKey(cfg.InstanceID))
+			switch {
+			case err == nil:
+				cfg.SourceCheckpoint = string(saved)
+				logger.Printf("  Source Checkpoint: %q (saved)", cfg.SourceCheckpoint)
+			case !errors.Is(err, statestore.ErrNotFound):
+				logger.Fatalf("Failed to load the saved source checkpoint: %v", err)
+			}
+		}
+	}
+
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -83,20 +204,79 @@ func main() {
 
 	// Start streaming
 	streamer := &Streamer{
-		client:      client,
-		cfg:         cfg,
-		logger:      logger,
-		buffer:      make([]*models.GPUMetric, 0, 1000),
-		batchesSent: 0,
-		metricsSent: 0,
+		client:   client,
+		cfg:      cfg,
+		logger:   logger,
+		clock:    clock.Real,
+		buffer:   make([]*models.GPUMetric, 0, 1000),
+		mappings: mappings,
+		state:    state,
+		keys:     keys,
+		signer:   signer,
+	}
+	streamer.publishRetry = retry.FromConfig("streamer-publish", cfg.PublishRetry)
+	streamer.publishRetry.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("Publish attempt %d failed, retrying in %v: %v", attempt, wait, err)
+	}
+
+	if cfg.UDP.Enabled() {
+		listener, err := udp.Listen(udp.Config{
+			Addr:            net.JoinHostPort(cfg.UDP.Host, strconv.Itoa(cfg.UDP.Port)),
+			MaxDatagramSize: cfg.UDP.MaxDatagramSize,
+			ReadBuffer:      cfg.UDP.ReadBuffer,
+		}, streamer.addDatagramMetrics, logger)
+		if err != nil {
+			logger.Fatalf("Failed to start UDP listener: %v", err)
+		}
+		streamer.udp = listener
+		logger.Printf("Listening for UDP telemetry on %s", listener.Addr())
+	}
+
+	streamer.support = support.NewSource("streamer", cfg, recentLogs)
+	streamer.support.AddStats("publish", func() any { return streamer.Progress() })
+	if streamer.udp != nil {
+		streamer.support.AddStats("udp", func() any { return streamer.udp.Stats() })
+	}
+
+	// Announce this streamer for the pipeline topology
+	if cfg.MQ.HeartbeatInterval > 0 {
+		hb := topology.Heartbeat("streamer", cfg.InstanceID, []string{models.RelationPublishes})
+		go topology.Announce(ctx, client, hb, cfg.MQ.HeartbeatInterval, logger)
+	}
+
+	if cfg.HealthPort != 0 {
+		health := &http.Server{
+			Addr:    net.JoinHostPort(cfg.HealthHost, strconv.Itoa(cfg.HealthPort)),
+			Handler: streamer.healthHandler(),
+		}
+		go func() {
+			if err := health.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Printf("Health endpoint stopped: %v", err)
+			}
+		}()
+		defer health.Close()
+		logger.Printf("Health endpoint listening on %s", health.Addr)
 	}
 
 	if err := streamer.Run(ctx); err != nil && ctx.Err() == nil {
 		logger.Fatalf("Streamer error: %v", err)
 	}
 
+	p := streamer.Progress()
 	logger.Printf("Streamer stopped. Total batches sent: %d, Total metrics sent: %d",
-		streamer.batchesSent, streamer.metricsSent)
+		p.BatchesSent, p.MetricsSent)
+	logger.Printf("Acknowledged data ends at %s", p.boundary())
+	if p.BatchesDropped > 0 {
+		logger.Printf("Data loss: %d batches (%d metrics) were dropped", p.BatchesDropped, p.MetricsDropped)
+	}
+	if streamer.udp != nil {
+		st := streamer.udp.Stats()
+		logger.Printf("UDP: datagrams=%d, metrics=%d, truncated=%d, malformed=%d, rejected=%d, overflow=%d",
+			st.Datagrams, st.Metrics, st.Truncated, st.Malformed, st.Rejected, st.Overflow)
+	}
+	for _, st := range retry.Snapshot() {
+		logger.Printf("Retry %s: attempts=%d, retries=%d, failures=%d", st.Name, st.Attempts, st.Retries, st.Failures)
+	}
 }
 
 // Streamer handles reading CSV data, buffering, and publishing to MQ.
@@ -104,15 +284,140 @@ type Streamer struct {
 	client      *mq.Client
 	cfg         config.StreamerConfig
 	logger      *log.Logger
+	clock       clock.Clock         // Paces collection and publishing and stamps metrics
 	buffer      []*models.GPUMetric // Local buffer to collect metrics
+	bufferLines *models.LineRange   // CSV lines read into the buffer, for lineage
+	bufferCheck string              // Source checkpoint after the buffered metrics
 	bufferMu    sync.Mutex          // Protect buffer access
-	batchesSent int64
-	metricsSent int64
+
+	progressMu sync.Mutex
+	progress   PublishProgress
+
+	publishRetry retry.Policy
+
+	// mappings are the CSV column layouts input files are read through
+	mappings []parser.Mapping
+
+	// udp receives datagrams into udpBuffer; nil when the listener is disabled.
+	// UDP metrics are published in their own batches, apart from file lineage.
+	udp          *udp.Listener
+	udpBuffer    []models.GPUMetric // protected by bufferMu
+	udpLossShown udp.Stats          // counters at the last loss report
+
+	// support serves the support report on the health port
+	support *support.Source
+
+	// state keeps the source checkpoint of acknowledged data; nil keeps none
+	state statestore.Store
+
+	// keys seals published payloads; nil publishes them in the clear
+	keys *envelope.Keyring
+
+	// signer signs published batches; nil leaves them unsigned
+	signer *signing.Signer
+}
+
+// PublishProgress is how far the MQ has acknowledged the streamer's data.
+type PublishProgress struct {
+	// PublishedThrough is the highest MQ log offset assigned to an
+	// acknowledged batch; absent until the first acknowledgement
+	PublishedThrough *mq.Offset `json:"published_through,omitempty"`
+
+	// LastBatchID is the batch acknowledged last
+	LastBatchID string `json:"last_batch_id,omitempty"`
+
+	// LastSourceLine is the last input file line in acknowledged batches
+	LastSourceLine int `json:"last_source_line,omitempty"`
+
+	// LastCheckpoint is the source checkpoint after the acknowledged
+	// metrics; STREAMER_SOURCE_CHECKPOINT resumes from it
+	LastCheckpoint string `json:"last_checkpoint,omitempty"`
+
+	// LastAckAt is when the last acknowledgement arrived
+	LastAckAt *time.Time `json:"last_ack_at,omitempty"`
+
+	BatchesSent    int64 `json:"batches_sent"`
+	MetricsSent    int64 `json:"metrics_sent"`
+	BatchesDropped int64 `json:"batches_dropped"`
+	MetricsDropped int64 `json:"metrics_dropped"`
+}
+
+// boundary describes where acknowledged data ends, for data-loss reports.
+func (p PublishProgress) boundary() string {
+	if p.PublishedThrough == nil {
+		return "nothing (no batch acknowledged yet)"
+	}
+	b := fmt.Sprintf("MQ offset %d (batch %s", *p.PublishedThrough, p.LastBatchID)
+	if p.LastSourceLine > 0 {
+		b += fmt.Sprintf(", source line %d", p.LastSourceLine)
+	}
+	if p.LastCheckpoint != "" {
+		b += fmt.Sprintf(", checkpoint %q", p.LastCheckpoint)
+	}
+	return b + ")"
+}
+
+// Progress returns how far published data has been acknowledged.
+func (s *Streamer) Progress() PublishProgress {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	return s.progress
 }
 
-// Run starts two goroutines:
-// 1. Collector - reads CSV and buffers locally
-// 2. Publisher - periodically sends buffer to MQ
+// acknowledged records a batch the MQ accepted at offset, and the source
+// checkpoint after it, if it came from the source.
+func (s *Streamer) acknowledged(batch *models.MetricBatch, offset mq.Offset, checkpoint string) PublishProgress {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	p := &s.progress
+	if p.PublishedThrough == nil || offset > *p.PublishedThrough {
+		p.PublishedThrough = &offset
+		p.LastBatchID = batch.BatchID
+	}
+	if batch.SourceLines != nil {
+		p.LastSourceLine = batch.SourceLines.Last
+	}
+	if checkpoint != "" {
+		p.LastCheckpoint = checkpoint
+	}
+	now := s.clock.Now()
+	p.LastAckAt = &now
+	p.BatchesSent++
+	p.MetricsSent += int64(len(batch.Metrics))
+	return *p
+}
+
+// dropped records a batch that could not be published.
+func (s *Streamer) dropped(batch *models.MetricBatch) PublishProgress {
+	s.progressMu.Lock()
+	defer s.progressMu.Unlock()
+	s.progress.BatchesDropped++
+	s.progress.MetricsDropped += int64(len(batch.Metrics))
+	return s.progress
+}
+
+// healthHandler serves the health endpoint with the publish progress, the
+// build info and the support report.
+func (s *Streamer) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Status string `json:"status"`
+			PublishProgress
+		}{"healthy", s.Progress()})
+	})
+	mux.HandleFunc("/version", buildinfo.Handler("streamer"))
+	if s.support != nil {
+		mux.HandleFunc("/debug/support", s.support.Handler())
+	}
+	return mux
+}
+
+// Run starts up to three goroutines:
+// 1. Collector - reads the source and buffers locally (unless input format is none)
+// 2. UDP listener - buffers metrics from datagrams (when enabled)
+// 3. Publisher - periodically sends buffers to MQ
 func (s *Streamer) Run(ctx context.Context) error {
 	var wg sync.WaitGroup
 
@@ -120,56 +425,93 @@ func (s *Streamer) Run(ctx context.Context) error {
 	collectorDone := make(chan struct{})
 
 	// Start collector goroutine
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		defer close(collectorDone)
-		s.collectLoop(ctx)
-	}()
+	if s.cfg.Source != source.FileName || s.cfg.InputFormat != "none" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(collectorDone)
+			s.collectLoop(ctx)
+		}()
+	}
+
+	// Keep publishing datagrams after the file is done; stop only on shutdown
+	publishUntil := (<-chan struct{})(collectorDone)
+	if s.udp != nil {
+		publishUntil = nil
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.udp.Serve(ctx); err != nil {
+				s.logger.Printf("UDP listener stopped: %v", err)
+			}
+		}()
+	}
 
 	// Start publisher goroutine
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		s.publishLoop(ctx, collectorDone)
+		s.publishLoop(ctx, publishUntil)
 	}()
 
 	wg.Wait()
 	return nil
 }
 
-// collectLoop continuously reads from CSV and buffers metrics.
+// collectLoop continuously reads from the source and buffers metrics. The
+// first pass resumes after the configured checkpoint; later passes start
+// over.
 func (s *Streamer) collectLoop(ctx context.Context) {
-	ticker := time.NewTicker(s.cfg.CollectInterval)
+	ticker := s.clock.NewTicker(s.cfg.CollectInterval)
 	defer ticker.Stop()
 
+	checkpoint := s.cfg.SourceCheckpoint
 	for {
-		// Create parser for this iteration
-		csvParser, err := parser.NewCSVParser(s.cfg.CSVPath)
+		// Open the source for this iteration
+		src, err := source.New(s.cfg.Source, source.Config{
+			Path:     s.cfg.CSVPath,
+			Format:   s.cfg.InputFormat,
+			Mappings: s.mappings,
+			Columns:  s.cfg.CSVColumns,
+			Options:  s.cfg.SourceOptions,
+		})
+		if err == nil {
+			err = src.Open(ctx, checkpoint)
+		}
 		if err != nil {
-			s.logger.Printf("Error opening CSV: %v", err)
+			s.logger.Printf("Error opening input: %v", err)
 			return
 		}
+		if checkpoint != "" {
+			s.logger.Printf("Resuming %s source after checkpoint %q", s.cfg.Source, checkpoint)
+		}
+		checkpoint = ""
+		if f, ok := src.(*source.File); ok {
+			if name, fingerprint := f.Mapping(); name != "" {
+				s.logger.Printf("Reading %s through CSV mapping %q (header fingerprint %s)", s.cfg.CSVPath, name, fingerprint)
+			}
+		}
 
-		// Read all records from CSV
-		if err := s.readCSV(ctx, csvParser, ticker); err != nil {
-			csvParser.Close()
+		// Read all metrics from the source
+		if err := s.readSource(ctx, src, ticker); err != nil {
+			src.Close()
 			if ctx.Err() != nil {
 				return // Graceful shutdown
 			}
-			s.logger.Printf("Error reading CSV: %v", err)
+			s.logger.Printf("Error reading input: %v", err)
 			return
 		}
 
-		csvParser.Close()
+		src.Close()
 
 		// Check if we should loop
 		if !s.cfg.Loop {
-			s.logger.Println("Finished reading CSV (loop disabled)")
+			s.logger.Println("Finished reading input (loop disabled)")
 			return
 		}
 
-		s.logger.Println("Reached end of CSV, restarting from beginning...")
+		s.logger.Println("Reached end of input, restarting from beginning...")
 
 		// Check for shutdown before looping
 		select {
@@ -180,51 +522,86 @@ func (s *Streamer) collectLoop(ctx context.Context) {
 	}
 }
 
-// readCSV reads data from CSV and adds to buffer.
-func (s *Streamer) readCSV(ctx context.Context, csvParser *parser.CSVParser, ticker *time.Ticker) error {
+// readSource reads metrics from the source into the buffer until it is
+// exhausted. The file source is replayed one metric per tick; others are
+// read up to BatchSize metrics per tick.
+func (s *Streamer) readSource(ctx context.Context, src source.Source, ticker clock.Ticker) error {
+	max := s.cfg.BatchSize
+	if s.cfg.Source == source.FileName {
+		max = 1
+	}
+	lines, _ := src.(source.LineReporter)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-ticker.C:
-			// Read one metric at a time
-			metric, err := csvParser.ReadNext()
+		case <-ticker.C():
+			metrics, err := src.ReadBatch(ctx, max)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
 			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
 				s.logger.Printf("Error reading metric: %v", err)
+			}
+			if len(metrics) == 0 {
 				continue
 			}
 
-			// End of file
-			if metric == nil {
-				return nil
+			// Update timestamps to current time
+			now := s.clock.Now()
+			for _, metric := range metrics {
+				metric.Timestamp = now
 			}
 
-			// Update timestamp to current time
-			metric.Timestamp = time.Now()
-
 			// Add to buffer (thread-safe)
 			s.bufferMu.Lock()
-			s.buffer = append(s.buffer, metric)
+			s.buffer = append(s.buffer, metrics...)
+			if lines != nil {
+				line := lines.Line()
+				if s.bufferLines == nil {
+					s.bufferLines = &models.LineRange{First: line}
+				}
+				s.bufferLines.Last = line
+			}
+			s.bufferCheck = src.Checkpoint()
 			bufLen := len(s.buffer)
 			s.bufferMu.Unlock()
 
-			if bufLen%100 == 0 {
+			if bufLen/100 != (bufLen-len(metrics))/100 {
 				s.logger.Printf("Buffer size: %d metrics", bufLen)
 			}
 		}
 	}
 }
 
+// addDatagramMetrics buffers metrics received over UDP, reporting false
+// when the buffer is full so the listener drops and counts them.
+func (s *Streamer) addDatagramMetrics(metrics []models.GPUMetric) bool {
+	s.bufferMu.Lock()
+	defer s.bufferMu.Unlock()
+	if len(s.udpBuffer)+len(metrics) > s.cfg.UDP.MaxBuffered {
+		return false
+	}
+	s.udpBuffer = append(s.udpBuffer, metrics...)
+	return true
+}
+
 // publishLoop periodically sends buffered metrics to MQ.
 func (s *Streamer) publishLoop(ctx context.Context, collectorDone <-chan struct{}) {
-	ticker := time.NewTicker(s.cfg.StreamInterval)
+	ticker := s.clock.NewTicker(s.cfg.StreamInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			// Final flush before shutdown
-			s.flushBuffer(ctx)
+			// Final flush before shutdown, on a fresh context since ctx is already cancelled
+			flushCtx, cancel := context.WithTimeout(context.Background(), s.cfg.MQ.PublishTimeout)
+			s.flushBuffer(flushCtx)
+			cancel()
 			return
 
 		case <-collectorDone:
@@ -232,41 +609,58 @@ func (s *Streamer) publishLoop(ctx context.Context, collectorDone <-chan struct{
 			s.flushBuffer(ctx)
 			return
 
-		case <-ticker.C:
+		case <-ticker.C():
 			// Periodic flush
 			s.flushBuffer(ctx)
 		}
 	}
 }
 
-// flushBuffer sends all buffered metrics to MQ and clears the buffer.
+// flushBuffer sends all buffered metrics to MQ and clears the buffers.
 func (s *Streamer) flushBuffer(ctx context.Context) {
-	// Get and clear buffer atomically
+	// Get and clear buffers atomically
 	s.bufferMu.Lock()
-	if len(s.buffer) == 0 {
-		s.bufferMu.Unlock()
-		return
-	}
-
-	// Take ownership of current buffer
 	metrics := s.buffer
-	s.buffer = make([]*models.GPUMetric, 0, 1000)
+	lines := s.bufferLines
+	checkpoint := s.bufferCheck
+	datagramMetrics := s.udpBuffer
+	if len(metrics) > 0 {
+		s.buffer = make([]*models.GPUMetric, 0, 1000)
+		s.bufferLines = nil
+		s.bufferCheck = ""
+	}
+	s.udpBuffer = nil
 	s.bufferMu.Unlock()
 
-	s.logger.Printf("Flushing %d metrics to MQ...", len(metrics))
-
-	// Create batch
-	batch := &models.MetricBatch{
-		BatchID:     uuid.New().String(),
-		Source:      s.cfg.InstanceID,
-		CollectedAt: time.Now(),
-		Metrics:     make([]models.GPUMetric, len(metrics)),
+	if len(metrics) > 0 {
+		batch := &models.MetricBatch{
+			SourceLines: lines,
+			Metrics:     make([]models.GPUMetric, len(metrics)),
+		}
+		if s.cfg.Source == source.FileName {
+			batch.SourceFile = s.cfg.CSVPath
+		}
+		for i, m := range metrics {
+			batch.Metrics[i] = *m
+		}
+		s.publishBatch(ctx, batch, checkpoint)
 	}
 
-	// Copy metrics to batch
-	for i, m := range metrics {
-		batch.Metrics[i] = *m
+	if len(datagramMetrics) > 0 {
+		s.publishBatch(ctx, &models.MetricBatch{Metrics: datagramMetrics}, "")
 	}
+	s.reportUDPLoss()
+}
+
+// publishBatch stamps a batch with its identity and publishes it. A batch
+// that cannot be published after retries is dropped. checkpoint is the
+// source's position after the batch, empty for UDP batches.
+func (s *Streamer) publishBatch(ctx context.Context, batch *models.MetricBatch, checkpoint string) {
+	s.logger.Printf("Flushing %d metrics to MQ...", len(batch.Metrics))
+
+	batch.BatchID = uuid.New().String()
+	batch.Source = s.cfg.InstanceID
+	batch.CollectedAt = s.clock.Now()
 
 	// Serialize
 	payload, err := json.Marshal(batch)
@@ -275,25 +669,102 @@ func (s *Streamer) flushBuffer(ctx context.Context) {
 		return
 	}
 
-	// Publish with retry
-	var publishErr error
-	for retries := 0; retries < 3; retries++ {
-		publishErr = s.client.Publish(ctx, payload)
-		if publishErr == nil {
-			break
-		}
-		s.logger.Printf("Publish attempt %d failed: %v", retries+1, publishErr)
-		time.Sleep(time.Duration(retries+1) * time.Second)
+	// Stamp routing metadata so the MQ and consumers can act without decoding the payload
+	metadata := batchMetadata(batch)
+	s.signer.Sign(payload, metadata)
+	if payload, err = s.keys.Seal(payload, metadata); err != nil {
+		s.logger.Printf("Error encrypting batch: %v", err)
+		return
 	}
 
+	// Publish with retry, bounding each attempt so a stalled server cannot hold the batch
+	var offset mq.Offset
+	publishErr := s.publishRetry.Do(ctx, func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, s.cfg.MQ.PublishTimeout)
+		defer cancel()
+		var err error
+		offset, err = s.client.PublishConfirmed(attemptCtx, payload, metadata)
+		return err
+	})
+
 	if publishErr != nil {
-		s.logger.Printf("Failed to publish batch after retries: %v", publishErr)
+		p := s.dropped(batch)
+		lines := ""
+		if batch.SourceLines != nil {
+			lines = fmt.Sprintf(", source lines %d-%d", batch.SourceLines.First, batch.SourceLines.Last)
+		}
+		s.logger.Printf("Failed to publish batch: %v", publishErr)
+		s.logger.Printf("Data loss: dropped batch %s (%d metrics%s); acknowledged data ends at %s",
+			batch.BatchID, len(batch.Metrics), lines, p.boundary())
+		return
+	}
+
+	// Logged per batch so that after a crash the last line marks where
+	// acknowledged data ends
+	p := s.acknowledged(batch, offset, checkpoint)
+	s.logger.Printf("Batch sent: %d metrics at offset %d (total: %d batches, %d metrics)",
+		len(batch.Metrics), offset, p.BatchesSent, p.MetricsSent)
+	if checkpoint != "" {
+		s.saveCheckpoint(checkpoint)
+	}
+}
+
+// saveCheckpoint saves the source checkpoint of acknowledged data, so a
+// restart resumes after it. It is saved even while shutting down, since
+// the batch was acknowledged.
+func (s *Streamer) saveCheckpoint(checkpoint string) {
+	if s.state == nil {
 		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.state.Put(ctx, checkpointKey(s.cfg.InstanceID), []byte(checkpoint)); err != nil {
+		s.logger.Printf("Failed to save source checkpoint %q: %v", checkpoint, err)
+	}
+}
+
+// checkpointKey is where a streamer instance's checkpoint is saved.
+func checkpointKey(instanceID string) string {
+	return "streamer/" + instanceID + "/checkpoint"
+}
 
-	s.batchesSent++
-	s.metricsSent += int64(len(metrics))
+// reportUDPLoss logs what the UDP listener dropped since the last report.
+func (s *Streamer) reportUDPLoss() {
+	if s.udp == nil {
+		return
+	}
+	st := s.udp.Stats()
+	prev := s.udpLossShown
+	lost := udp.Stats{
+		Truncated: st.Truncated - prev.Truncated,
+		Malformed: st.Malformed - prev.Malformed,
+		Rejected:  st.Rejected - prev.Rejected,
+		Overflow:  st.Overflow - prev.Overflow,
+	}
+	if !lost.Lost() {
+		return
+	}
+	s.udpLossShown = st
+	s.logger.Printf("UDP: dropped %d oversized and %d malformed datagrams, %d invalid metrics and %d metrics over the buffer limit",
+		lost.Truncated, lost.Malformed, lost.Rejected, lost.Overflow)
+}
 
-	s.logger.Printf("Batch sent: %d metrics (total: %d batches, %d metrics)",
-		len(metrics), s.batchesSent, s.metricsSent)
+// batchMetadata summarizes a batch as MQ message metadata for server-side
+// filters, partition keying, and collector-side quick-skip. A batch from a
+// single host is keyed by its hostname, so one collector sees all of a
+// host's batches in order; mixed batches are spread over the partitions.
+func batchMetadata(batch *models.MetricBatch) map[string]string {
+	hostnames := batch.Hostnames()
+	metadata := map[string]string{
+		mq.MetaHostname:    mq.JoinMetadataSet(hostnames),
+		mq.MetaMetricName:  mq.JoinMetadataSet(batch.MetricNames()),
+		mq.MetaRecordCount: strconv.Itoa(len(batch.Metrics)),
+
+		// A retry after a lost response is dropped by the MQ instead of appended twice
+		mq.MetaIdempotencyKey: batch.BatchID,
+	}
+	if len(hostnames) == 1 {
+		metadata[mq.MetaPartitionKey] = hostnames[0]
+	}
+	return metadata
 }
@@ -0,0 +1,11 @@
+package main
+
+// Site-specific sources are compiled into the streamer by importing their
+// packages here for the side effect of their init functions, which call
+// source.Register:
+//
+//	import _ "example.com/site/telemetry/fabric"
+//
+// Keeping these imports in this file leaves the rest of the streamer
+// unchanged. Sources can also be built as Go plugins and loaded at startup
+// from STREAMER_SOURCE_PLUGINS.
@@ -0,0 +1,87 @@
+package alert
+
+import (
+	"sort"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Backtest replays a rule against historical metrics and returns the alerts
+// it would have fired, in firing order. Each GPU's samples are evaluated in
+// time order as if each were an evaluation: an alert is pending from the
+// first breaching sample, fires once the condition has held for the rule's
+// For duration, and resolves at the first sample that no longer breaches.
+// Alerts still firing at the last sample have a zero ResolvedAt. Metrics
+// for other metrics or GPUs the rule does not apply to are ignored, and the
+// rule's Enabled flag is not consulted. Threshold expressions use the
+// current baselines, and GPUs whose model has none are skipped.
+func Backtest(rule *models.AlertRule, metrics []*models.GPUMetric, baselines BaselineSource) ([]models.Alert, error) {
+	th, err := newThreshold(rule, baselines)
+	if err != nil {
+		return nil, err
+	}
+
+	byGPU := make(map[string][]*models.GPUMetric)
+	for _, m := range metrics {
+		if m.MetricName != rule.Metric || !rule.AppliesTo(m.Hostname, m.UUID) {
+			continue
+		}
+		byGPU[m.UUID] = append(byGPU[m.UUID], m)
+	}
+
+	forDuration := rule.ForDuration()
+	fired := make([]models.Alert, 0)
+	for _, samples := range byGPU {
+		sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp.Before(samples[j].Timestamp) })
+
+		var a *models.Alert
+		for _, m := range samples {
+			threshold, ok := th.For(m.ModelName)
+			if !ok {
+				break
+			}
+			if !rule.Compare(m.Value, threshold) {
+				if a != nil && a.State == models.AlertFiring {
+					a.State = models.AlertResolved
+					a.ResolvedAt = m.Timestamp
+					fired = append(fired, *a)
+				}
+				a = nil
+				continue
+			}
+			if a == nil {
+				a = &models.Alert{
+					ID:        models.AlertID(rule.ID, m.UUID),
+					RuleID:    rule.ID,
+					RuleName:  rule.Name,
+					Severity:  rule.Severity,
+					State:     models.AlertPending,
+					UUID:      m.UUID,
+					GPUID:     m.GPUID,
+					Hostname:  m.Hostname,
+					ModelName: m.ModelName,
+					Metric:    rule.Metric,
+					Op:        rule.Op,
+					ActiveAt:  m.Timestamp,
+				}
+			}
+			a.Value = m.Value
+			a.Threshold = threshold
+			if a.State == models.AlertPending && m.Timestamp.Sub(a.ActiveAt) >= forDuration {
+				a.State = models.AlertFiring
+				a.FiredAt = m.Timestamp
+			}
+		}
+		if a != nil && a.State == models.AlertFiring {
+			fired = append(fired, *a)
+		}
+	}
+
+	sort.Slice(fired, func(i, j int) bool {
+		if !fired[i].FiredAt.Equal(fired[j].FiredAt) {
+			return fired[i].FiredAt.Before(fired[j].FiredAt)
+		}
+		return fired[i].UUID < fired[j].UUID
+	})
+	return fired, nil
+}
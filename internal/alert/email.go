@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"text/template"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// EmailNotifier sends a plain-text message over SMTP.
+type EmailNotifier struct {
+	name     string
+	Addr     string
+	Host     string
+	From     string
+	Username string
+	Password string
+	To       []string
+	subject  *template.Template
+	text     *template.Template
+}
+
+// Name returns the notifier's configured name.
+func (e *EmailNotifier) Name() string { return e.name }
+
+// Send sends one message to all recipients. net/smtp does not take a
+// context, so cancellation only applies before the send starts.
+func (e *EmailNotifier) Send(ctx context.Context, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := e.message(n)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		auth = smtp.PlainAuth("", e.Username, e.Password, e.Host)
+	}
+	if err := smtp.SendMail(e.Addr, auth, e.From, e.To, msg); err != nil {
+		return perrors.Transient(fmt.Errorf("alert email failed: %w", err))
+	}
+	return nil
+}
+
+// message renders the subject and body into an RFC 5322 message.
+func (e *EmailNotifier) message(n *Notification) ([]byte, error) {
+	subject, err := render(e.subject, n)
+	if err != nil {
+		return nil, err
+	}
+	body, err := render(e.text, n)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.To, ", "))
+	// Header values must not contain line breaks
+	fmt.Fprintf(&buf, "Subject: %s\r\n", strings.Join(strings.Fields(subject), " "))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,364 @@
+// Package alert evaluates threshold rules against the latest value of every
+// GPU metric and notifies email, Slack and PagerDuty when alerts fire,
+// repeat, escalate and resolve, unless a silence or maintenance window holds
+// them back. Only the elected leader among API replicas evaluates rules.
+package alert
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// SilenceSource supplies the silences checked before notifying.
+type SilenceSource interface {
+	ListSilences(ctx context.Context) ([]*models.Silence, error)
+}
+
+// Evaluator periodically evaluates alert rules and tracks the resulting alerts.
+type Evaluator struct {
+	rules    RuleSource
+	latest   *cache.Latest
+	silences SilenceSource
+	router   *Router
+	leader   leader.Leader
+	events   notify.Notifier
+	interval time.Duration
+	logger   *log.Logger
+	clock    clock.Clock
+	reload   chan struct{}
+
+	mu               sync.Mutex
+	alerts           map[string]*models.Alert // alert ID -> pending or firing alert
+	lastEval         time.Time
+	maintenance      MaintenanceSource
+	maintenanceKinds map[string]bool
+	baselines        BaselineSource
+	windows          map[string]*window // annotation ID -> active maintenance window
+	state            statestore.Store
+	restored         bool // the saved state was loaded since this replica became leader
+}
+
+// NewEvaluator creates an evaluator of rules against the latest-values
+// cache that runs only while l leads. Alerts are routed to notifiers
+// through router, unless a silence from silences matches, and every firing
+// and resolution raises an event on events.
+func NewEvaluator(rules RuleSource, latest *cache.Latest, silences SilenceSource, router *Router, l leader.Leader,
+	events notify.Notifier, interval time.Duration, logger *log.Logger) *Evaluator {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Evaluator{
+		rules:    rules,
+		latest:   latest,
+		silences: silences,
+		router:   router,
+		leader:   l,
+		events:   events,
+		interval: interval,
+		logger:   logger,
+		clock:    clock.Real,
+		reload:   make(chan struct{}, 1),
+		alerts:   make(map[string]*models.Alert),
+		windows:  make(map[string]*window),
+	}
+}
+
+// Run evaluates the rules every interval, and straight away after Reload,
+// until ctx is done.
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := e.clock.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.Evaluate(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		case <-e.reload:
+		}
+	}
+}
+
+// SetClock replaces the clock that paces Run and times pending and firing
+// alerts. Call it before Run.
+func (e *Evaluator) SetClock(c clock.Clock) {
+	e.clock = c
+}
+
+// Reload asks Run to evaluate now, so rule changes take effect without
+// waiting for the next interval. It never blocks; reloads requested while
+// one is already pending are merged.
+func (e *Evaluator) Reload() {
+	select {
+	case e.reload <- struct{}{}:
+	default:
+	}
+}
+
+// CheckRule checks a rule, that it only names configured notifiers, and
+// that any threshold expression is over a metric with baselines.
+func (e *Evaluator) CheckRule(rule *models.AlertRule) error {
+	if err := ValidateRule(rule); err != nil {
+		return err
+	}
+	if err := e.router.CheckNotifiers(rule); err != nil {
+		return perrors.Validation(err)
+	}
+	_, err := newThreshold(rule, e.Baselines())
+	return err
+}
+
+// SetBaselines supplies the per-model baselines that threshold expressions
+// are computed from.
+func (e *Evaluator) SetBaselines(baselines BaselineSource) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.baselines = baselines
+}
+
+// Baselines returns the baselines threshold expressions use, or nil.
+func (e *Evaluator) Baselines() BaselineSource {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.baselines
+}
+
+// Evaluate runs one evaluation. Followers forget their alerts, so a replica
+// that becomes leader starts from the current data rather than stale state,
+// or from the state the previous leader saved when there is a state store.
+func (e *Evaluator) Evaluate(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.leader.IsLeader() {
+		clear(e.alerts)
+		clear(e.windows)
+		e.router.Reset()
+		e.restored = false
+		return
+	}
+	if !e.restored {
+		e.restore(ctx)
+	}
+
+	rules, err := e.rules.Rules(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.logger.Printf("Alert evaluator could not load rules: %v", err)
+		}
+		return
+	}
+	now := e.clock.Now()
+	// A silence store outage must not stop paging, so evaluation carries on
+	// without silences
+	silences := e.activeSilences(ctx, now)
+	windows := e.activeMaintenance(ctx, now)
+	snapshot := e.latest.Snapshot()
+
+	seen := make(map[string]bool, len(e.alerts))
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled {
+			continue
+		}
+		th, err := newThreshold(rule, e.baselines)
+		if err != nil {
+			e.logger.Printf("Alert rule %q skipped: %v", rule.Name, err)
+			continue
+		}
+		for j := range snapshot {
+			gpu := &snapshot[j]
+			mv, ok := gpu.Metrics[rule.Metric]
+			if !ok || !rule.AppliesTo(gpu.Hostname, gpu.UUID) {
+				continue
+			}
+			// GPUs whose model has no baseline yet are not evaluated
+			threshold, ok := th.For(gpu.ModelName)
+			if !ok || !rule.Compare(mv.Value, threshold) {
+				continue
+			}
+			id := models.AlertID(rule.ID, gpu.UUID)
+			seen[id] = true
+			e.observe(rule, gpu, mv.Value, threshold, silences, windows, now)
+		}
+	}
+
+	for id, a := range e.alerts {
+		if seen[id] {
+			continue
+		}
+		delete(e.alerts, id)
+		if a.State != models.AlertFiring {
+			continue
+		}
+		a.State = models.AlertResolved
+		a.ResolvedAt = now
+		e.raise(models.EventAlertResolved, a)
+		e.router.Resolved(a)
+	}
+	e.lastEval = now
+	e.save(ctx)
+}
+
+// observe updates the alert for a breaching GPU, firing it once the rule's
+// condition has held for its For duration.
+func (e *Evaluator) observe(rule *models.AlertRule, gpu *cache.GPUSnapshot, value, threshold float64,
+	silences []*models.Silence, windows []*models.Annotation, now time.Time) {
+	id := models.AlertID(rule.ID, gpu.UUID)
+	a, ok := e.alerts[id]
+	if !ok {
+		a = &models.Alert{
+			ID:       id,
+			RuleID:   rule.ID,
+			State:    models.AlertPending,
+			UUID:     gpu.UUID,
+			GPUID:    gpu.GPUID,
+			Hostname: gpu.Hostname,
+			ActiveAt: now,
+		}
+		e.alerts[id] = a
+	}
+	// Rules can change between evaluations, so the alert restates the current one
+	a.RuleName = rule.Name
+	a.Severity = rule.Severity
+	a.ModelName = gpu.ModelName
+	a.Metric = rule.Metric
+	a.Op = rule.Op
+	a.Threshold = threshold
+	a.Value = value
+	a.Summary = e.summary(rule, a)
+	a.SuppressedBy = ""
+	if w := maintenanceFor(windows, a); w != nil {
+		a.SuppressedBy = w.ID
+	}
+
+	if a.State == models.AlertPending && now.Sub(a.ActiveAt) >= rule.ForDuration() {
+		a.State = models.AlertFiring
+		a.FiredAt = now
+		e.raise(models.EventAlertFired, a)
+	}
+	if a.State == models.AlertFiring {
+		mute := MuteNone
+		switch {
+		case a.SuppressedBy != "":
+			mute = MuteMaintenance
+			e.windows[a.SuppressedBy].suppressed[id] = true
+		case silenced(silences, a):
+			mute = MuteSilence
+		}
+		e.router.Firing(a, rule.Notifiers, mute, now)
+	}
+}
+
+// summary renders the rule's summary template against the alert.
+func (e *Evaluator) summary(rule *models.AlertRule, a *models.Alert) string {
+	if rule.Summary == "" {
+		return ""
+	}
+	tmpl, err := parseTemplate("summary", rule.Summary, "")
+	if err == nil {
+		var text string
+		if text, err = render(tmpl, a); err == nil {
+			return text
+		}
+	}
+	e.logger.Printf("Alert rule %q summary: %v", rule.Name, err)
+	return ""
+}
+
+// activeSilences returns the silences in effect at now.
+func (e *Evaluator) activeSilences(ctx context.Context, now time.Time) []*models.Silence {
+	if e.silences == nil {
+		return nil
+	}
+	all, err := e.silences.ListSilences(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.logger.Printf("Alert evaluator could not load silences, notifying without them: %v", err)
+		}
+		return nil
+	}
+	var active []*models.Silence
+	for _, s := range all {
+		if s.Active(now) {
+			active = append(active, s)
+		}
+	}
+	return active
+}
+
+// silenced reports whether any silence matches the alert.
+func silenced(silences []*models.Silence, a *models.Alert) bool {
+	for _, s := range silences {
+		if s.Matches(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// raise sends an alert event to the webhooks.
+func (e *Evaluator) raise(eventType string, a *models.Alert) {
+	if e.events == nil {
+		return
+	}
+	e.events.Notify(eventType, map[string]interface{}{
+		"alert_id":  a.ID,
+		"rule":      a.RuleName,
+		"severity":  a.Severity,
+		"uuid":      a.UUID,
+		"hostname":  a.Hostname,
+		"gpu_id":    a.GPUID,
+		"metric":    a.Metric,
+		"value":     a.Value,
+		"threshold": a.Threshold,
+	})
+}
+
+// Alerts returns the pending and firing alerts, firing first and then by ID.
+func (e *Evaluator) Alerts() []models.Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]models.Alert, 0, len(e.alerts))
+	for _, a := range e.alerts {
+		out = append(out, *a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].State != out[j].State {
+			return out[i].State == models.AlertFiring
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Leading reports whether this replica evaluates rules.
+func (e *Evaluator) Leading() bool {
+	return e.leader.IsLeader()
+}
+
+// LastEvaluation returns when rules were last evaluated by this replica.
+func (e *Evaluator) LastEvaluation() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastEval
+}
+
+// Stats returns the router's notification counters.
+func (e *Evaluator) Stats() RouterStats {
+	return e.router.Stats()
+}
@@ -0,0 +1,396 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+type staticLeader bool
+
+func (l staticLeader) IsLeader() bool { return bool(l) }
+
+// recordingNotifier records raised event types.
+type recordingNotifier struct {
+	events []string
+}
+
+func (n *recordingNotifier) Notify(eventType string, data map[string]interface{}) {
+	n.events = append(n.events, eventType)
+}
+
+// fakeNotifier records notifications and returns err.
+type fakeNotifier struct {
+	name string
+	sent []Notification
+	err  error
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Send(ctx context.Context, n *Notification) error {
+	f.sent = append(f.sent, *n)
+	return f.err
+}
+
+// staticSilences returns fixed silences or an error.
+type staticSilences struct {
+	silences []*models.Silence
+	err      error
+}
+
+func (s *staticSilences) ListSilences(ctx context.Context) ([]*models.Silence, error) {
+	return s.silences, s.err
+}
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testAlertConfig() config.AlertConfig {
+	return config.AlertConfig{
+		RepeatInterval: time.Hour,
+		Timeout:        time.Second,
+		QueueSize:      100,
+		Retry:          config.RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 2},
+	}
+}
+
+func newTestRouter(cfg config.AlertConfig, notifiers ...Notifier) *Router {
+	byName := make(map[string]Notifier, len(notifiers))
+	for _, n := range notifiers {
+		byName[n.Name()] = n
+	}
+	return NewRouter(cfg, byName, log.New(io.Discard, "", 0))
+}
+
+// drain delivers every queued notification.
+func drain(r *Router) {
+	for {
+		select {
+		case d := <-r.queue:
+			r.deliver(context.Background(), d)
+		default:
+			return
+		}
+	}
+}
+
+func tempRule() models.AlertRule {
+	return models.AlertRule{
+		ID:        "gpu-hot",
+		Name:      "GPU hot",
+		Metric:    "DCGM_FI_DEV_GPU_TEMP",
+		Op:        ">",
+		Threshold: 85,
+		For:       "2m",
+		Severity:  models.SeverityCritical,
+		Summary:   "{{.Hostname}} GPU {{.GPUID}} at {{.Value}}C",
+		Enabled:   true,
+	}
+}
+
+func setTemp(latest *cache.Latest, value float64, at time.Time) {
+	latest.Update([]*models.GPUMetric{{
+		Timestamp:  at,
+		MetricName: "DCGM_FI_DEV_GPU_TEMP",
+		UUID:       "GPU-1",
+		Hostname:   "host-001",
+		GPUID:      3,
+		ModelName:  "H100",
+		Value:      value,
+	}})
+}
+
+type testEvaluator struct {
+	*Evaluator
+	clock    *clock.Simulated
+	latest   *cache.Latest
+	events   *recordingNotifier
+	silences *staticSilences
+	pager    *fakeNotifier
+}
+
+func newTestEvaluator(rules ...models.AlertRule) *testEvaluator {
+	te := &testEvaluator{
+		clock:    clock.NewSimulated(start),
+		latest:   cache.NewLatest(cache.SourceStorage),
+		events:   &recordingNotifier{},
+		silences: &staticSilences{},
+		pager:    &fakeNotifier{name: "pager"},
+	}
+	router := newTestRouter(testAlertConfig(), te.pager)
+	te.Evaluator = NewEvaluator(StaticRules(rules), te.latest, te.silences, router, staticLeader(true), te.events, time.Minute, log.New(io.Discard, "", 0))
+	te.SetClock(te.clock)
+	return te
+}
+
+// step advances the clock, evaluates and delivers notifications.
+func (te *testEvaluator) step(d time.Duration) {
+	te.clock.Advance(d)
+	te.Evaluate(context.Background())
+	drain(te.router)
+}
+
+func TestEvaluatorPendingFiringResolved(t *testing.T) {
+	te := newTestEvaluator(tempRule())
+	setTemp(te.latest, 90, start)
+
+	te.step(0)
+	alerts := te.Alerts()
+	if len(alerts) != 1 || alerts[0].State != models.AlertPending {
+		t.Fatalf("expected one pending alert, got %+v", alerts)
+	}
+	if len(te.pager.sent) != 0 {
+		t.Fatalf("pending alerts must not notify, got %d", len(te.pager.sent))
+	}
+
+	te.step(2 * time.Minute)
+	alerts = te.Alerts()
+	if alerts[0].State != models.AlertFiring || alerts[0].ID != "gpu-hot/GPU-1" {
+		t.Fatalf("expected firing alert after 2m, got %+v", alerts[0])
+	}
+	if alerts[0].Summary != "host-001 GPU 3 at 90C" {
+		t.Errorf("unexpected summary %q", alerts[0].Summary)
+	}
+	if len(te.pager.sent) != 1 || te.pager.sent[0].Status != models.AlertFiring {
+		t.Fatalf("expected one firing notification, got %+v", te.pager.sent)
+	}
+
+	setTemp(te.latest, 70, te.clock.Now())
+	te.step(time.Minute)
+	if len(te.Alerts()) != 0 {
+		t.Errorf("expected the alert to resolve, got %+v", te.Alerts())
+	}
+	if len(te.pager.sent) != 2 || te.pager.sent[1].Status != models.AlertResolved || te.pager.sent[1].Alert.ResolvedAt.IsZero() {
+		t.Errorf("expected a resolved notification, got %+v", te.pager.sent)
+	}
+	want := []string{models.EventAlertFired, models.EventAlertResolved}
+	if len(te.events.events) != 2 || te.events.events[0] != want[0] || te.events.events[1] != want[1] {
+		t.Errorf("expected events %v, got %v", want, te.events.events)
+	}
+}
+
+func TestEvaluatorPendingRecoveryIsSilent(t *testing.T) {
+	te := newTestEvaluator(tempRule())
+	setTemp(te.latest, 90, start)
+	te.step(0)
+
+	setTemp(te.latest, 70, start)
+	te.step(time.Minute)
+	if len(te.Alerts()) != 0 || len(te.pager.sent) != 0 || len(te.events.events) != 0 {
+		t.Errorf("a pending alert that recovers should vanish quietly, got alerts=%v sent=%d events=%v",
+			te.Alerts(), len(te.pager.sent), te.events.events)
+	}
+}
+
+func TestEvaluatorRunPacedByClock(t *testing.T) {
+	te := newTestEvaluator(tempRule())
+	setTemp(te.latest, 90, start)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go te.Run(ctx)
+
+	waitForState := func(state string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if alerts := te.Alerts(); len(alerts) == 1 && alerts[0].State == state {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("alert never reached %s, got %+v", state, te.Alerts())
+	}
+
+	te.clock.BlockUntil(1)
+	waitForState(models.AlertPending)
+
+	// No evaluation happens until the simulated interval elapses
+	te.clock.Advance(2 * time.Minute)
+	waitForState(models.AlertFiring)
+}
+
+func TestEvaluatorRuleScopeAndDisabled(t *testing.T) {
+	other := tempRule()
+	other.ID = "other-host"
+	other.Hostname = "host-9*"
+	other.For = ""
+	disabled := tempRule()
+	disabled.ID = "disabled"
+	disabled.For = ""
+	disabled.Enabled = false
+
+	te := newTestEvaluator(other, disabled)
+	setTemp(te.latest, 90, start)
+	te.step(0)
+	if len(te.Alerts()) != 0 {
+		t.Errorf("expected no alerts from out-of-scope or disabled rules, got %+v", te.Alerts())
+	}
+}
+
+func TestEvaluatorSilences(t *testing.T) {
+	rule := tempRule()
+	rule.For = ""
+	te := newTestEvaluator(rule)
+	te.silences.silences = []*models.Silence{{
+		ID:       "s1",
+		Matchers: models.SilenceMatchers{Hostname: "host-0*"},
+		StartsAt: start,
+		EndsAt:   start.Add(30 * time.Minute),
+	}}
+	setTemp(te.latest, 90, start)
+
+	te.step(0)
+	if len(te.Alerts()) != 1 || len(te.pager.sent) != 0 {
+		t.Fatalf("silenced alerts are tracked but not notified, got alerts=%d sent=%d", len(te.Alerts()), len(te.pager.sent))
+	}
+	if got := te.Stats().Silenced; got != 1 {
+		t.Errorf("expected 1 silenced notification, got %d", got)
+	}
+
+	// Once the silence ends, the held-back notification goes out
+	te.step(31 * time.Minute)
+	if len(te.pager.sent) != 1 || te.pager.sent[0].Repeat {
+		t.Errorf("expected a first notification after the silence, got %+v", te.pager.sent)
+	}
+
+	// A silence store outage must not stop paging
+	te.silences.err = errors.New("storage down")
+	te.step(time.Hour)
+	if len(te.pager.sent) != 2 || !te.pager.sent[1].Repeat {
+		t.Errorf("expected a repeat notification without silences, got %+v", te.pager.sent)
+	}
+}
+
+func TestEvaluatorFollowerForgetsAlerts(t *testing.T) {
+	rule := tempRule()
+	rule.For = ""
+	te := newTestEvaluator(rule)
+	setTemp(te.latest, 90, start)
+	te.step(0)
+	if len(te.Alerts()) != 1 {
+		t.Fatalf("expected one alert, got %d", len(te.Alerts()))
+	}
+
+	te.leader = staticLeader(false)
+	te.step(time.Minute)
+	if len(te.Alerts()) != 0 {
+		t.Errorf("expected followers to forget alerts, got %+v", te.Alerts())
+	}
+}
+
+func TestEvaluatorResumesSavedState(t *testing.T) {
+	rule := tempRule()
+	rule.For = ""
+	store := statestore.NewMemory(nil)
+	first := newTestEvaluator(rule)
+	first.SetStateStore(store)
+	setTemp(first.latest, 90, start)
+	first.step(0)
+	if len(first.pager.sent) != 1 {
+		t.Fatalf("expected one firing notification, got %+v", first.pager.sent)
+	}
+
+	// The next leader resumes the firing alert without paging again
+	next := newTestEvaluator(rule)
+	next.SetStateStore(store)
+	setTemp(next.latest, 90, start)
+	next.step(time.Minute)
+	alerts := next.Alerts()
+	if len(alerts) != 1 || alerts[0].State != models.AlertFiring || !alerts[0].FiredAt.Equal(start) {
+		t.Fatalf("expected the saved firing alert, got %+v", alerts)
+	}
+	if len(next.pager.sent) != 0 {
+		t.Errorf("expected no notification before the repeat interval, got %+v", next.pager.sent)
+	}
+
+	// Resolving notifies the notifiers the previous leader paged
+	setTemp(next.latest, 70, next.clock.Now())
+	next.step(time.Minute)
+	if len(next.pager.sent) != 1 || next.pager.sent[0].Status != models.AlertResolved {
+		t.Errorf("expected a resolved notification, got %+v", next.pager.sent)
+	}
+}
+
+func TestRouterRepeatAndEscalation(t *testing.T) {
+	cfg := testAlertConfig()
+	cfg.EscalateAfter = 30 * time.Minute
+	cfg.EscalateTo = []string{"manager"}
+	slack := &fakeNotifier{name: "slack"}
+	pager := &fakeNotifier{name: "pager"}
+	manager := &fakeNotifier{name: "manager"}
+	r := newTestRouter(cfg, slack, pager, manager)
+
+	a := &models.Alert{ID: "gpu-hot/GPU-1", State: models.AlertFiring, FiredAt: start}
+	targets := []string{"slack", "pager"}
+
+	r.Firing(a, targets, MuteNone, start)
+	drain(r)
+	if len(slack.sent) != 1 || len(pager.sent) != 1 || len(manager.sent) != 0 {
+		t.Fatalf("expected first notification to the rule's notifiers, got slack=%d pager=%d manager=%d",
+			len(slack.sent), len(pager.sent), len(manager.sent))
+	}
+
+	// Not yet due: nothing is sent
+	r.Firing(a, targets, MuteNone, start.Add(10*time.Minute))
+	drain(r)
+	if len(slack.sent) != 1 {
+		t.Fatalf("expected no notification before the repeat interval, got %d", len(slack.sent))
+	}
+
+	// Escalation goes only to the escalation notifiers
+	r.Firing(a, targets, MuteNone, start.Add(30*time.Minute))
+	drain(r)
+	if len(manager.sent) != 1 || !manager.sent[0].Escalated || len(slack.sent) != 1 {
+		t.Fatalf("expected escalation to manager only, got slack=%d manager=%+v", len(slack.sent), manager.sent)
+	}
+
+	// Repeats after escalation include the escalation notifiers
+	r.Firing(a, targets, MuteNone, start.Add(time.Hour))
+	drain(r)
+	if len(slack.sent) != 2 || !slack.sent[1].Repeat || !slack.sent[1].Escalated || len(manager.sent) != 2 {
+		t.Fatalf("expected escalated repeat to everyone, got slack=%+v manager=%d", slack.sent, len(manager.sent))
+	}
+
+	r.Resolved(a)
+	drain(r)
+	for _, n := range []*fakeNotifier{slack, pager, manager} {
+		if last := n.sent[len(n.sent)-1]; last.Status != models.AlertResolved {
+			t.Errorf("expected %s to get the resolved notice, got %s", n.name, last.Status)
+		}
+	}
+	if stats := r.Stats(); stats.Sent != 9 || stats.Failed != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRouterDefaultsToAllNotifiersAndCountsFailures(t *testing.T) {
+	ok := &fakeNotifier{name: "ok"}
+	broken := &fakeNotifier{name: "broken", err: errors.New("connection refused")}
+	r := newTestRouter(testAlertConfig(), ok, broken)
+
+	a := &models.Alert{ID: "a", State: models.AlertFiring, FiredAt: start}
+	r.Firing(a, nil, MuteNone, start)
+	drain(r)
+	if len(ok.sent) != 1 || len(broken.sent) != 2 {
+		t.Errorf("expected every notifier to be tried, with retries for the broken one; got ok=%d broken=%d", len(ok.sent), len(broken.sent))
+	}
+	if stats := r.Stats(); stats.Sent != 1 || stats.Failed != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// Alerts that were never notified have nothing to resolve
+	r.Resolved(&models.Alert{ID: "unknown"})
+	if len(r.queue) != 0 {
+		t.Errorf("expected no resolved notice for an unknown alert, got %d", len(r.queue))
+	}
+}
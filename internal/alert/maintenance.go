@@ -0,0 +1,144 @@
+package alert
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// MaintenanceSource supplies the annotations that declare maintenance windows.
+type MaintenanceSource interface {
+	ListAnnotations(ctx context.Context, query *models.AnnotationQuery) ([]*models.Annotation, error)
+}
+
+// MaintenanceWindow is an active maintenance window and how many alerts it
+// has suppressed so far.
+type MaintenanceWindow struct {
+	AnnotationID string    `json:"annotation_id"`
+	Kind         string    `json:"kind"`
+	Title        string    `json:"title"`
+	Hostname     string    `json:"hostname,omitempty"`
+	UUID         string    `json:"uuid,omitempty"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+
+	// SuppressedAlerts counts the distinct alerts that fired on the window's
+	// GPUs while it was active
+	SuppressedAlerts int `json:"suppressed_alerts"`
+}
+
+// window is what the evaluator remembers about an active maintenance window.
+type window struct {
+	annotation *models.Annotation
+	suppressed map[string]bool // alert IDs
+}
+
+// SetMaintenance makes annotations of the given kinds suppress notifications
+// for the GPUs they cover between their start and end. Annotations without
+// an end mark a moment rather than a window and suppress nothing.
+func (e *Evaluator) SetMaintenance(source MaintenanceSource, kinds []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maintenance = source
+	e.maintenanceKinds = make(map[string]bool, len(kinds))
+	for _, kind := range kinds {
+		e.maintenanceKinds[kind] = true
+	}
+}
+
+// activeMaintenance refreshes the active maintenance windows and returns
+// their annotations. If annotations cannot be read, the windows last read
+// stay in effect until they end, so a storage outage in the middle of a
+// driver upgrade does not page the on-call.
+func (e *Evaluator) activeMaintenance(ctx context.Context, now time.Time) []*models.Annotation {
+	if e.maintenance == nil || len(e.maintenanceKinds) == 0 {
+		return nil
+	}
+
+	var active []*models.Annotation
+	all, err := e.maintenance.ListAnnotations(ctx, &models.AnnotationQuery{StartTime: &now, EndTime: &now})
+	if err != nil {
+		if ctx.Err() == nil {
+			e.logger.Printf("Alert evaluator could not load maintenance windows, keeping the last read: %v", err)
+		}
+		for _, w := range e.windows {
+			all = append(all, w.annotation)
+		}
+	}
+	for _, a := range all {
+		if e.maintenanceKinds[a.Kind] && !a.End.IsZero() && !now.Before(a.Start) && now.Before(a.End) {
+			active = append(active, a)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Start.Before(active[j].Start) })
+
+	current := make(map[string]bool, len(active))
+	for _, a := range active {
+		current[a.ID] = true
+		if w, ok := e.windows[a.ID]; ok {
+			w.annotation = a
+			continue
+		}
+		e.windows[a.ID] = &window{annotation: a, suppressed: make(map[string]bool)}
+		e.logger.Printf("Maintenance window %q started, suppressing alert notifications%s until %s",
+			a.Title, windowScope(a), a.End.Format(time.RFC3339))
+	}
+	for id, w := range e.windows {
+		if !current[id] {
+			delete(e.windows, id)
+			e.logger.Printf("Maintenance window %q ended, %d alerts suppressed", w.annotation.Title, len(w.suppressed))
+		}
+	}
+	return active
+}
+
+// windowScope describes the GPUs a maintenance window covers for logging.
+func windowScope(a *models.Annotation) string {
+	switch {
+	case a.UUID != "":
+		return " for GPU " + a.UUID
+	case a.Hostname != "":
+		return " for host " + a.Hostname
+	}
+	return " for every GPU"
+}
+
+// maintenanceFor returns the first window covering the alert's GPU.
+func maintenanceFor(windows []*models.Annotation, a *models.Alert) *models.Annotation {
+	for _, w := range windows {
+		if w.AppliesTo(a.Hostname, a.UUID) {
+			return w
+		}
+	}
+	return nil
+}
+
+// Maintenance returns the active maintenance windows, earliest first.
+func (e *Evaluator) Maintenance() []MaintenanceWindow {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]MaintenanceWindow, 0, len(e.windows))
+	for _, w := range e.windows {
+		a := w.annotation
+		out = append(out, MaintenanceWindow{
+			AnnotationID:     a.ID,
+			Kind:             a.Kind,
+			Title:            a.Title,
+			Hostname:         a.Hostname,
+			UUID:             a.UUID,
+			Start:            a.Start,
+			End:              a.End,
+			SuppressedAlerts: len(w.suppressed),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].AnnotationID < out[j].AnnotationID
+	})
+	return out
+}
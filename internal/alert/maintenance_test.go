@@ -0,0 +1,79 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// staticAnnotations returns fixed annotations matching the query, or an error.
+type staticAnnotations struct {
+	annotations []*models.Annotation
+	err         error
+}
+
+func (s *staticAnnotations) ListAnnotations(ctx context.Context, query *models.AnnotationQuery) ([]*models.Annotation, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	var out []*models.Annotation
+	for _, a := range s.annotations {
+		if query.Matches(a) {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func TestEvaluatorMaintenanceWindows(t *testing.T) {
+	rule := tempRule()
+	rule.For = ""
+	te := newTestEvaluator(rule)
+	annotations := &staticAnnotations{annotations: []*models.Annotation{
+		{ID: "upgrade", Kind: models.AnnotationDriverUpgrade, Title: "Driver 550", Hostname: "host-001",
+			Start: start, End: start.Add(2 * time.Hour)},
+		// Other hosts, other kinds and moments without an end suppress nothing
+		{ID: "elsewhere", Kind: models.AnnotationMaintenance, Title: "Rack 9", Hostname: "host-900",
+			Start: start, End: start.Add(2 * time.Hour)},
+		{ID: "job", Kind: models.AnnotationJobLaunch, Title: "Training run", Start: start, End: start.Add(2 * time.Hour)},
+		{ID: "reboot", Kind: models.AnnotationMaintenance, Title: "Reboot", Start: start.Add(time.Minute)},
+	}}
+	te.SetMaintenance(annotations, []string{models.AnnotationMaintenance, models.AnnotationDriverUpgrade})
+	setTemp(te.latest, 90, start)
+
+	te.step(time.Minute)
+	alerts := te.Alerts()
+	if len(alerts) != 1 || alerts[0].State != models.AlertFiring || alerts[0].SuppressedBy != "upgrade" {
+		t.Fatalf("expected a firing alert suppressed by the upgrade, got %+v", alerts)
+	}
+	if len(te.pager.sent) != 0 {
+		t.Fatalf("suppressed alerts must not notify, got %+v", te.pager.sent)
+	}
+	if got := te.Stats().Suppressed; got != 1 {
+		t.Errorf("expected 1 suppressed notification, got %d", got)
+	}
+	windows := te.Maintenance()
+	if len(windows) != 2 || windows[1].AnnotationID != "upgrade" || windows[1].SuppressedAlerts != 1 {
+		t.Errorf("expected the upgrade window with 1 suppressed alert, got %+v", windows)
+	}
+
+	// An annotation outage keeps the windows last read
+	annotations.err = errors.New("storage down")
+	te.step(time.Hour)
+	if len(te.pager.sent) != 0 || len(te.Maintenance()) != 2 {
+		t.Errorf("expected the window to hold during an outage, got sent=%+v windows=%+v", te.pager.sent, te.Maintenance())
+	}
+
+	// Once the window ends, the held-back notification goes out
+	annotations.err = nil
+	te.step(time.Hour)
+	if len(te.pager.sent) != 1 || te.pager.sent[0].Alert.SuppressedBy != "" {
+		t.Errorf("expected a notification after the window, got %+v", te.pager.sent)
+	}
+	if len(te.Maintenance()) != 0 {
+		t.Errorf("expected no active windows, got %+v", te.Maintenance())
+	}
+}
@@ -0,0 +1,146 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Notifier types.
+const (
+	TypeEmail     = "email"
+	TypeSlack     = "slack"
+	TypePagerDuty = "pagerduty"
+)
+
+// DefaultTemplate renders the message text when a notifier sets no template.
+const DefaultTemplate = `[{{.Status | upper}}{{if .Escalated}}, ESCALATED{{end}}] {{.Alert.RuleName}} ({{.Alert.Severity}}) on {{.Alert.Hostname}} GPU {{.Alert.GPUID}}: ` +
+	`{{.Alert.Metric}} = {{printf "%.2f" .Alert.Value}} ({{.Alert.Op}} {{.Alert.Threshold}}){{if .Alert.Summary}} - {{.Alert.Summary}}{{end}}`
+
+// DefaultSubject renders the email subject when a notifier sets none.
+const DefaultSubject = `[{{.Status | upper}}] {{.Alert.RuleName}} on {{.Alert.Hostname}} GPU {{.Alert.GPUID}}`
+
+// Notification is one message about an alert. Templates are rendered
+// against it, so its fields are the template's variables.
+type Notification struct {
+	Alert models.Alert
+
+	// Status is firing or resolved
+	Status string
+
+	// Repeat is true when the alert was already notified to this notifier
+	Repeat bool
+
+	// Escalated is true once the alert has fired for longer than the
+	// escalation delay
+	Escalated bool
+}
+
+// Notifier sends alert notifications to one channel.
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, n *Notification) error
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// parseTemplate parses a notification template, falling back to def when text is empty.
+func parseTemplate(name, text, def string) (*template.Template, error) {
+	if text == "" {
+		text = def
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, perrors.Validation(fmt.Errorf("invalid %s template: %w", name, err))
+	}
+	return tmpl, nil
+}
+
+// render executes a template against data.
+func render(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", perrors.Permanent(fmt.Errorf("rendering %s template: %w", tmpl.Name(), err))
+	}
+	return buf.String(), nil
+}
+
+// NewNotifiers builds the configured notifiers, keyed by name. Email
+// notifiers send through the scheduler's SMTP settings.
+func NewNotifiers(cfgs []config.AlertNotifierConfig, smtpCfg config.SchedulerConfig) (map[string]Notifier, error) {
+	notifiers := make(map[string]Notifier, len(cfgs))
+	for _, cfg := range cfgs {
+		text, err := parseTemplate(cfg.Name+" message", cfg.Template, DefaultTemplate)
+		if err != nil {
+			return nil, err
+		}
+
+		var n Notifier
+		switch cfg.Type {
+		case TypeEmail:
+			subject, err := parseTemplate(cfg.Name+" subject", cfg.Subject, DefaultSubject)
+			if err != nil {
+				return nil, err
+			}
+			n = &EmailNotifier{
+				name:     cfg.Name,
+				Addr:     fmt.Sprintf("%s:%d", smtpCfg.SMTPHost, smtpCfg.SMTPPort),
+				Host:     smtpCfg.SMTPHost,
+				From:     smtpCfg.SMTPFrom,
+				Username: smtpCfg.SMTPUsername,
+				Password: smtpCfg.SMTPPassword,
+				To:       cfg.To,
+				subject:  subject,
+				text:     text,
+			}
+		case TypeSlack:
+			n = &SlackNotifier{name: cfg.Name, URL: cfg.URL, Client: http.DefaultClient, text: text}
+		case TypePagerDuty:
+			url := cfg.URL
+			if url == "" {
+				url = PagerDutyURL
+			}
+			n = &PagerDutyNotifier{name: cfg.Name, URL: url, RoutingKey: cfg.RoutingKey, Client: http.DefaultClient, text: text}
+		default:
+			return nil, perrors.Validation(fmt.Errorf("alert notifier %q has unknown type %q", cfg.Name, cfg.Type))
+		}
+		notifiers[cfg.Name] = n
+	}
+	return notifiers, nil
+}
+
+// postJSON posts a JSON body and classifies the response like other
+// outbound deliveries: throttling and server errors are retried.
+func postJSON(ctx context.Context, client *http.Client, target, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return perrors.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return perrors.Transient(fmt.Errorf("%s request failed: %w", target, err))
+	}
+	resp.Body.Close()
+
+	err = fmt.Errorf("%s returned HTTP %d", target, resp.StatusCode)
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return perrors.Transient(err)
+	default:
+		return perrors.Permanent(err)
+	}
+}
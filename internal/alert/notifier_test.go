@@ -0,0 +1,192 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func testNotification(status string) *Notification {
+	return &Notification{
+		Status: status,
+		Alert: models.Alert{
+			ID:        "gpu-hot/GPU-1",
+			RuleName:  "GPU hot",
+			Severity:  models.SeverityCritical,
+			UUID:      "GPU-1",
+			GPUID:     3,
+			Hostname:  "host-001",
+			ModelName: "H100",
+			Metric:    "DCGM_FI_DEV_GPU_TEMP",
+			Value:     91.25,
+			Op:        ">",
+			Threshold: 85,
+			Summary:   "too hot",
+			FiredAt:   start,
+		},
+	}
+}
+
+func TestNewNotifiers(t *testing.T) {
+	notifiers, err := NewNotifiers([]config.AlertNotifierConfig{
+		{Name: "ops", Type: TypeEmail, To: []string{"ops@example.com"}},
+		{Name: "chat", Type: TypeSlack, URL: "https://hooks.slack.com/services/x"},
+		{Name: "oncall", Type: TypePagerDuty, RoutingKey: "key"},
+	}, config.SchedulerConfig{SMTPHost: "smtp.example.com", SMTPPort: 587, SMTPFrom: "alerts@example.com"})
+	if err != nil {
+		t.Fatalf("NewNotifiers: %v", err)
+	}
+	if len(notifiers) != 3 {
+		t.Fatalf("expected 3 notifiers, got %d", len(notifiers))
+	}
+	if pd := notifiers["oncall"].(*PagerDutyNotifier); pd.URL != PagerDutyURL {
+		t.Errorf("expected the default PagerDuty URL, got %s", pd.URL)
+	}
+
+	_, err = NewNotifiers([]config.AlertNotifierConfig{
+		{Name: "chat", Type: TypeSlack, URL: "https://hooks.slack.com/services/x", Template: "{{.Alert.RuleName"},
+	}, config.SchedulerConfig{})
+	if !perrors.IsValidation(err) {
+		t.Errorf("expected a validation error for a bad template, got %v", err)
+	}
+}
+
+func TestDefaultTemplate(t *testing.T) {
+	tmpl, err := parseTemplate("message", "", DefaultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := testNotification(models.AlertFiring)
+	n.Escalated = true
+	text, err := render(tmpl, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[FIRING, ESCALATED] GPU hot (critical) on host-001 GPU 3: DCGM_FI_DEV_GPU_TEMP = 91.25 (> 85) - too hot"
+	if text != want {
+		t.Errorf("got  %q\nwant %q", text, want)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+
+	notifiers, err := NewNotifiers([]config.AlertNotifierConfig{
+		{Name: "chat", Type: TypeSlack, URL: server.URL, Template: "{{.Status}}: {{.Alert.RuleName}} on {{.Alert.Hostname}}"},
+	}, config.SchedulerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifiers["chat"].Send(context.Background(), testNotification(models.AlertFiring)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got["text"] != "firing: GPU hot on host-001" {
+		t.Errorf("unexpected Slack text %q", got["text"])
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	var events []pagerDutyEvent
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e pagerDutyEvent
+		json.NewDecoder(r.Body).Decode(&e)
+		events = append(events, e)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	pd := &PagerDutyNotifier{name: "oncall", URL: server.URL, RoutingKey: "key", Client: server.Client()}
+	pd.text, _ = parseTemplate("message", "", DefaultTemplate)
+
+	if err := pd.Send(context.Background(), testNotification(models.AlertFiring)); err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	if err := pd.Send(context.Background(), testNotification(models.AlertResolved)); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	trigger, resolve := events[0], events[1]
+	if trigger.EventAction != "trigger" || trigger.DedupKey != "gpu-hot/GPU-1" || trigger.RoutingKey != "key" {
+		t.Errorf("unexpected trigger event %+v", trigger)
+	}
+	if trigger.Payload == nil || trigger.Payload.Severity != "critical" || trigger.Payload.Source != "host-001" {
+		t.Errorf("unexpected trigger payload %+v", trigger.Payload)
+	}
+	if resolve.EventAction != "resolve" || resolve.DedupKey != trigger.DedupKey || resolve.Payload != nil {
+		t.Errorf("unexpected resolve event %+v", resolve)
+	}
+
+	status = http.StatusBadRequest
+	if err := pd.Send(context.Background(), testNotification(models.AlertFiring)); !perrors.IsPermanent(err) {
+		t.Errorf("expected a permanent error for HTTP 400, got %v", err)
+	}
+	status = http.StatusTooManyRequests
+	if err := pd.Send(context.Background(), testNotification(models.AlertFiring)); !perrors.IsTransient(err) {
+		t.Errorf("expected a transient error for HTTP 429, got %v", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	e := &EmailNotifier{From: "alerts@example.com", To: []string{"a@example.com", "b@example.com"}}
+	e.subject, _ = parseTemplate("subject", "", DefaultSubject)
+	e.text, _ = parseTemplate("message", "Value {{.Alert.Value}}\nSee dashboard", "")
+
+	msg, err := e.message(testNotification(models.AlertResolved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: [RESOLVED] GPU hot on host-001 GPU 3\r\n",
+		"Value 91.25\r\nSee dashboard\r\n",
+	} {
+		if !strings.Contains(string(msg), want) {
+			t.Errorf("expected message to contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`[
+		{"name": "gpu-hot", "metric": "DCGM_FI_DEV_GPU_TEMP", "op": ">", "threshold": 85, "for": "5m", "severity": "critical"},
+		{"id": "low-util", "name": "Low utilization", "metric": "DCGM_FI_DEV_GPU_UTIL", "op": "<", "threshold": 5, "severity": "info", "enabled": false}
+	]`), 0o644)
+
+	rules, err := LoadRulesFile(path)
+	if err != nil {
+		t.Fatalf("LoadRulesFile: %v", err)
+	}
+	if len(rules) != 2 || rules[0].ID != "gpu-hot" || !rules[0].Enabled || rules[1].Enabled {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+
+	os.WriteFile(path, []byte(`[{"name": "bad", "metric": "m", "op": "~", "severity": "page", "summary": "{{.Value"}]`), 0o644)
+	if _, err := LoadRulesFile(path); !perrors.IsValidation(err) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+
+	os.WriteFile(path, []byte(`[
+		{"name": "dup", "metric": "m", "op": ">", "severity": "info"},
+		{"name": "dup", "metric": "m", "op": "<", "severity": "info"}
+	]`), 0o644)
+	if _, err := LoadRulesFile(path); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected a duplicate rule error, got %v", err)
+	}
+}
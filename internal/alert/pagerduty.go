@@ -0,0 +1,89 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// PagerDutyURL is the Events API v2 endpoint.
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pagerDutySummaryLimit is the longest summary the Events API accepts.
+const pagerDutySummaryLimit = 1024
+
+// pagerDutyEvent is an Events API v2 event. The alert ID is the dedup key, so
+// repeats update the open incident and the resolve event closes it.
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// PagerDutyNotifier triggers and resolves PagerDuty incidents.
+type PagerDutyNotifier struct {
+	name       string
+	URL        string
+	RoutingKey string
+	Client     *http.Client
+	text       *template.Template
+}
+
+// Name returns the notifier's configured name.
+func (p *PagerDutyNotifier) Name() string { return p.name }
+
+// Send triggers an incident for a firing alert and resolves it once the
+// alert resolves.
+func (p *PagerDutyNotifier) Send(ctx context.Context, n *Notification) error {
+	event := pagerDutyEvent{RoutingKey: p.RoutingKey, EventAction: "trigger", DedupKey: n.Alert.ID}
+	if n.Status == models.AlertResolved {
+		event.EventAction = "resolve"
+	} else {
+		summary, err := render(p.text, n)
+		if err != nil {
+			return err
+		}
+		if len(summary) > pagerDutySummaryLimit {
+			summary = summary[:pagerDutySummaryLimit]
+		}
+		a := &n.Alert
+		event.Payload = &pagerDutyPayload{
+			Summary:   summary,
+			Source:    a.Hostname,
+			Severity:  a.Severity,
+			Timestamp: a.FiredAt.UTC().Format(time.RFC3339),
+			Component: a.UUID,
+			Group:     a.ModelName,
+			Class:     a.Metric,
+			CustomDetails: map[string]interface{}{
+				"rule":      a.RuleName,
+				"gpu_id":    a.GPUID,
+				"value":     a.Value,
+				"threshold": a.Threshold,
+				"escalated": n.Escalated,
+			},
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, p.Client, "pagerduty", p.URL, body)
+}
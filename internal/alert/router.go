@@ -0,0 +1,301 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// RouterStats counts notifications since the router started. Silenced and
+// Suppressed count notifications held back by silences and maintenance
+// windows.
+type RouterStats struct {
+	Sent       int64 `json:"sent"`
+	Failed     int64 `json:"failed"`
+	Silenced   int64 `json:"silenced"`
+	Suppressed int64 `json:"suppressed"`
+	Dropped    int64 `json:"dropped"`
+}
+
+// Mute says why a firing alert's notifications are held back.
+type Mute int
+
+// Mute reasons.
+const (
+	// MuteNone notifies as usual
+	MuteNone Mute = iota
+
+	// MuteSilence holds notifications back for a matching silence
+	MuteSilence
+
+	// MuteMaintenance holds notifications back for a maintenance window
+	// covering the GPU
+	MuteMaintenance
+)
+
+// delivery is one notification queued for one notifier.
+type delivery struct {
+	notifier     Notifier
+	notification Notification
+}
+
+// route is what the router remembers about a firing alert.
+type route struct {
+	lastSent  time.Time
+	escalated bool
+	sentTo    map[string]bool
+}
+
+// Router decides when firing and resolved alerts are notified, and to whom,
+// then delivers the notifications in the background. An alert is sent to its
+// rule's notifiers when it starts firing, again every repeat interval while
+// it keeps firing, and to the escalation notifiers as well once it has fired
+// for the escalation delay. Resolved notices go to every notifier that was
+// told about the alert.
+type Router struct {
+	notifiers     map[string]Notifier
+	names         []string
+	repeat        time.Duration
+	escalateAfter time.Duration
+	escalateTo    []string
+	timeout       time.Duration
+	retry         retry.Policy
+	logger        *log.Logger
+
+	queue      chan delivery
+	sent       atomic.Int64
+	failed     atomic.Int64
+	silenced   atomic.Int64
+	suppressed atomic.Int64
+	dropped    atomic.Int64
+
+	mu     sync.Mutex
+	routes map[string]*route // alert ID -> notification state
+}
+
+// NewRouter creates a router over the given notifiers.
+func NewRouter(cfg config.AlertConfig, notifiers map[string]Notifier, logger *log.Logger) *Router {
+	if logger == nil {
+		logger = log.Default()
+	}
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	policy := retry.FromConfig("alert-notify", cfg.Retry)
+	policy.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("Alert notification attempt %d failed, retrying in %v: %v", attempt, wait, err)
+	}
+
+	return &Router{
+		notifiers:     notifiers,
+		names:         names,
+		repeat:        cfg.RepeatInterval,
+		escalateAfter: cfg.EscalateAfter,
+		escalateTo:    cfg.EscalateTo,
+		timeout:       cfg.Timeout,
+		retry:         policy,
+		logger:        logger,
+		queue:         make(chan delivery, cfg.QueueSize),
+		routes:        make(map[string]*route),
+	}
+}
+
+// CheckNotifiers returns an error naming any notifier a rule refers to that
+// is not configured.
+func (r *Router) CheckNotifiers(rule *models.AlertRule) error {
+	for _, name := range rule.Notifiers {
+		if _, ok := r.notifiers[name]; !ok {
+			return fmt.Errorf("rule %q refers to unknown notifier %q (configured: %v)", rule.Name, name, r.names)
+		}
+	}
+	return nil
+}
+
+// Firing routes a firing alert to targets, or to every notifier when targets
+// is empty. It is called on every evaluation and only queues notifications
+// when one is due. Notifications that are due while the alert is muted are
+// counted and skipped; they go out once the silence or maintenance window
+// ends.
+func (r *Router) Firing(a *models.Alert, targets []string, mute Mute, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rt, ok := r.routes[a.ID]
+	if !ok {
+		rt = &route{sentTo: make(map[string]bool)}
+		r.routes[a.ID] = rt
+	}
+
+	escalate := r.escalateAfter > 0 && !rt.escalated && now.Sub(a.FiredAt) >= r.escalateAfter
+	due := rt.lastSent.IsZero() || now.Sub(rt.lastSent) >= r.repeat
+	if !due && !escalate {
+		return
+	}
+	switch mute {
+	case MuteSilence:
+		r.silenced.Add(1)
+		return
+	case MuteMaintenance:
+		r.suppressed.Add(1)
+		return
+	}
+
+	if escalate {
+		rt.escalated = true
+	}
+	recipients := r.escalateTo
+	if due {
+		rt.lastSent = now
+		if len(targets) == 0 {
+			targets = r.names
+		}
+		recipients = targets
+		if rt.escalated {
+			recipients = append(append([]string(nil), targets...), r.escalateTo...)
+		}
+	}
+
+	queued := make(map[string]bool, len(recipients))
+	for _, name := range recipients {
+		if queued[name] {
+			continue
+		}
+		queued[name] = true
+		r.enqueue(name, Notification{Alert: *a, Status: models.AlertFiring, Repeat: rt.sentTo[name], Escalated: rt.escalated})
+		rt.sentTo[name] = true
+	}
+}
+
+// Resolved sends a resolved notice to every notifier that was told about the
+// alert, and forgets it. Resolved notices are never muted, so incidents
+// opened before a silence or maintenance window still close.
+func (r *Router) Resolved(a *models.Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rt, ok := r.routes[a.ID]
+	if !ok {
+		return
+	}
+	delete(r.routes, a.ID)
+
+	names := make([]string, 0, len(rt.sentTo))
+	for name := range rt.sentTo {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r.enqueue(name, Notification{Alert: *a, Status: models.AlertResolved, Escalated: rt.escalated})
+	}
+}
+
+// RouteState is the notification state of a firing alert, saved so a new
+// leader neither repeats nor escalates notifications early.
+type RouteState struct {
+	LastSent  time.Time `json:"last_sent"`
+	Escalated bool      `json:"escalated,omitempty"`
+	SentTo    []string  `json:"sent_to,omitempty"`
+}
+
+// Routes returns the notification state of every alert, by alert ID.
+func (r *Router) Routes() map[string]RouteState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	routes := make(map[string]RouteState, len(r.routes))
+	for id, rt := range r.routes {
+		state := RouteState{LastSent: rt.lastSent, Escalated: rt.escalated, SentTo: make([]string, 0, len(rt.sentTo))}
+		for name := range rt.sentTo {
+			state.SentTo = append(state.SentTo, name)
+		}
+		sort.Strings(state.SentTo)
+		routes[id] = state
+	}
+	return routes
+}
+
+// RestoreRoutes replaces the notification state with routes.
+func (r *Router) RestoreRoutes(routes map[string]RouteState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.routes)
+	for id, state := range routes {
+		rt := &route{lastSent: state.LastSent, escalated: state.Escalated, sentTo: make(map[string]bool, len(state.SentTo))}
+		for _, name := range state.SentTo {
+			rt.sentTo[name] = true
+		}
+		r.routes[id] = rt
+	}
+}
+
+// Reset forgets every alert's notification state, for when this replica
+// stops evaluating.
+func (r *Router) Reset() {
+	r.mu.Lock()
+	clear(r.routes)
+	r.mu.Unlock()
+}
+
+// enqueue queues a notification without blocking, dropping it if the queue is full.
+func (r *Router) enqueue(name string, n Notification) {
+	notifier, ok := r.notifiers[name]
+	if !ok {
+		return
+	}
+	select {
+	case r.queue <- delivery{notifier: notifier, notification: n}:
+	default:
+		if r.dropped.Add(1) == 1 {
+			r.logger.Printf("Alert notification queue full, dropping notifications")
+		}
+	}
+}
+
+// Run delivers queued notifications until ctx is done.
+func (r *Router) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-r.queue:
+			r.deliver(ctx, d)
+		}
+	}
+}
+
+// deliver sends one notification, retrying transient failures.
+func (r *Router) deliver(ctx context.Context, d delivery) {
+	err := r.retry.Do(ctx, func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+		return d.notifier.Send(attemptCtx, &d.notification)
+	})
+	if err != nil {
+		r.failed.Add(1)
+		r.logger.Printf("Alert %s notification for %s to %s failed: %v",
+			d.notification.Status, d.notification.Alert.ID, d.notifier.Name(), err)
+		return
+	}
+	r.sent.Add(1)
+}
+
+// Stats returns the notification counters.
+func (r *Router) Stats() RouterStats {
+	return RouterStats{
+		Sent:       r.sent.Load(),
+		Failed:     r.failed.Load(),
+		Silenced:   r.silenced.Load(),
+		Suppressed: r.suppressed.Load(),
+		Dropped:    r.dropped.Load(),
+	}
+}
@@ -0,0 +1,141 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// RuleSource supplies the rules to evaluate. It is read on every evaluation.
+type RuleSource interface {
+	Rules(ctx context.Context) ([]models.AlertRule, error)
+}
+
+// StaticRules is a fixed set of rules.
+type StaticRules []models.AlertRule
+
+// Rules returns the rules.
+func (s StaticRules) Rules(ctx context.Context) ([]models.AlertRule, error) {
+	return s, nil
+}
+
+// RuleLister lists stored rules.
+type RuleLister interface {
+	ListAlertRules(ctx context.Context) ([]*models.AlertRule, error)
+}
+
+// RuleSet combines the rules file with rules managed through the API. Stored
+// rules are listed on every read, so changes take effect at the next
+// evaluation without a restart.
+type RuleSet struct {
+	file   StaticRules
+	store  RuleLister
+	logger *log.Logger
+
+	mu         sync.Mutex
+	lastStored []models.AlertRule
+}
+
+// NewRuleSet creates a rule set of file rules plus the rules in store, which
+// may be nil.
+func NewRuleSet(file StaticRules, store RuleLister, logger *log.Logger) *RuleSet {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &RuleSet{file: file, store: store, logger: logger}
+}
+
+// Rules returns the file rules followed by the stored rules. If the store
+// cannot be read, the stored rules from the last successful read are used,
+// so a storage outage does not resolve every API-managed alert.
+func (r *RuleSet) Rules(ctx context.Context) ([]models.AlertRule, error) {
+	rules := append([]models.AlertRule(nil), r.file...)
+	if r.store == nil {
+		return rules, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, err := r.store.ListAlertRules(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		r.logger.Printf("Could not list stored alert rules, using the last %d read: %v", len(r.lastStored), err)
+		return append(rules, r.lastStored...), nil
+	}
+	r.lastStored = r.lastStored[:0]
+	for _, rule := range stored {
+		r.lastStored = append(r.lastStored, *rule)
+	}
+	return append(rules, r.lastStored...), nil
+}
+
+// FileRules returns the rules loaded from the rules file.
+func (r *RuleSet) FileRules() StaticRules {
+	return r.file
+}
+
+// FileRule returns the file rule with the given ID.
+func (r *RuleSet) FileRule(id string) (models.AlertRule, bool) {
+	for _, rule := range r.file {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return models.AlertRule{}, false
+}
+
+// ValidateRule checks a rule, including that its summary template parses.
+func ValidateRule(rule *models.AlertRule) error {
+	if err := rule.Validate(); err != nil {
+		return perrors.Validation(err)
+	}
+	if rule.Summary != "" {
+		if _, err := parseTemplate("summary", rule.Summary, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadRulesFile reads a JSON array of rules. Rules are enabled unless they
+// set "enabled": false, and a rule without an ID uses its name.
+func LoadRulesFile(path string) (StaticRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, perrors.Validation(fmt.Errorf("%s: %w", path, err))
+	}
+
+	rules := make(StaticRules, 0, len(raw))
+	ids := make(map[string]bool, len(raw))
+	for i, r := range raw {
+		rule := models.AlertRule{Enabled: true}
+		if err := json.Unmarshal(r, &rule); err != nil {
+			return nil, perrors.Validation(fmt.Errorf("%s: rule %d: %w", path, i, err))
+		}
+		rule.Source = models.RuleSourceFile
+		if rule.ID == "" {
+			rule.ID = rule.Name
+		}
+		if err := ValidateRule(&rule); err != nil {
+			return nil, perrors.Validation(fmt.Errorf("%s: rule %q: %w", path, rule.Name, err))
+		}
+		if ids[rule.ID] {
+			return nil, perrors.Validation(fmt.Errorf("%s: duplicate rule ID %q", path, rule.ID))
+		}
+		ids[rule.ID] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
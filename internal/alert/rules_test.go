@@ -0,0 +1,128 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// fakeRuleLister returns fixed stored rules or an error.
+type fakeRuleLister struct {
+	rules []*models.AlertRule
+	err   error
+}
+
+func (f *fakeRuleLister) ListAlertRules(ctx context.Context) ([]*models.AlertRule, error) {
+	return f.rules, f.err
+}
+
+func TestRuleSetKeepsLastStoredRules(t *testing.T) {
+	file := tempRule()
+	stored := tempRule()
+	stored.ID, stored.Name = "stored", "Stored"
+	lister := &fakeRuleLister{rules: []*models.AlertRule{&stored}}
+	set := NewRuleSet(StaticRules{file}, lister, log.New(io.Discard, "", 0))
+
+	rules, err := set.Rules(context.Background())
+	if err != nil || len(rules) != 2 || rules[0].ID != "gpu-hot" || rules[1].ID != "stored" {
+		t.Fatalf("Rules() = %+v, %v", rules, err)
+	}
+
+	// A store outage keeps evaluating the rules last read
+	lister.rules, lister.err = nil, errors.New("influxdb down")
+	rules, err = set.Rules(context.Background())
+	if err != nil || len(rules) != 2 {
+		t.Fatalf("Rules() during outage = %+v, %v", rules, err)
+	}
+
+	// Deleting every stored rule leaves only the file's
+	lister.err = nil
+	if rules, _ = set.Rules(context.Background()); len(rules) != 1 {
+		t.Errorf("Rules() after delete = %+v, want only the file rule", rules)
+	}
+	if _, ok := set.FileRule("gpu-hot"); !ok {
+		t.Error("FileRule(gpu-hot) not found")
+	}
+}
+
+func TestEvaluatorPicksUpRuleChanges(t *testing.T) {
+	rule := tempRule()
+	rule.For = ""
+	lister := &fakeRuleLister{}
+	te := newTestEvaluator()
+	te.rules = NewRuleSet(nil, lister, log.New(io.Discard, "", 0))
+	setTemp(te.latest, 95, start)
+
+	te.step(time.Minute)
+	if len(te.Alerts()) != 0 {
+		t.Fatalf("alerts before the rule exists: %+v", te.Alerts())
+	}
+
+	lister.rules = []*models.AlertRule{&rule}
+	te.step(time.Minute)
+	if alerts := te.Alerts(); len(alerts) != 1 || alerts[0].State != models.AlertFiring {
+		t.Fatalf("alerts after create = %+v, want one firing", alerts)
+	}
+
+	rule.Enabled = false
+	te.step(time.Minute)
+	if len(te.Alerts()) != 0 || len(te.pager.sent) != 2 || te.pager.sent[1].Status != models.AlertResolved {
+		t.Errorf("disabling the rule should resolve its alert, got alerts %+v, sent %+v", te.Alerts(), te.pager.sent)
+	}
+
+	// Reloads never block, however many are requested
+	te.Reload()
+	te.Reload()
+}
+
+func TestBacktest(t *testing.T) {
+	rule := tempRule()
+	rule.Hostname = "host-*"
+	sample := func(uuid, host string, minute int, value float64) *models.GPUMetric {
+		return &models.GPUMetric{
+			Timestamp: start.Add(time.Duration(minute) * time.Minute), MetricName: rule.Metric,
+			UUID: uuid, Hostname: host, Value: value,
+		}
+	}
+	// Newest first, as storage returns them
+	metrics := []*models.GPUMetric{
+		sample("GPU-1", "host-001", 6, 99),
+		sample("GPU-1", "host-001", 5, 99),
+		sample("GPU-1", "host-001", 4, 80),
+		sample("GPU-1", "host-001", 3, 95),
+		sample("GPU-1", "host-001", 2, 95),
+		sample("GPU-1", "host-001", 1, 90),
+		sample("GPU-2", "host-002", 1, 90), // pending only, never fires
+		sample("GPU-2", "host-002", 2, 70),
+		sample("GPU-3", "other", 1, 99), // outside the rule's scope
+		sample("GPU-3", "other", 5, 99),
+	}
+
+	alerts, err := Backtest(&rule, metrics, nil)
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("Backtest() = %+v, want one alert", alerts)
+	}
+	a := alerts[0]
+	if a.UUID != "GPU-1" || a.State != models.AlertResolved {
+		t.Errorf("alert = %+v, want GPU-1 resolved", a)
+	}
+	if !a.FiredAt.Equal(start.Add(3*time.Minute)) || !a.ResolvedAt.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("fired %v resolved %v, want minutes 3 and 4", a.FiredAt, a.ResolvedAt)
+	}
+
+	// Without a for duration the second breach fires at once and is still firing
+	rule.For = ""
+	alerts, _ = Backtest(&rule, metrics, nil)
+	if len(alerts) != 3 {
+		t.Fatalf("Backtest() without for = %+v, want three alerts", alerts)
+	}
+	last := alerts[2]
+	if last.State != models.AlertFiring || !last.ResolvedAt.IsZero() || last.Value != 99 {
+		t.Errorf("last alert = %+v, want still firing at 99", last)
+	}
+}
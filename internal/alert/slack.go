@@ -0,0 +1,32 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"text/template"
+)
+
+// SlackNotifier posts the rendered message to a Slack incoming webhook.
+type SlackNotifier struct {
+	name   string
+	URL    string
+	Client *http.Client
+	text   *template.Template
+}
+
+// Name returns the notifier's configured name.
+func (s *SlackNotifier) Name() string { return s.name }
+
+// Send posts one message.
+func (s *SlackNotifier) Send(ctx context.Context, n *Notification) error {
+	text, err := render(s.text, n)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, s.Client, "slack", s.URL, body)
+}
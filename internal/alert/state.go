@@ -0,0 +1,67 @@
+package alert
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// stateKey is where the leader saves its alerts in the state store.
+const stateKey = "alerts/state"
+
+// savedState is the leader's pending and firing alerts and their
+// notification state.
+type savedState struct {
+	Alerts []models.Alert        `json:"alerts"`
+	Routes map[string]RouteState `json:"routes"`
+}
+
+// SetStateStore saves the alerts after every evaluation in store, so a
+// replica that becomes leader, or this one after a restart, carries on
+// from them: pending alerts keep counting toward their For duration and
+// firing alerts are not notified again before their repeat interval. Call
+// it before Run.
+func (e *Evaluator) SetStateStore(store statestore.Store) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state = store
+}
+
+// restore loads the saved alerts. Without them, or if they cannot be read,
+// evaluation starts from the current data.
+func (e *Evaluator) restore(ctx context.Context) {
+	e.restored = true
+	if e.state == nil {
+		return
+	}
+	var saved savedState
+	if err := statestore.GetJSON(ctx, e.state, stateKey, &saved); err != nil {
+		if !errors.Is(err, statestore.ErrNotFound) && ctx.Err() == nil {
+			e.logger.Printf("Alert evaluator could not load saved alerts, starting afresh: %v", err)
+		}
+		return
+	}
+	clear(e.alerts)
+	for i := range saved.Alerts {
+		a := saved.Alerts[i]
+		e.alerts[a.ID] = &a
+	}
+	e.router.RestoreRoutes(saved.Routes)
+	e.logger.Printf("Alert evaluator resumed %d saved alerts", len(saved.Alerts))
+}
+
+// save writes the alerts to the state store.
+func (e *Evaluator) save(ctx context.Context) {
+	if e.state == nil {
+		return
+	}
+	saved := savedState{Alerts: make([]models.Alert, 0, len(e.alerts)), Routes: e.router.Routes()}
+	for _, a := range e.alerts {
+		saved.Alerts = append(saved.Alerts, *a)
+	}
+	if err := statestore.PutJSON(ctx, e.state, stateKey, saved); err != nil && ctx.Err() == nil {
+		e.logger.Printf("Alert evaluator could not save alerts: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package alert
+
+import (
+	"fmt"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// BaselineSource supplies per-model metric baselines for rules whose
+// threshold is an expression such as model_p99.
+type BaselineSource interface {
+	Baseline(model, metric string) (*models.Baseline, bool)
+	Profiles(metric string) bool
+}
+
+// threshold resolves a rule's threshold for each GPU model.
+type threshold struct {
+	rule      *models.AlertRule
+	expr      models.ThresholdExpr
+	baselines BaselineSource
+}
+
+// newThreshold prepares a rule's threshold. Rules with a threshold
+// expression need baselines.
+func newThreshold(rule *models.AlertRule, baselines BaselineSource) (*threshold, error) {
+	t := &threshold{rule: rule, baselines: baselines}
+	if rule.ThresholdExpr == "" {
+		return t, nil
+	}
+	if baselines == nil {
+		return nil, perrors.Validation(fmt.Errorf("rule %q uses threshold_expr but baselines are not enabled", rule.Name))
+	}
+	if !baselines.Profiles(rule.Metric) {
+		return nil, perrors.Validation(fmt.Errorf("rule %q uses threshold_expr but %s is not a baseline metric", rule.Name, rule.Metric))
+	}
+	expr, err := models.ParseThresholdExpr(rule.ThresholdExpr)
+	if err != nil {
+		return nil, perrors.Validation(err)
+	}
+	t.expr = expr
+	return t, nil
+}
+
+// For returns the threshold for a GPU model. It is false when the rule
+// needs a baseline the model does not have yet.
+func (t *threshold) For(model string) (float64, bool) {
+	if t.expr == nil {
+		return t.rule.Threshold, true
+	}
+	b, ok := t.baselines.Baseline(model, t.rule.Metric)
+	if !ok {
+		return 0, false
+	}
+	return t.expr.Eval(b), true
+}
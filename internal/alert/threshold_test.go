@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// staticBaselines serves fixed baselines keyed by model.
+type staticBaselines map[string]*models.Baseline
+
+func (s staticBaselines) Baseline(model, metric string) (*models.Baseline, bool) {
+	b, ok := s[model]
+	return b, ok && b.Metric == metric
+}
+
+func (s staticBaselines) Profiles(metric string) bool {
+	return metric == "DCGM_FI_DEV_GPU_TEMP"
+}
+
+func TestEvaluatorThresholdExpr(t *testing.T) {
+	rule := tempRule()
+	rule.For, rule.Threshold, rule.ThresholdExpr = "", 0, "model_p99 + 5"
+
+	// Without baselines the rule is rejected
+	te := newTestEvaluator(rule)
+	if err := te.CheckRule(&rule); err == nil {
+		t.Fatal("CheckRule() accepted threshold_expr without baselines")
+	}
+
+	te.SetBaselines(staticBaselines{})
+	if err := te.CheckRule(&rule); err != nil {
+		t.Fatalf("CheckRule() = %v", err)
+	}
+	setTemp(te.latest, 99, start)
+	te.step(time.Minute)
+	if len(te.Alerts()) != 0 {
+		t.Fatalf("alerts for a model without a baseline: %+v", te.Alerts())
+	}
+
+	te.SetBaselines(staticBaselines{"H100": {ModelName: "H100", Metric: rule.Metric, P99: 80}})
+	setTemp(te.latest, 84, start.Add(time.Minute))
+	te.step(time.Minute)
+	if len(te.Alerts()) != 0 {
+		t.Fatalf("alerts below model_p99 + 5: %+v", te.Alerts())
+	}
+	setTemp(te.latest, 86, start.Add(2*time.Minute))
+	te.step(time.Minute)
+	if alerts := te.Alerts(); len(alerts) != 1 || alerts[0].Threshold != 85 {
+		t.Errorf("alerts = %+v, want one with threshold 85", alerts)
+	}
+
+	rule.Metric = "DCGM_FI_DEV_FB_USED"
+	if err := te.CheckRule(&rule); err == nil {
+		t.Error("CheckRule() accepted threshold_expr on a metric without baselines")
+	}
+}
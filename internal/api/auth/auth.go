@@ -0,0 +1,142 @@
+// Package auth authenticates API requests by bearer token, identifies the
+// tenant a request is metered against, and enforces the role a route
+// requires.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Role is a set of permissions granted to a token.
+type Role string
+
+// Roles a token can grant.
+const (
+	// RoleAdmin may call the /api/v1/admin endpoints
+	RoleAdmin Role = "admin"
+
+	// RoleTenant identifies a tenant whose usage is metered and subject to quotas
+	RoleTenant Role = "tenant"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+}
+
+type principalKey struct{}
+
+// FromContext returns the caller authenticated by Identify or Require, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// credential is a configured token, kept as a hash so lookups compare in
+// constant time regardless of token length.
+type credential struct {
+	hash      [sha256.Size]byte
+	principal Principal
+}
+
+// Authenticator maps bearer tokens to principals.
+type Authenticator struct {
+	credentials []credential
+}
+
+// New creates an authenticator. An empty adminToken leaves the admin role
+// without any token, so admin routes refuse every request.
+func New(adminToken string) *Authenticator {
+	a := &Authenticator{}
+	if adminToken != "" {
+		a.credentials = append(a.credentials, credential{
+			hash:      sha256.Sum256([]byte(adminToken)),
+			principal: Principal{Name: "admin", Role: RoleAdmin},
+		})
+	}
+	return a
+}
+
+// AddTenant grants a tenant's token, identifying its requests as the tenant
+// for metering. Tenant tokens never grant the admin role.
+func (a *Authenticator) AddTenant(name, token string) {
+	a.credentials = append(a.credentials, credential{
+		hash:      sha256.Sum256([]byte(token)),
+		principal: Principal{Name: name, Role: RoleTenant},
+	})
+}
+
+// authenticate returns the principal for the request's bearer token.
+func (a *Authenticator) authenticate(r *http.Request) (Principal, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, false
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	var found Principal
+	matched := false
+	for _, c := range a.credentials {
+		if subtle.ConstantTimeCompare(hash[:], c.hash[:]) == 1 {
+			found, matched = c.principal, true
+		}
+	}
+	return found, matched
+}
+
+// hasRole reports whether any configured token grants role.
+func (a *Authenticator) hasRole(role Role) bool {
+	for _, c := range a.credentials {
+		if c.principal.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Require returns middleware that admits only requests whose bearer token
+// grants role, and records the caller in the request context.
+func (a *Authenticator) Require(role Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !a.hasRole(role) {
+				perrors.WriteHTTP(w, http.StatusForbidden, "forbidden", "No token is configured for the "+string(role)+" role")
+				return
+			}
+
+			p, ok := a.authenticate(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gpu-telemetry"`)
+				perrors.WriteHTTP(w, http.StatusUnauthorized, "unauthorized", "A valid bearer token is required")
+				return
+			}
+			if p.Role != role {
+				perrors.WriteHTTP(w, http.StatusForbidden, "forbidden", "Token does not grant the "+string(role)+" role")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+		})
+	}
+}
+
+// Identify returns middleware that records the caller of requests carrying a
+// valid bearer token in the request context. Other requests pass through
+// anonymously, so public routes stay public.
+func (a *Authenticator) Identify() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, ok := a.authenticate(r); ok {
+				r = r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
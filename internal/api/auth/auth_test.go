@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(a *Authenticator, token string) (*httptest.ResponseRecorder, *Principal) {
+	var seen *Principal
+	h := a.Require(RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := FromContext(r.Context()); ok {
+			seen = &p
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/retention", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w, seen
+}
+
+func TestRequireAdmin(t *testing.T) {
+	a := New("s3cret-admin-token")
+
+	w, p := serve(a, "s3cret-admin-token")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 with valid token, got %d", w.Code)
+	}
+	if p == nil || p.Role != RoleAdmin || p.Name != "admin" {
+		t.Errorf("expected admin principal in context, got %+v", p)
+	}
+
+	w, _ = serve(a, "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", w.Code)
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("expected WWW-Authenticate header on 401")
+	}
+
+	w, _ = serve(a, "wrong-token")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %d", w.Code)
+	}
+}
+
+func TestRequireWithoutConfiguredToken(t *testing.T) {
+	w, p := serve(New(""), "anything")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 when no admin token is configured, got %d", w.Code)
+	}
+	if p != nil {
+		t.Error("handler should not run")
+	}
+}
+
+func TestFromContextEmpty(t *testing.T) {
+	if _, ok := FromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); ok {
+		t.Error("expected no principal on an unauthenticated context")
+	}
+}
+
+func TestIdentifyTenant(t *testing.T) {
+	a := New("s3cret-admin-token")
+	a.AddTenant("acme", "acme-token")
+
+	identify := func(token string) *Principal {
+		var seen *Principal
+		h := a.Identify()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, ok := FromContext(r.Context()); ok {
+				seen = &p
+			}
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/gpus", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		return seen
+	}
+
+	if p := identify("acme-token"); p == nil || p.Name != "acme" || p.Role != RoleTenant {
+		t.Errorf("expected tenant acme, got %+v", p)
+	}
+	if p := identify("s3cret-admin-token"); p == nil || p.Role != RoleAdmin {
+		t.Errorf("expected admin, got %+v", p)
+	}
+	if p := identify("wrong-token"); p != nil {
+		t.Errorf("unknown token should pass through anonymously, got %+v", p)
+	}
+	if p := identify(""); p != nil {
+		t.Errorf("no token should pass through anonymously, got %+v", p)
+	}
+
+	// A tenant token does not open admin routes
+	w, _ := serve(a, "acme-token")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for tenant on admin route, got %d", w.Code)
+	}
+}
@@ -0,0 +1,52 @@
+// Package dashboard serves a minimal single-page web dashboard built on the
+// REST API, for environments that don't run Grafana. Its files are embedded
+// in the binary, so it needs no separate deployment.
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// static holds index.html and the assets it loads from AssetPrefix.
+//
+//go:embed static
+var static embed.FS
+
+// AssetPrefix is the path the dashboard's scripts and styles are served under.
+const AssetPrefix = "/ui/"
+
+// files returns the embedded files rooted at the static directory.
+func files() http.FileSystem {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		// The directory is embedded at build time, so this cannot happen
+		panic(err)
+	}
+	return http.FS(sub)
+}
+
+// Index serves the dashboard page.
+func Index() http.Handler {
+	fsys := files()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, err := fsys.Open("index.html")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, "index.html", stat.ModTime(), f)
+	})
+}
+
+// Assets serves the dashboard's scripts and styles under AssetPrefix.
+func Assets() http.Handler {
+	return http.StripPrefix(AssetPrefix, http.FileServer(files()))
+}
@@ -0,0 +1,293 @@
+// Minimal dashboard over the REST API. Everything is read from the same
+// endpoints documented in Swagger, so the dashboard never needs its own backend.
+"use strict";
+
+const METRICS = {
+  util: "DCGM_FI_DEV_GPU_UTIL",
+  temp: "DCGM_FI_DEV_GPU_TEMP",
+  power: "DCGM_FI_DEV_POWER_USAGE",
+  smClock: "DCGM_FI_DEV_SM_CLOCK",
+  memUsed: "DCGM_FI_DEV_FB_USED",
+};
+
+const CHARTS = [
+  { metric: METRICS.util, title: "Utilization (%)" },
+  { metric: METRICS.temp, title: "Temperature (°C)" },
+  { metric: METRICS.power, title: "Power (W)" },
+  { metric: METRICS.smClock, title: "SM clock (MHz)" },
+  { metric: METRICS.memUsed, title: "Framebuffer used (MiB)" },
+];
+
+const REFRESH_MS = 30000;
+
+let snapshot = [];
+let selected = null;
+
+// getJSON fetches an API path, resolving to null on 503 so disabled features
+// (alerts, the latest cache) hide quietly.
+async function getJSON(path) {
+  const resp = await fetch(path, { headers: { Accept: "application/json" } });
+  if (resp.status === 503) {
+    return null;
+  }
+  if (!resp.ok) {
+    throw new Error(`${path}: ${resp.status}`);
+  }
+  return resp.json();
+}
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) {
+    if (k === "class") {
+      node.className = v;
+    } else {
+      node.setAttribute(k, v);
+    }
+  }
+  for (const child of children) {
+    node.append(child instanceof Node ? child : document.createTextNode(String(child)));
+  }
+  return node;
+}
+
+function fmt(v, digits = 0) {
+  return v === undefined || v === null ? "–" : Number(v).toFixed(digits);
+}
+
+function ago(ts) {
+  if (!ts || ts.startsWith("0001")) {
+    return "never";
+  }
+  const s = Math.round((Date.now() - new Date(ts).getTime()) / 1000);
+  if (s < 60) return `${s}s ago`;
+  if (s < 3600) return `${Math.round(s / 60)}m ago`;
+  return `${Math.round(s / 3600)}h ago`;
+}
+
+function metricValue(gpu, name) {
+  const m = gpu.metrics && gpu.metrics[name];
+  return m ? m.value : undefined;
+}
+
+async function loadStatus() {
+  const status = document.getElementById("status");
+  const [ready, stats] = await Promise.all([
+    fetch("/ready").then((r) => r.json().then((body) => ({ ok: r.ok, body }))),
+    getJSON("/api/v1/stats").catch(() => null),
+  ]);
+  const cache = ready.body.cache;
+  const dot = ready.ok ? (cache && cache.last_error ? "warn" : "ok") : "crit";
+  status.replaceChildren(
+    el("span", {}, el("span", { class: `dot ${dot}` }), ready.body.status),
+    el("span", {}, `cache updated ${cache ? ago(cache.last_update) : "–"}`),
+    el("span", {}, `newest metric ${stats ? ago(stats.newest_metric) : "–"}`),
+  );
+  return stats;
+}
+
+function renderTiles(stats) {
+  const utils = snapshot.map((g) => metricValue(g, METRICS.util)).filter((v) => v !== undefined);
+  const powers = snapshot.map((g) => metricValue(g, METRICS.power)).filter((v) => v !== undefined);
+  const temps = snapshot.map((g) => metricValue(g, METRICS.temp)).filter((v) => v !== undefined);
+  const hosts = new Set(snapshot.map((g) => g.hostname));
+  const avg = (xs) => (xs.length ? xs.reduce((a, b) => a + b, 0) / xs.length : undefined);
+
+  const tiles = [
+    ["GPUs", stats ? stats.total_gpus : snapshot.length],
+    ["Hosts", hosts.size],
+    ["Avg utilization", `${fmt(avg(utils))}%`],
+    ["Fleet power", `${fmt(powers.reduce((a, b) => a + b, 0) / 1000, 1)} kW`],
+    ["Max temperature", `${fmt(temps.length ? Math.max(...temps) : undefined)}°C`],
+  ];
+  document.getElementById("tiles").replaceChildren(
+    ...tiles.map(([label, value]) => el("div", { class: "tile" }, el("div", { class: "value" }, value), el("div", { class: "label" }, label))),
+  );
+}
+
+function renderGPUs() {
+  const q = document.getElementById("filter").value.toLowerCase();
+  const rows = snapshot
+    .filter((g) => !q || [g.hostname, g.uuid, g.model_name].some((s) => (s || "").toLowerCase().includes(q)))
+    .sort((a, b) => a.hostname.localeCompare(b.hostname) || a.gpu_id - b.gpu_id)
+    .map((g) => {
+      const tr = el(
+        "tr",
+        {},
+        el("td", {}, g.hostname),
+        el("td", {}, g.gpu_id),
+        el("td", {}, g.model_name),
+        el("td", { class: "num" }, fmt(metricValue(g, METRICS.util))),
+        el("td", { class: "num" }, fmt(metricValue(g, METRICS.temp))),
+        el("td", { class: "num" }, fmt(metricValue(g, METRICS.power))),
+        el("td", {}, ago(g.last_seen)),
+      );
+      tr.addEventListener("click", () => selectGPU(g));
+      return tr;
+    });
+  document.getElementById("gpu-rows").replaceChildren(...rows);
+}
+
+async function loadSnapshot() {
+  const resp = await getJSON("/api/v1/snapshot");
+  snapshot = resp ? resp.data : [];
+}
+
+async function loadAlerts() {
+  const list = document.getElementById("alert-list");
+  const resp = await getJSON("/api/v1/alerts").catch(() => null);
+  if (!resp) {
+    list.replaceChildren(el("div", { class: "note" }, "Alerting is not enabled."));
+    return;
+  }
+  if (!resp.leader) {
+    list.replaceChildren(el("div", { class: "note" }, "This replica is not evaluating alerts."));
+    return;
+  }
+  const alerts = resp.data.filter((a) => a.state !== "resolved");
+  if (!alerts.length) {
+    list.replaceChildren(el("div", { class: "note" }, "No pending or firing alerts."));
+    return;
+  }
+  list.replaceChildren(
+    ...alerts.map((a) =>
+      el(
+        "div",
+        { class: `alert ${a.severity}` },
+        el("strong", {}, `${a.rule_name} `),
+        `${a.hostname} GPU ${a.gpu_id}: ${fmt(a.value, 1)} ${a.op} ${fmt(a.threshold, 1)}`,
+        el("div", { class: "meta" }, `${a.state}${a.suppressed_by ? " (maintenance)" : ""} since ${ago(a.fired_at || a.active_at)}`),
+      ),
+    ),
+  );
+}
+
+// heatColor maps 0..1 to a light-to-dark blue.
+function heatColor(t) {
+  const l = 95 - 60 * Math.min(Math.max(t, 0), 1);
+  return `hsl(217, 85%, ${l}%)`;
+}
+
+async function loadHeatmap() {
+  const canvas = document.getElementById("heatmap");
+  const note = document.getElementById("heatmap-note");
+  const resp = await getJSON(`/api/v1/heatmap?metric=${METRICS.util}&window=6h&columns=72&limit=200`).catch((err) => {
+    note.textContent = `Heatmap unavailable: ${err.message}`;
+    return null;
+  });
+  if (!resp || !resp.rows.length) {
+    canvas.height = 0;
+    return;
+  }
+  const rowHeight = 6;
+  const width = canvas.clientWidth || 800;
+  canvas.width = width;
+  canvas.height = resp.rows.length * rowHeight;
+  const ctx = canvas.getContext("2d");
+  const cell = width / resp.columns.length;
+  resp.values.forEach((row, i) => {
+    row.forEach((v, j) => {
+      ctx.fillStyle = v === null ? "#eceef1" : heatColor(v / 100);
+      ctx.fillRect(j * cell, i * rowHeight, Math.ceil(cell), rowHeight - 1);
+    });
+  });
+  note.textContent = `${resp.rows.length} of ${resp.total_rows} GPUs, ${resp.step} buckets; darker is busier.`;
+}
+
+// chart draws a series as an SVG line with min and max labels.
+function chart(title, points) {
+  const box = el("div", { class: "chart" }, el("h3", {}, title));
+  if (!points.length) {
+    box.append(el("div", { class: "note" }, "No data in this window."));
+    return box;
+  }
+  const W = 400;
+  const H = 160;
+  const pad = 28;
+  const xs = points.map((p) => new Date(p.time).getTime());
+  const ys = points.map((p) => p.value);
+  const x0 = Math.min(...xs);
+  const x1 = Math.max(...xs);
+  let y0 = Math.min(...ys);
+  let y1 = Math.max(...ys);
+  if (y0 === y1) {
+    y0 -= 1;
+    y1 += 1;
+  }
+  const sx = (x) => pad + ((x - x0) / (x1 - x0 || 1)) * (W - pad - 4);
+  const sy = (y) => H - 16 - ((y - y0) / (y1 - y0)) * (H - 24);
+  const ns = "http://www.w3.org/2000/svg";
+  const svg = document.createElementNS(ns, "svg");
+  svg.setAttribute("viewBox", `0 0 ${W} ${H}`);
+  svg.setAttribute("preserveAspectRatio", "none");
+  const add = (tag, attrs, text) => {
+    const node = document.createElementNS(ns, tag);
+    for (const [k, v] of Object.entries(attrs)) node.setAttribute(k, v);
+    if (text !== undefined) node.textContent = text;
+    svg.append(node);
+  };
+  add("line", { class: "grid", x1: pad, x2: W, y1: sy(y1), y2: sy(y1) });
+  add("line", { class: "grid", x1: pad, x2: W, y1: sy(y0), y2: sy(y0) });
+  add("text", { class: "axis", x: 0, y: sy(y1) + 3 }, fmt(y1));
+  add("text", { class: "axis", x: 0, y: sy(y0) + 3 }, fmt(y0));
+  add("text", { class: "axis", x: pad, y: H - 2 }, new Date(x0).toLocaleTimeString());
+  add("text", { class: "axis", x: W - 60, y: H - 2 }, new Date(x1).toLocaleTimeString());
+  add("polyline", { points: points.map((p, i) => `${sx(xs[i])},${sy(p.value)}`).join(" ") });
+  box.append(svg);
+  return box;
+}
+
+// loadDetail charts the selected GPU from the heatmap endpoint scoped to its
+// host, which aggregates server-side instead of shipping raw points.
+async function loadDetail() {
+  if (!selected) {
+    return;
+  }
+  const gpu = selected;
+  const range = document.getElementById("window").value;
+  const charts = await Promise.all(
+    CHARTS.map(async ({ metric, title }) => {
+      const params = new URLSearchParams({ metric, hostname: gpu.hostname, window: range, columns: "120", limit: "1000" });
+      const resp = await getJSON(`/api/v1/heatmap?${params}`).catch(() => null);
+      const i = resp ? resp.rows.findIndex((r) => r.uuid === gpu.uuid) : -1;
+      const points = i < 0 ? [] : resp.values[i].map((v, j) => ({ time: resp.columns[j], value: v })).filter((p) => p.value !== null);
+      return chart(title, points);
+    }),
+  );
+  if (selected === gpu) {
+    document.getElementById("charts").replaceChildren(...charts);
+  }
+}
+
+function selectGPU(gpu) {
+  selected = gpu;
+  document.getElementById("detail").hidden = false;
+  document.getElementById("detail-title").textContent = `${gpu.hostname} GPU ${gpu.gpu_id} · ${gpu.model_name} · ${gpu.uuid}`;
+  document.getElementById("charts").replaceChildren(el("div", { class: "note" }, "Loading…"));
+  loadDetail();
+  document.getElementById("detail").scrollIntoView({ behavior: "smooth" });
+}
+
+async function refresh() {
+  try {
+    await loadSnapshot();
+    const stats = await loadStatus();
+    renderTiles(stats);
+    renderGPUs();
+  } catch (err) {
+    document.getElementById("status").replaceChildren(el("span", {}, el("span", { class: "dot crit" }), err.message));
+  }
+  loadAlerts();
+  loadHeatmap();
+  loadDetail();
+}
+
+document.getElementById("filter").addEventListener("input", renderGPUs);
+document.getElementById("window").addEventListener("change", loadDetail);
+document.getElementById("close-detail").addEventListener("click", () => {
+  selected = null;
+  document.getElementById("detail").hidden = true;
+});
+
+refresh();
+setInterval(refresh, REFRESH_MS);
@@ -0,0 +1,163 @@
+// Package environment lets one API serve several named data sets, such as
+// dev, stage and prod, each read from its own storage. A request selects
+// one with the X-Environment header or an /env/<name> path prefix, and an
+// environment may be restricted to some tenants.
+package environment
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+const (
+	// Header selects the environment of a request; it is also set on responses
+	Header = "X-Environment"
+
+	// PathPrefix selects the environment of a request by path, as in
+	// /env/prod/api/v1/gpus. It takes precedence over Header.
+	PathPrefix = "/env/"
+)
+
+// Environment is a named data set.
+type Environment struct {
+	Name  string
+	Store storage.ReadStorage
+
+	// Tenants lists the tenants allowed to read the environment; admins
+	// always may. Empty allows every caller
+	Tenants []string
+}
+
+// allows reports whether the caller of ctx may read the environment.
+func (e *Environment) allows(ctx context.Context) (allowed, authenticated bool) {
+	if len(e.Tenants) == 0 {
+		return true, true
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return false, false
+	}
+	return p.Role == auth.RoleAdmin || slices.Contains(e.Tenants, p.Name), true
+}
+
+type (
+	environmentKey struct{}
+	pathKey        struct{}
+)
+
+// FromContext returns the environment Select chose for a request.
+func FromContext(ctx context.Context) (*Environment, bool) {
+	e, ok := ctx.Value(environmentKey{}).(*Environment)
+	return e, ok
+}
+
+// Set holds the environments an API serves, one of them the default.
+type Set struct {
+	def  *Environment
+	envs map[string]*Environment
+}
+
+// NewSet creates a set whose default environment, selected when a request
+// names none, is read from store by anyone.
+func NewSet(defaultName string, store storage.ReadStorage) *Set {
+	def := &Environment{Name: defaultName, Store: store}
+	return &Set{def: def, envs: map[string]*Environment{defaultName: def}}
+}
+
+// Add adds an environment, replacing any of the same name.
+func (s *Set) Add(e *Environment) {
+	s.envs[e.Name] = e
+}
+
+// Default returns the default environment.
+func (s *Set) Default() *Environment {
+	return s.def
+}
+
+// List returns the environments the caller of ctx may read, by name.
+func (s *Set) List(ctx context.Context) []*Environment {
+	var list []*Environment
+	for _, e := range s.envs {
+		if ok, _ := e.allows(ctx); ok {
+			list = append(list, e)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// StripPrefix returns a handler serving /env/<name>/... requests with next
+// as if they were sent to the rest of the path, remembering the name for
+// Select.
+func (s *Set) StripPrefix(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		r = r.Clone(context.WithValue(r.Context(), pathKey{}, name))
+		r.URL.Path = "/" + rest
+		r.URL.RawPath = ""
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Select returns middleware that chooses the environment named by the path
+// prefix or Header, or the default, and records it in the request context.
+// Unknown environments are refused with 404 and restricted ones with 401
+// or 403. The listed paths, and the paths below them, are served by the
+// default environment only, for features that keep their state in this
+// process rather than in storage.
+func (s *Set) Select(defaultOnly ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, _ := r.Context().Value(pathKey{}).(string)
+			if name == "" {
+				name = r.Header.Get(Header)
+			}
+			e := s.def
+			if name != "" {
+				if e = s.envs[name]; e == nil {
+					perrors.WriteHTTP(w, http.StatusNotFound, "environment_not_found", "Environment "+name+" is not configured")
+					return
+				}
+			}
+
+			allowed, authenticated := e.allows(r.Context())
+			if !authenticated {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="gpu-telemetry"`)
+				perrors.WriteHTTP(w, http.StatusUnauthorized, "unauthorized", "Environment "+e.Name+" requires a valid bearer token")
+				return
+			}
+			if !allowed {
+				perrors.WriteHTTP(w, http.StatusForbidden, "forbidden", "Token does not grant access to environment "+e.Name)
+				return
+			}
+			if e != s.def && underAny(r.URL.Path, defaultOnly) {
+				perrors.WriteHTTP(w, http.StatusBadRequest, "environment_unsupported", "This endpoint serves the "+s.def.Name+" environment only")
+				return
+			}
+
+			w.Header().Set(Header, e.Name)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), environmentKey{}, e)))
+		})
+	}
+}
+
+// underAny reports whether path is one of paths or below one.
+func underAny(path string, paths []string) bool {
+	for _, p := range paths {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package environment
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+)
+
+func newSet() (*Set, *auth.Authenticator) {
+	a := auth.New("s3cret-admin-token")
+	a.AddTenant("acme", "acme-token")
+	a.AddTenant("globex", "globex-token")
+
+	s := NewSet("prod", nil)
+	s.Add(&Environment{Name: "dev"})
+	s.Add(&Environment{Name: "stage", Tenants: []string{"acme"}})
+	return s, a
+}
+
+// serve sends a request through Identify and Select, returning the
+// environment the handler saw and the path it was served at.
+func serve(s *Set, a *auth.Authenticator, path, header, token string) (w *httptest.ResponseRecorder, env, served string) {
+	h := a.Identify()(s.Select("/api/v1/snapshot")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e, ok := FromContext(r.Context()); ok {
+			env = e.Name
+		}
+		served = r.URL.Path
+	})))
+	mux := http.NewServeMux()
+	mux.Handle("/api/", h)
+	mux.Handle(PathPrefix, s.StripPrefix(mux))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if header != "" {
+		req.Header.Set(Header, header)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	return w, env, served
+}
+
+func TestSelectEnvironment(t *testing.T) {
+	s, a := newSet()
+
+	if _, env, _ := serve(s, a, "/api/v1/gpus", "", ""); env != "prod" {
+		t.Errorf("expected the default environment, got %q", env)
+	}
+	w, env, _ := serve(s, a, "/api/v1/gpus", "dev", "")
+	if env != "dev" || w.Header().Get(Header) != "dev" {
+		t.Errorf("expected dev selected by header, got %q (%q)", env, w.Header().Get(Header))
+	}
+	if _, env, served := serve(s, a, "/env/dev/api/v1/gpus", "prod", ""); env != "dev" || served != "/api/v1/gpus" {
+		t.Errorf("expected dev selected by path at /api/v1/gpus, got %q at %q", env, served)
+	}
+	if w, _, _ := serve(s, a, "/api/v1/gpus", "qa", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown environment, got %d", w.Code)
+	}
+}
+
+func TestSelectRestrictedEnvironment(t *testing.T) {
+	s, a := newSet()
+
+	if w, _, _ := serve(s, a, "/api/v1/gpus", "stage", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", w.Code)
+	}
+	if w, _, _ := serve(s, a, "/api/v1/gpus", "stage", "globex-token"); w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for another tenant, got %d", w.Code)
+	}
+	for _, token := range []string{"acme-token", "s3cret-admin-token"} {
+		if _, env, _ := serve(s, a, "/api/v1/gpus", "stage", token); env != "stage" {
+			t.Errorf("expected stage served with %s, got %q", token, env)
+		}
+	}
+}
+
+func TestSelectDefaultOnly(t *testing.T) {
+	s, a := newSet()
+
+	if w, _, _ := serve(s, a, "/api/v1/snapshot", "dev", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a default-only endpoint, got %d", w.Code)
+	}
+	if _, env, _ := serve(s, a, "/api/v1/snapshot", "prod", ""); env != "prod" {
+		t.Errorf("expected the default environment served, got %q", env)
+	}
+}
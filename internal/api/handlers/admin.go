@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// minRetention is the shortest retention InfluxDB accepts (0 keeps data forever).
+const minRetention = time.Hour
+
+// defaultCleanupHistoryLimit is how many history entries are returned by default.
+const defaultCleanupHistoryLimit = 50
+
+// RetentionRequest is the body for changing the retention period.
+type RetentionRequest struct {
+	// Retention is a Go duration; "0s" keeps data forever
+	Retention string `json:"retention" example:"168h"`
+}
+
+// RetentionResponse describes the current retention period.
+type RetentionResponse struct {
+	Retention        string `json:"retention" example:"168h0m0s"`
+	RetentionSeconds int64  `json:"retention_seconds" example:"604800"`
+	Forever          bool   `json:"forever" example:"false"`
+}
+
+// CleanupHistoryResponse represents the admin cleanup history.
+type CleanupHistoryResponse struct {
+	Data  []*models.CleanupRecord `json:"data"`
+	Count int                     `json:"count" example:"4"`
+}
+
+// dataAdmin returns the backend's DataAdmin, writing a 501 if it has none.
+func (h *Handler) dataAdmin(w http.ResponseWriter, r *http.Request) (storage.DataAdmin, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.DataAdmin)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support admin cleanup")
+	}
+	return store, ok
+}
+
+// newCleanupRecord starts a history entry for the authenticated caller.
+func newCleanupRecord(r *http.Request, kind string) *models.CleanupRecord {
+	record := &models.CleanupRecord{
+		ID:          uuid.New().String(),
+		Kind:        kind,
+		RequestedAt: time.Now().UTC(),
+		Status:      models.CleanupSucceeded,
+	}
+	if p, ok := auth.FromContext(r.Context()); ok {
+		record.RequestedBy = p.Name
+	}
+	return record
+}
+
+// finishCleanupRecord completes a history entry with the outcome and stores it.
+// Failing to store history does not fail the operation that already happened.
+func finishCleanupRecord(r *http.Request, store storage.DataAdmin, record *models.CleanupRecord, err error) {
+	record.FinishedAt = time.Now().UTC()
+	if err != nil {
+		record.Status = models.CleanupFailed
+		record.Error = err.Error()
+	}
+	store.RecordCleanup(r.Context(), record)
+}
+
+// RunCleanup godoc
+// @Summary      Delete telemetry on demand
+// @Description  Deletes telemetry in [start, end), optionally only for the listed GPU UUIDs, and records the operation in the cleanup history. Data under retention holds is kept; the record lists the holds that applied. Requires the admin role.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  models.CleanupRequest  true  "Range and GPUs to delete"
+// @Success      200  {object}  models.CleanupRecord
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/cleanup [post]
+func (h *Handler) RunCleanup(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.dataAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CleanupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	record := newCleanupRecord(r, models.CleanupDelete)
+	record.Start, record.End, record.UUIDs = &req.Start, &req.End, req.UUIDs
+	if holds, ok := h.storeFor(r.Context()).(storage.HoldStore); ok {
+		active, err := holds.ListHolds(r.Context())
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		record.Holds = models.HoldIDs(active, req.Start, req.End)
+	}
+
+	metrics, err := store.DeleteTelemetry(r.Context(), &req)
+	record.Metrics = metrics
+	finishCleanupRecord(r, store, record, err)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+// ListCleanupHistory godoc
+// @Summary      List cleanup history
+// @Description  Returns recent on-demand deletions and retention changes, newest first. Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        limit  query  int  false  "Maximum number of entries (default 50)"
+// @Success      200  {object}  CleanupHistoryResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/cleanup/history [get]
+func (h *Handler) ListCleanupHistory(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.dataAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultCleanupHistoryLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid limit parameter")
+			return
+		}
+		limit = min(l, h.maxLimit)
+	}
+
+	records, err := store.ListCleanups(r.Context(), limit)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CleanupHistoryResponse{
+		Data:  records,
+		Count: len(records),
+	})
+}
+
+// GetRetention godoc
+// @Summary      Get the retention period
+// @Description  Returns how long telemetry is kept before it expires. Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  RetentionResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/retention [get]
+func (h *Handler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.dataAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	retention, err := store.GetRetention(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, retentionResponse(retention))
+}
+
+// SetRetention godoc
+// @Summary      Change the retention period
+// @Description  Changes how long telemetry is kept, effective immediately, and records the change in the cleanup history. "0s" keeps data forever; otherwise the minimum is 1h. Bucket retention cannot spare held data, so a finite retention is refused while retention holds exist. Requires the admin role.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  RetentionRequest  true  "New retention"
+// @Success      200  {object}  RetentionResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/retention [put]
+func (h *Handler) SetRetention(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.dataAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req RetentionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	retention, err := time.ParseDuration(req.Retention)
+	if err != nil || (retention != 0 && retention < minRetention) {
+		writeError(w, http.StatusBadRequest, "bad_request", "retention must be 0s (forever) or a duration of at least 1h")
+		return
+	}
+	if holds, ok := h.storeFor(r.Context()).(storage.HoldStore); ok && retention != 0 {
+		active, err := holds.ListHolds(r.Context())
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if len(active) > 0 {
+			writeError(w, http.StatusConflict, "conflict", fmt.Sprintf("%d retention hold(s) exist and bucket retention would expire held data; release them or let the collector expire telemetry", len(active)))
+			return
+		}
+	}
+
+	previous, err := store.GetRetention(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	record := newCleanupRecord(r, models.CleanupRetention)
+	record.Retention = retention.String()
+	record.PreviousRetention = previous.String()
+
+	err = store.SetRetention(r.Context(), retention)
+	finishCleanupRecord(r, store, record, err)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, retentionResponse(retention))
+}
+
+// Reingest godoc
+// @Summary      Re-ingest stored batches
+// @Description  Re-fetches the original payload of each selected batch from the MQ log at its recorded offset and republishes it, so collectors store it again after a bug fix. Select batches by batch_ids or by the time range in which they were received. Batches whose lineage or payload is gone are reported as skipped. Requires the admin role.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  models.ReplayRequest  true  "Batches to re-ingest"
+// @Success      200  {object}  models.ReplayResult
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/reingest [post]
+func (h *Handler) Reingest(w http.ResponseWriter, r *http.Request) {
+	if h.replayer == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Re-ingestion is not configured")
+		return
+	}
+
+	var req models.ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	result, err := h.replayer.Replay(r.Context(), &req)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func retentionResponse(retention time.Duration) RetentionResponse {
+	return RetentionResponse{
+		Retention:        retention.String(),
+		RetentionSeconds: int64(retention / time.Second),
+		Forever:          retention == 0,
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/replay"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// adminStorage adds an in-memory storage.DataAdmin to mockStorage.
+type adminStorage struct {
+	*mockStorage
+	mu        sync.Mutex
+	retention time.Duration
+	deleted   []*models.CleanupRequest
+	history   []*models.CleanupRecord
+	deleteErr error
+}
+
+func newAdminStorage() *adminStorage {
+	return &adminStorage{mockStorage: newMockStorage(), retention: 168 * time.Hour}
+}
+
+func (s *adminStorage) DeleteTelemetry(ctx context.Context, req *models.CleanupRequest) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deleteErr != nil {
+		return nil, s.deleteErr
+	}
+	s.deleted = append(s.deleted, req)
+	return []string{"DCGM_FI_DEV_GPU_UTIL"}, nil
+}
+
+func (s *adminStorage) GetRetention(ctx context.Context) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.retention, nil
+}
+
+func (s *adminStorage) SetRetention(ctx context.Context, retention time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = retention
+	return nil
+}
+
+func (s *adminStorage) RecordCleanup(ctx context.Context, record *models.CleanupRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append([]*models.CleanupRecord{record}, s.history...)
+	return nil
+}
+
+func (s *adminStorage) ListCleanups(ctx context.Context, limit int) ([]*models.CleanupRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.history[:min(limit, len(s.history))], nil
+}
+
+func setupAdminRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.HandleFunc("/cleanup", h.RunCleanup).Methods(http.MethodPost)
+	admin.HandleFunc("/cleanup/history", h.ListCleanupHistory).Methods(http.MethodGet)
+	admin.HandleFunc("/retention", h.GetRetention).Methods(http.MethodGet)
+	admin.HandleFunc("/retention", h.SetRetention).Methods(http.MethodPut)
+	admin.HandleFunc("/reingest", h.Reingest).Methods(http.MethodPost)
+	return router
+}
+
+func TestRunCleanup(t *testing.T) {
+	store := newAdminStorage()
+	router := setupAdminRouter(NewHandler(store, 100, 1000))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/admin/cleanup", models.CleanupRequest{
+		Start: start, End: start.Add(time.Hour), UUIDs: []string{"GPU-1"},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var record models.CleanupRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &record))
+	assert.Equal(t, models.CleanupDelete, record.Kind)
+	assert.Equal(t, models.CleanupSucceeded, record.Status)
+	assert.Equal(t, []string{"GPU-1"}, record.UUIDs)
+	assert.Equal(t, []string{"DCGM_FI_DEV_GPU_UTIL"}, record.Metrics)
+	require.Len(t, store.deleted, 1)
+	require.Len(t, store.history, 1)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/cleanup/history", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var history CleanupHistoryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &history))
+	assert.Equal(t, 1, history.Count)
+	assert.Equal(t, record.ID, history.Data[0].ID)
+}
+
+func TestRunCleanupValidation(t *testing.T) {
+	store := newAdminStorage()
+	router := setupAdminRouter(NewHandler(store, 100, 1000))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		body models.CleanupRequest
+	}{
+		{"missing range", models.CleanupRequest{}},
+		{"end before start", models.CleanupRequest{Start: start, End: start.Add(-time.Hour)}},
+		{"quoted uuid", models.CleanupRequest{Start: start, End: start.Add(time.Hour), UUIDs: []string{`GPU" or true`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, router, http.MethodPost, "/api/v1/admin/cleanup", tt.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+	assert.Empty(t, store.deleted)
+	assert.Empty(t, store.history)
+}
+
+func TestRunCleanupFailureIsRecorded(t *testing.T) {
+	store := newAdminStorage()
+	store.deleteErr = errors.New("influx unavailable")
+	router := setupAdminRouter(NewHandler(store, 100, 1000))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/admin/cleanup", models.CleanupRequest{Start: start, End: start.Add(time.Hour)})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Len(t, store.history, 1)
+	assert.Equal(t, models.CleanupFailed, store.history[0].Status)
+	assert.Contains(t, store.history[0].Error, "influx unavailable")
+}
+
+func TestRetention(t *testing.T) {
+	store := newAdminStorage()
+	router := setupAdminRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/admin/retention", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp RetentionResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(168*3600), resp.RetentionSeconds)
+
+	w = doJSON(t, router, http.MethodPut, "/api/v1/admin/retention", RetentionRequest{Retention: "72h"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 72*time.Hour, store.retention)
+	require.Len(t, store.history, 1)
+	assert.Equal(t, models.CleanupRetention, store.history[0].Kind)
+	assert.Equal(t, "168h0m0s", store.history[0].PreviousRetention)
+	assert.Equal(t, "72h0m0s", store.history[0].Retention)
+
+	w = doJSON(t, router, http.MethodPut, "/api/v1/admin/retention", RetentionRequest{Retention: "0s"})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Forever)
+
+	for _, bad := range []string{"30m", "-1h", "soon"} {
+		w = doJSON(t, router, http.MethodPut, "/api/v1/admin/retention", RetentionRequest{Retention: bad})
+		assert.Equal(t, http.StatusBadRequest, w.Code, bad)
+	}
+	assert.Equal(t, time.Duration(0), store.retention)
+}
+
+func TestAdminNotImplemented(t *testing.T) {
+	router := setupAdminRouter(NewHandler(newMockStorage(), 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/admin/retention", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/cleanup/history", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+// replayLog is a one-message replay.Log that records republished payloads.
+type replayLog struct {
+	message   *mq.Message
+	published int
+}
+
+func (l *replayLog) Fetch(ctx context.Context, offset mq.Offset) (*mq.Message, error) {
+	return l.message, nil
+}
+
+func (l *replayLog) PublishWithMetadata(ctx context.Context, payload []byte, metadata map[string]string) error {
+	l.published++
+	return nil
+}
+
+func TestReingest(t *testing.T) {
+	store := &lineageStorage{mockStorage: newMockStorage(), batches: map[string]*models.BatchLineage{
+		"batch-1": {BatchID: "batch-1", MQOffset: 0},
+	}}
+	mqLog := &replayLog{message: &mq.Message{Payload: []byte(`{"batch_id":"batch-1","metrics":[{},{}]}`)}}
+	h := NewHandler(store, 100, 1000)
+	router := setupAdminRouter(h)
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/admin/reingest", models.ReplayRequest{BatchIDs: []string{"batch-1"}})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	h.SetReplayer(replay.New(store, mqLog, 100, log.New(io.Discard, "", 0)))
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/reingest", models.ReplayRequest{BatchIDs: []string{"batch-1"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	var result models.ReplayResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, []string{"batch-1"}, result.Replayed)
+	assert.Equal(t, 2, result.Metrics)
+	assert.Equal(t, 1, mqLog.published)
+
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/reingest", models.ReplayRequest{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
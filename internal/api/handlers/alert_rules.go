@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// maxRuleTestPoints caps the samples an alert rule test replays.
+const maxRuleTestPoints = 100000
+
+// AlertRuleRequest is the body for creating or replacing an alert rule.
+type AlertRuleRequest struct {
+	Name      string   `json:"name" example:"gpu-hot"`
+	Metric    string   `json:"metric" example:"DCGM_FI_DEV_GPU_TEMP"`
+	Op        string   `json:"op" example:">"`
+	Threshold float64  `json:"threshold" example:"85"`
+	For       string   `json:"for,omitempty" example:"5m"`
+	Severity  string   `json:"severity" example:"critical"`
+	Hostname  string   `json:"hostname,omitempty" example:"dgx-*"`
+	UUID      string   `json:"uuid,omitempty"`
+	Summary   string   `json:"summary,omitempty" example:"GPU at {{.Value}}C"`
+	Notifiers []string `json:"notifiers,omitempty"`
+	Enabled   *bool    `json:"enabled,omitempty"`
+
+	// ThresholdExpr replaces threshold with a per-model baseline expression
+	ThresholdExpr string `json:"threshold_expr,omitempty" example:"model_p99 + 2"`
+}
+
+// AlertRuleListResponse represents the response for listing alert rules.
+type AlertRuleListResponse struct {
+	Data  []*models.AlertRule `json:"data"`
+	Count int                 `json:"count" example:"4"`
+}
+
+// AlertRuleTestRequest is the body for test-evaluating a rule against
+// stored telemetry. Set rule_id to test an existing rule, or rule to test
+// one before saving it. The window defaults to the last 24 hours.
+type AlertRuleTestRequest struct {
+	RuleID string            `json:"rule_id,omitempty"`
+	Rule   *AlertRuleRequest `json:"rule,omitempty"`
+	Start  *time.Time        `json:"start,omitempty"`
+	End    *time.Time        `json:"end,omitempty"`
+}
+
+// AlertRuleTestResponse lists the alerts a rule would have fired. Alerts
+// still firing at the end of the window have no resolved_at.
+type AlertRuleTestResponse struct {
+	Rule    *models.AlertRule `json:"rule"`
+	Start   time.Time         `json:"start"`
+	End     time.Time         `json:"end"`
+	Samples int               `json:"samples" example:"8640"`
+	Data    []models.Alert    `json:"data"`
+	Count   int               `json:"count" example:"2"`
+
+	// Truncated is true when the window held more samples than are replayed;
+	// only the most recent are used
+	Truncated bool `json:"truncated"`
+}
+
+// SetAlertRules sets the rule set whose file rules are listed alongside the
+// stored ones.
+func (h *Handler) SetAlertRules(rules *alert.RuleSet) {
+	h.alertRules = rules
+}
+
+// alertRuleStore returns the backend's AlertRuleStore, writing a 501 if it has none.
+func (h *Handler) alertRuleStore(w http.ResponseWriter, r *http.Request) (storage.AlertRuleStore, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.AlertRuleStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support alert rules")
+	}
+	return store, ok
+}
+
+// fileRule returns the rules file's rule with the given ID.
+func (h *Handler) fileRule(id string) (models.AlertRule, bool) {
+	if h.alertRules == nil {
+		return models.AlertRule{}, false
+	}
+	return h.alertRules.FileRule(id)
+}
+
+// rejectFileRule writes a 409 if id names a rule from the rules file, which
+// the API cannot change.
+func (h *Handler) rejectFileRule(w http.ResponseWriter, id string) bool {
+	if _, ok := h.fileRule(id); ok {
+		writeError(w, http.StatusConflict, "conflict", "Alert rule "+id+" is defined in the rules file; edit the file to change it")
+		return true
+	}
+	return false
+}
+
+// toAlertRule converts a request to a rule and validates it. Enabled
+// defaults to true. Rules may only name configured notifiers while alerting
+// is enabled.
+func (h *Handler) toAlertRule(w http.ResponseWriter, req *AlertRuleRequest) (*models.AlertRule, bool) {
+	rule := &models.AlertRule{
+		Name:          req.Name,
+		Metric:        req.Metric,
+		Op:            req.Op,
+		Threshold:     req.Threshold,
+		ThresholdExpr: req.ThresholdExpr,
+		For:           req.For,
+		Severity:      req.Severity,
+		Hostname:      req.Hostname,
+		UUID:          req.UUID,
+		Summary:       req.Summary,
+		Notifiers:     req.Notifiers,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+		Source:        models.RuleSourceAPI,
+	}
+	validate := alert.ValidateRule
+	if h.alerts != nil {
+		validate = h.alerts.CheckRule
+	}
+	if err := validate(rule); err != nil {
+		writeBadRequest(w, err)
+		return nil, false
+	}
+	return rule, true
+}
+
+// decodeAlertRule reads and validates an AlertRuleRequest into an AlertRule.
+func (h *Handler) decodeAlertRule(w http.ResponseWriter, r *http.Request) (*models.AlertRule, bool) {
+	var req AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return nil, false
+	}
+	return h.toAlertRule(w, &req)
+}
+
+// reloadAlerts has the evaluator pick up rule changes now rather than at the
+// next interval.
+func (h *Handler) reloadAlerts() {
+	if h.alerts != nil {
+		h.alerts.Reload()
+	}
+}
+
+// CreateAlertRule godoc
+// @Summary      Create an alert rule
+// @Description  Stores a threshold rule that the evaluator picks up without a restart. The rule fires for every GPU whose latest value of metric crosses threshold for the for duration. Requires the admin role.
+// @Tags         alerts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        rule  body  AlertRuleRequest  true  "Alert rule"
+// @Success      201  {object}  models.AlertRule
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules [post]
+func (h *Handler) CreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.alertRuleStore(w, r)
+	if !ok {
+		return
+	}
+	rule, ok := h.decodeAlertRule(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	if err := store.CreateAlertRule(r.Context(), rule); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	h.reloadAlerts()
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// ListAlertRules godoc
+// @Summary      List alert rules
+// @Description  Returns the rules from the rules file (source "file", read-only) followed by the rules managed through the API (source "api"), ordered by name
+// @Tags         alerts
+// @Produce      json
+// @Success      200  {object}  AlertRuleListResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules [get]
+func (h *Handler) ListAlertRules(w http.ResponseWriter, r *http.Request) {
+	rules := make([]*models.AlertRule, 0)
+	if h.alertRules != nil {
+		for _, rule := range h.alertRules.FileRules() {
+			rules = append(rules, &rule)
+		}
+	}
+	if store, ok := h.storeFor(r.Context()).(storage.AlertRuleStore); ok {
+		stored, err := store.ListAlertRules(r.Context())
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		rules = append(rules, stored...)
+	}
+	writeJSON(w, http.StatusOK, AlertRuleListResponse{
+		Data:  rules,
+		Count: len(rules),
+	})
+}
+
+// getAlertRule returns a rule from the rules file or the store.
+func (h *Handler) getAlertRule(w http.ResponseWriter, r *http.Request, id string) (*models.AlertRule, bool) {
+	if rule, ok := h.fileRule(id); ok {
+		return &rule, true
+	}
+	store, ok := h.alertRuleStore(w, r)
+	if !ok {
+		return nil, false
+	}
+	rule, err := store.GetAlertRule(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err)
+		return nil, false
+	}
+	return rule, true
+}
+
+// GetAlertRule godoc
+// @Summary      Get an alert rule
+// @Tags         alerts
+// @Produce      json
+// @Param        id   path  string  true  "Alert rule ID"
+// @Success      200  {object}  models.AlertRule
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules/{id} [get]
+func (h *Handler) GetAlertRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.getAlertRule(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// UpdateAlertRule godoc
+// @Summary      Replace an alert rule
+// @Description  Replaces a rule managed through the API; the evaluator uses the new definition from its next evaluation. Alerts already pending or firing for the rule carry on under it. Requires the admin role.
+// @Tags         alerts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path  string            true  "Alert rule ID"
+// @Param        rule  body  AlertRuleRequest  true  "Alert rule"
+// @Success      200  {object}  models.AlertRule
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules/{id} [put]
+func (h *Handler) UpdateAlertRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if h.rejectFileRule(w, id) {
+		return
+	}
+	store, ok := h.alertRuleStore(w, r)
+	if !ok {
+		return
+	}
+	rule, ok := h.decodeAlertRule(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := store.GetAlertRule(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	rule.ID = existing.ID
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now().UTC()
+
+	if err := store.UpdateAlertRule(r.Context(), rule); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	h.reloadAlerts()
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// DeleteAlertRule godoc
+// @Summary      Delete an alert rule
+// @Description  Removes a rule managed through the API; its firing alerts resolve at the next evaluation. Requires the admin role.
+// @Tags         alerts
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Alert rule ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules/{id} [delete]
+func (h *Handler) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if h.rejectFileRule(w, id) {
+		return
+	}
+	store, ok := h.alertRuleStore(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.DeleteAlertRule(r.Context(), id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	h.reloadAlerts()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EnableAlertRule godoc
+// @Summary      Enable an alert rule
+// @Description  Requires the admin role.
+// @Tags         alerts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Alert rule ID"
+// @Success      200  {object}  models.AlertRule
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules/{id}/enable [post]
+func (h *Handler) EnableAlertRule(w http.ResponseWriter, r *http.Request) {
+	h.setAlertRuleEnabled(w, r, true)
+}
+
+// DisableAlertRule godoc
+// @Summary      Disable an alert rule
+// @Description  Pauses evaluation of a rule; its firing alerts resolve at the next evaluation. Requires the admin role.
+// @Tags         alerts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Alert rule ID"
+// @Success      200  {object}  models.AlertRule
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules/{id}/disable [post]
+func (h *Handler) DisableAlertRule(w http.ResponseWriter, r *http.Request) {
+	h.setAlertRuleEnabled(w, r, false)
+}
+
+// setAlertRuleEnabled enables or disables a stored rule.
+func (h *Handler) setAlertRuleEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	id := mux.Vars(r)["id"]
+	if h.rejectFileRule(w, id) {
+		return
+	}
+	store, ok := h.alertRuleStore(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := store.GetAlertRule(r.Context(), id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if rule.Enabled != enabled {
+		rule.Enabled = enabled
+		rule.UpdatedAt = time.Now().UTC()
+		if err := store.UpdateAlertRule(r.Context(), rule); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		h.reloadAlerts()
+	}
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// TestAlertRule godoc
+// @Summary      Test an alert rule against stored telemetry
+// @Description  Replays a saved rule (rule_id) or an unsaved one (rule) over a window of stored samples and returns the alerts it would have fired. Each sample is treated as an evaluation, so for durations are measured between samples, and threshold expressions use the current baselines. Nothing is notified.
+// @Tags         alerts
+// @Accept       json
+// @Produce      json
+// @Param        test  body  AlertRuleTestRequest  true  "Rule and window"
+// @Success      200  {object}  AlertRuleTestResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/rules/test [post]
+func (h *Handler) TestAlertRule(w http.ResponseWriter, r *http.Request) {
+	var req AlertRuleTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	var rule *models.AlertRule
+	switch {
+	case (req.RuleID == "") == (req.Rule == nil):
+		writeError(w, http.StatusBadRequest, "bad_request", "Set exactly one of rule_id and rule")
+		return
+	case req.RuleID != "":
+		var ok bool
+		if rule, ok = h.getAlertRule(w, r, req.RuleID); !ok {
+			return
+		}
+	default:
+		var ok bool
+		if rule, ok = h.toAlertRule(w, req.Rule); !ok {
+			return
+		}
+	}
+
+	end := time.Now().UTC()
+	if req.End != nil {
+		end = req.End.UTC()
+	}
+	start := end.Add(-24 * time.Hour)
+	if req.Start != nil {
+		start = req.Start.UTC()
+	}
+	if !end.After(start) {
+		writeError(w, http.StatusBadRequest, "invalid_range", "end must be after start")
+		return
+	}
+
+	// Exact hostname and UUID patterns narrow the query; prefixes are
+	// matched by the replay
+	query := &models.TelemetryQuery{
+		MetricName: rule.Metric,
+		StartTime:  &start,
+		EndTime:    &end,
+		Limit:      maxRuleTestPoints + 1,
+	}
+	if !strings.HasSuffix(rule.Hostname, "*") {
+		query.Hostname = rule.Hostname
+	}
+	if !strings.HasSuffix(rule.UUID, "*") {
+		query.UUID = rule.UUID
+	}
+	metrics, err := h.storeFor(r.Context()).GetTelemetry(r.Context(), query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	// Results are newest first, so truncating keeps the most recent samples
+	truncated := len(metrics) > maxRuleTestPoints
+	if truncated {
+		metrics = metrics[:maxRuleTestPoints]
+	}
+
+	alerts, err := alert.Backtest(rule, metrics, h.baselineSource())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AlertRuleTestResponse{
+		Rule:      rule,
+		Start:     start,
+		End:       end,
+		Samples:   len(metrics),
+		Data:      alerts,
+		Count:     len(alerts),
+		Truncated: truncated,
+	})
+}
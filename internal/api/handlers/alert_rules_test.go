@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// alertRuleStorage adds an in-memory storage.AlertRuleStore to mockStorage.
+type alertRuleStorage struct {
+	*mockStorage
+	mu    sync.Mutex
+	rules map[string]*models.AlertRule
+}
+
+func newAlertRuleStorage() *alertRuleStorage {
+	return &alertRuleStorage{mockStorage: newMockStorage(), rules: make(map[string]*models.AlertRule)}
+}
+
+func (s *alertRuleStorage) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *rule
+	s.rules[rule.ID] = &cp
+	return nil
+}
+
+func (s *alertRuleStorage) GetAlertRule(ctx context.Context, id string) (*models.AlertRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rule, ok := s.rules[id]
+	if !ok {
+		return nil, perrors.NotFound(fmt.Errorf("alert rule %q not found", id))
+	}
+	cp := *rule
+	return &cp, nil
+}
+
+func (s *alertRuleStorage) ListAlertRules(ctx context.Context) ([]*models.AlertRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*models.AlertRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		cp := *rule
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *alertRuleStorage) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	if _, err := s.GetAlertRule(ctx, rule.ID); err != nil {
+		return err
+	}
+	return s.CreateAlertRule(ctx, rule)
+}
+
+func (s *alertRuleStorage) DeleteAlertRule(ctx context.Context, id string) error {
+	if _, err := s.GetAlertRule(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.rules, id)
+	return nil
+}
+
+func setupAlertRuleRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/alerts/rules", h.ListAlertRules).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/rules", h.CreateAlertRule).Methods(http.MethodPost)
+	api.HandleFunc("/alerts/rules/test", h.TestAlertRule).Methods(http.MethodPost)
+	api.HandleFunc("/alerts/rules/{id}", h.GetAlertRule).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/rules/{id}", h.UpdateAlertRule).Methods(http.MethodPut)
+	api.HandleFunc("/alerts/rules/{id}", h.DeleteAlertRule).Methods(http.MethodDelete)
+	api.HandleFunc("/alerts/rules/{id}/enable", h.EnableAlertRule).Methods(http.MethodPost)
+	api.HandleFunc("/alerts/rules/{id}/disable", h.DisableAlertRule).Methods(http.MethodPost)
+	return router
+}
+
+func hotRuleRequest() AlertRuleRequest {
+	return AlertRuleRequest{
+		Name: "GPU hot", Metric: "DCGM_FI_DEV_GPU_TEMP", Op: ">", Threshold: 85, Severity: models.SeverityCritical,
+	}
+}
+
+func TestAlertRuleCRUD(t *testing.T) {
+	store := newAlertRuleStorage()
+	h := NewHandler(store, 100, 1000)
+	h.SetAlertRules(alert.NewRuleSet(alert.StaticRules{{
+		ID: "file-rule", Name: "From file", Metric: "DCGM_FI_DEV_POWER_USAGE", Op: ">", Threshold: 700,
+		Severity: models.SeverityWarning, Enabled: true, Source: models.RuleSourceFile,
+	}}, store, nil))
+	router := setupAlertRuleRouter(h)
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules", hotRuleRequest())
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.AlertRule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.NotEmpty(t, created.ID)
+	assert.True(t, created.Enabled)
+	assert.Equal(t, models.RuleSourceAPI, created.Source)
+
+	var list AlertRuleListResponse
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts/rules", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, 2, list.Count)
+	assert.Equal(t, "file-rule", list.Data[0].ID)
+	assert.Equal(t, created.ID, list.Data[1].ID)
+
+	update := hotRuleRequest()
+	update.Threshold = 90
+	w = doJSON(t, router, http.MethodPut, "/api/v1/alerts/rules/"+created.ID, update)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var updated models.AlertRule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.Equal(t, 90.0, updated.Threshold)
+	assert.True(t, updated.CreatedAt.Equal(created.CreatedAt))
+
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules/"+created.ID+"/disable", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err := store.GetAlertRule(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Enabled)
+
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules/"+created.ID+"/enable", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, _ = store.GetAlertRule(context.Background(), created.ID)
+	assert.True(t, stored.Enabled)
+
+	// File rules can be read but not changed through the API
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts/rules/file-rule", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/alerts/rules/file-rule", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules/file-rule/disable", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/alerts/rules/"+created.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts/rules/"+created.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateAlertRuleValidation(t *testing.T) {
+	router := setupAlertRuleRouter(NewHandler(newAlertRuleStorage(), 100, 1000))
+
+	badOp := hotRuleRequest()
+	badOp.Op = "=>"
+	badFor := hotRuleRequest()
+	badFor.For = "soon"
+	badSummary := hotRuleRequest()
+	badSummary.Summary = "{{.Value"
+	for name, req := range map[string]AlertRuleRequest{
+		"bad op":      badOp,
+		"bad for":     badFor,
+		"bad summary": badSummary,
+		"no metric":   {Name: "x", Op: ">", Severity: models.SeverityInfo},
+	} {
+		w := doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules", req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	router = setupAlertRuleRouter(NewHandler(newMockStorage(), 100, 1000))
+	w := doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules", hotRuleRequest())
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestTestAlertRule(t *testing.T) {
+	store := newAlertRuleStorage()
+	router := setupAlertRuleRouter(NewHandler(store, 100, 1000))
+
+	// GPU-1 runs hot for three minutes then cools; GPU-2 stays cool
+	start := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	temps := []float64{70, 90, 92, 95, 80, 75}
+	for i, temp := range temps {
+		ts := start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, store.Store(context.Background(), &models.GPUMetric{
+			Timestamp: ts, MetricName: "DCGM_FI_DEV_GPU_TEMP", UUID: "GPU-1", Hostname: "host-001", Value: temp,
+		}))
+		require.NoError(t, store.Store(context.Background(), &models.GPUMetric{
+			Timestamp: ts, MetricName: "DCGM_FI_DEV_GPU_TEMP", UUID: "GPU-2", Hostname: "host-001", Value: 60,
+		}))
+	}
+
+	rule := hotRuleRequest()
+	rule.For = "2m"
+	w := doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules/test", AlertRuleTestRequest{Rule: &rule})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response AlertRuleTestResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 12, response.Samples)
+	assert.False(t, response.Truncated)
+	require.Equal(t, 1, response.Count)
+	fired := response.Data[0]
+	assert.Equal(t, "GPU-1", fired.UUID)
+	assert.True(t, fired.ActiveAt.Equal(start.Add(time.Minute)))
+	assert.True(t, fired.FiredAt.Equal(start.Add(3*time.Minute)))
+	assert.True(t, fired.ResolvedAt.Equal(start.Add(4*time.Minute)))
+
+	// A stored rule is tested by ID
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules", hotRuleRequest())
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created models.AlertRule
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules/test", AlertRuleTestRequest{RuleID: created.ID})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Count)
+
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules/test", AlertRuleTestRequest{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/rules/test", AlertRuleTestRequest{RuleID: "missing"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
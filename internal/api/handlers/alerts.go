@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// AlertListResponse represents the current alerts, the maintenance windows
+// suppressing them and notification counters.
+type AlertListResponse struct {
+	Data  []models.Alert `json:"data"`
+	Count int            `json:"count" example:"3"`
+
+	// Leader is false on replicas that are not evaluating rules; their list is empty
+	Leader         bool                      `json:"leader"`
+	LastEvaluation time.Time                 `json:"last_evaluation,omitempty"`
+	Maintenance    []alert.MaintenanceWindow `json:"maintenance"`
+	Notifications  alert.RouterStats         `json:"notifications"`
+}
+
+// SilenceRequest is the body for creating a silence. The window starts now
+// unless starts_at is set, and ends at ends_at or after duration.
+type SilenceRequest struct {
+	Matchers  models.SilenceMatchers `json:"matchers"`
+	Comment   string                 `json:"comment" example:"Driver upgrade on rack 4"`
+	CreatedBy string                 `json:"created_by,omitempty" example:"oncall@example.com"`
+	StartsAt  *time.Time             `json:"starts_at,omitempty"`
+	EndsAt    *time.Time             `json:"ends_at,omitempty"`
+	Duration  string                 `json:"duration,omitempty" example:"2h"`
+}
+
+// SilenceListResponse represents the response for listing silences.
+type SilenceListResponse struct {
+	Data  []*models.Silence `json:"data"`
+	Count int               `json:"count" example:"1"`
+}
+
+// SetAlerts sets the alert evaluator whose alerts are served.
+func (h *Handler) SetAlerts(evaluator *alert.Evaluator) {
+	h.alerts = evaluator
+}
+
+// silenceStore returns the backend's SilenceStore, writing a 501 if it has none.
+func (h *Handler) silenceStore(w http.ResponseWriter, r *http.Request) (storage.SilenceStore, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.SilenceStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support alert silences")
+	}
+	return store, ok
+}
+
+// ListAlerts godoc
+// @Summary      List current alerts
+// @Description  Returns the pending and firing alerts of the replica evaluating alert rules, firing first, with the active maintenance windows and how many alerts each has suppressed, and notification counters
+// @Tags         alerts
+// @Produce      json
+// @Success      200  {object}  AlertListResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts [get]
+func (h *Handler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Alerting is not enabled")
+		return
+	}
+
+	alerts := h.alerts.Alerts()
+	writeJSON(w, http.StatusOK, AlertListResponse{
+		Data:           alerts,
+		Count:          len(alerts),
+		Leader:         h.alerts.Leading(),
+		LastEvaluation: h.alerts.LastEvaluation(),
+		Maintenance:    h.alerts.Maintenance(),
+		Notifications:  h.alerts.Stats(),
+	})
+}
+
+// CreateSilence godoc
+// @Summary      Create an alert silence
+// @Description  Mutes notifications for alerts matching every given matcher (rule name, hostname, uuid, severity; a trailing * matches a prefix) during a time window. Silenced alerts are still evaluated, and resolved notices are still sent. Requires the admin role.
+// @Tags         alerts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        silence  body  SilenceRequest  true  "Silence"
+// @Success      201  {object}  models.Silence
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/silences [post]
+func (h *Handler) CreateSilence(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.silenceStore(w, r)
+	if !ok {
+		return
+	}
+
+	var req SilenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	silence := &models.Silence{
+		ID:        uuid.New().String(),
+		Matchers:  req.Matchers,
+		Comment:   req.Comment,
+		CreatedBy: req.CreatedBy,
+		StartsAt:  now,
+		CreatedAt: now,
+	}
+	if req.StartsAt != nil {
+		silence.StartsAt = req.StartsAt.UTC()
+	}
+	switch {
+	case req.EndsAt != nil && req.Duration != "":
+		writeError(w, http.StatusBadRequest, "bad_request", "Set ends_at or duration, not both")
+		return
+	case req.EndsAt != nil:
+		silence.EndsAt = req.EndsAt.UTC()
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "bad_request", "duration must be a positive duration (e.g., 2h)")
+			return
+		}
+		silence.EndsAt = silence.StartsAt.Add(d)
+	}
+	if err := silence.Validate(); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if err := store.CreateSilence(r.Context(), silence); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, silence)
+}
+
+// ListSilences godoc
+// @Summary      List alert silences
+// @Tags         alerts
+// @Produce      json
+// @Param        active  query  bool  false  "Only silences in effect now"
+// @Success      200  {object}  SilenceListResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/silences [get]
+func (h *Handler) ListSilences(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.silenceStore(w, r)
+	if !ok {
+		return
+	}
+	var activeOnly bool
+	if v := r.URL.Query().Get("active"); v != "" {
+		var err error
+		if activeOnly, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "active must be true or false")
+			return
+		}
+	}
+
+	silences, err := store.ListSilences(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if activeOnly {
+		now := time.Now()
+		active := make([]*models.Silence, 0, len(silences))
+		for _, s := range silences {
+			if s.Active(now) {
+				active = append(active, s)
+			}
+		}
+		silences = active
+	}
+	writeJSON(w, http.StatusOK, SilenceListResponse{
+		Data:  silences,
+		Count: len(silences),
+	})
+}
+
+// GetSilence godoc
+// @Summary      Get an alert silence
+// @Tags         alerts
+// @Produce      json
+// @Param        id   path  string  true  "Silence ID"
+// @Success      200  {object}  models.Silence
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/silences/{id} [get]
+func (h *Handler) GetSilence(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.silenceStore(w, r)
+	if !ok {
+		return
+	}
+
+	silence, err := store.GetSilence(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, silence)
+}
+
+// DeleteSilence godoc
+// @Summary      Delete an alert silence
+// @Description  Removes a silence, so matching alerts notify again from the next evaluation. Requires the admin role.
+// @Tags         alerts
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Silence ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/alerts/silences/{id} [delete]
+func (h *Handler) DeleteSilence(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.silenceStore(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.DeleteSilence(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// silenceStorage adds an in-memory storage.SilenceStore to mockStorage.
+type silenceStorage struct {
+	*mockStorage
+	mu       sync.Mutex
+	silences map[string]*models.Silence
+}
+
+func newSilenceStorage() *silenceStorage {
+	return &silenceStorage{mockStorage: newMockStorage(), silences: make(map[string]*models.Silence)}
+}
+
+func (s *silenceStorage) CreateSilence(ctx context.Context, silence *models.Silence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *silence
+	s.silences[silence.ID] = &cp
+	return nil
+}
+
+func (s *silenceStorage) GetSilence(ctx context.Context, id string) (*models.Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	silence, ok := s.silences[id]
+	if !ok {
+		return nil, perrors.NotFound(fmt.Errorf("silence %q not found", id))
+	}
+	cp := *silence
+	return &cp, nil
+}
+
+func (s *silenceStorage) ListSilences(ctx context.Context) ([]*models.Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.Silence
+	for _, silence := range s.silences {
+		cp := *silence
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *silenceStorage) DeleteSilence(ctx context.Context, id string) error {
+	if _, err := s.GetSilence(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.silences, id)
+	return nil
+}
+
+func setupAlertRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/alerts", h.ListAlerts).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/silences", h.ListSilences).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/silences", h.CreateSilence).Methods(http.MethodPost)
+	api.HandleFunc("/alerts/silences/{id}", h.GetSilence).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/silences/{id}", h.DeleteSilence).Methods(http.MethodDelete)
+	return router
+}
+
+func TestSilenceCRUD(t *testing.T) {
+	store := newSilenceStorage()
+	router := setupAlertRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/alerts/silences", SilenceRequest{
+		Matchers: models.SilenceMatchers{Hostname: "host-00*"},
+		Comment:  "driver upgrade",
+		Duration: "2h",
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.Silence
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.NotEmpty(t, created.ID)
+	assert.Equal(t, 2*time.Hour, created.EndsAt.Sub(created.StartsAt))
+
+	// A future silence is listed, but not as active
+	later := time.Now().Add(24 * time.Hour)
+	end := later.Add(time.Hour)
+	w = doJSON(t, router, http.MethodPost, "/api/v1/alerts/silences", SilenceRequest{
+		Matchers: models.SilenceMatchers{RuleName: "GPU hot"},
+		Comment:  "planned maintenance",
+		StartsAt: &later,
+		EndsAt:   &end,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var list SilenceListResponse
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts/silences", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 2, list.Count)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts/silences?active=true", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, 1, list.Count)
+	assert.Equal(t, created.ID, list.Data[0].ID)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts/silences/"+created.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/alerts/silences/"+created.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts/silences/"+created.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateSilenceValidation(t *testing.T) {
+	router := setupAlertRouter(NewHandler(newSilenceStorage(), 100, 1000))
+	end := time.Now().Add(time.Hour)
+
+	for name, req := range map[string]SilenceRequest{
+		"no matchers":       {Comment: "x", Duration: "1h"},
+		"no window":         {Matchers: models.SilenceMatchers{UUID: "GPU-1"}, Comment: "x"},
+		"bad duration":      {Matchers: models.SilenceMatchers{UUID: "GPU-1"}, Comment: "x", Duration: "soon"},
+		"ends and duration": {Matchers: models.SilenceMatchers{UUID: "GPU-1"}, Comment: "x", Duration: "1h", EndsAt: &end},
+		"no comment":        {Matchers: models.SilenceMatchers{UUID: "GPU-1"}, Duration: "1h"},
+	} {
+		w := doJSON(t, router, http.MethodPost, "/api/v1/alerts/silences", req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	router = setupAlertRouter(NewHandler(newMockStorage(), 100, 1000))
+	w := doJSON(t, router, http.MethodGet, "/api/v1/alerts/silences", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestListAlerts(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := setupAlertRouter(h)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/alerts", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	latest := cache.NewLatest(cache.SourceStorage)
+	latest.Update([]*models.GPUMetric{{
+		Timestamp: time.Now(), MetricName: "DCGM_FI_DEV_GPU_TEMP", UUID: "GPU-1", Hostname: "host-001", Value: 95,
+	}})
+	rules := alert.StaticRules{{
+		ID: "hot", Name: "GPU hot", Metric: "DCGM_FI_DEV_GPU_TEMP", Op: ">", Threshold: 85,
+		Severity: models.SeverityCritical, Enabled: true,
+	}}
+	cfg := config.AlertConfig{RepeatInterval: time.Hour, Timeout: time.Second, QueueSize: 10}
+	alertRouter := alert.NewRouter(cfg, nil, log.New(io.Discard, "", 0))
+	evaluator := alert.NewEvaluator(rules, latest, nil, alertRouter, leader.Always{}, nil, time.Minute, log.New(io.Discard, "", 0))
+	evaluator.Evaluate(context.Background())
+	h.SetAlerts(evaluator)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/alerts", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response AlertListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Count)
+	assert.True(t, response.Leader)
+	assert.Equal(t, models.AlertFiring, response.Data[0].State)
+	assert.Equal(t, "hot/GPU-1", response.Data[0].ID)
+}
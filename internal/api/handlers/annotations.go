@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// AnnotationRequest is the body for creating or replacing an annotation.
+type AnnotationRequest struct {
+	Kind        string    `json:"kind" example:"maintenance"`
+	Title       string    `json:"title" example:"Rack 12 firmware update"`
+	Description string    `json:"description,omitempty"`
+	Hostname    string    `json:"hostname,omitempty" example:"host-001"`
+	UUID        string    `json:"uuid,omitempty"`
+	Start       time.Time `json:"start" example:"2024-01-01T00:00:00Z"`
+	End         time.Time `json:"end,omitempty" example:"2024-01-01T02:00:00Z"`
+}
+
+// AnnotationListResponse represents the response for listing annotations.
+type AnnotationListResponse struct {
+	Data  []*models.Annotation `json:"data"`
+	Count int                  `json:"count" example:"3"`
+}
+
+// annotationStore returns the backend's AnnotationStore, writing a 501 if it has none.
+func (h *Handler) annotationStore(w http.ResponseWriter, r *http.Request) (storage.AnnotationStore, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.AnnotationStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support annotations")
+	}
+	return store, ok
+}
+
+// decodeAnnotation reads and validates an AnnotationRequest into an Annotation.
+func decodeAnnotation(w http.ResponseWriter, r *http.Request) (*models.Annotation, bool) {
+	var req AnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return nil, false
+	}
+
+	annotation := &models.Annotation{
+		Kind:        req.Kind,
+		Title:       req.Title,
+		Description: req.Description,
+		Hostname:    req.Hostname,
+		UUID:        req.UUID,
+		Start:       req.Start,
+		End:         req.End,
+	}
+	if err := annotation.Validate(); err != nil {
+		writeBadRequest(w, err)
+		return nil, false
+	}
+	return annotation, true
+}
+
+// CreateAnnotation godoc
+// @Summary      Create an annotation
+// @Description  Records an operational event (maintenance window, driver upgrade, job launch) for a host, GPU or the whole fleet. Requires the admin role.
+// @Tags         annotations
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        annotation  body  AnnotationRequest  true  "Annotation"
+// @Success      201  {object}  models.Annotation
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/annotations [post]
+func (h *Handler) CreateAnnotation(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.annotationStore(w, r)
+	if !ok {
+		return
+	}
+	annotation, ok := decodeAnnotation(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	annotation.ID = uuid.New().String()
+	annotation.CreatedAt = now
+	annotation.UpdatedAt = now
+
+	if err := store.CreateAnnotation(r.Context(), annotation); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, annotation)
+}
+
+// ListAnnotations godoc
+// @Summary      List annotations
+// @Description  Returns annotations overlapping the time range whose scope covers the given host or GPU
+// @Tags         annotations
+// @Produce      json
+// @Param        hostname    query  string  false  "Host scope (also returns fleet-wide annotations)"
+// @Param        uuid        query  string  false  "GPU scope (also returns host and fleet-wide annotations)"
+// @Param        kind        query  string  false  "Annotation kind"  enum(maintenance,driver_upgrade,job_launch,other)
+// @Param        start_time  query  string  false  "Start of range (RFC3339)"
+// @Param        end_time    query  string  false  "End of range (RFC3339)"
+// @Success      200  {object}  AnnotationListResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/annotations [get]
+func (h *Handler) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.annotationStore(w, r)
+	if !ok {
+		return
+	}
+
+	query := &models.AnnotationQuery{
+		Hostname: r.URL.Query().Get("hostname"),
+		UUID:     r.URL.Query().Get("uuid"),
+		Kind:     r.URL.Query().Get("kind"),
+	}
+	if startTimeStr := r.URL.Query().Get("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_range", "Invalid start_time format. Use RFC3339 (e.g., 2024-01-01T00:00:00Z)")
+			return
+		}
+		query.StartTime = &startTime
+	}
+	if endTimeStr := r.URL.Query().Get("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_range", "Invalid end_time format. Use RFC3339 (e.g., 2024-01-02T00:00:00Z)")
+			return
+		}
+		query.EndTime = &endTime
+	}
+
+	annotations, err := store.ListAnnotations(r.Context(), query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AnnotationListResponse{
+		Data:  annotations,
+		Count: len(annotations),
+	})
+}
+
+// GetAnnotation godoc
+// @Summary      Get an annotation
+// @Tags         annotations
+// @Produce      json
+// @Param        id   path  string  true  "Annotation ID"
+// @Success      200  {object}  models.Annotation
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/annotations/{id} [get]
+func (h *Handler) GetAnnotation(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.annotationStore(w, r)
+	if !ok {
+		return
+	}
+
+	annotation, err := store.GetAnnotation(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, annotation)
+}
+
+// UpdateAnnotation godoc
+// @Summary      Replace an annotation
+// @Description  Requires the admin role.
+// @Tags         annotations
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id          path  string             true  "Annotation ID"
+// @Param        annotation  body  AnnotationRequest  true  "Annotation"
+// @Success      200  {object}  models.Annotation
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/annotations/{id} [put]
+func (h *Handler) UpdateAnnotation(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.annotationStore(w, r)
+	if !ok {
+		return
+	}
+	annotation, ok := decodeAnnotation(w, r)
+	if !ok {
+		return
+	}
+
+	annotation.ID = mux.Vars(r)["id"]
+	annotation.UpdatedAt = time.Now().UTC()
+
+	if err := store.UpdateAnnotation(r.Context(), annotation); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, annotation)
+}
+
+// DeleteAnnotation godoc
+// @Summary      Delete an annotation
+// @Description  Requires the admin role.
+// @Tags         annotations
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Annotation ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/annotations/{id} [delete]
+func (h *Handler) DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.annotationStore(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.DeleteAnnotation(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// overlappingAnnotations returns the annotations that apply to a GPU over the
+// queried range, or over the span of the returned metrics when the query is
+// open-ended. It is best-effort: backends without annotations, or a failed
+// lookup, yield none rather than failing the telemetry request.
+func (h *Handler) overlappingAnnotations(ctx context.Context, query *models.TelemetryQuery, metrics []*models.GPUMetric) []*models.Annotation {
+	store, ok := h.storeFor(ctx).(storage.AnnotationStore)
+	if !ok || len(metrics) == 0 {
+		return nil
+	}
+
+	// Metrics are sorted descending, so the last is the oldest
+	start, end := metrics[len(metrics)-1].Timestamp, metrics[0].Timestamp
+	if query.StartTime != nil {
+		start = *query.StartTime
+	}
+	if query.EndTime != nil {
+		end = *query.EndTime
+	}
+
+	annotations, err := store.ListAnnotations(ctx, &models.AnnotationQuery{
+		Hostname:  metrics[0].Hostname,
+		UUID:      query.UUID,
+		StartTime: &start,
+		EndTime:   &end,
+	})
+	if err != nil {
+		return nil
+	}
+	return annotations
+}
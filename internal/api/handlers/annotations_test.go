@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// annotatedStorage adds an in-memory storage.AnnotationStore to mockStorage.
+type annotatedStorage struct {
+	*mockStorage
+	mu          sync.Mutex
+	annotations map[string]*models.Annotation
+}
+
+func newAnnotatedStorage() *annotatedStorage {
+	return &annotatedStorage{mockStorage: newMockStorage(), annotations: make(map[string]*models.Annotation)}
+}
+
+func (s *annotatedStorage) CreateAnnotation(ctx context.Context, a *models.Annotation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *a
+	s.annotations[a.ID] = &cp
+	return nil
+}
+
+func (s *annotatedStorage) GetAnnotation(ctx context.Context, id string) (*models.Annotation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.annotations[id]
+	if !ok {
+		return nil, perrors.NotFound(fmt.Errorf("annotation %q not found", id))
+	}
+	cp := *a
+	return &cp, nil
+}
+
+func (s *annotatedStorage) ListAnnotations(ctx context.Context, q *models.AnnotationQuery) ([]*models.Annotation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.Annotation
+	for _, a := range s.annotations {
+		if q.Matches(a) {
+			cp := *a
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *annotatedStorage) UpdateAnnotation(ctx context.Context, a *models.Annotation) error {
+	existing, err := s.GetAnnotation(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	a.CreatedAt = existing.CreatedAt
+	return s.CreateAnnotation(ctx, a)
+}
+
+func (s *annotatedStorage) DeleteAnnotation(ctx context.Context, id string) error {
+	if _, err := s.GetAnnotation(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.annotations, id)
+	return nil
+}
+
+func setupAnnotationRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/gpus/{id}/telemetry", h.GetGPUTelemetry).Methods(http.MethodGet)
+	api.HandleFunc("/annotations", h.ListAnnotations).Methods(http.MethodGet)
+	api.HandleFunc("/annotations", h.CreateAnnotation).Methods(http.MethodPost)
+	api.HandleFunc("/annotations/{id}", h.GetAnnotation).Methods(http.MethodGet)
+	api.HandleFunc("/annotations/{id}", h.UpdateAnnotation).Methods(http.MethodPut)
+	api.HandleFunc("/annotations/{id}", h.DeleteAnnotation).Methods(http.MethodDelete)
+	return router
+}
+
+func doJSON(t *testing.T, router http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, _ := http.NewRequest(method, path, &buf)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAnnotationCRUD(t *testing.T) {
+	router := setupAnnotationRouter(NewHandler(newAnnotatedStorage(), 100, 1000))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/annotations", AnnotationRequest{
+		Kind: models.AnnotationMaintenance, Title: "Firmware update", Hostname: "host-001",
+		Start: start, End: start.Add(time.Hour),
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.Annotation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.NotEmpty(t, created.ID)
+
+	w = doJSON(t, router, http.MethodPut, "/api/v1/annotations/"+created.ID, AnnotationRequest{
+		Kind: models.AnnotationMaintenance, Title: "Firmware update (extended)", Hostname: "host-001",
+		Start: start, End: start.Add(2 * time.Hour),
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/annotations/"+created.ID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var fetched models.Annotation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.Equal(t, "Firmware update (extended)", fetched.Title)
+	assert.True(t, fetched.CreatedAt.Equal(created.CreatedAt))
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/annotations?hostname=host-002", nil)
+	var list AnnotationListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 0, list.Count)
+
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/annotations/"+created.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/annotations/"+created.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateAnnotationValidation(t *testing.T) {
+	router := setupAnnotationRouter(NewHandler(newAnnotatedStorage(), 100, 1000))
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/annotations", AnnotationRequest{Kind: "outage"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	router = setupAnnotationRouter(NewHandler(newMockStorage(), 100, 1000))
+	w = doJSON(t, router, http.MethodGet, "/api/v1/annotations", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestTelemetryIncludesOverlappingAnnotations(t *testing.T) {
+	store := newAnnotatedStorage()
+	seedTestData(t, store.mockStorage)
+	now := time.Now()
+	require.NoError(t, store.CreateAnnotation(context.Background(), &models.Annotation{
+		ID: "a1", Kind: models.AnnotationJobLaunch, Title: "training run", Hostname: "host-001", Start: now.Add(time.Minute),
+	}))
+	require.NoError(t, store.CreateAnnotation(context.Background(), &models.Annotation{
+		ID: "a2", Kind: models.AnnotationMaintenance, Title: "other host", Hostname: "host-002", Start: now.Add(time.Minute),
+	}))
+	require.NoError(t, store.CreateAnnotation(context.Background(), &models.Annotation{
+		ID: "a3", Kind: models.AnnotationOther, Title: "last week", Start: now.Add(-7 * 24 * time.Hour),
+	}))
+	router := setupAnnotationRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-12345-AAAA/telemetry", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response TelemetryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Annotations, 1)
+	assert.Equal(t, "a1", response.Annotations[0].ID)
+}
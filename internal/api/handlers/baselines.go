@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/baseline"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// BaselineListResponse represents the learned per-model baselines.
+type BaselineListResponse struct {
+	Data  []models.Baseline `json:"data"`
+	Count int               `json:"count" example:"6"`
+
+	// RefreshedAt is when every baseline metric was last recomputed
+	RefreshedAt time.Time `json:"refreshed_at,omitempty"`
+
+	// LastError is the last refresh's error; baselines it could not
+	// recompute are from an earlier refresh
+	LastError string `json:"last_error,omitempty"`
+}
+
+// SetBaselines sets the profiler whose baselines are served and used by
+// alert rule tests.
+func (h *Handler) SetBaselines(profiler *baseline.Profiler) {
+	h.baselines = profiler
+}
+
+// baselineSource returns the profiler as an alert.BaselineSource, or nil
+// when baselines are not enabled.
+func (h *Handler) baselineSource() alert.BaselineSource {
+	if h.baselines == nil {
+		return nil
+	}
+	return h.baselines
+}
+
+// ListBaselines godoc
+// @Summary      List per-model baselines
+// @Description  Returns the normal range of each baseline metric for each GPU model, learned from fleet history. Alert rules reference them in threshold_expr as model_<stat> (mean, stddev, min, max, p1, p5, p50, p95, p99).
+// @Tags         alerts
+// @Produce      json
+// @Param        model   query  string  false  "GPU model name"
+// @Param        metric  query  string  false  "Metric name"
+// @Success      200  {object}  BaselineListResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/baselines [get]
+func (h *Handler) ListBaselines(w http.ResponseWriter, r *http.Request) {
+	if h.baselines == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Baselines are not enabled")
+		return
+	}
+	model := r.URL.Query().Get("model")
+	metric := h.aliases.Canonical(r.URL.Query().Get("metric"))
+
+	baselines := make([]models.Baseline, 0)
+	for _, b := range h.baselines.Baselines() {
+		if (model == "" || b.ModelName == model) && (metric == "" || b.Metric == metric) {
+			baselines = append(baselines, b)
+		}
+	}
+	refreshed, err := h.baselines.Status()
+	resp := BaselineListResponse{
+		Data:        baselines,
+		Count:       len(baselines),
+		RefreshedAt: refreshed,
+	}
+	if err != nil {
+		resp.LastError = err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
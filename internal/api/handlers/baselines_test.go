@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/baseline"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// baselineStorage adds a fixed storage.BaselineReader to mockStorage.
+type baselineStorage struct {
+	*mockStorage
+}
+
+func (s *baselineStorage) ModelBaselines(ctx context.Context, metric string, start, end time.Time) ([]*models.Baseline, error) {
+	return []*models.Baseline{
+		{ModelName: "B200", Metric: metric, Samples: 2000, P99: 70},
+		{ModelName: "H100", Metric: metric, Samples: 2000, P99: 80},
+	}, nil
+}
+
+func TestListBaselines(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/baselines", h.ListBaselines).Methods(http.MethodGet)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/baselines", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	cfg := config.DefaultBaselineConfig()
+	cfg.Metrics = []string{"DCGM_FI_DEV_GPU_TEMP", "DCGM_FI_DEV_POWER_USAGE"}
+	profiler := baseline.NewProfiler(&baselineStorage{newMockStorage()}, cfg, nil)
+	require.NoError(t, profiler.Refresh(context.Background()))
+	h.SetBaselines(profiler)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/baselines", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp BaselineListResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 4, resp.Count)
+	assert.False(t, resp.RefreshedAt.IsZero())
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/baselines?model=H100&metric=DCGM_FI_DEV_GPU_TEMP", nil)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, 80.0, resp.Data[0].P99)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+)
+
+// GetBatch godoc
+// @Summary      Get batch lineage
+// @Description  Returns the provenance of a stored batch: the streamer and source file lines that produced it, the collector that stored it, its MQ offset and ingest timestamps. Telemetry results carry the batch_id to look up here.
+// @Tags         batches
+// @Produce      json
+// @Param        id   path  string  true  "Batch ID"
+// @Success      200  {object}  models.BatchLineage
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/batches/{id} [get]
+func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.storeFor(r.Context()).(storage.LineageReader)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support batch lineage")
+		return
+	}
+
+	lineage, err := store.GetBatch(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, lineage)
+}
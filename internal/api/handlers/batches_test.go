@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// lineageStorage adds an in-memory storage.LineageReader to mockStorage.
+type lineageStorage struct {
+	*mockStorage
+	batches map[string]*models.BatchLineage
+}
+
+func (s *lineageStorage) GetBatch(ctx context.Context, id string) (*models.BatchLineage, error) {
+	if b, ok := s.batches[id]; ok {
+		return b, nil
+	}
+	return nil, perrors.NotFound(errors.New("batch not found"))
+}
+
+func (s *lineageStorage) ListBatches(ctx context.Context, start, end time.Time, limit int) ([]*models.BatchLineage, error) {
+	return nil, nil
+}
+
+func setupBatchRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/batches/{id}", h.GetBatch).Methods(http.MethodGet)
+	return router
+}
+
+func TestGetBatch(t *testing.T) {
+	received := time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC)
+	store := &lineageStorage{mockStorage: newMockStorage(), batches: map[string]*models.BatchLineage{
+		"batch-1": {
+			BatchID: "batch-1", Source: "streamer-0", SourceFile: "/data/dcgm_metrics.csv",
+			SourceLines: &models.LineRange{First: 2, Last: 101}, Collector: "collector-0",
+			MQOffset: 42, MetricCount: 100, ReceivedAt: received,
+		},
+	}}
+	router := setupBatchRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/batches/batch-1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var lineage models.BatchLineage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &lineage))
+	assert.Equal(t, "streamer-0", lineage.Source)
+	assert.Equal(t, "/data/dcgm_metrics.csv", lineage.SourceFile)
+	assert.Equal(t, 101, lineage.SourceLines.Last)
+	assert.Equal(t, int64(42), lineage.MQOffset)
+	assert.True(t, lineage.ReceivedAt.Equal(received))
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/batches/missing", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetBatchNotImplemented(t *testing.T) {
+	router := setupBatchRouter(NewHandler(newMockStorage(), 100, 1000))
+	w := doJSON(t, router, http.MethodGet, "/api/v1/batches/batch-1", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
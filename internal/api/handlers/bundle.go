@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/bundle"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// SetBundleKey sets the key configuration bundles are signed and verified
+// with; empty disables the bundle endpoints.
+func (h *Handler) SetBundleKey(key string) {
+	h.bundleKey = []byte(key)
+}
+
+// bundleKeyOK writes a 503 unless a bundle key is configured.
+func (h *Handler) bundleKeyOK(w http.ResponseWriter) bool {
+	if len(h.bundleKey) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Configuration bundles are disabled (API_BUNDLE_SIGNING_KEY not set)")
+		return false
+	}
+	return true
+}
+
+// ExportBundle godoc
+// @Summary      Export the configuration bundle
+// @Description  Returns the deployment's runtime configuration as a bundle signed with API_BUNDLE_SIGNING_KEY: the retention period, the alert rules managed through the API and the saved queries. Rules from the rules file and tokens, which come from each deployment's own files and environment, are not included. Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  models.ConfigBundle
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/bundle [get]
+func (h *Handler) ExportBundle(w http.ResponseWriter, r *http.Request) {
+	if !h.bundleKeyOK(w) {
+		return
+	}
+	b, err := bundle.Export(r.Context(), h.storeFor(r.Context()), time.Now())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if err := bundle.Sign(b, h.bundleKey); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, b)
+}
+
+// ImportBundle godoc
+// @Summary      Import a configuration bundle
+// @Description  Applies a bundle exported by a deployment sharing API_BUNDLE_SIGNING_KEY. Alert rules and saved queries are matched by name: missing ones are created and differing ones replaced, and nothing is deleted. The retention changes if the bundle's differs, which is recorded in the cleanup history and refused while retention holds exist. The whole bundle is checked before anything changes. With dry_run=true the changes are reported but not made. Requires the admin role.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        bundle   body   models.ConfigBundle  true   "Signed bundle"
+// @Param        dry_run  query  bool                 false  "Report the changes without making them"
+// @Success      200  {object}  models.BundleImportResult
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/bundle [post]
+func (h *Handler) ImportBundle(w http.ResponseWriter, r *http.Request) {
+	if !h.bundleKeyOK(w) {
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "dry_run must be true or false")
+			return
+		}
+	}
+
+	var b models.ConfigBundle
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := bundle.Verify(&b, h.bundleKey); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	opts := bundle.Options{DryRun: dryRun, Deliveries: &h.deliveries, Now: time.Now()}
+	if h.alerts != nil {
+		opts.ValidateRule = h.alerts.CheckRule
+	}
+	result, err := bundle.Import(r.Context(), h.storeFor(r.Context()), &b, opts)
+	switch {
+	case errors.Is(err, bundle.ErrRetentionHeld):
+		writeError(w, http.StatusConflict, "conflict", err.Error())
+		return
+	case err != nil:
+		writeStoreError(w, err)
+		return
+	}
+	if result.Retention != "" && !dryRun {
+		record := newCleanupRecord(r, models.CleanupRetention)
+		record.Retention = result.Retention
+		record.PreviousRetention = result.PreviousRetention
+		finishCleanupRecord(r, h.storeFor(r.Context()).(storage.DataAdmin), record, nil)
+	}
+	if !dryRun && len(b.AlertRules) > 0 {
+		h.reloadAlerts()
+	}
+	writeJSON(w, http.StatusOK, result)
+}
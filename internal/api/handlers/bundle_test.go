@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// bundleStorage combines the alert rule and admin fakes.
+type bundleStorage struct {
+	*alertRuleStorage
+	storage.DataAdmin
+}
+
+func newBundleStorage() (*bundleStorage, *adminStorage) {
+	admin := newAdminStorage()
+	return &bundleStorage{alertRuleStorage: newAlertRuleStorage(), DataAdmin: admin}, admin
+}
+
+func setupBundleRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.HandleFunc("/bundle", h.ExportBundle).Methods(http.MethodGet)
+	admin.HandleFunc("/bundle", h.ImportBundle).Methods(http.MethodPost)
+	return router
+}
+
+func TestBundleDisabled(t *testing.T) {
+	store, _ := newBundleStorage()
+	router := setupBundleRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/admin/bundle", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestBundleExportImport(t *testing.T) {
+	source, sourceAdmin := newBundleStorage()
+	sourceAdmin.retention = 720 * time.Hour
+	source.rules["rule-1"] = &models.AlertRule{ID: "rule-1", Name: "gpu-hot", Metric: "DCGM_FI_DEV_GPU_TEMP", Op: ">",
+		Threshold: 85, Severity: models.SeverityCritical, Enabled: true, Source: models.RuleSourceAPI}
+	h := NewHandler(source, 100, 1000)
+	h.SetBundleKey("0123456789abcdef")
+
+	w := doJSON(t, setupBundleRouter(h), http.MethodGet, "/api/v1/admin/bundle", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var b models.ConfigBundle
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &b))
+	assert.Equal(t, "720h0m0s", b.Retention)
+	require.Len(t, b.AlertRules, 1)
+	assert.NotEmpty(t, b.Signature)
+
+	target, targetAdmin := newBundleStorage()
+	h = NewHandler(target, 100, 1000)
+	h.SetBundleKey("0123456789abcdef")
+	router := setupBundleRouter(h)
+
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/bundle?dry_run=true", b)
+	require.Equal(t, http.StatusOK, w.Code)
+	var result models.BundleImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []string{"gpu-hot"}, result.AlertRules.Created)
+	assert.Empty(t, target.rules)
+	assert.Equal(t, 168*time.Hour, targetAdmin.retention)
+
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/bundle", b)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, target.rules, 1)
+	assert.Equal(t, 720*time.Hour, targetAdmin.retention)
+	require.Len(t, targetAdmin.history, 1)
+	assert.Equal(t, models.CleanupRetention, targetAdmin.history[0].Kind)
+	assert.Equal(t, "168h0m0s", targetAdmin.history[0].PreviousRetention)
+
+	b.AlertRules[0].Threshold = 95
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/bundle", b)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
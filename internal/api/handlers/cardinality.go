@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+const (
+	// defaultCardinalityWindow and maxCardinalityWindow bound the time counted
+	defaultCardinalityWindow = time.Hour
+	maxCardinalityWindow     = 24 * time.Hour
+)
+
+// GetCardinality godoc
+// @Summary      Get telemetry series cardinality
+// @Description  Counts the distinct series (metric and tag set) stored over the window ending now, the series of the metrics with the most, and the distinct values of each tag. A tag with far more values than there are GPUs, such as a pod label carrying a job ID, is usually what is inflating InfluxDB. Each collector also reports the series it wrote and any it dropped on its status port at /cardinality.
+// @Tags         telemetry
+// @Produce      json
+// @Param        window  query  string  false  "How far back from now (default 1h, max 24h)"
+// @Success      200  {object}  models.CardinalityReport
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/cardinality [get]
+func (h *Handler) GetCardinality(w http.ResponseWriter, r *http.Request) {
+	reader, ok := h.storeFor(r.Context()).(storage.CardinalityReader)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support cardinality reports")
+		return
+	}
+	window, err := parseDuration(r, "window", defaultCardinalityWindow)
+	if err == nil && window > maxCardinalityWindow {
+		err = perrors.WithCode(perrors.CodeQueryTooBroad, fmt.Errorf("window must be at most %v", maxCardinalityWindow))
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	report, err := reader.GetCardinality(r.Context(), window)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
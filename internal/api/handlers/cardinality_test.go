@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// cardinalityStorage adds a fixed storage.CardinalityReader to mockStorage.
+type cardinalityStorage struct {
+	*mockStorage
+	window time.Duration
+}
+
+func (s *cardinalityStorage) GetCardinality(ctx context.Context, window time.Duration) (*models.CardinalityReport, error) {
+	s.window = window
+	return &models.CardinalityReport{
+		Series:  3,
+		Metrics: []models.CardinalityCount{{Name: "DCGM_FI_DEV_GPU_UTIL", Count: 3}},
+		Tags:    []models.CardinalityCount{{Name: "pod", Count: 3}, {Name: "uuid", Count: 1}},
+	}, nil
+}
+
+func TestGetCardinality(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/cardinality", NewHandler(newMockStorage(), 100, 1000).GetCardinality)
+	w := doJSON(t, router, http.MethodGet, "/api/v1/cardinality", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	store := &cardinalityStorage{mockStorage: newMockStorage()}
+	router = mux.NewRouter()
+	router.HandleFunc("/api/v1/cardinality", NewHandler(store, 100, 1000).GetCardinality)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/cardinality", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, time.Hour, store.window)
+	var report models.CardinalityReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, 3, report.Series)
+	assert.Equal(t, "pod", report.Tags[0].Name)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/cardinality?window=6h", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 6*time.Hour, store.window)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/cardinality?window=48h", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/correlate"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+const (
+	// defaultCorrelateWindow and maxCorrelateWindow bound the history correlated
+	defaultCorrelateWindow = time.Hour
+	maxCorrelateWindow     = 7 * 24 * time.Hour
+
+	// correlateBuckets is how many buckets the window is split into by default,
+	// and maxCorrelateBuckets the most an explicit step may produce
+	correlateBuckets    = 360
+	maxCorrelateBuckets = 10000
+
+	// defaultCorrelateLags is how many buckets either way lags are examined
+	// by default
+	defaultCorrelateLags = 30
+)
+
+// CorrelationResponse is the correlation of two of a GPU's metrics.
+type CorrelationResponse struct {
+	UUID    string    `json:"uuid" example:"GPU-5fd4f087-86f3-7a43-b711-4771313afc50"`
+	Metrics []string  `json:"metrics" example:"DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_SM_CLOCK"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+
+	// Step is the bucket width the metrics were aggregated to
+	Step      string `json:"step" example:"10s"`
+	Aggregate string `json:"aggregate" example:"mean"`
+
+	correlate.Result
+}
+
+// seriesReader returns the storage as a SeriesReader, writing 501 if unsupported.
+func (h *Handler) seriesReader(w http.ResponseWriter, r *http.Request) (storage.SeriesReader, bool) {
+	reader, ok := h.storeFor(r.Context()).(storage.SeriesReader)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support aggregated series")
+	}
+	return reader, ok
+}
+
+// parseDuration parses an optional positive duration query parameter.
+func parseDuration(r *http.Request, name string, def time.Duration) (time.Duration, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration (e.g., 1h), got %q", name, s)
+	}
+	return d, nil
+}
+
+// parseEndTime parses the optional end_time query parameter, defaulting to now.
+func parseEndTime(r *http.Request) (time.Time, error) {
+	s := r.URL.Query().Get("end_time")
+	if s == "" {
+		return time.Now().UTC(), nil
+	}
+	end, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, perrors.WithCode(perrors.CodeInvalidRange, fmt.Errorf("end_time must be RFC3339 (e.g., 2024-01-02T00:00:00Z), got %q", s))
+	}
+	return end.UTC(), nil
+}
+
+// splitQueryList splits a comma-separated query parameter, dropping empty items.
+func splitQueryList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseAggregate parses the optional aggregate query parameter, defaulting to mean.
+func parseAggregate(r *http.Request) (string, error) {
+	fn := r.URL.Query().Get("aggregate")
+	if fn == "" {
+		return models.SeriesMean, nil
+	}
+	if !models.IsSeriesFn(fn) {
+		return "", fmt.Errorf("aggregate must be mean, min, max or last, got %q", fn)
+	}
+	return fn, nil
+}
+
+// CorrelateGPUMetrics godoc
+// @Summary      Correlate two GPU metrics
+// @Description  Aggregates two of a GPU's metrics into buckets over the window ending at end_time and returns their Pearson correlation, and the correlation with the second metric shifted by up to max_lag either way. A positive best_lag means the second metric follows the first (e.g., clocks dropping after temperature rises). Buckets missing from either metric are skipped.
+// @Tags         gpus
+// @Produce      json
+// @Param        id         path   string  true   "GPU UUID"
+// @Param        metrics    query  string  true   "Two comma-separated metric names"  example(DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_SM_CLOCK)
+// @Param        window     query  string  false  "How far back from end_time to correlate (default 1h, max 168h)"
+// @Param        end_time   query  string  false  "End of the window (RFC3339, default now)"
+// @Param        step       query  string  false  "Bucket width (default window/360, at least 1s)"
+// @Param        max_lag    query  string  false  "Largest lag examined either way (default 30 buckets, at most window/2)"
+// @Param        aggregate  query  string  false  "Bucket aggregate: mean (default), min, max or last"
+// @Success      200  {object}  CorrelationResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      422  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/gpus/{id}/correlate [get]
+func (h *Handler) CorrelateGPUMetrics(w http.ResponseWriter, r *http.Request) {
+	reader, ok := h.seriesReader(w, r)
+	if !ok {
+		return
+	}
+	uuid := mux.Vars(r)["id"]
+
+	metrics := splitQueryList(r.URL.Query().Get("metrics"))
+	for i, m := range metrics {
+		metrics[i] = h.aliases.Canonical(m)
+	}
+	if len(metrics) != 2 || metrics[0] == metrics[1] {
+		writeError(w, http.StatusBadRequest, "bad_request", "metrics must name two different metrics (e.g., DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_SM_CLOCK)")
+		return
+	}
+	window, err := parseDuration(r, "window", defaultCorrelateWindow)
+	if err == nil && window > maxCorrelateWindow {
+		err = perrors.WithCode(perrors.CodeQueryTooBroad, fmt.Errorf("window must be at most %v", maxCorrelateWindow))
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	defaultStep := max((window / correlateBuckets).Round(time.Second), time.Second)
+	step, err := parseDuration(r, "step", defaultStep)
+	if err == nil && (step < time.Second || window/step > maxCorrelateBuckets) {
+		err = perrors.WithCode(perrors.CodeQueryTooBroad, fmt.Errorf("step must be at least 1s and split window into at most %d buckets", maxCorrelateBuckets))
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	maxLag, err := parseDuration(r, "max_lag", min(defaultCorrelateLags*step, window/2))
+	if err == nil && maxLag > window/2 {
+		err = errors.New("max_lag must be at most half the window")
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	end, err := parseEndTime(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	fn, err := parseAggregate(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// Buckets are aligned to the step, so both metrics share bucket times
+	start := end.Add(-window).Truncate(step)
+	series, err := reader.GetSeries(r.Context(), &models.SeriesQuery{
+		Metrics: metrics,
+		UUID:    uuid,
+		Start:   start,
+		End:     end,
+		Every:   step,
+		Fn:      fn,
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	points := make(map[string][]models.SeriesPoint, 2)
+	for _, s := range series {
+		points[s.Metric] = append(points[s.Metric], s.Points...)
+	}
+
+	result, err := correlate.Series(points[metrics[0]], points[metrics[1]], step, int(maxLag/step))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "insufficient_data", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, CorrelationResponse{
+		UUID:      uuid,
+		Metrics:   metrics,
+		Start:     start,
+		End:       end,
+		Step:      step.String(),
+		Aggregate: fn,
+		Result:    result,
+	})
+}
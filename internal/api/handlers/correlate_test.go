@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// seriesStorage adds a storage.SeriesReader to mockStorage. Temperature
+// follows a fixed pattern and the SM clock mirrors it two buckets later.
+type seriesStorage struct {
+	*mockStorage
+	last *models.SeriesQuery
+}
+
+func (s *seriesStorage) GetSeries(ctx context.Context, query *models.SeriesQuery) ([]*models.Series, error) {
+	s.last = query
+	pattern := []float64{60, 72, 65, 80, 62, 77, 70, 85, 66, 74}
+	out := make([]*models.Series, 0)
+	for _, metric := range query.Metrics {
+		series := &models.Series{Metric: metric, UUID: query.UUID, Hostname: "host-001"}
+		for t, i := query.Start, 0; t.Before(query.End); t, i = t.Add(query.Every), i+1 {
+			switch metric {
+			case "DCGM_FI_DEV_GPU_TEMP":
+				series.Points = append(series.Points, models.SeriesPoint{Time: t, Value: pattern[i%len(pattern)]})
+			case "DCGM_FI_DEV_SM_CLOCK":
+				if i >= 2 {
+					series.Points = append(series.Points, models.SeriesPoint{Time: t, Value: 2000 - 10*pattern[(i-2)%len(pattern)]})
+				}
+			}
+		}
+		if len(series.Points) > 0 {
+			out = append(out, series)
+		}
+	}
+	return out, nil
+}
+
+func TestCorrelateGPUMetrics(t *testing.T) {
+	setup := func(h *Handler) *mux.Router {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/gpus/{id}/correlate", h.CorrelateGPUMetrics).Methods(http.MethodGet)
+		return router
+	}
+	const path = "/api/v1/gpus/GPU-1/correlate?metrics=DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_SM_CLOCK"
+
+	w := doJSON(t, setup(NewHandler(newMockStorage(), 100, 1000)), http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	store := &seriesStorage{mockStorage: newMockStorage()}
+	router := setup(NewHandler(store, 100, 1000))
+	for _, bad := range []string{
+		"/api/v1/gpus/GPU-1/correlate?metrics=DCGM_FI_DEV_GPU_TEMP",
+		path + "&window=1y", path + "&window=720h", path + "&step=1ms",
+		path + "&max_lag=45m", path + "&aggregate=median", path + "&end_time=yesterday",
+	} {
+		w = doJSON(t, router, http.MethodGet, bad, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, bad)
+	}
+
+	w = doJSON(t, router, http.MethodGet, path+"&window=1h&max_lag=1m&end_time=2024-01-01T12:00:05Z", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp CorrelationResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "10s", resp.Step)
+	assert.Equal(t, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), store.last.Start, "start is aligned to the step")
+	assert.Equal(t, "GPU-1", store.last.UUID)
+	assert.Len(t, resp.Lags, 13)
+	assert.Equal(t, 2, resp.BestLag.Steps)
+	assert.Equal(t, "20s", resp.BestLag.Lag)
+	assert.InDelta(t, -1, resp.BestLag.Coefficient, 1e-9)
+
+	// No clock data means no correlation
+	w = doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-1/correlate?metrics=DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_POWER_USAGE", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
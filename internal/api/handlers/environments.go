@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/environment"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// SetEnvironments sets the environments requests can select. Without them
+// every request reads the handler's own storage.
+func (h *Handler) SetEnvironments(set *environment.Set) {
+	h.environments = set
+}
+
+// storeFor returns the storage of the environment selected for the request
+// ctx belongs to.
+func (h *Handler) storeFor(ctx context.Context) storage.ReadStorage {
+	if e, ok := environment.FromContext(ctx); ok && e.Store != nil {
+		return e.Store
+	}
+	return h.store
+}
+
+// EnvironmentListResponse represents the response for listing environments.
+type EnvironmentListResponse struct {
+	Data  []models.Environment `json:"data"`
+	Count int                  `json:"count" example:"2"`
+}
+
+// ListEnvironments godoc
+// @Summary      List environments
+// @Description  Lists the environments the caller may read. Select one with the X-Environment header or an /env/{name} path prefix; requests naming none read the default.
+// @Tags         system
+// @Produce      json
+// @Success      200  {object}  EnvironmentListResponse
+// @Router       /api/v1/environments [get]
+func (h *Handler) ListEnvironments(w http.ResponseWriter, r *http.Request) {
+	data := []models.Environment{}
+	if h.environments != nil {
+		for _, e := range h.environments.List(r.Context()) {
+			data = append(data, models.Environment{
+				Name:       e.Name,
+				Default:    e == h.environments.Default(),
+				Restricted: len(e.Tenants) > 0,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, EnvironmentListResponse{Data: data, Count: len(data)})
+}
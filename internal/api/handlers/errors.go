@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// ErrorCodeInfo documents one code an error response can carry in its error
+// field.
+type ErrorCodeInfo struct {
+	Code   string `json:"code" example:"query_too_broad"`
+	Status int    `json:"status" example:"400"`
+
+	// Description says when the code is returned and what a client can do
+	Description string `json:"description"`
+}
+
+// ErrorCodeListResponse represents the error code catalog.
+type ErrorCodeListResponse struct {
+	Data  []ErrorCodeInfo `json:"data"`
+	Count int             `json:"count" example:"20"`
+}
+
+// errorCodes is the catalog of codes error responses carry. Codes are stable:
+// clients branch on them, so they are added to, never renamed.
+var errorCodes = []ErrorCodeInfo{
+	{"bad_request", http.StatusBadRequest, "The request is malformed or fails validation; the message says why. Not retryable as is."},
+	{perrors.CodeInvalidRange, http.StatusBadRequest, "A time is not RFC3339, or a range ends before it starts. Fix the times and retry."},
+	{perrors.CodeQueryTooBroad, http.StatusBadRequest, "The query covers more time, or splits it into more buckets, than the endpoint allows. Narrow the window or widen the step."},
+	{"environment_unsupported", http.StatusBadRequest, "The endpoint serves only the default environment."},
+	{"unauthorized", http.StatusUnauthorized, "A valid bearer token is required."},
+	{"forbidden", http.StatusForbidden, "The token does not grant the role or environment the endpoint needs."},
+	{"not_found", http.StatusNotFound, "The resource does not exist."},
+	{"environment_not_found", http.StatusNotFound, "The environment named in the path is not configured."},
+	{"not_acceptable", http.StatusNotAcceptable, "No representation matches the Accept header."},
+	{"conflict", http.StatusConflict, "The request conflicts with the resource's state, such as an export not yet completed or data under a retention hold."},
+	{"gone", http.StatusGone, "The resource existed but has expired, such as an export artifact."},
+	{"insufficient_data", http.StatusUnprocessableEntity, "Too few points were stored to compute the result. Widen the window or coarsen the step."},
+	{"quota_exceeded", http.StatusTooManyRequests, "The tenant has used a monthly usage quota. Retry-After says when it resets."},
+	{"internal_error", http.StatusInternalServerError, "An unexpected failure. Retrying may not help."},
+	{"not_implemented", http.StatusNotImplemented, "The storage backend does not support the endpoint."},
+	{"unavailable", http.StatusServiceUnavailable, "The feature the endpoint serves is not enabled on this server."},
+	{"storage_unavailable", http.StatusServiceUnavailable, "The storage backend is unreachable or overloaded. Retry with backoff."},
+	{"maintenance", http.StatusServiceUnavailable, "The API is in maintenance mode; the message says why. Retry later."},
+}
+
+// ListErrorCodes godoc
+// @Summary      List error codes
+// @Description  Lists the codes error responses carry in their error field, with the status each is returned with. Codes are stable, so clients can branch on them instead of parsing messages.
+// @Tags         errors
+// @Produce      json
+// @Success      200  {object}  ErrorCodeListResponse
+// @Router       /api/v1/errors [get]
+func (h *Handler) ListErrorCodes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ErrorCodeListResponse{Data: errorCodes, Count: len(errorCodes)})
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListErrorCodes(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/errors", h.ListErrorCodes).Methods(http.MethodGet)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/errors", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ErrorCodeListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, len(errorCodes), resp.Count)
+	assert.Contains(t, resp.Data, ErrorCodeInfo{"query_too_broad", http.StatusBadRequest, errorCodes[2].Description})
+}
+
+// TestErrorCodesCataloged checks every code the API writes is in the catalog
+// with the status it is written with.
+func TestErrorCodesCataloged(t *testing.T) {
+	statuses := make(map[string]int)
+	for _, c := range errorCodes {
+		_, dup := statuses[c.Code]
+		require.False(t, dup, "duplicate code %s", c.Code)
+		statuses[c.Code] = c.Status
+	}
+
+	written := regexp.MustCompile(`writeError\(w, http\.Status(\w+), "([a-z_]+)"`)
+	names := map[string]int{
+		"BadRequest": 400, "Unauthorized": 401, "Forbidden": 403, "NotFound": 404, "NotAcceptable": 406,
+		"Conflict": 409, "Gone": 410, "UnprocessableEntity": 422, "TooManyRequests": 429,
+		"InternalServerError": 500, "NotImplemented": 501, "ServiceUnavailable": 503,
+	}
+	var files []string
+	for _, pattern := range []string{"*.go", "../auth/*.go", "../environment/*.go", "../../usage/*.go"} {
+		matches, err := filepath.Glob(pattern)
+		require.NoError(t, err)
+		files = append(files, matches...)
+	}
+	found := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		require.NoError(t, err)
+		for _, m := range written.FindAllStringSubmatch(string(src), -1) {
+			found++
+			status, ok := names[m[1]]
+			require.True(t, ok, "%s: add http.Status%s to the test", file, m[1])
+			assert.Equal(t, status, statuses[m[2]], "%s: code %s", file, m[2])
+		}
+	}
+	assert.Greater(t, found, 100)
+}
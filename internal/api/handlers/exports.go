@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/export"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/usage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// ExportListResponse represents the export jobs and the disk their artifacts use.
+type ExportListResponse struct {
+	Data  []models.ExportJob `json:"data"`
+	Count int                `json:"count" example:"3"`
+
+	// UsedBytes is the disk held by completed artifacts
+	UsedBytes int64 `json:"used_bytes" example:"734003200"`
+
+	// QuotaBytes caps UsedBytes; the oldest artifacts are deleted to make room
+	QuotaBytes int64 `json:"quota_bytes" example:"10737418240"`
+}
+
+// SetExports sets the store that runs export jobs.
+func (h *Handler) SetExports(exports *export.Store) {
+	h.exports = exports
+}
+
+// exportStore returns the export job store, writing a 503 if jobs are disabled.
+func (h *Handler) exportStore(w http.ResponseWriter) (*export.Store, bool) {
+	if h.exports == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Export jobs are not enabled")
+	}
+	return h.exports, h.exports != nil
+}
+
+// CreateExport godoc
+// @Summary      Start an export job
+// @Description  Queues a background export of telemetry in a time range to a CSV or JSON artifact, for exports too large for /gpus/{id}/telemetry/export. Poll the job until it completes, then download the artifact.
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        spec  body  models.ExportSpec  true  "What to export"
+// @Success      202  {object}  models.ExportJob
+// @Failure      400  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/exports [post]
+func (h *Handler) CreateExport(w http.ResponseWriter, r *http.Request) {
+	exports, ok := h.exportStore(w)
+	if !ok {
+		return
+	}
+	var spec models.ExportSpec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	job, err := exports.Create(spec)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/exports/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// ListExports godoc
+// @Summary      List export jobs
+// @Tags         exports
+// @Produce      json
+// @Success      200  {object}  ExportListResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/exports [get]
+func (h *Handler) ListExports(w http.ResponseWriter, r *http.Request) {
+	exports, ok := h.exportStore(w)
+	if !ok {
+		return
+	}
+	jobs := exports.List()
+	used, quota := exports.Usage()
+	writeJSON(w, http.StatusOK, ExportListResponse{
+		Data:       jobs,
+		Count:      len(jobs),
+		UsedBytes:  used,
+		QuotaBytes: quota,
+	})
+}
+
+// GetExport godoc
+// @Summary      Get an export job
+// @Tags         exports
+// @Produce      json
+// @Param        id  path  string  true  "Export job ID"
+// @Success      200  {object}  models.ExportJob
+// @Failure      404  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/exports/{id} [get]
+func (h *Handler) GetExport(w http.ResponseWriter, r *http.Request) {
+	exports, ok := h.exportStore(w)
+	if !ok {
+		return
+	}
+	job, err := exports.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// DeleteExport godoc
+// @Summary      Delete an export job
+// @Description  Cancels the job if it is running and deletes its artifact
+// @Tags         exports
+// @Param        id  path  string  true  "Export job ID"
+// @Success      204
+// @Failure      404  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/exports/{id} [delete]
+func (h *Handler) DeleteExport(w http.ResponseWriter, r *http.Request) {
+	exports, ok := h.exportStore(w)
+	if !ok {
+		return
+	}
+	if err := exports.Delete(mux.Vars(r)["id"]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DownloadExport godoc
+// @Summary      Download an export artifact
+// @Description  Serves a completed job's artifact. Range requests fetch part of it, so interrupted downloads resume where they stopped; send If-Range with the ETag to restart from the beginning if the artifact changed.
+// @Tags         exports
+// @Produce      plain
+// @Produce      json
+// @Param        id     path    string  true   "Export job ID"
+// @Param        Range  header  string  false  "Byte range, e.g. bytes=1048576-"
+// @Success      200  {string}  string  "The artifact"
+// @Success      206  {string}  string  "The requested range of the artifact"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      410  {object}  ErrorResponse
+// @Failure      416  {string}  string  "Range not satisfiable"
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/exports/{id}/download [get]
+func (h *Handler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	exports, ok := h.exportStore(w)
+	if !ok {
+		return
+	}
+	f, job, err := exports.Artifact(mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, export.ErrExpired):
+		writeError(w, http.StatusGone, "gone", "Export artifact has expired; start a new export")
+		return
+	case errors.Is(err, export.ErrNotReady):
+		writeError(w, http.StatusConflict, "conflict", fmt.Sprintf("Export is %s, not completed", job.Status))
+		return
+	case err != nil:
+		writeStoreError(w, err)
+		return
+	}
+	defer f.Close()
+
+	contentType := contentTypeCSV
+	if job.Spec.Format == "json" {
+		contentType = contentTypeJSON
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.Filename()))
+	// Artifacts never change, so the job ID is a strong validator
+	w.Header().Set("ETag", `"`+job.ID+`"`)
+	usage.Export(r.Context())
+	http.ServeContent(w, r, job.Filename(), *job.CompletedAt, f)
+}
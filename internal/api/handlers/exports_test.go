@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/export"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func setupExportRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/exports", h.ListExports).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/exports", h.CreateExport).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/exports/{id}", h.GetExport).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/exports/{id}", h.DeleteExport).Methods(http.MethodDelete)
+	router.HandleFunc("/api/v1/exports/{id}/download", h.DownloadExport).Methods(http.MethodGet, http.MethodHead)
+	return router
+}
+
+func TestExportJobs(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	h := NewHandler(store, 100, 1000)
+	router := setupExportRouter(h)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/exports", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	exports, err := export.Open(store, config.ExportConfig{
+		Dir:             t.TempDir(),
+		TTL:             time.Hour,
+		QuotaBytes:      1 << 20,
+		MaxRows:         1000,
+		QueueSize:       1,
+		JanitorInterval: time.Hour,
+	}, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	h.SetExports(exports)
+
+	w = doJSON(t, router, http.MethodPost, "/api/v1/exports", models.ExportSpec{UUID: "GPU-12345-AAAA"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	now := time.Now()
+	spec := models.ExportSpec{UUID: "GPU-12345-AAAA", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}
+	w = doJSON(t, router, http.MethodPost, "/api/v1/exports", spec)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var job models.ExportJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/api/v1/exports/"+job.ID, w.Header().Get("Location"))
+
+	// Not downloadable until the job has run
+	w = doJSON(t, router, http.MethodGet, "/api/v1/exports/"+job.ID+"/download", nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// The queue holds one job
+	w = doJSON(t, router, http.MethodPost, "/api/v1/exports", spec)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go exports.Run(ctx)
+	require.Eventually(t, func() bool {
+		job, _ = exports.Get(job.ID)
+		return job.Status == models.ExportCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 5, job.Rows)
+
+	path := "/api/v1/exports/" + job.ID + "/download"
+	w = doJSON(t, router, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	full := w.Body.String()
+	assert.Equal(t, job.Size, int64(len(full)))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.Equal(t, contentTypeCSV, w.Header().Get("Content-Type"))
+	etag := w.Header().Get("ETag")
+
+	// Resume from byte 100
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Range", "bytes=100-")
+	req.Header.Set("If-Range", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, full[100:], w.Body.String())
+	assert.Equal(t, fmt.Sprintf("bytes 100-%d/%d", len(full)-1, len(full)), w.Header().Get("Content-Range"))
+
+	// A stale validator restarts the download
+	req.Header.Set("If-Range", `"other"`)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, full, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", len(full)+10))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/exports", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list ExportListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Count)
+	assert.Equal(t, job.Size, list.UsedBytes)
+
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/exports/"+job.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doJSON(t, router, http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/features"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+const (
+	// defaultFeatureWindow and maxFeatureWindow bound the history sampled
+	defaultFeatureWindow = time.Hour
+	maxFeatureWindow     = 7 * 24 * time.Hour
+
+	// defaultFeatureSamples is how many samples a window has by default,
+	// and maxFeatureSamples the most it may have
+	defaultFeatureSamples = 60
+	maxFeatureSamples     = 10000
+
+	// maxFeatureMetrics is the most metrics one window may hold
+	maxFeatureMetrics = 16
+)
+
+// FeatureWindowResponse is a fixed-length window of a GPU's metrics,
+// sampled at the same times, for feeding machine learning models.
+type FeatureWindowResponse struct {
+	UUID  string    `json:"uuid" example:"GPU-5fd4f087-86f3-7a43-b711-4771313afc50"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Step is the spacing of the samples, each the aggregate of one bucket
+	Step          string `json:"step" example:"1m0s"`
+	Samples       int    `json:"samples" example:"60"`
+	Aggregate     string `json:"aggregate" example:"mean"`
+	Interpolation string `json:"interpolation" example:"linear"`
+	Normalization string `json:"normalization" example:"zscore"`
+
+	features.Window
+}
+
+// GetGPUFeatures godoc
+// @Summary      Sample a GPU's metrics for ML models
+// @Description  Returns a fixed-length window of a GPU's metrics: samples evenly spaced over the window ending at end_time, each the aggregate of one bucket, at the same times for every metric. Buckets without data are filled by interpolation and flagged in filled. Values are then optionally normalized per metric; each feature reports its mean, std, min and max before normalization so the same transform can be applied at inference.
+// @Tags         gpus
+// @Produce      json
+// @Param        id             path   string  true   "GPU UUID"
+// @Param        metrics        query  string  true   "Comma-separated metric names (at most 16)"  example(DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_POWER_USAGE)
+// @Param        window         query  string  false  "How far back from end_time to sample (default 1h, max 168h)"
+// @Param        samples        query  int     false  "Samples per metric (default 60, max 10000); window/samples must be at least 1s"
+// @Param        end_time       query  string  false  "End of the window (RFC3339, default now)"
+// @Param        aggregate      query  string  false  "Bucket aggregate: mean (default), min, max or last"
+// @Param        interpolation  query  string  false  "Gap filling: linear (default), previous or zero"
+// @Param        normalize      query  string  false  "Normalization: none (default), zscore or minmax"
+// @Param        min_coverage   query  number  false  "Smallest fraction of each metric's samples that must be measured, 0 to 1 (default 0)"
+// @Success      200  {object}  FeatureWindowResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      422  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/gpus/{id}/features [get]
+func (h *Handler) GetGPUFeatures(w http.ResponseWriter, r *http.Request) {
+	reader, ok := h.seriesReader(w, r)
+	if !ok {
+		return
+	}
+	uuid := mux.Vars(r)["id"]
+	q := r.URL.Query()
+
+	metrics := splitQueryList(q.Get("metrics"))
+	seen := make(map[string]bool, len(metrics))
+	for i, m := range metrics {
+		metrics[i] = h.aliases.Canonical(m)
+		if seen[metrics[i]] {
+			writeBadRequest(w, fmt.Errorf("metric %s is listed twice", metrics[i]))
+			return
+		}
+		seen[metrics[i]] = true
+	}
+	if len(metrics) == 0 || len(metrics) > maxFeatureMetrics {
+		writeBadRequest(w, fmt.Errorf("metrics must name 1 to %d metrics (e.g., DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_POWER_USAGE)", maxFeatureMetrics))
+		return
+	}
+	window, err := parseDuration(r, "window", defaultFeatureWindow)
+	if err == nil && window > maxFeatureWindow {
+		err = perrors.WithCode(perrors.CodeQueryTooBroad, fmt.Errorf("window must be at most %v", maxFeatureWindow))
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	samples := defaultFeatureSamples
+	if s := q.Get("samples"); s != "" {
+		samples, err = strconv.Atoi(s)
+		if err != nil || samples < 1 {
+			writeBadRequest(w, fmt.Errorf("samples must be a positive integer, got %q", s))
+			return
+		}
+	}
+	step := window / time.Duration(samples)
+	if samples > maxFeatureSamples || step < time.Second {
+		writeBadRequest(w, perrors.WithCode(perrors.CodeQueryTooBroad,
+			fmt.Errorf("samples must be at most %d and leave at least 1s between samples", maxFeatureSamples)))
+		return
+	}
+	end, err := parseEndTime(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	fn, err := parseAggregate(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	opts := features.Options{
+		Interpolation: q.Get("interpolation"),
+		Normalization: q.Get("normalize"),
+	}
+	if opts.Interpolation == "" {
+		opts.Interpolation = features.InterpolateLinear
+	}
+	if !features.IsInterpolation(opts.Interpolation) {
+		writeBadRequest(w, fmt.Errorf("interpolation must be linear, previous or zero, got %q", opts.Interpolation))
+		return
+	}
+	if opts.Normalization == "" {
+		opts.Normalization = features.NormalizeNone
+	}
+	if !features.IsNormalization(opts.Normalization) {
+		writeBadRequest(w, fmt.Errorf("normalize must be none, zscore or minmax, got %q", opts.Normalization))
+		return
+	}
+	if s := q.Get("min_coverage"); s != "" {
+		opts.MinCoverage, err = strconv.ParseFloat(s, 64)
+		if err != nil || opts.MinCoverage < 0 || opts.MinCoverage > 1 {
+			writeBadRequest(w, fmt.Errorf("min_coverage must be between 0 and 1, got %q", s))
+			return
+		}
+	}
+
+	// Samples are aligned to the step, so a window ending at the same time
+	// always samples the same buckets
+	start := end.Add(-window).Truncate(step)
+	end = start.Add(time.Duration(samples) * step)
+	series, err := reader.GetSeries(r.Context(), &models.SeriesQuery{
+		Metrics: metrics,
+		UUID:    uuid,
+		Start:   start,
+		End:     end,
+		Every:   step,
+		Fn:      fn,
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	points := make(map[string][]models.SeriesPoint, len(metrics))
+	for _, s := range series {
+		points[s.Metric] = append(points[s.Metric], s.Points...)
+	}
+
+	result, err := features.Build(points, metrics, start, step, samples, opts)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "insufficient_data", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, FeatureWindowResponse{
+		UUID:          uuid,
+		Start:         start,
+		End:           end,
+		Step:          step.String(),
+		Samples:       samples,
+		Aggregate:     fn,
+		Interpolation: opts.Interpolation,
+		Normalization: opts.Normalization,
+		Window:        result,
+	})
+}
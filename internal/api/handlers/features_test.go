@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGPUFeatures(t *testing.T) {
+	setup := func(h *Handler) *mux.Router {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/gpus/{id}/features", h.GetGPUFeatures).Methods(http.MethodGet)
+		return router
+	}
+	const path = "/api/v1/gpus/GPU-1/features?metrics=DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_SM_CLOCK"
+
+	w := doJSON(t, setup(NewHandler(newMockStorage(), 100, 1000)), http.MethodGet, path, nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	store := &seriesStorage{mockStorage: newMockStorage()}
+	router := setup(NewHandler(store, 100, 1000))
+	for _, bad := range []string{
+		"/api/v1/gpus/GPU-1/features", path + ",DCGM_FI_DEV_GPU_TEMP",
+		path + "&window=720h", path + "&samples=0", path + "&window=1m&samples=120",
+		path + "&interpolation=spline", path + "&normalize=log", path + "&min_coverage=2",
+		path + "&aggregate=median", path + "&end_time=yesterday",
+	} {
+		w = doJSON(t, router, http.MethodGet, bad, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, bad)
+	}
+
+	w = doJSON(t, router, http.MethodGet, path+"&window=1m&samples=6&normalize=minmax&end_time=2024-01-01T12:00:05Z", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp FeatureWindowResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "10s", resp.Step)
+	assert.Equal(t, time.Date(2024, 1, 1, 11, 59, 0, 0, time.UTC), store.last.Start, "start is aligned to the step")
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), resp.End)
+	assert.Equal(t, "linear", resp.Interpolation)
+	assert.Len(t, resp.Times, 6)
+	require.Len(t, resp.Features, 2)
+
+	temp, clock := resp.Features[0], resp.Features[1]
+	assert.Equal(t, "DCGM_FI_DEV_GPU_TEMP", temp.Metric)
+	assert.Equal(t, []float64{0, 0.6, 0.25, 1, 0.1, 0.85}, temp.Values)
+	assert.Equal(t, 1.0, temp.Coverage)
+	assert.Equal(t, 60.0, temp.Min)
+	assert.Equal(t, 80.0, temp.Max)
+
+	// The clock has no data for the first two buckets
+	assert.Equal(t, []bool{true, true, false, false, false, false}, clock.Filled)
+	assert.InDelta(t, 4.0/6, clock.Coverage, 1e-9)
+	assert.Equal(t, clock.Values[2], clock.Values[0])
+
+	w = doJSON(t, router, http.MethodGet, path+"&window=1m&samples=6&min_coverage=0.9", nil)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "DCGM_FI_DEV_SM_CLOCK")
+}
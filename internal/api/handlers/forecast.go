@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/forecast"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+const (
+	// defaultForecastDays and maxForecastDays bound how far ahead to project
+	defaultForecastDays = 30
+	maxForecastDays     = 365
+
+	// defaultForecastHistory and maxForecastHistory bound how many days of
+	// history the trend is fitted to
+	defaultForecastHistory = 90
+	maxForecastHistory     = 730
+)
+
+// day is the forecast's step; history is read in whole UTC days.
+const day = 24 * time.Hour
+
+// ForecastSeries is one metric's daily history and projection.
+type ForecastSeries struct {
+	Metric string `json:"metric" example:"DCGM_FI_DEV_GPU_UTIL"`
+	Unit   string `json:"unit" example:"percent"`
+
+	// GPUs is the number of GPUs that reported on the last day of history
+	GPUs int `json:"gpus" example:"256"`
+
+	History  []forecast.Point      `json:"history"`
+	Trend    *forecast.Trend       `json:"trend,omitempty"`
+	Forecast []forecast.Projection `json:"forecast"`
+
+	// Error explains a missing forecast, e.g. too little history
+	Error string `json:"error,omitempty"`
+}
+
+// ForecastResponse projects fleet utilization and energy.
+type ForecastResponse struct {
+	GeneratedAt  time.Time `json:"generated_at"`
+	HistoryStart time.Time `json:"history_start"`
+	HistoryEnd   time.Time `json:"history_end"`
+	Days         int       `json:"days" example:"30"`
+
+	// Utilization is the fleet's average GPU utilization per day
+	Utilization ForecastSeries `json:"utilization"`
+
+	// Energy is the fleet's energy use per day, from average power draw
+	Energy ForecastSeries `json:"energy"`
+}
+
+// fleetSeriesReader returns the storage as a FleetSeriesReader, writing 501 if unsupported.
+func (h *Handler) fleetSeriesReader(w http.ResponseWriter, r *http.Request) (storage.FleetSeriesReader, bool) {
+	reader, ok := h.storeFor(r.Context()).(storage.FleetSeriesReader)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support forecasts")
+	}
+	return reader, ok
+}
+
+// parseDays parses a positive whole number of days no larger than max.
+func parseDays(r *http.Request, name string, def, max int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("%s must be a number of days between 1 and %d", name, max)
+	}
+	return n, nil
+}
+
+// forecastSeries fits a trend to daily values and projects it.
+func forecastSeries(metric, unit string, fleet []models.FleetPoint, value func(models.FleetPoint) float64, days int) ForecastSeries {
+	series := ForecastSeries{
+		Metric:   metric,
+		Unit:     unit,
+		History:  make([]forecast.Point, 0, len(fleet)),
+		Forecast: make([]forecast.Projection, 0),
+	}
+	if len(fleet) > 0 {
+		series.GPUs = fleet[len(fleet)-1].GPUs
+	}
+	for _, p := range fleet {
+		series.History = append(series.History, forecast.Point{Time: p.Time, Value: value(p)})
+	}
+	trend, projections, err := forecast.Linear(series.History, day, days, true)
+	if err != nil {
+		series.Error = err.Error()
+		return series
+	}
+	series.Trend, series.Forecast = &trend, projections
+	return series
+}
+
+// GetForecast godoc
+// @Summary      Forecast fleet utilization and energy
+// @Description  Fits a linear trend to the fleet's daily average GPU utilization (percent) and daily energy use (kWh, from average power draw) over the last history days, and projects both days ahead with approximate 95% intervals. Days with no data are left out of the fit. A series with fewer than 3 days of history has no forecast and sets error.
+// @Tags         forecast
+// @Produce      json
+// @Param        days     query  int  false  "Days to project (default 30, max 365)"
+// @Param        history  query  int  false  "Days of history to fit (default 90, max 730)"
+// @Success      200  {object}  ForecastResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/forecast [get]
+func (h *Handler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	reader, ok := h.fleetSeriesReader(w, r)
+	if !ok {
+		return
+	}
+	days, err := parseDays(r, "days", defaultForecastDays, maxForecastDays)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	history, err := parseDays(r, "history", defaultForecastHistory, maxForecastHistory)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	// Today is incomplete, so history ends at the start of the UTC day
+	now := time.Now().UTC()
+	end := now.Truncate(day)
+	start := end.Add(-time.Duration(history) * day)
+
+	utilization, err := reader.FleetSeries(r.Context(), models.MetricGPUUtil, start, end, day)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	power, err := reader.FleetSeries(r.Context(), models.MetricPowerUsage, start, end, day)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ForecastResponse{
+		GeneratedAt:  now,
+		HistoryStart: start,
+		HistoryEnd:   end,
+		Days:         days,
+		Utilization: forecastSeries(models.MetricGPUUtil, "percent", utilization,
+			func(p models.FleetPoint) float64 { return p.Mean }, days),
+		// A day at the fleet's average draw of Sum watts uses Sum*24 Wh
+		Energy: forecastSeries(models.MetricPowerUsage, "kWh", power,
+			func(p models.FleetPoint) float64 { return p.Sum * 24 / 1000 }, days),
+	})
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// fleetStorage adds a storage.FleetSeriesReader to mockStorage. Utilization
+// rises one point a day; power has only two days of history.
+type fleetStorage struct {
+	*mockStorage
+}
+
+func (s *fleetStorage) FleetSeries(ctx context.Context, metric string, start, end time.Time, every time.Duration) ([]models.FleetPoint, error) {
+	points := make([]models.FleetPoint, 0)
+	for t, i := start, 0; t.Before(end); t, i = t.Add(every), i+1 {
+		if metric == models.MetricPowerUsage && i >= 2 {
+			break
+		}
+		points = append(points, models.FleetPoint{Time: t, Mean: 50 + float64(i), Sum: 1000, GPUs: 4})
+	}
+	return points, nil
+}
+
+func TestGetForecast(t *testing.T) {
+	setup := func(h *Handler) *mux.Router {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/forecast", h.GetForecast).Methods(http.MethodGet)
+		return router
+	}
+
+	w := doJSON(t, setup(NewHandler(newMockStorage(), 100, 1000)), http.MethodGet, "/api/v1/forecast", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	router := setup(NewHandler(&fleetStorage{newMockStorage()}, 100, 1000))
+	for _, bad := range []string{"?days=0", "?days=x", "?history=1000"} {
+		w = doJSON(t, router, http.MethodGet, "/api/v1/forecast"+bad, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, bad)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/forecast?days=7&history=10", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ForecastResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 7, resp.Days)
+	assert.Equal(t, 10*24*time.Hour, resp.HistoryEnd.Sub(resp.HistoryStart))
+
+	util := resp.Utilization
+	require.Len(t, util.History, 10)
+	require.Len(t, util.Forecast, 7)
+	require.NotNil(t, util.Trend)
+	assert.InDelta(t, 1, util.Trend.Slope, 1e-9)
+	assert.InDelta(t, 66, util.Forecast[6].Value, 1e-9)
+	assert.Equal(t, 4, util.GPUs)
+
+	// 1000 W for a day is 24 kWh; two days are too few to forecast
+	energy := resp.Energy
+	require.Len(t, energy.History, 2)
+	assert.InDelta(t, 24, energy.History[0].Value, 1e-9)
+	assert.Empty(t, energy.Forecast)
+	assert.NotEmpty(t, energy.Error)
+}
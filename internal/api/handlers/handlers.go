@@ -2,21 +2,59 @@
 package handlers
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/environment"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/baseline"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/export"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/maintenance"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/replay"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/slo"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/summary"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/usage"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
 )
 
 // Handler handles GPU telemetry API requests.
 type Handler struct {
 	store        storage.ReadStorage
+	environments *environment.Set
+	latest       *cache.Latest
+	webhooks     *notify.Dispatcher
+	replayer     *replay.Replayer
+	alerts       *alert.Evaluator
+	alertRules   *alert.RuleSet
+	baselines    *baseline.Profiler
+	slo          *slo.Tracker
+	topology     *topology.Source
+	summary      *summary.Summarizer
+	exports      *export.Store
+	usage        *usage.Meter
+	logs         *logging.Runtime
+	maintenance  *maintenance.Mode
+	bundleKey    []byte
+	support      *support.Source
+	aliases      models.MetricAliases
+	deliveries   models.DeliveryAllowlist
 	defaultLimit int
 	maxLimit     int
 }
@@ -30,6 +68,27 @@ func NewHandler(store storage.ReadStorage, defaultLimit, maxLimit int) *Handler
 	}
 }
 
+// SetLatestCache sets the latest-values cache that backs the snapshot endpoint.
+func (h *Handler) SetLatestCache(latest *cache.Latest) {
+	h.latest = latest
+}
+
+// SetMetricAliases sets the alternate metric names requests may use. They
+// are answered under the canonical name telemetry is stored with.
+func (h *Handler) SetMetricAliases(aliases models.MetricAliases) {
+	h.aliases = aliases
+}
+
+// SetWebhooks sets the event dispatcher whose delivery log is served.
+func (h *Handler) SetWebhooks(webhooks *notify.Dispatcher) {
+	h.webhooks = webhooks
+}
+
+// SetReplayer sets the replayer used to re-ingest batches.
+func (h *Handler) SetReplayer(replayer *replay.Replayer) {
+	h.replayer = replayer
+}
+
 // ErrorResponse represents an error response.
 type ErrorResponse struct {
 	Error   string `json:"error" example:"internal_error"`
@@ -44,8 +103,22 @@ type GPUListResponse struct {
 
 // TelemetryResponse represents the response for telemetry queries.
 type TelemetryResponse struct {
-	Data  []*models.GPUMetric `json:"data"`
-	Count int                 `json:"count" example:"100"`
+	Data        []*models.GPUMetric  `json:"data"`
+	Count       int                  `json:"count" example:"100"`
+	Annotations []*models.Annotation `json:"annotations,omitempty"`
+}
+
+// WriteCSV writes the telemetry as CSV rows, one per data point.
+func (resp TelemetryResponse) WriteCSV(w io.Writer) error {
+	models.WriteMetricsCSV(w, resp.Data)
+	return nil
+}
+
+// ExplainResponse describes how a telemetry query was executed, returned
+// instead of data when ?explain=true is set.
+type ExplainResponse struct {
+	Request *models.TelemetryQuery `json:"request"`
+	Plan    *storage.QueryPlan     `json:"plan"`
 }
 
 // writeJSON writes a JSON response.
@@ -63,18 +136,100 @@ func writeError(w http.ResponseWriter, status int, err string, message string) {
 	})
 }
 
+// writeBadRequest writes a 400 response for an invalid request, with the
+// error's code if it carries one.
+func writeBadRequest(w http.ResponseWriter, err error) {
+	code := perrors.CodeOf(err)
+	if code == "" {
+		code = "bad_request"
+	}
+	writeError(w, http.StatusBadRequest, code, err.Error())
+}
+
+// writeStoreError writes an error response for a storage failure, choosing the
+// status code from the error's classification and the code from the error, or
+// else from the status.
+func writeStoreError(w http.ResponseWriter, err error) {
+	status := perrors.HTTPStatus(err)
+	code := perrors.CodeOf(err)
+	switch {
+	case code != "":
+	case status == http.StatusBadRequest:
+		code = "bad_request"
+	case status == http.StatusNotFound:
+		code = "not_found"
+	case status == http.StatusServiceUnavailable:
+		code = "storage_unavailable"
+	default:
+		code = "internal_error"
+	}
+	writeError(w, status, code, err.Error())
+}
+
+// parseExplain reports whether the request asked for a query plan.
+func parseExplain(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("explain")
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// writeExplain runs query through the storage backend's explainer and writes the plan.
+func (h *Handler) writeExplain(w http.ResponseWriter, r *http.Request, query *models.TelemetryQuery) {
+	explainer, ok := h.storeFor(r.Context()).(storage.QueryExplainer)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support explain")
+		return
+	}
+
+	plan, err := explainer.ExplainTelemetry(r.Context(), query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ExplainResponse{Request: query, Plan: plan})
+}
+
+// GPUDetailsResponse represents the response for listing GPUs with details.
+type GPUDetailsResponse struct {
+	Data  []*models.GPUInfo `json:"data"`
+	Count int               `json:"count" example:"100"`
+	Total int               `json:"total" example:"256"`
+}
+
 // ListGPUs godoc
 // @Summary      List all GPUs
-// @Description  Returns a list of all GPUs for which telemetry data is available
+// @Description  Returns a list of all GPUs for which telemetry data is available. With details=true, returns full GPU info objects, filterable and paginated.
 // @Tags         gpus
 // @Produce      json
+// @Param        details   query  bool    false  "Return GPU info objects instead of UUIDs"
+// @Param        hostname  query  string  false  "Filter by hostname (details only)"
+// @Param        model     query  string  false  "Filter by model name substring, case-insensitive (details only)"
+// @Param        limit     query  int     false  "Maximum results (details only)"  default(100)
+// @Param        offset    query  int     false  "Offset for pagination (details only)"  default(0)
 // @Success      200  {object}  GPUListResponse
+// @Success      200  {object}  GPUDetailsResponse
+// @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/v1/gpus [get]
 func (h *Handler) ListGPUs(w http.ResponseWriter, r *http.Request) {
-	gpus, err := h.store.GetGPUs(r.Context())
+	if details := r.URL.Query().Get("details"); details != "" {
+		detailed, err := strconv.ParseBool(details)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid details parameter")
+			return
+		}
+		if detailed {
+			h.listGPUDetails(w, r)
+			return
+		}
+	}
+
+	gpus, err := h.storeFor(r.Context()).GetGPUs(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		writeStoreError(w, err)
 		return
 	}
 
@@ -84,11 +239,115 @@ func (h *Handler) ListGPUs(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// listGPUDetails serves ListGPUs with details=true.
+func (h *Handler) listGPUDetails(w http.ResponseWriter, r *http.Request) {
+	limit := h.defaultLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid limit parameter")
+			return
+		}
+		limit = min(l, h.maxLimit)
+	}
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		o, err := strconv.Atoi(offsetStr)
+		if err != nil || o < 0 {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid offset parameter")
+			return
+		}
+		offset = o
+	}
+	hostname := r.URL.Query().Get("hostname")
+	model := strings.ToLower(r.URL.Query().Get("model"))
+
+	infos, err := h.gpuInfos(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	matched := make([]*models.GPUInfo, 0, len(infos))
+	for _, info := range infos {
+		if hostname != "" && info.Hostname != hostname {
+			continue
+		}
+		if model != "" && !strings.Contains(strings.ToLower(info.ModelName), model) {
+			continue
+		}
+		matched = append(matched, info)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].UUID < matched[j].UUID })
+
+	page := matched[min(offset, len(matched)):]
+	page = page[:min(limit, len(page))]
+
+	writeJSON(w, http.StatusOK, GPUDetailsResponse{
+		Data:  page,
+		Count: len(page),
+		Total: len(matched),
+	})
+}
+
+// gpuInfos returns info for every GPU, in one call when the backend supports
+// storage.GPUInfoReader and by sampling each GPU's telemetry otherwise.
+func (h *Handler) gpuInfos(ctx context.Context) ([]*models.GPUInfo, error) {
+	if reader, ok := h.storeFor(ctx).(storage.GPUInfoReader); ok {
+		return reader.GetGPUInfos(ctx)
+	}
+
+	gpus, err := h.storeFor(ctx).GetGPUs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*models.GPUInfo, 0, len(gpus))
+	for _, uuid := range gpus {
+		info, err := h.lookupGPUInfo(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// lookupGPUInfo derives a GPU's info from a sample of its most recent
+// telemetry. It returns nil if the GPU has no telemetry.
+func (h *Handler) lookupGPUInfo(ctx context.Context, uuid string) (*models.GPUInfo, error) {
+	metrics, err := h.storeFor(ctx).GetTelemetry(ctx, &models.TelemetryQuery{
+		UUID:  uuid,
+		Limit: 1000, // Large enough sample to find the oldest point
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+
+	// Metrics are sorted descending, so first is newest, last is oldest
+	newest := metrics[0]
+	return &models.GPUInfo{
+		UUID:      newest.UUID,
+		GPUID:     newest.GPUID,
+		Device:    newest.Device,
+		ModelName: newest.ModelName,
+		Hostname:  newest.Hostname,
+		FirstSeen: metrics[len(metrics)-1].Timestamp,
+		LastSeen:  newest.Timestamp,
+	}, nil
+}
+
 // GetGPUTelemetry godoc
 // @Summary      Get GPU telemetry
-// @Description  Returns all telemetry entries for a specific GPU, ordered by time
+// @Description  Returns all telemetry entries for a specific GPU, ordered by time, with annotations overlapping the returned range. The Accept header selects JSON (default), MessagePack (same fields) or CSV (data points only).
 // @Tags         gpus
 // @Produce      json
+// @Produce      application/msgpack
+// @Produce      text/csv
 // @Param        id          path      string  true   "GPU UUID"
 // @Param        start_time  query     string  false  "Start time filter (RFC3339)"  example(2024-01-01T00:00:00Z)
 // @Param        end_time    query     string  false  "End time filter (RFC3339)"    example(2024-01-02T00:00:00Z)
@@ -97,10 +356,16 @@ func (h *Handler) ListGPUs(w http.ResponseWriter, r *http.Request) {
 // @Success      200  {object}  TelemetryResponse
 // @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/v1/gpus/{id}/telemetry [get]
 // @Param        metric_name query string false "Metric name filter (e.g., DCGM_FI_DEV_GPU_UTIL)"
 // @Param        hostname    query string false "Hostname filter"
 // @Param        gpu_id      query int    false "GPU ID filter"
+// @Param        filter      query string false "Saved filter name; its GPUs, hosts, metrics and labels narrow the query"
+// @Param        explain     query bool   false "Return the query plan and timing instead of data"
+// @Failure      404  {object}  ErrorResponse
+// @Failure      406  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
 func (h *Handler) GetGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	gpuID := vars["id"]
@@ -108,6 +373,10 @@ func (h *Handler) GetGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "bad_request", "GPU ID is required")
 		return
 	}
+	contentType, ok := acceptable(w, r, offers(TelemetryResponse{}))
+	if !ok {
+		return
+	}
 	query := &models.TelemetryQuery{
 		UUID:   gpuID,
 		Limit:  h.defaultLimit,
@@ -117,7 +386,7 @@ func (h *Handler) GetGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 	if startTimeStr := r.URL.Query().Get("start_time"); startTimeStr != "" {
 		startTime, err := time.Parse(time.RFC3339, startTimeStr)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "bad_request", "Invalid start_time format. Use RFC3339 (e.g., 2024-01-01T00:00:00Z)")
+			writeError(w, http.StatusBadRequest, "invalid_range", "Invalid start_time format. Use RFC3339 (e.g., 2024-01-01T00:00:00Z)")
 			return
 		}
 		query.StartTime = &startTime
@@ -126,7 +395,7 @@ func (h *Handler) GetGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 	if endTimeStr := r.URL.Query().Get("end_time"); endTimeStr != "" {
 		endTime, err := time.Parse(time.RFC3339, endTimeStr)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "bad_request", "Invalid end_time format. Use RFC3339 (e.g., 2024-01-02T00:00:00Z)")
+			writeError(w, http.StatusBadRequest, "invalid_range", "Invalid end_time format. Use RFC3339 (e.g., 2024-01-02T00:00:00Z)")
 			return
 		}
 		query.EndTime = &endTime
@@ -154,7 +423,7 @@ func (h *Handler) GetGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 	}
 	// Parse metric_name
 	if metricName := r.URL.Query().Get("metric_name"); metricName != "" {
-		query.MetricName = metricName
+		query.MetricName = h.aliases.Canonical(metricName)
 	}
 	// Parse hostname
 	if hostname := r.URL.Query().Get("hostname"); hostname != "" {
@@ -169,14 +438,35 @@ func (h *Handler) GetGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 		}
 		query.GPUID = &gpuIDVal
 	}
-	metrics, err := h.store.GetTelemetry(r.Context(), query)
+	// Apply filter
+	filter, ok := h.savedFilter(w, r)
+	if !ok {
+		return
+	}
+	if filter != nil && !filter.Apply(query) {
+		writeAs(w, contentType, http.StatusOK, TelemetryResponse{Data: []*models.GPUMetric{}})
+		return
+	}
+	// Parse explain
+	explain, err := parseExplain(r)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid explain parameter")
 		return
 	}
-	writeJSON(w, http.StatusOK, TelemetryResponse{
-		Data:  metrics,
-		Count: len(metrics),
+	if explain {
+		h.writeExplain(w, r, query)
+		return
+	}
+	metrics, err := h.storeFor(r.Context()).GetTelemetry(r.Context(), query)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	usage.AddRows(r.Context(), len(metrics))
+	writeAs(w, contentType, http.StatusOK, TelemetryResponse{
+		Data:        metrics,
+		Count:       len(metrics),
+		Annotations: h.overlappingAnnotations(r.Context(), query, metrics),
 	})
 }
 
@@ -195,6 +485,7 @@ type MetricNamesResponse struct {
 // @Success      200  {object}  MetricNamesResponse
 // @Failure      400  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/v1/gpus/{id}/metrics [get]
 func (h *Handler) ListMetricNames(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -208,9 +499,9 @@ func (h *Handler) ListMetricNames(w http.ResponseWriter, r *http.Request) {
 		UUID:  gpuID,
 		Limit: 1000,
 	}
-	metrics, err := h.store.GetTelemetry(r.Context(), query)
+	metrics, err := h.storeFor(r.Context()).GetTelemetry(r.Context(), query)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		writeStoreError(w, err)
 		return
 	}
 	metricSet := make(map[string]struct{})
@@ -248,6 +539,7 @@ type GPUInfoResponse struct {
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/v1/gpus/{id} [get]
 func (h *Handler) GetGPUInfo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -257,48 +549,24 @@ func (h *Handler) GetGPUInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get telemetry to extract GPU info (get latest first)
-	query := &models.TelemetryQuery{
-		UUID:  gpuID,
-		Limit: 1,
-	}
-	metrics, err := h.store.GetTelemetry(r.Context(), query)
+	gpu, err := h.lookupGPUInfo(r.Context(), gpuID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		writeStoreError(w, err)
 		return
 	}
-
-	if len(metrics) == 0 {
+	if gpu == nil {
 		writeError(w, http.StatusNotFound, "not_found", "GPU not found")
 		return
 	}
 
-	// Get a larger sample to find first and last timestamps
-	allQuery := &models.TelemetryQuery{
-		UUID:  gpuID,
-		Limit: 1000, // Get more to find oldest
-	}
-	allMetrics, _ := h.store.GetTelemetry(r.Context(), allQuery)
-
-	var firstSeen, lastSeen time.Time
-	if len(allMetrics) > 0 {
-		// Metrics are sorted descending, so first is newest, last is oldest
-		lastSeen = allMetrics[0].Timestamp
-		firstSeen = allMetrics[len(allMetrics)-1].Timestamp
-	} else if len(metrics) > 0 {
-		// Fallback to single metric
-		lastSeen = metrics[0].Timestamp
-		firstSeen = metrics[0].Timestamp
-	}
-
 	info := GPUInfoResponse{
-		UUID:      metrics[0].UUID,
-		GPUID:     metrics[0].GPUID,
-		Device:    metrics[0].Device,
-		ModelName: metrics[0].ModelName,
-		Hostname:  metrics[0].Hostname,
-		FirstSeen: firstSeen,
-		LastSeen:  lastSeen,
+		UUID:      gpu.UUID,
+		GPUID:     gpu.GPUID,
+		Device:    gpu.Device,
+		ModelName: gpu.ModelName,
+		Hostname:  gpu.Hostname,
+		FirstSeen: gpu.FirstSeen,
+		LastSeen:  gpu.LastSeen,
 	}
 
 	writeJSON(w, http.StatusOK, info)
@@ -310,20 +578,54 @@ type StatsResponse struct {
 	TotalMetrics int64     `json:"total_metrics,omitempty"`
 	OldestMetric time.Time `json:"oldest_metric,omitempty"`
 	NewestMetric time.Time `json:"newest_metric,omitempty"`
+
+	// GPUsByModel counts the GPUs of each model; from the fleet summary only
+	GPUsByModel map[string]int `json:"gpus_by_model,omitempty"`
+
+	// DailyVolume counts the points stored per UTC day over the last
+	// API_SUMMARY_DAYS days, oldest first; from the fleet summary only
+	DailyVolume []models.DailyVolume `json:"daily_volume,omitempty"`
+
+	// SummarizedAt is when the fleet summary was computed; zero when the
+	// statistics were read from storage for this request
+	SummarizedAt time.Time `json:"summarized_at,omitempty"`
+}
+
+// SetSummary sets the summarizer whose fleet summary /api/v1/stats serves
+// for the default environment.
+func (h *Handler) SetSummary(summarizer *summary.Summarizer) {
+	h.summary = summarizer
 }
 
 // GetStats godoc
 // @Summary      Get system statistics
-// @Description  Returns overall system statistics about GPUs and telemetry data
+// @Description  Returns overall statistics about GPUs and telemetry data. With the fleet summary enabled (API_SUMMARY_ENABLED, the default) they are served from memory, recomputed every API_SUMMARY_REFRESH over all stored telemetry, and include GPUs per model and points stored per day. Other environments, and the default one until the first summary is computed, get GPU counts from storage instead.
 // @Tags         system
 // @Produce      json
 // @Success      200  {object}  StatsResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/v1/stats [get]
 func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
-	gpus, err := h.store.GetGPUs(r.Context())
+	store := h.storeFor(r.Context())
+	if h.summary != nil && store == h.store {
+		if fleet, at := h.summary.Summary(); fleet != nil {
+			writeJSON(w, http.StatusOK, StatsResponse{
+				TotalGPUs:    fleet.TotalGPUs,
+				TotalMetrics: fleet.TotalMetrics,
+				OldestMetric: fleet.OldestMetric,
+				NewestMetric: fleet.NewestMetric,
+				GPUsByModel:  fleet.GPUsByModel,
+				DailyVolume:  fleet.Daily,
+				SummarizedAt: at,
+			})
+			return
+		}
+	}
+
+	gpus, err := store.GetGPUs(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		writeStoreError(w, err)
 		return
 	}
 
@@ -333,7 +635,7 @@ func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// If store implements Stats() method, get detailed stats
-	if statsStore, ok := h.store.(interface{ Stats() storage.StorageStats }); ok {
+	if statsStore, ok := store.(interface{ Stats() storage.StorageStats }); ok {
 		storageStats := statsStore.Stats()
 		stats.TotalMetrics = storageStats.TotalMetrics
 		stats.OldestMetric = storageStats.OldestMetric
@@ -356,11 +658,12 @@ type AllMetricsResponse struct {
 // @Produce      json
 // @Success      200  {object}  AllMetricsResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/v1/metrics [get]
 func (h *Handler) ListAllMetrics(w http.ResponseWriter, r *http.Request) {
-	gpus, err := h.store.GetGPUs(r.Context())
+	gpus, err := h.storeFor(r.Context()).GetGPUs(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		writeStoreError(w, err)
 		return
 	}
 
@@ -371,7 +674,7 @@ func (h *Handler) ListAllMetrics(w http.ResponseWriter, r *http.Request) {
 			UUID:  gpuID,
 			Limit: 100,
 		}
-		metrics, err := h.store.GetTelemetry(r.Context(), query)
+		metrics, err := h.storeFor(r.Context()).GetTelemetry(r.Context(), query)
 		if err != nil {
 			continue
 		}
@@ -393,19 +696,26 @@ func (h *Handler) ListAllMetrics(w http.ResponseWriter, r *http.Request) {
 
 // ExportGPUTelemetry godoc
 // @Summary      Export GPU telemetry data
-// @Description  Exports telemetry data for a specific GPU in CSV or JSON format
+// @Description  Exports telemetry data for a specific GPU in CSV, JSON or MessagePack format, chosen by the format parameter or else the Accept header
 // @Tags         gpus
 // @Produce      plain
 // @Produce      json
+// @Produce      application/msgpack
 // @Param        id          path      string  true   "GPU UUID"
-// @Param        format      query     string  false  "Output format (csv or json)"    default(json)  enum(csv,json)
+// @Param        format      query     string  false  "Output format, overriding the Accept header"  enum(csv,json,msgpack)
 // @Param        start_time  query     string  false  "Start time filter (RFC3339)"  example(2024-01-01T00:00:00Z)
 // @Param        end_time    query     string  false  "End time filter (RFC3339)"    example(2024-01-02T00:00:00Z)
 // @Param        limit       query     int     false  "Maximum results"              default(10000)
 // @Param        offset      query     int     false  "Offset for pagination"        default(0)
+// @Param        filter      query     string  false  "Saved filter name; its GPUs, hosts, metrics and labels narrow the export"
+// @Param        explain     query     bool    false  "Return the query plan and timing instead of data"
 // @Success      200  {string}    string  "Telemetry data in specified format"
 // @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      406  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/v1/gpus/{id}/telemetry/export [get]
 func (h *Handler) ExportGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -415,12 +725,21 @@ func (h *Handler) ExportGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	format := r.URL.Query().Get("format")
-	if format == "" {
-		format = "json" // Default format
-	}
-	if format != "csv" && format != "json" {
-		writeError(w, http.StatusBadRequest, "bad_request", "Invalid format. Must be 'csv' or 'json'")
+	var contentType string
+	switch format := r.URL.Query().Get("format"); format {
+	case "csv":
+		contentType = contentTypeCSV
+	case "json":
+		contentType = contentTypeJSON
+	case "msgpack":
+		contentType = contentTypeMsgpack
+	case "":
+		var ok bool
+		if contentType, ok = acceptable(w, r, offers(TelemetryResponse{})); !ok {
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid format. Must be 'csv', 'json' or 'msgpack'")
 		return
 	}
 
@@ -434,7 +753,7 @@ func (h *Handler) ExportGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 	if startTimeStr := r.URL.Query().Get("start_time"); startTimeStr != "" {
 		startTime, err := time.Parse(time.RFC3339, startTimeStr)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "bad_request", "Invalid start_time format. Use RFC3339 (e.g., 2024-01-01T00:00:00Z)")
+			writeError(w, http.StatusBadRequest, "invalid_range", "Invalid start_time format. Use RFC3339 (e.g., 2024-01-01T00:00:00Z)")
 			return
 		}
 		query.StartTime = &startTime
@@ -443,7 +762,7 @@ func (h *Handler) ExportGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 	if endTimeStr := r.URL.Query().Get("end_time"); endTimeStr != "" {
 		endTime, err := time.Parse(time.RFC3339, endTimeStr)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "bad_request", "Invalid end_time format. Use RFC3339 (e.g., 2024-01-02T00:00:00Z)")
+			writeError(w, http.StatusBadRequest, "invalid_range", "Invalid end_time format. Use RFC3339 (e.g., 2024-01-02T00:00:00Z)")
 			return
 		}
 		query.EndTime = &endTime
@@ -466,40 +785,195 @@ func (h *Handler) ExportGPUTelemetry(w http.ResponseWriter, r *http.Request) {
 		}
 		query.Offset = offset
 	}
+	// Apply filter
+	filter, ok := h.savedFilter(w, r)
+	if !ok {
+		return
+	}
+	if filter != nil && !filter.Apply(query) {
+		writeAs(w, contentType, http.StatusOK, TelemetryResponse{Data: []*models.GPUMetric{}})
+		return
+	}
+	// Parse explain
+	explain, err := parseExplain(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid explain parameter")
+		return
+	}
+	if explain {
+		h.writeExplain(w, r, query)
+		return
+	}
 
-	metrics, err := h.store.GetTelemetry(r.Context(), query)
+	metrics, err := h.storeFor(r.Context()).GetTelemetry(r.Context(), query)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		writeStoreError(w, err)
 		return
 	}
 
-	if format == "csv" {
-		w.Header().Set("Content-Type", "text/csv")
+	usage.AddRows(r.Context(), len(metrics))
+	usage.Export(r.Context())
+	if contentType == contentTypeCSV {
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"telemetry-%s.csv\"", gpuID))
+	}
+	writeAs(w, contentType, http.StatusOK, TelemetryResponse{
+		Data:  metrics,
+		Count: len(metrics),
+	})
+}
 
-		// Write CSV header
-		fmt.Fprintf(w, "Timestamp,MetricName,GPUID,Device,UUID,ModelName,Hostname,Container,Pod,Namespace,Value\n")
-		// Write CSV data
-		for _, m := range metrics {
-			fmt.Fprintf(w, "%s,%s,%d,%s,%s,%s,%s,%s,%s,%s,%.2f\n",
-				m.Timestamp.Format(time.RFC3339),
-				m.MetricName,
-				m.GPUID,
-				m.Device,
-				m.UUID,
-				m.ModelName,
-				m.Hostname,
-				m.Container,
-				m.Pod,
-				m.Namespace,
-				m.Value,
-			)
+// SnapshotResponse represents the latest value of every metric for every GPU.
+type SnapshotResponse struct {
+	Data  []cache.GPUSnapshot `json:"data"`
+	Count int                 `json:"count" example:"256"`
+	Cache cache.Status        `json:"cache"`
+}
+
+// WriteCSV writes the snapshot as CSV rows, one per GPU and metric.
+func (resp SnapshotResponse) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Hostname", "UUID", "GPUID", "Device", "ModelName", "MetricName", "Value", "Timestamp"})
+	for _, gpu := range resp.Data {
+		names := make([]string, 0, len(gpu.Metrics))
+		for name := range gpu.Metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m := gpu.Metrics[name]
+			cw.Write([]string{
+				gpu.Hostname,
+				gpu.UUID,
+				strconv.Itoa(gpu.GPUID),
+				gpu.Device,
+				gpu.ModelName,
+				name,
+				strconv.FormatFloat(m.Value, 'f', -1, 64),
+				m.Timestamp.Format(time.RFC3339Nano),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// GetSnapshot godoc
+// @Summary      Get latest values for all GPUs
+// @Description  Returns the most recent value of every metric for every GPU from the in-memory cache, without querying storage. The Accept header selects JSON (default), MessagePack (same fields) or CSV (one row per GPU and metric).
+// @Tags         gpus
+// @Produce      json
+// @Produce      application/msgpack
+// @Produce      text/csv
+// @Param        hostname     query  string  false  "Filter by hostname"
+// @Param        metric_name  query  string  false  "Only include this metric"
+// @Success      200  {object}  SnapshotResponse
+// @Failure      406  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/snapshot [get]
+func (h *Handler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	if h.latest == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Latest-values cache is disabled")
+		return
+	}
+	contentType, ok := acceptable(w, r, offers(SnapshotResponse{}))
+	if !ok {
+		return
+	}
+
+	data := h.filterSnapshots(r, h.latest.Snapshot())
+	writeAs(w, contentType, http.StatusOK, SnapshotResponse{
+		Data:  data,
+		Count: len(data),
+		Cache: h.latest.Status(),
+	})
+}
+
+// filterSnapshots applies the hostname and metric_name parameters to
+// snapshots and meters the rows returned.
+func (h *Handler) filterSnapshots(r *http.Request, snapshots []cache.GPUSnapshot) []cache.GPUSnapshot {
+	hostname := r.URL.Query().Get("hostname")
+	metricName := h.aliases.Canonical(r.URL.Query().Get("metric_name"))
+
+	data := make([]cache.GPUSnapshot, 0, len(snapshots))
+	rows := 0
+	for _, gpu := range snapshots {
+		if hostname != "" && gpu.Hostname != hostname {
+			continue
+		}
+		if metricName != "" {
+			v, ok := gpu.Metrics[metricName]
+			if !ok {
+				continue
+			}
+			gpu.Metrics = map[string]cache.MetricValue{metricName: v}
 		}
-	} else { // Default to JSON
-		w.Header().Set("Content-Type", "application/json")
-		writeJSON(w, http.StatusOK, TelemetryResponse{
-			Data:  metrics,
-			Count: len(metrics),
-		})
+		data = append(data, gpu)
+		rows += len(gpu.Metrics)
 	}
+	usage.AddRows(r.Context(), rows)
+	return data
+}
+
+// SnapshotChangesResponse lists the GPUs whose latest values changed since
+// the request's cursor or time, with the cursor for the next request.
+type SnapshotChangesResponse struct {
+	Data  []cache.GPUSnapshot `json:"data"`
+	Count int                 `json:"count" example:"3"`
+
+	// Cursor is passed as cursor on the next request to get what changed since this one
+	Cursor string `json:"cursor" example:"1729252800000000000.4182"`
+
+	// Reset is set when the cursor came from another API replica or
+	// process, so Data holds every GPU and replaces the client's copy
+	Reset bool `json:"reset"`
+
+	Cache cache.Status `json:"cache"`
+}
+
+// GetSnapshotChanges godoc
+// @Summary      Get the GPUs whose latest values changed
+// @Description  Returns the GPUs in the latest-values cache whose values, hostname, device, model or GPU ID changed since the cursor of a previous response, or since an RFC3339 time. A newer sample with an unchanged value is not a change. Polling dashboards merge the changed GPUs into their copy of the snapshot instead of fetching it all on every refresh. Without cursor or since, or with a cursor from another replica or before a restart, every GPU is returned with reset set.
+// @Tags         gpus
+// @Produce      json
+// @Param        cursor       query  string  false  "Cursor from the previous response"
+// @Param        since        query  string  false  "Only GPUs changed at or after this time (RFC3339), instead of cursor"
+// @Param        hostname     query  string  false  "Filter by hostname"
+// @Param        metric_name  query  string  false  "Only include this metric"
+// @Success      200  {object}  SnapshotChangesResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/snapshot/changes [get]
+func (h *Handler) GetSnapshotChanges(w http.ResponseWriter, r *http.Request) {
+	if h.latest == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Latest-values cache is disabled")
+		return
+	}
+	cursor := r.URL.Query().Get("cursor")
+	sinceStr := r.URL.Query().Get("since")
+	if cursor != "" && sinceStr != "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "Use either cursor or since, not both")
+		return
+	}
+
+	var resp SnapshotChangesResponse
+	if sinceStr != "" {
+		since, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid since format. Use RFC3339 (e.g., 2024-01-01T00:00:00Z)")
+			return
+		}
+		resp.Data, resp.Cursor = h.latest.ChangedSince(since)
+	} else {
+		var err error
+		resp.Data, resp.Cursor, resp.Reset, err = h.latest.Changes(cursor)
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+	}
+
+	resp.Data = h.filterSnapshots(r, resp.Data)
+	resp.Count = len(resp.Data)
+	resp.Cache = h.latest.Status()
+	writeJSON(w, http.StatusOK, resp)
 }
@@ -3,6 +3,9 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -15,7 +18,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/summary"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
 )
 
@@ -131,7 +139,7 @@ func (s *mockStorage) matchesQuery(metric *models.GPUMetric, query *models.Telem
 	if query.EndTime != nil && metric.Timestamp.After(*query.EndTime) {
 		return false
 	}
-	return true
+	return query.MatchesSets(metric)
 }
 
 func (s *mockStorage) GetMetricsByGPU(ctx context.Context, uuid string, startTime, endTime *time.Time) ([]*models.GPUMetric, error) {
@@ -328,7 +336,7 @@ func TestGetGPUTelemetryInvalidTimeFormat(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
 
-	assert.Equal(t, "bad_request", response.Error)
+	assert.Equal(t, "invalid_range", response.Error)
 }
 
 func TestGetGPUTelemetryEmpty(t *testing.T) {
@@ -414,3 +422,338 @@ func TestInvalidOffset(t *testing.T) {
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+// failingStorage returns a fixed error from every read.
+type failingStorage struct {
+	*mockStorage
+	err error
+}
+
+func (s *failingStorage) GetGPUs(ctx context.Context) ([]string, error) {
+	return nil, s.err
+}
+
+func TestStorageErrorStatusMapping(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{perrors.Transient(errors.New("influx unavailable")), http.StatusServiceUnavailable, "storage_unavailable"},
+		{perrors.Permanent(errors.New("bad query")), http.StatusInternalServerError, "internal_error"},
+		{errors.New("unclassified"), http.StatusInternalServerError, "internal_error"},
+		{perrors.Validation(perrors.WithCode(perrors.CodeInvalidRange, errors.New("end must be after start"))), http.StatusBadRequest, "invalid_range"},
+	}
+
+	for _, tt := range tests {
+		router := setupTestRouter(&failingStorage{mockStorage: newMockStorage(), err: tt.err})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/gpus", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code)
+
+		var response ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, tt.code, response.Error)
+	}
+}
+
+func TestGetSnapshot(t *testing.T) {
+	latest := cache.NewLatest(cache.SourceStorage)
+	now := time.Now()
+	latest.Update([]*models.GPUMetric{
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 80, Timestamp: now},
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 65, Timestamp: now},
+		{UUID: "GPU-2", Hostname: "host-002", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 40, Timestamp: now},
+	})
+
+	handler := NewHandler(newMockStorage(), 100, 1000)
+	handler.SetLatestCache(latest)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/snapshot?hostname=host-001&metric_name=DCGM_FI_DEV_GPU_UTIL", nil)
+	handler.GetSnapshot(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response SnapshotResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, "GPU-1", response.Data[0].UUID)
+	assert.Len(t, response.Data[0].Metrics, 1)
+	assert.Equal(t, 80.0, response.Data[0].Metrics["DCGM_FI_DEV_GPU_UTIL"].Value)
+	assert.True(t, response.Cache.Loaded)
+	assert.Equal(t, 2, response.Cache.GPUs)
+}
+
+func TestGetSnapshotChanges(t *testing.T) {
+	handler := NewHandler(newMockStorage(), 100, 1000)
+	get := func(query string) (*httptest.ResponseRecorder, SnapshotChangesResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/snapshot/changes"+query, nil)
+		handler.GetSnapshotChanges(w, req)
+		var response SnapshotChangesResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w, response
+	}
+
+	w, _ := get("")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	latest := cache.NewLatest(cache.SourceMQ)
+	handler.SetLatestCache(latest)
+	now := time.Now()
+	latest.Update([]*models.GPUMetric{
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 80, Timestamp: now},
+		{UUID: "GPU-2", Hostname: "host-002", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 40, Timestamp: now},
+	})
+
+	w, first := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, first.Reset)
+	assert.Equal(t, 2, first.Count)
+	require.NotEmpty(t, first.Cursor)
+
+	latest.Update([]*models.GPUMetric{
+		{UUID: "GPU-2", Hostname: "host-002", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 45, Timestamp: now.Add(time.Second)},
+	})
+	w, delta := get("?cursor=" + first.Cursor)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, delta.Reset)
+	require.Equal(t, 1, delta.Count)
+	assert.Equal(t, "GPU-2", delta.Data[0].UUID)
+
+	w, filtered := get("?cursor=" + first.Cursor + "&hostname=host-001")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, filtered.Count)
+
+	w, since := get("?since=" + now.Add(-time.Minute).UTC().Format(time.RFC3339))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, since.Count)
+
+	for _, query := range []string{"?cursor=bogus", "?since=yesterday", "?cursor=" + first.Cursor + "&since=" + now.UTC().Format(time.RFC3339)} {
+		w, _ := get(query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGetSnapshotMetricAlias(t *testing.T) {
+	latest := cache.NewLatest(cache.SourceStorage)
+	latest.Update([]*models.GPUMetric{
+		{UUID: "GPU-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 65, Timestamp: time.Now()},
+	})
+	handler := NewHandler(newMockStorage(), 100, 1000)
+	handler.SetLatestCache(latest)
+	handler.SetMetricAliases(models.MetricAliases{"gpu_temperature": "DCGM_FI_DEV_GPU_TEMP"})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/snapshot?metric_name=gpu_temperature", nil)
+	handler.GetSnapshot(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response SnapshotResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, 65.0, response.Data[0].Metrics["DCGM_FI_DEV_GPU_TEMP"].Value)
+}
+
+func TestGetSnapshotCacheDisabled(t *testing.T) {
+	handler := NewHandler(newMockStorage(), 100, 1000)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/snapshot", nil)
+	handler.GetSnapshot(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer hook.Close()
+
+	cfg := config.DefaultWebhookConfig()
+	cfg.URLs = []string{hook.URL}
+	events, err := notify.NewDispatcher(cfg, "api-test", log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go events.Run(ctx)
+
+	handler := NewHandler(newMockStorage(), 100, 1000)
+	handler.SetWebhooks(events)
+	events.Notify(models.EventExportCompleted, map[string]interface{}{"saved_query_id": "q-1"})
+
+	var response WebhookDeliveriesResponse
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/webhooks/deliveries?status=delivered", nil)
+		handler.ListWebhookDeliveries(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Count == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, models.EventExportCompleted, response.Data[0].EventType)
+
+	handler.SetWebhooks(nil)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/webhooks/deliveries", nil)
+	handler.ListWebhookDeliveries(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+// explainingStorage adds storage.QueryExplainer to mockStorage.
+type explainingStorage struct {
+	*mockStorage
+}
+
+func (s *explainingStorage) ExplainTelemetry(ctx context.Context, query *models.TelemetryQuery) (*storage.QueryPlan, error) {
+	metrics, err := s.GetTelemetry(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.QueryPlan{
+		Backend:    "mock",
+		Query:      "uuid=" + query.UUID,
+		Resolution: storage.ResolutionRaw,
+		Rows:       len(metrics),
+	}, nil
+}
+
+func TestGetGPUTelemetryExplain(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	router := setupTestRouter(&explainingStorage{mockStorage: store})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/gpus/GPU-12345-AAAA/telemetry?explain=true&limit=2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response ExplainResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.NotNil(t, response.Plan)
+	assert.Equal(t, "uuid=GPU-12345-AAAA", response.Plan.Query)
+	assert.Equal(t, storage.ResolutionRaw, response.Plan.Resolution)
+	assert.Equal(t, 2, response.Plan.Rows)
+	assert.Equal(t, 2, response.Request.Limit)
+}
+
+func TestGetGPUTelemetryExplainUnsupported(t *testing.T) {
+	router := setupTestRouter(newMockStorage())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/gpus/GPU-1/telemetry?explain=true", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/gpus/GPU-1/telemetry?explain=maybe", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListGPUsDetails(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	router := setupTestRouter(store)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/gpus?details=true&hostname=host-001&model=h100&limit=1&offset=1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response GPUDetailsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 2, response.Total)
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, "GPU-12345-BBBB", response.Data[0].UUID)
+	assert.Equal(t, "host-001", response.Data[0].Hostname)
+	assert.True(t, response.Data[0].FirstSeen.Before(response.Data[0].LastSeen))
+}
+
+// gpuInfoStorage adds storage.GPUInfoReader to mockStorage.
+type gpuInfoStorage struct {
+	*mockStorage
+	infos []*models.GPUInfo
+}
+
+func (s *gpuInfoStorage) GetGPUInfos(ctx context.Context) ([]*models.GPUInfo, error) {
+	return s.infos, nil
+}
+
+func TestListGPUsDetailsUsesBulkReader(t *testing.T) {
+	store := &gpuInfoStorage{mockStorage: newMockStorage(), infos: []*models.GPUInfo{
+		{UUID: "GPU-B", Hostname: "host-002", ModelName: "NVIDIA A100"},
+		{UUID: "GPU-A", Hostname: "host-001", ModelName: "NVIDIA H100"},
+	}}
+	router := setupTestRouter(store)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/gpus?details=true&model=A100", nil)
+	router.ServeHTTP(w, req)
+
+	var response GPUDetailsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 1, response.Count)
+	assert.Equal(t, "GPU-B", response.Data[0].UUID)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/gpus?details=yes", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// summaryStorage adds a fixed storage.FleetSummaryReader to mockStorage.
+type summaryStorage struct {
+	*mockStorage
+	summary *models.FleetSummary
+}
+
+func (s *summaryStorage) GetFleetSummary(ctx context.Context) (*models.FleetSummary, error) {
+	return s.summary, nil
+}
+
+func TestGetStats(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &summaryStorage{mockStorage: newMockStorage(), summary: &models.FleetSummary{
+		TotalGPUs:    3,
+		TotalMetrics: 300,
+		GPUsByModel:  map[string]int{"H100": 2, "A100": 1},
+		Daily:        []models.DailyVolume{{Day: day, Metrics: 100}, {Day: day.AddDate(0, 0, 1), Metrics: 200}},
+		OldestMetric: day,
+		NewestMetric: day.Add(47 * time.Hour),
+	}}
+	store.Store(context.Background(), &models.GPUMetric{UUID: "GPU-1", MetricName: "DCGM_FI_DEV_GPU_UTIL", Timestamp: day})
+	h := NewHandler(store, 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/stats", h.GetStats).Methods(http.MethodGet)
+
+	// Until a summary is computed, GPUs are counted in storage
+	summarizer := summary.New(store, config.SummaryConfig{Refresh: time.Minute, Days: 30}, log.New(io.Discard, "", 0))
+	h.SetSummary(summarizer)
+	w := doJSON(t, router, http.MethodGet, "/api/v1/stats", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats StatsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 1, stats.TotalGPUs)
+	assert.Nil(t, stats.GPUsByModel)
+	assert.True(t, stats.SummarizedAt.IsZero())
+
+	require.NoError(t, summarizer.Refresh(context.Background()))
+	w = doJSON(t, router, http.MethodGet, "/api/v1/stats", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, 3, stats.TotalGPUs)
+	assert.Equal(t, int64(300), stats.TotalMetrics)
+	assert.Equal(t, map[string]int{"H100": 2, "A100": 1}, stats.GPUsByModel)
+	require.Len(t, stats.DailyVolume, 2)
+	assert.Equal(t, int64(200), stats.DailyVolume[1].Metrics)
+	assert.True(t, stats.NewestMetric.Equal(day.Add(47*time.Hour)))
+	assert.False(t, stats.SummarizedAt.IsZero())
+}
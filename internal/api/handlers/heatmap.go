@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+const (
+	// defaultHeatmapWindow and maxHeatmapWindow bound the time covered
+	defaultHeatmapWindow = 24 * time.Hour
+	maxHeatmapWindow     = 31 * 24 * time.Hour
+
+	// defaultHeatmapColumns and maxHeatmapColumns bound the time buckets
+	defaultHeatmapColumns = 120
+	maxHeatmapColumns     = 1000
+)
+
+// HeatmapRow identifies the GPU of one heatmap row.
+type HeatmapRow struct {
+	UUID     string `json:"uuid" example:"GPU-5fd4f087-86f3-7a43-b711-4771313afc50"`
+	Hostname string `json:"hostname" example:"host-001"`
+}
+
+// HeatmapResponse is a GPU × time matrix of one metric. Values[i][j] is row
+// i's aggregate over the bucket starting at Columns[j], or null when the GPU
+// reported nothing then.
+type HeatmapResponse struct {
+	Metric    string    `json:"metric" example:"DCGM_FI_DEV_GPU_UTIL"`
+	Aggregate string    `json:"aggregate" example:"mean"`
+	Hostname  string    `json:"hostname,omitempty" example:"host-001"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+
+	// Step is the width of each column
+	Step string `json:"step" example:"12m0s"`
+
+	Columns []time.Time  `json:"columns"`
+	Rows    []HeatmapRow `json:"rows"`
+	Values  [][]*float64 `json:"values"`
+
+	// Min and Max span the returned values, for the color scale
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+
+	// TotalRows is the number of GPUs before limit and offset
+	TotalRows int `json:"total_rows" example:"256"`
+}
+
+// GetHeatmap godoc
+// @Summary      Get a GPU × time heatmap
+// @Description  Aggregates one metric into a matrix sized for heatmap rendering: one row per GPU (on a host, or the whole fleet), ordered by hostname and UUID, and one column per time bucket over the window ending at end_time. Rows are paged with limit and offset.
+// @Tags         gpus
+// @Produce      json
+// @Param        metric     query  string  true   "Metric name, optional when the filter lists one"  example(DCGM_FI_DEV_GPU_UTIL)
+// @Param        hostname   query  string  false  "Only GPUs on this host"
+// @Param        filter     query  string  false  "Saved filter name; only its GPUs and hosts are rows"
+// @Param        window     query  string  false  "How far back from end_time (default 24h, max 744h)"
+// @Param        end_time   query  string  false  "End of the window (RFC3339, default now)"
+// @Param        columns    query  int     false  "Number of time buckets (default 120, max 1000)"
+// @Param        aggregate  query  string  false  "Bucket aggregate: mean (default), min, max or last"
+// @Param        limit      query  int     false  "Maximum rows"
+// @Param        offset     query  int     false  "Rows to skip"
+// @Success      200  {object}  HeatmapResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/heatmap [get]
+func (h *Handler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	reader, ok := h.seriesReader(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	filter, ok := h.savedFilter(w, r)
+	if !ok {
+		return
+	}
+
+	// A filter on one metric stands in for the metric parameter
+	metric := h.aliases.Canonical(q.Get("metric"))
+	if metric == "" && filter != nil && len(filter.Metrics) == 1 {
+		metric = filter.Metrics[0]
+	}
+	if metric == "" {
+		writeError(w, http.StatusBadRequest, "bad_request", "metric is required")
+		return
+	}
+	if filter != nil && len(filter.Labels) > 0 {
+		writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("filter %q matches labels, which heatmap rows do not carry", filter.Name))
+		return
+	}
+	window, err := parseDuration(r, "window", defaultHeatmapWindow)
+	if err == nil && window > maxHeatmapWindow {
+		err = perrors.WithCode(perrors.CodeQueryTooBroad, fmt.Errorf("window must be at most %v", maxHeatmapWindow))
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	end, err := parseEndTime(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	fn, err := parseAggregate(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	columns := defaultHeatmapColumns
+	if columnsStr := q.Get("columns"); columnsStr != "" {
+		c, err := strconv.Atoi(columnsStr)
+		if err != nil || c < 1 || c > maxHeatmapColumns {
+			writeError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("columns must be between 1 and %d", maxHeatmapColumns))
+			return
+		}
+		columns = c
+	}
+	limit := h.defaultLimit
+	if limitStr := q.Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid limit parameter")
+			return
+		}
+		limit = min(l, h.maxLimit)
+	}
+	offset := 0
+	if offsetStr := q.Get("offset"); offsetStr != "" {
+		o, err := strconv.Atoi(offsetStr)
+		if err != nil || o < 0 {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid offset parameter")
+			return
+		}
+		offset = o
+	}
+
+	// Steps are whole seconds, rounded up so the columns cover the window,
+	// and buckets are aligned to the step so refreshes reuse the same ones
+	step := max((window/time.Duration(columns) + time.Second - 1).Truncate(time.Second), time.Second)
+	start := end.Add(-window).Truncate(step)
+	hostname := q.Get("hostname")
+	if hostname == "" && filter != nil && len(filter.Hostnames) == 1 {
+		hostname = filter.Hostnames[0]
+	}
+	series, err := reader.GetSeries(r.Context(), &models.SeriesQuery{
+		Metrics:  []string{metric},
+		Hostname: hostname,
+		Start:    start,
+		End:      end,
+		Every:    step,
+		Fn:       fn,
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if filter != nil {
+		// The filter may exclude the metric itself, or only some GPUs
+		excluded := len(filter.Metrics) > 0 && !slices.Contains(filter.Metrics, metric)
+		series = slices.DeleteFunc(series, func(s *models.Series) bool {
+			return excluded || !filter.MatchesGPU(s.UUID, s.Hostname)
+		})
+	}
+
+	resp := HeatmapResponse{
+		Metric:    metric,
+		Aggregate: fn,
+		Hostname:  q.Get("hostname"),
+		Start:     start,
+		End:       end,
+		Step:      step.String(),
+		Columns:   make([]time.Time, 0),
+		Rows:      make([]HeatmapRow, 0),
+		Values:    make([][]*float64, 0),
+		TotalRows: len(series),
+	}
+	for t := start; t.Before(end); t = t.Add(step) {
+		resp.Columns = append(resp.Columns, t)
+	}
+	// Series are ordered by hostname and UUID
+	series = series[min(offset, len(series)):]
+	series = series[:min(limit, len(series))]
+	for _, s := range series {
+		row := make([]*float64, len(resp.Columns))
+		for _, p := range s.Points {
+			col := int(p.Time.Sub(start) / step)
+			if col < 0 || col >= len(row) {
+				continue
+			}
+			v := p.Value
+			row[col] = &v
+			if resp.Min == nil || v < *resp.Min {
+				resp.Min = &v
+			}
+			if resp.Max == nil || v > *resp.Max {
+				resp.Max = &v
+			}
+		}
+		resp.Rows = append(resp.Rows, HeatmapRow{UUID: s.UUID, Hostname: s.Hostname})
+		resp.Values = append(resp.Values, row)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
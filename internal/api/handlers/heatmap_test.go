@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// heatmapStorage adds a storage.SeriesReader to mockStorage with two GPUs:
+// GPU-1 reports in every bucket, GPU-2 only in the first.
+type heatmapStorage struct {
+	*mockStorage
+	last *models.SeriesQuery
+}
+
+func (s *heatmapStorage) GetSeries(ctx context.Context, query *models.SeriesQuery) ([]*models.Series, error) {
+	s.last = query
+	gpu1 := &models.Series{Metric: query.Metrics[0], UUID: "GPU-1", Hostname: "host-001"}
+	for t, i := query.Start, 0; t.Before(query.End); t, i = t.Add(query.Every), i+1 {
+		gpu1.Points = append(gpu1.Points, models.SeriesPoint{Time: t, Value: float64(10 * i)})
+	}
+	gpu2 := &models.Series{Metric: query.Metrics[0], UUID: "GPU-2", Hostname: "host-002",
+		Points: []models.SeriesPoint{{Time: query.Start, Value: 5}}}
+	return []*models.Series{gpu1, gpu2}, nil
+}
+
+func TestGetHeatmap(t *testing.T) {
+	setup := func(h *Handler) *mux.Router {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/heatmap", h.GetHeatmap).Methods(http.MethodGet)
+		return router
+	}
+
+	w := doJSON(t, setup(NewHandler(newMockStorage(), 100, 1000)), http.MethodGet, "/api/v1/heatmap?metric=DCGM_FI_DEV_GPU_UTIL", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	store := &heatmapStorage{mockStorage: newMockStorage()}
+	router := setup(NewHandler(store, 100, 1000))
+	for _, bad := range []string{"", "?metric=m&columns=0", "?metric=m&columns=5000", "?metric=m&window=1000h", "?metric=m&offset=-1"} {
+		w = doJSON(t, router, http.MethodGet, "/api/v1/heatmap"+bad, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, bad)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/heatmap?metric=DCGM_FI_DEV_GPU_UTIL&window=1h&columns=6&end_time=2024-01-01T12:00:00Z&aggregate=max", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp HeatmapResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "10m0s", resp.Step)
+	assert.Equal(t, models.SeriesMax, store.last.Fn)
+	require.Len(t, resp.Columns, 6)
+	assert.Equal(t, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), resp.Columns[0])
+	require.Len(t, resp.Rows, 2)
+	assert.Equal(t, "GPU-2", resp.Rows[1].UUID)
+	require.Len(t, resp.Values[1], 6)
+	assert.Equal(t, 5.0, *resp.Values[1][0])
+	assert.Nil(t, resp.Values[1][1], "buckets without data are null")
+	assert.Equal(t, 50.0, *resp.Values[0][5])
+	assert.Equal(t, 0.0, *resp.Min)
+	assert.Equal(t, 50.0, *resp.Max)
+
+	// Rows are paged
+	w = doJSON(t, router, http.MethodGet, "/api/v1/heatmap?metric=DCGM_FI_DEV_GPU_UTIL&limit=1&offset=1", nil)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, 2, resp.TotalRows)
+	require.Len(t, resp.Rows, 1)
+	assert.Equal(t, "GPU-2", resp.Rows[0].UUID)
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// HoldRequest is the body for placing a retention hold. At least one of
+// start, end and uuids is required.
+type HoldRequest struct {
+	Reason string     `json:"reason" example:"RMA 4411: ECC errors on node-7"`
+	Start  *time.Time `json:"start,omitempty" example:"2024-01-01T00:00:00Z"`
+	End    *time.Time `json:"end,omitempty" example:"2024-01-08T00:00:00Z"`
+	UUIDs  []string   `json:"uuids,omitempty"`
+}
+
+// HoldListResponse represents the response for listing retention holds.
+type HoldListResponse struct {
+	Data  []*models.RetentionHold `json:"data"`
+	Count int                     `json:"count" example:"1"`
+}
+
+// holdStore returns the backend's HoldStore, writing a 501 if it has none.
+func (h *Handler) holdStore(w http.ResponseWriter, r *http.Request) (storage.HoldStore, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.HoldStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support retention holds")
+	}
+	return store, ok
+}
+
+// CreateHold godoc
+// @Summary      Place a retention hold
+// @Description  Pins telemetry in a time range, for some GPUs, or both, so that on-demand cleanup and collector expiry never delete it. Bucket retention cannot spare individual rows, so a hold is refused while the bucket expires data; set retention to 0s and enable collector expiry first. Requires the admin role.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  HoldRequest  true  "Data to hold"
+// @Success      201  {object}  models.RetentionHold
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/holds [post]
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.holdStore(w, r)
+	if !ok {
+		return
+	}
+
+	var req HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	hold := &models.RetentionHold{
+		ID:        uuid.New().String(),
+		Reason:    req.Reason,
+		Start:     utcTime(req.Start),
+		End:       utcTime(req.End),
+		UUIDs:     req.UUIDs,
+		CreatedAt: time.Now().UTC(),
+	}
+	if p, ok := auth.FromContext(r.Context()); ok {
+		hold.CreatedBy = p.Name
+	}
+	if err := hold.Validate(); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	if admin, ok := h.storeFor(r.Context()).(storage.DataAdmin); ok {
+		retention, err := admin.GetRetention(r.Context())
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		if retention != 0 {
+			writeError(w, http.StatusConflict, "conflict", "Bucket retention of "+retention.String()+" would expire held data; set retention to 0s and let the collector expire telemetry instead")
+			return
+		}
+	}
+
+	if err := store.CreateHold(r.Context(), hold); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, hold)
+}
+
+// ListHolds godoc
+// @Summary      List retention holds
+// @Description  Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  HoldListResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/holds [get]
+func (h *Handler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.holdStore(w, r)
+	if !ok {
+		return
+	}
+
+	holds, err := store.ListHolds(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, HoldListResponse{
+		Data:  holds,
+		Count: len(holds),
+	})
+}
+
+// GetHold godoc
+// @Summary      Get a retention hold
+// @Description  Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Hold ID"
+// @Success      200  {object}  models.RetentionHold
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/holds/{id} [get]
+func (h *Handler) GetHold(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.holdStore(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := store.GetHold(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hold)
+}
+
+// DeleteHold godoc
+// @Summary      Release a retention hold
+// @Description  Removes a hold, so the next cleanup or collector expiry may delete the data it covered. Requires the admin role.
+// @Tags         admin
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Hold ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/holds/{id} [delete]
+func (h *Handler) DeleteHold(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.holdStore(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.DeleteHold(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// utcTime returns t in UTC, or nil if t is nil.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// holdStorage adds an in-memory storage.HoldStore to adminStorage.
+type holdStorage struct {
+	*adminStorage
+	holds []*models.RetentionHold
+}
+
+func newHoldStorage() *holdStorage {
+	store := &holdStorage{adminStorage: newAdminStorage()}
+	store.retention = 0
+	return store
+}
+
+func (s *holdStorage) CreateHold(ctx context.Context, hold *models.RetentionHold) error {
+	s.holds = append(s.holds, hold)
+	return nil
+}
+
+func (s *holdStorage) GetHold(ctx context.Context, id string) (*models.RetentionHold, error) {
+	for _, hold := range s.holds {
+		if hold.ID == id {
+			return hold, nil
+		}
+	}
+	return nil, perrors.NotFound(fmt.Errorf("retention hold %q not found", id))
+}
+
+func (s *holdStorage) ListHolds(ctx context.Context) ([]*models.RetentionHold, error) {
+	return s.holds, nil
+}
+
+func (s *holdStorage) DeleteHold(ctx context.Context, id string) error {
+	for i, hold := range s.holds {
+		if hold.ID == id {
+			s.holds = append(s.holds[:i], s.holds[i+1:]...)
+			return nil
+		}
+	}
+	return perrors.NotFound(fmt.Errorf("retention hold %q not found", id))
+}
+
+func setupHoldRouter(h *Handler) *mux.Router {
+	router := setupAdminRouter(h)
+	admin := router.PathPrefix("/api/v1/admin").Subrouter()
+	admin.HandleFunc("/holds", h.ListHolds).Methods(http.MethodGet)
+	admin.HandleFunc("/holds", h.CreateHold).Methods(http.MethodPost)
+	admin.HandleFunc("/holds/{id}", h.GetHold).Methods(http.MethodGet)
+	admin.HandleFunc("/holds/{id}", h.DeleteHold).Methods(http.MethodDelete)
+	return router
+}
+
+func TestHolds(t *testing.T) {
+	store := newHoldStorage()
+	router := setupHoldRouter(NewHandler(store, 100, 1000))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/admin/holds", HoldRequest{Reason: "RMA 4411", Start: &start, End: &end})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var hold models.RetentionHold
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &hold))
+	assert.NotEmpty(t, hold.ID)
+	assert.Equal(t, start, *hold.Start)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/holds/"+hold.ID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/holds", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list HoldListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Count)
+
+	// Cleanup overlapping the hold records it
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/cleanup", models.CleanupRequest{Start: start.Add(-time.Hour), End: start.Add(time.Hour)})
+	require.Equal(t, http.StatusOK, w.Code)
+	var record models.CleanupRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &record))
+	assert.Equal(t, []string{hold.ID}, record.Holds)
+
+	// Bucket retention would expire held data
+	w = doJSON(t, router, http.MethodPut, "/api/v1/admin/retention", RetentionRequest{Retention: "72h"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, time.Duration(0), store.retention)
+
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/admin/holds/"+hold.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/holds/"+hold.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(t, router, http.MethodPut, "/api/v1/admin/retention", RetentionRequest{Retention: "72h"})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCreateHoldRefused(t *testing.T) {
+	store := newHoldStorage()
+	router := setupHoldRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/admin/holds", HoldRequest{UUIDs: []string{"GPU-1"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "reason is required")
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/holds", HoldRequest{Reason: "everything"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "unbounded holds are refused")
+
+	store.retention = 168 * time.Hour
+	w = doJSON(t, router, http.MethodPost, "/api/v1/admin/holds", HoldRequest{Reason: "RMA 4411", UUIDs: []string{"GPU-1"}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Empty(t, store.holds)
+
+	router = setupHoldRouter(NewHandler(newMockStorage(), 100, 1000))
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/holds", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
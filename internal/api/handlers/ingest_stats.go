@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+const (
+	// defaultIngestStatsWindow and maxIngestStatsWindow bound the hours covered
+	defaultIngestStatsWindow = 24 * time.Hour
+	maxIngestStatsWindow     = 31 * 24 * time.Hour
+)
+
+// IngestStatsResponse holds the hourly ingest statistics of each streamer
+// over a window, and their totals per streamer.
+type IngestStatsResponse struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Data holds one row per streamer and hour, ordered by hour and source
+	Data []*models.IngestStats `json:"data"`
+
+	// Totals sums Data per streamer over the window, ordered by source;
+	// their Hour is the window's first hour
+	Totals []*models.IngestStats `json:"totals"`
+
+	Count int `json:"count" example:"24"`
+}
+
+// GetIngestStats godoc
+// @Summary      Get ingest statistics
+// @Description  Returns what the collectors ingested from each streamer instance in each hour of the window ending at end_time: batches, metrics and bytes stored, batches rejected whole (undecodable or clock-skewed), metrics dropped, and the average batch size. Undecodable batches are counted under the source "unknown". Hours are counted by when the collectors received the batches, and the latest hour is partial.
+// @Tags         ingest
+// @Produce      json
+// @Param        window    query  string  false  "How far back from end_time (default 24h, max 744h)"
+// @Param        end_time  query  string  false  "End of the window (RFC3339, default now)"
+// @Param        source    query  string  false  "Only this streamer instance"
+// @Success      200  {object}  IngestStatsResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/ingest/stats [get]
+func (h *Handler) GetIngestStats(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.storeFor(r.Context()).(storage.IngestStatsReader)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support ingest statistics")
+		return
+	}
+	window, err := parseDuration(r, "window", defaultIngestStatsWindow)
+	if err == nil && window > maxIngestStatsWindow {
+		err = perrors.WithCode(perrors.CodeQueryTooBroad, fmt.Errorf("window must be at most %v", maxIngestStatsWindow))
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	end, err := parseEndTime(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	start := end.Add(-window)
+
+	stats, err := store.GetIngestStats(r.Context(), start, end, r.URL.Query().Get("source"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	totals := make(map[string]*models.IngestStats)
+	sources := make([]string, 0)
+	for _, st := range stats {
+		total := totals[st.Source]
+		if total == nil {
+			total = &models.IngestStats{Source: st.Source, Hour: start.Truncate(time.Hour)}
+			totals[st.Source] = total
+			sources = append(sources, st.Source)
+		}
+		total.Add(st)
+	}
+	slices.Sort(sources)
+	resp := IngestStatsResponse{
+		Start:  start,
+		End:    end,
+		Data:   stats,
+		Totals: make([]*models.IngestStats, 0, len(sources)),
+		Count:  len(stats),
+	}
+	for _, source := range sources {
+		resp.Totals = append(resp.Totals, totals[source])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
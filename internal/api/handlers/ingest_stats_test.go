@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// ingestStatsStorage adds an in-memory storage.IngestStatsReader to mockStorage.
+type ingestStatsStorage struct {
+	*mockStorage
+	stats      []*models.IngestStats
+	start, end time.Time
+	source     string
+}
+
+func (s *ingestStatsStorage) GetIngestStats(ctx context.Context, start, end time.Time, source string) ([]*models.IngestStats, error) {
+	s.start, s.end, s.source = start, end, source
+	var out []*models.IngestStats
+	for _, st := range s.stats {
+		if source == "" || st.Source == source {
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+
+func setupIngestStatsRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/ingest/stats", h.GetIngestStats).Methods(http.MethodGet)
+	return router
+}
+
+func TestGetIngestStats(t *testing.T) {
+	hour := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	stat := func(source string, h time.Time, batches, metrics int64) *models.IngestStats {
+		st := &models.IngestStats{Source: source, Hour: h}
+		st.Add(&models.IngestStats{Batches: batches, Metrics: metrics, Bytes: metrics * 100})
+		return st
+	}
+	store := &ingestStatsStorage{mockStorage: newMockStorage(), stats: []*models.IngestStats{
+		stat("streamer-1", hour, 10, 1000),
+		stat("streamer-0", hour.Add(time.Hour), 4, 100),
+		stat("streamer-1", hour.Add(time.Hour), 30, 1000),
+	}}
+	router := setupIngestStatsRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/ingest/stats?window=6h&end_time=2024-01-01T12:00:00Z", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp IngestStatsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Count)
+	assert.True(t, store.start.Equal(time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)))
+	assert.True(t, store.end.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)))
+
+	require.Len(t, resp.Totals, 2)
+	assert.Equal(t, "streamer-0", resp.Totals[0].Source)
+	total := resp.Totals[1]
+	assert.Equal(t, "streamer-1", total.Source)
+	assert.Equal(t, int64(40), total.Batches)
+	assert.Equal(t, int64(2000), total.Metrics)
+	assert.Equal(t, int64(200000), total.Bytes)
+	assert.Equal(t, 50.0, total.AvgBatchSize)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/ingest/stats?source=streamer-0", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "streamer-0", store.source)
+	assert.Equal(t, 1, resp.Count)
+	assert.Equal(t, 24*time.Hour, resp.End.Sub(resp.Start))
+
+	for query, code := range map[string]string{"window=-1h": "bad_request", "window=745h": "query_too_broad", "end_time=yesterday": "invalid_range"} {
+		w = doJSON(t, router, http.MethodGet, "/api/v1/ingest/stats?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		var errResp ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+		assert.Equal(t, code, errResp.Error, query)
+	}
+}
+
+func TestGetIngestStatsNotImplemented(t *testing.T) {
+	router := setupIngestStatsRouter(NewHandler(newMockStorage(), 100, 1000))
+	w := doJSON(t, router, http.MethodGet, "/api/v1/ingest/stats", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+)
+
+// SetLogging sets the runtime log controls that admins can change.
+func (h *Handler) SetLogging(logs *logging.Runtime) {
+	h.logs = logs
+}
+
+// GetLogging godoc
+// @Summary      Get the log level and debug toggles
+// @Description  Returns this replica's log level and its debug toggles, such as flux.queries, which logs every Flux query with its duration. Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  logging.State
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/logging [get]
+func (h *Handler) GetLogging(w http.ResponseWriter, r *http.Request) {
+	if h.logs == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Runtime logging controls are not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.logs.State())
+}
+
+// UpdateLogging godoc
+// @Summary      Change the log level or debug toggles
+// @Description  Changes this replica's log level ("debug" or "info") and switches debug toggles on or off, effective immediately and until the next change or restart. Omitted fields are left unchanged; an unknown level or toggle changes nothing. Each change is logged. Requires the admin role.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  logging.Update  true  "Changes"
+// @Success      200  {object}  logging.State
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/logging [put]
+func (h *Handler) UpdateLogging(w http.ResponseWriter, r *http.Request) {
+	if h.logs == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Runtime logging controls are not configured")
+		return
+	}
+
+	var update logging.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	if err := h.logs.Apply(update); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.logs.State())
+}
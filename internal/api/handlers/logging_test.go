@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+)
+
+func TestLogging(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/admin/logging", h.GetLogging).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/admin/logging", h.UpdateLogging).Methods(http.MethodPut)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/admin/logging", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var out bytes.Buffer
+	logs := logging.New(log.New(&out, "", 0), logging.LevelInfo)
+	queries := logs.Toggle("flux.queries", "Log every Flux query")
+	h.SetLogging(logs)
+
+	w = doJSON(t, router, http.MethodPut, "/api/v1/admin/logging", logging.Update{Level: "debug", Toggles: map[string]bool{"flux.queries": true}})
+	require.Equal(t, http.StatusOK, w.Code)
+	var state logging.State
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, "debug", state.Level)
+	assert.True(t, state.Toggles["flux.queries"].Enabled)
+	assert.True(t, queries.Enabled())
+	assert.Contains(t, out.String(), "Log level changed from info to debug")
+
+	w = doJSON(t, router, http.MethodPut, "/api/v1/admin/logging", logging.Update{Toggles: map[string]bool{"mq.frames": true}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "mq.frames")
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/logging", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+	assert.Equal(t, "debug", state.Level)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/maintenance"
+)
+
+// MaintenanceRequest is the body for turning maintenance mode on or off.
+type MaintenanceRequest struct {
+	Enabled bool `json:"enabled" example:"true"`
+
+	// Message is the banner shown to clients (optional)
+	Message string `json:"message,omitempty" example:"InfluxDB upgrade until 14:00 UTC"`
+}
+
+// SetMaintenance sets the maintenance mode that admins can switch.
+func (h *Handler) SetMaintenance(mode *maintenance.Mode) {
+	h.maintenance = mode
+}
+
+// GetMaintenance godoc
+// @Summary      Get maintenance mode
+// @Description  Returns whether this replica refuses writes for maintenance, with the banner message. Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  maintenance.State
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/maintenance [get]
+func (h *Handler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if h.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Maintenance mode is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.maintenance.State())
+}
+
+// UpdateMaintenance godoc
+// @Summary      Turn maintenance mode on or off
+// @Description  While maintenance mode is on, this replica answers requests that change data, including re-ingestion, with 503 and the banner message, and serves reads with the message in an X-Maintenance header. The change lasts until the next change or restart. Requires the admin role.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body  MaintenanceRequest  true  "Mode"
+// @Success      200  {object}  maintenance.State
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/maintenance [put]
+func (h *Handler) UpdateMaintenance(w http.ResponseWriter, r *http.Request) {
+	if h.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Maintenance mode is not configured")
+		return
+	}
+
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+	var by string
+	if p, ok := auth.FromContext(r.Context()); ok {
+		by = p.Name
+	}
+	writeJSON(w, http.StatusOK, h.maintenance.Set(req.Enabled, req.Message, by))
+}
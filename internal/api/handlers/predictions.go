@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+const (
+	// defaultPredictionHistory and maxPredictionHistory bound the history
+	// of a GPU's predictions returned
+	defaultPredictionHistory = 24 * time.Hour
+	maxPredictionHistory     = 30 * 24 * time.Hour
+)
+
+// PredictionsResponse lists failure predictions.
+type PredictionsResponse struct {
+	Data  []*models.Prediction `json:"data"`
+	Count int                  `json:"count"`
+}
+
+// predictionStore returns the storage as a PredictionStore, writing 501 if unsupported.
+func (h *Handler) predictionStore(w http.ResponseWriter, r *http.Request) (storage.PredictionStore, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.PredictionStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support predictions")
+	}
+	return store, ok
+}
+
+// ListPredictions godoc
+// @Summary      List GPU failure predictions
+// @Description  Returns the latest failure prediction of every GPU scored in the last 24 hours, highest score first. With API_PREDICT_ENABLED the API scores every GPU each API_PREDICT_INTERVAL with the API_PREDICT_MODEL model over a window of its recent telemetry. Scores run from 0 to 1; risk is high from 0.7 and medium from 0.3.
+// @Tags         predictions
+// @Produce      json
+// @Param        hostname   query  string  false  "Only GPUs on this host"
+// @Param        risk       query  string  false  "Only predictions at this risk (low, medium or high)"
+// @Param        min_score  query  number  false  "Only predictions scoring at least this, 0 to 1"
+// @Param        limit      query  int     false  "Return at most this many predictions"
+// @Success      200  {object}  PredictionsResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/predictions [get]
+func (h *Handler) ListPredictions(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.predictionStore(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	hostname := q.Get("hostname")
+	risk := q.Get("risk")
+	switch risk {
+	case "", models.RiskLow, models.RiskMedium, models.RiskHigh:
+	default:
+		writeError(w, http.StatusBadRequest, "bad_request", "risk must be low, medium or high")
+		return
+	}
+	var minScore float64
+	if s := q.Get("min_score"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || v > 1 {
+			writeBadRequest(w, fmt.Errorf("min_score must be between 0 and 1, got %q", s))
+			return
+		}
+		minScore = v
+	}
+	limit := 0
+	if s := q.Get("limit"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			writeBadRequest(w, fmt.Errorf("limit must be a positive integer, got %q", s))
+			return
+		}
+		limit = v
+	}
+
+	predictions, err := store.ListPredictions(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	filtered := make([]*models.Prediction, 0, len(predictions))
+	for _, p := range predictions {
+		if (hostname == "" || p.Hostname == hostname) && (risk == "" || p.Risk == risk) && p.Score >= minScore {
+			filtered = append(filtered, p)
+		}
+	}
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	writeJSON(w, http.StatusOK, PredictionsResponse{Data: filtered, Count: len(filtered)})
+}
+
+// GetGPUPredictions godoc
+// @Summary      Get a GPU's failure prediction history
+// @Description  Returns the failure predictions scored for a GPU over the window ending at end_time, oldest first, to show how its risk developed.
+// @Tags         predictions
+// @Produce      json
+// @Param        id        path   string  true   "GPU UUID"
+// @Param        window    query  string  false  "How far back from end_time to list (default 24h, max 720h)"
+// @Param        end_time  query  string  false  "End of the window (RFC3339, default now)"
+// @Success      200  {object}  PredictionsResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/gpus/{id}/predictions [get]
+func (h *Handler) GetGPUPredictions(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.predictionStore(w, r)
+	if !ok {
+		return
+	}
+	window, err := parseDuration(r, "window", defaultPredictionHistory)
+	if err == nil && window > maxPredictionHistory {
+		err = fmt.Errorf("window must be at most %v", maxPredictionHistory)
+	}
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	end, err := parseEndTime(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	predictions, err := store.PredictionHistory(r.Context(), mux.Vars(r)["id"], end.Add(-window), end)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, PredictionsResponse{Data: predictions, Count: len(predictions)})
+}
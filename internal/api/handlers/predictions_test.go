@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// predictionStorage adds fixed storage.PredictionStore predictions to mockStorage.
+type predictionStorage struct {
+	*mockStorage
+	predictions []*models.Prediction
+	start, end  time.Time
+}
+
+func (s *predictionStorage) WritePredictions(ctx context.Context, predictions []*models.Prediction) error {
+	s.predictions = append(s.predictions, predictions...)
+	return nil
+}
+
+func (s *predictionStorage) ListPredictions(ctx context.Context) ([]*models.Prediction, error) {
+	return s.predictions, nil
+}
+
+func (s *predictionStorage) PredictionHistory(ctx context.Context, uuid string, start, end time.Time) ([]*models.Prediction, error) {
+	s.start, s.end = start, end
+	var history []*models.Prediction
+	for _, p := range s.predictions {
+		if p.UUID == uuid {
+			history = append(history, p)
+		}
+	}
+	return history, nil
+}
+
+func TestPredictions(t *testing.T) {
+	setup := func(h *Handler) *mux.Router {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/predictions", h.ListPredictions).Methods(http.MethodGet)
+		router.HandleFunc("/api/v1/gpus/{id}/predictions", h.GetGPUPredictions).Methods(http.MethodGet)
+		return router
+	}
+	w := doJSON(t, setup(NewHandler(newMockStorage(), 100, 1000)), http.MethodGet, "/api/v1/predictions", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	store := &predictionStorage{mockStorage: newMockStorage(), predictions: []*models.Prediction{
+		{UUID: "GPU-1", Hostname: "host-1", Score: 0.9, Risk: models.RiskHigh, Reasons: []string{"max DCGM_FI_DEV_XID_ERRORS 2 above 0"}},
+		{UUID: "GPU-2", Hostname: "host-2", Score: 0.4, Risk: models.RiskMedium},
+		{UUID: "GPU-3", Hostname: "host-1", Score: 0.1, Risk: models.RiskLow},
+	}}
+	router := setup(NewHandler(store, 100, 1000))
+	for _, bad := range []string{"?risk=severe", "?min_score=2", "?limit=0"} {
+		w = doJSON(t, router, http.MethodGet, "/api/v1/predictions"+bad, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, bad)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/predictions", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp PredictionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Count)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/predictions?hostname=host-1&min_score=0.3", nil)
+	resp = PredictionsResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, "GPU-1", resp.Data[0].UUID)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/predictions?risk=medium", nil)
+	resp = PredictionsResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, "GPU-2", resp.Data[0].UUID)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/predictions?limit=2", nil)
+	resp = PredictionsResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Count)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-1/predictions?window=48h&end_time=2024-01-03T00:00:00Z", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = PredictionsResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Count)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), store.start)
+	assert.Equal(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), store.end)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-1/predictions?window=2000h", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/msgpack"
+)
+
+// Media types the telemetry endpoints can respond with.
+const (
+	contentTypeJSON    = "application/json"
+	contentTypeMsgpack = msgpack.ContentType
+	contentTypeCSV     = "text/csv"
+)
+
+// mediaAliases maps alternative names clients send to the type served.
+var mediaAliases = map[string]string{
+	"application/x-msgpack":   contentTypeMsgpack,
+	"application/vnd.msgpack": contentTypeMsgpack,
+}
+
+// csvWriter is implemented by responses with a tabular form, which are also
+// offered as text/csv.
+type csvWriter interface {
+	WriteCSV(w io.Writer) error
+}
+
+// offers returns the media types data can be written as, in the server's
+// order of preference.
+func offers(data interface{}) []string {
+	if _, ok := data.(csvWriter); ok {
+		return []string{contentTypeJSON, contentTypeMsgpack, contentTypeCSV}
+	}
+	return []string{contentTypeJSON, contentTypeMsgpack}
+}
+
+// acceptRange is one media range of an Accept header.
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// parseAccept parses an Accept header, skipping malformed ranges.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if alias, ok := mediaAliases[mediaType]; ok {
+			mediaType = alias
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+					q = f
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// negotiate picks the offered media type the request's Accept header
+// prefers. Each offer takes the quality of the most specific range matching
+// it; ties go to the earlier offer. No Accept header selects the first
+// offer, and an Accept header matching no offer selects none.
+func negotiate(r *http.Request, offered []string) (string, bool) {
+	header := r.Header.Get("Accept")
+	if strings.TrimSpace(header) == "" {
+		return offered[0], true
+	}
+	ranges := parseAccept(header)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		typ, subtype, _ := strings.Cut(offer, "/")
+		q, specificity := 0.0, -1
+		for _, ar := range ranges {
+			s := -1
+			switch {
+			case ar.typ == typ && ar.subtype == subtype:
+				s = 2
+			case ar.typ == typ && ar.subtype == "*":
+				s = 1
+			case ar.typ == "*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, best != ""
+}
+
+// acceptable negotiates the response format, writing 406 Not Acceptable
+// when the request accepts none of the offered types. Handlers call it
+// before doing any work, passing offers of their response type.
+func acceptable(w http.ResponseWriter, r *http.Request, offered []string) (string, bool) {
+	contentType, ok := negotiate(r, offered)
+	if !ok {
+		writeError(w, http.StatusNotAcceptable, "not_acceptable",
+			fmt.Sprintf("Unsupported Accept header %q; this endpoint serves %s", r.Header.Get("Accept"), strings.Join(offered, ", ")))
+	}
+	return contentType, ok
+}
+
+// writeAs writes data as the given media type, which must be one offers
+// returned for it.
+func writeAs(w http.ResponseWriter, contentType string, status int, data interface{}) {
+	// Encode before writing the header so a failure can still be reported
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case contentTypeMsgpack:
+		err = msgpack.NewEncoder(&buf).Encode(data)
+	case contentTypeCSV:
+		err = data.(csvWriter).WriteCSV(&buf)
+	default:
+		err = json.NewEncoder(&buf).Encode(data)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to encode response: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/msgpack"
+)
+
+func TestNegotiate(t *testing.T) {
+	tabular := []string{contentTypeJSON, contentTypeMsgpack, contentTypeCSV}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", contentTypeJSON},
+		{"*/*", contentTypeJSON},
+		{"application/msgpack", contentTypeMsgpack},
+		{"application/x-msgpack", contentTypeMsgpack},
+		{"text/csv", contentTypeCSV},
+		{"text/*", contentTypeCSV},
+		{"application/*", contentTypeJSON},
+		{"application/json;q=0.5, application/msgpack", contentTypeMsgpack},
+		{"text/csv;q=0.9, application/*;q=0.8", contentTypeCSV},
+		{"application/json;q=0, */*", contentTypeMsgpack},
+		{"text/html, application/xhtml+xml, */*;q=0.8", contentTypeJSON},
+		{"text/html", ""},
+		{"application/json;q=0", ""},
+		{"garbage", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		got, ok := negotiate(req, tabular)
+		assert.Equal(t, tt.want, got, "Accept: %s", tt.accept)
+		assert.Equal(t, tt.want != "", ok, "Accept: %s", tt.accept)
+	}
+
+	// CSV is only offered for tabular responses
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/csv")
+	_, ok := negotiate(req, offers(ExplainResponse{}))
+	assert.False(t, ok)
+}
+
+func getWithAccept(t *testing.T, handler http.Handler, path, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetGPUTelemetryFormats(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	router := setupTestRouter(store)
+	path := "/api/v1/gpus/GPU-12345-AAAA/telemetry"
+
+	w := getWithAccept(t, router, path, "application/msgpack")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, contentTypeMsgpack, w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	decoded, err := msgpack.Decode(w.Body.Bytes())
+	require.NoError(t, err)
+	body := decoded.(map[string]any)
+	assert.Equal(t, int64(5), body["count"])
+	point := body["data"].([]any)[0].(map[string]any)
+	assert.Equal(t, "GPU-12345-AAAA", point["uuid"])
+	assert.Equal(t, "DCGM_FI_DEV_GPU_UTIL", point["metric_name"])
+	_, err = time.Parse(time.RFC3339Nano, point["timestamp"].(string))
+	assert.NoError(t, err, "timestamps are encoded as in JSON")
+
+	w = getWithAccept(t, router, path, "text/csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, contentTypeCSV, w.Header().Get("Content-Type"))
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, rows, 6)
+
+	w = getWithAccept(t, router, path, "application/xml")
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Contains(t, w.Body.String(), "not_acceptable")
+	assert.Equal(t, contentTypeJSON, w.Header().Get("Content-Type"))
+}
+
+func TestExportGPUTelemetryFormats(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	router := setupTestRouter(store)
+	router.HandleFunc("/api/v1/gpus/{id}/telemetry/export", NewHandler(store, 100, 1000).ExportGPUTelemetry).Methods(http.MethodGet)
+	path := "/api/v1/gpus/GPU-12345-AAAA/telemetry/export"
+
+	// The format parameter wins over the Accept header
+	w := getWithAccept(t, router, path+"?format=csv", "application/json")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, contentTypeCSV, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "telemetry-GPU-12345-AAAA.csv")
+
+	w = getWithAccept(t, router, path, "application/vnd.msgpack")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, contentTypeMsgpack, w.Header().Get("Content-Type"))
+
+	w = getWithAccept(t, router, path+"?format=msgpack", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, contentTypeMsgpack, w.Header().Get("Content-Type"))
+
+	w = getWithAccept(t, router, path+"?format=xml", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetSnapshotCSV(t *testing.T) {
+	latest := cache.NewLatest(cache.SourceStorage)
+	now := time.Date(2025, 7, 18, 13, 42, 33, 0, time.UTC)
+	latest.Update([]*models.GPUMetric{
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 80, Timestamp: now},
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 65.25, Timestamp: now},
+	})
+	handler := NewHandler(newMockStorage(), 100, 1000)
+	handler.SetLatestCache(latest)
+
+	w := getWithAccept(t, http.HandlerFunc(handler.GetSnapshot), "/api/v1/snapshot", "text/csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "Hostname,UUID,GPUID,Device,ModelName,MetricName,Value,Timestamp", lines[0])
+	assert.Equal(t, "host-001,GPU-1,0,,,DCGM_FI_DEV_GPU_TEMP,65.25,2025-07-18T13:42:33Z", lines[1])
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// SavedFilterRequest is the body for saving a filter under the name in the path.
+type SavedFilterRequest struct {
+	Description string            `json:"description,omitempty" example:"Training nodes in rack 4"`
+	UUIDs       []string          `json:"uuids,omitempty"`
+	Hostnames   []string          `json:"hostnames,omitempty" example:"host-001,host-002"`
+	Metrics     []string          `json:"metrics,omitempty" example:"DCGM_FI_DEV_GPU_UTIL"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// SavedFilterListResponse represents the response for listing saved filters.
+type SavedFilterListResponse struct {
+	Data  []*models.SavedFilter `json:"data"`
+	Count int                   `json:"count" example:"3"`
+}
+
+// savedFilterStore returns the backend's SavedFilterStore, writing a 501 if it has none.
+func (h *Handler) savedFilterStore(w http.ResponseWriter, r *http.Request) (storage.SavedFilterStore, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.SavedFilterStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support saved filters")
+	}
+	return store, ok
+}
+
+// savedFilter resolves the filter query parameter. It returns nil when the
+// request names no filter, and writes an error if the filter is unknown.
+func (h *Handler) savedFilter(w http.ResponseWriter, r *http.Request) (*models.SavedFilter, bool) {
+	name := r.URL.Query().Get("filter")
+	if name == "" {
+		return nil, true
+	}
+	store, ok := h.savedFilterStore(w, r)
+	if !ok {
+		return nil, false
+	}
+	filter, err := store.GetFilter(r.Context(), name)
+	if err != nil {
+		writeStoreError(w, err)
+		return nil, false
+	}
+	return filter, true
+}
+
+// SaveFilter godoc
+// @Summary      Create or replace a saved filter
+// @Description  Saves a named set of GPUs, hosts, metrics and labels. The telemetry, export and heatmap endpoints apply it when called with filter=name. Each list matches any of its values and every label must match. Label keys are the stored series tags: gpu_id, device, model, container, pod and namespace.
+// @Tags         filters
+// @Accept       json
+// @Produce      json
+// @Param        name    path  string              true  "Filter name"
+// @Param        filter  body  SavedFilterRequest  true  "Filter"
+// @Success      200  {object}  models.SavedFilter
+// @Success      201  {object}  models.SavedFilter
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/filters/{name} [put]
+func (h *Handler) SaveFilter(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedFilterStore(w, r)
+	if !ok {
+		return
+	}
+	var req SavedFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	filter := &models.SavedFilter{
+		Name:        mux.Vars(r)["name"],
+		Description: req.Description,
+		UUIDs:       req.UUIDs,
+		Hostnames:   req.Hostnames,
+		Labels:      req.Labels,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	// Metrics are matched under the names they are stored with
+	for _, metric := range req.Metrics {
+		filter.Metrics = append(filter.Metrics, h.aliases.Canonical(metric))
+	}
+	if err := filter.Validate(); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if _, err := store.GetFilter(r.Context(), filter.Name); err == nil {
+		status = http.StatusOK
+	}
+	if err := store.SaveFilter(r.Context(), filter); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, status, filter)
+}
+
+// ListSavedFilters godoc
+// @Summary      List saved filters
+// @Tags         filters
+// @Produce      json
+// @Success      200  {object}  SavedFilterListResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/filters [get]
+func (h *Handler) ListSavedFilters(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedFilterStore(w, r)
+	if !ok {
+		return
+	}
+
+	filters, err := store.ListFilters(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SavedFilterListResponse{
+		Data:  filters,
+		Count: len(filters),
+	})
+}
+
+// GetSavedFilter godoc
+// @Summary      Get a saved filter
+// @Tags         filters
+// @Produce      json
+// @Param        name  path  string  true  "Filter name"
+// @Success      200  {object}  models.SavedFilter
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/filters/{name} [get]
+func (h *Handler) GetSavedFilter(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedFilterStore(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := store.GetFilter(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, filter)
+}
+
+// DeleteSavedFilter godoc
+// @Summary      Delete a saved filter
+// @Description  Removes the filter; requests still naming it fail with 404
+// @Tags         filters
+// @Param        name  path  string  true  "Filter name"
+// @Success      204
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/filters/{name} [delete]
+func (h *Handler) DeleteSavedFilter(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedFilterStore(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.DeleteFilter(r.Context(), mux.Vars(r)["name"]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
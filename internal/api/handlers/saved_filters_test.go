@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// savedFilterStorage adds an in-memory storage.SavedFilterStore to
+// heatmapStorage, so filters apply to telemetry and heatmaps alike.
+type savedFilterStorage struct {
+	*heatmapStorage
+	mu      sync.Mutex
+	filters map[string]*models.SavedFilter
+}
+
+func newSavedFilterStorage() *savedFilterStorage {
+	return &savedFilterStorage{
+		heatmapStorage: &heatmapStorage{mockStorage: newMockStorage()},
+		filters:        make(map[string]*models.SavedFilter),
+	}
+}
+
+func (s *savedFilterStorage) SaveFilter(ctx context.Context, f *models.SavedFilter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.filters[f.Name]; ok {
+		f.CreatedAt = existing.CreatedAt
+	}
+	cp := *f
+	s.filters[f.Name] = &cp
+	return nil
+}
+
+func (s *savedFilterStorage) GetFilter(ctx context.Context, name string) (*models.SavedFilter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.filters[name]
+	if !ok {
+		return nil, perrors.NotFound(fmt.Errorf("saved filter %q not found", name))
+	}
+	cp := *f
+	return &cp, nil
+}
+
+func (s *savedFilterStorage) ListFilters(ctx context.Context) ([]*models.SavedFilter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*models.SavedFilter, 0, len(s.filters))
+	for _, f := range s.filters {
+		cp := *f
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (s *savedFilterStorage) DeleteFilter(ctx context.Context, name string) error {
+	if _, err := s.GetFilter(ctx, name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.filters, name)
+	return nil
+}
+
+func setupSavedFilterRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/filters", h.ListSavedFilters).Methods(http.MethodGet)
+	api.HandleFunc("/filters/{name}", h.GetSavedFilter).Methods(http.MethodGet)
+	api.HandleFunc("/filters/{name}", h.SaveFilter).Methods(http.MethodPut)
+	api.HandleFunc("/filters/{name}", h.DeleteSavedFilter).Methods(http.MethodDelete)
+	api.HandleFunc("/gpus/{id}/telemetry", h.GetGPUTelemetry).Methods(http.MethodGet)
+	api.HandleFunc("/gpus/{id}/telemetry/export", h.ExportGPUTelemetry).Methods(http.MethodGet)
+	api.HandleFunc("/heatmap", h.GetHeatmap).Methods(http.MethodGet)
+	return router
+}
+
+func TestSavedFilterCRUD(t *testing.T) {
+	h := NewHandler(newSavedFilterStorage(), 100, 1000)
+	h.SetMetricAliases(models.MetricAliases{"gpu_util": "DCGM_FI_DEV_GPU_UTIL"})
+	router := setupSavedFilterRouter(h)
+
+	request := SavedFilterRequest{Hostnames: []string{"host-001"}, Metrics: []string{"gpu_util"}}
+	w := doJSON(t, router, http.MethodPut, "/api/v1/filters/rack-4", request)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.SavedFilter
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "rack-4", created.Name)
+	assert.Equal(t, []string{"DCGM_FI_DEV_GPU_UTIL"}, created.Metrics, "metrics are saved under their stored names")
+
+	request.Description = "Rack 4"
+	w = doJSON(t, router, http.MethodPut, "/api/v1/filters/rack-4", request)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/filters/rack-4", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var fetched models.SavedFilter
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.Equal(t, "Rack 4", fetched.Description)
+	assert.True(t, fetched.CreatedAt.Equal(created.CreatedAt))
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/filters", nil)
+	var list SavedFilterListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Count)
+
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/filters/rack-4", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = doJSON(t, router, http.MethodGet, "/api/v1/filters/rack-4", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSaveFilterValidation(t *testing.T) {
+	router := setupSavedFilterRouter(NewHandler(newSavedFilterStorage(), 100, 1000))
+	for name, request := range map[string]SavedFilterRequest{
+		"empty":     {},
+		"bad-label": {Labels: map[string]string{"rack": "4"}},
+		"quoted":    {Hostnames: []string{`host" or true`}},
+	} {
+		w := doJSON(t, router, http.MethodPut, "/api/v1/filters/"+name, request)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+
+	router = setupSavedFilterRouter(NewHandler(newMockStorage(), 100, 1000))
+	w := doJSON(t, router, http.MethodGet, "/api/v1/filters", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	w = doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-1/telemetry?filter=rack-4", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestTelemetryWithSavedFilter(t *testing.T) {
+	store := newSavedFilterStorage()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for i, metric := range []*models.GPUMetric{
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Pod: "train-0", Value: 90},
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_TEMP", Pod: "train-0", Value: 70},
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_POWER_USAGE", Pod: "train-0", Value: 300},
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Pod: "idle", Value: 0},
+	} {
+		metric.Timestamp = now.Add(-time.Duration(i) * time.Minute)
+		require.NoError(t, store.Store(ctx, metric))
+	}
+	require.NoError(t, store.SaveFilter(ctx, &models.SavedFilter{
+		Name:    "training",
+		Metrics: []string{"DCGM_FI_DEV_GPU_UTIL", "DCGM_FI_DEV_GPU_TEMP"},
+		Labels:  map[string]string{"pod": "train-0"},
+	}))
+	require.NoError(t, store.SaveFilter(ctx, &models.SavedFilter{Name: "other-gpus", UUIDs: []string{"GPU-9"}}))
+	router := setupSavedFilterRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-1/telemetry?filter=training", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp TelemetryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Count, "only the filter's metrics from its pod")
+	assert.Equal(t, 90.0, resp.Data[0].Value)
+	assert.Equal(t, 70.0, resp.Data[1].Value)
+
+	// The limit applies after the filter
+	w = doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-1/telemetry/export?format=json&filter=training&offset=1&limit=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, 70.0, resp.Data[0].Value)
+
+	// A filter excluding the GPU or the requested metric matches nothing
+	for _, path := range []string{
+		"/api/v1/gpus/GPU-1/telemetry?filter=other-gpus",
+		"/api/v1/gpus/GPU-1/telemetry?filter=training&metric_name=DCGM_FI_DEV_POWER_USAGE",
+	} {
+		w = doJSON(t, router, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, w.Code, path)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Zero(t, resp.Count, path)
+	}
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/gpus/GPU-1/telemetry?filter=missing", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHeatmapWithSavedFilter(t *testing.T) {
+	store := newSavedFilterStorage()
+	ctx := context.Background()
+	require.NoError(t, store.SaveFilter(ctx, &models.SavedFilter{
+		Name: "host-2-util", Hostnames: []string{"host-002"}, Metrics: []string{"DCGM_FI_DEV_GPU_UTIL"},
+	}))
+	require.NoError(t, store.SaveFilter(ctx, &models.SavedFilter{Name: "by-pod", Labels: map[string]string{"pod": "train-0"}}))
+	router := setupSavedFilterRouter(NewHandler(store, 100, 1000))
+
+	// The filter's one metric stands in for the metric parameter
+	w := doJSON(t, router, http.MethodGet, "/api/v1/heatmap?filter=host-2-util", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp HeatmapResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "DCGM_FI_DEV_GPU_UTIL", resp.Metric)
+	assert.Equal(t, "host-002", store.last.Hostname)
+	assert.Equal(t, 1, resp.TotalRows)
+	require.Len(t, resp.Rows, 1)
+	assert.Equal(t, "GPU-2", resp.Rows[0].UUID)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/heatmap?filter=host-2-util&metric=DCGM_FI_DEV_GPU_TEMP", nil)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Zero(t, resp.TotalRows, "the filter excludes the metric")
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/heatmap?filter=by-pod&metric=DCGM_FI_DEV_GPU_UTIL", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "heatmap rows carry no labels")
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// defaultRunsLimit is how many runs GET /saved-queries/{id}/runs returns by default.
+const defaultRunsLimit = 20
+
+// SavedQueryRequest is the body for creating or replacing a saved query.
+type SavedQueryRequest struct {
+	Name     string                `json:"name" example:"nightly-utilization"`
+	Query    models.SavedQuerySpec `json:"query"`
+	Schedule string                `json:"schedule" example:"24h"`
+	Format   string                `json:"format,omitempty" example:"csv"`
+	Target   models.DeliveryTarget `json:"target"`
+	Enabled  *bool                 `json:"enabled,omitempty"`
+}
+
+// SavedQueryListResponse represents the response for listing saved queries.
+type SavedQueryListResponse struct {
+	Data  []*models.SavedQuery `json:"data"`
+	Count int                  `json:"count" example:"2"`
+}
+
+// SavedQueryRunsResponse represents a saved query's run history.
+type SavedQueryRunsResponse struct {
+	Data  []*models.SavedQueryRun `json:"data"`
+	Count int                     `json:"count" example:"5"`
+}
+
+// savedQueryStore returns the backend's SavedQueryStore, writing a 501 if it has none.
+func (h *Handler) savedQueryStore(w http.ResponseWriter, r *http.Request) (storage.SavedQueryStore, bool) {
+	store, ok := h.storeFor(r.Context()).(storage.SavedQueryStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support saved queries")
+	}
+	return store, ok
+}
+
+// SetDeliveryAllowlist sets the targets saved queries may deliver to. Until
+// it is set, every target is refused.
+func (h *Handler) SetDeliveryAllowlist(allow models.DeliveryAllowlist) {
+	h.deliveries = allow
+}
+
+// decodeSavedQuery reads and validates a SavedQueryRequest into a SavedQuery.
+// Format defaults to json and Enabled to true.
+func (h *Handler) decodeSavedQuery(w http.ResponseWriter, r *http.Request) (*models.SavedQuery, bool) {
+	var req SavedQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid JSON body: "+err.Error())
+		return nil, false
+	}
+
+	query := &models.SavedQuery{
+		Name:     req.Name,
+		Query:    req.Query,
+		Schedule: req.Schedule,
+		Format:   req.Format,
+		Target:   req.Target,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+	if query.Format == "" {
+		query.Format = "json"
+	}
+	if err := query.Validate(); err != nil {
+		writeBadRequest(w, err)
+		return nil, false
+	}
+	if err := h.deliveries.Check(query.Target); err != nil {
+		writeBadRequest(w, err)
+		return nil, false
+	}
+	return query, true
+}
+
+// CreateSavedQuery godoc
+// @Summary      Create a saved query
+// @Description  Registers a telemetry query that the API runs on a schedule and delivers to a webhook, email recipients or an S3 bucket on the configured delivery allowlist. Requires the admin role.
+// @Tags         saved-queries
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        query  body  SavedQueryRequest  true  "Saved query"
+// @Success      201  {object}  models.SavedQuery
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/saved-queries [post]
+func (h *Handler) CreateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedQueryStore(w, r)
+	if !ok {
+		return
+	}
+	query, ok := h.decodeSavedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	query.ID = uuid.New().String()
+	query.CreatedAt = now
+	query.UpdatedAt = now
+
+	if err := store.CreateSavedQuery(r.Context(), query); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, query)
+}
+
+// ListSavedQueries godoc
+// @Summary      List saved queries
+// @Tags         saved-queries
+// @Produce      json
+// @Success      200  {object}  SavedQueryListResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/saved-queries [get]
+func (h *Handler) ListSavedQueries(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedQueryStore(w, r)
+	if !ok {
+		return
+	}
+
+	queries, err := store.ListSavedQueries(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SavedQueryListResponse{
+		Data:  queries,
+		Count: len(queries),
+	})
+}
+
+// GetSavedQuery godoc
+// @Summary      Get a saved query
+// @Tags         saved-queries
+// @Produce      json
+// @Param        id   path  string  true  "Saved query ID"
+// @Success      200  {object}  models.SavedQuery
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/saved-queries/{id} [get]
+func (h *Handler) GetSavedQuery(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedQueryStore(w, r)
+	if !ok {
+		return
+	}
+
+	query, err := store.GetSavedQuery(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, query)
+}
+
+// UpdateSavedQuery godoc
+// @Summary      Replace a saved query
+// @Description  Requires the admin role.
+// @Tags         saved-queries
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path  string             true  "Saved query ID"
+// @Param        query  body  SavedQueryRequest  true  "Saved query"
+// @Success      200  {object}  models.SavedQuery
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/saved-queries/{id} [put]
+func (h *Handler) UpdateSavedQuery(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedQueryStore(w, r)
+	if !ok {
+		return
+	}
+	query, ok := h.decodeSavedQuery(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := store.GetSavedQuery(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	query.ID = existing.ID
+	query.CreatedAt = existing.CreatedAt
+	query.UpdatedAt = time.Now().UTC()
+
+	if err := store.UpdateSavedQuery(r.Context(), query); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, query)
+}
+
+// DeleteSavedQuery godoc
+// @Summary      Delete a saved query
+// @Description  Removes the saved query and its run history. Requires the admin role.
+// @Tags         saved-queries
+// @Security     BearerAuth
+// @Param        id   path  string  true  "Saved query ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/saved-queries/{id} [delete]
+func (h *Handler) DeleteSavedQuery(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedQueryStore(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.DeleteSavedQuery(r.Context(), mux.Vars(r)["id"]); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSavedQueryRuns godoc
+// @Summary      List a saved query's runs
+// @Description  Returns the most recent scheduled runs, newest first
+// @Tags         saved-queries
+// @Produce      json
+// @Param        id     path   string  true   "Saved query ID"
+// @Param        limit  query  int     false  "Maximum number of runs (default 20)"
+// @Success      200  {object}  SavedQueryRunsResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/saved-queries/{id}/runs [get]
+func (h *Handler) ListSavedQueryRuns(w http.ResponseWriter, r *http.Request) {
+	store, ok := h.savedQueryStore(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultRunsLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid limit parameter")
+			return
+		}
+		limit = min(l, h.maxLimit)
+	}
+
+	id := mux.Vars(r)["id"]
+	if _, err := store.GetSavedQuery(r.Context(), id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	runs, err := store.ListRuns(r.Context(), id, limit)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, SavedQueryRunsResponse{
+		Data:  runs,
+		Count: len(runs),
+	})
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// savedQueryStorage adds an in-memory storage.SavedQueryStore to mockStorage.
+type savedQueryStorage struct {
+	*mockStorage
+	mu      sync.Mutex
+	queries map[string]*models.SavedQuery
+	runs    []*models.SavedQueryRun
+}
+
+func newSavedQueryStorage() *savedQueryStorage {
+	return &savedQueryStorage{mockStorage: newMockStorage(), queries: make(map[string]*models.SavedQuery)}
+}
+
+func (s *savedQueryStorage) CreateSavedQuery(ctx context.Context, q *models.SavedQuery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *q
+	s.queries[q.ID] = &cp
+	return nil
+}
+
+func (s *savedQueryStorage) GetSavedQuery(ctx context.Context, id string) (*models.SavedQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[id]
+	if !ok {
+		return nil, perrors.NotFound(fmt.Errorf("saved query %q not found", id))
+	}
+	cp := *q
+	return &cp, nil
+}
+
+func (s *savedQueryStorage) ListSavedQueries(ctx context.Context) ([]*models.SavedQuery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.SavedQuery
+	for _, q := range s.queries {
+		cp := *q
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func (s *savedQueryStorage) UpdateSavedQuery(ctx context.Context, q *models.SavedQuery) error {
+	if _, err := s.GetSavedQuery(ctx, q.ID); err != nil {
+		return err
+	}
+	return s.CreateSavedQuery(ctx, q)
+}
+
+func (s *savedQueryStorage) DeleteSavedQuery(ctx context.Context, id string) error {
+	if _, err := s.GetSavedQuery(ctx, id); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.queries, id)
+	return nil
+}
+
+func (s *savedQueryStorage) RecordRun(ctx context.Context, run *models.SavedQueryRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs = append([]*models.SavedQueryRun{run}, s.runs...)
+	return nil
+}
+
+func (s *savedQueryStorage) ListRuns(ctx context.Context, queryID string, limit int) ([]*models.SavedQueryRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*models.SavedQueryRun
+	for _, run := range s.runs {
+		if run.QueryID == queryID && len(out) < limit {
+			out = append(out, run)
+		}
+	}
+	return out, nil
+}
+
+func setupSavedQueryRouter(h *Handler) *mux.Router {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/saved-queries", h.ListSavedQueries).Methods(http.MethodGet)
+	api.HandleFunc("/saved-queries", h.CreateSavedQuery).Methods(http.MethodPost)
+	api.HandleFunc("/saved-queries/{id}", h.GetSavedQuery).Methods(http.MethodGet)
+	api.HandleFunc("/saved-queries/{id}", h.UpdateSavedQuery).Methods(http.MethodPut)
+	api.HandleFunc("/saved-queries/{id}", h.DeleteSavedQuery).Methods(http.MethodDelete)
+	api.HandleFunc("/saved-queries/{id}/runs", h.ListSavedQueryRuns).Methods(http.MethodGet)
+	return router
+}
+
+func TestSavedQueryCRUD(t *testing.T) {
+	store := newSavedQueryStorage()
+	h := NewHandler(store, 100, 1000)
+	h.SetDeliveryAllowlist(models.DeliveryAllowlist{WebhookHosts: []string{"example.com"}})
+	router := setupSavedQueryRouter(h)
+
+	request := SavedQueryRequest{
+		Name:     "nightly",
+		Query:    models.SavedQuerySpec{Hostname: "host-001", Window: "24h"},
+		Schedule: "24h",
+		Target:   models.DeliveryTarget{Type: models.DeliveryWebhook, URL: "https://example.com/hook"},
+	}
+	w := doJSON(t, router, http.MethodPost, "/api/v1/saved-queries", request)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.SavedQuery
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.NotEmpty(t, created.ID)
+	assert.Equal(t, "json", created.Format)
+	assert.True(t, created.Enabled)
+
+	disabled := false
+	request.Enabled = &disabled
+	request.Format = "csv"
+	w = doJSON(t, router, http.MethodPut, "/api/v1/saved-queries/"+created.ID, request)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/saved-queries/"+created.ID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var fetched models.SavedQuery
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.False(t, fetched.Enabled)
+	assert.Equal(t, "csv", fetched.Format)
+	assert.True(t, fetched.CreatedAt.Equal(created.CreatedAt))
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/saved-queries", nil)
+	var list SavedQueryListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, 1, list.Count)
+
+	w = doJSON(t, router, http.MethodDelete, "/api/v1/saved-queries/"+created.ID, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/saved-queries/"+created.ID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateSavedQueryValidation(t *testing.T) {
+	router := setupSavedQueryRouter(NewHandler(newSavedQueryStorage(), 100, 1000))
+
+	w := doJSON(t, router, http.MethodPost, "/api/v1/saved-queries", SavedQueryRequest{
+		Name: "too-often", Schedule: "10s", Query: models.SavedQuerySpec{Window: "1h"},
+		Target: models.DeliveryTarget{Type: models.DeliveryEmail},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Valid targets off the delivery allowlist are refused too
+	w = doJSON(t, router, http.MethodPost, "/api/v1/saved-queries", SavedQueryRequest{
+		Name: "exfiltrate", Schedule: "1h", Query: models.SavedQuerySpec{Window: "1h"},
+		Target: models.DeliveryTarget{Type: models.DeliveryWebhook, URL: "http://169.254.169.254/latest"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "allowed webhook host")
+
+	router = setupSavedQueryRouter(NewHandler(newMockStorage(), 100, 1000))
+	w = doJSON(t, router, http.MethodGet, "/api/v1/saved-queries", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestListSavedQueryRuns(t *testing.T) {
+	store := newSavedQueryStorage()
+	ctx := context.Background()
+	require.NoError(t, store.CreateSavedQuery(ctx, &models.SavedQuery{ID: "q-1", Name: "nightly"}))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, store.RecordRun(ctx, &models.SavedQueryRun{
+			ID: fmt.Sprintf("r-%d", i), QueryID: "q-1", StartedAt: start.Add(time.Duration(i) * time.Hour), Status: models.RunSucceeded,
+		}))
+	}
+	router := setupSavedQueryRouter(NewHandler(store, 100, 1000))
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/saved-queries/q-1/runs?limit=2", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response SavedQueryRunsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, 2, response.Count)
+	assert.Equal(t, "r-2", response.Data[0].ID)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/saved-queries/missing/runs", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/saved-queries/q-1/runs?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/schemas"
+)
+
+// SchemaInfo describes one published JSON Schema.
+type SchemaInfo struct {
+	Name  string `json:"name" example:"metric-batch"`
+	Title string `json:"title" example:"MetricBatch"`
+	ID    string `json:"id" example:"https://github.com/cisco/gpu-telemetry-pipeline/schemas/metric-batch.schema.json"`
+
+	// Description says where the format is used
+	Description string `json:"description"`
+}
+
+// SchemaListResponse represents the published JSON Schemas.
+type SchemaListResponse struct {
+	Data  []SchemaInfo `json:"data"`
+	Count int          `json:"count" example:"3"`
+}
+
+// ListSchemas godoc
+// @Summary      List wire format schemas
+// @Description  Lists the JSON Schemas of the pipeline's wire formats, generated from the types that decode them. Producers in other languages can validate payloads against them before publishing.
+// @Tags         schemas
+// @Produce      json
+// @Success      200  {object}  SchemaListResponse
+// @Router       /api/v1/schemas [get]
+func (h *Handler) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	docs := schemas.All()
+	resp := SchemaListResponse{Data: make([]SchemaInfo, 0, len(docs)), Count: len(docs)}
+	for _, d := range docs {
+		resp.Data = append(resp.Data, SchemaInfo{
+			Name:        d.Name,
+			Title:       d.Schema.Title,
+			ID:          d.Schema.ID,
+			Description: d.Schema.Description,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetSchema godoc
+// @Summary      Get a wire format schema
+// @Description  Returns a JSON Schema (draft 2020-12) document, identical to the one checked in under schemas/.
+// @Tags         schemas
+// @Produce      json
+// @Param        name  path  string  true  "Schema name"  example(metric-batch)
+// @Success      200  {object}  map[string]interface{}
+// @Failure      404  {object}  ErrorResponse
+// @Router       /api/v1/schemas/{name} [get]
+func (h *Handler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	doc, ok := schemas.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Unknown schema: "+name)
+		return
+	}
+	data, err := schemas.Marshal(doc)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
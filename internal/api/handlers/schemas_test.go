@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemas(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/schemas", h.ListSchemas).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/schemas/{name}", h.GetSchema).Methods(http.MethodGet)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/schemas", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list SchemaListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, 3, list.Count)
+	assert.Equal(t, "metric-batch", list.Data[1].Name)
+	assert.Equal(t, "MetricBatch", list.Data[1].Title)
+
+	// Served byte for byte as published
+	w = doJSON(t, router, http.MethodGet, "/api/v1/schemas/metric-batch", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+	published, err := os.ReadFile("../../../schemas/metric-batch.schema.json")
+	require.NoError(t, err)
+	assert.Equal(t, string(published), w.Body.String())
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/schemas/nope", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// searchFields maps a search endpoint to the storage tag it looks up.
+var searchFields = map[string]string{
+	"hostnames": storage.TagHostname,
+	"uuids":     storage.TagUUID,
+	"pods":      storage.TagPod,
+	"metrics":   storage.TagMetricName,
+}
+
+// defaultSearchLimit keeps autocomplete responses small unless asked otherwise.
+const defaultSearchLimit = 20
+
+// SearchResponse represents the values matching a search prefix.
+type SearchResponse struct {
+	Field     string   `json:"field" example:"hostnames"`
+	Query     string   `json:"query" example:"host-0"`
+	Data      []string `json:"data"`
+	Count     int      `json:"count" example:"20"`
+	Truncated bool     `json:"truncated"`
+}
+
+// Search godoc
+// @Summary      Search field values
+// @Description  Returns known hostnames, GPU UUIDs, pod names or metric names starting with a prefix (case-insensitive), for dashboard autocomplete
+// @Tags         search
+// @Produce      json
+// @Param        field  path   string  true   "Field to search"  enum(hostnames,uuids,pods,metrics)
+// @Param        q      query  string  false  "Prefix to match"
+// @Param        limit  query  int     false  "Maximum results"  default(20)
+// @Success      200  {object}  SearchResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/search/{field} [get]
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	field := mux.Vars(r)["field"]
+	tag, ok := searchFields[field]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Unknown search field. Use hostnames, uuids, pods or metrics")
+		return
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "Invalid limit parameter")
+			return
+		}
+		limit = min(l, h.maxLimit)
+	}
+	prefix := r.URL.Query().Get("q")
+
+	values, err := h.tagValues(r.Context(), tag)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	lower := strings.ToLower(prefix)
+	matches := make([]string, 0)
+	for _, v := range values {
+		if strings.HasPrefix(strings.ToLower(v), lower) {
+			matches = append(matches, v)
+		}
+	}
+	sort.Strings(matches)
+
+	truncated := len(matches) > limit
+	if truncated {
+		matches = matches[:limit]
+	}
+
+	writeJSON(w, http.StatusOK, SearchResponse{
+		Field:     field,
+		Query:     prefix,
+		Data:      matches,
+		Count:     len(matches),
+		Truncated: truncated,
+	})
+}
+
+// tagValues returns the distinct values of tag, from the backend's tag index
+// when it implements storage.TagValuesReader and by sampling each GPU's
+// recent telemetry otherwise.
+func (h *Handler) tagValues(ctx context.Context, tag string) ([]string, error) {
+	if reader, ok := h.storeFor(ctx).(storage.TagValuesReader); ok {
+		return reader.GetTagValues(ctx, tag)
+	}
+
+	gpus, err := h.storeFor(ctx).GetGPUs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tag == storage.TagUUID {
+		return gpus, nil
+	}
+
+	seen := make(map[string]struct{})
+	for _, uuid := range gpus {
+		metrics, err := h.storeFor(ctx).GetTelemetry(ctx, &models.TelemetryQuery{UUID: uuid, Limit: 100})
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range metrics {
+			var v string
+			switch tag {
+			case storage.TagHostname:
+				v = m.Hostname
+			case storage.TagPod:
+				v = m.Pod
+			case storage.TagMetricName:
+				v = m.MetricName
+			}
+			if v != "" {
+				seen[v] = struct{}{}
+			}
+		}
+	}
+
+	values := make([]string, 0, len(seen))
+	for v := range seen {
+		values = append(values, v)
+	}
+	return values, nil
+}
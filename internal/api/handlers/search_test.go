@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+)
+
+func setupSearchRouter(store storage.ReadStorage) *mux.Router {
+	router := mux.NewRouter()
+	handler := NewHandler(store, 100, 1000)
+	router.HandleFunc("/api/v1/search/{field}", handler.Search).Methods(http.MethodGet)
+	return router
+}
+
+func TestSearchHostnamesFallback(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	router := setupSearchRouter(store)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/search/hostnames?q=HOST-00", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response SearchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"host-001", "host-002"}, response.Data)
+	assert.False(t, response.Truncated)
+}
+
+func TestSearchUUIDPrefixLimit(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	router := setupSearchRouter(store)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/search/uuids?q=GPU-12345&limit=1", nil)
+	router.ServeHTTP(w, req)
+
+	var response SearchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"GPU-12345-AAAA"}, response.Data)
+	assert.True(t, response.Truncated)
+}
+
+// tagStorage adds storage.TagValuesReader to mockStorage.
+type tagStorage struct {
+	*mockStorage
+	tags map[string][]string
+}
+
+func (s *tagStorage) GetTagValues(ctx context.Context, tag string) ([]string, error) {
+	return s.tags[tag], nil
+}
+
+func TestSearchUsesTagIndex(t *testing.T) {
+	store := &tagStorage{mockStorage: newMockStorage(), tags: map[string][]string{
+		storage.TagPod: {"trainer-1", "trainer-0", "inference-0"},
+	}}
+	router := setupSearchRouter(store)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/search/pods?q=train", nil)
+	router.ServeHTTP(w, req)
+
+	var response SearchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{"trainer-0", "trainer-1"}, response.Data)
+}
+
+func TestSearchUnknownField(t *testing.T) {
+	router := setupSearchRouter(newMockStorage())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/search/models", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/search/hostnames?limit=0", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
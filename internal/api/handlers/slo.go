@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/slo"
+)
+
+// SetSLO sets the tracker whose ingest-latency objective is served.
+func (h *Handler) SetSLO(tracker *slo.Tracker) {
+	h.slo = tracker
+}
+
+// GetSLO godoc
+// @Summary      Get ingest-latency SLO compliance
+// @Description  Returns the share of metrics stored within the target latency of collection over the sliding window, the error budget left, and the burn rates over the short and long alerting windows. Computed from batch lineage; replayed batches are not counted.
+// @Tags         system
+// @Produce      json
+// @Success      200  {object}  models.SLOStatus
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/pipeline/slo [get]
+func (h *Handler) GetSLO(w http.ResponseWriter, r *http.Request) {
+	if h.slo == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "The ingest-latency SLO is not enabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.slo.Status())
+}
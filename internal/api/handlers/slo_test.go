@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/slo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// recentLineage serves the same lineage for every range.
+type recentLineage struct {
+	lineageStorage
+	recent []*models.BatchLineage
+}
+
+func (s *recentLineage) ListBatches(ctx context.Context, start, end time.Time, limit int) ([]*models.BatchLineage, error) {
+	return s.recent, nil
+}
+
+func TestGetSLO(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/pipeline/slo", h.GetSLO).Methods(http.MethodGet)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/pipeline/slo", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	received := time.Now().Add(-time.Hour)
+	store := &recentLineage{recent: []*models.BatchLineage{
+		{MetricCount: 90, CollectedAt: received, ReceivedAt: received, StoredAt: received.Add(time.Second)},
+		{MetricCount: 10, CollectedAt: received, ReceivedAt: received, StoredAt: received.Add(time.Minute)},
+	}}
+	tracker := slo.New(store, config.DefaultSLOConfig(), leader.Always{}, nil, nil)
+	require.NoError(t, tracker.Evaluate(context.Background()))
+	h.SetSLO(tracker)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/pipeline/slo", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var st models.SLOStatus
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&st))
+	assert.Equal(t, int64(100), st.Metrics)
+	assert.Equal(t, int64(10), st.Late)
+	assert.InDelta(t, 0.9, st.Compliance, 1e-9)
+	assert.False(t, st.Met)
+	assert.Equal(t, "30s", st.Latency)
+	assert.Len(t, st.BurnRates, 2)
+}
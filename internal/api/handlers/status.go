@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// FleetStatusResponse is the fleet overview: one status per GPU and counts
+// by health.
+type FleetStatusResponse struct {
+	Summary models.FleetStatusSummary `json:"summary"`
+	Data    []*models.GPUStatus       `json:"data"`
+	Count   int                       `json:"count"`
+}
+
+// GetFleetStatus godoc
+// @Summary      Get the fleet status overview
+// @Description  Returns the materialized current status of every GPU: the latest values of the key metrics, firing and pending alerts and a health score from 100 down to 0, with counts by health. The API keeps the statuses up to date in storage every API_STATUS_INTERVAL, so this is a single read however large the fleet; summary.updated_at shows how current they are.
+// @Tags         gpus
+// @Produce      json
+// @Param        hostname  query  string  false  "Only GPUs on this host"
+// @Param        health    query  string  false  "Only GPUs with this health (healthy, warning, critical or stale)"
+// @Param        since     query  string  false  "Only GPUs whose health, score or alerts changed at or after this time (RFC3339); the summary still counts every GPU"
+// @Success      200  {object}  FleetStatusResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      501  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/fleet/status [get]
+func (h *Handler) GetFleetStatus(w http.ResponseWriter, r *http.Request) {
+	reader, ok := h.storeFor(r.Context()).(storage.GPUStatusStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "not_implemented", "Storage backend does not support GPU statuses")
+		return
+	}
+	hostname := r.URL.Query().Get("hostname")
+	health := r.URL.Query().Get("health")
+	switch health {
+	case "", models.HealthHealthy, models.HealthWarning, models.HealthCritical, models.HealthStale:
+	default:
+		writeError(w, http.StatusBadRequest, "bad_request", "health must be healthy, warning, critical or stale")
+		return
+	}
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", "since must be an RFC3339 time")
+			return
+		}
+		since = t
+	}
+
+	statuses, err := reader.ListGPUStatuses(r.Context())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	filtered := make([]*models.GPUStatus, 0, len(statuses))
+	for _, s := range statuses {
+		if (hostname == "" || s.Hostname == hostname) && (health == "" || s.Health == health) {
+			filtered = append(filtered, s)
+		}
+	}
+
+	summary := models.SummarizeStatuses(filtered)
+	if !since.IsZero() {
+		changed := filtered[:0]
+		for _, s := range filtered {
+			if !s.ChangedAt.Before(since) {
+				changed = append(changed, s)
+			}
+		}
+		filtered = changed
+	}
+
+	writeJSON(w, http.StatusOK, FleetStatusResponse{
+		Summary: summary,
+		Data:    filtered,
+		Count:   len(filtered),
+	})
+}
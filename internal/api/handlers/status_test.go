@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// statusStorage adds fixed storage.GPUStatusStore statuses to mockStorage.
+type statusStorage struct {
+	*mockStorage
+	statuses []*models.GPUStatus
+}
+
+func (s *statusStorage) WriteGPUStatuses(ctx context.Context, statuses []*models.GPUStatus) error {
+	s.statuses = statuses
+	return nil
+}
+
+func (s *statusStorage) ListGPUStatuses(ctx context.Context) ([]*models.GPUStatus, error) {
+	return s.statuses, nil
+}
+
+func TestGetFleetStatus(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/fleet/status", NewHandler(newMockStorage(), 100, 1000).GetFleetStatus)
+	w := doJSON(t, router, http.MethodGet, "/api/v1/fleet/status", nil)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	store := &statusStorage{mockStorage: newMockStorage(), statuses: []*models.GPUStatus{
+		{UUID: "GPU-1", Hostname: "host-1", Health: models.HealthCritical, HealthScore: 50, Firing: 1, ChangedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{UUID: "GPU-2", Hostname: "host-1", Health: models.HealthHealthy, HealthScore: 100},
+		{UUID: "GPU-3", Hostname: "host-2", Health: models.HealthHealthy, HealthScore: 100},
+	}}
+	router = mux.NewRouter()
+	router.HandleFunc("/api/v1/fleet/status", NewHandler(store, 100, 1000).GetFleetStatus)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/fleet/status", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp FleetStatusResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Count)
+	assert.Equal(t, 3, resp.Summary.GPUs)
+	assert.Equal(t, 2, resp.Summary.ByHealth[models.HealthHealthy])
+	assert.Equal(t, 1, resp.Summary.Firing)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/fleet/status?hostname=host-1&health=healthy", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = FleetStatusResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, "GPU-2", resp.Data[0].UUID)
+
+	// since keeps the GPUs that changed, while the summary still counts all
+	w = doJSON(t, router, http.MethodGet, "/api/v1/fleet/status?since=2024-01-01T11:00:00Z", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = FleetStatusResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Count)
+	assert.Equal(t, "GPU-1", resp.Data[0].UUID)
+	assert.Equal(t, 3, resp.Summary.GPUs)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/fleet/status?health=bad", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/fleet/status?since=yesterday", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
+)
+
+// SetSupport sets the source of the replica's support report.
+func (h *Handler) SetSupport(src *support.Source) {
+	h.support = src
+}
+
+// GetSupport godoc
+// @Summary      Get a support report
+// @Description  Returns this replica's build, configuration with secrets redacted, recent log lines, cache and storage statistics, and a goroutine dump, for attaching to bug reports. pipelinectl support collects it from every component into one tarball.
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  support.Report
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/admin/support [get]
+func (h *Handler) GetSupport(w http.ResponseWriter, r *http.Request) {
+	if h.support == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Support reports are not enabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, h.support.Report())
+}
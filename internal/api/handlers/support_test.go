@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
+)
+
+func TestGetSupport(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/admin/support", h.GetSupport).Methods(http.MethodGet)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/admin/support", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	src := support.NewSource("api", map[string]string{"admin_token": "0123456789abcdef"}, nil)
+	src.AddStats("cache", func() any { return map[string]bool{"loaded": true} })
+	h.SetSupport(src)
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/admin/support", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var report support.Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, "api", report.Build.Component)
+	assert.JSONEq(t, `{"admin_token":"[redacted]"}`, string(report.Config))
+	assert.Contains(t, report.Stats, "cache")
+	assert.NotEmpty(t, report.Goroutines)
+}
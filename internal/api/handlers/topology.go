@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+)
+
+// SetTopology sets the source the pipeline topology is assembled from.
+func (h *Handler) SetTopology(source *topology.Source) {
+	h.topology = source
+}
+
+// GetPipelineTopology godoc
+// @Summary      Get the live pipeline topology
+// @Description  Returns the running components and how they are wired: which streamers publish to the MQ server, which collectors consume from it, and which storage buckets each writes to or reads from, with each component's version. Assembled from the heartbeats components send the MQ server; a component drops out after three missed heartbeats.
+// @Tags         system
+// @Produce      json
+// @Success      200  {object}  models.PipelineTopology
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/pipeline/topology [get]
+func (h *Handler) GetPipelineTopology(w http.ResponseWriter, r *http.Request) {
+	if h.topology == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "The pipeline topology is not available without an MQ server")
+		return
+	}
+	topo, err := h.topology.Topology(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Failed to list components from the MQ server: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, topo)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// componentList serves fixed components, or fails with err.
+type componentList struct {
+	components []mq.Heartbeat
+	err        error
+}
+
+func (l *componentList) Components(ctx context.Context) ([]mq.Heartbeat, error) {
+	return l.components, l.err
+}
+
+func TestGetPipelineTopology(t *testing.T) {
+	h := NewHandler(newMockStorage(), 100, 1000)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/pipeline/topology", h.GetPipelineTopology).Methods(http.MethodGet)
+
+	w := doJSON(t, router, http.MethodGet, "/api/v1/pipeline/topology", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	list := &componentList{components: []mq.Heartbeat{
+		{Component: mq.ComponentMQServer, ID: "mq:9000", Version: "1.2.0"},
+		topology.Heartbeat("streamer", "streamer-1", []string{models.RelationPublishes}),
+	}}
+	h.SetTopology(topology.New(list))
+
+	w = doJSON(t, router, http.MethodGet, "/api/v1/pipeline/topology", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var topo models.PipelineTopology
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&topo))
+	require.Len(t, topo.Nodes, 2)
+	assert.Equal(t, "1.2.0", topo.Nodes[0].Version)
+	assert.Equal(t, []models.TopologyLink{{From: "streamer/streamer-1", To: "mq-server/mq:9000", Relation: models.RelationPublishes}}, topo.Links)
+
+	list.err = errors.New("not connected")
+	w = doJSON(t, router, http.MethodGet, "/api/v1/pipeline/topology", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/usage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// UsageListResponse represents every tenant's usage in a month.
+type UsageListResponse struct {
+	Month string               `json:"month" example:"2026-10"`
+	Data  []models.TenantUsage `json:"data"`
+	Count int                  `json:"count" example:"3"`
+}
+
+// SetUsage sets the meter whose counters are served.
+func (h *Handler) SetUsage(meter *usage.Meter) {
+	h.usage = meter
+}
+
+// usageMonth returns the meter and the month requested by the month
+// parameter, defaulting to the current one. It writes a 503 if metering is
+// disabled and a 400 for a malformed month.
+func (h *Handler) usageMonth(w http.ResponseWriter, r *http.Request) (*usage.Meter, string, bool) {
+	if h.usage == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Usage metering is not enabled")
+		return nil, "", false
+	}
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		return h.usage, h.usage.Month(), true
+	}
+	if _, err := time.Parse(usage.MonthFormat, month); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", "Invalid month parameter; use YYYY-MM")
+		return nil, "", false
+	}
+	return h.usage, month, true
+}
+
+// GetUsage godoc
+// @Summary      Get the caller's API usage
+// @Description  Returns the requests, telemetry data points and exported bytes metered against the caller's bearer token in a month, with its monthly quota. Requests without a tenant token are metered as anonymous. Counts are those of the replica answering.
+// @Tags         usage
+// @Produce      json
+// @Param        month  query  string  false  "Month as YYYY-MM (default: current month, UTC)"
+// @Success      200  {object}  models.TenantUsage
+// @Failure      400  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/v1/usage [get]
+func (h *Handler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	meter, month, ok := h.usageMonth(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, meter.Get(usage.Tenant(r.Context()), month))
+}
+
+// ListUsage godoc
+// @Summary      List API usage by tenant
+// @Description  Returns every tenant's metered usage in a month, for billing. Requires the admin role.
+// @Tags         admin
+// @Produce      json
+// @Param        month  query  string  false  "Month as YYYY-MM (default: current month, UTC)"
+// @Success      200  {object}  UsageListResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Security     BearerAuth
+// @Router       /api/v1/admin/usage [get]
+func (h *Handler) ListUsage(w http.ResponseWriter, r *http.Request) {
+	meter, month, ok := h.usageMonth(w, r)
+	if !ok {
+		return
+	}
+	data := meter.List(month)
+	writeJSON(w, http.StatusOK, UsageListResponse{Month: month, Data: data, Count: len(data)})
+}
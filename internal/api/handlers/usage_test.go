@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/usage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func TestUsage(t *testing.T) {
+	store := newMockStorage()
+	seedTestData(t, store)
+	h := NewHandler(store, 100, 1000)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/usage", h.GetUsage).Methods(http.MethodGet)
+	w := doJSON(t, router, http.MethodGet, "/api/v1/usage", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	meter, err := usage.New(config.UsageConfig{QuotaRequests: 100}, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+	h.SetUsage(meter)
+	a := auth.New("admin-token")
+	a.AddTenant("acme", "acme-token")
+	router.Use(a.Identify(), meter.Middleware)
+	router.HandleFunc("/api/v1/gpus/{id}/telemetry", h.GetGPUTelemetry).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/gpus/{id}/telemetry/export", h.ExportGPUTelemetry).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/admin/usage", h.ListUsage).Methods(http.MethodGet)
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = get("/api/v1/gpus/GPU-12345-AAAA/telemetry", "acme-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	var telemetry TelemetryResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &telemetry))
+	require.NotZero(t, telemetry.Count)
+	export := get("/api/v1/gpus/GPU-12345-AAAA/telemetry/export?format=csv", "acme-token")
+	require.Equal(t, http.StatusOK, export.Code)
+
+	w = get("/api/v1/usage", "acme-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	var mine models.TenantUsage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &mine))
+	assert.Equal(t, "acme", mine.Tenant)
+	assert.Equal(t, meter.Month(), mine.Month)
+	// The usage request itself is counted once it has been answered
+	assert.Equal(t, int64(2), mine.Requests)
+	// Both requests returned the GPU's telemetry
+	assert.Equal(t, int64(2*telemetry.Count), mine.Rows)
+	assert.Equal(t, int64(export.Body.Len()), mine.ExportBytes)
+	require.NotNil(t, mine.Quota)
+	assert.Equal(t, int64(100), mine.Quota.Requests)
+
+	w = get("/api/v1/admin/usage?month="+meter.Month(), "admin-token")
+	require.Equal(t, http.StatusOK, w.Code)
+	var all UsageListResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	require.Equal(t, 1, all.Count)
+	assert.Equal(t, "acme", all.Data[0].Tenant)
+
+	w = get("/api/v1/admin/usage?month=2026-13", "admin-token")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = get("/api/v1/admin/usage", "admin-token")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	require.Equal(t, 2, all.Count)
+	assert.Equal(t, "admin", all.Data[1].Tenant)
+	assert.Nil(t, all.Data[1].Quota, "quotas do not apply to the admin")
+}
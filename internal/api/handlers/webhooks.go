@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+)
+
+// WebhookDeliveriesResponse represents the recent webhook delivery log.
+type WebhookDeliveriesResponse struct {
+	Data    []notify.Delivery `json:"data"`
+	Count   int               `json:"count" example:"10"`
+	Dropped int64             `json:"dropped" example:"0"`
+}
+
+// ListWebhookDeliveries godoc
+// @Summary      List recent webhook deliveries
+// @Description  Returns this replica's recent event deliveries, newest first, with attempts, response status and errors. Dropped counts events discarded because the delivery queue was full.
+// @Tags         webhooks
+// @Produce      json
+// @Param        status  query  string  false  "Only deliveries with this outcome"  enum(delivered,failed)
+// @Success      200  {object}  WebhookDeliveriesResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/v1/webhooks/deliveries [get]
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if h.webhooks == nil {
+		writeError(w, http.StatusServiceUnavailable, "unavailable", "Webhooks are not configured")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	deliveries := h.webhooks.Deliveries()
+	data := make([]notify.Delivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		if status == "" || d.Status == status {
+			data = append(data, d)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, WebhookDeliveriesResponse{
+		Data:    data,
+		Count:   len(data),
+		Dropped: h.webhooks.Dropped(),
+	})
+}
@@ -2,13 +2,33 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/dashboard"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/environment"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/api/handlers"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/baseline"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/export"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/maintenance"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/replay"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/slo"
 	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/summary"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/topology"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/usage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
 )
 
 // RouterConfig configures the API router.
@@ -18,6 +38,67 @@ type RouterConfig struct {
 
 	// MaxLimit is the maximum pagination limit
 	MaxLimit int
+
+	// LatestCache backs /api/v1/snapshot and the health endpoints (optional)
+	LatestCache *cache.Latest
+
+	// Webhooks is the event dispatcher whose delivery log is served (optional)
+	Webhooks *notify.Dispatcher
+
+	// Replayer re-ingests batches for the admin reingest endpoint (optional)
+	Replayer *replay.Replayer
+
+	// Alerts is the alert evaluator whose current alerts are served (optional)
+	Alerts *alert.Evaluator
+
+	// AlertRules lists the rules file's rules alongside stored rules (optional)
+	AlertRules *alert.RuleSet
+
+	// Baselines are the learned per-model baselines served and used by rule tests (optional)
+	Baselines *baseline.Profiler
+
+	// SLO evaluates the ingest-latency objective served at /api/v1/pipeline/slo (optional)
+	SLO *slo.Tracker
+
+	// Topology assembles the pipeline wiring served at /api/v1/pipeline/topology (optional)
+	Topology *topology.Source
+
+	// Summary keeps the fleet summary served at /api/v1/stats (optional)
+	Summary *summary.Summarizer
+
+	// Auth guards role-scoped routes; nil refuses all admin requests
+	Auth *auth.Authenticator
+
+	// Exports runs background export jobs and serves their artifacts (optional)
+	Exports *export.Store
+
+	// Usage meters /api/v1 requests per tenant and enforces monthly quotas (optional)
+	Usage *usage.Meter
+
+	// Dashboard serves the built-in web dashboard at /
+	Dashboard bool
+
+	// Logging is changed at /api/v1/admin/logging and logs each request at debug level (optional)
+	Logging *logging.Runtime
+
+	// Maintenance refuses writes while on and is switched at /api/v1/admin/maintenance (optional)
+	Maintenance *maintenance.Mode
+
+	// BundleKey signs and verifies configuration bundles at /api/v1/admin/bundle; empty disables them
+	BundleKey string
+
+	// MetricAliases are alternate metric names requests may use (optional)
+	MetricAliases models.MetricAliases
+
+	// Support serves this replica's support report at /api/v1/admin/support (optional)
+	Support *support.Source
+
+	// Deliveries are the targets saved queries may deliver to; empty refuses all
+	Deliveries models.DeliveryAllowlist
+
+	// Environments are the named data sets requests can select; nil serves
+	// only the router's storage, as environment "default"
+	Environments *environment.Set
 }
 
 // DefaultRouterConfig returns a router config with sensible defaults.
@@ -31,26 +112,107 @@ func DefaultRouterConfig() RouterConfig {
 // NewRouter creates a new mux router with all routes configured.
 func NewRouter(store storage.ReadStorage, config RouterConfig) *mux.Router {
 	router := mux.NewRouter()
+	if config.Logging != nil {
+		router.Use(logRequests(config.Logging))
+	}
 
 	// Create handler
 	handler := handlers.NewHandler(store, config.DefaultLimit, config.MaxLimit)
+	handler.SetLatestCache(config.LatestCache)
+	handler.SetWebhooks(config.Webhooks)
+	handler.SetReplayer(config.Replayer)
+	handler.SetAlerts(config.Alerts)
+	handler.SetAlertRules(config.AlertRules)
+	handler.SetBaselines(config.Baselines)
+	handler.SetSLO(config.SLO)
+	handler.SetTopology(config.Topology)
+	handler.SetSummary(config.Summary)
+	handler.SetExports(config.Exports)
+	handler.SetUsage(config.Usage)
+	handler.SetLogging(config.Logging)
+	handler.SetMaintenance(config.Maintenance)
+	handler.SetBundleKey(config.BundleKey)
+	handler.SetMetricAliases(config.MetricAliases)
+	handler.SetSupport(config.Support)
+	handler.SetDeliveryAllowlist(config.Deliveries)
+
+	authenticator := config.Auth
+	if authenticator == nil {
+		authenticator = auth.New("")
+	}
+	environments := config.Environments
+	if environments == nil {
+		environments = environment.NewSet("default", store)
+	}
+	handler.SetEnvironments(environments)
 
-	// Health check endpoints for Kubernetes probes
+	// Health check endpoints for Kubernetes probes. They answer from memory so
+	// frequent probing never reaches storage.
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy"}`))
+		resp := healthResponse{Status: "healthy"}
+		if config.LatestCache != nil {
+			st := config.LatestCache.Status()
+			resp.Cache = &st
+		}
+		if config.Maintenance != nil {
+			if st := config.Maintenance.State(); st.Enabled {
+				resp.Maintenance = &st
+			}
+		}
+		writeHealth(w, http.StatusOK, resp)
 	}).Methods(http.MethodGet)
 
 	router.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ready"}`))
+		// Not ready until the latest cache has loaded once, so dashboards
+		// behind the service never see an empty snapshot
+		if config.LatestCache != nil && !config.LatestCache.Status().Loaded {
+			writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: "not_ready"})
+			return
+		}
+		writeHealth(w, http.StatusOK, healthResponse{Status: "ready"})
 	}).Methods(http.MethodGet)
 
+	// Build info, for spotting mixed-version deployments
+	router.HandleFunc("/version", buildinfo.Handler("api")).Methods(http.MethodGet)
+
 	// Swagger UI
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
-	// API v1 routes
+	// Built-in dashboard, drawn in the browser from the API below
+	if config.Dashboard {
+		router.Handle("/", dashboard.Index()).Methods(http.MethodGet)
+		router.PathPrefix(dashboard.AssetPrefix).Handler(dashboard.Assets()).Methods(http.MethodGet)
+	}
+
+	// GET /api/v1/usage - The caller's metered usage. Routed outside the
+	// metered subrouter so tenants over quota can still see their usage.
+	router.Handle("/api/v1/usage", authenticator.Identify()(http.HandlerFunc(handler.GetUsage))).Methods(http.MethodGet)
+
+	// /env/{name}/api/v1/... selects an environment by path, like the
+	// X-Environment header
+	router.PathPrefix(environment.PathPrefix).Handler(environments.StripPrefix(router))
+
+	// API v1 routes, metered against the tenant their bearer token identifies
 	api := router.PathPrefix("/api/v1").Subrouter()
+	api.Use(authenticator.Identify())
+
+	// Read from the selected environment's storage. Features keeping their
+	// state in this process serve the default environment only
+	api.Use(environments.Select(
+		"/api/v1/snapshot", "/api/v1/alerts", "/api/v1/baselines", "/api/v1/saved-queries",
+		"/api/v1/pipeline/slo", "/api/v1/pipeline/topology", "/api/v1/webhooks", "/api/v1/exports",
+		"/api/v1/admin/reingest", "/api/v1/admin/usage", "/api/v1/admin/logging",
+		"/api/v1/admin/maintenance", "/api/v1/admin/bundle", "/api/v1/admin/support",
+	))
+	if config.Usage != nil {
+		api.Use(config.Usage.Middleware)
+	}
+
+	// Maintenance mode refuses writes; rule tests only read, and admins must
+	// be able to end maintenance
+	if config.Maintenance != nil {
+		api.Use(config.Maintenance.Middleware("/api/v1/alerts/rules/test", "/api/v1/admin/maintenance"))
+	}
 
 	// GET /api/v1/gpus - List all GPUs
 	api.HandleFunc("/gpus", handler.ListGPUs).Methods(http.MethodGet)
@@ -64,14 +226,203 @@ func NewRouter(store storage.ReadStorage, config RouterConfig) *mux.Router {
 	// GET /api/v1/gpus/{id}/metrics - List available metric names for a GPU
 	api.HandleFunc("/gpus/{id}/metrics", handler.ListMetricNames).Methods(http.MethodGet)
 
+	// GET /api/v1/gpus/{id}/correlate - Correlation and lag between two of a GPU's metrics
+	api.HandleFunc("/gpus/{id}/correlate", handler.CorrelateGPUMetrics).Methods(http.MethodGet)
+
+	// GET /api/v1/gpus/{id}/features - Fixed-length, gap-filled metric windows for ML models
+	api.HandleFunc("/gpus/{id}/features", handler.GetGPUFeatures).Methods(http.MethodGet)
+
+	// GET /api/v1/gpus/{id}/predictions - History of a GPU's failure predictions
+	api.HandleFunc("/gpus/{id}/predictions", handler.GetGPUPredictions).Methods(http.MethodGet)
+
+	// GET /api/v1/predictions - Latest failure prediction of every GPU, riskiest first
+	api.HandleFunc("/predictions", handler.ListPredictions).Methods(http.MethodGet)
+
+	// GET /api/v1/fleet/status - Materialized current status and health of every GPU
+	api.HandleFunc("/fleet/status", handler.GetFleetStatus).Methods(http.MethodGet)
+
+	// GET /api/v1/heatmap - GPU × time matrix of one metric for heatmap rendering
+	api.HandleFunc("/heatmap", handler.GetHeatmap).Methods(http.MethodGet)
+
 	// GET /api/v1/metrics - List all available metric types
 	api.HandleFunc("/metrics", handler.ListAllMetrics).Methods(http.MethodGet)
 
+	// GET /api/v1/cardinality - Distinct series stored recently, per metric and tag
+	api.HandleFunc("/cardinality", handler.GetCardinality).Methods(http.MethodGet)
+
+	// GET /api/v1/errors - Codes error responses carry, with their statuses
+	api.HandleFunc("/errors", handler.ListErrorCodes).Methods(http.MethodGet)
+
 	// GET /api/v1/stats - Get system statistics
 	api.HandleFunc("/stats", handler.GetStats).Methods(http.MethodGet)
 
+	// GET /api/v1/snapshot - Latest value of every metric for every GPU (served from cache)
+	api.HandleFunc("/snapshot", handler.GetSnapshot).Methods(http.MethodGet)
+
+	// GET /api/v1/snapshot/changes - GPUs whose latest values changed since a cursor or time
+	api.HandleFunc("/snapshot/changes", handler.GetSnapshotChanges).Methods(http.MethodGet)
+
+	// GET /api/v1/search/{field} - Prefix search over hostnames, uuids, pods or metrics
+	api.HandleFunc("/search/{field}", handler.Search).Methods(http.MethodGet)
+
+	// GET /api/v1/batches/{id} - Provenance of a stored batch, joined from telemetry batch_id
+	api.HandleFunc("/batches/{id}", handler.GetBatch).Methods(http.MethodGet)
+
+	// GET /api/v1/ingest/stats - Hourly batches, metrics, bytes and rejects per streamer
+	api.HandleFunc("/ingest/stats", handler.GetIngestStats).Methods(http.MethodGet)
+
+	// Annotations for operational events (maintenance, driver upgrades, job
+	// launches). Changing one is restricted to the admin role, since
+	// maintenance windows suppress alert notifications
+	api.HandleFunc("/annotations", handler.ListAnnotations).Methods(http.MethodGet)
+	api.HandleFunc("/annotations/{id}", handler.GetAnnotation).Methods(http.MethodGet)
+	annotations := api.PathPrefix("/annotations").Subrouter()
+	annotations.Use(authenticator.Require(auth.RoleAdmin))
+	annotations.HandleFunc("", handler.CreateAnnotation).Methods(http.MethodPost)
+	annotations.HandleFunc("/{id}", handler.UpdateAnnotation).Methods(http.MethodPut)
+	annotations.HandleFunc("/{id}", handler.DeleteAnnotation).Methods(http.MethodDelete)
+
+	// Saved queries run on a schedule by the API's scheduler, with run
+	// history. Changing one is restricted to the admin role, since it sends
+	// telemetry wherever its target points
+	api.HandleFunc("/saved-queries", handler.ListSavedQueries).Methods(http.MethodGet)
+	api.HandleFunc("/saved-queries/{id}", handler.GetSavedQuery).Methods(http.MethodGet)
+	api.HandleFunc("/saved-queries/{id}/runs", handler.ListSavedQueryRuns).Methods(http.MethodGet)
+	savedQueries := api.PathPrefix("/saved-queries").Subrouter()
+	savedQueries.Use(authenticator.Require(auth.RoleAdmin))
+	savedQueries.HandleFunc("", handler.CreateSavedQuery).Methods(http.MethodPost)
+	savedQueries.HandleFunc("/{id}", handler.UpdateSavedQuery).Methods(http.MethodPut)
+	savedQueries.HandleFunc("/{id}", handler.DeleteSavedQuery).Methods(http.MethodDelete)
+
+	// Named filters that telemetry, export and heatmap requests reference
+	// with ?filter=name
+	api.HandleFunc("/filters", handler.ListSavedFilters).Methods(http.MethodGet)
+	api.HandleFunc("/filters/{name}", handler.GetSavedFilter).Methods(http.MethodGet)
+	api.HandleFunc("/filters/{name}", handler.SaveFilter).Methods(http.MethodPut)
+	api.HandleFunc("/filters/{name}", handler.DeleteSavedFilter).Methods(http.MethodDelete)
+
+	// Alerts from the evaluating replica, the rules that raise them, and
+	// silences that mute their notifications
+	api.HandleFunc("/alerts", handler.ListAlerts).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/rules", handler.ListAlertRules).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/rules/test", handler.TestAlertRule).Methods(http.MethodPost)
+	api.HandleFunc("/alerts/rules/{id}", handler.GetAlertRule).Methods(http.MethodGet)
+
+	// Changing alert rules is restricted to the admin role; testing one only reads
+	alertRules := api.PathPrefix("/alerts/rules").Subrouter()
+	alertRules.Use(authenticator.Require(auth.RoleAdmin))
+	alertRules.HandleFunc("", handler.CreateAlertRule).Methods(http.MethodPost)
+	alertRules.HandleFunc("/{id}", handler.UpdateAlertRule).Methods(http.MethodPut)
+	alertRules.HandleFunc("/{id}", handler.DeleteAlertRule).Methods(http.MethodDelete)
+	alertRules.HandleFunc("/{id}/enable", handler.EnableAlertRule).Methods(http.MethodPost)
+	alertRules.HandleFunc("/{id}/disable", handler.DisableAlertRule).Methods(http.MethodPost)
+
+	// Silencing alerts or lifting a silence is restricted to the admin role
+	api.HandleFunc("/alerts/silences", handler.ListSilences).Methods(http.MethodGet)
+	api.HandleFunc("/alerts/silences/{id}", handler.GetSilence).Methods(http.MethodGet)
+	silences := api.PathPrefix("/alerts/silences").Subrouter()
+	silences.Use(authenticator.Require(auth.RoleAdmin))
+	silences.HandleFunc("", handler.CreateSilence).Methods(http.MethodPost)
+	silences.HandleFunc("/{id}", handler.DeleteSilence).Methods(http.MethodDelete)
+
+	// GET /api/v1/baselines - Per-model normal ranges learned from fleet history
+	api.HandleFunc("/baselines", handler.ListBaselines).Methods(http.MethodGet)
+
+	// GET /api/v1/forecast - Projected fleet utilization and energy for capacity planning
+	api.HandleFunc("/forecast", handler.GetForecast).Methods(http.MethodGet)
+
+	// JSON Schemas of the wire formats, for producers written in other languages
+	api.HandleFunc("/schemas", handler.ListSchemas).Methods(http.MethodGet)
+	api.HandleFunc("/schemas/{name}", handler.GetSchema).Methods(http.MethodGet)
+
+	// GET /api/v1/environments - Environments the caller may select
+	api.HandleFunc("/environments", handler.ListEnvironments).Methods(http.MethodGet)
+
+	// GET /api/v1/pipeline/slo - Ingest-latency objective compliance and burn rates
+	api.HandleFunc("/pipeline/slo", handler.GetSLO).Methods(http.MethodGet)
+
+	// GET /api/v1/pipeline/topology - Running components and how they are wired
+	api.HandleFunc("/pipeline/topology", handler.GetPipelineTopology).Methods(http.MethodGet)
+
+	// GET /api/v1/webhooks/deliveries - Recent webhook deliveries from this replica
+	api.HandleFunc("/webhooks/deliveries", handler.ListWebhookDeliveries).Methods(http.MethodGet)
+
+	// Admin endpoints for cleanup, retention, holds, re-ingestion, usage, logging, maintenance, config bundles and support reports, restricted to the admin role
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(authenticator.Require(auth.RoleAdmin))
+	admin.HandleFunc("/cleanup", handler.RunCleanup).Methods(http.MethodPost)
+	admin.HandleFunc("/cleanup/history", handler.ListCleanupHistory).Methods(http.MethodGet)
+	admin.HandleFunc("/retention", handler.GetRetention).Methods(http.MethodGet)
+	admin.HandleFunc("/retention", handler.SetRetention).Methods(http.MethodPut)
+	admin.HandleFunc("/holds", handler.ListHolds).Methods(http.MethodGet)
+	admin.HandleFunc("/holds", handler.CreateHold).Methods(http.MethodPost)
+	admin.HandleFunc("/holds/{id}", handler.GetHold).Methods(http.MethodGet)
+	admin.HandleFunc("/holds/{id}", handler.DeleteHold).Methods(http.MethodDelete)
+	admin.HandleFunc("/reingest", handler.Reingest).Methods(http.MethodPost)
+	admin.HandleFunc("/usage", handler.ListUsage).Methods(http.MethodGet)
+	admin.HandleFunc("/logging", handler.GetLogging).Methods(http.MethodGet)
+	admin.HandleFunc("/logging", handler.UpdateLogging).Methods(http.MethodPut)
+	admin.HandleFunc("/maintenance", handler.GetMaintenance).Methods(http.MethodGet)
+	admin.HandleFunc("/maintenance", handler.UpdateMaintenance).Methods(http.MethodPut)
+	admin.HandleFunc("/bundle", handler.ExportBundle).Methods(http.MethodGet)
+	admin.HandleFunc("/bundle", handler.ImportBundle).Methods(http.MethodPost)
+	admin.HandleFunc("/support", handler.GetSupport).Methods(http.MethodGet)
+
 	// GET /api/v1/gpus/{id}/telemetry/export - Export telemetry data for a GPU as CSV or JSON
 	api.HandleFunc("/gpus/{id}/telemetry/export", handler.ExportGPUTelemetry).Methods(http.MethodGet)
 
+	// Background export jobs for large ranges, with resumable artifact downloads
+	api.HandleFunc("/exports", handler.ListExports).Methods(http.MethodGet)
+	api.HandleFunc("/exports", handler.CreateExport).Methods(http.MethodPost)
+	api.HandleFunc("/exports/{id}", handler.GetExport).Methods(http.MethodGet)
+	api.HandleFunc("/exports/{id}", handler.DeleteExport).Methods(http.MethodDelete)
+	api.HandleFunc("/exports/{id}/download", handler.DownloadExport).Methods(http.MethodGet, http.MethodHead)
+
 	return router
 }
+
+// healthResponse is the body of the /health and /ready endpoints.
+type healthResponse struct {
+	Status      string             `json:"status"`
+	Cache       *cache.Status      `json:"cache,omitempty"`
+	Maintenance *maintenance.State `json:"maintenance,omitempty"`
+}
+
+func writeHealth(w http.ResponseWriter, status int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// logRequests logs each request's method, path, status and duration while
+// the level is debug.
+func logRequests(logs *logging.Runtime) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if logs.Level() > logging.LevelDebug {
+				next.ServeHTTP(w, r)
+				return
+			}
+			began := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			logs.Debugf("%s %s %d (%v, from %s)", r.Method, r.URL.RequestURI(), rec.status, time.Since(began), r.RemoteAddr)
+		})
+	}
+}
+
+// statusRecorder remembers the status code a handler wrote.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
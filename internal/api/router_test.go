@@ -1,11 +1,24 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/environment"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/maintenance"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/usage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
 )
 
@@ -106,3 +119,320 @@ func TestRouterMethodNotAllowed(t *testing.T) {
 		t.Errorf("expected status 404 or 405, got %d", w.Code)
 	}
 }
+
+func TestRouterReadyWaitsForCache(t *testing.T) {
+	config := DefaultRouterConfig()
+	config.LatestCache = cache.NewLatest(cache.SourceStorage)
+	router := NewRouter(&mockReadStorage{}, config)
+
+	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before cache load, got %d", w.Code)
+	}
+
+	config.LatestCache.Update([]*models.GPUMetric{{UUID: "GPU-1", MetricName: "m", Timestamp: time.Now()}})
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 after cache load, got %d", w.Code)
+	}
+}
+
+func TestRouterVersion(t *testing.T) {
+	router := NewRouter(&mockReadStorage{}, DefaultRouterConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/version", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var info buildinfo.Info
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if info.Component != "api" || info.Version == "" {
+		t.Errorf("unexpected build info %+v", info)
+	}
+}
+
+func TestRouterAdminRequiresToken(t *testing.T) {
+	config := DefaultRouterConfig()
+	config.Auth = auth.New("0123456789abcdef")
+	router := NewRouter(&mockReadStorage{}, config)
+
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusUnauthorized},
+		// mockReadStorage is not a DataAdmin, so an authorised call reaches the handler's 501
+		{"0123456789abcdef", http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/api/v1/admin/retention", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("token %q: expected status %d, got %d", tt.token, tt.want, w.Code)
+		}
+	}
+
+	// Without an authenticator the admin routes are closed
+	router = NewRouter(&mockReadStorage{}, DefaultRouterConfig())
+	req, _ := http.NewRequest("GET", "/api/v1/admin/retention", nil)
+	req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without admin token configured, got %d", w.Code)
+	}
+}
+
+func TestRouterWritesRequireAdmin(t *testing.T) {
+	config := DefaultRouterConfig()
+	config.Auth = auth.New("0123456789abcdef")
+	config.Auth.AddTenant("acme", "acme-token")
+	router := NewRouter(&mockReadStorage{}, config)
+
+	do := func(method, path, token string) int {
+		req, _ := http.NewRequest(method, path, strings.NewReader("{}"))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// mockReadStorage stores none of these, so an allowed call reaches the handler's 501
+	writes := []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/saved-queries"},
+		{http.MethodPut, "/api/v1/saved-queries/q-1"},
+		{http.MethodDelete, "/api/v1/saved-queries/q-1"},
+		{http.MethodPost, "/api/v1/annotations"},
+		{http.MethodPut, "/api/v1/annotations/a-1"},
+		{http.MethodDelete, "/api/v1/annotations/a-1"},
+		{http.MethodPost, "/api/v1/alerts/rules"},
+		{http.MethodPut, "/api/v1/alerts/rules/r-1"},
+		{http.MethodDelete, "/api/v1/alerts/rules/r-1"},
+		{http.MethodPost, "/api/v1/alerts/rules/r-1/enable"},
+		{http.MethodPost, "/api/v1/alerts/rules/r-1/disable"},
+		{http.MethodPost, "/api/v1/alerts/silences"},
+		{http.MethodDelete, "/api/v1/alerts/silences/s-1"},
+	}
+	for _, tt := range writes {
+		if got := do(tt.method, tt.path, ""); got != http.StatusUnauthorized {
+			t.Errorf("%s %s without a token: expected 401, got %d", tt.method, tt.path, got)
+		}
+		if got := do(tt.method, tt.path, "acme-token"); got != http.StatusForbidden {
+			t.Errorf("%s %s as a tenant: expected 403, got %d", tt.method, tt.path, got)
+		}
+		if got := do(tt.method, tt.path, "0123456789abcdef"); got != http.StatusNotImplemented {
+			t.Errorf("%s %s as admin: expected 501, got %d", tt.method, tt.path, got)
+		}
+	}
+
+	// Reads stay open to tenants
+	reads := []string{
+		"/api/v1/saved-queries", "/api/v1/saved-queries/q-1", "/api/v1/saved-queries/q-1/runs",
+		"/api/v1/annotations", "/api/v1/annotations/a-1",
+		"/api/v1/alerts/rules/r-1", "/api/v1/alerts/silences", "/api/v1/alerts/silences/s-1",
+	}
+	for _, path := range reads {
+		if got := do(http.MethodGet, path, "acme-token"); got != http.StatusNotImplemented {
+			t.Errorf("GET %s as a tenant: expected 501, got %d", path, got)
+		}
+	}
+
+	// Testing a rule only reads, so tenants may
+	if got := do(http.MethodPost, "/api/v1/alerts/rules/test", "acme-token"); got == http.StatusUnauthorized || got == http.StatusForbidden {
+		t.Errorf("POST /api/v1/alerts/rules/test as a tenant: expected it allowed, got %d", got)
+	}
+}
+
+func TestRouterEnvironments(t *testing.T) {
+	config := DefaultRouterConfig()
+	config.Auth = auth.New("0123456789abcdef")
+	config.Auth.AddTenant("acme", "acme-token")
+	config.Environments = environment.NewSet("prod", &mockReadStorage{gpus: []string{"GPU-prod"}})
+	config.Environments.Add(&environment.Environment{Name: "dev", Store: &mockReadStorage{gpus: []string{"GPU-dev"}}, Tenants: []string{"acme"}})
+	router := NewRouter(&mockReadStorage{gpus: []string{"GPU-prod"}}, config)
+
+	get := func(path, env, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if env != "" {
+			req.Header.Set(environment.Header, env)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		path, env, token string
+		want             int
+		body             string
+	}{
+		{"/api/v1/gpus", "", "", http.StatusOK, "GPU-prod"},
+		{"/api/v1/gpus", "dev", "acme-token", http.StatusOK, "GPU-dev"},
+		{"/env/dev/api/v1/gpus", "", "acme-token", http.StatusOK, "GPU-dev"},
+		{"/env/prod/api/v1/gpus", "", "", http.StatusOK, "GPU-prod"},
+		{"/api/v1/gpus", "dev", "", http.StatusUnauthorized, "unauthorized"},
+		{"/env/qa/api/v1/gpus", "", "", http.StatusNotFound, "environment_not_found"},
+		{"/api/v1/snapshot", "dev", "acme-token", http.StatusBadRequest, "environment_unsupported"},
+	}
+	for _, tt := range tests {
+		w := get(tt.path, tt.env, tt.token)
+		if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s (%s): expected %d with %q, got %d: %s", tt.path, tt.env, tt.want, tt.body, w.Code, w.Body.String())
+		}
+	}
+
+	// Anonymous callers only see the unrestricted environments
+	w := get("/api/v1/environments", "", "")
+	var list struct {
+		Data []models.Environment `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || len(list.Data) != 1 || list.Data[0].Name != "prod" || !list.Data[0].Default {
+		t.Errorf("expected only prod listed, got %+v (%v)", list.Data, err)
+	}
+}
+
+func TestRouterLogging(t *testing.T) {
+	var out bytes.Buffer
+	config := DefaultRouterConfig()
+	config.Auth = auth.New("0123456789abcdef")
+	config.Logging = logging.New(log.New(&out, "", 0), logging.LevelInfo)
+	router := NewRouter(&mockReadStorage{gpus: []string{"GPU-1"}}, config)
+
+	do := func(method, path, token, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do(http.MethodPut, "/api/v1/admin/logging", "", `{"level": "debug"}`); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", code)
+	}
+	do(http.MethodGet, "/api/v1/gpus", "", "")
+	if strings.Contains(out.String(), "DEBUG") {
+		t.Fatalf("requests logged at info level: %q", out.String())
+	}
+
+	if code := do(http.MethodPut, "/api/v1/admin/logging", "0123456789abcdef", `{"level": "debug"}`); code != http.StatusOK {
+		t.Fatalf("expected 200 changing the level, got %d", code)
+	}
+	do(http.MethodGet, "/api/v1/gpus?limit=5", "", "")
+	if !strings.Contains(out.String(), "DEBUG GET /api/v1/gpus?limit=5 200") {
+		t.Errorf("request not logged at debug level: %q", out.String())
+	}
+}
+
+func TestRouterMaintenance(t *testing.T) {
+	config := DefaultRouterConfig()
+	config.Auth = auth.New("0123456789abcdef")
+	config.Maintenance = maintenance.NewMode(true, "InfluxDB upgrade")
+	router := NewRouter(&mockReadStorage{gpus: []string{"GPU-1"}}, config)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer 0123456789abcdef")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/api/v1/gpus", ""); w.Code != http.StatusOK || w.Header().Get("X-Maintenance") != "InfluxDB upgrade" {
+		t.Errorf("expected reads served with the banner, got %d %v", w.Code, w.Header())
+	}
+	if w := do(http.MethodPost, "/api/v1/admin/reingest", `{}`); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "maintenance") {
+		t.Errorf("expected re-ingestion refused, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/health", ""); !strings.Contains(w.Body.String(), "InfluxDB upgrade") {
+		t.Errorf("expected /health to report maintenance, got %s", w.Body.String())
+	}
+
+	if w := do(http.MethodPut, "/api/v1/admin/maintenance", `{"enabled": false}`); w.Code != http.StatusOK {
+		t.Fatalf("expected maintenance switched off, got %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/admin/reingest", `{}`); w.Code == http.StatusServiceUnavailable && strings.Contains(w.Body.String(), "maintenance") {
+		t.Errorf("expected writes allowed after maintenance, got %s", w.Body.String())
+	}
+}
+
+func TestRouterUsageQuota(t *testing.T) {
+	meter, err := usage.New(config.UsageConfig{QuotaRequests: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultRouterConfig()
+	cfg.Auth = auth.New("")
+	cfg.Auth.AddTenant("acme", "acme-token")
+	cfg.Usage = meter
+	router := NewRouter(&mockReadStorage{gpus: []string{"GPU-1"}}, cfg)
+
+	get := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer acme-token")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("/api/v1/gpus"); code != http.StatusOK {
+		t.Fatalf("first request = %d, want 200", code)
+	}
+	if code := get("/api/v1/gpus"); code != http.StatusTooManyRequests {
+		t.Errorf("request over quota = %d, want 429", code)
+	}
+	// The tenant can still see why
+	if code := get("/api/v1/usage"); code != http.StatusOK {
+		t.Errorf("usage over quota = %d, want 200", code)
+	}
+}
+
+func TestRouterDashboard(t *testing.T) {
+	config := DefaultRouterConfig()
+	config.Dashboard = true
+	router := NewRouter(&mockReadStorage{}, config)
+
+	for path, contentType := range map[string]string{
+		"/":             "text/html",
+		"/ui/app.js":    "javascript",
+		"/ui/style.css": "text/css",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.Contains(got, contentType) {
+			t.Errorf("GET %s content type = %q, want %s", path, got, contentType)
+		}
+	}
+
+	// Disabled, nothing is served at /
+	router = NewRouter(&mockReadStorage{}, DefaultRouterConfig())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET / with the dashboard disabled = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,160 @@
+// Package baseline learns the normal range of GPU metrics for each GPU model
+// from fleet history, so alert rules can use thresholds such as model_p99
+// instead of hard-coding one per model.
+package baseline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// key identifies a baseline.
+type key struct {
+	model  string
+	metric string
+}
+
+// Profiler periodically recomputes each profiled metric's baseline for every
+// GPU model over a trailing window of history.
+type Profiler struct {
+	reader     storage.BaselineReader
+	metrics    []string
+	window     time.Duration
+	refresh    time.Duration
+	minSamples int
+	logger     *log.Logger
+	now        func() time.Time
+
+	mu          sync.RWMutex
+	baselines   map[key]*models.Baseline
+	lastRefresh time.Time
+	lastErr     error
+}
+
+// NewProfiler creates a profiler that learns baselines from reader.
+func NewProfiler(reader storage.BaselineReader, cfg config.BaselineConfig, logger *log.Logger) *Profiler {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Profiler{
+		reader:     reader,
+		metrics:    cfg.Metrics,
+		window:     cfg.Window,
+		refresh:    cfg.Refresh,
+		minSamples: cfg.MinSamples,
+		logger:     logger,
+		now:        time.Now,
+		baselines:  make(map[key]*models.Baseline),
+	}
+}
+
+// Run refreshes the baselines now and then every refresh interval until ctx is done.
+func (p *Profiler) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.refresh)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Refresh(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Printf("Baseline refresh failed, keeping the previous baselines: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh recomputes every profiled metric's baselines. A metric whose
+// history cannot be read keeps its previous baselines. Models with fewer
+// than the minimum samples have no baseline, so a handful of new GPUs never
+// define "normal" for their model.
+func (p *Profiler) Refresh(ctx context.Context) error {
+	end := p.now()
+	start := end.Add(-p.window)
+
+	learned := make(map[key]*models.Baseline)
+	var errs []error
+	for _, metric := range p.metrics {
+		baselines, err := p.reader.ModelBaselines(ctx, metric, start, end)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", metric, err))
+			p.mu.RLock()
+			for k, b := range p.baselines {
+				if k.metric == metric {
+					learned[k] = b
+				}
+			}
+			p.mu.RUnlock()
+			continue
+		}
+		for _, b := range baselines {
+			if b.Samples >= p.minSamples {
+				learned[key{model: b.ModelName, metric: metric}] = b
+			}
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.baselines = learned
+	p.lastErr = errors.Join(errs...)
+	if p.lastErr == nil {
+		p.lastRefresh = end
+	}
+	return p.lastErr
+}
+
+// Baseline returns a model's baseline for a metric.
+func (p *Profiler) Baseline(model, metric string) (*models.Baseline, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	b, ok := p.baselines[key{model: model, metric: metric}]
+	return b, ok
+}
+
+// Profiles reports whether the metric is profiled.
+func (p *Profiler) Profiles(metric string) bool {
+	for _, m := range p.metrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// Baselines returns every baseline, ordered by metric and then model.
+func (p *Profiler) Baselines() []models.Baseline {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make([]models.Baseline, 0, len(p.baselines))
+	for _, b := range p.baselines {
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Metric != out[j].Metric {
+			return out[i].Metric < out[j].Metric
+		}
+		return out[i].ModelName < out[j].ModelName
+	})
+	return out
+}
+
+// Status reports when the baselines were last fully refreshed and the last
+// refresh's error, if any.
+func (p *Profiler) Status() (time.Time, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.lastRefresh, p.lastErr
+}
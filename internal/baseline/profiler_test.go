@@ -0,0 +1,70 @@
+package baseline
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// fakeReader returns fixed baselines per metric, or an error.
+type fakeReader struct {
+	baselines map[string][]*models.Baseline
+	err       error
+}
+
+func (f *fakeReader) ModelBaselines(ctx context.Context, metric string, start, end time.Time) ([]*models.Baseline, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.baselines[metric], nil
+}
+
+func TestProfilerRefresh(t *testing.T) {
+	const temp = "DCGM_FI_DEV_GPU_TEMP"
+	reader := &fakeReader{baselines: map[string][]*models.Baseline{
+		temp: {
+			{ModelName: "H100", Metric: temp, Samples: 5000, P99: 80},
+			{ModelName: "B200", Metric: temp, Samples: 10, P99: 60}, // too few samples
+		},
+	}}
+	cfg := config.DefaultBaselineConfig()
+	cfg.Metrics, cfg.MinSamples = []string{temp}, 1000
+	p := NewProfiler(reader, cfg, log.New(io.Discard, "", 0))
+	now := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	if err := p.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+	if b, ok := p.Baseline("H100", temp); !ok || b.P99 != 80 {
+		t.Errorf("Baseline(H100) = %+v, %v", b, ok)
+	}
+	if _, ok := p.Baseline("B200", temp); ok {
+		t.Error("B200 has a baseline from fewer than the minimum samples")
+	}
+	if !p.Profiles(temp) || p.Profiles("DCGM_FI_DEV_FB_USED") {
+		t.Error("Profiles() should report only the configured metrics")
+	}
+	if refreshed, err := p.Status(); !refreshed.Equal(now) || err != nil {
+		t.Errorf("Status() = %v, %v", refreshed, err)
+	}
+
+	// A failed refresh keeps the previous baselines and reports the error
+	reader.err = errors.New("influxdb down")
+	now = now.Add(time.Hour)
+	if err := p.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh() succeeded while the reader fails")
+	}
+	if got := p.Baselines(); len(got) != 1 || got[0].ModelName != "H100" {
+		t.Errorf("Baselines() after failure = %+v, want H100 kept", got)
+	}
+	if refreshed, err := p.Status(); refreshed.Equal(now) || err == nil {
+		t.Errorf("Status() after failure = %v, %v", refreshed, err)
+	}
+}
@@ -0,0 +1,328 @@
+// Package bundle exports a deployment's runtime configuration (alert
+// rules, saved queries and retention) as a signed bundle and imports it
+// into another deployment, so an environment can be promoted
+// reproducibly. Bundles are signed with HMAC-SHA256 under a key the
+// deployments share; importing checks the signature before anything else.
+package bundle
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// minRetention is the shortest retention InfluxDB accepts (0 keeps data forever).
+const minRetention = time.Hour
+
+var (
+	// ErrBadSignature is returned by Verify for a bundle not signed with the key
+	ErrBadSignature = errors.New("bundle signature does not match; it was signed with another key or changed after export")
+
+	// ErrRetentionHeld is returned by Import for a finite retention while
+	// retention holds exist, since bucket retention would expire held data
+	ErrRetentionHeld = errors.New("bucket retention would expire held data")
+)
+
+// Export reads the configuration stored in store. Kinds the backend does
+// not support are left empty.
+func Export(ctx context.Context, store storage.ReadStorage, now time.Time) (*models.ConfigBundle, error) {
+	b := &models.ConfigBundle{
+		Version:      models.ConfigBundleVersion,
+		CreatedAt:    now.UTC(),
+		AlertRules:   []*models.AlertRule{},
+		SavedQueries: []*models.SavedQuery{},
+	}
+	if admin, ok := store.(storage.DataAdmin); ok {
+		retention, err := admin.GetRetention(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.Retention = retention.String()
+	}
+	if rules, ok := store.(storage.AlertRuleStore); ok {
+		list, err := rules.ListAlertRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.AlertRules = list
+	}
+	if queries, ok := store.(storage.SavedQueryStore); ok {
+		list, err := queries.ListSavedQueries(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.SavedQueries = list
+	}
+	return b, nil
+}
+
+// Sign sets the bundle's signature under key.
+func Sign(b *models.ConfigBundle, key []byte) error {
+	sig, err := signature(b, key)
+	if err != nil {
+		return err
+	}
+	b.Signature = sig
+	return nil
+}
+
+// Verify returns ErrBadSignature unless the bundle is signed under key.
+func Verify(b *models.ConfigBundle, key []byte) error {
+	want, err := signature(b, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(want), []byte(b.Signature)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// signature is the hex HMAC-SHA256 of the bundle's JSON without its signature.
+func signature(b *models.ConfigBundle, key []byte) (string, error) {
+	unsigned := *b
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Options controls an import.
+type Options struct {
+	// DryRun reports what would change without changing anything
+	DryRun bool
+
+	// ValidateRule checks each alert rule (default alert.ValidateRule)
+	ValidateRule func(*models.AlertRule) error
+
+	// Deliveries, when set, limits where imported saved queries may deliver
+	Deliveries *models.DeliveryAllowlist
+
+	// Now stamps created and updated items
+	Now time.Time
+}
+
+// Import applies a verified bundle to store. Alert rules and saved queries
+// are matched by name: missing ones are created and differing ones
+// replaced, keeping their IDs. Nothing is deleted. The whole bundle is
+// validated before anything is written; a validation failure is returned
+// as a perrors validation error.
+func Import(ctx context.Context, store storage.ReadStorage, b *models.ConfigBundle, opts Options) (*models.BundleImportResult, error) {
+	if opts.ValidateRule == nil {
+		opts.ValidateRule = alert.ValidateRule
+	}
+	if err := validate(store, b, opts); err != nil {
+		return nil, perrors.Validation(err)
+	}
+	result := &models.BundleImportResult{DryRun: opts.DryRun}
+
+	if b.Retention != "" {
+		if err := importRetention(ctx, store, b.Retention, opts.DryRun, result); err != nil {
+			return nil, err
+		}
+	}
+	if len(b.AlertRules) > 0 {
+		if err := importAlertRules(ctx, store.(storage.AlertRuleStore), b.AlertRules, opts, &result.AlertRules); err != nil {
+			return nil, err
+		}
+	}
+	if len(b.SavedQueries) > 0 {
+		if err := importSavedQueries(ctx, store.(storage.SavedQueryStore), b.SavedQueries, opts, &result.SavedQueries); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// validate checks the bundle's version and items, and that store supports
+// every kind the bundle holds.
+func validate(store storage.ReadStorage, b *models.ConfigBundle, opts Options) error {
+	if b.Version != models.ConfigBundleVersion {
+		return fmt.Errorf("bundle version %d is not supported (want %d)", b.Version, models.ConfigBundleVersion)
+	}
+
+	var errs []error
+	if b.Retention != "" {
+		if _, ok := store.(storage.DataAdmin); !ok {
+			errs = append(errs, errors.New("storage backend does not support retention changes"))
+		}
+		if retention, err := time.ParseDuration(b.Retention); err != nil || (retention != 0 && retention < minRetention) {
+			errs = append(errs, fmt.Errorf("retention %q must be 0s (forever) or a duration of at least 1h", b.Retention))
+		}
+	}
+
+	if _, ok := store.(storage.AlertRuleStore); !ok && len(b.AlertRules) > 0 {
+		errs = append(errs, errors.New("storage backend does not support alert rules"))
+	}
+	names := make(map[string]bool)
+	for _, rule := range b.AlertRules {
+		if names[rule.Name] {
+			errs = append(errs, fmt.Errorf("alert rule %q appears more than once", rule.Name))
+		}
+		names[rule.Name] = true
+		// Imported rules are managed through the API like any stored rule
+		rule.Source = models.RuleSourceAPI
+		if err := opts.ValidateRule(rule); err != nil {
+			errs = append(errs, fmt.Errorf("alert rule %q: %w", rule.Name, err))
+		}
+	}
+
+	if _, ok := store.(storage.SavedQueryStore); !ok && len(b.SavedQueries) > 0 {
+		errs = append(errs, errors.New("storage backend does not support saved queries"))
+	}
+	names = make(map[string]bool)
+	for _, query := range b.SavedQueries {
+		if names[query.Name] {
+			errs = append(errs, fmt.Errorf("saved query %q appears more than once", query.Name))
+		}
+		names[query.Name] = true
+		if err := query.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("saved query %q: %w", query.Name, err))
+		}
+		if opts.Deliveries != nil {
+			if err := opts.Deliveries.Check(query.Target); err != nil {
+				errs = append(errs, fmt.Errorf("saved query %q: %w", query.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// importRetention changes the retention unless it is already the bundle's.
+// Like the retention endpoint, it refuses a finite retention while
+// retention holds exist.
+func importRetention(ctx context.Context, store storage.ReadStorage, value string, dryRun bool, result *models.BundleImportResult) error {
+	admin := store.(storage.DataAdmin)
+	retention, _ := time.ParseDuration(value)
+	previous, err := admin.GetRetention(ctx)
+	if err != nil || previous == retention {
+		return err
+	}
+	if holds, ok := store.(storage.HoldStore); ok && retention != 0 {
+		active, err := holds.ListHolds(ctx)
+		if err != nil {
+			return err
+		}
+		if len(active) > 0 {
+			return fmt.Errorf("%d retention hold(s) exist: %w", len(active), ErrRetentionHeld)
+		}
+	}
+
+	result.Retention = retention.String()
+	result.PreviousRetention = previous.String()
+	if dryRun {
+		return nil
+	}
+	return admin.SetRetention(ctx, retention)
+}
+
+func importAlertRules(ctx context.Context, store storage.AlertRuleStore, rules []*models.AlertRule, opts Options, changes *models.BundleChanges) error {
+	existing, err := store.ListAlertRules(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*models.AlertRule, len(existing))
+	for _, rule := range existing {
+		if _, ok := byName[rule.Name]; !ok {
+			byName[rule.Name] = rule
+		}
+	}
+
+	for _, imported := range rules {
+		rule := *imported
+		current, ok := byName[rule.Name]
+		switch {
+		case !ok:
+			changes.Created = append(changes.Created, rule.Name)
+			if !opts.DryRun {
+				rule.ID = uuid.New().String()
+				rule.CreatedAt, rule.UpdatedAt = opts.Now.UTC(), opts.Now.UTC()
+				err = store.CreateAlertRule(ctx, &rule)
+			}
+		case sameRule(&rule, current):
+			changes.Unchanged = append(changes.Unchanged, rule.Name)
+		default:
+			changes.Updated = append(changes.Updated, rule.Name)
+			if !opts.DryRun {
+				rule.ID, rule.CreatedAt, rule.UpdatedAt = current.ID, current.CreatedAt, opts.Now.UTC()
+				err = store.UpdateAlertRule(ctx, &rule)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("alert rule %q: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+func importSavedQueries(ctx context.Context, store storage.SavedQueryStore, queries []*models.SavedQuery, opts Options, changes *models.BundleChanges) error {
+	existing, err := store.ListSavedQueries(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*models.SavedQuery, len(existing))
+	for _, query := range existing {
+		if _, ok := byName[query.Name]; !ok {
+			byName[query.Name] = query
+		}
+	}
+
+	for _, imported := range queries {
+		query := *imported
+		current, ok := byName[query.Name]
+		switch {
+		case !ok:
+			changes.Created = append(changes.Created, query.Name)
+			if !opts.DryRun {
+				query.ID = uuid.New().String()
+				query.CreatedAt, query.UpdatedAt = opts.Now.UTC(), opts.Now.UTC()
+				err = store.CreateSavedQuery(ctx, &query)
+			}
+		case sameQuery(&query, current):
+			changes.Unchanged = append(changes.Unchanged, query.Name)
+		default:
+			changes.Updated = append(changes.Updated, query.Name)
+			if !opts.DryRun {
+				query.ID, query.CreatedAt, query.UpdatedAt = current.ID, current.CreatedAt, opts.Now.UTC()
+				err = store.UpdateSavedQuery(ctx, &query)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("saved query %q: %w", query.Name, err)
+		}
+	}
+	return nil
+}
+
+// sameRule reports whether two rules differ only in identity and timestamps.
+func sameRule(a, b *models.AlertRule) bool {
+	x, y := *a, *b
+	x.ID, x.CreatedAt, x.UpdatedAt, x.Source = "", time.Time{}, time.Time{}, ""
+	y.ID, y.CreatedAt, y.UpdatedAt, y.Source = "", time.Time{}, time.Time{}, ""
+	return reflect.DeepEqual(x, y)
+}
+
+// sameQuery reports whether two saved queries differ only in identity and timestamps.
+func sameQuery(a, b *models.SavedQuery) bool {
+	x, y := *a, *b
+	x.ID, x.CreatedAt, x.UpdatedAt = "", time.Time{}, time.Time{}
+	y.ID, y.CreatedAt, y.UpdatedAt = "", time.Time{}, time.Time{}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,201 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// memStore keeps alert rules, saved queries and the retention in memory.
+type memStore struct {
+	rules     []*models.AlertRule
+	queries   []*models.SavedQuery
+	retention time.Duration
+}
+
+func (s *memStore) GetGPUs(ctx context.Context) ([]string, error) { return nil, nil }
+func (s *memStore) GetTelemetry(ctx context.Context, query *models.TelemetryQuery) ([]*models.GPUMetric, error) {
+	return nil, nil
+}
+func (s *memStore) Close() error { return nil }
+
+func (s *memStore) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	s.rules = append(s.rules, rule)
+	return nil
+}
+func (s *memStore) GetAlertRule(ctx context.Context, id string) (*models.AlertRule, error) {
+	return nil, errors.New("not used")
+}
+func (s *memStore) ListAlertRules(ctx context.Context) ([]*models.AlertRule, error) {
+	return s.rules, nil
+}
+func (s *memStore) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	for i, r := range s.rules {
+		if r.ID == rule.ID {
+			s.rules[i] = rule
+		}
+	}
+	return nil
+}
+func (s *memStore) DeleteAlertRule(ctx context.Context, id string) error { return nil }
+
+func (s *memStore) CreateSavedQuery(ctx context.Context, query *models.SavedQuery) error {
+	s.queries = append(s.queries, query)
+	return nil
+}
+func (s *memStore) GetSavedQuery(ctx context.Context, id string) (*models.SavedQuery, error) {
+	return nil, errors.New("not used")
+}
+func (s *memStore) ListSavedQueries(ctx context.Context) ([]*models.SavedQuery, error) {
+	return s.queries, nil
+}
+func (s *memStore) UpdateSavedQuery(ctx context.Context, query *models.SavedQuery) error {
+	for i, q := range s.queries {
+		if q.ID == query.ID {
+			s.queries[i] = query
+		}
+	}
+	return nil
+}
+func (s *memStore) DeleteSavedQuery(ctx context.Context, id string) error          { return nil }
+func (s *memStore) RecordRun(ctx context.Context, run *models.SavedQueryRun) error { return nil }
+func (s *memStore) ListRuns(ctx context.Context, queryID string, limit int) ([]*models.SavedQueryRun, error) {
+	return nil, nil
+}
+
+func (s *memStore) DeleteTelemetry(ctx context.Context, req *models.CleanupRequest) ([]string, error) {
+	return nil, nil
+}
+func (s *memStore) GetRetention(ctx context.Context) (time.Duration, error) { return s.retention, nil }
+func (s *memStore) SetRetention(ctx context.Context, retention time.Duration) error {
+	s.retention = retention
+	return nil
+}
+func (s *memStore) RecordCleanup(ctx context.Context, record *models.CleanupRecord) error { return nil }
+func (s *memStore) ListCleanups(ctx context.Context, limit int) ([]*models.CleanupRecord, error) {
+	return nil, nil
+}
+
+var (
+	key = []byte("0123456789abcdef")
+	now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func hotRule(threshold float64) *models.AlertRule {
+	return &models.AlertRule{ID: "rule-1", Name: "gpu-hot", Metric: "DCGM_FI_DEV_GPU_TEMP", Op: ">", Threshold: threshold,
+		Severity: models.SeverityCritical, Enabled: true, Source: models.RuleSourceAPI, CreatedAt: now}
+}
+
+func nightlyQuery() *models.SavedQuery {
+	return &models.SavedQuery{ID: "query-1", Name: "nightly", Query: models.SavedQuerySpec{Window: "24h"}, Schedule: "24h",
+		Format: "csv", Target: models.DeliveryTarget{Type: models.DeliveryWebhook, URL: "https://reports.example.com"}, Enabled: true}
+}
+
+func TestSignVerify(t *testing.T) {
+	b, err := Export(context.Background(), &memStore{rules: []*models.AlertRule{hotRule(85)}, retention: 720 * time.Hour}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Retention != "720h0m0s" || len(b.AlertRules) != 1 || len(b.SavedQueries) != 0 {
+		t.Fatalf("unexpected bundle %+v", b)
+	}
+	if err := Sign(b, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(b, key); err != nil {
+		t.Errorf("expected the signature to verify, got %v", err)
+	}
+	if err := Verify(b, []byte("another-key-0123")); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected ErrBadSignature under another key, got %v", err)
+	}
+	b.AlertRules[0].Threshold = 95
+	if err := Verify(b, key); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("expected ErrBadSignature after an edit, got %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	b := &models.ConfigBundle{
+		Version:      models.ConfigBundleVersion,
+		Retention:    "720h0m0s",
+		AlertRules:   []*models.AlertRule{hotRule(90)},
+		SavedQueries: []*models.SavedQuery{nightlyQuery()},
+	}
+	existing := hotRule(85)
+	existing.ID = "target-rule"
+	target := &memStore{rules: []*models.AlertRule{existing}, retention: 168 * time.Hour}
+
+	result, err := Import(ctx, target, b, Options{DryRun: true, Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Retention != "720h0m0s" || len(result.AlertRules.Updated) != 1 || len(result.SavedQueries.Created) != 1 {
+		t.Errorf("unexpected dry-run result %+v", result)
+	}
+	if target.retention != 168*time.Hour || target.rules[0].Threshold != 85 || len(target.queries) != 0 {
+		t.Fatal("dry run changed the target")
+	}
+
+	if _, err := Import(ctx, target, b, Options{Now: now}); err != nil {
+		t.Fatal(err)
+	}
+	if target.retention != 720*time.Hour || len(target.queries) != 1 {
+		t.Errorf("expected retention and saved query imported, got %v and %d queries", target.retention, len(target.queries))
+	}
+	if rule := target.rules[0]; rule.ID != "target-rule" || rule.Threshold != 90 {
+		t.Errorf("expected the rule replaced under its own ID, got %+v", rule)
+	}
+
+	result, err = Import(ctx, target, b, Options{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Retention != "" || len(result.AlertRules.Unchanged) != 1 || len(result.SavedQueries.Unchanged) != 1 {
+		t.Errorf("expected a repeated import to change nothing, got %+v", result)
+	}
+
+	b.SavedQueries[0].Schedule = "12h"
+	result, err = Import(ctx, target, b, Options{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SavedQueries.Updated) != 1 || target.queries[0].Schedule != "12h" || target.queries[0].ID == "query-1" {
+		t.Errorf("expected the saved query updated under the ID it was created with, got %+v", target.queries[0])
+	}
+}
+
+func TestImportValidates(t *testing.T) {
+	bad := nightlyQuery()
+	bad.Schedule = "1s"
+	b := &models.ConfigBundle{
+		Version:      models.ConfigBundleVersion,
+		Retention:    "10m",
+		AlertRules:   []*models.AlertRule{hotRule(90), hotRule(95)},
+		SavedQueries: []*models.SavedQuery{bad},
+	}
+	target := &memStore{retention: 168 * time.Hour}
+	_, err := Import(context.Background(), target, b, Options{Now: now})
+	if !perrors.IsValidation(err) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if target.retention != 168*time.Hour || len(target.rules) != 0 {
+		t.Error("expected nothing imported from an invalid bundle")
+	}
+
+	b = &models.ConfigBundle{Version: 2}
+	if _, err := Import(context.Background(), target, b, Options{}); !perrors.IsValidation(err) {
+		t.Errorf("expected an unsupported version refused, got %v", err)
+	}
+
+	// Saved queries delivering off the allowlist are refused
+	b = &models.ConfigBundle{Version: models.ConfigBundleVersion, SavedQueries: []*models.SavedQuery{nightlyQuery()}}
+	allow := &models.DeliveryAllowlist{WebhookHosts: []string{"hooks.example.com"}}
+	if _, err := Import(context.Background(), target, b, Options{Deliveries: allow, Now: now}); !perrors.IsValidation(err) {
+		t.Errorf("expected a target off the allowlist refused, got %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+// Package cache provides an in-memory cache of the latest telemetry values per GPU,
+// so high-frequency dashboard reads never reach the storage backend.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/envelope"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Cache sources.
+const (
+	SourceStorage = "storage" // periodic refresh from the storage backend
+	SourceMQ      = "mq"      // live updates from an MQ subscription
+)
+
+// MetricValue is the latest observation of a single metric.
+type MetricValue struct {
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GPUSnapshot is the latest known state of a GPU.
+type GPUSnapshot struct {
+	UUID      string                 `json:"uuid"`
+	GPUID     int                    `json:"gpu_id"`
+	Device    string                 `json:"device"`
+	ModelName string                 `json:"model_name"`
+	Hostname  string                 `json:"hostname"`
+	LastSeen  time.Time              `json:"last_seen"`
+	Metrics   map[string]MetricValue `json:"metrics"`
+
+	// version and changedAt record the cache update that last changed the
+	// GPU's identity or one of its values
+	version   uint64
+	changedAt time.Time
+}
+
+// Status describes the cache contents and freshness.
+type Status struct {
+	Source     string    `json:"source"`
+	Loaded     bool      `json:"loaded"`
+	GPUs       int       `json:"gpus"`
+	Metrics    int       `json:"metrics"`
+	LastUpdate time.Time `json:"last_update,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// ErrInvalidCursor is returned by Changes for a cursor it did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Latest holds the most recent value of every metric for every GPU.
+type Latest struct {
+	source string
+
+	// epoch tells this cache's cursors from those of another process
+	epoch int64
+
+	mu         sync.RWMutex
+	gpus       map[string]*GPUSnapshot
+	version    uint64 // bumped by every update that changes a value
+	loaded     bool
+	lastUpdate time.Time
+	lastErr    error
+}
+
+// NewLatest creates an empty cache fed from the given source.
+func NewLatest(source string) *Latest {
+	return &Latest{
+		source: source,
+		epoch:  time.Now().UnixNano(),
+		gpus:   make(map[string]*GPUSnapshot),
+	}
+}
+
+// Update merges metrics into the cache, keeping the newest value per GPU and metric.
+func (c *Latest) Update(metrics []*models.GPUMetric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A newer sample of an unchanged value is not a change, so deltas stay
+	// small while a GPU reports steady values
+	now := time.Now()
+	version := c.version + 1
+	changed := false
+	for _, m := range metrics {
+		if m == nil || m.UUID == "" {
+			continue
+		}
+
+		gpu, ok := c.gpus[m.UUID]
+		if !ok {
+			gpu = &GPUSnapshot{UUID: m.UUID, Metrics: make(map[string]MetricValue)}
+			c.gpus[m.UUID] = gpu
+		}
+		before := gpu.identity()
+
+		cur, seen := gpu.Metrics[m.MetricName]
+		if seen && cur.Timestamp.After(m.Timestamp) {
+			continue
+		}
+		gpu.Metrics[m.MetricName] = MetricValue{Value: m.Value, Timestamp: m.Timestamp}
+
+		if !m.Timestamp.Before(gpu.LastSeen) {
+			gpu.LastSeen = m.Timestamp
+			gpu.GPUID = m.GPUID
+			if m.Device != "" {
+				gpu.Device = m.Device
+			}
+			if m.ModelName != "" {
+				gpu.ModelName = m.ModelName
+			}
+			if m.Hostname != "" {
+				gpu.Hostname = m.Hostname
+			}
+		}
+
+		if !seen || cur.Value != m.Value || gpu.identity() != before {
+			gpu.version, gpu.changedAt = version, now
+			changed = true
+		}
+	}
+	if changed {
+		c.version = version
+	}
+
+	c.loaded = true
+	c.lastUpdate = now
+	c.lastErr = nil
+}
+
+// gpuIdentity is a GPU's descriptive fields, compared to notice changes.
+type gpuIdentity struct {
+	gpuID                       int
+	device, modelName, hostname string
+}
+
+func (g *GPUSnapshot) identity() gpuIdentity {
+	return gpuIdentity{g.GPUID, g.Device, g.ModelName, g.Hostname}
+}
+
+// recordError notes a failed refresh for Status reporting.
+func (c *Latest) recordError(err error) {
+	c.mu.Lock()
+	c.lastErr = err
+	c.mu.Unlock()
+}
+
+// Get returns a copy of the snapshot for one GPU.
+func (c *Latest) Get(uuid string) (GPUSnapshot, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	gpu, ok := c.gpus[uuid]
+	if !ok {
+		return GPUSnapshot{}, false
+	}
+	return gpu.clone(), true
+}
+
+// Snapshot returns copies of all GPU snapshots, sorted by UUID.
+func (c *Latest) Snapshot() []GPUSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]GPUSnapshot, 0, len(c.gpus))
+	for _, gpu := range c.gpus {
+		out = append(out, gpu.clone())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UUID < out[j].UUID })
+	return out
+}
+
+// Changes returns copies of the GPUs whose identity or values changed after
+// cursor, sorted by UUID, with the cursor to pass next time. An empty
+// cursor, or one issued by another cache such as before an API restart,
+// returns every GPU with reset set.
+func (c *Latest) Changes(cursor string) (changed []GPUSnapshot, next string, reset bool, err error) {
+	var since uint64
+	if cursor != "" {
+		epoch, version, ok := strings.Cut(cursor, ".")
+		e, err1 := strconv.ParseInt(epoch, 10, 64)
+		v, err2 := strconv.ParseUint(version, 10, 64)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, "", false, fmt.Errorf("%w %q", ErrInvalidCursor, cursor)
+		}
+		since = v
+		reset = e != c.epoch
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if cursor == "" || since > c.version {
+		reset = true
+	}
+	if reset {
+		since = 0
+	}
+	changed = c.collect(func(g *GPUSnapshot) bool { return g.version > since })
+	return changed, c.cursorLocked(), reset, nil
+}
+
+// ChangedSince returns copies of the GPUs whose identity or values changed
+// at or after t, sorted by UUID, with a cursor for Changes.
+func (c *Latest) ChangedSince(t time.Time) ([]GPUSnapshot, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.collect(func(g *GPUSnapshot) bool { return !g.changedAt.Before(t) }), c.cursorLocked()
+}
+
+// collect copies the GPUs matching keep, sorted by UUID. The caller holds mu.
+func (c *Latest) collect(keep func(*GPUSnapshot) bool) []GPUSnapshot {
+	out := make([]GPUSnapshot, 0)
+	for _, gpu := range c.gpus {
+		if keep(gpu) {
+			out = append(out, gpu.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UUID < out[j].UUID })
+	return out
+}
+
+// cursorLocked marks the current version. The caller holds mu.
+func (c *Latest) cursorLocked() string {
+	return fmt.Sprintf("%d.%d", c.epoch, c.version)
+}
+
+// Status reports the cache size and freshness.
+func (c *Latest) Status() Status {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	st := Status{
+		Source:     c.source,
+		Loaded:     c.loaded,
+		GPUs:       len(c.gpus),
+		LastUpdate: c.lastUpdate,
+	}
+	for _, gpu := range c.gpus {
+		st.Metrics += len(gpu.Metrics)
+	}
+	if c.lastErr != nil {
+		st.LastError = c.lastErr.Error()
+	}
+	return st
+}
+
+// clone returns a deep copy so callers can't race with updates.
+func (g *GPUSnapshot) clone() GPUSnapshot {
+	cp := *g
+	cp.Metrics = make(map[string]MetricValue, len(g.Metrics))
+	for k, v := range g.Metrics {
+		cp.Metrics[k] = v
+	}
+	return cp
+}
+
+// RefreshFromStorage loads the latest values within window from store.
+// Backends implementing storage.LatestReader answer in one query; others are
+// sampled per GPU.
+func (c *Latest) RefreshFromStorage(ctx context.Context, store storage.ReadStorage, window time.Duration) error {
+	metrics, err := loadLatest(ctx, store, window)
+	if err != nil {
+		c.recordError(err)
+		return err
+	}
+	c.Update(metrics)
+	return nil
+}
+
+// loadLatest reads the newest values from storage.
+func loadLatest(ctx context.Context, store storage.ReadStorage, window time.Duration) ([]*models.GPUMetric, error) {
+	if lr, ok := store.(storage.LatestReader); ok {
+		return lr.GetLatest(ctx, window)
+	}
+
+	gpus, err := store.GetGPUs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now().Add(-window)
+	var metrics []*models.GPUMetric
+	for _, uuid := range gpus {
+		recent, err := store.GetTelemetry(ctx, &models.TelemetryQuery{UUID: uuid, StartTime: &start, Limit: 100})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load latest values for %s: %w", uuid, err)
+		}
+		metrics = append(metrics, recent...)
+	}
+	return metrics, nil
+}
+
+// RunStorageRefresh refreshes the cache from storage every interval until ctx is done.
+func (c *Latest) RunStorageRefresh(ctx context.Context, store storage.ReadStorage, interval, window time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.RefreshFromStorage(ctx, store, window); err != nil && ctx.Err() == nil {
+			logger.Printf("Latest cache refresh failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SubscribeMQ keeps the cache current from new batches published to the MQ,
+// storing aliased metrics under their canonical names and opening encrypted
+// payloads with keys as the collector does.
+// The subscription starts at the latest offset, so callers typically seed the
+// cache with RefreshFromStorage first.
+func (c *Latest) SubscribeMQ(ctx context.Context, client *mq.Client, subscriberID string, aliases models.MetricAliases, keys *envelope.Keyring) error {
+	return client.Subscribe(ctx, subscriberID, mq.OffsetLatest, func(ctx context.Context, msg *mq.Message) error {
+		payload, err := keys.Open(msg.Payload, msg.Metadata)
+		if err != nil {
+			c.recordError(err)
+			return err
+		}
+		var batch models.MetricBatch
+		if err := json.Unmarshal(payload, &batch); err != nil {
+			c.recordError(err)
+			return err
+		}
+
+		metrics := make([]*models.GPUMetric, len(batch.Metrics))
+		for i := range batch.Metrics {
+			batch.Metrics[i].MetricName = aliases.Canonical(batch.Metrics[i].MetricName)
+			metrics[i] = &batch.Metrics[i]
+		}
+		c.Update(metrics)
+		return nil
+	})
+}
@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// mockReadStorage implements storage.ReadStorage without GetLatest, forcing the per-GPU fallback.
+type mockReadStorage struct {
+	metrics map[string][]*models.GPUMetric
+	err     error
+}
+
+func (m *mockReadStorage) GetGPUs(ctx context.Context) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	gpus := make([]string, 0, len(m.metrics))
+	for uuid := range m.metrics {
+		gpus = append(gpus, uuid)
+	}
+	return gpus, nil
+}
+
+func (m *mockReadStorage) GetTelemetry(ctx context.Context, query *models.TelemetryQuery) ([]*models.GPUMetric, error) {
+	return m.metrics[query.UUID], m.err
+}
+
+func (m *mockReadStorage) Close() error {
+	return nil
+}
+
+// latestStorage also implements storage.LatestReader.
+type latestStorage struct {
+	mockReadStorage
+	latest []*models.GPUMetric
+}
+
+func (m *latestStorage) GetLatest(ctx context.Context, window time.Duration) ([]*models.GPUMetric, error) {
+	return m.latest, nil
+}
+
+func TestLatestUpdateKeepsNewest(t *testing.T) {
+	c := NewLatest(SourceStorage)
+	now := time.Now()
+
+	c.Update([]*models.GPUMetric{
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 80, Timestamp: now},
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 10, Timestamp: now.Add(-time.Minute)},
+		{UUID: "GPU-1", Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 65, Timestamp: now},
+	})
+
+	gpu, ok := c.Get("GPU-1")
+	if !ok {
+		t.Fatal("expected GPU-1 in cache")
+	}
+	if v := gpu.Metrics["DCGM_FI_DEV_GPU_UTIL"].Value; v != 80 {
+		t.Errorf("expected newest value 80, got %v", v)
+	}
+	if len(gpu.Metrics) != 2 {
+		t.Errorf("expected 2 metrics, got %d", len(gpu.Metrics))
+	}
+	if gpu.Hostname != "host-001" {
+		t.Errorf("expected hostname host-001, got %s", gpu.Hostname)
+	}
+
+	st := c.Status()
+	if !st.Loaded || st.GPUs != 1 || st.Metrics != 2 || st.Source != SourceStorage {
+		t.Errorf("unexpected status: %+v", st)
+	}
+}
+
+func TestLatestSnapshotReturnsCopies(t *testing.T) {
+	c := NewLatest(SourceMQ)
+	now := time.Now()
+	c.Update([]*models.GPUMetric{
+		{UUID: "GPU-2", MetricName: "m", Value: 1, Timestamp: now},
+		{UUID: "GPU-1", MetricName: "m", Value: 2, Timestamp: now},
+	})
+
+	snap := c.Snapshot()
+	if len(snap) != 2 || snap[0].UUID != "GPU-1" || snap[1].UUID != "GPU-2" {
+		t.Fatalf("expected snapshot sorted by UUID, got %+v", snap)
+	}
+
+	snap[0].Metrics["m"] = MetricValue{Value: 99}
+	gpu, _ := c.Get("GPU-1")
+	if gpu.Metrics["m"].Value != 2 {
+		t.Error("modifying a snapshot should not affect the cache")
+	}
+}
+
+func TestLatestNotLoadedUntilUpdate(t *testing.T) {
+	c := NewLatest(SourceStorage)
+	if c.Status().Loaded {
+		t.Error("expected empty cache to report not loaded")
+	}
+	if _, ok := c.Get("GPU-1"); ok {
+		t.Error("expected miss on empty cache")
+	}
+}
+
+func TestRefreshFromStorageFallback(t *testing.T) {
+	now := time.Now()
+	store := &mockReadStorage{metrics: map[string][]*models.GPUMetric{
+		"GPU-1": {
+			{UUID: "GPU-1", MetricName: "m", Value: 5, Timestamp: now},
+			{UUID: "GPU-1", MetricName: "m", Value: 3, Timestamp: now.Add(-time.Second)},
+		},
+		"GPU-2": {{UUID: "GPU-2", MetricName: "m", Value: 7, Timestamp: now}},
+	}}
+
+	c := NewLatest(SourceStorage)
+	if err := c.RefreshFromStorage(context.Background(), store, time.Minute); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	if st := c.Status(); st.GPUs != 2 {
+		t.Errorf("expected 2 GPUs, got %d", st.GPUs)
+	}
+	gpu, _ := c.Get("GPU-1")
+	if gpu.Metrics["m"].Value != 5 {
+		t.Errorf("expected newest value 5, got %v", gpu.Metrics["m"].Value)
+	}
+}
+
+func TestRefreshFromStorageUsesLatestReader(t *testing.T) {
+	store := &latestStorage{latest: []*models.GPUMetric{
+		{UUID: "GPU-9", MetricName: "m", Value: 42, Timestamp: time.Now()},
+	}}
+
+	c := NewLatest(SourceStorage)
+	if err := c.RefreshFromStorage(context.Background(), store, time.Minute); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if _, ok := c.Get("GPU-9"); !ok {
+		t.Error("expected GPU-9 from GetLatest")
+	}
+}
+
+func TestRefreshFromStorageError(t *testing.T) {
+	c := NewLatest(SourceStorage)
+	err := c.RefreshFromStorage(context.Background(), &mockReadStorage{err: errors.New("influx down")}, time.Minute)
+	if err == nil {
+		t.Fatal("expected refresh error")
+	}
+	st := c.Status()
+	if st.Loaded {
+		t.Error("failed refresh should not mark the cache loaded")
+	}
+	if st.LastError == "" {
+		t.Error("expected last error to be recorded")
+	}
+}
+
+func TestLatestChanges(t *testing.T) {
+	c := NewLatest(SourceMQ)
+	now := time.Now()
+	metric := func(uuid string, value float64, ts time.Time) *models.GPUMetric {
+		return &models.GPUMetric{UUID: uuid, Hostname: "host-001", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: value, Timestamp: ts}
+	}
+	c.Update([]*models.GPUMetric{metric("GPU-1", 10, now), metric("GPU-2", 20, now)})
+
+	all, cursor, reset, err := c.Changes("")
+	if err != nil || !reset || len(all) != 2 {
+		t.Fatalf("Changes(\"\") = %d GPUs, reset %v, err %v; want 2, true, nil", len(all), reset, err)
+	}
+
+	// A newer sample of the same value is not a change
+	c.Update([]*models.GPUMetric{metric("GPU-1", 10, now.Add(time.Second)), metric("GPU-2", 25, now.Add(time.Second))})
+	changed, next, reset, err := c.Changes(cursor)
+	if err != nil || reset || len(changed) != 1 || changed[0].UUID != "GPU-2" {
+		t.Fatalf("Changes(cursor) = %+v, reset %v, err %v; want GPU-2 only", changed, reset, err)
+	}
+
+	changed, again, _, _ := c.Changes(next)
+	if len(changed) != 0 || again != next {
+		t.Errorf("Changes(next) = %d GPUs, cursor %q; want none and %q", len(changed), again, next)
+	}
+
+	// A new hostname changes the GPU even with the same values
+	moved := metric("GPU-1", 10, now.Add(2*time.Second))
+	moved.Hostname = "host-002"
+	c.Update([]*models.GPUMetric{moved})
+	if changed, _, _, _ := c.Changes(next); len(changed) != 1 || changed[0].Hostname != "host-002" {
+		t.Errorf("after a move Changes = %+v, want GPU-1 on host-002", changed)
+	}
+
+	if since, _ := c.ChangedSince(now.Add(-time.Minute)); len(since) != 2 {
+		t.Errorf("ChangedSince(a minute ago) = %d GPUs, want 2", len(since))
+	}
+	if since, _ := c.ChangedSince(time.Now().Add(time.Minute)); len(since) != 0 {
+		t.Errorf("ChangedSince(the future) = %d GPUs, want 0", len(since))
+	}
+}
+
+func TestLatestChangesCursors(t *testing.T) {
+	c := NewLatest(SourceMQ)
+	c.Update([]*models.GPUMetric{{UUID: "GPU-1", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 10, Timestamp: time.Now()}})
+
+	for _, cursor := range []string{"abc", "1", "1.x"} {
+		if _, _, _, err := c.Changes(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Changes(%q) error = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+
+	// A cursor from another cache, or ahead of this one, starts over
+	_, cursor, _, _ := NewLatest(SourceMQ).Changes("")
+	for _, cursor := range []string{cursor, fmt.Sprintf("%d.%d", c.epoch, 99)} {
+		changed, _, reset, err := c.Changes(cursor)
+		if err != nil || !reset || len(changed) != 1 {
+			t.Errorf("Changes(%q) = %d GPUs, reset %v, err %v; want a full reset", cursor, len(changed), reset, err)
+		}
+	}
+}
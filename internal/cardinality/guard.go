@@ -0,0 +1,169 @@
+// Package cardinality keeps the collector from flooding InfluxDB with
+// series. A tag that takes a new value per workload, such as a pod label
+// carrying a job ID, creates a new series for every metric of every GPU,
+// and InfluxDB's memory and index grow with each.
+package cardinality
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// topMetrics bounds the metrics listed in a report.
+const topMetrics = 20
+
+// Report is a guard's count of the series written in its current window.
+type Report struct {
+	models.CardinalityReport
+
+	// Budget is the most series allowed per window (0 = unlimited)
+	Budget int `json:"budget"`
+
+	// Created is how many of the series were not written in the previous window
+	Created int `json:"created"`
+
+	// Exceeded is set once a metric would have added a series past the budget
+	Exceeded bool `json:"exceeded"`
+
+	// Drop is set when metrics for new series past the budget are dropped
+	Drop bool `json:"drop"`
+
+	// Dropped is how many metrics were dropped in the window
+	Dropped int64 `json:"dropped"`
+}
+
+// Guard counts the distinct series written per window and, once a window's
+// budget is spent, warns and optionally drops metrics for further new
+// series. Series already written in the window are never dropped. It is
+// safe for concurrent use.
+type Guard struct {
+	cfg    config.CardinalityConfig
+	schema storage.Schema
+	logger *log.Logger
+
+	mu       sync.Mutex
+	start    time.Time
+	current  *storage.SeriesCounter
+	previous *storage.SeriesCounter // nil in the first window
+	created  int
+	exceeded bool
+	dropped  int64
+}
+
+// NewGuard creates a guard whose first window starts at now. Series are
+// identified as schema stores them.
+func NewGuard(cfg config.CardinalityConfig, schema storage.Schema, logger *log.Logger, now time.Time) *Guard {
+	return &Guard{
+		cfg:     cfg,
+		schema:  schema,
+		logger:  logger,
+		start:   now,
+		current: storage.NewSeriesCounter(),
+	}
+}
+
+// Filter counts the series of metrics received at now and returns the
+// metrics to store. It returns metrics itself unless some are dropped.
+func (g *Guard) Filter(metrics []*models.GPUMetric, now time.Time) []*models.GPUMetric {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.roll(now)
+
+	var kept []*models.GPUMetric
+	for i, m := range metrics {
+		tags := g.schema.SeriesTags(m)
+		if !g.current.Contains(m.MetricName, tags) {
+			if g.cfg.Budget > 0 && g.current.Len() >= g.cfg.Budget {
+				if !g.exceeded {
+					g.exceeded = true
+					g.warn(m, tags)
+				}
+				if g.cfg.Drop {
+					if kept == nil {
+						kept = append(make([]*models.GPUMetric, 0, len(metrics)), metrics[:i]...)
+					}
+					g.dropped++
+					continue
+				}
+			}
+			g.current.Add(m.MetricName, tags)
+			if g.previous == nil || !g.previous.Contains(m.MetricName, tags) {
+				g.created++
+			}
+		}
+		if kept != nil {
+			kept = append(kept, m)
+		}
+	}
+
+	if kept == nil {
+		return metrics
+	}
+	return kept
+}
+
+// Report returns the counts of the current window as of now.
+func (g *Guard) Report(now time.Time) Report {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.roll(now)
+	return g.report(now)
+}
+
+func (g *Guard) report(now time.Time) Report {
+	r := Report{
+		CardinalityReport: models.CardinalityReport{Start: g.start.UTC(), End: now.UTC()},
+		Budget:            g.cfg.Budget,
+		Created:           g.created,
+		Exceeded:          g.exceeded,
+		Drop:              g.cfg.Drop,
+		Dropped:           g.dropped,
+	}
+	g.current.Fill(&r.CardinalityReport, topMetrics)
+	return r
+}
+
+// roll starts a new window once the current one has lasted cfg.Window.
+func (g *Guard) roll(now time.Time) {
+	if now.Sub(g.start) < g.cfg.Window {
+		return
+	}
+	if g.exceeded {
+		g.logger.Printf("Cardinality window since %s closed: %d series written against a budget of %d, %d metrics dropped",
+			g.start.UTC().Format(time.RFC3339), g.current.Len(), g.cfg.Budget, g.dropped)
+	}
+	g.previous, g.current = g.current, storage.NewSeriesCounter()
+	g.start = now
+	g.created = 0
+	g.exceeded = false
+	g.dropped = 0
+}
+
+// warn logs that the budget is spent, naming the series that would exceed
+// it and the tags with the most values, which point at the offending label.
+func (g *Guard) warn(m *models.GPUMetric, tags map[string]string) {
+	action := "still written"
+	if g.cfg.Drop {
+		action = "dropped"
+	}
+	pairs := make([]string, 0, len(tags))
+	for tag, v := range tags {
+		pairs = append(pairs, tag+"="+v)
+	}
+	sort.Strings(pairs)
+	top := g.report(g.start)
+	counts := make([]string, 0, len(top.Tags))
+	for _, c := range top.Tags {
+		counts = append(counts, fmt.Sprintf("%s=%d", c.Name, c.Count))
+	}
+	g.logger.Printf("Warning: cardinality budget of %d series exceeded in the window since %s; metrics for new series such as %s{%s} are %s until it ends. Distinct values per tag: %s",
+		g.cfg.Budget, g.start.UTC().Format(time.RFC3339), m.MetricName, strings.Join(pairs, ","), action, strings.Join(counts, ", "))
+}
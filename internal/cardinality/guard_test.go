@@ -0,0 +1,75 @@
+package cardinality
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func podMetrics(pods ...string) []*models.GPUMetric {
+	metrics := make([]*models.GPUMetric, 0, len(pods))
+	for _, pod := range pods {
+		metrics = append(metrics, &models.GPUMetric{UUID: "GPU-1", Hostname: "host-1", MetricName: "DCGM_FI_DEV_GPU_UTIL", Pod: pod})
+	}
+	return metrics
+}
+
+func TestGuardWarnsWithoutDropping(t *testing.T) {
+	var out bytes.Buffer
+	g := NewGuard(config.CardinalityConfig{Budget: 2, Window: time.Hour}, storage.Schema{}, log.New(&out, "", 0), start)
+
+	metrics := podMetrics("job-1", "job-2", "job-3", "job-1")
+	if kept := g.Filter(metrics, start); len(kept) != 4 {
+		t.Fatalf("expected every metric kept without drop, got %d", len(kept))
+	}
+	if !strings.Contains(out.String(), "cardinality budget of 2 series exceeded") || !strings.Contains(out.String(), "pod=job-3") {
+		t.Errorf("expected a warning naming the new series, got %q", out.String())
+	}
+
+	r := g.Report(start.Add(time.Minute))
+	if r.Series != 3 || r.Created != 3 || !r.Exceeded || r.Dropped != 0 {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if len(r.Tags) == 0 || r.Tags[0].Name != "pod" || r.Tags[0].Count != 3 {
+		t.Errorf("expected pod to have the most values, got %+v", r.Tags)
+	}
+}
+
+func TestGuardDropsNewSeries(t *testing.T) {
+	g := NewGuard(config.CardinalityConfig{Budget: 2, Window: time.Hour, Drop: true}, storage.Schema{}, log.New(&bytes.Buffer{}, "", 0), start)
+
+	kept := g.Filter(podMetrics("job-1", "job-2", "job-3", "job-1", "job-4"), start)
+	if len(kept) != 3 || kept[2].Pod != "job-1" {
+		t.Fatalf("expected job-3 and job-4 dropped, kept %d", len(kept))
+	}
+	if r := g.Report(start); r.Dropped != 2 || r.Series != 2 {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	// A new window restores the budget; known series are not counted as created
+	kept = g.Filter(podMetrics("job-1", "job-3"), start.Add(time.Hour))
+	if len(kept) != 2 {
+		t.Fatalf("expected both kept in the next window, kept %d", len(kept))
+	}
+	if r := g.Report(start.Add(time.Hour)); r.Series != 2 || r.Created != 1 || r.Exceeded || r.Dropped != 0 {
+		t.Errorf("unexpected report after rolling %+v", r)
+	}
+}
+
+func TestGuardFollowsSchemaTags(t *testing.T) {
+	schema := storage.Schema{Tags: []string{"uuid", "hostname"}}
+	g := NewGuard(config.CardinalityConfig{Budget: 1, Window: time.Hour, Drop: true}, schema, log.New(&bytes.Buffer{}, "", 0), start)
+
+	// Pods are not written, so they do not create series
+	if kept := g.Filter(podMetrics("job-1", "job-2", "job-3"), start); len(kept) != 3 {
+		t.Errorf("expected every metric kept when pod is not a tag, kept %d", len(kept))
+	}
+}
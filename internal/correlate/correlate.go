@@ -0,0 +1,112 @@
+// Package correlate measures how two metric series move together, and with
+// what delay, to aid performance investigations such as whether clocks drop
+// after temperature rises.
+package correlate
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// MinSamples is the fewest paired points a coefficient is computed from.
+const MinSamples = 3
+
+// ErrUndefined is returned when the series overlap in fewer than MinSamples
+// buckets or one of them is constant, so no coefficient exists.
+var ErrUndefined = errors.New("correlation is undefined: the metrics overlap in fewer than 3 buckets or one of them is constant")
+
+// Lag is the correlation with the second series shifted by Steps buckets.
+type Lag struct {
+	// Lag is Steps buckets as a duration; positive means the second metric follows the first
+	Lag   string `json:"lag" example:"30s"`
+	Steps int    `json:"steps" example:"3"`
+
+	// Coefficient is the Pearson correlation, from -1 to 1
+	Coefficient float64 `json:"coefficient" example:"-0.82"`
+
+	// Samples is the number of paired buckets
+	Samples int `json:"samples" example:"357"`
+}
+
+// Result is the correlation of two series at every lag examined.
+type Result struct {
+	// Coefficient is the Pearson correlation with no lag
+	Coefficient float64 `json:"coefficient" example:"-0.64"`
+	Samples     int     `json:"samples" example:"360"`
+
+	// BestLag is the lag with the strongest correlation, positive or negative
+	BestLag Lag `json:"best_lag"`
+
+	// Lags lists every lag with a defined coefficient, from -maxLag to maxLag
+	Lags []Lag `json:"lags"`
+}
+
+// Series correlates a and b, whose points are bucketed every step, at lags
+// of up to maxLag buckets either way. Buckets missing from either series are
+// skipped rather than filled.
+func Series(a, b []models.SeriesPoint, step time.Duration, maxLag int) (Result, error) {
+	bAt := make(map[int64]float64, len(b))
+	for _, p := range b {
+		bAt[p.Time.UnixNano()] = p.Value
+	}
+
+	result := Result{Lags: make([]Lag, 0, 2*maxLag+1)}
+	zero := false
+	for steps := -maxLag; steps <= maxLag; steps++ {
+		shift := time.Duration(steps) * step
+		var xs, ys []float64
+		for _, p := range a {
+			if v, ok := bAt[p.Time.Add(shift).UnixNano()]; ok {
+				xs = append(xs, p.Value)
+				ys = append(ys, v)
+			}
+		}
+		r, ok := pearson(xs, ys)
+		if !ok {
+			continue
+		}
+		lag := Lag{Lag: shift.String(), Steps: steps, Coefficient: r, Samples: len(xs)}
+		result.Lags = append(result.Lags, lag)
+		if steps == 0 {
+			result.Coefficient, result.Samples, zero = r, len(xs), true
+		}
+		if len(result.Lags) == 1 || math.Abs(r) > math.Abs(result.BestLag.Coefficient) {
+			result.BestLag = lag
+		}
+	}
+	if !zero {
+		return Result{}, ErrUndefined
+	}
+	return result, nil
+}
+
+// pearson returns the Pearson correlation of xs and ys. It is false when
+// there are too few pairs or either side is constant.
+func pearson(xs, ys []float64) (float64, bool) {
+	n := len(xs)
+	if n < MinSamples {
+		return 0, false
+	}
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0, false
+	}
+	return sxy / math.Sqrt(sxx*syy), true
+}
@@ -0,0 +1,46 @@
+package correlate
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func TestSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	step := 10 * time.Second
+	signal := []float64{1, 5, 2, 8, 3, 9, 4, 7, 6, 2, 8, 1, 5, 3, 9}
+
+	// b mirrors a two buckets later, inverted, as clocks drop after heat
+	var a, b []models.SeriesPoint
+	for i, v := range signal {
+		at := start.Add(time.Duration(i) * step)
+		a = append(a, models.SeriesPoint{Time: at, Value: v})
+		b = append(b, models.SeriesPoint{Time: at.Add(2 * step), Value: 100 - v})
+	}
+
+	result, err := Series(a, b, step, 4)
+	if err != nil {
+		t.Fatalf("Series() = %v", err)
+	}
+	if result.BestLag.Steps != 2 || result.BestLag.Lag != "20s" || math.Abs(result.BestLag.Coefficient+1) > 1e-9 {
+		t.Errorf("best lag = %+v, want 2 steps at -1", result.BestLag)
+	}
+	if result.BestLag.Samples != len(signal) || result.Samples != len(signal)-2 {
+		t.Errorf("samples = %d at lag 0 and %d at best, want %d and %d", result.Samples, result.BestLag.Samples, len(signal)-2, len(signal))
+	}
+	if len(result.Lags) != 9 || result.Lags[0].Steps != -4 {
+		t.Errorf("lags = %+v, want -4 to 4", result.Lags)
+	}
+
+	// A constant series has no correlation
+	for i := range b {
+		b[i].Value = 50
+	}
+	if _, err := Series(a, b, step, 4); !errors.Is(err, ErrUndefined) {
+		t.Errorf("Series() with a constant series = %v, want ErrUndefined", err)
+	}
+}
@@ -0,0 +1,360 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/alert"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/baseline"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/envelope"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/forward"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/parser"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/signing"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+// StreamerChecks returns the checks for the telemetry streamer.
+func StreamerChecks(cfg config.StreamerConfig) []Check {
+	checks := []Check{ConfigCheck("config", cfg.Validate)}
+	if (cfg.Source == "file" || cfg.Source == "") && cfg.InputFormat != "none" {
+		checks = append(checks, Check{Name: "input schema", Run: func(ctx context.Context) error {
+			return checkInputSchema(cfg.CSVPath, cfg.InputFormat, cfg.CSVMappingsFile)
+		}})
+	}
+	if cfg.UDP.Enabled() {
+		checks = append(checks, UDPListenCheck("udp port available", cfg.UDP.Host, cfg.UDP.Port))
+	}
+	if cfg.Encryption.Enabled() {
+		checks = append(checks, EncryptionKeysCheck(cfg.Encryption))
+	}
+	if cfg.Signing.Enabled() {
+		checks = append(checks, Check{Name: "signing key", Run: func(ctx context.Context) error {
+			_, err := signing.NewSigner(cfg.InstanceID, cfg.Signing)
+			return err
+		}})
+	}
+	return append(checks, TCPCheck("mq server reachable", cfg.MQ.Host, cfg.MQ.Port))
+}
+
+// CollectorChecks returns the checks for the telemetry collector. The
+// source checks follow cfg.Source: the MQ offset, filter and server, or
+// each Kafka bootstrap broker. Each forward sink's filter and endpoint are
+// checked too.
+func CollectorChecks(cfg config.CollectorConfig) []Check {
+	influx := storage.InfluxDBConfig{
+		URL:    cfg.InfluxURL,
+		Token:  cfg.InfluxToken,
+		Org:    cfg.InfluxOrg,
+		Bucket: cfg.InfluxBucket,
+		Schema: storage.DefaultInfluxDBConfig().Schema,
+	}
+	checks := []Check{ConfigCheck("config", cfg.Validate), ConfigCheck("influxdb schema", influx.Schema.Validate)}
+	if cfg.Source == "kafka" {
+		checks = append(checks, InfluxCredentialsCheck(influx))
+		for _, addr := range cfg.Kafka.Brokers {
+			host, port, err := net.SplitHostPort(addr)
+			n, _ := strconv.Atoi(port)
+			if err != nil || n == 0 {
+				continue // reported by the config check
+			}
+			checks = append(checks, TCPCheck("kafka broker "+addr+" reachable", host, n))
+		}
+	} else {
+		checks = append(checks,
+			Check{Name: "start offset", Run: func(ctx context.Context) error {
+				_, err := mq.ParseOffset(cfg.StartOffset)
+				return err
+			}},
+			Check{Name: "subscribe filter", Run: func(ctx context.Context) error {
+				_, err := mq.ParseFilter(cfg.SubscribeFilter)
+				return err
+			}},
+			InfluxCredentialsCheck(influx),
+			TCPCheck("mq server reachable", cfg.MQ.Host, cfg.MQ.Port),
+		)
+		if cfg.Encryption.Enabled() {
+			checks = append(checks, EncryptionKeysCheck(cfg.Encryption))
+		}
+		if cfg.Verification.Enabled() {
+			checks = append(checks, Check{Name: "producer keys", Run: func(ctx context.Context) error {
+				_, err := signing.NewRegistry(cfg.Verification)
+				return err
+			}})
+		}
+	}
+	checks = append(checks, InfluxHealthCheck(influx), InfluxAuthCheck(influx))
+	if cfg.LateData.Policy == config.LatePolicyReroute && cfg.LateData.Bucket != "" {
+		late := influx
+		late.Bucket = cfg.LateData.Bucket
+		check := InfluxAuthCheck(late)
+		check.Name = "influxdb late bucket access"
+		checks = append(checks, check)
+	}
+	return append(checks, forwardChecks(cfg.Forward)...)
+}
+
+// forwardChecks checks each forward sink's filter and that its endpoint
+// accepts connections.
+func forwardChecks(sinks []config.ForwardSinkConfig) []Check {
+	var checks []Check
+	for _, sink := range sinks {
+		sink := sink
+		checks = append(checks, Check{Name: "forward " + sink.Name + " filter", Run: func(ctx context.Context) error {
+			_, err := mq.ParseFilter(sink.Filter)
+			return err
+		}})
+
+		raw := sink.URL
+		if raw == "" && sink.Type == forward.TypeDatadog {
+			raw = forward.DatadogURL
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			continue // reported by the config check
+		}
+		port, _ := strconv.Atoi(u.Port())
+		if port == 0 {
+			port = 80
+			if u.Scheme == "https" {
+				port = 443
+			}
+		}
+		checks = append(checks, TCPCheck("forward "+sink.Name+" reachable", u.Hostname(), port))
+	}
+	return checks
+}
+
+// APIChecks returns the checks for the API gateway.
+func APIChecks(cfg config.APIConfig, influx storage.InfluxDBConfig) []Check {
+	checks := []Check{
+		ConfigCheck("config", cfg.Validate),
+		ConfigCheck("influxdb schema", influx.Schema.Validate),
+		InfluxCredentialsCheck(influx),
+		ListenCheck("api port available", cfg.Host, cfg.Port),
+		InfluxHealthCheck(influx),
+		InfluxAuthCheck(influx),
+	}
+	if cfg.Alerts.Enabled {
+		checks = append(checks, Check{Name: "alert rules", Run: func(ctx context.Context) error {
+			return checkAlertRules(cfg)
+		}})
+	}
+	return checks
+}
+
+// checkAlertRules checks that the alert rules file loads, that notifier
+// templates parse, that rules only name configured notifiers, and that
+// threshold expressions are over baseline metrics.
+func checkAlertRules(cfg config.APIConfig) error {
+	notifiers, err := alert.NewNotifiers(cfg.Alerts.Notifiers, cfg.Scheduler)
+	if err != nil {
+		return err
+	}
+	if cfg.Alerts.RulesFile == "" {
+		return nil
+	}
+	rules, err := alert.LoadRulesFile(cfg.Alerts.RulesFile)
+	if err != nil {
+		return err
+	}
+	router := alert.NewRouter(cfg.Alerts, notifiers, nil)
+	evaluator := alert.NewEvaluator(rules, nil, nil, router, leader.Always{}, nil, cfg.Alerts.EvalInterval, nil)
+	if cfg.Baselines.Enabled {
+		evaluator.SetBaselines(baseline.NewProfiler(nil, cfg.Baselines, nil))
+	}
+	var errs []error
+	for i := range rules {
+		errs = append(errs, evaluator.CheckRule(&rules[i]))
+	}
+	return errors.Join(errs...)
+}
+
+// MQServerChecks returns the checks for the MQ server.
+func MQServerChecks(cfg config.MQServerConfig) []Check {
+	return []Check{
+		ConfigCheck("config", cfg.Validate),
+		ListenCheck("tcp port available", cfg.TCPHost, cfg.TCPPort),
+		ListenCheck("http port available", cfg.HTTPHost, cfg.HTTPPort),
+	}
+}
+
+// OTLPReceiverChecks returns the checks for the OTLP metrics receiver.
+func OTLPReceiverChecks(cfg config.OTLPReceiverConfig) []Check {
+	return []Check{
+		ConfigCheck("config", cfg.Validate),
+		ListenCheck("grpc port available", cfg.Host, cfg.GRPCPort),
+		ListenCheck("http port available", cfg.Host, cfg.HTTPPort),
+		TCPCheck("mq server reachable", cfg.MQ.Host, cfg.MQ.Port),
+	}
+}
+
+// ConfigCheck wraps a configuration validation function as a static check.
+func ConfigCheck(name string, validate func() error) Check {
+	return Check{Name: name, Run: func(ctx context.Context) error {
+		return validate()
+	}}
+}
+
+// EncryptionKeysCheck checks that the payload encryption keys load. Keys
+// fetched by a command, typically from a KMS, are a probe.
+func EncryptionKeysCheck(cfg config.EncryptionConfig) Check {
+	return Check{Name: "encryption keys", Probe: cfg.KeyCommand != "", Run: func(ctx context.Context) error {
+		_, err := envelope.New(ctx, cfg)
+		return err
+	}}
+}
+
+// TCPCheck probes that a TCP endpoint accepts connections.
+func TCPCheck(name, host string, port int) Check {
+	return Check{Name: name, Probe: true, Run: func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}}
+}
+
+// ListenCheck probes that a listener can be bound on host:port.
+func ListenCheck(name, host string, port int) Check {
+	return Check{Name: name, Probe: true, Run: func(ctx context.Context) error {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		return l.Close()
+	}}
+}
+
+// UDPListenCheck probes that a UDP socket can be bound on host:port.
+func UDPListenCheck(name, host string, port int) Check {
+	return Check{Name: name, Probe: true, Run: func(ctx context.Context) error {
+		conn, err := net.ListenPacket("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}}
+}
+
+// InfluxCredentialsCheck warns when no InfluxDB token is configured.
+func InfluxCredentialsCheck(cfg storage.InfluxDBConfig) Check {
+	return Check{Name: "influxdb credentials", Run: func(ctx context.Context) error {
+		if cfg.Token == "" {
+			return Warn(errors.New("INFLUXDB_TOKEN is not set; InfluxDB 2.x will reject requests"))
+		}
+		if cfg.Org == "" || cfg.Bucket == "" {
+			return errors.New("org and bucket must be set")
+		}
+		return nil
+	}}
+}
+
+// InfluxHealthCheck probes the InfluxDB /health endpoint.
+func InfluxHealthCheck(cfg storage.InfluxDBConfig) Check {
+	return Check{Name: "influxdb reachable", Probe: true, Run: func(ctx context.Context) error {
+		resp, err := influxRequest(ctx, cfg, "/health", false)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("health endpoint returned %s", resp.Status)
+		}
+		return nil
+	}}
+}
+
+// InfluxAuthCheck probes that the configured token can see the configured bucket.
+func InfluxAuthCheck(cfg storage.InfluxDBConfig) Check {
+	return Check{Name: "influxdb bucket access", Probe: true, Run: func(ctx context.Context) error {
+		path := "/api/v2/buckets?name=" + url.QueryEscape(cfg.Bucket) + "&org=" + url.QueryEscape(cfg.Org)
+		resp, err := influxRequest(ctx, cfg, path, true)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("credentials rejected (%s)", resp.Status)
+		default:
+			return fmt.Errorf("bucket lookup returned %s", resp.Status)
+		}
+
+		var body struct {
+			Buckets []struct {
+				Name string `json:"name"`
+			} `json:"buckets"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return fmt.Errorf("failed to decode bucket list: %w", err)
+		}
+		if len(body.Buckets) == 0 {
+			return fmt.Errorf("bucket %q not found in org %q", cfg.Bucket, cfg.Org)
+		}
+		return nil
+	}}
+}
+
+// influxRequest issues a GET against the InfluxDB HTTP API.
+func influxRequest(ctx context.Context, cfg storage.InfluxDBConfig, path string, auth bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(cfg.URL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth {
+		req.Header.Set("Authorization", "Token "+cfg.Token)
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// checkInputSchema validates the streamer's input file in its format, and
+// the CSV mappings file if one is set.
+func checkInputSchema(path, format, mappingsFile string) error {
+	var mappings []parser.Mapping
+	if mappingsFile != "" {
+		var err error
+		if mappings, err = parser.LoadMappingsFile(mappingsFile); err != nil {
+			return err
+		}
+	}
+	if format == parser.FormatAuto {
+		detected, err := parser.DetectFormat(path)
+		if err != nil {
+			return err
+		}
+		format = detected
+	}
+	if format == parser.FormatPrometheus {
+		return parser.ValidatePrometheus(path)
+	}
+	return checkCSVSchema(path, mappings)
+}
+
+// checkCSVSchema validates the required columns and warns about unmapped ones.
+func checkCSVSchema(path string, mappings []parser.Mapping) error {
+	if err := parser.ValidateCSV(path, mappings); err != nil {
+		return err
+	}
+	missing, err := parser.MissingColumns(path, mappings)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return Warn(fmt.Errorf("columns not present, fields will be empty: %s", strings.Join(missing, ", ")))
+	}
+	return nil
+}
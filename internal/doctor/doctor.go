@@ -0,0 +1,182 @@
+// Package doctor runs pre-flight checks for pipeline components and reports
+// the results as a pass/fail table.
+//
+// Checks come in two kinds: static checks that only inspect configuration and
+// local files, and probes that contact dependencies (MQ server, InfluxDB) or
+// bind ports. Binaries run the static checks on every start and the full set
+// when invoked as "<binary> doctor".
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds each individual check.
+const DefaultTimeout = 5 * time.Second
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+)
+
+// Check is a single named pre-flight check.
+type Check struct {
+	// Name identifies the check in the report (e.g., "influxdb reachable")
+	Name string
+
+	// Probe marks checks that contact external dependencies or bind ports
+	Probe bool
+
+	// Run performs the check. A nil error passes; errors wrapped with Warn
+	// are reported but do not fail the report.
+	Run func(ctx context.Context) error
+}
+
+// Result is the outcome of running a Check.
+type Result struct {
+	Name     string
+	Status   Status
+	Err      error
+	Duration time.Duration
+}
+
+// Report is the ordered set of results from a doctor run.
+type Report struct {
+	Results []Result
+}
+
+// warning marks a check error as non-fatal.
+type warning struct {
+	err error
+}
+
+func (w warning) Error() string { return w.err.Error() }
+func (w warning) Unwrap() error { return w.err }
+
+// Warn marks err as a warning: it is reported but does not fail the check run.
+func Warn(err error) error {
+	if err == nil {
+		return nil
+	}
+	return warning{err: err}
+}
+
+// Static returns the checks that do not probe external dependencies.
+func Static(checks []Check) []Check {
+	var out []Check
+	for _, c := range checks {
+		if !c.Probe {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Prefix returns the checks with prefix prepended to their names.
+func Prefix(prefix string, checks []Check) []Check {
+	out := make([]Check, len(checks))
+	for i, c := range checks {
+		c.Name = prefix + c.Name
+		out[i] = c
+	}
+	return out
+}
+
+// Run executes the checks in order, each bounded by DefaultTimeout.
+func Run(ctx context.Context, checks []Check) Report {
+	report := Report{Results: make([]Result, 0, len(checks))}
+	for _, c := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+		start := time.Now()
+		err := c.Run(checkCtx)
+		cancel()
+
+		result := Result{Name: c.Name, Status: StatusPass, Err: err, Duration: time.Since(start)}
+		var w warning
+		switch {
+		case err == nil:
+		case errors.As(err, &w):
+			result.Status = StatusWarn
+		default:
+			result.Status = StatusFail
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// Passed reports whether no check failed. Warnings do not fail the report.
+func (r Report) Passed() bool {
+	for _, res := range r.Results {
+		if res.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Lines formats each result as a single report line.
+func (r Report) Lines() []string {
+	lines := make([]string, 0, len(r.Results))
+	for _, res := range r.Results {
+		line := fmt.Sprintf("[%s] %s", res.Status, res.Name)
+		if res.Err != nil {
+			// Joined errors span multiple lines; keep one result per line
+			line += ": " + strings.ReplaceAll(res.Err.Error(), "\n", "; ")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Print writes the report and a summary line to w.
+func (r Report) Print(w io.Writer) {
+	counts := make(map[Status]int)
+	for _, line := range r.Lines() {
+		fmt.Fprintln(w, line)
+	}
+	for _, res := range r.Results {
+		counts[res.Status]++
+	}
+
+	verdict := "OK"
+	if !r.Passed() {
+		verdict = "FAILED"
+	}
+	fmt.Fprintf(w, "\n%s: %d passed, %d warnings, %d failed\n",
+		verdict, counts[StatusPass], counts[StatusWarn], counts[StatusFail])
+}
+
+// Main runs every check, prints the report to w and returns a process exit code.
+// Binaries call it when invoked as "<binary> doctor".
+func Main(w io.Writer, checks []Check) int {
+	report := Run(context.Background(), checks)
+	report.Print(w)
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
+
+// Preflight runs the static checks, logs each result and returns an error if any failed.
+// Binaries call it on startup before connecting to dependencies.
+func Preflight(logger *log.Logger, checks []Check) error {
+	report := Run(context.Background(), Static(checks))
+	for _, line := range report.Lines() {
+		logger.Printf("  %s", line)
+	}
+	if !report.Passed() {
+		return errors.New("configuration checks failed (run with 'doctor' for the full report)")
+	}
+	return nil
+}
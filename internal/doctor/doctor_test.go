@@ -0,0 +1,153 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+func TestRunStatuses(t *testing.T) {
+	checks := []Check{
+		{Name: "ok", Run: func(ctx context.Context) error { return nil }},
+		{Name: "soft", Run: func(ctx context.Context) error { return Warn(errors.New("careful")) }},
+		{Name: "hard", Run: func(ctx context.Context) error { return errors.New("broken") }},
+	}
+
+	report := Run(context.Background(), checks)
+	want := []Status{StatusPass, StatusWarn, StatusFail}
+	for i, res := range report.Results {
+		if res.Status != want[i] {
+			t.Errorf("check %s: expected %s, got %s", res.Name, want[i], res.Status)
+		}
+	}
+	if report.Passed() {
+		t.Error("expected report with a failure to not pass")
+	}
+
+	report = Run(context.Background(), checks[:2])
+	if !report.Passed() {
+		t.Error("expected warnings not to fail the report")
+	}
+
+	var buf bytes.Buffer
+	report.Print(&buf)
+	if !strings.Contains(buf.String(), "[WARN] soft: careful") || !strings.Contains(buf.String(), "OK: 1 passed, 1 warnings, 0 failed") {
+		t.Errorf("unexpected report output:\n%s", buf.String())
+	}
+}
+
+func TestStaticAndPrefix(t *testing.T) {
+	checks := []Check{
+		{Name: "config"},
+		{Name: "probe", Probe: true},
+	}
+
+	static := Prefix("collector: ", Static(checks))
+	if len(static) != 1 || static[0].Name != "collector: config" {
+		t.Errorf("unexpected static checks: %+v", static)
+	}
+	if checks[0].Name != "config" {
+		t.Error("Prefix must not modify the input slice")
+	}
+}
+
+func TestCollectorChecksFollowSource(t *testing.T) {
+	cfg := config.DefaultCollectorConfig()
+	cfg.Source = "kafka"
+	cfg.Kafka.Brokers = []string{"kafka-0:9092", "kafka-1:9092"}
+
+	var names []string
+	for _, c := range CollectorChecks(cfg) {
+		names = append(names, c.Name)
+	}
+	joined := strings.Join(names, ",")
+	if !strings.Contains(joined, "kafka broker kafka-0:9092 reachable") || !strings.Contains(joined, "kafka broker kafka-1:9092 reachable") {
+		t.Errorf("expected a reachability check per broker, got %v", names)
+	}
+	if strings.Contains(joined, "mq server") || strings.Contains(joined, "start offset") {
+		t.Errorf("expected no MQ checks for the Kafka source, got %v", names)
+	}
+}
+
+func TestCollectorChecksForwardSinks(t *testing.T) {
+	cfg := config.DefaultCollectorConfig()
+	cfg.Forward = []config.ForwardSinkConfig{
+		{Name: "prom", Type: "prometheus", URL: "http://prometheus:9090/api/v1/write", Filter: "uuid"},
+		{Name: "dd", Type: "datadog"},
+	}
+
+	checks := make(map[string]Check)
+	for _, c := range CollectorChecks(cfg) {
+		checks[c.Name] = c
+	}
+	for _, name := range []string{"forward prom reachable", "forward dd reachable", "forward dd filter"} {
+		if _, ok := checks[name]; !ok {
+			t.Errorf("expected check %q", name)
+		}
+	}
+	if err := checks["forward prom filter"].Run(context.Background()); err == nil {
+		t.Error("expected the invalid prom filter to fail its check")
+	}
+}
+
+func TestAPIChecksAlertRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`[{"name": "hot", "metric": "DCGM_FI_DEV_GPU_TEMP", "op": ">", "threshold": 85, "severity": "critical", "notifiers": ["pager"]}]`), 0o644)
+
+	cfg := config.DefaultAPIConfig()
+	cfg.Alerts.Enabled = true
+	cfg.Alerts.RulesFile = path
+	cfg.Alerts.Notifiers = []config.AlertNotifierConfig{{Name: "chat", Type: "slack", URL: "https://hooks.slack.com/services/x"}}
+
+	var check *Check
+	for _, c := range APIChecks(cfg, storage.InfluxDBConfig{}) {
+		if c.Name == "alert rules" {
+			check = &c
+		}
+	}
+	if check == nil {
+		t.Fatal("expected an alert rules check")
+	}
+	if err := check.Run(context.Background()); err == nil || !strings.Contains(err.Error(), `unknown notifier "pager"`) {
+		t.Errorf("expected the unknown notifier to fail the check, got %v", err)
+	}
+}
+
+func TestInfluxAuthCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("name") == "gpu_telemetry" {
+			w.Write([]byte(`{"buckets":[{"name":"gpu_telemetry"}]}`))
+			return
+		}
+		w.Write([]byte(`{"buckets":[]}`))
+	}))
+	defer srv.Close()
+
+	cfg := storage.InfluxDBConfig{URL: srv.URL, Token: "good", Org: "cisco", Bucket: "gpu_telemetry"}
+	if err := InfluxAuthCheck(cfg).Run(context.Background()); err != nil {
+		t.Errorf("expected bucket access, got %v", err)
+	}
+
+	cfg.Bucket = "other"
+	if err := InfluxAuthCheck(cfg).Run(context.Background()); err == nil {
+		t.Error("expected missing bucket to fail")
+	}
+
+	cfg.Token = "bad"
+	if err := InfluxAuthCheck(cfg).Run(context.Background()); err == nil {
+		t.Error("expected rejected credentials to fail")
+	}
+}
@@ -0,0 +1,339 @@
+// Package dryrun verifies a streamer input file without publishing. It
+// parses every record, maps it into the batches the streamer would publish,
+// validates them against the metric-batch schema, and reports per-column
+// error statistics with estimated batch sizes and publish rates. Operators
+// run it as "streamer dry-run" before replaying a new data file in
+// production.
+package dryrun
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/parser"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/schemas"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// recordColumn collects problems that are not tied to one field, such as a
+// malformed CSV row.
+const recordColumn = "record"
+
+// ColumnStats counts the problems found in one column.
+type ColumnStats struct {
+	Column string
+
+	// Errors is how many records had a problem in the column
+	Errors int
+
+	// Rejected is how many of those records the streamer would skip; the
+	// rest are published with the field read as 0
+	Rejected int
+
+	// Example is the first problem found, with its line
+	Example string
+}
+
+// Report is the outcome of a dry run.
+type Report struct {
+	File   string
+	Format string
+
+	// Records is how many rows or samples were read
+	Records int
+
+	// Valid is how many records would be published
+	Valid int
+
+	// Rejected is how many records the streamer would skip
+	Rejected int
+
+	// Tolerated is how many valid records had a field read as 0
+	Tolerated int
+
+	// Fingerprint identifies the CSV header, and Mapping names the mapping
+	// it is read through ("" for the expected column names)
+	Fingerprint, Mapping string
+
+	// MissingColumns lists optional CSV columns absent from the header
+	MissingColumns []string
+
+	// Columns holds the problems found, by column
+	Columns []ColumnStats
+
+	// Hosts, GPUs and MetricNames count the distinct values in valid records
+	Hosts, GPUs, MetricNames int
+
+	// Batches is how many batches one pass over the file publishes
+	Batches int
+
+	// SchemaErrors is how many batches fail the metric-batch schema
+	SchemaErrors  int
+	SchemaExample string
+
+	// AvgBatchMetrics, AvgBatchBytes and MaxBatchBytes describe the batches
+	AvgBatchMetrics float64
+	AvgBatchBytes   int64
+	MaxBatchBytes   int64
+
+	// MetricsPerSecond, BatchesPerSecond and BytesPerSecond estimate the
+	// publish rate at the configured collect and stream intervals
+	MetricsPerSecond float64
+	BatchesPerSecond float64
+	BytesPerSecond   float64
+
+	// PassDuration estimates how long streaming the file once takes
+	PassDuration time.Duration
+}
+
+// Passed reports whether the file can be replayed as is: every record is
+// published and every batch matches the schema.
+func (r *Report) Passed() bool {
+	return r.Valid > 0 && r.Rejected == 0 && r.SchemaErrors == 0
+}
+
+// batcher groups records into the batches the streamer would publish.
+type batcher struct {
+	cfg    config.StreamerConfig
+	size   int // records read per batch
+	report *Report
+
+	batch     *models.MetricBatch
+	read      int // records read into the current batch
+	bytes     int64
+	published int // metrics in all batches
+}
+
+// Run reads the streamer's input file and reports what replaying it would
+// publish. It returns an error only when the file cannot be read.
+func Run(cfg config.StreamerConfig) (*Report, error) {
+	if cfg.Source != "file" && cfg.Source != "" {
+		return nil, fmt.Errorf("dry runs read the input file, and source %s reads none", cfg.Source)
+	}
+	format := cfg.InputFormat
+	if format == parser.FormatAuto || format == "" {
+		detected, err := parser.DetectFormat(cfg.CSVPath)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+	var mappings []parser.Mapping
+	if cfg.CSVMappingsFile != "" {
+		var err error
+		if mappings, err = parser.LoadMappingsFile(cfg.CSVMappingsFile); err != nil {
+			return nil, err
+		}
+	}
+	reader, err := parser.OpenColumns(cfg.CSVPath, format, mappings, cfg.CSVColumns)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	report := &Report{File: cfg.CSVPath, Format: format}
+	if csvParser, ok := reader.(*parser.CSVParser); ok {
+		report.Fingerprint, report.Mapping = csvParser.Fingerprint(), csvParser.Mapping()
+		if report.MissingColumns, err = parser.MissingColumns(cfg.CSVPath, mappings); err != nil {
+			return nil, err
+		}
+	}
+
+	// The streamer reads one record per collect interval and publishes what
+	// it read every stream interval
+	b := &batcher{cfg: cfg, size: max(1, int(cfg.StreamInterval/cfg.CollectInterval)), report: report}
+	columns := make(map[string]*ColumnStats)
+	hosts, gpus, names := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+	count := func(field, problem string, rejected bool) {
+		c := columns[field]
+		if c == nil {
+			c = &ColumnStats{Column: field, Example: problem}
+			columns[field] = c
+		}
+		c.Errors++
+		if rejected {
+			c.Rejected++
+		}
+	}
+
+	for {
+		metric, err := reader.ReadNext()
+		if err == nil && metric == nil {
+			break
+		}
+		report.Records++
+
+		if err != nil {
+			var fieldErr *parser.FieldError
+			var parseErr *csv.ParseError
+			switch {
+			case errors.As(err, &fieldErr):
+				count(fieldErr.Field, fmt.Sprintf("line %d: %v", reader.Line(), err), true)
+			case errors.As(err, &parseErr):
+				count(recordColumn, err.Error(), true)
+			default:
+				return nil, err
+			}
+			report.Rejected++
+			b.skip()
+			continue
+		}
+
+		if ir, ok := reader.(parser.IssueReporter); ok && len(ir.Issues()) > 0 {
+			report.Tolerated++
+			for _, issue := range ir.Issues() {
+				count(issue.Field, fmt.Sprintf("line %d: %v", reader.Line(), issue), false)
+			}
+		}
+		report.Valid++
+		hosts[metric.Hostname] = true
+		gpus[metric.UUID] = true
+		names[metric.MetricName] = true
+		b.add(metric, reader.Line())
+	}
+	b.flush()
+
+	for _, c := range columns {
+		report.Columns = append(report.Columns, *c)
+	}
+	sort.Slice(report.Columns, func(i, j int) bool { return report.Columns[i].Column < report.Columns[j].Column })
+	report.Hosts, report.GPUs, report.MetricNames = len(hosts), len(gpus), len(names)
+
+	if report.Batches > 0 {
+		report.AvgBatchMetrics = float64(b.published) / float64(report.Batches)
+		report.AvgBatchBytes = b.bytes / int64(report.Batches)
+	}
+	report.PassDuration = time.Duration(report.Records) * cfg.CollectInterval
+	if report.PassDuration > 0 {
+		seconds := report.PassDuration.Seconds()
+		report.MetricsPerSecond = float64(report.Valid) / seconds
+		report.BatchesPerSecond = float64(report.Batches) / seconds
+		report.BytesPerSecond = float64(b.bytes) / seconds
+	}
+	return report, nil
+}
+
+// add adds a valid record to the current batch.
+func (b *batcher) add(metric *models.GPUMetric, line int) {
+	if b.batch == nil {
+		b.batch = &models.MetricBatch{SourceFile: b.cfg.CSVPath, SourceLines: &models.LineRange{First: line}}
+	}
+	b.batch.Metrics = append(b.batch.Metrics, *metric)
+	b.batch.SourceLines.Last = line
+	b.next()
+}
+
+// skip accounts for a rejected record, which still takes a collect tick.
+func (b *batcher) skip() {
+	b.next()
+}
+
+func (b *batcher) next() {
+	b.read++
+	if b.read == b.size {
+		b.flush()
+	}
+}
+
+// flush stamps the current batch as the streamer does, then measures and
+// validates it.
+func (b *batcher) flush() {
+	b.read = 0
+	batch := b.batch
+	b.batch = nil
+	if batch == nil {
+		return
+	}
+	batch.BatchID = uuid.New().String()
+	batch.Source = b.cfg.InstanceID
+	batch.CollectedAt = time.Now()
+
+	r := b.report
+	r.Batches++
+	b.published += len(batch.Metrics)
+	payload, err := json.Marshal(batch)
+	if err == nil {
+		doc, _ := schemas.Get("metric-batch")
+		err = doc.Schema.Validate(payload)
+	}
+	if err != nil {
+		r.SchemaErrors++
+		if r.SchemaExample == "" {
+			r.SchemaExample = fmt.Sprintf("lines %d-%d: %v", batch.SourceLines.First, batch.SourceLines.Last, err)
+		}
+		return
+	}
+	size := int64(len(payload))
+	b.bytes += size
+	r.MaxBatchBytes = max(r.MaxBatchBytes, size)
+}
+
+// Print writes the report as text.
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "Dry run of %s (%s)\n", r.File, r.Format)
+	fmt.Fprintf(w, "  Records:  %d read, %d valid, %d rejected, %d with fields read as 0\n",
+		r.Records, r.Valid, r.Rejected, r.Tolerated)
+	if r.Mapping != "" {
+		fmt.Fprintf(w, "  Header:   fingerprint %s, read through mapping %q\n", r.Fingerprint, r.Mapping)
+	} else if r.Fingerprint != "" {
+		fmt.Fprintf(w, "  Header:   fingerprint %s, no mapping\n", r.Fingerprint)
+	}
+	if len(r.MissingColumns) > 0 {
+		fmt.Fprintf(w, "  Missing optional columns: %s\n", strings.Join(r.MissingColumns, ", "))
+	}
+	if len(r.Columns) > 0 {
+		fmt.Fprintln(w, "  Column problems:")
+		for _, c := range r.Columns {
+			fmt.Fprintf(w, "    %-12s %d (%d rejected), first at %s\n", c.Column, c.Errors, c.Rejected, c.Example)
+		}
+	}
+	fmt.Fprintf(w, "  Coverage: %d hosts, %d GPUs, %d metric names\n", r.Hosts, r.GPUs, r.MetricNames)
+	fmt.Fprintf(w, "  Batches:  %d, averaging %.1f metrics and %s (max %s)\n",
+		r.Batches, r.AvgBatchMetrics, formatBytes(float64(r.AvgBatchBytes)), formatBytes(float64(r.MaxBatchBytes)))
+	if r.SchemaErrors > 0 {
+		fmt.Fprintf(w, "  Schema:   %d batches fail the metric-batch schema, first at %s\n", r.SchemaErrors, r.SchemaExample)
+	}
+	fmt.Fprintf(w, "  Rate:     %.1f metrics/s, %.2f batches/s, %s/s; one pass takes %v\n",
+		r.MetricsPerSecond, r.BatchesPerSecond, formatBytes(r.BytesPerSecond), r.PassDuration.Round(time.Second))
+
+	verdict := "OK"
+	if !r.Passed() {
+		verdict = "FAILED"
+	}
+	fmt.Fprintf(w, "\n%s: nothing was published\n", verdict)
+}
+
+// formatBytes formats a byte count with a binary unit.
+func formatBytes(n float64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", n/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", n/(1<<10))
+	}
+	return fmt.Sprintf("%.0f B", n)
+}
+
+// Main runs a dry run, prints the report to w and returns a process exit
+// code. The streamer calls it when invoked as "streamer dry-run".
+func Main(w io.Writer, cfg config.StreamerConfig) int {
+	report, err := Run(cfg)
+	if err != nil {
+		fmt.Fprintf(w, "Dry run of %s failed: %v\n", cfg.CSVPath, err)
+		return 1
+	}
+	report.Print(w)
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package dryrun
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/parser"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+const header = "timestamp,metric_name,gpu_id,device,uuid,modelName,Hostname,value\n"
+
+func writeInput(t *testing.T, name, content string) config.StreamerConfig {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return config.StreamerConfig{
+		InstanceID:      "streamer-test",
+		CSVPath:         path,
+		InputFormat:     "auto",
+		CollectInterval: 100 * time.Millisecond,
+		StreamInterval:  time.Second,
+	}
+}
+
+func TestDryRunCSV(t *testing.T) {
+	var rows strings.Builder
+	rows.WriteString(header)
+	for i := 0; i < 25; i++ {
+		rows.WriteString("2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,GPU-1,H100,host-1,42\n")
+	}
+	rows.WriteString("2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,x,nvidia0,GPU-2,H100,host-2,n/a\n")
+	rows.WriteString("2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,,H100,host-2,1\n")
+	rows.WriteString("2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,GPU-2,H100,host-2,Inf\n")
+
+	report, err := Run(writeInput(t, "replay.csv", rows.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Format != "csv" || report.Records != 28 || report.Valid != 26 || report.Rejected != 2 || report.Tolerated != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if report.Passed() {
+		t.Error("a file with rejected records should not pass")
+	}
+
+	want := map[string][2]int{"gpu_id": {1, 0}, "uuid": {1, 1}, "value": {2, 1}}
+	if len(report.Columns) != len(want) {
+		t.Fatalf("unexpected columns: %+v", report.Columns)
+	}
+	for _, c := range report.Columns {
+		if w := want[c.Column]; c.Errors != w[0] || c.Rejected != w[1] {
+			t.Errorf("column %s: %d errors, %d rejected; want %v", c.Column, c.Errors, c.Rejected, w)
+		}
+	}
+	if c := report.Columns[2]; !strings.HasPrefix(c.Example, "line 27: value") {
+		t.Errorf("expected the first value problem with its line, got %q", c.Example)
+	}
+	if !strings.Contains(strings.Join(report.MissingColumns, ","), "namespace") {
+		t.Errorf("expected missing optional columns, got %v", report.MissingColumns)
+	}
+
+	// Ten records per batch: 28 records read in 3 batches, rejected ones included
+	if report.Batches != 3 || report.SchemaErrors != 0 {
+		t.Errorf("expected 3 valid batches, got %d (%d schema errors)", report.Batches, report.SchemaErrors)
+	}
+	if report.MaxBatchBytes < report.AvgBatchBytes || report.AvgBatchBytes == 0 {
+		t.Errorf("unexpected batch sizes: avg %d, max %d", report.AvgBatchBytes, report.MaxBatchBytes)
+	}
+	if report.PassDuration != 2800*time.Millisecond {
+		t.Errorf("expected one pass to take 2.8s, got %v", report.PassDuration)
+	}
+	if report.Hosts != 2 || report.GPUs != 2 || report.MetricNames != 1 {
+		t.Errorf("unexpected coverage: %d hosts, %d GPUs, %d names", report.Hosts, report.GPUs, report.MetricNames)
+	}
+
+	var out bytes.Buffer
+	report.Print(&out)
+	for _, s := range []string{"28 read, 26 valid, 2 rejected", "uuid", "FAILED"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("report missing %q:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestDryRunPrometheus(t *testing.T) {
+	cfg := writeInput(t, "scrape.prom", `# TYPE DCGM_FI_DEV_GPU_TEMP gauge
+DCGM_FI_DEV_GPU_TEMP{gpu="0",UUID="GPU-1",Hostname="host-1"} 51
+DCGM_FI_DEV_GPU_TEMP{gpu="1",UUID="GPU-2",Hostname="host-1"} 49
+`)
+	var out bytes.Buffer
+	if code := Main(&out, cfg); code != 0 {
+		t.Fatalf("expected a clean file to pass, got exit %d:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "(prometheus)") || !strings.Contains(out.String(), "OK") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+
+	cfg.CSVPath = filepath.Join(t.TempDir(), "missing.csv")
+	if code := Main(&out, cfg); code != 1 {
+		t.Errorf("expected exit 1 for an unreadable file, got %d", code)
+	}
+}
+
+func TestDryRunCSVMapping(t *testing.T) {
+	cfg := writeInput(t, "legacy.csv", "name,gpu_uuid,host,val\nDCGM_FI_DEV_GPU_TEMP,GPU-1,host-1,71\n")
+	fingerprint := parser.Fingerprint([]string{"name", "gpu_uuid", "host", "val"})
+	cfg.CSVMappingsFile = filepath.Join(t.TempDir(), "mappings.json")
+	mappings := `[{"name": "legacy", "fingerprints": ["` + fingerprint + `"],
+		"columns": {"metric_name": "name", "uuid": "gpu_uuid", "hostname": "host", "value": "val"}}]`
+	if err := os.WriteFile(cfg.CSVMappingsFile, []byte(mappings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Mapping != "legacy" || report.Fingerprint != fingerprint || report.Valid != 1 {
+		t.Errorf("expected the file read through the legacy mapping, got %+v", report)
+	}
+}
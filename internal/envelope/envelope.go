@@ -0,0 +1,189 @@
+// Package envelope encrypts MQ batch payloads end to end with AES-GCM, so
+// telemetry crossing a shared MQ or broker stays confidential whatever
+// the transport. The streamer seals each payload with the first key of a
+// keyring and names the key in the message metadata; consumers open it
+// with whichever of their keys it names. Metadata stays in the clear, since
+// the MQ routes and filters on it.
+package envelope
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+// Message metadata naming how a payload was encrypted.
+const (
+	// MetaAlgorithm is the cipher, AlgorithmAESGCM
+	MetaAlgorithm = "encryption"
+
+	// MetaKeyID is the ID of the key that sealed the payload
+	MetaKeyID = "encryption_key_id"
+)
+
+// AlgorithmAESGCM is AES in Galois/Counter mode, with a random 12 byte
+// nonce prefixed to the ciphertext and the key ID as additional data.
+const AlgorithmAESGCM = "aes-gcm"
+
+// keyCommandTimeout bounds how long a KeyCommand may take.
+const keyCommandTimeout = 30 * time.Second
+
+// ErrUnencrypted is returned by Open for a plaintext payload when
+// encryption is required.
+var ErrUnencrypted = errors.New("payload is not encrypted and encryption is required")
+
+// Keyring holds the keys payloads are sealed and opened with. A nil
+// Keyring seals nothing and opens only plaintext payloads.
+type Keyring struct {
+	primary  string
+	aeads    map[string]cipher.AEAD
+	required bool
+}
+
+// New returns the keyring cfg describes, or nil when no keys are
+// configured.
+func New(ctx context.Context, cfg config.EncryptionConfig) (*Keyring, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	lines := cfg.Keys
+	switch {
+	case cfg.KeysFile != "":
+		data, err := os.ReadFile(cfg.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption keys: %w", err)
+		}
+		lines = keyLines(data)
+	case cfg.KeyCommand != "":
+		ctx, cancel := context.WithTimeout(ctx, keyCommandTimeout)
+		defer cancel()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", cfg.KeyCommand)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("encryption key command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		lines = keyLines(out)
+	}
+	k, err := NewKeyring(lines)
+	if err != nil {
+		return nil, err
+	}
+	k.required = cfg.Required
+	return k, nil
+}
+
+// keyLines splits a keys file into its keys, skipping blank lines and
+// # comments.
+func keyLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// NewKeyring parses keys written ID:KEY, as config.ParseEncryptionKey
+// does. The first seals payloads.
+func NewKeyring(keys []string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys")
+	}
+	k := &Keyring{aeads: make(map[string]cipher.AEAD, len(keys))}
+	for _, s := range keys {
+		id, secret, err := config.ParseEncryptionKey(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := k.aeads[id]; ok {
+			return nil, fmt.Errorf("key %s is listed more than once", id)
+		}
+		block, err := aes.NewCipher(secret)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		k.aeads[id] = aead
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+	return k, nil
+}
+
+// Primary returns the ID of the key that seals payloads, or "" for a nil
+// keyring.
+func (k *Keyring) Primary() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// Seal encrypts payload with the primary key and records how in metadata.
+// A nil keyring returns payload as it is.
+func (k *Keyring) Seal(payload []byte, metadata map[string]string) ([]byte, error) {
+	if k == nil {
+		return payload, nil
+	}
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate a nonce: %w", err)
+	}
+	metadata[MetaAlgorithm] = AlgorithmAESGCM
+	metadata[MetaKeyID] = k.primary
+	return aead.Seal(nonce, nonce, payload, []byte(k.primary)), nil
+}
+
+// Open decrypts a payload sealed by Seal, as its metadata describes.
+// Plaintext payloads are returned as they are unless encryption is
+// required.
+func (k *Keyring) Open(payload []byte, metadata map[string]string) ([]byte, error) {
+	alg, sealed := metadata[MetaAlgorithm]
+	if !sealed {
+		if k != nil && k.required {
+			return nil, ErrUnencrypted
+		}
+		return payload, nil
+	}
+	if alg != AlgorithmAESGCM {
+		return nil, fmt.Errorf("payload is encrypted with unknown algorithm %q", alg)
+	}
+	id := metadata[MetaKeyID]
+	if k == nil {
+		return nil, fmt.Errorf("payload is encrypted with key %s but no encryption keys are configured", id)
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("payload is encrypted with unknown key %s", id)
+	}
+	if len(payload) < aead.NonceSize() {
+		return nil, errors.New("encrypted payload is truncated")
+	}
+	nonce, ciphertext := payload[:aead.NonceSize()], payload[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload with key %s: %w", id, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,90 @@
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+func testKey(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestSealAndOpen(t *testing.T) {
+	producer, err := NewKeyring([]string{testKey("k2", 'b'), testKey("k1", 'a')})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]string{"hostname": "host-1"}
+	sealed, err := producer.Seal([]byte(`{"batch_id":"b1"}`), metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sealed), "b1") || metadata[MetaKeyID] != "k2" || metadata[MetaAlgorithm] != AlgorithmAESGCM {
+		t.Fatalf("expected the payload sealed with k2, got %q %v", sealed, metadata)
+	}
+
+	// A consumer that already has the next key opens payloads sealed with either
+	consumer, _ := NewKeyring([]string{testKey("k1", 'a'), testKey("k2", 'b')})
+	plain, err := consumer.Open(sealed, metadata)
+	if err != nil || string(plain) != `{"batch_id":"b1"}` {
+		t.Fatalf("expected the payload back, got %q, %v", plain, err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := consumer.Open(sealed, metadata); err == nil {
+		t.Error("expected a tampered payload to fail")
+	}
+	old, _ := NewKeyring([]string{testKey("k1", 'a')})
+	if _, err := old.Open(sealed, metadata); err == nil || !strings.Contains(err.Error(), "unknown key k2") {
+		t.Errorf("expected an unknown key error, got %v", err)
+	}
+	var none *Keyring
+	if _, err := none.Open(sealed, metadata); err == nil {
+		t.Error("expected an encrypted payload to fail without keys")
+	}
+}
+
+func TestOpenPlaintext(t *testing.T) {
+	var none *Keyring
+	if plain, err := none.Open([]byte("{}"), nil); err != nil || string(plain) != "{}" {
+		t.Fatalf("expected plaintext through, got %q, %v", plain, err)
+	}
+
+	k, err := New(context.Background(), config.EncryptionConfig{Keys: []string{testKey("k1", 'a')}, Required: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Open([]byte("{}"), map[string]string{}); !errors.Is(err, ErrUnencrypted) {
+		t.Errorf("expected ErrUnencrypted, got %v", err)
+	}
+}
+
+func TestNewFromFileAndCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte("# rotated monthly\n"+testKey("k1", 'a')+"\n\n"+testKey("k0", 'z')+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := New(context.Background(), config.EncryptionConfig{KeysFile: path})
+	if err != nil || k.Primary() != "k1" || len(k.aeads) != 2 {
+		t.Fatalf("expected two keys from the file, got %v, %v", k, err)
+	}
+
+	k, err = New(context.Background(), config.EncryptionConfig{KeyCommand: "cat " + path})
+	if err != nil || k.Primary() != "k1" {
+		t.Fatalf("expected keys from the command, got %v, %v", k, err)
+	}
+	if _, err := New(context.Background(), config.EncryptionConfig{KeyCommand: "echo denied >&2; exit 1"}); err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("expected the command's error, got %v", err)
+	}
+
+	if k, err := New(context.Background(), config.EncryptionConfig{}); k != nil || err != nil {
+		t.Errorf("expected no keyring without keys, got %v, %v", k, err)
+	}
+}
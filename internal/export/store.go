@@ -0,0 +1,479 @@
+// Package export runs telemetry exports too large for a single request as
+// background jobs. Each job writes an artifact file that clients download,
+// resuming interrupted transfers with HTTP Range requests, until it expires.
+// The oldest artifacts are deleted early to keep the export directory within
+// its disk quota.
+//
+// Jobs and artifacts live on the local disk of the API replica that created
+// them, so deployments with several replicas need session affinity for the
+// export endpoints.
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var (
+	// ErrNotReady is returned when downloading an artifact that has not been written.
+	ErrNotReady = errors.New("export is not completed")
+
+	// ErrExpired is returned when downloading an artifact that has been deleted.
+	ErrExpired = errors.New("export artifact has expired")
+
+	// ErrNotFound is returned for unknown job IDs.
+	ErrNotFound = perrors.New(perrors.KindNotFound, "export job not found")
+)
+
+// pageSize is how many data points each storage query reads.
+const pageSize = 10000
+
+// metaSuffix names a job's record, kept in a state store over the export
+// directory next to its artifact.
+const metaSuffix = ".meta.json"
+
+// Store queues export jobs, runs them one at a time and keeps their
+// artifacts until they expire.
+type Store struct {
+	dir    string
+	state  *statestore.File
+	reader storage.ReadStorage
+	cfg    config.ExportConfig
+	logger *log.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*models.ExportJob
+	cancels map[string]context.CancelFunc // running jobs
+	queue   chan string
+}
+
+// Open creates the export directory if needed and loads its jobs. Jobs an
+// earlier process left queued or running are marked failed.
+func Open(reader storage.ReadStorage, cfg config.ExportConfig, logger *log.Logger) (*Store, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	state, err := statestore.OpenFile(cfg.Dir, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	s := &Store{
+		dir:     cfg.Dir,
+		state:   state,
+		reader:  reader,
+		cfg:     cfg,
+		logger:  logger,
+		now:     time.Now,
+		jobs:    make(map[string]*models.ExportJob),
+		cancels: make(map[string]context.CancelFunc),
+		queue:   make(chan string, max(cfg.QueueSize, 1)),
+	}
+
+	partials, _ := filepath.Glob(filepath.Join(s.dir, "*.part"))
+	for _, p := range partials {
+		os.Remove(p)
+	}
+	keys, err := state.List(context.Background(), "")
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if !strings.HasSuffix(key, metaSuffix) {
+			continue
+		}
+		var job models.ExportJob
+		if err := statestore.GetJSON(context.Background(), state, key, &job); err != nil || job.ID+metaSuffix != key {
+			logger.Printf("Skipping unreadable export job record %s", key)
+			continue
+		}
+		if job.Status == models.ExportQueued || job.Status == models.ExportRunning {
+			s.finish(&job, 0, false, errors.New("interrupted by an API restart"))
+		}
+		s.jobs[job.ID] = &job
+	}
+	return s, nil
+}
+
+// Create validates spec and queues a job for it.
+func (s *Store) Create(spec models.ExportSpec) (models.ExportJob, error) {
+	if err := spec.Validate(); err != nil {
+		return models.ExportJob{}, perrors.Validation(err)
+	}
+	job := &models.ExportJob{
+		ID:        uuid.New().String(),
+		Spec:      spec,
+		Status:    models.ExportQueued,
+		CreatedAt: s.now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case s.queue <- job.ID:
+	default:
+		return models.ExportJob{}, perrors.New(perrors.KindTransient, "export queue is full")
+	}
+	s.jobs[job.ID] = job
+	s.save(job)
+	return *job, nil
+}
+
+// Get returns a job.
+func (s *Store) Get(id string) (models.ExportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return models.ExportJob{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// List returns every job, newest first.
+func (s *Store) List() []models.ExportJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]models.ExportJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Delete cancels a job if it is running and deletes it with its artifact.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	s.remove(job)
+	return nil
+}
+
+// Artifact opens a completed job's artifact for reading. The file stays
+// readable even if the artifact expires while it is open.
+func (s *Store) Artifact(id string) (*os.File, models.ExportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, models.ExportJob{}, ErrNotFound
+	}
+	switch job.Status {
+	case models.ExportCompleted:
+		f, err := os.Open(s.artifactPath(job))
+		if err != nil {
+			return nil, models.ExportJob{}, err
+		}
+		return f, *job, nil
+	case models.ExportExpired:
+		return nil, *job, ErrExpired
+	default:
+		return nil, *job, fmt.Errorf("%w: job is %s", ErrNotReady, job.Status)
+	}
+}
+
+// Usage returns the bytes held by artifacts and the quota.
+func (s *Store) Usage() (used, quota int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.used(), s.cfg.QuotaBytes
+}
+
+// Run runs queued jobs one at a time and deletes expired artifacts every
+// janitor interval until ctx is done.
+func (s *Store) Run(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.JanitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Expire()
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			s.run(ctx, id)
+		}
+	}
+}
+
+// run writes one job's artifact.
+func (s *Store) run(ctx context.Context, id string) {
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	if !ok {
+		// Deleted while queued
+		s.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.cancels[id] = cancel
+	started := s.now()
+	job.Status = models.ExportRunning
+	job.StartedAt = &started
+	s.save(job)
+	spec := job.Spec
+	s.mu.Unlock()
+
+	rows, truncated, err := s.write(ctx, id, spec)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cancels, id)
+	if job, ok = s.jobs[id]; !ok {
+		os.Remove(s.partPath(id, spec.Format))
+		return
+	}
+	s.finish(job, rows, truncated, err)
+	if job.Status != models.ExportCompleted {
+		s.logger.Printf("Export %s failed after %d rows: %s", id, rows, job.Error)
+		return
+	}
+	s.logger.Printf("Export %s completed: %d rows, %d bytes", id, job.Rows, job.Size)
+	s.enforceQuota(id)
+}
+
+// finish records a job's outcome, publishing its artifact on success.
+func (s *Store) finish(job *models.ExportJob, rows int, truncated bool, err error) {
+	now := s.now()
+	expires := now.Add(s.cfg.TTL)
+	job.ExpiresAt = &expires
+	part := s.partPath(job.ID, job.Spec.Format)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = os.Stat(part); err == nil {
+			err = os.Rename(part, s.artifactPath(job))
+		}
+		if err == nil {
+			job.Status = models.ExportCompleted
+			job.CompletedAt = &now
+			job.Rows = rows
+			job.Size = info.Size()
+			job.Truncated = truncated
+		}
+	}
+	if err != nil {
+		os.Remove(part)
+		job.Status = models.ExportFailed
+		job.Error = err.Error()
+	}
+	s.save(job)
+}
+
+// write pages through storage into the job's partial artifact.
+func (s *Store) write(ctx context.Context, id string, spec models.ExportSpec) (rows int, truncated bool, err error) {
+	f, err := os.Create(s.partPath(id, spec.Format))
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	w := &quotaWriter{w: buf, remaining: s.cfg.QuotaBytes}
+	if spec.Format == "csv" {
+		models.WriteMetricsCSVHeader(w)
+	} else {
+		io.WriteString(w, "[")
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return rows, false, err
+		}
+		limit := min(pageSize, s.cfg.MaxRows-rows)
+		if limit <= 0 {
+			more, err := s.reader.GetTelemetry(ctx, spec.TelemetryQuery(1, rows))
+			if err != nil {
+				return rows, false, err
+			}
+			truncated = len(more) > 0
+			break
+		}
+		metrics, err := s.reader.GetTelemetry(ctx, spec.TelemetryQuery(limit, rows))
+		if err != nil {
+			return rows, false, err
+		}
+		if spec.Format == "csv" {
+			models.WriteMetricsCSVRows(w, metrics)
+		} else {
+			for i, m := range metrics {
+				if rows+i > 0 {
+					io.WriteString(w, ",")
+				}
+				data, err := json.Marshal(m)
+				if err != nil {
+					return rows, false, err
+				}
+				io.WriteString(w, "\n")
+				w.Write(data)
+			}
+		}
+		if w.err != nil {
+			return rows, false, w.err
+		}
+		rows += len(metrics)
+		if len(metrics) < limit {
+			break
+		}
+	}
+
+	if spec.Format != "csv" {
+		io.WriteString(w, "\n]\n")
+	}
+	if w.err != nil {
+		return rows, false, w.err
+	}
+	if err := buf.Flush(); err != nil {
+		return rows, false, err
+	}
+	return rows, truncated, f.Sync()
+}
+
+// quotaWriter fails once more than the quota has been written, so one
+// export cannot fill the disk.
+type quotaWriter struct {
+	w         io.Writer
+	remaining int64
+	err       error
+}
+
+func (q *quotaWriter) Write(p []byte) (int, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	if int64(len(p)) > q.remaining {
+		q.err = errors.New("artifact exceeds the export disk quota")
+		return 0, q.err
+	}
+	n, err := q.w.Write(p)
+	q.remaining -= int64(n)
+	q.err = err
+	return n, err
+}
+
+// Expire deletes artifacts past their expiry, and the records of failed and
+// expired jobs once a further TTL has passed.
+func (s *Store) Expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, job := range s.jobs {
+		if job.ExpiresAt == nil || now.Before(*job.ExpiresAt) {
+			continue
+		}
+		switch job.Status {
+		case models.ExportCompleted:
+			s.expire(job, "")
+		case models.ExportFailed:
+			s.remove(job)
+		case models.ExportExpired:
+			if !now.Before(job.ExpiresAt.Add(s.cfg.TTL)) {
+				s.remove(job)
+			}
+		}
+	}
+}
+
+// enforceQuota deletes the oldest artifacts other than keep until the
+// artifacts fit the quota.
+func (s *Store) enforceQuota(keep string) {
+	used := s.used()
+	if used <= s.cfg.QuotaBytes {
+		return
+	}
+	var completed []*models.ExportJob
+	for _, job := range s.jobs {
+		if job.Status == models.ExportCompleted && job.ID != keep {
+			completed = append(completed, job)
+		}
+	}
+	sort.Slice(completed, func(i, j int) bool { return completed[i].CompletedAt.Before(*completed[j].CompletedAt) })
+	for _, job := range completed {
+		if used <= s.cfg.QuotaBytes {
+			return
+		}
+		used -= job.Size
+		s.logger.Printf("Deleting export %s (%d bytes) to stay within the export disk quota", job.ID, job.Size)
+		s.expire(job, "deleted early to stay within the export disk quota")
+	}
+}
+
+// expire deletes a job's artifact, keeping its record so downloads report
+// that it expired.
+func (s *Store) expire(job *models.ExportJob, reason string) {
+	os.Remove(s.artifactPath(job))
+	job.Status = models.ExportExpired
+	job.Error = reason
+	s.save(job)
+}
+
+// remove deletes a job's files and record.
+func (s *Store) remove(job *models.ExportJob) {
+	os.Remove(s.artifactPath(job))
+	os.Remove(s.partPath(job.ID, job.Spec.Format))
+	if err := s.state.Delete(context.Background(), job.ID+metaSuffix); err != nil {
+		s.logger.Printf("Failed to delete export job %s: %v", job.ID, err)
+	}
+	delete(s.jobs, job.ID)
+}
+
+func (s *Store) used() int64 {
+	var used int64
+	for _, job := range s.jobs {
+		if job.Status == models.ExportCompleted {
+			used += job.Size
+		}
+	}
+	return used
+}
+
+// save writes a job's record, replacing the previous one atomically.
+func (s *Store) save(job *models.ExportJob) {
+	if err := statestore.PutJSON(context.Background(), s.state, job.ID+metaSuffix, job); err != nil {
+		s.logger.Printf("Failed to save export job %s: %v", job.ID, err)
+	}
+}
+
+func (s *Store) artifactPath(job *models.ExportJob) string {
+	return filepath.Join(s.dir, job.ID+"."+job.Spec.Format)
+}
+
+func (s *Store) partPath(id, format string) string {
+	return filepath.Join(s.dir, id+"."+format+".part")
+}
@@ -0,0 +1,214 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// pointReader serves n data points, one per second from start, in pages.
+type pointReader struct {
+	start time.Time
+	n     int
+}
+
+func (r *pointReader) GetGPUs(ctx context.Context) ([]string, error) { return nil, nil }
+func (r *pointReader) Close() error                                  { return nil }
+
+func (r *pointReader) GetTelemetry(ctx context.Context, q *models.TelemetryQuery) ([]*models.GPUMetric, error) {
+	var metrics []*models.GPUMetric
+	for i := q.Offset; i < r.n && len(metrics) < q.Limit; i++ {
+		metrics = append(metrics, &models.GPUMetric{
+			Timestamp:  r.start.Add(time.Duration(i) * time.Second),
+			MetricName: "DCGM_FI_DEV_GPU_UTIL",
+			UUID:       q.UUID,
+			Hostname:   "host-1",
+			Value:      float64(i),
+		})
+	}
+	return metrics, nil
+}
+
+var testStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testConfig(t *testing.T) config.ExportConfig {
+	return config.ExportConfig{
+		Dir:             t.TempDir(),
+		TTL:             time.Hour,
+		QuotaBytes:      16 << 20,
+		MaxRows:         100000,
+		QueueSize:       4,
+		JanitorInterval: time.Minute,
+	}
+}
+
+func testSpec(format string) models.ExportSpec {
+	return models.ExportSpec{UUID: "GPU-1", Start: testStart, End: testStart.Add(time.Hour), Format: format}
+}
+
+// runNext runs the next queued job and returns its final state.
+func runNext(t *testing.T, s *Store) models.ExportJob {
+	t.Helper()
+	id := <-s.queue
+	s.run(context.Background(), id)
+	job, err := s.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestExportCSV(t *testing.T) {
+	s, err := Open(&pointReader{start: testStart, n: 25000}, testConfig(t), log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(models.ExportSpec{Format: "csv"}); err == nil {
+		t.Error("Create accepted a spec without a time range")
+	}
+	created, err := s.Create(testSpec(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Artifact(created.ID); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Artifact(queued) = %v, want ErrNotReady", err)
+	}
+
+	job := runNext(t, s)
+	if job.Status != models.ExportCompleted || job.Rows != 25000 || job.Truncated {
+		t.Fatalf("job = %+v", job)
+	}
+	f, _, err := s.Artifact(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if int64(len(data)) != job.Size {
+		t.Errorf("artifact is %d bytes, job says %d", len(data), job.Size)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 25001 || !strings.HasPrefix(lines[0], "Timestamp,") {
+		t.Errorf("artifact has %d lines starting %q", len(lines), lines[0])
+	}
+	if used, _ := s.Usage(); used != job.Size {
+		t.Errorf("Usage() = %d, want %d", used, job.Size)
+	}
+}
+
+func TestExportJSONTruncated(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MaxRows = 30
+	s, err := Open(&pointReader{start: testStart, n: 50}, cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(testSpec("json")); err != nil {
+		t.Fatal(err)
+	}
+	job := runNext(t, s)
+	if job.Status != models.ExportCompleted || job.Rows != 30 || !job.Truncated {
+		t.Fatalf("job = %+v", job)
+	}
+	f, _, err := s.Artifact(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var metrics []models.GPUMetric
+	if err := json.NewDecoder(f).Decode(&metrics); err != nil || len(metrics) != 30 {
+		t.Errorf("artifact decodes to %d metrics: %v", len(metrics), err)
+	}
+}
+
+func TestExportQuotaAndExpiry(t *testing.T) {
+	cfg := testConfig(t)
+	s, err := Open(&pointReader{start: testStart, n: 100}, cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := testStart
+	s.now = func() time.Time { return now }
+
+	s.Create(testSpec("csv"))
+	first := runNext(t, s)
+
+	// Room for one artifact: the second evicts the first
+	s.cfg.QuotaBytes = first.Size + first.Size/2
+	now = now.Add(time.Minute)
+	s.Create(testSpec("csv"))
+	second := runNext(t, s)
+	if second.Status != models.ExportCompleted {
+		t.Fatalf("second = %+v", second)
+	}
+	if _, _, err := s.Artifact(first.ID); !errors.Is(err, ErrExpired) {
+		t.Errorf("Artifact(evicted) = %v, want ErrExpired", err)
+	}
+
+	// An artifact bigger than the whole quota fails without evicting anything
+	s.cfg.QuotaBytes = first.Size / 2
+	s.Create(testSpec("csv"))
+	if third := runNext(t, s); third.Status != models.ExportFailed || !strings.Contains(third.Error, "quota") {
+		t.Errorf("third = %+v", third)
+	}
+	if job, _ := s.Get(second.ID); job.Status != models.ExportCompleted {
+		t.Errorf("second was evicted by a failed export: %+v", job)
+	}
+
+	// Past the TTL the artifact goes; past another TTL the record goes too
+	now = now.Add(cfg.TTL)
+	s.Expire()
+	if _, _, err := s.Artifact(second.ID); !errors.Is(err, ErrExpired) {
+		t.Errorf("Artifact(expired) = %v, want ErrExpired", err)
+	}
+	if _, err := os.Stat(s.artifactPath(&second)); !os.IsNotExist(err) {
+		t.Errorf("expired artifact still on disk: %v", err)
+	}
+	now = now.Add(cfg.TTL)
+	s.Expire()
+	if len(s.List()) != 0 {
+		t.Errorf("records left after expiry: %+v", s.List())
+	}
+}
+
+func TestExportReopen(t *testing.T) {
+	cfg := testConfig(t)
+	reader := &pointReader{start: testStart, n: 10}
+	s, err := Open(reader, cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Create(testSpec("csv"))
+	done := runNext(t, s)
+	queued, _ := s.Create(testSpec("csv"))
+
+	// A restart keeps completed artifacts and fails jobs that never ran
+	s, err = Open(reader, cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, _, err := s.Artifact(done.ID); err != nil {
+		t.Errorf("Artifact(completed) after reopen: %v", err)
+	} else {
+		f.Close()
+	}
+	if job, _ := s.Get(queued.ID); job.Status != models.ExportFailed {
+		t.Errorf("queued job after reopen = %+v", job)
+	}
+
+	if err := s.Delete(done.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(done.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(deleted) = %v", err)
+	}
+}
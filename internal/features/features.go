@@ -0,0 +1,246 @@
+// Package features turns a GPU's metric series into fixed-length, regularly
+// sampled windows for machine learning models such as failure predictors:
+// every metric gets one value per step, gaps are filled, and values can be
+// normalized per metric.
+package features
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Interpolation methods for samples missing from a series.
+const (
+	// InterpolateLinear draws a straight line between the samples either
+	// side of a gap
+	InterpolateLinear = "linear"
+
+	// InterpolatePrevious repeats the last sample before a gap
+	InterpolatePrevious = "previous"
+
+	// InterpolateZero fills gaps with zero
+	InterpolateZero = "zero"
+)
+
+// Normalizations applied to each metric's window.
+const (
+	// NormalizeNone returns values as measured
+	NormalizeNone = "none"
+
+	// NormalizeZScore subtracts the window's mean and divides by its
+	// standard deviation
+	NormalizeZScore = "zscore"
+
+	// NormalizeMinMax scales the window's values to between 0 and 1
+	NormalizeMinMax = "minmax"
+)
+
+// IsInterpolation reports whether method is a known interpolation method.
+func IsInterpolation(method string) bool {
+	switch method {
+	case InterpolateLinear, InterpolatePrevious, InterpolateZero:
+		return true
+	}
+	return false
+}
+
+// IsNormalization reports whether method is a known normalization.
+func IsNormalization(method string) bool {
+	switch method {
+	case NormalizeNone, NormalizeZScore, NormalizeMinMax:
+		return true
+	}
+	return false
+}
+
+// Options says how a window is filled and normalized.
+type Options struct {
+	Interpolation string
+	Normalization string
+
+	// MinCoverage is the smallest fraction of a metric's samples that must
+	// be measured rather than filled, from 0 to 1. A metric with no
+	// samples at all is always refused.
+	MinCoverage float64
+}
+
+// Feature is one metric's samples over a window.
+type Feature struct {
+	Metric string `json:"metric" example:"DCGM_FI_DEV_GPU_TEMP"`
+
+	// Values holds one value per window step, after filling and normalizing
+	Values []float64 `json:"values"`
+
+	// Filled marks the values filled in rather than measured
+	Filled []bool `json:"filled"`
+
+	// Coverage is the fraction of values measured
+	Coverage float64 `json:"coverage" example:"0.97"`
+
+	// Mean, Std, Min and Max describe the filled values before
+	// normalization, so the same transform can be applied at inference
+	Mean float64 `json:"mean" example:"68.4"`
+	Std  float64 `json:"std" example:"6.1"`
+	Min  float64 `json:"min" example:"58"`
+	Max  float64 `json:"max" example:"85"`
+}
+
+// Window is several metrics sampled at the same times.
+type Window struct {
+	Times    []time.Time `json:"times"`
+	Features []Feature   `json:"features"`
+}
+
+// Feature returns the metric's feature, if the window has it.
+func (w Window) Feature(metric string) (Feature, bool) {
+	for _, f := range w.Features {
+		if f.Metric == metric {
+			return f, true
+		}
+	}
+	return Feature{}, false
+}
+
+// InsufficientDataError reports a metric measured too sparsely to fill.
+type InsufficientDataError struct {
+	Metric   string
+	Coverage float64
+}
+
+func (e *InsufficientDataError) Error() string {
+	if e.Coverage == 0 {
+		return fmt.Sprintf("metric %s has no samples in the window", e.Metric)
+	}
+	return fmt.Sprintf("metric %s has only %.0f%% of the window's samples", e.Metric, 100*e.Coverage)
+}
+
+// Build samples each metric n times, every step from start, from points
+// bucketed at those times. Buckets missing from a series are filled by the
+// interpolation method; gaps at either end of the window take the nearest
+// sample, except under InterpolateZero.
+func Build(points map[string][]models.SeriesPoint, metrics []string, start time.Time, step time.Duration, n int, opts Options) (Window, error) {
+	w := Window{Times: make([]time.Time, n), Features: make([]Feature, 0, len(metrics))}
+	for i := range w.Times {
+		w.Times[i] = start.Add(time.Duration(i) * step)
+	}
+	for _, metric := range metrics {
+		f := sample(points[metric], start, step, n)
+		f.Metric = metric
+		if f.Coverage == 0 || f.Coverage < opts.MinCoverage {
+			return Window{}, &InsufficientDataError{Metric: metric, Coverage: f.Coverage}
+		}
+		fill(f.Values, f.Filled, opts.Interpolation)
+		describe(&f)
+		normalize(&f, opts.Normalization)
+		w.Features = append(w.Features, f)
+	}
+	return w, nil
+}
+
+// sample places points in their window step, marking the steps without
+// one as filled.
+func sample(points []models.SeriesPoint, start time.Time, step time.Duration, n int) Feature {
+	f := Feature{Values: make([]float64, n), Filled: make([]bool, n)}
+	for i := range f.Filled {
+		f.Filled[i] = true
+	}
+	measured := 0
+	for _, p := range points {
+		offset := p.Time.Sub(start)
+		if offset < 0 || offset%step != 0 {
+			continue
+		}
+		i := int(offset / step)
+		if i >= n {
+			continue
+		}
+		if f.Filled[i] {
+			measured++
+		}
+		f.Values[i], f.Filled[i] = p.Value, false
+	}
+	f.Coverage = float64(measured) / float64(n)
+	return f
+}
+
+// fill replaces the filled values using the interpolation method. At least
+// one value is measured.
+func fill(values []float64, filled []bool, method string) {
+	if method == InterpolateZero {
+		for i := range values {
+			if filled[i] {
+				values[i] = 0
+			}
+		}
+		return
+	}
+
+	prev := -1
+	for i := range values {
+		if filled[i] {
+			continue
+		}
+		switch {
+		case prev < 0:
+			// Leading gap: take the first sample
+			for j := 0; j < i; j++ {
+				values[j] = values[i]
+			}
+		case method == InterpolateLinear:
+			for j := prev + 1; j < i; j++ {
+				frac := float64(j-prev) / float64(i-prev)
+				values[j] = values[prev] + frac*(values[i]-values[prev])
+			}
+		default:
+			for j := prev + 1; j < i; j++ {
+				values[j] = values[prev]
+			}
+		}
+		prev = i
+	}
+	// Trailing gap: repeat the last sample
+	for j := prev + 1; j < len(values); j++ {
+		values[j] = values[prev]
+	}
+}
+
+// describe sets the feature's mean, standard deviation, min and max.
+func describe(f *Feature) {
+	f.Min, f.Max = math.Inf(1), math.Inf(-1)
+	for _, v := range f.Values {
+		f.Mean += v
+		f.Min = math.Min(f.Min, v)
+		f.Max = math.Max(f.Max, v)
+	}
+	f.Mean /= float64(len(f.Values))
+	for _, v := range f.Values {
+		f.Std += (v - f.Mean) * (v - f.Mean)
+	}
+	f.Std = math.Sqrt(f.Std / float64(len(f.Values)))
+}
+
+// normalize rescales the feature's values. A constant window normalizes to
+// zeros.
+func normalize(f *Feature, method string) {
+	switch method {
+	case NormalizeZScore:
+		for i, v := range f.Values {
+			if f.Std == 0 {
+				f.Values[i] = 0
+			} else {
+				f.Values[i] = (v - f.Mean) / f.Std
+			}
+		}
+	case NormalizeMinMax:
+		for i, v := range f.Values {
+			if f.Max == f.Min {
+				f.Values[i] = 0
+			} else {
+				f.Values[i] = (v - f.Min) / (f.Max - f.Min)
+			}
+		}
+	}
+}
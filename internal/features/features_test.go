@@ -0,0 +1,92 @@
+package features
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// points returns values bucketed every 10s from start, skipping NaNs.
+func points(values ...float64) []models.SeriesPoint {
+	var out []models.SeriesPoint
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			out = append(out, models.SeriesPoint{Time: start.Add(time.Duration(i) * 10 * time.Second), Value: v})
+		}
+	}
+	return out
+}
+
+func TestBuildFillsGaps(t *testing.T) {
+	gap := math.NaN()
+	series := map[string][]models.SeriesPoint{"temp": points(gap, 10, gap, gap, 40, gap)}
+	for _, tc := range []struct {
+		method string
+		want   []float64
+	}{
+		{InterpolateLinear, []float64{10, 10, 20, 30, 40, 40}},
+		{InterpolatePrevious, []float64{10, 10, 10, 10, 40, 40}},
+		{InterpolateZero, []float64{0, 10, 0, 0, 40, 0}},
+	} {
+		w, err := Build(series, []string{"temp"}, start, 10*time.Second, 6, Options{Interpolation: tc.method, Normalization: NormalizeNone})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.method, err)
+		}
+		if f := w.Features[0]; !reflect.DeepEqual(f.Values, tc.want) {
+			t.Errorf("%s: values = %v, want %v", tc.method, f.Values, tc.want)
+		}
+	}
+
+	w, _ := Build(series, []string{"temp"}, start, 10*time.Second, 6, Options{Interpolation: InterpolateLinear})
+	f := w.Features[0]
+	if !reflect.DeepEqual(f.Filled, []bool{true, false, true, true, false, true}) || f.Coverage != 2.0/6 {
+		t.Errorf("filled = %v at coverage %v", f.Filled, f.Coverage)
+	}
+	if len(w.Times) != 6 || !w.Times[5].Equal(start.Add(50*time.Second)) {
+		t.Errorf("times = %v", w.Times)
+	}
+}
+
+func TestBuildNormalizes(t *testing.T) {
+	series := map[string][]models.SeriesPoint{"temp": points(2, 4, 4, 4, 5, 5, 7, 9), "flat": points(3, 3, 3, 3, 3, 3, 3, 3)}
+	w, err := Build(series, []string{"temp", "flat"}, start, 10*time.Second, 8, Options{Interpolation: InterpolateLinear, Normalization: NormalizeZScore})
+	if err != nil {
+		t.Fatal(err)
+	}
+	temp := w.Features[0]
+	if temp.Mean != 5 || temp.Std != 2 || temp.Min != 2 || temp.Max != 9 {
+		t.Errorf("stats = %+v, want mean 5, std 2, min 2, max 9", temp)
+	}
+	if temp.Values[0] != -1.5 || temp.Values[7] != 2 {
+		t.Errorf("z-scores = %v", temp.Values)
+	}
+	if flat := w.Features[1]; flat.Values[0] != 0 || flat.Std != 0 {
+		t.Errorf("expected a constant metric to normalize to zeros, got %+v", flat)
+	}
+
+	w, _ = Build(series, []string{"temp"}, start, 10*time.Second, 8, Options{Interpolation: InterpolateLinear, Normalization: NormalizeMinMax})
+	if v := w.Features[0].Values; v[0] != 0 || v[7] != 1 || v[4] != 3.0/7 {
+		t.Errorf("min-max values = %v", v)
+	}
+}
+
+func TestBuildCoverage(t *testing.T) {
+	gap := math.NaN()
+	series := map[string][]models.SeriesPoint{"temp": points(1, gap, gap, 4)}
+	opts := Options{Interpolation: InterpolateLinear, MinCoverage: 0.75}
+
+	var insufficient *InsufficientDataError
+	if _, err := Build(series, []string{"temp"}, start, 10*time.Second, 4, opts); !errors.As(err, &insufficient) || insufficient.Coverage != 0.5 {
+		t.Errorf("expected temp refused at 50%% coverage, got %v", err)
+	}
+	opts.MinCoverage = 0
+	if _, err := Build(series, []string{"temp", "power"}, start, 10*time.Second, 4, opts); !errors.As(err, &insufficient) || insufficient.Metric != "power" {
+		t.Errorf("expected power refused without samples, got %v", err)
+	}
+}
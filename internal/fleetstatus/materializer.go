@@ -0,0 +1,200 @@
+// Package fleetstatus keeps a current-status record per GPU materialized in
+// storage: the latest values of the key metrics, the GPU's alert state and a
+// health score. The fleet overview then reads one small document per GPU
+// instead of scanning telemetry and evaluating alerts on every request.
+package fleetstatus
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Health score penalties: each firing alert takes off its severity's
+// penalty and each pending alert pendingPenalty.
+var severityPenalty = map[string]int{
+	models.SeverityCritical: 50,
+	models.SeverityWarning:  20,
+	models.SeverityInfo:     5,
+}
+
+const pendingPenalty = 5
+
+// AlertSource reports the alert state of the GPUs. Only the replica
+// evaluating rules knows it.
+type AlertSource interface {
+	Alerts() []models.Alert
+	Leading() bool
+}
+
+// Materializer periodically computes every GPU's status from the
+// latest-values cache and the alert state, and writes them to storage.
+type Materializer struct {
+	store      storage.GPUStatusStore
+	latest     *cache.Latest
+	alerts     AlertSource // nil when alerting is off
+	leader     leader.Leader
+	interval   time.Duration
+	metrics    []string
+	staleAfter time.Duration
+	logger     *log.Logger
+	clock      clock.Clock
+
+	// previous holds the statuses last written, so each status keeps its
+	// ChangedAt until its health changes; nil until the first Materialize
+	previous map[string]*models.GPUStatus
+}
+
+// New creates a materializer. With alerts set it writes statuses while that
+// replica evaluates rules; otherwise it writes them while l is the leader.
+func New(store storage.GPUStatusStore, latest *cache.Latest, alerts AlertSource, l leader.Leader, cfg config.StatusConfig, logger *log.Logger) *Materializer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Materializer{
+		store:      store,
+		latest:     latest,
+		alerts:     alerts,
+		leader:     l,
+		interval:   cfg.Interval,
+		metrics:    cfg.Metrics,
+		staleAfter: cfg.StaleAfter,
+		logger:     logger,
+		clock:      clock.Real,
+	}
+}
+
+// Run writes the statuses every interval until ctx is done.
+func (m *Materializer) Run(ctx context.Context) {
+	ticker := m.clock.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if m.leading() {
+			if err := m.Materialize(ctx); err != nil && ctx.Err() == nil {
+				m.logger.Printf("GPU status update failed: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+func (m *Materializer) leading() bool {
+	if m.alerts != nil {
+		return m.alerts.Leading()
+	}
+	return m.leader.IsLeader()
+}
+
+// Materialize computes and writes the status of every GPU in the cache.
+func (m *Materializer) Materialize(ctx context.Context) error {
+	var alerts []models.Alert
+	if m.alerts != nil {
+		alerts = m.alerts.Alerts()
+	}
+	now := m.clock.Now()
+	statuses := Build(m.latest.Snapshot(), alerts, m.metrics, m.staleAfter, now)
+	if len(statuses) == 0 {
+		return nil
+	}
+
+	// Start from the stored statuses so a restart or a new leader doesn't
+	// mark every GPU changed; if they can't be read, every GPU is new
+	if m.previous == nil {
+		m.previous = make(map[string]*models.GPUStatus)
+		if stored, err := m.store.ListGPUStatuses(ctx); err == nil {
+			for _, s := range stored {
+				m.previous[s.UUID] = s
+			}
+		}
+	}
+	for _, s := range statuses {
+		s.ChangedAt = now.UTC()
+		if prev, ok := m.previous[s.UUID]; ok && prev.SameHealth(s) && !prev.ChangedAt.IsZero() {
+			s.ChangedAt = prev.ChangedAt
+		}
+	}
+
+	if err := m.store.WriteGPUStatuses(ctx, statuses); err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		m.previous[s.UUID] = s
+	}
+	return nil
+}
+
+// Build computes the status of each GPU in snapshots at now. A GPU without
+// telemetry for staleAfter is stale and scores 0; otherwise it is critical
+// with a critical alert firing, warning with any other alert firing and
+// healthy without.
+func Build(snapshots []cache.GPUSnapshot, alerts []models.Alert, metrics []string, staleAfter time.Duration, now time.Time) []*models.GPUStatus {
+	byGPU := make(map[string][]models.Alert)
+	for _, a := range alerts {
+		byGPU[a.UUID] = append(byGPU[a.UUID], a)
+	}
+
+	statuses := make([]*models.GPUStatus, 0, len(snapshots))
+	for _, gpu := range snapshots {
+		status := &models.GPUStatus{
+			UUID:      gpu.UUID,
+			GPUID:     gpu.GPUID,
+			Device:    gpu.Device,
+			ModelName: gpu.ModelName,
+			Hostname:  gpu.Hostname,
+			LastSeen:  gpu.LastSeen.UTC(),
+			Metrics:   make(map[string]float64, len(metrics)),
+			UpdatedAt: now.UTC(),
+		}
+		for _, name := range metrics {
+			if v, ok := gpu.Metrics[name]; ok {
+				status.Metrics[name] = v.Value
+			}
+		}
+
+		score := 100
+		for _, a := range byGPU[gpu.UUID] {
+			switch a.State {
+			case models.AlertFiring:
+				status.Firing++
+				status.Rules = append(status.Rules, a.RuleName)
+				score -= severityPenalty[a.Severity]
+				if severityPenalty[a.Severity] > severityPenalty[status.Severity] {
+					status.Severity = a.Severity
+				}
+			case models.AlertPending:
+				status.Pending++
+				score -= pendingPenalty
+			}
+		}
+		sort.Strings(status.Rules)
+
+		switch {
+		case now.Sub(gpu.LastSeen) > staleAfter:
+			status.Health = models.HealthStale
+			score = 0
+		case status.Severity == models.SeverityCritical:
+			status.Health = models.HealthCritical
+		case status.Firing > 0:
+			status.Health = models.HealthWarning
+		default:
+			status.Health = models.HealthHealthy
+		}
+		status.HealthScore = max(score, 0)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
@@ -0,0 +1,138 @@
+package fleetstatus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/cache"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestBuild(t *testing.T) {
+	snapshots := []cache.GPUSnapshot{
+		{UUID: "GPU-1", Hostname: "host-1", LastSeen: now, Metrics: map[string]cache.MetricValue{
+			"DCGM_FI_DEV_GPU_TEMP": {Value: 91},
+			"DCGM_FI_DEV_SM_CLOCK": {Value: 1400},
+		}},
+		{UUID: "GPU-2", Hostname: "host-1", LastSeen: now},
+		{UUID: "GPU-3", Hostname: "host-2", LastSeen: now.Add(-time.Hour)},
+	}
+	alerts := []models.Alert{
+		{UUID: "GPU-1", RuleName: "hot", Severity: models.SeverityCritical, State: models.AlertFiring},
+		{UUID: "GPU-1", RuleName: "throttled", Severity: models.SeverityWarning, State: models.AlertFiring},
+		{UUID: "GPU-2", RuleName: "hot", Severity: models.SeverityCritical, State: models.AlertPending},
+	}
+
+	statuses := Build(snapshots, alerts, []string{"DCGM_FI_DEV_GPU_TEMP"}, 5*time.Minute, now)
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 statuses, got %d", len(statuses))
+	}
+
+	gpu1 := statuses[0]
+	if gpu1.Health != models.HealthCritical || gpu1.HealthScore != 30 || gpu1.Firing != 2 || gpu1.Severity != models.SeverityCritical {
+		t.Errorf("unexpected status for GPU-1: %+v", gpu1)
+	}
+	if len(gpu1.Metrics) != 1 || gpu1.Metrics["DCGM_FI_DEV_GPU_TEMP"] != 91 {
+		t.Errorf("expected only the key metric, got %v", gpu1.Metrics)
+	}
+	if len(gpu1.Rules) != 2 || gpu1.Rules[0] != "hot" {
+		t.Errorf("expected the firing rules, got %v", gpu1.Rules)
+	}
+
+	if gpu2 := statuses[1]; gpu2.Health != models.HealthHealthy || gpu2.HealthScore != 95 || gpu2.Pending != 1 {
+		t.Errorf("expected a pending alert to lower the score only, got %+v", gpu2)
+	}
+	if gpu3 := statuses[2]; gpu3.Health != models.HealthStale || gpu3.HealthScore != 0 {
+		t.Errorf("expected GPU-3 stale, got %+v", gpu3)
+	}
+}
+
+// statusStore records the statuses written.
+type statusStore struct {
+	written []*models.GPUStatus
+}
+
+func (s *statusStore) WriteGPUStatuses(ctx context.Context, statuses []*models.GPUStatus) error {
+	s.written = statuses
+	return nil
+}
+
+func (s *statusStore) ListGPUStatuses(ctx context.Context) ([]*models.GPUStatus, error) {
+	return s.written, nil
+}
+
+func TestMaterialize(t *testing.T) {
+	latest := cache.NewLatest(cache.SourceStorage)
+	latest.Update([]*models.GPUMetric{{UUID: "GPU-1", Hostname: "host-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 60, Timestamp: time.Now()}})
+	store := &statusStore{}
+	cfg := config.StatusConfig{Interval: time.Minute, Metrics: []string{"DCGM_FI_DEV_GPU_TEMP"}, StaleAfter: 5 * time.Minute}
+
+	m := New(store, latest, nil, leader.Always{}, cfg, nil)
+	if err := m.Materialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.written) != 1 || store.written[0].Health != models.HealthHealthy || store.written[0].Metrics["DCGM_FI_DEV_GPU_TEMP"] != 60 {
+		t.Errorf("unexpected statuses written: %+v", store.written)
+	}
+}
+
+// fixedAlerts is an AlertSource with a settable set of alerts.
+type fixedAlerts struct {
+	alerts []models.Alert
+}
+
+func (a *fixedAlerts) Alerts() []models.Alert { return a.alerts }
+func (a *fixedAlerts) Leading() bool          { return true }
+
+func TestMaterializeChangedAt(t *testing.T) {
+	clk := clock.NewSimulated(now)
+	latest := cache.NewLatest(cache.SourceStorage)
+	update := func(value float64) {
+		latest.Update([]*models.GPUMetric{{UUID: "GPU-1", Hostname: "host-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: value, Timestamp: clk.Now()}})
+	}
+	started := now.Add(-time.Hour)
+	store := &statusStore{written: []*models.GPUStatus{{UUID: "GPU-1", Health: models.HealthHealthy, HealthScore: 100, ChangedAt: started}}}
+	alerts := &fixedAlerts{}
+	cfg := config.StatusConfig{Interval: time.Minute, Metrics: []string{"DCGM_FI_DEV_GPU_TEMP"}, StaleAfter: 5 * time.Minute}
+
+	m := New(store, latest, alerts, leader.Always{}, cfg, nil)
+	m.clock = clk
+	changedAt := func() time.Time {
+		t.Helper()
+		if err := m.Materialize(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return store.written[0].ChangedAt
+	}
+
+	// The stored status is carried over, and new metric values aren't a change
+	update(60)
+	if got := changedAt(); !got.Equal(started) {
+		t.Errorf("after restart changed_at = %v, want stored %v", got, started)
+	}
+	clk.Advance(time.Minute)
+	update(70)
+	if got := changedAt(); !got.Equal(started) {
+		t.Errorf("after a value change changed_at = %v, want %v", got, started)
+	}
+
+	// A firing alert is
+	clk.Advance(time.Minute)
+	update(70)
+	alerts.alerts = []models.Alert{{UUID: "GPU-1", RuleName: "hot", Severity: models.SeverityWarning, State: models.AlertFiring}}
+	fired := clk.Now()
+	if got := changedAt(); !got.Equal(fired) {
+		t.Errorf("after an alert changed_at = %v, want %v", got, fired)
+	}
+	clk.Advance(time.Minute)
+	update(70)
+	if got := changedAt(); !got.Equal(fired) {
+		t.Errorf("with the alert still firing changed_at = %v, want %v", got, fired)
+	}
+}
@@ -0,0 +1,103 @@
+// Package forecast projects fleet metrics forward for capacity planning by
+// fitting a least-squares linear trend to their history.
+package forecast
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Point is one observed value of a series.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Projection is one forecast value with its approximate 95% prediction interval.
+type Projection struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	Lower float64   `json:"lower"`
+	Upper float64   `json:"upper"`
+}
+
+// Trend is a linear fit of a series against time.
+type Trend struct {
+	// Slope is the change per step
+	Slope float64 `json:"slope"`
+
+	// Intercept is the fitted value at the first point
+	Intercept float64 `json:"intercept"`
+
+	// ResidualStdDev is the spread of the history around the fit
+	ResidualStdDev float64 `json:"residual_stddev"`
+}
+
+// MinPoints is the shortest history a trend is fitted to.
+const MinPoints = 3
+
+// ErrInsufficientHistory is returned when a series is shorter than MinPoints.
+var ErrInsufficientHistory = errors.New("forecast needs at least 3 points of history")
+
+// z95 scales the residual spread to a two-sided 95% interval.
+const z95 = 1.96
+
+// Linear fits a trend to points, oldest first and on a grid of step, and
+// projects it horizon steps past the last point. Missing steps are gaps in
+// the fit rather than zeros. Projections of non-negative quantities such as
+// utilization and energy should never go below zero, so floor clamps them.
+func Linear(points []Point, step time.Duration, horizon int, floor bool) (Trend, []Projection, error) {
+	n := len(points)
+	if n < MinPoints {
+		return Trend{}, nil, ErrInsufficientHistory
+	}
+
+	// Fit value = intercept + slope*x where x counts steps from the first point
+	first := points[0].Time
+	steps := func(t time.Time) float64 { return float64(t.Sub(first)) / float64(step) }
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := steps(p.Time)
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+	fn := float64(n)
+	meanX, meanY := sumX/fn, sumY/fn
+	sxx := sumXX - fn*meanX*meanX
+	if sxx == 0 {
+		return Trend{}, nil, ErrInsufficientHistory
+	}
+	slope := (sumXY - fn*meanX*meanY) / sxx
+	trend := Trend{Slope: slope, Intercept: meanY - slope*meanX}
+
+	var sse float64
+	for _, p := range points {
+		r := p.Value - (trend.Intercept + slope*steps(p.Time))
+		sse += r * r
+	}
+	trend.ResidualStdDev = math.Sqrt(sse / float64(n-2))
+
+	// The interval widens with distance from the history's center
+	last := points[n-1].Time
+	projections := make([]Projection, 0, horizon)
+	for h := 1; h <= horizon; h++ {
+		at := last.Add(time.Duration(h) * step)
+		x := steps(at)
+		value := trend.Intercept + slope*x
+		margin := z95 * trend.ResidualStdDev * math.Sqrt(1+1/fn+(x-meanX)*(x-meanX)/sxx)
+		p := Projection{
+			Time:  at,
+			Value: value,
+			Lower: value - margin,
+			Upper: value + margin,
+		}
+		if floor {
+			p.Value, p.Lower, p.Upper = math.Max(p.Value, 0), math.Max(p.Lower, 0), math.Max(p.Upper, 0)
+		}
+		projections = append(projections, p)
+	}
+	return trend, projections, nil
+}
@@ -0,0 +1,66 @@
+package forecast
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLinear(t *testing.T) {
+	day := 24 * time.Hour
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var points []Point
+	for i := 0; i < 10; i++ {
+		points = append(points, Point{Time: start.Add(time.Duration(i) * day), Value: 40 + 2*float64(i)})
+	}
+
+	trend, projections, err := Linear(points, day, 5, true)
+	if err != nil {
+		t.Fatalf("Linear() = %v", err)
+	}
+	if math.Abs(trend.Slope-2) > 1e-9 || math.Abs(trend.Intercept-40) > 1e-9 || trend.ResidualStdDev > 1e-9 {
+		t.Errorf("trend = %+v, want slope 2 from 40 with no spread", trend)
+	}
+	if len(projections) != 5 {
+		t.Fatalf("got %d projections, want 5", len(projections))
+	}
+	if last := projections[4]; !last.Time.Equal(start.Add(14*day)) || math.Abs(last.Value-68) > 1e-9 {
+		t.Errorf("last projection = %+v, want 68 on day 14", last)
+	}
+
+	// A noisy series has an interval that widens with distance
+	for i := range points {
+		points[i].Value = 10 - 3*float64(i) + float64(i%2)
+	}
+	_, projections, err = Linear(points, day, 5, false)
+	if err != nil {
+		t.Fatalf("Linear() = %v", err)
+	}
+	first, last := projections[0], projections[4]
+	if last.Upper-last.Lower <= first.Upper-first.Lower {
+		t.Errorf("interval narrowed from %+v to %+v", first, last)
+	}
+
+	// The series is falling below zero, which floor clamps
+	_, projections, _ = Linear(points, day, 5, true)
+	for _, p := range projections {
+		if p.Value < 0 || p.Lower < 0 {
+			t.Errorf("projection %+v below zero", p)
+		}
+	}
+
+	// A missing day is a gap, not a zero
+	gapped := []Point{
+		{Time: start, Value: 10},
+		{Time: start.Add(day), Value: 12},
+		{Time: start.Add(3 * day), Value: 16},
+	}
+	if trend, _, _ := Linear(gapped, day, 1, false); math.Abs(trend.Slope-2) > 1e-9 {
+		t.Errorf("slope across a gap = %v, want 2", trend.Slope)
+	}
+
+	if _, _, err := Linear(points[:2], day, 5, false); !errors.Is(err, ErrInsufficientHistory) {
+		t.Errorf("Linear() with two points = %v, want ErrInsufficientHistory", err)
+	}
+}
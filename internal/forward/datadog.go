@@ -0,0 +1,79 @@
+package forward
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// datadogGauge is the v2 series intake's metric type for gauges.
+const datadogGauge = 3
+
+type datadogPayload struct {
+	Series []datadogSeries `json:"series"`
+}
+
+type datadogSeries struct {
+	Metric    string            `json:"metric"`
+	Type      int               `json:"type"`
+	Points    []datadogPoint    `json:"points"`
+	Tags      []string          `json:"tags,omitempty"`
+	Resources []datadogResource `json:"resources,omitempty"`
+}
+
+type datadogPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type datadogResource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// datadogTags returns a metric's tags. Kubernetes identity uses the tag
+// names of Datadog's own Kubernetes integration.
+func datadogTags(m *models.GPUMetric) []string {
+	tags := []string{"uuid:" + m.UUID, "gpu:" + strconv.Itoa(m.GPUID)}
+	for _, t := range [][2]string{
+		{"model_name", m.ModelName},
+		{"device", m.Device},
+		{"kube_namespace", m.Namespace},
+		{"pod_name", m.Pod},
+		{"kube_container_name", m.Container},
+	} {
+		if t[1] != "" {
+			tags = append(tags, t[0]+":"+t[1])
+		}
+	}
+	labels := make([]string, 0, len(m.Labels))
+	for k, v := range m.Labels {
+		labels = append(labels, k+":"+v)
+	}
+	sort.Strings(labels)
+	return append(tags, labels...)
+}
+
+// encodeDatadog encodes a v2 series submission with every metric as a
+// gauge. The hostname is sent as the series' host resource.
+func encodeDatadog(metrics []models.GPUMetric) ([]byte, error) {
+	var payload datadogPayload
+	for _, s := range groupSeries(metrics) {
+		first := &s.samples[0]
+		ds := datadogSeries{
+			Metric: first.MetricName,
+			Type:   datadogGauge,
+			Tags:   datadogTags(first),
+		}
+		if first.Hostname != "" {
+			ds.Resources = []datadogResource{{Name: first.Hostname, Type: "host"}}
+		}
+		for _, m := range s.samples {
+			ds.Points = append(ds.Points, datadogPoint{Timestamp: m.Timestamp.Unix(), Value: m.Value})
+		}
+		payload.Series = append(payload.Series, ds)
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,329 @@
+package forward
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/otlp"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var t0 = time.Unix(1700000000, 0)
+
+func sampleMetrics() []models.GPUMetric {
+	return []models.GPUMetric{
+		{Timestamp: t0.Add(time.Second), MetricName: "DCGM_FI_DEV_GPU_TEMP", GPUID: 0, UUID: "GPU-1", Hostname: "node-1",
+			ModelName: "H100", Pod: "trainer-0", Namespace: "ml", Value: 46, Labels: map[string]string{"pci.bus_id": "0000:01"}},
+		{Timestamp: t0, MetricName: "DCGM_FI_DEV_GPU_TEMP", GPUID: 0, UUID: "GPU-1", Hostname: "node-1",
+			ModelName: "H100", Pod: "trainer-0", Namespace: "ml", Value: 45, Labels: map[string]string{"pci.bus_id": "0000:01"}},
+		{Timestamp: t0, MetricName: "DCGM_FI_DEV_POWER_USAGE", GPUID: 1, UUID: "GPU-2", Hostname: "node-2", Value: 300.5},
+	}
+}
+
+// pbFields splits a protobuf message into its length-delimited and fixed64
+// fields by field number; varints are returned in fixed.
+func pbFields(t *testing.T, b []byte) (bytes map[int][][]byte, fixed map[int][]uint64) {
+	t.Helper()
+	bytes, fixed = make(map[int][][]byte), make(map[int][]uint64)
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		b = b[n:]
+		num := int(key >> 3)
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(b)
+			fixed[num] = append(fixed[num], v)
+			b = b[n:]
+		case wireFixed64:
+			fixed[num] = append(fixed[num], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			bytes[num] = append(bytes[num], b[n:n+int(size)])
+			b = b[n+int(size):]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return bytes, fixed
+}
+
+func TestEncodeRemoteWrite(t *testing.T) {
+	body, err := encodeRemoteWrite(sampleMetrics())
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	raw, err := s2.Decode(nil, body)
+	if err != nil {
+		t.Fatalf("snappy: %v", err)
+	}
+
+	req, _ := pbFields(t, raw)
+	if len(req[1]) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(req[1]))
+	}
+
+	ts, _ := pbFields(t, req[1][0])
+	var names []string
+	labels := make(map[string]string)
+	for _, l := range ts[1] {
+		f, _ := pbFields(t, l)
+		name, value := string(f[1][0]), string(f[2][0])
+		names = append(names, name)
+		labels[name] = value
+	}
+	for i := 1; i < len(names); i++ {
+		if names[i-1] >= names[i] {
+			t.Errorf("labels not sorted: %v", names)
+		}
+	}
+	want := map[string]string{"__name__": "DCGM_FI_DEV_GPU_TEMP", "UUID": "GPU-1", "gpu": "0", "Hostname": "node-1",
+		"modelName": "H100", "pod": "trainer-0", "namespace": "ml", "pci_bus_id": "0000:01"}
+	for k, v := range want {
+		if labels[k] != v {
+			t.Errorf("label %s = %q, want %q", k, labels[k], v)
+		}
+	}
+
+	if len(ts[2]) != 2 {
+		t.Fatalf("expected 2 samples, got %d", len(ts[2]))
+	}
+	for i, wantValue := range []float64{45, 46} {
+		_, f := pbFields(t, ts[2][i])
+		if v := math.Float64frombits(f[1][0]); v != wantValue {
+			t.Errorf("sample %d = %v, want %v (samples must be in time order)", i, v, wantValue)
+		}
+		if ms := int64(f[2][0]); ms != t0.Add(time.Duration(i)*time.Second).UnixMilli() {
+			t.Errorf("sample %d timestamp = %d", i, ms)
+		}
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	for in, want := range map[string]string{
+		"DCGM_FI_DEV_GPU_TEMP": "DCGM_FI_DEV_GPU_TEMP",
+		"gpu.temp-c":           "gpu_temp_c",
+		"0x":                   "_0x",
+		"job:rate":             "job:rate",
+	} {
+		if got := sanitizeName(in, true); got != want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := sanitizeName("job:rate", false); got != "job_rate" {
+		t.Errorf("label names may not contain ':', got %q", got)
+	}
+}
+
+func TestEncodeOTLPRoundTrip(t *testing.T) {
+	in := sampleMetrics()
+	body, err := encodeOTLP(in)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	req, err := otlp.UnmarshalJSON(body)
+	if err != nil {
+		t.Fatalf("the OTLP receiver cannot read the request: %v", err)
+	}
+	if len(req.ResourceMetrics) != 2 {
+		t.Errorf("expected a resource per host, got %d", len(req.ResourceMetrics))
+	}
+
+	conv := otlp.Convert(req, time.Now())
+	if conv.Rejected != 0 || len(conv.Metrics) != len(in) {
+		t.Fatalf("converted %d metrics, rejected %d (%v)", len(conv.Metrics), conv.Rejected, conv.Reasons)
+	}
+	for i, got := range conv.Metrics {
+		want := in[i]
+		if got.MetricName != want.MetricName || got.UUID != want.UUID || got.GPUID != want.GPUID ||
+			got.Hostname != want.Hostname || got.ModelName != want.ModelName || got.Pod != want.Pod ||
+			got.Namespace != want.Namespace || got.Value != want.Value || !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("metric %d: got %+v, want %+v", i, got, want)
+		}
+		if len(want.Labels) > 0 && got.Labels["pci.bus_id"] != want.Labels["pci.bus_id"] {
+			t.Errorf("metric %d lost its labels: %v", i, got.Labels)
+		}
+	}
+}
+
+func TestEncodeDatadog(t *testing.T) {
+	body, err := encodeDatadog(sampleMetrics())
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	var payload datadogPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(payload.Series) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(payload.Series))
+	}
+	s := payload.Series[0]
+	if s.Metric != "DCGM_FI_DEV_GPU_TEMP" || s.Type != datadogGauge || len(s.Points) != 2 || s.Points[0].Value != 45 {
+		t.Errorf("unexpected series: %+v", s)
+	}
+	if len(s.Resources) != 1 || s.Resources[0].Name != "node-1" || s.Resources[0].Type != "host" {
+		t.Errorf("expected node-1 as host resource, got %+v", s.Resources)
+	}
+	tags := make(map[string]bool)
+	for _, tag := range s.Tags {
+		tags[tag] = true
+	}
+	for _, want := range []string{"uuid:GPU-1", "gpu:0", "model_name:H100", "kube_namespace:ml", "pod_name:trainer-0", "pci.bus_id:0000:01"} {
+		if !tags[want] {
+			t.Errorf("missing tag %s in %v", want, s.Tags)
+		}
+	}
+}
+
+// recorder is a sink endpoint that answers with the queued statuses, then 200.
+type recorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	if len(r.statuses) > 0 {
+		w.WriteHeader(r.statuses[0])
+		r.statuses = r.statuses[1:]
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func sinkConfig(url string) config.ForwardSinkConfig {
+	return config.ForwardSinkConfig{
+		Name:          "dd",
+		Type:          TypeDatadog,
+		URL:           url,
+		APIKey:        "key",
+		Headers:       []string{"X-Team=gpu"},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		QueueSize:     3,
+		Timeout:       5 * time.Second,
+		Retry:         config.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 1},
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestForwarderFilterAndBatching(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	cfg := sinkConfig(srv.URL)
+	cfg.Filter = "metric_name=DCGM_FI_DEV_GPU_TEMP;hostname=node-*"
+	f, err := New([]config.ForwardSinkConfig{cfg}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// 5 matching metrics into a queue of 3, plus one filtered out
+	metrics := make([]models.GPUMetric, 0, 6)
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, models.GPUMetric{Timestamp: t0, MetricName: "DCGM_FI_DEV_GPU_TEMP", UUID: "GPU-1", Hostname: "node-1", Value: float64(i)})
+	}
+	metrics = append(metrics, models.GPUMetric{Timestamp: t0, MetricName: "DCGM_FI_DEV_POWER_USAGE", UUID: "GPU-1", Hostname: "node-1"})
+	f.Forward(metrics)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.Run(ctx)
+		close(done)
+	}()
+
+	// The full batch is sent at once, the remainder only at shutdown
+	waitFor(t, "the full batch", func() bool { return rec.count() == 1 })
+	cancel()
+	<-done
+
+	st := f.Stats()[0]
+	if st.Forwarded != 3 || st.Filtered != 1 || st.Dropped != 2 || st.Failed != 0 || st.Queued != 0 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if rec.count() != 2 {
+		t.Fatalf("expected 2 requests, got %d", rec.count())
+	}
+	req := rec.requests[0]
+	if req.Header.Get("DD-API-KEY") != "key" || req.Header.Get("X-Team") != "gpu" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers: %v", req.Header)
+	}
+}
+
+func TestForwarderRetries(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusServiceUnavailable, http.StatusAccepted, http.StatusBadRequest}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	f, err := New([]config.ForwardSinkConfig{sinkConfig(srv.URL)}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Run(ctx)
+
+	batch := []models.GPUMetric{{Timestamp: t0, MetricName: "temp", UUID: "GPU-1"}, {Timestamp: t0, MetricName: "temp", UUID: "GPU-2"}}
+
+	// A 503 is retried and the batch delivered
+	f.Forward(batch)
+	waitFor(t, "the retried batch", func() bool { return f.Stats()[0].Forwarded == 2 })
+
+	// A 400 is permanent: one request, then the batch is counted as failed
+	f.Forward(batch)
+	waitFor(t, "the rejected batch", func() bool { return f.Stats()[0].Failed == 2 })
+	if rec.count() != 3 {
+		t.Errorf("expected 3 requests, got %d", rec.count())
+	}
+}
+
+func TestNewRejectsInvalidFilter(t *testing.T) {
+	cfg := sinkConfig("http://localhost")
+	cfg.Filter = "metric_name"
+	if _, err := New([]config.ForwardSinkConfig{cfg}, nil); err == nil {
+		t.Error("expected an error for an invalid filter")
+	}
+
+	var f *Forwarder
+	f.Forward(sampleMetrics()) // a nil forwarder discards
+	if f.Stats() != nil {
+		t.Error("expected no stats from a nil forwarder")
+	}
+}
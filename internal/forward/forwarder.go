@@ -0,0 +1,402 @@
+// Package forward pushes stored telemetry on to external observability
+// systems (Prometheus remote_write, Datadog, OTLP/HTTP). Each sink has its
+// own filter, queue, batching and retry policy, so a slow or unreachable
+// sink drops its own backlog without holding up storage or the other sinks.
+package forward
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// Sink types.
+const (
+	TypePrometheus = "prometheus"
+	TypeDatadog    = "datadog"
+	TypeOTLP       = "otlp"
+)
+
+// DatadogURL is the series intake used when a Datadog sink has no URL.
+const DatadogURL = "https://api.datadoghq.com/api/v2/series"
+
+// format is how one sink type encodes a batch of metrics.
+type format struct {
+	encode  func(metrics []models.GPUMetric) ([]byte, error)
+	headers map[string]string
+}
+
+var formats = map[string]format{
+	TypePrometheus: {encode: encodeRemoteWrite, headers: map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	}},
+	TypeDatadog: {encode: encodeDatadog, headers: map[string]string{
+		"Content-Type": "application/json",
+	}},
+	TypeOTLP: {encode: encodeOTLP, headers: map[string]string{
+		"Content-Type": "application/json",
+	}},
+}
+
+// Stats counts what one sink has done with the metrics offered to it.
+type Stats struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Forwarded counts metrics the sink accepted
+	Forwarded int64 `json:"forwarded"`
+
+	// Filtered counts metrics that did not match the sink's filter
+	Filtered int64 `json:"filtered"`
+
+	// Dropped counts metrics discarded because the queue was full
+	Dropped int64 `json:"dropped"`
+
+	// Failed counts metrics in requests that failed after all retries
+	Failed int64 `json:"failed"`
+
+	// Queued is the number of metrics waiting to be sent
+	Queued int `json:"queued"`
+}
+
+// Forwarder offers stored metrics to every configured sink. A nil
+// *Forwarder is valid and discards everything, so the collector can call
+// Forward unconditionally.
+type Forwarder struct {
+	sinks []*sink
+}
+
+// New creates a forwarder for the given sinks. It returns nil when none are
+// configured.
+func New(cfgs []config.ForwardSinkConfig, logger *log.Logger) (*Forwarder, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	f := &Forwarder{}
+	for _, cfg := range cfgs {
+		s, err := newSink(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		f.sinks = append(f.sinks, s)
+	}
+	return f, nil
+}
+
+// Forward queues metrics for every sink whose filter they match, without
+// blocking. Metrics that do not fit in a sink's queue are dropped and counted.
+func (f *Forwarder) Forward(metrics []models.GPUMetric) {
+	if f == nil || len(metrics) == 0 {
+		return
+	}
+
+	// Metadata is only built when some sink filters
+	var metadata []map[string]string
+	for _, s := range f.sinks {
+		if s.filter != nil && metadata == nil {
+			metadata = make([]map[string]string, len(metrics))
+			for i := range metrics {
+				metadata[i] = metricMetadata(&metrics[i])
+			}
+		}
+	}
+
+	for _, s := range f.sinks {
+		if s.filter == nil {
+			s.enqueue(metrics)
+			continue
+		}
+		matched := make([]models.GPUMetric, 0, len(metrics))
+		for i := range metrics {
+			if s.filter.Match(metadata[i]) {
+				matched = append(matched, metrics[i])
+			}
+		}
+		s.filtered.Add(int64(len(metrics) - len(matched)))
+		s.enqueue(matched)
+	}
+}
+
+// Run sends queued metrics until ctx is done, then makes a last attempt to
+// send what is still queued and returns.
+func (f *Forwarder) Run(ctx context.Context) {
+	if f == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, s := range f.sinks {
+		wg.Add(1)
+		go func(s *sink) {
+			defer wg.Done()
+			s.run(ctx)
+		}(s)
+	}
+	wg.Wait()
+}
+
+// Stats returns the counters of every sink, in configuration order.
+func (f *Forwarder) Stats() []Stats {
+	if f == nil {
+		return nil
+	}
+	stats := make([]Stats, len(f.sinks))
+	for i, s := range f.sinks {
+		stats[i] = s.stats()
+	}
+	return stats
+}
+
+// metricMetadata describes a metric with the keys a sink filter matches on:
+// the batch metadata keys, the other identity fields and the metric's labels.
+func metricMetadata(m *models.GPUMetric) map[string]string {
+	md := make(map[string]string, len(m.Labels)+9)
+	for k, v := range m.Labels {
+		md[k] = v
+	}
+	md[mq.MetaHostname] = m.Hostname
+	md[mq.MetaMetricName] = m.MetricName
+	md["uuid"] = m.UUID
+	md["gpu_id"] = strconv.Itoa(m.GPUID)
+	md["model_name"] = m.ModelName
+	md["device"] = m.Device
+	md["pod"] = m.Pod
+	md["namespace"] = m.Namespace
+	md["container"] = m.Container
+	return md
+}
+
+// sink queues and sends metrics to one external system.
+type sink struct {
+	name      string
+	kind      string
+	url       string
+	filter    *mq.Filter
+	format    format
+	header    http.Header
+	batchSize int
+	queueSize int
+	interval  time.Duration
+	timeout   time.Duration
+	retry     retry.Policy
+	client    *http.Client
+	logger    *log.Logger
+
+	mu    sync.Mutex
+	queue []models.GPUMetric
+	ready chan struct{} // signalled when a full batch is queued
+
+	forwarded atomic.Int64
+	filtered  atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+func newSink(cfg config.ForwardSinkConfig, logger *log.Logger) (*sink, error) {
+	fmtr, ok := formats[cfg.Type]
+	if !ok {
+		return nil, perrors.Validation(fmt.Errorf("forward sink %s: unknown type %q", cfg.Name, cfg.Type))
+	}
+	filter, err := mq.ParseFilter(cfg.Filter)
+	if err != nil {
+		return nil, perrors.Validation(fmt.Errorf("forward sink %s: %w", cfg.Name, err))
+	}
+
+	url := cfg.URL
+	header := make(http.Header)
+	for k, v := range fmtr.headers {
+		header.Set(k, v)
+	}
+	if cfg.Type == TypeDatadog {
+		if url == "" {
+			url = DatadogURL
+		}
+		header.Set("DD-API-KEY", cfg.APIKey)
+	}
+	for _, h := range cfg.Headers {
+		k, v, _ := strings.Cut(h, "=")
+		header.Set(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+
+	s := &sink{
+		name:      cfg.Name,
+		kind:      cfg.Type,
+		url:       url,
+		filter:    filter,
+		format:    fmtr,
+		header:    header,
+		batchSize: cfg.BatchSize,
+		queueSize: cfg.QueueSize,
+		interval:  cfg.FlushInterval,
+		timeout:   cfg.Timeout,
+		retry:     retry.FromConfig("forward-"+cfg.Name, cfg.Retry),
+		client:    &http.Client{},
+		logger:    logger,
+		ready:     make(chan struct{}, 1),
+	}
+	s.retry.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("Forward to %s attempt %d failed, retrying in %v: %v", s.name, attempt, wait, err)
+	}
+	return s, nil
+}
+
+// enqueue adds metrics to the queue, dropping those that do not fit.
+func (s *sink) enqueue(metrics []models.GPUMetric) {
+	if len(metrics) == 0 {
+		return
+	}
+	s.mu.Lock()
+	if room := s.queueSize - len(s.queue); room < len(metrics) {
+		lost := int64(len(metrics) - room)
+		if s.dropped.Add(lost) == lost {
+			s.logger.Printf("Forward queue for %s is full, dropping metrics", s.name)
+		}
+		metrics = metrics[:room]
+	}
+	s.queue = append(s.queue, metrics...)
+	full := len(s.queue) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// take removes up to n metrics from the front of the queue.
+func (s *sink) take(n int) []models.GPUMetric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n = min(n, len(s.queue))
+	if n == 0 {
+		return nil
+	}
+	batch := make([]models.GPUMetric, n)
+	copy(batch, s.queue)
+	s.queue = append(s.queue[:0], s.queue[n:]...)
+	return batch
+}
+
+// queued returns the number of metrics waiting to be sent.
+func (s *sink) queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// run sends full batches as they fill and everything queued every interval.
+func (s *sink) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Last attempt on a fresh context, without retries, since ctx is already cancelled
+			flushCtx, cancel := context.WithTimeout(context.Background(), s.timeout)
+			for batch := s.take(s.batchSize); batch != nil && flushCtx.Err() == nil; batch = s.take(s.batchSize) {
+				s.send(flushCtx, batch, false)
+			}
+			cancel()
+			return
+
+		case <-s.ready:
+			for s.queued() >= s.batchSize && ctx.Err() == nil {
+				s.send(ctx, s.take(s.batchSize), true)
+			}
+
+		case <-ticker.C:
+			for batch := s.take(s.batchSize); batch != nil && ctx.Err() == nil; batch = s.take(s.batchSize) {
+				s.send(ctx, batch, true)
+			}
+		}
+	}
+}
+
+// send encodes and posts one batch, retrying transient failures when retry is set.
+func (s *sink) send(ctx context.Context, batch []models.GPUMetric, withRetry bool) {
+	body, err := s.format.encode(batch)
+	if err != nil {
+		s.failed.Add(int64(len(batch)))
+		s.logger.Printf("Could not encode %d metrics for %s: %v", len(batch), s.name, err)
+		return
+	}
+
+	attempt := func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+		return s.post(attemptCtx, body)
+	}
+	if withRetry {
+		err = s.retry.Do(ctx, attempt)
+	} else {
+		err = attempt(ctx)
+	}
+	if err != nil {
+		s.failed.Add(int64(len(batch)))
+		s.logger.Printf("Forwarding %d metrics to %s failed: %v", len(batch), s.name, err)
+		return
+	}
+	s.forwarded.Add(int64(len(batch)))
+}
+
+// post makes a single request. Throttling and server errors are transient;
+// any other rejection is permanent, since resending the same body will not help.
+func (s *sink) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return perrors.Permanent(err)
+	}
+	req.Header = s.header.Clone()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return perrors.Transient(fmt.Errorf("%s request failed: %w", s.kind, err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("%s returned HTTP %d: %s", s.kind, resp.StatusCode, strings.TrimSpace(string(msg)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return perrors.Transient(err)
+	}
+	return perrors.Permanent(err)
+}
+
+// stats returns the sink's counters.
+func (s *sink) stats() Stats {
+	return Stats{
+		Name:      s.name,
+		Type:      s.kind,
+		Forwarded: s.forwarded.Load(),
+		Filtered:  s.filtered.Load(),
+		Dropped:   s.dropped.Load(),
+		Failed:    s.failed.Load(),
+		Queued:    s.queued(),
+	}
+}
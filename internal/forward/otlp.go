@@ -0,0 +1,146 @@
+package forward
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// scopeName identifies the pipeline as the instrumentation scope of
+// forwarded OTLP metrics.
+const scopeName = "gpu-telemetry-pipeline"
+
+// The types below are the subset of the OTLP/HTTP JSON encoding of an
+// ExportMetricsServiceRequest that forwarded gauges need.
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name  string    `json:"name"`
+	Gauge otlpGauge `json:"gauge"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    string  `json:"intValue,omitempty"`
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+// otlpResourceAttributes returns the resource a metric belongs to, in
+// OpenTelemetry semantic convention names.
+func otlpResourceAttributes(m *models.GPUMetric) []otlpKeyValue {
+	var attrs []otlpKeyValue
+	for _, a := range [][2]string{
+		{"host.name", m.Hostname},
+		{"k8s.namespace.name", m.Namespace},
+		{"k8s.pod.name", m.Pod},
+		{"k8s.container.name", m.Container},
+	} {
+		if a[1] != "" {
+			attrs = append(attrs, otlpString(a[0], a[1]))
+		}
+	}
+	return attrs
+}
+
+// otlpPointAttributes returns the GPU identity and labels of a data point.
+func otlpPointAttributes(m *models.GPUMetric) []otlpKeyValue {
+	attrs := []otlpKeyValue{
+		otlpString("gpu.uuid", m.UUID),
+		{Key: "gpu.index", Value: otlpAnyValue{IntValue: strconv.Itoa(m.GPUID)}},
+	}
+	if m.ModelName != "" {
+		attrs = append(attrs, otlpString("gpu.model", m.ModelName))
+	}
+	if m.Device != "" {
+		attrs = append(attrs, otlpString("gpu.device", m.Device))
+	}
+	keys := make([]string, 0, len(m.Labels))
+	for k := range m.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, otlpString(k, m.Labels[k]))
+	}
+	return attrs
+}
+
+// encodeOTLP encodes an OTLP/HTTP JSON export request with one resource per
+// host and pod, and every metric as a gauge.
+func encodeOTLP(metrics []models.GPUMetric) ([]byte, error) {
+	type resourceKey struct{ host, namespace, pod, container string }
+	var req otlpRequest
+	resources := make(map[resourceKey]int)
+	metricIndex := make(map[resourceKey]map[string]int)
+
+	for i := range metrics {
+		m := &metrics[i]
+		rk := resourceKey{m.Hostname, m.Namespace, m.Pod, m.Container}
+		ri, ok := resources[rk]
+		if !ok {
+			ri = len(req.ResourceMetrics)
+			resources[rk] = ri
+			metricIndex[rk] = make(map[string]int)
+			req.ResourceMetrics = append(req.ResourceMetrics, otlpResourceMetrics{
+				Resource:     otlpResource{Attributes: otlpResourceAttributes(m)},
+				ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: scopeName}}},
+			})
+		}
+
+		scope := &req.ResourceMetrics[ri].ScopeMetrics[0]
+		mi, ok := metricIndex[rk][m.MetricName]
+		if !ok {
+			mi = len(scope.Metrics)
+			metricIndex[rk][m.MetricName] = mi
+			scope.Metrics = append(scope.Metrics, otlpMetric{Name: m.MetricName})
+		}
+		gauge := &scope.Metrics[mi].Gauge
+		gauge.DataPoints = append(gauge.DataPoints, otlpDataPoint{
+			Attributes:   otlpPointAttributes(m),
+			TimeUnixNano: strconv.FormatInt(m.Timestamp.UnixNano(), 10),
+			AsDouble:     m.Value,
+		})
+	}
+	return json.Marshal(req)
+}
@@ -0,0 +1,155 @@
+package forward
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// label is one Prometheus label.
+type label struct {
+	name, value string
+}
+
+// series is one time series and its samples.
+type series struct {
+	labels  []label
+	samples []models.GPUMetric
+}
+
+// promLabels returns the labels of a metric's series, sorted by name as
+// remote_write requires. Identity labels use dcgm-exporter's names so
+// forwarded series line up with scraped ones in existing dashboards.
+func promLabels(m *models.GPUMetric) []label {
+	labels := []label{{"__name__", sanitizeName(m.MetricName, true)}}
+	identity := map[string]string{
+		"UUID":      m.UUID,
+		"gpu":       strconv.Itoa(m.GPUID),
+		"Hostname":  m.Hostname,
+		"modelName": m.ModelName,
+		"device":    m.Device,
+		"container": m.Container,
+		"pod":       m.Pod,
+		"namespace": m.Namespace,
+	}
+	for name, value := range identity {
+		if value != "" {
+			labels = append(labels, label{name, value})
+		}
+	}
+	for k, v := range m.Labels {
+		name := sanitizeName(k, false)
+		if _, ok := identity[name]; ok || name == "__name__" || v == "" {
+			continue
+		}
+		labels = append(labels, label{name, v})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+	return labels
+}
+
+// sanitizeName replaces characters Prometheus does not allow in metric
+// (letters, digits, '_' and ':') or label names (no ':') with '_'.
+func sanitizeName(name string, metric bool) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':' && metric:
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// groupSeries groups metrics into series, in order of first appearance, with
+// each series' samples sorted by time.
+func groupSeries(metrics []models.GPUMetric) []*series {
+	var out []*series
+	index := make(map[string]*series)
+	var key strings.Builder
+	for i := range metrics {
+		labels := promLabels(&metrics[i])
+		key.Reset()
+		for _, l := range labels {
+			key.WriteString(l.name)
+			key.WriteByte(0)
+			key.WriteString(l.value)
+			key.WriteByte(0)
+		}
+		s, ok := index[key.String()]
+		if !ok {
+			s = &series{labels: labels}
+			index[key.String()] = s
+			out = append(out, s)
+		}
+		s.samples = append(s.samples, metrics[i])
+	}
+	for _, s := range out {
+		sort.SliceStable(s.samples, func(i, j int) bool { return s.samples[i].Timestamp.Before(s.samples[j].Timestamp) })
+	}
+	return out
+}
+
+// encodeRemoteWrite encodes a snappy-compressed prometheus.WriteRequest.
+func encodeRemoteWrite(metrics []models.GPUMetric) ([]byte, error) {
+	var req, ts, msg []byte
+	for _, s := range groupSeries(metrics) {
+		ts = ts[:0]
+		for _, l := range s.labels {
+			msg = appendBytesField(msg[:0], 1, []byte(l.name))
+			msg = appendBytesField(msg, 2, []byte(l.value))
+			ts = appendBytesField(ts, 1, msg)
+		}
+		for _, m := range s.samples {
+			msg = appendFixed64Field(msg[:0], 1, math.Float64bits(m.Value))
+			msg = appendVarintField(msg, 2, uint64(m.Timestamp.UnixMilli()))
+			ts = appendBytesField(ts, 2, msg)
+		}
+		req = appendBytesField(req, 1, ts)
+	}
+	return s2.EncodeSnappy(nil, req), nil
+}
+
+// Protobuf wire types used by the remote_write messages.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// appendTag appends a field key.
+func appendTag(b []byte, num, typ int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(typ))
+}
+
+// appendVarintField appends a varint field.
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	b = appendTag(b, num, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+// appendFixed64Field appends a fixed64 field.
+func appendFixed64Field(b []byte, num int, v uint64) []byte {
+	b = appendTag(b, num, wireFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+// appendBytesField appends a length-delimited field.
+func appendBytesField(b []byte, num int, v []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
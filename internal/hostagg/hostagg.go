@@ -0,0 +1,117 @@
+// Package hostagg computes host-level aggregates of GPU metrics at ingest,
+// such as the power a node draws across its GPUs or their mean
+// utilization. The collector stores them as synthetic metrics carrying the
+// hostname but no GPU, so node-level dashboards read one series per host
+// instead of fanning out over every GPU.
+package hostagg
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Name returns the metric name an aggregate is stored under, e.g.
+// HOST_SUM_DCGM_FI_DEV_POWER_USAGE.
+func Name(a config.HostAggregate) string {
+	return "HOST_" + strings.ToUpper(a.Func) + "_" + a.Metric
+}
+
+// Aggregator computes the configured aggregates from batches of metrics.
+type Aggregator struct {
+	byMetric map[string][]config.HostAggregate
+}
+
+// New creates an aggregator computing aggregates.
+func New(aggregates []config.HostAggregate) *Aggregator {
+	byMetric := make(map[string][]config.HostAggregate)
+	for _, a := range aggregates {
+		byMetric[a.Metric] = append(byMetric[a.Metric], a)
+	}
+	return &Aggregator{byMetric: byMetric}
+}
+
+// group is the latest sample of one metric from each GPU of a host.
+type group struct {
+	hostname string
+	metric   string
+	latest   map[string]*models.GPUMetric
+}
+
+// Aggregate returns the aggregates of metrics, one per host and configured
+// aggregate. Each combines the latest sample in metrics of every GPU on
+// the host, is timestamped with the newest of them and carries the model
+// when all the GPUs share it. Late metrics are left out.
+func (a *Aggregator) Aggregate(metrics []*models.GPUMetric) []*models.GPUMetric {
+	if len(a.byMetric) == 0 {
+		return nil
+	}
+
+	type key struct{ hostname, metric string }
+	groups := make(map[key]*group)
+	var order []*group
+	for _, m := range metrics {
+		if m.Late || m.UUID == "" || m.Hostname == "" || len(a.byMetric[m.MetricName]) == 0 {
+			continue
+		}
+		k := key{m.Hostname, m.MetricName}
+		g, ok := groups[k]
+		if !ok {
+			g = &group{hostname: m.Hostname, metric: m.MetricName, latest: make(map[string]*models.GPUMetric)}
+			groups[k] = g
+			order = append(order, g)
+		}
+		if prev, ok := g.latest[m.UUID]; !ok || m.Timestamp.After(prev.Timestamp) {
+			g.latest[m.UUID] = m
+		}
+	}
+
+	var out []*models.GPUMetric
+	for _, g := range order {
+		for _, agg := range a.byMetric[g.metric] {
+			out = append(out, g.aggregate(agg))
+		}
+	}
+	return out
+}
+
+func (g *group) aggregate(agg config.HostAggregate) *models.GPUMetric {
+	var sum float64
+	lo, hi := math.Inf(1), math.Inf(-1)
+	var newest time.Time
+	var model, batchID string
+	first := true
+	for _, m := range g.latest {
+		sum += m.Value
+		lo, hi = math.Min(lo, m.Value), math.Max(hi, m.Value)
+		if m.Timestamp.After(newest) {
+			newest, batchID = m.Timestamp, m.BatchID
+		}
+		if first {
+			model, first = m.ModelName, false
+		} else if m.ModelName != model {
+			model = ""
+		}
+	}
+
+	value := sum
+	switch agg.Func {
+	case config.AggregateMean:
+		value = sum / float64(len(g.latest))
+	case config.AggregateMin:
+		value = lo
+	case config.AggregateMax:
+		value = hi
+	}
+	return &models.GPUMetric{
+		Timestamp:  newest,
+		MetricName: Name(agg),
+		ModelName:  model,
+		Hostname:   g.hostname,
+		Value:      value,
+		BatchID:    batchID,
+	}
+}
@@ -0,0 +1,62 @@
+package hostagg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func TestAggregate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	power := func(uuid, host string, value float64, age time.Duration) *models.GPUMetric {
+		return &models.GPUMetric{UUID: uuid, Hostname: host, ModelName: "H100", MetricName: "DCGM_FI_DEV_POWER_USAGE", Value: value, Timestamp: now.Add(-age)}
+	}
+	metrics := []*models.GPUMetric{
+		power("GPU-1", "host-1", 100, 10*time.Second),
+		power("GPU-1", "host-1", 300, 0), // supersedes the older sample
+		power("GPU-2", "host-1", 200, 5*time.Second),
+		power("GPU-3", "host-2", 50, 0),
+		{UUID: "GPU-1", Hostname: "host-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 70, Timestamp: now},
+	}
+	late := power("GPU-4", "host-2", 1000, time.Hour)
+	late.Late = true
+	metrics = append(metrics, late)
+
+	a := New([]config.HostAggregate{
+		{Func: config.AggregateSum, Metric: "DCGM_FI_DEV_POWER_USAGE"},
+		{Func: config.AggregateMax, Metric: "DCGM_FI_DEV_POWER_USAGE"},
+	})
+	got := a.Aggregate(metrics)
+	if len(got) != 4 {
+		t.Fatalf("expected 2 aggregates for each of 2 hosts, got %d", len(got))
+	}
+
+	want := []struct {
+		name, host string
+		value      float64
+	}{
+		{"HOST_SUM_DCGM_FI_DEV_POWER_USAGE", "host-1", 500},
+		{"HOST_MAX_DCGM_FI_DEV_POWER_USAGE", "host-1", 300},
+		{"HOST_SUM_DCGM_FI_DEV_POWER_USAGE", "host-2", 50},
+		{"HOST_MAX_DCGM_FI_DEV_POWER_USAGE", "host-2", 50},
+	}
+	for i, w := range want {
+		if got[i].MetricName != w.name || got[i].Hostname != w.host || got[i].Value != w.value || got[i].UUID != "" {
+			t.Errorf("aggregate %d: expected %s on %s = %v, got %+v", i, w.name, w.host, w.value, got[i])
+		}
+	}
+	if !got[0].Timestamp.Equal(now) || got[0].ModelName != "H100" {
+		t.Errorf("expected the newest timestamp and the shared model, got %+v", got[0])
+	}
+
+	mean := New([]config.HostAggregate{{Func: config.AggregateMean, Metric: "DCGM_FI_DEV_POWER_USAGE"}}).Aggregate(metrics[:3])
+	if len(mean) != 1 || mean[0].Value != 250 {
+		t.Errorf("expected a mean of 250, got %+v", mean)
+	}
+
+	if got := New(nil).Aggregate(metrics); got != nil {
+		t.Errorf("expected no aggregates when none are configured, got %v", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Package ingeststats counts what a collector ingests from each streamer
+// instance, by the hour it was received: batches, metrics and bytes
+// accepted, batches rejected and metrics dropped. The counts are taken
+// periodically and written to storage, where the API sums every
+// collector's counts for /api/v1/ingest/stats.
+package ingeststats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+type key struct {
+	source string
+	hour   time.Time
+}
+
+// Recorder counts ingest per streamer and hour until the counts are taken.
+// It is safe for concurrent use; a nil Recorder counts nothing.
+type Recorder struct {
+	mu      sync.Mutex
+	pending map[key]*models.IngestStats
+}
+
+// New creates an empty recorder.
+func New() *Recorder {
+	return &Recorder{pending: make(map[key]*models.IngestStats)}
+}
+
+// Batch counts a batch from source that was stored, holding metrics
+// metrics and size bytes as received.
+func (r *Recorder) Batch(source string, metrics, size int, now time.Time) {
+	r.add(source, now, &models.IngestStats{Batches: 1, Metrics: int64(metrics), Bytes: int64(size)})
+}
+
+// Rejected counts a batch refused whole. Batches that could not be decoded
+// are counted under models.IngestUnknownSource.
+func (r *Recorder) Rejected(source string, now time.Time) {
+	r.add(source, now, &models.IngestStats{RejectedBatches: 1})
+}
+
+// Dropped counts n metrics from source that were not stored.
+func (r *Recorder) Dropped(source string, n int, now time.Time) {
+	if n > 0 {
+		r.add(source, now, &models.IngestStats{DroppedMetrics: int64(n)})
+	}
+}
+
+func (r *Recorder) add(source string, now time.Time, delta *models.IngestStats) {
+	if r == nil {
+		return
+	}
+	if source == "" {
+		source = models.IngestUnknownSource
+	}
+	k := key{source: source, hour: now.UTC().Truncate(time.Hour)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.pending[k]
+	if st == nil {
+		st = &models.IngestStats{Source: k.source, Hour: k.hour}
+		r.pending[k] = st
+	}
+	st.Add(delta)
+}
+
+// Take returns the counts since the previous Take, sorted by hour and
+// source, and starts counting afresh.
+func (r *Recorder) Take() []*models.IngestStats {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[key]*models.IngestStats)
+	r.mu.Unlock()
+
+	stats := make([]*models.IngestStats, 0, len(pending))
+	for _, st := range pending {
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if !stats[i].Hour.Equal(stats[j].Hour) {
+			return stats[i].Hour.Before(stats[j].Hour)
+		}
+		return stats[i].Source < stats[j].Source
+	})
+	return stats
+}
+
+// Restore adds back counts returned by Take that could not be written, so
+// the next Take includes them.
+func (r *Recorder) Restore(stats []*models.IngestStats) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, st := range stats {
+		k := key{source: st.Source, hour: st.Hour}
+		if cur := r.pending[k]; cur != nil {
+			cur.Add(st)
+		} else {
+			r.pending[k] = st
+		}
+	}
+}
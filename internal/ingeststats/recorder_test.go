@@ -0,0 +1,74 @@
+package ingeststats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func TestRecorderCountsBySourceAndHour(t *testing.T) {
+	r := New()
+	t0 := time.Date(2024, 1, 1, 10, 59, 0, 0, time.UTC)
+
+	r.Batch("streamer-1", 100, 2048, t0)
+	r.Batch("streamer-1", 50, 1024, t0)
+	r.Dropped("streamer-1", 5, t0)
+	r.Dropped("streamer-1", 0, t0)
+	r.Rejected("", t0)
+	r.Batch("streamer-0", 10, 200, t0.Add(2*time.Minute))
+
+	stats := r.Take()
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(stats))
+	}
+	hour := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	if stats[0].Source != "streamer-1" || !stats[0].Hour.Equal(hour) {
+		t.Errorf("expected streamer-1 at 10:00 first, got %s at %s", stats[0].Source, stats[0].Hour)
+	}
+	if stats[1].Source != models.IngestUnknownSource {
+		t.Errorf("expected an unnamed source to be counted as %q, got %q", models.IngestUnknownSource, stats[1].Source)
+	}
+	if stats[2].Source != "streamer-0" || !stats[2].Hour.Equal(hour.Add(time.Hour)) {
+		t.Errorf("expected streamer-0 at 11:00 last, got %s at %s", stats[2].Source, stats[2].Hour)
+	}
+
+	st := stats[0]
+	if st.Batches != 2 || st.Metrics != 150 || st.Bytes != 3072 || st.DroppedMetrics != 5 {
+		t.Errorf("unexpected counts: %+v", st)
+	}
+	if st.AvgBatchSize != 75 {
+		t.Errorf("expected average batch size 75, got %v", st.AvgBatchSize)
+	}
+	if stats[1].RejectedBatches != 1 || stats[1].AvgBatchSize != 0 {
+		t.Errorf("unexpected rejected counts: %+v", stats[1])
+	}
+
+	if stats := r.Take(); len(stats) != 0 {
+		t.Errorf("expected nothing after a take, got %d rows", len(stats))
+	}
+}
+
+func TestRecorderRestore(t *testing.T) {
+	r := New()
+	t0 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	r.Batch("streamer-0", 100, 1000, t0)
+	failed := r.Take()
+	r.Batch("streamer-0", 300, 3000, t0)
+	r.Restore(failed)
+
+	stats := r.Take()
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(stats))
+	}
+	if stats[0].Batches != 2 || stats[0].Metrics != 400 || stats[0].AvgBatchSize != 200 {
+		t.Errorf("expected restored counts to be merged, got %+v", stats[0])
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	r.Batch("streamer-0", 1, 1, time.Now())
+	r.Restore(r.Take())
+}
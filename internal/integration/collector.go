@@ -0,0 +1,181 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Collector consumes the harness's MQ and stores batches the way the
+// collector service does. It can be stopped gracefully, committing its
+// position, or crashed, and started again under the same ID.
+type Collector struct {
+	h  *Harness
+	id string
+
+	mu      sync.Mutex
+	changed *sync.Cond
+	client  *mq.Client // nil while stopped
+	base    mq.Offset  // offset the current run started from
+	handled map[mq.Offset]bool
+}
+
+// NewCollector creates a stopped collector subscribing as id.
+func (h *Harness) NewCollector(id string) *Collector {
+	c := &Collector{h: h, id: id}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Start subscribes from the collector's committed offset, or from the
+// start of the log if it has never committed, so nothing published while it
+// was down is skipped.
+func (c *Collector) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return fmt.Errorf("collector %s is already running", c.id)
+	}
+
+	start := mq.OffsetEarliest
+	c.base = 0
+	if info, err := c.h.server.GetQueue().GetOffsetInfo(c.id); err == nil && info.Committed > 0 {
+		// A numeric 0 means "latest" on the wire, so only positive offsets are sent as is
+		start, c.base = info.Committed, info.Committed
+	} else if err != nil && !errors.Is(err, mq.ErrSubscriberNotFound) {
+		return err
+	}
+
+	client, err := c.h.NewClient()
+	if err != nil {
+		return err
+	}
+	c.handled = make(map[mq.Offset]bool)
+	if err := client.Subscribe(ctx, c.id, start, c.handle); err != nil {
+		client.Close()
+		return err
+	}
+	c.client = client
+	return nil
+}
+
+// handle stores a delivered batch and its lineage.
+func (c *Collector) handle(ctx context.Context, msg *mq.Message) error {
+	defer c.markHandled(msg.Offset)
+
+	if msg.Metadata[mq.MetaRecordCount] == "0" {
+		return nil
+	}
+	receivedAt := time.Now()
+
+	var batch models.MetricBatch
+	if err := json.Unmarshal(msg.Payload, &batch); err != nil {
+		c.h.logger.Printf("Collector %s: dropping undecodable message at offset %d: %v", c.id, msg.Offset, err)
+		return err
+	}
+
+	metrics := make([]*models.GPUMetric, len(batch.Metrics))
+	for i := range batch.Metrics {
+		batch.Metrics[i].BatchID = batch.BatchID
+		metrics[i] = &batch.Metrics[i]
+	}
+	if err := c.h.Store.StoreBatch(ctx, metrics); err != nil {
+		c.h.logger.Printf("Collector %s: error storing batch %s: %v", c.id, batch.BatchID, err)
+		return err
+	}
+
+	lineage := models.NewBatchLineage(&batch)
+	lineage.Collector = c.id
+	lineage.MQOffset = int64(msg.Offset)
+	lineage.PublishedAt = msg.Timestamp
+	lineage.ReceivedAt = receivedAt
+	lineage.Replayed = msg.Metadata[mq.MetaReplayOf] != ""
+	lineage.StoredAt = time.Now()
+	return c.h.Store.RecordBatch(ctx, lineage)
+}
+
+// markHandled records that the message at offset was processed.
+func (c *Collector) markHandled(offset mq.Offset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handled != nil {
+		c.handled[offset] = true
+		c.changed.Broadcast()
+	}
+}
+
+// Stop waits for every message delivered so far to be stored, commits the
+// position after them and unsubscribes, like a graceful shutdown.
+func (c *Collector) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return nil
+	}
+
+	info, err := client.GetOffset(ctx, c.id)
+	if err != nil {
+		return err
+	}
+	if err := c.waitHandled(ctx, info.Current); err != nil {
+		return err
+	}
+	if err := client.CommitOffset(ctx, c.id, info.Current); err != nil {
+		return err
+	}
+	if err := client.Unsubscribe(ctx, c.id); err != nil {
+		return err
+	}
+	return c.close()
+}
+
+// waitHandled waits until every offset from the run's start up to next has
+// been handled.
+func (c *Collector) waitHandled(ctx context.Context, next mq.Offset) error {
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.changed.Broadcast()
+	})
+	defer stop()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for o := c.base; o < next; {
+		if c.handled[o] {
+			o++
+			continue
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("collector %s: offset %d not stored: %w", c.id, o, ctx.Err())
+		}
+		c.changed.Wait()
+	}
+	return nil
+}
+
+// Crash drops the connection without committing or unsubscribing, as if
+// the process died. Batches being stored at that moment may still land.
+func (c *Collector) Crash() {
+	c.close()
+}
+
+// close disconnects the collector's client.
+func (c *Collector) close() error {
+	c.mu.Lock()
+	client := c.client
+	c.client = nil
+	c.handled = nil
+	c.mu.Unlock()
+	if client == nil {
+		return nil
+	}
+	return client.Close()
+}
@@ -0,0 +1,297 @@
+// Package integration runs the pipeline end to end inside one process for
+// scenario tests. A Harness starts an MQ server on loopback, collectors and
+// streamers that talk to it over TCP like the real services, and the API
+// router over the same store, then checks that every batch the MQ
+// acknowledged was stored. The store defaults to MemoryStore; any backend
+// that also keeps lineage, such as InfluxDB started with docker compose,
+// can be supplied instead.
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/handlers"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/replay"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Store is the storage a harness runs against: collectors write metrics and
+// lineage to it, and the API, replay and loss checks read from it.
+type Store interface {
+	storage.Storage
+	storage.LineageRecorder
+	storage.LineageReader
+}
+
+// storeCounter is implemented by stores that count how many times each
+// batch was stored, which lets a LossReport include duplicates.
+type storeCounter interface {
+	StoredCounts() map[string]int
+}
+
+// Config configures a Harness.
+type Config struct {
+	// Store is the storage backend (default: a new MemoryStore)
+	Store Store
+
+	// Clock times the default store's retention cleanup (default: clock.Real)
+	Clock clock.Clock
+
+	// Logger receives the components' logs (default: discarded)
+	Logger *log.Logger
+}
+
+// Harness is a running in-process pipeline.
+type Harness struct {
+	// Store is the storage collectors write to and the API reads from
+	Store Store
+
+	server *mq.Server
+	api    *httptest.Server
+	logger *log.Logger
+
+	mu        sync.Mutex
+	published map[string]bool // IDs of batches the MQ acknowledged
+	clients   []*mq.Client    // closed with the harness
+}
+
+// Start starts the MQ server and API on loopback ports chosen by the OS.
+func Start(cfg Config) (*Harness, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = log.New(io.Discard, "", 0)
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore(cfg.Clock)
+	}
+
+	server := mq.NewServer(mq.ServerConfig{
+		TCPHost:  "127.0.0.1",
+		HTTPHost: "127.0.0.1",
+		Queue:    mq.DefaultQueueConfig(),
+	}, cfg.Logger)
+	if err := server.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MQ server: %w", err)
+	}
+
+	h := &Harness{
+		Store:     cfg.Store,
+		server:    server,
+		logger:    cfg.Logger,
+		published: make(map[string]bool),
+	}
+
+	// The API's reingest endpoint replays through its own client
+	replayClient, err := h.NewClient()
+	if err != nil {
+		server.Stop(context.Background())
+		return nil, fmt.Errorf("failed to connect to MQ server: %w", err)
+	}
+
+	routerCfg := api.DefaultRouterConfig()
+	routerCfg.MaxLimit = 1000000
+	routerCfg.Replayer = replay.New(h.Store, replayClient, 1000, cfg.Logger)
+	h.api = httptest.NewServer(api.NewRouter(h.Store, routerCfg))
+	return h, nil
+}
+
+// Close stops the API, every client the harness created and the MQ server.
+func (h *Harness) Close() error {
+	h.api.Close()
+
+	h.mu.Lock()
+	clients := h.clients
+	h.clients = nil
+	h.mu.Unlock()
+	for _, c := range clients {
+		c.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return h.server.Stop(ctx)
+}
+
+// MQPort returns the port the MQ server accepts clients on.
+func (h *Harness) MQPort() int {
+	return h.server.TCPAddr().(*net.TCPAddr).Port
+}
+
+// APIURL returns the base URL of the API, such as http://127.0.0.1:1234.
+func (h *Harness) APIURL() string {
+	return h.api.URL
+}
+
+// NewClient returns an MQ client connected to the harness. The harness
+// closes it on Close unless the caller does first.
+func (h *Harness) NewClient() (*mq.Client, error) {
+	client := mq.NewClient(mq.ClientConfig{
+		Host:    "127.0.0.1",
+		Port:    h.MQPort(),
+		Timeout: 5 * time.Second,
+	})
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	h.clients = append(h.clients, client)
+	h.mu.Unlock()
+	return client, nil
+}
+
+// acknowledged records a batch the MQ confirmed.
+func (h *Harness) acknowledged(batchID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.published[batchID] = true
+}
+
+// Backlog returns how many messages a collector has yet to read: all of
+// them if it has never subscribed, and those after its committed offset
+// while it is stopped.
+func (h *Harness) Backlog(ctx context.Context, collectorID string) (int64, error) {
+	total := int64(h.server.GetQueue().Len())
+	info, err := h.server.GetQueue().GetOffsetInfo(collectorID)
+	if errors.Is(err, mq.ErrSubscriberNotFound) {
+		return total, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	next := info.Committed
+	if info.Active {
+		next = info.Current
+	}
+	if next < 0 {
+		next = 0
+	}
+	return total - int64(next), nil
+}
+
+// Replay re-ingests batches the way the API's admin reingest endpoint does.
+func (h *Harness) Replay(ctx context.Context, req *models.ReplayRequest) (*models.ReplayResult, error) {
+	client, err := h.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return replay.New(h.Store, client, 1000, h.logger).Replay(ctx, req)
+}
+
+// TelemetryCount returns how many points the API serves for a GPU.
+func (h *Harness) TelemetryCount(ctx context.Context, uuid string) (int, error) {
+	url := fmt.Sprintf("%s/api/v1/gpus/%s/telemetry?limit=1000000", h.api.URL, uuid)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	var body handlers.TelemetryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	return body.Count, nil
+}
+
+// LossReport compares the batches the MQ acknowledged with those stored.
+type LossReport struct {
+	// Published is how many batches the MQ acknowledged
+	Published int `json:"published"`
+
+	// Stored is how many of those were stored at least once
+	Stored int `json:"stored"`
+
+	// Duplicates is how many extra times acknowledged batches were stored,
+	// from redelivery after a crash or replay; zero when the store cannot tell
+	Duplicates int `json:"duplicates"`
+
+	// Missing lists acknowledged batches that were never stored
+	Missing []string `json:"missing,omitempty"`
+}
+
+// Lossless reports whether every acknowledged batch was stored.
+func (r *LossReport) Lossless() bool {
+	return len(r.Missing) == 0
+}
+
+// Verify checks every acknowledged batch against the store's lineage.
+func (h *Harness) Verify(ctx context.Context) (*LossReport, error) {
+	h.mu.Lock()
+	ids := make([]string, 0, len(h.published))
+	for id := range h.published {
+		ids = append(ids, id)
+	}
+	h.mu.Unlock()
+	sort.Strings(ids)
+
+	var counts map[string]int
+	if c, ok := h.Store.(storeCounter); ok {
+		counts = c.StoredCounts()
+	}
+
+	report := &LossReport{Published: len(ids)}
+	for _, id := range ids {
+		_, err := h.Store.GetBatch(ctx, id)
+		if perrors.IsNotFound(err) {
+			report.Missing = append(report.Missing, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.Stored++
+		if n := counts[id]; n > 1 {
+			report.Duplicates += n - 1
+		}
+	}
+	return report, nil
+}
+
+// WaitLossless polls Verify until every acknowledged batch is stored or
+// ctx is done, returning the last report and, on timeout, an error
+// naming how many batches are still missing.
+func (h *Harness) WaitLossless(ctx context.Context) (*LossReport, error) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		report, err := h.Verify(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if report.Lossless() {
+			return report, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return report, fmt.Errorf("%d of %d acknowledged batches never stored: %w",
+				len(report.Missing), report.Published, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
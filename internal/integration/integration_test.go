@@ -0,0 +1,263 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	gpus      = 4
+	batchSize = 10
+)
+
+func newHarness(t *testing.T) *Harness {
+	t.Helper()
+	h, err := Start(Config{})
+	if err != nil {
+		t.Fatalf("failed to start harness: %v", err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// stream publishes samples readings per GPU starting at offset seconds.
+func stream(t *testing.T, ctx context.Context, s *Streamer, offset, samples int) {
+	t.Helper()
+	metrics := SyntheticMetrics(gpus, samples, start.Add(time.Duration(offset)*time.Second))
+	if err := s.Stream(ctx, metrics, batchSize); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+}
+
+// assertLossless waits for every acknowledged batch to be stored and checks
+// the API serves each GPU's samples exactly once.
+func assertLossless(t *testing.T, ctx context.Context, h *Harness, samples int) *LossReport {
+	t.Helper()
+	report, err := h.WaitLossless(ctx)
+	if err != nil {
+		t.Fatalf("data loss: %v (missing %v)", err, report.Missing)
+	}
+	for g := 0; g < gpus; g++ {
+		n, err := h.TelemetryCount(ctx, SyntheticUUID(g))
+		if err != nil {
+			t.Fatalf("TelemetryCount failed: %v", err)
+		}
+		if n != samples {
+			t.Errorf("API serves %d points for GPU %d, want %d", n, g, samples)
+		}
+	}
+	return report
+}
+
+func startCollector(t *testing.T, ctx context.Context, c *Collector) {
+	t.Helper()
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("collector start failed: %v", err)
+	}
+}
+
+func TestEndToEnd(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	collector := h.NewCollector("collector-0")
+	startCollector(t, ctx, collector)
+	streamer, err := h.NewStreamer("streamer-0")
+	if err != nil {
+		t.Fatalf("NewStreamer failed: %v", err)
+	}
+
+	stream(t, ctx, streamer, 0, 25)
+	report := assertLossless(t, ctx, h, 25)
+	if report.Published != 10 || report.Duplicates != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestCollectorCrashRestart(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	collector := h.NewCollector("collector-0")
+	streamer, err := h.NewStreamer("streamer-0")
+	if err != nil {
+		t.Fatalf("NewStreamer failed: %v", err)
+	}
+
+	// A graceful stop commits the position after everything stored
+	startCollector(t, ctx, collector)
+	stream(t, ctx, streamer, 0, 10)
+	assertLossless(t, ctx, h, 10)
+	if err := collector.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	// A crash loses the uncommitted position, not the data
+	startCollector(t, ctx, collector)
+	stream(t, ctx, streamer, 10, 10)
+	assertLossless(t, ctx, h, 20)
+	collector.Crash()
+
+	stream(t, ctx, streamer, 20, 10)
+	startCollector(t, ctx, collector)
+	report := assertLossless(t, ctx, h, 30)
+
+	if report.Published != 12 {
+		t.Errorf("expected 12 batches published, got %d", report.Published)
+	}
+	// Batches stored after the last commit are redelivered, at least once
+	if report.Duplicates < 4 {
+		t.Errorf("expected the 4 uncommitted batches to be redelivered, got %d duplicates", report.Duplicates)
+	}
+}
+
+func TestLagRecovery(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	collector := h.NewCollector("collector-0")
+	startCollector(t, ctx, collector)
+	if err := collector.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	streamer, err := h.NewStreamer("streamer-0")
+	if err != nil {
+		t.Fatalf("NewStreamer failed: %v", err)
+	}
+	stream(t, ctx, streamer, 0, 100)
+
+	backlog, err := h.Backlog(ctx, "collector-0")
+	if err != nil {
+		t.Fatalf("Backlog failed: %v", err)
+	}
+	if backlog != 40 {
+		t.Fatalf("expected a backlog of 40 batches while stopped, got %d", backlog)
+	}
+
+	startCollector(t, ctx, collector)
+	report := assertLossless(t, ctx, h, 100)
+	if report.Duplicates != 0 {
+		t.Errorf("catching up must not redeliver, got %d duplicates", report.Duplicates)
+	}
+	if err := collector.Stop(ctx); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if backlog, _ := h.Backlog(ctx, "collector-0"); backlog != 0 {
+		t.Errorf("expected no backlog after catching up, got %d", backlog)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	h := newHarness(t)
+	ctx := testContext(t)
+
+	collector := h.NewCollector("collector-0")
+	startCollector(t, ctx, collector)
+	streamer, err := h.NewStreamer("streamer-0")
+	if err != nil {
+		t.Fatalf("NewStreamer failed: %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		batch, err := streamer.Publish(ctx, SyntheticMetrics(gpus, 1, start.Add(time.Duration(i)*time.Second)))
+		if err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+		ids = append(ids, batch.BatchID)
+	}
+	assertLossless(t, ctx, h, 3)
+
+	result, err := h.Replay(ctx, &models.ReplayRequest{BatchIDs: ids[:2]})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(result.Replayed) != 2 {
+		t.Fatalf("expected 2 batches replayed, got %+v", result)
+	}
+
+	for {
+		report, err := h.Verify(ctx)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if report.Duplicates == 2 {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("replayed batches never stored: %+v", report)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	lineage, err := h.Store.GetBatch(ctx, ids[0])
+	if err != nil || !lineage.Replayed {
+		t.Errorf("expected replayed lineage, got %+v (%v)", lineage, err)
+	}
+	// Replayed points overwrite themselves rather than doubling up
+	assertLossless(t, ctx, h, 3)
+}
+
+func TestMemoryStoreRetention(t *testing.T) {
+	sim := clock.NewSimulated(start)
+	store := NewMemoryStore(sim)
+	ctx := context.Background()
+
+	metrics := SyntheticMetrics(1, 120, start)
+	batch := make([]*models.GPUMetric, len(metrics))
+	for i := range metrics {
+		batch[i] = &metrics[i]
+	}
+	store.StoreBatch(ctx, batch)
+
+	sim.Advance(3 * time.Minute)
+	removed, err := store.Cleanup(ctx, 2*time.Minute)
+	if err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if removed != 60 || store.Stats().TotalMetrics != 60 {
+		t.Errorf("expected the first minute removed, removed %d and kept %d", removed, store.Stats().TotalMetrics)
+	}
+}
+
+func TestMemoryStoreFleetSummary(t *testing.T) {
+	store := NewMemoryStore(nil)
+	ctx := context.Background()
+	metric := func(uuid, model string, at time.Time) *models.GPUMetric {
+		return &models.GPUMetric{UUID: uuid, ModelName: model, MetricName: "DCGM_FI_DEV_GPU_UTIL", Timestamp: at}
+	}
+	store.StoreBatch(ctx, []*models.GPUMetric{
+		metric("GPU-0", "H100", start.Add(23*time.Hour)),
+		metric("GPU-0", "H100", start.Add(25*time.Hour)),
+		metric("GPU-1", "H100", start.Add(26*time.Hour)),
+		metric("GPU-2", "", start.Add(27*time.Hour)),
+	})
+
+	summary, err := store.GetFleetSummary(ctx)
+	if err != nil {
+		t.Fatalf("GetFleetSummary failed: %v", err)
+	}
+	if summary.TotalGPUs != 3 || summary.TotalMetrics != 4 || len(summary.GPUsByModel) != 1 || summary.GPUsByModel["H100"] != 2 {
+		t.Errorf("unexpected counts: %+v", summary)
+	}
+	if len(summary.Daily) != 2 || !summary.Daily[0].Day.Equal(start) || summary.Daily[0].Metrics != 1 || summary.Daily[1].Metrics != 3 {
+		t.Errorf("unexpected daily volume: %+v", summary.Daily)
+	}
+	if !summary.OldestMetric.Equal(start.Add(23*time.Hour)) || !summary.NewestMetric.Equal(start.Add(27*time.Hour)) {
+		t.Errorf("unexpected oldest %v and newest %v", summary.OldestMetric, summary.NewestMetric)
+	}
+}
@@ -0,0 +1,297 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// MemoryStore is an in-memory storage backend for the harness. It serves
+// the collector, the API and replay like InfluxDB does, including
+// overwriting a point stored again with the same GPU, metric and timestamp,
+// and counts how many times each batch was stored so scenarios can check
+// for loss.
+type MemoryStore struct {
+	clock clock.Clock
+
+	mu      sync.RWMutex
+	metrics []*models.GPUMetric
+	points  map[pointKey]int                // point identity -> index in metrics
+	lineage map[string]*models.BatchLineage // batch ID -> most recent lineage
+	stored  map[string]int                  // batch ID -> times recorded
+}
+
+// Compile-time interface checks.
+var (
+	_ storage.Storage            = (*MemoryStore)(nil)
+	_ storage.LineageRecorder    = (*MemoryStore)(nil)
+	_ storage.LineageReader      = (*MemoryStore)(nil)
+	_ storage.FleetSummaryReader = (*MemoryStore)(nil)
+)
+
+// NewMemoryStore creates an empty store whose retention cleanup is timed by c.
+func NewMemoryStore(c clock.Clock) *MemoryStore {
+	if c == nil {
+		c = clock.Real
+	}
+	return &MemoryStore{
+		clock:   c,
+		points:  make(map[pointKey]int),
+		lineage: make(map[string]*models.BatchLineage),
+		stored:  make(map[string]int),
+	}
+}
+
+// Store stores a single metric.
+func (s *MemoryStore) Store(ctx context.Context, metric *models.GPUMetric) error {
+	return s.StoreBatch(ctx, []*models.GPUMetric{metric})
+}
+
+// pointKey identifies a point the way an InfluxDB series and timestamp do.
+type pointKey struct {
+	uuid, metric string
+	at           int64
+}
+
+func keyOf(m *models.GPUMetric) pointKey {
+	return pointKey{uuid: m.UUID, metric: m.MetricName, at: m.Timestamp.UnixNano()}
+}
+
+// StoreBatch stores copies of the metrics, overwriting existing points.
+func (s *MemoryStore) StoreBatch(ctx context.Context, metrics []*models.GPUMetric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range metrics {
+		c := *m
+		if i, ok := s.points[keyOf(m)]; ok {
+			s.metrics[i] = &c
+			continue
+		}
+		s.points[keyOf(m)] = len(s.metrics)
+		s.metrics = append(s.metrics, &c)
+	}
+	return nil
+}
+
+// GetGPUs returns the UUIDs of every GPU with stored metrics.
+func (s *MemoryStore) GetGPUs(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[string]bool)
+	var uuids []string
+	for _, m := range s.metrics {
+		if !seen[m.UUID] {
+			seen[m.UUID] = true
+			uuids = append(uuids, m.UUID)
+		}
+	}
+	sort.Strings(uuids)
+	return uuids, nil
+}
+
+// GetTelemetry returns the metrics matching query, oldest first.
+func (s *MemoryStore) GetTelemetry(ctx context.Context, query *models.TelemetryQuery) ([]*models.GPUMetric, error) {
+	s.mu.RLock()
+	var matched []*models.GPUMetric
+	for _, m := range s.metrics {
+		if matches(m, query) {
+			c := *m
+			matched = append(matched, &c)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Timestamp.Before(matched[j].Timestamp) })
+	if query.Offset > 0 {
+		if query.Offset >= len(matched) {
+			return []*models.GPUMetric{}, nil
+		}
+		matched = matched[query.Offset:]
+	}
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+	return matched, nil
+}
+
+// matches reports whether m satisfies every filter set in q.
+func matches(m *models.GPUMetric, q *models.TelemetryQuery) bool {
+	switch {
+	case q.UUID != "" && m.UUID != q.UUID,
+		q.Hostname != "" && m.Hostname != q.Hostname,
+		q.GPUID != nil && m.GPUID != *q.GPUID,
+		q.MetricName != "" && m.MetricName != q.MetricName,
+		q.StartTime != nil && m.Timestamp.Before(*q.StartTime),
+		q.EndTime != nil && m.Timestamp.After(*q.EndTime),
+		!q.MatchesSets(m):
+		return false
+	}
+	return true
+}
+
+// GetGPUByUUID describes a GPU from its stored metrics.
+func (s *MemoryStore) GetGPUByUUID(ctx context.Context, uuid string) (*models.GPUInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var info *models.GPUInfo
+	for _, m := range s.metrics {
+		if m.UUID != uuid {
+			continue
+		}
+		if info == nil {
+			info = &models.GPUInfo{UUID: m.UUID, GPUID: m.GPUID, Device: m.Device, ModelName: m.ModelName,
+				Hostname: m.Hostname, FirstSeen: m.Timestamp, LastSeen: m.Timestamp}
+		}
+		if m.Timestamp.Before(info.FirstSeen) {
+			info.FirstSeen = m.Timestamp
+		}
+		if m.Timestamp.After(info.LastSeen) {
+			info.LastSeen = m.Timestamp
+		}
+	}
+	if info == nil {
+		return nil, perrors.NotFound(fmt.Errorf("GPU %q not found", uuid))
+	}
+	return info, nil
+}
+
+// GetMetricsByGPU returns a GPU's metrics in the optional time range.
+func (s *MemoryStore) GetMetricsByGPU(ctx context.Context, uuid string, startTime, endTime *time.Time) ([]*models.GPUMetric, error) {
+	return s.GetTelemetry(ctx, &models.TelemetryQuery{UUID: uuid, StartTime: startTime, EndTime: endTime})
+}
+
+// Cleanup removes metrics older than retentionPeriod by the store's clock.
+func (s *MemoryStore) Cleanup(ctx context.Context, retentionPeriod time.Duration) (int, error) {
+	if retentionPeriod <= 0 {
+		return 0, nil
+	}
+	cutoff := s.clock.Now().Add(-retentionPeriod)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.metrics[:0]
+	clear(s.points)
+	for _, m := range s.metrics {
+		if !m.Timestamp.Before(cutoff) {
+			s.points[keyOf(m)] = len(kept)
+			kept = append(kept, m)
+		}
+	}
+	removed := len(s.metrics) - len(kept)
+	s.metrics = kept
+	return removed, nil
+}
+
+// Stats returns storage statistics.
+func (s *MemoryStore) Stats() storage.StorageStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := storage.StorageStats{TotalMetrics: int64(len(s.metrics))}
+	gpus := make(map[string]bool)
+	for _, m := range s.metrics {
+		gpus[m.UUID] = true
+		if stats.OldestMetric.IsZero() || m.Timestamp.Before(stats.OldestMetric) {
+			stats.OldestMetric = m.Timestamp
+		}
+		if m.Timestamp.After(stats.NewestMetric) {
+			stats.NewestMetric = m.Timestamp
+		}
+	}
+	stats.TotalGPUs = len(gpus)
+	return stats
+}
+
+// GetFleetSummary summarizes the stored metrics like InfluxDB does.
+func (s *MemoryStore) GetFleetSummary(ctx context.Context) (*models.FleetSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summary := &models.FleetSummary{GPUsByModel: make(map[string]int), Daily: make([]models.DailyVolume, 0)}
+	gpus := make(map[string]bool)
+	modelGPUs := make(map[[2]string]bool)
+	daily := make(map[time.Time]int64)
+	for _, m := range s.metrics {
+		summary.TotalMetrics++
+		gpus[m.UUID] = true
+		if m.ModelName != "" && !modelGPUs[[2]string{m.ModelName, m.UUID}] {
+			modelGPUs[[2]string{m.ModelName, m.UUID}] = true
+			summary.GPUsByModel[m.ModelName]++
+		}
+		daily[m.Timestamp.UTC().Truncate(24*time.Hour)]++
+		if summary.OldestMetric.IsZero() || m.Timestamp.Before(summary.OldestMetric) {
+			summary.OldestMetric = m.Timestamp.UTC()
+		}
+		if m.Timestamp.After(summary.NewestMetric) {
+			summary.NewestMetric = m.Timestamp.UTC()
+		}
+	}
+	summary.TotalGPUs = len(gpus)
+	for day, count := range daily {
+		summary.Daily = append(summary.Daily, models.DailyVolume{Day: day, Metrics: count})
+	}
+	sort.Slice(summary.Daily, func(i, j int) bool { return summary.Daily[i].Day.Before(summary.Daily[j].Day) })
+	return summary, nil
+}
+
+// RecordBatch stores a batch's lineage and counts the store.
+func (s *MemoryStore) RecordBatch(ctx context.Context, lineage *models.BatchLineage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *lineage
+	s.lineage[lineage.BatchID] = &c
+	s.stored[lineage.BatchID]++
+	return nil
+}
+
+// GetBatch returns a batch's most recent lineage.
+func (s *MemoryStore) GetBatch(ctx context.Context, id string) (*models.BatchLineage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l, ok := s.lineage[id]
+	if !ok {
+		return nil, perrors.NotFound(fmt.Errorf("batch %q not found", id))
+	}
+	c := *l
+	return &c, nil
+}
+
+// ListBatches returns up to limit batches received in [start, end), oldest first.
+func (s *MemoryStore) ListBatches(ctx context.Context, start, end time.Time, limit int) ([]*models.BatchLineage, error) {
+	s.mu.RLock()
+	var batches []*models.BatchLineage
+	for _, l := range s.lineage {
+		if !l.ReceivedAt.Before(start) && l.ReceivedAt.Before(end) {
+			c := *l
+			batches = append(batches, &c)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(batches, func(i, j int) bool { return batches[i].ReceivedAt.Before(batches[j].ReceivedAt) })
+	if limit > 0 && len(batches) > limit {
+		batches = batches[:limit]
+	}
+	return batches, nil
+}
+
+// StoredCounts returns how many times each batch has been stored.
+func (s *MemoryStore) StoredCounts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int, len(s.stored))
+	for id, n := range s.stored {
+		counts[id] = n
+	}
+	return counts
+}
+
+// Close releases nothing; the store lives until it is garbage collected.
+func (s *MemoryStore) Close() error {
+	return nil
+}
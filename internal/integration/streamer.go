@@ -0,0 +1,101 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Streamer publishes metric batches to the harness's MQ the way the
+// streamer service does, recording each batch the MQ acknowledges.
+type Streamer struct {
+	h      *Harness
+	client *mq.Client
+	source string
+}
+
+// NewStreamer connects a streamer that stamps its batches with source.
+func (h *Harness) NewStreamer(source string) (*Streamer, error) {
+	client, err := h.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return &Streamer{h: h, client: client, source: source}, nil
+}
+
+// Publish publishes metrics as one batch and returns it once the MQ has
+// acknowledged it.
+func (s *Streamer) Publish(ctx context.Context, metrics []models.GPUMetric) (*models.MetricBatch, error) {
+	batch := &models.MetricBatch{
+		BatchID:     uuid.New().String(),
+		Source:      s.source,
+		CollectedAt: time.Now(),
+		Metrics:     metrics,
+	}
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{
+		mq.MetaHostname:    mq.JoinMetadataSet(batch.Hostnames()),
+		mq.MetaMetricName:  mq.JoinMetadataSet(batch.MetricNames()),
+		mq.MetaRecordCount: strconv.Itoa(len(batch.Metrics)),
+	}
+	if _, err := s.client.PublishConfirmed(ctx, payload, metadata); err != nil {
+		return nil, fmt.Errorf("failed to publish batch %s: %w", batch.BatchID, err)
+	}
+	s.h.acknowledged(batch.BatchID)
+	return batch, nil
+}
+
+// Stream publishes metrics in batches of up to batchSize.
+func (s *Streamer) Stream(ctx context.Context, metrics []models.GPUMetric, batchSize int) error {
+	for len(metrics) > 0 {
+		n := min(batchSize, len(metrics))
+		if _, err := s.Publish(ctx, metrics[:n]); err != nil {
+			return err
+		}
+		metrics = metrics[n:]
+	}
+	return nil
+}
+
+// Close disconnects the streamer.
+func (s *Streamer) Close() error {
+	return s.client.Close()
+}
+
+// SyntheticMetrics returns samples readings of GPU temperature for each of
+// gpus GPUs spread over hosts of four GPUs, one second apart from start.
+// Every reading is distinct, so lost or duplicated points are countable.
+func SyntheticMetrics(gpus, samples int, start time.Time) []models.GPUMetric {
+	metrics := make([]models.GPUMetric, 0, gpus*samples)
+	for i := 0; i < samples; i++ {
+		for g := 0; g < gpus; g++ {
+			metrics = append(metrics, models.GPUMetric{
+				Timestamp:  start.Add(time.Duration(i) * time.Second),
+				MetricName: "DCGM_FI_DEV_GPU_TEMP",
+				GPUID:      g % 4,
+				Device:     fmt.Sprintf("nvidia%d", g%4),
+				UUID:       SyntheticUUID(g),
+				ModelName:  "NVIDIA H100 80GB HBM3",
+				Hostname:   fmt.Sprintf("host-%03d", g/4),
+				Value:      float64(40 + i%50),
+			})
+		}
+	}
+	return metrics
+}
+
+// SyntheticUUID returns the UUID SyntheticMetrics gives GPU g.
+func SyntheticUUID(g int) string {
+	return fmt.Sprintf("GPU-%08d-0000-0000-0000-000000000000", g)
+}
@@ -0,0 +1,391 @@
+// Package kafka implements the collector's Kafka source on top of the
+// franz-go client: it reads every partition of one topic assigned to it,
+// delivers records in partition order, and stores its position as consumer
+// group offsets.
+//
+// Collectors sharing a group ID split the topic's partitions between them
+// and take over each other's partitions as they come and go; give each
+// collector its own group ID for every one to consume the whole stream, as
+// on the pipeline's own MQ.
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// Start offsets for partitions the group has not committed.
+const (
+	StartEarliest = "earliest"
+	StartLatest   = "latest"
+)
+
+// Config configures a Consumer.
+type Config struct {
+	// Brokers are bootstrap broker addresses (host:port)
+	Brokers []string
+
+	// Topic is the topic to consume
+	Topic string
+
+	// GroupID names the consumer group the consumer joins and commits
+	// offsets under; empty reads every partition without a group or
+	// commits, so every start uses StartOffset
+	GroupID string
+
+	// ClientID identifies the consumer in broker logs and quotas
+	ClientID string
+
+	// StartOffset is where partitions without a committed offset begin:
+	// StartEarliest or StartLatest (the default)
+	StartOffset string
+
+	// MaxWait is how long a fetch waits for new records
+	MaxWait time.Duration
+
+	// MaxBytes bounds the records returned per fetch and per partition
+	MaxBytes int32
+
+	// CommitInterval is how often offsets are committed
+	CommitInterval time.Duration
+
+	// Timeout bounds dialing and each request
+	Timeout time.Duration
+
+	// TLS, when set, connects to the brokers over TLS. The server name
+	// defaults to each broker's host.
+	TLS *tls.Config
+
+	// SASL authenticates each connection when its Mechanism is set
+	SASL SASL
+
+	// Retry paces retries after broker and handler failures. Failures are
+	// retried until shutdown: skipping a record would lose it.
+	Retry retry.Policy
+}
+
+// Message is a record delivered to the handler.
+type Message struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Time      time.Time
+}
+
+// Handler processes one message. An error redelivers the message after a
+// backoff; handlers should return nil for messages they choose to drop.
+type Handler func(ctx context.Context, msg Message) error
+
+// Consumer reads a topic and tracks its position per partition.
+type Consumer struct {
+	cfg    Config
+	logger *log.Logger
+
+	mu             sync.Mutex
+	offsets        map[int32]int64 // next offset to deliver
+	highWatermarks map[int32]int64
+}
+
+// NewConsumer creates a consumer. It connects when Run starts.
+func NewConsumer(cfg Config, logger *log.Logger) *Consumer {
+	if cfg.StartOffset == "" {
+		cfg.StartOffset = StartLatest
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = 500 * time.Millisecond
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = 1 << 20
+	}
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Retry.InitialBackoff <= 0 {
+		cfg.Retry.InitialBackoff = time.Second
+	}
+	if cfg.ClientID == "" {
+		cfg.ClientID = "gpu-telemetry-collector"
+	}
+	return &Consumer{
+		cfg:            cfg,
+		logger:         logger,
+		offsets:        make(map[int32]int64),
+		highWatermarks: make(map[int32]int64),
+	}
+}
+
+// Run joins the group and consumes until ctx is done, calling handle for
+// each record in partition order. Handled records are committed every
+// CommitInterval, when a rebalance takes partitions away, and once more as
+// Run leaves the group. It returns nil after a clean shutdown.
+func (c *Consumer) Run(ctx context.Context, handle Handler) error {
+	opts, err := c.options()
+	if err != nil {
+		return err
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return fmt.Errorf("kafka: %w", err)
+	}
+	// Partitions may be assigned differently when the group is rejoined
+	c.forgetAll()
+	defer c.close(client)
+
+	failures := 0
+	for ctx.Err() == nil {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		if err := fetchError(fetches); err != nil {
+			failures++
+			wait := c.cfg.Retry.Backoff(failures)
+			c.logger.Printf("Kafka consume error, retrying in %v: %v", wait, err)
+			client.AllowRebalance()
+			sleep(ctx, wait)
+			continue
+		}
+		failures = 0
+
+		err := c.consume(ctx, client, fetches, handle)
+		// Rebalances wait while records are handled, so a partition is
+		// never revoked with its records half delivered
+		client.AllowRebalance()
+		if err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// consume delivers one poll's records, marking each for commit once handled.
+func (c *Consumer) consume(ctx context.Context, client *kgo.Client, fetches kgo.Fetches, handle Handler) error {
+	var err error
+	fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+		c.mu.Lock()
+		c.highWatermarks[p.Partition] = p.HighWatermark
+		c.mu.Unlock()
+		for _, rec := range p.Records {
+			if err != nil {
+				return
+			}
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			msg := Message{
+				Topic:     rec.Topic,
+				Partition: rec.Partition,
+				Offset:    rec.Offset,
+				Key:       rec.Key,
+				Value:     rec.Value,
+				Time:      rec.Timestamp,
+			}
+			if err = c.deliver(ctx, handle, msg); err != nil {
+				return
+			}
+			if c.cfg.GroupID != "" {
+				client.MarkCommitRecords(rec)
+			}
+			c.mu.Lock()
+			c.offsets[rec.Partition] = rec.Offset + 1
+			c.mu.Unlock()
+		}
+	})
+	return err
+}
+
+// close leaves the group, committing what was handled, then closes the
+// client. Leaving gives up after Timeout so an unreachable cluster cannot
+// hold up shutdown; the group then drops the consumer when its session
+// expires.
+func (c *Consumer) close(client *kgo.Client) {
+	client.AllowRebalance()
+	if c.cfg.GroupID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+		defer cancel()
+		if err := client.LeaveGroupContext(ctx); err != nil {
+			c.logger.Printf("Failed to leave Kafka group %s: %v", c.cfg.GroupID, err)
+		}
+	}
+	client.Close()
+}
+
+// fetchError returns the first error in a poll, if any.
+func fetchError(fetches kgo.Fetches) error {
+	for _, fe := range fetches.Errors() {
+		if fe.Partition < 0 {
+			return fe.Err
+		}
+		return fmt.Errorf("partition %d: %w", fe.Partition, fe.Err)
+	}
+	return nil
+}
+
+// deliver calls handle until it succeeds or ctx is done.
+func (c *Consumer) deliver(ctx context.Context, handle Handler, msg Message) error {
+	for attempt := 1; ; attempt++ {
+		err := handle(ctx, msg)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		wait := c.cfg.Retry.Backoff(attempt)
+		c.logger.Printf("Failed to process Kafka message %s/%d@%d (attempt %d), retrying in %v: %v",
+			msg.Topic, msg.Partition, msg.Offset, attempt, wait, err)
+		if !sleep(ctx, wait) {
+			return ctx.Err()
+		}
+	}
+}
+
+// options translates the configuration into client options.
+func (c *Consumer) options() ([]kgo.Opt, error) {
+	start := kgo.NewOffset().AtEnd()
+	if c.cfg.StartOffset == StartEarliest {
+		start = kgo.NewOffset().AtStart()
+	}
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(c.cfg.Brokers...),
+		kgo.ClientID(c.cfg.ClientID),
+		kgo.ConsumeTopics(c.cfg.Topic),
+		kgo.ConsumeResetOffset(start),
+		kgo.FetchMaxWait(c.cfg.MaxWait),
+		kgo.FetchMaxBytes(c.cfg.MaxBytes),
+		kgo.FetchMaxPartitionBytes(c.cfg.MaxBytes),
+		kgo.DialTimeout(c.cfg.Timeout),
+		kgo.RequestTimeoutOverhead(c.cfg.Timeout),
+		kgo.RetryBackoffFn(c.cfg.Retry.Backoff),
+		kgo.WithLogger(logger{c.logger}),
+	}
+	if c.cfg.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(c.cfg.TLS.Clone()))
+	}
+	if c.cfg.SASL.Mechanism != "" {
+		mechanism, err := c.cfg.SASL.mechanism()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	if c.cfg.GroupID != "" {
+		opts = append(opts,
+			kgo.ConsumerGroup(c.cfg.GroupID),
+			kgo.AutoCommitMarks(),
+			kgo.AutoCommitInterval(c.cfg.CommitInterval),
+			kgo.BlockRebalanceOnPoll(),
+			kgo.OnPartitionsAssigned(c.assigned),
+			kgo.OnPartitionsRevoked(c.revoked),
+			kgo.OnPartitionsLost(c.lost),
+		)
+	}
+	return opts, nil
+}
+
+// assigned logs the partitions the group gave this consumer.
+func (c *Consumer) assigned(_ context.Context, _ *kgo.Client, partitions map[string][]int32) {
+	c.logger.Printf("Kafka group %s assigned partitions %v of %s", c.cfg.GroupID, partitions[c.cfg.Topic], c.cfg.Topic)
+}
+
+// revoked commits what was handled of partitions moving to another member,
+// so it resumes after the last handled record.
+func (c *Consumer) revoked(ctx context.Context, client *kgo.Client, partitions map[string][]int32) {
+	commitCtx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	if err := client.CommitMarkedOffsets(commitCtx); err != nil {
+		c.logger.Printf("Failed to commit Kafka offsets of revoked partitions: %v", err)
+	}
+	c.forget(partitions[c.cfg.Topic])
+}
+
+// lost forgets partitions the consumer was dropped from; committing them
+// would fail, so another member redelivers what was not committed.
+func (c *Consumer) lost(_ context.Context, _ *kgo.Client, partitions map[string][]int32) {
+	c.logger.Printf("Kafka group %s lost partitions %v of %s", c.cfg.GroupID, partitions[c.cfg.Topic], c.cfg.Topic)
+	c.forget(partitions[c.cfg.Topic])
+}
+
+func (c *Consumer) forget(partitions []int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range partitions {
+		delete(c.offsets, p)
+		delete(c.highWatermarks, p)
+	}
+}
+
+func (c *Consumer) forgetAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offsets = make(map[int32]int64)
+	c.highWatermarks = make(map[int32]int64)
+}
+
+// Lag returns how many records the consumer is behind the high watermarks
+// of its partitions as of the last fetch.
+func (c *Consumer) Lag() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var lag int64
+	for p, hw := range c.highWatermarks {
+		next, ok := c.offsets[p]
+		if !ok {
+			continue
+		}
+		if behind := hw - next; behind > 0 {
+			lag += behind
+		}
+	}
+	return lag
+}
+
+// Offsets returns the next offset to consume per partition.
+func (c *Consumer) Offsets() map[int32]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	offsets := make(map[int32]int64, len(c.offsets))
+	for p, offset := range c.offsets {
+		offsets[p] = offset
+	}
+	return offsets
+}
+
+// logger passes the client's warnings and errors to the collector's log.
+type logger struct {
+	*log.Logger
+}
+
+func (l logger) Level() kgo.LogLevel { return kgo.LogLevelWarn }
+
+func (l logger) Log(level kgo.LogLevel, msg string, keyvals ...any) {
+	l.Printf("Kafka client %s: %s %v", level, msg, keyvals)
+}
+
+// sleep waits for d or until ctx is done, reporting whether it waited the full time.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
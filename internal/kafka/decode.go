@@ -0,0 +1,200 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	protocol "github.com/influxdata/line-protocol"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/otlp"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Message formats the collector can decode.
+const (
+	// FormatJSON is a models.MetricBatch as the streamer publishes it
+	FormatJSON = "json"
+
+	// FormatProtobuf is an OTLP ExportMetricsServiceRequest, as written by
+	// the otel-collector Kafka exporter with encoding otlp_proto
+	FormatProtobuf = "protobuf"
+
+	// FormatLine is InfluxDB line protocol, as written by Telegraf
+	FormatLine = "line"
+)
+
+// Formats lists the supported message formats.
+var Formats = []string{FormatJSON, FormatProtobuf, FormatLine}
+
+// Decoded is a message turned into a batch.
+type Decoded struct {
+	Batch models.MetricBatch
+
+	// Dropped counts values in the message that could not be converted
+	Dropped int
+
+	// Reasons describes why values were dropped
+	Reasons []string
+}
+
+// Decoder turns a message into a metric batch. It returns an error for a
+// message that cannot be read at all; retrying it will not help.
+type Decoder func(msg Message) (Decoded, error)
+
+// NewDecoder returns the decoder for format.
+func NewDecoder(format string) (Decoder, error) {
+	var decode Decoder
+	switch format {
+	case FormatJSON:
+		decode = decodeJSON
+	case FormatProtobuf:
+		decode = decodeProtobuf
+	case FormatLine:
+		decode = decodeLine
+	default:
+		return nil, fmt.Errorf("unknown Kafka message format %q (want json, protobuf or line)", format)
+	}
+
+	return func(msg Message) (Decoded, error) {
+		d, err := decode(msg)
+		if err != nil {
+			return Decoded{}, err
+		}
+		// Give every batch a stable identity: redelivering the same record
+		// after a restart yields the same batch ID, so lineage and
+		// deduplication see one batch
+		if d.Batch.BatchID == "" {
+			d.Batch.BatchID = uuid.NewSHA1(uuid.NameSpaceURL,
+				[]byte(fmt.Sprintf("kafka://%s/%d/%d", msg.Topic, msg.Partition, msg.Offset))).String()
+		}
+		if d.Batch.Source == "" {
+			d.Batch.Source = "kafka/" + msg.Topic
+		}
+		if d.Batch.CollectedAt.IsZero() {
+			d.Batch.CollectedAt = msg.Time
+		}
+		return d, nil
+	}, nil
+}
+
+// decodeJSON reads a batch published in the pipeline's own format.
+func decodeJSON(msg Message) (Decoded, error) {
+	var batch models.MetricBatch
+	if err := json.Unmarshal(msg.Value, &batch); err != nil {
+		return Decoded{}, fmt.Errorf("invalid metric batch: %w", err)
+	}
+	return Decoded{Batch: batch}, nil
+}
+
+// decodeProtobuf reads an OTLP export request and maps it the way the OTLP
+// receiver does. Points without a timestamp take the record's timestamp.
+func decodeProtobuf(msg Message) (Decoded, error) {
+	req, err := otlp.UnmarshalProto(msg.Value)
+	if err != nil {
+		return Decoded{}, fmt.Errorf("invalid OTLP metrics: %w", err)
+	}
+	conv := otlp.Convert(req, msg.Time)
+	return Decoded{
+		Batch:   models.MetricBatch{Metrics: conv.Metrics},
+		Dropped: conv.Rejected,
+		Reasons: conv.Reasons,
+	}, nil
+}
+
+// genericFields are line protocol field keys that carry the value of the
+// measurement itself rather than naming a metric, as Telegraf's prometheus
+// input writes dcgm-exporter gauges (DCGM_FI_DEV_GPU_TEMP gauge=45).
+var genericFields = map[string]bool{
+	"value":   true,
+	"gauge":   true,
+	"counter": true,
+	"untyped": true,
+}
+
+// decodeLine reads InfluxDB line protocol. Each numeric field becomes a
+// metric named after the field, or after the measurement for a generic
+// field (value, gauge, ...). Tags fill the GPU identity as dcgm-exporter
+// labels and the pipeline's own InfluxDB tags do, and the rest become
+// labels. String and boolean fields, non-finite values and lines without a
+// GPU UUID are dropped.
+func decodeLine(msg Message) (Decoded, error) {
+	handler := protocol.NewMetricHandler()
+	parser := protocol.NewParser(handler)
+	parser.SetTimeFunc(func() time.Time { return msg.Time })
+	lines, err := parser.Parse(msg.Value)
+	if err != nil {
+		return Decoded{}, fmt.Errorf("invalid line protocol: %w", err)
+	}
+
+	var d Decoded
+	var noUUID, nonNumeric, nonFinite int
+	for _, line := range lines {
+		var base models.GPUMetric
+		base.Timestamp = line.Time()
+		for _, tag := range line.TagList() {
+			if base.SetIdentity(tag.Key, tag.Value) {
+				continue
+			}
+			if base.Labels == nil {
+				base.Labels = make(map[string]string)
+			}
+			base.Labels[tag.Key] = tag.Value
+		}
+
+		for _, field := range line.FieldList() {
+			if field.Key == "batch_id" {
+				continue // written by the pipeline's own storage schema
+			}
+			value, ok := numeric(field.Value)
+			switch {
+			case !ok:
+				nonNumeric++
+				continue
+			case !models.FiniteValue(value):
+				nonFinite++
+				continue
+			case base.UUID == "":
+				noUUID++
+				continue
+			}
+
+			metric := base
+			metric.MetricName = field.Key
+			if genericFields[field.Key] {
+				metric.MetricName = line.Name()
+			}
+			metric.Value = value
+			d.Batch.Metrics = append(d.Batch.Metrics, metric)
+		}
+	}
+
+	for _, r := range []struct {
+		n      int
+		reason string
+	}{
+		{noUUID, "values without a GPU UUID tag"},
+		{nonNumeric, "string or boolean fields"},
+		{nonFinite, "NaN or infinite values"},
+	} {
+		if r.n > 0 {
+			d.Dropped += r.n
+			d.Reasons = append(d.Reasons, fmt.Sprintf("%d %s", r.n, r.reason))
+		}
+	}
+	return d, nil
+}
+
+// numeric returns a line protocol field value as a float64.
+func numeric(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// Set KAFKA_TEST_BROKERS to run TestBrokerIntegration against a real
+// cluster that auto-creates topics, e.g. with make kafka-test. It reads
+// KAFKA_TEST_TLS_CA_FILE, KAFKA_TEST_SASL_MECHANISM, KAFKA_TEST_USERNAME
+// and KAFKA_TEST_PASSWORD to connect the way a secured site would.
+func integrationConfig(t *testing.T) Config {
+	t.Helper()
+	brokers := os.Getenv("KAFKA_TEST_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_TEST_BROKERS is not set")
+	}
+	cfg := Config{
+		Brokers:        strings.Split(brokers, ","),
+		Topic:          fmt.Sprintf("gpu-telemetry-test-%d", time.Now().UnixNano()),
+		GroupID:        fmt.Sprintf("gpu-telemetry-test-%d", time.Now().UnixNano()),
+		ClientID:       "gpu-telemetry-test",
+		StartOffset:    StartEarliest,
+		MaxWait:        100 * time.Millisecond,
+		CommitInterval: time.Hour,
+		Timeout:        10 * time.Second,
+		Retry:          retry.Policy{InitialBackoff: 100 * time.Millisecond},
+		SASL: SASL{
+			Mechanism: os.Getenv("KAFKA_TEST_SASL_MECHANISM"),
+			Username:  os.Getenv("KAFKA_TEST_USERNAME"),
+			Password:  os.Getenv("KAFKA_TEST_PASSWORD"),
+		},
+	}
+	if caFile := os.Getenv("KAFKA_TEST_TLS_CA_FILE"); caFile != "" {
+		var err error
+		if cfg.TLS, err = LoadTLS(caFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+func TestBrokerIntegration(t *testing.T) {
+	cfg := integrationConfig(t)
+	logger := log.New(io.Discard, "", 0)
+	produce(t, cfg, 0, "a", "b", "c")
+
+	got := consume(t, NewConsumer(cfg, logger), 3, nil)
+	var values []string
+	for _, msg := range got {
+		values = append(values, string(msg.Value))
+	}
+	if strings.Join(values, ",") != "a,b,c" {
+		t.Errorf("consumed %v, want a, b and c in order", values)
+	}
+
+	// A consumer in the same group resumes after what the first committed
+	produce(t, cfg, 0, "d")
+	got = consume(t, NewConsumer(cfg, logger), 1, nil)
+	if string(got[0].Value) != "d" || got[0].Offset != 3 {
+		t.Errorf("resumed at %q offset %d, want d at 3", got[0].Value, got[0].Offset)
+	}
+}
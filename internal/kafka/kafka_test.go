@@ -0,0 +1,560 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// newCluster starts an in-memory Kafka cluster serving topic.
+func newCluster(t *testing.T, topic string, partitions int32, opts ...kfake.Opt) *kfake.Cluster {
+	t.Helper()
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{kfake.NumBrokers(1), kfake.SeedTopics(partitions, topic)}, opts...)...)
+	if err != nil {
+		t.Fatalf("start cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+// newSecureCluster starts a cluster serving TLS and requiring sasl,
+// returning it and the TLS config that trusts it.
+func newSecureCluster(t *testing.T, topic string, sasl SASL) (*kfake.Cluster, *tls.Config) {
+	// Borrow httptest's certificate for 127.0.0.1
+	https := httptest.NewTLSServer(nil)
+	cert, pool := https.TLS.Certificates[0], x509.NewCertPool()
+	pool.AddCert(https.Certificate())
+	https.Close()
+
+	cluster := newCluster(t, topic, 1,
+		kfake.TLS(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		kfake.EnableSASL(),
+		kfake.Superuser(sasl.Mechanism, sasl.Username, sasl.Password))
+	return cluster, &tls.Config{RootCAs: pool}
+}
+
+func testConfig(cluster *kfake.Cluster, topic, start string) Config {
+	return Config{
+		Brokers:        cluster.ListenAddrs(),
+		Topic:          topic,
+		GroupID:        "collectors",
+		StartOffset:    start,
+		MaxWait:        10 * time.Millisecond,
+		CommitInterval: time.Hour,
+		Timeout:        2 * time.Second,
+		Retry:          retry.Policy{InitialBackoff: 10 * time.Millisecond},
+	}
+}
+
+func testConsumer(cluster *kfake.Cluster, topic, start string) *Consumer {
+	return NewConsumer(testConfig(cluster, topic, start), log.New(io.Discard, "", 0))
+}
+
+// client connects to the brokers in cfg the way a consumer would.
+func client(t *testing.T, cfg Config, opts ...kgo.Opt) *kgo.Client {
+	t.Helper()
+	opts = append([]kgo.Opt{kgo.SeedBrokers(cfg.Brokers...)}, opts...)
+	if cfg.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(cfg.TLS.Clone()))
+	}
+	if cfg.SASL.Mechanism != "" {
+		mechanism, err := cfg.SASL.mechanism()
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cl.Close)
+	return cl
+}
+
+// produce appends values to partition of cfg.Topic, creating the topic on
+// first use.
+func produce(t *testing.T, cfg Config, partition int32, values ...string) {
+	t.Helper()
+	produceCompressed(t, cfg, kgo.NoCompression(), partition, values...)
+}
+
+// produceCompressed appends values to partition in one batch compressed
+// with codec.
+func produceCompressed(t *testing.T, cfg Config, codec kgo.CompressionCodec, partition int32, values ...string) {
+	t.Helper()
+	cl := client(t, cfg,
+		kgo.AllowAutoTopicCreation(),
+		kgo.RecordPartitioner(kgo.ManualPartitioner()),
+		kgo.ProducerBatchCompression(codec),
+		kgo.ProducerLinger(0))
+	records := make([]*kgo.Record, len(values))
+	for i, v := range values {
+		records[i] = &kgo.Record{Topic: cfg.Topic, Partition: partition, Value: []byte(v)}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := cl.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		t.Fatalf("produce to %s: %v", cfg.Topic, err)
+	}
+}
+
+// committed returns the offsets cfg.GroupID has committed for cfg.Topic.
+func committed(t *testing.T, cfg Config) map[int32]int64 {
+	t.Helper()
+	req := kmsg.NewPtrOffsetFetchRequest()
+	req.Group = cfg.GroupID
+	topic := kmsg.NewOffsetFetchRequestTopic()
+	topic.Topic = cfg.Topic
+	topic.Partitions = []int32{0, 1, 2, 3}
+	req.Topics = append(req.Topics, topic)
+	resp, err := req.RequestWith(context.Background(), client(t, cfg))
+	if err != nil {
+		t.Fatalf("fetch offsets: %v", err)
+	}
+	offsets := map[int32]int64{}
+	for _, topic := range resp.Topics {
+		for _, p := range topic.Partitions {
+			if p.Offset >= 0 {
+				offsets[p.Partition] = p.Offset
+			}
+		}
+	}
+	return offsets
+}
+
+// consume runs c until want messages arrive or the test times out.
+func consume(t *testing.T, c *Consumer, want int, handle Handler) []Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var got []Message
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx, func(ctx context.Context, msg Message) error {
+			if handle != nil {
+				if err := handle(ctx, msg); err != nil {
+					return err
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, msg)
+			if len(got) == want {
+				cancel()
+			}
+			return nil
+		})
+	}()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != want {
+		t.Fatalf("got %d messages, want %d", len(got), want)
+	}
+	return got
+}
+
+// runFailing runs a consumer that cannot connect for a moment, returning
+// what it logged.
+func runFailing(t *testing.T, cfg Config) string {
+	t.Helper()
+	var logs bytes.Buffer
+	var mu sync.Mutex
+	c := NewConsumer(cfg, log.New(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return logs.Write(p)
+	}), "", 0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := c.Run(ctx, func(context.Context, Message) error {
+		t.Error("unexpected delivery")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return logs.String()
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestConsumerReadsAllPartitionsAndCommits(t *testing.T) {
+	cluster := newCluster(t, "gpu-metrics", 2)
+	cfg := testConfig(cluster, "gpu-metrics", StartEarliest)
+	produce(t, cfg, 0, "p0-a", "p0-b")
+	produce(t, cfg, 1, "p1-a")
+
+	got := consume(t, NewConsumer(cfg, log.New(io.Discard, "", 0)), 3, nil)
+
+	byPartition := map[int32][]string{}
+	for _, msg := range got {
+		if msg.Topic != "gpu-metrics" {
+			t.Errorf("topic = %q", msg.Topic)
+		}
+		byPartition[msg.Partition] = append(byPartition[msg.Partition], string(msg.Value))
+	}
+	if strings.Join(byPartition[0], ",") != "p0-a,p0-b" || strings.Join(byPartition[1], ",") != "p1-a" {
+		t.Errorf("unexpected delivery: %v", byPartition)
+	}
+
+	offsets := committed(t, cfg)
+	if offsets[0] != 2 || offsets[1] != 1 {
+		t.Errorf("committed = %v, want partition 0 at 2 and 1 at 1", offsets)
+	}
+}
+
+func TestConsumerResumesFromCommittedOffset(t *testing.T) {
+	cluster := newCluster(t, "gpu-metrics", 1)
+	cfg := testConfig(cluster, "gpu-metrics", StartEarliest)
+	produce(t, cfg, 0, "old", "new")
+
+	// The first consumer handles one record and commits it as it leaves
+	if got := consume(t, testConsumer(cluster, "gpu-metrics", StartEarliest), 1, nil); string(got[0].Value) != "old" {
+		t.Fatalf("got %q, want old", got[0].Value)
+	}
+	got := consume(t, testConsumer(cluster, "gpu-metrics", StartEarliest), 1, nil)
+	if string(got[0].Value) != "new" || got[0].Offset != 1 {
+		t.Errorf("got %q at %d, want new at 1", got[0].Value, got[0].Offset)
+	}
+}
+
+func TestConsumerStartsAtLatest(t *testing.T) {
+	cluster := newCluster(t, "gpu-metrics", 1)
+	cfg := testConfig(cluster, "gpu-metrics", StartLatest)
+	produce(t, cfg, 0, "before")
+
+	// Produce once the consumer has positioned itself at the end
+	fetched := make(chan struct{})
+	var once sync.Once
+	cluster.ControlKey(int16(kmsg.Fetch), func(kmsg.Request) (kmsg.Response, error, bool) {
+		cluster.KeepControl()
+		once.Do(func() { close(fetched) })
+		return nil, nil, false
+	})
+	go func() {
+		<-fetched
+		produce(t, cfg, 0, "after")
+	}()
+
+	got := consume(t, NewConsumer(cfg, log.New(io.Discard, "", 0)), 1, nil)
+	if string(got[0].Value) != "after" {
+		t.Errorf("got %q, want after", got[0].Value)
+	}
+}
+
+func TestConsumerRedeliversOnHandlerError(t *testing.T) {
+	cluster := newCluster(t, "gpu-metrics", 1)
+	c := testConsumer(cluster, "gpu-metrics", StartEarliest)
+	produce(t, c.cfg, 0, "a", "b")
+
+	attempts := map[string]int{}
+	got := consume(t, c, 2, func(ctx context.Context, msg Message) error {
+		attempts[string(msg.Value)]++
+		if string(msg.Value) == "a" && attempts["a"] < 3 {
+			return errors.New("storage unavailable")
+		}
+		return nil
+	})
+	if string(got[0].Value) != "a" || string(got[1].Value) != "b" {
+		t.Errorf("delivery order = %q, %q", got[0].Value, got[1].Value)
+	}
+	if attempts["a"] != 3 || attempts["b"] != 1 {
+		t.Errorf("attempts = %v, want a 3 times and b once", attempts)
+	}
+}
+
+func TestConsumerDecompressesBatches(t *testing.T) {
+	codecs := map[string]kgo.CompressionCodec{
+		"gzip":   kgo.GzipCompression(),
+		"snappy": kgo.SnappyCompression(),
+		"lz4":    kgo.Lz4Compression(),
+		"zstd":   kgo.ZstdCompression(),
+	}
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			cluster := newCluster(t, "gpu-metrics", 1)
+			c := testConsumer(cluster, "gpu-metrics", StartEarliest)
+			produceCompressed(t, c.cfg, codec, 0, "a", "b")
+			got := consume(t, c, 2, nil)
+			if string(got[0].Value) != "a" || string(got[1].Value) != "b" {
+				t.Errorf("got %q, %q, want a and b", got[0].Value, got[1].Value)
+			}
+		})
+	}
+}
+
+func TestConsumersInGroupSplitPartitions(t *testing.T) {
+	cluster := newCluster(t, "gpu-metrics", 2)
+	cfg := testConfig(cluster, "gpu-metrics", StartEarliest)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Record which collector handled each value
+	var mu sync.Mutex
+	handledBy := map[string][]int{}
+	counts := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range counts {
+		c := NewConsumer(cfg, log.New(io.Discard, "", 0))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Run(ctx, func(_ context.Context, msg Message) error {
+				mu.Lock()
+				defer mu.Unlock()
+				handledBy[string(msg.Value)] = append(handledBy[string(msg.Value)], i)
+				counts[i]++
+				return nil
+			}); err != nil {
+				t.Errorf("Run: %v", err)
+			}
+		}()
+	}
+
+	// Keep producing until the group has settled and both collectors have
+	// partitions to read
+	for n := 0; ; n++ {
+		mu.Lock()
+		split := counts[0] > 0 && counts[1] > 0
+		mu.Unlock()
+		if split {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("partitions never split: handled %v", counts)
+		}
+		produce(t, cfg, int32(n%2), fmt.Sprintf("v%d", n))
+		time.Sleep(50 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	for value, by := range handledBy {
+		if len(by) != 1 {
+			t.Errorf("%s handled by collectors %v, want exactly one", value, by)
+		}
+	}
+}
+
+func TestConsumerAuthenticatesOverTLS(t *testing.T) {
+	for _, mechanism := range SASLMechanisms {
+		t.Run(mechanism, func(t *testing.T) {
+			sasl := SASL{Mechanism: mechanism, Username: "collector", Password: "s3cret"}
+			cluster, tlsConfig := newSecureCluster(t, "gpu-metrics", sasl)
+			c := testConsumer(cluster, "gpu-metrics", StartEarliest)
+			c.cfg.TLS, c.cfg.SASL = tlsConfig, sasl
+			produce(t, c.cfg, 0, "a")
+
+			if got := consume(t, c, 1, nil); string(got[0].Value) != "a" {
+				t.Errorf("got %q, want a", got[0].Value)
+			}
+			if offsets := committed(t, c.cfg); offsets[0] != 1 {
+				t.Errorf("committed = %v, want partition 0 at 1", offsets)
+			}
+
+			// A wrong password is refused, so nothing is delivered
+			cfg := c.cfg
+			cfg.SASL.Password = "guess"
+			runFailing(t, cfg)
+		})
+	}
+}
+
+func TestConsumerReportsUnsupportedMechanism(t *testing.T) {
+	cluster, tlsConfig := newSecureCluster(t, "gpu-metrics", SASL{Mechanism: SASLScramSHA512, Username: "collector", Password: "s3cret"})
+	cfg := testConfig(cluster, "gpu-metrics", StartEarliest)
+	cfg.TLS = tlsConfig
+	cfg.SASL = SASL{Mechanism: "GSSAPI", Username: "collector", Password: "s3cret"}
+	c := NewConsumer(cfg, log.New(io.Discard, "", 0))
+	if err := c.Run(context.Background(), nil); err == nil || !strings.Contains(err.Error(), `unsupported SASL mechanism "GSSAPI"`) {
+		t.Errorf("expected the mechanism refused, got %v", err)
+	}
+
+	// Nor does a client that does not trust the broker's certificate connect
+	cfg.SASL.Mechanism = SASLScramSHA512
+	cfg.TLS = &tls.Config{}
+	if logs := runFailing(t, cfg); !strings.Contains(logs, "certificate") {
+		t.Errorf("expected an untrusted certificate refused, logged %q", logs)
+	}
+}
+
+func TestConsumerLag(t *testing.T) {
+	c := NewConsumer(Config{}, log.New(io.Discard, "", 0))
+	c.offsets[0], c.highWatermarks[0] = 5, 8
+	c.offsets[1], c.highWatermarks[1] = 3, 3
+	if lag := c.Lag(); lag != 3 {
+		t.Errorf("Lag() = %d, want 3", lag)
+	}
+}
+
+func TestNewDecoderUnknownFormat(t *testing.T) {
+	if _, err := NewDecoder("avro"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	decode, _ := NewDecoder(FormatJSON)
+	value, _ := json.Marshal(models.MetricBatch{
+		BatchID: "batch-1",
+		Source:  "streamer-1",
+		Metrics: []models.GPUMetric{{UUID: "GPU-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 40}},
+	})
+	d, err := decode(Message{Topic: "t", Value: value, Time: time.Now()})
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if d.Batch.BatchID != "batch-1" || d.Batch.Source != "streamer-1" || len(d.Batch.Metrics) != 1 {
+		t.Errorf("unexpected batch: %+v", d.Batch)
+	}
+
+	if _, err := decode(Message{Value: []byte("not json")}); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestDecodeAssignsStableBatchID(t *testing.T) {
+	decode, _ := NewDecoder(FormatJSON)
+	msg := Message{Topic: "gpu", Partition: 2, Offset: 7, Value: []byte(`{"metrics":[]}`), Time: time.Now()}
+	d1, _ := decode(msg)
+	d2, _ := decode(msg)
+	if d1.Batch.BatchID == "" || d1.Batch.BatchID != d2.Batch.BatchID {
+		t.Errorf("batch IDs %q and %q should be equal and non-empty", d1.Batch.BatchID, d2.Batch.BatchID)
+	}
+	msg.Offset = 8
+	if d3, _ := decode(msg); d3.Batch.BatchID == d1.Batch.BatchID {
+		t.Error("different offsets should get different batch IDs")
+	}
+	if d1.Batch.Source != "kafka/gpu" || !d1.Batch.CollectedAt.Equal(msg.Time) {
+		t.Errorf("source = %q, collected at %v", d1.Batch.Source, d1.Batch.CollectedAt)
+	}
+}
+
+// Protobuf helpers for a minimal OTLP export request.
+
+func pbBytes(num int, v []byte) []byte {
+	b := binary.AppendUvarint(nil, uint64(num)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func pbFixed64(num int, v uint64) []byte {
+	b := binary.AppendUvarint(nil, uint64(num)<<3|1)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+func pbAttr(key, value string) []byte {
+	return pbBytes(7, append(pbBytes(1, []byte(key)), pbBytes(2, pbBytes(1, []byte(value)))...))
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	point := append(pbAttr("UUID", "GPU-1"), pbFixed64(4, math.Float64bits(71.5))...)
+	metric := append(pbBytes(1, []byte("DCGM_FI_DEV_GPU_TEMP")), pbBytes(5, pbBytes(1, point))...)
+	noUUID := append(pbBytes(1, []byte("DCGM_FI_DEV_POWER_USAGE")), pbBytes(5, pbBytes(1, pbFixed64(4, math.Float64bits(1))))...)
+	scope := append(pbBytes(2, metric), pbBytes(2, noUUID)...)
+	req := pbBytes(1, pbBytes(2, scope))
+
+	decode, _ := NewDecoder(FormatProtobuf)
+	at := time.UnixMilli(1700000000000)
+	d, err := decode(Message{Topic: "otlp", Value: req, Time: at})
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(d.Batch.Metrics) != 1 {
+		t.Fatalf("got %d metrics, want 1", len(d.Batch.Metrics))
+	}
+	m := d.Batch.Metrics[0]
+	if m.UUID != "GPU-1" || m.MetricName != "DCGM_FI_DEV_GPU_TEMP" || m.Value != 71.5 || !m.Timestamp.Equal(at) {
+		t.Errorf("unexpected metric: %+v", m)
+	}
+	if d.Dropped != 1 || len(d.Reasons) != 1 {
+		t.Errorf("dropped = %d, reasons = %v", d.Dropped, d.Reasons)
+	}
+
+	if _, err := decode(Message{Value: []byte{0xff}}); err == nil {
+		t.Error("expected error for invalid protobuf")
+	}
+}
+
+func TestDecodeLine(t *testing.T) {
+	value := strings.Join([]string{
+		// Telegraf prometheus input scraping dcgm-exporter
+		`DCGM_FI_DEV_GPU_TEMP,UUID=GPU-1,gpu=0,Hostname=node-1,modelName=H100,pci_bus_id=0000:01 gauge=45 1700000000000000000`,
+		// The pipeline's own InfluxDB schema
+		`DCGM_FI_DEV_POWER_USAGE,uuid=GPU-2,gpu_id=1,hostname=node-2 value=250.5,batch_id="b1" 1700000000000000000`,
+		// Telegraf nvidia_smi style: one line, several fields
+		`nvidia_smi,uuid=GPU-3,host=node-3 temperature_gpu=50i,fan_speed=30i,pstate="P0"`,
+		`cpu,host=node-3 usage_idle=99`,
+	}, "\n")
+
+	decode, _ := NewDecoder(FormatLine)
+	at := time.UnixMilli(1700000001000)
+	d, err := decode(Message{Topic: "telegraf", Value: []byte(value), Time: at})
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(d.Batch.Metrics) != 4 {
+		t.Fatalf("got %d metrics, want 4: %+v", len(d.Batch.Metrics), d.Batch.Metrics)
+	}
+
+	m := d.Batch.Metrics[0]
+	if m.MetricName != "DCGM_FI_DEV_GPU_TEMP" || m.UUID != "GPU-1" || m.GPUID != 0 || m.Hostname != "node-1" ||
+		m.ModelName != "H100" || m.Value != 45 || m.Labels["pci_bus_id"] != "0000:01" {
+		t.Errorf("unexpected dcgm metric: %+v", m)
+	}
+	if !m.Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("timestamp = %v", m.Timestamp)
+	}
+
+	m = d.Batch.Metrics[1]
+	if m.MetricName != "DCGM_FI_DEV_POWER_USAGE" || m.UUID != "GPU-2" || m.GPUID != 1 || m.Value != 250.5 {
+		t.Errorf("unexpected pipeline-schema metric: %+v", m)
+	}
+
+	names := d.Batch.Metrics[2].MetricName + "," + d.Batch.Metrics[3].MetricName
+	if names != "fan_speed,temperature_gpu" && names != "temperature_gpu,fan_speed" {
+		t.Errorf("field metric names = %s", names)
+	}
+	if d.Batch.Metrics[2].Hostname != "node-3" || !d.Batch.Metrics[2].Timestamp.Equal(at) {
+		t.Errorf("unexpected field metric: %+v", d.Batch.Metrics[2])
+	}
+
+	// pstate is a string; the cpu line has no GPU UUID
+	if d.Dropped != 2 || len(d.Reasons) != 2 {
+		t.Errorf("dropped = %d, reasons = %v", d.Dropped, d.Reasons)
+	}
+
+	if _, err := decode(Message{Value: []byte("bad line no fields")}); err == nil {
+		t.Error("expected error for invalid line protocol")
+	}
+}
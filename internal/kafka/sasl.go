@@ -0,0 +1,43 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// SASL mechanisms the consumer authenticates with.
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SASLMechanisms lists the supported SASL mechanisms.
+var SASLMechanisms = []string{SASLPlain, SASLScramSHA256, SASLScramSHA512}
+
+// SASL holds the credentials the consumer authenticates to brokers with.
+type SASL struct {
+	// Mechanism is SASLPlain, SASLScramSHA256 or SASLScramSHA512; empty
+	// authenticates no connections. PLAIN sends the password as is, so
+	// only use it over TLS.
+	Mechanism string
+
+	Username string
+	Password string
+}
+
+// mechanism returns the franz-go SASL mechanism for the credentials.
+func (s SASL) mechanism() (sasl.Mechanism, error) {
+	switch s.Mechanism {
+	case SASLPlain:
+		return plain.Auth{User: s.Username, Pass: s.Password}.AsMechanism(), nil
+	case SASLScramSHA256:
+		return scram.Auth{User: s.Username, Pass: s.Password}.AsSha256Mechanism(), nil
+	case SASLScramSHA512:
+		return scram.Auth{User: s.Username, Pass: s.Password}.AsSha512Mechanism(), nil
+	}
+	return nil, fmt.Errorf("kafka: unsupported SASL mechanism %q", s.Mechanism)
+}
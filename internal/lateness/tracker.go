@@ -0,0 +1,179 @@
+// Package lateness decides what the collector does with metrics that
+// arrive long after their timestamp, such as a backlog replayed after an
+// outage or a host whose exporter was stuck, and tracks the watermark: the
+// time before which no more on-time telemetry is expected.
+package lateness
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Stats reports a tracker's late metrics and watermark.
+type Stats struct {
+	// Threshold is how far behind a metric may be before it is late
+	Threshold string `json:"threshold"`
+
+	// Policy is what happens to late metrics
+	Policy string `json:"policy"`
+
+	// Late counts the late metrics received; Tagged, Rerouted and Dropped
+	// count those handled by the tag, reroute and drop policies
+	Late     int64 `json:"late"`
+	Tagged   int64 `json:"tagged"`
+	Rerouted int64 `json:"rerouted"`
+	Dropped  int64 `json:"dropped"`
+
+	// MaxLatenessSeconds is how far behind the latest late metric was at most
+	MaxLatenessSeconds float64 `json:"max_lateness_seconds"`
+
+	// LastLate is when a late metric was last received
+	LastLate time.Time `json:"last_late,omitempty"`
+
+	// Watermark is the time before which no more on-time telemetry is
+	// expected: the oldest of the latest timestamps of the hosts reporting,
+	// and never earlier than the threshold ago. Windows ending at or before
+	// it are complete, except for late metrics.
+	Watermark time.Time `json:"watermark"`
+
+	// Hosts is how many hosts reported on-time metrics within the threshold
+	Hosts int `json:"hosts"`
+
+	// SlowestHost is the host holding the watermark back, if it is later
+	// than the threshold ago
+	SlowestHost string `json:"slowest_host,omitempty"`
+}
+
+// hostMark is the latest on-time timestamp from a host and when it arrived.
+type hostMark struct {
+	latest time.Time
+	seen   time.Time
+}
+
+// Tracker classifies metrics as on time or late, applies the late-data
+// policy and tracks the watermark. It is safe for concurrent use.
+type Tracker struct {
+	cfg config.LateDataConfig
+
+	mu          sync.Mutex
+	hosts       map[string]hostMark
+	watermark   time.Time
+	late        int64
+	tagged      int64
+	rerouted    int64
+	dropped     int64
+	maxLateness time.Duration
+	lastLate    time.Time
+}
+
+// NewTracker creates a tracker applying cfg.
+func NewTracker(cfg config.LateDataConfig) *Tracker {
+	return &Tracker{cfg: cfg, hosts: make(map[string]hostMark)}
+}
+
+// Sort applies the policy to metrics received at now. It returns the
+// metrics to store, which is metrics itself unless some are rerouted or
+// dropped, and the late metrics to store in the late bucket under the
+// reroute policy. Under the tag policy late metrics are marked Late.
+func (t *Tracker) Sort(metrics []*models.GPUMetric, now time.Time) (store, reroute []*models.GPUMetric) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var kept []*models.GPUMetric
+	for i, m := range metrics {
+		lateness := now.Sub(m.Timestamp)
+		if lateness <= t.cfg.Threshold {
+			mark := t.hosts[m.Hostname]
+			if m.Timestamp.After(mark.latest) {
+				mark.latest = m.Timestamp
+			}
+			mark.seen = now
+			t.hosts[m.Hostname] = mark
+			if kept != nil {
+				kept = append(kept, m)
+			}
+			continue
+		}
+
+		t.late++
+		t.lastLate = now
+		if lateness > t.maxLateness {
+			t.maxLateness = lateness
+		}
+		keep := true
+		switch t.cfg.Policy {
+		case config.LatePolicyTag:
+			m.Late = true
+			t.tagged++
+		case config.LatePolicyReroute:
+			reroute = append(reroute, m)
+			t.rerouted++
+			keep = false
+		case config.LatePolicyDrop:
+			t.dropped++
+			keep = false
+		}
+		if !keep && kept == nil {
+			kept = append(make([]*models.GPUMetric, 0, len(metrics)), metrics[:i]...)
+		}
+		if keep && kept != nil {
+			kept = append(kept, m)
+		}
+	}
+
+	if kept == nil {
+		return metrics, reroute
+	}
+	return kept, reroute
+}
+
+// Stats returns the counters and the watermark as of now.
+func (t *Tracker) Stats(now time.Time) Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	st := Stats{
+		Threshold:          t.cfg.Threshold.String(),
+		Policy:             t.cfg.Policy,
+		Late:               t.late,
+		Tagged:             t.tagged,
+		Rerouted:           t.rerouted,
+		Dropped:            t.dropped,
+		MaxLatenessSeconds: t.maxLateness.Seconds(),
+		LastLate:           t.lastLate,
+	}
+
+	// Anything older than the threshold is late whatever the hosts send,
+	// and hosts silent for longer no longer hold the watermark back
+	watermark := now.Add(-t.cfg.Threshold)
+	var slowest string
+	var oldest time.Time
+	for host, mark := range t.hosts {
+		if now.Sub(mark.seen) > t.cfg.Threshold {
+			delete(t.hosts, host)
+			continue
+		}
+		if oldest.IsZero() || mark.latest.Before(oldest) {
+			slowest, oldest = host, mark.latest
+		}
+	}
+	if oldest.After(watermark) {
+		watermark = oldest
+	} else {
+		slowest = ""
+	}
+
+	// The watermark never moves back, even when a new host starts reporting
+	if watermark.Before(t.watermark) {
+		watermark = t.watermark
+	}
+	t.watermark = watermark
+
+	st.Watermark = watermark.UTC()
+	st.Hosts = len(t.hosts)
+	st.SlowestHost = slowest
+	return st
+}
@@ -0,0 +1,85 @@
+package lateness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func metricsAt(host string, ages ...time.Duration) []*models.GPUMetric {
+	metrics := make([]*models.GPUMetric, 0, len(ages))
+	for _, age := range ages {
+		metrics = append(metrics, &models.GPUMetric{UUID: "GPU-1", Hostname: host, MetricName: "DCGM_FI_DEV_GPU_UTIL", Timestamp: now.Add(-age)})
+	}
+	return metrics
+}
+
+func TestTrackerPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy           string
+		stored, rerouted int
+	}{
+		{config.LatePolicyAccept, 3, 0},
+		{config.LatePolicyTag, 3, 0},
+		{config.LatePolicyReroute, 1, 2},
+		{config.LatePolicyDrop, 1, 0},
+	} {
+		tr := NewTracker(config.LateDataConfig{Threshold: time.Hour, Policy: tc.policy})
+		metrics := metricsAt("host-1", 2*time.Hour, time.Minute, 3*time.Hour)
+
+		stored, rerouted := tr.Sort(metrics, now)
+		if len(stored) != tc.stored || len(rerouted) != tc.rerouted {
+			t.Errorf("%s: expected %d stored and %d rerouted, got %d and %d", tc.policy, tc.stored, tc.rerouted, len(stored), len(rerouted))
+		}
+		if tagged := metrics[0].Late && metrics[2].Late && !metrics[1].Late; tagged != (tc.policy == config.LatePolicyTag) {
+			t.Errorf("%s: unexpected late marks", tc.policy)
+		}
+
+		st := tr.Stats(now)
+		if st.Late != 2 || st.MaxLatenessSeconds != (3*time.Hour).Seconds() {
+			t.Errorf("%s: unexpected stats %+v", tc.policy, st)
+		}
+		handled := int64(2)
+		if tc.policy == config.LatePolicyAccept {
+			handled = 0
+		}
+		if st.Tagged+st.Rerouted+st.Dropped != handled {
+			t.Errorf("%s: expected %d late metrics counted by the policy, got %+v", tc.policy, handled, st)
+		}
+	}
+}
+
+func TestTrackerWatermark(t *testing.T) {
+	tr := NewTracker(config.LateDataConfig{Threshold: time.Hour, Policy: config.LatePolicyAccept})
+
+	// Without hosts the watermark is the threshold ago
+	if st := tr.Stats(now); !st.Watermark.Equal(now.Add(-time.Hour)) || st.Hosts != 0 {
+		t.Fatalf("unexpected initial watermark %+v", st)
+	}
+
+	// The slowest host holds the watermark back
+	tr.Sort(metricsAt("host-1", time.Minute), now)
+	tr.Sort(metricsAt("host-2", 10*time.Minute, 20*time.Minute), now)
+	st := tr.Stats(now)
+	if !st.Watermark.Equal(now.Add(-10*time.Minute)) || st.SlowestHost != "host-2" || st.Hosts != 2 {
+		t.Errorf("expected host-2 to hold the watermark at its latest metric, got %+v", st)
+	}
+
+	// A new host behind the watermark does not move it back
+	tr.Sort(metricsAt("host-3", 30*time.Minute), now)
+	if st := tr.Stats(now); !st.Watermark.Equal(now.Add(-10 * time.Minute)) {
+		t.Errorf("expected the watermark not to move back, got %v", st.Watermark)
+	}
+
+	// Hosts silent for longer than the threshold stop holding it back
+	later := now.Add(2 * time.Hour)
+	tr.Sort([]*models.GPUMetric{{Hostname: "host-1", Timestamp: later.Add(-time.Minute)}}, later)
+	st = tr.Stats(later)
+	if !st.Watermark.Equal(later.Add(-time.Minute)) || st.Hosts != 1 || st.SlowestHost != "host-1" {
+		t.Errorf("expected only host-1 left holding the watermark, got %+v", st)
+	}
+}
@@ -0,0 +1,119 @@
+// Package leader elects a single active replica among several instances of a
+// component, using a lease held on the MQ server or in a shared state store.
+package leader
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+)
+
+// Leader reports whether this instance currently leads.
+type Leader interface {
+	IsLeader() bool
+}
+
+// Always is a Leader for single-instance deployments that need no election.
+type Always struct{}
+
+// IsLeader always returns true.
+func (Always) IsLeader() bool { return true }
+
+// LeaseClient is the subset of mq.Client the elector needs.
+type LeaseClient interface {
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (mq.Lease, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// StoreLeases is a LeaseClient over the leases of a state store, for
+// deployments electing leaders without the MQ.
+type StoreLeases struct {
+	Store statestore.Store
+}
+
+// AcquireLease acquires or renews the lease in the store.
+func (s StoreLeases) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (mq.Lease, error) {
+	lease, err := s.Store.AcquireLease(ctx, name, holder, ttl)
+	if err != nil {
+		return mq.Lease{}, err
+	}
+	return mq.Lease{Name: lease.Name, Holder: lease.Holder, ExpiresAt: lease.ExpiresAt, Acquired: lease.Acquired}, nil
+}
+
+// ReleaseLease releases the lease in the store.
+func (s StoreLeases) ReleaseLease(ctx context.Context, name, holder string) error {
+	return s.Store.ReleaseLease(ctx, name, holder)
+}
+
+// Elector campaigns for a named lease and keeps renewing it while held.
+type Elector struct {
+	client LeaseClient
+	name   string
+	holder string
+	ttl    time.Duration
+	logger *log.Logger
+
+	leading atomic.Bool
+}
+
+// NewElector creates an elector for lease name on behalf of holder. The lease
+// is renewed every ttl/3, so a leader that stops renewing is replaced within ttl.
+func NewElector(client LeaseClient, name, holder string, ttl time.Duration, logger *log.Logger) *Elector {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Elector{client: client, name: name, holder: holder, ttl: ttl, logger: logger}
+}
+
+// IsLeader reports whether this instance held the lease at its last renewal.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run campaigns until ctx is done, then releases the lease if held.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		e.campaign(ctx)
+
+		select {
+		case <-ctx.Done():
+			if e.leading.Swap(false) {
+				releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := e.client.ReleaseLease(releaseCtx, e.name, e.holder); err != nil {
+					e.logger.Printf("Failed to release %s lease: %v", e.name, err)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign makes one acquire-or-renew attempt and logs leadership changes.
+// Any error drops leadership, since the lease may expire before the next attempt.
+func (e *Elector) campaign(ctx context.Context) {
+	attemptCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+	defer cancel()
+
+	lease, err := e.client.AcquireLease(attemptCtx, e.name, e.holder, e.ttl)
+	leading := err == nil && lease.Acquired
+
+	if was := e.leading.Swap(leading); was != leading {
+		switch {
+		case leading:
+			e.logger.Printf("Acquired %s lease as %s", e.name, e.holder)
+		case err != nil:
+			e.logger.Printf("Lost %s lease: %v", e.name, err)
+		default:
+			e.logger.Printf("Lost %s lease to %s", e.name, lease.Holder)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+)
+
+// fakeLeases grants the lease to whoever asks first, like the MQ server.
+type fakeLeases struct {
+	mu       sync.Mutex
+	holder   string
+	fail     bool
+	released []string
+}
+
+func (f *fakeLeases) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (mq.Lease, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return mq.Lease{}, errors.New("connection lost")
+	}
+	if f.holder == "" {
+		f.holder = holder
+	}
+	return mq.Lease{Name: name, Holder: f.holder, Acquired: f.holder == holder}, nil
+}
+
+func (f *fakeLeases) ReleaseLease(ctx context.Context, name, holder string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.holder == holder {
+		f.holder = ""
+	}
+	f.released = append(f.released, holder)
+	return nil
+}
+
+func TestElectorCampaign(t *testing.T) {
+	leases := &fakeLeases{}
+	logger := log.New(io.Discard, "", 0)
+	a := NewElector(leases, "sched", "a", time.Second, logger)
+	b := NewElector(leases, "sched", "b", time.Second, logger)
+
+	a.campaign(context.Background())
+	b.campaign(context.Background())
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("expected only a to lead, got a=%t b=%t", a.IsLeader(), b.IsLeader())
+	}
+
+	// Failing to renew drops leadership
+	leases.fail = true
+	a.campaign(context.Background())
+	if a.IsLeader() {
+		t.Error("expected a to stop leading when renewal fails")
+	}
+}
+
+func TestElectorRunReleasesOnExit(t *testing.T) {
+	leases := &fakeLeases{}
+	e := NewElector(leases, "sched", "a", 30*time.Millisecond, log.New(io.Discard, "", 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for !e.IsLeader() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !e.IsLeader() {
+		t.Fatal("expected elector to become leader")
+	}
+
+	cancel()
+	<-done
+	if e.IsLeader() {
+		t.Error("expected elector to stop leading after Run returns")
+	}
+	if len(leases.released) != 1 || leases.released[0] != "a" {
+		t.Errorf("expected lease released by a, got %v", leases.released)
+	}
+}
+
+func TestElectorStoreLeases(t *testing.T) {
+	leases := StoreLeases{Store: statestore.NewMemory(nil)}
+	logger := log.New(io.Discard, "", 0)
+	a := NewElector(leases, "alerts", "a", time.Second, logger)
+	b := NewElector(leases, "alerts", "b", time.Second, logger)
+
+	a.campaign(context.Background())
+	b.campaign(context.Background())
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("expected only a to lead, got a=%t b=%t", a.IsLeader(), b.IsLeader())
+	}
+	if err := leases.ReleaseLease(context.Background(), "alerts", "a"); err != nil {
+		t.Fatal(err)
+	}
+	b.campaign(context.Background())
+	if !b.IsLeader() {
+		t.Error("expected b to lead once a released the lease")
+	}
+}
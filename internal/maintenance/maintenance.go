@@ -0,0 +1,144 @@
+// Package maintenance holds the switches operators flip around storage
+// upgrades: the API's maintenance mode, which refuses writes while reads
+// keep working, and the collector's read-only mode, which stops consuming
+// so nothing is written at all.
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultMessage is shown while maintenance mode is on without a message.
+const DefaultMessage = "The telemetry pipeline is under maintenance; changes are disabled until it ends"
+
+// State describes whether maintenance mode is on.
+type State struct {
+	// Enabled is set while writes are refused
+	Enabled bool `json:"enabled"`
+
+	// Message is the banner shown to clients
+	Message string `json:"message,omitempty"`
+
+	// Since is when the mode last changed
+	Since time.Time `json:"since"`
+
+	// ChangedBy names who last changed the mode ("config" at startup)
+	ChangedBy string `json:"changed_by,omitempty"`
+}
+
+// Mode is the API's maintenance mode. While it is on, requests that change
+// data are refused with 503 and the banner message; reads are served as
+// usual and carry the message in an X-Maintenance header. It is safe for
+// concurrent use.
+type Mode struct {
+	mu    sync.RWMutex
+	state State
+}
+
+// NewMode creates a mode, on when enabled. The change is attributed to
+// the configuration.
+func NewMode(enabled bool, message string) *Mode {
+	m := &Mode{}
+	m.Set(enabled, message, "config")
+	return m
+}
+
+// State returns the current state.
+func (m *Mode) State() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set turns maintenance mode on or off. An empty message while on shows
+// DefaultMessage.
+func (m *Mode) Set(enabled bool, message, by string) State {
+	if enabled && message == "" {
+		message = DefaultMessage
+	}
+	if !enabled {
+		message = ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = State{Enabled: enabled, Message: message, Since: time.Now().UTC(), ChangedBy: by}
+	return m.state
+}
+
+// Middleware refuses requests that change data while maintenance mode is
+// on. Safe methods always pass, as do the listed paths, for reads sent as
+// POST and for the endpoint turning maintenance mode off.
+func (m *Mode) Middleware(exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := m.State()
+			if !state.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-Maintenance", state.Message)
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if slices.Contains(exempt, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"error": "maintenance", "message": state.Message})
+		})
+	}
+}
+
+// Switch is an on/off switch whose changes can be waited for, such as the
+// collector's read-only mode. It is safe for concurrent use.
+type Switch struct {
+	mu      sync.Mutex
+	on      bool
+	since   time.Time
+	changed chan struct{}
+}
+
+// NewSwitch creates a switch in the given position.
+func NewSwitch(on bool) *Switch {
+	return &Switch{on: on, since: time.Now().UTC(), changed: make(chan struct{})}
+}
+
+// Get returns the switch's position and a channel closed at its next change.
+func (s *Switch) Get() (on bool, changed <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.on, s.changed
+}
+
+// Since returns when the switch last changed position.
+func (s *Switch) Since() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.since
+}
+
+// Set moves the switch, waking those waiting for a change. It reports
+// whether the position changed.
+func (s *Switch) Set(on bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.on == on {
+		return false
+	}
+	s.on = on
+	s.since = time.Now().UTC()
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return true
+}
@@ -0,0 +1,73 @@
+package maintenance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModeMiddleware(t *testing.T) {
+	m := NewMode(false, "")
+	handler := m.Middleware("/api/v1/admin/maintenance")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/api/v1/annotations"); w.Code != http.StatusOK {
+		t.Fatalf("expected writes to pass while off, got %d", w.Code)
+	}
+
+	m.Set(true, "InfluxDB upgrade until 14:00 UTC", "admin")
+	w := serve(http.MethodPost, "/api/v1/annotations")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "InfluxDB upgrade") {
+		t.Errorf("expected a 503 with the banner, got %d %s", w.Code, w.Body.String())
+	}
+	w = serve(http.MethodGet, "/api/v1/gpus")
+	if w.Code != http.StatusOK || w.Header().Get("X-Maintenance") == "" {
+		t.Errorf("expected reads served with the banner header, got %d %v", w.Code, w.Header())
+	}
+	if w := serve(http.MethodPut, "/api/v1/admin/maintenance"); w.Code != http.StatusOK {
+		t.Errorf("expected the exempt path to pass, got %d", w.Code)
+	}
+
+	if st := m.Set(true, "", "admin"); st.Message != DefaultMessage {
+		t.Errorf("expected the default message, got %q", st.Message)
+	}
+	if st := m.Set(false, "ignored", "admin"); st.Message != "" {
+		t.Errorf("expected no message while off, got %q", st.Message)
+	}
+}
+
+func TestSwitch(t *testing.T) {
+	s := NewSwitch(false)
+	on, changed := s.Get()
+	if on {
+		t.Fatal("expected the switch off")
+	}
+
+	if s.Set(false) {
+		t.Error("expected setting the same position to report no change")
+	}
+	select {
+	case <-changed:
+		t.Fatal("changed closed without a change")
+	default:
+	}
+
+	if !s.Set(true) {
+		t.Error("expected a change")
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changed not closed after a change")
+	}
+	if on, _ := s.Get(); !on {
+		t.Error("expected the switch on")
+	}
+}
@@ -0,0 +1,211 @@
+package mq
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// delivery is a message handed to an acknowledging subscriber and not yet
+// acked.
+type delivery struct {
+	msg      *Message
+	size     int64     // Counted in the subscriber's pending bytes
+	attempts int       // Deliveries so far
+	due      time.Time // Redelivered at this time unless acked first
+}
+
+// ackState tracks the unacked deliveries of a subscriber created with
+// SubscribeOptions.Acknowledge.
+type ackState struct {
+	mu      sync.Mutex
+	pending map[string]*delivery // By message ID
+}
+
+func newAckState() *ackState {
+	return &ackState{pending: make(map[string]*delivery)}
+}
+
+// acknowledges reports whether the queue redelivers unacked messages.
+func (q *InMemoryQueue) acknowledges() bool {
+	return q.config.AckTimeout > 0
+}
+
+// deliver hands msg to sub, on one of its workers if it has them.
+func (q *InMemoryQueue) deliver(sub *subscriber, msg *Message) {
+	q.dispatch(sub, msg.Offset, func() { q.handle(sub, msg) })
+}
+
+// handle calls sub's handler with msg. An acknowledging subscriber's
+// message is tracked until acked, and a handler error counts as a nack;
+// otherwise a failed message is abandoned, after retries in place if the
+// subscriber asked for them.
+func (q *InMemoryQueue) handle(sub *subscriber, msg *Message) {
+	if sub.acks == nil {
+		size := messageSize(msg)
+		sub.pending.Add(size)
+		err := q.retryInPlace(sub, 1, func() error { return sub.handler(q.ctx, msg) })
+		sub.pending.Add(-size)
+		if err != nil {
+			q.abandon(sub, "handler failed: "+err.Error(), msg)
+		}
+		return
+	}
+
+	sub.acks.mu.Lock()
+	d := sub.acks.pending[msg.ID]
+	if d == nil {
+		d = &delivery{msg: msg, size: messageSize(msg)}
+		sub.acks.pending[msg.ID] = d
+		sub.pending.Add(d.size)
+		sub.unacked.Add(1)
+	}
+	d.attempts++
+	d.due = q.clock.Now().Add(q.config.AckTimeout)
+	sub.acks.mu.Unlock()
+
+	// Tracked first, so an ack racing back finds the delivery
+	err := sub.handler(q.ctx, msg)
+	q.handled(sub, 1, err)
+	if err != nil {
+		q.nack(sub, msg.ID)
+	}
+}
+
+// Ack acknowledges a message delivered to an acknowledging subscriber, so
+// it is not delivered again. Unknown messages, and subscribers that do not
+// acknowledge, are ignored. Group members ack for their group.
+func (q *InMemoryQueue) Ack(subscriberID, messageID string) error {
+	sub, err := q.ackingSubscriber(subscriberID)
+	if sub == nil || err != nil {
+		return err
+	}
+	sub.acks.mu.Lock()
+	sub.forgetLocked(messageID)
+	sub.acks.mu.Unlock()
+	q.resume(sub)
+	return nil
+}
+
+// forgetLocked stops tracking an acked or abandoned delivery, releasing
+// its pending bytes. The caller holds acks.mu.
+func (s *subscriber) forgetLocked(messageID string) {
+	if d, ok := s.acks.pending[messageID]; ok {
+		delete(s.acks.pending, messageID)
+		s.pending.Add(-d.size)
+		s.unacked.Add(-1)
+	}
+}
+
+// Nack reports a message an acknowledging subscriber failed to process. It
+// is delivered again after a backoff growing with each attempt, unless it
+// has already been redelivered MaxRetries times, when it is abandoned. Unknown messages, and
+// subscribers that do not acknowledge, are ignored.
+func (q *InMemoryQueue) Nack(subscriberID, messageID string) error {
+	sub, err := q.ackingSubscriber(subscriberID)
+	if sub == nil || err != nil {
+		return err
+	}
+	// A remote consumer's handler failed
+	sub.handlerErrors.Add(1)
+	q.nack(sub, messageID)
+	return nil
+}
+
+func (q *InMemoryQueue) nack(sub *subscriber, messageID string) {
+	sub.acks.mu.Lock()
+	d := sub.acks.pending[messageID]
+	if d == nil {
+		sub.acks.mu.Unlock()
+		return
+	}
+	if d.attempts <= q.config.MaxRetries {
+		d.due = q.clock.Now().Add(q.retryPolicy().Wait(d.attempts))
+		sub.acks.mu.Unlock()
+		return
+	}
+	sub.forgetLocked(messageID)
+	sub.acks.mu.Unlock()
+	q.abandon(sub, "nacked, retries exhausted", d.msg)
+	q.resume(sub)
+}
+
+// ackingSubscriber returns the subscriber whose acks subscriberID reports,
+// or nil if it does not acknowledge.
+func (q *InMemoryQueue) ackingSubscriber(subscriberID string) (*subscriber, error) {
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+	sub, exists := q.subscribers[q.positionID(subscriberID)]
+	if !exists {
+		return nil, ErrSubscriberNotFound
+	}
+	if sub.acks == nil {
+		return nil, nil
+	}
+	return sub, nil
+}
+
+// startRedeliverer redelivers the messages that were nacked or not acked in
+// time until the queue shuts down. It checks at the shorter of AckTimeout
+// and RetryDelay.
+func (q *InMemoryQueue) startRedeliverer() {
+	interval := q.config.AckTimeout
+	if d := q.config.RetryDelay; d > 0 && d < interval {
+		interval = d
+	}
+	ticker := q.clock.NewTicker(interval)
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-ticker.C():
+				q.redeliver()
+			}
+		}
+	}()
+}
+
+// redeliver delivers again every message now due, and abandons those
+// already redelivered MaxRetries times.
+func (q *InMemoryQueue) redeliver() {
+	now := q.clock.Now()
+	q.subMu.RLock()
+	subs := make([]*subscriber, 0, len(q.subscribers))
+	for _, sub := range q.subscribers {
+		// A paused subscriber's messages wait until it resumes
+		if sub.acks != nil && !sub.suspended.Load() {
+			subs = append(subs, sub)
+		}
+	}
+	q.subMu.RUnlock()
+
+	for _, sub := range subs {
+		var due, abandoned []*Message
+		sub.acks.mu.Lock()
+		for id, d := range sub.acks.pending {
+			if d.due.After(now) {
+				continue
+			}
+			if d.attempts > q.config.MaxRetries {
+				sub.forgetLocked(id)
+				abandoned = append(abandoned, d.msg)
+				continue
+			}
+			due = append(due, d.msg)
+		}
+		sub.acks.mu.Unlock()
+		q.abandon(sub, "not acked in time, retries exhausted", abandoned...)
+		q.resume(sub)
+
+		// Oldest first, as they were first delivered
+		sort.Slice(due, func(i, j int) bool { return due[i].Offset < due[j].Offset })
+		for _, msg := range due {
+			sub.redelivered.Add(1)
+			q.deliver(sub, msg)
+		}
+	}
+}
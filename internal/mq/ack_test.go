@@ -0,0 +1,117 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+func startAcking(t *testing.T) (*InMemoryQueue, *clock.Simulated) {
+	t.Helper()
+	sim := clock.NewSimulated(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	cfg := DefaultQueueConfig()
+	cfg.AckTimeout = 10 * time.Second
+	cfg.RetryDelay = time.Second
+	cfg.MaxRetries = 2
+	cfg.RetryJitter = 0
+	q := NewInMemoryQueue(cfg)
+	q.SetClock(sim)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+	sim.BlockUntil(1)
+	return q, sim
+}
+
+func subscriberInfo(q *InMemoryQueue, id string) SubscriberInfo {
+	for _, sub := range q.GetStats().Subscribers {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	return SubscriberInfo{}
+}
+
+func TestUnackedMessagesAreRedelivered(t *testing.T) {
+	q, sim := startAcking(t)
+	ctx := context.Background()
+
+	var deliveries atomic.Int64
+	q.SubscribeWithOptions(ctx, "consumer", OffsetEarliest, SubscribeOptions{Acknowledge: true}, func(context.Context, *Message) error {
+		deliveries.Add(1)
+		return nil
+	})
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return deliveries.Load() == 1 })
+	if info := subscriberInfo(q, "consumer"); info.Unacked != 1 {
+		t.Fatalf("expected 1 unacked message, got %+v", info)
+	}
+
+	// Not acked within the timeout: delivered again, MaxRetries times
+	for want := int64(2); want <= 3; want++ {
+		sim.Advance(10 * time.Second)
+		waitFor(t, func() bool { return deliveries.Load() == want })
+	}
+
+	// Then abandoned
+	sim.Advance(10 * time.Second)
+	waitFor(t, func() bool { return subscriberInfo(q, "consumer").Abandoned == 1 })
+	info := subscriberInfo(q, "consumer")
+	if info.Unacked != 0 || info.Redelivered != 2 || deliveries.Load() != 3 {
+		t.Errorf("expected 3 deliveries and nothing unacked, got %d deliveries, %+v", deliveries.Load(), info)
+	}
+}
+
+func TestNackedMessagesAreRetried(t *testing.T) {
+	q, sim := startAcking(t)
+	ctx := context.Background()
+
+	var deliveries atomic.Int64
+	q.SubscribeWithOptions(ctx, "consumer", OffsetEarliest, SubscribeOptions{Acknowledge: true}, func(ctx context.Context, msg *Message) error {
+		if deliveries.Add(1) == 1 {
+			return errors.New("temporary failure")
+		}
+		return q.Ack("consumer", msg.ID)
+	})
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return deliveries.Load() == 1 })
+
+	// A nack is retried after the retry delay rather than the ack timeout
+	sim.Advance(time.Second)
+	waitFor(t, func() bool { return deliveries.Load() == 2 })
+	waitFor(t, func() bool { return subscriberInfo(q, "consumer").Unacked == 0 })
+
+	sim.Advance(time.Minute)
+	if deliveries.Load() != 2 {
+		t.Errorf("expected an acked message not to be delivered again, got %d deliveries", deliveries.Load())
+	}
+}
+
+func TestAckIgnoredWithoutAcknowledge(t *testing.T) {
+	q, sim := startAcking(t)
+	ctx := context.Background()
+
+	var deliveries atomic.Int64
+	q.Subscribe(ctx, "consumer", OffsetEarliest, func(context.Context, *Message) error {
+		deliveries.Add(1)
+		return errors.New("failed")
+	})
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return deliveries.Load() == 1 })
+
+	if err := q.Nack("consumer", "unknown"); err != nil {
+		t.Errorf("expected a nack for a subscriber without acks to be ignored, got %v", err)
+	}
+	if err := q.Ack("nobody", "unknown"); err != ErrSubscriberNotFound {
+		t.Errorf("expected ErrSubscriberNotFound, got %v", err)
+	}
+	sim.Advance(time.Minute)
+	if deliveries.Load() != 1 || subscriberInfo(q, "consumer").Redelivered != 0 {
+		t.Errorf("expected no redelivery, got %d deliveries", deliveries.Load())
+	}
+}
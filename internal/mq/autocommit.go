@@ -0,0 +1,72 @@
+package mq
+
+import "fmt"
+
+// autoCommits reports whether the queue commits subscriber positions
+// without being asked.
+func (q *InMemoryQueue) autoCommits() bool {
+	return q.config.AutoCommitInterval > 0
+}
+
+// startCommitter commits every subscriber's position each
+// AutoCommitInterval until the queue shuts down.
+func (q *InMemoryQueue) startCommitter() {
+	ticker := q.clock.NewTicker(q.config.AutoCommitInterval)
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-ticker.C():
+				q.subMu.Lock()
+				subs := make([]*subscriber, 0, len(q.subscribers))
+				for _, sub := range q.subscribers {
+					subs = append(subs, sub)
+				}
+				q.commitPositionsLocked(subs...)
+				q.subMu.Unlock()
+			}
+		}
+	}()
+}
+
+// Release unsubscribes a consumer that went away without unsubscribing.
+// Under auto-commit its position, or its group's, is committed first, so
+// the consumer resumes there when it subscribes again.
+func (q *InMemoryQueue) Release(subscriberID string) error {
+	q.subMu.Lock()
+	if sub, ok := q.subscribers[q.positionID(subscriberID)]; ok && q.autoCommits() {
+		q.commitPositionsLocked(sub)
+	}
+	q.subMu.Unlock()
+	return q.Unsubscribe(subscriberID)
+}
+
+// commitPositionsLocked records the current offset of each subscriber as
+// its committed offset, persisting the offsets once if any moved. Manually
+// committing subscribers are skipped. A failure to persist is reported in
+// the stats. The caller holds subMu.
+func (q *InMemoryQueue) commitPositionsLocked(subs ...*subscriber) {
+	moved := false
+	for _, sub := range subs {
+		if sub.id == probeSubscriber || sub.manualCommit {
+			continue
+		}
+		// Deferred messages are redelivered after a restart
+		if committed, ok := q.committed[sub.id]; !ok || committed != sub.position() {
+			q.committed[sub.id] = sub.position()
+			moved = true
+		}
+	}
+	if !moved || q.wal == nil {
+		return
+	}
+	if err := q.wal.saveOffsets(q.committed); err != nil {
+		q.commitErr.Store(fmt.Sprintf("failed to persist committed offsets: %v", err))
+	} else {
+		q.commitErr.Store("")
+	}
+}
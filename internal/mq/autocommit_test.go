@@ -0,0 +1,130 @@
+package mq
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+func TestAutoCommitEveryInterval(t *testing.T) {
+	sim := clock.NewSimulated(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	cfg := DefaultQueueConfig()
+	cfg.AutoCommitInterval = 5 * time.Second
+	q := NewInMemoryQueue(cfg)
+	q.SetClock(sim)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Shutdown(context.Background())
+
+	var received atomic.Int64
+	q.Subscribe(context.Background(), "collector", OffsetEarliest, func(context.Context, *Message) error {
+		received.Add(1)
+		return nil
+	})
+	publishN(t, q, 3)
+	waitFor(t, func() bool { return received.Load() == 3 })
+	if _, ok := q.GetCommittedOffset("collector"); ok {
+		t.Fatal("expected nothing committed before the interval")
+	}
+
+	sim.BlockUntil(1)
+	sim.Advance(5 * time.Second)
+	waitFor(t, func() bool {
+		offset, ok := q.GetCommittedOffset("collector")
+		return ok && offset == 3
+	})
+}
+
+func TestAutoCommittedOffsetsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q := openAutoCommitQueue(t, dir)
+	var received atomic.Int64
+	count := func(context.Context, *Message) error {
+		received.Add(1)
+		return nil
+	}
+	q.Subscribe(ctx, "collector", OffsetEarliest, count)
+	q.Subscribe(ctx, "vanished", OffsetEarliest, count)
+	publishN(t, q, 4)
+	waitFor(t, func() bool { return received.Load() == 8 })
+
+	// A consumer whose connection dropped is released at its position
+	if err := q.Release("vanished"); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	publishN(t, q, 2)
+	waitFor(t, func() bool { return received.Load() == 10 })
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	q = openAutoCommitQueue(t, dir)
+	defer q.Shutdown(ctx)
+	for id, want := range map[string]Offset{"collector": 6, "vanished": 4} {
+		if offset, ok := q.GetCommittedOffset(id); !ok || offset != want {
+			t.Errorf("expected %s's position %d restored, got %d (%v)", id, want, offset, ok)
+		}
+	}
+
+	// A reconnecting subscriber resumes there rather than at its start offset
+	if err := q.SubscribeWithOptions(ctx, "vanished", OffsetLatest, SubscribeOptions{Resume: true}, count); err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := q.GetSubscriberOffset("vanished"); offset < 4 {
+		t.Errorf("expected vanished to resume at offset 4, got %d", offset)
+	}
+	waitFor(t, func() bool { return received.Load() == 12 })
+}
+
+// openAutoCommitQueue opens a persistent queue that commits positions every
+// second.
+func openAutoCommitQueue(t *testing.T, dir string) *InMemoryQueue {
+	t.Helper()
+	cfg := DefaultQueueConfig()
+	cfg.DataDir = dir
+	cfg.AutoCommitInterval = time.Second
+	q := NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start queue: %v", err)
+	}
+	return q
+}
+
+func TestReconnectResumesAtCommittedOffset(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// Without auto-commit only the consumer's own commit survives a restart
+	q := openQueue(t, dir, 1<<20)
+	var received atomic.Int64
+	count := func(context.Context, *Message) error {
+		received.Add(1)
+		return nil
+	}
+	q.Subscribe(ctx, "collector", OffsetEarliest, count)
+	publishN(t, q, 5)
+	waitFor(t, func() bool { return received.Load() == 5 })
+	if err := q.CommitOffset("collector", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Release("collector"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	q = openQueue(t, dir, 1<<20)
+	defer q.Shutdown(ctx)
+	if err := q.SubscribeWithOptions(ctx, "collector", OffsetLatest, SubscribeOptions{Resume: true}, count); err != nil {
+		t.Fatal(err)
+	}
+	// Messages 3 and 4 were delivered but not committed, so they come again
+	waitFor(t, func() bool { return received.Load() == 7 })
+}
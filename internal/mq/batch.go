@@ -0,0 +1,180 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// BatchHandler processes contiguous messages in one call.
+type BatchHandler func(ctx context.Context, msgs []*Message) error
+
+// batchState is a batch subscriber's handler and batch size.
+type batchState struct {
+	handler BatchHandler
+	max     int
+	calls   atomic.Int64 // Handler calls made
+}
+
+// SubscribeBatch is like Subscribe but hands the handler up to maxBatch
+// contiguous messages per call, moving the subscriber's offset past all of
+// them at once when the call returns. Messages the subscriber does not
+// receive are passed over within a batch. Scheduled messages falling due,
+// urgent messages delivered ahead of a backlog and redeliveries come in
+// batches of one.
+func (q *InMemoryQueue) SubscribeBatch(ctx context.Context, subscriberID string, startOffset Offset, maxBatch int, handler BatchHandler) error {
+	return q.SubscribeBatchWithOptions(ctx, subscriberID, startOffset, maxBatch, SubscribeOptions{}, handler)
+}
+
+// SubscribeBatchWithOptions is like SubscribeBatch but applies the given
+// subscriber options. A batch subscriber cannot join a consumer group. With
+// Acknowledge, each message in a batch is tracked until acked, and a
+// handler error nacks them all.
+func (q *InMemoryQueue) SubscribeBatchWithOptions(ctx context.Context, subscriberID string, startOffset Offset, maxBatch int, opts SubscribeOptions, handler BatchHandler) error {
+	if maxBatch <= 0 {
+		return perrors.Validation(fmt.Errorf("batch size must be positive, got %d", maxBatch))
+	}
+	if opts.Group != "" {
+		return perrors.Validation(fmt.Errorf("group %s members receive single messages", opts.Group))
+	}
+	if q.standby.Load() {
+		return ErrStandby
+	}
+
+	q.subMu.Lock()
+	defer q.subMu.Unlock()
+
+	if _, exists := q.subscribers[subscriberID]; exists {
+		return ErrSubscriberExists
+	}
+	if _, exists := q.members[subscriberID]; exists {
+		return ErrSubscriberExists
+	}
+	if err := q.checkPartitions(opts.Partitions); err != nil {
+		return err
+	}
+
+	batch := &batchState{max: maxBatch}
+	batch.handler = func(ctx context.Context, msgs []*Message) error {
+		batch.calls.Add(1)
+		return handler(ctx, msgs)
+	}
+	// Messages delivered on their own come as batches of one
+	single := func(ctx context.Context, msg *Message) error {
+		return batch.handler(ctx, []*Message{msg})
+	}
+	opts.batch = batch
+	_, err := q.addSubscriber(subscriberID, startOffset, opts, single)
+	return err
+}
+
+// offerBatch delivers sub the messages from offset on, up to its batch
+// size, in one handler call, then moves its offset past them unless a
+// concurrent seek moved it. ok is false when the budget held back the
+// first message, or it could not be read back from disk.
+func (q *InMemoryQueue) offerBatch(sub *subscriber, offset Offset, ahead [urgentLevels]Offset) (ok bool) {
+	var (
+		batch  []*Message
+		size   int64
+		passed int // Messages the offset moves past
+	)
+	limit := q.config.SubscriberMaxPendingBytes
+	messages, err := q.messagesFrom(offset, sub.batch.max)
+	if len(messages) == 0 && err != nil {
+		if errors.Is(err, ErrOffsetOutOfRange) {
+			return true // Trimmed while it was read back; skipped on the next pass
+		}
+		q.retryPageIn(sub)
+		return false // Left at the message until it can be read back
+	}
+scan:
+	for _, msg := range messages {
+		switch {
+		case sub.deliveredAhead(msg, ahead):
+		case q.deferLater(sub, msg):
+		case !sub.receives(msg):
+		case q.corrupted(sub, msg):
+		case !sub.filter.Match(msg.Metadata):
+			atomic.AddInt64(&sub.filtered, 1)
+		case len(batch) == 0:
+			if !q.admit(sub, msg) {
+				return false
+			}
+			batch, size = append(batch, msg), messageSize(msg)
+		case !sub.underUnacked(len(batch)):
+			break scan
+		case limit > 0 && sub.pending.Load()+size+messageSize(msg) > limit:
+			break scan // Left for the next batch
+		default:
+			batch, size = append(batch, msg), size+messageSize(msg)
+		}
+		passed++
+	}
+	if len(batch) > 0 {
+		q.dispatch(sub, batch[0].Offset, func() { q.deliverBatch(sub, batch, size) })
+	}
+
+	q.subMu.Lock()
+	if sub.offset == offset {
+		sub.offset += Offset(passed)
+	}
+	q.subMu.Unlock()
+	return true
+}
+
+// deliverBatch hands msgs to a batch subscriber, as handle hands it one
+// message.
+func (q *InMemoryQueue) deliverBatch(sub *subscriber, msgs []*Message, size int64) {
+	if sub.acks == nil {
+		sub.pending.Add(size)
+		err := q.retryInPlace(sub, len(msgs), func() error { return sub.batch.handler(q.ctx, msgs) })
+		sub.pending.Add(-size)
+		if err != nil {
+			q.abandon(sub, "handler failed: "+err.Error(), msgs...)
+		}
+		return
+	}
+
+	due := q.clock.Now().Add(q.config.AckTimeout)
+	sub.acks.mu.Lock()
+	for _, msg := range msgs {
+		d := sub.acks.pending[msg.ID]
+		if d == nil {
+			d = &delivery{msg: msg, size: messageSize(msg)}
+			sub.acks.pending[msg.ID] = d
+			sub.pending.Add(d.size)
+			sub.unacked.Add(1)
+		}
+		d.attempts++
+		d.due = due
+	}
+	sub.acks.mu.Unlock()
+
+	err := sub.batch.handler(q.ctx, msgs)
+	q.handled(sub, len(msgs), err)
+	if err != nil {
+		for _, msg := range msgs {
+			q.nack(sub, msg.ID)
+		}
+	}
+}
+
+// messagesFrom returns up to n retained messages from offset on, paging
+// spilled ones back in once logMu is released. It stops short of a message
+// that could not be, returning why.
+func (q *InMemoryQueue) messagesFrom(offset Offset, n int) ([]*Message, error) {
+	q.logMu.RLock()
+	idx := int(offset - q.base)
+	if idx < 0 || idx >= len(q.log) {
+		q.logMu.RUnlock()
+		return nil, nil
+	}
+	messages := slices.Clone(q.log[idx:min(idx+n, len(q.log))])
+	q.logMu.RUnlock()
+
+	return q.pageInAll(messages)
+}
@@ -0,0 +1,153 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// batchCollector subscribes a batch subscriber recording the payloads of
+// each batch it is handed.
+func batchCollector(t *testing.T, q *InMemoryQueue, id string, maxBatch int, opts SubscribeOptions) func() [][]string {
+	t.Helper()
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+	err := q.SubscribeBatchWithOptions(context.Background(), id, OffsetEarliest, maxBatch, opts, func(_ context.Context, msgs []*Message) error {
+		payloads := make([]string, len(msgs))
+		for i, msg := range msgs {
+			payloads[i] = string(msg.Payload)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, payloads)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(batches)
+	}
+}
+
+func TestSubscribeBatch(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	publishN(t, q, 5)
+	batches := batchCollector(t, q, "collector", 2, SubscribeOptions{})
+	waitFor(t, func() bool { return len(batches()) == 3 })
+
+	want := [][]string{{"msg-00", "msg-01"}, {"msg-02", "msg-03"}, {"msg-04"}}
+	if got := batches(); !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Fatalf("expected batches %v, got %v", want, got)
+	}
+	if info := subscriberInfo(q, "collector"); info.CurrentOffset != 5 || info.Batches != 3 {
+		t.Errorf("expected offset 5 after 3 batches, got %+v", info)
+	}
+
+	// Messages published later come in the next batch
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return len(batches()) == 4 })
+}
+
+func TestSubscribeBatchPassesOverFiltered(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	ctx := context.Background()
+	for _, host := range []string{"a", "b", "a", "a"} {
+		q.PublishWithMetadata(ctx, []byte("from-"+host), map[string]string{"hostname": host})
+	}
+	filter, _ := ParseFilter("hostname=a")
+	batches := batchCollector(t, q, "collector", 4, SubscribeOptions{Filter: filter})
+	waitFor(t, func() bool { return subscriberInfo(q, "collector").CurrentOffset == 4 })
+
+	want := [][]string{{"from-a", "from-a", "from-a"}}
+	if got := batches(); !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Fatalf("expected one batch without the filtered message, got %v", got)
+	}
+	if info := subscriberInfo(q, "collector"); info.Filtered != 1 {
+		t.Errorf("expected 1 filtered, got %+v", info)
+	}
+}
+
+func TestSubscribeBatchNacksFailedBatch(t *testing.T) {
+	q, sim := startAcking(t)
+	var calls atomic.Int64
+	err := q.SubscribeBatchWithOptions(context.Background(), "collector", OffsetEarliest, 10, SubscribeOptions{Acknowledge: true}, func(_ context.Context, msgs []*Message) error {
+		if calls.Add(1) == 1 {
+			return errors.New("sink down")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	if info := subscriberInfo(q, "collector"); info.Unacked != 1 {
+		t.Fatalf("expected the failed message unacked, got %+v", info)
+	}
+
+	sim.Advance(time.Second)
+	waitFor(t, func() bool { return calls.Load() == 2 })
+}
+
+func TestSubscribeBatchValidation(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	ctx := context.Background()
+	handler := func(context.Context, []*Message) error { return nil }
+
+	if err := q.SubscribeBatch(ctx, "collector", OffsetEarliest, 0, handler); !perrors.IsValidation(err) {
+		t.Errorf("expected a validation error for batch size 0, got %v", err)
+	}
+	opts := SubscribeOptions{Group: "collectors"}
+	if err := q.SubscribeBatchWithOptions(ctx, "collector", OffsetEarliest, 5, opts, handler); !perrors.IsValidation(err) {
+		t.Errorf("expected a validation error for a group member, got %v", err)
+	}
+	if err := q.SubscribeBatch(ctx, "collector", OffsetEarliest, 5, handler); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SubscribeBatch(ctx, "collector", OffsetEarliest, 5, handler); !errors.Is(err, ErrSubscriberExists) {
+		t.Errorf("expected ErrSubscriberExists, got %v", err)
+	}
+}
+
+func TestClientSubscribeBatch(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+	for _, payload := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+		if err := server.queue.Publish(ctx, []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	received := make(chan []*Message, 10)
+	err := client.SubscribeBatch(ctx, "collector", OffsetEarliest, 3, func(_ context.Context, msgs []*Message) error {
+		received <- msgs
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msgs := <-received:
+		if len(msgs) != 3 || string(msgs[0].Payload) != `{"n":1}` || msgs[2].Offset != 2 {
+			t.Errorf("expected the three messages in one batch, got %d", len(msgs))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a batch")
+	}
+	waitFor(t, func() bool { return subscriberInfo(server.queue, "collector").Unacked == 0 })
+
+	if err := client.SubscribeBatch(ctx, "other", OffsetEarliest, 0, nil); !perrors.IsValidation(err) {
+		t.Errorf("expected a validation error for batch size 0, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package mq
+
+// Policies for a subscriber whose pending bytes would exceed
+// QueueConfig.SubscriberMaxPendingBytes.
+const (
+	// SubscriberPause stops delivering new messages to the subscriber
+	// until acks bring it back under its budget; unacked messages are
+	// still redelivered
+	SubscriberPause = "pause"
+
+	// SubscriberSkipAhead gives up the subscriber's unacked messages and
+	// moves it to the end of the log, so it carries on with new messages
+	SubscriberSkipAhead = "skip_ahead"
+
+	// SubscriberDisconnect removes the subscriber, as Release does, and
+	// calls its SubscribeOptions.OnOverflow, which the server uses to close
+	// the connection. A consumer group's position is paused instead, since
+	// no one member is at fault.
+	SubscriberDisconnect = "disconnect"
+)
+
+// admit reports whether msg may be delivered to sub within its budget of
+// pending bytes, applying the subscriber overflow policy when it may not.
+// A subscriber with nothing pending is always admitted one message, however
+// large. One holding MaxUnacked unacked messages is paused until acks make
+// room, whatever the policy.
+func (q *InMemoryQueue) admit(sub *subscriber, msg *Message) bool {
+	if !sub.underUnacked(0) {
+		// Not an overflow: the consumer is only behind on acks
+		sub.paused.Store(true)
+		if !sub.underUnacked(0) {
+			return false
+		}
+		sub.paused.Store(false)
+	}
+
+	size := messageSize(msg)
+	if q.withinBudget(sub, size) {
+		return true
+	}
+	sub.overflows.Add(1)
+
+	switch q.config.SubscriberOverflowPolicy {
+	case SubscriberSkipAhead:
+		q.skipAhead(sub)
+		return false
+	case SubscriberDisconnect:
+		if sub.group == nil {
+			q.evict(sub)
+			return false
+		}
+	}
+	sub.paused.Store(true)
+	// An ack that raced the pause saw it unpaused and did not resume it
+	if q.withinBudget(sub, size) {
+		sub.paused.Store(false)
+		return true
+	}
+	return false
+}
+
+// withinBudget reports whether size more pending bytes fit sub's budget.
+func (q *InMemoryQueue) withinBudget(sub *subscriber, size int64) bool {
+	limit := q.config.SubscriberMaxPendingBytes
+	pending := sub.pending.Load()
+	return limit <= 0 || pending == 0 || pending+size <= limit
+}
+
+// underUnacked reports whether n more unacked messages fit sub's
+// SubscribeOptions.MaxUnacked.
+func (s *subscriber) underUnacked(n int) bool {
+	return s.maxUnacked <= 0 || s.unacked.Load()+int64(n) < int64(s.maxUnacked)
+}
+
+// resume wakes a paused subscriber once acks have brought it back under
+// its budget and its MaxUnacked.
+func (q *InMemoryQueue) resume(sub *subscriber) {
+	limit := q.config.SubscriberMaxPendingBytes
+	if !sub.paused.Load() || (limit > 0 && sub.pending.Load() >= limit) || !sub.underUnacked(0) {
+		return
+	}
+	if !sub.paused.CompareAndSwap(true, false) {
+		return
+	}
+	// A removed subscriber's notify channel is closed
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+	if q.subscribers[sub.id] == sub {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// skipAhead abandons sub's unacked messages and moves it past the newest
+// message, counting the messages it never received, deferred ones
+// included, as skipped.
+func (q *InMemoryQueue) skipAhead(sub *subscriber) {
+	if sub.acks != nil {
+		sub.acks.mu.Lock()
+		for id := range sub.acks.pending {
+			sub.forgetLocked(id)
+			sub.abandoned.Add(1)
+		}
+		sub.acks.mu.Unlock()
+	}
+
+	next := q.resolveOffset(OffsetLatest)
+	q.subMu.Lock()
+	if sub.offset < next {
+		sub.skipped += int64(next - sub.offset)
+		sub.offset = next
+	}
+	sub.skipped += int64(len(sub.deferred))
+	sub.deferred = nil
+	q.subMu.Unlock()
+}
+
+// evict removes sub for exceeding its budget, committing its position as
+// Release does, and tells its owner through OnOverflow.
+func (q *InMemoryQueue) evict(sub *subscriber) {
+	q.subMu.Lock()
+	if q.subscribers[sub.id] != sub {
+		q.subMu.Unlock()
+		return
+	}
+	if q.autoCommits() {
+		q.commitPositionsLocked(sub)
+	}
+	close(sub.notify)
+	delete(q.subscribers, sub.id)
+	q.subMu.Unlock()
+
+	q.evicted.Add(1)
+	if sub.onOverflow != nil {
+		sub.onOverflow()
+	}
+}
@@ -0,0 +1,137 @@
+package mq
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+// startBudgeted starts an acknowledging queue that allows each subscriber
+// three of publishN's 6 byte messages pending.
+func startBudgeted(t *testing.T, policy string) *InMemoryQueue {
+	t.Helper()
+	sim := clock.NewSimulated(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	cfg := DefaultQueueConfig()
+	cfg.AckTimeout = time.Minute
+	cfg.SubscriberMaxPendingBytes = 20
+	cfg.SubscriberOverflowPolicy = policy
+	q := NewInMemoryQueue(cfg)
+	q.SetClock(sim)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+	return q
+}
+
+// collector records the IDs of the messages delivered to a subscriber.
+type collector struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (c *collector) handle(_ context.Context, msg *Message) error {
+	c.mu.Lock()
+	c.ids = append(c.ids, msg.ID)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *collector) delivered() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.ids...)
+}
+
+func TestSubscriberPausesOverBudget(t *testing.T) {
+	q := startBudgeted(t, SubscriberPause)
+	publishN(t, q, 10)
+
+	var slow collector
+	var live atomic.Int64
+	ctx := context.Background()
+	q.SubscribeWithOptions(ctx, "backfill", OffsetEarliest, SubscribeOptions{Acknowledge: true}, slow.handle)
+	q.SubscribeWithOptions(ctx, "live", OffsetLatest, SubscribeOptions{Acknowledge: true}, func(_ context.Context, msg *Message) error {
+		live.Add(1)
+		return q.Ack("live", msg.ID)
+	})
+
+	waitFor(t, func() bool { return subscriberInfo(q, "backfill").Paused })
+	info := subscriberInfo(q, "backfill")
+	if len(slow.delivered()) != 3 || info.PendingBytes != 18 || info.Overflows != 1 {
+		t.Fatalf("expected 3 deliveries and 18 pending bytes before pausing, got %d and %+v", len(slow.delivered()), info)
+	}
+
+	// The paused backfill does not hold up a consumer that keeps up
+	publishN(t, q, 2)
+	waitFor(t, func() bool { return live.Load() == 2 })
+
+	// Acks resume it
+	for _, id := range slow.delivered() {
+		if err := q.Ack("backfill", id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, func() bool { return len(slow.delivered()) == 6 })
+	if info := subscriberInfo(q, "backfill"); !info.Paused || info.PendingBytes != 18 || info.CurrentOffset != 6 {
+		t.Fatalf("expected a pause after 3 more deliveries, got %+v", info)
+	}
+}
+
+func TestSubscriberSkipsAheadOverBudget(t *testing.T) {
+	q := startBudgeted(t, SubscriberSkipAhead)
+	publishN(t, q, 10)
+
+	var slow collector
+	q.SubscribeWithOptions(context.Background(), "backfill", OffsetEarliest, SubscribeOptions{Acknowledge: true}, slow.handle)
+	waitFor(t, func() bool { return subscriberInfo(q, "backfill").Skipped > 0 })
+
+	info := subscriberInfo(q, "backfill")
+	if info.Skipped != 7 || info.Abandoned != 3 || info.PendingBytes != 0 || info.CurrentOffset != 10 || info.Unacked != 0 {
+		t.Fatalf("expected 7 skipped and 3 abandoned at offset 10, got %+v", info)
+	}
+
+	// It carries on with new messages
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return len(slow.delivered()) == 4 })
+}
+
+func TestSubscriberDisconnectedOverBudget(t *testing.T) {
+	q := startBudgeted(t, SubscriberDisconnect)
+	publishN(t, q, 10)
+
+	evicted := make(chan struct{})
+	var slow collector
+	opts := SubscribeOptions{Acknowledge: true, OnOverflow: func() { close(evicted) }}
+	q.SubscribeWithOptions(context.Background(), "backfill", OffsetEarliest, opts, slow.handle)
+
+	select {
+	case <-evicted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscriber was not evicted")
+	}
+	stats := q.GetStats()
+	if stats.EvictedSubscribers != 1 || stats.SubscriberCount != 0 || len(slow.delivered()) != 3 {
+		t.Fatalf("expected the subscriber evicted after 3 deliveries, got %+v", stats)
+	}
+}
+
+func TestNonAcknowledgingSubscriberIsNotBudgeted(t *testing.T) {
+	q := startBudgeted(t, SubscriberPause)
+	publishN(t, q, 10)
+
+	var delivered atomic.Int64
+	q.Subscribe(context.Background(), "plain", OffsetEarliest, func(context.Context, *Message) error {
+		delivered.Add(1)
+		return nil
+	})
+	// Nothing stays pending without acks, so nothing exceeds the budget
+	waitFor(t, func() bool { return delivered.Load() == 10 })
+	if info := subscriberInfo(q, "plain"); info.PendingBytes != 0 || info.Paused || info.Overflows != 0 {
+		t.Fatalf("expected no pending bytes, got %+v", info)
+	}
+}
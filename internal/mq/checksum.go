@@ -0,0 +1,58 @@
+package mq
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sync/atomic"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// CorruptMessageError reports a message whose payload no longer matches the
+// checksum taken when it was published.
+type CorruptMessageError struct {
+	Offset   Offset
+	Checksum uint32 // Taken at publish
+	Actual   uint32 // Of the payload as read
+}
+
+func (e *CorruptMessageError) Error() string {
+	return fmt.Sprintf("message at offset %d is corrupt: payload checksum %08x, expected %08x", e.Offset, e.Actual, e.Checksum)
+}
+
+// ErrorKind marks corruption permanent: reading the message again returns
+// the same payload.
+func (e *CorruptMessageError) ErrorKind() perrors.Kind {
+	return perrors.KindPermanent
+}
+
+// payloadChecksum is the CRC-32 (IEEE) of a payload, as for WAL records.
+func payloadChecksum(payload []byte) uint32 {
+	return crc32.ChecksumIEEE(payload)
+}
+
+// Verify checks the message's payload against its checksum, returning a
+// *CorruptMessageError when they differ. Messages not Checksummed, from
+// logs written before checksums were taken, always pass.
+func (m *Message) Verify() error {
+	if !m.Checksummed {
+		return nil
+	}
+	if actual := payloadChecksum(m.Payload); actual != m.Checksum {
+		return &CorruptMessageError{Offset: m.Offset, Checksum: m.Checksum, Actual: actual}
+	}
+	return nil
+}
+
+// corrupted reports whether msg fails its checksum, so the subscriber is
+// never handed a garbage payload. The subscriber moves past it, so it is
+// counted and dead-lettered rather than lost without a trace.
+func (q *InMemoryQueue) corrupted(sub *subscriber, msg *Message) bool {
+	err := msg.Verify()
+	if err == nil {
+		return false
+	}
+	atomic.AddInt64(&sub.corrupt, 1)
+	q.deadLetter(sub.id, err.Error(), msg)
+	return true
+}
@@ -0,0 +1,105 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// corruptPayload flips a byte of the payload retained at offset.
+func corruptPayload(q *InMemoryQueue, offset Offset) {
+	q.logMu.Lock()
+	defer q.logMu.Unlock()
+	q.log[offset-q.base].Payload[0] ^= 0xff
+}
+
+func TestCorruptMessagesAreNotDelivered(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	publishN(t, q, 3)
+	corruptPayload(q, 1)
+
+	payloads := payloadCollector(q, "collector", OffsetEarliest, SubscribeOptions{})
+	batches := batchCollector(t, q, "batcher", 3, SubscribeOptions{})
+	waitFor(t, func() bool {
+		return subscriberInfo(q, "collector").CurrentOffset == 3 && subscriberInfo(q, "batcher").CurrentOffset == 3
+	})
+
+	if got := payloads(); !slices.Equal(got, []string{"msg-00", "msg-02"}) {
+		t.Errorf("expected the corrupt message passed over, got %v", got)
+	}
+	want := [][]string{{"msg-00", "msg-02"}}
+	if got := batches(); !slices.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("expected the corrupt message left out of the batch, got %v", got)
+	}
+	if info := subscriberInfo(q, "collector"); info.Corrupt != 1 {
+		t.Errorf("expected 1 corrupt message counted, got %+v", info)
+	}
+	letters := q.DeadLetters()
+	if len(letters) != 2 || letters[0].Message.Offset != 1 || letters[1].Message.Offset != 1 ||
+		!strings.Contains(letters[0].Reason, "is corrupt") {
+		t.Errorf("expected the corrupt message dead-lettered for each subscriber, got %+v", letters)
+	}
+
+	_, err := q.FetchMessage(1)
+	var corrupt *CorruptMessageError
+	if !errors.As(err, &corrupt) || corrupt.Offset != 1 || perrors.KindOf(err) != perrors.KindPermanent {
+		t.Errorf("expected a permanent CorruptMessageError fetching offset 1, got %v", err)
+	}
+	if _, err := q.FetchMessage(2); err != nil {
+		t.Errorf("expected an intact message to fetch, got %v", err)
+	}
+}
+
+func TestVerifyChecksummedFlag(t *testing.T) {
+	// A zero checksum is checked like any other
+	msg := &Message{Payload: []byte("msg-00"), Checksummed: true}
+	var corrupt *CorruptMessageError
+	if err := msg.Verify(); !errors.As(err, &corrupt) || corrupt.Checksum != 0 {
+		t.Errorf("expected a zero checksum checked, got %v", err)
+	}
+	// Messages from logs written before checksums were taken are not
+	legacy := &Message{Payload: []byte("msg-00")}
+	if err := legacy.Verify(); err != nil {
+		t.Errorf("expected a message without a checksum to pass, got %v", err)
+	}
+}
+
+func TestChecksumSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q := openQueue(t, dir, 1<<20)
+	publishN(t, q, 1)
+	q.Shutdown(ctx)
+
+	q = openQueue(t, dir, 1<<20)
+	defer q.Shutdown(ctx)
+	msg, err := q.FetchMessage(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msg.Checksummed || msg.Checksum != payloadChecksum([]byte("msg-00")) {
+		t.Errorf("expected the checksum reloaded from the log, got %08x", msg.Checksum)
+	}
+}
+
+func TestApplyReplicaRefusesCorruptMessages(t *testing.T) {
+	primary := startRetaining(t, DefaultQueueConfig())
+	standby := startStandby(t, DefaultQueueConfig())
+	publishN(t, primary, 2)
+
+	batch := replicaBatch(t, primary, context.Background(), 0, 10)
+	batch.Messages[1] = batch.Messages[1].Clone()
+	batch.Messages[1].Payload[0] ^= 0xff
+	var corrupt *CorruptMessageError
+	if _, err := standby.ApplyReplica(batch); !errors.As(err, &corrupt) {
+		t.Fatalf("expected a CorruptMessageError, got %v", err)
+	}
+	if stats := standby.GetStats(); stats.TotalMessages != 0 {
+		t.Errorf("expected none of the batch applied, got %+v", stats)
+	}
+}
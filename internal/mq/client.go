@@ -3,30 +3,60 @@ package mq
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
 )
 
 // Client is a TCP-based client for the message queue server.
 type Client struct {
-	addr         string
-	conn         net.Conn
-	mu           sync.Mutex
-	connected    atomic.Bool
-	reconnect    bool
-	timeout      time.Duration
-	handler      MessageHandler
-	handlerMu    sync.RWMutex
-	startOffset  Offset // Saved for reconnection
-	subscriberID string // Saved for reconnection
-	ctx          context.Context
-	cancel       context.CancelFunc
-	wg           sync.WaitGroup
+	addrs           []string // The server's, then its failovers
+	current         int      // Index in addrs of the last reached
+	transport       transport
+	conn            net.Conn
+	mu              sync.Mutex
+	connected       atomic.Bool
+	reconnect       bool
+	reconnectPolicy retry.Policy
+	timeout         time.Duration
+	handler         MessageHandler
+	batchHandler    BatchHandler // Set instead of handler by SubscribeBatch
+	handlerMu       sync.RWMutex
+	wireFormat      string          // Asked for on every connection
+	binaryWire      bool            // The connection speaks WireBinary; guarded by mu
+	subscription    ProtocolMessage // Saved for reconnection
+	paused          bool            // Subscription paused; restored on reconnection
+	tracker         *commitTracker  // Set under ManualCommit
+	workers         chan struct{}   // Held by each running handler; nil when unbounded
+	epoch           atomic.Int64    // Highest server epoch seen; see InMemoryQueue.Epoch
+	ctx             context.Context
+	cancel          context.CancelFunc
+	wg              sync.WaitGroup
+
+	// Pending request/response correlation, keyed by RequestID
+	pending   map[string]chan *ProtocolMessage
+	pendingMu sync.Mutex
+
+	// Active stats watches, keyed by the RequestID of the watch request
+	watches   map[string]*statsWatch
+	watchesMu sync.Mutex
+}
+
+// statsWatch tracks a WatchStats subscription so it can be restored on reconnect.
+type statsWatch struct {
+	interval time.Duration
+	ch       chan QueueStats
 }
 
 // ClientConfig configures the MQ client.
@@ -36,6 +66,34 @@ type ClientConfig struct {
 	Timeout        time.Duration `json:"timeout"`
 	AutoReconnect  bool          `json:"auto_reconnect"`
 	ReconnectDelay time.Duration `json:"reconnect_delay"`
+
+	// ReconnectPolicy overrides the fixed ReconnectDelay with a backoff policy
+	ReconnectPolicy *retry.Policy `json:"-"`
+
+	// Failover lists the host:port addresses of standby servers, tried in
+	// order when the server at Host and Port cannot be reached. A server
+	// that refuses a request as a standby is left for the next address, so
+	// the client settles on whichever has been promoted, and resubscribes
+	// there at its committed offset.
+	Failover []string `json:"failover"`
+
+	// WireFormat asks the server to exchange frames in WireBinary rather
+	// than WireJSON on every connection. A server that does not know it
+	// keeps to JSON, and so does the client.
+	WireFormat string `json:"wire_format"`
+
+	// ManualCommit subscribes for at-least-once processing: the server never
+	// auto-commits the subscription, a message whose handler fails is
+	// delivered again, and CommitProcessed commits only what the handler
+	// processed. Consumer group members share one committed offset, so a
+	// member's commit can pass a message another member is still handling.
+	ManualCommit bool `json:"manual_commit"`
+
+	// Workers runs the subscription's handler on up to this many messages,
+	// or batches, at once; the rest wait their turn. Without ManualCommit
+	// the server also holds no more unacked for the client than that. 0
+	// runs the handler on each message as it arrives.
+	Workers int `json:"workers"`
 }
 
 // DefaultClientConfig returns a client config with sensible defaults.
@@ -52,42 +110,209 @@ func DefaultClientConfig() ClientConfig {
 // NewClient creates a new MQ client.
 func NewClient(config ClientConfig) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
+
+	policy := retry.Policy{InitialBackoff: config.ReconnectDelay, Multiplier: 1}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 5 * time.Second
+	}
+	if config.ReconnectPolicy != nil {
+		policy = *config.ReconnectPolicy
+	}
+	if policy.Name == "" {
+		policy.Name = "mq-reconnect"
+	}
+
+	var tracker *commitTracker
+	if config.ManualCommit {
+		tracker = newCommitTracker()
+	}
+	var workers chan struct{}
+	if config.Workers > 0 {
+		workers = make(chan struct{}, config.Workers)
+	}
+
 	return &Client{
-		addr:      fmt.Sprintf("%s:%d", config.Host, config.Port),
-		reconnect: config.AutoReconnect,
-		timeout:   config.Timeout,
-		ctx:       ctx,
-		cancel:    cancel,
+		addrs:           append([]string{fmt.Sprintf("%s:%d", config.Host, config.Port)}, config.Failover...),
+		transport:       tcpTransport{},
+		tracker:         tracker,
+		workers:         workers,
+		wireFormat:      config.WireFormat,
+		reconnect:       config.AutoReconnect,
+		reconnectPolicy: policy,
+		timeout:         config.Timeout,
+		ctx:             ctx,
+		cancel:          cancel,
+		pending:         make(map[string]chan *ProtocolMessage),
+		watches:         make(map[string]*statsWatch),
+	}
+}
+
+// transport carries a Client's frames: length-prefixed on a TCP stream, or
+// one WebSocket message each for a WSClient.
+type transport interface {
+	// dial connects to the server at addr, one of the client's addresses
+	dial(ctx context.Context, addr string, timeout time.Duration) (net.Conn, error)
+
+	// writeFrame writes one frame, encoded in WireBinary when binaryWire
+	// is set, in a single write
+	writeFrame(conn net.Conn, data []byte, binaryWire bool) error
+
+	// readFrame reads the next frame. An error means the connection is
+	// unusable, except errFrameTooLarge, when the frame was passed over.
+	readFrame(conn net.Conn) ([]byte, error)
+}
+
+// tcpTransport frames with a 4-byte big-endian length prefix.
+type tcpTransport struct{}
+
+func (tcpTransport) dial(ctx context.Context, addr string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func (tcpTransport) writeFrame(conn net.Conn, data []byte, binaryWire bool) error {
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	_, err := conn.Write(frame)
+	return err
+}
+
+func (tcpTransport) readFrame(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length > maxFrameBytes {
+		if _, err := io.CopyN(io.Discard, conn, int64(length)); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %d bytes", errFrameTooLarge, length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(conn, data); err != nil {
+		return nil, err
 	}
+	return data, nil
 }
 
 // Protocol message types for client-server communication.
 const (
-	MsgTypePublish     = "publish"
-	MsgTypeSubscribe   = "subscribe"
-	MsgTypeUnsubscribe = "unsubscribe"
-	MsgTypeAck         = "ack"
-	MsgTypeNack        = "nack"
-	MsgTypeGetStats    = "get_stats"
-	// MQ pushes data to Collector
+	MsgTypePublish       = "publish"
+	MsgTypeSubscribe     = "subscribe"
+	MsgTypeUnsubscribe   = "unsubscribe"
+	MsgTypePause         = "pause_subscriber"
+	MsgTypeResume        = "resume_subscriber"
+	MsgTypeAck           = "ack"
+	MsgTypeNack          = "nack"
+	MsgTypeGetStats      = "get_stats"
+	MsgTypeWatchStats    = "watch_stats"
+	MsgTypeUnwatch       = "unwatch_stats"
+	MsgTypeGetOffset     = "get_offset"
+	MsgTypeSeekOffset    = "seek_offset"
+	MsgTypeSeekTimestamp = "seek_timestamp"
+	MsgTypeCommit        = "commit_offset"
+	MsgTypeFetch         = "fetch"
+	MsgTypeAcquireLease  = "acquire_lease"
+	MsgTypeReleaseLease  = "release_lease"
+	MsgTypeHeartbeat     = "heartbeat"
+	MsgTypeComponents    = "list_components"
+	MsgTypeReplicate     = "replicate"
+	MsgTypeDeadLetters   = "list_dead_letters"
+	// Client asks to switch the connection to the wire format in Format
+	// before sending anything else; see WireBinary
+	MsgTypeHello = "hello"
+	// MQ pushes data to Collector: one message, or a batch subscriber's
+	// messages as a JSON array of message frames in the payload
 	MsgTypeMessage  = "message"
+	MsgTypeBatch    = "batch"
 	MsgTypeResponse = "response"
 	MsgTypeError    = "error"
+	// MQ refuses a publish over the rate limit; see ThrottleError
+	MsgTypeThrottle = "throttle"
+	// MQ refuses a request because it is a standby; see ErrStandby
+	MsgTypeStandby = "standby"
+	// MQ pushes periodic stats to watchers
+	MsgTypeStats = "stats"
 )
 
 // ProtocolMessage is the wire format for client-server messages.
 type ProtocolMessage struct {
-	Type         string          `json:"type"`
-	SubscriberID string          `json:"subscriber_id,omitempty"`
-	MessageID    string          `json:"message_id,omitempty"`
-	Offset       Offset          `json:"offset,omitempty"`
-	Payload      json.RawMessage `json:"payload,omitempty"`
-	Error        string          `json:"error,omitempty"`
-	Success      bool            `json:"success,omitempty"`
+	Type         string            `json:"type"`
+	RequestID    string            `json:"request_id,omitempty"`
+	SubscriberID string            `json:"subscriber_id,omitempty"`
+	MessageID    string            `json:"message_id,omitempty"`
+	Offset       Offset            `json:"offset,omitempty"`
+	Payload      json.RawMessage   `json:"payload,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	ErrorKind    string            `json:"error_kind,omitempty"`
+	Success      bool              `json:"success,omitempty"`
+	IntervalMs   int64             `json:"interval_ms,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Filter       string            `json:"filter,omitempty"`
+	Partition    int               `json:"partition,omitempty"`
+	Partitions   []int             `json:"partitions,omitempty"`
+	Group        string            `json:"group,omitempty"`
+	Resume       bool              `json:"resume,omitempty"`
+	ManualCommit bool              `json:"manual_commit,omitempty"`
+	Paused       bool              `json:"paused,omitempty"`
+	MaxBatch     int               `json:"max_batch,omitempty"`
+	Workers      int               `json:"workers,omitempty"`
+	RetryAfterMs int64             `json:"retry_after_ms,omitempty"`
+	Format       string            `json:"format,omitempty"`
+	Epoch        int64             `json:"epoch,omitempty"`
 }
 
-// Connect establishes a connection to the MQ server.
+// ErrNotConnected is returned when an operation requires a live connection.
+var ErrNotConnected = perrors.New(perrors.KindTransient, "not connected")
+
+// ServerError is an error reported by the MQ server in a response.
+type ServerError struct {
+	Message string
+	Kind    perrors.Kind
+}
+
+func (e *ServerError) Error() string {
+	return "mq server: " + e.Message
+}
+
+// ErrorKind reports the classification the server attached to the error.
+func (e *ServerError) ErrorKind() perrors.Kind {
+	return e.Kind
+}
+
+// ThrottleError is returned when the server refuses a publish because the
+// publisher is over its rate limit. It is transient: the publish succeeds
+// if retried after Wait, which retry.Policy honors.
+type ThrottleError struct {
+	Message string
+	Wait    time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return "mq server: " + e.Message
+}
+
+// RetryAfter returns how long the server asked the publisher to wait.
+func (e *ThrottleError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// ErrorKind reports throttling as transient.
+func (e *ThrottleError) ErrorKind() perrors.Kind {
+	return perrors.KindTransient
+}
+
+// Connect establishes a connection to the MQ server, bounded by the client timeout.
 func (c *Client) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext establishes a connection to the MQ server, or the first of
+// its failovers that can be reached. Dialing each stops when ctx is done or
+// the client timeout elapses, whichever comes first.
+func (c *Client) ConnectContext(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -95,21 +320,75 @@ func (c *Client) Connect() error {
 		return nil
 	}
 
-	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	var (
+		conn net.Conn
+		err  error
+	)
+	// Start with the address last reached, so a client that failed over
+	// stays with the promoted standby
+	for i := range c.addrs {
+		idx := (c.current + i) % len(c.addrs)
+		if conn, err = c.transport.dial(ctx, c.addrs[idx], c.timeout); err == nil {
+			c.current = idx
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	if err != nil {
+		return perrors.Transient(fmt.Errorf("failed to connect to MQ server: %w", err))
+	}
+
+	binaryWire, err := c.hello(ctx, conn)
 	if err != nil {
-		return fmt.Errorf("failed to connect to MQ server: %w", err)
+		conn.Close()
+		return perrors.Transient(fmt.Errorf("failed to negotiate the wire format: %w", err))
 	}
 
 	c.conn = conn
+	c.binaryWire = binaryWire
 	c.connected.Store(true)
 
 	// Start message receiver
 	c.wg.Add(1)
-	go c.receiveLoop()
+	go c.receiveLoop(conn, binaryWire)
 
 	return nil
 }
 
+// hello asks the server on a new connection to switch to WireBinary when
+// the client was configured for it, and reports whether it did. Nothing
+// else is on the wire yet, so the reply is read here, in JSON. A server
+// refusing the hello, or not knowing it, keeps the connection in JSON.
+func (c *Client) hello(ctx context.Context, conn net.Conn) (bool, error) {
+	if c.wireFormat != WireBinary {
+		return false, nil
+	}
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	defer conn.SetDeadline(time.Time{})
+
+	data, err := json.Marshal(&ProtocolMessage{Type: MsgTypeHello, RequestID: uuid.New().String(), Format: WireBinary})
+	if err != nil {
+		return false, err
+	}
+	if err := c.transport.writeFrame(conn, data, false); err != nil {
+		return false, err
+	}
+	if data, err = c.transport.readFrame(conn); err != nil {
+		return false, err
+	}
+	var resp ProtocolMessage
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return false, err
+	}
+	return resp.Type == MsgTypeResponse && resp.Success && resp.Format == WireBinary, nil
+}
+
 // Close closes the connection to the MQ server.
 func (c *Client) Close() error {
 	c.cancel()
@@ -121,6 +400,7 @@ func (c *Client) Close() error {
 		c.conn = nil
 		c.mu.Unlock()
 		c.wg.Wait()
+		c.failPending()
 		return err
 	}
 	c.mu.Unlock()
@@ -132,155 +412,401 @@ func (c *Client) IsConnected() bool {
 	return c.connected.Load()
 }
 
-// sendMessage sends a protocol message to the server.
-func (c *Client) sendMessage(msg *ProtocolMessage) error {
+// sendMessage sends a protocol message to the server. The write is bounded by
+// the earlier of ctx's deadline and the client timeout, and is interrupted if
+// ctx is cancelled. A failed write may leave a partial frame on the wire, so
+// the connection is closed and the receive loop takes care of reconnecting.
+func (c *Client) sendMessage(ctx context.Context, msg *ProtocolMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if !c.connected.Load() {
-		return errors.New("not connected")
+		return ErrNotConnected
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.conn == nil {
-		return errors.New("connection is nil")
+	conn := c.conn
+	if conn == nil {
+		return ErrNotConnected
 	}
 
-	data, err := json.Marshal(msg)
+	// A server a standby was promoted over finds out from the epoch
+	msg.Epoch = c.epoch.Load()
+	data, err := encodeFrame(msg, c.binaryWire)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return perrors.Permanent(fmt.Errorf("failed to marshal message: %w", err))
 	}
 
-	// Write length-prefixed message
-	length := uint32(len(data))
-	header := []byte{
-		byte(length >> 24),
-		byte(length >> 16),
-		byte(length >> 8),
-		byte(length),
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
 	}
+	if err := conn.SetWriteDeadline(deadline); err != nil {
+		return perrors.Transient(err)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetWriteDeadline(time.Now())
+	})
+	defer stop()
 
-	if err := c.conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
-		return err
+	if err := c.transport.writeFrame(conn, data, c.binaryWire); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return perrors.Transient(fmt.Errorf("failed to write message: %w", err))
 	}
 
-	if _, err := c.conn.Write(header); err != nil {
-		return fmt.Errorf("failed to write header: %w", err)
+	return nil
+}
+
+// request sends a message and waits for the server response carrying the same RequestID.
+func (c *Client) request(ctx context.Context, msg *ProtocolMessage) (*ProtocolMessage, error) {
+	if msg.RequestID == "" {
+		msg.RequestID = uuid.New().String()
 	}
 
-	if _, err := c.conn.Write(data); err != nil {
-		return fmt.Errorf("failed to write message: %w", err)
+	ch := make(chan *ProtocolMessage, 1)
+	c.pendingMu.Lock()
+	c.pending[msg.RequestID] = ch
+	c.pendingMu.Unlock()
+
+	defer func() {
+		c.pendingMu.Lock()
+		delete(c.pending, msg.RequestID)
+		c.pendingMu.Unlock()
+	}()
+
+	if err := c.sendMessage(ctx, msg); err != nil {
+		return nil, err
 	}
 
-	return nil
+	// The client timeout only applies when the caller did not set a deadline
+	var timeout <-chan time.Time
+	if _, ok := ctx.Deadline(); !ok {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, ErrNotConnected
+		}
+		if resp.Type == MsgTypeThrottle {
+			return resp, &ThrottleError{Message: resp.Error, Wait: time.Duration(resp.RetryAfterMs) * time.Millisecond}
+		}
+		if resp.Type == MsgTypeStandby {
+			return resp, ErrStandby
+		}
+		if resp.Type == MsgTypeError || !resp.Success {
+			return resp, &ServerError{Message: resp.Error, Kind: perrors.ParseKind(resp.ErrorKind)}
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, ErrNotConnected
+	case <-timeout:
+		return nil, perrors.Transient(fmt.Errorf("request %s timed out after %v", msg.Type, c.timeout))
+	}
 }
 
-// receiveLoop continuously reads messages from the server.
-func (c *Client) receiveLoop() {
-	defer c.wg.Done()
+// failPending releases every waiting request after the connection is lost.
+func (c *Client) failPending() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
 
-	header := make([]byte, 4)
-	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		default:
-		}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
 
-		if !c.connected.Load() || c.conn == nil {
-			return
-		}
+// receiveLoop continuously reads messages from the server on a single
+// connection, whose frames are in WireBinary when binaryWire is set.
+func (c *Client) receiveLoop(conn net.Conn, binaryWire bool) {
+	defer c.wg.Done()
 
-		// Read message length
-		if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
-			continue
+	for {
+		// Close unblocks the read on shutdown
+		data, err := c.transport.readFrame(conn)
+		if errors.Is(err, errFrameTooLarge) {
+			continue // Passed over, like a frame that does not decode
 		}
-
-		_, err := c.conn.Read(header)
 		if err != nil {
 			if c.reconnect && c.ctx.Err() == nil {
-				c.handleReconnect()
+				c.failPending()
+				c.wg.Add(1)
+				go func() {
+					defer c.wg.Done()
+					c.handleReconnect(conn)
+				}()
+				return
 			}
-			continue
+			// Refuse requests first, so none waits for a reply that cannot come
+			c.connected.Store(false)
+			c.failPending()
+			return
 		}
 
-		length := uint32(header[0])<<24 | uint32(header[1])<<16 | uint32(header[2])<<8 | uint32(header[3])
-		if length > 10*1024*1024 { // 10MB max
+		var msg ProtocolMessage
+		if err := decodeFrame(data, &msg, binaryWire); err != nil {
 			continue
 		}
 
-		data := make([]byte, length)
-		if _, err := c.conn.Read(data); err != nil {
-			continue
+		c.checkEpoch(&msg)
+		c.handleMessage(&msg, binaryWire)
+		if msg.Type == MsgTypeStandby {
+			c.leaveStandby(conn)
 		}
+	}
+}
 
-		var msg ProtocolMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
-			continue
+// checkEpoch takes on the epoch of a reply from a later one than seen so
+// far. A reply from an earlier one comes from a server a standby was
+// promoted over, and is turned into a standby refusal, so the client moves
+// on as it would from a standby.
+func (c *Client) checkEpoch(msg *ProtocolMessage) {
+	switch msg.Type {
+	case MsgTypeResponse, MsgTypeError, MsgTypeThrottle, MsgTypeStandby:
+	default:
+		return
+	}
+	for {
+		seen := c.epoch.Load()
+		if msg.Epoch < seen {
+			*msg = ProtocolMessage{
+				Type:      MsgTypeStandby,
+				RequestID: msg.RequestID,
+				Error:     fmt.Sprintf("server in epoch %d was superseded by a promotion to epoch %d", msg.Epoch, seen),
+				ErrorKind: perrors.KindTransient.String(),
+				Epoch:     msg.Epoch,
+			}
+			return
+		}
+		if msg.Epoch == seen || c.epoch.CompareAndSwap(seen, msg.Epoch) {
+			return
 		}
+	}
+}
 
-		c.handleMessage(&msg)
+// leaveStandby drops a connection to a standby when the client has
+// failovers, so it reconnects to the next of its addresses rather than
+// waiting for the standby to be promoted. The read on the closed connection
+// fails as if it had been lost.
+func (c *Client) leaveStandby(conn net.Conn) {
+	if len(c.addrs) < 2 {
+		return
+	}
+	c.mu.Lock()
+	if c.conn == conn {
+		c.current = (c.current + 1) % len(c.addrs)
 	}
+	c.mu.Unlock()
+	conn.Close()
 }
 
 // handleMessage processes incoming messages from the server.
-func (c *Client) handleMessage(msg *ProtocolMessage) {
-	if msg.Type == MsgTypeMessage {
+func (c *Client) handleMessage(msg *ProtocolMessage, binaryWire bool) {
+	switch msg.Type {
+	case MsgTypeMessage:
 		c.handlerMu.RLock()
 		handler := c.handler
+		subscriberID := c.subscription.SubscriberID
 		c.handlerMu.RUnlock()
 
 		if handler != nil {
-			queueMsg := &Message{
-				ID:        msg.MessageID,
-				Payload:   msg.Payload,
-				Timestamp: time.Now(),
+			if c.tracker != nil {
+				c.tracker.delivered(msg.Offset)
 			}
+			c.run(func() {
+				err := handler(c.ctx, msg.message())
+				c.settle(subscriberID, msg, err)
+			})
+		}
 
-			go func() {
-				if err := handler(c.ctx, queueMsg); err != nil {
-					_ = c.Nack(msg.MessageID)
-				} else {
-					_ = c.Ack(msg.MessageID)
+	case MsgTypeBatch:
+		c.handlerMu.RLock()
+		handler := c.batchHandler
+		subscriberID := c.subscription.SubscriberID
+		c.handlerMu.RUnlock()
+
+		if handler == nil {
+			return
+		}
+		frames, err := decodeBatch(msg.Payload, binaryWire)
+		if err != nil || len(frames) == 0 {
+			return
+		}
+		msgs := make([]*Message, len(frames))
+		for i, frame := range frames {
+			msgs[i] = frame.message()
+			if c.tracker != nil {
+				c.tracker.delivered(frame.Offset)
+			}
+		}
+		c.run(func() {
+			switch err := handler(c.ctx, msgs); {
+			case err != nil && c.tracker != nil:
+				// Rewinding to the first message redelivers the rest
+				_ = c.nackOffset(c.ctx, subscriberID, frames[0])
+			default:
+				for _, frame := range frames {
+					c.settle(subscriberID, frame, err)
 				}
-			}()
+			}
+		})
+
+	case MsgTypeStats:
+		var stats QueueStats
+		if err := json.Unmarshal(msg.Payload, &stats); err != nil {
+			return
+		}
+		c.watchesMu.Lock()
+		defer c.watchesMu.Unlock()
+		if w := c.watches[msg.RequestID]; w != nil {
+			// Drop the update if the watcher is not keeping up; the next push supersedes it
+			select {
+			case w.ch <- stats:
+			default:
+			}
+		}
+
+	case MsgTypeResponse, MsgTypeError, MsgTypeThrottle, MsgTypeStandby:
+		if msg.RequestID == "" {
+			return
+		}
+		c.pendingMu.Lock()
+		defer c.pendingMu.Unlock()
+		if ch := c.pending[msg.RequestID]; ch != nil {
+			select {
+			case ch <- msg:
+			default:
+			}
 		}
 	}
 }
 
-// handleReconnect attempts to reconnect to the server.
-func (c *Client) handleReconnect() {
+// run calls a handler off the receive loop, once one of the client's
+// workers is free when it has them.
+func (c *Client) run(handle func()) {
+	go func() {
+		if c.workers != nil {
+			select {
+			case c.workers <- struct{}{}:
+			case <-c.ctx.Done():
+				return
+			}
+			defer func() { <-c.workers }()
+		}
+		handle()
+	}()
+}
+
+// message returns the queue message a message frame carries.
+func (msg *ProtocolMessage) message() *Message {
+	return &Message{
+		ID:        msg.MessageID,
+		Offset:    msg.Offset,
+		Partition: msg.Partition,
+		Payload:   msg.Payload,
+		Timestamp: time.Now(),
+		Metadata:  msg.Metadata,
+	}
+}
+
+// settle acks a message its handler processed, or has it delivered again
+// when the handler failed.
+func (c *Client) settle(subscriberID string, msg *ProtocolMessage, err error) {
+	switch {
+	case err != nil && c.tracker != nil:
+		_ = c.nackOffset(c.ctx, subscriberID, msg)
+	case err != nil:
+		_ = c.Nack(c.ctx, msg.MessageID)
+	default:
+		if c.tracker != nil {
+			c.tracker.handled(msg.Offset)
+		}
+		_ = c.Ack(c.ctx, msg.MessageID)
+	}
+}
+
+// handleReconnect attempts to reconnect to the server after the given connection failed.
+func (c *Client) handleReconnect(failed net.Conn) {
 	c.connected.Store(false)
 	c.mu.Lock()
-	if c.conn != nil {
+	if c.conn == failed {
 		c.conn.Close()
 		c.conn = nil
 	}
 	c.mu.Unlock()
 
-	for c.ctx.Err() == nil {
-		time.Sleep(5 * time.Second)
-		if err := c.Connect(); err == nil {
-			// Re-subscribe if we had a handler
-			c.handlerMu.RLock()
-			hasHandler := c.handler != nil
-			subID := c.subscriberID
-			offset := c.startOffset
-			c.handlerMu.RUnlock()
-			if hasHandler {
-				_ = c.sendSubscribe(subID, offset)
+	// Give the server a moment to recover before the first attempt
+	select {
+	case <-c.ctx.Done():
+		return
+	case <-time.After(c.reconnectPolicy.Backoff(1)):
+	}
+	if err := c.reconnectPolicy.Do(c.ctx, c.ConnectContext); err != nil {
+		return
+	}
+
+	// Re-subscribe if we had a handler
+	c.handlerMu.RLock()
+	hasHandler := c.handler != nil || c.batchHandler != nil
+	subscription, paused := c.subscription, c.paused
+	c.handlerMu.RUnlock()
+	if hasHandler {
+		// Pick up from the position the server committed for us, which
+		// survives its restarts when it persists the log
+		subscription.Resume = true
+		if c.tracker != nil {
+			// Without a commit yet, start at the first unprocessed message
+			// rather than the original start offset. Offset 0 means latest
+			// on the wire, and earliest is the same message or a later one.
+			if pos, ok := c.tracker.position(); ok {
+				subscription.Offset = pos
+				if pos == 0 {
+					subscription.Offset = OffsetEarliest
+				}
 			}
-			return
 		}
+		// Pause before anything is delivered
+		subscription.Paused = paused
+		_ = c.sendSubscribe(c.ctx, subscription)
 	}
+	c.restoreWatches()
 }
 
-// Publish publishes a message to the queue.
+// Publish publishes a message to the queue and waits for the server to accept it.
 func (c *Client) Publish(ctx context.Context, payload []byte) error {
+	return c.PublishWithMetadata(ctx, payload, nil)
+}
+
+// PublishWithMetadata publishes a message with metadata that subscriber
+// filters can evaluate on the server without decoding the payload.
+func (c *Client) PublishWithMetadata(ctx context.Context, payload []byte, metadata map[string]string) error {
+	_, err := c.PublishConfirmed(ctx, payload, metadata)
+	return err
+}
+
+// PublishConfirmed is like PublishWithMetadata but also returns the log
+// offset the server assigned to the message once it was accepted.
+func (c *Client) PublishConfirmed(ctx context.Context, payload []byte, metadata map[string]string) (Offset, error) {
 	msg := &ProtocolMessage{
-		Type:    MsgTypePublish,
-		Payload: payload,
+		Type:     MsgTypePublish,
+		Payload:  payload,
+		Metadata: metadata,
 	}
-	return c.sendMessage(msg)
+	resp, err := c.request(ctx, msg)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Offset, nil
 }
 
 // PublishBatch publishes multiple messages to the queue.
@@ -296,59 +822,399 @@ func (c *Client) PublishBatch(ctx context.Context, payloads [][]byte) error {
 // Subscribe subscribes to the queue with the given handler.
 // startOffset can be OffsetEarliest (-2), OffsetLatest (-1), or a specific offset.
 func (c *Client) Subscribe(ctx context.Context, subscriberID string, startOffset Offset, handler MessageHandler) error {
+	return c.SubscribeWithFilter(ctx, subscriberID, startOffset, "", handler)
+}
+
+// SubscribeWithFilter subscribes with a server-side filter expression (see Filter),
+// so only messages whose metadata matches are pushed to this client.
+func (c *Client) SubscribeWithFilter(ctx context.Context, subscriberID string, startOffset Offset, filter string, handler MessageHandler) error {
+	return c.SubscribePartitions(ctx, subscriberID, startOffset, filter, nil, handler)
+}
+
+// SubscribePartitions is like SubscribeWithFilter but only receives messages
+// in the given partitions (nil = all), so several consumers can split a
+// partitioned queue between them.
+func (c *Client) SubscribePartitions(ctx context.Context, subscriberID string, startOffset Offset, filter string, partitions []int, handler MessageHandler) error {
+	return c.subscribe(ctx, ProtocolMessage{
+		SubscriberID: subscriberID,
+		Offset:       startOffset,
+		Filter:       filter,
+		Partitions:   partitions,
+	}, handler, nil)
+}
+
+// SubscribeGroup joins the consumer group as subscriberID. Members of a
+// group share one position in the log and the server splits its partitions
+// between them, so each message reaches one member. startOffset and filter
+// apply when the first member creates the group.
+func (c *Client) SubscribeGroup(ctx context.Context, group, subscriberID string, startOffset Offset, filter string, handler MessageHandler) error {
+	return c.subscribe(ctx, ProtocolMessage{
+		SubscriberID: subscriberID,
+		Offset:       startOffset,
+		Filter:       filter,
+		Group:        group,
+	}, handler, nil)
+}
+
+// SubscribeBatch is like Subscribe but the server hands the handler up to
+// maxBatch contiguous messages per call (see InMemoryQueue.SubscribeBatch).
+// The messages are acked together when the handler returns nil and
+// delivered again when it fails.
+func (c *Client) SubscribeBatch(ctx context.Context, subscriberID string, startOffset Offset, maxBatch int, handler BatchHandler) error {
+	if maxBatch <= 0 {
+		return perrors.Validation(fmt.Errorf("batch size must be positive, got %d", maxBatch))
+	}
+	return c.subscribe(ctx, ProtocolMessage{
+		SubscriberID: subscriberID,
+		Offset:       startOffset,
+		MaxBatch:     maxBatch,
+	}, nil, handler)
+}
+
+// subscribe saves the subscription for reconnection and sends it. Exactly
+// one of handler and batch is set.
+func (c *Client) subscribe(ctx context.Context, subscription ProtocolMessage, handler MessageHandler, batch BatchHandler) error {
+	// Catch syntax errors locally rather than on every reconnect
+	if _, err := ParseFilter(subscription.Filter); err != nil {
+		return err
+	}
+
+	subscription.ManualCommit = c.tracker != nil
+	subscription.Workers = cap(c.workers)
+
 	c.handlerMu.Lock()
 	c.handler = handler
-	c.startOffset = startOffset
-	c.subscriberID = subscriberID
+	c.batchHandler = batch
+	c.subscription = subscription
+	c.paused = false
 	c.handlerMu.Unlock()
 
-	return c.sendSubscribe(subscriberID, startOffset)
+	err := c.sendSubscribe(ctx, subscription)
+	if errors.Is(err, ErrStandby) && c.reconnect && len(c.addrs) > 1 {
+		// The client is failing over, and subscribes again once it reaches
+		// the primary
+		return nil
+	}
+	return err
 }
 
-// sendSubscribe sends a subscribe message to the server.
-func (c *Client) sendSubscribe(subscriberID string, offset Offset) error {
-	msg := &ProtocolMessage{
-		Type:         MsgTypeSubscribe,
-		SubscriberID: subscriberID,
-		Offset:       offset,
-	}
-	return c.sendMessage(msg)
+// sendSubscribe registers the subscription with the server and waits for confirmation.
+func (c *Client) sendSubscribe(ctx context.Context, subscription ProtocolMessage) error {
+	subscription.Type = MsgTypeSubscribe
+	_, err := c.request(ctx, &subscription)
+	return err
 }
 
 // Unsubscribe unsubscribes from the queue.
-func (c *Client) Unsubscribe(subscriberID string) error {
+func (c *Client) Unsubscribe(ctx context.Context, subscriberID string) error {
 	c.handlerMu.Lock()
 	c.handler = nil
+	c.batchHandler = nil
 	c.handlerMu.Unlock()
 
 	msg := &ProtocolMessage{
 		Type:         MsgTypeUnsubscribe,
 		SubscriberID: subscriberID,
 	}
-	return c.sendMessage(msg)
+	_, err := c.request(ctx, msg)
+	return err
+}
+
+// PauseSubscriber stops the server delivering to the subscriber, which
+// keeps its position and subscription, until ResumeSubscriber. Messages
+// already on their way are still handled. The pause outlasts a reconnection.
+func (c *Client) PauseSubscriber(ctx context.Context, subscriberID string) error {
+	return c.setPaused(ctx, subscriberID, true)
 }
 
-// Ack acknowledges a message.
-func (c *Client) Ack(messageID string) error {
+// ResumeSubscriber resumes delivery to a subscriber PauseSubscriber paused.
+func (c *Client) ResumeSubscriber(ctx context.Context, subscriberID string) error {
+	return c.setPaused(ctx, subscriberID, false)
+}
+
+func (c *Client) setPaused(ctx context.Context, subscriberID string, paused bool) error {
+	msgType := MsgTypeResume
+	if paused {
+		msgType = MsgTypePause
+	}
+	// Recorded first, so a pause sent as the connection drops takes
+	// effect when it is restored
+	c.handlerMu.Lock()
+	c.paused = paused
+	c.handlerMu.Unlock()
+	_, err := c.request(ctx, &ProtocolMessage{Type: msgType, SubscriberID: subscriberID})
+	return err
+}
+
+// Ack acknowledges a message. Acks are not confirmed by the server.
+func (c *Client) Ack(ctx context.Context, messageID string) error {
 	msg := &ProtocolMessage{
 		Type:      MsgTypeAck,
 		MessageID: messageID,
 	}
-	return c.sendMessage(msg)
+	return c.sendMessage(ctx, msg)
 }
 
 // Nack negatively acknowledges a message (triggers retry).
-func (c *Client) Nack(messageID string) error {
+func (c *Client) Nack(ctx context.Context, messageID string) error {
 	msg := &ProtocolMessage{
 		Type:      MsgTypeNack,
 		MessageID: messageID,
 	}
-	return c.sendMessage(msg)
+	return c.sendMessage(ctx, msg)
+}
+
+// GetStats requests a queue statistics snapshot from the server.
+func (c *Client) GetStats(ctx context.Context) (QueueStats, error) {
+	resp, err := c.request(ctx, &ProtocolMessage{Type: MsgTypeGetStats})
+	if err != nil {
+		return QueueStats{}, err
+	}
+
+	var stats QueueStats
+	if err := json.Unmarshal(resp.Payload, &stats); err != nil {
+		return QueueStats{}, fmt.Errorf("failed to decode stats: %w", err)
+	}
+	return stats, nil
 }
 
-// GetStats returns queue statistics (requires server response).
-func (c *Client) GetStats() QueueStats {
-	// This would require a synchronous request-response pattern
-	// For now, return empty - implement with response channel if needed
-	return QueueStats{}
+// GetOffset returns the current and committed log position of a subscriber.
+func (c *Client) GetOffset(ctx context.Context, subscriberID string) (OffsetInfo, error) {
+	resp, err := c.request(ctx, &ProtocolMessage{
+		Type:         MsgTypeGetOffset,
+		SubscriberID: subscriberID,
+	})
+	if err != nil {
+		return OffsetInfo{}, err
+	}
+
+	var info OffsetInfo
+	if err := json.Unmarshal(resp.Payload, &info); err != nil {
+		return OffsetInfo{}, fmt.Errorf("failed to decode offset info: %w", err)
+	}
+	return info, nil
+}
+
+// SeekOffset moves an active subscriber to the given offset. OffsetEarliest and
+// OffsetLatest are resolved by the server; other offsets are clamped to the log.
+func (c *Client) SeekOffset(ctx context.Context, subscriberID string, offset Offset) error {
+	_, err := c.request(ctx, &ProtocolMessage{
+		Type:         MsgTypeSeekOffset,
+		SubscriberID: subscriberID,
+		Payload:      offsetPayload(offset),
+	})
+	return err
+}
+
+// SeekToTimestamp moves an active subscriber to the first message the server
+// received at or after ts, and returns that offset. It fails with an
+// out-of-range error if messages after ts may already have been trimmed.
+func (c *Client) SeekToTimestamp(ctx context.Context, subscriberID string, ts time.Time) (Offset, error) {
+	data, _ := json.Marshal(ts)
+	resp, err := c.request(ctx, &ProtocolMessage{
+		Type:         MsgTypeSeekTimestamp,
+		SubscriberID: subscriberID,
+		Payload:      data,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var offset Offset
+	if err := json.Unmarshal(resp.Payload, &offset); err != nil {
+		return 0, fmt.Errorf("failed to decode offset: %w", err)
+	}
+	return offset, nil
+}
+
+// CommitOffset records that the subscriber has processed everything before offset.
+// A later subscription with OffsetCommitted resumes from this position.
+func (c *Client) CommitOffset(ctx context.Context, subscriberID string, offset Offset) error {
+	_, err := c.request(ctx, &ProtocolMessage{
+		Type:         MsgTypeCommit,
+		SubscriberID: subscriberID,
+		Payload:      offsetPayload(offset),
+	})
+	return err
+}
+
+// Fetch returns the message at offset without subscribing. It fails with a
+// not-found error if the offset is not in the server's log.
+func (c *Client) Fetch(ctx context.Context, offset Offset) (*Message, error) {
+	resp, err := c.request(ctx, &ProtocolMessage{
+		Type:    MsgTypeFetch,
+		Payload: offsetPayload(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(resp.Payload, &msg); err != nil {
+		return nil, fmt.Errorf("failed to decode fetched message: %w", err)
+	}
+	if err := msg.Verify(); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// AcquireLease asks the server for the named lease on behalf of holder, or
+// renews it if holder already owns it. The returned lease reports whether it
+// was acquired and, if not, who holds it until when.
+func (c *Client) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (Lease, error) {
+	payload, _ := json.Marshal(LeaseRequest{Name: name, Holder: holder, TTLMs: ttl.Milliseconds()})
+	resp, err := c.request(ctx, &ProtocolMessage{
+		Type:    MsgTypeAcquireLease,
+		Payload: payload,
+	})
+	if err != nil {
+		return Lease{}, err
+	}
+
+	var lease Lease
+	if err := json.Unmarshal(resp.Payload, &lease); err != nil {
+		return Lease{}, fmt.Errorf("failed to decode lease: %w", err)
+	}
+	return lease, nil
+}
+
+// ReleaseLease gives up the named lease if holder owns it.
+func (c *Client) ReleaseLease(ctx context.Context, name, holder string) error {
+	payload, _ := json.Marshal(LeaseRequest{Name: name, Holder: holder})
+	_, err := c.request(ctx, &ProtocolMessage{
+		Type:    MsgTypeReleaseLease,
+		Payload: payload,
+	})
+	return err
+}
+
+// SendHeartbeat announces the component hb describes to the server, which
+// lists it until ttl passes without another heartbeat.
+func (c *Client) SendHeartbeat(ctx context.Context, hb Heartbeat, ttl time.Duration) error {
+	hb.TTLMs = ttl.Milliseconds()
+	payload, _ := json.Marshal(hb)
+	_, err := c.request(ctx, &ProtocolMessage{
+		Type:    MsgTypeHeartbeat,
+		Payload: payload,
+	})
+	return err
+}
+
+// Components returns the components the server has heard from within their
+// TTL, starting with the server itself.
+func (c *Client) Components(ctx context.Context) ([]Heartbeat, error) {
+	resp, err := c.request(ctx, &ProtocolMessage{Type: MsgTypeComponents})
+	if err != nil {
+		return nil, err
+	}
+
+	var components []Heartbeat
+	if err := json.Unmarshal(resp.Payload, &components); err != nil {
+		return nil, fmt.Errorf("failed to decode components: %w", err)
+	}
+	return components, nil
+}
+
+// DeadLetters lists the messages the server's subscribers gave up on, oldest
+// first.
+func (c *Client) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	resp, err := c.request(ctx, &ProtocolMessage{Type: MsgTypeDeadLetters})
+	if err != nil {
+		return nil, err
+	}
+
+	var letters []DeadLetter
+	if err := json.Unmarshal(resp.Payload, &letters); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letters: %w", err)
+	}
+	return letters, nil
+}
+
+// Replicate fetches up to maxBatch messages of the server's log from offset
+// on, with its committed offsets, for a standby. The server waits up to
+// wait for a message to be published when it has none, so wait must be
+// shorter than the client timeout.
+func (c *Client) Replicate(ctx context.Context, offset Offset, maxBatch int, wait time.Duration) (ReplicaBatch, error) {
+	resp, err := c.request(ctx, &ProtocolMessage{
+		Type:       MsgTypeReplicate,
+		Payload:    offsetPayload(offset),
+		MaxBatch:   maxBatch,
+		IntervalMs: wait.Milliseconds(),
+	})
+	if err != nil {
+		return ReplicaBatch{}, err
+	}
+
+	var batch ReplicaBatch
+	if err := json.Unmarshal(resp.Payload, &batch); err != nil {
+		return ReplicaBatch{}, fmt.Errorf("failed to decode replica batch: %w", err)
+	}
+	return batch, nil
+}
+
+// offsetPayload encodes an offset as a message payload. The Offset field is
+// omitted on the wire when zero, so explicit positions travel in the payload.
+func offsetPayload(offset Offset) json.RawMessage {
+	data, _ := json.Marshal(offset)
+	return data
+}
+
+// WatchStats asks the server to push queue statistics every interval.
+// The returned channel receives snapshots until ctx is cancelled, at which
+// point the watch is cancelled on the server and the channel is closed.
+// Slow readers miss intermediate snapshots rather than blocking the client.
+func (c *Client) WatchStats(ctx context.Context, interval time.Duration) (<-chan QueueStats, error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	msg := &ProtocolMessage{
+		Type:       MsgTypeWatchStats,
+		RequestID:  uuid.New().String(),
+		IntervalMs: interval.Milliseconds(),
+	}
+
+	w := &statsWatch{
+		interval: interval,
+		ch:       make(chan QueueStats, 1),
+	}
+	c.watchesMu.Lock()
+	c.watches[msg.RequestID] = w
+	c.watchesMu.Unlock()
+
+	if _, err := c.request(ctx, msg); err != nil {
+		c.watchesMu.Lock()
+		delete(c.watches, msg.RequestID)
+		c.watchesMu.Unlock()
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.ctx.Done():
+		}
+		c.watchesMu.Lock()
+		delete(c.watches, msg.RequestID)
+		c.watchesMu.Unlock()
+		close(w.ch)
+		_ = c.sendMessage(c.ctx, &ProtocolMessage{Type: MsgTypeUnwatch, RequestID: msg.RequestID})
+	}()
+
+	return w.ch, nil
+}
+
+// restoreWatches re-registers active stats watches after a reconnect.
+func (c *Client) restoreWatches() {
+	c.watchesMu.Lock()
+	defer c.watchesMu.Unlock()
+
+	for id, w := range c.watches {
+		_ = c.sendMessage(c.ctx, &ProtocolMessage{
+			Type:       MsgTypeWatchStats,
+			RequestID:  id,
+			IntervalMs: w.interval.Milliseconds(),
+		})
+	}
 }
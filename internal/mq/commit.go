@@ -0,0 +1,102 @@
+package mq
+
+import (
+	"context"
+	"sync"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// ErrNotManualCommit is returned by CommitProcessed on a client without
+// ClientConfig.ManualCommit, which does not track what it processed.
+var ErrNotManualCommit = perrors.New(perrors.KindPermanent, "client does not commit manually")
+
+// commitTracker follows the messages delivered to a manually committing
+// client, so it commits only offsets whose messages were all handled.
+type commitTracker struct {
+	mu        sync.Mutex
+	pending   map[Offset]struct{} // Delivered but not handled, including failed messages
+	next      Offset              // One past the newest offset delivered
+	seen      bool                // Set once a message was delivered
+	committed Offset              // Last offset committed, -1 if none
+}
+
+func newCommitTracker() *commitTracker {
+	return &commitTracker{pending: make(map[Offset]struct{}), committed: -1}
+}
+
+// delivered notes a message handed to the handler. A redelivered message
+// is pending until one of its deliveries is handled.
+func (t *commitTracker) delivered(offset Offset) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[offset] = struct{}{}
+	if !t.seen || offset >= t.next {
+		t.next, t.seen = offset+1, true
+	}
+}
+
+// handled notes a message the handler processed without error.
+func (t *commitTracker) handled(offset Offset) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, offset)
+}
+
+// position returns the offset every message before which was handled: the
+// oldest pending message, or one past the newest delivered. It returns
+// false before the first delivery.
+func (t *commitTracker) position() (Offset, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.seen {
+		return 0, false
+	}
+	pos := t.next
+	for offset := range t.pending {
+		if offset < pos {
+			pos = offset
+		}
+	}
+	return pos, true
+}
+
+// CommitProcessed commits the offset up to which the subscriber's handler
+// has processed every message, so a restart or a reconnect delivers again
+// the messages that failed or were still being handled. It returns the
+// committed offset, and false when there was nothing new to commit. The
+// client must have been created with ManualCommit.
+func (c *Client) CommitProcessed(ctx context.Context, subscriberID string) (Offset, bool, error) {
+	t := c.tracker
+	if t == nil {
+		return 0, false, ErrNotManualCommit
+	}
+	pos, ok := t.position()
+	t.mu.Lock()
+	last := t.committed
+	t.mu.Unlock()
+	if !ok || pos <= last {
+		return last, false, nil
+	}
+
+	if err := c.CommitOffset(ctx, subscriberID, pos); err != nil {
+		return last, false, err
+	}
+	t.mu.Lock()
+	if pos > t.committed {
+		t.committed = pos
+	}
+	t.mu.Unlock()
+	return pos, true, nil
+}
+
+// nackOffset reports a message the handler failed, so the server delivers
+// it again. Like Nack it is not confirmed.
+func (c *Client) nackOffset(ctx context.Context, subscriberID string, msg *ProtocolMessage) error {
+	return c.sendMessage(ctx, &ProtocolMessage{
+		Type:         MsgTypeNack,
+		SubscriberID: subscriberID,
+		MessageID:    msg.MessageID,
+		Payload:      offsetPayload(msg.Offset),
+	})
+}
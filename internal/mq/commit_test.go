@@ -0,0 +1,146 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCommitTracker(t *testing.T) {
+	tr := newCommitTracker()
+	if _, ok := tr.position(); ok {
+		t.Fatal("expected no position before the first delivery")
+	}
+
+	// Filtered offsets are never delivered, so 6 follows 4
+	for _, offset := range []Offset{3, 4, 6} {
+		tr.delivered(offset)
+	}
+	tr.handled(4)
+	tr.handled(6)
+	if pos, _ := tr.position(); pos != 3 {
+		t.Errorf("expected position 3 while 3 is unhandled, got %d", pos)
+	}
+	tr.handled(3)
+	if pos, _ := tr.position(); pos != 7 {
+		t.Errorf("expected position 7 with everything handled, got %d", pos)
+	}
+
+	// A redelivery of a handled message holds the position until handled again
+	tr.delivered(4)
+	if pos, _ := tr.position(); pos != 4 {
+		t.Errorf("expected position 4 during a redelivery, got %d", pos)
+	}
+}
+
+func TestManualCommitRedeliversFailedMessages(t *testing.T) {
+	server, _ := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+	q := server.GetQueue()
+
+	client := NewClient(ClientConfig{
+		Host:         "127.0.0.1",
+		Port:         server.TCPAddr().(*net.TCPAddr).Port,
+		Timeout:      2 * time.Second,
+		ManualCommit: true,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if _, _, err := client.CommitProcessed(ctx, "collector"); err != nil {
+		t.Fatalf("expected nothing to commit before a delivery, got %v", err)
+	}
+
+	var mu sync.Mutex
+	deliveries := make(map[Offset]int)
+	handled := make(map[Offset]bool)
+	err := client.Subscribe(ctx, "collector", OffsetEarliest, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deliveries[msg.Offset]++
+		if msg.Offset == 2 && deliveries[msg.Offset] == 1 {
+			return context.DeadlineExceeded
+		}
+		handled[msg.Offset] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		q.Publish(ctx, []byte(`{}`))
+	}
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(handled) == 5
+	})
+	mu.Lock()
+	if deliveries[2] < 2 {
+		t.Errorf("expected the failed message to be delivered again, got %d deliveries", deliveries[2])
+	}
+	mu.Unlock()
+
+	var offset Offset
+	waitFor(t, func() bool {
+		offset, _, err = client.CommitProcessed(ctx, "collector")
+		return err == nil && offset == 5
+	})
+	if committed, ok := q.GetCommittedOffset("collector"); !ok || committed != 5 {
+		t.Errorf("expected committed offset 5, got %d (ok=%v)", committed, ok)
+	}
+	if _, moved, _ := client.CommitProcessed(ctx, "collector"); moved {
+		t.Error("expected nothing new to commit")
+	}
+}
+
+func TestManualCommitSubscriber(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.AutoCommitInterval = time.Hour
+	q := NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Shutdown(context.Background())
+
+	ctx := context.Background()
+	var manual, auto atomic.Int64
+	q.SubscribeWithOptions(ctx, "manual", OffsetEarliest, SubscribeOptions{ManualCommit: true}, func(context.Context, *Message) error {
+		manual.Add(1)
+		return nil
+	})
+	q.Subscribe(ctx, "auto", OffsetEarliest, func(context.Context, *Message) error {
+		auto.Add(1)
+		return nil
+	})
+	publishN(t, q, 3)
+	waitFor(t, func() bool { return manual.Load() == 3 && auto.Load() == 3 })
+
+	// Only the manual subscriber is rewound
+	if err := q.Rewind("auto", 1); err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := q.GetSubscriberOffset("auto"); offset != 3 {
+		t.Errorf("expected the auto-committed subscriber to stay at 3, got %d", offset)
+	}
+	if err := q.Rewind("manual", 1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return manual.Load() == 5 })
+
+	// Releasing commits the auto-committed position only
+	q.Release("manual")
+	q.Release("auto")
+	if _, ok := q.GetCommittedOffset("manual"); ok {
+		t.Error("expected no auto-commit for the manual subscriber")
+	}
+	if offset, ok := q.GetCommittedOffset("auto"); !ok || offset != 3 {
+		t.Errorf("expected auto-committed offset 3, got %d (ok=%v)", offset, ok)
+	}
+}
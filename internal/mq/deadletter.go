@@ -0,0 +1,99 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+)
+
+// deadLettersFile holds a persistent queue's dead letters, replaced
+// atomically like the committed offsets.
+const deadLettersFile = "dead-letters.json"
+
+// DeadLetter is a message a subscriber gave up on, kept so operators can
+// see what failed and why, and publish it again once the cause is fixed.
+type DeadLetter struct {
+	Subscriber string    `json:"subscriber"`
+	Reason     string    `json:"reason"`
+	At         time.Time `json:"at"`
+	Message    *Message  `json:"message"`
+}
+
+// deadLetters keeps the newest QueueConfig.MaxDeadLetters dead letters.
+type deadLetters struct {
+	mu      sync.Mutex
+	letters []DeadLetter // Oldest first
+	total   int64        // Dead-lettered since the queue started
+	err     error        // Why they last could not be persisted
+}
+
+// deadLetter records msgs as given up on by the subscriber, for reason.
+// With a DataDir the dead letters kept are persisted alongside the log.
+func (q *InMemoryQueue) deadLetter(subscriberID, reason string, msgs ...*Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	d := &q.deadLetters
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.total += int64(len(msgs))
+	if q.config.MaxDeadLetters <= 0 {
+		return
+	}
+	now := q.clock.Now()
+	for _, msg := range msgs {
+		letter := DeadLetter{Subscriber: subscriberID, Reason: reason, At: now, Message: msg.Clone()}
+		d.letters = appendCapped(d.letters, letter, q.config.MaxDeadLetters)
+	}
+	if q.wal != nil {
+		d.err = q.wal.saveDeadLetters(d.letters)
+	}
+}
+
+// DeadLetters returns the dead letters kept, oldest first.
+func (q *InMemoryQueue) DeadLetters() []DeadLetter {
+	q.deadLetters.mu.Lock()
+	defer q.deadLetters.mu.Unlock()
+	return slices.Clone(q.deadLetters.letters)
+}
+
+// restoreDeadLetters takes on the dead letters recovered from disk.
+func (q *InMemoryQueue) restoreDeadLetters(letters []DeadLetter) {
+	if n := q.config.MaxDeadLetters; len(letters) > n {
+		letters = letters[len(letters)-n:]
+	}
+	q.deadLetters.mu.Lock()
+	q.deadLetters.letters = letters
+	q.deadLetters.mu.Unlock()
+}
+
+// deadLetterStats reports how many messages were dead-lettered, and why
+// they last could not be persisted.
+func (q *InMemoryQueue) deadLetterStats() (total int64, kept int, persistErr string) {
+	d := &q.deadLetters
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		persistErr = d.err.Error()
+	}
+	return d.total, len(d.letters), persistErr
+}
+
+// readDeadLetters loads the dead letters, if any were saved.
+func (w *wal) readDeadLetters() ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := statestore.GetJSON(context.Background(), w.state, deadLettersFile, &letters)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return nil, nil
+	}
+	return letters, err
+}
+
+// saveDeadLetters replaces the dead letters on disk.
+func (w *wal) saveDeadLetters(letters []DeadLetter) error {
+	return statestore.PutJSON(context.Background(), w.state, deadLettersFile, letters)
+}
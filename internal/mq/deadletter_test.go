@@ -0,0 +1,100 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFailedMessagesDeadLetteredWithoutRetryInPlace(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+
+	delivered := make(chan string, 2)
+	err := q.Subscribe(context.Background(), "collector", OffsetEarliest, func(_ context.Context, msg *Message) error {
+		delivered <- string(msg.Payload)
+		if string(msg.Payload) == "msg-00" {
+			return errors.New("sink down")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, q, 2)
+
+	// Not retried, so the next message is not held back
+	for _, want := range []string{"msg-00", "msg-01"} {
+		if got := <-delivered; got != want {
+			t.Fatalf("expected %s delivered, got %s", want, got)
+		}
+	}
+	waitFor(t, func() bool { return len(q.DeadLetters()) == 1 })
+	letter := q.DeadLetters()[0]
+	if letter.Subscriber != "collector" || string(letter.Message.Payload) != "msg-00" || letter.Reason != "handler failed: sink down" {
+		t.Errorf("unexpected dead letter %+v", letter)
+	}
+	if stats := q.GetStats(); stats.DeadLetters != 1 || stats.DeadLettersKept != 1 {
+		t.Errorf("expected 1 dead letter counted, got %d (%d kept)", stats.DeadLetters, stats.DeadLettersKept)
+	}
+	if info := subscriberInfo(q, "collector"); info.Abandoned != 1 || info.Redelivered != 0 {
+		t.Errorf("expected the message abandoned without retries, got %+v", info)
+	}
+}
+
+func TestAbandonedMessagesDeadLetteredAndPersisted(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultQueueConfig()
+	cfg.DataDir = dir
+	cfg.AckTimeout = time.Minute
+	cfg.MaxRetries = 0
+	cfg.MaxDeadLetters = 2
+	q := NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := SubscribeOptions{Acknowledge: true}
+	q.SubscribeWithOptions(context.Background(), "consumer", OffsetEarliest, opts, func(context.Context, *Message) error {
+		return errors.New("bad message")
+	})
+	publishN(t, q, 3)
+	waitFor(t, func() bool { return q.GetStats().DeadLetters == 3 })
+	if err := q.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The newest two survive a restart
+	q = NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Shutdown(context.Background())
+	letters := q.DeadLetters()
+	if len(letters) != 2 || string(letters[0].Message.Payload) != "msg-01" || string(letters[1].Message.Payload) != "msg-02" {
+		t.Fatalf("expected msg-01 and msg-02 recovered, got %+v", letters)
+	}
+	if letters[0].Reason != "nacked, retries exhausted" {
+		t.Errorf("unexpected reason %q", letters[0].Reason)
+	}
+}
+
+func TestClientListsDeadLetters(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	server.queue.Subscribe(context.Background(), "exporter", OffsetEarliest, func(context.Context, *Message) error {
+		return errors.New("export failed")
+	})
+	if err := client.Publish(context.Background(), []byte(`{"n":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var letters []DeadLetter
+	waitFor(t, func() bool {
+		var err error
+		letters, err = client.DeadLetters(context.Background())
+		return err == nil && len(letters) == 1
+	})
+	if letters[0].Subscriber != "exporter" || string(letters[0].Message.Payload) != `{"n":1}` {
+		t.Errorf("unexpected dead letter %+v", letters[0])
+	}
+}
@@ -0,0 +1,94 @@
+package mq
+
+import "time"
+
+// MetaIdempotencyKey identifies a publish, such as a batch ID, so that a
+// publisher retrying after a lost response does not append it twice: the
+// queue drops a message whose key it saw within QueueConfig.DedupWindow and
+// returns the offset of the first.
+const MetaIdempotencyKey = "idempotency_key"
+
+// dedupWindow remembers the offsets of recently published idempotency
+// keys, forgetting each DedupWindow after it was published, or sooner
+// when more than DedupMaxKeys are remembered. It is guarded by logMu.
+type dedupWindow struct {
+	window  time.Duration
+	maxKeys int
+	offsets map[string]Offset
+	order   []dedupEntry // Keys in publish order, oldest first
+}
+
+type dedupEntry struct {
+	key  string
+	seen time.Time
+}
+
+// newDedupWindow returns a window for config, or nil when deduplication
+// is off.
+func newDedupWindow(config QueueConfig) *dedupWindow {
+	if config.DedupWindow <= 0 {
+		return nil
+	}
+	return &dedupWindow{
+		window:  config.DedupWindow,
+		maxKeys: config.DedupMaxKeys,
+		offsets: make(map[string]Offset),
+	}
+}
+
+// lookup returns the offset key was published at, if it is in the window.
+func (d *dedupWindow) lookup(key string, now time.Time) (Offset, bool) {
+	if d == nil || key == "" {
+		return 0, false
+	}
+	d.expire(now)
+	offset, ok := d.offsets[key]
+	return offset, ok
+}
+
+// add remembers that key was published at offset.
+func (d *dedupWindow) add(key string, offset Offset, seen time.Time) {
+	if d == nil || key == "" {
+		return
+	}
+	if _, ok := d.offsets[key]; ok {
+		return
+	}
+	d.offsets[key] = offset
+	d.order = append(d.order, dedupEntry{key: key, seen: seen})
+	if d.maxKeys > 0 && len(d.order) > d.maxKeys {
+		d.forget(len(d.order) - d.maxKeys)
+	}
+}
+
+// expire forgets the keys published more than the window before now.
+func (d *dedupWindow) expire(now time.Time) {
+	cutoff := now.Add(-d.window)
+	n := 0
+	for n < len(d.order) && !d.order[n].seen.After(cutoff) {
+		n++
+	}
+	d.forget(n)
+}
+
+// forget drops the n oldest keys.
+func (d *dedupWindow) forget(n int) {
+	if n == 0 {
+		return
+	}
+	for _, e := range d.order[:n] {
+		delete(d.offsets, e.key)
+	}
+	// Reslicing leaves the dropped entries to the next reallocation by add
+	d.order = d.order[n:]
+}
+
+// duplicateLocked returns the offset key was first published at when it is
+// within the dedup window, counting the duplicate. The caller holds logMu.
+func (q *InMemoryQueue) duplicateLocked(key string, now time.Time) (Offset, bool) {
+	offset, ok := q.dedup.lookup(key, now)
+	if ok {
+		q.duplicates++
+	}
+	return offset, ok
+}
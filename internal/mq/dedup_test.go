@@ -0,0 +1,97 @@
+package mq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+// publishKeyed appends a message with an idempotency key and returns its offset.
+func publishKeyed(t *testing.T, q *InMemoryQueue, key string) Offset {
+	t.Helper()
+	offset, err := q.append(context.Background(), []byte("batch-"+key), map[string]string{MetaIdempotencyKey: key})
+	if err != nil {
+		t.Fatalf("publish %s failed: %v", key, err)
+	}
+	return offset
+}
+
+func TestDedupDropsRepublishedKeys(t *testing.T) {
+	sim := clock.NewSimulated(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	cfg := DefaultQueueConfig()
+	cfg.DedupWindow = time.Minute
+	cfg.DedupMaxKeys = 2
+	q := NewInMemoryQueue(cfg)
+	q.SetClock(sim)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Shutdown(context.Background())
+
+	a := publishKeyed(t, q, "a")
+	publishKeyed(t, q, "b")
+	if offset := publishKeyed(t, q, "a"); offset != a {
+		t.Errorf("expected the duplicate answered with offset %d, got %d", a, offset)
+	}
+	if err := q.Publish(context.Background(), []byte("unkeyed")); err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 3 {
+		t.Errorf("expected 3 messages in the log, got %d", q.Len())
+	}
+
+	// The window forgets keys by age...
+	sim.Advance(2 * time.Minute)
+	if offset := publishKeyed(t, q, "a"); offset == a {
+		t.Error("expected a key outside the window to be published again")
+	}
+
+	// ...and the oldest beyond DedupMaxKeys
+	publishKeyed(t, q, "c")
+	publishKeyed(t, q, "d")
+	if q.Len() != 6 {
+		t.Fatalf("expected 6 messages in the log, got %d", q.Len())
+	}
+	publishKeyed(t, q, "a")
+	if q.Len() != 7 {
+		t.Errorf("expected the evicted key published again, got %d messages", q.Len())
+	}
+	if stats := q.GetStats(); stats.DuplicateMessages != 1 {
+		t.Errorf("expected 1 duplicate counted, got %d", stats.DuplicateMessages)
+	}
+}
+
+func TestDedupSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q := openQueue(t, dir, 1<<20)
+	offset := publishKeyed(t, q, "batch-1")
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	q = openQueue(t, dir, 1<<20)
+	defer q.Shutdown(ctx)
+	if got := publishKeyed(t, q, "batch-1"); got != offset || q.Len() != 1 {
+		t.Errorf("expected the recovered key to dedup to offset %d, got %d with %d messages", offset, got, q.Len())
+	}
+}
+
+func TestDedupDisabled(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.DedupWindow = 0
+	q := NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer q.Shutdown(context.Background())
+
+	publishKeyed(t, q, "a")
+	publishKeyed(t, q, "a")
+	if q.Len() != 2 {
+		t.Errorf("expected both publishes appended, got %d", q.Len())
+	}
+}
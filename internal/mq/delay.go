@@ -0,0 +1,220 @@
+package mq
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Metadata scheduling a message's delivery. A scheduled message keeps its
+// offset in the log, but a subscriber that reaches it before it is due
+// passes over it, deferring it, and carries on with the messages after it;
+// it is delivered once it falls due. Under auto-commit a subscriber's
+// committed position stays before the first message it has deferred, so a
+// restart loses none of them. Scheduled messages are not expedited by
+// priority.
+const (
+	// MetaDeliverAt holds a message back from subscribers until a time,
+	// written in RFC 3339
+	MetaDeliverAt = "deliver_at"
+
+	// MetaDeliverAfter holds a message back for a duration after it is
+	// published, such as "30s". The queue replaces it with MetaDeliverAt.
+	MetaDeliverAfter = "deliver_after"
+)
+
+// schedule replaces a MetaDeliverAfter in metadata with the
+// MetaDeliverAt it comes to from now, and checks MetaDeliverAt.
+func schedule(metadata map[string]string, now time.Time) error {
+	if after, ok := metadata[MetaDeliverAfter]; ok {
+		d, err := time.ParseDuration(after)
+		if err != nil {
+			return perrors.Validation(fmt.Errorf("%s: %w", MetaDeliverAfter, err))
+		}
+		delete(metadata, MetaDeliverAfter)
+		metadata[MetaDeliverAt] = now.Add(d).UTC().Format(time.RFC3339Nano)
+	}
+	if at, ok := metadata[MetaDeliverAt]; ok {
+		if _, err := time.Parse(time.RFC3339Nano, at); err != nil {
+			return perrors.Validation(fmt.Errorf("%s must be an RFC 3339 time: %w", MetaDeliverAt, err))
+		}
+	}
+	return nil
+}
+
+// deliverAt returns when msg falls due, if it is scheduled.
+func deliverAt(msg *Message) (time.Time, bool) {
+	v, ok := msg.Metadata[MetaDeliverAt]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	return t, err == nil
+}
+
+// deferral is a message a subscriber passed over before it was due.
+type deferral struct {
+	offset Offset
+	due    time.Time
+}
+
+// deferLater defers msg when it is not yet due and sub would receive it,
+// and reports whether it did.
+func (q *InMemoryQueue) deferLater(sub *subscriber, msg *Message) bool {
+	due, ok := deliverAt(msg)
+	if !ok || !due.After(q.clock.Now()) || !sub.receives(msg) || !sub.filter.Match(msg.Metadata) {
+		return false
+	}
+	q.subMu.Lock()
+	defer q.subMu.Unlock()
+	// Kept in due order, so the head is the next to fall due
+	i := sort.Search(len(sub.deferred), func(i int) bool { return sub.deferred[i].due.After(due) })
+	sub.deferred = append(sub.deferred, deferral{})
+	copy(sub.deferred[i+1:], sub.deferred[i:])
+	sub.deferred[i] = deferral{offset: msg.Offset, due: due}
+	return true
+}
+
+// deliverDue delivers the first of sub's deferred messages when it has
+// fallen due, and reports whether there was one. stop is set when the
+// budget held it back or it could not be read back from disk.
+func (q *InMemoryQueue) deliverDue(sub *subscriber) (found, stop bool) {
+	now := q.clock.Now()
+	q.subMu.RLock()
+	if len(sub.deferred) == 0 || sub.deferred[0].due.After(now) {
+		q.subMu.RUnlock()
+		return false, false
+	}
+	d := sub.deferred[0]
+	q.subMu.RUnlock()
+
+	msg, _, err := q.getMessageAtOffset(d.offset)
+	if err != nil {
+		q.retryPageIn(sub)
+		return true, true // Left deferred until it can be read back
+	}
+	if msg != nil {
+		if _, ok := q.offer(sub, msg); !ok {
+			return true, true
+		}
+	}
+
+	q.subMu.Lock()
+	// A seek forgets what was deferred
+	if len(sub.deferred) > 0 && sub.deferred[0] == d {
+		sub.deferred = sub.deferred[1:]
+		if msg == nil {
+			sub.trimmed++ // Trimmed while it waited
+		}
+	}
+	q.subMu.Unlock()
+	return true, false
+}
+
+// position returns the offset sub would resume from: its current offset,
+// or its first deferred message or message a worker is still handling
+// when that is earlier. The caller holds subMu.
+func (s *subscriber) position() Offset {
+	offset := s.offset
+	for _, d := range s.deferred {
+		offset = min(offset, d.offset)
+	}
+	if s.workers != nil {
+		for inflight := range s.workers.inflight {
+			offset = min(offset, inflight)
+		}
+	}
+	return offset
+}
+
+// scheduler wakes the subscribers when scheduled messages fall due.
+type scheduler struct {
+	mu   sync.Mutex
+	due  dueTimes
+	wake chan struct{} // Signaled when an earlier time is added
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{wake: make(chan struct{}, 1)}
+}
+
+// add schedules a wake-up at t.
+func (s *scheduler) add(t time.Time) {
+	s.mu.Lock()
+	earliest := len(s.due) == 0 || t.Before(s.due[0])
+	heap.Push(&s.due, t)
+	s.mu.Unlock()
+	if earliest {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// next drops the times at or before now, reporting whether there were any,
+// and returns how long until the next.
+func (s *scheduler) next(now time.Time) (fell bool, wait time.Duration, pending bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.due) > 0 && !s.due[0].After(now) {
+		heap.Pop(&s.due)
+		fell = true
+	}
+	if len(s.due) == 0 {
+		return fell, 0, false
+	}
+	return fell, s.due[0].Sub(now), true
+}
+
+// len returns the number of wake-ups scheduled.
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.due)
+}
+
+// startScheduler wakes the subscribers whenever a scheduled message falls
+// due.
+func (q *InMemoryQueue) startScheduler() {
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		// The clock is not read until something is scheduled
+		var timer <-chan time.Time
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-q.scheduled.wake:
+			case <-timer:
+			}
+			fell, wait, pending := q.scheduled.next(q.clock.Now())
+			if fell {
+				q.notifySubscribers()
+			}
+			timer = nil
+			if pending {
+				timer = q.clock.After(wait)
+			}
+		}
+	}()
+}
+
+// dueTimes is a min-heap of times.
+type dueTimes []time.Time
+
+func (h dueTimes) Len() int           { return len(h) }
+func (h dueTimes) Less(i, j int) bool { return h[i].Before(h[j]) }
+func (h dueTimes) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *dueTimes) Push(x any)        { *h = append(*h, x.(time.Time)) }
+func (h *dueTimes) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
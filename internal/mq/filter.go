@@ -0,0 +1,136 @@
+package mq
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Well-known metadata keys stamped on telemetry batches by producers.
+// Set-valued keys hold a sorted, comma-separated list.
+const (
+	MetaHostname    = "hostname"
+	MetaMetricName  = "metric_name"
+	MetaRecordCount = "record_count"
+
+	// MetaReplayOf marks a batch republished by re-ingestion; the value is its batch ID
+	MetaReplayOf = "replay_of"
+
+	// MetaPublishedAt carries, on a replayed batch, when the MQ server first
+	// received it (RFC 3339), so consumers can still measure the producer's
+	// clock skew against it
+	MetaPublishedAt = "published_at"
+
+	// MetaProbe marks a loopback probe published by the queue to itself; the
+	// value is its sequence number. Probes reach only the built-in probe subscriber.
+	MetaProbe = "probe"
+
+	// MetaPartitionKey routes a message to a partition; messages with the
+	// same key, such as a GPU UUID or hostname, share a partition
+	MetaPartitionKey = "partition_key"
+)
+
+// Filter is a subscriber-defined predicate evaluated against message metadata
+// before delivery, so consumers only receive the batches they care about.
+//
+// The expression syntax is a semicolon-separated list of clauses, all of which
+// must match:
+//
+//	hostname=mtv5-*;metric_name=DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_POWER_USAGE
+//
+// Each clause names a metadata key and a comma-separated list of patterns.
+// A pattern ending in '*' is a prefix match, otherwise it must match exactly.
+// For set-valued metadata the clause matches if any element matches any pattern.
+// Messages that do not carry the key are delivered, since they cannot be
+// evaluated without unmarshaling the payload.
+type Filter struct {
+	expr    string
+	clauses []filterClause
+}
+
+// filterClause matches a single metadata key against a set of patterns.
+type filterClause struct {
+	key      string
+	patterns []string
+}
+
+// ParseFilter parses a filter expression. An empty expression returns a nil filter.
+func ParseFilter(expr string) (*Filter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	f := &Filter{expr: expr}
+	for _, part := range strings.Split(expr, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		idx := strings.Index(part, "=")
+		if idx <= 0 {
+			return nil, fmt.Errorf("%w: filter clause %q must be key=pattern", ErrInvalidConfig, part)
+		}
+
+		clause := filterClause{key: strings.TrimSpace(part[:idx])}
+		for _, p := range strings.Split(part[idx+1:], ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				clause.patterns = append(clause.patterns, p)
+			}
+		}
+		if len(clause.patterns) == 0 {
+			return nil, fmt.Errorf("%w: filter clause %q has no patterns", ErrInvalidConfig, part)
+		}
+		f.clauses = append(f.clauses, clause)
+	}
+
+	return f, nil
+}
+
+// String returns the original filter expression.
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.expr
+}
+
+// Match reports whether a message with the given metadata passes the filter.
+// A nil filter matches everything.
+func (f *Filter) Match(metadata map[string]string) bool {
+	if f == nil {
+		return true
+	}
+
+	for _, clause := range f.clauses {
+		value, ok := metadata[clause.key]
+		if !ok {
+			continue
+		}
+		if !clause.match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether any element of a (possibly set-valued) metadata value matches.
+func (c filterClause) match(value string) bool {
+	for _, elem := range strings.Split(value, ",") {
+		for _, pattern := range c.patterns {
+			if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+				if strings.HasPrefix(elem, prefix) {
+					return true
+				}
+			} else if elem == pattern {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// JoinMetadataSet encodes a list of values as a set-valued metadata entry.
+func JoinMetadataSet(values []string) string {
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,92 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseFilterEmpty(t *testing.T) {
+	f, err := ParseFilter("  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f != nil {
+		t.Error("expected nil filter for empty expression")
+	}
+	if !f.Match(map[string]string{MetaHostname: "any"}) {
+		t.Error("nil filter should match everything")
+	}
+}
+
+func TestParseFilterInvalid(t *testing.T) {
+	for _, expr := range []string{"hostname", "=abc", "hostname="} {
+		if _, err := ParseFilter(expr); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("expected ErrInvalidConfig for %q, got %v", expr, err)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	f, err := ParseFilter("hostname=mtv5-*;metric_name=DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_POWER_USAGE")
+	if err != nil {
+		t.Fatalf("failed to parse filter: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		want     bool
+	}{
+		{"all match", map[string]string{MetaHostname: "mtv5-dgx1", MetaMetricName: "DCGM_FI_DEV_GPU_TEMP"}, true},
+		{"one of set matches", map[string]string{MetaHostname: "other,mtv5-dgx2", MetaMetricName: "DCGM_FI_DEV_GPU_UTIL,DCGM_FI_DEV_POWER_USAGE"}, true},
+		{"hostname mismatch", map[string]string{MetaHostname: "sjc-dgx1", MetaMetricName: "DCGM_FI_DEV_GPU_TEMP"}, false},
+		{"metric mismatch", map[string]string{MetaHostname: "mtv5-dgx1", MetaMetricName: "DCGM_FI_DEV_GPU_UTIL"}, false},
+		{"missing metadata passes", map[string]string{}, true},
+	}
+
+	for _, tt := range tests {
+		if got := f.Match(tt.metadata); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestSubscribeWithFilter(t *testing.T) {
+	q := NewInMemoryQueue(DefaultQueueConfig())
+	ctx := context.Background()
+	q.Start(ctx)
+	defer q.Shutdown(ctx)
+
+	filter, _ := ParseFilter("metric_name=DCGM_FI_DEV_GPU_TEMP")
+
+	var received int64
+	handler := func(ctx context.Context, msg *Message) error {
+		atomic.AddInt64(&received, 1)
+		return nil
+	}
+	if err := q.SubscribeWithOptions(ctx, "alerter", OffsetEarliest, SubscribeOptions{Filter: filter}, handler); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	q.PublishWithMetadata(ctx, []byte("a"), map[string]string{MetaMetricName: "DCGM_FI_DEV_GPU_TEMP"})
+	q.PublishWithMetadata(ctx, []byte("b"), map[string]string{MetaMetricName: "DCGM_FI_DEV_GPU_UTIL"})
+	q.Publish(ctx, []byte("c"))
+
+	time.Sleep(100 * time.Millisecond)
+
+	if got := atomic.LoadInt64(&received); got != 2 {
+		t.Errorf("expected 2 delivered messages, got %d", got)
+	}
+
+	stats := q.GetStats()
+	if len(stats.Subscribers) != 1 || stats.Subscribers[0].Filtered != 1 {
+		t.Errorf("expected 1 filtered message in stats, got %+v", stats.Subscribers)
+	}
+	offset, _ := q.GetSubscriberOffset("alerter")
+	if offset != 3 {
+		t.Errorf("expected offset to advance past filtered messages to 3, got %d", offset)
+	}
+}
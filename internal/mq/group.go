@@ -0,0 +1,143 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// group is a consumer group: members share one position in the log, a
+// subscriber registered under the group's name, which hands each message to
+// the member its partition is assigned to. Partitions are reassigned
+// whenever a member joins or leaves.
+type group struct {
+	name string
+
+	mu      sync.RWMutex
+	members map[string]MessageHandler
+	owners  []string // Member ID by partition, "" while the group is empty
+
+	rebalances atomic.Int64
+}
+
+// joinGroup adds a member to its group, creating the group on the first
+// join. The caller holds subMu.
+func (q *InMemoryQueue) joinGroup(memberID string, startOffset Offset, opts SubscribeOptions, handler MessageHandler) error {
+	if opts.Partitions != nil {
+		return perrors.Validation(fmt.Errorf("group %s assigns its members' partitions, which cannot also be listed", opts.Group))
+	}
+
+	g, exists := q.groups[opts.Group]
+	if !exists {
+		if _, taken := q.subscribers[opts.Group]; taken {
+			return perrors.Validation(fmt.Errorf("group %s is named like a subscriber", opts.Group))
+		}
+		g = &group{
+			name:    opts.Group,
+			members: make(map[string]MessageHandler),
+			owners:  make([]string, q.config.Partitions),
+		}
+		sub, err := q.addSubscriber(opts.Group, startOffset, SubscribeOptions{Filter: opts.Filter, Resume: opts.Resume, ManualCommit: opts.ManualCommit, Acknowledge: opts.Acknowledge, Paused: opts.Paused, Workers: opts.Workers, MaxUnacked: opts.MaxUnacked}, g.dispatch)
+		if err != nil {
+			return err
+		}
+		sub.group = g
+		q.groups[opts.Group] = g
+	} else if opts.Paused {
+		q.subscribers[opts.Group].suspended.Store(true)
+	}
+
+	q.members[memberID] = g
+	g.mu.Lock()
+	g.members[memberID] = handler
+	g.mu.Unlock()
+	g.rebalance()
+	return nil
+}
+
+// leaveGroup removes a member, deleting the group with its last member. The
+// caller holds subMu.
+func (q *InMemoryQueue) leaveGroup(memberID string, g *group) {
+	delete(q.members, memberID)
+	g.mu.Lock()
+	delete(g.members, memberID)
+	empty := len(g.members) == 0
+	g.mu.Unlock()
+
+	if !empty {
+		g.rebalance()
+		return
+	}
+	if sub, ok := q.subscribers[g.name]; ok {
+		close(sub.notify)
+		delete(q.subscribers, g.name)
+	}
+	delete(q.groups, g.name)
+}
+
+// positionID returns the ID a subscriber's position is kept under: its
+// group's name for a group member, its own ID otherwise. The caller holds
+// subMu.
+func (q *InMemoryQueue) positionID(subscriberID string) string {
+	if g, ok := q.members[subscriberID]; ok {
+		return g.name
+	}
+	return subscriberID
+}
+
+// rebalance assigns the partitions round-robin to the members in ID order,
+// so every member gets a share and extra members stand by.
+func (g *group) rebalance() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ids := make([]string, 0, len(g.members))
+	for id := range g.members {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for p := range g.owners {
+		g.owners[p] = ""
+		if len(ids) > 0 {
+			g.owners[p] = ids[p%len(ids)]
+		}
+	}
+	g.rebalances.Add(1)
+}
+
+// dispatch delivers msg to the member its partition is assigned to.
+func (g *group) dispatch(ctx context.Context, msg *Message) error {
+	g.mu.RLock()
+	var handler MessageHandler
+	if msg.Partition < len(g.owners) {
+		handler = g.members[g.owners[msg.Partition]]
+	}
+	g.mu.RUnlock()
+
+	if handler == nil {
+		return nil // The group emptied while the message was read
+	}
+	return handler(ctx, msg)
+}
+
+// assignments returns the partitions assigned to each member; standby
+// members have none.
+func (g *group) assignments() map[string][]int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	assigned := make(map[string][]int, len(g.members))
+	for id := range g.members {
+		assigned[id] = []int{}
+	}
+	for p, id := range g.owners {
+		if id != "" {
+			assigned[id] = append(assigned[id], p)
+		}
+	}
+	return assigned
+}
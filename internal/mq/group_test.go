@@ -0,0 +1,166 @@
+package mq
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// recorder counts the messages each group member receives.
+type recorder struct {
+	mu       sync.Mutex
+	received map[string][]Offset
+}
+
+func (r *recorder) handler(id string) MessageHandler {
+	return func(ctx context.Context, msg *Message) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.received[id] = append(r.received[id], msg.Offset)
+		return nil
+	}
+}
+
+func (r *recorder) total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, offsets := range r.received {
+		n += len(offsets)
+	}
+	return n
+}
+
+func TestGroupSplitsPartitions(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.Partitions = 4
+	q := startRetaining(t, cfg)
+	ctx := context.Background()
+	r := &recorder{received: make(map[string][]Offset)}
+
+	for _, id := range []string{"collector-a", "collector-b"} {
+		if err := q.SubscribeWithOptions(ctx, id, OffsetEarliest, SubscribeOptions{Group: "collectors"}, r.handler(id)); err != nil {
+			t.Fatalf("join %s failed: %v", id, err)
+		}
+	}
+	publishN(t, q, 8)
+	waitFor(t, func() bool { return r.total() == 8 })
+
+	r.mu.Lock()
+	if len(r.received["collector-a"]) != 4 || len(r.received["collector-b"]) != 4 {
+		t.Errorf("expected the members to split 8 messages evenly, got %v", r.received)
+	}
+	r.mu.Unlock()
+
+	stats := q.GetStats()
+	if len(stats.Subscribers) != 1 || stats.Subscribers[0].ID != "collectors" || stats.Subscribers[0].CurrentOffset != 8 {
+		t.Fatalf("expected one shared position for the group, got %+v", stats.Subscribers)
+	}
+	members := stats.Subscribers[0].Members
+	if len(members["collector-a"]) != 2 || len(members["collector-b"]) != 2 {
+		t.Errorf("expected 2 partitions each, got %v", members)
+	}
+
+	// Members read and commit the group's position
+	if offset, err := q.GetSubscriberOffset("collector-b"); err != nil || offset != 8 {
+		t.Errorf("expected the member at the group's offset 8, got %d (%v)", offset, err)
+	}
+	if err := q.CommitOffset("collector-a", 8); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if offset, ok := q.GetCommittedOffset("collectors"); !ok || offset != 8 {
+		t.Errorf("expected the commit recorded for the group, got %d (%v)", offset, ok)
+	}
+}
+
+func TestGroupRebalancesOnLeave(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.Partitions = 2
+	q := startRetaining(t, cfg)
+	ctx := context.Background()
+	r := &recorder{received: make(map[string][]Offset)}
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := q.SubscribeWithOptions(ctx, id, OffsetLatest, SubscribeOptions{Group: "g"}, r.handler(id)); err != nil {
+			t.Fatalf("join %s failed: %v", id, err)
+		}
+	}
+	if members := q.GetStats().Subscribers[0].Members; len(members["c"]) != 0 {
+		t.Errorf("expected c on standby with two partitions, got %v", members)
+	}
+
+	if err := q.Unsubscribe("a"); err != nil {
+		t.Fatalf("leave failed: %v", err)
+	}
+	publishN(t, q, 4)
+	waitFor(t, func() bool { return r.total() == 4 })
+	r.mu.Lock()
+	if len(r.received["a"]) != 0 || len(r.received["b"]) != 2 || len(r.received["c"]) != 2 {
+		t.Errorf("expected b and c to take over, got %v", r.received)
+	}
+	r.mu.Unlock()
+
+	// The group goes away with its last member
+	q.Unsubscribe("b")
+	q.Unsubscribe("c")
+	if stats := q.GetStats(); len(stats.Subscribers) != 0 {
+		t.Errorf("expected no subscribers once the group emptied, got %+v", stats.Subscribers)
+	}
+	if err := q.Unsubscribe("c"); err != ErrSubscriberNotFound {
+		t.Errorf("expected ErrSubscriberNotFound for a departed member, got %v", err)
+	}
+}
+
+func TestGroupValidation(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	ctx := context.Background()
+	noop := func(context.Context, *Message) error { return nil }
+
+	if err := q.Subscribe(ctx, "plain", OffsetLatest, noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SubscribeWithOptions(ctx, "m", OffsetLatest, SubscribeOptions{Group: "plain"}, noop); !perrors.IsValidation(err) {
+		t.Errorf("expected a group named like a subscriber to be rejected, got %v", err)
+	}
+	if err := q.SubscribeWithOptions(ctx, "m", OffsetLatest, SubscribeOptions{Group: "g", Partitions: []int{0}}, noop); !perrors.IsValidation(err) {
+		t.Errorf("expected listed partitions to be rejected for a group member, got %v", err)
+	}
+	if err := q.SubscribeWithOptions(ctx, "plain", OffsetLatest, SubscribeOptions{Group: "g"}, noop); err != ErrSubscriberExists {
+		t.Errorf("expected ErrSubscriberExists for a taken member ID, got %v", err)
+	}
+	if err := q.Unsubscribe("g"); err != ErrSubscriberNotFound {
+		t.Errorf("expected a group's name not to be unsubscribable, got %v", err)
+	}
+}
+
+func TestClientSubscribeGroup(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.Partitions = 2
+	server, first := startTestServer(t, cfg)
+	second := NewClient(ClientConfig{Host: "127.0.0.1", Port: server.TCPAddr().(*net.TCPAddr).Port, Timeout: 2 * time.Second})
+	if err := second.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer second.Close()
+	ctx := context.Background()
+	r := &recorder{received: make(map[string][]Offset)}
+
+	for id, client := range map[string]*Client{"replica-1": first, "replica-2": second} {
+		if err := client.SubscribeGroup(ctx, "collectors", id, OffsetLatest, "", r.handler(id)); err != nil {
+			t.Fatalf("join %s failed: %v", id, err)
+		}
+	}
+	for i := 0; i < 6; i++ {
+		server.GetQueue().Publish(ctx, []byte(`{}`))
+	}
+	waitFor(t, func() bool { return r.total() == 6 })
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.received["replica-1"]) != 3 || len(r.received["replica-2"]) != 3 {
+		t.Errorf("expected each replica to receive half, got %v", r.received)
+	}
+}
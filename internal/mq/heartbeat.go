@@ -0,0 +1,76 @@
+package mq
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ComponentMQServer is the component name the server lists itself under.
+const ComponentMQServer = "mq-server"
+
+// Heartbeat announces a running pipeline component to the MQ server, which
+// lists the components it has heard from recently. Components send one
+// periodically; one not renewed within its TTL is dropped from the list.
+type Heartbeat struct {
+	// Component is the kind of component, such as "streamer" or "collector"
+	Component string `json:"component"`
+
+	// ID identifies the instance among components of its kind
+	ID string `json:"id"`
+
+	Version string `json:"version,omitempty"`
+	Host    string `json:"host,omitempty"`
+
+	// Attributes describe how the component is wired, such as the storage
+	// it writes to. They must not hold credentials
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	TTLMs int64 `json:"ttl_ms,omitempty"`
+
+	// Set by the server: the address the heartbeat came from and when
+	Addr     string    `json:"addr,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// heartbeatTable tracks the components heard from on the server.
+type heartbeatTable struct {
+	mu         sync.Mutex
+	components map[string]Heartbeat
+}
+
+func newHeartbeatTable() *heartbeatTable {
+	return &heartbeatTable{components: make(map[string]Heartbeat)}
+}
+
+// beat records hb as seen at now, replacing the instance's last heartbeat.
+func (t *heartbeatTable) beat(hb Heartbeat, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hb.LastSeen = now
+	t.components[hb.Component+"/"+hb.ID] = hb
+}
+
+// list drops the components whose heartbeats expired by now and returns the
+// rest, ordered by component and ID.
+func (t *heartbeatTable) list(now time.Time) []Heartbeat {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	components := make([]Heartbeat, 0, len(t.components))
+	for key, hb := range t.components {
+		if now.Sub(hb.LastSeen) > time.Duration(hb.TTLMs)*time.Millisecond {
+			delete(t.components, key)
+			continue
+		}
+		components = append(components, hb)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].Component != components[j].Component {
+			return components[i].Component < components[j].Component
+		}
+		return components[i].ID < components[j].ID
+	})
+	return components
+}
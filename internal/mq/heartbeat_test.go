@@ -0,0 +1,54 @@
+package mq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatTableExpires(t *testing.T) {
+	table := newHeartbeatTable()
+	now := time.Now()
+	table.beat(Heartbeat{Component: "streamer", ID: "s1", TTLMs: 1000}, now)
+	table.beat(Heartbeat{Component: "collector", ID: "c1", TTLMs: 5000}, now)
+	table.beat(Heartbeat{Component: "streamer", ID: "s0", TTLMs: 1000}, now.Add(500*time.Millisecond))
+
+	got := table.list(now.Add(time.Second))
+	if len(got) != 3 || got[0].ID != "c1" || got[1].ID != "s0" || got[2].ID != "s1" {
+		t.Fatalf("expected the components ordered by kind and ID, got %+v", got)
+	}
+
+	// s1 missed its TTL, s0 renewed later
+	got = table.list(now.Add(1200 * time.Millisecond))
+	if len(got) != 2 || got[0].ID != "c1" || got[1].ID != "s0" {
+		t.Errorf("expected s1 dropped, got %+v", got)
+	}
+}
+
+func TestClientHeartbeats(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	hb := Heartbeat{Component: "collector", ID: "collector-1", Version: "1.2.0", Attributes: map[string]string{"group": "writers"}}
+	if err := client.SendHeartbeat(ctx, hb, time.Minute); err != nil {
+		t.Fatalf("SendHeartbeat failed: %v", err)
+	}
+	components, err := client.Components(ctx)
+	if err != nil {
+		t.Fatalf("Components failed: %v", err)
+	}
+	if len(components) != 2 {
+		t.Fatalf("expected the server and the collector, got %+v", components)
+	}
+	if self := components[0]; self.Component != ComponentMQServer || self.ID != server.tcpAddr {
+		t.Errorf("expected the server listed first, got %+v", self)
+	}
+	got := components[1]
+	if got.ID != "collector-1" || got.Version != "1.2.0" || got.Attributes["group"] != "writers" || got.Addr == "" || got.LastSeen.IsZero() {
+		t.Errorf("unexpected component: %+v", got)
+	}
+
+	if err := client.SendHeartbeat(ctx, Heartbeat{Component: "collector"}, time.Minute); err == nil {
+		t.Error("expected error for a heartbeat without an ID")
+	}
+}
@@ -0,0 +1,61 @@
+package mq
+
+import (
+	"sync"
+	"time"
+)
+
+// Lease is a named, time-limited lock held on the MQ server. Replicas of a
+// component use it for leader election: whoever holds the lease leads until
+// it stops renewing and the lease expires.
+type Lease struct {
+	Name      string    `json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Acquired is true when the requesting holder now owns the lease
+	Acquired bool `json:"acquired"`
+}
+
+// LeaseRequest is the payload of acquire_lease and release_lease messages.
+type LeaseRequest struct {
+	Name   string `json:"name"`
+	Holder string `json:"holder"`
+	TTLMs  int64  `json:"ttl_ms,omitempty"`
+}
+
+// leaseTable tracks leases on the server.
+type leaseTable struct {
+	mu     sync.Mutex
+	leases map[string]Lease
+}
+
+func newLeaseTable() *leaseTable {
+	return &leaseTable{leases: make(map[string]Lease)}
+}
+
+// acquire grants or renews the lease for holder if it is free, expired, or
+// already held by holder. Otherwise it reports the current holder.
+func (t *leaseTable) acquire(name, holder string, ttl time.Duration, now time.Time) Lease {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cur, ok := t.leases[name]
+	if ok && cur.Holder != holder && now.Before(cur.ExpiresAt) {
+		cur.Acquired = false
+		return cur
+	}
+
+	lease := Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl), Acquired: true}
+	t.leases[name] = lease
+	return lease
+}
+
+// release frees the lease if holder owns it.
+func (t *leaseTable) release(name, holder string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cur, ok := t.leases[name]; ok && cur.Holder == holder {
+		delete(t.leases, name)
+	}
+}
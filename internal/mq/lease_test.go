@@ -0,0 +1,82 @@
+package mq
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLeaseTableAcquire(t *testing.T) {
+	table := newLeaseTable()
+	now := time.Now()
+
+	if l := table.acquire("sched", "a", time.Second, now); !l.Acquired || l.Holder != "a" {
+		t.Fatalf("expected a to acquire free lease, got %+v", l)
+	}
+	if l := table.acquire("sched", "b", time.Second, now.Add(500*time.Millisecond)); l.Acquired || l.Holder != "a" {
+		t.Errorf("expected b to be refused while a holds the lease, got %+v", l)
+	}
+	if l := table.acquire("sched", "a", time.Second, now.Add(500*time.Millisecond)); !l.Acquired {
+		t.Errorf("expected a to renew its lease, got %+v", l)
+	}
+	if l := table.acquire("sched", "b", time.Second, now.Add(2*time.Second)); !l.Acquired || l.Holder != "b" {
+		t.Errorf("expected b to take over an expired lease, got %+v", l)
+	}
+}
+
+func TestLeaseTableRelease(t *testing.T) {
+	table := newLeaseTable()
+	now := time.Now()
+	table.acquire("sched", "a", time.Minute, now)
+
+	// Only the holder can release
+	table.release("sched", "b")
+	if l := table.acquire("sched", "b", time.Minute, now); l.Acquired {
+		t.Error("expected release by non-holder to be ignored")
+	}
+
+	table.release("sched", "a")
+	if l := table.acquire("sched", "b", time.Minute, now); !l.Acquired {
+		t.Error("expected lease to be free after holder released it")
+	}
+}
+
+func TestClientLeases(t *testing.T) {
+	_, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	lease, err := client.AcquireLease(ctx, "api-scheduler", "replica-1", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLease failed: %v", err)
+	}
+	if !lease.Acquired || lease.Holder != "replica-1" {
+		t.Errorf("expected replica-1 to acquire lease, got %+v", lease)
+	}
+
+	lease, err = client.AcquireLease(ctx, "api-scheduler", "replica-2", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLease failed: %v", err)
+	}
+	if lease.Acquired || lease.Holder != "replica-1" {
+		t.Errorf("expected replica-2 to see replica-1 holding the lease, got %+v", lease)
+	}
+
+	if err := client.ReleaseLease(ctx, "api-scheduler", "replica-1"); err != nil {
+		t.Fatalf("ReleaseLease failed: %v", err)
+	}
+	lease, err = client.AcquireLease(ctx, "api-scheduler", "replica-2", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLease failed: %v", err)
+	}
+	if !lease.Acquired {
+		t.Errorf("expected replica-2 to acquire released lease, got %+v", lease)
+	}
+}
+
+func TestClientAcquireLeaseValidation(t *testing.T) {
+	_, client := startTestServer(t, DefaultQueueConfig())
+
+	if _, err := client.AcquireLease(context.Background(), "", "replica-1", time.Minute); err == nil {
+		t.Error("expected error for lease without a name")
+	}
+}
@@ -0,0 +1,109 @@
+package mq
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The queue samples its totals and subscriber lag every metricsInterval,
+// keeping metricsHistory samples, so rates and lag history cover the last
+// minute.
+const (
+	metricsInterval = 5 * time.Second
+	metricsHistory  = 12
+)
+
+// throughputSample is the queue's totals at a point in time.
+type throughputSample struct {
+	at        time.Time
+	published int64
+	delivered int64
+}
+
+// throughput holds the samples rates are computed from, oldest first.
+type throughput struct {
+	mu      sync.Mutex
+	samples []throughputSample
+}
+
+// startSampler samples throughput and subscriber lag until shutdown.
+func (q *InMemoryQueue) startSampler() {
+	q.sample(q.clock.Now())
+
+	ticker := q.clock.NewTicker(metricsInterval)
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case now := <-ticker.C():
+				q.sample(now)
+			}
+		}
+	}()
+}
+
+// sample records each subscriber's lag and the totals as of now.
+func (q *InMemoryQueue) sample(now time.Time) {
+	q.logMu.RLock()
+	latest := q.latestLocked()
+	q.logMu.RUnlock()
+
+	q.subMu.Lock()
+	for _, sub := range q.subscribers {
+		sub.lagHistory = appendCapped(sub.lagHistory, max(int64(latest-sub.offset), 0), metricsHistory)
+	}
+	q.subMu.Unlock()
+
+	q.throughput.mu.Lock()
+	defer q.throughput.mu.Unlock()
+	q.throughput.samples = appendCapped(q.throughput.samples, throughputSample{
+		at:        now,
+		published: atomic.LoadInt64(&q.totalPublished),
+		delivered: q.delivered.Load(),
+	}, metricsHistory+1)
+}
+
+// rates returns the messages published and delivered per second between
+// the oldest and newest samples, or zeros until there are two.
+func (q *InMemoryQueue) rates() (publish, deliver float64) {
+	q.throughput.mu.Lock()
+	defer q.throughput.mu.Unlock()
+
+	n := len(q.throughput.samples)
+	if n < 2 {
+		return 0, 0
+	}
+	first, last := q.throughput.samples[0], q.throughput.samples[n-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+	return float64(last.published-first.published) / elapsed, float64(last.delivered-first.delivered) / elapsed
+}
+
+// handled counts n messages handed to sub's handler, and whether handling
+// them failed. Probes are left out of the queue's total, as they are of
+// those published.
+func (q *InMemoryQueue) handled(sub *subscriber, n int, err error) {
+	if !sub.probes {
+		q.delivered.Add(int64(n))
+	}
+	sub.delivered.Add(int64(n))
+	if err != nil {
+		sub.handlerErrors.Add(int64(n))
+	}
+}
+
+// appendCapped appends v to s, dropping the oldest elements beyond n.
+func appendCapped[T any](s []T, v T, n int) []T {
+	s = append(s, v)
+	if len(s) > n {
+		s = append(s[:0], s[len(s)-n:]...)
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+// advance moves the clock on to the next sample and waits for it.
+func advance(t *testing.T, q *InMemoryQueue, sim *clock.Simulated) {
+	t.Helper()
+	sim.Advance(metricsInterval)
+	waitFor(t, func() bool {
+		q.throughput.mu.Lock()
+		defer q.throughput.mu.Unlock()
+		samples := q.throughput.samples
+		return samples[len(samples)-1].at.Equal(sim.Now())
+	})
+}
+
+func TestThroughputAndLagMetrics(t *testing.T) {
+	sim := clock.NewSimulated(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	cfg := DefaultQueueConfig()
+	cfg.MaxRetries = 0 // Count the failure without retrying it
+	q := NewInMemoryQueue(cfg)
+	q.SetClock(sim)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+	ctx := context.Background()
+
+	q.Subscribe(ctx, "collector", OffsetEarliest, func(_ context.Context, msg *Message) error {
+		if string(msg.Payload) == "msg-01" {
+			return errors.New("sink down")
+		}
+		return nil
+	})
+	q.Subscribe(ctx, "lagging", OffsetEarliest, func(context.Context, *Message) error { return nil })
+	if err := q.PauseSubscriber("lagging"); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, q, 4)
+	waitFor(t, func() bool { return subscriberInfo(q, "collector").Delivered == 4 })
+
+	if info := subscriberInfo(q, "collector"); info.HandlerErrors != 1 {
+		t.Errorf("expected 1 handler error, got %+v", info)
+	}
+	if stats := q.GetStats(); stats.DeliveredMessages != 4 || stats.PublishRate != 0 {
+		t.Errorf("expected 4 delivered and no rate before a second sample, got %+v", stats)
+	}
+
+	advance(t, q, sim)
+	stats := q.GetStats()
+	if stats.PublishRate != 0.8 || stats.DeliverRate != 0.8 {
+		t.Errorf("expected 4 messages over 5s published and delivered, got %v and %v/s", stats.PublishRate, stats.DeliverRate)
+	}
+	if info := subscriberInfo(q, "lagging"); info.Lag == 0 || !slices.Equal(info.LagHistory, []int64{info.Lag}) {
+		t.Errorf("expected the paused subscriber's lag sampled, got %+v", info)
+	}
+
+	// History keeps the last minute
+	for i := 0; i < metricsHistory+2; i++ {
+		advance(t, q, sim)
+	}
+	if stats := q.GetStats(); stats.PublishRate != 0 {
+		t.Errorf("expected no publishes in the last minute, got %v/s", stats.PublishRate)
+	}
+	if got := subscriberInfo(q, "lagging").LagHistory; len(got) != metricsHistory {
+		t.Errorf("expected %d lag samples, got %v", metricsHistory, got)
+	}
+}
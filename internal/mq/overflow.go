@@ -0,0 +1,104 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Overflow policies for a publish that would take the log past
+// QueueConfig.MaxMessages or MaxBytes.
+const (
+	// OverflowReject fails the publish with ErrQueueFull
+	OverflowReject = "reject"
+
+	// OverflowBlock waits up to PublishTimeout for retention to trim
+	// enough of the log, then fails the publish with ErrPublishTimeout
+	OverflowBlock = "block"
+
+	// OverflowDropOldest trims the oldest messages to make room, whether
+	// or not subscribers have read them
+	OverflowDropOldest = "drop_oldest"
+)
+
+// bounded reports whether the log has a hard bound.
+func (q *InMemoryQueue) bounded() bool {
+	return q.config.MaxMessages > 0 || q.config.MaxBytes > 0
+}
+
+// fitsLocked reports whether n more messages of size bytes fit in the log.
+// The caller holds logMu.
+func (q *InMemoryQueue) fitsLocked(n int, size int64) bool {
+	return (q.config.MaxMessages <= 0 || len(q.log)+n <= q.config.MaxMessages) &&
+		(q.config.MaxBytes <= 0 || q.logBytes+size <= q.config.MaxBytes)
+}
+
+// reserveLocked makes room in the log for n messages of size bytes as the
+// overflow policy says, or returns why it could not. The caller holds
+// logMu, which is released while blocking and held again on return.
+func (q *InMemoryQueue) reserveLocked(ctx context.Context, n int, size int64) error {
+	if !q.bounded() || q.fitsLocked(n, size) {
+		return nil
+	}
+	// Nothing makes room for more than the bound itself
+	if (q.config.MaxMessages > 0 && n > q.config.MaxMessages) || (q.config.MaxBytes > 0 && size > q.config.MaxBytes) {
+		q.rejected += int64(n)
+		return fmt.Errorf("%w: %d messages of %d bytes exceed the log bound", ErrQueueFull, n, size)
+	}
+
+	switch q.config.OverflowPolicy {
+	case OverflowDropOldest:
+		q.dropOldestLocked(n, size)
+		return nil
+	case OverflowBlock:
+		timeout := q.config.PublishTimeout
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		deadline := q.clock.After(timeout)
+		for !q.fitsLocked(n, size) {
+			freed := q.spaceFreed
+			q.logMu.Unlock()
+			var err error
+			select {
+			case <-freed:
+			case <-deadline:
+				err = fmt.Errorf("%w: the log stayed full for %v", ErrPublishTimeout, timeout)
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-q.ctx.Done():
+				err = ErrQueueShutdown
+			}
+			q.logMu.Lock()
+			if err != nil {
+				q.rejected += int64(n)
+				return err
+			}
+		}
+		return nil
+	default:
+		q.rejected += int64(n)
+		return ErrQueueFull
+	}
+}
+
+// dropOldestLocked trims the fewest oldest messages that make room for n
+// messages of size bytes. Subscribers still reading them skip ahead, as
+// after retention. The caller holds logMu.
+func (q *InMemoryQueue) dropOldestLocked(n int, size int64) {
+	cfg := q.config
+	bytes := int64(0)
+	drop := 0
+	for ; drop < len(q.log); drop++ {
+		fits := (cfg.MaxMessages <= 0 || len(q.log)-drop+n <= cfg.MaxMessages) &&
+			(cfg.MaxBytes <= 0 || q.logBytes-bytes+size <= cfg.MaxBytes)
+		if fits {
+			break
+		}
+		bytes += messageSize(q.log[drop])
+	}
+	if drop > 0 {
+		q.removeOldestLocked(drop, bytes)
+		q.dropped += int64(drop)
+	}
+}
@@ -0,0 +1,116 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+func TestOverflowReject(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.MaxMessages = 3
+	cfg.MaxBytes = 100
+	q := startRetaining(t, cfg)
+	publishN(t, q, 3)
+
+	ctx := context.Background()
+	err := q.Publish(ctx, []byte("msg-03"))
+	if !errors.Is(err, ErrQueueFull) || perrors.KindOf(err) != perrors.KindTransient {
+		t.Fatalf("expected a transient ErrQueueFull, got %v", err)
+	}
+	if err := q.PublishBatch(ctx, [][]byte{[]byte("a"), []byte("b")}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected the batch to be refused whole, got %v", err)
+	}
+	if q.Len() != 3 || q.GetLatestOffset() != 2 {
+		t.Errorf("expected the log untouched, got %d messages up to %d", q.Len(), q.GetLatestOffset())
+	}
+
+	// Retention making room lets publishes through again
+	q.config.RetentionMessages = 1
+	q.trim(time.Now())
+	if err := q.Publish(ctx, []byte("msg-03")); err != nil {
+		t.Errorf("expected a publish to fit after trimming, got %v", err)
+	}
+	if err := q.Publish(ctx, make([]byte, 101)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected a message larger than MaxBytes to be refused, got %v", err)
+	}
+	if stats := q.GetStats(); stats.RejectedMessages != 4 || stats.DroppedMessages != 0 {
+		t.Errorf("unexpected overflow stats %+v", stats)
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.MaxMessages = 3
+	cfg.OverflowPolicy = OverflowDropOldest
+	q := startRetaining(t, cfg)
+	publishN(t, q, 5)
+
+	stats := q.GetStats()
+	if q.Len() != 3 || stats.OldestOffset != 2 || stats.LatestOffset != 4 {
+		t.Fatalf("expected offsets 2..4 kept, got %+v", stats)
+	}
+	if err := q.PublishBatch(context.Background(), [][]byte{[]byte("a"), []byte("b")}); err != nil {
+		t.Fatal(err)
+	}
+	stats = q.GetStats()
+	if stats.OldestOffset != 4 || stats.DroppedMessages != 4 || stats.TrimmedMessages != 4 || stats.RejectedMessages != 0 {
+		t.Errorf("unexpected stats after dropping %+v", stats)
+	}
+	if msg, err := q.FetchMessage(4); err != nil || string(msg.Payload) != "msg-04" {
+		t.Errorf("expected msg-04 at offset 4, got %+v (%v)", msg, err)
+	}
+
+	// Bytes are bounded the same way
+	cfg.MaxMessages = 0
+	cfg.MaxBytes = 3 * 6
+	q = startRetaining(t, cfg)
+	publishN(t, q, 4)
+	if stats := q.GetStats(); stats.OldestOffset != 1 || stats.RetainedBytes != 18 {
+		t.Errorf("expected the log trimmed to 18 bytes, got %+v", stats)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.MaxMessages = 2
+	cfg.OverflowPolicy = OverflowBlock
+	q := startRetaining(t, cfg)
+	sim := clock.NewSimulated(time.Now())
+	q.SetClock(sim)
+	publishN(t, q, 2)
+
+	published := make(chan error, 1)
+	go func() { published <- q.Publish(context.Background(), []byte("msg-02")) }()
+	sim.BlockUntil(1)
+	select {
+	case err := <-published:
+		t.Fatalf("expected the publish to wait for room, got %v", err)
+	default:
+	}
+
+	// Retention making room wakes it
+	q.config.RetentionMessages = 1
+	q.trim(sim.Now())
+	if err := <-published; err != nil {
+		t.Fatalf("expected the publish to go through once trimmed, got %v", err)
+	}
+	if q.GetLatestOffset() != 2 || q.Len() != 2 {
+		t.Errorf("expected offsets 1..2, got %d messages up to %d", q.Len(), q.GetLatestOffset())
+	}
+
+	// Without room it gives up after PublishTimeout
+	go func() { published <- q.Publish(context.Background(), []byte("msg-03")) }()
+	sim.BlockUntil(2)
+	sim.Advance(cfg.PublishTimeout)
+	if err := <-published; !errors.Is(err, ErrPublishTimeout) {
+		t.Errorf("expected ErrPublishTimeout, got %v", err)
+	}
+	if stats := q.GetStats(); stats.RejectedMessages != 1 {
+		t.Errorf("expected the timed out publish counted, got %+v", stats)
+	}
+}
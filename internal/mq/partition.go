@@ -0,0 +1,48 @@
+package mq
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// partitionFor returns the partition of a message with metadata: the hash
+// of its MetaPartitionKey, or the next partition round-robin without one.
+func (q *InMemoryQueue) partitionFor(metadata map[string]string) int {
+	n := q.config.Partitions
+	if n <= 1 {
+		return 0
+	}
+	key, ok := metadata[MetaPartitionKey]
+	if !ok || key == "" {
+		return int((q.nextPartition.Add(1) - 1) % uint64(n))
+	}
+	return PartitionOf(key, n)
+}
+
+// PartitionOf returns the partition a key is routed to among n partitions.
+func PartitionOf(key string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// checkPartitions validates the partitions a subscriber asks for.
+func (q *InMemoryQueue) checkPartitions(partitions []int) error {
+	for _, p := range partitions {
+		if p < 0 || p >= q.config.Partitions {
+			return perrors.Validation(fmt.Errorf("partition %d does not exist, the queue has %d", p, q.config.Partitions))
+		}
+	}
+	return nil
+}
+
+// consumes reports whether the subscriber receives messages in partition.
+func (s *subscriber) consumes(partition int) bool {
+	return len(s.partitions) == 0 || slices.Contains(s.partitions, partition)
+}
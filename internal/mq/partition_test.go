@@ -0,0 +1,127 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+func TestPartitionKeyRouting(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.Partitions = 4
+	q := startRetaining(t, cfg)
+	ctx := context.Background()
+
+	for i := 0; i < 12; i++ {
+		key := fmt.Sprintf("GPU-%d", i%3)
+		if err := q.PublishWithMetadata(ctx, []byte(key), map[string]string{MetaPartitionKey: key}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	for offset := Offset(0); offset < 12; offset++ {
+		msg, _ := q.FetchMessage(offset)
+		if want := PartitionOf(string(msg.Payload), 4); msg.Partition != want {
+			t.Errorf("offset %d: expected %s in partition %d, got %d", offset, msg.Payload, want, msg.Partition)
+		}
+	}
+
+	// Without a key, messages go round-robin
+	publishN(t, q, 4)
+	seen := make(map[int]bool)
+	for offset := Offset(12); offset < 16; offset++ {
+		msg, _ := q.FetchMessage(offset)
+		seen[msg.Partition] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected unkeyed messages spread over all 4 partitions, got %v", seen)
+	}
+	if stats := q.GetStats(); stats.Partitions != 4 {
+		t.Errorf("expected 4 partitions in stats, got %d", stats.Partitions)
+	}
+}
+
+func TestSubscribePartitions(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.Partitions = 2
+	q := startRetaining(t, cfg)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	for _, id := range []string{"collector-0", "collector-1"} {
+		partition := len(got)
+		got[id] = nil
+		err := q.SubscribeWithOptions(ctx, id, OffsetEarliest, SubscribeOptions{Partitions: []int{partition}}, func(ctx context.Context, msg *Message) error {
+			mu.Lock()
+			got[id] = append(got[id], msg.Partition)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("subscribe %s failed: %v", id, err)
+		}
+	}
+	publishN(t, q, 10)
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got["collector-0"])+len(got["collector-1"]) == 10
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, partitions := range got {
+		if len(partitions) != 5 {
+			t.Errorf("expected %s to receive 5 messages, got %d", id, len(partitions))
+		}
+		for _, p := range partitions {
+			if id != fmt.Sprintf("collector-%d", p) {
+				t.Errorf("%s received a message from partition %d", id, p)
+			}
+		}
+	}
+
+	err := q.SubscribeWithOptions(ctx, "collector-2", OffsetEarliest, SubscribeOptions{Partitions: []int{2}}, func(context.Context, *Message) error { return nil })
+	if !perrors.IsValidation(err) {
+		t.Errorf("expected a validation error for a partition out of range, got %v", err)
+	}
+}
+
+func TestClientSubscribePartitions(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.Partitions = 3
+	server, client := startTestServer(t, cfg)
+	ctx := context.Background()
+
+	partition := PartitionOf("node-1", 3)
+	received := make(chan *Message, 10)
+	err := client.SubscribePartitions(ctx, "collector", OffsetEarliest, "", []int{partition}, func(ctx context.Context, msg *Message) error {
+		received <- msg
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	for _, host := range []string{"node-1", "node-2", "node-1"} {
+		server.GetQueue().PublishWithMetadata(ctx, []byte(`"`+host+`"`), map[string]string{MetaPartitionKey: host})
+	}
+	for i := 0; i < 2; i++ {
+		var msg *Message
+		select {
+		case msg = <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for node-1's messages")
+		}
+		if string(msg.Payload) != `"node-1"` || msg.Partition != partition {
+			t.Errorf("expected node-1 in partition %d, got %s in %d", partition, msg.Payload, msg.Partition)
+		}
+	}
+	if info := server.GetQueue().GetStats().Subscribers; len(info) != 1 || len(info[0].Partitions) != 1 {
+		t.Errorf("expected the subscriber's partitions in stats, got %+v", info)
+	}
+}
@@ -0,0 +1,39 @@
+package mq
+
+// PauseSubscriber stops delivering messages to a subscriber without
+// removing it, so it keeps its position, its unacked messages and, over
+// TCP, its connection until ResumeSubscriber. A message being handed over
+// as it is paused still arrives, and unacked messages are not redelivered
+// until it resumes. Pausing a consumer group member pauses the group's
+// shared position.
+func (q *InMemoryQueue) PauseSubscriber(subscriberID string) error {
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+
+	sub, exists := q.subscribers[q.positionID(subscriberID)]
+	if !exists {
+		return ErrSubscriberNotFound
+	}
+	sub.suspended.Store(true)
+	return nil
+}
+
+// ResumeSubscriber resumes delivery to a subscriber PauseSubscriber paused,
+// from its position. Resuming a subscriber that is not paused does
+// nothing.
+func (q *InMemoryQueue) ResumeSubscriber(subscriberID string) error {
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+
+	sub, exists := q.subscribers[q.positionID(subscriberID)]
+	if !exists {
+		return ErrSubscriberNotFound
+	}
+	if sub.suspended.CompareAndSwap(true, false) {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
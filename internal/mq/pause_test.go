@@ -0,0 +1,124 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPauseKeepsPosition(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	delivered := payloadCollector(q, "collector", OffsetEarliest, SubscribeOptions{})
+	publishN(t, q, 2)
+	waitFor(t, func() bool { return len(delivered()) == 2 })
+
+	if err := q.PauseSubscriber("collector"); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, q, 3)
+	time.Sleep(10 * time.Millisecond)
+	info := subscriberInfo(q, "collector")
+	if len(delivered()) != 2 || !info.Suspended || info.CurrentOffset != 2 {
+		t.Fatalf("expected nothing delivered while paused, got %v and %+v", delivered(), info)
+	}
+
+	if err := q.ResumeSubscriber("collector"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(delivered()) == 5 })
+	if got := delivered(); !slices.Equal(got[2:], []string{"msg-00", "msg-01", "msg-02"}) {
+		t.Errorf("expected delivery to resume from the position, got %v", got)
+	}
+	if subscriberInfo(q, "collector").Suspended {
+		t.Error("expected the subscriber resumed")
+	}
+
+	// Resuming twice, or pausing someone unknown
+	if err := q.ResumeSubscriber("collector"); err != nil {
+		t.Errorf("expected resuming a running subscriber to do nothing, got %v", err)
+	}
+	if err := q.PauseSubscriber("nobody"); !errors.Is(err, ErrSubscriberNotFound) {
+		t.Errorf("expected ErrSubscriberNotFound, got %v", err)
+	}
+}
+
+func TestSubscribePaused(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	publishN(t, q, 2)
+	delivered := payloadCollector(q, "collector", OffsetEarliest, SubscribeOptions{Paused: true})
+	time.Sleep(10 * time.Millisecond)
+	if got := delivered(); len(got) != 0 {
+		t.Fatalf("expected nothing delivered to a paused subscription, got %v", got)
+	}
+	q.ResumeSubscriber("collector")
+	waitFor(t, func() bool { return len(delivered()) == 2 })
+}
+
+func TestPausedSubscriberIsNotRedelivered(t *testing.T) {
+	q, sim := startAcking(t)
+	var deliveries atomic.Int64
+	q.SubscribeWithOptions(context.Background(), "collector", OffsetEarliest, SubscribeOptions{Acknowledge: true}, func(context.Context, *Message) error {
+		deliveries.Add(1)
+		return nil
+	})
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return deliveries.Load() == 1 })
+
+	q.PauseSubscriber("collector")
+	sim.Advance(10 * time.Second)
+	sim.BlockUntil(1)
+	if info := subscriberInfo(q, "collector"); deliveries.Load() != 1 || info.Unacked != 1 || info.Redelivered != 0 {
+		t.Fatalf("expected the unacked message held while paused, got %d deliveries, %+v", deliveries.Load(), info)
+	}
+
+	q.ResumeSubscriber("collector")
+	sim.Advance(time.Second)
+	waitFor(t, func() bool { return deliveries.Load() == 2 })
+}
+
+func TestClientPauseSubscriber(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	received := make(chan string, 10)
+	err := client.Subscribe(ctx, "collector", OffsetLatest, func(_ context.Context, msg *Message) error {
+		received <- string(msg.Payload)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PauseSubscriber(ctx, "collector"); err != nil {
+		t.Fatalf("PauseSubscriber failed: %v", err)
+	}
+	if err := server.queue.Publish(ctx, []byte(`{"id":"held"}`)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-received:
+		t.Fatalf("expected nothing while paused, got %s", p)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if info := subscriberInfo(server.queue, "collector"); !info.Suspended {
+		t.Errorf("expected the subscriber paused on the server, got %+v", info)
+	}
+
+	if err := client.ResumeSubscriber(ctx, "collector"); err != nil {
+		t.Fatalf("ResumeSubscriber failed: %v", err)
+	}
+	select {
+	case p := <-received:
+		if p != `{"id":"held"}` {
+			t.Errorf("expected the held message, got %s", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected delivery after resuming")
+	}
+
+	if err := client.PauseSubscriber(ctx, "nobody"); err == nil {
+		t.Error("expected error pausing an unknown subscriber")
+	}
+}
@@ -0,0 +1,150 @@
+package mq
+
+import (
+	"errors"
+	"sort"
+)
+
+// MetaPriority sets a message's priority class: PriorityControl,
+// PriorityAlert or PriorityTelemetry. Messages without it, or with an
+// unknown class, are telemetry.
+const MetaPriority = "priority"
+
+// Priority classes, most urgent first. A subscriber lagging behind the log
+// is delivered control messages, then alerts, ahead of its telemetry
+// backlog; a subscriber that keeps up receives everything in offset order.
+// Messages delivered ahead are skipped when the subscriber's position
+// reaches them, so each is delivered once, but the position, and so what
+// is committed, still only advances in order: a consumer resuming from its
+// committed offset may see them again.
+const (
+	PriorityControl   = "control"
+	PriorityAlert     = "alert"
+	PriorityTelemetry = "telemetry"
+)
+
+// priorities lists the classes by level, most urgent first.
+var priorities = [...]string{PriorityControl, PriorityAlert, PriorityTelemetry}
+
+// urgentLevels is the number of classes delivered ahead of the backlog.
+const urgentLevels = len(priorities) - 1
+
+// PriorityStats counts the messages of one priority class.
+type PriorityStats struct {
+	Published int64 `json:"published"`
+	Expedited int64 `json:"expedited"` // Deliveries ahead of a subscriber's backlog
+}
+
+// priorityLevel returns the level of the class metadata names.
+func priorityLevel(metadata map[string]string) int {
+	switch metadata[MetaPriority] {
+	case PriorityControl:
+		return 0
+	case PriorityAlert:
+		return 1
+	}
+	return urgentLevels
+}
+
+// indexUrgentLocked records the offset of msg when its class is delivered
+// ahead of the backlog. The caller holds logMu.
+func (q *InMemoryQueue) indexUrgentLocked(msg *Message) {
+	if _, scheduled := msg.Metadata[MetaDeliverAt]; scheduled {
+		return
+	}
+	if level := priorityLevel(msg.Metadata); level < urgentLevels {
+		q.urgent[level] = append(q.urgent[level], msg.Offset)
+	}
+}
+
+// trimUrgentLocked forgets the urgent offsets trimmed from the log. The
+// caller holds logMu.
+func (q *InMemoryQueue) trimUrgentLocked() {
+	for level, offsets := range q.urgent {
+		i := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= q.base })
+		q.urgent[level] = offsets[i:]
+	}
+}
+
+// nextUrgent returns the most urgent message after offset that sub has not
+// been delivered ahead, or nil when there is none. ahead holds, per level,
+// the offset after the last message delivered ahead. A spilled message is
+// paged back in once logMu is released; err is why it could not be.
+func (q *InMemoryQueue) nextUrgent(offset Offset, ahead [urgentLevels]Offset) (*Message, error) {
+	q.logMu.RLock()
+	var msg *Message
+	for level, offsets := range q.urgent {
+		from := max(offset+1, ahead[level])
+		i := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= from })
+		if i < len(offsets) {
+			msg = q.log[offsets[i]-q.base]
+			break
+		}
+	}
+	q.logMu.RUnlock()
+	if msg == nil {
+		return nil, nil
+	}
+
+	msg, err := q.pageIn(msg)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Clone(), nil
+}
+
+// expedite delivers the most urgent message past sub's position ahead of
+// its backlog, and reports whether there was one. stop is set when the
+// budget held it back or it could not be read back from disk.
+func (q *InMemoryQueue) expedite(sub *subscriber) (found, stop bool) {
+	q.subMu.RLock()
+	offset, ahead := sub.offset, sub.ahead
+	q.subMu.RUnlock()
+
+	msg, err := q.nextUrgent(offset, ahead)
+	if errors.Is(err, ErrOffsetOutOfRange) {
+		return true, false // Trimmed while it was read back
+	}
+	if err != nil {
+		q.retryPageIn(sub)
+		return true, true
+	}
+	if msg == nil {
+		return false, false
+	}
+	level := priorityLevel(msg.Metadata)
+	delivered, ok := q.offer(sub, msg)
+	if !ok {
+		return true, true
+	}
+	if delivered {
+		q.expedited[level].Add(1)
+	}
+
+	q.subMu.Lock()
+	// A seek forgets what was delivered ahead
+	if sub.offset == offset && sub.ahead == ahead {
+		sub.ahead[level] = msg.Offset + 1
+	}
+	q.subMu.Unlock()
+	return true, false
+}
+
+// deliveredAhead reports whether msg, at sub's position, was already
+// delivered ahead of the backlog.
+func (s *subscriber) deliveredAhead(msg *Message, ahead [urgentLevels]Offset) bool {
+	level := priorityLevel(msg.Metadata)
+	return level < urgentLevels && msg.Offset < ahead[level]
+}
+
+// priorityStats returns the per-class counters.
+func (q *InMemoryQueue) priorityStats() map[string]PriorityStats {
+	stats := make(map[string]PriorityStats, len(priorities))
+	for level, name := range priorities {
+		stats[name] = PriorityStats{
+			Published: q.published[level].Load(),
+			Expedited: q.expedited[level].Load(),
+		}
+	}
+	return stats
+}
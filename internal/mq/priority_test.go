@@ -0,0 +1,143 @@
+package mq
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// publishPriorities publishes a message with each priority class, named
+// by its ID in the payload.
+func publishPriorities(t *testing.T, q *InMemoryQueue, messages map[string]string) {
+	t.Helper()
+	for _, id := range []string{"t0", "t1", "a0", "t2", "c0", "t3"} {
+		priority, ok := messages[id]
+		if !ok {
+			continue
+		}
+		if err := q.PublishWithMetadata(context.Background(), []byte(id), map[string]string{MetaPriority: priority}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+}
+
+// payloadCollector records the payloads delivered to a subscriber.
+func payloadCollector(q *InMemoryQueue, id string, start Offset, opts SubscribeOptions) func() []string {
+	got := make(chan string, 100)
+	q.SubscribeWithOptions(context.Background(), id, start, opts, func(_ context.Context, msg *Message) error {
+		got <- string(msg.Payload)
+		return nil
+	})
+	var payloads []string
+	return func() []string {
+		for {
+			select {
+			case p := <-got:
+				payloads = append(payloads, p)
+			default:
+				return payloads
+			}
+		}
+	}
+}
+
+var mixedPriorities = map[string]string{
+	"t0": PriorityTelemetry, "t1": "", "a0": PriorityAlert, "t2": "bulk", "c0": PriorityControl, "t3": PriorityTelemetry,
+}
+
+func TestLaggingSubscriberGetsUrgentMessagesFirst(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	publishPriorities(t, q, mixedPriorities)
+
+	delivered := payloadCollector(q, "backfill", OffsetEarliest, SubscribeOptions{})
+	waitFor(t, func() bool { return len(delivered()) == 6 })
+	want := []string{"c0", "a0", "t0", "t1", "t2", "t3"}
+	if got := delivered(); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// Nothing is delivered twice once the position catches up
+	time.Sleep(10 * time.Millisecond)
+	if got := delivered(); len(got) != 6 {
+		t.Fatalf("expected 6 deliveries, got %v", got)
+	}
+	if info := subscriberInfo(q, "backfill"); info.CurrentOffset != 6 || info.Lag != 0 {
+		t.Errorf("expected the position at the end of the log, got %+v", info)
+	}
+
+	stats := q.GetStats().Priorities
+	if stats[PriorityControl] != (PriorityStats{Published: 1, Expedited: 1}) ||
+		stats[PriorityAlert] != (PriorityStats{Published: 1, Expedited: 1}) ||
+		stats[PriorityTelemetry] != (PriorityStats{Published: 4}) {
+		t.Errorf("unexpected priority stats: %+v", stats)
+	}
+}
+
+func TestCaughtUpSubscriberKeepsOffsetOrder(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	delivered := payloadCollector(q, "live", OffsetLatest, SubscribeOptions{})
+
+	ids := []string{"t0", "t1", "a0", "t2", "c0", "t3"}
+	for i, id := range ids {
+		q.PublishWithMetadata(context.Background(), []byte(id), map[string]string{MetaPriority: mixedPriorities[id]})
+		waitFor(t, func() bool { return len(delivered()) == i+1 })
+	}
+	if got := delivered(); !slices.Equal(got, ids) {
+		t.Fatalf("expected %v, got %v", ids, got)
+	}
+	if stats := q.GetStats().Priorities; stats[PriorityControl].Expedited != 0 || stats[PriorityAlert].Expedited != 0 {
+		t.Errorf("expected nothing expedited, got %+v", stats)
+	}
+}
+
+func TestExpeditedMessagesRespectFilters(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	ctx := context.Background()
+	q.PublishWithMetadata(ctx, []byte("t0"), map[string]string{MetaHostname: "host-1"})
+	q.PublishWithMetadata(ctx, []byte("a0"), map[string]string{MetaHostname: "host-2", MetaPriority: PriorityAlert})
+	q.PublishWithMetadata(ctx, []byte("a1"), map[string]string{MetaHostname: "host-1", MetaPriority: PriorityAlert})
+
+	filter, _ := ParseFilter("hostname=host-1")
+	delivered := payloadCollector(q, "host-1", OffsetEarliest, SubscribeOptions{Filter: filter})
+	waitFor(t, func() bool { return subscriberInfo(q, "host-1").CurrentOffset == 3 })
+	if got := delivered(); !slices.Equal(got, []string{"a1", "t0"}) {
+		t.Fatalf("expected a1 then t0, got %v", got)
+	}
+	if info := subscriberInfo(q, "host-1"); info.Filtered != 1 {
+		t.Errorf("expected the other host's alert filtered once, got %+v", info)
+	}
+}
+
+func TestSeekRedeliversExpeditedMessages(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	publishPriorities(t, q, mixedPriorities)
+
+	delivered := payloadCollector(q, "replay", OffsetEarliest, SubscribeOptions{})
+	waitFor(t, func() bool { return len(delivered()) == 6 })
+
+	if err := q.SetSubscriberOffset("replay", 3); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(delivered()) == 9 })
+	if got := delivered()[6:]; !slices.Equal(got, []string{"c0", "t2", "t3"}) {
+		t.Fatalf("expected c0 ahead of t2 and t3 again, got %v", got)
+	}
+}
+
+func TestTrimForgetsUrgentOffsets(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.RetentionMessages = 3
+	q := startRetaining(t, cfg)
+	publishPriorities(t, q, mixedPriorities)
+
+	q.trim(time.Now())
+	if len(q.urgent[0]) != 1 || len(q.urgent[1]) != 0 {
+		t.Fatalf("expected only the control message indexed, got %v", q.urgent)
+	}
+	delivered := payloadCollector(q, "late", OffsetEarliest, SubscribeOptions{})
+	waitFor(t, func() bool { return len(delivered()) == 3 })
+	if got := delivered(); !slices.Equal(got, []string{"c0", "t2", "t3"}) {
+		t.Fatalf("expected c0 ahead of t2 and t3, got %v", got)
+	}
+}
@@ -0,0 +1,137 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// probeSubscriber is the built-in subscriber that receives loopback probes.
+const probeSubscriber = "_probe"
+
+// probeWindow is how many recent probe latencies the percentiles cover.
+const probeWindow = 100
+
+// ProbeStats summarizes the delivery latency of loopback probes: messages
+// the queue publishes to itself on a timer and delivers to a built-in
+// subscriber. It shows whether the queue delivers promptly even when no
+// telemetry is flowing.
+type ProbeStats struct {
+	// Interval is how often a probe is published
+	Interval string `json:"interval"`
+
+	// Sent and Received count probes published and delivered
+	Sent     int64 `json:"sent"`
+	Received int64 `json:"received"`
+
+	// LastMs is the latest probe's publish-to-delivery latency; P50Ms, P99Ms
+	// and MaxMs cover the last 100 probes
+	LastMs float64 `json:"last_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+
+	// LastReceived is when the latest probe was delivered
+	LastReceived time.Time `json:"last_received,omitempty"`
+}
+
+// prober publishes probes and records their latencies.
+type prober struct {
+	interval time.Duration
+
+	mu        sync.Mutex
+	sent      int64
+	received  int64
+	latencies []time.Duration // ring of the last probeWindow latencies
+	next      int
+	last      time.Duration
+	lastAt    time.Time
+}
+
+// startProbes subscribes the built-in probe subscriber and publishes a probe
+// every interval until the queue shuts down.
+func (q *InMemoryQueue) startProbes(interval time.Duration) error {
+	p := &prober{interval: interval}
+	err := q.SubscribeWithOptions(q.ctx, probeSubscriber, OffsetLatest, SubscribeOptions{Probes: true}, func(ctx context.Context, msg *Message) error {
+		now := q.clock.Now()
+		p.observe(now.Sub(msg.Timestamp), now)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	q.probes = p
+
+	ticker := q.clock.NewTicker(interval)
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case <-ticker.C():
+				seq := p.markSent()
+				_, err := q.append(q.ctx, nil, map[string]string{MetaProbe: strconv.FormatInt(seq, 10)})
+				// A probe that finds the log full, or a standby's log closed to
+				// publishes, is lost, like one never delivered
+				if err != nil && !errors.Is(err, ErrQueueFull) && !errors.Is(err, ErrPublishTimeout) && !errors.Is(err, ErrStandby) {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// markSent counts a probe about to be published and returns its sequence number.
+func (p *prober) markSent() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent++
+	return p.sent
+}
+
+// observe records a delivered probe's latency.
+func (p *prober) observe(latency time.Duration, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.received++
+	p.last, p.lastAt = latency, at
+	if len(p.latencies) < probeWindow {
+		p.latencies = append(p.latencies, latency)
+	} else {
+		p.latencies[p.next] = latency
+	}
+	p.next = (p.next + 1) % probeWindow
+}
+
+// stats summarizes the probes so far.
+func (p *prober) stats() *ProbeStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := &ProbeStats{
+		Interval:     p.interval.String(),
+		Sent:         p.sent,
+		Received:     p.received,
+		LastMs:       ms(p.last),
+		LastReceived: p.lastAt,
+	}
+	if len(p.latencies) == 0 {
+		return st
+	}
+	sorted := slices.Clone(p.latencies)
+	slices.Sort(sorted)
+	st.P50Ms = ms(sorted[len(sorted)*50/100])
+	st.P99Ms = ms(sorted[len(sorted)*99/100])
+	st.MaxMs = ms(sorted[len(sorted)-1])
+	return st
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,61 @@
+package mq
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbes(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.ProbeInterval = 5 * time.Millisecond
+	q := NewInMemoryQueue(cfg)
+	ctx := context.Background()
+	if err := q.Start(ctx); err != nil {
+		t.Fatalf("failed to start queue: %v", err)
+	}
+	defer q.Shutdown(ctx)
+
+	var received int64
+	if err := q.Subscribe(ctx, "collector", OffsetEarliest, func(ctx context.Context, msg *Message) error {
+		atomic.AddInt64(&received, 1)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := q.Publish(ctx, []byte("telemetry")); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for q.GetStats().Probes.Received < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("probes not delivered: %+v", q.GetStats().Probes)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stats := q.GetStats()
+	if got := atomic.LoadInt64(&received); got != 1 {
+		t.Errorf("expected the subscriber to see only the telemetry message, got %d", got)
+	}
+	if stats.TotalMessages != 1 || stats.SubscriberCount != 1 {
+		t.Errorf("expected probes left out of message and subscriber counts, got %+v", stats)
+	}
+	if p := stats.Probes; p.Sent < p.Received || p.MaxMs < p.P50Ms || p.Interval != "5ms" || p.LastReceived.IsZero() {
+		t.Errorf("unexpected probe stats %+v", p)
+	}
+}
+
+func TestProbesDisabled(t *testing.T) {
+	q := NewInMemoryQueue(DefaultQueueConfig())
+	ctx := context.Background()
+	if err := q.Start(ctx); err != nil {
+		t.Fatalf("failed to start queue: %v", err)
+	}
+	defer q.Shutdown(ctx)
+	if p := q.GetStats().Probes; p != nil {
+		t.Errorf("expected no probe stats without an interval, got %+v", p)
+	}
+}
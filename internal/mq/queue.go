@@ -6,22 +6,32 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
 )
 
 // Common errors returned by the message queue.
 var (
-	ErrQueueFull          = errors.New("queue is full")
-	ErrPublishTimeout     = errors.New("publish timeout")
-	ErrQueueShutdown      = errors.New("queue is shutting down")
-	ErrInvalidConfig      = errors.New("invalid configuration")
-	ErrInvalidOffset      = errors.New("invalid offset")
-	ErrSubscriberExists   = errors.New("subscriber already exists")
-	ErrSubscriberNotFound = errors.New("subscriber not found")
+	ErrQueueFull          = perrors.New(perrors.KindTransient, "queue is full")
+	ErrPublishTimeout     = perrors.New(perrors.KindTransient, "publish timeout")
+	ErrQueueShutdown      = perrors.New(perrors.KindPermanent, "queue is shutting down")
+	ErrInvalidConfig      = perrors.New(perrors.KindValidation, "invalid configuration")
+	ErrInvalidOffset      = perrors.New(perrors.KindValidation, "invalid offset")
+	ErrOffsetOutOfRange   = perrors.New(perrors.KindNotFound, "offset has been trimmed from the log")
+	ErrSubscriberExists   = perrors.New(perrors.KindValidation, "subscriber already exists")
+	ErrSubscriberNotFound = perrors.New(perrors.KindNotFound, "subscriber not found")
+	ErrStandby            = perrors.New(perrors.KindTransient, "standby server only replicates its primary until promoted")
 )
 
 // Offset represents a position in the message log.
@@ -32,35 +42,75 @@ const (
 	OffsetEarliest Offset = -2
 	// OffsetLatest starts reading from new messages only.
 	OffsetLatest Offset = -1
+	// OffsetCommitted resumes from the subscriber's last committed offset,
+	// falling back to OffsetLatest when nothing has been committed.
+	OffsetCommitted Offset = -3
 )
 
+// ParseOffset parses an offset name ("earliest", "latest", "committed") or a
+// non-negative numeric offset.
+func ParseOffset(s string) (Offset, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "earliest":
+		return OffsetEarliest, nil
+	case "latest", "":
+		return OffsetLatest, nil
+	case "committed":
+		return OffsetCommitted, nil
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidOffset, s)
+	}
+	return Offset(n), nil
+}
+
 // Message represents a message in the queue.
 type Message struct {
 	ID        string            `json:"id"`
 	Offset    Offset            `json:"offset"`
+	Partition int               `json:"partition"`
 	Payload   []byte            `json:"payload"`
 	Timestamp time.Time         `json:"timestamp"`
 	Metadata  map[string]string `json:"metadata,omitempty"`
+
+	// Checksum is the CRC-32 of the payload taken at publish when
+	// Checksummed is set; logs written before checksums were taken hold
+	// messages without it. See Verify.
+	Checksum    uint32 `json:"checksum,omitempty"`
+	Checksummed bool   `json:"checksummed,omitempty"`
+
+	// spilled is the length of the payload of a message in the log whose
+	// payload was spilled to disk, and onDisk marks the message standing in
+	// for it until pageIn reads it back; see spillLocked
+	spilled int
+	onDisk  bool
 }
 
 // NewMessage creates a new message with the given payload.
 func NewMessage(payload []byte) *Message {
 	return &Message{
-		ID:        uuid.New().String(),
-		Payload:   payload,
-		Timestamp: time.Now(),
-		Metadata:  make(map[string]string),
+		ID:          uuid.New().String(),
+		Payload:     payload,
+		Timestamp:   time.Now(),
+		Metadata:    make(map[string]string),
+		Checksum:    payloadChecksum(payload),
+		Checksummed: true,
 	}
 }
 
 // Clone creates a deep copy of the message.
 func (m *Message) Clone() *Message {
 	clone := &Message{
-		ID:        m.ID,
-		Offset:    m.Offset,
-		Payload:   make([]byte, len(m.Payload)),
-		Timestamp: m.Timestamp,
-		Metadata:  make(map[string]string),
+		ID:          m.ID,
+		Offset:      m.Offset,
+		Partition:   m.Partition,
+		Payload:     make([]byte, len(m.Payload)),
+		Timestamp:   m.Timestamp,
+		Metadata:    make(map[string]string),
+		Checksum:    m.Checksum,
+		Checksummed: m.Checksummed,
 	}
 	copy(clone.Payload, m.Payload)
 	for k, v := range m.Metadata {
@@ -89,13 +139,143 @@ type QueueStats struct {
 	LatestOffset    Offset           `json:"latest_offset"`
 	SubscriberCount int              `json:"subscriber_count"`
 	Subscribers     []SubscriberInfo `json:"subscribers"`
+	Partitions      int              `json:"partitions"`
+
+	// Probes reports loopback probe latency when probes are enabled
+	Probes *ProbeStats `json:"probes,omitempty"`
+
+	// WAL reports the write-ahead log when the queue persists to disk
+	WAL *WALStats `json:"wal,omitempty"`
+
+	// Spill reports the messages held on disk only when the queue has a
+	// memory budget
+	Spill *SpillStats `json:"spill,omitempty"`
+
+	// CommitError is the last failure to persist auto-committed offsets
+	CommitError string `json:"commit_error,omitempty"`
+
+	// DeadLetters counts messages subscribers gave up on since the server
+	// started, DeadLettersKept those DeadLetters still holds, and
+	// DeadLetterError is the last failure to persist them
+	DeadLetters     int64  `json:"dead_letters"`
+	DeadLettersKept int    `json:"dead_letters_kept"`
+	DeadLetterError string `json:"dead_letter_error,omitempty"`
+
+	// RetainedBytes is the payload and metadata size of the messages in the
+	// log, and TrimmedMessages how many retention has removed from it
+	RetainedBytes   int64 `json:"retained_bytes"`
+	TrimmedMessages int64 `json:"trimmed_messages"`
+
+	// RejectedMessages counts publishes refused because the log was full,
+	// and DroppedMessages the oldest messages trimmed to make room for new
+	// ones, which TrimmedMessages includes
+	RejectedMessages int64 `json:"rejected_messages"`
+	DroppedMessages  int64 `json:"dropped_messages"`
+
+	// ThrottledPublishes counts publishes refused because the publisher
+	// was over its rate limit
+	ThrottledPublishes int64 `json:"throttled_publishes"`
+
+	// DuplicateMessages counts publishes dropped because their idempotency
+	// key was published within the dedup window
+	DuplicateMessages int64 `json:"duplicate_messages"`
+
+	// EvictedSubscribers counts subscribers removed for exceeding their
+	// pending byte budget
+	EvictedSubscribers int64 `json:"evicted_subscribers"`
+
+	// Messages published, and delivered ahead of a lagging subscriber's
+	// backlog, by priority class
+	Priorities map[string]PriorityStats `json:"priorities,omitempty"`
+
+	// ScheduledMessages counts messages published with MetaDeliverAt or
+	// MetaDeliverAfter that are not yet due
+	ScheduledMessages int `json:"scheduled_messages"`
+
+	// Replication reports a standby's replication of its primary, or that
+	// the server was promoted from a standby
+	Replication *ReplicationStats `json:"replication,omitempty"`
+
+	// Epoch is the promotion epoch; see InMemoryQueue.Epoch
+	Epoch int64 `json:"epoch,omitempty"`
+
+	// DeliveredMessages counts messages handed to subscribers, redeliveries
+	// included. PublishRate and DeliverRate are messages per second over
+	// the last minute.
+	DeliveredMessages int64   `json:"delivered_messages"`
+	PublishRate       float64 `json:"publish_rate"`
+	DeliverRate       float64 `json:"deliver_rate"`
+	// Clients reports the traffic of each connected client; only the
+	// server fills it in
+	Clients []ClientInfo `json:"clients,omitempty"`
 }
 
 // SubscriberInfo contains info about a subscriber's position.
 type SubscriberInfo struct {
 	ID            string `json:"id"`
 	CurrentOffset Offset `json:"current_offset"`
-	Lag           int64  `json:"lag"` // How far behind latest
+	Lag           int64  `json:"lag"`                  // How far behind latest
+	Filter        string `json:"filter,omitempty"`     // Server-side filter expression
+	Filtered      int64  `json:"filtered"`             // Messages skipped by the filter
+	Trimmed       int64  `json:"trimmed"`              // Messages trimmed before delivery
+	Corrupt       int64  `json:"corrupt,omitempty"`    // Messages failing their checksum, dead-lettered
+	Partitions    []int  `json:"partitions,omitempty"` // Partitions consumed, all if empty
+
+	// Unacked counts messages delivered to an acknowledging subscriber and
+	// not yet acked, Redelivered those delivered again after a nack, ack
+	// timeout or handler error, and Abandoned those given up on after
+	// MaxRetries redeliveries
+	Unacked     int   `json:"unacked,omitempty"`
+	Redelivered int64 `json:"redelivered,omitempty"`
+	Abandoned   int64 `json:"abandoned,omitempty"`
+
+	// Members lists, for a consumer group, the partitions assigned to each
+	// member, and Rebalances how often they were reassigned
+	Members    map[string][]int `json:"members,omitempty"`
+	Rebalances int64            `json:"rebalances,omitempty"`
+
+	// PendingBytes is the size of the messages delivered and not yet
+	// acked. Paused is set while delivery waits for acks to bring it under
+	// the budget or its MaxUnacked, Overflows counts messages that would
+	// have exceeded the budget, and Skipped the messages passed over by
+	// skipping ahead.
+	PendingBytes int64 `json:"pending_bytes"`
+	Paused       bool  `json:"paused,omitempty"`
+	Overflows    int64 `json:"overflows,omitempty"`
+	Skipped      int64 `json:"skipped,omitempty"`
+
+	// Deferred counts scheduled messages passed over, waiting to fall due
+	Deferred int `json:"deferred,omitempty"`
+
+	// Suspended is set while PauseSubscriber holds delivery
+	Suspended bool `json:"suspended,omitempty"`
+
+	// Batches counts the handler calls of a batch subscriber
+	Batches int64 `json:"batches,omitempty"`
+
+	// InFlight counts the handler calls its workers are running
+	InFlight int `json:"in_flight,omitempty"`
+
+	// Delivered counts messages handed to the subscriber, and HandlerErrors
+	// those its handler failed, remote consumers' nacks included
+	Delivered     int64 `json:"delivered"`
+	HandlerErrors int64 `json:"handler_errors"`
+
+	// LagHistory is the subscriber's lag every 5s over the last minute,
+	// oldest first
+	LagHistory []int64 `json:"lag_history,omitempty"`
+}
+
+// OffsetInfo describes a subscriber's position in the log.
+type OffsetInfo struct {
+	SubscriberID string `json:"subscriber_id"`
+	Active       bool   `json:"active"`           // Currently subscribed
+	Current      Offset `json:"current_offset"`   // Next offset to deliver (active only)
+	Committed    Offset `json:"committed_offset"` // Last committed offset, -1 if none
+	Latest       Offset `json:"latest_offset"`    // Offset of the newest message
+	Oldest       Offset `json:"oldest_offset"`    // Offset of the oldest retained message
+	Lag          int64  `json:"lag"`              // Messages between current and latest
+	Filter       string `json:"filter,omitempty"` // Server-side filter expression
 }
 
 // QueueConfig configures the queue behavior.
@@ -104,6 +284,90 @@ type QueueConfig struct {
 	PublishTimeout time.Duration `json:"publish_timeout"`
 	MaxRetries     int           `json:"max_retries"`
 	RetryDelay     time.Duration `json:"retry_delay"`
+	ProbeInterval  time.Duration `json:"probe_interval"` // Loopback probe period (0 = no probes)
+
+	// AckTimeout is how long a message delivered to a subscriber with
+	// SubscribeOptions.Acknowledge may go unacked before it is delivered
+	// again. Nacked messages are delivered again after a retry delay.
+	// Either way a message is redelivered at most MaxRetries times and then
+	// abandoned (0 = acks are not tracked)
+	AckTimeout time.Duration `json:"ack_timeout"`
+
+	// A failed message is retried after RetryDelay, and each further retry
+	// waits RetryMultiplier times longer, up to RetryMaxDelay (0 = no cap).
+	// RetryJitter randomizes each delay by up to that fraction either way,
+	// so failing messages do not all come back at once.
+	RetryMultiplier float64       `json:"retry_multiplier"`
+	RetryMaxDelay   time.Duration `json:"retry_max_delay"`
+	RetryJitter     float64       `json:"retry_jitter"`
+
+	// DataDir persists the log and committed offsets to a write-ahead log
+	// in this directory, recovered on Start (empty = memory only)
+	DataDir       string        `json:"data_dir"`
+	FsyncPolicy   string        `json:"fsync_policy"`   // FsyncAlways, FsyncInterval or FsyncNever
+	FsyncInterval time.Duration `json:"fsync_interval"` // Sync period under FsyncInterval
+	SegmentBytes  int64         `json:"segment_bytes"`  // Size a segment file is rolled at
+
+	// MemoryBytes bounds the payload and metadata of the log held in memory
+	// when it persists to DataDir (0 = unbounded). Past it, the payloads of
+	// the oldest segments are dropped from memory, their files standing in,
+	// and read back for subscribers that far behind.
+	MemoryBytes int64 `json:"memory_bytes"`
+
+	// Retention trims the oldest messages once the log holds more than
+	// RetentionMessages messages or RetentionBytes of payload and metadata,
+	// and once they are older than RetentionAge (0 = unlimited). The
+	// trimmer runs every RetentionInterval.
+	RetentionMessages int           `json:"retention_messages"`
+	RetentionBytes    int64         `json:"retention_bytes"`
+	RetentionAge      time.Duration `json:"retention_age"`
+	RetentionInterval time.Duration `json:"retention_interval"`
+
+	// MaxMessages and MaxBytes bound the log on every publish, where
+	// retention only trims it periodically (0 = unbounded). A publish that
+	// would take the log past either bound is handled by OverflowPolicy:
+	// OverflowReject fails it with ErrQueueFull, OverflowBlock waits up to
+	// PublishTimeout for retention to make room, and OverflowDropOldest
+	// trims the oldest messages to fit it.
+	MaxMessages    int    `json:"max_messages"`
+	MaxBytes       int64  `json:"max_bytes"`
+	OverflowPolicy string `json:"overflow_policy"`
+
+	// AutoCommitInterval commits every subscriber's position this often, on
+	// Release and when the queue shuts down, so a consumer that never
+	// commits resumes near where it was after either restarts. Positions
+	// count messages as delivered, not processed; subscribers with
+	// SubscribeOptions.ManualCommit are never auto-committed (0 = only
+	// explicit commits)
+	AutoCommitInterval time.Duration `json:"auto_commit_interval"`
+
+	// Partitions splits the log into this many partitions (0 or 1 = one).
+	// Messages with the same MetaPartitionKey always land in the same
+	// partition, so collectors can share the load by consuming disjoint
+	// sets of partitions. Offsets stay global to the log.
+	Partitions int `json:"partitions"`
+
+	// DedupWindow is how long the idempotency key of a published message
+	// is remembered, so a republish carrying the same MetaIdempotencyKey
+	// is dropped and answered with the first message's offset. At most
+	// DedupMaxKeys keys are remembered, the oldest forgotten first
+	// (DedupWindow 0 = no deduplication, DedupMaxKeys 0 = unlimited)
+	DedupWindow  time.Duration `json:"dedup_window"`
+	DedupMaxKeys int           `json:"dedup_max_keys"`
+
+	// MaxDeadLetters is how many of the messages subscribers gave up on
+	// are kept for DeadLetters, the oldest dropped first, and persisted
+	// with DataDir (0 = none are kept)
+	MaxDeadLetters int `json:"max_dead_letters"`
+
+	// SubscriberMaxPendingBytes bounds the memory each subscriber holds in
+	// messages delivered and not yet acked, so a consumer backfilling from
+	// an old offset cannot crowd out the others (0 = unbounded). A
+	// subscriber that would exceed it is handled by SubscriberOverflowPolicy:
+	// SubscriberPause waits for its acks, SubscriberSkipAhead moves it to
+	// the end of the log, and SubscriberDisconnect removes it.
+	SubscriberMaxPendingBytes int64  `json:"subscriber_max_pending_bytes"`
+	SubscriberOverflowPolicy  string `json:"subscriber_overflow_policy"`
 }
 
 // DefaultQueueConfig returns a queue config with sensible defaults.
@@ -113,37 +377,239 @@ func DefaultQueueConfig() QueueConfig {
 		PublishTimeout: 5 * time.Second,
 		MaxRetries:     3,
 		RetryDelay:     time.Second,
+		AckTimeout:     30 * time.Second,
+		FsyncPolicy:    FsyncInterval,
+		FsyncInterval:  time.Second,
+		SegmentBytes:   64 << 20,
+
+		RetryMultiplier: 2,
+		RetryMaxDelay:   30 * time.Second,
+		RetryJitter:     0.2,
+
+		RetentionInterval: 10 * time.Second,
+		OverflowPolicy:    OverflowReject,
+		Partitions:        1,
+		DedupWindow:       5 * time.Minute,
+		DedupMaxKeys:      100000,
+		MaxDeadLetters:    1000,
+
+		SubscriberOverflowPolicy: SubscriberPause,
 	}
 }
 
+// SubscribeOptions configures optional subscriber behavior.
+type SubscribeOptions struct {
+	// Filter drops messages whose metadata does not match before delivery
+	Filter *Filter
+
+	// Probes delivers loopback probes, which other subscribers never see
+	Probes bool
+
+	// Partitions restricts delivery to messages in these partitions (nil =
+	// all). Messages in other partitions are skipped without being counted
+	// as filtered.
+	Partitions []int
+
+	// Resume starts at the subscriber's committed offset when it has one,
+	// and at the start offset otherwise
+	Resume bool
+
+	// Group joins the subscriber to a consumer group. Members share one
+	// position, kept under the group's name, and the group's partitions are
+	// split between them. The start offset and filter apply when the first
+	// member creates the group.
+	Group string
+
+	// ManualCommit leaves committing to the consumer, which commits only
+	// what it has processed: auto-commit skips the subscriber, and Rewind
+	// redelivers messages its handler failed
+	ManualCommit bool
+
+	// Acknowledge tracks each delivered message until the consumer calls
+	// Ack: messages nacked, or not acked within QueueConfig.AckTimeout, are
+	// delivered again. A handler error counts as a nack. Ignored with
+	// ManualCommit, or when the queue's AckTimeout is 0.
+	Acknowledge bool
+
+	// Paused subscribes paused, as PauseSubscriber leaves it, so nothing
+	// is delivered until ResumeSubscriber. A member joining a group pauses
+	// the group.
+	Paused bool
+
+	// OnAbandon is called with each message given up on after MaxRetries
+	// retries in place of dead-lettering it; see InMemoryQueue.DeadLetters
+	OnAbandon func(msg *Message)
+
+	// RetryInPlace retries a message that the handler of a subscriber that
+	// does not acknowledge failed, after the same backoff as a nack, up to
+	// MaxRetries times. Retries hold back the messages after it, for up to
+	// 7s per failing message with the default retry settings. Otherwise a
+	// failed message is given up on at once.
+	RetryInPlace bool
+
+	// Workers handles up to this many messages, or batches, at once (0 or
+	// 1 = one at a time). They are handed out in offset order but may
+	// complete in any order, so only use it when messages can be handled
+	// independently. The committed position still only advances past a
+	// message once it and every message before it completed. For a group,
+	// it applies when the first member creates the group.
+	Workers int
+
+	// OnOverflow is called after the subscriber is removed for exceeding
+	// QueueConfig.SubscriberMaxPendingBytes under SubscriberDisconnect
+	OnOverflow func()
+
+	// MaxUnacked pauses delivery to an acknowledging subscriber holding
+	// this many unacked messages until acks make room (0 = unlimited).
+	// Ignored when Acknowledge is. For a group, it applies when the first
+	// member creates the group.
+	MaxUnacked int
+
+	// batch is set by SubscribeBatchWithOptions
+	batch *batchState
+}
+
 // subscriber tracks a consumer's offset and notification channel.
 type subscriber struct {
-	id      string
-	offset  Offset // Current read position
-	handler MessageHandler
-	notify  chan struct{} // Signaled when new messages arrive
+	id       string
+	offset   Offset // Current read position
+	handler  MessageHandler
+	notify   chan struct{} // Signaled when new messages arrive
+	filter   *Filter
+	filtered int64 // Messages skipped by the filter
+	trimmed  int64 // Messages trimmed before they were delivered
+	corrupt  int64 // Messages failing their checksum, dead-lettered
+	probes   bool  // Receives loopback probes
+
+	partitions   []int     // Partitions delivered, all if empty
+	group        *group    // Set when this is a consumer group's shared position
+	manualCommit bool      // Commits its own offsets; see SubscribeOptions
+	acks         *ackState // Unacked deliveries; nil unless the subscriber acknowledges
+
+	// Bytes delivered and not yet acked, or being handed to the handler,
+	// bounded by QueueConfig.SubscriberMaxPendingBytes
+	pending    atomic.Int64
+	unacked    atomic.Int64 // Deliveries tracked in acks, bounded by maxUnacked
+	maxUnacked int          // See SubscribeOptions.MaxUnacked
+	paused     atomic.Bool  // Waiting for acks under SubscriberPause or maxUnacked
+	suspended  atomic.Bool  // Paused by PauseSubscriber
+	overflows  atomic.Int64 // Messages that would have exceeded the budget
+	skipped    int64        // Messages skipped under SubscriberSkipAhead
+	onOverflow func()       // Called when evicted under SubscriberDisconnect
+
+	// Per urgent priority level, the offset after the last message
+	// delivered ahead of the backlog; reset by seeks
+	ahead [urgentLevels]Offset
+
+	// Scheduled messages passed over before they were due, in due order;
+	// reset by seeks
+	deferred []deferral
+
+	// batch is set for a batch subscriber; see SubscribeBatch
+	batch *batchState
+
+	// workers is set when the subscriber has more than one; see
+	// SubscribeOptions.Workers
+	workers *workerPool
+
+	delivered     atomic.Int64 // Messages handed to the handler
+	handlerErrors atomic.Int64 // Messages the handler failed
+	redelivered   atomic.Int64 // Messages handed over again after failing
+	abandoned     atomic.Int64 // Messages given up on after MaxRetries
+	lagHistory    []int64      // Sampled lag, oldest first; see sample
+
+	// onAbandon is called with abandoned messages; see SubscribeOptions
+	onAbandon    func(*Message)
+	retryInPlace bool
 }
 
 // InMemoryQueue is a log-based in-memory queue.
-// Messages are stored in an append-only log that grows dynamically.
+// Messages are stored in an append-only log that grows dynamically, and
+// that retention trims from the front.
 // Multiple consumers can read independently using offsets.
 type InMemoryQueue struct {
 	// Message log - append-only, grows dynamically
-	log   []*Message
-	logMu sync.RWMutex
+	log      []*Message
+	base     Offset // Offset of log[0], advanced by trimming
+	logBytes int64  // Size of the messages in the log
+	trimmed  int64  // Messages trimmed since the queue started
+	rejected int64  // Messages refused because the log was full
+	dropped  int64  // Messages trimmed to make room under OverflowDropOldest
+	logMu    sync.RWMutex
+
+	// spill tracks the payloads held on disk only; see spillLocked
+	spill spill
+
+	// urgent holds the offsets of retained messages, per priority level
+	// above telemetry, that lagging subscribers are delivered first
+	urgent [urgentLevels][]Offset
+
+	// scheduled wakes subscribers as scheduled messages fall due
+	scheduled *scheduler
+
+	// dedup remembers recent idempotency keys; nil when deduplication is off
+	dedup      *dedupWindow
+	duplicates int64 // Publishes dropped as duplicates
+
+	// spaceFreed is closed and replaced whenever messages leave the log,
+	// waking publishers blocked under OverflowBlock
+	spaceFreed chan struct{}
+
+	// appended is closed and replaced whenever messages join the log,
+	// waking standbys waiting in ReplicaBatch
+	appended chan struct{}
+
+	// standby refuses publishes, subscriptions and commits while the log
+	// is replicated from a primary; see SetStandby
+	standby     atomic.Bool
+	replication atomic.Pointer[ReplicationStats]
+	epoch       atomic.Int64 // See Epoch
 
 	// Subscribers - each tracks their own offset
 	subscribers map[string]*subscriber
 	subMu       sync.RWMutex
 
+	// Committed offsets by subscriber ID; outlive the subscription itself
+	committed map[string]Offset
+
+	// Consumer groups by name, and the groups of their members by ID
+	groups  map[string]*group
+	members map[string]*group
+
 	config  QueueConfig
+	clock   clock.Clock // Stamps message timestamps
 	ctx     context.Context
 	cancel  context.CancelFunc
 	wg      sync.WaitGroup
 	running atomic.Bool
 
+	// probes measures delivery latency; nil unless ProbeInterval is set
+	probes *prober
+
+	// wal persists the log; nil unless DataDir is set
+	wal      *wal
+	walStop  chan struct{}
+	walSyncs sync.WaitGroup
+
+	// commitErr holds the last failure to persist auto-committed offsets
+	commitErr atomic.Value
+
+	// deadLetters keeps the messages subscribers gave up on
+	deadLetters deadLetters
+
+	// nextPartition assigns messages without a partition key round-robin
+	nextPartition atomic.Uint64
+
 	// Stats
 	totalPublished int64
+	delivered      atomic.Int64 // Messages handed to subscribers
+	throughput     throughput
+	throttled      atomic.Int64 // Publishes refused by the server's rate limits
+	evicted        atomic.Int64 // Subscribers removed under SubscriberDisconnect
+
+	// Messages published and delivered ahead of a backlog, by priority level
+	published [len(priorities)]atomic.Int64
+	expedited [len(priorities)]atomic.Int64
 }
 
 // NewInMemoryQueue creates a new log-based in-memory queue.
@@ -151,24 +617,61 @@ func NewInMemoryQueue(config QueueConfig) *InMemoryQueue {
 	if config.BufferSize <= 0 {
 		config.BufferSize = 10000
 	}
+	if config.Partitions <= 0 {
+		config.Partitions = 1
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &InMemoryQueue{
 		log:         make([]*Message, 0, config.BufferSize),
 		subscribers: make(map[string]*subscriber),
+		committed:   make(map[string]Offset),
+		groups:      make(map[string]*group),
+		members:     make(map[string]*group),
+		spaceFreed:  make(chan struct{}),
+		appended:    make(chan struct{}),
+		dedup:       newDedupWindow(config),
+		scheduled:   newScheduler(),
 		config:      config,
+		clock:       clock.Real,
 		ctx:         ctx,
 		cancel:      cancel,
 	}
 }
 
-// Start starts the queue processing.
+// SetClock replaces the clock that stamps published messages. Call it
+// before publishing.
+func (q *InMemoryQueue) SetClock(c clock.Clock) {
+	q.clock = c
+}
+
+// Start starts the queue processing. With a DataDir, the log and committed
+// offsets are first recovered from the write-ahead log.
 func (q *InMemoryQueue) Start(ctx context.Context) error {
 	if q.running.Load() {
 		return nil
 	}
+	if q.config.DataDir != "" {
+		if err := q.openWAL(); err != nil {
+			return fmt.Errorf("failed to recover the log from %s: %w", q.config.DataDir, err)
+		}
+	}
 	q.running.Store(true)
+	q.startScheduler()
+	q.startSampler()
+	if q.retains() {
+		q.startTrimmer()
+	}
+	if q.autoCommits() {
+		q.startCommitter()
+	}
+	if q.acknowledges() {
+		q.startRedeliverer()
+	}
+	if q.config.ProbeInterval > 0 {
+		return q.startProbes(q.config.ProbeInterval)
+	}
 	return nil
 }
 
@@ -180,10 +683,18 @@ func (q *InMemoryQueue) Shutdown(ctx context.Context) error {
 	q.running.Store(false)
 	q.cancel()
 
-	// Close all subscriber notify channels
+	// Close all subscriber notify channels; a later Unsubscribe finds nothing to close
 	q.subMu.Lock()
-	for _, sub := range q.subscribers {
+	if q.autoCommits() {
+		subs := make([]*subscriber, 0, len(q.subscribers))
+		for _, sub := range q.subscribers {
+			subs = append(subs, sub)
+		}
+		q.commitPositionsLocked(subs...)
+	}
+	for id, sub := range q.subscribers {
 		close(sub.notify)
+		delete(q.subscribers, id)
 	}
 	q.subMu.Unlock()
 
@@ -195,49 +706,218 @@ func (q *InMemoryQueue) Shutdown(ctx context.Context) error {
 
 	select {
 	case <-done:
-		return nil
 	case <-ctx.Done():
 		return ctx.Err()
 	}
+	return q.closeWAL()
+}
+
+// openWAL recovers the log, committed offsets and dead letters from disk,
+// and starts syncing appends under the interval policy.
+func (q *InMemoryQueue) openWAL() error {
+	w, messages, committed, err := openWAL(q.config)
+	if err != nil {
+		return err
+	}
+	letters, err := w.readDeadLetters()
+	if err != nil {
+		w.close()
+		return err
+	}
+	q.restoreDeadLetters(letters)
+	epoch, err := w.readEpoch()
+	if err != nil {
+		w.close()
+		return err
+	}
+	q.epoch.Store(epoch)
+
+	q.logMu.Lock()
+	q.log = append(q.log[:0], messages...)
+	q.base = w.oldest()
+	q.logBytes = 0
+	for _, msg := range messages {
+		q.logBytes += messageSize(msg)
+		q.indexUrgentLocked(msg)
+	}
+	q.spillLocked()
+	// Keys published within the window before the restart still dedup
+	now := q.clock.Now()
+	for _, msg := range messages {
+		if now.Sub(msg.Timestamp) < q.config.DedupWindow {
+			q.dedup.add(msg.Metadata[MetaIdempotencyKey], msg.Offset, msg.Timestamp)
+		}
+	}
+	q.logMu.Unlock()
+	for _, msg := range messages {
+		if _, probe := msg.Metadata[MetaProbe]; !probe {
+			q.totalPublished++
+			q.published[priorityLevel(msg.Metadata)].Add(1)
+		}
+		if due, ok := deliverAt(msg); ok && due.After(now) {
+			q.scheduled.add(due)
+		}
+	}
+	q.subMu.Lock()
+	for id, offset := range committed {
+		if offset <= q.base+Offset(len(messages)) {
+			q.committed[id] = offset
+		}
+	}
+	q.subMu.Unlock()
+
+	q.wal = w
+	if w.policy == FsyncInterval {
+		interval := q.config.FsyncInterval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		q.walStop = make(chan struct{})
+		q.walSyncs.Add(1)
+		go func() {
+			defer q.walSyncs.Done()
+			w.runSync(interval, q.walStop)
+		}()
+	}
+	return nil
+}
+
+// closeWAL stops the sync loop and closes the log, synced.
+func (q *InMemoryQueue) closeWAL() error {
+	if q.wal == nil {
+		return nil
+	}
+	if q.walStop != nil {
+		close(q.walStop)
+		q.walSyncs.Wait()
+	}
+	// Appends racing shutdown hold logMu while writing
+	q.logMu.Lock()
+	defer q.logMu.Unlock()
+	return q.wal.close()
 }
 
 // Publish publishes a message to the queue.
 func (q *InMemoryQueue) Publish(ctx context.Context, payload []byte) error {
+	return q.PublishWithMetadata(ctx, payload, nil)
+}
+
+// PublishWithMetadata publishes a message carrying metadata that subscriber
+// filters can evaluate without decoding the payload. A MetaPartitionKey entry
+// selects the message's partition.
+func (q *InMemoryQueue) PublishWithMetadata(ctx context.Context, payload []byte, metadata map[string]string) error {
+	_, err := q.append(ctx, payload, metadata)
+	return err
+}
+
+// append adds a message to the log and returns the offset it was assigned.
+func (q *InMemoryQueue) append(ctx context.Context, payload []byte, metadata map[string]string) (Offset, error) {
 	if !q.running.Load() {
-		return ErrQueueShutdown
+		return 0, ErrQueueShutdown
+	}
+	if q.standby.Load() {
+		return 0, ErrStandby
 	}
 
 	msg := NewMessage(payload)
+	msg.Timestamp = q.clock.Now()
+	for k, v := range metadata {
+		msg.Metadata[k] = v
+	}
+	msg.Partition = q.partitionFor(metadata)
+	key := metadata[MetaIdempotencyKey]
+	if err := schedule(msg.Metadata, msg.Timestamp); err != nil {
+		return 0, err
+	}
 
 	q.logMu.Lock()
-	// Offset = index in the log
-	msg.Offset = Offset(len(q.log))
+	if offset, ok := q.duplicateLocked(key, msg.Timestamp); ok {
+		q.logMu.Unlock()
+		return offset, nil
+	}
+	if err := q.reserveLocked(ctx, 1, messageSize(msg)); err != nil {
+		q.logMu.Unlock()
+		return 0, err
+	}
+	// A retry may have been published while reserving blocked
+	if offset, ok := q.duplicateLocked(key, msg.Timestamp); ok {
+		q.logMu.Unlock()
+		return offset, nil
+	}
+	// Offset = base + index in the log
+	msg.Offset = q.base + Offset(len(q.log))
+	if q.wal != nil {
+		// Written ahead, so a message is never delivered that a restart loses
+		if err := q.wal.append(msg); err != nil {
+			q.logMu.Unlock()
+			return 0, err
+		}
+	}
 	q.log = append(q.log, msg)
+	q.logBytes += messageSize(msg)
+	q.spillLocked()
+	q.indexUrgentLocked(msg)
+	q.dedup.add(key, msg.Offset, msg.Timestamp)
+	q.signalAppendedLocked()
 	q.logMu.Unlock()
 
-	atomic.AddInt64(&q.totalPublished, 1)
+	if _, probe := metadata[MetaProbe]; !probe {
+		atomic.AddInt64(&q.totalPublished, 1)
+		q.published[priorityLevel(metadata)].Add(1)
+	}
+	if due, ok := deliverAt(msg); ok && due.After(msg.Timestamp) {
+		q.scheduled.add(due)
+	}
 
 	// Notify all subscribers that new data is available
 	q.notifySubscribers()
 
-	return nil
+	return msg.Offset, nil
 }
 
-// PublishBatch publishes multiple messages to the queue.
+// PublishBatch publishes multiple messages to the queue, spread over the
+// partitions round-robin.
 func (q *InMemoryQueue) PublishBatch(ctx context.Context, payloads [][]byte) error {
 	if !q.running.Load() {
 		return ErrQueueShutdown
 	}
+	if q.standby.Load() {
+		return ErrStandby
+	}
 
-	q.logMu.Lock()
-	for _, payload := range payloads {
+	messages := make([]*Message, len(payloads))
+	var size int64
+	for i, payload := range payloads {
 		msg := NewMessage(payload)
-		msg.Offset = Offset(len(q.log))
-		q.log = append(q.log, msg)
+		msg.Timestamp = q.clock.Now()
+		msg.Partition = q.partitionFor(nil)
+		messages[i] = msg
+		size += messageSize(msg)
+	}
+
+	q.logMu.Lock()
+	// The batch fits whole or not at all
+	if err := q.reserveLocked(ctx, len(messages), size); err != nil {
+		q.logMu.Unlock()
+		return err
 	}
+	for i, msg := range messages {
+		msg.Offset = q.base + Offset(len(q.log)+i)
+	}
+	if q.wal != nil && len(messages) > 0 {
+		if err := q.wal.append(messages...); err != nil {
+			q.logMu.Unlock()
+			return err
+		}
+	}
+	q.log = append(q.log, messages...)
+	q.logBytes += size
+	q.spillLocked()
+	q.signalAppendedLocked()
 	q.logMu.Unlock()
 
 	atomic.AddInt64(&q.totalPublished, int64(len(payloads)))
+	q.published[urgentLevels].Add(int64(len(payloads)))
 	q.notifySubscribers()
 
 	return nil
@@ -261,22 +941,81 @@ func (q *InMemoryQueue) notifySubscribers() {
 // Use OffsetEarliest to start from the beginning, OffsetLatest for new messages only,
 // or a specific offset to resume from a saved position.
 func (q *InMemoryQueue) Subscribe(ctx context.Context, subscriberID string, startOffset Offset, handler MessageHandler) error {
+	return q.SubscribeWithOptions(ctx, subscriberID, startOffset, SubscribeOptions{}, handler)
+}
+
+// SubscribeWithOptions is like Subscribe but applies the given subscriber options.
+func (q *InMemoryQueue) SubscribeWithOptions(ctx context.Context, subscriberID string, startOffset Offset, opts SubscribeOptions, handler MessageHandler) error {
+	// A standby's probes measure how long replicated probes take to arrive
+	if q.standby.Load() && !opts.Probes {
+		return ErrStandby
+	}
+
 	q.subMu.Lock()
 	defer q.subMu.Unlock()
 
 	if _, exists := q.subscribers[subscriberID]; exists {
 		return ErrSubscriberExists
 	}
+	if _, exists := q.members[subscriberID]; exists {
+		return ErrSubscriberExists
+	}
+	if err := q.checkPartitions(opts.Partitions); err != nil {
+		return err
+	}
+	if opts.Group != "" {
+		return q.joinGroup(subscriberID, startOffset, opts, handler)
+	}
+	_, err := q.addSubscriber(subscriberID, startOffset, opts, handler)
+	return err
+}
+
+// addSubscriber registers a subscriber and starts delivering to it. The
+// caller holds subMu.
+func (q *InMemoryQueue) addSubscriber(subscriberID string, startOffset Offset, opts SubscribeOptions, handler MessageHandler) (*subscriber, error) {
 
 	// Resolve special offsets
+	resumed := false
+	if committed, ok := q.committed[subscriberID]; ok && (opts.Resume || startOffset == OffsetCommitted) {
+		startOffset, resumed = committed, true
+	} else if startOffset == OffsetCommitted {
+		startOffset = OffsetLatest
+	}
 	actualOffset := q.resolveOffset(startOffset)
+	var trimmed int64
+	if oldest := q.GetOldestOffset(); actualOffset < oldest {
+		if !resumed {
+			return nil, fmt.Errorf("%w: offset %d is older than the oldest retained, %d", ErrOffsetOutOfRange, actualOffset, oldest)
+		}
+		// The committed position was trimmed while the subscriber was away
+		trimmed = int64(oldest - actualOffset)
+		actualOffset = oldest
+	}
 
 	sub := &subscriber{
 		id:      subscriberID,
 		offset:  actualOffset,
+		trimmed: trimmed,
 		handler: handler,
 		notify:  make(chan struct{}, 1),
+		filter:  opts.Filter,
+		probes:  opts.Probes,
+
+		partitions:   opts.Partitions,
+		manualCommit: opts.ManualCommit,
+		onOverflow:   opts.OnOverflow,
+		onAbandon:    opts.OnAbandon,
+		retryInPlace: opts.RetryInPlace,
+		batch:        opts.batch,
+		maxUnacked:   opts.MaxUnacked,
+	}
+	if opts.Acknowledge && !opts.ManualCommit && q.acknowledges() {
+		sub.acks = newAckState()
 	}
+	if opts.Workers > 1 {
+		sub.workers = newWorkerPool(opts.Workers)
+	}
+	sub.suspended.Store(opts.Paused)
 
 	q.subscribers[subscriberID] = sub
 
@@ -288,26 +1027,28 @@ func (q *InMemoryQueue) Subscribe(ctx context.Context, subscriberID string, star
 	}
 	go q.consumeLoop(sub)
 
-	return nil
+	return sub, nil
 }
 
-// resolveOffset converts special offsets to actual values.
+// resolveOffset converts special offsets to actual values. Offsets past
+// the end of the log are clamped to it; trimmed ones are returned as is.
 func (q *InMemoryQueue) resolveOffset(offset Offset) Offset {
 	q.logMu.RLock()
 	defer q.logMu.RUnlock()
 
+	next := q.base + Offset(len(q.log))
 	switch offset {
 	case OffsetEarliest:
-		return 0 // Start from the beginning
+		return q.base // Start from the oldest retained message
 	case OffsetLatest:
-		return Offset(len(q.log)) // Start from next new message
+		return next // Start from next new message
 	default:
 		// Clamp to valid range
 		if offset < 0 {
-			return 0
+			return q.base
 		}
-		if offset > Offset(len(q.log)) {
-			return Offset(len(q.log))
+		if offset > next {
+			return next
 		}
 		return offset
 	}
@@ -333,45 +1074,154 @@ func (q *InMemoryQueue) consumeLoop(sub *subscriber) {
 // processMessages delivers available messages to a subscriber.
 func (q *InMemoryQueue) processMessages(sub *subscriber) {
 	for {
-		msg := q.getMessageAtOffset(sub.offset)
+		if sub.suspended.Load() {
+			return // Woken by ResumeSubscriber
+		}
+
+		// Scheduled messages that fell due, then urgent messages, go ahead
+		// of a backlog
+		if found, stop := q.deliverDue(sub); stop {
+			return // Resumed by acks, or moved or removed by the policy
+		} else if found {
+			continue
+		}
+		if found, stop := q.expedite(sub); stop {
+			return // Resumed by acks, or moved or removed by the policy
+		} else if found {
+			continue
+		}
+
+		q.subMu.RLock()
+		offset, ahead := sub.offset, sub.ahead
+		q.subMu.RUnlock()
+
+		msg, oldest, err := q.getMessageAtOffset(offset)
+		if err != nil {
+			q.retryPageIn(sub)
+			return // Left at the message until it can be read back
+		}
+		if msg == nil && offset < oldest {
+			// Trimmed before it was delivered; resume at the oldest retained
+			q.subMu.Lock()
+			if sub.offset == offset {
+				sub.offset = oldest
+				sub.trimmed += int64(oldest - offset)
+			}
+			q.subMu.Unlock()
+			continue
+		}
 		if msg == nil {
 			return // No more messages available
 		}
+		if sub.batch != nil {
+			if !q.offerBatch(sub, offset, ahead) {
+				return // Resumed by acks, or moved or removed by the policy
+			}
+			continue
+		}
 
-		// Deliver message to handler
-		err := sub.handler(q.ctx, msg)
-		if err != nil {
-			// Handler failed - could implement retry logic here
-			// For now, we'll skip and continue to allow progress
+		switch {
+		case sub.deliveredAhead(msg, ahead):
+		case q.deferLater(sub, msg):
+		default:
+			if _, ok := q.offer(sub, msg); !ok {
+				return // Resumed by acks, or moved or removed by the policy
+			}
 		}
 
-		// Advance offset
+		// Advance offset unless a concurrent seek already moved it
 		q.subMu.Lock()
-		sub.offset++
+		if sub.offset == offset {
+			sub.offset++
+		}
 		q.subMu.Unlock()
 	}
 }
 
-// getMessageAtOffset returns the message at the given offset, or nil if not available.
-func (q *InMemoryQueue) getMessageAtOffset(offset Offset) *Message {
+// offer delivers msg to sub unless sub does not receive it, and reports
+// whether it did. ok is false when the budget held msg back.
+func (q *InMemoryQueue) offer(sub *subscriber, msg *Message) (delivered, ok bool) {
+	switch {
+	case !sub.receives(msg):
+	case q.corrupted(sub, msg):
+	case sub.filter.Match(msg.Metadata):
+		if !q.admit(sub, msg) {
+			return false, false
+		}
+		q.deliver(sub, msg)
+		return true, true
+	default:
+		atomic.AddInt64(&sub.filtered, 1)
+	}
+	return false, true
+}
+
+// receives reports whether msg is for sub, filter aside. Probes reach only
+// the probe subscriber and are not counted as filtered.
+func (s *subscriber) receives(msg *Message) bool {
+	_, probe := msg.Metadata[MetaProbe]
+	return probe == s.probes && s.consumes(msg.Partition)
+}
+
+// getMessageAtOffset returns the message at the given offset, or nil if not
+// available, along with the offset of the oldest retained message. A
+// spilled message is paged back in once logMu is released; err is why it
+// could not be.
+func (q *InMemoryQueue) getMessageAtOffset(offset Offset) (*Message, Offset, error) {
 	q.logMu.RLock()
-	defer q.logMu.RUnlock()
+	idx := int(offset - q.base)
+	if offset < 0 || idx < 0 || idx >= len(q.log) {
+		base := q.base
+		q.logMu.RUnlock()
+		return nil, base, nil
+	}
+	msg, oldest := q.log[idx], q.base
+	q.logMu.RUnlock()
 
-	idx := int(offset)
-	if idx < 0 || idx >= len(q.log) {
-		return nil
+	msg, err := q.pageIn(msg)
+	if errors.Is(err, ErrOffsetOutOfRange) {
+		return nil, q.GetOldestOffset(), nil // Trimmed while it was read back
+	}
+	if err != nil {
+		return nil, oldest, err
 	}
+	return msg.Clone(), oldest, nil
+}
 
-	return q.log[idx].Clone()
+// FetchMessage returns a copy of the message at offset, for re-reading a
+// specific message without subscribing. Trimmed offsets return
+// ErrOffsetOutOfRange, a message failing its checksum a
+// *CorruptMessageError, and a spilled one that cannot be read back a
+// transient error.
+func (q *InMemoryQueue) FetchMessage(offset Offset) (*Message, error) {
+	msg, oldest, err := q.getMessageAtOffset(offset)
+	if err != nil {
+		return nil, perrors.Transient(fmt.Errorf("failed to read offset %d back from disk: %w", offset, err))
+	}
+	if msg == nil && offset >= 0 && offset < oldest {
+		return nil, fmt.Errorf("%w: offset %d is older than the oldest retained, %d", ErrOffsetOutOfRange, offset, oldest)
+	}
+	if msg == nil {
+		return nil, perrors.NotFound(fmt.Errorf("offset %d is not in the log", offset))
+	}
+	if err := msg.Verify(); err != nil {
+		return nil, err
+	}
+	return msg, nil
 }
 
-// Unsubscribe removes a subscriber.
+// Unsubscribe removes a subscriber. A group member leaves its group, whose
+// partitions are reassigned to the remaining members.
 func (q *InMemoryQueue) Unsubscribe(subscriberID string) error {
 	q.subMu.Lock()
 	defer q.subMu.Unlock()
 
+	if g, ok := q.members[subscriberID]; ok {
+		q.leaveGroup(subscriberID, g)
+		return nil
+	}
 	sub, exists := q.subscribers[subscriberID]
-	if !exists {
+	if !exists || sub.group != nil {
 		return ErrSubscriberNotFound
 	}
 
@@ -380,12 +1230,13 @@ func (q *InMemoryQueue) Unsubscribe(subscriberID string) error {
 	return nil
 }
 
-// GetSubscriberOffset returns the current offset for a subscriber.
+// GetSubscriberOffset returns the current offset for a subscriber, which a
+// group member shares with its group.
 func (q *InMemoryQueue) GetSubscriberOffset(subscriberID string) (Offset, error) {
 	q.subMu.RLock()
 	defer q.subMu.RUnlock()
 
-	sub, exists := q.subscribers[subscriberID]
+	sub, exists := q.subscribers[q.positionID(subscriberID)]
 	if !exists {
 		return 0, ErrSubscriberNotFound
 	}
@@ -393,28 +1244,35 @@ func (q *InMemoryQueue) GetSubscriberOffset(subscriberID string) (Offset, error)
 }
 
 // SetSubscriberOffset manually sets a subscriber's offset (for seeking).
+// Seeking to a trimmed offset returns ErrOffsetOutOfRange. Seeking a group
+// member moves its whole group.
 func (q *InMemoryQueue) SetSubscriberOffset(subscriberID string, offset Offset) error {
 	q.subMu.Lock()
 	defer q.subMu.Unlock()
 
-	sub, exists := q.subscribers[subscriberID]
+	sub, exists := q.subscribers[q.positionID(subscriberID)]
 	if !exists {
 		return ErrSubscriberNotFound
 	}
 
 	// Clamp to valid range
 	q.logMu.RLock()
-	maxOffset := Offset(len(q.log))
+	minOffset, maxOffset := q.base, q.base+Offset(len(q.log))
 	q.logMu.RUnlock()
 
 	if offset < 0 {
-		offset = 0
+		offset = minOffset
+	}
+	if offset < minOffset {
+		return fmt.Errorf("%w: offset %d is older than the oldest retained, %d", ErrOffsetOutOfRange, offset, minOffset)
 	}
 	if offset > maxOffset {
 		offset = maxOffset
 	}
 
 	sub.offset = offset
+	sub.ahead = [urgentLevels]Offset{}
+	sub.deferred = nil
 
 	// Notify to process from new position
 	select {
@@ -425,58 +1283,262 @@ func (q *InMemoryQueue) SetSubscriberOffset(subscriberID string, offset Offset)
 	return nil
 }
 
+// OffsetForTimestamp returns the offset of the oldest retained message
+// published at or after ts, or the next offset to be assigned if none was.
+// Messages are stamped as they are appended, so timestamps rise with
+// offsets. When ts is before the oldest retained message and older ones were
+// trimmed, messages published after ts may be gone, and ErrOffsetOutOfRange
+// is returned.
+func (q *InMemoryQueue) OffsetForTimestamp(ts time.Time) (Offset, error) {
+	q.logMu.RLock()
+	defer q.logMu.RUnlock()
+
+	i := sort.Search(len(q.log), func(i int) bool { return !q.log[i].Timestamp.Before(ts) })
+	if i == 0 && q.base > 0 && len(q.log) > 0 && q.log[0].Timestamp.After(ts) {
+		return 0, fmt.Errorf("%w: %s is before the oldest retained message, published at %s",
+			ErrOffsetOutOfRange, ts.Format(time.RFC3339), q.log[0].Timestamp.Format(time.RFC3339))
+	}
+	return q.base + Offset(i), nil
+}
+
+// SeekToTimestamp moves a subscriber to the first message published at or
+// after ts, so it replays everything from then on, and returns the offset
+// it moved to. A time in the future moves it to the end of the log. Seeking
+// a group member moves its whole group.
+func (q *InMemoryQueue) SeekToTimestamp(subscriberID string, ts time.Time) (Offset, error) {
+	offset, err := q.OffsetForTimestamp(ts)
+	if err != nil {
+		return 0, err
+	}
+	if err := q.SetSubscriberOffset(subscriberID, offset); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// Rewind moves a manually committing subscriber back to offset when it has
+// read past it, so the message there and those after it are delivered
+// again. Other subscribers are left where they are. Group members rewind
+// their group.
+func (q *InMemoryQueue) Rewind(subscriberID string, offset Offset) error {
+	q.subMu.Lock()
+	defer q.subMu.Unlock()
+
+	sub, exists := q.subscribers[q.positionID(subscriberID)]
+	if !exists {
+		return ErrSubscriberNotFound
+	}
+	if !sub.manualCommit || offset < 0 || offset >= sub.offset {
+		return nil
+	}
+	if oldest := q.GetOldestOffset(); offset < oldest {
+		return fmt.Errorf("%w: offset %d is older than the oldest retained, %d", ErrOffsetOutOfRange, offset, oldest)
+	}
+
+	sub.offset = offset
+	sub.ahead = [urgentLevels]Offset{}
+	sub.deferred = nil
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// CommitOffset records the offset a subscriber has durably processed up to
+// (the next offset it needs). The committed offset survives Unsubscribe so a
+// consumer can resume with OffsetCommitted. Group members commit for their
+// group.
+func (q *InMemoryQueue) CommitOffset(subscriberID string, offset Offset) error {
+	if q.standby.Load() {
+		return ErrStandby
+	}
+
+	q.logMu.RLock()
+	maxOffset := q.base + Offset(len(q.log))
+	q.logMu.RUnlock()
+
+	if offset < 0 || offset > maxOffset {
+		return ErrInvalidOffset
+	}
+
+	q.subMu.Lock()
+	defer q.subMu.Unlock()
+	q.committed[q.positionID(subscriberID)] = offset
+	if q.wal != nil {
+		if err := q.wal.saveOffsets(q.committed); err != nil {
+			return fmt.Errorf("failed to persist committed offset: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetCommittedOffset returns the last committed offset for a subscriber.
+func (q *InMemoryQueue) GetCommittedOffset(subscriberID string) (Offset, bool) {
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+	offset, ok := q.committed[q.positionID(subscriberID)]
+	return offset, ok
+}
+
+// GetOffsetInfo returns the current and committed position of a subscriber.
+// Inactive subscribers are reported only if they have a committed offset.
+func (q *InMemoryQueue) GetOffsetInfo(subscriberID string) (OffsetInfo, error) {
+	latest := q.GetLatestOffset()
+
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+
+	info := OffsetInfo{
+		SubscriberID: subscriberID,
+		Committed:    -1,
+		Latest:       latest,
+		Oldest:       q.GetOldestOffset(),
+	}
+	id := q.positionID(subscriberID)
+	committed, hasCommit := q.committed[id]
+	if hasCommit {
+		info.Committed = committed
+	}
+
+	sub, active := q.subscribers[id]
+	if !active && !hasCommit {
+		return OffsetInfo{}, ErrSubscriberNotFound
+	}
+	if active {
+		info.Active = true
+		info.Current = sub.offset
+		info.Filter = sub.filter.String()
+		if lag := int64(latest) - int64(sub.offset); lag > 0 {
+			info.Lag = lag
+		}
+	}
+	return info, nil
+}
+
 // GetStats returns queue statistics.
 func (q *InMemoryQueue) GetStats() QueueStats {
 	q.logMu.RLock()
-	logLen := len(q.log)
-	var oldest, latest Offset
-	if logLen > 0 {
-		oldest = 0
-		latest = Offset(logLen - 1)
-	}
+	oldest, latest := q.base, q.latestLocked()
+	retained, trimmed := q.logBytes, q.trimmed
+	rejected, dropped, duplicates := q.rejected, q.dropped, q.duplicates
+	spilled := q.spillStatsLocked()
 	q.logMu.RUnlock()
 
 	q.subMu.RLock()
 	subs := make([]SubscriberInfo, 0, len(q.subscribers))
 	for _, sub := range q.subscribers {
+		if sub.id == probeSubscriber {
+			continue
+		}
 		lag := int64(latest) - int64(sub.offset)
 		if lag < 0 {
 			lag = 0
 		}
-		subs = append(subs, SubscriberInfo{
+		info := SubscriberInfo{
 			ID:            sub.id,
 			CurrentOffset: sub.offset,
 			Lag:           lag,
-		})
+			Filter:        sub.filter.String(),
+			Filtered:      atomic.LoadInt64(&sub.filtered),
+			Trimmed:       sub.trimmed,
+			Corrupt:       atomic.LoadInt64(&sub.corrupt),
+			Partitions:    sub.partitions,
+
+			PendingBytes: sub.pending.Load(),
+			Paused:       sub.paused.Load(),
+			Overflows:    sub.overflows.Load(),
+			Skipped:      sub.skipped,
+			Deferred:     len(sub.deferred),
+			Suspended:    sub.suspended.Load(),
+
+			Redelivered:   sub.redelivered.Load(),
+			Abandoned:     sub.abandoned.Load(),
+			Delivered:     sub.delivered.Load(),
+			HandlerErrors: sub.handlerErrors.Load(),
+			LagHistory:    slices.Clone(sub.lagHistory),
+		}
+		if sub.batch != nil {
+			info.Batches = sub.batch.calls.Load()
+		}
+		if sub.workers != nil {
+			info.InFlight = len(sub.workers.slots)
+		}
+		if sub.acks != nil {
+			sub.acks.mu.Lock()
+			info.Unacked = len(sub.acks.pending)
+			sub.acks.mu.Unlock()
+		}
+		if sub.group != nil {
+			info.Members = sub.group.assignments()
+			info.Rebalances = sub.group.rebalances.Load()
+		}
+		subs = append(subs, info)
 	}
-	subCount := len(q.subscribers)
 	q.subMu.RUnlock()
 
-	return QueueStats{
+	stats := QueueStats{
 		TotalMessages:   atomic.LoadInt64(&q.totalPublished),
 		OldestOffset:    oldest,
 		LatestOffset:    latest,
-		SubscriberCount: subCount,
+		SubscriberCount: len(subs),
 		Subscribers:     subs,
+		Partitions:      q.config.Partitions,
+		RetainedBytes:   retained,
+		TrimmedMessages: trimmed,
+
+		RejectedMessages:   rejected,
+		DroppedMessages:    dropped,
+		ThrottledPublishes: q.throttled.Load(),
+		DuplicateMessages:  duplicates,
+		EvictedSubscribers: q.evicted.Load(),
+		Priorities:         q.priorityStats(),
+		ScheduledMessages:  q.scheduled.len(),
+		DeliveredMessages:  q.delivered.Load(),
+	}
+	stats.PublishRate, stats.DeliverRate = q.rates()
+	if q.probes != nil {
+		stats.Probes = q.probes.stats()
+	}
+	if q.wal != nil {
+		stats.WAL = q.wal.stats()
+	}
+	stats.Spill = spilled
+	stats.CommitError, _ = q.commitErr.Load().(string)
+	stats.DeadLetters, stats.DeadLettersKept, stats.DeadLetterError = q.deadLetterStats()
+	if r := q.replication.Load(); r != nil {
+		replication := *r
+		stats.Replication = &replication
 	}
+	stats.Epoch = q.Epoch()
+	return stats
 }
 
 // GetLatestOffset returns the offset of the most recent message.
 func (q *InMemoryQueue) GetLatestOffset() Offset {
 	q.logMu.RLock()
 	defer q.logMu.RUnlock()
-	if len(q.log) == 0 {
-		return 0
+	return q.latestLocked()
+}
+
+// latestLocked returns the offset of the most recent message, which may
+// have been trimmed; 0 when nothing was ever published.
+func (q *InMemoryQueue) latestLocked() Offset {
+	if next := q.base + Offset(len(q.log)); next > 0 {
+		return next - 1
 	}
-	return Offset(len(q.log) - 1)
+	return 0
 }
 
-// GetOldestOffset returns the offset of the oldest message (always 0).
+// GetOldestOffset returns the offset of the oldest retained message, which
+// is 0 until retention trims the log.
 func (q *InMemoryQueue) GetOldestOffset() Offset {
-	return 0
+	q.logMu.RLock()
+	defer q.logMu.RUnlock()
+	return q.base
 }
 
-// Len returns the number of messages in the log.
+// Len returns the number of messages retained in the log.
 func (q *InMemoryQueue) Len() int {
 	q.logMu.RLock()
 	defer q.logMu.RUnlock()
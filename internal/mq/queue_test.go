@@ -2,9 +2,12 @@ package mq
 
 import (
 	"context"
+	"errors"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
 )
 
 func TestNewInMemoryQueue(t *testing.T) {
@@ -73,6 +76,33 @@ func TestPublishBatch(t *testing.T) {
 	}
 }
 
+func TestQueueTimestampsFromClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sim := clock.NewSimulated(start)
+	q := NewInMemoryQueue(DefaultQueueConfig())
+	q.SetClock(sim)
+	ctx := context.Background()
+
+	if err := q.Start(ctx); err != nil {
+		t.Fatalf("failed to start queue: %v", err)
+	}
+	defer q.Shutdown(ctx)
+
+	q.Publish(ctx, []byte("first"))
+	sim.Advance(time.Minute)
+	q.PublishBatch(ctx, [][]byte{[]byte("second")})
+
+	for offset, want := range []time.Time{start, start.Add(time.Minute)} {
+		msg, err := q.FetchMessage(Offset(offset))
+		if err != nil {
+			t.Fatalf("FetchMessage(%d): %v", offset, err)
+		}
+		if !msg.Timestamp.Equal(want) {
+			t.Errorf("message %d stamped %v, want %v", offset, msg.Timestamp, want)
+		}
+	}
+}
+
 func TestQueueStats(t *testing.T) {
 	q := NewInMemoryQueue(DefaultQueueConfig())
 	ctx := context.Background()
@@ -306,6 +336,60 @@ func TestSetSubscriberOffset(t *testing.T) {
 	}
 }
 
+func TestSeekToTimestamp(t *testing.T) {
+	start := time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC)
+	sim := clock.NewSimulated(start)
+	cfg := DefaultQueueConfig()
+	cfg.RetentionMessages = 3
+	q := startRetaining(t, cfg)
+	q.SetClock(sim)
+
+	// Offsets 0-2 at 14:00, 3-4 at 14:10, 5 at 14:20
+	publishN(t, q, 3)
+	sim.Advance(10 * time.Minute)
+	publishN(t, q, 2)
+	sim.Advance(10 * time.Minute)
+	publishN(t, q, 1)
+
+	var delivered atomic.Int64
+	if err := q.Subscribe(context.Background(), "replayer", OffsetLatest, func(context.Context, *Message) error {
+		delivered.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	for _, tc := range []struct {
+		at   time.Time
+		want Offset
+	}{
+		{start.Add(5 * time.Minute), 3},
+		{start.Add(10 * time.Minute), 3},
+		{start.Add(20 * time.Minute), 5},
+		{start.Add(time.Hour), 6},
+		{start.Add(-time.Hour), 0},
+	} {
+		offset, err := q.SeekToTimestamp("replayer", tc.at)
+		if err != nil || offset != tc.want {
+			t.Errorf("seek to %s: expected offset %d, got %d (%v)", tc.at.Format(time.Kitchen), tc.want, offset, err)
+		}
+	}
+	waitFor(t, func() bool { return delivered.Load() >= 6 })
+
+	if _, err := q.SeekToTimestamp("nobody", start); err != ErrSubscriberNotFound {
+		t.Errorf("expected ErrSubscriberNotFound, got %v", err)
+	}
+
+	// Once 14:00's messages are trimmed, seeking to 14:00 would skip them
+	q.trim(sim.Now())
+	if _, err := q.SeekToTimestamp("replayer", start); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("expected ErrOffsetOutOfRange seeking before the trimmed messages, got %v", err)
+	}
+	if offset, err := q.SeekToTimestamp("replayer", start.Add(10*time.Minute)); err != nil || offset != 3 {
+		t.Errorf("expected offset 3 for the oldest retained time, got %d (%v)", offset, err)
+	}
+}
+
 func TestGetLatestOffset(t *testing.T) {
 	q := NewInMemoryQueue(DefaultQueueConfig())
 	ctx := context.Background()
@@ -0,0 +1,133 @@
+package mq
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// ErrQuotaExceeded is returned for a request refused over a client quota.
+var ErrQuotaExceeded = perrors.New(perrors.KindPermanent, "client quota exceeded")
+
+// ClientQuotas bound what one client connection may hold on the server, so
+// a faulty client cannot monopolize it. Zero fields are unlimited.
+type ClientQuotas struct {
+	// MaxInFlight bounds the messages delivered to each of the client's
+	// subscriptions and not yet acked; delivery waits for acks past it.
+	// Only acknowledging subscriptions are bounded: it needs the queue's
+	// AckTimeout set, and does not hold back ManualCommit subscriptions,
+	// whose messages are committed rather than acked. A consumer group
+	// takes the bound of the member that created it.
+	MaxInFlight int `json:"max_in_flight"`
+
+	// MaxPublishBytes refuses publishes with a larger payload
+	MaxPublishBytes int `json:"max_publish_bytes"`
+
+	// MaxSubscriptions refuses subscribes and stats watches past this many
+	// held at once
+	MaxSubscriptions int `json:"max_subscriptions"`
+}
+
+// ClientInfo reports a connected client's traffic.
+type ClientInfo struct {
+	Addr          string   `json:"addr"`
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	Watches       int      `json:"watches,omitempty"`
+
+	PublishedMessages int64 `json:"published_messages"`
+	PublishedBytes    int64 `json:"published_bytes"`
+	DeliveredMessages int64 `json:"delivered_messages"`
+	DeliveredBytes    int64 `json:"delivered_bytes"`
+
+	// Refused counts requests refused over the client's quotas
+	Refused int64 `json:"refused,omitempty"`
+}
+
+// clientUsage counts a connection's traffic for ClientInfo.
+type clientUsage struct {
+	publishedMessages atomic.Int64
+	publishedBytes    atomic.Int64
+	deliveredMessages atomic.Int64
+	deliveredBytes    atomic.Int64
+	refused           atomic.Int64
+}
+
+// published counts a publish of size payload bytes by the client.
+func (u *clientUsage) published(size int) {
+	u.publishedMessages.Add(1)
+	u.publishedBytes.Add(int64(size))
+}
+
+// delivered counts msgs pushed to the client.
+func (u *clientUsage) delivered(msgs ...*Message) {
+	u.deliveredMessages.Add(int64(len(msgs)))
+	for _, msg := range msgs {
+		u.deliveredBytes.Add(int64(len(msg.Payload)))
+	}
+}
+
+// checkPublish refuses a publish of size payload bytes over the client's
+// MaxPublishBytes.
+func (s *Server) checkPublish(client *clientState, size int) error {
+	limit := s.quotas.MaxPublishBytes
+	if limit <= 0 || size <= limit {
+		return nil
+	}
+	return s.refuse(client, fmt.Errorf("%w: %d byte publish is over the %d byte limit", ErrQuotaExceeded, size, limit))
+}
+
+// checkSubscriptionsLocked refuses a new subscription or stats watch once
+// the client holds MaxSubscriptions. The caller holds client.mu.
+func (s *Server) checkSubscriptionsLocked(client *clientState) error {
+	limit := s.quotas.MaxSubscriptions
+	if limit <= 0 || len(client.subscriptions)+len(client.watches) < limit {
+		return nil
+	}
+	return s.refuse(client, fmt.Errorf("%w: already holding %d subscriptions", ErrQuotaExceeded, limit))
+}
+
+// refuse counts and logs a request refused with err.
+func (s *Server) refuse(client *clientState, err error) error {
+	client.usage.refused.Add(1)
+	s.logger.Printf("Refused request from %s: %v", client.conn.RemoteAddr(), err)
+	return err
+}
+
+// Clients reports the connected clients' traffic, by address.
+func (s *Server) Clients() []ClientInfo {
+	s.clientsMu.RLock()
+	clients := make([]*clientState, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, client)
+	}
+	s.clientsMu.RUnlock()
+
+	infos := make([]ClientInfo, 0, len(clients))
+	for _, client := range clients {
+		infos = append(infos, client.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Addr < infos[j].Addr })
+	return infos
+}
+
+// info reports the client's traffic.
+func (c *clientState) info() ClientInfo {
+	info := ClientInfo{
+		Addr:              c.conn.RemoteAddr().String(),
+		PublishedMessages: c.usage.publishedMessages.Load(),
+		PublishedBytes:    c.usage.publishedBytes.Load(),
+		DeliveredMessages: c.usage.deliveredMessages.Load(),
+		DeliveredBytes:    c.usage.deliveredBytes.Load(),
+		Refused:           c.usage.refused.Load(),
+	}
+	c.mu.Lock()
+	for id := range c.subscriptions {
+		info.Subscriptions = append(info.Subscriptions, id)
+	}
+	info.Watches = len(c.watches)
+	c.mu.Unlock()
+	sort.Strings(info.Subscriptions)
+	return info
+}
@@ -0,0 +1,174 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+func TestMaxUnackedPausesDelivery(t *testing.T) {
+	q, _ := startAcking(t)
+	ctx := context.Background()
+
+	ids := make(chan string, 10)
+	q.SubscribeWithOptions(ctx, "consumer", OffsetEarliest, SubscribeOptions{Acknowledge: true, MaxUnacked: 2}, func(_ context.Context, msg *Message) error {
+		ids <- msg.ID
+		return nil
+	})
+	publishN(t, q, 5)
+	waitFor(t, func() bool { return len(ids) == 2 && subscriberInfo(q, "consumer").Paused })
+
+	// Paused, the subscriber delivers nothing more until an ack makes room
+	if info := subscriberInfo(q, "consumer"); info.Delivered != 2 || info.Unacked != 2 {
+		t.Fatalf("expected delivery held at 2 unacked messages, got %+v", info)
+	}
+	if err := q.Ack("consumer", <-ids); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(ids) == 2 && subscriberInfo(q, "consumer").Paused })
+	if info := subscriberInfo(q, "consumer"); info.Delivered != 3 || info.Unacked != 2 {
+		t.Errorf("expected one more message delivered and 2 unacked again, got %+v", info)
+	}
+}
+
+func TestMaxUnackedBoundsGroups(t *testing.T) {
+	q, _ := startAcking(t)
+	ctx := context.Background()
+
+	handled := make(chan string, 10)
+	for _, member := range []string{"a", "b"} {
+		// The group takes the bound of the member that creates it
+		opts := SubscribeOptions{Group: "workers", Acknowledge: true, MaxUnacked: 2}
+		if member == "b" {
+			opts.MaxUnacked = 0
+		}
+		if err := q.SubscribeWithOptions(ctx, member, OffsetEarliest, opts, func(_ context.Context, msg *Message) error {
+			handled <- msg.ID
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	publishN(t, q, 5)
+	waitFor(t, func() bool { return len(handled) == 2 && subscriberInfo(q, "workers").Paused })
+	if info := subscriberInfo(q, "workers"); info.Delivered != 2 || info.Unacked != 2 {
+		t.Errorf("expected the group held at 2 unacked messages, got %+v", info)
+	}
+}
+
+func TestMaxInFlightOnlyBoundsAckingSubscriptions(t *testing.T) {
+	cfg := ServerConfig{
+		TCPHost:  "127.0.0.1",
+		TCPPort:  freePort(t),
+		HTTPHost: "127.0.0.1",
+		HTTPPort: freePort(t),
+		Queue:    DefaultQueueConfig(),
+		Quotas:   ClientQuotas{MaxInFlight: 2},
+	}
+	server := NewServer(cfg, log.New(io.Discard, "", 0))
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	release := make(chan struct{})
+	defer close(release)
+	subscribe := func(id string, manualCommit bool) *atomic.Int64 {
+		client := NewClient(ClientConfig{Host: "127.0.0.1", Port: cfg.TCPPort, Timeout: 2 * time.Second, ManualCommit: manualCommit})
+		if err := client.Connect(); err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		var received atomic.Int64
+		// Handlers hold on to their messages, so nothing is acked
+		if err := client.Subscribe(context.Background(), id, OffsetEarliest, func(context.Context, *Message) error {
+			received.Add(1)
+			<-release
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return &received
+	}
+	acking := subscribe("acking", false)
+	committing := subscribe("committing", true)
+	for i := 0; i < 5; i++ {
+		if err := server.queue.Publish(context.Background(), []byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, func() bool { return acking.Load() == 2 && subscriberInfo(server.queue, "acking").Paused })
+	if info := subscriberInfo(server.queue, "acking"); info.Delivered != 2 || info.Unacked != 2 {
+		t.Errorf("expected the acking subscription held at 2 in flight, got %+v", info)
+	}
+	// The manual-commit subscription does not ack, so the quota never holds it back
+	waitFor(t, func() bool { return committing.Load() == 5 })
+	if info := subscriberInfo(server.queue, "committing"); info.Paused || info.Unacked != 0 {
+		t.Errorf("expected the manual-commit subscription not held back, got %+v", info)
+	}
+}
+
+func TestServerEnforcesClientQuotas(t *testing.T) {
+	cfg := ServerConfig{
+		TCPHost:  "127.0.0.1",
+		TCPPort:  freePort(t),
+		HTTPHost: "127.0.0.1",
+		HTTPPort: freePort(t),
+		Queue:    DefaultQueueConfig(),
+		Quotas:   ClientQuotas{MaxPublishBytes: 16, MaxSubscriptions: 1},
+	}
+	server := NewServer(cfg, log.New(io.Discard, "", 0))
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+	client := NewClient(ClientConfig{Host: "127.0.0.1", Port: cfg.TCPPort, Timeout: 2 * time.Second})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	if err := client.Publish(ctx, []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("small publish failed: %v", err)
+	}
+	err := client.Publish(ctx, []byte(`{"data":"0123456789abcdef"}`))
+	if err == nil || perrors.KindOf(err) != perrors.KindPermanent || !strings.Contains(err.Error(), "client quota exceeded") {
+		t.Fatalf("expected a permanent quota error for the large publish, got %v", err)
+	}
+
+	var delivered atomic.Int64
+	if err := client.Subscribe(ctx, "consumer", OffsetEarliest, func(context.Context, *Message) error {
+		delivered.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("first subscription failed: %v", err)
+	}
+	if _, err := client.WatchStats(ctx, time.Second); err == nil || !strings.Contains(err.Error(), "client quota exceeded") {
+		t.Fatalf("expected the stats watch refused over the subscription quota, got %v", err)
+	}
+	waitFor(t, func() bool { return delivered.Load() == 1 })
+
+	var info ClientInfo
+	waitFor(t, func() bool {
+		stats, err := client.GetStats(ctx)
+		if err != nil || len(stats.Clients) != 1 {
+			return false
+		}
+		info = stats.Clients[0]
+		return info.DeliveredMessages == 1
+	})
+	if info.PublishedMessages != 1 || info.PublishedBytes != 7 || info.DeliveredBytes != 7 || info.Refused != 2 ||
+		!slices.Equal(info.Subscriptions, []string{"consumer"}) {
+		t.Errorf("unexpected client info %+v", info)
+	}
+}
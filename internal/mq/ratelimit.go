@@ -0,0 +1,138 @@
+package mq
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+// PublishLimits caps how fast clients may publish, per connection and
+// across all connections, so a misbehaving publisher cannot starve the
+// queue. Rates are per second and zero is unlimited. Each limit allows a
+// burst of one second's worth; a publish larger than that goes through
+// once the whole burst has built up.
+type PublishLimits struct {
+	ConnMessages   float64 `json:"conn_messages_per_sec"`
+	ConnBytes      float64 `json:"conn_bytes_per_sec"`
+	GlobalMessages float64 `json:"global_messages_per_sec"`
+	GlobalBytes    float64 `json:"global_bytes_per_sec"`
+}
+
+// enabled reports whether any limit is set.
+func (l PublishLimits) enabled() bool {
+	return l.ConnMessages > 0 || l.ConnBytes > 0 || l.GlobalMessages > 0 || l.GlobalBytes > 0
+}
+
+// tokenBucket refills at rate tokens per second up to one second's worth.
+// A nil bucket is unlimited.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: rate, tokens: rate, last: now}
+}
+
+// wait refills the bucket and returns how long until n tokens can be taken.
+func (b *tokenBucket) wait(n float64, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.rate, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+	need := math.Min(n, b.rate)
+	if b.tokens >= need {
+		return 0
+	}
+	return time.Duration(math.Ceil((need - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// take removes n tokens, leaving the bucket in debt for oversized takes.
+func (b *tokenBucket) take(n float64) {
+	if b != nil {
+		b.tokens -= n
+	}
+}
+
+// publishBuckets limits the messages and bytes published by one
+// connection, or by all of them.
+type publishBuckets struct {
+	messages *tokenBucket
+	bytes    *tokenBucket
+}
+
+// rateLimiter enforces PublishLimits. The global buckets are shared by
+// every connection; each connection gets its own from newConn.
+type rateLimiter struct {
+	limits PublishLimits
+	clock  clock.Clock
+
+	mu     sync.Mutex
+	global publishBuckets
+}
+
+// newRateLimiter returns a limiter for limits, or nil if none are set.
+func newRateLimiter(limits PublishLimits, clk clock.Clock) *rateLimiter {
+	if !limits.enabled() {
+		return nil
+	}
+	now := clk.Now()
+	return &rateLimiter{
+		limits: limits,
+		clock:  clk,
+		global: publishBuckets{
+			messages: newTokenBucket(limits.GlobalMessages, now),
+			bytes:    newTokenBucket(limits.GlobalBytes, now),
+		},
+	}
+}
+
+// newConn returns the buckets of a new connection.
+func (l *rateLimiter) newConn() *publishBuckets {
+	if l == nil {
+		return nil
+	}
+	now := l.clock.Now()
+	return &publishBuckets{
+		messages: newTokenBucket(l.limits.ConnMessages, now),
+		bytes:    newTokenBucket(l.limits.ConnBytes, now),
+	}
+}
+
+// allow admits a publish of size bytes on conn, returning zero, or how long
+// the publisher should wait before retrying. A throttled publish consumes
+// nothing, so retrying after the wait succeeds unless others publish first.
+func (l *rateLimiter) allow(conn *publishBuckets, size int) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	buckets := []*publishBuckets{&l.global}
+	if conn != nil {
+		buckets = append(buckets, conn)
+	}
+	var wait time.Duration
+	for _, b := range buckets {
+		wait = max(wait, b.messages.wait(1, now), b.bytes.wait(float64(size), now))
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, b := range buckets {
+		b.messages.take(1)
+		b.bytes.take(float64(size))
+	}
+	return 0
+}
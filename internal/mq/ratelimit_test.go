@@ -0,0 +1,95 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	l := newRateLimiter(PublishLimits{ConnMessages: 2, GlobalBytes: 100}, clk)
+	a, b := l.newConn(), l.newConn()
+
+	// Each connection bursts two messages a second
+	for i := 0; i < 2; i++ {
+		if wait := l.allow(a, 10); wait != 0 {
+			t.Fatalf("publish %d throttled for %v", i, wait)
+		}
+	}
+	if wait := l.allow(a, 10); wait != 500*time.Millisecond {
+		t.Errorf("expected a 500ms wait for the third message, got %v", wait)
+	}
+	if wait := l.allow(b, 10); wait != 0 {
+		t.Errorf("expected another connection not to be throttled, got %v", wait)
+	}
+
+	// 30 of the 100 global bytes are spent, so 80 more must wait for 10
+	if wait := l.allow(b, 80); wait != 100*time.Millisecond {
+		t.Errorf("expected a 100ms wait for the global byte limit, got %v", wait)
+	}
+
+	// A throttled publish consumes nothing; after the wait it goes through
+	clk.Advance(500 * time.Millisecond)
+	if wait := l.allow(a, 10); wait != 0 {
+		t.Errorf("expected the retry to go through, got %v", wait)
+	}
+
+	// A publish larger than a second's worth waits for a full bucket
+	clk.Advance(time.Second)
+	if wait := l.allow(b, 250); wait != 0 {
+		t.Errorf("expected an oversized publish on a full bucket to go through, got %v", wait)
+	}
+	if wait := l.allow(a, 1); wait != 1510*time.Millisecond {
+		t.Errorf("expected the debt of the oversized publish to be paid back, got %v", wait)
+	}
+
+	if newRateLimiter(PublishLimits{}, clk) != nil {
+		t.Error("expected no limiter without limits")
+	}
+}
+
+func TestServerThrottlesPublishers(t *testing.T) {
+	cfg := ServerConfig{
+		TCPHost:       "127.0.0.1",
+		TCPPort:       freePort(t),
+		HTTPHost:      "127.0.0.1",
+		HTTPPort:      freePort(t),
+		Queue:         DefaultQueueConfig(),
+		PublishLimits: PublishLimits{ConnMessages: 1},
+	}
+	server := NewServer(cfg, log.New(io.Discard, "", 0))
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+	client := NewClient(ClientConfig{Host: "127.0.0.1", Port: cfg.TCPPort, Timeout: 2 * time.Second})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	if err := client.Publish(ctx, []byte(`{"n":1}`)); err != nil {
+		t.Fatalf("first publish failed: %v", err)
+	}
+	err := client.Publish(ctx, []byte(`{"n":2}`))
+	var throttle *ThrottleError
+	if !errors.As(err, &throttle) || perrors.KindOf(err) != perrors.KindTransient {
+		t.Fatalf("expected a transient ThrottleError, got %v", err)
+	}
+	if throttle.RetryAfter() <= 0 || throttle.RetryAfter() > time.Second {
+		t.Errorf("expected a retry-after within a second, got %v", throttle.RetryAfter())
+	}
+
+	stats := server.GetQueue().GetStats()
+	if stats.TotalMessages != 1 || stats.ThrottledPublishes != 1 {
+		t.Errorf("expected 1 message and 1 throttled publish, got %d and %d", stats.TotalMessages, stats.ThrottledPublishes)
+	}
+}
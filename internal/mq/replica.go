@@ -0,0 +1,447 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+)
+
+// Replication limits. A batch stays well under the 10MB frame limit once
+// its payloads are base64-encoded, and the wait stays under the client
+// timeout.
+const (
+	replicaMaxMessages = 1000
+	replicaMaxBytes    = 4 << 20
+	replicaWait        = time.Second
+	replicaRetryDelay  = time.Second
+)
+
+// epochFile holds a persistent queue's promotion epoch.
+const epochFile = "epoch.json"
+
+// ReplicaBatch is the part of a primary's log a standby fetched, with the
+// primary's committed offsets.
+type ReplicaBatch struct {
+	// Messages are contiguous from the offset asked for, or from the
+	// oldest retained when that was trimmed
+	Messages []*Message `json:"messages"`
+
+	// Committed holds the primary's committed offsets by subscriber ID
+	Committed map[string]Offset `json:"committed,omitempty"`
+
+	// End is the offset the primary's next message will get
+	End Offset `json:"end"`
+
+	// Epoch is the primary's promotion epoch; see InMemoryQueue.Epoch
+	Epoch int64 `json:"epoch,omitempty"`
+}
+
+// ReplicationStats describes a standby's replication of its primary.
+type ReplicationStats struct {
+	// Primary is the address of the primary replicated from
+	Primary string `json:"primary"`
+
+	// Standby is cleared when the server is promoted
+	Standby   bool `json:"standby"`
+	Connected bool `json:"connected"`
+
+	// Next is the offset of the next message to replicate, and Lag how many
+	// messages the primary had past it at last contact
+	Next Offset `json:"next"`
+	Lag  int64  `json:"lag"`
+
+	LastContact *time.Time `json:"last_contact,omitempty"`
+	PromotedAt  *time.Time `json:"promoted_at,omitempty"`
+
+	// Error is the last failure to replicate, cleared by the next success
+	Error string `json:"error,omitempty"`
+}
+
+// SetStandby makes the queue refuse publishes, subscriptions and commits
+// with ErrStandby, so its log and committed offsets only change through
+// ApplyReplica, or lifts the refusal on promotion.
+func (q *InMemoryQueue) SetStandby(standby bool) {
+	q.standby.Store(standby)
+}
+
+// IsStandby reports whether the queue is a standby; see SetStandby.
+func (q *InMemoryQueue) IsStandby() bool {
+	return q.standby.Load()
+}
+
+// Epoch returns the queue's promotion epoch: 0 for a log that was never
+// failed over, and one more than its primary's once a standby is promoted.
+// Standbys take on their primary's. Servers send it in every reply, and
+// clients send back the highest they have seen, so a primary that a
+// standby was promoted over finds out from the first client to reach both.
+func (q *InMemoryQueue) Epoch() int64 {
+	return q.epoch.Load()
+}
+
+// raiseEpoch moves the epoch up to epoch, persisting it with a DataDir.
+func (q *InMemoryQueue) raiseEpoch(epoch int64) error {
+	for {
+		current := q.epoch.Load()
+		if epoch <= current {
+			return nil
+		}
+		if q.epoch.CompareAndSwap(current, epoch) {
+			break
+		}
+	}
+	if q.wal == nil {
+		return nil
+	}
+	return statestore.PutJSON(context.Background(), q.wal.state, epochFile, epoch)
+}
+
+// readEpoch loads the promotion epoch, 0 if none was saved.
+func (w *wal) readEpoch() (int64, error) {
+	var epoch int64
+	err := statestore.GetJSON(context.Background(), w.state, epochFile, &epoch)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return 0, nil
+	}
+	return epoch, err
+}
+
+// signalAppendedLocked wakes standbys waiting for messages. The caller
+// holds logMu.
+func (q *InMemoryQueue) signalAppendedLocked() {
+	close(q.appended)
+	q.appended = make(chan struct{})
+}
+
+// ReplicaBatchFrom returns up to maxMessages from offset on for a standby,
+// waiting until ctx is done for one to be published when there are none.
+// Spilled messages are paged back in once logMu is released; the batch
+// stops short of one that could not be, or fails if it is the first.
+func (q *InMemoryQueue) ReplicaBatchFrom(ctx context.Context, offset Offset, maxMessages int) (ReplicaBatch, error) {
+	var batch ReplicaBatch
+	for {
+		q.logMu.RLock()
+		appended := q.appended
+		batch.End = q.base + Offset(len(q.log))
+		var size int64
+		for idx := int(max(offset, q.base) - q.base); idx < len(q.log) && len(batch.Messages) < maxMessages; idx++ {
+			if size += messageSize(q.log[idx]); size > replicaMaxBytes && len(batch.Messages) > 0 {
+				break
+			}
+			batch.Messages = append(batch.Messages, q.log[idx])
+		}
+		q.logMu.RUnlock()
+
+		if len(batch.Messages) > 0 || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-appended:
+		case <-ctx.Done():
+		}
+	}
+	messages, err := q.pageInAll(batch.Messages)
+	if len(messages) == 0 && err != nil {
+		return ReplicaBatch{}, err
+	}
+	batch.Messages = messages
+	batch.Epoch = q.Epoch()
+
+	q.subMu.RLock()
+	batch.Committed = maps.Clone(q.committed)
+	q.subMu.RUnlock()
+	return batch, nil
+}
+
+// ApplyReplica appends the messages of a batch from the primary that the
+// log does not have yet, keeping their offsets, and takes on the primary's
+// committed offsets and epoch. It returns the offset of the next message to
+// replicate. When the primary trimmed messages the standby never received,
+// the log restarts at the primary's oldest. A message failing its checksum
+// is refused with a *CorruptMessageError, and none of the batch applied.
+func (q *InMemoryQueue) ApplyReplica(batch ReplicaBatch) (Offset, error) {
+	if !q.running.Load() {
+		return 0, ErrQueueShutdown
+	}
+
+	q.logMu.Lock()
+	next := q.base + Offset(len(q.log))
+	if batch.End < next {
+		q.logMu.Unlock()
+		return next, fmt.Errorf("standby log ends at offset %d, past the primary's %d", next, batch.End)
+	}
+	messages := batch.Messages
+	for len(messages) > 0 && messages[0].Offset < next {
+		messages = messages[1:]
+	}
+	if len(messages) > 0 && messages[0].Offset > next {
+		if err := q.resetLogLocked(messages[0].Offset); err != nil {
+			q.logMu.Unlock()
+			return next, err
+		}
+	}
+	var size int64
+	for _, msg := range messages {
+		if err := msg.Verify(); err != nil {
+			q.logMu.Unlock()
+			return next, err
+		}
+		if msg.Metadata == nil {
+			msg.Metadata = make(map[string]string)
+		}
+		size += messageSize(msg)
+	}
+	if q.wal != nil && len(messages) > 0 {
+		if err := q.wal.append(messages...); err != nil {
+			q.logMu.Unlock()
+			return next, err
+		}
+	}
+	q.log = append(q.log, messages...)
+	q.logBytes += size
+	q.spillLocked()
+	now := q.clock.Now()
+	for _, msg := range messages {
+		q.indexUrgentLocked(msg)
+		// Retries of publishes the primary took still dedup after promotion
+		if now.Sub(msg.Timestamp) < q.config.DedupWindow {
+			q.dedup.add(msg.Metadata[MetaIdempotencyKey], msg.Offset, msg.Timestamp)
+		}
+	}
+	if len(messages) > 0 {
+		q.signalAppendedLocked()
+	}
+	next = q.base + Offset(len(q.log))
+	q.logMu.Unlock()
+
+	for _, msg := range messages {
+		if _, probe := msg.Metadata[MetaProbe]; !probe {
+			atomic.AddInt64(&q.totalPublished, 1)
+			q.published[priorityLevel(msg.Metadata)].Add(1)
+		}
+		if due, ok := deliverAt(msg); ok && due.After(now) {
+			q.scheduled.add(due)
+		}
+	}
+
+	if err := q.raiseEpoch(batch.Epoch); err != nil {
+		return next, fmt.Errorf("failed to persist the epoch: %w", err)
+	}
+
+	q.subMu.Lock()
+	defer q.subMu.Unlock()
+	if maps.Equal(q.committed, batch.Committed) || batch.Committed == nil {
+		return next, nil
+	}
+	maps.Copy(q.committed, batch.Committed)
+	if q.wal != nil {
+		if err := q.wal.saveOffsets(q.committed); err != nil {
+			return next, fmt.Errorf("failed to persist committed offsets: %w", err)
+		}
+	}
+	return next, nil
+}
+
+// resetLogLocked empties the log to continue it from base. The caller
+// holds logMu.
+func (q *InMemoryQueue) resetLogLocked(base Offset) error {
+	if n := len(q.log); n > 0 {
+		q.removeOldestLocked(n, q.logBytes)
+	}
+	q.base = base
+	if q.wal != nil {
+		return q.wal.restart(base)
+	}
+	return nil
+}
+
+// replicator keeps a standby's log in step with its primary's until the
+// standby is promoted.
+type replicator struct {
+	host         string
+	port         int
+	promoteAfter time.Duration
+	stop         context.CancelFunc
+	done         chan struct{}
+	promoted     sync.Once
+
+	// stats is published to the queue on every change
+	stats ReplicationStats
+}
+
+// newReplicator returns a replicator of the primary at addr, a host:port.
+func newReplicator(addr string, promoteAfter time.Duration) (*replicator, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary address %q: %w", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary port %q: %w", portStr, err)
+	}
+	return &replicator{
+		host:         host,
+		port:         port,
+		promoteAfter: promoteAfter,
+		done:         make(chan struct{}),
+		stats:        ReplicationStats{Primary: addr, Standby: true},
+	}, nil
+}
+
+// replicate tails the primary's log into the queue until ctx is done, or
+// until the primary has been unreachable for promoteAfter, when it
+// promotes the server.
+func (s *Server) replicate(ctx context.Context, r *replicator) {
+	defer s.wg.Done()
+	defer close(r.done)
+
+	// Carry on from the log recovered from disk
+	s.queue.logMu.RLock()
+	next := s.queue.base + Offset(len(s.queue.log))
+	s.queue.logMu.RUnlock()
+	r.stats.Next = next
+	r.publish(s.queue)
+
+	lastContact := time.Now()
+	var client *Client
+	defer func() {
+		if client != nil {
+			client.Close()
+		}
+	}()
+	for ctx.Err() == nil {
+		if client == nil {
+			client = NewClient(ClientConfig{Host: r.host, Port: r.port, Timeout: 10 * time.Second})
+			if err := client.ConnectContext(ctx); err != nil {
+				client = nil
+				s.replicaFailed(ctx, r, err, lastContact)
+				continue
+			}
+			s.logger.Printf("Replicating %s from offset %d", r.stats.Primary, next)
+		}
+
+		batch, err := client.Replicate(ctx, next, replicaMaxMessages, replicaWait)
+		if err != nil {
+			// The connection cannot be trusted after a failed request
+			client.Close()
+			client = nil
+			s.replicaFailed(ctx, r, err, lastContact)
+			continue
+		}
+		lastContact = time.Now()
+		next, err = s.queue.ApplyReplica(batch)
+
+		r.stats.Connected = true
+		r.stats.Next = next
+		r.stats.Lag = max(int64(batch.End-next), 0)
+		r.stats.LastContact = &lastContact
+		r.stats.Error = ""
+		if err != nil {
+			r.stats.Error = err.Error()
+		}
+		r.publish(s.queue)
+		if err != nil {
+			s.logger.Printf("Replication from %s failed: %v", r.stats.Primary, err)
+			sleepContext(ctx, replicaRetryDelay)
+		}
+	}
+}
+
+// replicaFailed records a failure to reach the primary and waits to retry,
+// or promotes the server once the primary has been gone for promoteAfter.
+func (s *Server) replicaFailed(ctx context.Context, r *replicator, err error, lastContact time.Time) {
+	if ctx.Err() != nil {
+		return
+	}
+	if r.stats.Connected || r.stats.Error == "" {
+		s.logger.Printf("Lost the primary %s: %v", r.stats.Primary, err)
+	}
+	r.stats.Connected = false
+	r.stats.Error = err.Error()
+	r.publish(s.queue)
+
+	if r.promoteAfter > 0 && time.Since(lastContact) >= r.promoteAfter {
+		s.logger.Printf("Primary %s unreachable for %v, promoting", r.stats.Primary, r.promoteAfter)
+		s.promote(r)
+		r.stop()
+		return
+	}
+	sleepContext(ctx, replicaRetryDelay)
+}
+
+// publish makes the replicator's stats visible in the queue's.
+func (r *replicator) publish(q *InMemoryQueue) {
+	stats := r.stats
+	q.replication.Store(&stats)
+}
+
+// Promote turns a standby into a primary: it stops replicating and accepts
+// publishes, subscriptions and commits, carrying on from the log and
+// committed offsets replicated so far. Consumers resuming their committed
+// offsets pick up where they left off on the old primary, apart from
+// commits made after the last replication. Promoting a primary does
+// nothing.
+func (s *Server) Promote() {
+	r := s.replica
+	if r == nil {
+		return
+	}
+	r.stop()
+	<-r.done
+	s.promote(r)
+}
+
+// promote lifts the standby's refusals, once, in a new epoch.
+func (s *Server) promote(r *replicator) {
+	r.promoted.Do(func() {
+		if err := s.queue.raiseEpoch(s.queue.Epoch() + 1); err != nil {
+			s.logger.Printf("Failed to persist the epoch: %v", err)
+		}
+		s.queue.SetStandby(false)
+		now := time.Now()
+		stats := *s.queue.replication.Load()
+		stats.Standby = false
+		stats.Connected = false
+		stats.PromotedAt = &now
+		s.queue.replication.Store(&stats)
+		s.logger.Printf("Promoted to primary at offset %d in epoch %d; %s must not come back as a primary", stats.Next, s.queue.Epoch(), stats.Primary)
+	})
+}
+
+// checkEpoch deposes a primary when a client has seen a later epoch than
+// its own: a standby was promoted over it while it was cut off. It then
+// refuses publishes, subscriptions and commits as a standby does, and drops
+// its other clients so they look for the promoted server too.
+func (s *Server) checkEpoch(conn net.Conn, epoch int64) {
+	if epoch <= s.queue.Epoch() || s.queue.IsStandby() || !s.deposed.CompareAndSwap(false, true) {
+		return
+	}
+	s.queue.SetStandby(true)
+	s.logger.Printf("Deposed: a client has seen epoch %d, past this server's %d, so a standby was promoted over it. Restart it as a standby of the promoted server",
+		epoch, s.queue.Epoch())
+
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	for other := range s.clients {
+		if other != conn {
+			other.Close()
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
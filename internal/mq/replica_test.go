@@ -0,0 +1,467 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startStandby(t *testing.T, cfg QueueConfig) *InMemoryQueue {
+	t.Helper()
+	q := NewInMemoryQueue(cfg)
+	q.SetStandby(true)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+	return q
+}
+
+// replicaBatch returns q's ReplicaBatchFrom, failing the test on an error.
+func replicaBatch(t *testing.T, q *InMemoryQueue, ctx context.Context, offset Offset, maxMessages int) ReplicaBatch {
+	t.Helper()
+	batch, err := q.ReplicaBatchFrom(ctx, offset, maxMessages)
+	if err != nil {
+		t.Fatalf("failed to read a replica batch: %v", err)
+	}
+	return batch
+}
+
+func TestApplyReplica(t *testing.T) {
+	primary := startRetaining(t, DefaultQueueConfig())
+	standby := startStandby(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	publishN(t, primary, 3)
+	primary.PublishWithMetadata(ctx, []byte("keyed"), map[string]string{MetaIdempotencyKey: "batch-1"})
+	primary.CommitOffset("collector", 2)
+
+	batch := replicaBatch(t, primary, ctx, 0, 10)
+	next, err := standby.ApplyReplica(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 4 || batch.End != 4 {
+		t.Fatalf("expected to replicate up to offset 4, got %d (primary end %d)", next, batch.End)
+	}
+	for offset := Offset(0); offset < 4; offset++ {
+		want, _ := primary.FetchMessage(offset)
+		got, err := standby.FetchMessage(offset)
+		if err != nil || got.ID != want.ID || string(got.Payload) != string(want.Payload) || !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("offset %d: expected %+v, got %+v (%v)", offset, want, got, err)
+		}
+	}
+	if committed, ok := standby.GetCommittedOffset("collector"); !ok || committed != 2 {
+		t.Errorf("expected the committed offset replicated, got %d, %v", committed, ok)
+	}
+
+	// Applying a batch again adds nothing
+	if next, err := standby.ApplyReplica(batch); err != nil || next != 4 {
+		t.Errorf("expected a repeated batch to be skipped, got %d, %v", next, err)
+	}
+
+	// After promotion the standby carries on the primary's offsets and dedups its keys
+	standby.SetStandby(false)
+	standby.PublishWithMetadata(ctx, []byte("keyed"), map[string]string{MetaIdempotencyKey: "batch-1"})
+	publishN(t, standby, 1)
+	if stats := standby.GetStats(); stats.LatestOffset != 4 || stats.DuplicateMessages != 1 {
+		t.Errorf("expected the promoted log to continue at offset 4 with the retry dropped, got %+v", stats)
+	}
+}
+
+func TestApplyReplicaAfterTrim(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.RetentionMessages = 2
+	primary := startRetaining(t, cfg)
+	standby := startStandby(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	publishN(t, primary, 1)
+	if _, err := standby.ApplyReplica(replicaBatch(t, primary, ctx, 0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, primary, 4)
+	primary.trim(time.Now())
+
+	// Offsets 1 and 2 are gone, so the standby starts over at the oldest
+	next, err := standby.ApplyReplica(replicaBatch(t, primary, ctx, 1, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := standby.GetStats(); next != 5 || stats.OldestOffset != 3 || stats.LatestOffset != 4 {
+		t.Errorf("expected the standby to hold offsets 3 and 4, got next %d, %+v", next, stats)
+	}
+}
+
+func TestApplyReplicaAheadOfPrimary(t *testing.T) {
+	primary := startRetaining(t, DefaultQueueConfig())
+	standby := startStandby(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	publishN(t, primary, 3)
+	standby.ApplyReplica(replicaBatch(t, primary, ctx, 0, 10))
+
+	// A primary that restarted without its log is behind the standby
+	empty := startRetaining(t, DefaultQueueConfig())
+	wait, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := standby.ApplyReplica(replicaBatch(t, empty, wait, 3, 10)); err == nil {
+		t.Error("expected an error replicating a primary behind the standby")
+	}
+}
+
+func TestReplicaBatchFromWaitsForPublish(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.Publish(context.Background(), []byte("late"))
+	}()
+	batch := replicaBatch(t, q, ctx, 0, 10)
+	if len(batch.Messages) != 1 || string(batch.Messages[0].Payload) != "late" {
+		t.Errorf("expected the late message, got %+v", batch.Messages)
+	}
+
+	// With nothing published the wait times out empty
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if batch := replicaBatch(t, q, ctx, 1, 10); len(batch.Messages) != 0 || batch.End != 1 {
+		t.Errorf("expected an empty batch ending at 1, got %+v", batch)
+	}
+}
+
+func TestStandbyRefusesWrites(t *testing.T) {
+	q := startStandby(t, DefaultQueueConfig())
+	ctx := context.Background()
+	handler := func(context.Context, *Message) error { return nil }
+
+	if err := q.Publish(ctx, []byte("x")); !errors.Is(err, ErrStandby) {
+		t.Errorf("expected ErrStandby publishing, got %v", err)
+	}
+	if err := q.PublishBatch(ctx, [][]byte{[]byte("x")}); !errors.Is(err, ErrStandby) {
+		t.Errorf("expected ErrStandby publishing a batch, got %v", err)
+	}
+	if err := q.Subscribe(ctx, "collector", OffsetEarliest, handler); !errors.Is(err, ErrStandby) {
+		t.Errorf("expected ErrStandby subscribing, got %v", err)
+	}
+	if err := q.CommitOffset("collector", 0); !errors.Is(err, ErrStandby) {
+		t.Errorf("expected ErrStandby committing, got %v", err)
+	}
+}
+
+// startStandbyServer starts a standby server replicating primary.
+func startStandbyServer(t *testing.T, primary string, promoteAfter time.Duration) (*Server, int) {
+	t.Helper()
+	port := freePort(t)
+	server := NewServer(ServerConfig{
+		TCPHost:      "127.0.0.1",
+		TCPPort:      port,
+		HTTPHost:     "127.0.0.1",
+		HTTPPort:     freePort(t),
+		Queue:        DefaultQueueConfig(),
+		ReplicaOf:    primary,
+		PromoteAfter: promoteAfter,
+	}, log.New(io.Discard, "", 0))
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+	return server, port
+}
+
+func TestServerReplicationAndPromotion(t *testing.T) {
+	primary, _ := startTestServer(t, DefaultQueueConfig())
+	standby, port := startStandbyServer(t, primary.TCPAddr().String(), 0)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		primary.queue.Publish(ctx, []byte(fmt.Sprintf(`{"n":%d}`, i)))
+	}
+	primary.queue.CommitOffset("collector", 3)
+	waitFor(t, func() bool {
+		r := standby.queue.GetStats().Replication
+		return r != nil && r.Connected && r.Next == 5 && r.Lag == 0
+	})
+	waitFor(t, func() bool {
+		committed, _ := standby.queue.GetCommittedOffset("collector")
+		return committed == 3
+	})
+
+	client := NewClient(ClientConfig{Host: "127.0.0.1", Port: port, Timeout: 2 * time.Second})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Publish(ctx, []byte(`{"n":1}`)); err == nil {
+		t.Fatal("expected the standby to refuse a publish")
+	}
+
+	standby.Promote()
+	if r := standby.queue.GetStats().Replication; r.Standby || r.PromotedAt == nil {
+		t.Errorf("expected the standby promoted, got %+v", r)
+	}
+	offset, err := client.PublishConfirmed(ctx, []byte(`{"n":5}`), nil)
+	if err != nil || offset != 5 {
+		t.Errorf("expected the promoted server to take the publish at offset 5, got %d, %v", offset, err)
+	}
+	var (
+		mu       sync.Mutex
+		received []Offset
+	)
+	err = client.Subscribe(ctx, "collector", OffsetCommitted, func(_ context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, msg.Offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 3
+	})
+	if slices.Sort(received); !slices.Equal(received, []Offset{3, 4, 5}) {
+		t.Errorf("expected to resume at the replicated commit, offset 3, got %v", received)
+	}
+}
+
+func TestStandbyPromotesAfterLosingPrimary(t *testing.T) {
+	primary, _ := startTestServer(t, DefaultQueueConfig())
+	standby, _ := startStandbyServer(t, primary.TCPAddr().String(), 100*time.Millisecond)
+	waitFor(t, func() bool {
+		r := standby.queue.GetStats().Replication
+		return r != nil && r.Connected
+	})
+
+	primary.Stop(context.Background())
+	deadline := time.Now().Add(5 * time.Second)
+	for standby.queue.IsStandby() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected promotion without the primary, got %+v", standby.queue.GetStats().Replication)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClientFailover(t *testing.T) {
+	server, _ := startTestServer(t, DefaultQueueConfig())
+	client := NewClient(ClientConfig{
+		Host:     "127.0.0.1",
+		Port:     freePort(t),
+		Failover: []string{server.TCPAddr().String()},
+		Timeout:  2 * time.Second,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("expected to connect to the failover, got %v", err)
+	}
+	defer client.Close()
+	if err := client.Publish(context.Background(), []byte(`{"n":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := NewClient(ClientConfig{Host: "127.0.0.1", Port: freePort(t), Failover: []string{fmt.Sprintf("127.0.0.1:%d", freePort(t))}, Timeout: time.Second})
+	if err := bad.Connect(); err == nil {
+		bad.Close()
+		t.Error("expected an error with no server reachable")
+	}
+}
+
+// offsetCollector subscribes client as collector, recording the offsets it
+// is delivered.
+func offsetCollector(t *testing.T, client *Client) func() []Offset {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		received []Offset
+	)
+	err := client.Subscribe(context.Background(), "collector", OffsetEarliest, func(_ context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, msg.Offset)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() []Offset {
+		mu.Lock()
+		defer mu.Unlock()
+		offsets := slices.Clone(received)
+		slices.Sort(offsets)
+		return offsets
+	}
+}
+
+func TestClientLeavesStandby(t *testing.T) {
+	primary, _ := startTestServer(t, DefaultQueueConfig())
+	_, port := startStandbyServer(t, primary.TCPAddr().String(), 0)
+
+	// The standby is listed first, and refuses the subscription
+	client := NewClient(ClientConfig{
+		Host:           "127.0.0.1",
+		Port:           port,
+		Failover:       []string{primary.TCPAddr().String()},
+		Timeout:        2 * time.Second,
+		AutoReconnect:  true,
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	received := offsetCollector(t, client)
+
+	waitFor(t, func() bool { return subscriberInfo(primary.queue, "collector").ID != "" })
+	if err := client.Publish(context.Background(), []byte(`{"n":0}`)); err != nil {
+		t.Fatalf("expected the publish to reach the primary, got %v", err)
+	}
+	waitFor(t, func() bool { return slices.Equal(received(), []Offset{0}) })
+}
+
+func TestClientFailsOverToPromotedStandby(t *testing.T) {
+	primary, _ := startTestServer(t, DefaultQueueConfig())
+	standby, port := startStandbyServer(t, primary.TCPAddr().String(), 100*time.Millisecond)
+	ctx := context.Background()
+
+	client := NewClient(ClientConfig{
+		Host:           "127.0.0.1",
+		Port:           primary.TCPAddr().(*net.TCPAddr).Port,
+		Failover:       []string{fmt.Sprintf("127.0.0.1:%d", port)},
+		Timeout:        2 * time.Second,
+		AutoReconnect:  true,
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	received := offsetCollector(t, client)
+
+	for i := 0; i < 3; i++ {
+		primary.queue.Publish(ctx, []byte(fmt.Sprintf(`{"n":%d}`, i)))
+	}
+	waitFor(t, func() bool { return len(received()) == 3 })
+	if err := client.CommitOffset(ctx, "collector", 3); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		committed, _ := standby.queue.GetCommittedOffset("collector")
+		return committed == 3
+	})
+
+	// The client resubscribes to the promoted standby at its committed offset
+	primary.Stop(ctx)
+	waitFor(t, func() bool { return subscriberInfo(standby.queue, "collector").ID != "" })
+	if err := client.Publish(ctx, []byte(`{"n":3}`)); err != nil {
+		t.Fatalf("expected the publish to reach the promoted standby, got %v", err)
+	}
+	waitFor(t, func() bool { return len(received()) == 4 })
+	if got := received(); !slices.Equal(got, []Offset{0, 1, 2, 3}) {
+		t.Errorf("expected each message once across the failover, got %v", got)
+	}
+}
+
+func TestEpochPersistsAndReplicates(t *testing.T) {
+	dir := t.TempDir()
+	q := openQueue(t, dir, 1<<20)
+	if err := q.raiseEpoch(2); err != nil {
+		t.Fatal(err)
+	}
+	q.Shutdown(context.Background())
+	q = openQueue(t, dir, 1<<20)
+	defer q.Shutdown(context.Background())
+	if epoch := q.Epoch(); epoch != 2 {
+		t.Fatalf("expected the epoch reloaded, got %d", epoch)
+	}
+
+	// A standby takes on its primary's epoch
+	publishN(t, q, 1)
+	standby := startStandby(t, DefaultQueueConfig())
+	if _, err := standby.ApplyReplica(replicaBatch(t, q, context.Background(), 0, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if epoch := standby.Epoch(); epoch != 2 {
+		t.Errorf("expected the primary's epoch replicated, got %d", epoch)
+	}
+}
+
+func TestPromotionDeposesOldPrimary(t *testing.T) {
+	primary, _ := startTestServer(t, DefaultQueueConfig())
+	standby, port := startStandbyServer(t, primary.TCPAddr().String(), 0)
+	ctx := context.Background()
+	waitFor(t, func() bool {
+		r := standby.queue.GetStats().Replication
+		return r != nil && r.Connected
+	})
+
+	// Promoted as if the primary were cut off, though it is still up
+	standby.Promote()
+	if epoch := standby.queue.Epoch(); epoch != 1 {
+		t.Fatalf("expected the promotion to start epoch 1, got %d", epoch)
+	}
+	old := NewClient(ClientConfig{
+		Host:           "127.0.0.1",
+		Port:           primary.TCPAddr().(*net.TCPAddr).Port,
+		Timeout:        2 * time.Second,
+		AutoReconnect:  true,
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	if err := old.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	if err := old.Publish(ctx, []byte(`{"n":0}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A client of the promoted server reaches the old primary
+	client := NewClient(ClientConfig{
+		Host:           "127.0.0.1",
+		Port:           port,
+		Failover:       []string{primary.TCPAddr().String()},
+		Timeout:        2 * time.Second,
+		AutoReconnect:  true,
+		ReconnectDelay: 10 * time.Millisecond,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Publish(ctx, []byte(`{"n":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	standby.Stop(ctx)
+	waitFor(t, func() bool {
+		client.Publish(ctx, []byte(`{"n":2}`))
+		return primary.deposed.Load()
+	})
+
+	// The old primary takes no more writes from anyone
+	waitFor(t, func() bool {
+		// A publish caught by the dropped connection goes unanswered
+		attempt, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+		defer cancel()
+		return errors.Is(old.Publish(attempt, []byte(`{"n":3}`)), ErrStandby)
+	})
+	if stats := primary.queue.GetStats(); stats.TotalMessages != 1 {
+		t.Errorf("expected only the publish from before the promotion, got %d messages", stats.TotalMessages)
+	}
+
+	// A client that has seen the later epoch refuses replies from an earlier one
+	reply := ProtocolMessage{Type: MsgTypeResponse, RequestID: "1", Success: true}
+	client.checkEpoch(&reply)
+	if reply.Type != MsgTypeStandby || reply.RequestID != "1" {
+		t.Errorf("expected a reply from epoch 0 turned into a standby refusal, got %+v", reply)
+	}
+}
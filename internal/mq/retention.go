@@ -0,0 +1,88 @@
+package mq
+
+import "time"
+
+// retains reports whether any retention limit is set.
+func (q *InMemoryQueue) retains() bool {
+	return q.config.RetentionMessages > 0 || q.config.RetentionBytes > 0 || q.config.RetentionAge > 0
+}
+
+// startTrimmer trims the log now, catching up on what was recovered from
+// disk, and then every RetentionInterval until the queue shuts down.
+func (q *InMemoryQueue) startTrimmer() {
+	interval := q.config.RetentionInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	q.trim(q.clock.Now())
+
+	ticker := q.clock.NewTicker(interval)
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-q.ctx.Done():
+				return
+			case now := <-ticker.C():
+				q.trim(now)
+			}
+		}
+	}()
+}
+
+// trim removes the oldest messages until the log is within every retention
+// limit, advancing the oldest offset, and returns how many it removed.
+// Subscribers still reading them skip ahead on their next delivery.
+func (q *InMemoryQueue) trim(now time.Time) int {
+	q.logMu.Lock()
+	defer q.logMu.Unlock()
+
+	cfg := q.config
+	bytes := q.logBytes
+	n := 0
+	for ; n < len(q.log); n++ {
+		msg := q.log[n]
+		over := (cfg.RetentionMessages > 0 && len(q.log)-n > cfg.RetentionMessages) ||
+			(cfg.RetentionBytes > 0 && bytes > cfg.RetentionBytes) ||
+			(cfg.RetentionAge > 0 && now.Sub(msg.Timestamp) > cfg.RetentionAge)
+		if !over {
+			break
+		}
+		bytes -= messageSize(msg)
+	}
+	if n == 0 {
+		return 0
+	}
+	q.removeOldestLocked(n, q.logBytes-bytes)
+	return n
+}
+
+// removeOldestLocked removes the n oldest messages, of size bytes, from the
+// log and wakes publishers waiting for room. The caller holds logMu.
+func (q *InMemoryQueue) removeOldestLocked(n int, bytes int64) {
+	q.unspillLocked(n)
+	// Drop the references so the trimmed messages can be collected
+	clear(q.log[:n])
+	q.log = q.log[n:]
+	q.base += Offset(n)
+	q.logBytes -= bytes
+	q.trimUrgentLocked()
+	q.trimmed += int64(n)
+	if q.wal != nil {
+		q.wal.trim(q.base)
+	}
+	close(q.spaceFreed)
+	q.spaceFreed = make(chan struct{})
+}
+
+// messageSize is the size retention counts for a message: its payload,
+// spilled to disk or not, and metadata.
+func messageSize(msg *Message) int64 {
+	size := int64(len(msg.Payload) + msg.spilled)
+	for k, v := range msg.Metadata {
+		size += int64(len(k) + len(v))
+	}
+	return size
+}
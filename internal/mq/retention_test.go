@@ -0,0 +1,196 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// startRetaining starts a queue with cfg's retention limits and the
+// trimmer's interval out of the way, so tests trim explicitly.
+func startRetaining(t *testing.T, cfg QueueConfig) *InMemoryQueue {
+	t.Helper()
+	cfg.RetentionInterval = time.Hour
+	q := NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start queue: %v", err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+	return q
+}
+
+func publishN(t *testing.T, q *InMemoryQueue, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := q.Publish(context.Background(), []byte(fmt.Sprintf("msg-%02d", i))); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+}
+
+func TestTrimByMessagesAndBytes(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.RetentionMessages = 5
+	q := startRetaining(t, cfg)
+	publishN(t, q, 8)
+
+	if n := q.trim(time.Now()); n != 3 {
+		t.Fatalf("expected 3 messages trimmed, got %d", n)
+	}
+	stats := q.GetStats()
+	if q.Len() != 5 || stats.OldestOffset != 3 || stats.LatestOffset != 7 || stats.TrimmedMessages != 3 || stats.RetainedBytes != 5*6 {
+		t.Errorf("unexpected stats after trimming %+v", stats)
+	}
+	if msg, err := q.FetchMessage(3); err != nil || string(msg.Payload) != "msg-03" {
+		t.Errorf("expected msg-03 at offset 3, got %+v (%v)", msg, err)
+	}
+
+	// Appends continue the offsets
+	publishN(t, q, 1)
+	if q.GetLatestOffset() != 8 {
+		t.Errorf("expected latest offset 8, got %d", q.GetLatestOffset())
+	}
+
+	// Bytes are trimmed down to the limit too
+	q.config.RetentionMessages = 0
+	q.config.RetentionBytes = 13
+	if n := q.trim(time.Now()); n != 4 || q.GetOldestOffset() != 7 {
+		t.Errorf("expected 4 trimmed down to offset 7, got %d (oldest %d)", n, q.GetOldestOffset())
+	}
+}
+
+func TestTrimByAge(t *testing.T) {
+	sim := clock.NewSimulated(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	cfg := DefaultQueueConfig()
+	cfg.RetentionAge = time.Hour
+	q := startRetaining(t, cfg)
+	q.SetClock(sim)
+
+	publishN(t, q, 3)
+	sim.Advance(30 * time.Minute)
+	publishN(t, q, 2)
+
+	if n := q.trim(sim.Now()); n != 0 {
+		t.Errorf("expected nothing trimmed within the hour, got %d", n)
+	}
+	sim.Advance(45 * time.Minute)
+	if n := q.trim(sim.Now()); n != 3 || q.GetOldestOffset() != 3 {
+		t.Errorf("expected the first 3 trimmed, got %d (oldest %d)", n, q.GetOldestOffset())
+	}
+	sim.Advance(time.Hour)
+	if n := q.trim(sim.Now()); n != 2 || q.Len() != 0 {
+		t.Fatalf("expected the log emptied, got %d trimmed (%d left)", n, q.Len())
+	}
+
+	// An emptied log keeps its offsets
+	publishN(t, q, 1)
+	if msg, err := q.FetchMessage(5); err != nil || string(msg.Payload) != "msg-00" {
+		t.Errorf("expected the next message at offset 5, got %+v (%v)", msg, err)
+	}
+}
+
+func TestTrimmedOffsetsAreOutOfRange(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.RetentionMessages = 4
+	q := startRetaining(t, cfg)
+	ctx := context.Background()
+	publishN(t, q, 10)
+	q.trim(time.Now())
+
+	_, err := q.FetchMessage(2)
+	if !errors.Is(err, ErrOffsetOutOfRange) || !perrors.IsNotFound(err) {
+		t.Errorf("expected a not-found ErrOffsetOutOfRange, got %v", err)
+	}
+	if err := q.Subscribe(ctx, "replayer", 2, func(context.Context, *Message) error { return nil }); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("expected ErrOffsetOutOfRange subscribing at a trimmed offset, got %v", err)
+	}
+
+	var first atomic.Int64
+	first.Store(-1)
+	if err := q.Subscribe(ctx, "collector", OffsetEarliest, func(ctx context.Context, msg *Message) error {
+		first.CompareAndSwap(-1, int64(msg.Offset))
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := q.SetSubscriberOffset("collector", 1); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("expected ErrOffsetOutOfRange seeking to a trimmed offset, got %v", err)
+	}
+	waitFor(t, func() bool { return first.Load() >= 0 })
+	if got := first.Load(); got != 6 {
+		t.Errorf("expected earliest to start at the oldest retained offset 6, got %d", got)
+	}
+}
+
+func TestSubscriberSkipsTrimmedMessages(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.RetentionMessages = 3
+	q := startRetaining(t, cfg)
+	ctx := context.Background()
+	publishN(t, q, 4)
+	if err := q.CommitOffset("collector", 1); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	publishN(t, q, 4)
+	q.trim(time.Now())
+
+	// The committed position was trimmed while the collector was away
+	var received atomic.Int64
+	if err := q.Subscribe(ctx, "collector", OffsetCommitted, func(ctx context.Context, msg *Message) error {
+		received.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	waitFor(t, func() bool { return received.Load() == 3 })
+
+	stats := q.GetStats()
+	if len(stats.Subscribers) != 1 || stats.Subscribers[0].Trimmed != 4 || stats.Subscribers[0].CurrentOffset != 8 {
+		t.Errorf("expected 4 messages reported trimmed before delivery, got %+v", stats.Subscribers)
+	}
+}
+
+func TestWALDeletesTrimmedSegments(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// Every message fills a segment of its own
+	q := openQueue(t, dir, 64)
+	publishN(t, q, 6)
+	q.config.RetentionMessages = 2
+	q.trim(time.Now())
+	if wal := q.GetStats().WAL; wal.Segments != 2 || wal.TrimError != "" {
+		t.Errorf("expected the trimmed segments deleted, got %+v", wal)
+	}
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	q = openQueue(t, dir, 64)
+	defer q.Shutdown(ctx)
+	if q.GetOldestOffset() != 4 || q.Len() != 2 {
+		t.Fatalf("expected offsets 4 and 5 recovered, got oldest %d and %d messages", q.GetOldestOffset(), q.Len())
+	}
+	publishN(t, q, 1)
+	if q.GetLatestOffset() != 6 {
+		t.Errorf("expected appends to continue at offset 6, got %d", q.GetLatestOffset())
+	}
+}
+
+// waitFor polls cond until it holds or a deadline passes.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before the deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,75 @@
+package mq
+
+import (
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// retryPolicy is the backoff between retries of a failed message.
+func (q *InMemoryQueue) retryPolicy() retry.Policy {
+	return retry.Policy{
+		InitialBackoff: q.config.RetryDelay,
+		MaxBackoff:     q.config.RetryMaxDelay,
+		Multiplier:     q.config.RetryMultiplier,
+		Jitter:         q.config.RetryJitter,
+	}
+}
+
+// retryInPlace calls a handler that does not acknowledge with n messages.
+// With SubscribeOptions.RetryInPlace it calls it again after a backoff each
+// time it fails, holding back the messages after them, up to MaxRetries
+// retries. It returns the error the handler last failed with, or nil once
+// it succeeded. Retries stop without giving up when the queue shuts down or
+// the subscriber is removed.
+func (q *InMemoryQueue) retryInPlace(sub *subscriber, n int, call func() error) error {
+	err := call()
+	q.handled(sub, n, err)
+	if !sub.retryInPlace {
+		return err
+	}
+	policy := q.retryPolicy()
+	for retry := 1; err != nil; retry++ {
+		if retry > q.config.MaxRetries {
+			return err
+		}
+		if !q.sleep(policy.Wait(retry)) || !q.subscribed(sub) {
+			return nil
+		}
+		sub.redelivered.Add(int64(n))
+		err = call()
+		q.handled(sub, n, err)
+	}
+	return nil
+}
+
+// abandon gives up on msgs after their retries, for reason, handing them to
+// the subscriber's OnAbandon, or dead-lettering them when it has none.
+func (q *InMemoryQueue) abandon(sub *subscriber, reason string, msgs ...*Message) {
+	sub.abandoned.Add(int64(len(msgs)))
+	if sub.onAbandon == nil {
+		q.deadLetter(sub.id, reason, msgs...)
+		return
+	}
+	for _, msg := range msgs {
+		sub.onAbandon(msg)
+	}
+}
+
+// sleep waits for d on the queue's clock, and reports false if the queue
+// shut down first.
+func (q *InMemoryQueue) sleep(d time.Duration) bool {
+	select {
+	case <-q.clock.After(d):
+		return true
+	case <-q.ctx.Done():
+		return false
+	}
+}
+
+// subscribed reports whether sub is still subscribed.
+func (q *InMemoryQueue) subscribed(sub *subscriber) bool {
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+	return q.subscribers[sub.id] == sub
+}
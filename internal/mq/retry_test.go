@@ -0,0 +1,96 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+func TestFailedMessagesRetriedInPlaceWithBackoff(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.RetryDelay = time.Second
+	cfg.MaxRetries = 2
+	cfg.RetryJitter = 0
+	q := startRetaining(t, cfg)
+	sim := clock.NewSimulated(time.Now())
+	q.SetClock(sim)
+
+	var calls atomic.Int64
+	abandoned := make(chan *Message, 1)
+	opts := SubscribeOptions{RetryInPlace: true, OnAbandon: func(msg *Message) { abandoned <- msg }}
+	delivered := make(chan string, 4)
+	err := q.SubscribeWithOptions(context.Background(), "collector", OffsetEarliest, opts, func(_ context.Context, msg *Message) error {
+		delivered <- string(msg.Payload)
+		if string(msg.Payload) == "msg-00" {
+			calls.Add(1)
+			return errors.New("sink down")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, q, 2)
+
+	// Retried after 1s, then 2s, holding back the next message
+	for retry, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		waitFor(t, func() bool { return calls.Load() == int64(retry+1) })
+		sim.BlockUntil(1)
+		sim.Advance(wait - time.Millisecond)
+		if calls.Load() != int64(retry+1) {
+			t.Fatalf("expected retry %d to wait %v", retry+1, wait)
+		}
+		sim.Advance(time.Millisecond)
+	}
+	waitFor(t, func() bool { return calls.Load() == 3 })
+
+	if msg := <-abandoned; string(msg.Payload) != "msg-00" {
+		t.Errorf("expected msg-00 abandoned, got %q", msg.Payload)
+	}
+	for _, want := range []string{"msg-00", "msg-00", "msg-00", "msg-01"} {
+		if got := <-delivered; got != want {
+			t.Fatalf("expected %s delivered, got %s", want, got)
+		}
+	}
+	info := subscriberInfo(q, "collector")
+	if info.Redelivered != 2 || info.Abandoned != 1 || info.HandlerErrors != 3 {
+		t.Errorf("expected 2 retries of 1 abandoned message, got %+v", info)
+	}
+}
+
+func TestNackBackoffGrows(t *testing.T) {
+	q, sim := startAcking(t)
+	ctx := context.Background()
+
+	var deliveries atomic.Int64
+	abandoned := make(chan *Message, 1)
+	opts := SubscribeOptions{Acknowledge: true, OnAbandon: func(msg *Message) { abandoned <- msg }}
+	q.SubscribeWithOptions(ctx, "consumer", OffsetEarliest, opts, func(context.Context, *Message) error {
+		deliveries.Add(1)
+		return errors.New("temporary failure")
+	})
+	publishN(t, q, 1)
+	waitFor(t, func() bool { return deliveries.Load() == 1 })
+
+	sim.Advance(time.Second)
+	waitFor(t, func() bool { return deliveries.Load() == 2 })
+
+	// The second retry waits twice as long
+	sim.Advance(time.Second)
+	if deliveries.Load() != 2 {
+		t.Fatalf("expected the second retry to back off, got %d deliveries", deliveries.Load())
+	}
+	sim.Advance(time.Second)
+	waitFor(t, func() bool { return deliveries.Load() == 3 })
+
+	if msg := <-abandoned; string(msg.Payload) != "msg-00" {
+		t.Errorf("expected msg-00 abandoned, got %q", msg.Payload)
+	}
+	if info := subscriberInfo(q, "consumer"); info.Abandoned != 1 || info.Unacked != 0 {
+		t.Errorf("expected the message abandoned, got %+v", info)
+	}
+}
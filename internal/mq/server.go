@@ -4,13 +4,25 @@ package mq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/schema"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/support"
 )
 
 // Server is a TCP server for the message queue.
@@ -22,18 +34,70 @@ type Server struct {
 	httpAddr    string
 	clients     map[net.Conn]*clientState
 	clientsMu   sync.RWMutex
+	stopping    bool // Set by Stop under clientsMu; no client is added after
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 	logger      *log.Logger
+	leases      *leaseTable
+	heartbeats  *heartbeatTable
+
+	// Publish rate limits; nil when none are set
+	limiter *rateLimiter
+
+	// Limits on what each client may hold
+	quotas ClientQuotas
+
+	// Origins whose pages may connect at /ws; see ServerConfig
+	wsOrigins []string
+
+	// Schemas that frames and published payloads are validated against in
+	// debug mode; nil skips validation
+	protocolSchema *schema.Schema
+	payloadSchema  *schema.Schema
+
+	// Runtime log controls served at /admin/logging to admins; nil serves none
+	logs   *logging.Runtime
+	admin  *auth.Authenticator
+	frames *logging.Toggle
+
+	// Support report served at /admin/support to admins; nil serves none
+	support *support.Source
+
+	// replica tails the primary at replicaOf while the server is a
+	// standby; nil for a primary
+	replicaOf    string
+	promoteAfter time.Duration
+	replica      *replicator
+
+	// deposed is set once a client showed a standby was promoted over the
+	// server; see checkEpoch
+	deposed atomic.Bool
 }
 
 // clientState tracks per-client state.
 type clientState struct {
 	conn         net.Conn
-	subscriberID string
-	subscribed   bool
+	subscriberID string // The last one subscribed as
 	mu           sync.Mutex
+
+	// Subscriber IDs the connection holds, released when it closes
+	subscriptions map[string]bool
+
+	// writeMu serializes frames written to conn from concurrent deliveries
+	writeMu sync.Mutex
+
+	// binaryWire is set once a hello switched the connection to WireBinary
+	binaryWire atomic.Bool
+
+	// Active stats watches, keyed by the RequestID of the watch request
+	watches map[string]context.CancelFunc
+
+	// Per-connection publish rate limits; nil when none are set
+	publish *publishBuckets
+
+	// Traffic reported by Clients
+	usage clientUsage
 }
 
 // ServerConfig configures the MQ server.
@@ -43,6 +107,27 @@ type ServerConfig struct {
 	HTTPHost string      `json:"http_host"`
 	HTTPPort int         `json:"http_port"`
 	Queue    QueueConfig `json:"queue"`
+
+	// PublishLimits throttles publishers; the zero value is unlimited
+	PublishLimits PublishLimits `json:"publish_limits"`
+
+	// Quotas bound what each client may hold; the zero value is unlimited
+	Quotas ClientQuotas `json:"quotas"`
+
+	// ReplicaOf is the TCP address, host:port, of the primary this server
+	// stands by for, replicating its log until promoted; empty runs a
+	// primary
+	ReplicaOf string `json:"replica_of"`
+
+	// PromoteAfter promotes a standby once its primary has been unreachable
+	// this long; 0 waits for Promote or /admin/promote
+	PromoteAfter time.Duration `json:"promote_after"`
+
+	// WebSocketOrigins lists the origins, e.g. https://dash.example.com,
+	// whose pages may connect at /ws; "*" allows any. Empty allows only
+	// pages served from the server's own host, and clients sending no
+	// Origin, as non-browser ones need not.
+	WebSocketOrigins []string `json:"websocket_origins"`
 }
 
 // DefaultServerConfig returns a server config with sensible defaults.
@@ -66,18 +151,64 @@ func NewServer(config ServerConfig, logger *log.Logger) *Server {
 	queue := NewInMemoryQueue(config.Queue)
 
 	return &Server{
-		queue:    queue,
-		tcpAddr:  fmt.Sprintf("%s:%d", config.TCPHost, config.TCPPort),
-		httpAddr: fmt.Sprintf("%s:%d", config.HTTPHost, config.HTTPPort),
-		clients:  make(map[net.Conn]*clientState),
-		ctx:      ctx,
-		cancel:   cancel,
-		logger:   logger,
+		queue:      queue,
+		tcpAddr:    fmt.Sprintf("%s:%d", config.TCPHost, config.TCPPort),
+		httpAddr:   fmt.Sprintf("%s:%d", config.HTTPHost, config.HTTPPort),
+		clients:    make(map[net.Conn]*clientState),
+		ctx:        ctx,
+		cancel:     cancel,
+		logger:     logger,
+		leases:     newLeaseTable(),
+		heartbeats: newHeartbeatTable(),
+		limiter:    newRateLimiter(config.PublishLimits, clock.Real),
+		quotas:     config.Quotas,
+		wsOrigins:  config.WebSocketOrigins,
+
+		replicaOf:    config.ReplicaOf,
+		promoteAfter: config.PromoteAfter,
 	}
 }
 
-// Start starts the MQ server.
+// SetValidation validates every frame against protocol and every published
+// payload against payload, rejecting those that do not match with a
+// validation error naming the offending fields. Either may be nil. It is meant
+// for debugging producers and must be called before Start.
+func (s *Server) SetValidation(protocol, payload *schema.Schema) {
+	s.protocolSchema = protocol
+	s.payloadSchema = payload
+}
+
+// SetLogging logs each request at debug level, registers the mq.frames
+// toggle, which dumps every frame read from and written to clients, and
+// serves the level and toggles at /admin/logging to callers holding an
+// admin token of admin. It must be called before Start.
+func (s *Server) SetLogging(logs *logging.Runtime, admin *auth.Authenticator) {
+	s.logs = logs
+	s.admin = admin
+	s.frames = logs.Toggle("mq.frames", "Log every frame read from and written to clients")
+}
+
+// SetSupport serves src's support report, with the queue statistics added,
+// at /admin/support to callers holding an admin token of the authenticator
+// given to SetLogging. It must be called before Start.
+func (s *Server) SetSupport(src *support.Source) {
+	src.AddStats("queue", func() any { return s.stats() })
+	s.support = src
+}
+
+// Start starts the MQ server, as a standby replicating its primary when
+// ReplicaOf is set.
 func (s *Server) Start() error {
+	if s.replicaOf != "" {
+		r, err := newReplicator(s.replicaOf, s.promoteAfter)
+		if err != nil {
+			return err
+		}
+		s.replica = r
+		// Refuse publishes before the queue's own probes start
+		s.queue.SetStandby(true)
+	}
+
 	// Start the queue
 	if err := s.queue.Start(s.ctx); err != nil {
 		return fmt.Errorf("failed to start queue: %w", err)
@@ -95,6 +226,25 @@ func (s *Server) Start() error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/stats", s.handleStats)
+	mux.HandleFunc("/version", buildinfo.Handler("mq-server"))
+	mux.Handle("/ws", s.webSocketHandler())
+	if s.logs != nil {
+		mux.Handle("/admin/logging", s.admin.Require(auth.RoleAdmin)(http.HandlerFunc(s.handleLogging)))
+	}
+	if s.support != nil {
+		admin := s.admin
+		if admin == nil {
+			admin = auth.New("")
+		}
+		mux.Handle("/admin/support", admin.Require(auth.RoleAdmin)(s.support.Handler()))
+	}
+	if s.replica != nil {
+		admin := s.admin
+		if admin == nil {
+			admin = auth.New("")
+		}
+		mux.Handle("/admin/promote", admin.Require(auth.RoleAdmin)(http.HandlerFunc(s.handlePromote)))
+	}
 
 	s.httpServer = &http.Server{
 		Addr:    s.httpAddr,
@@ -114,9 +264,25 @@ func (s *Server) Start() error {
 	s.wg.Add(1)
 	go s.acceptLoop()
 
+	if r := s.replica; r != nil {
+		var ctx context.Context
+		ctx, r.stop = context.WithCancel(s.ctx)
+		s.wg.Add(1)
+		go s.replicate(ctx, r)
+	}
+
 	return nil
 }
 
+// TCPAddr returns the address the server accepts clients on, which tells
+// callers the port chosen when TCPPort is 0. It is nil before Start.
+func (s *Server) TCPAddr() net.Addr {
+	if s.tcpListener == nil {
+		return nil
+	}
+	return s.tcpListener.Addr()
+}
+
 // Stop gracefully stops the MQ server.
 func (s *Server) Stop(ctx context.Context) error {
 	s.cancel()
@@ -126,8 +292,9 @@ func (s *Server) Stop(ctx context.Context) error {
 		s.tcpListener.Close()
 	}
 
-	// Close all client connections
+	// Close all client connections, refusing any that races in
 	s.clientsMu.Lock()
+	s.stopping = true
 	for conn := range s.clients {
 		conn.Close()
 	}
@@ -138,8 +305,10 @@ func (s *Server) Stop(ctx context.Context) error {
 		s.httpServer.Shutdown(ctx)
 	}
 
-	// Stop queue
-	s.queue.Shutdown(ctx)
+	// Stop queue, syncing the write-ahead log
+	if err := s.queue.Shutdown(ctx); err != nil {
+		s.logger.Printf("Queue shutdown: %v", err)
+	}
 
 	// Wait for goroutines
 	done := make(chan struct{})
@@ -176,29 +345,66 @@ func (s *Server) acceptLoop() {
 			continue
 		}
 
-		s.clientsMu.Lock()
-		s.clients[conn] = &clientState{
-			conn: conn,
+		if _, ok := s.addClient(conn); !ok {
+			conn.Close()
+			return
 		}
-		s.clientsMu.Unlock()
-
-		s.wg.Add(1)
 		go s.handleClient(conn)
 	}
 }
 
+// addClient registers a new client connection, counting its handler in
+// wg; the caller calls wg.Done when it returns. Once Stop has begun no
+// client is added, so none outlives the wait for handlers, and ok is false.
+func (s *Server) addClient(conn net.Conn) (client *clientState, ok bool) {
+	client = &clientState{
+		conn:          conn,
+		subscriptions: make(map[string]bool),
+		watches:       make(map[string]context.CancelFunc),
+		publish:       s.limiter.newConn(),
+	}
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if s.stopping {
+		return nil, false
+	}
+	s.clients[conn] = client
+	s.wg.Add(1)
+	return client, true
+}
+
+// dropClient closes a client connection and releases what it held.
+func (s *Server) dropClient(conn net.Conn) {
+	s.clientsMu.Lock()
+	client := s.clients[conn]
+	delete(s.clients, conn)
+	s.clientsMu.Unlock()
+	if client != nil {
+		client.mu.Lock()
+		for _, stop := range client.watches {
+			stop()
+		}
+		// A consumer that vanished without unsubscribing must not hold its
+		// subscriber IDs, or it could never subscribe again after a restart
+		for subscriberID := range client.subscriptions {
+			s.queue.Release(subscriberID)
+		}
+		client.mu.Unlock()
+	}
+	conn.Close()
+}
+
 // handleClient handles a single client connection.
 func (s *Server) handleClient(conn net.Conn) {
 	defer s.wg.Done()
-	defer func() {
-		s.clientsMu.Lock()
-		delete(s.clients, conn)
-		s.clientsMu.Unlock()
-		conn.Close()
-	}()
+	defer s.dropClient(conn)
 
 	s.logger.Printf("Client connected: %s", conn.RemoteAddr())
 
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
+
 	header := make([]byte, 4)
 	for {
 		select {
@@ -211,8 +417,12 @@ func (s *Server) handleClient(conn net.Conn) {
 		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 
 		// Read message length
-		_, err := io.ReadFull(conn, header)
+		n, err := io.ReadFull(conn, header)
 		if err != nil {
+			// Subscribers and stats watchers legitimately go quiet; only idle clients time out
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && n == 0 && s.hasActiveStreams(conn) {
+				continue
+			}
 			if err != io.EOF && s.ctx.Err() == nil {
 				s.logger.Printf("Client read error: %v", err)
 			}
@@ -220,7 +430,7 @@ func (s *Server) handleClient(conn net.Conn) {
 		}
 
 		length := uint32(header[0])<<24 | uint32(header[1])<<16 | uint32(header[2])<<8 | uint32(header[3])
-		if length > 10*1024*1024 { // 10MB max
+		if length > maxFrameBytes {
 			s.logger.Printf("Message too large: %d bytes", length)
 			continue
 		}
@@ -233,18 +443,56 @@ func (s *Server) handleClient(conn net.Conn) {
 			return
 		}
 
-		var msg ProtocolMessage
-		if err := json.Unmarshal(data, &msg); err != nil {
-			s.logger.Printf("Invalid message: %v", err)
-			continue
+		s.handleFrame(conn, client, data)
+	}
+}
+
+// handleFrame decodes a frame read from a client and handles its message.
+func (s *Server) handleFrame(conn net.Conn, client *clientState, data []byte) {
+	binaryWire := client != nil && client.binaryWire.Load()
+	var msg ProtocolMessage
+	if err := decodeFrame(data, &msg, binaryWire); err != nil {
+		s.logger.Printf("Invalid message: %v", err)
+		return
+	}
+	if s.frames.Enabled() {
+		if binaryWire {
+			data, _ = json.Marshal(&msg)
 		}
+		s.frames.Printf("Frame from %s: %s", conn.RemoteAddr(), frameDump(data))
+	}
+	// Binary frames have no JSON to hold to the schema
+	if s.protocolSchema != nil && !binaryWire {
+		if err := s.protocolSchema.Validate(data); err != nil {
+			s.logger.Printf("Rejected frame from %s: %v", conn.RemoteAddr(), err)
+			s.sendError(conn, &msg, perrors.Validation(fmt.Errorf("frame does not match schema: %w", err)))
+			return
+		}
+	}
+
+	s.handleMessage(conn, &msg)
+}
+
+// hasActiveStreams reports whether the server is pushing data to the client.
+func (s *Server) hasActiveStreams(conn net.Conn) bool {
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
 
-		s.handleMessage(conn, &msg)
+	if client == nil {
+		return false
 	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return len(client.subscriptions) > 0 || len(client.watches) > 0
 }
 
 // handleMessage processes a client message.
 func (s *Server) handleMessage(conn net.Conn, msg *ProtocolMessage) {
+	s.logs.Debugf("%s from %s (request=%s subscriber=%s, %d byte payload)",
+		msg.Type, conn.RemoteAddr(), msg.RequestID, msg.SubscriberID, len(msg.Payload))
+	s.checkEpoch(conn, msg.Epoch)
+
 	switch msg.Type {
 	case MsgTypePublish:
 		s.handlePublish(conn, msg)
@@ -252,25 +500,92 @@ func (s *Server) handleMessage(conn net.Conn, msg *ProtocolMessage) {
 		s.handleSubscribe(conn, msg)
 	case MsgTypeUnsubscribe:
 		s.handleUnsubscribe(conn, msg)
+	case MsgTypePause, MsgTypeResume:
+		s.handlePause(conn, msg)
 	case MsgTypeAck:
 		s.handleAck(conn, msg)
 	case MsgTypeNack:
 		s.handleNack(conn, msg)
 	case MsgTypeGetStats:
 		s.handleGetStats(conn, msg)
+	case MsgTypeGetOffset:
+		s.handleGetOffset(conn, msg)
+	case MsgTypeSeekOffset:
+		s.handleSeekOffset(conn, msg)
+	case MsgTypeSeekTimestamp:
+		s.handleSeekTimestamp(conn, msg)
+	case MsgTypeCommit:
+		s.handleCommitOffset(conn, msg)
+	case MsgTypeFetch:
+		s.handleFetch(conn, msg)
+	case MsgTypeWatchStats:
+		s.handleWatchStats(conn, msg)
+	case MsgTypeUnwatch:
+		s.handleUnwatchStats(conn, msg)
+	case MsgTypeAcquireLease:
+		s.handleAcquireLease(conn, msg)
+	case MsgTypeReleaseLease:
+		s.handleReleaseLease(conn, msg)
+	case MsgTypeHeartbeat:
+		s.handleHeartbeat(conn, msg)
+	case MsgTypeComponents:
+		s.handleListComponents(conn, msg)
+	case MsgTypeReplicate:
+		s.handleReplicate(conn, msg)
+	case MsgTypeDeadLetters:
+		s.handleListDeadLetters(conn, msg)
+	case MsgTypeHello:
+		s.handleHello(conn, msg)
 	default:
-		s.sendError(conn, "unknown message type")
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "unknown message type"))
 	}
 }
 
 // handlePublish handles a publish message.
 func (s *Server) handlePublish(conn net.Conn, msg *ProtocolMessage) {
-	err := s.queue.Publish(s.ctx, msg.Payload)
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
+	if client != nil {
+		if err := s.checkPublish(client, len(msg.Payload)); err != nil {
+			s.sendError(conn, msg, err)
+			return
+		}
+	}
+	if s.limiter != nil {
+		var buckets *publishBuckets
+		if client != nil {
+			buckets = client.publish
+		}
+		if wait := s.limiter.allow(buckets, len(msg.Payload)); wait > 0 {
+			s.queue.throttled.Add(1)
+			s.sendThrottle(conn, msg, wait)
+			return
+		}
+	}
+	if s.payloadSchema != nil {
+		if err := s.payloadSchema.Validate(msg.Payload); err != nil {
+			s.logger.Printf("Rejected payload from %s: %v", conn.RemoteAddr(), err)
+			s.sendError(conn, msg, perrors.Validation(fmt.Errorf("payload does not match schema: %w", err)))
+			return
+		}
+	}
+	offset, err := s.queue.append(s.ctx, msg.Payload, msg.Metadata)
 	if err != nil {
-		s.sendError(conn, err.Error())
+		s.sendError(conn, msg, err)
 		return
 	}
-	s.sendResponse(conn, true, "")
+	if client != nil {
+		client.usage.published(len(msg.Payload))
+	}
+	// Confirm with the assigned offset so publishers know how far the log
+	// holds their data
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Success:   true,
+		Offset:    offset,
+	})
 }
 
 // handleSubscribe handles a subscribe message.
@@ -280,7 +595,7 @@ func (s *Server) handleSubscribe(conn net.Conn, msg *ProtocolMessage) {
 	s.clientsMu.RUnlock()
 
 	if client == nil {
-		s.sendError(conn, "client not found")
+		s.sendError(conn, msg, perrors.New(perrors.KindNotFound, "client not found"))
 		return
 	}
 
@@ -289,35 +604,107 @@ func (s *Server) handleSubscribe(conn net.Conn, msg *ProtocolMessage) {
 		subscriberID = conn.RemoteAddr().String()
 	}
 
+	// An ID the connection already holds is not a new subscription
+	client.mu.Lock()
+	var err error
+	if !client.subscriptions[subscriberID] {
+		err = s.checkSubscriptionsLocked(client)
+	}
+	client.mu.Unlock()
+	if err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+
 	// Use the offset from the message, default to OffsetLatest for new messages only
 	startOffset := msg.Offset
 	if startOffset == 0 {
 		startOffset = OffsetLatest
 	}
 
+	filter, err := ParseFilter(msg.Filter)
+	if err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+
 	handler := func(ctx context.Context, queueMsg *Message) error {
 		// Forward message to client
-		response := &ProtocolMessage{
-			Type:      MsgTypeMessage,
-			MessageID: queueMsg.ID,
-			Offset:    queueMsg.Offset,
-			Payload:   queueMsg.Payload,
+		if err := s.sendToClient(conn, messageFrame(queueMsg)); err != nil {
+			return err
 		}
-		return s.sendToClient(conn, response)
+		client.usage.delivered(queueMsg)
+		return nil
+	}
+	batchHandler := func(ctx context.Context, queueMsgs []*Message) error {
+		frames := make([]*ProtocolMessage, len(queueMsgs))
+		for i, queueMsg := range queueMsgs {
+			frames[i] = messageFrame(queueMsg)
+		}
+		payload, err := encodeBatch(frames, client.binaryWire.Load())
+		if err != nil {
+			return err
+		}
+		if err := s.sendToClient(conn, &ProtocolMessage{Type: MsgTypeBatch, Payload: payload}); err != nil {
+			return err
+		}
+		client.usage.delivered(queueMsgs...)
+		return nil
 	}
 
-	err := s.queue.Subscribe(s.ctx, subscriberID, startOffset, handler)
+	// A client with workers handles no more than that many messages at once
+	maxUnacked := s.quotas.MaxInFlight
+	if msg.Workers > 0 {
+		if n := msg.Workers * max(msg.MaxBatch, 1); maxUnacked <= 0 || n < maxUnacked {
+			maxUnacked = n
+		}
+	}
+
+	// Clients ack each message they handle, so unacked ones are delivered again
+	opts := SubscribeOptions{
+		Filter:       filter,
+		Partitions:   msg.Partitions,
+		Group:        msg.Group,
+		Resume:       msg.Resume,
+		ManualCommit: msg.ManualCommit,
+		Paused:       msg.Paused,
+		Workers:      msg.Workers,
+		Acknowledge:  true,
+		MaxUnacked:   maxUnacked,
+		OnOverflow: func() {
+			// Evicted for falling too far behind; the client reconnects and resumes
+			s.logger.Printf("Disconnecting subscriber %s: over its pending byte budget", subscriberID)
+			conn.Close()
+		},
+	}
+	if msg.MaxBatch > 0 {
+		err = s.queue.SubscribeBatchWithOptions(s.ctx, subscriberID, startOffset, msg.MaxBatch, opts, batchHandler)
+	} else {
+		err = s.queue.SubscribeWithOptions(s.ctx, subscriberID, startOffset, opts, handler)
+	}
 	if err != nil {
-		s.sendError(conn, err.Error())
+		s.sendError(conn, msg, err)
 		return
 	}
 
 	client.mu.Lock()
 	client.subscriberID = subscriberID
-	client.subscribed = true
+	client.subscriptions[subscriberID] = true
 	client.mu.Unlock()
 
-	s.sendResponse(conn, true, "")
+	s.sendResponse(conn, msg, true, "")
+}
+
+// messageFrame returns the frame that pushes queueMsg to a subscriber.
+func messageFrame(queueMsg *Message) *ProtocolMessage {
+	return &ProtocolMessage{
+		Type:      MsgTypeMessage,
+		MessageID: queueMsg.ID,
+		Offset:    queueMsg.Offset,
+		Partition: queueMsg.Partition,
+		Payload:   queueMsg.Payload,
+		Metadata:  queueMsg.Metadata,
+	}
 }
 
 // handleUnsubscribe handles an unsubscribe message.
@@ -327,79 +714,522 @@ func (s *Server) handleUnsubscribe(conn net.Conn, msg *ProtocolMessage) {
 	s.clientsMu.RUnlock()
 
 	if client == nil {
-		s.sendError(conn, "client not found")
+		s.sendError(conn, msg, perrors.New(perrors.KindNotFound, "client not found"))
 		return
 	}
 
+	// The subscription named, if the connection holds it, or else the last
 	client.mu.Lock()
 	subscriberID := client.subscriberID
-	client.subscribed = false
-	client.mu.Unlock()
-
-	if subscriberID == "" {
+	if client.subscriptions[msg.SubscriberID] || subscriberID == "" {
 		subscriberID = msg.SubscriberID
 	}
+	delete(client.subscriptions, subscriberID)
+	client.mu.Unlock()
 
 	err := s.queue.Unsubscribe(subscriberID)
 	if err != nil {
-		s.sendError(conn, err.Error())
+		s.sendError(conn, msg, err)
 		return
 	}
 
-	s.sendResponse(conn, true, "")
+	s.sendResponse(conn, msg, true, "")
 }
 
-// handleAck handles an ack message.
+// handleAck acknowledges a message, so it is not delivered again.
 func (s *Server) handleAck(conn net.Conn, msg *ProtocolMessage) {
-	// Acknowledgment is handled automatically by the queue
-	s.sendResponse(conn, true, "")
+	if err := s.queue.Ack(s.ackSubscriber(conn, msg), msg.MessageID); err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	s.sendResponse(conn, msg, true, "")
 }
 
-// handleNack handles a nack message.
+// handleNack handles a nack message. The failed message is delivered again
+// after a backoff growing with each attempt, up to the queue's MaxRetries times. A nack naming
+// the subscriber and the offset of the failed message instead rewinds a
+// manually committing subscriber to it, so it and the messages after it
+// are delivered again.
 func (s *Server) handleNack(conn net.Conn, msg *ProtocolMessage) {
-	// Negative acknowledgment triggers retry in the queue
-	s.sendResponse(conn, true, "")
+	var offset Offset
+	if msg.SubscriberID != "" && json.Unmarshal(msg.Payload, &offset) == nil {
+		if err := s.queue.Rewind(msg.SubscriberID, offset); err != nil {
+			s.sendError(conn, msg, err)
+			return
+		}
+	}
+	if err := s.queue.Nack(s.ackSubscriber(conn, msg), msg.MessageID); err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	s.sendResponse(conn, msg, true, "")
+}
+
+// ackSubscriber returns the subscriber an ack or nack is for: the one it
+// names, or else the one the connection subscribed as.
+func (s *Server) ackSubscriber(conn net.Conn, msg *ProtocolMessage) string {
+	if msg.SubscriberID != "" {
+		return msg.SubscriberID
+	}
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
+	if client == nil {
+		return ""
+	}
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.subscriberID
+}
+
+// stats returns the queue's stats along with the connected clients'.
+func (s *Server) stats() QueueStats {
+	stats := s.queue.GetStats()
+	stats.Clients = s.Clients()
+	return stats
 }
 
 // handleGetStats handles a get stats message.
 func (s *Server) handleGetStats(conn net.Conn, msg *ProtocolMessage) {
-	stats := s.queue.GetStats()
+	stats := s.stats()
 	data, _ := json.Marshal(stats)
 
 	response := &ProtocolMessage{
-		Type:    MsgTypeResponse,
-		Payload: data,
-		Success: true,
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Payload:   data,
+		Success:   true,
 	}
 	s.sendToClient(conn, response)
 }
 
-// sendResponse sends a response to the client.
-func (s *Server) sendResponse(conn net.Conn, success bool, errorMsg string) {
+// handleGetOffset reports a subscriber's current and committed offsets.
+func (s *Server) handleGetOffset(conn net.Conn, msg *ProtocolMessage) {
+	info, err := s.queue.GetOffsetInfo(msg.SubscriberID)
+	if err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	data, _ := json.Marshal(info)
+
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Payload:   data,
+		Success:   true,
+	})
+}
+
+// handlePause pauses or resumes delivery to a subscriber.
+func (s *Server) handlePause(conn net.Conn, msg *ProtocolMessage) {
+	apply := s.queue.ResumeSubscriber
+	if msg.Type == MsgTypePause {
+		apply = s.queue.PauseSubscriber
+	}
+	if err := apply(msg.SubscriberID); err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	s.sendResponse(conn, msg, true, "")
+}
+
+// handleSeekOffset repositions an active subscriber.
+func (s *Server) handleSeekOffset(conn net.Conn, msg *ProtocolMessage) {
+	var offset Offset
+	if err := json.Unmarshal(msg.Payload, &offset); err != nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "seek_offset requires an offset payload"))
+		return
+	}
+	if offset == OffsetEarliest || offset == OffsetLatest {
+		offset = s.queue.resolveOffset(offset)
+	}
+
+	if err := s.queue.SetSubscriberOffset(msg.SubscriberID, offset); err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	s.sendResponse(conn, msg, true, "")
+}
+
+// handleSeekTimestamp moves a subscriber to the first message received at
+// or after a time, and returns the offset it moved to.
+func (s *Server) handleSeekTimestamp(conn net.Conn, msg *ProtocolMessage) {
+	var ts time.Time
+	if err := json.Unmarshal(msg.Payload, &ts); err != nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "seek_timestamp requires an RFC3339 timestamp payload"))
+		return
+	}
+
+	offset, err := s.queue.SeekToTimestamp(msg.SubscriberID, ts)
+	if err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Payload:   offsetPayload(offset),
+		Success:   true,
+	})
+}
+
+// handleCommitOffset records a subscriber's committed offset.
+func (s *Server) handleCommitOffset(conn net.Conn, msg *ProtocolMessage) {
+	var offset Offset
+	if err := json.Unmarshal(msg.Payload, &offset); err != nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "commit_offset requires an offset payload"))
+		return
+	}
+
+	if err := s.queue.CommitOffset(msg.SubscriberID, offset); err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	s.sendResponse(conn, msg, true, "")
+}
+
+// handleFetch returns the message at the requested offset.
+func (s *Server) handleFetch(conn net.Conn, msg *ProtocolMessage) {
+	var offset Offset
+	if err := json.Unmarshal(msg.Payload, &offset); err != nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "fetch requires an offset payload"))
+		return
+	}
+
+	fetched, err := s.queue.FetchMessage(offset)
+	if err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+	data, _ := json.Marshal(fetched)
+
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Payload:   data,
+		Success:   true,
+	})
+}
+
+// handleReplicate answers a standby with the messages from the requested
+// offset on, waiting up to the requested interval for one to be published
+// when there are none.
+func (s *Server) handleReplicate(conn net.Conn, msg *ProtocolMessage) {
+	var offset Offset
+	if err := json.Unmarshal(msg.Payload, &offset); err != nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "replicate requires an offset payload"))
+		return
+	}
+	maxBatch := msg.MaxBatch
+	if maxBatch <= 0 || maxBatch > replicaMaxMessages {
+		maxBatch = replicaMaxMessages
+	}
+	wait := min(time.Duration(msg.IntervalMs)*time.Millisecond, 5*time.Second)
+
+	// Wait off the read loop, so the connection's other requests are served
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ctx, cancel := context.WithTimeout(s.ctx, wait)
+		defer cancel()
+		batch, err := s.queue.ReplicaBatchFrom(ctx, offset, maxBatch)
+		if err != nil {
+			s.sendError(conn, msg, perrors.Transient(err))
+			return
+		}
+		data, err := json.Marshal(batch)
+		if err != nil {
+			s.sendError(conn, msg, err)
+			return
+		}
+		s.sendToClient(conn, &ProtocolMessage{
+			Type:      MsgTypeResponse,
+			RequestID: msg.RequestID,
+			Payload:   data,
+			Success:   true,
+		})
+	}()
+}
+
+// handleWatchStats starts pushing queue stats to the client every requested interval.
+func (s *Server) handleWatchStats(conn net.Conn, msg *ProtocolMessage) {
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
+
+	if client == nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindNotFound, "client not found"))
+		return
+	}
+	if msg.RequestID == "" {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "watch_stats requires a request_id"))
+		return
+	}
+
+	interval := time.Duration(msg.IntervalMs) * time.Millisecond
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+
+	client.mu.Lock()
+	if prev, exists := client.watches[msg.RequestID]; exists {
+		prev()
+	} else if err := s.checkSubscriptionsLocked(client); err != nil {
+		client.mu.Unlock()
+		s.sendError(conn, msg, err)
+		return
+	}
+	ctx, stop := context.WithCancel(s.ctx)
+	client.watches[msg.RequestID] = stop
+	client.mu.Unlock()
+
+	s.sendResponse(conn, msg, true, "")
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				data, err := json.Marshal(s.stats())
+				if err != nil {
+					continue
+				}
+				push := &ProtocolMessage{
+					Type:      MsgTypeStats,
+					RequestID: msg.RequestID,
+					Payload:   data,
+				}
+				if err := s.sendToClient(conn, push); err != nil {
+					stop()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// handleUnwatchStats stops a stats watch started by handleWatchStats.
+func (s *Server) handleUnwatchStats(conn net.Conn, msg *ProtocolMessage) {
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
+
+	if client == nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindNotFound, "client not found"))
+		return
+	}
+
+	client.mu.Lock()
+	stop, exists := client.watches[msg.RequestID]
+	delete(client.watches, msg.RequestID)
+	client.mu.Unlock()
+
+	if exists {
+		stop()
+	}
+	s.sendResponse(conn, msg, true, "")
+}
+
+// handleAcquireLease grants, renews or refuses a named lease.
+func (s *Server) handleAcquireLease(conn net.Conn, msg *ProtocolMessage) {
+	// Leases are not replicated, so a standby would elect a second leader
+	if s.queue.IsStandby() {
+		s.sendError(conn, msg, ErrStandby)
+		return
+	}
+	var req LeaseRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil || req.Name == "" || req.Holder == "" || req.TTLMs <= 0 {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "acquire_lease requires name, holder and ttl_ms"))
+		return
+	}
+
+	lease := s.leases.acquire(req.Name, req.Holder, time.Duration(req.TTLMs)*time.Millisecond, time.Now())
+	data, _ := json.Marshal(lease)
+
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Payload:   data,
+		Success:   true,
+	})
+}
+
+// handleReleaseLease frees a lease held by the requester.
+func (s *Server) handleReleaseLease(conn net.Conn, msg *ProtocolMessage) {
+	var req LeaseRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil || req.Name == "" || req.Holder == "" {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "release_lease requires name and holder"))
+		return
+	}
+
+	s.leases.release(req.Name, req.Holder)
+	s.sendResponse(conn, msg, true, "")
+}
+
+// handleHeartbeat records a component's heartbeat.
+func (s *Server) handleHeartbeat(conn net.Conn, msg *ProtocolMessage) {
+	var hb Heartbeat
+	if err := json.Unmarshal(msg.Payload, &hb); err != nil || hb.Component == "" || hb.ID == "" || hb.TTLMs <= 0 {
+		s.sendError(conn, msg, perrors.New(perrors.KindValidation, "heartbeat requires component, id and ttl_ms"))
+		return
+	}
+
+	hb.Addr = conn.RemoteAddr().String()
+	s.heartbeats.beat(hb, time.Now())
+	s.sendResponse(conn, msg, true, "")
+}
+
+// handleListComponents lists the server and the components it has heard
+// from.
+func (s *Server) handleListComponents(conn net.Conn, msg *ProtocolMessage) {
+	now := time.Now()
+	hostname, _ := os.Hostname()
+	self := Heartbeat{
+		Component:  ComponentMQServer,
+		ID:         s.tcpAddr,
+		Version:    buildinfo.Get(ComponentMQServer).Version,
+		Host:       hostname,
+		Attributes: map[string]string{"http_addr": s.httpAddr, "wal": strconv.FormatBool(s.queue.wal != nil)},
+		Addr:       s.tcpAddr,
+		LastSeen:   now,
+	}
+	data, _ := json.Marshal(append([]Heartbeat{self}, s.heartbeats.list(now)...))
+
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Payload:   data,
+		Success:   true,
+	})
+}
+
+// handleListDeadLetters lists the messages subscribers gave up on.
+func (s *Server) handleListDeadLetters(conn net.Conn, msg *ProtocolMessage) {
+	data, err := json.Marshal(s.queue.DeadLetters())
+	if err != nil {
+		s.sendError(conn, msg, err)
+		return
+	}
+
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Payload:   data,
+		Success:   true,
+	})
+}
+
+// handleHello switches the connection to the wire format the client asks
+// for, answering in the one it leaves. Clients send it before anything
+// else, so no other frame is in flight across the switch.
+func (s *Server) handleHello(conn net.Conn, msg *ProtocolMessage) {
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
+
+	if client == nil {
+		s.sendError(conn, msg, perrors.New(perrors.KindNotFound, "client not found"))
+		return
+	}
+	if msg.Format != WireJSON && msg.Format != WireBinary {
+		s.sendError(conn, msg, perrors.Validation(fmt.Errorf("unsupported wire format %q", msg.Format)))
+		return
+	}
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:      MsgTypeResponse,
+		RequestID: msg.RequestID,
+		Success:   true,
+		Format:    msg.Format,
+	})
+	client.binaryWire.Store(msg.Format == WireBinary)
+	s.logs.Debugf("%s speaks %s", conn.RemoteAddr(), msg.Format)
+}
+
+// sendResponse sends a response to the client, correlated with the request.
+func (s *Server) sendResponse(conn net.Conn, req *ProtocolMessage, success bool, errorMsg string) {
 	response := &ProtocolMessage{
-		Type:    MsgTypeResponse,
-		Success: success,
-		Error:   errorMsg,
+		Type:      MsgTypeResponse,
+		RequestID: req.RequestID,
+		Success:   success,
+		Error:     errorMsg,
 	}
 	s.sendToClient(conn, response)
 }
 
-// sendError sends an error response to the client.
-func (s *Server) sendError(conn net.Conn, errorMsg string) {
+// sendError sends an error response to the client, correlated with the request.
+// The error's kind is sent along so the client can decide whether to retry.
+// A standby's refusals are standby frames, telling clients with failovers to
+// look for the primary elsewhere.
+func (s *Server) sendError(conn net.Conn, req *ProtocolMessage, err error) {
+	msgType := MsgTypeError
+	if errors.Is(err, ErrStandby) {
+		msgType = MsgTypeStandby
+	}
 	response := &ProtocolMessage{
-		Type:  MsgTypeError,
-		Error: errorMsg,
+		Type:      msgType,
+		RequestID: req.RequestID,
+		Error:     err.Error(),
+		ErrorKind: perrors.KindOf(err).String(),
 	}
 	s.sendToClient(conn, response)
 }
 
-// sendToClient sends a message to a client.
+// sendThrottle tells a publisher it is over its rate limit and how long to
+// wait before retrying. Throttles are transient errors to clients that do
+// not know the throttle type.
+func (s *Server) sendThrottle(conn net.Conn, req *ProtocolMessage, wait time.Duration) {
+	s.sendToClient(conn, &ProtocolMessage{
+		Type:         MsgTypeThrottle,
+		RequestID:    req.RequestID,
+		Error:        fmt.Sprintf("publish rate limit exceeded, retry after %v", wait),
+		ErrorKind:    perrors.KindTransient.String(),
+		RetryAfterMs: (wait + time.Millisecond - 1).Milliseconds(),
+	})
+}
+
+// sendToClient sends a message to a client, in the wire format of its
+// connection. Replies carry the server's epoch.
 func (s *Server) sendToClient(conn net.Conn, msg *ProtocolMessage) error {
-	data, err := json.Marshal(msg)
+	switch msg.Type {
+	case MsgTypeResponse, MsgTypeError, MsgTypeThrottle, MsgTypeStandby:
+		msg.Epoch = s.queue.Epoch()
+	}
+	s.clientsMu.RLock()
+	client := s.clients[conn]
+	s.clientsMu.RUnlock()
+	binaryWire := false
+	if client != nil {
+		client.writeMu.Lock()
+		defer client.writeMu.Unlock()
+		binaryWire = client.binaryWire.Load()
+	}
+
+	data, err := encodeFrame(msg, binaryWire)
 	if err != nil {
 		return err
 	}
 
+	if s.frames.Enabled() {
+		dump := data
+		if binaryWire {
+			dump, _ = json.Marshal(msg)
+		}
+		s.frames.Printf("Frame to %s: %s", conn.RemoteAddr(), frameDump(dump))
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+	// A WebSocket message frames itself
+	if ws, ok := conn.(*wsConn); ok {
+		return ws.send(data, binaryWire)
+	}
+
 	length := uint32(len(data))
 	header := []byte{
 		byte(length >> 24),
@@ -407,9 +1237,6 @@ func (s *Server) sendToClient(conn net.Conn, msg *ProtocolMessage) error {
 		byte(length >> 8),
 		byte(length),
 	}
-
-	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-
 	if _, err := conn.Write(header); err != nil {
 		return err
 	}
@@ -424,17 +1251,77 @@ func (s *Server) sendToClient(conn net.Conn, msg *ProtocolMessage) error {
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	role := "primary"
+	switch {
+	case s.deposed.Load():
+		role = "deposed"
+	case s.queue.IsStandby():
+		role = "standby"
+	}
 	json.NewEncoder(w).Encode(map[string]string{
 		"status": "healthy",
+		"role":   role,
 	})
 }
 
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	stats := s.queue.GetStats()
+	stats := s.stats()
 	json.NewEncoder(w).Encode(stats)
 }
 
+// handleLogging serves the log level and debug toggles on GET and applies
+// a logging.Update on PUT.
+func (s *Server) handleLogging(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var update logging.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_request", "message": "Invalid JSON body: " + err.Error()})
+			return
+		}
+		if err := s.logs.Apply(update); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_request", "message": err.Error()})
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "method_not_allowed"})
+		return
+	}
+	json.NewEncoder(w).Encode(s.logs.State())
+}
+
+// handlePromote promotes a standby on POST and reports its replication.
+func (s *Server) handlePromote(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "method_not_allowed"})
+		return
+	}
+	s.Promote()
+	json.NewEncoder(w).Encode(s.queue.GetStats().Replication)
+}
+
+// maxFrameDump caps how much of a frame is logged; published batches can be
+// megabytes.
+const maxFrameDump = 4096
+
+// frameDump returns a frame for logging, truncated to maxFrameDump bytes.
+func frameDump(data []byte) string {
+	if len(data) <= maxFrameDump {
+		return string(data)
+	}
+	return fmt.Sprintf("%s... (%d bytes)", data[:maxFrameDump], len(data))
+}
+
 // GetQueue returns the underlying queue (for testing).
 func (s *Server) GetQueue() *InMemoryQueue {
 	return s.queue
@@ -1,12 +1,26 @@
 package mq
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/schema"
 )
 
 func TestDefaultServerConfig(t *testing.T) {
@@ -237,3 +251,537 @@ func TestQueueErrors(t *testing.T) {
 		}
 	}
 }
+
+// freePort returns a TCP port that is currently unused on the loopback interface.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// startTestServer starts an MQ server on free loopback ports and returns a connected client.
+func startTestServer(t *testing.T, queueCfg QueueConfig) (*Server, *Client) {
+	t.Helper()
+	cfg := ServerConfig{
+		TCPHost:  "127.0.0.1",
+		TCPPort:  freePort(t),
+		HTTPHost: "127.0.0.1",
+		HTTPPort: freePort(t),
+		Queue:    queueCfg,
+	}
+
+	server := NewServer(cfg, log.New(io.Discard, "", 0))
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	client := NewClient(ClientConfig{
+		Host:    "127.0.0.1",
+		Port:    cfg.TCPPort,
+		Timeout: 2 * time.Second,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	return server, client
+}
+
+func TestClientGetStats(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	server.GetQueue().Publish(ctx, []byte(`{"n":1}`))
+	server.GetQueue().Publish(ctx, []byte(`{"n":2}`))
+
+	stats, err := client.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if stats.TotalMessages != 2 {
+		t.Errorf("expected 2 total messages, got %d", stats.TotalMessages)
+	}
+}
+
+func TestClientWatchStats(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := client.WatchStats(ctx, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WatchStats failed: %v", err)
+	}
+
+	server.GetQueue().Publish(context.Background(), []byte(`{"n":1}`))
+
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case stats := <-updates:
+			if stats.TotalMessages == 1 {
+				cancel()
+				// Channel must close once the watch is cancelled
+				for range updates {
+				}
+				return
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for stats push")
+		}
+	}
+}
+
+func TestClientOffsetManagement(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+	q := server.GetQueue()
+
+	for i := 0; i < 5; i++ {
+		q.Publish(ctx, []byte(`{}`))
+	}
+
+	// Unknown subscriber
+	if _, err := client.GetOffset(ctx, "nobody"); !perrors.IsNotFound(err) {
+		t.Errorf("expected not-found error for unknown subscriber, got %v", err)
+	}
+
+	q.Subscribe(ctx, "sub-1", OffsetLatest, func(ctx context.Context, msg *Message) error { return nil })
+
+	if err := client.SeekOffset(ctx, "sub-1", 0); err != nil {
+		t.Fatalf("SeekOffset failed: %v", err)
+	}
+	if err := client.CommitOffset(ctx, "sub-1", 3); err != nil {
+		t.Fatalf("CommitOffset failed: %v", err)
+	}
+	if err := client.CommitOffset(ctx, "sub-1", 99); err == nil {
+		t.Error("expected commit beyond the log to fail")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	info, err := client.GetOffset(ctx, "sub-1")
+	if err != nil {
+		t.Fatalf("GetOffset failed: %v", err)
+	}
+	if !info.Active || info.Committed != 3 || info.Current != 5 {
+		t.Errorf("unexpected offset info: %+v", info)
+	}
+
+	// Committed offset survives unsubscribe
+	q.Unsubscribe("sub-1")
+	if off, ok := q.GetCommittedOffset("sub-1"); !ok || off != 3 {
+		t.Errorf("expected committed offset 3, got %d (ok=%v)", off, ok)
+	}
+}
+
+func TestClientSeekToTimestamp(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+	q := server.GetQueue()
+	sim := clock.NewSimulated(time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC))
+	q.SetClock(sim)
+
+	for i := 0; i < 5; i++ {
+		q.Publish(ctx, []byte(`{}`))
+		sim.Advance(time.Minute)
+	}
+	q.Subscribe(ctx, "sub-1", OffsetLatest, func(ctx context.Context, msg *Message) error { return nil })
+
+	offset, err := client.SeekToTimestamp(ctx, "sub-1", time.Date(2026, 10, 18, 14, 2, 30, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SeekToTimestamp failed: %v", err)
+	}
+	if offset != 3 {
+		t.Errorf("expected offset 3, got %d", offset)
+	}
+	if _, err := client.SeekToTimestamp(ctx, "nobody", time.Now()); !perrors.IsNotFound(err) {
+		t.Errorf("expected not-found error for unknown subscriber, got %v", err)
+	}
+}
+
+func TestClientNackRedelivers(t *testing.T) {
+	cfg := DefaultQueueConfig()
+	cfg.RetryDelay = 10 * time.Millisecond
+	cfg.MaxRetries = 1
+	server, client := startTestServer(t, cfg)
+	ctx := context.Background()
+	q := server.GetQueue()
+
+	var mu sync.Mutex
+	deliveries := make(map[Offset]int)
+	err := client.Subscribe(ctx, "sub-1", OffsetEarliest, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deliveries[msg.Offset]++
+		if msg.Offset == 1 {
+			return errors.New("always fails")
+		}
+		if msg.Offset == 0 && deliveries[0] == 1 {
+			return errors.New("fails once")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	q.Publish(ctx, []byte(`{}`))
+	q.Publish(ctx, []byte(`{}`))
+
+	// The failing message is abandoned after MaxRetries redeliveries
+	waitFor(t, func() bool {
+		stats, err := client.GetStats(ctx)
+		return err == nil && len(stats.Subscribers) == 1 && stats.Subscribers[0].Abandoned == 1 && stats.Subscribers[0].Unacked == 0
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	if deliveries[0] != 2 || deliveries[1] != 2 {
+		t.Errorf("expected both messages delivered twice, got %v", deliveries)
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := map[string]Offset{
+		"earliest":  OffsetEarliest,
+		"latest":    OffsetLatest,
+		"committed": OffsetCommitted,
+		"42":        42,
+	}
+	for in, want := range tests {
+		got, err := ParseOffset(in)
+		if err != nil || got != want {
+			t.Errorf("ParseOffset(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseOffset("-5"); err == nil {
+		t.Error("expected error for negative offset")
+	}
+}
+
+// startSilentServer accepts connections but never responds, so requests only
+// complete through their context or the client timeout.
+func startSilentServer(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestClientHonorsContextDeadline(t *testing.T) {
+	client := NewClient(ClientConfig{
+		Host:    "127.0.0.1",
+		Port:    startSilentServer(t),
+		Timeout: 10 * time.Second,
+	})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.Publish(ctx, []byte(`{}`))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("publish took %v, expected it to stop at the context deadline", elapsed)
+	}
+}
+
+func TestClientHonorsContextCancellation(t *testing.T) {
+	client := NewClient(ClientConfig{
+		Host:    "127.0.0.1",
+		Port:    startSilentServer(t),
+		Timeout: 10 * time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.ConnectContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelled connect, got %v", err)
+	}
+
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.GetStats(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelled request, got %v", err)
+	}
+	if err := client.Ack(ctx, "msg-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelled ack, got %v", err)
+	}
+}
+
+func TestClientPublishConfirmed(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := client.PublishWithMetadata(ctx, []byte(`{}`), map[string]string{MetaHostname: "host-1"}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	// Publish waits for the server, so the message is already in the log
+	if got := server.GetQueue().GetStats().TotalMessages; got != 1 {
+		t.Errorf("expected 1 message after confirmed publish, got %d", got)
+	}
+
+	// The confirmation carries the offset the message was assigned
+	for want := Offset(1); want <= 2; want++ {
+		offset, err := client.PublishConfirmed(ctx, []byte(`{}`), nil)
+		if err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+		if offset != want {
+			t.Errorf("expected offset %d, got %d", want, offset)
+		}
+	}
+}
+
+func TestClientFetch(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+
+	server.GetQueue().Publish(ctx, []byte(`{"n":1}`))
+	server.GetQueue().PublishWithMetadata(ctx, []byte(`{"n":2}`), map[string]string{MetaHostname: "host-1"})
+
+	msg, err := client.Fetch(ctx, 1)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if string(msg.Payload) != `{"n":2}` || msg.Offset != 1 || msg.Metadata[MetaHostname] != "host-1" {
+		t.Errorf("unexpected fetched message: offset=%d payload=%s metadata=%v", msg.Offset, msg.Payload, msg.Metadata)
+	}
+
+	_, err = client.Fetch(ctx, 5)
+	if !perrors.IsNotFound(err) {
+		t.Errorf("expected not-found for offset past the log, got %v", err)
+	}
+}
+
+func TestClientSubscribeWithFilter(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	for _, host := range []string{"mtv5-a", "sjc2-a", "mtv5-b"} {
+		if err := client.PublishWithMetadata(ctx, []byte(`"`+host+`"`), map[string]string{MetaHostname: host}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	received := make(chan string, 3)
+	err := client.SubscribeWithFilter(ctx, "mtv5", OffsetEarliest, "hostname=mtv5-*", func(_ context.Context, msg *Message) error {
+		received <- msg.Metadata[MetaHostname]
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	// Only the matching messages cross the wire
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case host := <-received:
+			got[host] = true
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for messages, got %v", got)
+		}
+	}
+	if !got["mtv5-a"] || !got["mtv5-b"] {
+		t.Errorf("expected only mtv5 hosts, got %v", got)
+	}
+	waitFor(t, func() bool { return subscriberInfo(server.GetQueue(), "mtv5").Filtered == 1 })
+
+	if err := client.SubscribeWithFilter(ctx, "bad", OffsetEarliest, "hostname", nil); err == nil {
+		t.Error("expected an invalid filter to be rejected")
+	}
+}
+
+func TestServerValidation(t *testing.T) {
+	cfg := ServerConfig{
+		TCPHost:  "127.0.0.1",
+		TCPPort:  freePort(t),
+		HTTPHost: "127.0.0.1",
+		HTTPPort: freePort(t),
+		Queue:    DefaultQueueConfig(),
+	}
+	type payload struct {
+		Source string `json:"source"`
+		Count  int    `json:"count"`
+	}
+	server := NewServer(cfg, log.New(io.Discard, "", 0))
+	server.SetValidation(schema.Generate(ProtocolMessage{}, ""), schema.Generate(payload{}, ""))
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	client := NewClient(ClientConfig{Host: "127.0.0.1", Port: cfg.TCPPort, Timeout: 2 * time.Second})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := client.Publish(ctx, []byte(`{"source": "python", "count": 3}`)); err != nil {
+		t.Fatalf("valid payload rejected: %v", err)
+	}
+
+	err := client.Publish(ctx, []byte(`{"source": "python", "count": "3"}`))
+	if !perrors.IsValidation(err) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "$.count") {
+		t.Errorf("error does not name the field: %v", err)
+	}
+	if got := server.GetQueue().GetStats().TotalMessages; got != 1 {
+		t.Errorf("expected only the valid payload in the log, got %d messages", got)
+	}
+}
+
+func TestServerReleasesSubscriberOnDisconnect(t *testing.T) {
+	server, client := startTestServer(t, DefaultQueueConfig())
+	ctx := context.Background()
+	noop := func(ctx context.Context, msg *Message) error { return nil }
+
+	if err := client.Subscribe(ctx, "collector-0", OffsetEarliest, noop); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	// Vanish without unsubscribing, as a crashed consumer would
+	client.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := server.GetQueue().GetSubscriberOffset("collector-0"); errors.Is(err, ErrSubscriberNotFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscription outlived its connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	restarted := NewClient(ClientConfig{Host: "127.0.0.1", Port: server.TCPAddr().(*net.TCPAddr).Port, Timeout: 2 * time.Second})
+	if err := restarted.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer restarted.Close()
+	if err := restarted.Subscribe(ctx, "collector-0", OffsetCommitted, noop); err != nil {
+		t.Errorf("restarted consumer could not reuse its subscriber ID: %v", err)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to read while the server logs to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServerLoggingAdmin(t *testing.T) {
+	const token = "0123456789abcdef"
+	cfg := ServerConfig{
+		TCPHost:  "127.0.0.1",
+		TCPPort:  freePort(t),
+		HTTPHost: "127.0.0.1",
+		HTTPPort: freePort(t),
+		Queue:    DefaultQueueConfig(),
+	}
+	var out syncBuffer
+	logger := log.New(&out, "", 0)
+	logs := logging.New(logger, logging.LevelInfo)
+	server := NewServer(cfg, logger)
+	server.SetLogging(logs, auth.New(token))
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/admin/logging", cfg.HTTPPort)
+	call := func(method, bearer, body string) (int, logging.State) {
+		t.Helper()
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		var resp *http.Response
+		var err error
+		for i := 0; i < 50; i++ {
+			if resp, err = http.DefaultClient.Do(req); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond) // the HTTP listener starts asynchronously
+		}
+		if err != nil {
+			t.Fatalf("%s failed: %v", method, err)
+		}
+		defer resp.Body.Close()
+		var state logging.State
+		json.NewDecoder(resp.Body).Decode(&state)
+		return resp.StatusCode, state
+	}
+
+	if status, _ := call(http.MethodGet, "", ""); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", status)
+	}
+	if status, _ := call(http.MethodPut, token, `{"level": "verbose"}`); status != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown level, got %d", status)
+	}
+	status, state := call(http.MethodPut, token, `{"toggles": {"mq.frames": true}}`)
+	if status != http.StatusOK || !state.Toggles["mq.frames"].Enabled || state.Level != "info" {
+		t.Fatalf("enabling frame dumps: %d %+v", status, state)
+	}
+
+	client := NewClient(ClientConfig{Host: "127.0.0.1", Port: cfg.TCPPort, Timeout: 2 * time.Second})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Close()
+	if err := client.Publish(context.Background(), []byte(`{"source": "frame-dump"}`)); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	logged := out.String()
+	for _, want := range []string{"Debug toggle mq.frames enabled", "DEBUG Frame from", "frame-dump", "DEBUG Frame to"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("log is missing %q:\n%s", want, logged)
+		}
+	}
+}
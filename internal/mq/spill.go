@@ -0,0 +1,210 @@
+package mq
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// pagedSegments is how many spilled segments are kept paged back in, so a
+// lagging subscriber reading through one reads it from disk once.
+const pagedSegments = 2
+
+// SpillStats reports the messages of a persistent queue's log held on disk
+// only, because the log outgrew QueueConfig.MemoryBytes.
+type SpillStats struct {
+	MemoryBytes     int64 `json:"memory_bytes"`     // Budget for messages held in memory
+	ResidentBytes   int64 `json:"resident_bytes"`   // Size of the messages held in memory
+	SpilledMessages int64 `json:"spilled_messages"` // Retained messages whose payloads are on disk only
+	PageIns         int64 `json:"page_ins"`         // Spilled segments read back for lagging readers
+
+	// PageErrors counts failed reads of spilled segments, and PageError is
+	// why the last failed. Readers stay at a message that could not be read
+	// back and try again after RetryDelay.
+	PageErrors int64  `json:"page_errors,omitempty"`
+	PageError  string `json:"page_error,omitempty"`
+}
+
+// spill tracks the oldest messages of the log whose payloads were dropped
+// from memory, the segments of the write-ahead log standing in for them,
+// and the segments paged back in for lagging readers.
+type spill struct {
+	to    Offset // Messages before to are spilled; guarded by logMu
+	bytes int64  // Size of the payloads spilled; guarded by logMu
+
+	mu         sync.Mutex
+	pages      []page // Most recently read last
+	pageIns    int64
+	pageErrors int64
+	err        error
+}
+
+// page is a spilled segment read back from disk.
+type page struct {
+	base     Offset
+	messages []*Message
+}
+
+// spilledCopy returns m without its payload, for a log holding the payload
+// on disk only.
+func (m *Message) spilledCopy() *Message {
+	c := *m
+	c.Payload = nil
+	c.spilled = len(m.Payload)
+	c.onDisk = true
+	return &c
+}
+
+// spillLocked drops the payloads of the oldest whole segments from memory
+// until the rest of the log fits in MemoryBytes. The segment being
+// appended to always stays in memory, keeping delivery to subscribers
+// that keep up off the disk. The caller holds logMu.
+func (q *InMemoryQueue) spillLocked() {
+	if q.config.MemoryBytes <= 0 || q.wal == nil {
+		return
+	}
+	from := max(q.spill.to, q.base)
+	for q.logBytes-q.spill.bytes > q.config.MemoryBytes {
+		_, end, ok := q.wal.segmentOf(from)
+		if !ok {
+			return
+		}
+		for offset := from; offset < end; offset++ {
+			idx := int(offset - q.base)
+			q.spill.bytes += int64(len(q.log[idx].Payload))
+			q.log[idx] = q.log[idx].spilledCopy()
+		}
+		q.spill.to, from = end, end
+	}
+}
+
+// unspillLocked forgets the spilled messages among the n oldest, which are
+// leaving the log, and the segments paged in that only they were in. The
+// caller holds logMu.
+func (q *InMemoryQueue) unspillLocked(n int) {
+	for _, msg := range q.log[:n] {
+		q.spill.bytes -= int64(msg.spilled)
+	}
+	base := q.base + Offset(n)
+	q.spill.mu.Lock()
+	defer q.spill.mu.Unlock()
+	q.spill.pages = slices.DeleteFunc(q.spill.pages, func(p page) bool {
+		return p.base+Offset(len(p.messages)) <= base
+	})
+}
+
+// pageIn returns msg, or when it stands in for a spilled message, the
+// message read back from its segment on disk. The segment is read without
+// logMu held, so publishes and deliveries from memory carry on meanwhile.
+// It returns ErrOffsetOutOfRange when retention trimmed the message during
+// the read, and otherwise why the segment could not be read back; callers
+// leave the message where it is and try again rather than pass over it.
+func (q *InMemoryQueue) pageIn(msg *Message) (*Message, error) {
+	if !msg.onDisk {
+		return msg, nil
+	}
+	if paged := q.paged(msg.Offset); paged != nil {
+		return paged, nil
+	}
+
+	base, _, ok := q.wal.segmentOf(msg.Offset)
+	var messages []*Message
+	err := fmt.Errorf("no segment holds offset %d", msg.Offset)
+	if ok {
+		messages, _, _, err = q.wal.readSegment(base, base)
+		if n := int(msg.Offset - base); err == nil && (n >= len(messages) || messages[n].ID != msg.ID) {
+			err = fmt.Errorf("segment %s does not hold message %s at offset %d", q.wal.segmentName(base), msg.ID, msg.Offset)
+		}
+	}
+	if err != nil {
+		if msg.Offset < q.GetOldestOffset() {
+			return nil, fmt.Errorf("%w: offset %d was trimmed while it was read back", ErrOffsetOutOfRange, msg.Offset)
+		}
+		q.spill.mu.Lock()
+		q.spill.err = err
+		q.spill.pageErrors++
+		q.spill.mu.Unlock()
+		return nil, err
+	}
+
+	q.spill.mu.Lock()
+	defer q.spill.mu.Unlock()
+	q.spill.pageIns++
+	q.spill.pages = appendCapped(q.spill.pages, page{base: base, messages: messages}, pagedSegments)
+	return messages[msg.Offset-base], nil
+}
+
+// paged returns the message at offset from the segments paged in, or nil
+// if none holds it.
+func (q *InMemoryQueue) paged(offset Offset) *Message {
+	q.spill.mu.Lock()
+	defer q.spill.mu.Unlock()
+	for i, p := range q.spill.pages {
+		if n := int(offset - p.base); n >= 0 && n < len(p.messages) {
+			q.spill.pages = append(slices.Delete(q.spill.pages, i, i+1), p)
+			return p.messages[n]
+		}
+	}
+	return nil
+}
+
+// pageInAll pages msgs in, in order, replacing them in place. It stops at
+// the first that cannot be, returning those before it and why.
+func (q *InMemoryQueue) pageInAll(msgs []*Message) ([]*Message, error) {
+	for i, msg := range msgs {
+		paged, err := q.pageIn(msg)
+		if err != nil {
+			return msgs[:i], err
+		}
+		msgs[i] = paged
+	}
+	return msgs, nil
+}
+
+// retryPageIn wakes sub after RetryDelay to read a spilled message again,
+// once its segment could not be read back. Until then the subscriber stays
+// at the message rather than passing over it.
+func (q *InMemoryQueue) retryPageIn(sub *subscriber) {
+	delay := q.config.RetryDelay
+	if delay <= 0 {
+		delay = time.Second
+	}
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		if !q.sleep(delay) {
+			return
+		}
+		// A removed subscriber's notify channel is closed
+		q.subMu.RLock()
+		defer q.subMu.RUnlock()
+		if q.subscribers[sub.id] == sub {
+			select {
+			case sub.notify <- struct{}{}:
+			default:
+			}
+		}
+	}()
+}
+
+// spillStatsLocked reports what was spilled, or nil when the queue does not
+// spill. The caller holds logMu.
+func (q *InMemoryQueue) spillStatsLocked() *SpillStats {
+	if q.config.MemoryBytes <= 0 || q.wal == nil {
+		return nil
+	}
+	stats := &SpillStats{
+		MemoryBytes:     q.config.MemoryBytes,
+		ResidentBytes:   q.logBytes - q.spill.bytes,
+		SpilledMessages: int64(max(q.spill.to-q.base, 0)),
+	}
+	q.spill.mu.Lock()
+	defer q.spill.mu.Unlock()
+	stats.PageIns = q.spill.pageIns
+	stats.PageErrors = q.spill.pageErrors
+	if q.spill.err != nil {
+		stats.PageError = q.spill.err.Error()
+	}
+	return stats
+}
@@ -0,0 +1,142 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// openSpilling opens a persistent queue of one-message segments that holds
+// about memoryBytes of them in memory.
+func openSpilling(t *testing.T, memoryBytes int64) *InMemoryQueue {
+	t.Helper()
+	cfg := DefaultQueueConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.FsyncPolicy = FsyncAlways
+	cfg.SegmentBytes = 1
+	cfg.MemoryBytes = memoryBytes
+	q := NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Shutdown(context.Background()) })
+	return q
+}
+
+func TestSpilledMessagesArePagedBackIn(t *testing.T) {
+	q := openSpilling(t, 18)
+	publishN(t, q, 10)
+
+	// Three 6-byte payloads stay in memory
+	stats := q.GetStats()
+	if s := stats.Spill; s == nil || s.SpilledMessages != 7 || s.ResidentBytes != 18 || stats.RetainedBytes != 60 {
+		t.Fatalf("expected 7 messages spilled, got %+v", s)
+	}
+	q.logMu.RLock()
+	spilled, resident := q.log[6].Payload, q.log[7].Payload
+	q.logMu.RUnlock()
+	if spilled != nil || string(resident) != "msg-07" {
+		t.Fatalf("expected only the oldest payloads dropped from memory, got %q and %q", spilled, resident)
+	}
+
+	// A lagging subscriber is delivered them from disk
+	payloads := payloadCollector(q, "collector", OffsetEarliest, SubscribeOptions{})
+	waitFor(t, func() bool { return len(payloads()) == 10 })
+	want := make([]string, 10)
+	for i := range want {
+		want[i] = fmt.Sprintf("msg-%02d", i)
+	}
+	if got := payloads(); !slices.Equal(got, want) {
+		t.Errorf("expected every message in order, got %v", got)
+	}
+	if s := q.GetStats().Spill; s.PageIns != 7 {
+		t.Errorf("expected each spilled segment paged in once, got %+v", s)
+	}
+	if msg, err := q.FetchMessage(2); err != nil || string(msg.Payload) != "msg-02" {
+		t.Errorf("expected a spilled message to fetch, got %v, %v", msg, err)
+	}
+
+	// Trimming spilled messages forgets them
+	q.config.RetentionMessages = 5
+	q.trim(q.clock.Now())
+	if s := q.GetStats().Spill; s.SpilledMessages != 2 || s.ResidentBytes != 18 {
+		t.Errorf("expected 2 spilled messages left, got %+v", s)
+	}
+}
+
+func TestUnreadableSpilledSegmentHoldsReaders(t *testing.T) {
+	q := openSpilling(t, 6)
+	q.config.RetryDelay = 10 * time.Millisecond
+	publishN(t, q, 3)
+	path := q.wal.segmentPath(1)
+	if err := os.Rename(path, path+".away"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The subscriber waits at the message it cannot read rather than skip it
+	payloads := payloadCollector(q, "collector", OffsetEarliest, SubscribeOptions{})
+	waitFor(t, func() bool { return q.GetStats().Spill.PageErrors >= 2 })
+	if got, info := payloads(), subscriberInfo(q, "collector"); !slices.Equal(got, []string{"msg-00"}) || info.CurrentOffset != 1 || info.Corrupt != 0 {
+		t.Fatalf("expected delivery held at offset 1, got %v, %+v", got, info)
+	}
+	if s := q.GetStats().Spill; s.PageError == "" {
+		t.Errorf("expected the page error reported, got %+v", s)
+	}
+	if _, err := q.FetchMessage(1); perrors.KindOf(err) != perrors.KindTransient {
+		t.Errorf("expected a transient error fetching the unreadable message, got %v", err)
+	}
+
+	// Once the segment reads again, nothing was lost
+	if err := os.Rename(path+".away", path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(payloads()) == 3 })
+	if got := payloads(); !slices.Equal(got, []string{"msg-00", "msg-01", "msg-02"}) {
+		t.Errorf("expected every message in order, got %v", got)
+	}
+	if letters := q.DeadLetters(); len(letters) != 0 {
+		t.Errorf("expected nothing dead-lettered, got %+v", letters)
+	}
+}
+
+func TestSpilledEmptyPayloadIsPagedBackIn(t *testing.T) {
+	q := openSpilling(t, 6)
+	ctx := context.Background()
+	for _, payload := range []string{"", "msg-01", "msg-02"} {
+		if err := q.Publish(ctx, []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.logMu.RLock()
+	onDisk := q.log[0].onDisk
+	q.logMu.RUnlock()
+	if !onDisk {
+		t.Fatal("expected the empty message spilled")
+	}
+
+	var msgs []*Message
+	var mu sync.Mutex
+	q.Subscribe(ctx, "collector", OffsetEarliest, func(_ context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		msgs = append(msgs, msg)
+		return nil
+	})
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(msgs) == 3
+	})
+	if msgs[0].Offset != 0 || len(msgs[0].Payload) != 0 || string(msgs[1].Payload) != "msg-01" {
+		t.Errorf("expected the empty message delivered first, got %+v", msgs)
+	}
+	if s := q.GetStats().Spill; s.PageIns == 0 || s.PageErrors != 0 {
+		t.Errorf("expected it read back from disk, got %+v", s)
+	}
+}
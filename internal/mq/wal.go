@@ -0,0 +1,476 @@
+package mq
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/statestore"
+)
+
+// Fsync policies for the write-ahead log.
+const (
+	// FsyncAlways syncs every append before it is acknowledged
+	FsyncAlways = "always"
+
+	// FsyncInterval syncs appends every QueueConfig.FsyncInterval, so a
+	// crash loses at most that much of the log
+	FsyncInterval = "interval"
+
+	// FsyncNever leaves syncing to the operating system
+	FsyncNever = "never"
+)
+
+const (
+	// segmentExt names segment files: the offset of their first message,
+	// zero-padded so they sort in log order
+	segmentExt = ".log"
+
+	// offsetsFile holds the committed offsets, replaced atomically
+	offsetsFile = "offsets.json"
+
+	// recordHeader is a record's length and CRC-32 of its body
+	recordHeader = 8
+
+	// maxRecord bounds a record's body, so a corrupt length is not allocated
+	maxRecord = 256 << 20
+)
+
+// WALStats reports the write-ahead log of a persistent queue.
+type WALStats struct {
+	Dir         string `json:"dir"`
+	FsyncPolicy string `json:"fsync_policy"`
+	Segments    int    `json:"segments"`
+	SizeBytes   int64  `json:"size_bytes"`
+
+	// Recovered counts the messages read back when the queue started, and
+	// TruncatedBytes the torn write discarded from the end of the log
+	Recovered      int   `json:"recovered"`
+	TruncatedBytes int64 `json:"truncated_bytes"`
+
+	// TrimError is why the last segments trimmed by retention could not be
+	// deleted; they are retried on the next trim
+	TrimError string `json:"trim_error,omitempty"`
+
+	// WriteError is why the log stopped taking appends: a failed append
+	// could not be discarded, so publishes fail until the queue restarts
+	WriteError string `json:"write_error,omitempty"`
+}
+
+// segmentFile is the segment being appended to, an *os.File but for tests
+// of failing writes.
+type segmentFile interface {
+	io.Writer
+	Sync() error
+	Close() error
+	Truncate(size int64) error
+}
+
+// wal persists the message log as segment files of length-prefixed,
+// checksummed JSON records, and the committed offsets alongside them in a
+// state store over the same directory.
+type wal struct {
+	dir          string
+	policy       string
+	segmentBytes int64
+	state        *statestore.File
+
+	mu        sync.Mutex
+	file      segmentFile // the last segment, appended to
+	size      int64       // bytes in the last segment
+	bases     []Offset    // first offset of every segment, in order
+	total     int64       // bytes in all segments
+	dirty     bool        // appended since the last sync
+	failed    error       // why appends are refused
+	recovered int
+	truncated int64
+	trimErr   error
+}
+
+// openWAL opens the log in cfg.DataDir, creating the directory if needed,
+// and returns the messages and committed offsets recovered from it. The
+// log starts at the first segment's offset, as retention deletes the
+// segments before it. A record cut short or failing its checksum at the
+// end of the last segment is a write torn by a crash and is truncated;
+// anywhere else the log is corrupt and opening fails.
+func openWAL(cfg QueueConfig) (*wal, []*Message, map[string]Offset, error) {
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	w := &wal{dir: cfg.DataDir, policy: cfg.FsyncPolicy, segmentBytes: cfg.SegmentBytes}
+	if w.policy == "" {
+		w.policy = FsyncInterval
+	}
+	state, err := statestore.OpenFile(cfg.DataDir, w.policy != FsyncNever)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	w.state = state
+
+	bases, err := w.segmentBases()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var messages []*Message
+	var next Offset
+	if len(bases) > 0 {
+		next = bases[0]
+	}
+	for i, base := range bases {
+		last := i == len(bases)-1
+		if base != next {
+			return nil, nil, nil, fmt.Errorf("segment %s starts at offset %d, expected %d", w.segmentName(base), base, next)
+		}
+		read, good, size, err := w.readSegment(base, next)
+		if err != nil && !last {
+			return nil, nil, nil, err
+		}
+		messages = append(messages, read...)
+		next += Offset(len(read))
+		if err != nil {
+			// Discard the torn write so appends continue from the last good record
+			if err := os.Truncate(w.segmentPath(base), good); err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to truncate torn write: %w", err)
+			}
+			w.truncated = size - good
+		}
+		w.total += good
+		if last {
+			w.size = good
+		}
+	}
+	w.bases = bases
+	w.recovered = len(messages)
+
+	if len(bases) == 0 {
+		err = w.roll(0)
+	} else {
+		w.file, err = os.OpenFile(w.segmentPath(bases[len(bases)-1]), os.O_WRONLY|os.O_APPEND, 0o644)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	committed, err := w.readOffsets()
+	if err != nil {
+		w.file.Close()
+		return nil, nil, nil, err
+	}
+	return w, messages, committed, nil
+}
+
+// segmentBases returns the first offset of every segment, in order.
+func (w *wal) segmentBases() ([]Offset, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+	var bases []Offset
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("unexpected segment file %s", name)
+		}
+		bases = append(bases, Offset(n))
+	}
+	sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+	return bases, nil
+}
+
+func (w *wal) segmentName(base Offset) string {
+	return fmt.Sprintf("%020d%s", base, segmentExt)
+}
+
+func (w *wal) segmentPath(base Offset) string {
+	return filepath.Join(w.dir, w.segmentName(base))
+}
+
+// readSegment reads a segment's messages, which must start at next. It
+// returns the messages read and the size of the segment up to the last
+// good record; on error, the segment's whole size too.
+func (w *wal) readSegment(base, next Offset) (messages []*Message, good, size int64, err error) {
+	f, err := os.Open(w.segmentPath(base))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	size = info.Size()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, recordHeader)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			return messages, good, size, nil
+		} else if err != nil {
+			return messages, good, size, fmt.Errorf("segment %s: record at byte %d is cut short", w.segmentName(base), good)
+		}
+		length := binary.BigEndian.Uint32(header)
+		if length > maxRecord {
+			return messages, good, size, fmt.Errorf("segment %s: record at byte %d has invalid length %d", w.segmentName(base), good, length)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return messages, good, size, fmt.Errorf("segment %s: record at byte %d is cut short", w.segmentName(base), good)
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[4:]) {
+			return messages, good, size, fmt.Errorf("segment %s: record at byte %d fails its checksum", w.segmentName(base), good)
+		}
+
+		var msg Message
+		if err := msg.FromJSON(body); err != nil {
+			return messages, good, size, fmt.Errorf("segment %s: record at byte %d: %w", w.segmentName(base), good, err)
+		}
+		if msg.Offset != next {
+			return messages, good, size, fmt.Errorf("segment %s: record at byte %d has offset %d, expected %d", w.segmentName(base), good, msg.Offset, next)
+		}
+		if msg.Metadata == nil {
+			msg.Metadata = make(map[string]string)
+		}
+		messages = append(messages, &msg)
+		next++
+		good += int64(recordHeader + length)
+	}
+}
+
+// append writes messages to the log, starting a new segment first when the
+// current one is full, and syncs them under the always policy. An append
+// that fails leaves nothing in the log.
+func (w *wal) append(messages ...*Message) error {
+	var buf []byte
+	for _, msg := range messages {
+		body, err := msg.ToJSON()
+		if err != nil {
+			return err
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(body)))
+		buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(body))
+		buf = append(buf, body...)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.failed != nil {
+		return fmt.Errorf("the log is not writable: %w", w.failed)
+	}
+	if w.segmentBytes > 0 && w.size > 0 && w.size+int64(len(buf)) > w.segmentBytes {
+		if err := w.roll(messages[0].Offset); err != nil {
+			return err
+		}
+	}
+	before := w.size
+	n, err := w.file.Write(buf)
+	w.size += int64(n)
+	w.total += int64(n)
+	w.dirty = true
+	if err != nil {
+		err = fmt.Errorf("failed to write to the log: %w", err)
+	} else if w.policy == FsyncAlways {
+		err = w.syncLocked()
+	}
+	if err != nil {
+		w.discard(before)
+	}
+	return err
+}
+
+// discard truncates the last segment back to size, dropping what a failed
+// append wrote. The queue never took those messages, so left in place they
+// would precede the next append's records at the same offsets, and a
+// restart reading them as a torn write would drop every record after them.
+// If they cannot be dropped the log takes no more appends.
+func (w *wal) discard(size int64) {
+	if err := w.file.Truncate(size); err != nil {
+		w.failed = fmt.Errorf("failed to discard a failed append: %w", err)
+		return
+	}
+	w.total -= w.size - size
+	w.size = size
+}
+
+// roll closes the current segment, synced, and starts one at base.
+func (w *wal) roll(base Offset) error {
+	if w.file != nil {
+		if err := w.syncLocked(); err != nil {
+			return err
+		}
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(w.segmentPath(base), os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+	w.file = f
+	w.size = 0
+	w.bases = append(w.bases, base)
+	return syncDir(w.dir)
+}
+
+// restart starts a segment at base and deletes those before it, for a log
+// continuing from a later offset than it ends at.
+func (w *wal) restart(base Offset) error {
+	w.mu.Lock()
+	err := w.roll(base)
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	w.trim(base)
+	return nil
+}
+
+// segmentOf returns the first offset of the segment holding offset and of
+// the one after it; ok is false for the segment being appended to, and for
+// offsets before the log.
+func (w *wal) segmentOf(offset Offset) (base, end Offset, ok bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	i := sort.Search(len(w.bases), func(i int) bool { return w.bases[i] > offset })
+	if i == 0 || i == len(w.bases) {
+		return 0, 0, false
+	}
+	return w.bases[i-1], w.bases[i], true
+}
+
+// oldest returns the offset the log starts at.
+func (w *wal) oldest() Offset {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.bases[0]
+}
+
+// trim deletes the segments holding only messages before base, oldest
+// first, so the log on disk stays contiguous. The segment being appended
+// to is always kept. A failure is reported in stats and retried by the
+// next trim.
+func (w *wal) trim(base Offset) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.trimErr = nil
+	n := 0
+	for ; n < len(w.bases)-1 && w.bases[n+1] <= base; n++ {
+		path := w.segmentPath(w.bases[n])
+		info, err := os.Stat(path)
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			w.trimErr = fmt.Errorf("failed to delete segment: %w", err)
+			break
+		}
+		w.total -= info.Size()
+	}
+	w.bases = append(w.bases[:0], w.bases[n:]...)
+}
+
+// sync flushes appends since the last sync to disk.
+func (w *wal) sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.syncLocked()
+}
+
+func (w *wal) syncLocked() error {
+	if !w.dirty || w.policy == FsyncNever {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync the log: %w", err)
+	}
+	w.dirty = false
+	return nil
+}
+
+// runSync syncs every interval until stop is closed.
+func (w *wal) runSync(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.sync()
+		}
+	}
+}
+
+// close syncs and closes the current segment, whatever the policy.
+func (w *wal) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.file.Sync()
+	return errors.Join(err, w.file.Close())
+}
+
+// readOffsets loads the committed offsets, if any were saved.
+func (w *wal) readOffsets() (map[string]Offset, error) {
+	committed := make(map[string]Offset)
+	err := statestore.GetJSON(context.Background(), w.state, offsetsFile, &committed)
+	if errors.Is(err, statestore.ErrNotFound) {
+		return committed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return committed, nil
+}
+
+// saveOffsets replaces the committed offsets on disk.
+func (w *wal) saveOffsets(committed map[string]Offset) error {
+	return statestore.PutJSON(context.Background(), w.state, offsetsFile, committed)
+}
+
+// stats reports the log's size and what was recovered.
+func (w *wal) stats() *WALStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := &WALStats{
+		Dir:            w.dir,
+		FsyncPolicy:    w.policy,
+		Segments:       len(w.bases),
+		SizeBytes:      w.total,
+		Recovered:      w.recovered,
+		TruncatedBytes: w.truncated,
+	}
+	if w.trimErr != nil {
+		stats.TrimError = w.trimErr.Error()
+	}
+	if w.failed != nil {
+		stats.WriteError = w.failed.Error()
+	}
+	return stats
+}
+
+// syncDir syncs a directory, so files created in it survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
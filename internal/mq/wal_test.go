@@ -0,0 +1,208 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openQueue starts a queue persisting to dir.
+func openQueue(t *testing.T, dir string, segmentBytes int64) *InMemoryQueue {
+	t.Helper()
+	cfg := DefaultQueueConfig()
+	cfg.DataDir = dir
+	cfg.FsyncPolicy = FsyncAlways
+	cfg.SegmentBytes = segmentBytes
+	q := NewInMemoryQueue(cfg)
+	if err := q.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start queue: %v", err)
+	}
+	return q
+}
+
+func TestWALRecoversAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// Small segments, so the log spans several files
+	q := openQueue(t, dir, 256)
+	for i := 0; i < 5; i++ {
+		if err := q.PublishWithMetadata(ctx, []byte("single"), map[string]string{"type": "metrics"}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	if err := q.PublishBatch(ctx, [][]byte{[]byte("a"), []byte("b"), []byte("c")}); err != nil {
+		t.Fatalf("publish batch failed: %v", err)
+	}
+	if err := q.CommitOffset("collector", 6); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	q = openQueue(t, dir, 256)
+	defer q.Shutdown(ctx)
+
+	if q.Len() != 8 {
+		t.Fatalf("expected 8 messages recovered, got %d", q.Len())
+	}
+	msg, err := q.FetchMessage(7)
+	if err != nil || string(msg.Payload) != "c" || msg.Offset != 7 {
+		t.Fatalf("expected message c at offset 7, got %+v (%v)", msg, err)
+	}
+	if msg, _ := q.FetchMessage(0); msg.Metadata["type"] != "metrics" {
+		t.Errorf("expected metadata recovered, got %v", msg.Metadata)
+	}
+	if offset, ok := q.GetCommittedOffset("collector"); !ok || offset != 6 {
+		t.Errorf("expected committed offset 6 recovered, got %d (%v)", offset, ok)
+	}
+
+	stats := q.GetStats()
+	if stats.TotalMessages != 8 || stats.WAL == nil || stats.WAL.Recovered != 8 || stats.WAL.Segments < 2 {
+		t.Errorf("unexpected stats %+v (wal %+v)", stats, stats.WAL)
+	}
+
+	// Appends continue the recovered log
+	if err := q.Publish(ctx, []byte("next")); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if msg, _ := q.FetchMessage(8); string(msg.Payload) != "next" {
+		t.Errorf("expected the next message at offset 8, got %+v", msg)
+	}
+}
+
+func TestWALTruncatesTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q := openQueue(t, dir, 64<<20)
+	for i := 0; i < 3; i++ {
+		q.Publish(ctx, []byte("message"))
+	}
+	q.Shutdown(ctx)
+
+	// A crash mid-append leaves part of a record at the end of the log
+	segment := filepath.Join(dir, "00000000000000000000.log")
+	info, err := os.Stat(segment)
+	if err != nil {
+		t.Fatalf("expected the first segment: %v", err)
+	}
+	f, _ := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0o644)
+	f.Write([]byte{0, 0, 1, 0, 9, 9})
+	f.Close()
+
+	q = openQueue(t, dir, 64<<20)
+	defer q.Shutdown(ctx)
+	if q.Len() != 3 {
+		t.Fatalf("expected the 3 whole messages, got %d", q.Len())
+	}
+	if wal := q.GetStats().WAL; wal.TruncatedBytes != 6 || wal.SizeBytes != info.Size() {
+		t.Errorf("expected the torn write truncated, got %+v", wal)
+	}
+	q.Publish(ctx, []byte("after"))
+	if msg, _ := q.FetchMessage(3); msg == nil || string(msg.Payload) != "after" {
+		t.Errorf("expected appends after the last whole record, got %+v", msg)
+	}
+}
+
+func TestWALRejectsCorruptSegment(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q := openQueue(t, dir, 256)
+	for i := 0; i < 6; i++ {
+		q.Publish(ctx, []byte("message"))
+	}
+	q.Shutdown(ctx)
+
+	// Corruption before the last segment is not a torn write
+	segment := filepath.Join(dir, "00000000000000000000.log")
+	data, _ := os.ReadFile(segment)
+	data[len(data)-2] ^= 0xff
+	os.WriteFile(segment, data, 0o644)
+
+	cfg := DefaultQueueConfig()
+	cfg.DataDir = dir
+	cfg.SegmentBytes = 256
+	if err := NewInMemoryQueue(cfg).Start(ctx); err == nil {
+		t.Fatal("expected a corrupt segment to fail the start")
+	}
+}
+
+// failingFile writes half of the next write and fails it, as a disk filling
+// up mid-record would, and fails truncation when truncateErr is set.
+type failingFile struct {
+	segmentFile
+	failNext    bool
+	truncateErr error
+}
+
+func (f *failingFile) Write(b []byte) (int, error) {
+	if !f.failNext {
+		return f.segmentFile.Write(b)
+	}
+	f.failNext = false
+	n, _ := f.segmentFile.Write(b[:len(b)/2])
+	return n, errors.New("no space left on device")
+}
+
+func (f *failingFile) Truncate(size int64) error {
+	if f.truncateErr != nil {
+		return f.truncateErr
+	}
+	return f.segmentFile.Truncate(size)
+}
+
+func TestWALDiscardsFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q := openQueue(t, dir, 64<<20)
+	q.Publish(ctx, []byte("before"))
+	file := &failingFile{segmentFile: q.wal.file, failNext: true}
+	q.wal.file = file
+	if err := q.Publish(ctx, []byte("lost")); err == nil {
+		t.Fatal("expected the failed write reported")
+	}
+	if err := q.PublishBatch(ctx, [][]byte{[]byte("after"), []byte("last")}); err != nil {
+		t.Fatalf("expected appends to continue, got %v", err)
+	}
+	size := q.GetStats().WAL.SizeBytes
+	q.Shutdown(ctx)
+
+	// Every acknowledged message survives a restart, and nothing was torn
+	q = openQueue(t, dir, 64<<20)
+	defer q.Shutdown(ctx)
+	if q.Len() != 3 {
+		t.Fatalf("expected the 3 acknowledged messages, got %d", q.Len())
+	}
+	for offset, want := range []string{"before", "after", "last"} {
+		if msg, _ := q.FetchMessage(Offset(offset)); msg == nil || string(msg.Payload) != want {
+			t.Errorf("expected %s at offset %d, got %+v", want, offset, msg)
+		}
+	}
+	if wal := q.GetStats().WAL; wal.TruncatedBytes != 0 || wal.SizeBytes != size {
+		t.Errorf("expected no torn write and %d bytes, got %+v", size, wal)
+	}
+}
+
+func TestWALStopsWhenFailedWriteStays(t *testing.T) {
+	q := openQueue(t, t.TempDir(), 64<<20)
+	ctx := context.Background()
+	defer q.Shutdown(ctx)
+
+	q.wal.file = &failingFile{segmentFile: q.wal.file, failNext: true, truncateErr: errors.New("read-only file system")}
+	if err := q.Publish(ctx, []byte("lost")); err == nil {
+		t.Fatal("expected the failed write reported")
+	}
+	// The partial record cannot be dropped, so nothing may follow it
+	if err := q.Publish(ctx, []byte("next")); err == nil {
+		t.Fatal("expected appends refused")
+	}
+	if wal := q.GetStats().WAL; wal.WriteError == "" {
+		t.Errorf("expected the write error in stats, got %+v", wal)
+	}
+}
@@ -0,0 +1,170 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// WSClient is a Client that reaches the server over WebSocket, at /ws on
+// its HTTP listener, from networks that only let HTTP through. It speaks
+// the same protocol messages, one per WebSocket message: JSON as text
+// messages, or binary ones once a hello switched to WireBinary.
+type WSClient struct {
+	*Client
+}
+
+// NewWSClient creates a client of the WebSocket endpoint at url, e.g.
+// ws://mq:9001/ws, or wss:// behind a TLS-terminating proxy. Host and
+// Port of config are ignored, and its Failover lists endpoint URLs.
+func NewWSClient(url string, config ClientConfig) *WSClient {
+	c := NewClient(config)
+	c.addrs = append([]string{url}, config.Failover...)
+	c.transport = wsTransport{}
+	return &WSClient{Client: c}
+}
+
+// wsTransport frames with WebSocket messages.
+type wsTransport struct{}
+
+func (wsTransport) dial(ctx context.Context, addr string, timeout time.Duration) (net.Conn, error) {
+	location, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	// The handshake needs an origin; the endpoint's own does for a non-browser client
+	origin := *location
+	origin.Scheme, origin.Path, origin.RawQuery = "http", "/", ""
+	if location.Scheme == "wss" {
+		origin.Scheme = "https"
+	}
+	config, err := websocket.NewConfig(addr, origin.String())
+	if err != nil {
+		return nil, err
+	}
+	config.Dialer = &net.Dialer{Timeout: timeout}
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ws.MaxPayloadBytes = maxFrameBytes
+	return ws, nil
+}
+
+func (wsTransport) writeFrame(conn net.Conn, data []byte, binaryWire bool) error {
+	return sendWS(conn.(*websocket.Conn), data, binaryWire)
+}
+
+func (wsTransport) readFrame(conn net.Conn) ([]byte, error) {
+	var data []byte
+	err := websocket.Message.Receive(conn.(*websocket.Conn), &data)
+	if errors.Is(err, websocket.ErrFrameTooLarge) {
+		// The next Receive discards the rest of it
+		return nil, errFrameTooLarge
+	}
+	return data, err
+}
+
+// sendWS writes a frame as one WebSocket message: a binary message in
+// WireBinary, a text message in JSON, as browsers expect.
+func sendWS(ws *websocket.Conn, data []byte, binaryWire bool) error {
+	if binaryWire {
+		return websocket.Message.Send(ws, data)
+	}
+	return websocket.Message.Send(ws, string(data))
+}
+
+// wsConn is a client connected at /ws. The server keys and names it by the
+// peer's address, where websocket.Conn gives the Origin header instead.
+type wsConn struct {
+	*websocket.Conn
+	remote wsAddr
+}
+
+func (c *wsConn) RemoteAddr() net.Addr { return c.remote }
+
+// send writes a frame to the client.
+func (c *wsConn) send(data []byte, binaryWire bool) error {
+	return sendWS(c.Conn, data, binaryWire)
+}
+
+// wsAddr is the host:port of a WebSocket peer.
+type wsAddr string
+
+func (a wsAddr) Network() string { return "websocket" }
+func (a wsAddr) String() string  { return string(a) }
+
+// webSocketHandler serves the protocol over WebSocket to the origins
+// allowed by ServerConfig.WebSocketOrigins.
+func (s *Server) webSocketHandler() http.Handler {
+	return websocket.Server{Handshake: s.checkOrigin, Handler: s.handleWebSocket}
+}
+
+// checkOrigin refuses the handshake of a page from an origin not allowed,
+// so a page open in a browser on the server's network cannot reach it on
+// the browser's behalf. Browsers always send an Origin; clients that send
+// none are not pages and are let through, as they are on the TCP port.
+func (s *Server) checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil || origin == nil {
+		return err
+	}
+	config.Origin = origin
+	if len(s.wsOrigins) == 0 {
+		if strings.EqualFold(origin.Host, req.Host) {
+			return nil
+		}
+	} else {
+		for _, allowed := range s.wsOrigins {
+			if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin.Scheme+"://"+origin.Host) {
+				return nil
+			}
+		}
+	}
+	s.logger.Printf("Refused WebSocket client %s from origin %s", req.RemoteAddr, origin)
+	return fmt.Errorf("origin %s is not allowed", origin)
+}
+
+// handleWebSocket handles a client connected at /ws as handleClient does
+// one on TCP, reading a frame from each WebSocket message.
+func (s *Server) handleWebSocket(ws *websocket.Conn) {
+	ws.MaxPayloadBytes = maxFrameBytes
+	conn := &wsConn{Conn: ws, remote: wsAddr(ws.Request().RemoteAddr)}
+	client, ok := s.addClient(conn)
+	if !ok {
+		return // Stopping; the connection closes as the handler returns
+	}
+	defer s.wg.Done()
+	defer s.dropClient(conn)
+	s.logger.Printf("WebSocket client connected: %s", conn.RemoteAddr())
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		var data []byte
+		err := websocket.Message.Receive(ws, &data)
+		if errors.Is(err, websocket.ErrFrameTooLarge) {
+			// Passed over as on TCP; the next Receive discards the rest of it
+			s.logger.Printf("Message too large from WebSocket client %s", conn.RemoteAddr())
+			continue
+		}
+		if err != nil {
+			// Subscribers and stats watchers legitimately go quiet; only idle clients time out
+			if ne, ok := err.(net.Error); ok && ne.Timeout() && s.hasActiveStreams(conn) {
+				continue
+			}
+			if err != io.EOF && s.ctx.Err() == nil {
+				s.logger.Printf("WebSocket client %s read error: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		s.handleFrame(conn, client, data)
+	}
+}
@@ -0,0 +1,224 @@
+package mq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// connectWS connects a WSClient to server's /ws endpoint once its HTTP
+// listener is up.
+func connectWS(t *testing.T, server *Server, config ClientConfig) *WSClient {
+	t.Helper()
+	config.Timeout = 2 * time.Second
+	client := NewWSClient("ws://"+server.httpAddr+"/ws", config)
+	waitFor(t, func() bool { return client.Connect() == nil })
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestWSClientPublishesAndSubscribes(t *testing.T) {
+	server, tcp := startTestServer(t, DefaultQueueConfig())
+	ws := connectWS(t, server, ClientConfig{})
+	ctx := context.Background()
+
+	got := make(chan string, 2)
+	if err := ws.Subscribe(ctx, "dashboard", OffsetEarliest, func(_ context.Context, msg *Message) error {
+		got <- string(msg.Payload)
+		return nil
+	}); err != nil {
+		t.Fatalf("subscribe over WebSocket failed: %v", err)
+	}
+	if err := tcp.Publish(ctx, []byte(`{"from":"tcp"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.Publish(ctx, []byte(`{"from":"ws"}`)); err != nil {
+		t.Fatalf("publish over WebSocket failed: %v", err)
+	}
+	for _, want := range []string{`{"from":"tcp"}`, `{"from":"ws"}`} {
+		select {
+		case p := <-got:
+			if p != want {
+				t.Errorf("expected %s, got %s", want, p)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	stats, err := ws.GetStats(ctx)
+	if err != nil || len(stats.Clients) != 2 {
+		t.Fatalf("expected the TCP and WebSocket clients listed, got %+v (%v)", stats.Clients, err)
+	}
+}
+
+func TestWSClientBinaryWire(t *testing.T) {
+	server, _ := startTestServer(t, DefaultQueueConfig())
+	ws := connectWS(t, server, ClientConfig{WireFormat: WireBinary})
+	ctx := context.Background()
+
+	got := make(chan []byte, 1)
+	if err := ws.Subscribe(ctx, "raw", OffsetEarliest, func(_ context.Context, msg *Message) error {
+		got <- msg.Payload
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Binary WebSocket messages carry payloads that are not JSON
+	if err := ws.Publish(ctx, []byte{0, 1, 2, 0xff}); err != nil {
+		t.Fatalf("binary publish failed: %v", err)
+	}
+	select {
+	case p := <-got:
+		if string(p) != "\x00\x01\x02\xff" {
+			t.Errorf("unexpected payload %q", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the binary payload")
+	}
+}
+
+func TestWebSocketSpeaksJSONTextMessages(t *testing.T) {
+	server, _ := startTestServer(t, DefaultQueueConfig())
+
+	// As a browser would: one JSON text message per frame, no length prefix
+	var conn *websocket.Conn
+	waitFor(t, func() bool {
+		var err error
+		conn, err = websocket.Dial("ws://"+server.httpAddr+"/ws", "", "http://"+server.httpAddr)
+		return err == nil
+	})
+	defer conn.Close()
+	if err := websocket.Message.Send(conn, `{"type":"get_stats","request_id":"1"}`); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var text string
+	if err := websocket.Message.Receive(conn, &text); err != nil {
+		t.Fatalf("no reply: %v", err)
+	}
+	var resp ProtocolMessage
+	if err := json.Unmarshal([]byte(text), &resp); err != nil || resp.Type != MsgTypeResponse || !resp.Success || resp.RequestID != "1" {
+		t.Errorf("unexpected reply %s (%v)", text, err)
+	}
+}
+
+func TestWebSocketChecksOrigin(t *testing.T) {
+	dial := func(server *Server, origin string) error {
+		conn, err := websocket.Dial("ws://"+server.httpAddr+"/ws", "", origin)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	// By default only pages served from the server itself may connect
+	server, _ := startTestServer(t, DefaultQueueConfig())
+	waitFor(t, func() bool { return dial(server, "http://"+server.httpAddr) == nil })
+	if err := dial(server, "http://evil.example"); err == nil {
+		t.Error("expected a page from another origin refused")
+	}
+
+	cfg := ServerConfig{
+		TCPHost:          "127.0.0.1",
+		TCPPort:          freePort(t),
+		HTTPHost:         "127.0.0.1",
+		HTTPPort:         freePort(t),
+		Queue:            DefaultQueueConfig(),
+		WebSocketOrigins: []string{"https://dash.example/"},
+	}
+	allowing := NewServer(cfg, log.New(io.Discard, "", 0))
+	if err := allowing.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	t.Cleanup(func() { allowing.Stop(context.Background()) })
+	waitFor(t, func() bool { return dial(allowing, "https://dash.example") == nil })
+	for _, origin := range []string{"http://dash.example", "https://evil.example", "http://" + allowing.httpAddr} {
+		if err := dial(allowing, origin); err == nil {
+			t.Errorf("expected origin %s refused", origin)
+		}
+	}
+}
+
+func TestClientPassesOverOversizeFrames(t *testing.T) {
+	// reply answers a client's request with a frame over the limit, then
+	// the real response
+	reply := func(request []byte, send func([]byte) error) error {
+		var req ProtocolMessage
+		if err := json.Unmarshal(request, &req); err != nil {
+			return err
+		}
+		if err := send(bytes.Repeat([]byte(" "), maxFrameBytes+1)); err != nil {
+			return err
+		}
+		resp, _ := json.Marshal(&ProtocolMessage{Type: MsgTypeResponse, RequestID: req.RequestID, Success: true, Payload: json.RawMessage(`{"total_messages":7}`)})
+		return send(resp)
+	}
+
+	t.Run("tcp", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			request, err := tcpTransport{}.readFrame(conn)
+			if err != nil {
+				return
+			}
+			reply(request, func(data []byte) error { return tcpTransport{}.writeFrame(conn, data, false) })
+			io.Copy(io.Discard, conn)
+		}()
+
+		addr := listener.Addr().(*net.TCPAddr)
+		client := NewClient(ClientConfig{Host: "127.0.0.1", Port: addr.Port, Timeout: 2 * time.Second})
+		if err := client.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		if stats, err := client.GetStats(context.Background()); err != nil || stats.TotalMessages != 7 {
+			t.Fatalf("expected the response after the oversize frame, got %+v, %v", stats, err)
+		}
+		if !client.IsConnected() {
+			t.Error("expected the connection kept")
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+			var request []byte
+			if err := websocket.Message.Receive(ws, &request); err != nil {
+				return
+			}
+			reply(request, func(data []byte) error { return sendWS(ws, data, false) })
+			io.Copy(io.Discard, ws)
+		}))
+		defer server.Close()
+
+		client := NewWSClient(strings.Replace(server.URL, "http", "ws", 1), ClientConfig{Timeout: 2 * time.Second})
+		if err := client.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		if stats, err := client.GetStats(context.Background()); err != nil || stats.TotalMessages != 7 {
+			t.Fatalf("expected the response after the oversize frame, got %+v, %v", stats, err)
+		}
+		if !client.IsConnected() {
+			t.Error("expected the connection kept")
+		}
+	})
+}
@@ -0,0 +1,291 @@
+package mq
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Wire formats a client and server exchange frames in, negotiated by a
+// hello when the client connects.
+const (
+	// WireJSON encodes frames as JSON objects, payloads inlined as JSON. It
+	// is the default, and what a server that does not know hello speaks.
+	WireJSON = "json"
+
+	// WireBinary encodes frames as tagged binary fields, payloads as raw
+	// bytes, sparing the JSON encoding of every frame and payload
+	WireBinary = "binary"
+)
+
+// maxFrameBytes bounds a frame either side reads.
+const maxFrameBytes = 10 * 1024 * 1024
+
+// errFrameTooLarge is returned reading a frame over maxFrameBytes. The
+// frame is passed over, so the connection can carry on with the next.
+var errFrameTooLarge = errors.New("frame is too large")
+
+// Field tags of WireBinary frames. A field is its tag, the uvarint length
+// of its value and the value, and fields at their zero value are left out,
+// as they are from JSON. Readers skip tags they do not know, so fields can
+// be added the same way.
+const (
+	tagType byte = iota + 1
+	tagRequestID
+	tagSubscriberID
+	tagMessageID
+	tagOffset
+	tagPayload
+	tagError
+	tagErrorKind
+	tagSuccess
+	tagIntervalMs
+	tagMetadata
+	tagFilter
+	tagPartition
+	tagPartitions
+	tagGroup
+	tagResume
+	tagManualCommit
+	tagPaused
+	tagMaxBatch
+	tagRetryAfterMs
+	tagFormat
+	tagWorkers
+	tagEpoch
+)
+
+var errShortFrame = errors.New("binary frame is cut short")
+
+// encodeFrame encodes msg as a frame body, in WireBinary when binaryWire
+// is set and WireJSON otherwise.
+func encodeFrame(msg *ProtocolMessage, binaryWire bool) ([]byte, error) {
+	if !binaryWire {
+		return json.Marshal(msg)
+	}
+	return marshalBinary(msg), nil
+}
+
+// decodeFrame decodes a frame body encoded by encodeFrame.
+func decodeFrame(data []byte, msg *ProtocolMessage, binaryWire bool) error {
+	if !binaryWire {
+		return json.Unmarshal(data, msg)
+	}
+	return unmarshalBinary(data, msg)
+}
+
+// encodeBatch encodes the frames of a batch subscriber's messages as the
+// payload of a batch frame: a JSON array, or in WireBinary each frame
+// prefixed with its uvarint length.
+func encodeBatch(frames []*ProtocolMessage, binaryWire bool) ([]byte, error) {
+	if !binaryWire {
+		return json.Marshal(frames)
+	}
+	var payload []byte
+	for _, frame := range frames {
+		body := marshalBinary(frame)
+		payload = binary.AppendUvarint(payload, uint64(len(body)))
+		payload = append(payload, body...)
+	}
+	return payload, nil
+}
+
+// decodeBatch decodes a batch frame's payload encoded by encodeBatch.
+func decodeBatch(payload []byte, binaryWire bool) ([]*ProtocolMessage, error) {
+	var frames []*ProtocolMessage
+	if !binaryWire {
+		err := json.Unmarshal(payload, &frames)
+		return frames, err
+	}
+	for len(payload) > 0 {
+		body, rest, err := splitField(payload)
+		if err != nil {
+			return nil, err
+		}
+		frame := &ProtocolMessage{}
+		if err := unmarshalBinary(body, frame); err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+		payload = rest
+	}
+	return frames, nil
+}
+
+// marshalBinary encodes msg in WireBinary.
+func marshalBinary(msg *ProtocolMessage) []byte {
+	b := make([]byte, 0, 64+len(msg.Payload))
+	b = appendString(b, tagType, msg.Type)
+	b = appendString(b, tagRequestID, msg.RequestID)
+	b = appendString(b, tagSubscriberID, msg.SubscriberID)
+	b = appendString(b, tagMessageID, msg.MessageID)
+	b = appendInt(b, tagOffset, int64(msg.Offset))
+	if len(msg.Payload) > 0 {
+		b = appendField(b, tagPayload, msg.Payload)
+	}
+	b = appendString(b, tagError, msg.Error)
+	b = appendString(b, tagErrorKind, msg.ErrorKind)
+	b = appendBool(b, tagSuccess, msg.Success)
+	b = appendInt(b, tagIntervalMs, msg.IntervalMs)
+	if len(msg.Metadata) > 0 {
+		var value []byte
+		for k, v := range msg.Metadata {
+			value = binary.AppendUvarint(value, uint64(len(k)))
+			value = append(value, k...)
+			value = binary.AppendUvarint(value, uint64(len(v)))
+			value = append(value, v...)
+		}
+		b = appendField(b, tagMetadata, value)
+	}
+	b = appendString(b, tagFilter, msg.Filter)
+	b = appendInt(b, tagPartition, int64(msg.Partition))
+	if len(msg.Partitions) > 0 {
+		var value []byte
+		for _, p := range msg.Partitions {
+			value = binary.AppendVarint(value, int64(p))
+		}
+		b = appendField(b, tagPartitions, value)
+	}
+	b = appendString(b, tagGroup, msg.Group)
+	b = appendBool(b, tagResume, msg.Resume)
+	b = appendBool(b, tagManualCommit, msg.ManualCommit)
+	b = appendBool(b, tagPaused, msg.Paused)
+	b = appendInt(b, tagMaxBatch, int64(msg.MaxBatch))
+	b = appendInt(b, tagRetryAfterMs, msg.RetryAfterMs)
+	b = appendString(b, tagFormat, msg.Format)
+	b = appendInt(b, tagWorkers, int64(msg.Workers))
+	b = appendInt(b, tagEpoch, msg.Epoch)
+	return b
+}
+
+// unmarshalBinary decodes a frame encoded by marshalBinary. The payload
+// refers to data rather than copying it.
+func unmarshalBinary(data []byte, msg *ProtocolMessage) error {
+	for len(data) > 0 {
+		tag := data[0]
+		value, rest, err := splitField(data[1:])
+		if err != nil {
+			return err
+		}
+		data = rest
+
+		var n int64
+		switch tag {
+		case tagOffset, tagIntervalMs, tagPartition, tagMaxBatch, tagRetryAfterMs, tagWorkers, tagEpoch:
+			var k int
+			if n, k = binary.Varint(value); k <= 0 || k != len(value) {
+				return fmt.Errorf("binary frame field %d is not a varint", tag)
+			}
+		}
+
+		switch tag {
+		case tagType:
+			msg.Type = string(value)
+		case tagRequestID:
+			msg.RequestID = string(value)
+		case tagSubscriberID:
+			msg.SubscriberID = string(value)
+		case tagMessageID:
+			msg.MessageID = string(value)
+		case tagOffset:
+			msg.Offset = Offset(n)
+		case tagPayload:
+			msg.Payload = value
+		case tagError:
+			msg.Error = string(value)
+		case tagErrorKind:
+			msg.ErrorKind = string(value)
+		case tagSuccess:
+			msg.Success = true
+		case tagIntervalMs:
+			msg.IntervalMs = n
+		case tagMetadata:
+			msg.Metadata = make(map[string]string)
+			for len(value) > 0 {
+				k, rest, err := splitField(value)
+				if err != nil {
+					return err
+				}
+				v, rest, err := splitField(rest)
+				if err != nil {
+					return err
+				}
+				msg.Metadata[string(k)] = string(v)
+				value = rest
+			}
+		case tagFilter:
+			msg.Filter = string(value)
+		case tagPartition:
+			msg.Partition = int(n)
+		case tagPartitions:
+			for len(value) > 0 {
+				p, k := binary.Varint(value)
+				if k <= 0 {
+					return errShortFrame
+				}
+				msg.Partitions = append(msg.Partitions, int(p))
+				value = value[k:]
+			}
+		case tagGroup:
+			msg.Group = string(value)
+		case tagResume:
+			msg.Resume = true
+		case tagManualCommit:
+			msg.ManualCommit = true
+		case tagPaused:
+			msg.Paused = true
+		case tagMaxBatch:
+			msg.MaxBatch = int(n)
+		case tagRetryAfterMs:
+			msg.RetryAfterMs = n
+		case tagFormat:
+			msg.Format = string(value)
+		case tagWorkers:
+			msg.Workers = int(n)
+		case tagEpoch:
+			msg.Epoch = n
+		}
+	}
+	return nil
+}
+
+// splitField splits a uvarint length-prefixed value off the front of data.
+func splitField(data []byte) (value, rest []byte, err error) {
+	n, k := binary.Uvarint(data)
+	if k <= 0 || n > uint64(len(data)-k) {
+		return nil, nil, errShortFrame
+	}
+	end := k + int(n)
+	return data[k:end], data[end:], nil
+}
+
+func appendField(b []byte, tag byte, value []byte) []byte {
+	b = append(b, tag)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendString(b []byte, tag byte, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = append(b, tag)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendInt(b []byte, tag byte, n int64) []byte {
+	if n == 0 {
+		return b
+	}
+	var value [binary.MaxVarintLen64]byte
+	return appendField(b, tag, value[:binary.PutVarint(value[:], n)])
+}
+
+func appendBool(b []byte, tag byte, ok bool) []byte {
+	if !ok {
+		return b
+	}
+	return appendField(b, tag, nil)
+}
@@ -0,0 +1,187 @@
+package mq
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBinaryFrameRoundTrip(t *testing.T) {
+	msg := &ProtocolMessage{
+		Type:         MsgTypeSubscribe,
+		RequestID:    "req-1",
+		SubscriberID: "collector",
+		MessageID:    "msg-1",
+		Offset:       OffsetEarliest,
+		Payload:      []byte{0, 0xff, '{'},
+		Error:        "sink down",
+		ErrorKind:    "transient",
+		Success:      true,
+		IntervalMs:   1500,
+		Metadata:     map[string]string{"hostname": "gpu-01", "type": "metrics"},
+		Filter:       "hostname=gpu-*",
+		Partition:    3,
+		Partitions:   []int{0, 2},
+		Group:        "collectors",
+		Resume:       true,
+		ManualCommit: true,
+		Paused:       true,
+		MaxBatch:     100,
+		Workers:      4,
+		Epoch:        2,
+		RetryAfterMs: 250,
+		Format:       WireBinary,
+	}
+	data, err := encodeFrame(msg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A field from a newer peer is skipped
+	data = appendString(data, 0xf0, "unknown")
+
+	var got ProtocolMessage
+	if err := decodeFrame(data, &got, true); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, msg) {
+		t.Errorf("expected %+v, got %+v", msg, &got)
+	}
+
+	if err := decodeFrame(data[:len(data)-1], &got, true); err == nil {
+		t.Error("expected a frame cut short to fail")
+	}
+}
+
+func TestBinaryBatchRoundTrip(t *testing.T) {
+	frames := []*ProtocolMessage{
+		{Type: MsgTypeMessage, MessageID: "a", Offset: 1, Payload: []byte("raw")},
+		{Type: MsgTypeMessage, MessageID: "b", Offset: 2},
+	}
+	payload, err := encodeBatch(frames, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeBatch(payload, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, frames) {
+		t.Errorf("expected %+v, got %+v", frames, got)
+	}
+}
+
+func TestClientNegotiatesBinaryWire(t *testing.T) {
+	server, _ := startTestServer(t, DefaultQueueConfig())
+	port := server.TCPAddr().(*net.TCPAddr).Port
+	client := NewClient(ClientConfig{Host: "127.0.0.1", Port: port, Timeout: 2 * time.Second, WireFormat: WireBinary})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	ctx := context.Background()
+
+	// Payloads need not be JSON
+	raw := []byte{0, 1, 0xff}
+	if err := client.PublishWithMetadata(ctx, raw, map[string]string{"type": "raw"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var single [][]byte
+	var batches int
+	err := client.Subscribe(ctx, "single", OffsetEarliest, func(_ context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.Metadata["type"] == "raw" {
+			single = append(single, msg.Payload)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	batcher := NewClient(ClientConfig{Host: "127.0.0.1", Port: port, Timeout: 2 * time.Second, WireFormat: WireBinary})
+	if err := batcher.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { batcher.Close() })
+	err = batcher.SubscribeBatch(ctx, "batch", OffsetEarliest, 10, func(_ context.Context, msgs []*Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(msgs) == 1 && slices.Equal(msgs[0].Payload, raw) {
+			batches++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(single) == 1 && batches == 1
+	})
+	if !slices.Equal(single[0], raw) {
+		t.Errorf("expected the raw payload delivered, got %v", single[0])
+	}
+	if msg, err := client.Fetch(ctx, 0); err != nil || !slices.Equal(msg.Payload, raw) {
+		t.Errorf("expected the raw payload fetched, got %v, %v", msg, err)
+	}
+}
+
+func TestClientKeepsJSONWithoutHello(t *testing.T) {
+	// A server from before hello refuses it as an unknown message type
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	frames := make(chan ProtocolMessage, 2)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			data := make([]byte, binary.BigEndian.Uint32(header))
+			if _, err := io.ReadFull(conn, data); err != nil {
+				return
+			}
+			var msg ProtocolMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Errorf("expected a JSON frame, got %q", data)
+				return
+			}
+			frames <- msg
+			reply, _ := json.Marshal(ProtocolMessage{Type: MsgTypeError, RequestID: msg.RequestID, Error: "unknown message type"})
+			conn.Write(binary.BigEndian.AppendUint32(nil, uint32(len(reply))))
+			conn.Write(reply)
+		}
+	}()
+
+	client := NewClient(ClientConfig{Host: "127.0.0.1", Port: l.Addr().(*net.TCPAddr).Port, Timeout: 2 * time.Second, WireFormat: WireBinary})
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var serverErr *ServerError
+	if err := client.Publish(context.Background(), []byte(`{}`)); !errors.As(err, &serverErr) {
+		t.Errorf("expected the old server's reply read as JSON, got %v", err)
+	}
+	if hello, publish := <-frames, <-frames; hello.Type != MsgTypeHello || publish.Type != MsgTypePublish {
+		t.Errorf("expected a hello then a JSON publish, got %+v and %+v", hello, publish)
+	}
+}
@@ -0,0 +1,51 @@
+package mq
+
+// workerPool runs a subscriber's handler calls concurrently.
+type workerPool struct {
+	slots chan struct{} // Held by each running handler call
+
+	// inflight counts the handler calls running per offset, the first of
+	// a batch's; guarded by subMu
+	inflight map[Offset]int
+}
+
+func newWorkerPool(workers int) *workerPool {
+	return &workerPool{
+		slots:    make(chan struct{}, workers),
+		inflight: make(map[Offset]int),
+	}
+}
+
+// dispatch runs handle, a handler call for the message at offset. With
+// workers it waits for a free one and returns while handle runs there,
+// holding the subscriber's committed position at or before offset until it
+// returns; without, it runs handle in place.
+func (q *InMemoryQueue) dispatch(sub *subscriber, offset Offset, handle func()) {
+	w := sub.workers
+	if w == nil {
+		handle()
+		return
+	}
+	select {
+	case w.slots <- struct{}{}:
+	case <-q.ctx.Done():
+		return
+	}
+
+	q.subMu.Lock()
+	w.inflight[offset]++
+	q.subMu.Unlock()
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		handle()
+
+		q.subMu.Lock()
+		if w.inflight[offset]--; w.inflight[offset] == 0 {
+			delete(w.inflight, offset)
+		}
+		q.subMu.Unlock()
+		<-w.slots
+	}()
+}
@@ -0,0 +1,114 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// position returns the offset a subscriber would resume from.
+func position(q *InMemoryQueue, id string) Offset {
+	q.subMu.RLock()
+	defer q.subMu.RUnlock()
+	return q.subscribers[id].position()
+}
+
+func TestWorkersHandleMessagesConcurrently(t *testing.T) {
+	q := startRetaining(t, DefaultQueueConfig())
+	gates := make([]chan struct{}, 5)
+	for i := range gates {
+		gates[i] = make(chan struct{})
+	}
+	err := q.SubscribeWithOptions(context.Background(), "collector", OffsetEarliest, SubscribeOptions{Workers: 3}, func(_ context.Context, msg *Message) error {
+		<-gates[msg.Offset]
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishN(t, q, 5)
+	waitFor(t, func() bool { return subscriberInfo(q, "collector").InFlight == 3 })
+
+	// Later messages completing do not move the position past the first
+	close(gates[1])
+	close(gates[2])
+	waitFor(t, func() bool { return subscriberInfo(q, "collector").Delivered == 2 })
+	if pos := position(q, "collector"); pos != 0 {
+		t.Errorf("expected the position held at offset 0, got %d", pos)
+	}
+
+	// The freed workers take the next messages
+	waitFor(t, func() bool { return subscriberInfo(q, "collector").CurrentOffset == 5 })
+	close(gates[0])
+	waitFor(t, func() bool { return position(q, "collector") == 3 })
+
+	close(gates[3])
+	close(gates[4])
+	waitFor(t, func() bool { return position(q, "collector") == 5 })
+	if info := subscriberInfo(q, "collector"); info.InFlight != 0 || info.Delivered != 5 {
+		t.Errorf("expected all 5 handled, got %+v", info)
+	}
+}
+
+func TestClientWorkers(t *testing.T) {
+	for _, manualCommit := range []bool{false, true} {
+		t.Run(fmt.Sprintf("manual_commit=%v", manualCommit), func(t *testing.T) {
+			server, publisher := startTestServer(t, DefaultQueueConfig())
+			client := NewClient(ClientConfig{
+				Host:         "127.0.0.1",
+				Port:         server.TCPAddr().(*net.TCPAddr).Port,
+				Timeout:      2 * time.Second,
+				ManualCommit: manualCommit,
+				Workers:      2,
+			})
+			if err := client.Connect(); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { client.Close() })
+
+			release := make(chan struct{})
+			var running, most, handled atomic.Int64
+			err := client.Subscribe(context.Background(), "collector", OffsetEarliest, func(context.Context, *Message) error {
+				n := running.Add(1)
+				for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+				}
+				<-release
+				running.Add(-1)
+				handled.Add(1)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				if err := publisher.Publish(context.Background(), []byte(fmt.Sprintf(`{"n":%d}`, i))); err != nil {
+					t.Fatal(err)
+				}
+			}
+			waitFor(t, func() bool { return running.Load() == 2 })
+			if !manualCommit {
+				// The server holds the rest back until the workers ack
+				waitFor(t, func() bool { return subscriberInfo(server.queue, "collector").Paused })
+				if info := subscriberInfo(server.queue, "collector"); info.Unacked != 2 || info.Delivered != 2 {
+					t.Errorf("expected 2 messages outstanding, got %+v", info)
+				}
+			}
+
+			close(release)
+			waitFor(t, func() bool { return handled.Load() == 5 })
+			if m := most.Load(); m != 2 {
+				t.Errorf("expected at most 2 handlers at once, got %d", m)
+			}
+			if manualCommit {
+				if offset, _, err := client.CommitProcessed(context.Background(), "collector"); err != nil || offset != 5 {
+					t.Errorf("expected every message committed, got %d, %v", offset, err)
+				}
+			} else {
+				waitFor(t, func() bool { return subscriberInfo(server.queue, "collector").Unacked == 0 })
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package notify delivers pipeline events (GPU discovered or silent, alerts,
+// completed exports, collector lag) to configured webhooks, with retries,
+// HMAC signing and a log of recent deliveries.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// Headers set on every webhook request.
+const (
+	HeaderEvent     = "X-Pipeline-Event"
+	HeaderDelivery  = "X-Pipeline-Delivery"
+	HeaderTimestamp = "X-Pipeline-Timestamp"
+	HeaderSignature = "X-Pipeline-Signature"
+)
+
+// Delivery outcomes.
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Notifier raises pipeline events.
+type Notifier interface {
+	Notify(eventType string, data map[string]interface{})
+}
+
+// Delivery records one event's delivery to one webhook, after all retries.
+type Delivery struct {
+	ID         string    `json:"id"`
+	EventID    string    `json:"event_id"`
+	EventType  string    `json:"event_type"`
+	URL        string    `json:"url"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// Dispatcher queues events and delivers them to every configured webhook.
+// A nil *Dispatcher is valid and discards all events, so components can call
+// Notify unconditionally.
+type Dispatcher struct {
+	urls    []string
+	events  map[string]bool // nil means all event types
+	secret  string
+	timeout time.Duration
+	retry   retry.Policy
+	source  string
+	client  *http.Client
+	logger  *log.Logger
+
+	queue   chan *models.Event
+	dropped atomic.Int64
+
+	mu      sync.Mutex
+	log     []Delivery // ring buffer of recent deliveries
+	next    int
+	logSize int
+}
+
+// NewDispatcher creates a dispatcher for events raised by source. It returns
+// nil when no webhook URLs are configured.
+func NewDispatcher(cfg config.WebhookConfig, source string, logger *log.Logger) (*Dispatcher, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, nil
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	var events map[string]bool
+	if len(cfg.Events) > 0 {
+		events = make(map[string]bool, len(cfg.Events))
+		for _, e := range cfg.Events {
+			if !models.IsEventType(e) {
+				return nil, perrors.Validation(fmt.Errorf("unknown webhook event type %q (known: %v)", e, models.EventTypes))
+			}
+			events[e] = true
+		}
+	}
+
+	policy := retry.FromConfig("webhook-delivery", cfg.Retry)
+	policy.OnRetry = func(attempt int, err error, wait time.Duration) {
+		logger.Printf("Webhook attempt %d failed, retrying in %v: %v", attempt, wait, err)
+	}
+
+	return &Dispatcher{
+		urls:    cfg.URLs,
+		events:  events,
+		secret:  cfg.Secret,
+		timeout: cfg.Timeout,
+		retry:   policy,
+		source:  source,
+		client:  &http.Client{},
+		logger:  logger,
+		queue:   make(chan *models.Event, cfg.QueueSize),
+		log:     make([]Delivery, 0, cfg.LogSize),
+		logSize: cfg.LogSize,
+	}, nil
+}
+
+// Notify queues an event for delivery without blocking. Events of types the
+// dispatcher is not subscribed to are ignored; if the queue is full the event
+// is dropped and counted.
+func (d *Dispatcher) Notify(eventType string, data map[string]interface{}) {
+	if d == nil || (d.events != nil && !d.events[eventType]) {
+		return
+	}
+
+	event := &models.Event{
+		ID:     uuid.New().String(),
+		Type:   eventType,
+		Time:   time.Now().UTC(),
+		Source: d.source,
+		Data:   data,
+	}
+	select {
+	case d.queue <- event:
+	default:
+		if d.dropped.Add(1) == 1 {
+			d.logger.Printf("Webhook queue full, dropping %s events", eventType)
+		}
+	}
+}
+
+// Dropped returns how many events were discarded because the queue was full.
+func (d *Dispatcher) Dropped() int64 {
+	if d == nil {
+		return 0
+	}
+	return d.dropped.Load()
+}
+
+// Run delivers queued events until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.queue:
+			body, err := json.Marshal(event)
+			if err != nil {
+				d.logger.Printf("Could not encode %s event: %v", event.Type, err)
+				continue
+			}
+			for _, url := range d.urls {
+				d.deliver(ctx, url, event, body)
+			}
+		}
+	}
+}
+
+// deliver sends one event to one webhook, retrying transient failures, and
+// records the outcome.
+func (d *Dispatcher) deliver(ctx context.Context, url string, event *models.Event, body []byte) {
+	delivery := Delivery{
+		ID:        uuid.New().String(),
+		EventID:   event.ID,
+		EventType: event.Type,
+		URL:       url,
+		Status:    DeliveryDelivered,
+		Time:      time.Now().UTC(),
+	}
+
+	err := d.retry.Do(ctx, func(ctx context.Context) error {
+		delivery.Attempts++
+		attemptCtx, cancel := context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+
+		status, err := d.post(attemptCtx, url, delivery.ID, event.Type, body)
+		delivery.StatusCode = status
+		return err
+	})
+	delivery.DurationMs = float64(time.Since(delivery.Time)) / float64(time.Millisecond)
+	if err != nil {
+		delivery.Status = DeliveryFailed
+		delivery.Error = err.Error()
+		d.logger.Printf("Webhook delivery of %s event %s to %s failed after %d attempts: %v",
+			event.Type, event.ID, url, delivery.Attempts, err)
+	}
+	d.record(delivery)
+}
+
+// post makes a single signed delivery attempt and returns the response status.
+func (d *Dispatcher) post(ctx context.Context, url, deliveryID, eventType string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, perrors.Permanent(err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if d.secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, perrors.Transient(fmt.Errorf("webhook request failed: %w", err))
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return resp.StatusCode, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return resp.StatusCode, perrors.Transient(fmt.Errorf("webhook returned HTTP %d", resp.StatusCode))
+	default:
+		return resp.StatusCode, perrors.Permanent(fmt.Errorf("webhook returned HTTP %d", resp.StatusCode))
+	}
+}
+
+// Sign returns the signature header value for a payload: "sha256=" followed
+// by the hex HMAC-SHA256 of "<timestamp>.<body>" keyed by secret. Receivers
+// recompute it to verify the sender and reject stale timestamps to prevent replay.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// record appends a delivery to the log, overwriting the oldest when full.
+func (d *Dispatcher) record(delivery Delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.log) < d.logSize {
+		d.log = append(d.log, delivery)
+		return
+	}
+	d.log[d.next] = delivery
+	d.next = (d.next + 1) % d.logSize
+}
+
+// Deliveries returns the recent delivery log, newest first.
+func (d *Dispatcher) Deliveries() []Delivery {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]Delivery, 0, len(d.log))
+	for i := len(d.log) - 1; i >= 0; i-- {
+		out = append(out, d.log[(d.next+i)%len(d.log)])
+	}
+	return out
+}
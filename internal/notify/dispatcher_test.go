@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func testWebhookConfig(urls ...string) config.WebhookConfig {
+	return config.WebhookConfig{
+		URLs:      urls,
+		Secret:    "s3cret",
+		Timeout:   time.Second,
+		QueueSize: 10,
+		LogSize:   2,
+		Retry: config.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+			Multiplier:     1,
+		},
+	}
+}
+
+func newTestDispatcher(t *testing.T, cfg config.WebhookConfig) *Dispatcher {
+	t.Helper()
+	d, err := NewDispatcher(cfg, "collector-1", log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("NewDispatcher failed: %v", err)
+	}
+	return d
+}
+
+// waitForDeliveries polls the delivery log until it holds n entries.
+func waitForDeliveries(t *testing.T, d *Dispatcher, n int) []Delivery {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if deliveries := d.Deliveries(); len(deliveries) >= n {
+			return deliveries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d deliveries, got %d", n, len(d.Deliveries()))
+	return nil
+}
+
+func TestNewDispatcherDisabled(t *testing.T) {
+	d, err := NewDispatcher(config.WebhookConfig{}, "collector-1", nil)
+	if err != nil || d != nil {
+		t.Fatalf("expected nil dispatcher without URLs, got %v, %v", d, err)
+	}
+
+	// A nil dispatcher discards events
+	d.Notify(models.EventGPUSilent, nil)
+	if d.Deliveries() != nil || d.Dropped() != 0 {
+		t.Error("expected nil dispatcher to have no deliveries")
+	}
+}
+
+func TestNewDispatcherUnknownEvent(t *testing.T) {
+	cfg := testWebhookConfig("http://example.com")
+	cfg.Events = []string{"gpu.exploded"}
+	if _, err := NewDispatcher(cfg, "collector-1", nil); err == nil {
+		t.Error("expected error for unknown event type")
+	}
+}
+
+func TestDispatcherDeliversSignedEvents(t *testing.T) {
+	var got models.Event
+	var signature, timestamp, eventHeader string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+		signature = r.Header.Get(HeaderSignature)
+		timestamp = r.Header.Get(HeaderTimestamp)
+		eventHeader = r.Header.Get(HeaderEvent)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, testWebhookConfig(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.Notify(models.EventGPUDiscovered, map[string]interface{}{"uuid": "GPU-1"})
+	deliveries := waitForDeliveries(t, d, 1)
+
+	if deliveries[0].Status != DeliveryDelivered || deliveries[0].Attempts != 1 || deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("unexpected delivery %+v", deliveries[0])
+	}
+	if got.Type != models.EventGPUDiscovered || got.Source != "collector-1" || got.Data["uuid"] != "GPU-1" {
+		t.Errorf("unexpected event %+v", got)
+	}
+	if eventHeader != models.EventGPUDiscovered {
+		t.Errorf("expected %s header %q, got %q", HeaderEvent, models.EventGPUDiscovered, eventHeader)
+	}
+	if want := Sign("s3cret", timestamp, body); signature != want {
+		t.Errorf("expected signature %s, got %s", want, signature)
+	}
+}
+
+func TestDispatcherRetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, testWebhookConfig(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.Notify(models.EventGPUSilent, nil)
+	deliveries := waitForDeliveries(t, d, 1)
+
+	if deliveries[0].Status != DeliveryDelivered || deliveries[0].Attempts != 3 {
+		t.Errorf("expected delivery on third attempt, got %+v", deliveries[0])
+	}
+}
+
+func TestDispatcherDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, testWebhookConfig(srv.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Run(ctx)
+
+	d.Notify(models.EventGPUSilent, nil)
+	deliveries := waitForDeliveries(t, d, 1)
+
+	if deliveries[0].Status != DeliveryFailed || deliveries[0].StatusCode != http.StatusBadRequest || calls.Load() != 1 {
+		t.Errorf("expected one failed attempt, got %+v after %d calls", deliveries[0], calls.Load())
+	}
+}
+
+func TestDispatcherEventFilterAndQueue(t *testing.T) {
+	cfg := testWebhookConfig("http://example.com")
+	cfg.Events = []string{models.EventCollectorLag}
+	cfg.QueueSize = 1
+	d := newTestDispatcher(t, cfg)
+
+	// Not subscribed: ignored without using the queue
+	d.Notify(models.EventGPUSilent, nil)
+	// Subscribed: first fills the queue, second is dropped
+	d.Notify(models.EventCollectorLag, nil)
+	d.Notify(models.EventCollectorLag, nil)
+
+	if d.Dropped() != 1 {
+		t.Errorf("expected 1 dropped event, got %d", d.Dropped())
+	}
+}
+
+func TestDeliveryLogKeepsNewest(t *testing.T) {
+	d := newTestDispatcher(t, testWebhookConfig("http://example.com"))
+	for _, id := range []string{"a", "b", "c"} {
+		d.record(Delivery{ID: id})
+	}
+
+	deliveries := d.Deliveries()
+	if len(deliveries) != 2 || deliveries[0].ID != "c" || deliveries[1].ID != "b" {
+		t.Errorf("expected [c b], got %+v", deliveries)
+	}
+}
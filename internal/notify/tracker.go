@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// GPUTracker raises gpu.discovered and gpu.silent events from the telemetry
+// a component receives. It tracks arrival time rather than metric timestamps,
+// so replayed historical data still counts as the GPU reporting.
+type GPUTracker struct {
+	notifier     Notifier
+	silenceAfter time.Duration
+	warmUntil    time.Time
+
+	mu   sync.Mutex
+	gpus map[string]*trackedGPU
+}
+
+// trackedGPU is what the tracker remembers about a GPU.
+type trackedGPU struct {
+	hostname  string
+	gpuID     int
+	modelName string
+	lastSeen  time.Time
+	silent    bool
+}
+
+// NewGPUTracker creates a tracker started at now. GPUs first seen within
+// silenceAfter of starting are taken to be already known, so a restart does
+// not announce the whole fleet as newly discovered.
+func NewGPUTracker(notifier Notifier, silenceAfter time.Duration, now time.Time) *GPUTracker {
+	return &GPUTracker{
+		notifier:     notifier,
+		silenceAfter: silenceAfter,
+		warmUntil:    now.Add(silenceAfter),
+		gpus:         make(map[string]*trackedGPU),
+	}
+}
+
+// Observe records that the GPUs in metrics reported at now.
+func (t *GPUTracker) Observe(metrics []*models.GPUMetric, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, m := range metrics {
+		if gpu, ok := t.gpus[m.UUID]; ok {
+			gpu.lastSeen = now
+			gpu.silent = false
+			continue
+		}
+
+		t.gpus[m.UUID] = &trackedGPU{hostname: m.Hostname, gpuID: m.GPUID, modelName: m.ModelName, lastSeen: now}
+		if now.After(t.warmUntil) {
+			t.notifier.Notify(models.EventGPUDiscovered, map[string]interface{}{
+				"uuid":       m.UUID,
+				"hostname":   m.Hostname,
+				"gpu_id":     m.GPUID,
+				"model_name": m.ModelName,
+			})
+		}
+	}
+}
+
+// CheckSilent raises gpu.silent once for each GPU that has not reported
+// within silenceAfter of now. A GPU that reports again can go silent again.
+func (t *GPUTracker) CheckSilent(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for uuid, gpu := range t.gpus {
+		if gpu.silent || now.Sub(gpu.lastSeen) < t.silenceAfter {
+			continue
+		}
+		gpu.silent = true
+		t.notifier.Notify(models.EventGPUSilent, map[string]interface{}{
+			"uuid":       uuid,
+			"hostname":   gpu.hostname,
+			"gpu_id":     gpu.gpuID,
+			"model_name": gpu.modelName,
+			"last_seen":  gpu.lastSeen.UTC(),
+		})
+	}
+}
+
+// LagMonitor raises collector.lag when a consumer's lag rises to the
+// threshold and again when it falls back below it.
+type LagMonitor struct {
+	notifier  Notifier
+	threshold int64
+
+	mu       sync.Mutex
+	exceeded bool
+}
+
+// NewLagMonitor creates a monitor for the given lag threshold, in messages.
+func NewLagMonitor(notifier Notifier, threshold int64) *LagMonitor {
+	return &LagMonitor{notifier: notifier, threshold: threshold}
+}
+
+// Observe records the subscriber's current lag.
+func (m *LagMonitor) Observe(subscriber string, lag int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exceeded := lag >= m.threshold
+	if exceeded == m.exceeded {
+		return
+	}
+	m.exceeded = exceeded
+
+	state := "exceeded"
+	if !exceeded {
+		state = "recovered"
+	}
+	m.notifier.Notify(models.EventCollectorLag, map[string]interface{}{
+		"subscriber": subscriber,
+		"lag":        lag,
+		"threshold":  m.threshold,
+		"state":      state,
+	})
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// recordingNotifier records raised events.
+type recordingNotifier struct {
+	types []string
+	data  []map[string]interface{}
+}
+
+func (n *recordingNotifier) Notify(eventType string, data map[string]interface{}) {
+	n.types = append(n.types, eventType)
+	n.data = append(n.data, data)
+}
+
+func TestGPUTrackerDiscovery(t *testing.T) {
+	events := &recordingNotifier{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewGPUTracker(events, time.Minute, start)
+
+	// GPUs seen while warming up are assumed known
+	tracker.Observe([]*models.GPUMetric{{UUID: "GPU-1", Hostname: "host-001"}}, start.Add(time.Second))
+	if len(events.types) != 0 {
+		t.Fatalf("expected no events during warm-up, got %v", events.types)
+	}
+
+	later := start.Add(2 * time.Minute)
+	tracker.Observe([]*models.GPUMetric{
+		{UUID: "GPU-1", Hostname: "host-001"},
+		{UUID: "GPU-2", Hostname: "host-002", GPUID: 3},
+		{UUID: "GPU-2", Hostname: "host-002", GPUID: 3},
+	}, later)
+	if len(events.types) != 1 || events.types[0] != models.EventGPUDiscovered || events.data[0]["uuid"] != "GPU-2" {
+		t.Errorf("expected one gpu.discovered for GPU-2, got %v %v", events.types, events.data)
+	}
+}
+
+func TestGPUTrackerSilence(t *testing.T) {
+	events := &recordingNotifier{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewGPUTracker(events, time.Minute, start)
+	tracker.Observe([]*models.GPUMetric{{UUID: "GPU-1"}, {UUID: "GPU-2"}}, start)
+
+	tracker.Observe([]*models.GPUMetric{{UUID: "GPU-2"}}, start.Add(50*time.Second))
+	tracker.CheckSilent(start.Add(90 * time.Second))
+	if len(events.types) != 1 || events.types[0] != models.EventGPUSilent || events.data[0]["uuid"] != "GPU-1" {
+		t.Fatalf("expected gpu.silent for GPU-1, got %v %v", events.types, events.data)
+	}
+
+	// Already silent: not raised again
+	tracker.CheckSilent(start.Add(100 * time.Second))
+	if len(events.types) != 1 {
+		t.Errorf("expected silence to be reported once, got %v", events.types)
+	}
+
+	// Reporting again re-arms the check
+	tracker.Observe([]*models.GPUMetric{{UUID: "GPU-1"}}, start.Add(2*time.Minute))
+	tracker.CheckSilent(start.Add(4 * time.Minute))
+	if len(events.types) != 3 {
+		t.Errorf("expected both GPUs to go silent again, got %v", events.types)
+	}
+}
+
+func TestLagMonitor(t *testing.T) {
+	events := &recordingNotifier{}
+	monitor := NewLagMonitor(events, 100)
+
+	for _, lag := range []int64{10, 150, 200, 50, 20} {
+		monitor.Observe("collector-1", lag)
+	}
+
+	if len(events.types) != 2 {
+		t.Fatalf("expected exceeded and recovered events, got %v", events.data)
+	}
+	if events.data[0]["state"] != "exceeded" || events.data[0]["lag"] != int64(150) {
+		t.Errorf("unexpected first event %v", events.data[0])
+	}
+	if events.data[1]["state"] != "recovered" {
+		t.Errorf("unexpected second event %v", events.data[1])
+	}
+}
@@ -0,0 +1,143 @@
+package otlp
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Conversion is the result of mapping an export request to GPU metrics.
+type Conversion struct {
+	Metrics []models.GPUMetric
+
+	// Rejected counts the data points that could not be converted
+	Rejected int
+
+	// Reasons describes why points were rejected, one entry per reason
+	Reasons []string
+}
+
+// Reasons for rejecting a data point, in the order they are reported.
+const (
+	rejectNoUUID    = "data points without a GPU UUID attribute"
+	rejectNoValue   = "data points without a value"
+	rejectNonFinite = "data points with NaN or infinite values"
+	rejectHistogram = "histogram data points are not supported"
+	rejectExpHist   = "exponential histogram data points are not supported"
+	rejectSummary   = "summary data points are not supported"
+)
+
+var rejectOrder = []string{rejectNoUUID, rejectNoValue, rejectNonFinite, rejectHistogram, rejectExpHist, rejectSummary}
+
+// Convert maps the gauge and sum data points of req to GPU metrics.
+//
+// Resource attributes supply the identity shared by a node's metrics (host.name,
+// k8s.pod.name, ...) and data point attributes (UUID or gpu.uuid, gpu, ...)
+// override them per GPU; see models.GPUMetric.SetIdentity for the names. Data point
+// attributes that are not identity are kept as labels; other resource
+// attributes (service.name, os.type, ...) describe the exporter rather than
+// the GPU and are dropped. Points without a GPU UUID or a finite value, and
+// histogram and summary points, are rejected. Points without a timestamp
+// are stamped with now.
+func Convert(req *ExportRequest, now time.Time) Conversion {
+	var conv Conversion
+	rejected := make(map[string]int)
+	reject := func(reason string, n int) {
+		rejected[reason] += n
+		conv.Rejected += n
+	}
+
+	for _, rm := range req.GetResourceMetrics() {
+		var base models.GPUMetric
+		for _, kv := range rm.GetResource().GetAttributes() {
+			base.SetIdentity(kv.GetKey(), attributeString(kv.GetValue()))
+		}
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				reject(rejectHistogram, len(m.GetHistogram().GetDataPoints()))
+				reject(rejectExpHist, len(m.GetExponentialHistogram().GetDataPoints()))
+				reject(rejectSummary, len(m.GetSummary().GetDataPoints()))
+
+				for _, points := range [][]*metricspb.NumberDataPoint{m.GetGauge().GetDataPoints(), m.GetSum().GetDataPoints()} {
+					for _, p := range points {
+						metric, reason := convertPoint(base, m.GetName(), p, now)
+						if reason != "" {
+							reject(reason, 1)
+							continue
+						}
+						conv.Metrics = append(conv.Metrics, metric)
+					}
+				}
+			}
+		}
+	}
+
+	for _, reason := range rejectOrder {
+		if n := rejected[reason]; n > 0 {
+			conv.Reasons = append(conv.Reasons, fmt.Sprintf("%d %s", n, reason))
+		}
+	}
+	return conv
+}
+
+// convertPoint builds the GPU metric for one data point on top of the
+// resource identity in base, or returns why the point is rejected.
+func convertPoint(base models.GPUMetric, name string, p *metricspb.NumberDataPoint, now time.Time) (models.GPUMetric, string) {
+	var value float64
+	switch v := p.GetValue().(type) {
+	case *metricspb.NumberDataPoint_AsDouble:
+		value = v.AsDouble
+	case *metricspb.NumberDataPoint_AsInt:
+		value = float64(v.AsInt)
+	default:
+		return models.GPUMetric{}, rejectNoValue
+	}
+	if !models.FiniteValue(value) {
+		return models.GPUMetric{}, rejectNonFinite
+	}
+
+	metric := base
+	metric.MetricName = name
+	metric.Value = value
+	metric.Timestamp = now
+	if t := p.GetTimeUnixNano(); t != 0 {
+		metric.Timestamp = time.Unix(0, int64(t))
+	}
+
+	for _, kv := range p.GetAttributes() {
+		value := attributeString(kv.GetValue())
+		if metric.SetIdentity(kv.GetKey(), value) {
+			continue
+		}
+		if metric.Labels == nil {
+			metric.Labels = make(map[string]string)
+		}
+		metric.Labels[kv.GetKey()] = value
+	}
+	if metric.UUID == "" {
+		return models.GPUMetric{}, rejectNoUUID
+	}
+	return metric, ""
+}
+
+// attributeString renders an attribute value as a label value. Arrays,
+// key-value lists and bytes are not used for GPU identity and render as "".
+func attributeString(v *commonpb.AnyValue) string {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	}
+	return ""
+}
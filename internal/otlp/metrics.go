@@ -0,0 +1,35 @@
+package otlp
+
+import (
+	"fmt"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExportRequest is an ExportMetricsServiceRequest, decoded by the generated
+// OTLP bindings from either encoding.
+type ExportRequest = colmetricspb.ExportMetricsServiceRequest
+
+// jsonOptions decodes the OTLP/HTTP JSON mapping. Fields from newer OTLP
+// versions are skipped, as the binary decoder skips unknown fields.
+var jsonOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// UnmarshalJSON decodes an ExportMetricsServiceRequest in the OTLP/HTTP JSON encoding.
+func UnmarshalJSON(b []byte) (*ExportRequest, error) {
+	var req ExportRequest
+	if err := jsonOptions.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+	return &req, nil
+}
+
+// UnmarshalProto decodes a binary protobuf ExportMetricsServiceRequest.
+func UnmarshalProto(b []byte) (*ExportRequest, error) {
+	var req ExportRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("invalid OTLP protobuf: %w", err)
+	}
+	return &req, nil
+}
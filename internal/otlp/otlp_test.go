@@ -0,0 +1,412 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+func attr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func intAttr(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}}}
+}
+
+func doublePoint(value float64, timeNanos uint64, attrs ...*commonpb.KeyValue) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:   attrs,
+		TimeUnixNano: timeNanos,
+		Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+// sampleRequest is a node exporting two GPUs' utilization, a power sum, and
+// a histogram the receiver cannot store.
+func sampleRequest() *ExportRequest {
+	return &ExportRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+			attr("host.name", "mtv5-dgx1-hgpu-001"),
+			attr("k8s.pod.name", "dcgm-exporter-abcde"),
+			attr("service.name", "otelcol"),
+		}},
+		ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{
+			{Name: "DCGM_FI_DEV_GPU_UTIL", Unit: "%", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{
+				doublePoint(100, 1752871354000000000,
+					attr("UUID", "GPU-5fd4f087"), intAttr("gpu", 0), attr("modelName", "NVIDIA H100 80GB HBM3"),
+					attr("DCGM_FI_DRIVER_VERSION", "535.129.03")),
+				doublePoint(85.5, 1752871354000000000,
+					attr("gpu.uuid", "GPU-6ae5f188"), intAttr("gpu", 1)),
+			}}}},
+			{Name: "DCGM_FI_DEV_TOTAL_ENERGY_CONSUMPTION", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{DataPoints: []*metricspb.NumberDataPoint{{
+				Attributes: []*commonpb.KeyValue{attr("UUID", "GPU-5fd4f087")},
+				Value:      &metricspb.NumberDataPoint_AsInt{AsInt: 123456},
+			}}}}},
+			{Name: "DCGM_FI_DEV_GPU_TEMP", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: []*metricspb.NumberDataPoint{
+				doublePoint(40, 0), // no UUID
+			}}}},
+			{Name: "latency", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{DataPoints: []*metricspb.HistogramDataPoint{{}, {}}}}},
+		}}},
+	}}}
+}
+
+func sampleProto() []byte {
+	b, err := proto.Marshal(sampleRequest())
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+const sampleJSON = `{
+  "resourceMetrics": [{
+    "resource": {"attributes": [
+      {"key": "host.name", "value": {"stringValue": "mtv5-dgx1-hgpu-002"}}
+    ]},
+    "scopeMetrics": [{
+      "scope": {"name": "otelcol/prometheusreceiver"},
+      "metrics": [{
+        "name": "DCGM_FI_DEV_SM_CLOCK",
+        "unit": "MHz",
+        "gauge": {"dataPoints": [{
+          "attributes": [
+            {"key": "UUID", "value": {"stringValue": "GPU-7bf6g289"}},
+            {"key": "gpu", "value": {"intValue": "3"}},
+            {"key": "throttled", "value": {"boolValue": false}}
+          ],
+          "timeUnixNano": "1752871354000000000",
+          "asInt": "1980"
+        }, {
+          "attributes": [{"key": "UUID", "value": {"stringValue": "GPU-7bf6g289"}}],
+          "asDouble": "NaN"
+        }]}
+      }, {
+        "name": "DCGM_FI_PROF_PIPE_TENSOR_ACTIVE",
+        "summary": {"dataPoints": [{"count": "1"}]}
+      }]
+    }]
+  }]
+}`
+
+func TestUnmarshalProtoInvalid(t *testing.T) {
+	b := sampleProto()
+	if _, err := UnmarshalProto(b[:len(b)-3]); err == nil {
+		t.Error("expected error for truncated message")
+	}
+	if req, err := UnmarshalProto(nil); err != nil || len(req.ResourceMetrics) != 0 {
+		t.Errorf("expected empty request, got %+v, %v", req, err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	req, err := UnmarshalProto(sampleProto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 7, 18, 21, 0, 0, 0, time.UTC)
+	conv := Convert(req, now)
+
+	if len(conv.Metrics) != 3 {
+		t.Fatalf("expected 3 metrics, got %d: %+v", len(conv.Metrics), conv.Metrics)
+	}
+	m := conv.Metrics[0]
+	if m.MetricName != "DCGM_FI_DEV_GPU_UTIL" || m.UUID != "GPU-5fd4f087" || m.GPUID != 0 ||
+		m.ModelName != "NVIDIA H100 80GB HBM3" || m.Hostname != "mtv5-dgx1-hgpu-001" ||
+		m.Pod != "dcgm-exporter-abcde" || m.Value != 100 {
+		t.Errorf("unexpected metric: %+v", m)
+	}
+	if !m.Timestamp.Equal(time.Unix(0, 1752871354000000000)) {
+		t.Errorf("unexpected timestamp %v", m.Timestamp)
+	}
+	if len(m.Labels) != 1 || m.Labels["DCGM_FI_DRIVER_VERSION"] != "535.129.03" {
+		t.Errorf("expected only the driver label, got %v", m.Labels)
+	}
+	if m := conv.Metrics[1]; m.UUID != "GPU-6ae5f188" || m.GPUID != 1 || m.Labels != nil {
+		t.Errorf("unexpected second GPU metric: %+v", m)
+	}
+	if m := conv.Metrics[2]; m.Value != 123456 || !m.Timestamp.Equal(now) {
+		t.Errorf("expected int sum stamped with now, got %+v", m)
+	}
+
+	if conv.Rejected != 3 {
+		t.Errorf("expected 3 rejected points, got %d", conv.Rejected)
+	}
+	reasons := strings.Join(conv.Reasons, "; ")
+	if !strings.Contains(reasons, "1 data points without a GPU UUID") || !strings.Contains(reasons, "2 histogram") {
+		t.Errorf("unexpected reasons %q", reasons)
+	}
+}
+
+func TestConvertJSON(t *testing.T) {
+	req, err := UnmarshalJSON([]byte(sampleJSON))
+	if err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	conv := Convert(req, time.Now())
+
+	if len(conv.Metrics) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(conv.Metrics))
+	}
+	m := conv.Metrics[0]
+	if m.Value != 1980 || m.GPUID != 3 || m.Hostname != "mtv5-dgx1-hgpu-002" || m.Labels["throttled"] != "false" {
+		t.Errorf("unexpected metric: %+v", m)
+	}
+	// The NaN point and the summary point are rejected
+	want := []string{"1 data points with NaN or infinite values", "1 summary data points are not supported"}
+	if conv.Rejected != 2 || strings.Join(conv.Reasons, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %q rejected, got %d %q", want, conv.Rejected, conv.Reasons)
+	}
+
+	if _, err := UnmarshalJSON([]byte(`{"resourceMetrics": [{"scopeMetrics": [{"metrics": [{"gauge": {"dataPoints": [{"asInt": "x"}]}}]}]}]}`)); err == nil {
+		t.Error("expected error for invalid asInt")
+	}
+}
+
+type fakePublisher struct {
+	mu       sync.Mutex
+	payloads [][]byte
+	metadata []map[string]string
+	err      error
+}
+
+func (p *fakePublisher) PublishWithMetadata(ctx context.Context, payload []byte, metadata map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.payloads = append(p.payloads, payload)
+	p.metadata = append(p.metadata, metadata)
+	return nil
+}
+
+func (p *fakePublisher) batch(t *testing.T, i int) models.MetricBatch {
+	t.Helper()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if i >= len(p.payloads) {
+		t.Fatalf("expected at least %d published batches, got %d", i+1, len(p.payloads))
+	}
+	var batch models.MetricBatch
+	if err := json.Unmarshal(p.payloads[i], &batch); err != nil {
+		t.Fatal(err)
+	}
+	return batch
+}
+
+func TestHTTPExport(t *testing.T) {
+	pub := &fakePublisher{}
+	r := New(pub, Config{InstanceID: "otlp-1", MaxRequestBytes: 1 << 20}, nil)
+	srv := httptest.NewServer(r.HTTPHandler())
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+HTTPPath, "application/x-protobuf", bytes.NewReader(sampleProto()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-protobuf" {
+		t.Fatalf("expected 200 protobuf response, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var exportResp colmetricspb.ExportMetricsServiceResponse
+	if err := proto.Unmarshal(body, &exportResp); err != nil {
+		t.Fatal(err)
+	}
+	if n := exportResp.GetPartialSuccess().GetRejectedDataPoints(); n != 3 {
+		t.Errorf("expected partial success with 3 rejected points, got %d", n)
+	}
+
+	batch := pub.batch(t, 0)
+	if batch.Source != "otlp-1" || batch.BatchID == "" || len(batch.Metrics) != 3 {
+		t.Errorf("unexpected batch: %+v", batch)
+	}
+	if md := pub.metadata[0]; md[mq.MetaRecordCount] != "3" || md[mq.MetaHostname] != "mtv5-dgx1-hgpu-001" {
+		t.Errorf("unexpected metadata %v", md)
+	}
+
+	// gzipped JSON
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(sampleJSON))
+	zw.Close()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+HTTPPath, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		PartialSuccess struct {
+			RejectedDataPoints string `json:"rejectedDataPoints"`
+		} `json:"partialSuccess"`
+	}
+	json.NewDecoder(resp.Body).Decode(&out)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || out.PartialSuccess.RejectedDataPoints != "2" {
+		t.Errorf("expected 200 with 2 rejected points, got %d %+v", resp.StatusCode, out)
+	}
+	if batch := pub.batch(t, 1); len(batch.Metrics) != 1 {
+		t.Errorf("expected 1 metric from JSON export, got %d", len(batch.Metrics))
+	}
+
+	if st := r.Stats(); st.Requests != 2 || st.Batches != 2 || st.Metrics != 4 || st.Rejected != 5 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestHTTPExportErrors(t *testing.T) {
+	pub := &fakePublisher{}
+	r := New(pub, Config{MaxRequestBytes: 64, PublishRetry: retry.Policy{MaxAttempts: 1}}, nil)
+	srv := httptest.NewServer(r.HTTPHandler())
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        int
+	}{
+		{"unsupported content type", "text/plain", []byte("x"), http.StatusUnsupportedMediaType},
+		{"invalid protobuf", "application/x-protobuf", []byte{0x0a, 0x05}, http.StatusBadRequest},
+		{"invalid json", "application/json", []byte("{"), http.StatusBadRequest},
+		{"too large", "application/x-protobuf", sampleProto(), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+HTTPPath, tt.contentType, bytes.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+
+	resp, err := http.Get(srv.URL + HTTPPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", resp.StatusCode)
+	}
+
+	// A publish failure is retryable for the exporter
+	pub.err = errors.New("mq down")
+	r.cfg.MaxRequestBytes = 0
+	resp, err = http.Post(srv.URL+HTTPPath, "application/json", strings.NewReader(sampleJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || codes.Code(status.Code) != codes.Unavailable || !strings.Contains(status.Message, "mq down") {
+		t.Errorf("expected 503 UNAVAILABLE, got %d %+v", resp.StatusCode, status)
+	}
+	if st := r.Stats(); st.PublishFailure != 1 {
+		t.Errorf("expected 1 publish failure, got %+v", st)
+	}
+}
+
+// grpcClient starts r's gRPC server and connects a MetricsService client
+// to it, as an OTLP gRPC exporter with tls.insecure would.
+func grpcClient(t *testing.T, r *Receiver) *grpc.ClientConn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := r.GRPCServer()
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGRPCExport(t *testing.T) {
+	pub := &fakePublisher{}
+	r := New(pub, Config{InstanceID: "otlp-1", MaxRequestBytes: 1 << 20, PublishRetry: retry.Policy{MaxAttempts: 1}}, nil)
+	conn := grpcClient(t, r)
+	client := colmetricspb.NewMetricsServiceClient(conn)
+	ctx := context.Background()
+
+	resp, err := client.Export(ctx, sampleRequest())
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if n := resp.GetPartialSuccess().GetRejectedDataPoints(); n != 3 {
+		t.Errorf("expected partial success with 3 rejected points, got %d", n)
+	}
+	if batch := pub.batch(t, 0); len(batch.Metrics) != 3 {
+		t.Errorf("expected 3 published metrics, got %d", len(batch.Metrics))
+	}
+
+	// Exporters compress with gzip by default
+	if _, err := client.Export(ctx, sampleRequest(), grpc.UseCompressor(grpcgzip.Name)); err != nil {
+		t.Errorf("gzip export failed: %v", err)
+	}
+
+	_, err = coltracepb.NewTraceServiceClient(conn).Export(ctx, &coltracepb.ExportTraceServiceRequest{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("expected UNIMPLEMENTED for the trace service, got %v", err)
+	}
+
+	pub.err = errors.New("mq down")
+	_, err = client.Export(ctx, sampleRequest())
+	if status.Code(err) != codes.Unavailable || !strings.Contains(err.Error(), "mq down") {
+		t.Errorf("expected UNAVAILABLE on publish failure, got %v", err)
+	}
+}
+
+func TestGRPCExportTooLarge(t *testing.T) {
+	r := New(&fakePublisher{}, Config{MaxRequestBytes: 64}, nil)
+	client := colmetricspb.NewMetricsServiceClient(grpcClient(t, r))
+
+	_, err := client.Export(context.Background(), sampleRequest())
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected RESOURCE_EXHAUSTED, got %v", err)
+	}
+	if st := r.Stats(); st.Requests != 0 {
+		t.Errorf("expected the request refused before export, got %+v", st)
+	}
+}
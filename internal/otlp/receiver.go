@@ -0,0 +1,312 @@
+// Package otlp implements an OpenTelemetry OTLP metrics receiver that maps
+// OTLP gauges and sums to GPU metrics and publishes them to the MQ, so
+// otel-collector agents on GPU nodes can feed the pipeline directly.
+//
+// Both OTLP transports are served: gRPC (MetricsService/Export on a grpc-go
+// server) and OTLP/HTTP (POST /v1/metrics with binary protobuf or JSON
+// bodies). Messages are decoded with the generated OTLP bindings, so every
+// field an exporter sends is read as the OpenTelemetry protos define it.
+package otlp
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+	"github.com/google/uuid"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // Accept gzip-compressed gRPC exports
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// HTTPPath is the OTLP/HTTP metrics endpoint.
+const HTTPPath = "/v1/metrics"
+
+const (
+	contentTypeProto = "application/x-protobuf"
+	contentTypeJSON  = "application/json"
+)
+
+// errTooLarge is returned when a request exceeds MaxRequestBytes.
+var errTooLarge = errors.New("request exceeds the maximum size")
+
+// Publisher publishes a serialized batch to the MQ; *mq.Client implements it.
+type Publisher interface {
+	PublishWithMetadata(ctx context.Context, payload []byte, metadata map[string]string) error
+}
+
+// Config configures a Receiver.
+type Config struct {
+	// InstanceID is the batch Source, identifying this receiver in lineage
+	InstanceID string
+
+	// MaxRequestBytes bounds a request body after decompression
+	MaxRequestBytes int64
+
+	// PublishTimeout bounds each publish attempt
+	PublishTimeout time.Duration
+
+	// PublishRetry is the retry policy for publishing a batch
+	PublishRetry retry.Policy
+}
+
+// Stats are the receiver's counters since start.
+type Stats struct {
+	Requests       int64 `json:"requests"`
+	Batches        int64 `json:"batches"`
+	Metrics        int64 `json:"metrics"`
+	Rejected       int64 `json:"rejected"`
+	PublishFailure int64 `json:"publish_failures"`
+}
+
+// Receiver accepts OTLP metric exports and publishes each export as one
+// MetricBatch. The export is acknowledged only after the batch is published,
+// so exporters retry (and can buffer) while the MQ is unavailable.
+type Receiver struct {
+	publisher Publisher
+	cfg       Config
+	logger    *log.Logger
+
+	requests        atomic.Int64
+	batches         atomic.Int64
+	metrics         atomic.Int64
+	rejected        atomic.Int64
+	publishFailures atomic.Int64
+}
+
+// New creates a receiver publishing through publisher.
+func New(publisher Publisher, cfg Config, logger *log.Logger) *Receiver {
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	return &Receiver{publisher: publisher, cfg: cfg, logger: logger}
+}
+
+// Stats returns a snapshot of the receiver's counters.
+func (r *Receiver) Stats() Stats {
+	return Stats{
+		Requests:       r.requests.Load(),
+		Batches:        r.batches.Load(),
+		Metrics:        r.metrics.Load(),
+		Rejected:       r.rejected.Load(),
+		PublishFailure: r.publishFailures.Load(),
+	}
+}
+
+// export converts req and publishes the resulting metrics.
+func (r *Receiver) export(ctx context.Context, req *ExportRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	r.requests.Add(1)
+	conv := Convert(req, time.Now())
+
+	if len(conv.Metrics) > 0 {
+		if err := r.publish(ctx, conv.Metrics); err != nil {
+			r.publishFailures.Add(1)
+			r.logger.Printf("Failed to publish %d metrics: %v", len(conv.Metrics), err)
+			return nil, err
+		}
+	}
+
+	r.metrics.Add(int64(len(conv.Metrics)))
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if conv.Rejected == 0 {
+		return resp, nil
+	}
+	r.rejected.Add(int64(conv.Rejected))
+	resp.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+		RejectedDataPoints: int64(conv.Rejected),
+		ErrorMessage:       strings.Join(conv.Reasons, "; "),
+	}
+	return resp, nil
+}
+
+// publish sends metrics to the MQ as one batch, retrying transient failures.
+func (r *Receiver) publish(ctx context.Context, metrics []models.GPUMetric) error {
+	batch := &models.MetricBatch{
+		BatchID:     uuid.New().String(),
+		Source:      r.cfg.InstanceID,
+		CollectedAt: time.Now(),
+		Metrics:     metrics,
+	}
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+	metadata := batchMetadata(batch)
+
+	err = r.cfg.PublishRetry.Do(ctx, func(ctx context.Context) error {
+		if r.cfg.PublishTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.cfg.PublishTimeout)
+			defer cancel()
+		}
+		return r.publisher.PublishWithMetadata(ctx, payload, metadata)
+	})
+	if err != nil {
+		return err
+	}
+	r.batches.Add(1)
+	return nil
+}
+
+// batchMetadata summarizes a batch as MQ message metadata, as the streamer does.
+func batchMetadata(batch *models.MetricBatch) map[string]string {
+	return map[string]string{
+		mq.MetaHostname:    mq.JoinMetadataSet(batch.Hostnames()),
+		mq.MetaMetricName:  mq.JoinMetadataSet(batch.MetricNames()),
+		mq.MetaRecordCount: strconv.Itoa(len(batch.Metrics)),
+	}
+}
+
+// readBody reads at most MaxRequestBytes of body, decompressing gzip.
+func (r *Receiver) readBody(body io.Reader, gzipped bool) ([]byte, error) {
+	if gzipped {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer zr.Close()
+		body = zr
+	}
+
+	limit := r.cfg.MaxRequestBytes
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+	b, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, errTooLarge
+	}
+	return b, nil
+}
+
+// HTTPHandler serves OTLP/HTTP at HTTPPath.
+func (r *Receiver) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HTTPPath, r.serveHTTP)
+	mux.HandleFunc("/version", buildinfo.Handler("otlp-receiver"))
+	return mux
+}
+
+func (r *Receiver) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	contentType, _, _ := strings.Cut(req.Header.Get("Content-Type"), ";")
+	contentType = strings.TrimSpace(strings.ToLower(contentType))
+	var unmarshal func([]byte) (*ExportRequest, error)
+	switch contentType {
+	case contentTypeProto:
+		unmarshal = UnmarshalProto
+	case contentTypeJSON:
+		unmarshal = UnmarshalJSON
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var gzipped bool
+	switch enc := strings.ToLower(req.Header.Get("Content-Encoding")); enc {
+	case "", "identity":
+	case "gzip":
+		gzipped = true
+	default:
+		writeHTTPStatus(w, contentType, http.StatusUnsupportedMediaType, codes.InvalidArgument,
+			fmt.Sprintf("unsupported content encoding %q", enc))
+		return
+	}
+
+	body, err := r.readBody(req.Body, gzipped)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeHTTPStatus(w, contentType, status, codes.InvalidArgument, err.Error())
+		return
+	}
+	exportReq, err := unmarshal(body)
+	if err != nil {
+		writeHTTPStatus(w, contentType, http.StatusBadRequest, codes.InvalidArgument, err.Error())
+		return
+	}
+
+	resp, err := r.export(req.Context(), exportReq)
+	if err != nil {
+		// 503 is retryable for OTLP/HTTP exporters
+		writeHTTPStatus(w, contentType, http.StatusServiceUnavailable, codes.Unavailable, "failed to publish metrics: "+err.Error())
+		return
+	}
+
+	writeHTTPMessage(w, contentType, http.StatusOK, resp)
+}
+
+// writeHTTPStatus writes an OTLP/HTTP error: a google.rpc.Status body in the
+// request's encoding.
+func writeHTTPStatus(w http.ResponseWriter, contentType string, httpStatus int, code codes.Code, message string) {
+	writeHTTPMessage(w, contentType, httpStatus, status.New(code, message).Proto())
+}
+
+// writeHTTPMessage writes msg in the request's encoding.
+func writeHTTPMessage(w http.ResponseWriter, contentType string, httpStatus int, msg proto.Message) {
+	var body []byte
+	if contentType == contentTypeJSON {
+		body, _ = protojson.Marshal(msg)
+	} else {
+		body, _ = proto.Marshal(msg)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(httpStatus)
+	w.Write(body)
+}
+
+// GRPCServer returns a gRPC server with MetricsService registered, which is
+// what OTLP gRPC exporters call. It serves cleartext, as exporters with
+// tls.insecure expect, and accepts gzip-compressed requests.
+func (r *Receiver) GRPCServer() *grpc.Server {
+	maxRecv := math.MaxInt32
+	if r.cfg.MaxRequestBytes > 0 {
+		maxRecv = int(r.cfg.MaxRequestBytes)
+	}
+	server := grpc.NewServer(grpc.MaxRecvMsgSize(maxRecv))
+	colmetricspb.RegisterMetricsServiceServer(server, metricsService{receiver: r})
+	return server
+}
+
+// metricsService implements MetricsService/Export on the receiver.
+type metricsService struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	receiver *Receiver
+}
+
+func (s metricsService) Export(ctx context.Context, req *ExportRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	resp, err := s.receiver.export(ctx, req)
+	if err != nil {
+		// UNAVAILABLE is retryable for OTLP gRPC exporters
+		return nil, status.Error(codes.Unavailable, "failed to publish metrics: "+err.Error())
+	}
+	return resp, nil
+}
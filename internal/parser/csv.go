@@ -2,10 +2,13 @@
 package parser
 
 import (
+	"bufio"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +23,17 @@ type CSVParser struct {
 	reader    *csv.Reader
 	headers   []string
 	headerMap map[string]int
+	issues    []*FieldError // tolerated in the record last read
+
+	fingerprint string
+	mapping     string // name of the mapping applied, "" for none
+
+	// index holds the record index of each expected column, -1 for those
+	// absent from the file or projected out
+	index     []int
+	projected []string // columns parsed, nil for all
+
+	line int // file line of the row last read
 }
 
 // Expected CSV columns (case-insensitive)
@@ -38,17 +52,39 @@ var expectedColumns = []string{
 	"labels_raw",
 }
 
+// Positions of the expected columns in expectedColumns.
+const (
+	colTimestamp = iota
+	colMetricName
+	colGPUID
+	colDevice
+	colUUID
+	colModelName
+	colHostname
+	colContainer
+	colPod
+	colNamespace
+	colValue
+	colLabelsRaw
+)
+
+// ProjectedColumns are always parsed, whatever the projection.
+var ProjectedColumns = []string{"metric_name", "uuid", "value"}
+
 // NewCSVParser creates a new CSV parser for the given file.
 func NewCSVParser(filePath string) (*CSVParser, error) {
+	return NewMappedCSVParser(filePath, nil)
+}
+
+// NewMappedCSVParser creates a CSV parser that reads the file's columns
+// through the mapping listing its header fingerprint, if any.
+func NewMappedCSVParser(filePath string, mappings []Mapping) (*CSVParser, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = -1 // Allow variable fields
-	reader.LazyQuotes = true
-	reader.TrimLeadingSpace = true
+	reader := newCSVReader(file)
 
 	// Read header row
 	headers, err := reader.Read()
@@ -63,13 +99,109 @@ func NewCSVParser(filePath string) (*CSVParser, error) {
 		headerMap[strings.ToLower(strings.TrimSpace(h))] = i
 	}
 
-	return &CSVParser{
-		filePath:  filePath,
-		file:      file,
-		reader:    reader,
-		headers:   headers,
-		headerMap: headerMap,
-	}, nil
+	p := &CSVParser{
+		filePath:    filePath,
+		file:        file,
+		reader:      reader,
+		headers:     headers,
+		headerMap:   headerMap,
+		fingerprint: Fingerprint(headers),
+	}
+	if m := matchMapping(mappings, p.fingerprint); m != nil {
+		if err := p.applyMapping(m); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	p.resolve()
+	return p, nil
+}
+
+// utf8BOM is the byte order mark spreadsheet exports start files with.
+const utf8BOM = "\ufeff"
+
+// newCSVReader returns a reader for telemetry CSV, which skips a leading
+// byte order mark and tolerates ragged rows, stray quotes and CRLF line
+// endings. Quoted fields may hold commas and newlines. Records are reused,
+// as rows are parsed into metrics before the next is read.
+func newCSVReader(file io.Reader) *csv.Reader {
+	buffered := bufio.NewReader(file)
+	if bom, err := buffered.Peek(len(utf8BOM)); err == nil && string(bom) == utf8BOM {
+		buffered.Discard(len(utf8BOM))
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1 // Allow variable fields
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+	return reader
+}
+
+// resolve looks up the record index of each expected column once, so rows
+// are parsed without looking up column names.
+func (p *CSVParser) resolve() {
+	p.index = make([]int, len(expectedColumns))
+	for i, col := range expectedColumns {
+		idx, ok := p.headerMap[col]
+		if !ok || (p.projected != nil && !slices.Contains(p.projected, col)) {
+			idx = -1
+		}
+		p.index[i] = idx
+	}
+}
+
+// Project limits parsing to columns, named as the expected columns. The
+// others are skipped and their GPUMetric fields left empty, which saves
+// most of the per-row work when labels_raw and the Kubernetes columns are
+// not needed. ProjectedColumns are always parsed; nil parses every column.
+func (p *CSVParser) Project(columns []string) error {
+	if columns == nil {
+		p.projected = nil
+		p.resolve()
+		return nil
+	}
+	projected := slices.Clone(ProjectedColumns)
+	for _, col := range columns {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if !slices.Contains(expectedColumns, col) {
+			return fmt.Errorf("unknown CSV column %q (want one of %s)", col, strings.Join(expectedColumns, ", "))
+		}
+		if !slices.Contains(projected, col) {
+			projected = append(projected, col)
+		}
+	}
+	p.projected = projected
+	p.resolve()
+	return nil
+}
+
+// applyMapping points the expected column names at the mapped file columns.
+func (p *CSVParser) applyMapping(m *Mapping) error {
+	mapped := make(map[string]int, len(m.Columns))
+	for col, source := range m.Columns {
+		idx, ok := p.headerMap[strings.ToLower(strings.TrimSpace(source))]
+		if !ok {
+			return fmt.Errorf("mapping %q: column %q is not in the header of %s", m.Name, source, p.filePath)
+		}
+		mapped[col] = idx
+	}
+	for col, idx := range mapped {
+		p.headerMap[col] = idx
+	}
+	p.mapping = m.Name
+	return nil
+}
+
+// Fingerprint returns the fingerprint of the file's header.
+func (p *CSVParser) Fingerprint() string {
+	return p.fingerprint
+}
+
+// Mapping returns the name of the mapping the file is read through, or ""
+// when it is read by the expected column names.
+func (p *CSVParser) Mapping() string {
+	return p.mapping
 }
 
 // Close closes the parser and underlying file.
@@ -92,10 +224,8 @@ func (p *CSVParser) Reset() error {
 	}
 
 	p.file = file
-	p.reader = csv.NewReader(file)
-	p.reader.FieldsPerRecord = -1
-	p.reader.LazyQuotes = true
-	p.reader.TrimLeadingSpace = true
+	p.reader = newCSVReader(file)
+	p.line = 0
 
 	// Skip header row
 	if _, err := p.reader.Read(); err != nil {
@@ -112,13 +242,30 @@ func (p *CSVParser) ReadNext() (*models.GPUMetric, error) {
 	if err == io.EOF {
 		return nil, nil
 	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		p.line = parseErr.StartLine
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read CSV row: %w", err)
 	}
+	p.line, _ = p.reader.FieldPos(0)
 
 	return p.parseRecord(record)
 }
 
+// Issues returns the problems tolerated in the row last read by ReadNext:
+// gpu_id and value columns that do not parse and are read as 0.
+func (p *CSVParser) Issues() []*FieldError {
+	return p.issues
+}
+
+// Line returns the 1-based file line the row last read by ReadNext starts
+// on, or 0 before the first row.
+func (p *CSVParser) Line() int {
+	return p.line
+}
+
 // ReadBatch reads up to n records from the CSV.
 func (p *CSVParser) ReadBatch(n int) ([]*models.GPUMetric, error) {
 	metrics := make([]*models.GPUMetric, 0, n)
@@ -162,50 +309,64 @@ func (p *CSVParser) parseRecord(record []string) (*models.GPUMetric, error) {
 		Timestamp: time.Now(),
 		Labels:    make(map[string]string),
 	}
+	p.issues = nil
 
 	// Helper to get field value safely
-	getField := func(name string) string {
-		if idx, ok := p.headerMap[strings.ToLower(name)]; ok && idx < len(record) {
+	getField := func(col int) string {
+		if idx := p.index[col]; idx >= 0 && idx < len(record) {
 			return strings.TrimSpace(record[idx])
 		}
 		return ""
 	}
 
 	// Parse fields
-	metric.MetricName = getField("metric_name")
-	metric.Device = getField("device")
-	metric.UUID = getField("uuid")
-	metric.ModelName = getField("modelname")
-	metric.Hostname = getField("hostname")
-	metric.Container = getField("container")
-	metric.Pod = getField("pod")
-	metric.Namespace = getField("namespace")
+	metric.MetricName = getField(colMetricName)
+	metric.Device = getField(colDevice)
+	metric.UUID = getField(colUUID)
+	metric.ModelName = getField(colModelName)
+	metric.Hostname = getField(colHostname)
+	metric.Container = getField(colContainer)
+	metric.Pod = getField(colPod)
+	metric.Namespace = getField(colNamespace)
 
 	// Parse gpu_id
-	if gpuIDStr := getField("gpu_id"); gpuIDStr != "" {
+	if gpuIDStr := getField(colGPUID); gpuIDStr != "" {
 		if gpuID, err := strconv.Atoi(gpuIDStr); err == nil {
 			metric.GPUID = gpuID
+		} else {
+			p.issues = append(p.issues, fieldError("gpu_id", "not an integer: %q", gpuIDStr))
 		}
 	}
 
+	// A row cut short before its value, such as a final line truncated
+	// by a crashed writer, must not be read as a zero
+	if idx := p.index[colValue]; idx >= len(record) {
+		return nil, fieldError("value", "missing, the row has %d of %d columns", len(record), len(p.headers))
+	}
+
 	// Parse value
-	if valueStr := getField("value"); valueStr != "" {
+	if valueStr := getField(colValue); valueStr != "" {
 		if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
 			metric.Value = value
+		} else {
+			p.issues = append(p.issues, fieldError("value", "not a number: %q", valueStr))
 		}
 	}
+	if !models.FiniteValue(metric.Value) {
+		return nil, fieldError("value", "non-finite value %s", getField(colValue))
+	}
 
 	// Parse labels_raw (Prometheus-style labels)
-	if labelsRaw := getField("labels_raw"); labelsRaw != "" {
+	if labelsRaw := getField(colLabelsRaw); labelsRaw != "" {
 		metric.Labels = parseLabels(labelsRaw)
 	}
 
 	// Validate required fields
 	if metric.UUID == "" {
-		return nil, fmt.Errorf("missing required field: uuid")
+		return nil, fieldError("uuid", "missing required field")
 	}
 	if metric.MetricName == "" {
-		return nil, fmt.Errorf("missing required field: metric_name")
+		return nil, fieldError("metric_name", "missing required field")
 	}
 
 	return metric, nil
@@ -217,8 +378,7 @@ func parseLabels(raw string) map[string]string {
 	labels := make(map[string]string)
 
 	// Handle DCGM label format: key=value,key=value or key="value",key="value"
-	parts := strings.Split(raw, ",")
-	for _, part := range parts {
+	for _, part := range splitLabels(raw) {
 		part = strings.TrimSpace(part)
 		if idx := strings.Index(part, "="); idx > 0 {
 			key := strings.TrimSpace(part[:idx])
@@ -234,6 +394,28 @@ func parseLabels(raw string) map[string]string {
 	return labels
 }
 
+// splitLabels splits raw labels at the commas outside quoted values, so a
+// value such as "a,b" stays whole. A backslash escapes a quote.
+func splitLabels(raw string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; {
+		case c == '\\' && quote != 0:
+			i++ // Skip the escaped character
+		case c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == ',':
+			parts = append(parts, raw[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, raw[start:])
+}
+
 // CountRecords counts the total number of data records in the CSV.
 func CountRecords(filePath string) (int, error) {
 	file, err := os.Open(filePath)
@@ -242,9 +424,7 @@ func CountRecords(filePath string) (int, error) {
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = -1
-	reader.LazyQuotes = true
+	reader := newCSVReader(file)
 
 	// Skip header
 	if _, err := reader.Read(); err != nil {
@@ -266,9 +446,10 @@ func CountRecords(filePath string) (int, error) {
 	return count, nil
 }
 
-// ValidateCSV checks if the CSV file has the expected format.
-func ValidateCSV(filePath string) error {
-	parser, err := NewCSVParser(filePath)
+// ValidateCSV checks if the CSV file has the expected format, read through
+// mappings.
+func ValidateCSV(filePath string, mappings []Mapping) error {
+	parser, err := NewMappedCSVParser(filePath, mappings)
 	if err != nil {
 		return err
 	}
@@ -278,7 +459,10 @@ func ValidateCSV(filePath string) error {
 	required := []string{"uuid", "metric_name", "value"}
 	for _, col := range required {
 		if _, ok := parser.headerMap[col]; !ok {
-			return fmt.Errorf("missing required column: %s", col)
+			if parser.mapping != "" {
+				return fmt.Errorf("missing required column: %s (mapping %q)", col, parser.mapping)
+			}
+			return fmt.Errorf("missing required column: %s (header fingerprint %s matches no mapping)", col, parser.fingerprint)
 		}
 	}
 
@@ -293,3 +477,22 @@ func ValidateCSV(filePath string) error {
 
 	return nil
 }
+
+// MissingColumns returns the expected columns that are absent from the CSV header.
+// Required columns are validated by ValidateCSV; the others are mapped to optional
+// GPUMetric fields and are left empty when missing.
+func MissingColumns(filePath string, mappings []Mapping) ([]string, error) {
+	parser, err := NewMappedCSVParser(filePath, mappings)
+	if err != nil {
+		return nil, err
+	}
+	defer parser.Close()
+
+	var missing []string
+	for _, col := range expectedColumns {
+		if _, ok := parser.headerMap[col]; !ok {
+			missing = append(missing, col)
+		}
+	}
+	return missing, nil
+}
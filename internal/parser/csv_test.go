@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,6 +69,21 @@ func TestReadNext(t *testing.T) {
 	assert.NotZero(t, metric.Timestamp)
 }
 
+func TestLine(t *testing.T) {
+	csvPath := createTestCSV(t, sampleCSV)
+
+	parser, err := NewCSVParser(csvPath)
+	require.NoError(t, err)
+	defer parser.Close()
+
+	// The header is line 1, so data rows start at line 2
+	for want := 2; want <= 3; want++ {
+		_, err := parser.ReadNext()
+		require.NoError(t, err)
+		assert.Equal(t, want, parser.Line())
+	}
+}
+
 func TestReadBatch(t *testing.T) {
 	csvPath := createTestCSV(t, sampleCSV)
 
@@ -129,7 +146,7 @@ func TestCountRecords(t *testing.T) {
 func TestValidateCSV(t *testing.T) {
 	csvPath := createTestCSV(t, sampleCSV)
 
-	err := ValidateCSV(csvPath)
+	err := ValidateCSV(csvPath, nil)
 	require.NoError(t, err)
 }
 
@@ -140,7 +157,7 @@ value1,value2
 `
 	csvPath := createTestCSV(t, invalidCSV)
 
-	err := ValidateCSV(csvPath)
+	err := ValidateCSV(csvPath, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "missing required column")
 }
@@ -151,7 +168,7 @@ func TestValidateCSVEmpty(t *testing.T) {
 `
 	csvPath := createTestCSV(t, emptyCSV)
 
-	err := ValidateCSV(csvPath)
+	err := ValidateCSV(csvPath, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "empty")
 }
@@ -183,6 +200,15 @@ func TestParseLabels(t *testing.T) {
 			raw:      "",
 			expected: map[string]string{},
 		},
+		{
+			name: "commas and escaped quotes inside quotes",
+			raw:  `pci="0000:3b:00.0,0000:3c:00.0",note='a "b"',esc="x\",y"`,
+			expected: map[string]string{
+				"pci":  "0000:3b:00.0,0000:3c:00.0",
+				"note": `a "b`,
+				"esc":  `x\",y`,
+			},
+		},
 		{
 			name: "DCGM format",
 			raw:  "DCGM_FI_DRIVER_VERSION=535.129.03,DCGM_EXPORTER=dgx_dcgm_exporter:9400",
@@ -277,3 +303,206 @@ func TestMissingRequiredField(t *testing.T) {
 	assert.Nil(t, metric)
 	assert.Contains(t, err.Error(), "uuid")
 }
+
+func TestCSVFieldIssues(t *testing.T) {
+	csvContent := `timestamp,metric_name,gpu_id,device,uuid,modelName,Hostname,container,pod,namespace,value,labels_raw
+2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,zero,nvidia0,GPU-1,H100,host1,,,,n/a,
+2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,GPU-1,H100,host1,,,,NaN,
+2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,,H100,host1,,,,1,
+2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,GPU-1,H100,host1,,,,1,
+`
+	parser, err := NewCSVParser(createTestCSV(t, csvContent))
+	require.NoError(t, err)
+	defer parser.Close()
+
+	// Unparseable optional columns are read as 0 and reported
+	metric, err := parser.ReadNext()
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, metric.Value)
+	issues := parser.Issues()
+	require.Len(t, issues, 2)
+	assert.Equal(t, "gpu_id", issues[0].Field)
+	assert.Equal(t, "value", issues[1].Field)
+
+	// Non-finite values cannot be published as JSON, so the row is rejected
+	var fieldErr *FieldError
+	_, err = parser.ReadNext()
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "value", fieldErr.Field)
+
+	_, err = parser.ReadNext()
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "uuid", fieldErr.Field)
+
+	_, err = parser.ReadNext()
+	require.NoError(t, err)
+	assert.Empty(t, parser.Issues())
+}
+
+func TestMissingColumns(t *testing.T) {
+	csvPath := createTestCSV(t, sampleCSV)
+	missing, err := MissingColumns(csvPath, nil)
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	csvPath = createTestCSV(t, "timestamp,metric_name,uuid,value\n2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,GPU-1,1\n")
+	missing, err = MissingColumns(csvPath, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpu_id", "device", "modelname", "hostname", "container", "pod", "namespace", "labels_raw"}, missing)
+}
+
+func TestProjectColumns(t *testing.T) {
+	csvPath := createTestCSV(t, sampleCSV)
+
+	r, err := OpenColumns(csvPath, FormatAuto, nil, []string{"Hostname"})
+	require.NoError(t, err)
+	defer r.Close()
+
+	metric, err := r.ReadNext()
+	require.NoError(t, err)
+	assert.Equal(t, "DCGM_FI_DEV_GPU_UTIL", metric.MetricName)
+	assert.Equal(t, "GPU-5fd4f087-86f3-1234-5678-abcdef123456", metric.UUID)
+	assert.Equal(t, 100.0, metric.Value)
+	assert.Equal(t, "mtv5-dgx1-hgpu-001", metric.Hostname)
+	assert.Empty(t, metric.Device, "device was projected out")
+	assert.Empty(t, metric.Labels, "labels_raw was projected out")
+
+	// A nil projection parses every column again
+	p := r.(*CSVParser)
+	require.NoError(t, p.Project(nil))
+	metric, err = p.ReadNext()
+	require.NoError(t, err)
+	assert.Equal(t, "nvidia0", metric.Device)
+	assert.Equal(t, "535.129.03", metric.Labels["DCGM_FI_DRIVER_VERSION"])
+
+	assert.ErrorContains(t, p.Project([]string{"hostname", "rack"}), `unknown CSV column "rack"`)
+}
+
+func BenchmarkParseRecord(b *testing.B) {
+	record := []string{"2025-07-18T20:42:34Z", "DCGM_FI_DEV_GPU_UTIL", "0", "nvidia0",
+		"GPU-5fd4f087-86f3-1234-5678-abcdef123456", "NVIDIA H100 80GB HBM3", "mtv5-dgx1-hgpu-001",
+		"trainer", "trainer-0", "ml", "100", `DCGM_FI_DRIVER_VERSION="535.129.03",Hostname="mtv5-dgx1-hgpu-001"`}
+	headerMap := make(map[string]int)
+	for i, col := range expectedColumns {
+		headerMap[col] = i
+	}
+
+	for _, bc := range []struct {
+		name    string
+		columns []string
+	}{
+		{"all", nil},
+		{"projected", []string{"timestamp"}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			p := &CSVParser{headerMap: headerMap}
+			if err := p.Project(bc.columns); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.parseRecord(record); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestMessyCSV(t *testing.T) {
+	// A spreadsheet export: BOM, CRLF endings, a quoted labels_raw holding
+	// commas and a newline, and a final row cut short by a crashed writer
+	content := "\ufefftimestamp,metric_name,gpu_id,device,uuid,modelName,Hostname,container,pod,namespace,value,labels_raw\r\n" +
+		"2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,GPU-1,H100,host1,,,,100,\"pci=\"\"0000:3b:00.0,0000:3c:00.0\"\",\r\nnote=two lines\"\r\n" +
+		"2025-07-18T20:42:34Z,DCGM_FI_DEV_SM_CLOCK,0,nvidia0,GPU-1,H100,host1,,,,1980,\r\n" +
+		"2025-07-18T20:42:34Z,DCGM_FI_DEV_MEM_CLOCK,0,nvidia0,GPU-1,H1"
+	csvPath := createTestCSV(t, content)
+
+	parser, err := NewCSVParser(csvPath)
+	require.NoError(t, err)
+	defer parser.Close()
+	assert.Contains(t, parser.headerMap, "timestamp", "the BOM is not part of the first column")
+
+	metric, err := parser.ReadNext()
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, metric.Value)
+	assert.Equal(t, map[string]string{"pci": "0000:3b:00.0,0000:3c:00.0", "note": "two lines"}, metric.Labels)
+	assert.Equal(t, 2, parser.Line())
+
+	metric, err = parser.ReadNext()
+	require.NoError(t, err)
+	assert.Equal(t, "DCGM_FI_DEV_SM_CLOCK", metric.MetricName)
+	assert.Equal(t, "host1", metric.Hostname, "no carriage return left on any field")
+	assert.Equal(t, 4, parser.Line())
+
+	_, err = parser.ReadNext()
+	var fieldErr *FieldError
+	require.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "value", fieldErr.Field)
+	assert.Equal(t, 5, parser.Line())
+
+	metric, err = parser.ReadNext()
+	assert.NoError(t, err)
+	assert.Nil(t, metric)
+
+	count, err := CountRecords(csvPath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	export := filepath.Join(t.TempDir(), "export")
+	require.NoError(t, os.WriteFile(export, []byte(content), 0o644))
+	format, err := DetectFormat(export)
+	require.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+}
+
+func FuzzCSVParser(f *testing.F) {
+	f.Add(sampleCSV)
+	f.Add("\ufefftimestamp,metric_name,uuid,value,labels_raw\r\nt,m,GPU-1,1,\"a=\"\"x,y\"\"\"\r\n")
+	f.Add("metric_name,uuid,value\nm,\"GPU\n-1\",1\nm,GPU-2,\"")
+	f.Add("metric_name,uuid,value\nm,GPU-1,NaN\nm,,1\n\"\"\"")
+
+	f.Fuzz(func(t *testing.T, content string) {
+		path := filepath.Join(t.TempDir(), "fuzz.csv")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		parser, err := NewCSVParser(path)
+		if err != nil {
+			return // No header
+		}
+		defer parser.Close()
+
+		line := 0
+		for i := 0; i <= len(content); i++ {
+			metric, err := parser.ReadNext()
+			if err == nil && metric == nil {
+				return
+			}
+			if parser.Line() < line {
+				t.Fatalf("line went back from %d to %d", line, parser.Line())
+			}
+			line = parser.Line()
+			if err != nil {
+				continue
+			}
+			if metric.UUID == "" || metric.MetricName == "" || math.IsNaN(metric.Value) || math.IsInf(metric.Value, 0) {
+				t.Fatalf("accepted an invalid metric %+v", metric)
+			}
+		}
+		t.Fatal("the parser did not reach the end of the file")
+	})
+}
+
+func FuzzParseLabels(f *testing.F) {
+	f.Add(`key1="value1",key2="value2"`)
+	f.Add(`pci="0000:3b:00.0,0000:3c:00.0",esc="x\",y"`)
+	f.Add(`a='unterminated,b=2`)
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		for key := range parseLabels(raw) {
+			if key == "" || strings.ContainsAny(key, "=") {
+				t.Fatalf("parsed an invalid key %q from %q", key, raw)
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Mapping is a named CSV column layout, such as that of an older archive.
+// A file uses the mapping listing its header's fingerprint; files matching
+// no mapping are read by the expected column names.
+type Mapping struct {
+	// Name identifies the layout, e.g. "dcgm-2023"
+	Name string `json:"name"`
+
+	// Fingerprints are the header fingerprints the layout applies to
+	Fingerprints []string `json:"fingerprints"`
+
+	// Columns maps expected column names to the file's column names; the
+	// expected columns not listed are read by their own names
+	Columns map[string]string `json:"columns"`
+}
+
+// Fingerprint identifies a CSV header by its column names, in order and
+// ignoring case and surrounding space.
+func Fingerprint(headers []string) string {
+	names := make([]string, len(headers))
+	for i, h := range headers {
+		names[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(names, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// matchMapping returns the mapping listing fingerprint, or nil.
+func matchMapping(mappings []Mapping, fingerprint string) *Mapping {
+	for i := range mappings {
+		if slices.Contains(mappings[i].Fingerprints, fingerprint) {
+			return &mappings[i]
+		}
+	}
+	return nil
+}
+
+// LoadMappingsFile reads a JSON array of mappings.
+func LoadMappingsFile(path string) ([]Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var mappings []Mapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, perrors.Validation(fmt.Errorf("%s: %w", path, err))
+	}
+
+	names := make(map[string]bool, len(mappings))
+	fingerprints := make(map[string]string)
+	for i, m := range mappings {
+		if err := m.validate(); err != nil {
+			return nil, perrors.Validation(fmt.Errorf("%s: mapping %d: %w", path, i, err))
+		}
+		if names[m.Name] {
+			return nil, perrors.Validation(fmt.Errorf("%s: duplicate mapping name %q", path, m.Name))
+		}
+		names[m.Name] = true
+		for _, f := range m.Fingerprints {
+			if other, ok := fingerprints[f]; ok {
+				return nil, perrors.Validation(fmt.Errorf("%s: fingerprint %s is listed by both %q and %q", path, f, other, m.Name))
+			}
+			fingerprints[f] = m.Name
+		}
+	}
+	return mappings, nil
+}
+
+func (m *Mapping) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(m.Fingerprints) == 0 {
+		return fmt.Errorf("%q: at least one fingerprint is required", m.Name)
+	}
+	if len(m.Columns) == 0 {
+		return fmt.Errorf("%q: at least one column is required", m.Name)
+	}
+	for col, source := range m.Columns {
+		if !slices.Contains(expectedColumns, col) {
+			return fmt.Errorf("%q: unknown column %q (want one of %s)", m.Name, col, strings.Join(expectedColumns, ", "))
+		}
+		if strings.TrimSpace(source) == "" {
+			return fmt.Errorf("%q: column %q maps to an empty name", m.Name, col)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+const legacyCSV = `ts,name,gpu,gpu_uuid,host,val
+2023-03-01T00:00:00Z,DCGM_FI_DEV_GPU_TEMP,2,GPU-legacy-1,old-host-01,71
+`
+
+func writeMappings(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "mappings.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, Fingerprint([]string{"uuid", "value"}), Fingerprint([]string{" UUID", "Value "}))
+	assert.NotEqual(t, Fingerprint([]string{"uuid", "value"}), Fingerprint([]string{"value", "uuid"}))
+	assert.Len(t, Fingerprint([]string{"uuid"}), 16)
+}
+
+func TestMappedCSVParser(t *testing.T) {
+	legacyPath := createTestCSV(t, legacyCSV)
+	mappings := []Mapping{{
+		Name:         "dcgm-2023",
+		Fingerprints: []string{Fingerprint([]string{"ts", "name", "gpu", "gpu_uuid", "host", "val"})},
+		Columns:      map[string]string{"metric_name": "name", "gpu_id": "gpu", "uuid": "gpu_uuid", "hostname": "host", "value": "val"},
+	}}
+
+	require.Error(t, ValidateCSV(legacyPath, nil))
+	require.NoError(t, ValidateCSV(legacyPath, mappings))
+
+	r, err := Open(legacyPath, FormatAuto, mappings)
+	require.NoError(t, err)
+	defer r.Close()
+	p := r.(*CSVParser)
+	assert.Equal(t, "dcgm-2023", p.Mapping())
+
+	metric, err := p.ReadNext()
+	require.NoError(t, err)
+	assert.Equal(t, "DCGM_FI_DEV_GPU_TEMP", metric.MetricName)
+	assert.Equal(t, 2, metric.GPUID)
+	assert.Equal(t, "GPU-legacy-1", metric.UUID)
+	assert.Equal(t, "old-host-01", metric.Hostname)
+	assert.Equal(t, 71.0, metric.Value)
+
+	// Files in the expected layout are read as before
+	current, err := NewMappedCSVParser(createTestCSV(t, sampleCSV), mappings)
+	require.NoError(t, err)
+	defer current.Close()
+	assert.Empty(t, current.Mapping())
+
+	// A mapped column the header lacks fails the file
+	mappings[0].Columns["pod"] = "k8s_pod"
+	_, err = NewMappedCSVParser(legacyPath, mappings)
+	assert.ErrorContains(t, err, `column "k8s_pod" is not in the header`)
+}
+
+func TestLoadMappingsFile(t *testing.T) {
+	path := writeMappings(t, `[{"name": "dcgm-2023", "fingerprints": ["0123456789abcdef"], "columns": {"value": "val"}}]`)
+	mappings, err := LoadMappingsFile(path)
+	require.NoError(t, err)
+	require.Len(t, mappings, 1)
+	assert.Equal(t, "val", mappings[0].Columns["value"])
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid json", `{`},
+		{"missing name", `[{"fingerprints": ["a"], "columns": {"value": "val"}}]`},
+		{"no fingerprints", `[{"name": "a", "columns": {"value": "val"}}]`},
+		{"unknown column", `[{"name": "a", "fingerprints": ["a"], "columns": {"reading": "val"}}]`},
+		{"duplicate name", `[{"name": "a", "fingerprints": ["a"], "columns": {"value": "val"}}, {"name": "a", "fingerprints": ["b"], "columns": {"value": "v"}}]`},
+		{"shared fingerprint", `[{"name": "a", "fingerprints": ["a"], "columns": {"value": "val"}}, {"name": "b", "fingerprints": ["a"], "columns": {"value": "v"}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMappingsFile(writeMappings(t, tt.content))
+			assert.True(t, perrors.IsValidation(err), "got %v", err)
+		})
+	}
+}
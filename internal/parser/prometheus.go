@@ -0,0 +1,316 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// maxPrometheusLine bounds a single exposition line; dcgm-exporter lines are
+// well under 1KB but label sets can grow with Kubernetes metadata.
+const maxPrometheusLine = 1 << 20
+
+// PrometheusParser parses telemetry from Prometheus text exposition format,
+// such as archived dcgm-exporter scrape output:
+//
+//	# HELP DCGM_FI_DEV_GPU_UTIL GPU utilization (in %).
+//	# TYPE DCGM_FI_DEV_GPU_UTIL gauge
+//	DCGM_FI_DEV_GPU_UTIL{gpu="0",UUID="GPU-5fd4f087",device="nvidia0",modelName="NVIDIA H100 80GB HBM3",Hostname="host-001"} 100
+//
+// Labels dcgm-exporter uses for GPU identity (gpu, UUID, device, modelName,
+// Hostname, container, pod, namespace) fill the matching GPUMetric fields,
+// as models.GPUMetric.SetIdentity maps them, and all other labels are kept
+// in Labels.
+type PrometheusParser struct {
+	filePath string
+	file     *os.File
+	scanner  *bufio.Scanner
+	line     int // line of the last line scanned
+	sample   int // line of the last sample returned
+
+	help  map[string]string
+	types map[string]string
+}
+
+// NewPrometheusParser creates a parser for the exposition-format file at filePath.
+func NewPrometheusParser(filePath string) (*PrometheusParser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Prometheus file: %w", err)
+	}
+
+	p := &PrometheusParser{
+		filePath: filePath,
+		help:     make(map[string]string),
+		types:    make(map[string]string),
+	}
+	p.reset(file)
+	return p, nil
+}
+
+func (p *PrometheusParser) reset(file *os.File) {
+	p.file = file
+	p.scanner = bufio.NewScanner(file)
+	p.scanner.Buffer(make([]byte, 0, 64*1024), maxPrometheusLine)
+	p.line = 0
+	p.sample = 0
+}
+
+// Close closes the parser and underlying file.
+func (p *PrometheusParser) Close() error {
+	if p.file != nil {
+		return p.file.Close()
+	}
+	return nil
+}
+
+// Reset resets the parser to the beginning of the file.
+func (p *PrometheusParser) Reset() error {
+	if p.file != nil {
+		p.file.Close()
+	}
+
+	file, err := os.Open(p.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to reopen Prometheus file: %w", err)
+	}
+	p.reset(file)
+	return nil
+}
+
+// Line returns the 1-based file line of the sample last read by ReadNext.
+func (p *PrometheusParser) Line() int {
+	return p.sample
+}
+
+// Type returns the TYPE declared for a metric family so far ("" if none).
+func (p *PrometheusParser) Type(name string) string {
+	return p.types[name]
+}
+
+// Help returns the HELP text declared for a metric family so far ("" if none).
+func (p *PrometheusParser) Help(name string) string {
+	return p.help[name]
+}
+
+// ReadNext reads and parses the next sample, recording HELP and TYPE lines
+// along the way. Returns nil when EOF is reached.
+func (p *PrometheusParser) ReadNext() (*models.GPUMetric, error) {
+	for p.scanner.Scan() {
+		p.line++
+		text := strings.TrimSpace(p.scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			p.parseComment(text)
+			continue
+		}
+
+		p.sample = p.line
+		metric, err := parseSample(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+		return metric, nil
+	}
+
+	if err := p.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Prometheus file: %w", err)
+	}
+	return nil, nil
+}
+
+// ReadAll reads all samples from the file.
+func (p *PrometheusParser) ReadAll() ([]*models.GPUMetric, error) {
+	var metrics []*models.GPUMetric
+	for {
+		metric, err := p.ReadNext()
+		if err != nil {
+			return metrics, err
+		}
+		if metric == nil {
+			return metrics, nil
+		}
+		metrics = append(metrics, metric)
+	}
+}
+
+// parseComment records "# HELP name text" and "# TYPE name type" lines;
+// other comments are ignored.
+func (p *PrometheusParser) parseComment(text string) {
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(text, "#")), " ", 3)
+	if len(fields) < 3 {
+		return
+	}
+	switch fields[0] {
+	case "HELP":
+		p.help[fields[1]] = unescapeHelp(fields[2])
+	case "TYPE":
+		p.types[fields[1]] = strings.TrimSpace(fields[2])
+	}
+}
+
+// parseSample parses `name{label="value",...} value [timestamp_ms]`.
+func parseSample(text string) (*models.GPUMetric, error) {
+	nameEnd := strings.IndexAny(text, "{ \t")
+	if nameEnd <= 0 {
+		return nil, fieldError("metric_name", "invalid sample %q", text)
+	}
+	metric := &models.GPUMetric{
+		MetricName: text[:nameEnd],
+		Timestamp:  time.Now(),
+		Labels:     make(map[string]string),
+	}
+
+	rest := text[nameEnd:]
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parsePromLabels(rest)
+		if err != nil {
+			return nil, &FieldError{Field: "labels", Err: err}
+		}
+		for k, v := range labels {
+			if !metric.SetIdentity(k, v) {
+				metric.Labels[k] = v
+			}
+		}
+		rest = rest[n:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fieldError("value", "expected value and optional timestamp after %s", metric.MetricName)
+	}
+	value, err := parsePromValue(fields[0])
+	if err != nil {
+		return nil, fieldError("value", "invalid value %q for %s", fields[0], metric.MetricName)
+	}
+	if !models.FiniteValue(value) {
+		return nil, fieldError("value", "non-finite value %s for %s", fields[0], metric.MetricName)
+	}
+	metric.Value = value
+	if len(fields) == 2 {
+		ms, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fieldError("timestamp", "invalid timestamp %q for %s", fields[1], metric.MetricName)
+		}
+		metric.Timestamp = time.UnixMilli(ms)
+	}
+
+	if metric.UUID == "" {
+		return nil, fieldError("uuid", "missing required label: UUID")
+	}
+	return metric, nil
+}
+
+// parsePromLabels parses a `{k="v",...}` label set at the start of s,
+// returning the labels and the number of bytes consumed.
+func parsePromLabels(s string) (map[string]string, int, error) {
+	labels := make(map[string]string)
+	i := 1 // skip '{'
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq <= 0 {
+			return nil, 0, fmt.Errorf("invalid label at %q", s[i:])
+		}
+		key := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("label %s: value must be quoted", key)
+		}
+		i++
+
+		var value strings.Builder
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("label %s: unterminated value", key)
+		}
+		i++ // closing quote
+		labels[key] = value.String()
+	}
+}
+
+// parsePromValue parses a sample value, including NaN and ±Inf.
+func parsePromValue(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// unescapeHelp undoes the \\ and \n escapes allowed in HELP text.
+func unescapeHelp(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(s)
+}
+
+// CountSamples counts the samples in an exposition-format file.
+func CountSamples(filePath string) (int, error) {
+	p, err := NewPrometheusParser(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Close()
+
+	count := 0
+	for p.scanner.Scan() {
+		text := strings.TrimSpace(p.scanner.Text())
+		if text != "" && !strings.HasPrefix(text, "#") {
+			count++
+		}
+	}
+	return count, p.scanner.Err()
+}
+
+// ValidatePrometheus checks that the file's first sample parses.
+func ValidatePrometheus(filePath string) error {
+	p, err := NewPrometheusParser(filePath)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	metric, err := p.ReadNext()
+	if err != nil {
+		return fmt.Errorf("failed to parse first sample: %w", err)
+	}
+	if metric == nil {
+		return fmt.Errorf("Prometheus file has no samples")
+	}
+	return nil
+}
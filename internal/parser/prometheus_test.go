@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const samplePrometheus = `# HELP DCGM_FI_DEV_GPU_UTIL GPU utilization (in %).
+# TYPE DCGM_FI_DEV_GPU_UTIL gauge
+DCGM_FI_DEV_GPU_UTIL{gpu="0",UUID="GPU-5fd4f087-86f3-1234-5678-abcdef123456",device="nvidia0",modelName="NVIDIA H100 80GB HBM3",Hostname="mtv5-dgx1-hgpu-001",DCGM_FI_DRIVER_VERSION="535.129.03"} 100 1752871354000
+DCGM_FI_DEV_GPU_UTIL{gpu="1",UUID="GPU-6ae5f188-97g4-2345-6789-bcdefg234567",device="nvidia1",modelName="NVIDIA H100 80GB HBM3",Hostname="mtv5-dgx1-hgpu-001"} 85.5 1752871354000
+
+# HELP DCGM_FI_DEV_SM_CLOCK SM clock frequency (in MHz).
+# TYPE DCGM_FI_DEV_SM_CLOCK gauge
+DCGM_FI_DEV_SM_CLOCK{gpu="0",UUID="GPU-5fd4f087-86f3-1234-5678-abcdef123456",pod="train-0",namespace="ml",container="trainer"} 1980
+`
+
+func createTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestPrometheusReadAll(t *testing.T) {
+	path := createTestFile(t, "scrape.prom", samplePrometheus)
+
+	p, err := NewPrometheusParser(path)
+	require.NoError(t, err)
+	defer p.Close()
+
+	metrics, err := p.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	m := metrics[0]
+	assert.Equal(t, "DCGM_FI_DEV_GPU_UTIL", m.MetricName)
+	assert.Equal(t, 0, m.GPUID)
+	assert.Equal(t, "GPU-5fd4f087-86f3-1234-5678-abcdef123456", m.UUID)
+	assert.Equal(t, "nvidia0", m.Device)
+	assert.Equal(t, "NVIDIA H100 80GB HBM3", m.ModelName)
+	assert.Equal(t, "mtv5-dgx1-hgpu-001", m.Hostname)
+	assert.Equal(t, 100.0, m.Value)
+	assert.True(t, m.Timestamp.Equal(time.UnixMilli(1752871354000)))
+	assert.Equal(t, map[string]string{"DCGM_FI_DRIVER_VERSION": "535.129.03"}, m.Labels)
+
+	assert.Equal(t, 85.5, metrics[1].Value)
+	assert.Equal(t, 1, metrics[1].GPUID)
+
+	m = metrics[2]
+	assert.Equal(t, "train-0", m.Pod)
+	assert.Equal(t, "ml", m.Namespace)
+	assert.Equal(t, "trainer", m.Container)
+	assert.WithinDuration(t, time.Now(), m.Timestamp, time.Minute)
+
+	assert.Equal(t, "gauge", p.Type("DCGM_FI_DEV_SM_CLOCK"))
+	assert.Equal(t, "GPU utilization (in %).", p.Help("DCGM_FI_DEV_GPU_UTIL"))
+}
+
+func TestPrometheusLine(t *testing.T) {
+	path := createTestFile(t, "scrape.prom", samplePrometheus)
+
+	p, err := NewPrometheusParser(path)
+	require.NoError(t, err)
+	defer p.Close()
+
+	for _, want := range []int{3, 4, 8} {
+		m, err := p.ReadNext()
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		assert.Equal(t, want, p.Line())
+	}
+
+	m, err := p.ReadNext()
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	require.NoError(t, p.Reset())
+	m, err = p.ReadNext()
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, 3, p.Line())
+}
+
+func TestParseSample(t *testing.T) {
+	m, err := parseSample(`DCGM_FI_DEV_XID_ERRORS{UUID="GPU-1",err_msg="a \"quoted\", b\\c\nd"} 1e3`)
+	require.NoError(t, err)
+	assert.Equal(t, "a \"quoted\", b\\c\nd", m.Labels["err_msg"])
+	assert.Equal(t, 1000.0, m.Value)
+
+	m, err = parseSample(`DCGM_FI_DEV_POWER_USAGE{uuid="GPU-1", gpu="x"} 250.5`)
+	require.NoError(t, err)
+	assert.Equal(t, "x", m.Labels["gpu"], "non-numeric gpu label is kept as a label")
+
+	for _, v := range []string{"NaN", "+Inf", "-Inf"} {
+		_, err := parseSample(`DCGM_FI_DEV_POWER_USAGE{UUID="GPU-1"} ` + v)
+		assert.ErrorContains(t, err, "non-finite", v)
+	}
+
+	for _, text := range []string{
+		`DCGM_FI_DEV_GPU_UTIL{gpu="0"} 1`,
+		`DCGM_FI_DEV_GPU_UTIL{UUID="GPU-1"} abc`,
+		`DCGM_FI_DEV_GPU_UTIL{UUID="GPU-1"} 1 later`,
+		`DCGM_FI_DEV_GPU_UTIL{UUID="GPU-1"`,
+		`DCGM_FI_DEV_GPU_UTIL{UUID=GPU-1} 1`,
+		`DCGM_FI_DEV_GPU_UTIL{UUID="GPU-1"}`,
+		`{UUID="GPU-1"} 1`,
+	} {
+		_, err := parseSample(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestPrometheusReadNextError(t *testing.T) {
+	path := createTestFile(t, "scrape.prom", "# TYPE x gauge\nx{gpu=\"0\"} 1\n")
+
+	p, err := NewPrometheusParser(path)
+	require.NoError(t, err)
+	defer p.Close()
+
+	_, err = p.ReadNext()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.Contains(t, err.Error(), "UUID")
+}
+
+func TestCountSamples(t *testing.T) {
+	count, err := CountSamples(createTestFile(t, "scrape.prom", samplePrometheus))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestValidatePrometheus(t *testing.T) {
+	assert.NoError(t, ValidatePrometheus(createTestFile(t, "ok.prom", samplePrometheus)))
+	assert.Error(t, ValidatePrometheus(createTestFile(t, "empty.prom", "# HELP x y\n")))
+	assert.Error(t, ValidatePrometheus(createTestFile(t, "bad.prom", "x 1\n")))
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"csv extension", "data.csv", samplePrometheus, FormatCSV},
+		{"prom extension", "data.prom", sampleCSV, FormatPrometheus},
+		{"sniff csv", "data.txt", sampleCSV, FormatCSV},
+		{"sniff comment", "data.txt", samplePrometheus, FormatPrometheus},
+		{"sniff sample", "data", "\nx{UUID=\"GPU-1\"} 1\n", FormatPrometheus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat(createTestFile(t, tt.file, tt.content))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOpenAndCount(t *testing.T) {
+	promPath := createTestFile(t, "scrape.txt", samplePrometheus)
+	csvPath := createTestCSV(t, sampleCSV)
+
+	r, err := Open(promPath, FormatAuto, nil)
+	require.NoError(t, err)
+	_, ok := r.(*PrometheusParser)
+	assert.True(t, ok)
+	r.Close()
+
+	r, err = Open(csvPath, FormatAuto, nil)
+	require.NoError(t, err)
+	_, ok = r.(*CSVParser)
+	assert.True(t, ok)
+	r.Close()
+
+	_, err = Open(csvPath, "parquet", nil)
+	assert.Error(t, err)
+
+	n, err := Count(promPath, FormatAuto)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = Count(csvPath, FormatCSV)
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+}
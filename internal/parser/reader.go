@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Input formats accepted by Open.
+const (
+	FormatAuto       = "auto"
+	FormatCSV        = "csv"
+	FormatPrometheus = "prometheus"
+)
+
+// Formats lists the accepted input formats.
+var Formats = []string{FormatAuto, FormatCSV, FormatPrometheus}
+
+// Reader reads telemetry from a file one metric at a time.
+type Reader interface {
+	// ReadNext returns the next metric, or nil at end of file
+	ReadNext() (*models.GPUMetric, error)
+
+	// Line returns the 1-based file line of the metric last read
+	Line() int
+
+	// Close closes the underlying file
+	Close() error
+}
+
+// FieldError is a problem with one field of an input record. Parsers return
+// it for records they reject, and report the problems they tolerate through
+// Issues.
+type FieldError struct {
+	// Field is the CSV column or exposition-format part the problem is in
+	Field string
+
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError returns a FieldError for field with a formatted message.
+func fieldError(field, format string, args ...any) *FieldError {
+	return &FieldError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+// IssueReporter is implemented by readers that tolerate some field problems,
+// such as an unparseable optional column, instead of rejecting the record.
+type IssueReporter interface {
+	// Issues returns the problems tolerated in the record last read
+	Issues() []*FieldError
+}
+
+// Open opens filePath with the parser for format. FormatAuto picks the
+// parser from the file extension (.csv or .prom) or, failing that, from the
+// first non-blank line. CSV files are read through mappings.
+func Open(filePath, format string, mappings []Mapping) (Reader, error) {
+	return OpenColumns(filePath, format, mappings, nil)
+}
+
+// OpenColumns is like Open but parses only columns of CSV files (nil =
+// all), as CSVParser.Project does. Other formats read every field.
+func OpenColumns(filePath, format string, mappings []Mapping, columns []string) (Reader, error) {
+	if format == FormatAuto || format == "" {
+		detected, err := DetectFormat(filePath)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	switch format {
+	case FormatCSV:
+		p, err := NewMappedCSVParser(filePath, mappings)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.Project(columns); err != nil {
+			p.Close()
+			return nil, err
+		}
+		return p, nil
+	case FormatPrometheus:
+		return NewPrometheusParser(filePath)
+	default:
+		return nil, fmt.Errorf("unknown input format %q (want one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// DetectFormat guesses a file's format. Exposition format starts with a
+// comment or a metric name followed by '{' or a space, where CSV starts with
+// a comma-separated header.
+func DetectFormat(filePath string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".prom":
+		return FormatPrometheus, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPrometheusLine)
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, utf8BOM)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			return FormatPrometheus, nil
+		}
+		if i := strings.IndexAny(line, "{ ,"); i > 0 && line[i] != ',' {
+			return FormatPrometheus, nil
+		}
+		return FormatCSV, nil
+	}
+	return FormatCSV, scanner.Err()
+}
+
+// Count counts the metrics in filePath for the given format.
+func Count(filePath, format string) (int, error) {
+	if format == FormatAuto || format == "" {
+		detected, err := DetectFormat(filePath)
+		if err != nil {
+			return 0, err
+		}
+		format = detected
+	}
+	if format == FormatPrometheus {
+		return CountSamples(filePath)
+	}
+	return CountRecords(filePath)
+}
@@ -0,0 +1,97 @@
+package predict
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/features"
+)
+
+// Coefficient weighs a term in a logistic model.
+type Coefficient struct {
+	Term
+	Weight float64 `json:"weight"`
+}
+
+// Logistic scores a GPU with logistic regression: the logistic function of
+// the intercept plus each term's value times its weight. Terms whose metric
+// the GPU does not report count as zero. Parameters, typically fitted
+// offline on past failures, look like:
+//
+//	{"intercept": -9, "coefficients": [{"metric": "DCGM_FI_DEV_GPU_TEMP", "stat": "max", "weight": 0.08}]}
+type Logistic struct {
+	Intercept    float64       `json:"intercept"`
+	Coefficients []Coefficient `json:"coefficients"`
+}
+
+// defaultLogistic is a hand-set baseline, not a fitted model: it scores
+// 0.03 for a GPU peaking at 70°C and rises steeply with heat, heating and
+// XID errors. Replace it with fitted coefficients.
+var defaultLogistic = Logistic{
+	Intercept: -9,
+	Coefficients: []Coefficient{
+		{Term: Term{Metric: "DCGM_FI_DEV_GPU_TEMP", Stat: StatMax}, Weight: 0.08},
+		{Term: Term{Metric: "DCGM_FI_DEV_GPU_TEMP", Stat: StatSlope}, Weight: 0.5},
+		{Term: Term{Metric: "DCGM_FI_DEV_XID_ERRORS", Stat: StatMax}, Weight: 5},
+	},
+}
+
+func newLogistic(params []byte) (Model, error) {
+	if params == nil {
+		return defaultLogistic, nil
+	}
+	var l Logistic
+	if err := decode(params, &l); err != nil {
+		return nil, fmt.Errorf("logistic model: %w", err)
+	}
+	if len(l.Coefficients) == 0 {
+		return nil, errors.New("logistic model: at least one coefficient is required")
+	}
+	for i, c := range l.Coefficients {
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("logistic model: coefficient %d: %w", i, err)
+		}
+	}
+	return l, nil
+}
+
+// Metrics lists the metrics the coefficients read.
+func (l Logistic) Metrics() []string {
+	terms := make([]Term, len(l.Coefficients))
+	for i, c := range l.Coefficients {
+		terms[i] = c.Term
+	}
+	return metricsOf(terms)
+}
+
+// Score returns the modeled probability, listing the terms that raised it,
+// largest contribution first.
+func (l Logistic) Score(w features.Window) (float64, []string) {
+	type contribution struct {
+		term  Term
+		value float64
+		delta float64
+	}
+	z := l.Intercept
+	var raised []contribution
+	for _, c := range l.Coefficients {
+		v, ok := c.value(w)
+		if !ok {
+			continue
+		}
+		delta := c.Weight * v
+		z += delta
+		if delta > 0 {
+			raised = append(raised, contribution{c.Term, v, delta})
+		}
+	}
+	sort.SliceStable(raised, func(i, j int) bool { return raised[i].delta > raised[j].delta })
+
+	reasons := make([]string, 0, len(raised))
+	for _, c := range raised {
+		reasons = append(reasons, fmt.Sprintf("%s %s (%+.2f)", c.term, format(c.value), c.delta))
+	}
+	return 1 / (1 + math.Exp(-z)), reasons
+}
@@ -0,0 +1,172 @@
+// Package predict scores each GPU's risk of failing soon from a window of
+// its recent telemetry, so the hardware team can pilot predictive
+// maintenance. Models are pluggable: a rules model and a logistic
+// regression baseline are built in, and others can be registered.
+package predict
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/features"
+)
+
+// Model scores a GPU's risk of failing soon.
+type Model interface {
+	// Metrics are the metrics the model reads
+	Metrics() []string
+
+	// Score scores a GPU from a window of the metrics it reports, returning
+	// a score from 0 to 1 and what raised it. Metrics the GPU does not
+	// report are missing from the window.
+	Score(w features.Window) (float64, []string)
+}
+
+// Factory creates a model from its JSON parameters, or with its defaults
+// when params is nil.
+type Factory func(params []byte) (Model, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{
+		"rules":    newRules,
+		"logistic": newLogistic,
+	}
+)
+
+// Register makes a model available by name, replacing any model of that
+// name.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// New creates the named model from its JSON parameters, or with its
+// defaults when params is nil.
+func New(name string, params []byte) (Model, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	names := make([]string, 0, len(factories))
+	for n := range factories {
+		names = append(names, n)
+	}
+	factoriesMu.RUnlock()
+	if !ok {
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown prediction model %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return factory(params)
+}
+
+// Statistics a Term computes over a metric's window.
+const (
+	StatMean  = "mean"
+	StatMin   = "min"
+	StatMax   = "max"
+	StatStd   = "std"
+	StatLast  = "last"
+	StatSlope = "slope" // Change per hour, by least squares
+)
+
+// Term is a statistic of one metric over the window.
+type Term struct {
+	Metric string `json:"metric"`
+	Stat   string `json:"stat"`
+}
+
+func (t Term) String() string {
+	return t.Stat + " " + t.Metric
+}
+
+func (t Term) validate() error {
+	if t.Metric == "" {
+		return errors.New("metric must be set")
+	}
+	switch t.Stat {
+	case StatMean, StatMin, StatMax, StatStd, StatLast, StatSlope:
+		return nil
+	}
+	return fmt.Errorf("stat must be mean, min, max, std, last or slope, got %q", t.Stat)
+}
+
+// value computes the term over w. It is false when the GPU does not report
+// the metric.
+func (t Term) value(w features.Window) (float64, bool) {
+	f, ok := w.Feature(t.Metric)
+	if !ok || len(f.Values) == 0 {
+		return 0, false
+	}
+	switch t.Stat {
+	case StatMean:
+		return f.Mean, true
+	case StatMin:
+		return f.Min, true
+	case StatMax:
+		return f.Max, true
+	case StatStd:
+		return f.Std, true
+	case StatLast:
+		return f.Values[len(f.Values)-1], true
+	default:
+		return slope(w, f), true
+	}
+}
+
+// slope fits a line through the feature's values by least squares and
+// returns its change per hour.
+func slope(w features.Window, f features.Feature) float64 {
+	if len(w.Times) < 2 {
+		return 0
+	}
+	n := float64(len(f.Values))
+	var meanX float64
+	xs := make([]float64, len(f.Values))
+	for i := range xs {
+		xs[i] = w.Times[i].Sub(w.Times[0]).Hours()
+		meanX += xs[i]
+	}
+	meanX /= n
+	var sxy, sxx float64
+	for i, v := range f.Values {
+		sxy += (xs[i] - meanX) * (v - f.Mean)
+		sxx += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+	if sxx == 0 {
+		return 0
+	}
+	return sxy / sxx
+}
+
+// metricsOf lists the metrics of terms, once each, sorted.
+func metricsOf(terms []Term) []string {
+	seen := make(map[string]bool, len(terms))
+	var metrics []string
+	for _, t := range terms {
+		if !seen[t.Metric] {
+			seen[t.Metric] = true
+			metrics = append(metrics, t.Metric)
+		}
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// decode unmarshals params into v, rejecting unknown fields.
+func decode(params []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// format formats a term's value for a reason, to at most two decimals.
+func format(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,114 @@
+package predict
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/features"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// window builds a window of the metrics sampled every 10 minutes.
+func window(t *testing.T, metrics map[string][]float64) features.Window {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make(map[string][]models.SeriesPoint)
+	var names []string
+	n := 0
+	for metric, values := range metrics {
+		names = append(names, metric)
+		n = len(values)
+		for i, v := range values {
+			points[metric] = append(points[metric], models.SeriesPoint{Time: start.Add(time.Duration(i) * 10 * time.Minute), Value: v})
+		}
+	}
+	w, err := features.Build(points, names, start, 10*time.Minute, n, features.Options{Interpolation: features.InterpolateLinear})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestRules(t *testing.T) {
+	m, err := New("rules", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Metrics(); !reflect.DeepEqual(got, []string{"DCGM_FI_DEV_GPU_TEMP", "DCGM_FI_DEV_XID_ERRORS"}) {
+		t.Errorf("Metrics() = %v", got)
+	}
+
+	// Heating 6°C an hour, peaking at 88°C, with no XID errors
+	hot := window(t, map[string][]float64{
+		"DCGM_FI_DEV_GPU_TEMP":   {82, 83, 84, 85, 86, 87, 88},
+		"DCGM_FI_DEV_XID_ERRORS": {0, 0, 0, 0, 0, 0, 0},
+	})
+	score, reasons := m.Score(hot)
+	if math.Abs(score-0.6) > 1e-9 {
+		t.Errorf("score = %v, want 0.6", score)
+	}
+	if !reflect.DeepEqual(reasons, []string{"max DCGM_FI_DEV_GPU_TEMP 88 above 85", "slope DCGM_FI_DEV_GPU_TEMP 6 above 5"}) {
+		t.Errorf("reasons = %q", reasons)
+	}
+
+	// Scores are capped at 1, and metrics a GPU lacks break no rules
+	failing := window(t, map[string][]float64{"DCGM_FI_DEV_GPU_TEMP": {80, 85, 90}, "DCGM_FI_DEV_XID_ERRORS": {0, 2, 0}})
+	if score, _ := m.Score(failing); score != 1 {
+		t.Errorf("score = %v, want 1", score)
+	}
+	if score, reasons := m.Score(window(t, map[string][]float64{"DCGM_FI_DEV_POWER_USAGE": {300}})); score != 0 || len(reasons) != 0 {
+		t.Errorf("expected no score without the rules' metrics, got %v %q", score, reasons)
+	}
+
+	custom, err := New("rules", []byte(`{"rules": [{"metric": "DCGM_FI_DEV_POWER_USAGE", "stat": "mean", "above": 250, "weight": 0.3}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score, _ := custom.Score(window(t, map[string][]float64{"DCGM_FI_DEV_POWER_USAGE": {300}})); score != 0.3 {
+		t.Errorf("custom score = %v, want 0.3", score)
+	}
+	for _, bad := range []string{`{}`, `{"rules": [{"metric": "x", "stat": "median", "weight": 1}]}`, `{"rules": [{"metric": "x", "stat": "max"}]}`, `{"rule": []}`} {
+		if _, err := New("rules", []byte(bad)); err == nil {
+			t.Errorf("expected %s to be rejected", bad)
+		}
+	}
+}
+
+func TestLogistic(t *testing.T) {
+	m, err := New("logistic", []byte(`{"intercept": -2, "coefficients": [
+		{"metric": "DCGM_FI_DEV_GPU_TEMP", "stat": "last", "weight": 0.05},
+		{"metric": "DCGM_FI_DEV_SM_CLOCK", "stat": "min", "weight": -0.001}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, reasons := m.Score(window(t, map[string][]float64{"DCGM_FI_DEV_GPU_TEMP": {70, 80}, "DCGM_FI_DEV_SM_CLOCK": {1000, 1500}}))
+	// z = -2 + 0.05*80 - 0.001*1000 = 1
+	if want := 1 / (1 + math.Exp(-1)); math.Abs(score-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", score, want)
+	}
+	if !reflect.DeepEqual(reasons, []string{"last DCGM_FI_DEV_GPU_TEMP 80 (+4.00)"}) {
+		t.Errorf("reasons = %q", reasons)
+	}
+
+	// The default baseline scores a cool GPU low and one with XID errors high
+	m, _ = New("logistic", nil)
+	if score, _ := m.Score(window(t, map[string][]float64{"DCGM_FI_DEV_GPU_TEMP": {70, 70}})); models.RiskLevel(score) != models.RiskLow {
+		t.Errorf("expected a cool GPU to be low risk, got %v", score)
+	}
+	if score, _ := m.Score(window(t, map[string][]float64{"DCGM_FI_DEV_GPU_TEMP": {75, 75}, "DCGM_FI_DEV_XID_ERRORS": {0, 1}})); models.RiskLevel(score) != models.RiskHigh {
+		t.Errorf("expected XID errors to be high risk, got %v", score)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("constant", func(params []byte) (Model, error) { return Rules{}, nil })
+	if _, err := New("constant", nil); err != nil {
+		t.Errorf("expected the registered model, got %v", err)
+	}
+	if _, err := New("forest", nil); err == nil || !strings.Contains(err.Error(), "constant, logistic, rules") {
+		t.Errorf("expected an unknown model listing the available ones, got %v", err)
+	}
+}
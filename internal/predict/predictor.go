@@ -0,0 +1,143 @@
+package predict
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/features"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Predictor periodically scores every GPU reporting the model's metrics and
+// stores the predictions.
+type Predictor struct {
+	reader   storage.SeriesReader
+	store    storage.PredictionStore
+	model    Model
+	name     string
+	leader   leader.Leader
+	interval time.Duration
+	window   time.Duration
+	samples  int
+	logger   *log.Logger
+	clock    clock.Clock
+}
+
+// NewPredictor creates a predictor scoring GPUs with the model, registered as name,
+// while l is the leader.
+func NewPredictor(reader storage.SeriesReader, store storage.PredictionStore, model Model, name string, l leader.Leader, cfg config.PredictConfig, logger *log.Logger) *Predictor {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Predictor{
+		reader:   reader,
+		store:    store,
+		model:    model,
+		name:     name,
+		leader:   l,
+		interval: cfg.Interval,
+		window:   cfg.Window,
+		samples:  cfg.Samples,
+		logger:   logger,
+		clock:    clock.Real,
+	}
+}
+
+// Run scores the GPUs now and then every interval until ctx is done.
+func (p *Predictor) Run(ctx context.Context) {
+	ticker := p.clock.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if p.leader.IsLeader() {
+			if err := p.Predict(ctx); err != nil && ctx.Err() == nil {
+				p.logger.Printf("Failure prediction failed: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Predict scores every GPU with telemetry in the window and stores the
+// predictions. Each sample is its bucket's maximum, so short spikes are
+// not averaged away, and gaps are filled linearly.
+func (p *Predictor) Predict(ctx context.Context) error {
+	now := p.clock.Now().UTC()
+	step := p.window / time.Duration(p.samples)
+	start := now.Add(-p.window).Truncate(step)
+
+	series, err := p.reader.GetSeries(ctx, &models.SeriesQuery{
+		Metrics: p.model.Metrics(),
+		Start:   start,
+		End:     start.Add(time.Duration(p.samples) * step),
+		Every:   step,
+		Fn:      models.SeriesMax,
+	})
+	if err != nil {
+		return err
+	}
+
+	type gpu struct {
+		hostname string
+		points   map[string][]models.SeriesPoint
+	}
+	gpus := make(map[string]*gpu)
+	for _, s := range series {
+		g, ok := gpus[s.UUID]
+		if !ok {
+			g = &gpu{hostname: s.Hostname, points: make(map[string][]models.SeriesPoint)}
+			gpus[s.UUID] = g
+		}
+		g.points[s.Metric] = append(g.points[s.Metric], s.Points...)
+	}
+	uuids := make([]string, 0, len(gpus))
+	for uuid := range gpus {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	predictions := make([]*models.Prediction, 0, len(uuids))
+	for _, uuid := range uuids {
+		g := gpus[uuid]
+		metrics := make([]string, 0, len(g.points))
+		for metric := range g.points {
+			metrics = append(metrics, metric)
+		}
+		sort.Strings(metrics)
+		w, err := features.Build(g.points, metrics, start, step, p.samples, features.Options{
+			Interpolation: features.InterpolateLinear,
+			Normalization: features.NormalizeNone,
+		})
+		if err != nil {
+			continue
+		}
+		score, reasons := p.model.Score(w)
+		score = math.Max(0, math.Min(score, 1))
+		predictions = append(predictions, &models.Prediction{
+			UUID:        uuid,
+			Hostname:    g.hostname,
+			Model:       p.name,
+			Score:       score,
+			Risk:        models.RiskLevel(score),
+			Reasons:     reasons,
+			WindowStart: start,
+			ScoredAt:    now,
+		})
+	}
+	if len(predictions) == 0 {
+		return nil
+	}
+	return p.store.WritePredictions(ctx, predictions)
+}
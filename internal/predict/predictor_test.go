@@ -0,0 +1,74 @@
+package predict
+
+import (
+	"context"
+	"io"
+	"log"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// fakeStorage serves a hot GPU and a cool one, and records predictions.
+type fakeStorage struct {
+	query   *models.SeriesQuery
+	written []*models.Prediction
+}
+
+func (f *fakeStorage) GetSeries(ctx context.Context, query *models.SeriesQuery) ([]*models.Series, error) {
+	f.query = query
+	hot := &models.Series{Metric: "DCGM_FI_DEV_GPU_TEMP", UUID: "GPU-hot", Hostname: "host-1"}
+	cool := &models.Series{Metric: "DCGM_FI_DEV_GPU_TEMP", UUID: "GPU-cool", Hostname: "host-2"}
+	for t, i := query.Start, 0; t.Before(query.End); t, i = t.Add(query.Every), i+1 {
+		hot.Points = append(hot.Points, models.SeriesPoint{Time: t, Value: 81 + float64(i)})
+		// The cool GPU misses every other bucket
+		if i%2 == 0 {
+			cool.Points = append(cool.Points, models.SeriesPoint{Time: t, Value: 60})
+		}
+	}
+	return []*models.Series{hot, cool}, nil
+}
+
+func (f *fakeStorage) WritePredictions(ctx context.Context, predictions []*models.Prediction) error {
+	f.written = append(f.written, predictions...)
+	return nil
+}
+
+func (f *fakeStorage) ListPredictions(ctx context.Context) ([]*models.Prediction, error) {
+	return f.written, nil
+}
+
+func (f *fakeStorage) PredictionHistory(ctx context.Context, uuid string, start, end time.Time) ([]*models.Prediction, error) {
+	return nil, nil
+}
+
+func TestPredictorScoresEveryGPU(t *testing.T) {
+	store := &fakeStorage{}
+	model, _ := New("rules", nil)
+	cfg := config.PredictConfig{Interval: 15 * time.Minute, Window: time.Hour, Samples: 6}
+	p := NewPredictor(store, store, model, "rules", leader.Always{}, cfg, log.New(io.Discard, "", 0))
+	now := time.Date(2024, 1, 1, 12, 5, 0, 0, time.UTC)
+	p.clock = clock.NewSimulated(now)
+
+	if err := p.Predict(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if q := store.query; q.Every != 10*time.Minute || !q.Start.Equal(time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)) || q.Fn != models.SeriesMax {
+		t.Errorf("unexpected query %+v", q)
+	}
+	if len(store.written) != 2 {
+		t.Fatalf("expected both GPUs scored, got %+v", store.written)
+	}
+	cool, hot := store.written[0], store.written[1]
+	if hot.UUID != "GPU-hot" || hot.Hostname != "host-1" || math.Abs(hot.Score-0.6) > 1e-9 || hot.Risk != models.RiskMedium || len(hot.Reasons) != 2 {
+		t.Errorf("unexpected hot prediction %+v", hot)
+	}
+	if cool.UUID != "GPU-cool" || cool.Score != 0 || cool.Risk != models.RiskLow || cool.Model != "rules" || !cool.ScoredAt.Equal(now) {
+		t.Errorf("unexpected cool prediction %+v", cool)
+	}
+}
@@ -0,0 +1,87 @@
+package predict
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/features"
+)
+
+// Rule adds Weight to the score when its term is above a threshold.
+type Rule struct {
+	Term
+	Above  float64 `json:"above"`
+	Weight float64 `json:"weight"`
+}
+
+// Rules scores a GPU by adding up the weights of the rules it breaks,
+// up to 1. Parameters look like:
+//
+//	{"rules": [{"metric": "DCGM_FI_DEV_GPU_TEMP", "stat": "max", "above": 85, "weight": 0.4}]}
+type Rules struct {
+	Rules []Rule `json:"rules"`
+}
+
+// defaultRules flags GPUs running hot, heating up or reporting XID errors.
+var defaultRules = Rules{Rules: []Rule{
+	{Term: Term{Metric: "DCGM_FI_DEV_XID_ERRORS", Stat: StatMax}, Above: 0, Weight: 0.5},
+	{Term: Term{Metric: "DCGM_FI_DEV_GPU_TEMP", Stat: StatMax}, Above: 85, Weight: 0.4},
+	{Term: Term{Metric: "DCGM_FI_DEV_GPU_TEMP", Stat: StatSlope}, Above: 5, Weight: 0.2},
+}}
+
+func newRules(params []byte) (Model, error) {
+	if params == nil {
+		return defaultRules, nil
+	}
+	var r Rules
+	if err := decode(params, &r); err != nil {
+		return nil, fmt.Errorf("rules model: %w", err)
+	}
+	if len(r.Rules) == 0 {
+		return nil, errors.New("rules model: at least one rule is required")
+	}
+	for i, rule := range r.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rules model: rule %d: %w", i, err)
+		}
+		if rule.Weight <= 0 {
+			return nil, fmt.Errorf("rules model: rule %d: weight must be positive, got %v", i, rule.Weight)
+		}
+	}
+	return r, nil
+}
+
+// Metrics lists the metrics the rules read.
+func (r Rules) Metrics() []string {
+	terms := make([]Term, len(r.Rules))
+	for i, rule := range r.Rules {
+		terms[i] = rule.Term
+	}
+	return metricsOf(terms)
+}
+
+// Score adds up the weights of the broken rules, listing them heaviest
+// first.
+func (r Rules) Score(w features.Window) (float64, []string) {
+	type breach struct {
+		rule  Rule
+		value float64
+	}
+	var broken []breach
+	for _, rule := range r.Rules {
+		if v, ok := rule.value(w); ok && v > rule.Above {
+			broken = append(broken, breach{rule, v})
+		}
+	}
+	sort.SliceStable(broken, func(i, j int) bool { return broken[i].rule.Weight > broken[j].rule.Weight })
+
+	var score float64
+	reasons := make([]string, 0, len(broken))
+	for _, b := range broken {
+		score += b.rule.Weight
+		reasons = append(reasons, fmt.Sprintf("%s %s above %s", b.rule.Term, format(b.value), format(b.rule.Above)))
+	}
+	return math.Min(score, 1), reasons
+}
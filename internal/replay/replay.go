@@ -0,0 +1,157 @@
+// Package replay re-ingests stored batches after a bug fix. It looks up each
+// batch's lineage, re-fetches the original payload from the MQ log at the
+// recorded offset and republishes it, so every collector stores it again
+// through the normal pipeline.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/envelope"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Log is the part of the MQ client a Replayer uses.
+type Log interface {
+	// Fetch returns the message at offset, or a not-found error once it has left the log
+	Fetch(ctx context.Context, offset mq.Offset) (*mq.Message, error)
+
+	// PublishWithMetadata appends a message to the log
+	PublishWithMetadata(ctx context.Context, payload []byte, metadata map[string]string) error
+}
+
+// Replayer re-ingests batches recorded in lineage.
+type Replayer struct {
+	lineage    storage.LineageReader
+	log        Log
+	maxBatches int
+	logger     *log.Logger
+	keys       *envelope.Keyring // Opens encrypted payloads; nil when none are configured
+}
+
+// New creates a replayer that selects at most maxBatches batches per request.
+func New(lineage storage.LineageReader, l Log, maxBatches int, logger *log.Logger) *Replayer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Replayer{lineage: lineage, log: l, maxBatches: maxBatches, logger: logger}
+}
+
+// SetKeyring opens encrypted payloads with keys, to check they still hold
+// their batch. Replays are republished as they were, still encrypted.
+func (r *Replayer) SetKeyring(keys *envelope.Keyring) {
+	r.keys = keys
+}
+
+// Replay republishes the selected batches. Batches whose lineage or payload
+// cannot be found are skipped and reported; a failure to publish stops the
+// replay and is returned along with what was replayed so far.
+func (r *Replayer) Replay(ctx context.Context, req *models.ReplayRequest) (*models.ReplayResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, perrors.Validation(err)
+	}
+
+	result := &models.ReplayResult{Replayed: []string{}, Skipped: []models.ReplaySkip{}}
+	batches, err := r.selectBatches(ctx, req, result)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lineage := range batches {
+		metrics, reason, err := r.replayBatch(ctx, lineage)
+		if err != nil {
+			return result, err
+		}
+		if reason != "" {
+			result.Skipped = append(result.Skipped, models.ReplaySkip{BatchID: lineage.BatchID, Reason: reason})
+			continue
+		}
+		result.Replayed = append(result.Replayed, lineage.BatchID)
+		result.Metrics += metrics
+	}
+
+	r.logger.Printf("Re-ingestion replayed %d batch(es) (%d metrics), skipped %d",
+		len(result.Replayed), result.Metrics, len(result.Skipped))
+	return result, nil
+}
+
+// selectBatches resolves the request to lineage records, recording unknown
+// batch IDs as skipped.
+func (r *Replayer) selectBatches(ctx context.Context, req *models.ReplayRequest, result *models.ReplayResult) ([]*models.BatchLineage, error) {
+	if len(req.BatchIDs) == 0 {
+		limit := r.maxBatches
+		if req.Limit > 0 && req.Limit < limit {
+			limit = req.Limit
+		}
+		return r.lineage.ListBatches(ctx, *req.Start, *req.End, limit)
+	}
+
+	if len(req.BatchIDs) > r.maxBatches {
+		return nil, perrors.Validation(fmt.Errorf("at most %d batch_ids per request", r.maxBatches))
+	}
+
+	batches := make([]*models.BatchLineage, 0, len(req.BatchIDs))
+	for _, id := range req.BatchIDs {
+		lineage, err := r.lineage.GetBatch(ctx, id)
+		if perrors.IsNotFound(err) {
+			result.Skipped = append(result.Skipped, models.ReplaySkip{BatchID: id, Reason: "no lineage recorded"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, lineage)
+	}
+	return batches, nil
+}
+
+// replayBatch republishes one batch, returning its metric count, or a skip
+// reason if its payload is no longer available.
+func (r *Replayer) replayBatch(ctx context.Context, lineage *models.BatchLineage) (int, string, error) {
+	// Kafka keeps its own log; the MQ never saw this batch
+	if k := lineage.Kafka; k != nil {
+		return 0, fmt.Sprintf("consumed from Kafka topic %s (partition %d, offset %d); re-consume the topic to re-ingest it",
+			k.Topic, k.Partition, k.Offset), nil
+	}
+
+	msg, err := r.log.Fetch(ctx, mq.Offset(lineage.MQOffset))
+	if perrors.IsNotFound(err) {
+		return 0, fmt.Sprintf("payload at offset %d is no longer in the MQ log", lineage.MQOffset), nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to fetch batch %s: %w", lineage.BatchID, err)
+	}
+
+	// The MQ log is in memory, so after a server restart the offset may hold another batch
+	payload, err := r.keys.Open(msg.Payload, msg.Metadata)
+	if err != nil {
+		return 0, fmt.Sprintf("payload at offset %d cannot be read: %v", lineage.MQOffset, err), nil
+	}
+	var batch models.MetricBatch
+	if err := json.Unmarshal(payload, &batch); err != nil || batch.BatchID != lineage.BatchID {
+		return 0, fmt.Sprintf("offset %d no longer holds this batch", lineage.MQOffset), nil
+	}
+
+	metadata := make(map[string]string, len(msg.Metadata)+2)
+	for k, v := range msg.Metadata {
+		metadata[k] = v
+	}
+	metadata[mq.MetaReplayOf] = lineage.BatchID
+	// A replay is deliberate, so it must not be dropped as a duplicate of the original
+	delete(metadata, mq.MetaIdempotencyKey)
+	if _, ok := metadata[mq.MetaPublishedAt]; !ok && !msg.Timestamp.IsZero() {
+		metadata[mq.MetaPublishedAt] = msg.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+
+	if err := r.log.PublishWithMetadata(ctx, msg.Payload, metadata); err != nil {
+		return 0, "", fmt.Errorf("failed to republish batch %s: %w", lineage.BatchID, err)
+	}
+	return len(batch.Metrics), "", nil
+}
@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Deliverer sends a report to a delivery target.
+type Deliverer interface {
+	Deliver(ctx context.Context, target models.DeliveryTarget, report *Report) error
+}
+
+// Allowlist returns the delivery targets the configuration permits.
+func Allowlist(cfg config.SchedulerConfig) models.DeliveryAllowlist {
+	return models.DeliveryAllowlist{
+		WebhookHosts: cfg.WebhookHosts,
+		EmailDomains: cfg.EmailDomains,
+		S3Buckets:    cfg.S3Buckets,
+	}
+}
+
+// Deliverers builds the deliverers available with the given configuration,
+// keyed by target type. Email and S3 are only available when configured.
+// Every deliverer refuses targets outside the configured allowlist, so a
+// saved query stored before the allowlist was narrowed is not delivered.
+func Deliverers(cfg config.SchedulerConfig) (map[string]Deliverer, error) {
+	deliverers := map[string]Deliverer{
+		models.DeliveryWebhook: &WebhookDeliverer{Client: &http.Client{
+			// A redirect could lead anywhere, allowlisted host or not
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}},
+	}
+	if cfg.SMTPHost != "" {
+		deliverers[models.DeliveryEmail] = &EmailDeliverer{
+			Addr:     fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort),
+			Host:     cfg.SMTPHost,
+			From:     cfg.SMTPFrom,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		}
+	}
+	if cfg.S3AccessKey != "" && cfg.S3SecretKey != "" {
+		s3, err := NewS3Deliverer(cfg)
+		if err != nil {
+			return nil, err
+		}
+		deliverers[models.DeliveryS3] = s3
+	}
+
+	allow := Allowlist(cfg)
+	for target, d := range deliverers {
+		deliverers[target] = &allowlisted{allow: allow, next: d}
+	}
+	return deliverers, nil
+}
+
+// allowlisted refuses targets the allowlist does not permit before
+// delivering through next.
+type allowlisted struct {
+	allow models.DeliveryAllowlist
+	next  Deliverer
+}
+
+// Deliver checks the target and passes the report on.
+func (d *allowlisted) Deliver(ctx context.Context, target models.DeliveryTarget, report *Report) error {
+	if err := d.allow.Check(target); err != nil {
+		return perrors.Permanent(err)
+	}
+	return d.next.Deliver(ctx, target, report)
+}
+
+// WebhookDeliverer POSTs the report body to the target URL.
+type WebhookDeliverer struct {
+	Client *http.Client
+}
+
+// Deliver posts the report and expects a 2xx response.
+func (d *WebhookDeliverer) Deliver(ctx context.Context, target models.DeliveryTarget, report *Report) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(report.Body))
+	if err != nil {
+		return perrors.Validation(err)
+	}
+	req.Header.Set("Content-Type", report.ContentType)
+	req.Header.Set("X-Saved-Query-ID", report.Query.ID)
+	req.Header.Set("X-Saved-Query-Name", report.Query.Name)
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return perrors.Transient(fmt.Errorf("webhook delivery failed: %w", err))
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError("webhook", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailDeliverer sends the report as an attachment over SMTP.
+type EmailDeliverer struct {
+	Addr     string
+	Host     string
+	From     string
+	Username string
+	Password string
+}
+
+// Deliver sends one message to all recipients. net/smtp does not take a
+// context, so cancellation only applies before the send starts.
+func (d *EmailDeliverer) Deliver(ctx context.Context, target models.DeliveryTarget, report *Report) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	msg, err := d.message(target.To, report)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if d.Username != "" {
+		auth = smtp.PlainAuth("", d.Username, d.Password, d.Host)
+	}
+	if err := smtp.SendMail(d.Addr, auth, d.From, target.To, msg); err != nil {
+		return perrors.Transient(fmt.Errorf("email delivery failed: %w", err))
+	}
+	return nil
+}
+
+// message builds a multipart MIME message with the report attached.
+func (d *EmailDeliverer) message(to []string, report *Report) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", d.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Telemetry report: "+report.Query.Name))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(text, "Saved query %q ran at %s and returned %d rows.\r\n",
+		report.Query.Name, report.GeneratedAt.UTC().Format("2006-01-02 15:04:05 MST"), report.Rows)
+
+	attachment, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {report.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {"attachment; filename=" + strconv.Quote(report.Filename)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Wrap base64 at 76 characters per line as MIME requires
+	encoded := base64.StdEncoding.EncodeToString(report.Body)
+	for len(encoded) > 76 {
+		fmt.Fprintf(attachment, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(attachment, "%s\r\n", encoded)
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// statusError classifies a non-2xx delivery response: throttling and server
+// errors are retried, anything else is a problem with the target itself.
+func statusError(target string, status int) error {
+	err := fmt.Errorf("%s delivery returned HTTP %d", target, status)
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return perrors.Transient(err)
+	}
+	return perrors.Permanent(err)
+}
@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func testReport() *Report {
+	return &Report{
+		Query:       &models.SavedQuery{ID: "q-1", Name: "nightly"},
+		GeneratedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Rows:        1,
+		Filename:    "nightly-20240101T000000Z.csv",
+		ContentType: "text/csv",
+		Body:        []byte("timestamp,metric_name\n"),
+	}
+}
+
+func TestWebhookDeliverer(t *testing.T) {
+	var gotBody, gotID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotID = r.Header.Get("X-Saved-Query-ID")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	d := &WebhookDeliverer{Client: srv.Client()}
+	target := models.DeliveryTarget{Type: models.DeliveryWebhook, URL: srv.URL}
+	if err := d.Deliver(context.Background(), target, testReport()); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if gotBody != "timestamp,metric_name\n" {
+		t.Errorf("unexpected body %q", gotBody)
+	}
+	if gotID != "q-1" {
+		t.Errorf("expected X-Saved-Query-ID q-1, got %q", gotID)
+	}
+}
+
+func TestWebhookDelivererStatusErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		retryable bool
+	}{
+		{http.StatusServiceUnavailable, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		d := &WebhookDeliverer{Client: srv.Client()}
+		err := d.Deliver(context.Background(), models.DeliveryTarget{URL: srv.URL}, testReport())
+		srv.Close()
+
+		if err == nil {
+			t.Errorf("status %d: expected error", tt.status)
+			continue
+		}
+		if perrors.IsRetryable(err) != tt.retryable {
+			t.Errorf("status %d: expected retryable=%t, got %v", tt.status, tt.retryable, err)
+		}
+	}
+}
+
+func TestDeliverersEnforceAllowlist(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	deliverers, err := Deliverers(config.SchedulerConfig{WebhookHosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatalf("Deliverers failed: %v", err)
+	}
+	d := deliverers[models.DeliveryWebhook]
+
+	allowed := models.DeliveryTarget{Type: models.DeliveryWebhook, URL: srv.URL}
+	if err := d.Deliver(context.Background(), allowed, testReport()); err != nil {
+		t.Fatalf("Deliver to an allowed host failed: %v", err)
+	}
+
+	denied := models.DeliveryTarget{Type: models.DeliveryWebhook, URL: strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)}
+	err = d.Deliver(context.Background(), denied, testReport())
+	if err == nil || perrors.IsRetryable(err) {
+		t.Errorf("expected a permanent error for a host off the allowlist, got %v", err)
+	}
+
+	redirect := models.DeliveryTarget{Type: models.DeliveryWebhook, URL: srv.URL + "/redirect"}
+	if err := d.Deliver(context.Background(), redirect, testReport()); err == nil {
+		t.Error("expected a redirect to fail delivery")
+	}
+	if hits != 2 {
+		t.Errorf("expected 2 requests with the redirect not followed, got %d", hits)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	d := &EmailDeliverer{From: "reports@example.com"}
+	msg, err := d.message([]string{"ops@example.com"}, testReport())
+	if err != nil {
+		t.Fatalf("message failed: %v", err)
+	}
+
+	text := string(msg)
+	for _, want := range []string{
+		"To: ops@example.com",
+		"Subject: Telemetry report: nightly",
+		`filename="nightly-20240101T000000Z.csv"`,
+		"Content-Transfer-Encoding: base64",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected message to contain %q", want)
+		}
+	}
+}
+
+func TestEmailMessageEncodesSubject(t *testing.T) {
+	// Names saved before control characters were refused must not break
+	// out of the Subject header
+	report := testReport()
+	report.Query.Name = "nightly\r\nBcc: x@evil.test"
+	msg, err := (&EmailDeliverer{From: "reports@example.com"}).message([]string{"ops@example.com"}, report)
+	if err != nil {
+		t.Fatalf("message failed: %v", err)
+	}
+	if strings.Contains(string(msg), "\r\nBcc:") {
+		t.Errorf("expected the subject encoded, got %q", msg)
+	}
+	if !strings.Contains(string(msg), "Subject: =?utf-8?q?") {
+		t.Errorf("expected a Q-encoded subject, got %q", msg)
+	}
+}
+
+func TestS3Deliverer(t *testing.T) {
+	var gotPath, gotAuth, gotType, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT, got %s", r.Method)
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotBody = r.URL.Path, string(body)
+		gotAuth = r.Header.Get("Authorization")
+		gotType = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer srv.Close()
+
+	d, err := NewS3Deliverer(config.SchedulerConfig{
+		S3Endpoint:  srv.URL,
+		S3Region:    "us-east-1",
+		S3AccessKey: "AKIDEXAMPLE",
+		S3SecretKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := models.DeliveryTarget{Type: models.DeliveryS3, Bucket: "reports", Prefix: "gpu/"}
+	if err := d.Deliver(context.Background(), target, testReport()); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	if gotPath != "/reports/gpu/nightly-20240101T000000Z.csv" {
+		t.Errorf("unexpected object path %q", gotPath)
+	}
+	// Over plain HTTP the body is signed chunk by chunk
+	if !strings.Contains(gotBody, "timestamp,metric_name\n") || gotType != "text/csv" {
+		t.Errorf("unexpected object %q of type %q", gotBody, gotType)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(gotAuth, "/us-east-1/s3/aws4_request") {
+		t.Errorf("unexpected Authorization %q", gotAuth)
+	}
+}
+
+func TestS3DelivererErrors(t *testing.T) {
+	tests := []struct {
+		status    int
+		code      string
+		retryable bool
+	}{
+		{http.StatusServiceUnavailable, "SlowDown", true},
+		{http.StatusForbidden, "AccessDenied", false},
+		{http.StatusNotFound, "NoSuchBucket", false},
+	}
+
+	for _, tt := range tests {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(tt.status)
+			fmt.Fprintf(w, "<Error><Code>%s</Code><Message>refused</Message></Error>", tt.code)
+		}))
+		d, err := NewS3Deliverer(config.SchedulerConfig{S3Endpoint: srv.URL, S3Region: "us-east-1", S3AccessKey: "a", S3SecretKey: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = d.Deliver(context.Background(), models.DeliveryTarget{Bucket: "reports"}, testReport())
+		srv.Close()
+
+		if err == nil || !strings.Contains(err.Error(), tt.code) {
+			t.Errorf("status %d: expected the %s error, got %v", tt.status, tt.code, err)
+			continue
+		}
+		if perrors.IsRetryable(err) != tt.retryable {
+			t.Errorf("status %d: expected retryable=%t, got %v", tt.status, tt.retryable, err)
+		}
+		// Retries are the scheduler's, which backs off between them
+		if requests != 1 {
+			t.Errorf("status %d: expected one request, got %d", tt.status, requests)
+		}
+	}
+
+	d, err := NewS3Deliverer(config.SchedulerConfig{S3Region: "us-east-1", S3AccessKey: "a", S3SecretKey: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Deliver(context.Background(), models.DeliveryTarget{Bucket: "Not_A_Bucket"}, testReport())
+	if !perrors.IsValidation(err) {
+		t.Errorf("expected an invalid bucket name rejected before sending, got %v", err)
+	}
+
+	if _, err := NewS3Deliverer(config.SchedulerConfig{S3Endpoint: "minio:9000"}); err == nil {
+		t.Error("expected an endpoint without a scheme refused")
+	}
+}
+
+// roundTripFunc lets a test answer the S3 client's requests.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestS3DelivererOnAWS(t *testing.T) {
+	var got string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.URL.Scheme + "://" + r.URL.Host + r.URL.Path
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})
+	d, err := newS3Deliverer(config.SchedulerConfig{S3Region: "eu-west-1", S3AccessKey: "a", S3SecretKey: "b"}, transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Deliver(context.Background(), models.DeliveryTarget{Bucket: "reports"}, testReport()); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if want := "https://reports.s3.dualstack.eu-west-1.amazonaws.com/nightly-20240101T000000Z.csv"; got != want {
+		t.Errorf("expected the object addressed virtual-hosted-style at %s, got %s", want, got)
+	}
+}
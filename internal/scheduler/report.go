@@ -0,0 +1,76 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Report is a rendered saved-query result ready for delivery.
+type Report struct {
+	Query       *models.SavedQuery
+	GeneratedAt time.Time
+	Rows        int
+	Filename    string
+	ContentType string
+	Body        []byte
+}
+
+// reportDocument is the JSON report layout.
+type reportDocument struct {
+	SavedQueryID   string              `json:"saved_query_id"`
+	SavedQueryName string              `json:"saved_query_name"`
+	GeneratedAt    time.Time           `json:"generated_at"`
+	Start          time.Time           `json:"start"`
+	End            time.Time           `json:"end"`
+	Count          int                 `json:"count"`
+	Data           []*models.GPUMetric `json:"data"`
+}
+
+var unsafeFilename = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// render formats metrics in the saved query's format.
+func render(q *models.SavedQuery, tq *models.TelemetryQuery, metrics []*models.GPUMetric, now time.Time) (*Report, error) {
+	report := &Report{
+		Query:       q,
+		GeneratedAt: now,
+		Rows:        len(metrics),
+	}
+
+	name := strings.Trim(unsafeFilename.ReplaceAllString(strings.ToLower(q.Name), "-"), "-")
+	if name == "" {
+		name = q.ID
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	switch q.Format {
+	case "csv":
+		var buf bytes.Buffer
+		models.WriteMetricsCSV(&buf, metrics)
+		report.Body = buf.Bytes()
+		report.ContentType = "text/csv"
+		report.Filename = fmt.Sprintf("%s-%s.csv", name, stamp)
+	default:
+		body, err := json.Marshal(reportDocument{
+			SavedQueryID:   q.ID,
+			SavedQueryName: q.Name,
+			GeneratedAt:    now,
+			Start:          *tq.StartTime,
+			End:            *tq.EndTime,
+			Count:          len(metrics),
+			Data:           metrics,
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.Body = body
+		report.ContentType = "application/json"
+		report.Filename = fmt.Sprintf("%s-%s.json", name, stamp)
+	}
+	return report, nil
+}
@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// awsEndpoint is the S3 endpoint when none is configured; the client
+// addresses buckets virtual-hosted-style on the configured region's
+// dual-stack endpoint.
+const awsEndpoint = "s3.amazonaws.com"
+
+// S3Deliverer uploads reports to AWS S3, or to an S3-compatible store such
+// as MinIO when an endpoint is configured.
+type S3Deliverer struct {
+	client *minio.Client
+}
+
+// NewS3Deliverer creates a deliverer from the scheduler's S3 settings.
+func NewS3Deliverer(cfg config.SchedulerConfig) (*S3Deliverer, error) {
+	return newS3Deliverer(cfg, nil)
+}
+
+// newS3Deliverer creates a deliverer sending through transport, or the
+// client's default transport when it is nil.
+func newS3Deliverer(cfg config.SchedulerConfig, transport http.RoundTripper) (*S3Deliverer, error) {
+	opts := &minio.Options{
+		Creds:     credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure:    true,
+		Transport: transport,
+		Region:    cfg.S3Region,
+		// The scheduler retries transient failures itself
+		MaxRetries: 1,
+	}
+	endpoint := awsEndpoint
+	if cfg.S3Endpoint != "" {
+		u, err := url.Parse(cfg.S3Endpoint)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid S3 endpoint %q: expected http(s)://host[:port]", cfg.S3Endpoint)
+		}
+		endpoint = u.Host
+		opts.Secure = u.Scheme == "https"
+		// S3-compatible stores rarely have a DNS name per bucket
+		opts.BucketLookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(endpoint, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return &S3Deliverer{client: client}, nil
+}
+
+// Deliver uploads the report as <prefix><filename> in the target bucket.
+func (d *S3Deliverer) Deliver(ctx context.Context, target models.DeliveryTarget, report *Report) error {
+	key := target.Prefix + report.Filename
+	if err := s3utils.CheckValidBucketNameStrict(target.Bucket); err != nil {
+		return perrors.Validation(err)
+	}
+	if err := s3utils.CheckValidObjectName(key); err != nil {
+		return perrors.Validation(err)
+	}
+
+	_, err := d.client.PutObject(ctx, target.Bucket, key,
+		bytes.NewReader(report.Body), int64(len(report.Body)),
+		minio.PutObjectOptions{ContentType: report.ContentType})
+	if err == nil {
+		return nil
+	}
+	if resp := minio.ToErrorResponse(err); resp.StatusCode != 0 {
+		// The store's error code says more than the status alone
+		return fmt.Errorf("%w: %s: %s", statusError("s3", resp.StatusCode), resp.Code, resp.Message)
+	}
+	return perrors.Transient(fmt.Errorf("s3 delivery failed: %w", err))
+}
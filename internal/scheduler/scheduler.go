@@ -0,0 +1,213 @@
+// Package scheduler runs saved telemetry queries on their schedules and
+// delivers the results to webhooks, email or S3. Only the elected leader
+// among API replicas runs schedules.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+// Scheduler periodically runs due saved queries.
+type Scheduler struct {
+	store      storage.SavedQueryStore
+	reader     storage.ReadStorage
+	leader     leader.Leader
+	deliverers map[string]Deliverer
+	events     notify.Notifier
+	tick       time.Duration
+	timeout    time.Duration
+	delivery   retry.Policy
+	logger     *log.Logger
+	now        func() time.Time
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time // saved query ID -> start of its last run
+}
+
+// New creates a scheduler that reads telemetry from reader, keeps saved
+// queries and run history in store, and runs only while l leads. Each
+// finished run raises an export.completed event on events.
+func New(store storage.SavedQueryStore, reader storage.ReadStorage, l leader.Leader, deliverers map[string]Deliverer,
+	events notify.Notifier, tick, deliveryTimeout time.Duration, logger *log.Logger) *Scheduler {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Scheduler{
+		store:      store,
+		reader:     reader,
+		leader:     l,
+		deliverers: deliverers,
+		events:     events,
+		tick:       tick,
+		timeout:    deliveryTimeout,
+		delivery: retry.Policy{
+			Name:           "scheduler-delivery",
+			MaxAttempts:    3,
+			InitialBackoff: 2 * time.Second,
+			MaxBackoff:     10 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		},
+		logger:  logger,
+		now:     time.Now,
+		lastRun: make(map[string]time.Time),
+	}
+}
+
+// Run checks for due saved queries every tick until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue runs every enabled saved query whose interval has elapsed since its
+// last run. Followers forget their run times, so a replica that becomes leader
+// picks up from the stored history rather than from stale memory.
+func (s *Scheduler) runDue(ctx context.Context) {
+	if !s.leader.IsLeader() {
+		s.mu.Lock()
+		clear(s.lastRun)
+		s.mu.Unlock()
+		return
+	}
+
+	queries, err := s.store.ListSavedQueries(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Printf("Scheduler could not list saved queries: %v", err)
+		}
+		return
+	}
+
+	for _, q := range queries {
+		if !q.Enabled || ctx.Err() != nil {
+			continue
+		}
+		last, err := s.lastRunOf(ctx, q.ID)
+		if err != nil {
+			s.logger.Printf("Scheduler could not read run history for %q: %v", q.Name, err)
+			continue
+		}
+		if !last.IsZero() && s.now().Before(last.Add(q.Interval())) {
+			continue
+		}
+
+		run := s.Execute(ctx, q)
+		if run.Status == models.RunFailed {
+			s.logger.Printf("Saved query %q failed: %s", q.Name, run.Error)
+		} else {
+			s.logger.Printf("Saved query %q delivered %d rows to %s", q.Name, run.Rows, q.Target.Type)
+		}
+	}
+}
+
+// lastRunOf returns when the saved query last started, from memory or history.
+func (s *Scheduler) lastRunOf(ctx context.Context, id string) (time.Time, error) {
+	s.mu.Lock()
+	last, ok := s.lastRun[id]
+	s.mu.Unlock()
+	if ok {
+		return last, nil
+	}
+
+	runs, err := s.store.ListRuns(ctx, id, 1)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(runs) > 0 {
+		last = runs[0].StartedAt
+	}
+
+	s.mu.Lock()
+	s.lastRun[id] = last
+	s.mu.Unlock()
+	return last, nil
+}
+
+// Execute runs a saved query once, delivers the report and records the run.
+func (s *Scheduler) Execute(ctx context.Context, q *models.SavedQuery) *models.SavedQueryRun {
+	started := s.now()
+	run := &models.SavedQueryRun{
+		ID:        uuid.New().String(),
+		QueryID:   q.ID,
+		StartedAt: started,
+		Status:    models.RunSucceeded,
+	}
+
+	rows, err := s.execute(ctx, q, started)
+	run.Rows = rows
+	run.FinishedAt = s.now()
+	if err != nil {
+		run.Status = models.RunFailed
+		run.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.lastRun[q.ID] = started
+	s.mu.Unlock()
+
+	if err := s.store.RecordRun(ctx, run); err != nil {
+		s.logger.Printf("Scheduler could not record run of %q: %v", q.Name, err)
+	}
+	s.events.Notify(models.EventExportCompleted, map[string]interface{}{
+		"saved_query_id":   q.ID,
+		"saved_query_name": q.Name,
+		"run_id":           run.ID,
+		"status":           run.Status,
+		"rows":             run.Rows,
+		"target":           q.Target.Type,
+		"error":            run.Error,
+	})
+	return run
+}
+
+// execute queries, renders and delivers, returning the row count.
+func (s *Scheduler) execute(ctx context.Context, q *models.SavedQuery, now time.Time) (int, error) {
+	deliverer, ok := s.deliverers[q.Target.Type]
+	if !ok {
+		return 0, fmt.Errorf("%s delivery is not configured", q.Target.Type)
+	}
+
+	tq := q.TelemetryQuery(now)
+	metrics, err := s.reader.GetTelemetry(ctx, tq)
+	if err != nil {
+		return 0, fmt.Errorf("query failed: %w", err)
+	}
+
+	report, err := render(q, tq, metrics, now)
+	if err != nil {
+		return len(metrics), fmt.Errorf("render failed: %w", err)
+	}
+
+	err = s.delivery.Do(ctx, func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+		return deliverer.Deliver(attemptCtx, q.Target, report)
+	})
+	if err != nil {
+		return len(metrics), err
+	}
+	return len(metrics), nil
+}
@@ -0,0 +1,256 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// memoryStore is an in-memory SavedQueryStore.
+type memoryStore struct {
+	mu      sync.Mutex
+	queries []*models.SavedQuery
+	runs    []*models.SavedQueryRun
+}
+
+func (m *memoryStore) CreateSavedQuery(ctx context.Context, q *models.SavedQuery) error {
+	m.queries = append(m.queries, q)
+	return nil
+}
+
+func (m *memoryStore) GetSavedQuery(ctx context.Context, id string) (*models.SavedQuery, error) {
+	for _, q := range m.queries {
+		if q.ID == id {
+			return q, nil
+		}
+	}
+	return nil, perrors.NotFound(errors.New("saved query not found: " + id))
+}
+
+func (m *memoryStore) ListSavedQueries(ctx context.Context) ([]*models.SavedQuery, error) {
+	return m.queries, nil
+}
+
+func (m *memoryStore) UpdateSavedQuery(ctx context.Context, q *models.SavedQuery) error { return nil }
+func (m *memoryStore) DeleteSavedQuery(ctx context.Context, id string) error            { return nil }
+
+func (m *memoryStore) RecordRun(ctx context.Context, run *models.SavedQueryRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs = append([]*models.SavedQueryRun{run}, m.runs...)
+	return nil
+}
+
+func (m *memoryStore) ListRuns(ctx context.Context, queryID string, limit int) ([]*models.SavedQueryRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var runs []*models.SavedQueryRun
+	for _, run := range m.runs {
+		if run.QueryID == queryID && len(runs) < limit {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// fixedReader returns the same metrics for every query.
+type fixedReader struct {
+	metrics []*models.GPUMetric
+	queries []*models.TelemetryQuery
+}
+
+func (r *fixedReader) GetGPUs(ctx context.Context) ([]string, error) { return nil, nil }
+func (r *fixedReader) Close() error                                  { return nil }
+
+func (r *fixedReader) GetTelemetry(ctx context.Context, query *models.TelemetryQuery) ([]*models.GPUMetric, error) {
+	r.queries = append(r.queries, query)
+	return r.metrics, nil
+}
+
+// recordingDeliverer records reports and returns err.
+type recordingDeliverer struct {
+	reports []*Report
+	err     error
+}
+
+func (d *recordingDeliverer) Deliver(ctx context.Context, target models.DeliveryTarget, report *Report) error {
+	d.reports = append(d.reports, report)
+	return d.err
+}
+
+type staticLeader bool
+
+func (l staticLeader) IsLeader() bool { return bool(l) }
+
+func testMetrics() []*models.GPUMetric {
+	return []*models.GPUMetric{{
+		Timestamp:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		MetricName: "DCGM_FI_DEV_GPU_UTIL",
+		GPUID:      0,
+		UUID:       "GPU-1",
+		Hostname:   "host-001",
+		Value:      87,
+	}}
+}
+
+// recordingNotifier records raised event types.
+type recordingNotifier struct {
+	events []string
+	data   []map[string]interface{}
+}
+
+func (n *recordingNotifier) Notify(eventType string, data map[string]interface{}) {
+	n.events = append(n.events, eventType)
+	n.data = append(n.data, data)
+}
+
+func newTestScheduler(store *memoryStore, reader *fixedReader, l staticLeader, d Deliverer) *Scheduler {
+	return newNotifyingScheduler(store, reader, l, d, &recordingNotifier{})
+}
+
+func newNotifyingScheduler(store *memoryStore, reader *fixedReader, l staticLeader, d Deliverer, events *recordingNotifier) *Scheduler {
+	s := New(store, reader, l, map[string]Deliverer{models.DeliveryWebhook: d}, events, time.Minute, time.Second, log.New(io.Discard, "", 0))
+	s.delivery.InitialBackoff = time.Millisecond
+	s.delivery.MaxBackoff = time.Millisecond
+	return s
+}
+
+func webhookQuery(id, format string) *models.SavedQuery {
+	return &models.SavedQuery{
+		ID:       id,
+		Name:     "Nightly Utilization",
+		Query:    models.SavedQuerySpec{UUID: "GPU-1", Window: "1h"},
+		Schedule: "1h",
+		Format:   format,
+		Target:   models.DeliveryTarget{Type: models.DeliveryWebhook, URL: "http://example.com/hook"},
+		Enabled:  true,
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	q := webhookQuery("q-1", "json")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	report, err := render(q, q.TelemetryQuery(now), testMetrics(), now)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if report.Filename != "nightly-utilization-20240102T030405Z.json" {
+		t.Errorf("unexpected filename %q", report.Filename)
+	}
+	var doc reportDocument
+	if err := json.Unmarshal(report.Body, &doc); err != nil {
+		t.Fatalf("report is not JSON: %v", err)
+	}
+	if doc.Count != 1 || doc.SavedQueryID != "q-1" || !doc.Start.Equal(now.Add(-time.Hour)) {
+		t.Errorf("unexpected report document %+v", doc)
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	q := webhookQuery("q-1", "csv")
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	report, err := render(q, q.TelemetryQuery(now), testMetrics(), now)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if report.ContentType != "text/csv" || !strings.HasSuffix(report.Filename, ".csv") {
+		t.Errorf("unexpected CSV report %q (%s)", report.Filename, report.ContentType)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(report.Body)), "\n"); len(lines) != 2 {
+		t.Errorf("expected header and one row, got %d lines", len(lines))
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{}
+	due := webhookQuery("due", "json")
+	recent := webhookQuery("recent", "json")
+	disabled := webhookQuery("disabled", "json")
+	disabled.Enabled = false
+	store.queries = []*models.SavedQuery{due, recent, disabled}
+	store.runs = []*models.SavedQueryRun{{QueryID: "recent", StartedAt: now.Add(-10 * time.Minute)}}
+
+	reader := &fixedReader{metrics: testMetrics()}
+	deliverer := &recordingDeliverer{}
+	s := newTestScheduler(store, reader, true, deliverer)
+	s.now = func() time.Time { return now }
+
+	s.runDue(context.Background())
+
+	if len(deliverer.reports) != 1 || deliverer.reports[0].Query.ID != "due" {
+		t.Fatalf("expected only the due query to run, got %d reports", len(deliverer.reports))
+	}
+	runs, _ := store.ListRuns(context.Background(), "due", 10)
+	if len(runs) != 1 || runs[0].Status != models.RunSucceeded || runs[0].Rows != 1 {
+		t.Errorf("expected one successful run recorded, got %+v", runs)
+	}
+
+	// Nothing is due again until the schedule elapses
+	s.runDue(context.Background())
+	if len(deliverer.reports) != 1 {
+		t.Errorf("expected no rerun within the schedule, got %d reports", len(deliverer.reports))
+	}
+	s.now = func() time.Time { return now.Add(time.Hour) }
+	s.runDue(context.Background())
+	if len(deliverer.reports) != 3 {
+		t.Errorf("expected both queries to run after an hour, got %d reports", len(deliverer.reports))
+	}
+}
+
+func TestRunDueFollowerDoesNothing(t *testing.T) {
+	store := &memoryStore{queries: []*models.SavedQuery{webhookQuery("q-1", "json")}}
+	deliverer := &recordingDeliverer{}
+	s := newTestScheduler(store, &fixedReader{}, false, deliverer)
+
+	s.runDue(context.Background())
+
+	if len(deliverer.reports) != 0 || len(store.runs) != 0 {
+		t.Error("expected a follower not to run saved queries")
+	}
+}
+
+func TestExecuteRecordsFailure(t *testing.T) {
+	store := &memoryStore{}
+	deliverer := &recordingDeliverer{err: perrors.Permanent(errors.New("webhook delivery returned HTTP 404"))}
+	events := &recordingNotifier{}
+	s := newNotifyingScheduler(store, &fixedReader{metrics: testMetrics()}, true, deliverer, events)
+
+	run := s.Execute(context.Background(), webhookQuery("q-1", "json"))
+
+	if run.Status != models.RunFailed || !strings.Contains(run.Error, "404") {
+		t.Errorf("expected failed run with delivery error, got %+v", run)
+	}
+	if len(deliverer.reports) != 1 {
+		t.Errorf("expected permanent errors not to be retried, got %d attempts", len(deliverer.reports))
+	}
+	if len(store.runs) != 1 {
+		t.Errorf("expected failed run to be recorded, got %d runs", len(store.runs))
+	}
+	if len(events.events) != 1 || events.events[0] != models.EventExportCompleted || events.data[0]["status"] != models.RunFailed {
+		t.Errorf("expected export.completed event with failed status, got %v %v", events.events, events.data)
+	}
+}
+
+func TestExecuteUnconfiguredTarget(t *testing.T) {
+	s := newTestScheduler(&memoryStore{}, &fixedReader{}, true, &recordingDeliverer{})
+	q := webhookQuery("q-1", "json")
+	q.Target = models.DeliveryTarget{Type: models.DeliveryEmail, To: []string{"ops@example.com"}}
+
+	run := s.Execute(context.Background(), q)
+
+	if run.Status != models.RunFailed || run.Error != "email delivery is not configured" {
+		t.Errorf("expected unconfigured delivery failure, got %+v", run)
+	}
+}
@@ -0,0 +1,66 @@
+// Package schemas publishes JSON Schemas of the pipeline's wire formats,
+// generated from the Go types that decode them, so producers in other
+// languages can validate payloads before publishing. The same documents are
+// checked in under schemas/ and served at /api/v1/schemas.
+package schemas
+
+import (
+	"encoding/json"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/schema"
+)
+
+// baseID prefixes each document's $id.
+const baseID = "https://github.com/cisco/gpu-telemetry-pipeline/schemas/"
+
+// Document is one published schema.
+type Document struct {
+	// Name identifies the schema in /api/v1/schemas/{name}
+	Name string
+
+	// File is the document's file name under schemas/
+	File string
+
+	Schema *schema.Schema
+}
+
+// document generates a named schema for v.
+func document(name string, v any, description string) Document {
+	file := name + ".schema.json"
+	s := schema.Generate(v, baseID+file)
+	s.Description = description
+	return Document{Name: name, File: file, Schema: s}
+}
+
+// All returns every published schema, in a fixed order.
+func All() []Document {
+	return []Document{
+		document("gpu-metric", models.GPUMetric{},
+			"A single DCGM telemetry data point."),
+		document("metric-batch", models.MetricBatch{},
+			"A batch of GPU metrics, published to the MQ as the payload of a publish message."),
+		document("protocol-message", mq.ProtocolMessage{},
+			"A frame of the MQ's TCP protocol, sent after a 4-byte big-endian length. A publish message's payload is a metric-batch."),
+	}
+}
+
+// Get returns the named schema.
+func Get(name string) (Document, bool) {
+	for _, d := range All() {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Document{}, false
+}
+
+// Marshal returns a document as indented JSON, as published under schemas/.
+func Marshal(doc Document) ([]byte, error) {
+	data, err := json.MarshalIndent(doc.Schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,104 @@
+package schemas
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the published schemas under schemas/")
+
+// publishedDir holds the checked-in schema documents.
+var publishedDir = filepath.Join("..", "..", "schemas")
+
+// TestPublishedSchemasUpToDate fails when a Go type changed without
+// regenerating schemas/; run go test ./internal/schemas -update to fix it.
+func TestPublishedSchemasUpToDate(t *testing.T) {
+	for _, doc := range All() {
+		want, err := Marshal(doc)
+		if err != nil {
+			t.Fatalf("%s: %v", doc.Name, err)
+		}
+		path := filepath.Join(publishedDir, doc.File)
+		if *update {
+			if err := os.WriteFile(path, want, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v (run go test ./internal/schemas -update)", doc.Name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is stale; run go test ./internal/schemas -update", path)
+		}
+	}
+}
+
+// TestProducerPayloads checks batches written the way Python (json.dumps of
+// isoformat timestamps) and Java (Jackson) producers write them validate and
+// decode, and that a typical type mistake is caught before it is published.
+func TestProducerPayloads(t *testing.T) {
+	batch, ok := Get("metric-batch")
+	if !ok {
+		t.Fatal("metric-batch schema not found")
+	}
+	for _, file := range []string{"python_batch.json", "java_batch.json"} {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Schema.Validate(data); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+		var decoded models.MetricBatch
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("%s validates but does not decode: %v", file, err)
+		} else if len(decoded.Metrics) != 1 || decoded.Metrics[0].UUID == "" {
+			t.Errorf("%s decoded to %+v", file, decoded)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "invalid_batch.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = batch.Schema.Validate(data)
+	if err == nil {
+		t.Fatal("invalid_batch.json validates")
+	}
+	for _, want := range []string{"$.collected_at", "$.metrics[0].timestamp", "$.metrics[0].gpu_id", "$.metrics[0].value"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+	// Every violation the schema reports is one Go would reject too
+	if json.Unmarshal(data, &models.MetricBatch{}) == nil {
+		t.Error("invalid_batch.json decodes in Go")
+	}
+}
+
+func TestProtocolMessageSchema(t *testing.T) {
+	doc, ok := Get("protocol-message")
+	if !ok {
+		t.Fatal("protocol-message schema not found")
+	}
+	frame, err := json.Marshal(mq.ProtocolMessage{Type: mq.MsgTypePublish, RequestID: "1", Payload: json.RawMessage(`{"metrics": []}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Schema.Validate(frame); err != nil {
+		t.Errorf("Validate(publish frame) = %v", err)
+	}
+	if err := doc.Schema.Validate([]byte(`{"request_id": 1}`)); err == nil {
+		t.Error("frame without a type validates")
+	}
+}
@@ -0,0 +1,98 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+func testSigner(t *testing.T, id string, b byte) *Signer {
+	t.Helper()
+	seed := base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), ed25519.SeedSize)))
+	s, err := NewSigner(id, config.SigningConfig{Key: seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSignAndVerify(t *testing.T) {
+	streamer := testSigner(t, "streamer-0", 'a')
+	registry, err := NewRegistry(config.VerificationConfig{Keys: []string{streamer.PublicKey()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"batch_id":"b1","source":"streamer-0"}`)
+	metadata := map[string]string{}
+	streamer.Sign(payload, metadata)
+	if metadata[MetaSigner] != "streamer-0" || metadata[MetaSignature] == "" {
+		t.Fatalf("expected the signature in the metadata, got %v", metadata)
+	}
+	if err := registry.Verify(payload, metadata, "streamer-0"); err != nil {
+		t.Fatalf("expected the batch verified, got %v", err)
+	}
+
+	// A batch claiming another source, or altered, fails
+	if err := registry.Verify(payload, metadata, "streamer-1"); err == nil || !strings.Contains(err.Error(), "signed by streamer-0") {
+		t.Errorf("expected a source mismatch, got %v", err)
+	}
+	if err := registry.Verify([]byte(`{"batch_id":"b2","source":"streamer-0"}`), metadata, "streamer-0"); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("expected an invalid signature, got %v", err)
+	}
+	if err := registry.Verify(payload, map[string]string{}, "streamer-0"); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned, got %v", err)
+	}
+
+	// A producer signing with a key the registry does not hold
+	spoofed := map[string]string{}
+	testSigner(t, "streamer-0", 'b').Sign(payload, spoofed)
+	if err := registry.Verify(payload, spoofed, "streamer-0"); err == nil {
+		t.Error("expected a signature by another key to fail")
+	}
+	rogue := map[string]string{}
+	testSigner(t, "rogue", 'b').Sign(payload, rogue)
+	if err := registry.Verify(payload, rogue, "rogue"); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("expected an unregistered producer, got %v", err)
+	}
+
+	var none *Registry
+	if err := none.Verify(payload, map[string]string{}, "streamer-0"); err != nil {
+		t.Errorf("expected no registry to verify nothing, got %v", err)
+	}
+}
+
+func TestNewFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	seed := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", ed25519.SeedSize)))
+	keyPath := filepath.Join(dir, "signing.key")
+	if err := os.WriteFile(keyPath, []byte(seed+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSigner("streamer-0", config.SigningConfig{KeyFile: keyPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registryPath := filepath.Join(dir, "producers")
+	if err := os.WriteFile(registryPath, []byte("# streamers\n"+s.PublicKey()+"\n\n"+testSigner(t, "streamer-1", 'b').PublicKey()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRegistry(config.VerificationConfig{KeysFile: registryPath})
+	if err != nil || r.Len() != 2 {
+		t.Fatalf("expected two producers from the file, got %v, %v", r, err)
+	}
+
+	if s, err := NewSigner("streamer-0", config.SigningConfig{}); s != nil || err != nil {
+		t.Errorf("expected no signer without a key, got %v, %v", s, err)
+	}
+	if r, err := NewRegistry(config.VerificationConfig{}); r != nil || err != nil {
+		t.Errorf("expected no registry without keys, got %v, %v", r, err)
+	}
+}
@@ -0,0 +1,141 @@
+// Package skew detects producers whose clock disagrees with the MQ
+// server's. A batch's skew is how long before the server received it the
+// streamer says it was collected; beyond the threshold the collector
+// accepts, corrects, flags or rejects the batch, and the skew of each
+// streamer instance is tracked so a drifting host stands out.
+package skew
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// SourceStats reports the skew of one streamer instance.
+type SourceStats struct {
+	// Source is the streamer instance, as named in its batches
+	Source string `json:"source"`
+
+	// Batches counts the batches checked; Skewed counts those past the
+	// threshold, and Corrected, Flagged and Rejected those handled by the
+	// correct, flag and reject policies
+	Batches   int64 `json:"batches"`
+	Skewed    int64 `json:"skewed"`
+	Corrected int64 `json:"corrected"`
+	Flagged   int64 `json:"flagged"`
+	Rejected  int64 `json:"rejected"`
+
+	// LastSkewSeconds is the skew of the latest batch; positive when the
+	// producer's clock is behind the server's
+	LastSkewSeconds float64 `json:"last_skew_seconds"`
+
+	// MaxSkewSeconds is the largest skew seen either way, with its sign
+	MaxSkewSeconds float64 `json:"max_skew_seconds"`
+
+	// LastSeen is when the latest batch was received
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Stats reports a monitor's settings and the skew of each streamer.
+type Stats struct {
+	// Threshold is how far apart the clocks may be before a batch is skewed
+	Threshold string `json:"threshold"`
+
+	// Policy is what happens to skewed batches
+	Policy string `json:"policy"`
+
+	// Sources holds each streamer instance seen, by name
+	Sources []SourceStats `json:"sources"`
+}
+
+// Monitor measures the skew of batches and applies the clock-skew policy.
+// It is safe for concurrent use.
+type Monitor struct {
+	cfg config.ClockSkewConfig
+
+	mu      sync.Mutex
+	sources map[string]*SourceStats
+}
+
+// New creates a monitor applying cfg.
+func New(cfg config.ClockSkewConfig) *Monitor {
+	return &Monitor{cfg: cfg, sources: make(map[string]*SourceStats)}
+}
+
+// Check measures batch's skew against serverTime, when the MQ server
+// received it, and applies the policy. It returns false if the batch is
+// rejected. Under the correct policy the batch's collection time and its
+// metrics' timestamps are shifted by the skew; under the flag policy its
+// metrics are marked Skewed. Batches without a collection time are passed.
+func (m *Monitor) Check(batch *models.MetricBatch, serverTime time.Time) bool {
+	if batch.CollectedAt.IsZero() || serverTime.IsZero() {
+		return true
+	}
+	skew := serverTime.Sub(batch.CollectedAt)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := m.sources[batch.Source]
+	if st == nil {
+		st = &SourceStats{Source: batch.Source}
+		m.sources[batch.Source] = st
+	}
+	st.Batches++
+	st.LastSkewSeconds = skew.Seconds()
+	st.LastSeen = serverTime
+	if math.Abs(skew.Seconds()) > math.Abs(st.MaxSkewSeconds) {
+		st.MaxSkewSeconds = skew.Seconds()
+	}
+	if abs(skew) <= m.cfg.Threshold {
+		return true
+	}
+
+	st.Skewed++
+	switch m.cfg.Policy {
+	case config.SkewPolicyCorrect:
+		for i := range batch.Metrics {
+			batch.Metrics[i].Timestamp = batch.Metrics[i].Timestamp.Add(skew)
+		}
+		batch.CollectedAt = serverTime
+		st.Corrected++
+	case config.SkewPolicyFlag:
+		for i := range batch.Metrics {
+			batch.Metrics[i].Skewed = true
+		}
+		st.Flagged++
+	case config.SkewPolicyReject:
+		st.Rejected++
+		return false
+	}
+	return true
+}
+
+// Stats returns the settings and the skew of each streamer seen, sorted
+// by source.
+func (m *Monitor) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st := Stats{
+		Threshold: m.cfg.Threshold.String(),
+		Policy:    m.cfg.Policy,
+		Sources:   make([]SourceStats, 0, len(m.sources)),
+	}
+	for _, s := range m.sources {
+		st.Sources = append(st.Sources, *s)
+	}
+	sort.Slice(st.Sources, func(i, j int) bool { return st.Sources[i].Source < st.Sources[j].Source })
+	return st
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,92 @@
+package skew
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// batchFrom returns a batch from source collected skew before now.
+func batchFrom(source string, skew time.Duration) *models.MetricBatch {
+	collected := now.Add(-skew)
+	return &models.MetricBatch{
+		Source:      source,
+		CollectedAt: collected,
+		Metrics: []models.GPUMetric{
+			{UUID: "GPU-1", MetricName: "DCGM_FI_DEV_GPU_UTIL", Timestamp: collected.Add(-time.Second)},
+		},
+	}
+}
+
+func TestMonitorPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy string
+		keep   bool
+	}{
+		{config.SkewPolicyAccept, true},
+		{config.SkewPolicyCorrect, true},
+		{config.SkewPolicyFlag, true},
+		{config.SkewPolicyReject, false},
+	} {
+		m := New(config.ClockSkewConfig{Threshold: time.Minute, Policy: tc.policy})
+
+		if !m.Check(batchFrom("streamer-1", 10*time.Second), now) {
+			t.Errorf("%s: expected a batch within the threshold kept", tc.policy)
+		}
+
+		batch := batchFrom("streamer-1", 2*time.Hour)
+		if keep := m.Check(batch, now); keep != tc.keep {
+			t.Errorf("%s: expected keep=%v, got %v", tc.policy, tc.keep, keep)
+		}
+		metric := batch.Metrics[0]
+		if corrected := metric.Timestamp.Equal(now.Add(-time.Second)); corrected != (tc.policy == config.SkewPolicyCorrect) {
+			t.Errorf("%s: unexpected timestamp %v", tc.policy, metric.Timestamp)
+		}
+		if metric.Skewed != (tc.policy == config.SkewPolicyFlag) {
+			t.Errorf("%s: unexpected skewed mark", tc.policy)
+		}
+
+		st := m.Stats()
+		if len(st.Sources) != 1 {
+			t.Fatalf("%s: expected one source, got %+v", tc.policy, st.Sources)
+		}
+		src := st.Sources[0]
+		if src.Batches != 2 || src.Skewed != 1 || src.MaxSkewSeconds != (2*time.Hour).Seconds() || !src.LastSeen.Equal(now) {
+			t.Errorf("%s: unexpected stats %+v", tc.policy, src)
+		}
+		handled := int64(1)
+		if tc.policy == config.SkewPolicyAccept {
+			handled = 0
+		}
+		if src.Corrected+src.Flagged+src.Rejected != handled {
+			t.Errorf("%s: expected %d skewed batches counted by the policy, got %+v", tc.policy, handled, src)
+		}
+	}
+}
+
+func TestMonitorSources(t *testing.T) {
+	m := New(config.ClockSkewConfig{Threshold: time.Minute, Policy: config.SkewPolicyAccept})
+
+	// A clock ahead of the server's skews the other way
+	m.Check(batchFrom("streamer-2", -5*time.Minute), now)
+	m.Check(batchFrom("streamer-1", time.Second), now)
+	m.Check(batchFrom("streamer-2", time.Second), now)
+
+	// Batches without a collection time are not measured
+	if !m.Check(&models.MetricBatch{Source: "streamer-3"}, now) {
+		t.Error("expected a batch without a collection time kept")
+	}
+
+	st := m.Stats()
+	if len(st.Sources) != 2 || st.Sources[0].Source != "streamer-1" || st.Sources[1].Source != "streamer-2" {
+		t.Fatalf("expected both streamers sorted, got %+v", st.Sources)
+	}
+	s2 := st.Sources[1]
+	if s2.MaxSkewSeconds != -300 || s2.LastSkewSeconds != 1 || s2.Skewed != 1 {
+		t.Errorf("unexpected streamer-2 stats %+v", s2)
+	}
+}
@@ -0,0 +1,231 @@
+// Package slo evaluates the ingest-latency service level objective, such as
+// 95% of metrics stored within 30s of collection over a sliding day, from
+// the lineage the collectors record for every batch. It raises slo.burn when
+// the error budget burns too fast over both a short and a long window, so a
+// sudden slowdown alerts within minutes while a blip does not.
+package slo
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/notify"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// settle is how long after a batch was received its lineage may still be
+// recorded. Minutes this recent are read again on every evaluation.
+const settle = 5 * time.Minute
+
+// bucket counts the metrics of the batches received in one minute.
+type bucket struct {
+	metrics int64
+	late    int64
+}
+
+// Tracker periodically reads recent batch lineage into per-minute counts of
+// metrics stored in time and late, and evaluates the objective over them.
+// Every replica evaluates it; burn-rate events are raised while l leads.
+type Tracker struct {
+	reader storage.LineageReader
+	cfg    config.SLOConfig
+	leader leader.Leader
+	events notify.Notifier
+	logger *log.Logger
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	buckets  map[int64]*bucket // minute (Unix time / 60) -> counts
+	loaded   time.Time         // lineage is read up to here
+	notified bool              // whether slo.burn was raised as burning by this replica
+	status   models.SLOStatus
+}
+
+// New creates a tracker reading lineage from reader. Burn-rate events are
+// raised on events while l leads.
+func New(reader storage.LineageReader, cfg config.SLOConfig, l leader.Leader, events notify.Notifier, logger *log.Logger) *Tracker {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Tracker{
+		reader:  reader,
+		cfg:     cfg,
+		leader:  l,
+		events:  events,
+		logger:  logger,
+		clock:   clock.Real,
+		buckets: make(map[int64]*bucket),
+		status: models.SLOStatus{
+			Objective:         cfg.Objective,
+			Latency:           cfg.Latency.String(),
+			Window:            cfg.Window.String(),
+			Compliance:        1,
+			Met:               true,
+			BudgetRemaining:   1,
+			BurnRates:         []models.SLOBurnRate{},
+			BurnRateThreshold: cfg.BurnRate,
+		},
+	}
+}
+
+// Run evaluates the objective every interval until ctx is done.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := t.clock.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.Evaluate(ctx); err != nil && ctx.Err() == nil {
+			t.logger.Printf("SLO evaluation failed, keeping the previous status: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Evaluate reads the lineage received since the last evaluation, and the
+// minutes still settling again, then recomputes compliance and burn rates.
+// The first evaluation reads the whole window.
+func (t *Tracker) Evaluate(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	windowStart := now.Add(-t.cfg.Window).Truncate(time.Minute)
+	from := t.loaded.Add(-settle).Truncate(time.Minute)
+	if from.Before(windowStart) {
+		from = windowStart
+	}
+
+	batches, err := t.reader.ListBatches(ctx, from, now, 0)
+	if err != nil {
+		t.status.LastError = err.Error()
+		return err
+	}
+
+	// The minutes read are replaced, so lineage read twice counts once
+	for minute := range t.buckets {
+		if minute >= from.Unix()/60 || minute < windowStart.Unix()/60 {
+			delete(t.buckets, minute)
+		}
+	}
+	for _, b := range batches {
+		// Replayed batches are old by design, and batches without both
+		// times cannot be measured
+		if b.Replayed || b.CollectedAt.IsZero() || b.StoredAt.IsZero() {
+			continue
+		}
+		minute := b.ReceivedAt.Unix() / 60
+		c := t.buckets[minute]
+		if c == nil {
+			c = &bucket{}
+			t.buckets[minute] = c
+		}
+		c.metrics += int64(b.MetricCount)
+		if b.StoredAt.Sub(b.CollectedAt) > t.cfg.Latency {
+			c.late += int64(b.MetricCount)
+		}
+	}
+	t.loaded = now
+
+	t.update(now)
+	return nil
+}
+
+// sum totals the counts of the minutes within d of now.
+func (t *Tracker) sum(now time.Time, d time.Duration) (metrics, late int64) {
+	start := now.Add(-d).Truncate(time.Minute).Unix() / 60
+	for minute, c := range t.buckets {
+		if minute >= start {
+			metrics += c.metrics
+			late += c.late
+		}
+	}
+	return metrics, late
+}
+
+// burnRate is how many times faster than sustainable late metrics spend
+// the error budget.
+func (t *Tracker) burnRate(metrics, late int64) float64 {
+	if metrics == 0 {
+		return 0
+	}
+	return float64(late) / float64(metrics) / (1 - t.cfg.Objective)
+}
+
+// update recomputes the status from the counts and raises slo.burn when
+// the alert changes state on the leader.
+func (t *Tracker) update(now time.Time) {
+	st := &t.status
+	st.EvaluatedAt = now
+	st.LastError = ""
+
+	st.Metrics, st.Late = t.sum(now, t.cfg.Window)
+	st.Compliance = 1
+	if st.Metrics > 0 {
+		st.Compliance = float64(st.Metrics-st.Late) / float64(st.Metrics)
+	}
+	st.Met = st.Compliance >= t.cfg.Objective
+	st.BudgetRemaining = 1 - t.burnRate(st.Metrics, st.Late)
+
+	burning := true
+	st.BurnRates = make([]models.SLOBurnRate, 0, 2)
+	for _, d := range []time.Duration{t.cfg.ShortWindow, t.cfg.LongWindow} {
+		metrics, late := t.sum(now, d)
+		rate := t.burnRate(metrics, late)
+		st.BurnRates = append(st.BurnRates, models.SLOBurnRate{Window: d.String(), BurnRate: rate, Metrics: metrics, Late: late})
+		if rate < t.cfg.BurnRate {
+			burning = false
+		}
+	}
+
+	if burning != st.Burning {
+		st.Burning = burning
+		st.BurningSince = time.Time{}
+		if burning {
+			st.BurningSince = now
+			t.logger.Printf("SLO burn-rate alert firing: burn rates %.1f (%v) and %.1f (%v), threshold %.1f",
+				st.BurnRates[0].BurnRate, t.cfg.ShortWindow, st.BurnRates[1].BurnRate, t.cfg.LongWindow, t.cfg.BurnRate)
+		} else {
+			t.logger.Printf("SLO burn-rate alert resolved: compliance %.4f over %v", st.Compliance, t.cfg.Window)
+		}
+	}
+
+	// Followers forget what they raised, so a new leader raises the current state
+	if !t.leader.IsLeader() {
+		t.notified = false
+		return
+	}
+	if burning == t.notified || t.events == nil {
+		return
+	}
+	t.notified = burning
+	state := "firing"
+	if !burning {
+		state = "resolved"
+	}
+	t.events.Notify(models.EventSLOBurn, map[string]interface{}{
+		"state":      state,
+		"objective":  t.cfg.Objective,
+		"latency":    t.cfg.Latency.String(),
+		"compliance": st.Compliance,
+		"burn_rates": st.BurnRates,
+		"threshold":  t.cfg.BurnRate,
+	})
+}
+
+// Status returns the latest evaluation.
+func (t *Tracker) Status() models.SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
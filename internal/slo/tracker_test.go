@@ -0,0 +1,166 @@
+package slo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/leader"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// lineageLog serves recorded lineage by receive time.
+type lineageLog struct {
+	batches []*models.BatchLineage
+	err     error
+}
+
+func (l *lineageLog) GetBatch(ctx context.Context, id string) (*models.BatchLineage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (l *lineageLog) ListBatches(ctx context.Context, start, end time.Time, limit int) ([]*models.BatchLineage, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	var batches []*models.BatchLineage
+	for _, b := range l.batches {
+		if !b.ReceivedAt.Before(start) && b.ReceivedAt.Before(end) {
+			batches = append(batches, b)
+		}
+	}
+	return batches, nil
+}
+
+// record adds a batch of 100 metrics received at, stored latency after collection.
+func (l *lineageLog) record(at time.Time, latency time.Duration) {
+	l.batches = append(l.batches, &models.BatchLineage{
+		MetricCount: 100, CollectedAt: at.Add(-time.Second), ReceivedAt: at, StoredAt: at.Add(latency - time.Second),
+	})
+}
+
+// events records the events raised.
+type events struct {
+	raised []map[string]interface{}
+}
+
+func (e *events) Notify(eventType string, data map[string]interface{}) {
+	e.raised = append(e.raised, data)
+}
+
+// leaderFlag is a Leader switched by the test.
+type leaderFlag bool
+
+func (l *leaderFlag) IsLeader() bool { return bool(*l) }
+
+func newTracker(reader *lineageLog, l leader.Leader, ev *events) (*Tracker, *clock.Simulated) {
+	cfg := config.SLOConfig{Objective: 0.95, Latency: 30 * time.Second, Window: 24 * time.Hour, Interval: time.Minute,
+		BurnRate: 10, ShortWindow: 5 * time.Minute, LongWindow: time.Hour}
+	tr := New(reader, cfg, l, ev, log.New(io.Discard, "", 0))
+	c := clock.NewSimulated(now)
+	tr.clock = c
+	return tr, c
+}
+
+func TestTrackerCompliance(t *testing.T) {
+	reader := &lineageLog{}
+	for i := 0; i < 20; i++ {
+		reader.record(now.Add(-time.Duration(i+2)*time.Hour), time.Second)
+	}
+	reader.record(now.Add(-3*time.Hour), time.Minute)
+	reader.record(now.Add(-30*time.Hour), time.Minute) // outside the window
+	replayed := &models.BatchLineage{MetricCount: 100, CollectedAt: now.Add(-48 * time.Hour), ReceivedAt: now.Add(-time.Hour), StoredAt: now.Add(-time.Hour), Replayed: true}
+	reader.batches = append(reader.batches, replayed)
+
+	tr, _ := newTracker(reader, leader.Always{}, nil)
+	if err := tr.Evaluate(context.Background()); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	st := tr.Status()
+	if st.Metrics != 2100 || st.Late != 100 {
+		t.Fatalf("expected 2100 metrics with 100 late, got %+v", st)
+	}
+	if !st.Met || st.Compliance < 0.952 || st.Compliance > 0.953 {
+		t.Errorf("expected the objective met at 20/21, got %+v", st)
+	}
+	if st.BudgetRemaining < 0.04 || st.BudgetRemaining > 0.05 {
+		t.Errorf("expected under 5%% of the budget left, got %v", st.BudgetRemaining)
+	}
+	if len(st.BurnRates) != 2 || st.BurnRates[0].Metrics != 0 || st.Burning {
+		t.Errorf("expected no recent metrics and no burn, got %+v", st.BurnRates)
+	}
+}
+
+func TestTrackerBurnRateAlert(t *testing.T) {
+	reader := &lineageLog{}
+	ev := &events{}
+	isLeader := leaderFlag(true)
+	tr, c := newTracker(reader, &isLeader, ev)
+	ctx := context.Background()
+
+	// Three quarters of the metrics late over the last hour burns the budget
+	// 15 times too fast
+	for i := 0; i < 60; i++ {
+		latency := time.Second
+		if i%4 != 0 {
+			latency = time.Minute
+		}
+		reader.record(now.Add(-time.Duration(i)*time.Minute-time.Second), latency)
+	}
+	if err := tr.Evaluate(ctx); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	st := tr.Status()
+	if !st.Burning || !st.BurningSince.Equal(now) || st.BurnRates[1].BurnRate < 14.9 {
+		t.Fatalf("expected the burn-rate alert firing, got %+v", st)
+	}
+	if len(ev.raised) != 1 || ev.raised[0]["state"] != "firing" {
+		t.Fatalf("expected a firing event, got %v", ev.raised)
+	}
+
+	// Lineage recorded late for a minute already read is picked up, once
+	c.Advance(time.Minute)
+	reader.record(now.Add(-30*time.Second), time.Second)
+	if err := tr.Evaluate(ctx); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if st := tr.Status(); st.Metrics != 6100 {
+		t.Errorf("expected 6100 metrics, got %d", st.Metrics)
+	}
+	if len(ev.raised) != 1 {
+		t.Errorf("expected no repeated event, got %v", ev.raised)
+	}
+
+	// Once the short window is in time again the alert resolves
+	for i := 0; i < 10; i++ {
+		reader.record(now.Add(time.Duration(i)*time.Minute), time.Second)
+	}
+	c.Advance(10 * time.Minute)
+	if err := tr.Evaluate(ctx); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if st := tr.Status(); st.Burning {
+		t.Errorf("expected the alert resolved, got %+v", st.BurnRates)
+	}
+	if len(ev.raised) != 2 || ev.raised[1]["state"] != "resolved" {
+		t.Errorf("expected a resolved event, got %v", ev.raised)
+	}
+
+	// Followers raise nothing, and a failed read keeps the status
+	isLeader = false
+	reader.err = errors.New("influxdb unavailable")
+	if err := tr.Evaluate(ctx); err == nil {
+		t.Fatal("expected the read error")
+	}
+	if st := tr.Status(); st.LastError == "" || st.Metrics != 7100 {
+		t.Errorf("expected the previous status with the error, got %+v", st)
+	}
+}
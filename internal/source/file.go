@@ -0,0 +1,116 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/parser"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// FileName is the built-in source reading a CSV or Prometheus text file.
+const FileName = "file"
+
+func init() {
+	Register(FileName, func(cfg Config) (Source, error) {
+		return &File{path: cfg.Path, format: cfg.Format, mappings: cfg.Mappings, columns: cfg.Columns}, nil
+	})
+}
+
+// File reads a telemetry file with the parser for its format. Its
+// checkpoint is the line of the last metric returned.
+type File struct {
+	path     string
+	format   string
+	mappings []parser.Mapping
+	columns  []string
+
+	reader parser.Reader
+	line   int
+}
+
+// Open opens the file and skips the metrics up to the checkpoint line.
+func (f *File) Open(ctx context.Context, checkpoint string) error {
+	after := 0
+	if checkpoint != "" {
+		n, err := strconv.Atoi(checkpoint)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid file checkpoint %q, want a line number", checkpoint)
+		}
+		after = n
+	}
+
+	reader, err := parser.OpenColumns(f.path, f.format, f.mappings, f.columns)
+	if err != nil {
+		return err
+	}
+	f.reader, f.line = reader, 0
+	for f.line < after {
+		metric, err := reader.ReadNext()
+		if err != nil && reader.Line() <= f.line {
+			// The error does not move past a record, so skipping cannot go on
+			reader.Close()
+			return err
+		}
+		if err != nil {
+			f.line = reader.Line()
+			continue
+		}
+		if metric == nil {
+			break
+		}
+		f.line = reader.Line()
+	}
+	return nil
+}
+
+// ReadBatch reads up to max metrics. A rejected record ends the batch and
+// is returned as the error, with the metrics read before it.
+func (f *File) ReadBatch(ctx context.Context, max int) ([]*models.GPUMetric, error) {
+	var metrics []*models.GPUMetric
+	for len(metrics) < max {
+		metric, err := f.reader.ReadNext()
+		if err != nil {
+			f.line = f.reader.Line()
+			return metrics, err
+		}
+		if metric == nil {
+			if len(metrics) == 0 {
+				return nil, io.EOF
+			}
+			break
+		}
+		f.line = f.reader.Line()
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// Checkpoint returns the line of the last metric returned.
+func (f *File) Checkpoint() string {
+	return strconv.Itoa(f.line)
+}
+
+// Line returns the line of the last metric returned.
+func (f *File) Line() int {
+	return f.line
+}
+
+// Mapping returns the CSV mapping the file is read through and its header
+// fingerprint, or empty strings when it is read by the expected names.
+func (f *File) Mapping() (name, fingerprint string) {
+	if p, ok := f.reader.(*parser.CSVParser); ok && p.Mapping() != "" {
+		return p.Mapping(), p.Fingerprint()
+	}
+	return "", ""
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	if f.reader == nil {
+		return nil
+	}
+	return f.reader.Close()
+}
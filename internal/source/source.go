@@ -0,0 +1,120 @@
+// Package source defines where the streamer reads telemetry from. A Source
+// opens an input, reads it in batches and reports a checkpoint to resume
+// from. Sources register under a name, so site-specific ones, such as
+// proprietary fabric counters or SMI wrappers, can be compiled into the
+// streamer with a blank import or built as Go plugins and loaded at
+// startup, without changing the streamer itself.
+package source
+
+import (
+	"context"
+	"fmt"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/parser"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Source produces telemetry for the streamer. The streamer calls its
+// methods from one goroutine.
+type Source interface {
+	// Open starts reading after checkpoint, a value Checkpoint returned
+	// earlier, or from the beginning when checkpoint is empty
+	Open(ctx context.Context, checkpoint string) error
+
+	// ReadBatch returns up to max metrics. A finite source returns io.EOF
+	// once it is exhausted; an endless one waits for metrics until ctx is
+	// done. Metrics returned with another error are kept, and the error,
+	// such as a rejected record, is logged.
+	ReadBatch(ctx context.Context, max int) ([]*models.GPUMetric, error)
+
+	// Checkpoint returns the position after the last metric returned, to
+	// pass to Open to resume there
+	Checkpoint() string
+
+	// Close releases the source
+	Close() error
+}
+
+// LineReporter is implemented by sources reading a file, so batches record
+// the input lines they came from.
+type LineReporter interface {
+	// Line returns the 1-based input line of the last metric returned
+	Line() int
+}
+
+// Config configures a source.
+type Config struct {
+	// Path and Format are the input file and its format, for file sources
+	Path   string
+	Format string
+
+	// Mappings are the CSV column layouts files are read through
+	Mappings []parser.Mapping
+
+	// Columns are the CSV columns parsed, nil for all
+	Columns []string
+
+	// Options holds source-specific settings
+	Options map[string]string
+}
+
+// Factory creates a source from its configuration.
+type Factory func(cfg Config) (Source, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a source available under name. Sources register from an
+// init function; registering a name twice panics.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if factory == nil {
+		panic("source: Register factory is nil for " + name)
+	}
+	if _, dup := factories[name]; dup {
+		panic("source: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// New creates the source registered under name.
+func New(name string, cfg Config) (Source, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source %q (registered: %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(cfg)
+}
+
+// Names returns the registered source names, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPlugins opens each Go plugin in paths, whose init functions register
+// their sources. Plugins must be built with -buildmode=plugin against the
+// same version of this module as the streamer.
+func LoadPlugins(paths []string) error {
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return fmt.Errorf("failed to load source plugin %s: %w", path, err)
+		}
+	}
+	return nil
+}
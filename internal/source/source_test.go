@@ -0,0 +1,106 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+const sampleCSV = `timestamp,metric_name,gpu_id,device,uuid,modelName,Hostname,container,pod,namespace,value,labels_raw
+2025-07-18T20:42:34Z,DCGM_FI_DEV_GPU_UTIL,0,nvidia0,GPU-1,NVIDIA H100 80GB HBM3,node-1,,,,100,
+2025-07-18T20:42:34Z,DCGM_FI_DEV_MEM_COPY_UTIL,0,nvidia0,GPU-1,NVIDIA H100 80GB HBM3,node-1,,,,45,
+2025-07-18T20:42:34Z,DCGM_FI_DEV_SM_CLOCK,1,nvidia1,GPU-2,NVIDIA H100 80GB HBM3,node-1,,,,1980,
+`
+
+// counters is a site-specific source serving fixed fabric counters.
+type counters struct {
+	port string
+	sent int
+}
+
+func (c *counters) Open(ctx context.Context, checkpoint string) error { return nil }
+func (c *counters) Checkpoint() string                                { return "" }
+func (c *counters) Close() error                                      { return nil }
+
+func (c *counters) ReadBatch(ctx context.Context, max int) ([]*models.GPUMetric, error) {
+	if c.sent > 0 {
+		return nil, io.EOF
+	}
+	c.sent++
+	return []*models.GPUMetric{{MetricName: "FABRIC_RX_BYTES", Labels: map[string]string{"port": c.port}}}, nil
+}
+
+func TestRegisterAndNew(t *testing.T) {
+	Register("test-fabric", func(cfg Config) (Source, error) {
+		return &counters{port: cfg.Options["port"]}, nil
+	})
+
+	src, err := New("test-fabric", Config{Options: map[string]string{"port": "eth1"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	metrics, err := src.ReadBatch(context.Background(), 10)
+	if err != nil || len(metrics) != 1 || metrics[0].Labels["port"] != "eth1" {
+		t.Errorf("expected one counter for eth1, got %v (%v)", metrics, err)
+	}
+
+	_, err = New("smi", Config{})
+	if err == nil || !strings.Contains(err.Error(), "file, test-fabric") {
+		t.Errorf("expected an unknown source error listing the registered ones, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a name twice to panic")
+		}
+	}()
+	Register("test-fabric", func(cfg Config) (Source, error) { return nil, nil })
+}
+
+func TestFileSourceResumesAfterCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.csv")
+	if err := os.WriteFile(path, []byte(sampleCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	src, err := New(FileName, Config{Path: path, Format: "auto"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := src.Open(ctx, ""); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	metrics, err := src.ReadBatch(ctx, 2)
+	if err != nil || len(metrics) != 2 {
+		t.Fatalf("expected 2 metrics, got %d (%v)", len(metrics), err)
+	}
+	checkpoint := src.Checkpoint()
+	if checkpoint != "3" || src.(LineReporter).Line() != 3 {
+		t.Errorf("expected checkpoint at line 3, got %q", checkpoint)
+	}
+	src.Close()
+
+	src, _ = New(FileName, Config{Path: path, Format: "auto"})
+	defer src.Close()
+	if err := src.Open(ctx, checkpoint); err != nil {
+		t.Fatalf("Open at checkpoint failed: %v", err)
+	}
+	metrics, err = src.ReadBatch(ctx, 10)
+	if err != nil || len(metrics) != 1 || metrics[0].MetricName != "DCGM_FI_DEV_SM_CLOCK" {
+		t.Fatalf("expected the third metric after the checkpoint, got %v (%v)", metrics, err)
+	}
+	if _, err := src.ReadBatch(ctx, 10); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at the end of the file, got %v", err)
+	}
+
+	if err := src.Open(ctx, "line 2"); err == nil {
+		t.Error("expected an invalid checkpoint to be rejected")
+	}
+}
@@ -0,0 +1,273 @@
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+const (
+	// lockRetry is how often a busy lease lock is retried
+	lockRetry = 10 * time.Millisecond
+
+	// lockStale is how old a lease lock must be to be taken over from a
+	// process that died holding it
+	lockStale = 10 * time.Second
+)
+
+// File is a Store keeping each key in a file under a directory, at the
+// key's path. Values are replaced atomically by renaming a temporary file,
+// so a crash leaves either the old or the new value. Leases are kept in
+// files too, so they coordinate only processes sharing the directory.
+type File struct {
+	dir   string
+	sync  bool
+	clock clock.Clock
+}
+
+var _ Store = (*File)(nil)
+
+// OpenFile opens a store in dir, creating it if needed. With sync, every
+// Put is flushed to disk before it returns.
+func OpenFile(dir string, sync bool) (*File, error) {
+	if dir == "" {
+		return nil, errors.New("statestore: the file backend needs a directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("statestore: %w", err)
+	}
+	return &File{dir: dir, sync: sync, clock: clock.Real}, nil
+}
+
+func (f *File) path(key string) string {
+	return filepath.Join(f.dir, filepath.FromSlash(key))
+}
+
+// Get returns the contents of key's file.
+func (f *File) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	return f.read(f.path(key))
+}
+
+// Put replaces key's file with value.
+func (f *File) Put(ctx context.Context, key string, value []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	return f.write(f.path(key), value)
+}
+
+func (f *File) read(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// write replaces the file at path through a temporary file in its
+// directory, flushing it first when the store syncs.
+func (f *File) write(path string, value []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if f.sync {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes key's file.
+func (f *File) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(f.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List returns the keys of the files under the directory starting with
+// prefix, sorted. Files and directories whose names start with a dot are
+// skipped.
+func (f *File) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == f.dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(f.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			// Skip directories that cannot hold a key with the prefix
+			if !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// AcquireLease grants or renews the lease name for holder. The lease's
+// file is read and written under a lock file, so processes sharing the
+// directory never both acquire it.
+func (f *File) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (Lease, error) {
+	if err := checkKey(name); err != nil {
+		return Lease{}, err
+	}
+	path := f.leasePath(name)
+	unlock, err := f.lock(ctx, path)
+	if err != nil {
+		return Lease{}, err
+	}
+	defer unlock()
+
+	now := f.clock.Now()
+	switch cur, err := f.readLease(path); {
+	case err == nil:
+		if cur.Holder != holder && now.Before(cur.ExpiresAt) {
+			return cur, nil
+		}
+	case !errors.Is(err, ErrNotFound):
+		return Lease{}, err
+	}
+	lease := Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return Lease{}, err
+	}
+	if err := f.write(path, data); err != nil {
+		return Lease{}, err
+	}
+	lease.Acquired = true
+	return lease, nil
+}
+
+// ReleaseLease frees the lease name if holder holds it.
+func (f *File) ReleaseLease(ctx context.Context, name, holder string) error {
+	if err := checkKey(name); err != nil {
+		return err
+	}
+	path := f.leasePath(name)
+	unlock, err := f.lock(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cur, err := f.readLease(path)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if cur.Holder != holder {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// leasePath is the file of the lease name, in a dot directory List skips.
+func (f *File) leasePath(name string) string {
+	return filepath.Join(f.dir, ".leases", filepath.FromSlash(name))
+}
+
+func (f *File) readLease(path string) (Lease, error) {
+	var lease Lease
+	data, err := f.read(path)
+	if err != nil {
+		return lease, err
+	}
+	if err := json.Unmarshal(data, &lease); err != nil {
+		return lease, fmt.Errorf("statestore: %s: %w", path, err)
+	}
+	return lease, nil
+}
+
+// lock takes the lock file next to path, waiting while another process
+// holds it, and returns the function releasing it.
+func (f *File) lock(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	lockPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+	for {
+		lf, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			lf.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetry):
+		}
+	}
+}
+
+// Close does nothing.
+func (f *File) Close() error {
+	return nil
+}
@@ -0,0 +1,113 @@
+package statestore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+)
+
+// Memory is a Store held in memory, for tests and single-process tools.
+type Memory struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	values map[string][]byte
+	leases map[string]Lease
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory creates an empty store whose leases expire by c; nil is the
+// real clock.
+func NewMemory(c clock.Clock) *Memory {
+	if c == nil {
+		c = clock.Real
+	}
+	return &Memory{clock: c, values: make(map[string][]byte), leases: make(map[string]Lease)}
+}
+
+// Get returns the value of key.
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), v...), nil
+}
+
+// Put sets key to a copy of value.
+func (m *Memory) Put(ctx context.Context, key string, value []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+// Delete removes key.
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// List returns the keys starting with prefix, sorted.
+func (m *Memory) List(ctx context.Context, prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0)
+	for k := range m.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// AcquireLease grants or renews the lease name for holder.
+func (m *Memory) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (Lease, error) {
+	if err := checkKey(name); err != nil {
+		return Lease{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	if cur, ok := m.leases[name]; ok && cur.Holder != holder && now.Before(cur.ExpiresAt) {
+		return cur, nil
+	}
+	lease := Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)}
+	m.leases[name] = lease
+	lease.Acquired = true
+	return lease, nil
+}
+
+// ReleaseLease frees the lease name if holder holds it.
+func (m *Memory) ReleaseLease(ctx context.Context, name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.leases[name]; ok && cur.Holder == holder {
+		delete(m.leases, name)
+	}
+	return nil
+}
+
+// Close does nothing.
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+// redisTimeout bounds connecting to Redis, and each read and write of a
+// command when the context has no earlier deadline.
+const redisTimeout = 5 * time.Second
+
+// acquireScript sets the lease key to the holder unless another holder has
+// it, returning whether it did, the holder and the milliseconds left.
+var acquireScript = redis.NewScript(`local cur = redis.call('GET', KEYS[1])
+if cur and cur ~= ARGV[1] then
+  return {0, cur, redis.call('PTTL', KEYS[1])}
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return {1, ARGV[1], tonumber(ARGV[2])}`)
+
+// releaseScript deletes the lease key if the holder has it.
+var releaseScript = redis.NewScript(`if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+// Redis is a Store in a Redis database, shared by every replica pointed at
+// it. Keys are stored under a prefix, and leases are Redis keys that expire
+// with them.
+type Redis struct {
+	client *redis.Client
+	prefix string
+	clock  clock.Clock
+}
+
+var _ Store = (*Redis)(nil)
+
+// OpenRedis connects to the Redis server cfg configures.
+//
+// Commands are never retried: one whose reply was lost may have run, and
+// running a lease script twice is not safe to hide from the caller. Pooled
+// connections the server closed while idle are checked for and replaced
+// before a command is sent on them.
+func OpenRedis(cfg config.StateConfig) (*Redis, error) {
+	r := &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:                  cfg.RedisAddr,
+			Password:              cfg.RedisPassword,
+			DB:                    cfg.RedisDB,
+			MaxRetries:            -1,
+			DialTimeout:           redisTimeout,
+			ReadTimeout:           redisTimeout,
+			WriteTimeout:          redisTimeout,
+			ContextTimeoutEnabled: true,
+		}),
+		prefix: cfg.RedisPrefix,
+		clock:  clock.Real,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("statestore: connect to redis at %s: %w", cfg.RedisAddr, err)
+	}
+	return r, nil
+}
+
+// Get returns the value of key.
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+// Put sets key to value.
+func (r *Redis) Put(ctx context.Context, key string, value []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+key, value, 0).Err()
+}
+
+// Delete removes key.
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
+
+// List returns the keys starting with prefix, sorted, scanning the
+// database incrementally.
+func (r *Redis) List(ctx context.Context, prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	iter := r.client.Scan(ctx, 0, globEscape(r.prefix+prefix)+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := strings.TrimPrefix(iter.Val(), r.prefix)
+		// Leases live under a dot prefix no key can have
+		if !strings.HasPrefix(key, ".") {
+			seen[key] = true
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// AcquireLease grants or renews the lease name for holder in one script,
+// so replicas racing for it never both acquire it.
+func (r *Redis) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (Lease, error) {
+	if err := checkKey(name); err != nil {
+		return Lease{}, err
+	}
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		ms = 1
+	}
+	now := r.clock.Now()
+	fields, err := acquireScript.Run(ctx, r.client, []string{r.leaseKey(name)}, holder, ms).Slice()
+	if err != nil {
+		return Lease{}, err
+	}
+	if len(fields) != 3 {
+		return Lease{}, fmt.Errorf("statestore: unexpected lease reply %v", fields)
+	}
+	acquired, _ := fields[0].(int64)
+	cur, _ := fields[1].(string)
+	left, _ := fields[2].(int64)
+	return Lease{
+		Name:      name,
+		Holder:    cur,
+		ExpiresAt: now.Add(time.Duration(left) * time.Millisecond),
+		Acquired:  acquired == 1,
+	}, nil
+}
+
+// ReleaseLease frees the lease name if holder holds it.
+func (r *Redis) ReleaseLease(ctx context.Context, name, holder string) error {
+	if err := checkKey(name); err != nil {
+		return err
+	}
+	return releaseScript.Run(ctx, r.client, []string{r.leaseKey(name)}, holder).Err()
+}
+
+// Close closes the connections.
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) leaseKey(name string) string {
+	return r.prefix + ".leases/" + name
+}
+
+// globEscape escapes the characters SCAN's MATCH pattern treats specially.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package statestore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+func TestRedisAuth(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	_, err := OpenRedis(config.StateConfig{RedisAddr: server.Addr(), RedisPassword: "wrong"})
+	var rerr redis.Error
+	if !errors.As(err, &rerr) || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("expected the wrong password to be refused, got %v", err)
+	}
+}
+
+func TestRedisReconnects(t *testing.T) {
+	server := miniredis.RunT(t)
+	s, err := OpenRedis(config.StateConfig{RedisAddr: server.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	if err := s.Put(ctx, "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server closing an idle connection must not fail the next command
+	server.Close()
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get(ctx, "k"); err != nil || string(v) != "v" {
+		t.Errorf("expected the value after reconnecting, got %q, %v", v, err)
+	}
+}
+
+func TestRedisDoesNotResendLostCommands(t *testing.T) {
+	// A server that answers PING and drops the connection on any script,
+	// as if it ran it and the reply was lost
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var scripts atomic.Int64
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				rd := bufio.NewReader(conn)
+				for {
+					args, err := readCommand(rd)
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(args[0]) {
+					case "PING":
+						io.WriteString(conn, "+PONG\r\n")
+					case "EVAL", "EVALSHA":
+						scripts.Add(1)
+						return
+					default:
+						io.WriteString(conn, "-ERR unknown command\r\n")
+					}
+				}
+			}()
+		}
+	}()
+
+	s, err := OpenRedis(config.StateConfig{RedisAddr: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.AcquireLease(context.Background(), "leader", "a", time.Minute); err == nil {
+		t.Fatal("expected the lost reply reported")
+	}
+	if n := scripts.Load(); n != 1 {
+		t.Errorf("expected the lease script sent once, got %d", n)
+	}
+}
+
+// readCommand reads one command, a RESP array of bulk strings.
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n <= 0 {
+		return nil, errors.New("malformed command")
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = rd.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestGlobEscape(t *testing.T) {
+	if got := globEscape(`a*b?[c]\`); got != `a\*b\?\[c\]\\` {
+		t.Errorf("unexpected escaped pattern %q", got)
+	}
+}
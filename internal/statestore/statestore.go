@@ -0,0 +1,118 @@
+// Package statestore is the small durable key-value store components keep
+// their state in: streamer checkpoints, the MQ server's committed offsets,
+// export job records, alert state and leader leases. A store is backed by
+// local files, or by Redis when several replicas share the state.
+//
+// Keys are slash-separated paths such as "streamer/streamer-0/checkpoint".
+// Values are opaque bytes; GetJSON and PutJSON store JSON documents. Leases
+// are named the same way but kept apart from the keys, so List never
+// returns them.
+package statestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Backends.
+const (
+	BackendFile  = "file"
+	BackendRedis = "redis"
+)
+
+// ErrNotFound is returned by Get for keys that are not set.
+var ErrNotFound = perrors.New(perrors.KindNotFound, "statestore: key not found")
+
+// Store is a durable key-value store with leases.
+type Store interface {
+	// Get returns the value of key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Put sets key to value, replacing any previous value atomically
+	Put(ctx context.Context, key string, value []byte) error
+
+	// Delete removes key; removing a key that is not set is not an error
+	Delete(ctx context.Context, key string) error
+
+	// List returns the keys starting with prefix, sorted
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// AcquireLease grants or renews the lease name for holder until ttl
+	// from now if it is free, expired or already held by holder.
+	// Otherwise it returns the current holder's lease, not Acquired.
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (Lease, error)
+
+	// ReleaseLease frees the lease name if holder holds it
+	ReleaseLease(ctx context.Context, name, holder string) error
+
+	// Close releases the store's resources
+	Close() error
+}
+
+// Lease is a named lease and its holder.
+type Lease struct {
+	Name      string    `json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Acquired is true when the requesting holder now holds the lease
+	Acquired bool `json:"acquired"`
+}
+
+// Open opens the store cfg configures, or returns nil when no backend is
+// configured.
+func Open(cfg config.StateConfig) (Store, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case BackendFile:
+		return OpenFile(cfg.Dir, true)
+	case BackendRedis:
+		return OpenRedis(cfg)
+	default:
+		return nil, fmt.Errorf("unknown state store backend %q", cfg.Backend)
+	}
+}
+
+// GetJSON decodes the JSON value of key into v. It returns ErrNotFound if
+// the key is not set.
+func GetJSON(ctx context.Context, s Store, key string, v any) error {
+	data, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("statestore: %s: %w", key, err)
+	}
+	return nil
+}
+
+// PutJSON sets key to the JSON encoding of v.
+func PutJSON(ctx context.Context, s Store, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, key, data)
+}
+
+// checkKey rejects keys that are not relative slash-separated paths, or
+// whose segments start with a dot, which file stores keep for themselves.
+func checkKey(key string) error {
+	if key == "" {
+		return perrors.Validation(errors.New("statestore: empty key"))
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || strings.HasPrefix(seg, ".") || strings.ContainsAny(seg, "\\\x00") {
+			return perrors.Validation(fmt.Errorf("statestore: invalid key %q", key))
+		}
+	}
+	return nil
+}
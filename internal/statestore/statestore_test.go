@@ -0,0 +1,189 @@
+package statestore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// stores opens every backend with leases expiring by sim, and returns the
+// Redis server, whose keys expire as it is fast-forwarded.
+func stores(t *testing.T, sim *clock.Simulated) (map[string]Store, *miniredis.Miniredis) {
+	file, err := OpenFile(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.clock = sim
+
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	redis, err := OpenRedis(config.StateConfig{
+		RedisAddr:     server.Addr(),
+		RedisPassword: "secret",
+		RedisDB:       2,
+		RedisPrefix:   "test:",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redis.clock = sim
+	t.Cleanup(func() { redis.Close() })
+
+	return map[string]Store{"memory": NewMemory(sim), "file": file, "redis": redis}, server
+}
+
+func TestStoreValues(t *testing.T) {
+	ctx := context.Background()
+	all, _ := stores(t, clock.NewSimulated(time.Now()))
+	for name, s := range all {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Get(ctx, "a/b"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound for a missing key, got %v", err)
+			}
+			for _, key := range []string{"a/b", "a/c", "b"} {
+				if err := s.Put(ctx, key, []byte("v-"+key)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Put(ctx, "a/b", []byte("new")); err != nil {
+				t.Fatal(err)
+			}
+			if v, err := s.Get(ctx, "a/b"); err != nil || string(v) != "new" {
+				t.Errorf("expected the replaced value, got %q, %v", v, err)
+			}
+
+			// Leases are not keys
+			if _, err := s.AcquireLease(ctx, "leader", "a", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if keys, err := s.List(ctx, "a/"); err != nil || !reflect.DeepEqual(keys, []string{"a/b", "a/c"}) {
+				t.Errorf("unexpected keys under a/: %v, %v", keys, err)
+			}
+			if keys, err := s.List(ctx, ""); err != nil || !reflect.DeepEqual(keys, []string{"a/b", "a/c", "b"}) {
+				t.Errorf("unexpected keys: %v, %v", keys, err)
+			}
+
+			if err := s.Delete(ctx, "a/b"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Delete(ctx, "a/b"); err != nil {
+				t.Errorf("expected deleting a missing key to succeed, got %v", err)
+			}
+			if _, err := s.Get(ctx, "a/b"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected a deleted key to be gone, got %v", err)
+			}
+
+			for _, key := range []string{"", "/a", "a//b", "../a", "a/.b", `a\b`} {
+				if err := s.Put(ctx, key, nil); perrors.KindOf(err) != perrors.KindValidation {
+					t.Errorf("expected key %q to be rejected, got %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreJSON(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory(nil)
+	type checkpoint struct {
+		Offset int `json:"offset"`
+	}
+	if err := PutJSON(ctx, s, "streamer/s-1/checkpoint", checkpoint{Offset: 42}); err != nil {
+		t.Fatal(err)
+	}
+	var got checkpoint
+	if err := GetJSON(ctx, s, "streamer/s-1/checkpoint", &got); err != nil || got.Offset != 42 {
+		t.Errorf("unexpected checkpoint %+v, %v", got, err)
+	}
+	if err := GetJSON(ctx, s, "streamer/s-2/checkpoint", &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestStoreLeases(t *testing.T) {
+	ctx := context.Background()
+	sim := clock.NewSimulated(time.Now())
+	all, server := stores(t, sim)
+	for name, s := range all {
+		t.Run(name, func(t *testing.T) {
+			if l, err := s.AcquireLease(ctx, "scheduler", "a", time.Minute); err != nil || !l.Acquired || l.Holder != "a" {
+				t.Fatalf("expected a to acquire the free lease, got %+v, %v", l, err)
+			}
+			l, err := s.AcquireLease(ctx, "scheduler", "b", time.Minute)
+			if err != nil || l.Acquired || l.Holder != "a" {
+				t.Errorf("expected b to be refused while a holds the lease, got %+v, %v", l, err)
+			}
+			if l.ExpiresAt.Before(sim.Now()) {
+				t.Errorf("expected the refused lease to report when a's lease expires, got %v", l.ExpiresAt)
+			}
+			if l, err := s.AcquireLease(ctx, "scheduler", "a", time.Minute); err != nil || !l.Acquired {
+				t.Errorf("expected a to renew its lease, got %+v, %v", l, err)
+			}
+
+			// Only the holder can release
+			if err := s.ReleaseLease(ctx, "scheduler", "b"); err != nil {
+				t.Fatal(err)
+			}
+			if l, _ := s.AcquireLease(ctx, "scheduler", "b", time.Minute); l.Acquired {
+				t.Error("expected release by non-holder to be ignored")
+			}
+			if err := s.ReleaseLease(ctx, "scheduler", "a"); err != nil {
+				t.Fatal(err)
+			}
+			if l, _ := s.AcquireLease(ctx, "scheduler", "b", time.Minute); !l.Acquired {
+				t.Error("expected the lease to be free after its holder released it")
+			}
+
+			sim.Advance(2 * time.Minute)
+			server.FastForward(2 * time.Minute)
+			if l, _ := s.AcquireLease(ctx, "scheduler", "a", time.Minute); !l.Acquired {
+				t.Error("expected a to take over an expired lease")
+			}
+		})
+	}
+}
+
+func TestFileStoreSkipsTemporaryFiles(t *testing.T) {
+	ctx := context.Background()
+	s, err := OpenFile(t.TempDir(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "exports/job-1.json", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	// A crash between creating and renaming leaves a dotfile behind
+	if err := s.write(s.path("exports")+"/.job-2.json.tmp123", []byte("{")); err != nil {
+		t.Fatal(err)
+	}
+	if keys, err := s.List(ctx, "exports/"); err != nil || !reflect.DeepEqual(keys, []string{"exports/job-1.json"}) {
+		t.Errorf("unexpected keys %v, %v", keys, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if s, err := Open(config.StateConfig{}); s != nil || err != nil {
+		t.Errorf("expected no store without a backend, got %v, %v", s, err)
+	}
+	s, err := Open(config.StateConfig{Backend: BackendFile, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(*File); !ok {
+		t.Errorf("expected a file store, got %T", s)
+	}
+	if _, err := Open(config.StateConfig{Backend: "etcd"}); err == nil {
+		t.Error("expected an unknown backend to be rejected")
+	}
+	if _, err := Open(config.StateConfig{Backend: BackendRedis, RedisAddr: "127.0.0.1:1"}); err == nil {
+		t.Error("expected an unreachable Redis to be reported")
+	}
+}
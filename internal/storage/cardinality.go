@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// SeriesCounter tallies distinct telemetry series, the series of each metric
+// and the distinct values of each tag. It is not safe for concurrent use.
+type SeriesCounter struct {
+	series  map[string]struct{}
+	metrics map[string]int
+	values  map[string]map[string]struct{}
+}
+
+// NewSeriesCounter returns an empty counter.
+func NewSeriesCounter() *SeriesCounter {
+	return &SeriesCounter{
+		series:  make(map[string]struct{}),
+		metrics: make(map[string]int),
+		values:  make(map[string]map[string]struct{}),
+	}
+}
+
+// seriesKey identifies a series by its metric and tag values.
+func seriesKey(metric string, tags map[string]string) string {
+	pairs := make([]string, 0, len(tags)+1)
+	pairs = append(pairs, metric)
+	for tag, value := range tags {
+		pairs = append(pairs, tag+"="+value)
+	}
+	sort.Strings(pairs[1:])
+	return strings.Join(pairs, "\x00")
+}
+
+// Contains reports whether the series has been counted.
+func (c *SeriesCounter) Contains(metric string, tags map[string]string) bool {
+	_, ok := c.series[seriesKey(metric, tags)]
+	return ok
+}
+
+// Add counts a series, returning false if it was already counted.
+func (c *SeriesCounter) Add(metric string, tags map[string]string) bool {
+	key := seriesKey(metric, tags)
+	if _, ok := c.series[key]; ok {
+		return false
+	}
+	c.series[key] = struct{}{}
+	c.metrics[metric]++
+	for tag, value := range tags {
+		if c.values[tag] == nil {
+			c.values[tag] = make(map[string]struct{})
+		}
+		c.values[tag][value] = struct{}{}
+	}
+	return true
+}
+
+// Len returns the number of distinct series counted.
+func (c *SeriesCounter) Len() int {
+	return len(c.series)
+}
+
+// Fill sets the report's series count, its top metrics by series and the
+// distinct values of every tag.
+func (c *SeriesCounter) Fill(report *models.CardinalityReport, top int) {
+	report.Series = len(c.series)
+
+	report.Metrics = make([]models.CardinalityCount, 0, len(c.metrics))
+	for metric, n := range c.metrics {
+		report.Metrics = append(report.Metrics, models.CardinalityCount{Name: metric, Count: n})
+	}
+	sortCounts(report.Metrics)
+	if len(report.Metrics) > top {
+		report.Metrics = report.Metrics[:top]
+	}
+
+	report.Tags = make([]models.CardinalityCount, 0, len(c.values))
+	for tag, values := range c.values {
+		report.Tags = append(report.Tags, models.CardinalityCount{Name: tag, Count: len(values)})
+	}
+	sortCounts(report.Tags)
+}
+
+// sortCounts orders counts highest first, then by name.
+func sortCounts(counts []models.CardinalityCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+}
@@ -3,14 +3,22 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
 	"github.com/influxdata/influxdb-client-go/v2/api/query"
 
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
 )
 
@@ -20,6 +28,7 @@ type InfluxDBConfig struct {
 	Token  string `json:"token"`  // API token
 	Org    string `json:"org"`    // Organization name
 	Bucket string `json:"bucket"` // Bucket name
+	Schema Schema `json:"schema"` // Telemetry measurement/tag layout
 }
 
 // DefaultInfluxDBConfig returns sensible defaults from environment variables.
@@ -29,6 +38,13 @@ func DefaultInfluxDBConfig() InfluxDBConfig {
 		Token:  os.Getenv("INFLUXDB_TOKEN"),
 		Org:    getEnv("INFLUXDB_ORG", "cisco"),
 		Bucket: getEnv("INFLUXDB_BUCKET", "gpu_telemetry"),
+		Schema: Schema{
+			Mode:        getEnv("INFLUXDB_SCHEMA", SchemaPerMetric),
+			Measurement: getEnv("INFLUXDB_MEASUREMENT", DefaultMeasurement),
+			Tags:        getEnvList("INFLUXDB_TAGS"),
+			BatchFields: os.Getenv("INFLUXDB_BATCH_FIELDS") == "true",
+			Aliases:     metricAliases(getEnvList("INFLUXDB_METRIC_ALIASES")),
+		},
 	}
 }
 
@@ -39,16 +55,77 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// InfluxDBStorage implements ReadStorage for read-only InfluxDB access.
+// getEnvList splits a comma-separated variable, returning nil when it is unset.
+func getEnvList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// metricAliases parses alias=canonical pairs. An entry without both names
+// is kept for Schema.Validate to report.
+func metricAliases(pairs []string) models.MetricAliases {
+	if len(pairs) == 0 {
+		return nil
+	}
+	aliases := make(models.MetricAliases, len(pairs))
+	for _, pair := range pairs {
+		alias, canonical, _ := strings.Cut(pair, "=")
+		aliases[strings.TrimSpace(alias)] = strings.TrimSpace(canonical)
+	}
+	return aliases
+}
+
+// classifyInfluxError attaches a retry classification to an InfluxDB client error
+// based on the HTTP status of the failed request. Errors without a status are
+// network failures and are treated as transient.
+func classifyInfluxError(err error) error {
+	var httpErr *influxhttp.Error
+	if errors.As(err, &httpErr) && httpErr.StatusCode != 0 {
+		switch {
+		case httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500:
+			return perrors.Transient(err)
+		default:
+			// Rejected writes and queries (bad points, auth, missing bucket) will fail again
+			return perrors.Permanent(err)
+		}
+	}
+	if perrors.KindOf(err) == perrors.KindUnknown {
+		return perrors.Transient(err)
+	}
+	return err
+}
+
+// InfluxDBStorage implements ReadStorage for read-only telemetry access, plus
+// AnnotationStore, SavedQueryStore, AlertRuleStore and SilenceStore for the
+// API's own documents, DataAdmin for admin cleanup and retention,
+// LineageReader for batch provenance, BaselineReader for model baselines,
+// FleetSeriesReader for forecasts and SeriesReader for per-GPU aggregates.
 // Used by the API to query telemetry data.
 type InfluxDBStorage struct {
-	client   influxdb2.Client
-	queryAPI api.QueryAPI
-	config   InfluxDBConfig
+	client    influxdb2.Client
+	queryAPI  api.QueryAPI
+	writeAPI  api.WriteAPIBlocking
+	deleteAPI api.DeleteAPI
+	config    InfluxDBConfig
+
+	// queryLog logs every Flux query while it is on
+	queryLog *logging.Toggle
 }
 
 // NewInfluxDBStorage creates a new read-only InfluxDB storage backend.
 func NewInfluxDBStorage(config InfluxDBConfig) (*InfluxDBStorage, error) {
+	if err := config.Schema.Validate(); err != nil {
+		return nil, perrors.Validation(fmt.Errorf("invalid InfluxDB schema: %w", err))
+	}
 	client := influxdb2.NewClient(config.URL, config.Token)
 
 	// Test connection
@@ -57,33 +134,59 @@ func NewInfluxDBStorage(config InfluxDBConfig) (*InfluxDBStorage, error) {
 
 	health, err := client.Health(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to InfluxDB: %w", err)
+		return nil, perrors.Transient(fmt.Errorf("failed to connect to InfluxDB: %w", err))
 	}
 	if health.Status != "pass" {
-		return nil, fmt.Errorf("InfluxDB health check failed: %s", health.Status)
+		return nil, perrors.Transient(fmt.Errorf("InfluxDB health check failed: %s", health.Status))
 	}
 
 	return &InfluxDBStorage{
-		client:   client,
-		queryAPI: client.QueryAPI(config.Org),
-		config:   config,
+		client:    client,
+		queryAPI:  client.QueryAPI(config.Org),
+		writeAPI:  client.WriteAPIBlocking(config.Org, config.Bucket),
+		deleteAPI: client.DeleteAPI(),
+		config:    config,
 	}, nil
 }
 
+// SetQueryLog logs the text, duration and outcome of every Flux query while
+// toggle is on. It must be called before the storage is used.
+func (s *InfluxDBStorage) SetQueryLog(toggle *logging.Toggle) {
+	s.queryLog = toggle
+}
+
+// query runs a Flux query, logging it while the query log is on.
+func (s *InfluxDBStorage) query(ctx context.Context, fluxQuery string) (*api.QueryTableResult, error) {
+	if !s.queryLog.Enabled() {
+		return s.queryAPI.Query(ctx, fluxQuery)
+	}
+
+	began := time.Now()
+	result, err := s.queryAPI.Query(ctx, fluxQuery)
+	// Collapse the query's indentation so each one is a single greppable line
+	flux := strings.Join(strings.Fields(fluxQuery), " ")
+	if err != nil {
+		s.queryLog.Printf("Flux query failed after %v: %v: %s", time.Since(began), err, flux)
+	} else {
+		s.queryLog.Printf("Flux query responded in %v: %s", time.Since(began), flux)
+	}
+	return result, err
+}
+
 // GetGPUs returns all known GPU IDs by querying distinct UUIDs from InfluxDB.
 func (s *InfluxDBStorage) GetGPUs(ctx context.Context) ([]string, error) {
 	// Query to get distinct GPU UUIDs
 	fluxQuery := fmt.Sprintf(`
 		from(bucket: "%s")
 			|> range(start: -24h)
-			|> filter(fn: (r) => r._field == "value")
+			|> filter(fn: (r) => %s)
 			|> group(columns: ["uuid"])
 			|> last()
-	`, s.config.Bucket)
+	`, s.config.Bucket, s.config.Schema.valueFilter())
 
-	result, err := s.queryAPI.Query(ctx, fluxQuery)
+	result, err := s.query(ctx, fluxQuery)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query GPUs: %w", err)
+		return nil, classifyInfluxError(fmt.Errorf("failed to query GPUs: %w", err))
 	}
 	defer result.Close()
 
@@ -101,7 +204,7 @@ func (s *InfluxDBStorage) GetGPUs(ctx context.Context) ([]string, error) {
 	}
 
 	if result.Err() != nil {
-		return nil, fmt.Errorf("query error: %w", result.Err())
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
 	}
 
 	gpus := make([]string, 0, len(gpuIDs))
@@ -112,8 +215,154 @@ func (s *InfluxDBStorage) GetGPUs(ctx context.Context) ([]string, error) {
 	return gpus, nil
 }
 
+// GetGPUInfos returns every GPU seen in the same window as GetGPUs, with its
+// tags and first/last-seen times, using one first() and one last() query.
+func (s *InfluxDBStorage) GetGPUInfos(ctx context.Context) ([]*models.GPUInfo, error) {
+	first, err := s.gpuEdge(ctx, "first")
+	if err != nil {
+		return nil, err
+	}
+	last, err := s.gpuEdge(ctx, "last")
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*models.GPUInfo, 0, len(last))
+	for uuid, newest := range last {
+		info := &models.GPUInfo{
+			UUID:      uuid,
+			GPUID:     newest.GPUID,
+			Device:    newest.Device,
+			ModelName: newest.ModelName,
+			Hostname:  newest.Hostname,
+			FirstSeen: newest.Timestamp,
+			LastSeen:  newest.Timestamp,
+		}
+		if oldest, ok := first[uuid]; ok {
+			info.FirstSeen = oldest.Timestamp
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// gpuEdge runs selector ("first" or "last") over each GPU's points and returns
+// the selected point per UUID, keeping the oldest or newest across metrics.
+func (s *InfluxDBStorage) gpuEdge(ctx context.Context, selector string) (map[string]*models.GPUMetric, error) {
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -24h)
+			|> filter(fn: (r) => %s)
+			|> group(columns: ["uuid"])
+			|> %s()
+	`, s.config.Bucket, s.config.Schema.valueFilter(), selector)
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query GPU info: %w", err))
+	}
+	defer result.Close()
+
+	edges := make(map[string]*models.GPUMetric)
+	for result.Next() {
+		metric := s.recordToMetric(result.Record())
+		if metric == nil || metric.UUID == "" {
+			continue
+		}
+		cur, ok := edges[metric.UUID]
+		if !ok ||
+			(selector == "first" && metric.Timestamp.Before(cur.Timestamp)) ||
+			(selector == "last" && metric.Timestamp.After(cur.Timestamp)) {
+			edges[metric.UUID] = metric
+		}
+	}
+
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	return edges, nil
+}
+
+// GetTagValues returns the distinct values of tag over the same window as GetGPUs.
+func (s *InfluxDBStorage) GetTagValues(ctx context.Context, tag string) ([]string, error) {
+	column := tag
+	if tag == TagMetricName {
+		column = s.config.Schema.metricColumn()
+	}
+	fluxQuery := fmt.Sprintf(`
+		import "influxdata/influxdb/schema"
+		schema.tagValues(bucket: "%s", tag: "%s", predicate: (r) => %s, start: -24h)
+	`, s.config.Bucket, column, s.config.Schema.valueFilter())
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query %s values: %w", tag, err))
+	}
+	defer result.Close()
+
+	values := make([]string, 0)
+	for result.Next() {
+		if v, ok := result.Record().Value().(string); ok && v != "" {
+			if tag == TagMetricName {
+				// Names stored under an alias are listed by their canonical name
+				v = s.config.Schema.Aliases.Canonical(v)
+				if slices.Contains(values, v) {
+					continue
+				}
+			}
+			values = append(values, v)
+		}
+	}
+
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	return values, nil
+}
+
 // GetTelemetry returns telemetry matching the query.
 func (s *InfluxDBStorage) GetTelemetry(ctx context.Context, query *models.TelemetryQuery) ([]*models.GPUMetric, error) {
+	fluxQuery, _, _ := s.buildTelemetryQuery(query)
+	metrics, _, _, err := s.runTelemetryQuery(ctx, fluxQuery)
+	return metrics, err
+}
+
+// ExplainTelemetry runs the telemetry query and reports the generated Flux,
+// scanned time range and per-phase timings.
+func (s *InfluxDBStorage) ExplainTelemetry(ctx context.Context, query *models.TelemetryQuery) (*QueryPlan, error) {
+	began := time.Now()
+	fluxQuery, start, stop := s.buildTelemetryQuery(query)
+	build := time.Since(began)
+
+	metrics, execute, decode, err := s.runTelemetryQuery(ctx, fluxQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryPlan{
+		Backend:    "influxdb",
+		Language:   "flux",
+		Query:      fluxQuery,
+		Resolution: ResolutionRaw,
+		ScanStart:  start,
+		ScanStop:   stop,
+		ScanRange:  stop.Sub(start).String(),
+		Rows:       len(metrics),
+		Timing: QueryTiming{
+			BuildMs:   Milliseconds(build),
+			ExecuteMs: Milliseconds(execute),
+			DecodeMs:  Milliseconds(decode),
+			TotalMs:   Milliseconds(time.Since(began)),
+		},
+	}, nil
+}
+
+// buildTelemetryQuery generates the Flux for a telemetry query and returns it
+// with the time range it scans.
+func (s *InfluxDBStorage) buildTelemetryQuery(query *models.TelemetryQuery) (string, time.Time, time.Time) {
 	start := time.Now().Add(-24 * time.Hour)
 	stop := time.Now()
 
@@ -124,17 +373,27 @@ func (s *InfluxDBStorage) GetTelemetry(ctx context.Context, query *models.Teleme
 		stop = *query.EndTime
 	}
 
-	// Build Flux query
+	// Build Flux query. Each point's batch ID, late and skew marks are pivoted into
+	// the row so results can be joined to their lineage. Batched fields are named after
+	// their metric, so those rows are read unpivoted, without batch IDs.
+	schema := s.config.Schema
 	fluxQuery := fmt.Sprintf(`
 		from(bucket: "%s")
 			|> range(start: %s, stop: %s)
 	`, s.config.Bucket,
 		start.Format(time.RFC3339),
 		stop.Format(time.RFC3339))
+	if schema.BatchFields {
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => %s)`, schema.valueFilter())
+	} else {
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => %s)
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+	`, schema.inMeasurement(`(r._field == "value" or r._field == "batch_id" or r._field == "late" or r._field == "skewed")`))
+	}
 
 	// Add metric name filter if specified
 	if query.MetricName != "" {
-		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => r._measurement == "%s")`, query.MetricName)
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => %s)`, schema.metricNameFilter(query.MetricName))
 	}
 
 	// Add UUID filter if specified
@@ -152,6 +411,24 @@ func (s *InfluxDBStorage) GetTelemetry(ctx context.Context, query *models.Teleme
 		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => r.gpu_id == "%d")`, *query.GPUID)
 	}
 
+	// Add saved filter sets if specified
+	if len(query.UUIDs) > 0 {
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => %s)`, anyOf("uuid", query.UUIDs))
+	}
+	if len(query.Hostnames) > 0 {
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => %s)`, anyOf("hostname", query.Hostnames))
+	}
+	if len(query.MetricNames) > 0 {
+		predicates := make([]string, len(query.MetricNames))
+		for i, name := range query.MetricNames {
+			predicates[i] = schema.metricNameFilter(name)
+		}
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => %s)`, strings.Join(predicates, " or "))
+	}
+	for _, key := range sortedKeys(query.Labels) {
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => r.%s == "%s")`, key, query.Labels[key])
+	}
+
 	// Sort by time descending
 	fluxQuery += `|> sort(columns: ["_time"], desc: true)`
 
@@ -168,12 +445,43 @@ func (s *InfluxDBStorage) GetTelemetry(ctx context.Context, query *models.Teleme
 		fluxQuery += fmt.Sprintf(`|> limit(n: %d)`, query.Limit)
 	}
 
-	result, err := s.queryAPI.Query(ctx, fluxQuery)
+	return fluxQuery, start, stop
+}
+
+// anyOf returns a Flux predicate matching rows whose column is one of values.
+func anyOf(column string, values []string) string {
+	predicates := make([]string, len(values))
+	for i, v := range values {
+		predicates[i] = fmt.Sprintf(`r.%s == "%s"`, column, v)
+	}
+	if len(predicates) == 1 {
+		return predicates[0]
+	}
+	return "(" + strings.Join(predicates, " or ") + ")"
+}
+
+// sortedKeys returns m's keys in order, so generated queries are stable.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runTelemetryQuery executes fluxQuery and decodes the result, reporting how
+// long InfluxDB took to respond and how long decoding the rows took.
+func (s *InfluxDBStorage) runTelemetryQuery(ctx context.Context, fluxQuery string) ([]*models.GPUMetric, time.Duration, time.Duration, error) {
+	began := time.Now()
+	result, err := s.query(ctx, fluxQuery)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query InfluxDB: %w", err)
+		return nil, 0, 0, classifyInfluxError(fmt.Errorf("failed to query InfluxDB: %w", err))
 	}
 	defer result.Close()
+	execute := time.Since(began)
 
+	began = time.Now()
 	metrics := make([]*models.GPUMetric, 0)
 	for result.Next() {
 		record := result.Record()
@@ -184,7 +492,37 @@ func (s *InfluxDBStorage) GetTelemetry(ctx context.Context, query *models.Teleme
 	}
 
 	if result.Err() != nil {
-		return nil, fmt.Errorf("query error: %w", result.Err())
+		return nil, 0, 0, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	return metrics, execute, time.Since(began), nil
+}
+
+// GetLatest returns the newest value of each metric for each GPU within the window.
+func (s *InfluxDBStorage) GetLatest(ctx context.Context, window time.Duration) ([]*models.GPUMetric, error) {
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => %s)
+			|> group(columns: ["uuid", "%s"])
+			|> last()
+	`, s.config.Bucket, window.String(), s.config.Schema.valueFilter(), s.config.Schema.metricColumn())
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query latest values: %w", err))
+	}
+	defer result.Close()
+
+	metrics := make([]*models.GPUMetric, 0)
+	for result.Next() {
+		if metric := s.recordToMetric(result.Record()); metric != nil && metric.UUID != "" {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
 	}
 
 	return metrics, nil
@@ -195,13 +533,26 @@ func (s *InfluxDBStorage) recordToMetric(record *query.FluxRecord) *models.GPUMe
 	values := record.Values()
 
 	metric := &models.GPUMetric{
-		Timestamp:  record.Time(),
-		MetricName: record.Measurement(),
+		Timestamp: record.Time(),
+	}
+	if v, ok := values[s.config.Schema.metricColumn()].(string); ok {
+		metric.MetricName = s.config.Schema.Aliases.Canonical(v)
 	}
 
-	// Extract value
+	// Extract value, from _value or from the "value" column of a pivoted row
 	if v, ok := record.Value().(float64); ok {
 		metric.Value = v
+	} else if v, ok := values["value"].(float64); ok {
+		metric.Value = v
+	}
+	if v, ok := values["batch_id"].(string); ok {
+		metric.BatchID = v
+	}
+	if v, ok := values[lateField].(bool); ok {
+		metric.Late = v
+	}
+	if v, ok := values[skewedField].(bool); ok {
+		metric.Skewed = v
 	}
 
 	// Extract tags
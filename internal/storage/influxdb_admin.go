@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// cleanupMeasurement holds the admin cleanup history as JSON documents
+// timestamped with their request time.
+const cleanupMeasurement = "cleanup_history"
+
+// DeleteTelemetry deletes telemetry points in [Start, End), sparing the data
+// under retention holds. Deletion is limited to telemetry measurements so
+// annotations, saved queries and history sharing the bucket are never matched.
+func (s *InfluxDBStorage) DeleteTelemetry(ctx context.Context, req *models.CleanupRequest) ([]string, error) {
+	metrics, err := s.telemetryMeasurements(ctx, req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return metrics, nil
+	}
+
+	holds, err := s.ListHolds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := models.PlanCleanup(req, holds, func(start, end time.Time) ([]string, error) {
+		return s.telemetryTagValues(ctx, "uuid", start, end)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, base := range s.config.Schema.deletePredicates(metrics) {
+		for _, part := range parts {
+			predicates := []string{base}
+			if len(part.UUIDs) > 0 {
+				// Delete predicates only support AND, so each GPU is its own call
+				predicates = predicates[:0]
+				for _, id := range part.UUIDs {
+					predicates = append(predicates, fmt.Sprintf(`%s AND uuid="%s"`, base, id))
+				}
+			}
+			for _, predicate := range predicates {
+				if err := s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket, part.Start, part.End, predicate); err != nil {
+					return nil, classifyInfluxError(fmt.Errorf("failed to delete %s: %w", base, err))
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// telemetryMeasurements returns the metric names with data in [start, stop).
+func (s *InfluxDBStorage) telemetryMeasurements(ctx context.Context, start, stop time.Time) ([]string, error) {
+	return s.telemetryTagValues(ctx, s.config.Schema.metricColumn(), start, stop)
+}
+
+// telemetryTagValues returns the values of a telemetry column in [start, stop).
+func (s *InfluxDBStorage) telemetryTagValues(ctx context.Context, tag string, start, stop time.Time) ([]string, error) {
+	fluxQuery := fmt.Sprintf(`
+		import "influxdata/influxdb/schema"
+		schema.tagValues(bucket: "%s", tag: "%s", predicate: (r) => %s, start: %s, stop: %s)
+	`, s.config.Bucket, tag, s.config.Schema.valueFilter(),
+		start.UTC().Format(time.RFC3339Nano), stop.UTC().Format(time.RFC3339Nano))
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query %s values: %w", tag, err))
+	}
+	defer result.Close()
+
+	values := make([]string, 0)
+	for result.Next() {
+		if v, ok := result.Record().Value().(string); ok && v != "" {
+			values = append(values, v)
+		}
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+	return values, nil
+}
+
+// GetRetention returns the bucket's expiry rule (0 = forever).
+func (s *InfluxDBStorage) GetRetention(ctx context.Context) (time.Duration, error) {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, rule := range bucket.RetentionRules {
+		return time.Duration(rule.EverySeconds) * time.Second, nil
+	}
+	return 0, nil
+}
+
+// SetRetention replaces the bucket's expiry rule. The shard group duration is
+// left for InfluxDB to derive, since it may not exceed the new retention.
+func (s *InfluxDBStorage) SetRetention(ctx context.Context, retention time.Duration) error {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+
+	expire := domain.RetentionRuleTypeExpire
+	bucket.RetentionRules = domain.RetentionRules{{
+		EverySeconds: int64(retention / time.Second),
+		Type:         &expire,
+	}}
+	if _, err := s.client.BucketsAPI().UpdateBucket(ctx, bucket); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to update retention: %w", err))
+	}
+	return nil
+}
+
+// bucket looks up the telemetry bucket.
+func (s *InfluxDBStorage) bucket(ctx context.Context) (*domain.Bucket, error) {
+	bucket, err := s.client.BucketsAPI().FindBucketByName(ctx, s.config.Bucket)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to find bucket %q: %w", s.config.Bucket, err))
+	}
+	return bucket, nil
+}
+
+// RecordCleanup appends an entry to the cleanup history.
+func (s *InfluxDBStorage) RecordCleanup(ctx context.Context, record *models.CleanupRecord) error {
+	if err := s.writeDocument(ctx, cleanupMeasurement, map[string]string{"kind": record.Kind}, record.RequestedAt, record); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to record cleanup: %w", err))
+	}
+	return nil
+}
+
+// ListCleanups returns the most recent cleanup history entries, newest first.
+func (s *InfluxDBStorage) ListCleanups(ctx context.Context, limit int) ([]*models.CleanupRecord, error) {
+	filter := `|> group() |> sort(columns: ["_time"], desc: true)`
+	if limit > 0 {
+		filter += fmt.Sprintf(` |> limit(n: %d)`, limit)
+	}
+
+	records := make([]*models.CleanupRecord, 0)
+	err := s.queryDocuments(ctx, cleanupMeasurement, filter, func(data []byte) error {
+		var record models.CleanupRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return err
+		}
+		records = append(records, &record)
+		return nil
+	})
+	return records, err
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Alert rules are stored like saved queries: one point per rule, tagged with
+// its ID and timestamped with its creation time so updates overwrite in place.
+const alertRuleMeasurement = "alert_rules"
+
+// CreateAlertRule stores a new alert rule.
+func (s *InfluxDBStorage) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	if err := s.writeDocument(ctx, alertRuleMeasurement, map[string]string{"id": rule.ID}, rule.CreatedAt, rule); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write alert rule: %w", err))
+	}
+	return nil
+}
+
+// GetAlertRule returns an alert rule by ID.
+func (s *InfluxDBStorage) GetAlertRule(ctx context.Context, id string) (*models.AlertRule, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, perrors.NotFound(fmt.Errorf("alert rule %q not found", id))
+	}
+
+	rules, err := s.queryAlertRules(ctx, fmt.Sprintf(`|> filter(fn: (r) => r.id == "%s")`, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, perrors.NotFound(fmt.Errorf("alert rule %q not found", id))
+	}
+	return rules[0], nil
+}
+
+// ListAlertRules returns all alert rules ordered by name.
+func (s *InfluxDBStorage) ListAlertRules(ctx context.Context) ([]*models.AlertRule, error) {
+	rules, err := s.queryAlertRules(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules, nil
+}
+
+// queryAlertRules decodes the alert rule documents matching filter.
+func (s *InfluxDBStorage) queryAlertRules(ctx context.Context, filter string) ([]*models.AlertRule, error) {
+	rules := make([]*models.AlertRule, 0)
+	err := s.queryDocuments(ctx, alertRuleMeasurement, filter, func(data []byte) error {
+		var rule models.AlertRule
+		if err := json.Unmarshal(data, &rule); err != nil {
+			return err
+		}
+		rules = append(rules, &rule)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// UpdateAlertRule overwrites an existing alert rule, keeping its creation time.
+func (s *InfluxDBStorage) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	existing, err := s.GetAlertRule(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+	rule.CreatedAt = existing.CreatedAt
+
+	if err := s.writeDocument(ctx, alertRuleMeasurement, map[string]string{"id": rule.ID}, rule.CreatedAt, rule); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to update alert rule: %w", err))
+	}
+	return nil
+}
+
+// DeleteAlertRule removes an alert rule.
+func (s *InfluxDBStorage) DeleteAlertRule(ctx context.Context, id string) error {
+	existing, err := s.GetAlertRule(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	predicate := fmt.Sprintf(`_measurement="%s" AND id="%s"`, alertRuleMeasurement, id)
+	err = s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket,
+		existing.CreatedAt, existing.CreatedAt.Add(time.Nanosecond), predicate)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to delete alert rule: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Alert silences are stored like saved queries: one point per silence,
+// tagged with its ID and timestamped with its creation time.
+const silenceMeasurement = "alert_silences"
+
+// CreateSilence stores a new silence.
+func (s *InfluxDBStorage) CreateSilence(ctx context.Context, silence *models.Silence) error {
+	if err := s.writeDocument(ctx, silenceMeasurement, map[string]string{"id": silence.ID}, silence.CreatedAt, silence); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write silence: %w", err))
+	}
+	return nil
+}
+
+// GetSilence returns a silence by ID.
+func (s *InfluxDBStorage) GetSilence(ctx context.Context, id string) (*models.Silence, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, perrors.NotFound(fmt.Errorf("silence %q not found", id))
+	}
+
+	silences, err := s.querySilences(ctx, fmt.Sprintf(`|> filter(fn: (r) => r.id == "%s")`, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(silences) == 0 {
+		return nil, perrors.NotFound(fmt.Errorf("silence %q not found", id))
+	}
+	return silences[0], nil
+}
+
+// ListSilences returns all silences ordered by start time.
+func (s *InfluxDBStorage) ListSilences(ctx context.Context) ([]*models.Silence, error) {
+	silences, err := s.querySilences(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].StartsAt.Before(silences[j].StartsAt) })
+	return silences, nil
+}
+
+// querySilences decodes the silence documents matching filter.
+func (s *InfluxDBStorage) querySilences(ctx context.Context, filter string) ([]*models.Silence, error) {
+	silences := make([]*models.Silence, 0)
+	err := s.queryDocuments(ctx, silenceMeasurement, filter, func(data []byte) error {
+		var silence models.Silence
+		if err := json.Unmarshal(data, &silence); err != nil {
+			return err
+		}
+		silences = append(silences, &silence)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+// DeleteSilence removes a silence.
+func (s *InfluxDBStorage) DeleteSilence(ctx context.Context, id string) error {
+	existing, err := s.GetSilence(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	predicate := fmt.Sprintf(`_measurement="%s" AND id="%s"`, silenceMeasurement, id)
+	err = s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket,
+		existing.CreatedAt, existing.CreatedAt.Add(time.Nanosecond), predicate)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to delete silence: %w", err))
+	}
+	return nil
+}
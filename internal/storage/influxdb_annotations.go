@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// annotationMeasurement holds annotations in the telemetry bucket. Each
+// annotation is one point tagged with its ID and timestamped with its creation
+// time, so an update overwrites the point in place.
+const annotationMeasurement = "annotations"
+
+// CreateAnnotation stores a new annotation.
+func (s *InfluxDBStorage) CreateAnnotation(ctx context.Context, annotation *models.Annotation) error {
+	if err := s.writeAnnotation(ctx, annotation); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write annotation: %w", err))
+	}
+	return nil
+}
+
+// GetAnnotation returns an annotation by ID.
+func (s *InfluxDBStorage) GetAnnotation(ctx context.Context, id string) (*models.Annotation, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, perrors.NotFound(fmt.Errorf("annotation %q not found", id))
+	}
+
+	annotations, err := s.queryAnnotations(ctx, fmt.Sprintf(`|> filter(fn: (r) => r.id == "%s")`, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(annotations) == 0 {
+		return nil, perrors.NotFound(fmt.Errorf("annotation %q not found", id))
+	}
+	return annotations[0], nil
+}
+
+// ListAnnotations returns annotations matching the query, ordered by start time.
+func (s *InfluxDBStorage) ListAnnotations(ctx context.Context, q *models.AnnotationQuery) ([]*models.Annotation, error) {
+	annotations, err := s.queryAnnotations(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]*models.Annotation, 0, len(annotations))
+	for _, a := range annotations {
+		if q.Matches(a) {
+			matched = append(matched, a)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Start.Before(matched[j].Start) })
+	return matched, nil
+}
+
+// UpdateAnnotation overwrites an existing annotation, keeping its creation time.
+func (s *InfluxDBStorage) UpdateAnnotation(ctx context.Context, annotation *models.Annotation) error {
+	existing, err := s.GetAnnotation(ctx, annotation.ID)
+	if err != nil {
+		return err
+	}
+	annotation.CreatedAt = existing.CreatedAt
+
+	if err := s.writeAnnotation(ctx, annotation); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to update annotation: %w", err))
+	}
+	return nil
+}
+
+// DeleteAnnotation removes an annotation by ID.
+func (s *InfluxDBStorage) DeleteAnnotation(ctx context.Context, id string) error {
+	existing, err := s.GetAnnotation(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	predicate := fmt.Sprintf(`_measurement="%s" AND id="%s"`, annotationMeasurement, id)
+	err = s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket,
+		existing.CreatedAt, existing.CreatedAt.Add(time.Nanosecond), predicate)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to delete annotation: %w", err))
+	}
+	return nil
+}
+
+// writeAnnotation writes the annotation's point.
+func (s *InfluxDBStorage) writeAnnotation(ctx context.Context, a *models.Annotation) error {
+	end := ""
+	if !a.End.IsZero() {
+		end = a.End.Format(time.RFC3339Nano)
+	}
+
+	point := influxdb2.NewPointWithMeasurement(annotationMeasurement).
+		AddTag("id", a.ID).
+		AddField("kind", a.Kind).
+		AddField("title", a.Title).
+		AddField("description", a.Description).
+		AddField("hostname", a.Hostname).
+		AddField("uuid", a.UUID).
+		AddField("start", a.Start.Format(time.RFC3339Nano)).
+		AddField("end", end).
+		AddField("updated_at", a.UpdatedAt.Format(time.RFC3339Nano)).
+		SetTime(a.CreatedAt)
+
+	return s.writeAPI.WritePoint(ctx, point)
+}
+
+// queryAnnotations reads annotations, applying an optional extra Flux filter.
+func (s *InfluxDBStorage) queryAnnotations(ctx context.Context, filter string) ([]*models.Annotation, error) {
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: 0)
+			|> filter(fn: (r) => r._measurement == "%s")
+			%s
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+	`, s.config.Bucket, annotationMeasurement, filter)
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query annotations: %w", err))
+	}
+	defer result.Close()
+
+	annotations := make([]*models.Annotation, 0)
+	for result.Next() {
+		annotations = append(annotations, recordToAnnotation(result.Record()))
+	}
+
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	return annotations, nil
+}
+
+// recordToAnnotation converts a pivoted annotation record to an Annotation.
+func recordToAnnotation(record *query.FluxRecord) *models.Annotation {
+	values := record.Values()
+	str := func(key string) string {
+		v, _ := values[key].(string)
+		return v
+	}
+	ts := func(key string) time.Time {
+		t, _ := time.Parse(time.RFC3339Nano, str(key))
+		return t
+	}
+
+	return &models.Annotation{
+		ID:          str("id"),
+		Kind:        str("kind"),
+		Title:       str("title"),
+		Description: str("description"),
+		Hostname:    str("hostname"),
+		UUID:        str("uuid"),
+		Start:       ts("start"),
+		End:         ts("end"),
+		CreatedAt:   record.Time(),
+		UpdatedAt:   ts("updated_at"),
+	}
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// baselineQuantiles maps quantile statistics to their q.
+var baselineQuantiles = []struct {
+	stat string
+	q    string
+}{
+	{"p1", "0.01"}, {"p5", "0.05"}, {"p50", "0.5"}, {"p95", "0.95"}, {"p99", "0.99"},
+}
+
+// ModelBaselines summarizes a metric per GPU model in one query: each
+// statistic is computed over the metric's points grouped by the model tag,
+// labelled with a stat column and unioned. Quantiles are t-digest estimates,
+// so the fleet's history never has to leave InfluxDB.
+func (s *InfluxDBStorage) ModelBaselines(ctx context.Context, metric string, start, end time.Time) ([]*models.Baseline, error) {
+	// Metric names are embedded in the Flux filter
+	if metric == "" || !isPlainID(metric) {
+		return nil, perrors.Validation(fmt.Errorf("invalid metric name %q", metric))
+	}
+
+	tables := []string{
+		`data |> count() |> toFloat() |> set(key: "stat", value: "samples")`,
+		`data |> distinct(column: "uuid") |> count() |> toFloat() |> set(key: "stat", value: "gpus")`,
+		`data |> mean() |> set(key: "stat", value: "mean")`,
+		`data |> stddev() |> set(key: "stat", value: "stddev")`,
+		`data |> min() |> set(key: "stat", value: "min")`,
+		`data |> max() |> set(key: "stat", value: "max")`,
+	}
+	for _, q := range baselineQuantiles {
+		tables = append(tables, fmt.Sprintf(`data |> quantile(q: %s, method: "estimate_tdigest") |> set(key: "stat", value: "%s")`, q.q, q.stat))
+	}
+	fluxQuery := fmt.Sprintf(`
+		data = from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => %s)
+			|> group(columns: ["model"])
+		union(tables: [
+			%s,
+		])
+			|> keep(columns: ["model", "stat", "_value"])
+	`, s.config.Bucket, start.Format(time.RFC3339), end.Format(time.RFC3339), s.config.Schema.metricFilter(metric), strings.Join(tables, ",\n\t\t\t"))
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query baselines: %w", err))
+	}
+	defer result.Close()
+
+	byModel := make(map[string]*models.Baseline)
+	for result.Next() {
+		record := result.Record()
+		model, _ := record.ValueByKey("model").(string)
+		stat, _ := record.ValueByKey("stat").(string)
+		value, ok := record.Value().(float64)
+		if model == "" || !ok {
+			continue
+		}
+		b, ok := byModel[model]
+		if !ok {
+			b = &models.Baseline{ModelName: model, Metric: metric, Start: start, End: end}
+			byModel[model] = b
+		}
+		switch stat {
+		case "samples":
+			b.Samples = int(value)
+		case "gpus":
+			b.GPUs = int(value)
+		default:
+			b.SetStat(stat, value)
+		}
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	baselines := make([]*models.Baseline, 0, len(byModel))
+	for _, b := range byModel {
+		baselines = append(baselines, b)
+	}
+	sort.Slice(baselines, func(i, j int) bool { return baselines[i].ModelName < baselines[j].ModelName })
+	return baselines, nil
+}
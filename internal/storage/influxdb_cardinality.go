@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// cardinalityTopMetrics bounds the metrics listed in a cardinality report.
+const cardinalityTopMetrics = 20
+
+// GetCardinality reads the last point of every telemetry series written in
+// the window and counts them. One row per series leaves InfluxDB.
+func (s *InfluxDBStorage) GetCardinality(ctx context.Context, window time.Duration) (*models.CardinalityReport, error) {
+	schema := s.config.Schema
+	end := time.Now().UTC()
+	report := &models.CardinalityReport{Start: end.Add(-window), End: end}
+
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => %s)
+			|> last()
+	`, s.config.Bucket, report.Start.Format(time.RFC3339), end.Format(time.RFC3339), schema.valueFilter())
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query series: %w", err))
+	}
+	defer result.Close()
+
+	counter := NewSeriesCounter()
+	for result.Next() {
+		values := result.Record().Values()
+		metric, _ := values[schema.metricColumn()].(string)
+		tags := make(map[string]string, len(schema.tags()))
+		for _, tag := range schema.tags() {
+			// InfluxDB does not store empty tags
+			if v, ok := values[tag].(string); ok {
+				tags[tag] = v
+			}
+		}
+		counter.Add(metric, tags)
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	counter.Fill(report, cardinalityTopMetrics)
+	return report, nil
+}
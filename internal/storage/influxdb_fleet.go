@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// FleetSeries averages each GPU's values per window, then sums and counts
+// the per-GPU means of each window in one reduce, so only one row per window
+// leaves InfluxDB.
+func (s *InfluxDBStorage) FleetSeries(ctx context.Context, metric string, start, end time.Time, every time.Duration) ([]models.FleetPoint, error) {
+	// Metric names are embedded in the Flux filter
+	if metric == "" || !isPlainID(metric) {
+		return nil, perrors.Validation(fmt.Errorf("invalid metric name %q", metric))
+	}
+	if every < time.Second {
+		return nil, perrors.Validation(fmt.Errorf("interval %v is shorter than 1s", every))
+	}
+
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => %s)
+			|> group(columns: ["uuid"])
+			|> aggregateWindow(every: %ds, fn: mean, timeSrc: "_start", createEmpty: false)
+			|> group(columns: ["_time"])
+			|> reduce(identity: {sum: 0.0, gpus: 0}, fn: (r, accumulator) => ({sum: accumulator.sum + r._value, gpus: accumulator.gpus + 1}))
+			|> group()
+			|> sort(columns: ["_time"])
+	`, s.config.Bucket, start.Format(time.RFC3339), end.Format(time.RFC3339), s.config.Schema.metricFilter(metric), int64(every/time.Second))
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query fleet series: %w", err))
+	}
+	defer result.Close()
+
+	points := make([]models.FleetPoint, 0)
+	for result.Next() {
+		record := result.Record()
+		at, ok := record.ValueByKey("_time").(time.Time)
+		sum, sumOK := record.ValueByKey("sum").(float64)
+		gpus, gpusOK := record.ValueByKey("gpus").(int64)
+		if !ok || !sumOK || !gpusOK || gpus == 0 {
+			continue
+		}
+		points = append(points, models.FleetPoint{
+			Time: at.UTC(),
+			Mean: sum / float64(gpus),
+			Sum:  sum,
+			GPUs: int(gpus),
+		})
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points, nil
+}
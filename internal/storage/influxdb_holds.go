@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Retention holds are stored like silences: one point per hold, tagged with
+// its ID and timestamped with its creation time. They live outside the
+// telemetry measurements, so deleting telemetry never removes them.
+const holdMeasurement = "retention_holds"
+
+// CreateHold stores a new retention hold.
+func (s *InfluxDBStorage) CreateHold(ctx context.Context, hold *models.RetentionHold) error {
+	if err := s.writeDocument(ctx, holdMeasurement, map[string]string{"id": hold.ID}, hold.CreatedAt, hold); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write retention hold: %w", err))
+	}
+	return nil
+}
+
+// GetHold returns a retention hold by ID.
+func (s *InfluxDBStorage) GetHold(ctx context.Context, id string) (*models.RetentionHold, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, perrors.NotFound(fmt.Errorf("retention hold %q not found", id))
+	}
+
+	holds, err := s.queryHolds(ctx, fmt.Sprintf(`|> filter(fn: (r) => r.id == "%s")`, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(holds) == 0 {
+		return nil, perrors.NotFound(fmt.Errorf("retention hold %q not found", id))
+	}
+	return holds[0], nil
+}
+
+// ListHolds returns all retention holds ordered by creation time.
+func (s *InfluxDBStorage) ListHolds(ctx context.Context) ([]*models.RetentionHold, error) {
+	holds, err := s.queryHolds(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(holds, func(i, j int) bool { return holds[i].CreatedAt.Before(holds[j].CreatedAt) })
+	return holds, nil
+}
+
+// queryHolds decodes the retention hold documents matching filter.
+func (s *InfluxDBStorage) queryHolds(ctx context.Context, filter string) ([]*models.RetentionHold, error) {
+	holds := make([]*models.RetentionHold, 0)
+	err := s.queryDocuments(ctx, holdMeasurement, filter, func(data []byte) error {
+		var hold models.RetentionHold
+		if err := json.Unmarshal(data, &hold); err != nil {
+			return err
+		}
+		holds = append(holds, &hold)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// DeleteHold removes a retention hold, letting cleanup delete its data again.
+func (s *InfluxDBStorage) DeleteHold(ctx context.Context, id string) error {
+	existing, err := s.GetHold(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	predicate := fmt.Sprintf(`_measurement="%s" AND id="%s"`, holdMeasurement, id)
+	err = s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket,
+		existing.CreatedAt, existing.CreatedAt.Add(time.Nanosecond), predicate)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to delete retention hold: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// ingestStatsMeasurement holds each collector's ingest counts per streamer,
+// one point per flush with the counts since the previous one. Reads sum
+// them by hour, so collector restarts and replicas add up.
+const ingestStatsMeasurement = "ingest_stats"
+
+// WriteIngestStats stores one point per streamer with the counts since the
+// collector's previous write. Each point is stamped at, or at the end of its
+// hour if at is past it, so it is summed into the right hour.
+func (s *InfluxDBWriteStorage) WriteIngestStats(ctx context.Context, collector string, stats []*models.IngestStats, at time.Time) error {
+	points := make([]*write.Point, 0, len(stats))
+	for _, st := range stats {
+		ts := at
+		if end := st.Hour.Add(time.Hour); !ts.Before(end) {
+			ts = end.Add(-time.Second)
+		}
+		points = append(points, influxdb2.NewPoint(ingestStatsMeasurement,
+			map[string]string{"source": st.Source, "collector": collector},
+			map[string]interface{}{
+				"batches":          st.Batches,
+				"metrics":          st.Metrics,
+				"bytes":            st.Bytes,
+				"rejected_batches": st.RejectedBatches,
+				"dropped_metrics":  st.DroppedMetrics,
+			},
+			ts))
+	}
+	if err := s.writeAPI.WritePoint(ctx, points...); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write ingest stats: %w", err))
+	}
+	return nil
+}
+
+// GetIngestStats sums the collectors' points by streamer and hour.
+func (s *InfluxDBStorage) GetIngestStats(ctx context.Context, start, end time.Time, source string) ([]*models.IngestStats, error) {
+	// Sources are embedded in the Flux filter
+	filter := ""
+	if source != "" {
+		if !isPlainID(source) {
+			return nil, perrors.Validation(fmt.Errorf("source %q may only contain letters, digits, '-', '_' and '.'", source))
+		}
+		filter = fmt.Sprintf(` and r.source == "%s"`, source)
+	}
+
+	// Whole hours: those starting in [start, end)
+	first := start.Truncate(time.Hour)
+	if first.Before(start) {
+		first = first.Add(time.Hour)
+	}
+	stop := end.Truncate(time.Hour)
+	if stop.Before(end) {
+		stop = stop.Add(time.Hour)
+	}
+	if !first.Before(stop) {
+		return []*models.IngestStats{}, nil
+	}
+
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "%s"%s)
+			|> group(columns: ["source", "_field"])
+			|> aggregateWindow(every: 1h, fn: sum, timeSrc: "_start", createEmpty: false)
+			|> pivot(rowKey: ["_time", "source"], columnKey: ["_field"], valueColumn: "_value")
+	`, s.config.Bucket, first.Format(time.RFC3339), stop.Format(time.RFC3339), ingestStatsMeasurement, filter)
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query ingest stats: %w", err))
+	}
+	defer result.Close()
+
+	stats := make([]*models.IngestStats, 0)
+	for result.Next() {
+		record := result.Record()
+		source, _ := record.ValueByKey("source").(string)
+		st := &models.IngestStats{Source: source, Hour: record.Time().UTC()}
+		st.Add(&models.IngestStats{
+			Batches:         int64Value(record.ValueByKey("batches")),
+			Metrics:         int64Value(record.ValueByKey("metrics")),
+			Bytes:           int64Value(record.ValueByKey("bytes")),
+			RejectedBatches: int64Value(record.ValueByKey("rejected_batches")),
+			DroppedMetrics:  int64Value(record.ValueByKey("dropped_metrics")),
+		})
+		stats = append(stats, st)
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if !stats[i].Hour.Equal(stats[j].Hour) {
+			return stats[i].Hour.Before(stats[j].Hour)
+		}
+		return stats[i].Source < stats[j].Source
+	})
+	return stats, nil
+}
+
+// int64Value converts a summed field, which is missing when nothing was counted.
+func int64Value(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
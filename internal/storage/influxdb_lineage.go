@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// lineageMeasurement holds one JSON document per stored batch in the
+// telemetry bucket, tagged by streamer. The batch ID is a field rather than a
+// tag so that every batch does not become a new series.
+const lineageMeasurement = "batch_lineage"
+
+// lateField marks telemetry points the collector received late, and
+// skewedField those from producers whose clock was skewed.
+const (
+	lateField   = "late"
+	skewedField = "skewed"
+)
+
+// addLineageField records the metric's batch, and whether it arrived late or
+// with a skewed clock, on its telemetry point. All are fields for the same
+// reason as in lineageMeasurement.
+func addLineageField(point *write.Point, metric *models.GPUMetric) {
+	if metric.BatchID != "" {
+		point.AddField("batch_id", metric.BatchID)
+	}
+	if metric.Late {
+		point.AddField(lateField, true)
+	}
+	if metric.Skewed {
+		point.AddField(skewedField, true)
+	}
+}
+
+// RecordBatch stores a batch's lineage, timestamped when it was received.
+func (s *InfluxDBWriteStorage) RecordBatch(ctx context.Context, lineage *models.BatchLineage) error {
+	data, err := json.Marshal(lineage)
+	if err != nil {
+		return perrors.Validation(err)
+	}
+
+	point := influxdb2.NewPoint(lineageMeasurement,
+		map[string]string{"source": lineage.Source},
+		map[string]interface{}{"batch_id": lineage.BatchID, "data": string(data)},
+		lineage.ReceivedAt)
+	if err := s.writeAPI.WritePoint(ctx, point); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to record batch lineage: %w", err))
+	}
+	return nil
+}
+
+// GetBatch returns a batch's most recent lineage by batch ID, so a replayed
+// batch reports its re-ingestion.
+func (s *InfluxDBStorage) GetBatch(ctx context.Context, id string) (*models.BatchLineage, error) {
+	// Batch IDs are embedded in the Flux filter
+	if id == "" || !isPlainID(id) {
+		return nil, perrors.NotFound(fmt.Errorf("batch %q not found", id))
+	}
+
+	batches, err := s.queryLineage(ctx, "0", fmt.Sprintf(`
+			|> filter(fn: (r) => r.batch_id == "%s")
+			|> group()
+			|> sort(columns: ["_time"], desc: true)
+			|> limit(n: 1)`, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, perrors.NotFound(fmt.Errorf("batch %q not found", id))
+	}
+	return batches[0], nil
+}
+
+// ListBatches returns lineage received in [start, end), oldest first.
+func (s *InfluxDBStorage) ListBatches(ctx context.Context, start, end time.Time, limit int) ([]*models.BatchLineage, error) {
+	filter := `
+			|> group()
+			|> sort(columns: ["_time"])`
+	if limit > 0 {
+		filter += fmt.Sprintf(` |> limit(n: %d)`, limit)
+	}
+	return s.queryLineage(ctx, fmt.Sprintf("%s, stop: %s", start.Format(time.RFC3339Nano), end.Format(time.RFC3339Nano)), filter)
+}
+
+// queryLineage reads lineage documents over the given range arguments,
+// applying filter to the pivoted rows.
+func (s *InfluxDBStorage) queryLineage(ctx context.Context, rangeArgs, filter string) ([]*models.BatchLineage, error) {
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s)
+			|> filter(fn: (r) => r._measurement == "%s")
+			|> pivot(rowKey: ["_time"], columnKey: ["_field"], valueColumn: "_value")
+			%s
+	`, s.config.Bucket, rangeArgs, lineageMeasurement, filter)
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query batch lineage: %w", err))
+	}
+	defer result.Close()
+
+	batches := make([]*models.BatchLineage, 0)
+	for result.Next() {
+		data, _ := result.Record().ValueByKey("data").(string)
+		var lineage models.BatchLineage
+		if err := json.Unmarshal([]byte(data), &lineage); err != nil {
+			return nil, perrors.Permanent(fmt.Errorf("failed to decode batch lineage: %w", err))
+		}
+		batches = append(batches, &lineage)
+	}
+
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+	return batches, nil
+}
+
+// isPlainID reports whether id contains only letters, digits, '-', '_' and '.'.
+func isPlainID(id string) bool {
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
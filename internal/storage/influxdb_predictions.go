@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Predictions are JSON documents tagged by UUID and timestamped when they
+// were scored, so a GPU's history is kept until bucket retention expires
+// it. ListPredictions looks back predictionLookback for the latest.
+const (
+	predictionMeasurement = "gpu_prediction"
+	predictionLookback    = 24 * time.Hour
+)
+
+// WritePredictions stores the predictions in one write.
+func (s *InfluxDBStorage) WritePredictions(ctx context.Context, predictions []*models.Prediction) error {
+	points := make([]*write.Point, 0, len(predictions))
+	for _, p := range predictions {
+		data, err := json.Marshal(p)
+		if err != nil {
+			return perrors.Validation(err)
+		}
+		points = append(points, influxdb2.NewPoint(predictionMeasurement,
+			map[string]string{"uuid": p.UUID, "hostname": p.Hostname},
+			map[string]interface{}{"data": string(data), "score": p.Score},
+			p.ScoredAt))
+	}
+	if err := s.writeAPI.WritePoint(ctx, points...); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write predictions: %w", err))
+	}
+	return nil
+}
+
+// ListPredictions returns the latest prediction of each GPU scored within
+// predictionLookback, highest score first.
+func (s *InfluxDBStorage) ListPredictions(ctx context.Context) ([]*models.Prediction, error) {
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "%s" and r._field == "data")
+			|> group(columns: ["uuid"])
+			|> last()
+	`, s.config.Bucket, predictionLookback.String(), predictionMeasurement)
+
+	predictions, err := s.queryPredictions(ctx, fluxQuery)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(predictions, func(i, j int) bool {
+		if predictions[i].Score != predictions[j].Score {
+			return predictions[i].Score > predictions[j].Score
+		}
+		return predictions[i].UUID < predictions[j].UUID
+	})
+	return predictions, nil
+}
+
+// PredictionHistory returns the GPU's predictions scored in [start, end),
+// oldest first.
+func (s *InfluxDBStorage) PredictionHistory(ctx context.Context, uuid string, start, end time.Time) ([]*models.Prediction, error) {
+	if uuid == "" || !isPlainID(uuid) {
+		return nil, perrors.Validation(fmt.Errorf("invalid GPU UUID %q", uuid))
+	}
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "%s" and r._field == "data" and r.uuid == "%s")
+			|> sort(columns: ["_time"])
+	`, s.config.Bucket, start.Format(time.RFC3339), end.Format(time.RFC3339), predictionMeasurement, uuid)
+
+	return s.queryPredictions(ctx, fluxQuery)
+}
+
+func (s *InfluxDBStorage) queryPredictions(ctx context.Context, fluxQuery string) ([]*models.Prediction, error) {
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query predictions: %w", err))
+	}
+	defer result.Close()
+
+	predictions := make([]*models.Prediction, 0)
+	for result.Next() {
+		data, _ := result.Record().Value().(string)
+		var p models.Prediction
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, perrors.Permanent(fmt.Errorf("failed to decode %s document: %w", predictionMeasurement, err))
+		}
+		predictions = append(predictions, &p)
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+	return predictions, nil
+}
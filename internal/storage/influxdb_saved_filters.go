@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Saved filters are stored like saved queries, tagged with their name
+// instead of an ID and timestamped with their creation time so saving
+// again overwrites in place.
+const savedFilterMeasurement = "saved_filters"
+
+// SaveFilter creates or replaces a saved filter.
+func (s *InfluxDBStorage) SaveFilter(ctx context.Context, filter *models.SavedFilter) error {
+	existing, err := s.GetFilter(ctx, filter.Name)
+	switch {
+	case err == nil:
+		filter.CreatedAt = existing.CreatedAt
+	case !perrors.IsNotFound(err):
+		return err
+	}
+
+	if err := s.writeDocument(ctx, savedFilterMeasurement, map[string]string{"name": filter.Name}, filter.CreatedAt, filter); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write saved filter: %w", err))
+	}
+	return nil
+}
+
+// GetFilter returns a saved filter by name.
+func (s *InfluxDBStorage) GetFilter(ctx context.Context, name string) (*models.SavedFilter, error) {
+	// Names are embedded in the Flux filter
+	if !isPlainID(name) {
+		return nil, perrors.NotFound(fmt.Errorf("saved filter %q not found", name))
+	}
+
+	filters, err := s.queryFilters(ctx, fmt.Sprintf(`|> filter(fn: (r) => r.name == "%s")`, name))
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) == 0 {
+		return nil, perrors.NotFound(fmt.Errorf("saved filter %q not found", name))
+	}
+	return filters[0], nil
+}
+
+// ListFilters returns all saved filters ordered by name.
+func (s *InfluxDBStorage) ListFilters(ctx context.Context) ([]*models.SavedFilter, error) {
+	filters, err := s.queryFilters(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Name < filters[j].Name })
+	return filters, nil
+}
+
+// queryFilters decodes the saved filter documents matching filter.
+func (s *InfluxDBStorage) queryFilters(ctx context.Context, filter string) ([]*models.SavedFilter, error) {
+	filters := make([]*models.SavedFilter, 0)
+	err := s.queryDocuments(ctx, savedFilterMeasurement, filter, func(data []byte) error {
+		var f models.SavedFilter
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+		filters = append(filters, &f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// DeleteFilter removes a saved filter.
+func (s *InfluxDBStorage) DeleteFilter(ctx context.Context, name string) error {
+	existing, err := s.GetFilter(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	predicate := fmt.Sprintf(`_measurement="%s" AND name="%s"`, savedFilterMeasurement, name)
+	err = s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket,
+		existing.CreatedAt, existing.CreatedAt.Add(time.Nanosecond), predicate)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to delete saved filter: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Saved queries and their runs live in the telemetry bucket next to
+// annotations. Each is one point tagged with its ID whose "data" field holds
+// the JSON document; saved queries are timestamped with their creation time
+// so updates overwrite in place, and runs with their start time.
+const (
+	savedQueryMeasurement = "saved_queries"
+	runMeasurement        = "saved_query_runs"
+)
+
+// CreateSavedQuery stores a new saved query.
+func (s *InfluxDBStorage) CreateSavedQuery(ctx context.Context, q *models.SavedQuery) error {
+	if err := s.writeDocument(ctx, savedQueryMeasurement, map[string]string{"id": q.ID}, q.CreatedAt, q); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write saved query: %w", err))
+	}
+	return nil
+}
+
+// GetSavedQuery returns a saved query by ID.
+func (s *InfluxDBStorage) GetSavedQuery(ctx context.Context, id string) (*models.SavedQuery, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, perrors.NotFound(fmt.Errorf("saved query %q not found", id))
+	}
+
+	var found []*models.SavedQuery
+	err := s.queryDocuments(ctx, savedQueryMeasurement, fmt.Sprintf(`|> filter(fn: (r) => r.id == "%s")`, id), func(data []byte) error {
+		var q models.SavedQuery
+		if err := json.Unmarshal(data, &q); err != nil {
+			return err
+		}
+		found = append(found, &q)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, perrors.NotFound(fmt.Errorf("saved query %q not found", id))
+	}
+	return found[0], nil
+}
+
+// ListSavedQueries returns all saved queries ordered by name.
+func (s *InfluxDBStorage) ListSavedQueries(ctx context.Context) ([]*models.SavedQuery, error) {
+	queries := make([]*models.SavedQuery, 0)
+	err := s.queryDocuments(ctx, savedQueryMeasurement, "", func(data []byte) error {
+		var q models.SavedQuery
+		if err := json.Unmarshal(data, &q); err != nil {
+			return err
+		}
+		queries = append(queries, &q)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+	return queries, nil
+}
+
+// UpdateSavedQuery overwrites an existing saved query, keeping its creation time.
+func (s *InfluxDBStorage) UpdateSavedQuery(ctx context.Context, q *models.SavedQuery) error {
+	existing, err := s.GetSavedQuery(ctx, q.ID)
+	if err != nil {
+		return err
+	}
+	q.CreatedAt = existing.CreatedAt
+
+	if err := s.writeDocument(ctx, savedQueryMeasurement, map[string]string{"id": q.ID}, q.CreatedAt, q); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to update saved query: %w", err))
+	}
+	return nil
+}
+
+// DeleteSavedQuery removes a saved query and its run history.
+func (s *InfluxDBStorage) DeleteSavedQuery(ctx context.Context, id string) error {
+	existing, err := s.GetSavedQuery(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	predicate := fmt.Sprintf(`_measurement="%s" AND id="%s"`, savedQueryMeasurement, id)
+	err = s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket,
+		existing.CreatedAt, existing.CreatedAt.Add(time.Nanosecond), predicate)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to delete saved query: %w", err))
+	}
+
+	predicate = fmt.Sprintf(`_measurement="%s" AND query_id="%s"`, runMeasurement, id)
+	err = s.deleteAPI.DeleteWithName(ctx, s.config.Org, s.config.Bucket,
+		existing.CreatedAt, time.Now(), predicate)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to delete saved query runs: %w", err))
+	}
+	return nil
+}
+
+// RecordRun appends a run to its saved query's history.
+func (s *InfluxDBStorage) RecordRun(ctx context.Context, run *models.SavedQueryRun) error {
+	if err := s.writeDocument(ctx, runMeasurement, map[string]string{"query_id": run.QueryID}, run.StartedAt, run); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to record saved query run: %w", err))
+	}
+	return nil
+}
+
+// ListRuns returns a saved query's most recent runs, newest first.
+func (s *InfluxDBStorage) ListRuns(ctx context.Context, queryID string, limit int) ([]*models.SavedQueryRun, error) {
+	if _, err := uuid.Parse(queryID); err != nil {
+		return nil, perrors.NotFound(fmt.Errorf("saved query %q not found", queryID))
+	}
+
+	filter := fmt.Sprintf(`|> filter(fn: (r) => r.query_id == "%s") |> sort(columns: ["_time"], desc: true)`, queryID)
+	if limit > 0 {
+		filter += fmt.Sprintf(` |> limit(n: %d)`, limit)
+	}
+
+	runs := make([]*models.SavedQueryRun, 0)
+	err := s.queryDocuments(ctx, runMeasurement, filter, func(data []byte) error {
+		var run models.SavedQueryRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			return err
+		}
+		runs = append(runs, &run)
+		return nil
+	})
+	return runs, err
+}
+
+// writeDocument writes v as a JSON document point.
+func (s *InfluxDBStorage) writeDocument(ctx context.Context, measurement string, tags map[string]string, ts time.Time, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return perrors.Validation(err)
+	}
+
+	point := influxdb2.NewPoint(measurement, tags, map[string]interface{}{"data": string(data)}, ts)
+	return s.writeAPI.WritePoint(ctx, point)
+}
+
+// queryDocuments reads the JSON documents of a measurement, applying an
+// optional extra Flux filter, and passes each to decode.
+func (s *InfluxDBStorage) queryDocuments(ctx context.Context, measurement, filter string, decode func([]byte) error) error {
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: 0)
+			|> filter(fn: (r) => r._measurement == "%s" and r._field == "data")
+			%s
+	`, s.config.Bucket, measurement, filter)
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to query %s: %w", measurement, err))
+	}
+	defer result.Close()
+
+	for result.Next() {
+		data, _ := result.Record().Value().(string)
+		if err := decode([]byte(data)); err != nil {
+			return perrors.Permanent(fmt.Errorf("failed to decode %s document: %w", measurement, err))
+		}
+	}
+
+	if result.Err() != nil {
+		return classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Telemetry schema modes.
+const (
+	// SchemaPerMetric writes each metric to a measurement named after it,
+	// with the value in a "value" field
+	SchemaPerMetric = "per_metric"
+
+	// SchemaSingle writes every metric to one measurement, naming the metric
+	// in a metric_name tag, or in the field name when fields are batched
+	SchemaSingle = "single"
+)
+
+// DefaultMeasurement is the measurement telemetry goes to in SchemaSingle.
+const DefaultMeasurement = "gpu_metrics"
+
+// metricNameTag names the metric of each point in SchemaSingle without
+// field batching.
+const metricNameTag = "metric_name"
+
+// TelemetryTags lists the tags a telemetry point can carry, in write order.
+var TelemetryTags = []string{"uuid", "hostname", "gpu_id", "device", "model", "container", "pod", "namespace"}
+
+// Schema decides how telemetry maps onto InfluxDB measurements, tags and
+// fields. The collector writing telemetry and the API reading it must use
+// the same schema. The zero value is the original layout: a measurement per
+// metric carrying every tag.
+type Schema struct {
+	// Mode is SchemaPerMetric (default) or SchemaSingle
+	Mode string `json:"mode"`
+
+	// Measurement is the measurement used by SchemaSingle (default DefaultMeasurement)
+	Measurement string `json:"measurement"`
+
+	// Tags is the subset of TelemetryTags written with each point (default
+	// all). Dropping per-workload tags like pod and container keeps series
+	// cardinality down; queries filtering on a dropped tag match nothing.
+	Tags []string `json:"tags"`
+
+	// BatchFields writes the metrics a GPU reports at one timestamp as the
+	// fields of a single point, named after each metric. SchemaSingle only.
+	BatchFields bool `json:"batch_fields"`
+
+	// Aliases maps alternate metric names to the name telemetry is stored
+	// under. The collector renames aliased metrics at ingest, and queries
+	// for any name of a metric match points stored under all of them, so a
+	// rename does not split the metric's history.
+	Aliases models.MetricAliases `json:"aliases"`
+}
+
+// Validate reports an unknown mode or tag, a tag set without uuid, or field
+// batching outside SchemaSingle.
+func (s Schema) Validate() error {
+	var errs []error
+	switch s.Mode {
+	case "", SchemaPerMetric:
+		if s.BatchFields {
+			errs = append(errs, fmt.Errorf("field batching requires the %s schema", SchemaSingle))
+		}
+	case SchemaSingle:
+		if !isPlainID(s.Measurement) {
+			errs = append(errs, fmt.Errorf("invalid measurement %q", s.Measurement))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown schema mode %q (want %s or %s)", s.Mode, SchemaPerMetric, SchemaSingle))
+	}
+
+	if s.Tags != nil {
+		seen := make(map[string]bool, len(s.Tags))
+		for _, tag := range s.Tags {
+			if !slices.Contains(TelemetryTags, tag) {
+				errs = append(errs, fmt.Errorf("unknown tag %q (want one of %s)", tag, strings.Join(TelemetryTags, ", ")))
+			}
+			if seen[tag] {
+				errs = append(errs, fmt.Errorf("tag %q listed twice", tag))
+			}
+			seen[tag] = true
+		}
+		// GPUs are listed and grouped by UUID
+		if !seen["uuid"] {
+			errs = append(errs, errors.New("tags must include uuid"))
+		}
+	}
+
+	// Metric names are embedded in Flux filters
+	aliases := make([]string, 0, len(s.Aliases))
+	for alias := range s.Aliases {
+		aliases = append(aliases, alias)
+	}
+	slices.Sort(aliases)
+	for _, alias := range aliases {
+		canonical := s.Aliases[alias]
+		switch {
+		case alias == "" || canonical == "":
+			errs = append(errs, fmt.Errorf("metric alias %q=%q needs both names", alias, canonical))
+		case !isPlainID(alias) || !isPlainID(canonical):
+			errs = append(errs, fmt.Errorf("invalid metric alias %q=%q", alias, canonical))
+		case alias == canonical:
+			errs = append(errs, fmt.Errorf("metric %q is aliased to itself", alias))
+		case s.Aliases[canonical] != "":
+			errs = append(errs, fmt.Errorf("metric alias %q points at %q, which is itself an alias", alias, canonical))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// String describes the schema for startup logs.
+func (s Schema) String() string {
+	layout := "measurement per metric"
+	if s.single() {
+		layout = fmt.Sprintf("single measurement %s, metric in %s tag", s.measurement(), metricNameTag)
+		if s.BatchFields {
+			layout = fmt.Sprintf("single measurement %s, field per metric", s.measurement())
+		}
+	}
+	if len(s.Aliases) > 0 {
+		return fmt.Sprintf("%s (tags: %s, %d metric aliases)", layout, strings.Join(s.tags(), ","), len(s.Aliases))
+	}
+	return fmt.Sprintf("%s (tags: %s)", layout, strings.Join(s.tags(), ","))
+}
+
+func (s Schema) single() bool {
+	return s.Mode == SchemaSingle
+}
+
+func (s Schema) measurement() string {
+	if s.Measurement == "" {
+		return DefaultMeasurement
+	}
+	return s.Measurement
+}
+
+func (s Schema) tags() []string {
+	if s.Tags == nil {
+		return TelemetryTags
+	}
+	return s.Tags
+}
+
+// tagValue returns the value metric carries for one of TelemetryTags.
+func tagValue(metric *models.GPUMetric, tag string) string {
+	switch tag {
+	case "uuid":
+		return metric.UUID
+	case "hostname":
+		return metric.Hostname
+	case "gpu_id":
+		// Host-level metrics belong to no GPU
+		if metric.UUID == "" {
+			return ""
+		}
+		return fmt.Sprintf("%d", metric.GPUID)
+	case "device":
+		return metric.Device
+	case "model":
+		return metric.ModelName
+	case "container":
+		return metric.Container
+	case "pod":
+		return metric.Pod
+	case "namespace":
+		return metric.Namespace
+	}
+	return ""
+}
+
+// points converts metrics to InfluxDB points. With field batching, metrics
+// sharing tags, timestamp and batch become one point, in the order their
+// first metric appears.
+func (s Schema) points(metrics []*models.GPUMetric) []*write.Point {
+	points := make([]*write.Point, 0, len(metrics))
+	var merged map[string]*write.Point
+	if s.BatchFields {
+		merged = make(map[string]*write.Point)
+	}
+
+	for _, metric := range metrics {
+		if merged != nil {
+			key := s.pointKey(metric)
+			if point, ok := merged[key]; ok {
+				point.AddField(metric.MetricName, metric.Value)
+				continue
+			}
+		}
+
+		point := influxdb2.NewPointWithMeasurement(metric.MetricName)
+		if s.single() {
+			point = influxdb2.NewPointWithMeasurement(s.measurement())
+		}
+		for _, tag := range s.tags() {
+			if v := tagValue(metric, tag); v != "" {
+				point.AddTag(tag, v)
+			}
+		}
+		switch {
+		case s.BatchFields:
+			point.AddField(metric.MetricName, metric.Value)
+			merged[s.pointKey(metric)] = point
+		case s.single():
+			point.AddTag(metricNameTag, metric.MetricName).AddField("value", metric.Value)
+		default:
+			point.AddField("value", metric.Value)
+		}
+		point.SetTime(metric.Timestamp)
+		addLineageField(point, metric)
+		points = append(points, point)
+	}
+	return points
+}
+
+// SeriesTags returns the tags a point for metric is stored with, which
+// together with the metric name identify its series. InfluxDB drops empty
+// tags, so they are left out.
+func (s Schema) SeriesTags(metric *models.GPUMetric) map[string]string {
+	tags := make(map[string]string, len(s.tags()))
+	for _, tag := range s.tags() {
+		if v := tagValue(metric, tag); v != "" {
+			tags[tag] = v
+		}
+	}
+	return tags
+}
+
+// pointKey identifies the point a metric is merged into when fields are batched.
+func (s Schema) pointKey(metric *models.GPUMetric) string {
+	parts := []string{metric.Timestamp.UTC().Format(time.RFC3339Nano), metric.BatchID}
+	for _, tag := range s.tags() {
+		parts = append(parts, tagValue(metric, tag))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// metricColumn is the column naming each row's metric in query results.
+func (s Schema) metricColumn() string {
+	switch {
+	case s.BatchFields:
+		return "_field"
+	case s.single():
+		return metricNameTag
+	default:
+		return "_measurement"
+	}
+}
+
+// inMeasurement prefixes a Flux predicate so it only matches telemetry
+// measurements. Every measurement outside SchemaSingle may hold telemetry.
+func (s Schema) inMeasurement(predicate string) string {
+	if !s.single() {
+		return predicate
+	}
+	return fmt.Sprintf(`r._measurement == "%s" and %s`, s.measurement(), predicate)
+}
+
+// valueFilter is a Flux predicate matching every telemetry value.
+func (s Schema) valueFilter() string {
+	if s.BatchFields {
+		return s.inMeasurement(`r._field != "batch_id" and r._field != "late" and r._field != "skewed"`)
+	}
+	return s.inMeasurement(`r._field == "value"`)
+}
+
+// metricFilter is a Flux predicate matching the values of one metric,
+// stored under any of its names.
+func (s Schema) metricFilter(metric string) string {
+	switch {
+	case s.BatchFields:
+		return s.inMeasurement(s.metricNameFilter(metric))
+	case s.single():
+		return s.inMeasurement(fmt.Sprintf(`%s and r._field == "value"`, s.metricNameFilter(metric)))
+	default:
+		return fmt.Sprintf(`%s and r._field == "value"`, s.metricNameFilter(metric))
+	}
+}
+
+// metricNameFilter is a Flux predicate matching rows whose metric column
+// holds any name of metric.
+func (s Schema) metricNameFilter(metric string) string {
+	names := s.Aliases.Names(metric)
+	predicates := make([]string, len(names))
+	for i, name := range names {
+		predicates[i] = fmt.Sprintf(`r.%s == "%s"`, s.metricColumn(), name)
+	}
+	if len(predicates) == 1 {
+		return predicates[0]
+	}
+	return "(" + strings.Join(predicates, " or ") + ")"
+}
+
+// deletePredicates returns the delete predicates removing metrics. Deletes
+// cannot match fields, so with field batching the whole measurement goes.
+func (s Schema) deletePredicates(metrics []string) []string {
+	switch {
+	case s.BatchFields:
+		return []string{fmt.Sprintf(`_measurement="%s"`, s.measurement())}
+	case s.single():
+		predicates := make([]string, 0, len(metrics))
+		for _, metric := range metrics {
+			predicates = append(predicates, fmt.Sprintf(`_measurement="%s" AND %s="%s"`, s.measurement(), metricNameTag, metric))
+		}
+		return predicates
+	default:
+		predicates := make([]string, 0, len(metrics))
+		for _, metric := range metrics {
+			predicates = append(predicates, fmt.Sprintf(`_measurement="%s"`, metric))
+		}
+		return predicates
+	}
+}
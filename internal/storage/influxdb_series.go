@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// GetSeries aggregates each GPU's metrics into buckets with aggregateWindow,
+// so only one point per bucket leaves InfluxDB.
+func (s *InfluxDBStorage) GetSeries(ctx context.Context, query *models.SeriesQuery) ([]*models.Series, error) {
+	if err := query.Validate(); err != nil {
+		return nil, perrors.Validation(err)
+	}
+	// Names are embedded in the Flux filters
+	for _, id := range append([]string{query.UUID, query.Hostname}, query.Metrics...) {
+		if !isPlainID(id) {
+			return nil, perrors.Validation(fmt.Errorf("invalid name %q", id))
+		}
+	}
+	fn := query.Fn
+	if fn == "" {
+		fn = models.SeriesMean
+	}
+
+	schema := s.config.Schema
+	filters := make([]string, 0, len(query.Metrics))
+	for _, m := range query.Metrics {
+		filters = append(filters, "("+schema.metricFilter(m)+")")
+	}
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => %s)
+	`, s.config.Bucket, query.Start.Format(time.RFC3339), query.End.Format(time.RFC3339), strings.Join(filters, " or "))
+	if query.UUID != "" {
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => r.uuid == "%s")`, query.UUID)
+	}
+	if query.Hostname != "" {
+		fluxQuery += fmt.Sprintf(`|> filter(fn: (r) => r.hostname == "%s")`, query.Hostname)
+	}
+	fluxQuery += fmt.Sprintf(`
+			|> group(columns: ["%[1]s", "uuid", "hostname"])
+			|> aggregateWindow(every: %[2]ds, fn: %[3]s, timeSrc: "_start", createEmpty: false)
+			|> keep(columns: ["%[1]s", "uuid", "hostname", "_time", "_value"])
+	`, schema.metricColumn(), int64(query.Every/time.Second), fn)
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query series: %w", err))
+	}
+	defer result.Close()
+
+	type seriesKey struct{ metric, uuid, hostname string }
+	bySeries := make(map[seriesKey]*models.Series)
+	for result.Next() {
+		record := result.Record()
+		value, ok := record.Value().(float64)
+		if !ok {
+			continue
+		}
+		k := seriesKey{}
+		k.metric, _ = record.ValueByKey(schema.metricColumn()).(string)
+		k.metric = schema.Aliases.Canonical(k.metric)
+		k.uuid, _ = record.ValueByKey("uuid").(string)
+		k.hostname, _ = record.ValueByKey("hostname").(string)
+		series, ok := bySeries[k]
+		if !ok {
+			series = &models.Series{Metric: k.metric, UUID: k.uuid, Hostname: k.hostname}
+			bySeries[k] = series
+		}
+		series.Points = append(series.Points, models.SeriesPoint{Time: record.Time().UTC(), Value: value})
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	out := make([]*models.Series, 0, len(bySeries))
+	for _, series := range bySeries {
+		sort.Slice(series.Points, func(i, j int) bool { return series.Points[i].Time.Before(series.Points[j].Time) })
+		out = append(out, series)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Hostname != out[j].Hostname {
+			return out[i].Hostname < out[j].Hostname
+		}
+		if out[i].UUID != out[j].UUID {
+			return out[i].UUID < out[j].UUID
+		}
+		return out[i].Metric < out[j].Metric
+	})
+	return out, nil
+}
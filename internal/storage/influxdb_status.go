@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// GPU statuses are JSON documents tagged by UUID. A status is timestamped
+// with the start of its statusSlot, so rewriting it within the slot
+// replaces the same point: the measurement holds one point per GPU per
+// slot rather than one per update, and bucket retention still expires it.
+const (
+	statusMeasurement = "gpu_status"
+	statusSlot        = time.Hour
+)
+
+// WriteGPUStatuses stores the statuses in one write.
+func (s *InfluxDBStorage) WriteGPUStatuses(ctx context.Context, statuses []*models.GPUStatus) error {
+	points := make([]*write.Point, 0, len(statuses))
+	for _, status := range statuses {
+		data, err := json.Marshal(status)
+		if err != nil {
+			return perrors.Validation(err)
+		}
+		points = append(points, influxdb2.NewPoint(statusMeasurement,
+			map[string]string{"uuid": status.UUID, "hostname": status.Hostname},
+			map[string]interface{}{"data": string(data)},
+			status.UpdatedAt.Truncate(statusSlot)))
+	}
+	if err := s.writeAPI.WritePoint(ctx, points...); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write GPU statuses: %w", err))
+	}
+	return nil
+}
+
+// ListGPUStatuses returns the latest status of each GPU written in the
+// current or previous slot, ordered by hostname and GPU index. GPUs whose
+// status is no longer updated drop out after at most two slots.
+func (s *InfluxDBStorage) ListGPUStatuses(ctx context.Context) ([]*models.GPUStatus, error) {
+	fluxQuery := fmt.Sprintf(`
+		from(bucket: "%s")
+			|> range(start: -%s)
+			|> filter(fn: (r) => r._measurement == "%s" and r._field == "data")
+			|> group(columns: ["uuid"])
+			|> last()
+	`, s.config.Bucket, (2 * statusSlot).String(), statusMeasurement)
+
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return nil, classifyInfluxError(fmt.Errorf("failed to query GPU statuses: %w", err))
+	}
+	defer result.Close()
+
+	statuses := make([]*models.GPUStatus, 0)
+	for result.Next() {
+		data, _ := result.Record().Value().(string)
+		var status models.GPUStatus
+		if err := json.Unmarshal([]byte(data), &status); err != nil {
+			return nil, perrors.Permanent(fmt.Errorf("failed to decode %s document: %w", statusMeasurement, err))
+		}
+		statuses = append(statuses, &status)
+	}
+	if result.Err() != nil {
+		return nil, classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Hostname != statuses[j].Hostname {
+			return statuses[i].Hostname < statuses[j].Hostname
+		}
+		return statuses[i].GPUID < statuses[j].GPUID
+	})
+	return statuses, nil
+}
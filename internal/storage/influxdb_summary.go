@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// GetFleetSummary summarizes every telemetry point in the bucket in three
+// queries, so only counts and two timestamps leave InfluxDB: the points per
+// series and day summed per day, the distinct GPUs per model and in total,
+// and the oldest and newest point.
+func (s *InfluxDBStorage) GetFleetSummary(ctx context.Context) (*models.FleetSummary, error) {
+	data := fmt.Sprintf(`
+		data = from(bucket: "%s")
+			|> range(start: 0, stop: %s)
+			|> filter(fn: (r) => %s)`,
+		s.config.Bucket, time.Now().UTC().Add(time.Minute).Format(time.RFC3339), s.config.Schema.valueFilter())
+
+	summary := &models.FleetSummary{GPUsByModel: make(map[string]int)}
+	if err := s.summaryDaily(ctx, data, summary); err != nil {
+		return nil, err
+	}
+	if err := s.summaryGPUs(ctx, data, summary); err != nil {
+		return nil, err
+	}
+	if err := s.summaryEdges(ctx, data, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// summaryDaily fills the points per UTC day and their total.
+func (s *InfluxDBStorage) summaryDaily(ctx context.Context, data string, summary *models.FleetSummary) error {
+	fluxQuery := data + `
+		data
+			|> aggregateWindow(every: 1d, fn: count, createEmpty: false, timeSrc: "_start")
+			|> group(columns: ["_time"])
+			|> sum()
+			|> group()
+			|> sort(columns: ["_time"])
+	`
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to query daily volume: %w", err))
+	}
+	defer result.Close()
+
+	summary.Daily = make([]models.DailyVolume, 0)
+	for result.Next() {
+		record := result.Record()
+		count := int64Value(record.Value())
+		if count == 0 {
+			continue
+		}
+		summary.Daily = append(summary.Daily, models.DailyVolume{Day: record.Time().UTC(), Metrics: count})
+		summary.TotalMetrics += count
+	}
+	if result.Err() != nil {
+		return classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+	return nil
+}
+
+// summaryGPUs fills the distinct GPUs per model and in total. A GPU whose
+// model tag changed is counted under each model.
+func (s *InfluxDBStorage) summaryGPUs(ctx context.Context, data string, summary *models.FleetSummary) error {
+	fluxQuery := data + `
+		union(tables: [
+			data |> group(columns: ["model"]) |> distinct(column: "uuid") |> count() |> set(key: "stat", value: "model"),
+			data |> group() |> distinct(column: "uuid") |> count() |> set(key: "stat", value: "total"),
+		])
+			|> keep(columns: ["model", "stat", "_value"])
+	`
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to query GPU counts: %w", err))
+	}
+	defer result.Close()
+
+	for result.Next() {
+		record := result.Record()
+		stat, _ := record.ValueByKey("stat").(string)
+		count := int(int64Value(record.Value()))
+		switch model, _ := record.ValueByKey("model").(string); {
+		case stat == "total":
+			summary.TotalGPUs = count
+		case model != "":
+			summary.GPUsByModel[model] = count
+		}
+	}
+	if result.Err() != nil {
+		return classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+	return nil
+}
+
+// summaryEdges fills the timestamps of the oldest and newest points.
+func (s *InfluxDBStorage) summaryEdges(ctx context.Context, data string, summary *models.FleetSummary) error {
+	fluxQuery := data + `
+		union(tables: [
+			data |> first() |> group() |> min(column: "_time") |> set(key: "stat", value: "oldest"),
+			data |> last() |> group() |> max(column: "_time") |> set(key: "stat", value: "newest"),
+		])
+			|> keep(columns: ["stat", "_time"])
+	`
+	result, err := s.query(ctx, fluxQuery)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to query oldest and newest points: %w", err))
+	}
+	defer result.Close()
+
+	for result.Next() {
+		record := result.Record()
+		switch record.ValueByKey("stat") {
+		case "oldest":
+			summary.OldestMetric = record.Time().UTC()
+		case "newest":
+			summary.NewestMetric = record.Time().UTC()
+		}
+	}
+	if result.Err() != nil {
+		return classifyInfluxError(fmt.Errorf("query error: %w", result.Err()))
+	}
+	return nil
+}
@@ -8,12 +8,12 @@ import (
 
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
-	"github.com/influxdata/influxdb-client-go/v2/api/write"
 
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
 )
 
-// InfluxDBWriteStorage implements Storage (read + write) for InfluxDB.
+// InfluxDBWriteStorage implements Storage (read + write) and LineageRecorder for InfluxDB.
 // Used by the collector to store telemetry data.
 type InfluxDBWriteStorage struct {
 	client   influxdb2.Client
@@ -23,6 +23,12 @@ type InfluxDBWriteStorage struct {
 	// Local cache for GPU info
 	gpuCache map[string]*models.GPUInfo
 
+	// expire makes Cleanup delete expired telemetry
+	expire bool
+
+	// lateAPI writes to the bucket late metrics are rerouted to (nil if none)
+	lateAPI api.WriteAPIBlocking
+
 	// Stats
 	totalWrites int64
 }
@@ -30,6 +36,9 @@ type InfluxDBWriteStorage struct {
 // NewInfluxDBWriteStorage creates a new read/write InfluxDB storage backend.
 // Used by the collector to store metrics.
 func NewInfluxDBWriteStorage(config InfluxDBConfig) (*InfluxDBWriteStorage, error) {
+	if err := config.Schema.Validate(); err != nil {
+		return nil, perrors.Validation(fmt.Errorf("invalid InfluxDB schema: %w", err))
+	}
 	client := influxdb2.NewClient(config.URL, config.Token)
 
 	// Test connection
@@ -38,10 +47,10 @@ func NewInfluxDBWriteStorage(config InfluxDBConfig) (*InfluxDBWriteStorage, erro
 
 	health, err := client.Health(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to InfluxDB: %w", err)
+		return nil, perrors.Transient(fmt.Errorf("failed to connect to InfluxDB: %w", err))
 	}
 	if health.Status != "pass" {
-		return nil, fmt.Errorf("InfluxDB health check failed: %s", health.Status)
+		return nil, perrors.Transient(fmt.Errorf("InfluxDB health check failed: %s", health.Status))
 	}
 
 	return &InfluxDBWriteStorage{
@@ -54,21 +63,9 @@ func NewInfluxDBWriteStorage(config InfluxDBConfig) (*InfluxDBWriteStorage, erro
 
 // Store stores a single metric.
 func (s *InfluxDBWriteStorage) Store(ctx context.Context, metric *models.GPUMetric) error {
-	point := influxdb2.NewPointWithMeasurement(metric.MetricName).
-		AddTag("uuid", metric.UUID).
-		AddTag("hostname", metric.Hostname).
-		AddTag("gpu_id", fmt.Sprintf("%d", metric.GPUID)).
-		AddTag("device", metric.Device).
-		AddTag("model", metric.ModelName).
-		AddTag("container", metric.Container).
-		AddTag("pod", metric.Pod).
-		AddTag("namespace", metric.Namespace).
-		AddField("value", metric.Value).
-		SetTime(metric.Timestamp)
-
-	err := s.writeAPI.WritePoint(ctx, point)
+	err := s.writeAPI.WritePoint(ctx, s.config.Schema.points([]*models.GPUMetric{metric})...)
 	if err != nil {
-		return fmt.Errorf("failed to write to InfluxDB: %w", err)
+		return classifyInfluxError(fmt.Errorf("failed to write to InfluxDB: %w", err))
 	}
 
 	s.updateGPUCache(metric)
@@ -77,33 +74,36 @@ func (s *InfluxDBWriteStorage) Store(ctx context.Context, metric *models.GPUMetr
 	return nil
 }
 
-// StoreBatch stores multiple metrics efficiently.
+// StoreBatch stores multiple metrics efficiently, laid out by the configured schema.
 func (s *InfluxDBWriteStorage) StoreBatch(ctx context.Context, metrics []*models.GPUMetric) error {
-	points := make([]*write.Point, 0, len(metrics))
+	err := s.writeAPI.WritePoint(ctx, s.config.Schema.points(metrics)...)
+	if err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write batch to InfluxDB: %w", err))
+	}
 
 	for _, metric := range metrics {
-		point := influxdb2.NewPointWithMeasurement(metric.MetricName).
-			AddTag("uuid", metric.UUID).
-			AddTag("hostname", metric.Hostname).
-			AddTag("gpu_id", fmt.Sprintf("%d", metric.GPUID)).
-			AddTag("device", metric.Device).
-			AddTag("model", metric.ModelName).
-			AddTag("container", metric.Container).
-			AddTag("pod", metric.Pod).
-			AddTag("namespace", metric.Namespace).
-			AddField("value", metric.Value).
-			SetTime(metric.Timestamp)
-
-		points = append(points, point)
 		s.updateGPUCache(metric)
 	}
+	s.totalWrites += int64(len(metrics))
+	return nil
+}
 
-	err := s.writeAPI.WritePoint(ctx, points...)
-	if err != nil {
-		return fmt.Errorf("failed to write batch to InfluxDB: %w", err)
-	}
+// SetLateBucket makes StoreLate write to bucket. It must be called before
+// the storage is used.
+func (s *InfluxDBWriteStorage) SetLateBucket(bucket string) {
+	s.lateAPI = s.client.WriteAPIBlocking(s.config.Org, bucket)
+}
 
-	s.totalWrites += int64(len(metrics))
+// StoreLate stores late metrics in the bucket set by SetLateBucket, laid out
+// by the configured schema. They are left out of the GPU cache and write
+// count, which describe the telemetry bucket.
+func (s *InfluxDBWriteStorage) StoreLate(ctx context.Context, metrics []*models.GPUMetric) error {
+	if s.lateAPI == nil {
+		return perrors.New(perrors.KindPermanent, "no bucket set for late metrics")
+	}
+	if err := s.lateAPI.WritePoint(ctx, s.config.Schema.points(metrics)...); err != nil {
+		return classifyInfluxError(fmt.Errorf("failed to write late metrics to InfluxDB: %w", err))
+	}
 	return nil
 }
 
@@ -151,16 +151,41 @@ func (s *InfluxDBWriteStorage) GetGPUByUUID(ctx context.Context, uuid string) (*
 
 // GetTelemetry is not implemented for write storage - use read storage for queries.
 func (s *InfluxDBWriteStorage) GetTelemetry(ctx context.Context, query *models.TelemetryQuery) ([]*models.GPUMetric, error) {
-	return nil, fmt.Errorf("GetTelemetry not implemented for write storage")
+	return nil, perrors.New(perrors.KindPermanent, "GetTelemetry not implemented for write storage")
 }
 
 // GetMetricsByGPU is not implemented for write storage.
 func (s *InfluxDBWriteStorage) GetMetricsByGPU(ctx context.Context, uuid string, startTime, endTime *time.Time) ([]*models.GPUMetric, error) {
-	return nil, fmt.Errorf("GetMetricsByGPU not implemented for write storage")
+	return nil, perrors.New(perrors.KindPermanent, "GetMetricsByGPU not implemented for write storage")
+}
+
+// SetExpiry makes Cleanup delete telemetry past the retention period,
+// sparing data under retention holds. Without it, expiry is left to the
+// bucket's retention rule, which cannot spare held data. It must be called
+// before the storage is used.
+func (s *InfluxDBWriteStorage) SetExpiry(enabled bool) {
+	s.expire = enabled
 }
 
-// Cleanup is handled by InfluxDB's built-in retention policies.
+// Cleanup deletes telemetry older than retentionPeriod when expiry is
+// enabled. InfluxDB does not say how many points a delete removed, so the
+// count is always 0.
 func (s *InfluxDBWriteStorage) Cleanup(ctx context.Context, retentionPeriod time.Duration) (int, error) {
+	if !s.expire {
+		return 0, nil
+	}
+
+	admin := &InfluxDBStorage{
+		client:    s.client,
+		queryAPI:  s.client.QueryAPI(s.config.Org),
+		writeAPI:  s.writeAPI,
+		deleteAPI: s.client.DeleteAPI(),
+		config:    s.config,
+	}
+	req := &models.CleanupRequest{Start: time.Unix(0, 0).UTC(), End: time.Now().Add(-retentionPeriod).UTC()}
+	if _, err := admin.DeleteTelemetry(ctx, req); err != nil {
+		return 0, err
+	}
 	return 0, nil
 }
 
@@ -21,6 +21,323 @@ type ReadStorage interface {
 	Close() error
 }
 
+// LatestReader is implemented by storage backends that can return the most
+// recent value of every metric for every GPU in a single query.
+// Used by: API latest-values cache
+type LatestReader interface {
+	// GetLatest returns the newest point per GPU and metric seen within the window
+	GetLatest(ctx context.Context, window time.Duration) ([]*models.GPUMetric, error)
+}
+
+// GPUInfoReader is implemented by storage backends that can describe every
+// known GPU in bulk rather than one telemetry query per GPU.
+// Used by: API GET /api/v1/gpus?details=true
+type GPUInfoReader interface {
+	// GetGPUInfos returns identity and first/last-seen times for all known GPUs
+	GetGPUInfos(ctx context.Context) ([]*models.GPUInfo, error)
+}
+
+// Tag keys searchable through TagValuesReader.
+const (
+	TagHostname   = "hostname"
+	TagUUID       = "uuid"
+	TagPod        = "pod"
+	TagMetricName = "_measurement"
+)
+
+// TagValuesReader is implemented by storage backends that can list the
+// distinct values of a tag without scanning telemetry.
+// Used by: API search/autocomplete
+type TagValuesReader interface {
+	// GetTagValues returns the distinct values of tag seen recently
+	GetTagValues(ctx context.Context, tag string) ([]string, error)
+}
+
+// CardinalityReader is implemented by storage backends that can count the
+// telemetry series written recently.
+// Used by: API GET /api/v1/cardinality
+type CardinalityReader interface {
+	// GetCardinality counts the series written in the window ending now
+	GetCardinality(ctx context.Context, window time.Duration) (*models.CardinalityReport, error)
+}
+
+// AnnotationStore is implemented by storage backends that persist
+// operational annotations alongside telemetry.
+// Used by: API annotations endpoints
+type AnnotationStore interface {
+	// CreateAnnotation stores a new annotation; the caller assigns its ID and timestamps
+	CreateAnnotation(ctx context.Context, annotation *models.Annotation) error
+
+	// GetAnnotation returns an annotation by ID, or a not-found error
+	GetAnnotation(ctx context.Context, id string) (*models.Annotation, error)
+
+	// ListAnnotations returns annotations matching the query, ordered by start time
+	ListAnnotations(ctx context.Context, query *models.AnnotationQuery) ([]*models.Annotation, error)
+
+	// UpdateAnnotation replaces an existing annotation, keeping its ID and creation time
+	UpdateAnnotation(ctx context.Context, annotation *models.Annotation) error
+
+	// DeleteAnnotation removes an annotation by ID, or returns a not-found error
+	DeleteAnnotation(ctx context.Context, id string) error
+}
+
+// SavedQueryStore is implemented by storage backends that persist scheduled
+// saved queries and their run history.
+// Used by: API saved-queries endpoints and scheduler
+type SavedQueryStore interface {
+	// CreateSavedQuery stores a new saved query; the caller assigns its ID and timestamps
+	CreateSavedQuery(ctx context.Context, query *models.SavedQuery) error
+
+	// GetSavedQuery returns a saved query by ID, or a not-found error
+	GetSavedQuery(ctx context.Context, id string) (*models.SavedQuery, error)
+
+	// ListSavedQueries returns all saved queries ordered by name
+	ListSavedQueries(ctx context.Context) ([]*models.SavedQuery, error)
+
+	// UpdateSavedQuery replaces an existing saved query, keeping its ID and creation time
+	UpdateSavedQuery(ctx context.Context, query *models.SavedQuery) error
+
+	// DeleteSavedQuery removes a saved query and its run history, or returns a not-found error
+	DeleteSavedQuery(ctx context.Context, id string) error
+
+	// RecordRun appends to a saved query's run history
+	RecordRun(ctx context.Context, run *models.SavedQueryRun) error
+
+	// ListRuns returns up to limit of a saved query's most recent runs, newest first
+	ListRuns(ctx context.Context, queryID string, limit int) ([]*models.SavedQueryRun, error)
+}
+
+// SavedFilterStore is implemented by storage backends that persist named
+// telemetry filters.
+// Used by: API filters endpoints and the telemetry endpoints' filter parameter
+type SavedFilterStore interface {
+	// SaveFilter creates or replaces a filter by name, keeping the creation time of one it replaces
+	SaveFilter(ctx context.Context, filter *models.SavedFilter) error
+
+	// GetFilter returns a filter by name, or a not-found error
+	GetFilter(ctx context.Context, name string) (*models.SavedFilter, error)
+
+	// ListFilters returns all filters ordered by name
+	ListFilters(ctx context.Context) ([]*models.SavedFilter, error)
+
+	// DeleteFilter removes a filter, or returns a not-found error
+	DeleteFilter(ctx context.Context, name string) error
+}
+
+// AlertRuleStore is implemented by storage backends that persist alert rules.
+// Used by: API alert rule endpoints and the alert evaluator
+type AlertRuleStore interface {
+	// CreateAlertRule stores a new rule; the caller assigns its ID and timestamps
+	CreateAlertRule(ctx context.Context, rule *models.AlertRule) error
+
+	// GetAlertRule returns a rule by ID, or a not-found error
+	GetAlertRule(ctx context.Context, id string) (*models.AlertRule, error)
+
+	// ListAlertRules returns all rules ordered by name
+	ListAlertRules(ctx context.Context) ([]*models.AlertRule, error)
+
+	// UpdateAlertRule replaces an existing rule, keeping its ID and creation time
+	UpdateAlertRule(ctx context.Context, rule *models.AlertRule) error
+
+	// DeleteAlertRule removes a rule, or returns a not-found error
+	DeleteAlertRule(ctx context.Context, id string) error
+}
+
+// SilenceStore is implemented by storage backends that persist alert silences.
+// Used by: API alert silence endpoints and the alert evaluator
+type SilenceStore interface {
+	// CreateSilence stores a new silence; the caller assigns its ID and timestamps
+	CreateSilence(ctx context.Context, silence *models.Silence) error
+
+	// GetSilence returns a silence by ID, or a not-found error
+	GetSilence(ctx context.Context, id string) (*models.Silence, error)
+
+	// ListSilences returns all silences ordered by start time
+	ListSilences(ctx context.Context) ([]*models.Silence, error)
+
+	// DeleteSilence removes a silence, or returns a not-found error
+	DeleteSilence(ctx context.Context, id string) error
+}
+
+// HoldStore is implemented by storage backends that keep retention holds.
+// A backend implementing both HoldStore and DataAdmin spares held data in
+// DeleteTelemetry.
+// Used by: API admin endpoints
+type HoldStore interface {
+	// CreateHold stores a new hold; the caller assigns its ID and timestamp
+	CreateHold(ctx context.Context, hold *models.RetentionHold) error
+
+	// GetHold returns a hold by ID, or a not-found error
+	GetHold(ctx context.Context, id string) (*models.RetentionHold, error)
+
+	// ListHolds returns all holds ordered by creation time
+	ListHolds(ctx context.Context) ([]*models.RetentionHold, error)
+
+	// DeleteHold removes a hold, or returns a not-found error
+	DeleteHold(ctx context.Context, id string) error
+}
+
+// DataAdmin is implemented by storage backends that support on-demand
+// deletion and runtime retention changes.
+// Used by: API admin endpoints
+type DataAdmin interface {
+	// DeleteTelemetry removes telemetry in the request's range, returning the metric names it deleted from
+	DeleteTelemetry(ctx context.Context, req *models.CleanupRequest) ([]string, error)
+
+	// GetRetention returns how long telemetry is kept (0 = forever)
+	GetRetention(ctx context.Context) (time.Duration, error)
+
+	// SetRetention changes how long telemetry is kept (0 = forever)
+	SetRetention(ctx context.Context, retention time.Duration) error
+
+	// RecordCleanup appends to the cleanup history
+	RecordCleanup(ctx context.Context, record *models.CleanupRecord) error
+
+	// ListCleanups returns up to limit of the most recent history entries, newest first
+	ListCleanups(ctx context.Context, limit int) ([]*models.CleanupRecord, error)
+}
+
+// LineageRecorder is implemented by storage backends that keep the
+// provenance of each stored batch.
+// Used by: Collector
+type LineageRecorder interface {
+	// RecordBatch stores a batch's lineage after its metrics are stored
+	RecordBatch(ctx context.Context, lineage *models.BatchLineage) error
+}
+
+// IngestStatsRecorder is implemented by storage backends that keep the
+// collectors' ingest statistics.
+// Used by: Collector
+type IngestStatsRecorder interface {
+	// WriteIngestStats adds a collector's counts, observed up to at, to
+	// the hourly rollups
+	WriteIngestStats(ctx context.Context, collector string, stats []*models.IngestStats, at time.Time) error
+}
+
+// LateStore is implemented by storage backends that can keep late-arriving
+// metrics apart from on-time telemetry.
+// Used by: Collector
+type LateStore interface {
+	// StoreLate stores metrics received past the late-data threshold
+	StoreLate(ctx context.Context, metrics []*models.GPUMetric) error
+}
+
+// LineageReader is implemented by storage backends that can look up the
+// provenance of a stored batch.
+// Used by: API GET /api/v1/batches/{id} and re-ingestion
+type LineageReader interface {
+	// GetBatch returns a batch's most recent lineage by batch ID, or a not-found error
+	GetBatch(ctx context.Context, id string) (*models.BatchLineage, error)
+
+	// ListBatches returns up to limit lineage records received in [start, end), oldest first
+	ListBatches(ctx context.Context, start, end time.Time, limit int) ([]*models.BatchLineage, error)
+}
+
+// IngestStatsReader is implemented by storage backends that can roll up the
+// collectors' ingest statistics.
+// Used by: API GET /api/v1/ingest/stats
+type IngestStatsReader interface {
+	// GetIngestStats returns the counts per streamer and hour for the hours
+	// starting in [start, end), of one streamer if source is set, ordered by
+	// hour and source
+	GetIngestStats(ctx context.Context, start, end time.Time, source string) ([]*models.IngestStats, error)
+}
+
+// FleetSummaryReader is implemented by storage backends that can summarize
+// all stored telemetry without returning it.
+// Used by: API fleet summarizer behind GET /api/v1/stats
+type FleetSummaryReader interface {
+	// GetFleetSummary counts the stored GPUs, per model and in total, and
+	// the stored points, per UTC day and in total, and finds the oldest and
+	// newest points
+	GetFleetSummary(ctx context.Context) (*models.FleetSummary, error)
+}
+
+// BaselineReader is implemented by storage backends that can summarize a
+// metric's distribution for each GPU model.
+// Used by: API baseline profiler
+type BaselineReader interface {
+	// ModelBaselines summarizes the metric's values in [start, end) per GPU model
+	ModelBaselines(ctx context.Context, metric string, start, end time.Time) ([]*models.Baseline, error)
+}
+
+// GPUStatusStore is implemented by storage backends that keep the
+// materialized current status of each GPU.
+// Used by: API status materializer, GET /api/v1/fleet/status
+type GPUStatusStore interface {
+	// WriteGPUStatuses replaces the stored status of each GPU given
+	WriteGPUStatuses(ctx context.Context, statuses []*models.GPUStatus) error
+
+	// ListGPUStatuses returns the current status of every GPU updated recently
+	ListGPUStatuses(ctx context.Context) ([]*models.GPUStatus, error)
+}
+
+// PredictionStore is implemented by storage backends that keep the failure
+// predictions scored for each GPU.
+// Used by: API failure predictor, GET /api/v1/predictions
+type PredictionStore interface {
+	// WritePredictions stores the predictions
+	WritePredictions(ctx context.Context, predictions []*models.Prediction) error
+
+	// ListPredictions returns the latest prediction of every GPU scored recently
+	ListPredictions(ctx context.Context) ([]*models.Prediction, error)
+
+	// PredictionHistory returns the GPU's predictions scored in [start, end), oldest first
+	PredictionHistory(ctx context.Context, uuid string, start, end time.Time) ([]*models.Prediction, error)
+}
+
+// FleetSeriesReader is implemented by storage backends that can summarize a
+// metric across the fleet per interval.
+type FleetSeriesReader interface {
+	// FleetSeries averages each GPU's values per interval of every in [start, end)
+	// and combines them across GPUs, oldest first
+	FleetSeries(ctx context.Context, metric string, start, end time.Time, every time.Duration) ([]models.FleetPoint, error)
+}
+
+// SeriesReader is implemented by storage backends that can aggregate
+// telemetry into fixed time buckets per GPU.
+type SeriesReader interface {
+	// GetSeries returns one series per GPU and metric matching the query
+	GetSeries(ctx context.Context, query *models.SeriesQuery) ([]*models.Series, error)
+}
+
+// QueryExplainer is implemented by storage backends that can describe and time
+// the query they run for a telemetry request.
+// Used by: API ?explain=true
+type QueryExplainer interface {
+	// ExplainTelemetry runs the query and returns its plan and timing instead of the data
+	ExplainTelemetry(ctx context.Context, query *models.TelemetryQuery) (*QueryPlan, error)
+}
+
+// ResolutionRaw means the query reads raw points with no rollup applied.
+const ResolutionRaw = "raw"
+
+// QueryPlan describes how a telemetry query was executed.
+type QueryPlan struct {
+	Backend    string      `json:"backend"`
+	Language   string      `json:"language"`
+	Query      string      `json:"query"`
+	Resolution string      `json:"resolution"`
+	ScanStart  time.Time   `json:"scan_start"`
+	ScanStop   time.Time   `json:"scan_stop"`
+	ScanRange  string      `json:"scan_range"`
+	Rows       int         `json:"rows"`
+	Timing     QueryTiming `json:"timing"`
+}
+
+// QueryTiming breaks a query's wall time into phases, in milliseconds.
+type QueryTiming struct {
+	BuildMs   float64 `json:"build_ms"`
+	ExecuteMs float64 `json:"execute_ms"`
+	DecodeMs  float64 `json:"decode_ms"`
+	TotalMs   float64 `json:"total_ms"`
+}
+
+// Milliseconds converts a duration to fractional milliseconds for QueryTiming.
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 // Storage defines the full interface for telemetry data storage.
 // Used by: Collector
 type Storage interface {
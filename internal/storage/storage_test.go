@@ -2,9 +2,17 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
 	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
 )
 
@@ -254,3 +262,289 @@ func TestMockReadStorageClose(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
+
+func TestClassifyInfluxError(t *testing.T) {
+	unavailable := &influxhttp.Error{StatusCode: http.StatusServiceUnavailable, Message: "busy"}
+	if !perrors.IsTransient(classifyInfluxError(unavailable)) {
+		t.Error("expected 503 to be transient")
+	}
+
+	badRequest := &influxhttp.Error{StatusCode: http.StatusBadRequest, Message: "invalid line protocol"}
+	if !perrors.IsPermanent(classifyInfluxError(badRequest)) {
+		t.Error("expected rejected write to be permanent")
+	}
+
+	if !perrors.IsTransient(classifyInfluxError(errors.New("connection refused"))) {
+		t.Error("expected network error to be transient")
+	}
+}
+
+func TestBuildTelemetryQuery(t *testing.T) {
+	s := &InfluxDBStorage{config: InfluxDBConfig{Bucket: "gpu_telemetry"}}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+
+	flux, scanStart, scanStop := s.buildTelemetryQuery(&models.TelemetryQuery{
+		UUID:       "GPU-1",
+		MetricName: "DCGM_FI_DEV_GPU_UTIL",
+		StartTime:  &start,
+		EndTime:    &end,
+		Limit:      50,
+	})
+
+	if !scanStart.Equal(start) || !scanStop.Equal(end) {
+		t.Errorf("expected scan range %v-%v, got %v-%v", start, end, scanStart, scanStop)
+	}
+	for _, want := range []string{
+		`from(bucket: "gpu_telemetry")`,
+		`range(start: 2024-01-01T00:00:00Z, stop: 2024-01-01T06:00:00Z)`,
+		`r.uuid == "GPU-1"`,
+		`r._measurement == "DCGM_FI_DEV_GPU_UTIL"`,
+		`r._field == "value" or r._field == "batch_id"`,
+		`pivot(rowKey: ["_time"]`,
+		`limit(n: 50)`,
+	} {
+		if !strings.Contains(flux, want) {
+			t.Errorf("expected query to contain %s, got %s", want, flux)
+		}
+	}
+	if strings.Contains(flux, "skip(") {
+		t.Error("expected no skip without offset")
+	}
+}
+
+func TestBuildTelemetryQueryFilterSets(t *testing.T) {
+	s := &InfluxDBStorage{config: InfluxDBConfig{Bucket: "gpu_telemetry"}}
+	flux, _, _ := s.buildTelemetryQuery(&models.TelemetryQuery{
+		UUIDs:       []string{"GPU-1"},
+		Hostnames:   []string{"host-001", "host-002"},
+		MetricNames: []string{"DCGM_FI_DEV_GPU_UTIL", "DCGM_FI_DEV_GPU_TEMP"},
+		Labels:      map[string]string{"pod": "train-0", "namespace": "ml"},
+	})
+	for _, want := range []string{
+		`filter(fn: (r) => r.uuid == "GPU-1")`,
+		`(r.hostname == "host-001" or r.hostname == "host-002")`,
+		`r._measurement == "DCGM_FI_DEV_GPU_UTIL" or r._measurement == "DCGM_FI_DEV_GPU_TEMP"`,
+		`r.namespace == "ml")|> filter(fn: (r) => r.pod == "train-0")`,
+	} {
+		if !strings.Contains(flux, want) {
+			t.Errorf("expected query to contain %s, got %s", want, flux)
+		}
+	}
+}
+
+func TestRecordToMetricPivoted(t *testing.T) {
+	s := &InfluxDBStorage{}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	metric := s.recordToMetric(query.NewFluxRecord(0, map[string]interface{}{
+		"_time":        ts,
+		"_measurement": "DCGM_FI_DEV_GPU_UTIL",
+		"uuid":         "GPU-1",
+		"gpu_id":       "3",
+		"value":        87.5,
+		"batch_id":     "batch-1",
+		"late":         true,
+	}))
+	if metric.Value != 87.5 || metric.BatchID != "batch-1" || metric.GPUID != 3 || !metric.Late {
+		t.Errorf("unexpected metric from pivoted row: %+v", metric)
+	}
+
+	// Unpivoted rows (latest values, GPU info) keep reading _value
+	metric = s.recordToMetric(query.NewFluxRecord(0, map[string]interface{}{
+		"_time":        ts,
+		"_measurement": "DCGM_FI_DEV_GPU_UTIL",
+		"_value":       12.0,
+	}))
+	if metric.Value != 12 || metric.BatchID != "" {
+		t.Errorf("unexpected metric from raw row: %+v", metric)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		schema Schema
+		valid  bool
+	}{
+		{"zero value", Schema{}, true},
+		{"single with tags", Schema{Mode: SchemaSingle, Measurement: "gpu", Tags: []string{"uuid", "hostname"}, BatchFields: true}, true},
+		{"unknown mode", Schema{Mode: "wide"}, false},
+		{"batching per metric", Schema{Mode: SchemaPerMetric, BatchFields: true}, false},
+		{"unknown tag", Schema{Tags: []string{"uuid", "rack"}}, false},
+		{"no uuid", Schema{Tags: []string{"hostname"}}, false},
+		{"bad measurement", Schema{Mode: SchemaSingle, Measurement: `gpu" or true`}, false},
+		{"aliases", Schema{Aliases: models.MetricAliases{"gpu_temperature": "DCGM_FI_DEV_GPU_TEMP"}}, true},
+		{"alias without canonical name", Schema{Aliases: metricAliases([]string{"gpu_temperature"})}, false},
+		{"alias chain", Schema{Aliases: models.MetricAliases{"gpu_temp": "gpu_temperature", "gpu_temperature": "DCGM_FI_DEV_GPU_TEMP"}}, false},
+		{"quoted alias", Schema{Aliases: models.MetricAliases{`temp" or true`: "DCGM_FI_DEV_GPU_TEMP"}}, false},
+	} {
+		if err := tc.schema.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", tc.name, err, tc.valid)
+		}
+	}
+}
+
+func TestSchemaPoints(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	metrics := []*models.GPUMetric{
+		{UUID: "GPU-1", Hostname: "host-1", Pod: "job-1", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 87, Timestamp: ts},
+		{UUID: "GPU-1", Hostname: "host-1", Pod: "job-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 45, Timestamp: ts},
+		{UUID: "GPU-2", Hostname: "host-1", Pod: "job-2", MetricName: "DCGM_FI_DEV_GPU_UTIL", Value: 12, Timestamp: ts},
+	}
+	lines := func(points []*write.Point) []string {
+		out := make([]string, 0, len(points))
+		for _, p := range points {
+			out = append(out, strings.TrimSpace(write.PointToLineProtocol(p, time.Second)))
+		}
+		return out
+	}
+
+	got := lines(Schema{}.points(metrics[:1]))
+	if len(got) != 1 || !strings.HasPrefix(got[0], "DCGM_FI_DEV_GPU_UTIL,") || !strings.Contains(got[0], "pod=job-1") || !strings.Contains(got[0], " value=87 ") {
+		t.Errorf("unexpected per-metric point: %v", got)
+	}
+
+	late := *metrics[0]
+	late.Late = true
+	got = lines(Schema{}.points([]*models.GPUMetric{&late}))
+	if len(got) != 1 || !strings.Contains(got[0], " value=87,late=true ") {
+		t.Errorf("expected a late field on the late point: %v", got)
+	}
+
+	skewed := *metrics[0]
+	skewed.Skewed = true
+	got = lines(Schema{}.points([]*models.GPUMetric{&skewed}))
+	if len(got) != 1 || !strings.Contains(got[0], " value=87,skewed=true ") {
+		t.Errorf("expected a skewed field on the skewed point: %v", got)
+	}
+
+	host := &models.GPUMetric{Hostname: "host-1", MetricName: "HOST_SUM_DCGM_FI_DEV_POWER_USAGE", Value: 2400, Timestamp: ts}
+	got = lines(Schema{}.points([]*models.GPUMetric{host}))
+	if len(got) != 1 || got[0] != "HOST_SUM_DCGM_FI_DEV_POWER_USAGE,hostname=host-1 value=2400 1704067200" {
+		t.Errorf("expected a host-level point without GPU tags: %v", got)
+	}
+
+	got = lines(Schema{Mode: SchemaSingle, Measurement: "gpu", Tags: []string{"uuid", "hostname"}}.points(metrics[:1]))
+	if len(got) != 1 || got[0] != "gpu,uuid=GPU-1,hostname=host-1,metric_name=DCGM_FI_DEV_GPU_UTIL value=87 1704067200" {
+		t.Errorf("unexpected single-measurement point: %v", got)
+	}
+
+	got = lines(Schema{Mode: SchemaSingle, Measurement: "gpu", Tags: []string{"uuid"}, BatchFields: true}.points(metrics))
+	want := []string{
+		"gpu,uuid=GPU-1 DCGM_FI_DEV_GPU_UTIL=87,DCGM_FI_DEV_GPU_TEMP=45 1704067200",
+		"gpu,uuid=GPU-2 DCGM_FI_DEV_GPU_UTIL=12 1704067200",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected batched points %v, got %v", want, got)
+	}
+}
+
+func TestBuildTelemetryQuerySingleSchema(t *testing.T) {
+	q := &models.TelemetryQuery{MetricName: "DCGM_FI_DEV_GPU_UTIL"}
+
+	s := &InfluxDBStorage{config: InfluxDBConfig{Bucket: "gpu_telemetry", Schema: Schema{Mode: SchemaSingle, Measurement: "gpu"}}}
+	flux, _, _ := s.buildTelemetryQuery(q)
+	for _, want := range []string{`r._measurement == "gpu" and (r._field == "value" or r._field == "batch_id" or r._field == "late" or r._field == "skewed")`, `r.metric_name == "DCGM_FI_DEV_GPU_UTIL"`, `pivot(`} {
+		if !strings.Contains(flux, want) {
+			t.Errorf("expected query to contain %s, got %s", want, flux)
+		}
+	}
+
+	s.config.Schema.BatchFields = true
+	flux, _, _ = s.buildTelemetryQuery(q)
+	if !strings.Contains(flux, `r._field == "DCGM_FI_DEV_GPU_UTIL"`) || strings.Contains(flux, "pivot(") {
+		t.Errorf("expected an unpivoted query on the metric's field, got %s", flux)
+	}
+	metric := s.recordToMetric(query.NewFluxRecord(0, map[string]interface{}{"_field": "DCGM_FI_DEV_GPU_UTIL", "_value": 87.0, "uuid": "GPU-1"}))
+	if metric.MetricName != "DCGM_FI_DEV_GPU_UTIL" || metric.Value != 87 {
+		t.Errorf("unexpected metric from batched row: %+v", metric)
+	}
+}
+
+func TestMetricAliases(t *testing.T) {
+	aliases := metricAliases([]string{"gpu_temperature = DCGM_FI_DEV_GPU_TEMP"})
+	s := &InfluxDBStorage{config: InfluxDBConfig{Bucket: "gpu_telemetry", Schema: Schema{Aliases: aliases}}}
+
+	// Either name matches points stored under both
+	for _, name := range []string{"gpu_temperature", "DCGM_FI_DEV_GPU_TEMP"} {
+		flux, _, _ := s.buildTelemetryQuery(&models.TelemetryQuery{MetricName: name})
+		want := `(r._measurement == "DCGM_FI_DEV_GPU_TEMP" or r._measurement == "gpu_temperature")`
+		if !strings.Contains(flux, want) {
+			t.Errorf("%s: expected query to contain %s, got %s", name, want, flux)
+		}
+	}
+	want := `(r._measurement == "DCGM_FI_DEV_GPU_TEMP" or r._measurement == "gpu_temperature") and r._field == "value"`
+	if got := s.config.Schema.metricFilter("gpu_temperature"); got != want {
+		t.Errorf("metricFilter = %s, want %s", got, want)
+	}
+
+	// Points stored under the alias are read under the canonical name
+	metric := s.recordToMetric(query.NewFluxRecord(0, map[string]interface{}{"_measurement": "gpu_temperature", "_value": 71.0}))
+	if metric.MetricName != "DCGM_FI_DEV_GPU_TEMP" {
+		t.Errorf("expected the canonical name, got %s", metric.MetricName)
+	}
+}
+
+func TestIsPlainID(t *testing.T) {
+	for id, want := range map[string]bool{
+		"3f0c8a4e-5b7d-4c1a-9e2f-8d6b7a5c4e3f": true,
+		"replay_2024.01":                       true,
+		`x" or true`:                           false,
+		"a b":                                  false,
+	} {
+		if got := isPlainID(id); got != want {
+			t.Errorf("isPlainID(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestRecordToAnnotation(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := query.NewFluxRecord(0, map[string]interface{}{
+		"_time":      created,
+		"id":         "6f1c1f0e-0000-4000-8000-000000000001",
+		"kind":       models.AnnotationDriverUpgrade,
+		"title":      "Driver 550",
+		"hostname":   "host-001",
+		"uuid":       "",
+		"start":      "2024-01-01T01:00:00Z",
+		"end":        "",
+		"updated_at": "2024-01-01T00:30:00Z",
+	})
+
+	a := recordToAnnotation(record)
+	if a.Title != "Driver 550" || a.Hostname != "host-001" || a.Kind != models.AnnotationDriverUpgrade {
+		t.Errorf("unexpected annotation: %+v", a)
+	}
+	if !a.CreatedAt.Equal(created) {
+		t.Errorf("expected created_at from point time, got %v", a.CreatedAt)
+	}
+	if !a.End.IsZero() {
+		t.Error("expected empty end to decode as zero time")
+	}
+	if a.Start.Hour() != 1 {
+		t.Errorf("expected start 01:00, got %v", a.Start)
+	}
+}
+
+func TestSeriesCounter(t *testing.T) {
+	c := NewSeriesCounter()
+	for _, pod := range []string{"job-1", "job-2", "job-1"} {
+		c.Add("DCGM_FI_DEV_GPU_UTIL", map[string]string{"uuid": "GPU-1", "pod": pod})
+	}
+	c.Add("DCGM_FI_DEV_GPU_TEMP", map[string]string{"uuid": "GPU-1"})
+
+	if c.Len() != 3 || !c.Contains("DCGM_FI_DEV_GPU_UTIL", map[string]string{"pod": "job-2", "uuid": "GPU-1"}) {
+		t.Fatalf("expected 3 series including job-2, got %d", c.Len())
+	}
+
+	var report models.CardinalityReport
+	c.Fill(&report, 1)
+	if report.Series != 3 || len(report.Metrics) != 1 || report.Metrics[0] != (models.CardinalityCount{Name: "DCGM_FI_DEV_GPU_UTIL", Count: 2}) {
+		t.Errorf("unexpected metrics %+v", report.Metrics)
+	}
+	if len(report.Tags) != 2 || report.Tags[0] != (models.CardinalityCount{Name: "pod", Count: 2}) {
+		t.Errorf("unexpected tags %+v", report.Tags)
+	}
+}
@@ -0,0 +1,99 @@
+// Package summary keeps a summary of all stored telemetry in memory: GPUs
+// per model, points per day and in total, and the oldest and newest points.
+// It is recomputed in the background, so GET /api/v1/stats answers without
+// scanning storage on every request.
+package summary
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Summarizer periodically recomputes the fleet summary from storage.
+type Summarizer struct {
+	reader  storage.FleetSummaryReader
+	refresh time.Duration
+	days    int
+	logger  *log.Logger
+	clock   clock.Clock
+
+	mu          sync.RWMutex
+	summary     *models.FleetSummary
+	refreshedAt time.Time
+	lastErr     error
+}
+
+// New creates a summarizer that summarizes the telemetry in reader.
+func New(reader storage.FleetSummaryReader, cfg config.SummaryConfig, logger *log.Logger) *Summarizer {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Summarizer{
+		reader:  reader,
+		refresh: cfg.Refresh,
+		days:    cfg.Days,
+		logger:  logger,
+		clock:   clock.Real,
+	}
+}
+
+// Run refreshes the summary now and then every refresh interval until ctx
+// is done.
+func (s *Summarizer) Run(ctx context.Context) {
+	ticker := s.clock.NewTicker(s.refresh)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Printf("Fleet summary refresh failed, keeping the previous summary: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// Refresh recomputes the summary. If storage cannot be read, the previous
+// summary is kept.
+func (s *Summarizer) Refresh(ctx context.Context) error {
+	summary, err := s.reader.GetFleetSummary(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastErr = err
+	if err != nil {
+		return err
+	}
+	if len(summary.Daily) > s.days {
+		summary.Daily = summary.Daily[len(summary.Daily)-s.days:]
+	}
+	s.summary = summary
+	s.refreshedAt = s.clock.Now().UTC()
+	return nil
+}
+
+// Summary returns the latest summary and when it was computed, or nil
+// before the first successful refresh. The summary must not be modified.
+func (s *Summarizer) Summary() (*models.FleetSummary, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.summary, s.refreshedAt
+}
+
+// Status reports when the summary was last refreshed and the last
+// refresh's error, if any.
+func (s *Summarizer) Status() (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refreshedAt, s.lastErr
+}
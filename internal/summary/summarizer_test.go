@@ -0,0 +1,100 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// fakeReader returns a summary with one day's volume per day in days, or an error.
+type fakeReader struct {
+	days int
+	err  error
+}
+
+func (f *fakeReader) GetFleetSummary(ctx context.Context) (*models.FleetSummary, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	summary := &models.FleetSummary{TotalGPUs: 2, GPUsByModel: map[string]int{"H100": 2}}
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < f.days; i++ {
+		summary.Daily = append(summary.Daily, models.DailyVolume{Day: day.AddDate(0, 0, i), Metrics: 10})
+		summary.TotalMetrics += 10
+	}
+	return summary, nil
+}
+
+func TestSummarizerRefresh(t *testing.T) {
+	reader := &fakeReader{days: 5}
+	s := New(reader, config.SummaryConfig{Refresh: time.Minute, Days: 3}, log.New(io.Discard, "", 0))
+	sim := clock.NewSimulated(time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC))
+	s.clock = sim
+
+	if summary, _ := s.Summary(); summary != nil {
+		t.Fatalf("expected no summary before the first refresh, got %+v", summary)
+	}
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() = %v", err)
+	}
+	summary, at := s.Summary()
+	if summary.TotalMetrics != 50 || len(summary.Daily) != 3 || !summary.Daily[0].Day.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the total over every day and the last 3 days listed, got %+v", summary)
+	}
+	if !at.Equal(sim.Now()) {
+		t.Errorf("Summary() computed at %v, want %v", at, sim.Now())
+	}
+
+	// A failed refresh keeps the previous summary and reports the error
+	reader.err = errors.New("influxdb down")
+	sim.Advance(time.Minute)
+	if err := s.Refresh(context.Background()); err == nil {
+		t.Fatal("expected the refresh to fail")
+	}
+	if kept, keptAt := s.Summary(); kept != summary || !keptAt.Equal(at) {
+		t.Error("expected the previous summary to be kept")
+	}
+	if refreshed, err := s.Status(); !refreshed.Equal(at) || err == nil {
+		t.Errorf("Status() = %v, %v", refreshed, err)
+	}
+}
+
+func TestSummarizerRun(t *testing.T) {
+	reader := &fakeReader{days: 1}
+	s := New(reader, config.SummaryConfig{Refresh: time.Minute, Days: 30}, log.New(io.Discard, "", 0))
+	sim := clock.NewSimulated(time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC))
+	s.clock = sim
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	sim.BlockUntil(1)
+	waitFor(t, func() bool { summary, _ := s.Summary(); return summary != nil })
+	first, _ := s.Summary()
+
+	sim.Advance(time.Minute)
+	waitFor(t, func() bool { summary, _ := s.Summary(); return summary != first })
+	cancel()
+	<-done
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,206 @@
+// Package topology reports the live wiring of a deployment. Each component
+// announces itself to the MQ server with periodic heartbeats describing
+// what it publishes, consumes and stores, and the API assembles the
+// components the server has heard from into a graph of nodes and links.
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/internal/storage"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Heartbeat attributes describing a component's wiring.
+const (
+	// AttrMQ lists what the component does with the MQ: "publishes",
+	// "consumes" or both, comma-separated
+	AttrMQ = "mq"
+
+	// AttrStorage holds the storage the component uses, as a JSON list of
+	// Storage
+	AttrStorage = "storage"
+)
+
+// Storage is a storage bucket a component writes to or reads from.
+type Storage struct {
+	Backend string `json:"backend"`
+	URL     string `json:"url"`
+	Org     string `json:"org,omitempty"`
+	Bucket  string `json:"bucket"`
+
+	// Access is models.RelationWrites or models.RelationReads
+	Access string `json:"access"`
+}
+
+// InfluxDB returns the InfluxDB bucket cfg describes, accessed as access.
+func InfluxDB(cfg storage.InfluxDBConfig, access string) Storage {
+	return Storage{Backend: "influxdb", URL: cfg.URL, Org: cfg.Org, Bucket: cfg.Bucket, Access: access}
+}
+
+// Heartbeat returns the heartbeat a component announces itself with, its
+// attributes describing its MQ use (models.RelationPublishes and
+// models.RelationConsumes) and storage.
+func Heartbeat(component, id string, mqUse []string, buckets ...Storage) mq.Heartbeat {
+	hostname, _ := os.Hostname()
+	hb := mq.Heartbeat{
+		Component:  component,
+		ID:         id,
+		Version:    buildinfo.Get(component).Version,
+		Host:       hostname,
+		Attributes: make(map[string]string),
+	}
+	if len(mqUse) > 0 {
+		hb.Attributes[AttrMQ] = strings.Join(mqUse, ",")
+	}
+	if len(buckets) > 0 {
+		for i := range buckets {
+			buckets[i].URL = redactURL(buckets[i].URL)
+		}
+		data, _ := json.Marshal(buckets)
+		hb.Attributes[AttrStorage] = string(data)
+	}
+	return hb
+}
+
+// redactURL drops any credentials from a storage URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	u.User = nil
+	return u.String()
+}
+
+// Beater sends heartbeats to the MQ server; *mq.Client implements it.
+type Beater interface {
+	SendHeartbeat(ctx context.Context, hb mq.Heartbeat, ttl time.Duration) error
+}
+
+// Announce sends hb every interval until ctx is done. The server forgets the
+// component after three missed heartbeats.
+func Announce(ctx context.Context, client Beater, hb mq.Heartbeat, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		err := client.SendHeartbeat(ctx, hb, 3*interval)
+		switch {
+		case err != nil && !failing && ctx.Err() == nil:
+			logger.Printf("Topology heartbeat failed: %v", err)
+		case err == nil && failing:
+			logger.Printf("Topology heartbeat recovered")
+		}
+		failing = err != nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Directory lists the components the MQ server has heard from;
+// *mq.Client implements it.
+type Directory interface {
+	Components(ctx context.Context) ([]mq.Heartbeat, error)
+}
+
+// Source assembles the topology from the MQ server's components.
+type Source struct {
+	dir Directory
+}
+
+// New returns a source listing components from dir.
+func New(dir Directory) *Source {
+	return &Source{dir: dir}
+}
+
+// Topology lists the components and assembles their topology.
+func (s *Source) Topology(ctx context.Context) (models.PipelineTopology, error) {
+	components, err := s.dir.Components(ctx)
+	if err != nil {
+		return models.PipelineTopology{}, err
+	}
+	return Build(components, time.Now()), nil
+}
+
+// Build assembles the topology of components, which start with the MQ
+// server they announced themselves to.
+func Build(components []mq.Heartbeat, now time.Time) models.PipelineTopology {
+	topo := models.PipelineTopology{
+		Nodes:       []models.TopologyNode{},
+		Links:       []models.TopologyLink{},
+		GeneratedAt: now,
+	}
+	var server string
+	seen := make(map[string]bool)
+	for _, hb := range components {
+		node := models.TopologyNode{
+			ID:       hb.Component + "/" + hb.ID,
+			Kind:     hb.Component,
+			Name:     hb.ID,
+			Version:  hb.Version,
+			Host:     hb.Host,
+			Addr:     hb.Addr,
+			LastSeen: &hb.LastSeen,
+		}
+		if hb.Component == mq.ComponentMQServer && server == "" {
+			server = node.ID
+		}
+
+		var buckets []Storage
+		for key, value := range hb.Attributes {
+			switch key {
+			case AttrMQ:
+				for _, use := range strings.Split(value, ",") {
+					switch {
+					case server == "":
+					case use == models.RelationPublishes:
+						topo.Links = append(topo.Links, models.TopologyLink{From: node.ID, To: server, Relation: use})
+					case use == models.RelationConsumes:
+						topo.Links = append(topo.Links, models.TopologyLink{From: server, To: node.ID, Relation: use})
+					}
+				}
+			case AttrStorage:
+				json.Unmarshal([]byte(value), &buckets)
+			default:
+				if node.Attributes == nil {
+					node.Attributes = make(map[string]string)
+				}
+				node.Attributes[key] = value
+			}
+		}
+		topo.Nodes = append(topo.Nodes, node)
+
+		for _, b := range buckets {
+			id := models.NodeKindStorage + "/" + b.Backend + ":" + b.URL + "/" + b.Org + "/" + b.Bucket
+			if !seen[id] {
+				seen[id] = true
+				topo.Nodes = append(topo.Nodes, models.TopologyNode{
+					ID:         id,
+					Kind:       models.NodeKindStorage,
+					Name:       b.Bucket,
+					Attributes: map[string]string{"backend": b.Backend, "url": b.URL, "org": b.Org},
+				})
+			}
+			if b.Access == models.RelationReads {
+				topo.Links = append(topo.Links, models.TopologyLink{From: id, To: node.ID, Relation: b.Access})
+			} else {
+				topo.Links = append(topo.Links, models.TopologyLink{From: node.ID, To: id, Relation: models.RelationWrites})
+			}
+		}
+	}
+	return topo
+}
@@ -0,0 +1,167 @@
+// Package udp implements a lightweight datagram ingest listener for
+// constrained environments where TCP back-pressure is undesirable: senders
+// fire and forget, and whatever cannot be accepted is dropped and counted
+// rather than slowing them down.
+package udp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// MaxDatagramSize is the largest payload a UDP datagram can carry over IPv4.
+const MaxDatagramSize = 65507
+
+// Config configures a Listener.
+type Config struct {
+	// Addr is the host:port to listen on
+	Addr string
+
+	// MaxDatagramSize is the largest datagram accepted; larger ones are
+	// dropped as truncated rather than parsed in part
+	MaxDatagramSize int
+
+	// ReadBuffer sizes the socket receive buffer, which absorbs bursts while
+	// datagrams are parsed; 0 keeps the OS default
+	ReadBuffer int
+}
+
+// Sink takes a datagram's metrics, reporting false when it has no room for
+// them. The metrics are then dropped and counted as overflow.
+type Sink func(metrics []models.GPUMetric) bool
+
+// Stats counts what the listener received and lost. UDP senders never learn
+// about drops, so these counters are the only record of them.
+type Stats struct {
+	// Datagrams is the number of datagrams received
+	Datagrams int64 `json:"datagrams"`
+
+	// Metrics is the number of metrics handed to the sink
+	Metrics int64 `json:"metrics"`
+
+	// Truncated counts datagrams dropped for exceeding MaxDatagramSize
+	Truncated int64 `json:"truncated"`
+
+	// Malformed counts datagrams that could not be parsed at all
+	Malformed int64 `json:"malformed"`
+
+	// Rejected counts metrics dropped as unusable (no GPU UUID, bad value, ...)
+	Rejected int64 `json:"rejected"`
+
+	// Overflow counts metrics dropped because the sink was full
+	Overflow int64 `json:"overflow"`
+}
+
+// Lost reports whether anything was dropped.
+func (s Stats) Lost() bool {
+	return s.Truncated+s.Malformed+s.Rejected+s.Overflow > 0
+}
+
+// Listener receives telemetry datagrams and passes their metrics to a sink.
+type Listener struct {
+	conn   *net.UDPConn
+	cfg    Config
+	sink   Sink
+	logger *log.Logger
+
+	datagrams atomic.Int64
+	metrics   atomic.Int64
+	truncated atomic.Int64
+	malformed atomic.Int64
+	rejected  atomic.Int64
+	overflow  atomic.Int64
+}
+
+// Listen binds the listener's socket. Call Serve to start receiving.
+func Listen(cfg Config, sink Sink, logger *log.Logger) (*Listener, error) {
+	if cfg.MaxDatagramSize <= 0 || cfg.MaxDatagramSize > MaxDatagramSize {
+		cfg.MaxDatagramSize = MaxDatagramSize
+	}
+	addr, err := net.ResolveUDPAddr("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UDP address %q: %w", cfg.Addr, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ReadBuffer > 0 {
+		if err := conn.SetReadBuffer(cfg.ReadBuffer); err != nil {
+			logger.Printf("Could not set UDP receive buffer to %d bytes: %v", cfg.ReadBuffer, err)
+		}
+	}
+	return &Listener{conn: conn, cfg: cfg, sink: sink, logger: logger}, nil
+}
+
+// Addr returns the address the listener is bound to.
+func (l *Listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Serve receives datagrams until ctx is done, then closes the socket.
+func (l *Listener) Serve(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() { l.conn.Close() })
+	defer stop()
+
+	// One byte beyond the limit tells an oversized datagram from one that
+	// fits exactly, since the kernel silently truncates to the buffer
+	buf := make([]byte, l.cfg.MaxDatagramSize+1)
+	for {
+		n, _, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		l.handle(buf[:n], time.Now())
+	}
+}
+
+// handle parses one datagram and hands its metrics to the sink.
+func (l *Listener) handle(datagram []byte, now time.Time) {
+	l.datagrams.Add(1)
+	if len(datagram) > l.cfg.MaxDatagramSize {
+		l.truncated.Add(1)
+		return
+	}
+
+	metrics, rejected, err := Parse(datagram, now)
+	l.rejected.Add(int64(rejected))
+	if err != nil {
+		l.malformed.Add(1)
+		return
+	}
+	if len(metrics) == 0 {
+		return
+	}
+	if !l.sink(metrics) {
+		l.overflow.Add(int64(len(metrics)))
+		return
+	}
+	l.metrics.Add(int64(len(metrics)))
+}
+
+// Stats returns the listener's counters.
+func (l *Listener) Stats() Stats {
+	return Stats{
+		Datagrams: l.datagrams.Load(),
+		Metrics:   l.metrics.Load(),
+		Truncated: l.truncated.Load(),
+		Malformed: l.malformed.Load(),
+		Rejected:  l.rejected.Load(),
+		Overflow:  l.overflow.Load(),
+	}
+}
+
+// Close closes the socket, ending Serve.
+func (l *Listener) Close() error {
+	return l.conn.Close()
+}
@@ -0,0 +1,156 @@
+package udp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// Parse decodes one datagram. A datagram starting with '{' or '[' is JSON: a
+// GPU metric object, or an array of them, in the pipeline's own field names.
+// Anything else is StatsD lines with DogStatsD tags, one metric per line:
+//
+//	DCGM_FI_DEV_GPU_TEMP:45|g|#UUID:GPU-1,Hostname:node-1,gpu:0
+//
+// Gauges (g) and counters (c, scaled by the @rate sample rate) are accepted.
+// Tags fill the GPU identity like dcgm-exporter labels and the rest become
+// labels. A |T<unix seconds> suffix sets the timestamp; otherwise metrics
+// are stamped with now.
+//
+// Metrics that cannot be used (no GPU UUID, non-finite or missing value,
+// gauge deltas, timers, sets, ...) are counted in rejected. An error means
+// the datagram as a whole could not be read.
+func Parse(datagram []byte, now time.Time) (metrics []models.GPUMetric, rejected int, err error) {
+	trimmed := bytes.TrimSpace(datagram)
+	if len(trimmed) == 0 {
+		return nil, 0, nil
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		return parseJSON(trimmed, now)
+	}
+
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		metric, ok := parseStatsdLine(line, now)
+		if !ok {
+			rejected++
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, rejected, nil
+}
+
+// jsonMetric detects a missing value, which a plain GPUMetric would read as 0.
+type jsonMetric struct {
+	models.GPUMetric
+	Value *float64 `json:"value"`
+}
+
+func parseJSON(b []byte, now time.Time) ([]models.GPUMetric, int, error) {
+	var in []jsonMetric
+	if b[0] == '{' {
+		var m jsonMetric
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, 0, fmt.Errorf("invalid JSON metric: %w", err)
+		}
+		in = append(in, m)
+	} else if err := json.Unmarshal(b, &in); err != nil {
+		return nil, 0, fmt.Errorf("invalid JSON metrics: %w", err)
+	}
+
+	var metrics []models.GPUMetric
+	rejected := 0
+	for _, m := range in {
+		if m.UUID == "" || m.MetricName == "" || m.Value == nil || !models.FiniteValue(*m.Value) {
+			rejected++
+			continue
+		}
+		metric := m.GPUMetric
+		metric.Value = *m.Value
+		metric.BatchID = "" // assigned by the batch the metric is published in
+		if metric.Timestamp.IsZero() {
+			metric.Timestamp = now
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, rejected, nil
+}
+
+// parseStatsdLine parses name:value|type[|@rate][|#tags][|T<timestamp>],
+// reporting false for lines that are malformed or cannot be used.
+func parseStatsdLine(line string, now time.Time) (models.GPUMetric, bool) {
+	nameValue, rest, ok := strings.Cut(line, "|")
+	if !ok {
+		return models.GPUMetric{}, false
+	}
+	name, rawValue, ok := strings.Cut(nameValue, ":")
+	// Several values on one line (name:1:2|g) are a StatsD extension we do not read
+	if !ok || name == "" || strings.Contains(rawValue, ":") {
+		return models.GPUMetric{}, false
+	}
+
+	sections := strings.Split(rest, "|")
+	metricType := sections[0]
+	// A signed gauge is a delta from the previous value, which only a
+	// stateful StatsD server can apply
+	if metricType == "g" && (strings.HasPrefix(rawValue, "+") || strings.HasPrefix(rawValue, "-")) {
+		return models.GPUMetric{}, false
+	}
+	if metricType != "g" && metricType != "c" {
+		return models.GPUMetric{}, false
+	}
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil || !models.FiniteValue(value) {
+		return models.GPUMetric{}, false
+	}
+
+	metric := models.GPUMetric{MetricName: name, Timestamp: now}
+	for _, section := range sections[1:] {
+		switch {
+		case strings.HasPrefix(section, "@"):
+			rate, err := strconv.ParseFloat(section[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return models.GPUMetric{}, false
+			}
+			if metricType == "c" {
+				value /= rate
+			}
+		case strings.HasPrefix(section, "#"):
+			for _, tag := range strings.Split(section[1:], ",") {
+				if tag == "" {
+					continue
+				}
+				key, val, _ := strings.Cut(tag, ":")
+				if metric.SetIdentity(key, val) {
+					continue
+				}
+				if metric.Labels == nil {
+					metric.Labels = make(map[string]string)
+				}
+				metric.Labels[key] = val
+			}
+		case strings.HasPrefix(section, "T"):
+			sec, err := strconv.ParseInt(section[1:], 10, 64)
+			if err != nil {
+				return models.GPUMetric{}, false
+			}
+			metric.Timestamp = time.Unix(sec, 0)
+		}
+		// Other sections (container ID, cardinality, ...) are ignored
+	}
+
+	if metric.UUID == "" {
+		return models.GPUMetric{}, false
+	}
+	metric.Value = value
+	return metric, true
+}
@@ -0,0 +1,164 @@
+package udp
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+func TestParseStatsd(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	datagram := "DCGM_FI_DEV_GPU_TEMP:45|g|#UUID:GPU-1,Hostname:node-1,gpu:2,pci_bus_id:0000:01\n" +
+		"DCGM_FI_DEV_XID_ERRORS:3|c|@0.5|#uuid:GPU-1|T1600000000\n"
+
+	metrics, rejected, err := Parse([]byte(datagram), now)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if rejected != 0 || len(metrics) != 2 {
+		t.Fatalf("got %d metrics, %d rejected; want 2, 0", len(metrics), rejected)
+	}
+
+	gauge := metrics[0]
+	if gauge.MetricName != "DCGM_FI_DEV_GPU_TEMP" || gauge.Value != 45 || gauge.UUID != "GPU-1" ||
+		gauge.Hostname != "node-1" || gauge.GPUID != 2 || !gauge.Timestamp.Equal(now) {
+		t.Errorf("unexpected gauge: %+v", gauge)
+	}
+	if gauge.Labels["pci_bus_id"] != "0000:01" {
+		t.Errorf("expected pci_bus_id label, got %v", gauge.Labels)
+	}
+
+	counter := metrics[1]
+	if counter.Value != 6 {
+		t.Errorf("counter value = %v, want 6 (scaled by sample rate)", counter.Value)
+	}
+	if !counter.Timestamp.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("counter timestamp = %v, want the T suffix", counter.Timestamp)
+	}
+}
+
+func TestParseStatsdRejects(t *testing.T) {
+	for _, line := range []string{
+		"temp:+5|g|#uuid:GPU-1",     // gauge delta
+		"temp:-5|g|#uuid:GPU-1",     // gauge delta
+		"latency:12|ms|#uuid:GPU-1", // timer
+		"temp:45|g|#host:node-1",    // no UUID
+		"temp:NaN|g|#uuid:GPU-1",    // not finite
+		"temp:45|g|@2|#uuid:GPU-1",  // bad sample rate
+		"temp:1:2|g|#uuid:GPU-1",    // multi-value
+		"temp45",                    // malformed
+	} {
+		metrics, rejected, err := Parse([]byte(line), time.Now())
+		if err != nil || len(metrics) != 0 || rejected != 1 {
+			t.Errorf("%q: got %d metrics, %d rejected, err %v; want it rejected", line, len(metrics), rejected, err)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	metrics, rejected, err := Parse([]byte(`{"uuid":"GPU-1","metric_name":"temp","value":50,"batch_id":"x"}`), now)
+	if err != nil || rejected != 0 || len(metrics) != 1 {
+		t.Fatalf("object: got %d metrics, %d rejected, err %v", len(metrics), rejected, err)
+	}
+	if m := metrics[0]; m.Value != 50 || m.BatchID != "" || !m.Timestamp.Equal(now) {
+		t.Errorf("unexpected metric: %+v", m)
+	}
+
+	metrics, rejected, err = Parse([]byte(`[
+		{"uuid":"GPU-1","metric_name":"temp","value":0},
+		{"uuid":"GPU-1","metric_name":"temp"},
+		{"metric_name":"temp","value":1}
+	]`), now)
+	if err != nil {
+		t.Fatalf("array: %v", err)
+	}
+	if len(metrics) != 1 || rejected != 2 {
+		t.Errorf("array: got %d metrics, %d rejected; want 1, 2", len(metrics), rejected)
+	}
+
+	if _, _, err := Parse([]byte(`{"uuid":`), now); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestListener(t *testing.T) {
+	var mu sync.Mutex
+	var got []models.GPUMetric
+	full := false
+	sink := func(metrics []models.GPUMetric) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if full {
+			return false
+		}
+		got = append(got, metrics...)
+		return true
+	}
+
+	l, err := Listen(Config{Addr: "127.0.0.1:0", MaxDatagramSize: 128}, sink, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Serve(ctx) }()
+
+	conn, err := net.Dial("udp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(s string) {
+		t.Helper()
+		if _, err := conn.Write([]byte(s)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	waitFor := func(datagrams int64) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for l.Stats().Datagrams < datagrams {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %d datagrams, stats %+v", datagrams, l.Stats())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	send("temp:45|g|#uuid:GPU-1\ntemp:1|g\n")
+	send(`{"uuid":`)
+	send("temp:45|g|#uuid:GPU-1," + strings.Repeat("x", 200))
+	waitFor(3)
+
+	mu.Lock()
+	full = true
+	mu.Unlock()
+	send("temp:46|g|#uuid:GPU-1")
+	waitFor(4)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+
+	want := Stats{Datagrams: 4, Metrics: 1, Truncated: 1, Malformed: 1, Rejected: 1, Overflow: 1}
+	if st := l.Stats(); st != want {
+		t.Errorf("stats = %+v, want %+v", st, want)
+	}
+	if !want.Lost() {
+		t.Error("Lost should report drops")
+	}
+	if len(got) != 1 || got[0].UUID != "GPU-1" {
+		t.Errorf("sink got %+v", got)
+	}
+}
@@ -0,0 +1,269 @@
+// Package usage meters API consumption per tenant: requests served, telemetry
+// data points returned and bytes exported, counted per calendar month (UTC).
+// Tenants are identified by their bearer tokens; requests without one are
+// metered as anonymous. Tenants that use up any of their monthly quotas are
+// refused until the month ends.
+//
+// Each API replica meters the requests it serves, so with several replicas
+// the counters and quotas are per replica.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// MonthFormat is the layout of metered months.
+const MonthFormat = "2006-01"
+
+// keepMonths is how many months of counters are kept, the current one included.
+const keepMonths = 13
+
+// Meter counts API usage per tenant and month and enforces monthly quotas.
+type Meter struct {
+	cfg    config.UsageConfig
+	quota  models.UsageQuota
+	logger *log.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	months map[string]map[string]*models.UsageCounters // month, then tenant
+	dirty  bool
+}
+
+// New creates a meter, loading the counters saved in cfg.File if it exists.
+func New(cfg config.UsageConfig, logger *log.Logger) (*Meter, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	m := &Meter{
+		cfg: cfg,
+		quota: models.UsageQuota{
+			Requests:    cfg.QuotaRequests,
+			Rows:        cfg.QuotaRows,
+			ExportBytes: cfg.QuotaExportBytes,
+		},
+		logger: logger,
+		now:    time.Now,
+		months: make(map[string]map[string]*models.UsageCounters),
+	}
+	if cfg.File == "" {
+		return m, nil
+	}
+	data, err := os.ReadFile(cfg.File)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.months); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file: %w", err)
+	}
+	return m, nil
+}
+
+// Middleware meters each request against the caller identified by
+// auth.Identify, refusing tenants over quota with 429 Too Many Requests.
+func (m *Meter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant, limited := tenantOf(r.Context())
+		now := m.now().UTC()
+		month := now.Format(MonthFormat)
+
+		if limited {
+			if counter, over := m.quota.Exceeded(m.counters(month, tenant)); over {
+				reset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+				w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+				perrors.WriteHTTP(w, http.StatusTooManyRequests, "quota_exceeded",
+					fmt.Sprintf("Tenant %s has used its monthly %s quota for %s", tenant, counter, month))
+				return
+			}
+		}
+
+		rec := &recorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), recorderKey{}, rec)))
+
+		usage := models.UsageCounters{Requests: 1, Rows: rec.rows}
+		if rec.export {
+			usage.ExportBytes = rec.bytes
+		}
+		m.add(month, tenant, usage)
+	})
+}
+
+// Get returns a tenant's usage in a month.
+func (m *Meter) Get(tenant, month string) models.TenantUsage {
+	return m.tenantUsage(tenant, month, m.counters(month, tenant))
+}
+
+// List returns every tenant's usage in a month, ordered by tenant.
+func (m *Meter) List(month string) []models.TenantUsage {
+	m.mu.Lock()
+	list := make([]models.TenantUsage, 0, len(m.months[month]))
+	for tenant, c := range m.months[month] {
+		list = append(list, m.tenantUsage(tenant, month, *c))
+	}
+	m.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Tenant < list[j].Tenant })
+	return list
+}
+
+// Month returns the month being metered now.
+func (m *Meter) Month() string {
+	return m.now().UTC().Format(MonthFormat)
+}
+
+// Run saves the counters every flush interval until ctx is done.
+func (m *Meter) Run(ctx context.Context) {
+	if m.cfg.File == "" {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Save(); err != nil {
+				m.logger.Printf("Failed to save usage: %v", err)
+			}
+		}
+	}
+}
+
+// Save writes changed counters to the usage file, replacing it atomically,
+// and drops months older than a year.
+func (m *Meter) Save() error {
+	if m.cfg.File == "" {
+		return nil
+	}
+	m.mu.Lock()
+	if !m.dirty {
+		m.mu.Unlock()
+		return nil
+	}
+	now := m.now().UTC()
+	oldest := time.Date(now.Year(), now.Month()-keepMonths+1, 1, 0, 0, 0, 0, time.UTC).Format(MonthFormat)
+	for month := range m.months {
+		if month < oldest {
+			delete(m.months, month)
+		}
+	}
+	data, err := json.Marshal(m.months)
+	m.dirty = false
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := m.cfg.File + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.cfg.File)
+}
+
+// counters returns a copy of a tenant's counters for a month.
+func (m *Meter) counters(month, tenant string) models.UsageCounters {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c := m.months[month][tenant]; c != nil {
+		return *c
+	}
+	return models.UsageCounters{}
+}
+
+func (m *Meter) add(month, tenant string, usage models.UsageCounters) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenants := m.months[month]
+	if tenants == nil {
+		tenants = make(map[string]*models.UsageCounters)
+		m.months[month] = tenants
+	}
+	c := tenants[tenant]
+	if c == nil {
+		c = &models.UsageCounters{}
+		tenants[tenant] = c
+	}
+	c.Requests += usage.Requests
+	c.Rows += usage.Rows
+	c.ExportBytes += usage.ExportBytes
+	m.dirty = true
+}
+
+func (m *Meter) tenantUsage(tenant, month string, c models.UsageCounters) models.TenantUsage {
+	u := models.TenantUsage{Tenant: tenant, Month: month, UsageCounters: c}
+	if Limited(tenant) && m.quota != (models.UsageQuota{}) {
+		quota := m.quota
+		u.Quota = &quota
+	}
+	return u
+}
+
+// Limited reports whether quotas apply to a tenant: every tenant except the
+// admin and anonymous callers.
+func Limited(tenant string) bool {
+	return tenant != string(auth.RoleAdmin) && tenant != models.AnonymousTenant
+}
+
+// Tenant returns the tenant a request is metered against.
+func Tenant(ctx context.Context) string {
+	tenant, _ := tenantOf(ctx)
+	return tenant
+}
+
+func tenantOf(ctx context.Context) (tenant string, limited bool) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return models.AnonymousTenant, false
+	}
+	return p.Name, p.Role == auth.RoleTenant
+}
+
+type recorderKey struct{}
+
+// recorder counts what a metered request's response sent.
+type recorder struct {
+	http.ResponseWriter
+	bytes  int64
+	rows   int64
+	export bool
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// AddRows counts telemetry data points returned by the request in ctx.
+func AddRows(ctx context.Context, n int) {
+	if rec, ok := ctx.Value(recorderKey{}).(*recorder); ok {
+		rec.rows += int64(n)
+	}
+}
+
+// Export counts the response body of the request in ctx as exported bytes.
+func Export(ctx context.Context) {
+	if rec, ok := ctx.Value(recorderKey{}).(*recorder); ok {
+		rec.export = true
+	}
+}
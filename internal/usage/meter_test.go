@@ -0,0 +1,139 @@
+package usage
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/api/auth"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+)
+
+// meteredHandler returns 3 rows of 10 bytes, as an export when asked to.
+func meteredHandler(m *Meter, a *auth.Authenticator) http.Handler {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddRows(r.Context(), 3)
+		if r.URL.Query().Get("export") != "" {
+			Export(r.Context())
+		}
+		io.WriteString(w, "0123456789")
+	})
+	return a.Identify()(m.Middleware(h))
+}
+
+func call(h http.Handler, token, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/gpus?"+query, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func newTestMeter(t *testing.T, cfg config.UsageConfig) *Meter {
+	t.Helper()
+	m, err := New(cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.now = func() time.Time { return time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC) }
+	return m
+}
+
+func TestMeterCounts(t *testing.T) {
+	m := newTestMeter(t, config.UsageConfig{})
+	a := auth.New("admin-token")
+	a.AddTenant("acme", "acme-token")
+	h := meteredHandler(m, a)
+
+	call(h, "acme-token", "")
+	call(h, "acme-token", "export=1")
+	call(h, "", "")
+	call(h, "admin-token", "")
+
+	acme := m.Get("acme", "2026-10")
+	if acme.Requests != 2 || acme.Rows != 6 || acme.ExportBytes != 10 {
+		t.Errorf("acme usage = %+v, want 2 requests, 6 rows, 10 export bytes", acme.UsageCounters)
+	}
+	if acme.Quota != nil {
+		t.Errorf("no quota is configured, got %+v", acme.Quota)
+	}
+
+	list := m.List("2026-10")
+	var tenants []string
+	for _, u := range list {
+		tenants = append(tenants, u.Tenant)
+	}
+	if got := strings.Join(tenants, ","); got != "acme,admin,anonymous" {
+		t.Errorf("tenants = %s", got)
+	}
+	if len(m.List("2026-09")) != 0 {
+		t.Error("expected no usage in another month")
+	}
+}
+
+func TestMeterQuota(t *testing.T) {
+	m := newTestMeter(t, config.UsageConfig{QuotaRows: 5})
+	a := auth.New("admin-token")
+	a.AddTenant("acme", "acme-token")
+	h := meteredHandler(m, a)
+
+	// The request crossing the quota is served; the next is refused
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if w := call(h, "acme-token", ""); w.Code != want {
+			t.Errorf("request %d: status %d, want %d", i, w.Code, want)
+		}
+	}
+	w := call(h, "acme-token", "")
+	if got := w.Header().Get("Retry-After"); got != "3601" {
+		t.Errorf("Retry-After = %q, want seconds to the next month", got)
+	}
+	if !strings.Contains(w.Body.String(), "quota_exceeded") {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+	if u := m.Get("acme", "2026-10"); u.Requests != 2 || u.Quota == nil || u.Quota.Rows != 5 {
+		t.Errorf("refused requests should not be metered, got %+v quota %+v", u.UsageCounters, u.Quota)
+	}
+
+	// Quotas apply to tenants only
+	for i := 0; i < 3; i++ {
+		if w := call(h, "", ""); w.Code != http.StatusOK {
+			t.Errorf("anonymous request refused with %d", w.Code)
+		}
+		if w := call(h, "admin-token", ""); w.Code != http.StatusOK {
+			t.Errorf("admin request refused with %d", w.Code)
+		}
+	}
+
+	// A new month starts from zero
+	m.now = func() time.Time { return time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC) }
+	if w := call(h, "acme-token", ""); w.Code != http.StatusOK {
+		t.Errorf("expected the quota to reset in a new month, got %d", w.Code)
+	}
+}
+
+func TestMeterPersistence(t *testing.T) {
+	cfg := config.UsageConfig{File: filepath.Join(t.TempDir(), "usage.json")}
+	m := newTestMeter(t, cfg)
+	a := auth.New("")
+	a.AddTenant("acme", "acme-token")
+	call(meteredHandler(m, a), "acme-token", "")
+	m.add("2025-09", "acme", m.counters("2026-10", "acme"))
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := newTestMeter(t, cfg)
+	if u := reopened.Get("acme", "2026-10"); u.Requests != 1 || u.Rows != 3 {
+		t.Errorf("reopened usage = %+v", u.UsageCounters)
+	}
+	if len(reopened.List("2025-09")) != 0 {
+		t.Error("months older than a year should be dropped on save")
+	}
+}
@@ -0,0 +1,104 @@
+// Package buildinfo reports the version, git commit and build date stamped
+// into each binary at build time, so mixed-version deployments can be
+// diagnosed. Stamp them with:
+//
+//	go build -ldflags "-X github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo.Version=1.2.0 \
+//	  -X github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Unstamped binaries fall back to the VCS details the Go toolchain records.
+package buildinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time with -ldflags -X.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the build of one component.
+type Info struct {
+	Component string `json:"component" example:"api"`
+	Version   string `json:"version" example:"1.2.0"`
+	Commit    string `json:"commit,omitempty" example:"9b35ff7"`
+	BuildDate string `json:"build_date,omitempty" example:"2024-01-15T10:30:00Z"`
+	GoVersion string `json:"go_version" example:"go1.22.5"`
+
+	// Modified is set when an unstamped binary was built from a dirty tree
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Get returns the build info of the named component.
+func Get(component string) Info {
+	info := Info{
+		Component: component,
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: Date,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit != "" {
+		return info
+	}
+
+	// Fall back to what go build recorded from the working tree
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = shortCommit(s.Value)
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// shortCommit abbreviates a full commit hash the way git does.
+func shortCommit(rev string) string {
+	if len(rev) > 7 {
+		return rev[:7]
+	}
+	return rev
+}
+
+// String formats the info for startup logs, such as
+// "api 1.2.0 (commit 9b35ff7, built 2024-01-15T10:30:00Z, go1.22.5)".
+func (i Info) String() string {
+	details := []string{}
+	if i.Commit != "" {
+		commit := "commit " + i.Commit
+		if i.Modified {
+			commit += "+dirty"
+		}
+		details = append(details, commit)
+	}
+	if i.BuildDate != "" {
+		details = append(details, "built "+i.BuildDate)
+	}
+	details = append(details, i.GoVersion)
+	return fmt.Sprintf("%s %s (%s)", i.Component, i.Version, strings.Join(details, ", "))
+}
+
+// Handler serves the component's build info as JSON, for /version.
+func Handler(component string) http.HandlerFunc {
+	info := Get(component)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(info)
+	}
+}
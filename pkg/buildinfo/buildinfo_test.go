@@ -0,0 +1,62 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func stamp(t *testing.T, version, commit, date string) {
+	t.Helper()
+	oldVersion, oldCommit, oldDate := Version, Commit, Date
+	Version, Commit, Date = version, commit, date
+	t.Cleanup(func() { Version, Commit, Date = oldVersion, oldCommit, oldDate })
+}
+
+func TestGetStamped(t *testing.T) {
+	stamp(t, "1.2.0", "9b35ff7", "2024-01-15T10:30:00Z")
+
+	info := Get("collector")
+	if info.Component != "collector" || info.Version != "1.2.0" || info.Commit != "9b35ff7" || info.BuildDate != "2024-01-15T10:30:00Z" {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if !strings.HasPrefix(info.GoVersion, "go") {
+		t.Errorf("expected the Go version, got %q", info.GoVersion)
+	}
+
+	want := "collector 1.2.0 (commit 9b35ff7, built 2024-01-15T10:30:00Z, " + info.GoVersion + ")"
+	if got := info.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestGetUnstamped(t *testing.T) {
+	stamp(t, "dev", "", "")
+
+	info := Get("api")
+	if info.Version != "dev" {
+		t.Errorf("expected the dev version, got %q", info.Version)
+	}
+	if !strings.HasPrefix(info.String(), "api dev (") {
+		t.Errorf("unexpected String() %q", info.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	stamp(t, "1.2.0", "9b35ff7", "")
+
+	rec := httptest.NewRecorder()
+	Handler("mq-server")(rec, httptest.NewRequest("GET", "/version", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	var info Info
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if info.Component != "mq-server" || info.Version != "1.2.0" || info.Commit != "9b35ff7" {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
@@ -0,0 +1,210 @@
+// Package clock abstracts the passage of time so that intervals, timestamps,
+// retention and alert durations can be driven by a simulated clock in tests
+// instead of the wall clock.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules tickers and timers.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+
+	// NewTicker returns a ticker that fires every d
+	NewTicker(d time.Duration) Ticker
+
+	// After returns a channel that receives the time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker delivers ticks at an interval, like time.Ticker.
+type Ticker interface {
+	// C returns the channel the ticks are delivered on
+	C() <-chan time.Time
+
+	// Stop turns the ticker off; no more ticks are delivered
+	Stop()
+
+	// Reset stops the ticker and restarts it with interval d
+	Reset(d time.Duration)
+}
+
+// Real is the wall clock.
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
+
+// Simulated is a clock that only moves when told to. Tickers and timers
+// created from it fire as Advance or Set moves time past their deadlines.
+// Like time.Ticker, a tick is dropped when the previous one is unread.
+type Simulated struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	changed chan struct{} // closed and replaced whenever waiters change
+}
+
+// waiter is a pending ticker or timer.
+type waiter struct {
+	clock    *Simulated
+	c        chan time.Time
+	deadline time.Time
+	interval time.Duration // zero for one-shot timers
+}
+
+// NewSimulated creates a simulated clock reading start.
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start, changed: make(chan struct{})}
+}
+
+// Now returns the simulated time.
+func (s *Simulated) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// Since returns the simulated time elapsed since t.
+func (s *Simulated) Since(t time.Time) time.Duration {
+	return s.Now().Sub(t)
+}
+
+// NewTicker returns a ticker that fires every d of simulated time.
+func (s *Simulated) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &waiter{clock: s, c: make(chan time.Time, 1), interval: d}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.deadline = s.now.Add(d)
+	s.add(w)
+	return w
+}
+
+// After returns a channel that receives the simulated time once d of it
+// has elapsed.
+func (s *Simulated) After(d time.Duration) <-chan time.Time {
+	w := &waiter{clock: s, c: make(chan time.Time, 1)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d <= 0 {
+		w.c <- s.now
+		return w.c
+	}
+	w.deadline = s.now.Add(d)
+	s.add(w)
+	return w.c
+}
+
+// Advance moves the clock forward by d, firing every ticker and timer that
+// falls due on the way in deadline order.
+func (s *Simulated) Advance(d time.Duration) {
+	s.Set(s.Now().Add(d))
+}
+
+// Set moves the clock to t, firing every ticker and timer due by then.
+// Setting a time before the current one only moves the clock back.
+func (s *Simulated) Set(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		sort.SliceStable(s.waiters, func(i, j int) bool {
+			return s.waiters[i].deadline.Before(s.waiters[j].deadline)
+		})
+		if len(s.waiters) == 0 || s.waiters[0].deadline.After(t) {
+			break
+		}
+
+		w := s.waiters[0]
+		s.now = w.deadline
+		select {
+		case w.c <- s.now:
+		default:
+		}
+		if w.interval > 0 {
+			w.deadline = w.deadline.Add(w.interval)
+		} else {
+			s.remove(w)
+		}
+	}
+	s.now = t
+}
+
+// BlockUntil waits until at least n tickers and timers are pending, so a
+// test can advance the clock knowing the goroutine under test is waiting.
+func (s *Simulated) BlockUntil(n int) {
+	for {
+		s.mu.Lock()
+		pending, changed := len(s.waiters), s.changed
+		s.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// add registers w; the caller holds s.mu.
+func (s *Simulated) add(w *waiter) {
+	s.waiters = append(s.waiters, w)
+	s.notify()
+}
+
+// remove unregisters w; the caller holds s.mu.
+func (s *Simulated) remove(w *waiter) {
+	for i, o := range s.waiters {
+		if o == w {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			s.notify()
+			return
+		}
+	}
+}
+
+// notify wakes BlockUntil callers; the caller holds s.mu.
+func (s *Simulated) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (w *waiter) C() <-chan time.Time { return w.c }
+
+func (w *waiter) Stop() {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	w.clock.remove(w)
+}
+
+func (w *waiter) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	s := w.clock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(w)
+	w.interval = d
+	w.deadline = s.now.Add(d)
+	s.add(w)
+}
@@ -0,0 +1,78 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSimulatedTicker(t *testing.T) {
+	c := NewSimulated(epoch)
+	ticker := c.NewTicker(time.Minute)
+
+	c.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired before its interval")
+	default:
+	}
+
+	c.Advance(time.Second)
+	if got := <-ticker.C(); !got.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("tick at %v, want %v", got, epoch.Add(time.Minute))
+	}
+
+	// Unread ticks are dropped, as with time.Ticker
+	c.Advance(3 * time.Minute)
+	if got := <-ticker.C(); !got.Equal(epoch.Add(2 * time.Minute)) {
+		t.Errorf("tick at %v, want the first missed tick", got)
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("expected later ticks to be dropped")
+	default:
+	}
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+	if got := c.Now(); !got.Equal(epoch.Add(time.Hour + 4*time.Minute)) {
+		t.Errorf("Now() = %v", got)
+	}
+}
+
+func TestSimulatedAfter(t *testing.T) {
+	c := NewSimulated(epoch)
+	late := c.After(2 * time.Second)
+	early := c.After(time.Second)
+
+	c.Advance(5 * time.Second)
+	if got := <-early; !got.Equal(epoch.Add(time.Second)) {
+		t.Errorf("early timer at %v", got)
+	}
+	if got := <-late; !got.Equal(epoch.Add(2 * time.Second)) {
+		t.Errorf("late timer at %v", got)
+	}
+	if got := c.Since(epoch); got != 5*time.Second {
+		t.Errorf("Since() = %v, want 5s", got)
+	}
+}
+
+func TestSimulatedBlockUntil(t *testing.T) {
+	c := NewSimulated(epoch)
+	done := make(chan time.Time)
+	go func() {
+		done <- <-c.After(time.Minute)
+	}()
+
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	if got := <-done; !got.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("timer at %v", got)
+	}
+}
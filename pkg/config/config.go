@@ -4,6 +4,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,6 +27,24 @@ type MQClientConfig struct {
 	PublishTimeout time.Duration `yaml:"publish_timeout" json:"publish_timeout"`
 }
 
+// RetryConfig holds the retry policy for an operation.
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts; 0 retries until shutdown
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"`
+
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration `yaml:"initial_backoff" json:"initial_backoff"`
+
+	// MaxBackoff caps the wait between attempts
+	MaxBackoff time.Duration `yaml:"max_backoff" json:"max_backoff"`
+
+	// Multiplier grows the backoff after each retry
+	Multiplier float64 `yaml:"multiplier" json:"multiplier"`
+
+	// Jitter randomizes each wait by up to this fraction
+	Jitter float64 `yaml:"jitter" json:"jitter"`
+}
+
 // MQQueueConfig holds configuration for the MQ server's internal queue.
 // Used by: MQ Server only
 type MQQueueConfig struct {
@@ -35,11 +54,91 @@ type MQQueueConfig struct {
 	// MaxRetries is the maximum number of retry attempts for failed messages
 	MaxRetries int `yaml:"max_retries" json:"max_retries"`
 
-	// RetryDelay is the delay between retry attempts
+	// RetryDelay is the delay before the first retry
 	RetryDelay time.Duration `yaml:"retry_delay" json:"retry_delay"`
 
+	// RetryMultiplier grows the delay before each further retry, up to
+	// RetryMaxDelay, and RetryJitter randomizes each delay by up to this
+	// fraction
+	RetryMultiplier float64       `yaml:"retry_multiplier" json:"retry_multiplier"`
+	RetryMaxDelay   time.Duration `yaml:"retry_max_delay" json:"retry_max_delay"`
+	RetryJitter     float64       `yaml:"retry_jitter" json:"retry_jitter"`
+
 	// PublishTimeout is the timeout for publishing messages
 	PublishTimeout time.Duration `yaml:"publish_timeout" json:"publish_timeout"`
+
+	// ProbeInterval is how often the server publishes a loopback probe to
+	// itself to measure delivery latency (0 disables probes)
+	ProbeInterval time.Duration `yaml:"probe_interval" json:"probe_interval"`
+
+	// DataDir persists the log and committed offsets to a write-ahead log
+	// in this directory, so they survive restarts (empty keeps them in
+	// memory only)
+	DataDir string `yaml:"data_dir" json:"data_dir"`
+
+	// FsyncPolicy is when appends are synced to disk: "always" before a
+	// publish is acknowledged, "interval" every FsyncInterval, or "never"
+	FsyncPolicy string `yaml:"fsync_policy" json:"fsync_policy"`
+
+	// FsyncInterval is how often appends are synced under "interval"
+	FsyncInterval time.Duration `yaml:"fsync_interval" json:"fsync_interval"`
+
+	// SegmentBytes is the size at which the log starts a new segment file
+	SegmentBytes int `yaml:"segment_bytes" json:"segment_bytes"`
+
+	// MemoryBytes bounds the log held in memory when it persists to
+	// DataDir: the payloads of the oldest segments are spilled, left on
+	// disk only, and read back for lagging subscribers (0 = unbounded)
+	MemoryBytes int `yaml:"memory_bytes" json:"memory_bytes"`
+
+	// RetentionMessages, RetentionBytes and RetentionAge bound the log:
+	// the oldest messages are trimmed once it holds more messages or bytes
+	// of payload and metadata, or once they are older (0 = unlimited)
+	RetentionMessages int           `yaml:"retention_messages" json:"retention_messages"`
+	RetentionBytes    int           `yaml:"retention_bytes" json:"retention_bytes"`
+	RetentionAge      time.Duration `yaml:"retention_age" json:"retention_age"`
+
+	// RetentionInterval is how often the log is trimmed
+	RetentionInterval time.Duration `yaml:"retention_interval" json:"retention_interval"`
+
+	// MaxMessages and MaxBytes bound the log on every publish (0 =
+	// unbounded), and OverflowPolicy says what a publish past them does:
+	// "reject" fails it, "block" waits up to PublishTimeout for retention
+	// to make room, and "drop_oldest" trims the oldest messages
+	MaxMessages    int    `yaml:"max_messages" json:"max_messages"`
+	MaxBytes       int    `yaml:"max_bytes" json:"max_bytes"`
+	OverflowPolicy string `yaml:"overflow_policy" json:"overflow_policy"`
+
+	// Partitions splits the log so collectors can share it; messages with
+	// the same partition key always land in the same partition
+	Partitions int `yaml:"partitions" json:"partitions"`
+
+	// DedupWindow is how long published idempotency keys are remembered,
+	// so retried publishes are dropped (0 = no deduplication); at most
+	// DedupMaxKeys are remembered (0 = unlimited)
+	DedupWindow  time.Duration `yaml:"dedup_window" json:"dedup_window"`
+	DedupMaxKeys int           `yaml:"dedup_max_keys" json:"dedup_max_keys"`
+
+	// MaxDeadLetters is how many messages that subscribers gave up on are
+	// kept for inspection, the oldest dropped first (0 = none)
+	MaxDeadLetters int `yaml:"max_dead_letters" json:"max_dead_letters"`
+
+	// SubscriberMaxPendingBytes bounds the unacked messages each
+	// subscriber holds (0 = unbounded), and SubscriberOverflowPolicy says
+	// what happens to one that would exceed it: "pause" waits for its
+	// acks, "skip_ahead" moves it to the end of the log, and "disconnect"
+	// closes its connection
+	SubscriberMaxPendingBytes int    `yaml:"subscriber_max_pending_bytes" json:"subscriber_max_pending_bytes"`
+	SubscriberOverflowPolicy  string `yaml:"subscriber_overflow_policy" json:"subscriber_overflow_policy"`
+
+	// AutoCommitInterval is how often subscriber positions are committed
+	// for consumers that never commit their own (0 = only explicit commits)
+	AutoCommitInterval time.Duration `yaml:"auto_commit_interval" json:"auto_commit_interval"`
+
+	// AckTimeout is how long a message delivered to a client may go unacked
+	// before it is delivered again; nacked messages are delivered again
+	// after a retry delay, up to MaxRetries times (0 = acks are not tracked)
+	AckTimeout time.Duration `yaml:"ack_timeout" json:"ack_timeout"`
 }
 
 // MQConfig is kept for backward compatibility - combines client and queue config.
@@ -51,6 +150,21 @@ type MQConfig struct {
 	MaxRetries     int           `yaml:"max_retries" json:"max_retries"`
 	RetryDelay     time.Duration `yaml:"retry_delay" json:"retry_delay"`
 	PublishTimeout time.Duration `yaml:"publish_timeout" json:"publish_timeout"`
+
+	// Reconnect is the retry policy for re-establishing a dropped connection
+	Reconnect RetryConfig `yaml:"reconnect" json:"reconnect"`
+
+	// HeartbeatInterval is how often the component announces itself to the
+	// MQ server for /api/v1/pipeline/topology; 0 disables heartbeats
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval" json:"heartbeat_interval"`
+
+	// Failover lists the host:port addresses of standby MQ servers to
+	// connect to when the one at Host and Port cannot be reached
+	Failover []string `yaml:"failover" json:"failover"`
+
+	// WireFormat is the framing asked of the MQ server: "json", or
+	// "binary", which servers that do not know it answer in JSON
+	WireFormat string `yaml:"wire_format" json:"wire_format"`
 }
 
 // StreamerConfig holds configuration for the telemetry streamer.
@@ -58,10 +172,40 @@ type StreamerConfig struct {
 	// InstanceID uniquely identifies this streamer instance
 	InstanceID string `yaml:"instance_id" json:"instance_id"`
 
-	// CSVPath is the path to the telemetry CSV file
+	// Source names the registered source metrics are read from: "file"
+	// reads CSVPath, and other names are site-specific sources compiled in
+	// or loaded from SourcePlugins
+	Source string `yaml:"source" json:"source"`
+
+	// SourceOptions holds the settings of a site-specific source
+	SourceOptions map[string]string `yaml:"source_options" json:"source_options"`
+
+	// SourcePlugins are Go plugins loaded at startup to register sources
+	SourcePlugins []string `yaml:"source_plugins" json:"source_plugins"`
+
+	// SourceCheckpoint resumes the first pass over the source after this
+	// checkpoint, such as one logged where acknowledged data ends
+	SourceCheckpoint string `yaml:"source_checkpoint" json:"source_checkpoint"`
+
+	// CSVPath is the path to the telemetry input file
 	CSVPath string `yaml:"csv_path" json:"csv_path"`
 
-	// BatchSize is the number of metrics to send in each batch
+	// InputFormat is the format of CSVPath: "csv", "prometheus" (text
+	// exposition format, e.g. dcgm-exporter scrapes), "auto" to detect it,
+	// or "none" to read no file and only ingest over UDP
+	InputFormat string `yaml:"input_format" json:"input_format"`
+
+	// CSVMappingsFile is a JSON file of named CSV column layouts, each used
+	// for the files whose header fingerprint it lists
+	CSVMappingsFile string `yaml:"csv_mappings_file" json:"csv_mappings_file"`
+
+	// CSVColumns limits CSV parsing to these columns, leaving the metric
+	// fields of the others empty; metric_name, uuid and value are always
+	// parsed (empty = all)
+	CSVColumns []string `yaml:"csv_columns" json:"csv_columns"`
+
+	// BatchSize is the most metrics read from a site-specific source each
+	// CollectInterval; the file source is replayed one metric at a time
 	BatchSize int `yaml:"batch_size" json:"batch_size"`
 
 	// CollectInterval is how often to read metrics from CSV into buffer
@@ -78,6 +222,53 @@ type StreamerConfig struct {
 
 	// HostFilter optionally filters which hosts this streamer handles
 	HostFilter []string `yaml:"host_filter" json:"host_filter"`
+
+	// PublishRetry is the retry policy for publishing a batch
+	PublishRetry RetryConfig `yaml:"publish_retry" json:"publish_retry"`
+
+	// UDP accepts telemetry datagrams alongside the input file
+	UDP UDPIngestConfig `yaml:"udp" json:"udp"`
+
+	// HealthHost is the host of the health endpoint
+	HealthHost string `yaml:"health_host" json:"health_host"`
+
+	// HealthPort is the port of the health endpoint, which reports how far
+	// the MQ has acknowledged published data; 0 disables it
+	HealthPort int `yaml:"health_port" json:"health_port"`
+
+	// State keeps the source checkpoint of acknowledged data, so a restart
+	// resumes where the MQ last acknowledged
+	State StateConfig `yaml:"state" json:"state"`
+
+	// Encryption encrypts published batch payloads
+	Encryption EncryptionConfig `yaml:"encryption" json:"encryption"`
+
+	// Signing signs published batches as this instance
+	Signing SigningConfig `yaml:"signing" json:"signing"`
+}
+
+// UDPIngestConfig holds configuration for the streamer's UDP listener.
+type UDPIngestConfig struct {
+	// Host is the listen host
+	Host string `yaml:"host" json:"host"`
+
+	// Port is the listen port; 0 disables the listener
+	Port int `yaml:"port" json:"port"`
+
+	// MaxDatagramSize is the largest datagram accepted; larger ones are dropped
+	MaxDatagramSize int `yaml:"max_datagram_size" json:"max_datagram_size"`
+
+	// ReadBuffer is the socket receive buffer size in bytes (0 = OS default)
+	ReadBuffer int `yaml:"read_buffer" json:"read_buffer"`
+
+	// MaxBuffered is how many received metrics may wait for publishing
+	// before new datagrams are dropped
+	MaxBuffered int `yaml:"max_buffered" json:"max_buffered"`
+}
+
+// Enabled reports whether the UDP listener is configured.
+func (c UDPIngestConfig) Enabled() bool {
+	return c.Port != 0
 }
 
 // CollectorConfig holds configuration for the telemetry collector.
@@ -85,9 +276,16 @@ type CollectorConfig struct {
 	// InstanceID uniquely identifies this collector instance
 	InstanceID string `yaml:"instance_id" json:"instance_id"`
 
+	// Source is where batches are consumed from: "mq" (the pipeline's MQ)
+	// or "kafka"
+	Source string `yaml:"source" json:"source"`
+
 	// MQ is the message queue configuration
 	MQ MQConfig `yaml:"mq" json:"mq"`
 
+	// Kafka is the Kafka topic configuration used when Source is "kafka"
+	Kafka KafkaConfig `yaml:"kafka" json:"kafka"`
+
 	// InfluxDB configuration
 	InfluxURL    string `yaml:"influx_url" json:"influx_url"`
 	InfluxToken  string `yaml:"influx_token" json:"influx_token"`
@@ -97,8 +295,283 @@ type CollectorConfig struct {
 	// RetentionPeriod is how long to keep telemetry data
 	RetentionPeriod time.Duration `yaml:"retention_period" json:"retention_period"`
 
+	// ReadOnly starts the collector without consuming, for storage upgrades;
+	// it can be switched at runtime on the health port
+	ReadOnly bool `yaml:"read_only" json:"read_only"`
+
+	// ExpireTelemetry makes the collector delete telemetry older than
+	// RetentionPeriod itself, sparing data under retention holds, instead
+	// of leaving expiry to the InfluxDB bucket's retention
+	ExpireTelemetry bool `yaml:"expire_telemetry" json:"expire_telemetry"`
+
 	// FlushInterval is how often to flush data to storage
 	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+
+	// StartOffset selects where consumption begins: "latest", "earliest",
+	// "committed" (resume from the last committed offset), or a numeric offset
+	StartOffset string `yaml:"start_offset" json:"start_offset"`
+
+	// CommitInterval is how often the MQ offset up to which every batch was
+	// stored is committed
+	CommitInterval time.Duration `yaml:"commit_interval" json:"commit_interval"`
+
+	// IngestStatsInterval is how often the per-streamer ingest statistics
+	// are written to storage; 0 disables them
+	IngestStatsInterval time.Duration `yaml:"ingest_stats_interval" json:"ingest_stats_interval"`
+
+	// SubscribeFilter is an optional server-side MQ filter expression
+	// (e.g., "hostname=mtv5-*;metric_name=DCGM_FI_DEV_GPU_TEMP")
+	SubscribeFilter string `yaml:"subscribe_filter" json:"subscribe_filter"`
+
+	// SubscribePartitions limits consumption to these MQ partitions, so
+	// several collectors can split a partitioned queue (empty = all)
+	SubscribePartitions []int `yaml:"subscribe_partitions" json:"subscribe_partitions"`
+
+	// Group joins an MQ consumer group, whose members split the log's
+	// partitions instead of each receiving every message
+	Group string `yaml:"group" json:"group"`
+
+	// Workers bounds the MQ batches handled at once (0 = a handler for
+	// each batch as it arrives)
+	Workers int `yaml:"workers" json:"workers"`
+
+	// StoreRetry is the retry policy for writing a batch to storage
+	StoreRetry RetryConfig `yaml:"store_retry" json:"store_retry"`
+
+	// Webhooks delivers GPU discovery/silence and lag events
+	Webhooks WebhookConfig `yaml:"webhooks" json:"webhooks"`
+
+	// Forward lists the external systems stored metrics are also pushed to
+	Forward []ForwardSinkConfig `yaml:"forward" json:"forward"`
+
+	// Cardinality bounds the InfluxDB series the collector creates
+	Cardinality CardinalityConfig `yaml:"cardinality" json:"cardinality"`
+
+	// LateData decides what happens to metrics that arrive long after
+	// their timestamp
+	LateData LateDataConfig `yaml:"late_data" json:"late_data"`
+
+	// ClockSkew decides what happens to batches whose producer clock
+	// disagrees with the MQ server's
+	ClockSkew ClockSkewConfig `yaml:"clock_skew" json:"clock_skew"`
+
+	// HostAggregates lists the host-level aggregates computed from each
+	// batch and stored alongside the GPU metrics
+	HostAggregates []HostAggregate `yaml:"host_aggregates" json:"host_aggregates"`
+
+	// HealthHost is the host of the health and version endpoints
+	HealthHost string `yaml:"health_host" json:"health_host"`
+
+	// HealthPort is the port of the health and version endpoints (0 disables them)
+	HealthPort int `yaml:"health_port" json:"health_port"`
+
+	// Encryption decrypts the batch payloads consumed from the MQ
+	Encryption EncryptionConfig `yaml:"encryption" json:"encryption"`
+
+	// Verification checks the signatures of the batches consumed from the MQ
+	Verification VerificationConfig `yaml:"verification" json:"verification"`
+}
+
+// CardinalityConfig holds the collector's series cardinality budget.
+type CardinalityConfig struct {
+	// Budget is the most distinct series written per window (0 = unlimited)
+	Budget int `yaml:"budget" json:"budget"`
+
+	// Window is how long series are counted before the count restarts
+	Window time.Duration `yaml:"window" json:"window"`
+
+	// Drop discards metrics that would add a series past the budget,
+	// rather than only warning
+	Drop bool `yaml:"drop" json:"drop"`
+}
+
+// Late-data policies.
+const (
+	// LatePolicyAccept stores late metrics like any other, only counting them
+	LatePolicyAccept = "accept"
+
+	// LatePolicyTag stores late metrics with a late=true field
+	LatePolicyTag = "tag"
+
+	// LatePolicyReroute stores late metrics in a separate bucket
+	LatePolicyReroute = "reroute"
+
+	// LatePolicyDrop discards late metrics
+	LatePolicyDrop = "drop"
+)
+
+// LateDataConfig holds the collector's handling of late-arriving metrics.
+type LateDataConfig struct {
+	// Threshold is how far behind the collector's clock a metric's
+	// timestamp may be before the metric counts as late
+	Threshold time.Duration `yaml:"threshold" json:"threshold"`
+
+	// Policy is what happens to late metrics: "accept", "tag", "reroute" or "drop"
+	Policy string `yaml:"policy" json:"policy"`
+
+	// Bucket receives late metrics under the reroute policy
+	Bucket string `yaml:"bucket" json:"bucket"`
+}
+
+// Clock-skew policies.
+const (
+	// SkewPolicyAccept stores skewed batches as they are, only counting them
+	SkewPolicyAccept = "accept"
+
+	// SkewPolicyCorrect shifts the metrics of skewed batches onto the MQ
+	// server's clock
+	SkewPolicyCorrect = "correct"
+
+	// SkewPolicyFlag stores the metrics of skewed batches with a
+	// skewed=true field
+	SkewPolicyFlag = "flag"
+
+	// SkewPolicyReject discards skewed batches
+	SkewPolicyReject = "reject"
+)
+
+// ClockSkewConfig holds the collector's handling of producer clock skew,
+// measured as how far a batch's collection time is from when the MQ
+// server received it.
+type ClockSkewConfig struct {
+	// Threshold is how far apart the clocks may be before a batch counts
+	// as skewed
+	Threshold time.Duration `yaml:"threshold" json:"threshold"`
+
+	// Policy is what happens to skewed batches: "accept", "correct",
+	// "flag" or "reject"
+	Policy string `yaml:"policy" json:"policy"`
+}
+
+// Host aggregate functions.
+const (
+	AggregateSum  = "sum"
+	AggregateMean = "mean"
+	AggregateMin  = "min"
+	AggregateMax  = "max"
+)
+
+// HostAggregate combines one metric across the GPUs of each host, such as
+// the total power a node draws.
+type HostAggregate struct {
+	// Func is "sum", "mean", "min" or "max"
+	Func string `yaml:"func" json:"func"`
+
+	// Metric is the GPU metric aggregated
+	Metric string `yaml:"metric" json:"metric"`
+}
+
+// ForwardSinkConfig holds configuration for one external system that
+// stored metrics are forwarded to.
+type ForwardSinkConfig struct {
+	// Name identifies the sink in logs and names its environment variables
+	Name string `yaml:"name" json:"name"`
+
+	// Type is the sink protocol: "prometheus" (remote_write), "datadog" or "otlp" (OTLP/HTTP)
+	Type string `yaml:"type" json:"type"`
+
+	// URL is the endpoint metrics are pushed to
+	URL string `yaml:"url" json:"url"`
+
+	// Filter selects the metrics to forward, in the COLLECTOR_FILTER syntax
+	// evaluated against each metric (empty forwards everything)
+	Filter string `yaml:"filter" json:"filter"`
+
+	// APIKey authenticates with Datadog
+	APIKey string `yaml:"api_key" json:"-"`
+
+	// Headers are extra request headers as Name=value, e.g. for authentication
+	Headers []string `yaml:"headers" json:"-"`
+
+	// BatchSize is the most metrics sent in one request
+	BatchSize int `yaml:"batch_size" json:"batch_size"`
+
+	// FlushInterval is how long metrics may wait for a batch to fill
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+
+	// QueueSize is how many metrics may wait to be sent before new ones are dropped
+	QueueSize int `yaml:"queue_size" json:"queue_size"`
+
+	// Timeout bounds each request
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// Retry is the retry policy for a failed request
+	Retry RetryConfig `yaml:"retry" json:"retry"`
+}
+
+// KafkaConfig holds configuration for consuming telemetry from Kafka.
+type KafkaConfig struct {
+	// Brokers are the bootstrap broker addresses (host:port)
+	Brokers []string `yaml:"brokers" json:"brokers"`
+
+	// Topic is the topic to consume
+	Topic string `yaml:"topic" json:"topic"`
+
+	// GroupID names the consumer group the collector joins; collectors
+	// sharing it split the partitions
+	GroupID string `yaml:"group_id" json:"group_id"`
+
+	// Format is the message format: "json" (pipeline metric batches),
+	// "protobuf" (OTLP metrics) or "line" (InfluxDB line protocol)
+	Format string `yaml:"format" json:"format"`
+
+	// StartOffset is where partitions without a committed offset begin:
+	// "earliest" or "latest"
+	StartOffset string `yaml:"start_offset" json:"start_offset"`
+
+	// MaxWait is how long a fetch waits for new records
+	MaxWait time.Duration `yaml:"max_wait" json:"max_wait"`
+
+	// FetchMaxBytes bounds the records returned per fetch
+	FetchMaxBytes int `yaml:"fetch_max_bytes" json:"fetch_max_bytes"`
+
+	// CommitInterval is how often consumed offsets are committed
+	CommitInterval time.Duration `yaml:"commit_interval" json:"commit_interval"`
+
+	// TLS connects to the brokers over TLS, trusting the system roots or,
+	// when set, the PEM certificates in TLSCAFile
+	TLS       bool   `yaml:"tls" json:"tls"`
+	TLSCAFile string `yaml:"tls_ca_file" json:"tls_ca_file"`
+
+	// SASLMechanism authenticates to the brokers as SASLUsername:
+	// "plain", "scram-sha-256" or "scram-sha-512"; empty sends no
+	// credentials
+	SASLMechanism string `yaml:"sasl_mechanism" json:"sasl_mechanism"`
+	SASLUsername  string `yaml:"sasl_username" json:"sasl_username"`
+	SASLPassword  string `yaml:"sasl_password" json:"-"`
+
+	// Retry paces retries after broker errors
+	Retry RetryConfig `yaml:"retry" json:"retry"`
+}
+
+// WebhookConfig holds configuration for pipeline event webhooks.
+type WebhookConfig struct {
+	// URLs receive every subscribed event; none disables webhooks
+	URLs []string `yaml:"urls" json:"urls"`
+
+	// Events limits which event types are sent (empty means all)
+	Events []string `yaml:"events" json:"events"`
+
+	// Secret signs each payload with HMAC-SHA256 when set
+	Secret string `yaml:"secret" json:"-"`
+
+	// Timeout bounds each delivery attempt
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// QueueSize is how many events may wait for delivery before new ones are dropped
+	QueueSize int `yaml:"queue_size" json:"queue_size"`
+
+	// LogSize is how many recent deliveries are kept for inspection
+	LogSize int `yaml:"log_size" json:"log_size"`
+
+	// Retry is the retry policy for a failed delivery
+	Retry RetryConfig `yaml:"retry" json:"retry"`
+
+	// SilenceAfter is how long a GPU may go unreported before it is declared silent
+	SilenceAfter time.Duration `yaml:"silence_after" json:"silence_after"`
+
+	// LagThreshold is the consumer lag, in messages, that raises a lag event
+	LagThreshold int64 `yaml:"lag_threshold" json:"lag_threshold"`
 }
 
 // APIConfig holds configuration for the REST API gateway.
@@ -120,6 +593,395 @@ type APIConfig struct {
 
 	// MaxLimit is the maximum pagination limit
 	MaxLimit int `yaml:"max_limit" json:"max_limit"`
+
+	// CacheSource feeds the latest-values cache: "storage" (periodic refresh),
+	// "mq" (live MQ subscription), or "off"
+	CacheSource string `yaml:"cache_source" json:"cache_source"`
+
+	// CacheRefreshInterval is how often the cache is refreshed from storage
+	CacheRefreshInterval time.Duration `yaml:"cache_refresh_interval" json:"cache_refresh_interval"`
+
+	// CacheWindow bounds how far back storage refreshes look for latest values
+	CacheWindow time.Duration `yaml:"cache_window" json:"cache_window"`
+
+	// MQ is the message queue configuration used when CacheSource is "mq"
+	// and for scheduler leader election
+	MQ MQConfig `yaml:"mq" json:"mq"`
+
+	// Scheduler runs saved queries and delivers their reports
+	Scheduler SchedulerConfig `yaml:"scheduler" json:"scheduler"`
+
+	// Webhooks delivers export-completed events for scheduled runs
+	Webhooks WebhookConfig `yaml:"webhooks" json:"webhooks"`
+
+	// Alerts evaluates alert rules against the latest-values cache and
+	// notifies email, Slack and PagerDuty
+	Alerts AlertConfig `yaml:"alerts" json:"alerts"`
+
+	// Baselines learns per-model normal ranges that alert rules can use as thresholds
+	Baselines BaselineConfig `yaml:"baselines" json:"baselines"`
+
+	// Status keeps each GPU's current status materialized in storage for
+	// the fleet overview
+	Status StatusConfig `yaml:"status" json:"status"`
+
+	// Summary keeps the fleet summary served at /api/v1/stats in memory
+	Summary SummaryConfig `yaml:"summary" json:"summary"`
+
+	// SLO evaluates the ingest-latency objective from batch lineage and
+	// raises burn-rate alerts
+	SLO SLOConfig `yaml:"slo" json:"slo"`
+
+	// Predict scores each GPU's risk of failing soon and stores the scores
+	Predict PredictConfig `yaml:"predict" json:"predict"`
+
+	// AdminToken is the bearer token granting the admin role; empty disables admin endpoints
+	AdminToken string `yaml:"admin_token" json:"-"`
+
+	// BundleKey signs exported configuration bundles and verifies imported
+	// ones; deployments exchanging bundles share it. Empty disables bundles
+	BundleKey string `yaml:"bundle_key" json:"-"`
+
+	// LogLevel is the initial log level (debug or info); admins can change it at runtime
+	LogLevel string `yaml:"log_level" json:"log_level"`
+
+	// Dashboard serves the built-in web dashboard at /
+	Dashboard bool `yaml:"dashboard" json:"dashboard"`
+
+	// Maintenance starts the API in maintenance mode, refusing writes with
+	// MaintenanceMessage; admins can switch it at runtime
+	Maintenance        bool   `yaml:"maintenance" json:"maintenance"`
+	MaintenanceMessage string `yaml:"maintenance_message" json:"maintenance_message"`
+
+	// Exports runs large telemetry exports as background jobs with downloadable artifacts
+	Exports ExportConfig `yaml:"exports" json:"exports"`
+
+	// TenantTokens maps tenant names to the bearer tokens that identify them
+	TenantTokens map[string]string `yaml:"tenant_tokens" json:"-"`
+
+	// Usage meters API consumption per tenant and enforces monthly quotas
+	Usage UsageConfig `yaml:"usage" json:"usage"`
+
+	// DefaultEnvironment names the data set configured by INFLUXDB_*,
+	// served to requests that select no environment
+	DefaultEnvironment string `yaml:"default_environment" json:"default_environment"`
+
+	// Environments are further named data sets requests can select
+	Environments []EnvironmentConfig `yaml:"environments" json:"environments"`
+
+	// State keeps alert state across restarts and, with the Redis backend,
+	// holds the leader leases instead of the MQ
+	State StateConfig `yaml:"state" json:"state"`
+
+	// Encryption decrypts the batch payloads the MQ cache and re-ingestion read
+	Encryption EncryptionConfig `yaml:"encryption" json:"encryption"`
+}
+
+// EnvironmentConfig holds one named data set the API serves besides the
+// default, such as dev or stage, read from its own InfluxDB bucket.
+type EnvironmentConfig struct {
+	// Name selects the environment in the X-Environment header or the
+	// /env/<name> path prefix
+	Name string `yaml:"name" json:"name"`
+
+	// URL, Org, Bucket and Token locate the environment's storage; URL,
+	// Org and Token default to those of the default environment
+	URL    string `yaml:"url" json:"url"`
+	Org    string `yaml:"org" json:"org"`
+	Bucket string `yaml:"bucket" json:"bucket"`
+	Token  string `yaml:"token" json:"-"`
+
+	// Tenants lists the tenants allowed to read the environment, by their
+	// names in TenantTokens; admins always may. Empty allows every caller
+	Tenants []string `yaml:"tenants" json:"tenants"`
+}
+
+// UsageConfig holds configuration for API usage metering.
+type UsageConfig struct {
+	// File persists usage counters across restarts; empty keeps them in memory
+	File string `yaml:"file" json:"file"`
+
+	// FlushInterval is how often counters are written to File
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+
+	// QuotaRequests caps each tenant's requests per month; 0 is unlimited
+	QuotaRequests int64 `yaml:"quota_requests" json:"quota_requests"`
+
+	// QuotaRows caps the data points returned to each tenant per month; 0 is unlimited
+	QuotaRows int64 `yaml:"quota_rows" json:"quota_rows"`
+
+	// QuotaExportBytes caps the bytes each tenant exports per month; 0 is unlimited
+	QuotaExportBytes int64 `yaml:"quota_export_bytes" json:"quota_export_bytes"`
+}
+
+// ExportConfig holds configuration for background export jobs.
+type ExportConfig struct {
+	// Dir holds job records and artifacts; empty disables export jobs
+	Dir string `yaml:"dir" json:"dir"`
+
+	// TTL is how long a completed artifact can be downloaded
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+
+	// QuotaBytes caps the disk used by artifacts; the oldest are deleted
+	// first to make room
+	QuotaBytes int64 `yaml:"quota_bytes" json:"quota_bytes"`
+
+	// MaxRows caps the data points in one artifact
+	MaxRows int `yaml:"max_rows" json:"max_rows"`
+
+	// QueueSize is how many jobs can wait to run
+	QueueSize int `yaml:"queue_size" json:"queue_size"`
+
+	// JanitorInterval is how often expired artifacts are deleted
+	JanitorInterval time.Duration `yaml:"janitor_interval" json:"janitor_interval"`
+}
+
+// SchedulerConfig holds configuration for the API's saved-query scheduler.
+type SchedulerConfig struct {
+	// Enabled starts the scheduler in this API instance
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Tick is how often the scheduler checks for due saved queries
+	Tick time.Duration `yaml:"tick" json:"tick"`
+
+	// LeaderElection elects one API replica to run schedules via an MQ lease;
+	// disable only for single-replica deployments
+	LeaderElection bool `yaml:"leader_election" json:"leader_election"`
+
+	// LeaseTTL is how long a leader keeps the lease without renewing it
+	LeaseTTL time.Duration `yaml:"lease_ttl" json:"lease_ttl"`
+
+	// DeliveryTimeout bounds each report delivery
+	DeliveryTimeout time.Duration `yaml:"delivery_timeout" json:"delivery_timeout"`
+
+	// SMTP settings for email delivery
+	SMTPHost     string `yaml:"smtp_host" json:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port" json:"smtp_port"`
+	SMTPFrom     string `yaml:"smtp_from" json:"smtp_from"`
+	SMTPUsername string `yaml:"smtp_username" json:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" json:"-"`
+
+	// S3 settings for object delivery; Endpoint overrides AWS for S3-compatible stores
+	S3Endpoint  string `yaml:"s3_endpoint" json:"s3_endpoint"`
+	S3Region    string `yaml:"s3_region" json:"s3_region"`
+	S3AccessKey string `yaml:"s3_access_key" json:"-"`
+	S3SecretKey string `yaml:"s3_secret_key" json:"-"`
+
+	// Delivery allowlists: the webhook hosts, recipient email domains and S3
+	// buckets saved queries may deliver to. Empty allows none; "*" allows any
+	WebhookHosts []string `yaml:"webhook_hosts" json:"webhook_hosts"`
+	EmailDomains []string `yaml:"email_domains" json:"email_domains"`
+	S3Buckets    []string `yaml:"s3_buckets" json:"s3_buckets"`
+}
+
+// AlertConfig holds configuration for alert evaluation and notification.
+// Email notifiers send through the scheduler's SMTP settings.
+type AlertConfig struct {
+	// Enabled starts the alert evaluator in this API instance
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// RulesFile is a JSON file of alert rules
+	RulesFile string `yaml:"rules_file" json:"rules_file"`
+
+	// EvalInterval is how often rules are evaluated
+	EvalInterval time.Duration `yaml:"eval_interval" json:"eval_interval"`
+
+	// RepeatInterval is how often a still-firing alert is notified again
+	RepeatInterval time.Duration `yaml:"repeat_interval" json:"repeat_interval"`
+
+	// EscalateAfter is how long an alert may fire before it is also sent to
+	// EscalateTo; zero disables escalation
+	EscalateAfter time.Duration `yaml:"escalate_after" json:"escalate_after"`
+
+	// EscalateTo names the notifiers that receive escalated alerts
+	EscalateTo []string `yaml:"escalate_to" json:"escalate_to"`
+
+	// LeaderElection elects one API replica to evaluate rules via an MQ lease;
+	// disable only for single-replica deployments
+	LeaderElection bool `yaml:"leader_election" json:"leader_election"`
+
+	// LeaseTTL is how long a leader keeps the lease without renewing it
+	LeaseTTL time.Duration `yaml:"lease_ttl" json:"lease_ttl"`
+
+	// Timeout bounds each notification attempt
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// QueueSize is how many notifications may wait for delivery before new ones are dropped
+	QueueSize int `yaml:"queue_size" json:"queue_size"`
+
+	// Retry is the retry policy for a failed notification
+	Retry RetryConfig `yaml:"retry" json:"retry"`
+
+	// Notifiers are the configured notification channels
+	Notifiers []AlertNotifierConfig `yaml:"notifiers" json:"notifiers"`
+
+	// MaintenanceKinds are the annotation kinds whose windows suppress
+	// notifications for the GPUs they cover; empty disables suppression
+	MaintenanceKinds []string `yaml:"maintenance_kinds" json:"maintenance_kinds"`
+}
+
+// BaselineConfig holds configuration for learning per-model metric baselines.
+type BaselineConfig struct {
+	// Enabled learns baselines in this API instance
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Metrics are the metrics profiled for every GPU model
+	Metrics []string `yaml:"metrics" json:"metrics"`
+
+	// Window is how much history baselines are learned from
+	Window time.Duration `yaml:"window" json:"window"`
+
+	// Refresh is how often baselines are recomputed
+	Refresh time.Duration `yaml:"refresh" json:"refresh"`
+
+	// MinSamples is how many samples a model needs before its baseline is used
+	MinSamples int `yaml:"min_samples" json:"min_samples"`
+}
+
+// SummaryConfig holds configuration for summarizing stored telemetry in the
+// background.
+type SummaryConfig struct {
+	// Enabled summarizes stored telemetry in this API instance
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Refresh is how often the summary is recomputed
+	Refresh time.Duration `yaml:"refresh" json:"refresh"`
+
+	// Days is how many of the latest days' volumes the summary lists
+	Days int `yaml:"days" json:"days"`
+}
+
+// PredictConfig holds configuration for scoring each GPU's risk of failure
+// from a window of its recent telemetry.
+type PredictConfig struct {
+	// Enabled scores GPUs from this API instance
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Interval is how often every GPU is scored
+	Interval time.Duration `yaml:"interval" json:"interval"`
+
+	// Window is how much recent telemetry a score is computed from, in
+	// Samples evenly spaced samples per metric
+	Window  time.Duration `yaml:"window" json:"window"`
+	Samples int           `yaml:"samples" json:"samples"`
+
+	// Model names the scoring model: rules, logistic, or one registered
+	// by a plugin
+	Model string `yaml:"model" json:"model"`
+
+	// ModelFile is a JSON file of the model's parameters; empty uses the
+	// model's defaults
+	ModelFile string `yaml:"model_file" json:"model_file"`
+
+	// LeaderElection elects one API replica to score GPUs via an MQ lease
+	LeaderElection bool `yaml:"leader_election" json:"leader_election"`
+
+	// LeaseTTL is how long a leader keeps the lease without renewing it
+	LeaseTTL time.Duration `yaml:"lease_ttl" json:"lease_ttl"`
+}
+
+// StatusConfig holds configuration for materializing each GPU's current status.
+type StatusConfig struct {
+	// Enabled materializes statuses from this API instance
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Interval is how often statuses are recomputed and written
+	Interval time.Duration `yaml:"interval" json:"interval"`
+
+	// Metrics are the key metrics whose latest values a status carries
+	Metrics []string `yaml:"metrics" json:"metrics"`
+
+	// StaleAfter is how long a GPU may go without telemetry before it is stale
+	StaleAfter time.Duration `yaml:"stale_after" json:"stale_after"`
+
+	// LeaderElection elects one API replica to write statuses via an MQ
+	// lease when alerting is off; with alerting on, the replica evaluating
+	// rules writes them, since only it knows the alert state
+	LeaderElection bool `yaml:"leader_election" json:"leader_election"`
+
+	// LeaseTTL is how long a leader keeps the lease without renewing it
+	LeaseTTL time.Duration `yaml:"lease_ttl" json:"lease_ttl"`
+}
+
+// SLOConfig holds the ingest-latency service level objective: the share of
+// metrics stored within Latency of collection over a sliding Window.
+type SLOConfig struct {
+	// Enabled evaluates the objective in this API instance
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Objective is the share of metrics that must be stored in time, e.g. 0.95
+	Objective float64 `yaml:"objective" json:"objective"`
+
+	// Latency is how long after collection a metric may be stored and count
+	// as in time
+	Latency time.Duration `yaml:"latency" json:"latency"`
+
+	// Window is the sliding window compliance is computed over
+	Window time.Duration `yaml:"window" json:"window"`
+
+	// Interval is how often the objective is evaluated
+	Interval time.Duration `yaml:"interval" json:"interval"`
+
+	// BurnRate is how many times faster than sustainable the error budget
+	// must be spent, over both ShortWindow and LongWindow, to alert
+	BurnRate    float64       `yaml:"burn_rate" json:"burn_rate"`
+	ShortWindow time.Duration `yaml:"short_window" json:"short_window"`
+	LongWindow  time.Duration `yaml:"long_window" json:"long_window"`
+
+	// LeaderElection elects one API replica to raise burn-rate alerts via
+	// an MQ lease; every replica still evaluates and serves the objective
+	LeaderElection bool `yaml:"leader_election" json:"leader_election"`
+
+	// LeaseTTL is how long a leader keeps the lease without renewing it
+	LeaseTTL time.Duration `yaml:"lease_ttl" json:"lease_ttl"`
+}
+
+// AlertNotifierConfig configures one alert notification channel.
+type AlertNotifierConfig struct {
+	// Name identifies the notifier in rules and escalation
+	Name string `yaml:"name" json:"name"`
+
+	// Type is email, slack or pagerduty
+	Type string `yaml:"type" json:"type"`
+
+	// URL is the Slack incoming webhook, or overrides the PagerDuty Events API endpoint
+	URL string `yaml:"url" json:"-"`
+
+	// RoutingKey is the PagerDuty integration key
+	RoutingKey string `yaml:"routing_key" json:"-"`
+
+	// To lists email recipients
+	To []string `yaml:"to" json:"to"`
+
+	// Subject is a text/template for the email subject (optional)
+	Subject string `yaml:"subject" json:"subject"`
+
+	// Template is a text/template for the message text (optional)
+	Template string `yaml:"template" json:"template"`
+}
+
+// OTLPReceiverConfig holds configuration for the OTLP metrics receiver.
+type OTLPReceiverConfig struct {
+	// InstanceID identifies this receiver as the source of its batches
+	InstanceID string `yaml:"instance_id" json:"instance_id"`
+
+	// Host is the listen host for both OTLP transports
+	Host string `yaml:"host" json:"host"`
+
+	// GRPCPort is the OTLP/gRPC port (OpenTelemetry default 4317)
+	GRPCPort int `yaml:"grpc_port" json:"grpc_port"`
+
+	// HTTPPort is the OTLP/HTTP port (OpenTelemetry default 4318)
+	HTTPPort int `yaml:"http_port" json:"http_port"`
+
+	// MaxRequestBytes bounds an export request after decompression
+	MaxRequestBytes int `yaml:"max_request_bytes" json:"max_request_bytes"`
+
+	// MQ is the message queue configuration
+	MQ MQConfig `yaml:"mq" json:"mq"`
+
+	// PublishRetry is the retry policy for publishing a batch
+	PublishRetry RetryConfig `yaml:"publish_retry" json:"publish_retry"`
 }
 
 // MQServerConfig holds configuration for the message queue server.
@@ -138,6 +1000,188 @@ type MQServerConfig struct {
 
 	// Queue is the internal queue configuration (no host/port needed)
 	Queue MQQueueConfig `yaml:"queue" json:"queue"`
+
+	// PublishLimits throttles publishers so none can starve the queue
+	PublishLimits MQPublishLimitsConfig `yaml:"publish_limits" json:"publish_limits"`
+
+	// Quotas bound what each client connection may hold, so one faulty
+	// client cannot monopolize the server
+	Quotas MQClientQuotasConfig `yaml:"quotas" json:"quotas"`
+
+	// Debug validates frames and published batches against the published
+	// JSON Schemas and rejects those that do not match
+	Debug bool `yaml:"debug" json:"debug"`
+
+	// AdminToken is the bearer token for the /admin endpoints; empty refuses them
+	AdminToken string `yaml:"admin_token" json:"-"`
+
+	// LogLevel is the initial log level (debug or info); admins can change it at runtime
+	LogLevel string `yaml:"log_level" json:"log_level"`
+
+	// ReplicaOf is the host:port of the primary MQ server this one stands
+	// by for, replicating its log until promoted; empty runs a primary
+	ReplicaOf string `yaml:"replica_of" json:"replica_of"`
+
+	// PromoteAfter promotes a standby once its primary has been unreachable
+	// this long; 0 waits for POST /admin/promote
+	PromoteAfter time.Duration `yaml:"promote_after" json:"promote_after"`
+
+	// WebSocketOrigins lists the origins, e.g. https://dash.example.com,
+	// whose pages may connect at /ws; "*" allows any. Empty allows only
+	// pages served from the MQ server's own host.
+	WebSocketOrigins []string `yaml:"websocket_origins" json:"websocket_origins"`
+}
+
+// MQPublishLimitsConfig holds the MQ server's publish rate limits. Rates
+// are per second; zero is unlimited.
+type MQPublishLimitsConfig struct {
+	// ConnMessages and ConnBytes limit each connection
+	ConnMessages float64 `yaml:"conn_messages_per_sec" json:"conn_messages_per_sec"`
+	ConnBytes    float64 `yaml:"conn_bytes_per_sec" json:"conn_bytes_per_sec"`
+
+	// GlobalMessages and GlobalBytes limit all connections together
+	GlobalMessages float64 `yaml:"global_messages_per_sec" json:"global_messages_per_sec"`
+	GlobalBytes    float64 `yaml:"global_bytes_per_sec" json:"global_bytes_per_sec"`
+}
+
+// MQClientQuotasConfig holds the MQ server's per-client quotas; zero is
+// unlimited.
+type MQClientQuotasConfig struct {
+	// MaxInFlight bounds the messages delivered to each subscription and
+	// not yet acked; subscriptions that commit manually do not ack and are
+	// not bounded
+	MaxInFlight int `yaml:"max_in_flight" json:"max_in_flight"`
+
+	// MaxPublishBytes refuses publishes with a larger payload
+	MaxPublishBytes int `yaml:"max_publish_bytes" json:"max_publish_bytes"`
+
+	// MaxSubscriptions bounds the subscriptions and stats watches a
+	// connection holds at once
+	MaxSubscriptions int `yaml:"max_subscriptions" json:"max_subscriptions"`
+}
+
+// StateConfig holds configuration for the key-value store components keep
+// durable state in.
+type StateConfig struct {
+	// Backend is "file", "redis", or empty to keep no state
+	Backend string `yaml:"backend" json:"backend"`
+
+	// Dir is the directory of the file backend
+	Dir string `yaml:"dir" json:"dir"`
+
+	// RedisAddr is the host:port of the Redis backend
+	RedisAddr string `yaml:"redis_addr" json:"redis_addr"`
+
+	// RedisPassword authenticates to Redis; empty sends no AUTH
+	RedisPassword string `yaml:"redis_password" json:"-"`
+
+	// RedisDB is the Redis database number
+	RedisDB int `yaml:"redis_db" json:"redis_db"`
+
+	// RedisPrefix is prepended to every key, so deployments can share a
+	// Redis database
+	RedisPrefix string `yaml:"redis_prefix" json:"redis_prefix"`
+}
+
+// EncryptionConfig encrypts batch payloads with AES-GCM between the
+// streamer and their consumers, so telemetry on a shared MQ stays
+// confidential whether or not the transport is. Keys come from exactly one
+// of Keys, KeysFile or KeyCommand.
+type EncryptionConfig struct {
+	// Keys are pre-shared keys written ID:KEY, KEY being 16, 24 or 32
+	// base64-encoded bytes. The first encrypts; all of them decrypt, so a
+	// new key can be added to consumers before producers switch to it
+	Keys []string `yaml:"keys" json:"-"`
+
+	// KeysFile reads the keys from a file, one ID:KEY per line
+	KeysFile string `yaml:"keys_file" json:"keys_file"`
+
+	// KeyCommand runs a shell command printing the keys, one ID:KEY per
+	// line, to fetch them from a KMS at startup
+	KeyCommand string `yaml:"key_command" json:"key_command"`
+
+	// Required makes consumers drop batches that are not encrypted
+	Required bool `yaml:"required" json:"required"`
+}
+
+// Enabled reports whether a key source is configured.
+func (c EncryptionConfig) Enabled() bool {
+	return len(c.Keys) > 0 || c.KeysFile != "" || c.KeyCommand != ""
+}
+
+// DefaultEncryptionConfig returns the payload encryption configuration.
+func DefaultEncryptionConfig() EncryptionConfig {
+	return EncryptionConfig{
+		Keys:       getEnvList("PAYLOAD_ENCRYPTION_KEYS"),
+		KeysFile:   getEnv("PAYLOAD_ENCRYPTION_KEYS_FILE", ""),
+		KeyCommand: getEnv("PAYLOAD_ENCRYPTION_KEY_COMMAND", ""),
+		Required:   getEnvBool("PAYLOAD_ENCRYPTION_REQUIRED", false),
+	}
+}
+
+// SigningConfig signs each published batch with the producer's Ed25519
+// key, so collectors can tell its batches from spoofed ones. The key
+// comes from Key or KeyFile.
+type SigningConfig struct {
+	// Key is the base64-encoded 32 byte Ed25519 seed, or 64 byte private
+	// key. Batches are signed as the streamer's instance ID, which the
+	// collectors' key registry maps to the public key
+	Key string `yaml:"key" json:"-"`
+
+	// KeyFile reads the key from a file
+	KeyFile string `yaml:"key_file" json:"key_file"`
+}
+
+// Enabled reports whether a signing key is configured.
+func (c SigningConfig) Enabled() bool {
+	return c.Key != "" || c.KeyFile != ""
+}
+
+// DefaultSigningConfig returns the batch signing configuration.
+func DefaultSigningConfig() SigningConfig {
+	return SigningConfig{
+		Key:     getEnv("PRODUCER_SIGNING_KEY", ""),
+		KeyFile: getEnv("PRODUCER_SIGNING_KEY_FILE", ""),
+	}
+}
+
+// Policies for batches that fail signature verification.
+const (
+	// SignaturePolicyReject drops unsigned and invalid batches
+	SignaturePolicyReject = "reject"
+
+	// SignaturePolicyFlag stores them, marking their lineage unverified, so
+	// a registry can be rolled out before every producer signs
+	SignaturePolicyFlag = "flag"
+)
+
+// VerificationConfig verifies that each consumed batch was signed by the
+// producer it names as its source. The key registry comes from Keys or
+// KeysFile; without one nothing is verified.
+type VerificationConfig struct {
+	// Keys map producers to their public keys, written ID:KEY with ID the
+	// streamer's instance ID and KEY its base64-encoded Ed25519 public key
+	Keys []string `yaml:"keys" json:"keys"`
+
+	// KeysFile reads the registry from a file, one ID:KEY per line
+	KeysFile string `yaml:"keys_file" json:"keys_file"`
+
+	// Policy is SignaturePolicyReject or SignaturePolicyFlag
+	Policy string `yaml:"policy" json:"policy"`
+}
+
+// Enabled reports whether a key registry is configured.
+func (c VerificationConfig) Enabled() bool {
+	return len(c.Keys) > 0 || c.KeysFile != ""
+}
+
+// DefaultVerificationConfig returns the batch verification configuration.
+func DefaultVerificationConfig() VerificationConfig {
+	return VerificationConfig{
+		Keys:     getEnvList("PRODUCER_VERIFY_KEYS"),
+		KeysFile: getEnv("PRODUCER_VERIFY_KEYS_FILE", ""),
+		Policy:   getEnv("PRODUCER_VERIFY_POLICY", SignaturePolicyReject),
+	}
 }
 
 // DefaultMQClientConfig returns a default MQ client configuration.
@@ -158,6 +1202,34 @@ func DefaultMQQueueConfig() MQQueueConfig {
 		MaxRetries:     getEnvInt("MQ_MAX_RETRIES", 3),
 		RetryDelay:     getEnvDuration("MQ_RETRY_DELAY", time.Second),
 		PublishTimeout: getEnvDuration("MQ_PUBLISH_TIMEOUT", 5*time.Second),
+		ProbeInterval:  getEnvDuration("MQ_PROBE_INTERVAL", 0),
+		DataDir:        getEnv("MQ_DATA_DIR", ""),
+		FsyncPolicy:    getEnv("MQ_FSYNC_POLICY", "interval"),
+		FsyncInterval:  getEnvDuration("MQ_FSYNC_INTERVAL", time.Second),
+		SegmentBytes:   getEnvInt("MQ_SEGMENT_BYTES", 64<<20),
+		MemoryBytes:    getEnvInt("MQ_MEMORY_BYTES", 0),
+
+		RetryMultiplier: getEnvFloat("MQ_RETRY_MULTIPLIER", 2),
+		RetryMaxDelay:   getEnvDuration("MQ_RETRY_MAX_DELAY", 30*time.Second),
+		RetryJitter:     getEnvFloat("MQ_RETRY_JITTER", 0.2),
+
+		RetentionMessages: getEnvInt("MQ_RETENTION_MESSAGES", 0),
+		RetentionBytes:    getEnvInt("MQ_RETENTION_BYTES", 1<<30),
+		RetentionAge:      getEnvDuration("MQ_RETENTION_AGE", 0),
+		RetentionInterval: getEnvDuration("MQ_RETENTION_INTERVAL", 10*time.Second),
+		MaxMessages:       getEnvInt("MQ_MAX_MESSAGES", 0),
+		MaxBytes:          getEnvInt("MQ_MAX_BYTES", 0),
+		OverflowPolicy:    getEnv("MQ_OVERFLOW_POLICY", "reject"),
+		Partitions:        getEnvInt("MQ_PARTITIONS", 1),
+		DedupWindow:       getEnvDuration("MQ_DEDUP_WINDOW", 5*time.Minute),
+		DedupMaxKeys:      getEnvInt("MQ_DEDUP_MAX_KEYS", 100000),
+		MaxDeadLetters:    getEnvInt("MQ_MAX_DEAD_LETTERS", 1000),
+
+		SubscriberMaxPendingBytes: getEnvInt("MQ_SUBSCRIBER_MAX_PENDING_BYTES", 64<<20),
+		SubscriberOverflowPolicy:  getEnv("MQ_SUBSCRIBER_OVERFLOW_POLICY", "pause"),
+
+		AutoCommitInterval: getEnvDuration("MQ_AUTO_COMMIT_INTERVAL", 0),
+		AckTimeout:         getEnvDuration("MQ_ACK_TIMEOUT", 30*time.Second),
 	}
 }
 
@@ -171,34 +1243,207 @@ func DefaultMQConfig() MQConfig {
 		MaxRetries:     getEnvInt("MQ_MAX_RETRIES", 3),
 		RetryDelay:     getEnvDuration("MQ_RETRY_DELAY", time.Second),
 		PublishTimeout: getEnvDuration("MQ_PUBLISH_TIMEOUT", 5*time.Second),
+		Reconnect: DefaultRetryConfig("MQ_RECONNECT", RetryConfig{
+			MaxAttempts:    0,
+			InitialBackoff: time.Second,
+			MaxBackoff:     30 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+		HeartbeatInterval: getEnvDuration("MQ_HEARTBEAT_INTERVAL", 15*time.Second),
+		Failover:          getEnvList("MQ_FAILOVER"),
+		WireFormat:        getEnv("MQ_WIRE_FORMAT", "json"),
+	}
+}
+
+// DefaultRetryConfig returns a retry configuration read from environment
+// variables named <prefix>_RETRY_MAX_ATTEMPTS, <prefix>_RETRY_INITIAL_BACKOFF,
+// <prefix>_RETRY_MAX_BACKOFF, <prefix>_RETRY_MULTIPLIER and <prefix>_RETRY_JITTER,
+// falling back to the given defaults.
+func DefaultRetryConfig(prefix string, defaults RetryConfig) RetryConfig {
+	return RetryConfig{
+		MaxAttempts:    getEnvInt(prefix+"_RETRY_MAX_ATTEMPTS", defaults.MaxAttempts),
+		InitialBackoff: getEnvDuration(prefix+"_RETRY_INITIAL_BACKOFF", defaults.InitialBackoff),
+		MaxBackoff:     getEnvDuration(prefix+"_RETRY_MAX_BACKOFF", defaults.MaxBackoff),
+		Multiplier:     getEnvFloat(prefix+"_RETRY_MULTIPLIER", defaults.Multiplier),
+		Jitter:         getEnvFloat(prefix+"_RETRY_JITTER", defaults.Jitter),
 	}
 }
 
 // DefaultStreamerConfig returns a default Streamer configuration.
 func DefaultStreamerConfig() StreamerConfig {
 	return StreamerConfig{
-		InstanceID:      getEnv("STREAMER_ID", "streamer-1"),
-		CSVPath:         getEnv("CSV_PATH", "/data/telemetry.csv"),
-		BatchSize:       getEnvInt("BATCH_SIZE", 100),
-		CollectInterval: getEnvDuration("COLLECT_INTERVAL", 100*time.Millisecond),
-		StreamInterval:  getEnvDuration("STREAM_INTERVAL", time.Second),
-		Loop:            getEnvBool("LOOP", true),
-		MQ:              DefaultMQConfig(),
-		HostFilter:      nil,
+		InstanceID:       getEnv("STREAMER_ID", "streamer-1"),
+		Source:           getEnv("STREAMER_SOURCE", "file"),
+		SourceOptions:    getEnvMap("STREAMER_SOURCE_OPTIONS"),
+		SourcePlugins:    getEnvList("STREAMER_SOURCE_PLUGINS"),
+		SourceCheckpoint: getEnv("STREAMER_SOURCE_CHECKPOINT", ""),
+		CSVPath:          getEnv("CSV_PATH", "/data/telemetry.csv"),
+		InputFormat:      getEnv("INPUT_FORMAT", "auto"),
+		CSVMappingsFile:  getEnv("CSV_MAPPINGS_FILE", ""),
+		CSVColumns:       getEnvList("CSV_COLUMNS"),
+		BatchSize:        getEnvInt("BATCH_SIZE", 100),
+		CollectInterval:  getEnvDuration("COLLECT_INTERVAL", 100*time.Millisecond),
+		StreamInterval:   getEnvDuration("STREAM_INTERVAL", time.Second),
+		Loop:             getEnvBool("LOOP", true),
+		MQ:               DefaultMQConfig(),
+		HostFilter:       nil,
+		PublishRetry: DefaultRetryConfig("STREAMER_PUBLISH", RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Second,
+			MaxBackoff:     10 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+		UDP: UDPIngestConfig{
+			Host:            getEnv("UDP_HOST", "0.0.0.0"),
+			Port:            getEnvInt("UDP_PORT", 0),
+			MaxDatagramSize: getEnvInt("UDP_MAX_DATAGRAM_SIZE", 8192),
+			ReadBuffer:      getEnvInt("UDP_READ_BUFFER", 4<<20),
+			MaxBuffered:     getEnvInt("UDP_MAX_BUFFERED", 100000),
+		},
+		HealthHost: getEnv("STREAMER_HEALTH_HOST", "0.0.0.0"),
+		HealthPort: getEnvInt("STREAMER_HEALTH_PORT", 8082),
+		State:      DefaultStateConfig(),
+		Encryption: DefaultEncryptionConfig(),
+		Signing:    DefaultSigningConfig(),
 	}
 }
 
 // DefaultCollectorConfig returns a default Collector configuration.
 func DefaultCollectorConfig() CollectorConfig {
+	instanceID := getEnv("COLLECTOR_ID", "collector-1")
 	return CollectorConfig{
-		InstanceID:      getEnv("COLLECTOR_ID", "collector-1"),
-		MQ:              DefaultMQConfig(),
-		InfluxURL:       getEnv("INFLUXDB_URL", "http://localhost:8086"),
-		InfluxToken:     getEnv("INFLUXDB_TOKEN", ""),
-		InfluxOrg:       getEnv("INFLUXDB_ORG", "cisco"),
-		InfluxBucket:    getEnv("INFLUXDB_BUCKET", "gpu_telemetry"),
-		RetentionPeriod: getEnvDuration("RETENTION_PERIOD", 24*time.Hour),
-		FlushInterval:   getEnvDuration("FLUSH_INTERVAL", 10*time.Second),
+		InstanceID:          instanceID,
+		Source:              getEnv("COLLECTOR_SOURCE", "mq"),
+		MQ:                  DefaultMQConfig(),
+		Kafka:               DefaultKafkaConfig(instanceID),
+		InfluxURL:           getEnv("INFLUXDB_URL", "http://localhost:8086"),
+		InfluxToken:         getEnv("INFLUXDB_TOKEN", ""),
+		InfluxOrg:           getEnv("INFLUXDB_ORG", "cisco"),
+		InfluxBucket:        getEnv("INFLUXDB_BUCKET", "gpu_telemetry"),
+		RetentionPeriod:     getEnvDuration("RETENTION_PERIOD", 24*time.Hour),
+		ExpireTelemetry:     getEnvBool("COLLECTOR_EXPIRE_TELEMETRY", false),
+		ReadOnly:            getEnvBool("COLLECTOR_READ_ONLY", false),
+		FlushInterval:       getEnvDuration("FLUSH_INTERVAL", 10*time.Second),
+		StartOffset:         getEnv("COLLECTOR_START_OFFSET", "latest"),
+		CommitInterval:      getEnvDuration("COLLECTOR_COMMIT_INTERVAL", 5*time.Second),
+		IngestStatsInterval: getEnvDuration("COLLECTOR_INGEST_STATS_INTERVAL", time.Minute),
+		SubscribeFilter:     getEnv("COLLECTOR_FILTER", ""),
+		SubscribePartitions: getEnvIntList("COLLECTOR_PARTITIONS"),
+		Group:               getEnv("COLLECTOR_GROUP", ""),
+		Workers:             getEnvInt("COLLECTOR_WORKERS", 0),
+		StoreRetry: DefaultRetryConfig("COLLECTOR_STORE", RetryConfig{
+			MaxAttempts:    4,
+			InitialBackoff: time.Second,
+			MaxBackoff:     15 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+		Webhooks: DefaultWebhookConfig(),
+		Forward:  DefaultForwardConfig(),
+		Cardinality: CardinalityConfig{
+			Budget: getEnvInt("COLLECTOR_CARDINALITY_BUDGET", 0),
+			Window: getEnvDuration("COLLECTOR_CARDINALITY_WINDOW", time.Hour),
+			Drop:   getEnvBool("COLLECTOR_CARDINALITY_DROP", false),
+		},
+		LateData: LateDataConfig{
+			Threshold: getEnvDuration("COLLECTOR_LATE_THRESHOLD", time.Hour),
+			Policy:    getEnv("COLLECTOR_LATE_POLICY", LatePolicyAccept),
+			Bucket:    getEnv("COLLECTOR_LATE_BUCKET", ""),
+		},
+		ClockSkew: ClockSkewConfig{
+			Threshold: getEnvDuration("COLLECTOR_SKEW_THRESHOLD", 30*time.Second),
+			Policy:    getEnv("COLLECTOR_SKEW_POLICY", SkewPolicyAccept),
+		},
+		HostAggregates: getEnvHostAggregates("COLLECTOR_HOST_AGGREGATES"),
+		HealthHost:     getEnv("COLLECTOR_HEALTH_HOST", "0.0.0.0"),
+		HealthPort:     getEnvInt("COLLECTOR_HEALTH_PORT", 8083),
+		Encryption:     DefaultEncryptionConfig(),
+		Verification:   DefaultVerificationConfig(),
+	}
+}
+
+// DefaultForwardConfig returns the forwarding sinks named in FORWARD_SINKS.
+// Each sink is configured by FORWARD_<NAME>_* variables, where <NAME> is
+// the sink name in upper case.
+func DefaultForwardConfig() []ForwardSinkConfig {
+	var sinks []ForwardSinkConfig
+	for _, name := range getEnvList("FORWARD_SINKS") {
+		sinks = append(sinks, DefaultForwardSinkConfig(name))
+	}
+	return sinks
+}
+
+// DefaultForwardSinkConfig returns the configuration of the named forwarding sink.
+func DefaultForwardSinkConfig(name string) ForwardSinkConfig {
+	prefix := "FORWARD_" + strings.ToUpper(name)
+	return ForwardSinkConfig{
+		Name:          name,
+		Type:          getEnv(prefix+"_TYPE", ""),
+		URL:           getEnv(prefix+"_URL", ""),
+		Filter:        getEnv(prefix+"_FILTER", ""),
+		APIKey:        getEnv(prefix+"_API_KEY", ""),
+		Headers:       getEnvList(prefix + "_HEADERS"),
+		BatchSize:     getEnvInt(prefix+"_BATCH_SIZE", 1000),
+		FlushInterval: getEnvDuration(prefix+"_FLUSH_INTERVAL", 10*time.Second),
+		QueueSize:     getEnvInt(prefix+"_QUEUE_SIZE", 100000),
+		Timeout:       getEnvDuration(prefix+"_TIMEOUT", 10*time.Second),
+		Retry: DefaultRetryConfig(prefix, RetryConfig{
+			MaxAttempts:    5,
+			InitialBackoff: time.Second,
+			MaxBackoff:     30 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+	}
+}
+
+// DefaultKafkaConfig returns the Kafka source configuration. The consumer
+// group defaults to the collector's instance ID, so each collector keeps
+// its own position like it does on the MQ.
+func DefaultKafkaConfig(instanceID string) KafkaConfig {
+	return KafkaConfig{
+		Brokers:        getEnvList("KAFKA_BROKERS"),
+		Topic:          getEnv("KAFKA_TOPIC", "gpu-telemetry"),
+		GroupID:        getEnv("KAFKA_GROUP_ID", instanceID),
+		Format:         getEnv("KAFKA_FORMAT", "json"),
+		StartOffset:    getEnv("KAFKA_START_OFFSET", "latest"),
+		MaxWait:        getEnvDuration("KAFKA_MAX_WAIT", 500*time.Millisecond),
+		FetchMaxBytes:  getEnvInt("KAFKA_FETCH_MAX_BYTES", 1<<20),
+		CommitInterval: getEnvDuration("KAFKA_COMMIT_INTERVAL", 5*time.Second),
+		TLS:            getEnvBool("KAFKA_TLS", false),
+		TLSCAFile:      getEnv("KAFKA_TLS_CA_FILE", ""),
+		SASLMechanism:  getEnv("KAFKA_SASL_MECHANISM", ""),
+		SASLUsername:   getEnv("KAFKA_SASL_USERNAME", ""),
+		SASLPassword:   getEnv("KAFKA_SASL_PASSWORD", ""),
+		Retry: DefaultRetryConfig("KAFKA", RetryConfig{
+			InitialBackoff: time.Second,
+			MaxBackoff:     30 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+	}
+}
+
+// DefaultWebhookConfig returns the event webhook configuration.
+func DefaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		URLs:      getEnvList("WEBHOOK_URLS"),
+		Events:    getEnvList("WEBHOOK_EVENTS"),
+		Secret:    getEnv("WEBHOOK_SECRET", ""),
+		Timeout:   getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		QueueSize: getEnvInt("WEBHOOK_QUEUE_SIZE", 1000),
+		LogSize:   getEnvInt("WEBHOOK_LOG_SIZE", 200),
+		Retry: DefaultRetryConfig("WEBHOOK", RetryConfig{
+			MaxAttempts:    5,
+			InitialBackoff: time.Second,
+			MaxBackoff:     30 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+		SilenceAfter: getEnvDuration("WEBHOOK_SILENCE_AFTER", 5*time.Minute),
+		LagThreshold: int64(getEnvInt("WEBHOOK_LAG_THRESHOLD", 10000)),
 	}
 }
 
@@ -211,6 +1456,253 @@ func DefaultAPIConfig() APIConfig {
 		WriteTimeout: getEnvDuration("API_WRITE_TIMEOUT", 10*time.Second),
 		DefaultLimit: getEnvInt("DEFAULT_LIMIT", 100),
 		MaxLimit:     getEnvInt("MAX_LIMIT", 1000),
+
+		CacheSource:          getEnv("API_CACHE_SOURCE", "storage"),
+		CacheRefreshInterval: getEnvDuration("API_CACHE_REFRESH_INTERVAL", 15*time.Second),
+		CacheWindow:          getEnvDuration("API_CACHE_WINDOW", 10*time.Minute),
+		MQ:                   DefaultMQConfig(),
+		Scheduler:            DefaultSchedulerConfig(),
+		Webhooks:             DefaultWebhookConfig(),
+		Alerts:               DefaultAlertConfig(),
+		Baselines:            DefaultBaselineConfig(),
+		Status:               DefaultStatusConfig(),
+		Summary:              DefaultSummaryConfig(),
+		SLO:                  DefaultSLOConfig(),
+		Predict:              DefaultPredictConfig(),
+		AdminToken:           getEnv("API_ADMIN_TOKEN", ""),
+		BundleKey:            getEnv("API_BUNDLE_SIGNING_KEY", ""),
+		LogLevel:             getEnv("API_LOG_LEVEL", "info"),
+		Dashboard:            getEnvBool("API_DASHBOARD_ENABLED", true),
+		Maintenance:          getEnvBool("API_MAINTENANCE", false),
+		MaintenanceMessage:   getEnv("API_MAINTENANCE_MESSAGE", ""),
+		Exports:              DefaultExportConfig(),
+		TenantTokens:         getEnvMap("API_TENANT_TOKENS"),
+		Usage:                DefaultUsageConfig(),
+		DefaultEnvironment:   getEnv("API_DEFAULT_ENVIRONMENT", "default"),
+		Environments:         DefaultEnvironmentConfigs(),
+		State:                DefaultStateConfig(),
+		Encryption:           DefaultEncryptionConfig(),
+	}
+}
+
+// DefaultEnvironmentConfigs returns the environments named in
+// API_ENVIRONMENTS. Each is configured by API_ENV_<NAME>_* variables, where
+// <NAME> is the environment name in upper case.
+func DefaultEnvironmentConfigs() []EnvironmentConfig {
+	var envs []EnvironmentConfig
+	for _, name := range getEnvList("API_ENVIRONMENTS") {
+		envs = append(envs, DefaultEnvironmentConfig(name))
+	}
+	return envs
+}
+
+// DefaultEnvironmentConfig returns the configuration of the named environment.
+func DefaultEnvironmentConfig(name string) EnvironmentConfig {
+	prefix := "API_ENV_" + strings.ToUpper(name)
+	return EnvironmentConfig{
+		Name:    name,
+		URL:     getEnv(prefix+"_URL", getEnv("INFLUXDB_URL", "http://localhost:8086")),
+		Org:     getEnv(prefix+"_ORG", getEnv("INFLUXDB_ORG", "cisco")),
+		Bucket:  getEnv(prefix+"_BUCKET", ""),
+		Token:   getEnv(prefix+"_TOKEN", getEnv("INFLUXDB_TOKEN", "")),
+		Tenants: getEnvList(prefix + "_TENANTS"),
+	}
+}
+
+// DefaultUsageConfig returns the usage metering configuration.
+func DefaultUsageConfig() UsageConfig {
+	return UsageConfig{
+		File:             getEnv("API_USAGE_FILE", ""),
+		FlushInterval:    getEnvDuration("API_USAGE_FLUSH_INTERVAL", time.Minute),
+		QuotaRequests:    int64(getEnvInt("API_USAGE_QUOTA_REQUESTS", 0)),
+		QuotaRows:        int64(getEnvInt("API_USAGE_QUOTA_ROWS", 0)),
+		QuotaExportBytes: int64(getEnvInt("API_USAGE_QUOTA_EXPORT_MB", 0)) << 20,
+	}
+}
+
+// DefaultExportConfig returns the export job configuration.
+func DefaultExportConfig() ExportConfig {
+	return ExportConfig{
+		Dir:             getEnv("API_EXPORT_DIR", ""),
+		TTL:             getEnvDuration("API_EXPORT_TTL", 24*time.Hour),
+		QuotaBytes:      int64(getEnvInt("API_EXPORT_QUOTA_MB", 10240)) << 20,
+		MaxRows:         getEnvInt("API_EXPORT_MAX_ROWS", 5000000),
+		QueueSize:       getEnvInt("API_EXPORT_QUEUE_SIZE", 16),
+		JanitorInterval: getEnvDuration("API_EXPORT_JANITOR_INTERVAL", 5*time.Minute),
+	}
+}
+
+// DefaultSchedulerConfig returns the saved-query scheduler configuration.
+func DefaultSchedulerConfig() SchedulerConfig {
+	return SchedulerConfig{
+		Enabled:         getEnvBool("API_SCHEDULER_ENABLED", false),
+		Tick:            getEnvDuration("API_SCHEDULER_TICK", 30*time.Second),
+		LeaderElection:  getEnvBool("API_SCHEDULER_LEADER_ELECTION", true),
+		LeaseTTL:        getEnvDuration("API_SCHEDULER_LEASE_TTL", 15*time.Second),
+		DeliveryTimeout: getEnvDuration("API_SCHEDULER_DELIVERY_TIMEOUT", 30*time.Second),
+		SMTPHost:        getEnv("SMTP_HOST", ""),
+		SMTPPort:        getEnvInt("SMTP_PORT", 587),
+		SMTPFrom:        getEnv("SMTP_FROM", ""),
+		SMTPUsername:    getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
+		S3Endpoint:      getEnv("S3_ENDPOINT", ""),
+		S3Region:        getEnv("AWS_REGION", "us-east-1"),
+		S3AccessKey:     getEnv("AWS_ACCESS_KEY_ID", ""),
+		S3SecretKey:     getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		WebhookHosts:    getEnvList("API_SCHEDULER_WEBHOOK_HOSTS"),
+		EmailDomains:    getEnvList("API_SCHEDULER_EMAIL_DOMAINS"),
+		S3Buckets:       getEnvList("API_SCHEDULER_S3_BUCKETS"),
+	}
+}
+
+// DefaultAlertConfig returns the alerting configuration. ALERT_NOTIFIERS
+// names the notifiers, each configured by ALERT_NOTIFIER_<NAME>_* variables.
+func DefaultAlertConfig() AlertConfig {
+	var notifiers []AlertNotifierConfig
+	for _, name := range getEnvList("ALERT_NOTIFIERS") {
+		notifiers = append(notifiers, DefaultAlertNotifierConfig(name))
+	}
+	// "none" turns maintenance suppression off
+	maintenanceKinds := splitList(getEnv("ALERT_MAINTENANCE_KINDS", "maintenance,driver_upgrade"))
+	if len(maintenanceKinds) == 1 && maintenanceKinds[0] == "none" {
+		maintenanceKinds = nil
+	}
+	return AlertConfig{
+		Enabled:        getEnvBool("ALERTS_ENABLED", false),
+		RulesFile:      getEnv("ALERT_RULES_FILE", ""),
+		EvalInterval:   getEnvDuration("ALERT_EVAL_INTERVAL", 30*time.Second),
+		RepeatInterval: getEnvDuration("ALERT_REPEAT_INTERVAL", 4*time.Hour),
+		EscalateAfter:  getEnvDuration("ALERT_ESCALATE_AFTER", 0),
+		EscalateTo:     getEnvList("ALERT_ESCALATE_TO"),
+		LeaderElection: getEnvBool("ALERT_LEADER_ELECTION", true),
+		LeaseTTL:       getEnvDuration("ALERT_LEASE_TTL", 15*time.Second),
+		Timeout:        getEnvDuration("ALERT_NOTIFY_TIMEOUT", 10*time.Second),
+		QueueSize:      getEnvInt("ALERT_NOTIFY_QUEUE_SIZE", 1000),
+		Retry: DefaultRetryConfig("ALERT_NOTIFY", RetryConfig{
+			MaxAttempts:    5,
+			InitialBackoff: time.Second,
+			MaxBackoff:     30 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+		Notifiers:        notifiers,
+		MaintenanceKinds: maintenanceKinds,
+	}
+}
+
+// DefaultBaselineConfig returns the baseline configuration. Temperature,
+// power and SM clock are profiled unless BASELINE_METRICS says otherwise.
+func DefaultBaselineConfig() BaselineConfig {
+	return BaselineConfig{
+		Enabled:    getEnvBool("BASELINES_ENABLED", false),
+		Metrics:    splitList(getEnv("BASELINE_METRICS", "DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_POWER_USAGE,DCGM_FI_DEV_SM_CLOCK")),
+		Window:     getEnvDuration("BASELINE_WINDOW", 7*24*time.Hour),
+		Refresh:    getEnvDuration("BASELINE_REFRESH", 6*time.Hour),
+		MinSamples: getEnvInt("BASELINE_MIN_SAMPLES", 1000),
+	}
+}
+
+// DefaultStatusConfig returns the GPU status configuration. Utilization,
+// temperature, power and framebuffer use are kept unless API_STATUS_METRICS
+// says otherwise.
+func DefaultStatusConfig() StatusConfig {
+	return StatusConfig{
+		Enabled:        getEnvBool("API_STATUS_ENABLED", false),
+		Interval:       getEnvDuration("API_STATUS_INTERVAL", 30*time.Second),
+		Metrics:        splitList(getEnv("API_STATUS_METRICS", "DCGM_FI_DEV_GPU_UTIL,DCGM_FI_DEV_GPU_TEMP,DCGM_FI_DEV_POWER_USAGE,DCGM_FI_DEV_FB_USED")),
+		StaleAfter:     getEnvDuration("API_STATUS_STALE_AFTER", 5*time.Minute),
+		LeaderElection: getEnvBool("API_STATUS_LEADER_ELECTION", true),
+		LeaseTTL:       getEnvDuration("API_STATUS_LEASE_TTL", 15*time.Second),
+	}
+}
+
+// DefaultSummaryConfig returns the fleet summary configuration: recomputed
+// every 10 minutes, listing the last 30 days' volumes.
+func DefaultSummaryConfig() SummaryConfig {
+	return SummaryConfig{
+		Enabled: getEnvBool("API_SUMMARY_ENABLED", true),
+		Refresh: getEnvDuration("API_SUMMARY_REFRESH", 10*time.Minute),
+		Days:    getEnvInt("API_SUMMARY_DAYS", 30),
+	}
+}
+
+// DefaultSLOConfig returns the ingest-latency objective: 95% of metrics
+// stored within 30s of collection over 24h, alerting when the error budget
+// burns 14.4 times too fast over both the last 5 minutes and the last hour.
+func DefaultSLOConfig() SLOConfig {
+	return SLOConfig{
+		Enabled:        getEnvBool("API_SLO_ENABLED", false),
+		Objective:      getEnvFloat("API_SLO_OBJECTIVE", 0.95),
+		Latency:        getEnvDuration("API_SLO_LATENCY", 30*time.Second),
+		Window:         getEnvDuration("API_SLO_WINDOW", 24*time.Hour),
+		Interval:       getEnvDuration("API_SLO_INTERVAL", time.Minute),
+		BurnRate:       getEnvFloat("API_SLO_BURN_RATE", 14.4),
+		ShortWindow:    getEnvDuration("API_SLO_SHORT_WINDOW", 5*time.Minute),
+		LongWindow:     getEnvDuration("API_SLO_LONG_WINDOW", time.Hour),
+		LeaderElection: getEnvBool("API_SLO_LEADER_ELECTION", true),
+		LeaseTTL:       getEnvDuration("API_SLO_LEASE_TTL", 15*time.Second),
+	}
+}
+
+// DefaultPredictConfig returns the failure prediction configuration: every
+// GPU scored by the rules model every 15 minutes from 72 samples over the
+// last 6 hours.
+func DefaultPredictConfig() PredictConfig {
+	return PredictConfig{
+		Enabled:        getEnvBool("API_PREDICT_ENABLED", false),
+		Interval:       getEnvDuration("API_PREDICT_INTERVAL", 15*time.Minute),
+		Window:         getEnvDuration("API_PREDICT_WINDOW", 6*time.Hour),
+		Samples:        getEnvInt("API_PREDICT_SAMPLES", 72),
+		Model:          getEnv("API_PREDICT_MODEL", "rules"),
+		ModelFile:      getEnv("API_PREDICT_MODEL_FILE", ""),
+		LeaderElection: getEnvBool("API_PREDICT_LEADER_ELECTION", true),
+		LeaseTTL:       getEnvDuration("API_PREDICT_LEASE_TTL", 15*time.Second),
+	}
+}
+
+// DefaultAlertNotifierConfig returns the configuration of the named alert notifier.
+func DefaultAlertNotifierConfig(name string) AlertNotifierConfig {
+	prefix := "ALERT_NOTIFIER_" + strings.ToUpper(name)
+	return AlertNotifierConfig{
+		Name:       name,
+		Type:       getEnv(prefix+"_TYPE", ""),
+		URL:        getEnv(prefix+"_URL", ""),
+		RoutingKey: getEnv(prefix+"_ROUTING_KEY", ""),
+		To:         getEnvList(prefix + "_TO"),
+		Subject:    getEnv(prefix+"_SUBJECT", ""),
+		Template:   getEnv(prefix+"_TEMPLATE", ""),
+	}
+}
+
+// DefaultOTLPReceiverConfig returns a default OTLP receiver configuration.
+func DefaultOTLPReceiverConfig() OTLPReceiverConfig {
+	return OTLPReceiverConfig{
+		InstanceID:      getEnv("OTLP_RECEIVER_ID", "otlp-receiver-1"),
+		Host:            getEnv("OTLP_HOST", "0.0.0.0"),
+		GRPCPort:        getEnvInt("OTLP_GRPC_PORT", 4317),
+		HTTPPort:        getEnvInt("OTLP_HTTP_PORT", 4318),
+		MaxRequestBytes: getEnvInt("OTLP_MAX_REQUEST_BYTES", 16<<20),
+		MQ:              DefaultMQConfig(),
+		PublishRetry: DefaultRetryConfig("OTLP_PUBLISH", RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     5 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		}),
+	}
+}
+
+// DefaultStateConfig returns the state store configuration: no store
+// unless STATE_BACKEND selects one.
+func DefaultStateConfig() StateConfig {
+	return StateConfig{
+		Backend:       getEnv("STATE_BACKEND", ""),
+		Dir:           getEnv("STATE_DIR", ""),
+		RedisAddr:     getEnv("STATE_REDIS_ADDR", "localhost:6379"),
+		RedisPassword: getEnv("STATE_REDIS_PASSWORD", ""),
+		RedisDB:       getEnvInt("STATE_REDIS_DB", 0),
+		RedisPrefix:   getEnv("STATE_REDIS_PREFIX", "gpu-telemetry:"),
 	}
 }
 
@@ -222,6 +1714,27 @@ func DefaultMQServerConfig() MQServerConfig {
 		HTTPHost: getEnv("HTTP_HOST", "0.0.0.0"),
 		HTTPPort: getEnvInt("HTTP_PORT", 9001),
 		Queue:    DefaultMQQueueConfig(),
+		Debug:    getEnvBool("MQ_DEBUG", false),
+
+		PublishLimits: MQPublishLimitsConfig{
+			ConnMessages:   getEnvFloat("MQ_CONN_PUBLISH_RATE", 0),
+			ConnBytes:      getEnvFloat("MQ_CONN_PUBLISH_BYTES_RATE", 0),
+			GlobalMessages: getEnvFloat("MQ_GLOBAL_PUBLISH_RATE", 0),
+			GlobalBytes:    getEnvFloat("MQ_GLOBAL_PUBLISH_BYTES_RATE", 0),
+		},
+		Quotas: MQClientQuotasConfig{
+			MaxInFlight:      getEnvInt("MQ_CLIENT_MAX_IN_FLIGHT", 0),
+			MaxPublishBytes:  getEnvInt("MQ_CLIENT_MAX_PUBLISH_BYTES", 0),
+			MaxSubscriptions: getEnvInt("MQ_CLIENT_MAX_SUBSCRIPTIONS", 0),
+		},
+
+		AdminToken: getEnv("MQ_ADMIN_TOKEN", ""),
+		LogLevel:   getEnv("MQ_LOG_LEVEL", "info"),
+
+		ReplicaOf:    getEnv("MQ_REPLICA_OF", ""),
+		PromoteAfter: getEnvDuration("MQ_PROMOTE_AFTER", 0),
+
+		WebSocketOrigins: getEnvList("MQ_WS_ORIGINS"),
 	}
 }
 
@@ -260,3 +1773,65 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+// getEnvIntList parses a comma-separated list of integers, dropping entries
+// that are not one.
+func getEnvIntList(key string) []int {
+	var ints []int
+	for _, item := range getEnvList(key) {
+		if i, err := strconv.Atoi(item); err == nil {
+			ints = append(ints, i)
+		}
+	}
+	return ints
+}
+
+// getEnvMap parses a comma-separated list of key=value pairs, dropping
+// entries without a key or value.
+func getEnvMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		k, v, _ := strings.Cut(item, "=")
+		if k, v = strings.TrimSpace(k), strings.TrimSpace(v); k != "" && v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// getEnvHostAggregates parses a comma-separated list of func:metric pairs.
+// An entry without a colon is kept as a func with no metric, for
+// validation to report.
+func getEnvHostAggregates(key string) []HostAggregate {
+	var aggregates []HostAggregate
+	for _, item := range getEnvList(key) {
+		fn, metric, _ := strings.Cut(item, ":")
+		aggregates = append(aggregates, HostAggregate{Func: strings.TrimSpace(fn), Metric: strings.TrimSpace(metric)})
+	}
+	return aggregates
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"encoding/base64"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -249,3 +252,1084 @@ func TestConfigWithEnvOverrides(t *testing.T) {
 		t.Errorf("expected default limit 50, got %d", cfg.DefaultLimit)
 	}
 }
+
+func TestDefaultConfigsValidate(t *testing.T) {
+	if err := DefaultStreamerConfig().Validate(); err != nil {
+		t.Errorf("default streamer config invalid: %v", err)
+	}
+	if err := DefaultCollectorConfig().Validate(); err != nil {
+		t.Errorf("default collector config invalid: %v", err)
+	}
+	if err := DefaultAPIConfig().Validate(); err != nil {
+		t.Errorf("default API config invalid: %v", err)
+	}
+	if err := DefaultMQServerConfig().Validate(); err != nil {
+		t.Errorf("default MQ server config invalid: %v", err)
+	}
+	if err := DefaultOTLPReceiverConfig().Validate(); err != nil {
+		t.Errorf("default OTLP receiver config invalid: %v", err)
+	}
+}
+
+func TestCollectorConfigValidateRetention(t *testing.T) {
+	cfg := DefaultCollectorConfig()
+	cfg.RetentionPeriod = 5 * time.Second
+	cfg.FlushInterval = 10 * time.Second
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when retention is shorter than flush interval")
+	}
+
+	cfg.InfluxURL = "localhost:8086"
+	cfg.RetentionPeriod = time.Hour
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for influx URL without scheme")
+	}
+}
+
+func TestCollectorConfigCommitInterval(t *testing.T) {
+	t.Setenv("COLLECTOR_COMMIT_INTERVAL", "30s")
+	cfg := DefaultCollectorConfig()
+	if cfg.CommitInterval != 30*time.Second {
+		t.Errorf("expected commit interval 30s, got %v", cfg.CommitInterval)
+	}
+
+	cfg.CommitInterval = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "commit_interval") {
+		t.Errorf("expected commit_interval error, got %v", err)
+	}
+
+	// Kafka commits on its own interval
+	cfg.Source = "kafka"
+	cfg.Kafka.Brokers = []string{"localhost:9092"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected no error for a Kafka source, got %v", err)
+	}
+}
+
+func TestCollectorConfigWorkers(t *testing.T) {
+	t.Setenv("COLLECTOR_WORKERS", "4")
+	cfg := DefaultCollectorConfig()
+	if cfg.Workers != 4 {
+		t.Fatalf("expected 4 workers, got %d", cfg.Workers)
+	}
+	cfg.Workers = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "workers") {
+		t.Errorf("expected a workers error, got %v", err)
+	}
+}
+
+func TestCollectorConfigIngestStatsInterval(t *testing.T) {
+	cfg := DefaultCollectorConfig()
+	if cfg.IngestStatsInterval != time.Minute {
+		t.Errorf("expected ingest stats interval 1m, got %v", cfg.IngestStatsInterval)
+	}
+
+	t.Setenv("COLLECTOR_INGEST_STATS_INTERVAL", "0s")
+	cfg = DefaultCollectorConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected disabled ingest stats to be valid, got %v", err)
+	}
+
+	cfg.IngestStatsInterval = -time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "ingest_stats_interval") {
+		t.Errorf("expected ingest_stats_interval error, got %v", err)
+	}
+}
+
+func TestCollectorConfigValidateKafkaSource(t *testing.T) {
+	cfg := DefaultCollectorConfig()
+	if cfg.Source != "mq" {
+		t.Errorf("expected default source mq, got %q", cfg.Source)
+	}
+	if cfg.Kafka.GroupID != cfg.InstanceID {
+		t.Errorf("expected Kafka group to default to the instance ID, got %q", cfg.Kafka.GroupID)
+	}
+
+	cfg.Source = "kafka"
+	cfg.MQ.Host = ""
+	cfg.Kafka.Brokers = nil
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "kafka.brokers") {
+		t.Errorf("expected missing brokers error, got %v", err)
+	}
+
+	cfg.Kafka.Brokers = []string{"kafka-0:9092", "kafka-1:9092"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid Kafka source without an MQ host, got %v", err)
+	}
+
+	cfg.Kafka.Brokers = []string{"kafka-0"}
+	cfg.Kafka.Format = "avro"
+	cfg.Kafka.StartOffset = "committed"
+	err := cfg.Validate()
+	for _, want := range []string{"host:port", "kafka.format", "kafka.start_offset"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+
+	cfg.Kafka.Brokers = []string{"kafka-0:9092"}
+	cfg.Kafka.Format, cfg.Kafka.StartOffset = "json", "latest"
+	cfg.Kafka.SASLMechanism = "plain"
+	err = cfg.Validate()
+	for _, want := range []string{"kafka.sasl_username", "requires kafka.tls"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+	cfg.Kafka.TLS, cfg.Kafka.SASLUsername = true, "collector"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected SASL PLAIN over TLS to be valid, got %v", err)
+	}
+	cfg.Kafka.SASLMechanism = "gssapi"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "kafka.sasl_mechanism") {
+		t.Errorf("expected unknown mechanism error, got %v", err)
+	}
+
+	cfg.Source = "pulsar"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "source") {
+		t.Errorf("expected unknown source error, got %v", err)
+	}
+}
+
+func TestCollectorConfigForwardSinks(t *testing.T) {
+	t.Setenv("FORWARD_SINKS", "prom,dd")
+	t.Setenv("FORWARD_PROM_TYPE", "prometheus")
+	t.Setenv("FORWARD_PROM_URL", "http://prometheus:9090/api/v1/write")
+	t.Setenv("FORWARD_PROM_FILTER", "metric_name=DCGM_FI_DEV_GPU_TEMP")
+	t.Setenv("FORWARD_PROM_BATCH_SIZE", "200")
+	t.Setenv("FORWARD_DD_TYPE", "datadog")
+
+	cfg := DefaultCollectorConfig()
+	if len(cfg.Forward) != 2 {
+		t.Fatalf("expected 2 forward sinks, got %d", len(cfg.Forward))
+	}
+	prom := cfg.Forward[0]
+	if prom.Name != "prom" || prom.Type != "prometheus" || prom.BatchSize != 200 || prom.Filter == "" {
+		t.Errorf("unexpected prom sink: %+v", prom)
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "forward.dd.api_key") {
+		t.Errorf("expected missing Datadog API key error, got %v", err)
+	}
+
+	cfg.Forward[1].APIKey = "secret"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid forward sinks, got %v", err)
+	}
+
+	cfg.Forward[1] = cfg.Forward[0]
+	cfg.Forward[0].Type = "graphite"
+	cfg.Forward[0].QueueSize = 10
+	err = cfg.Validate()
+	for _, want := range []string{"more than once", "forward.prom.type", "forward.prom.queue_size"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+}
+
+func TestStreamerConfigValidateInputFormat(t *testing.T) {
+	cfg := DefaultStreamerConfig()
+	if cfg.InputFormat != "auto" {
+		t.Errorf("expected default input format auto, got %q", cfg.InputFormat)
+	}
+
+	cfg.InputFormat = "parquet"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown input format")
+	}
+}
+
+func TestStreamerConfigWireFormat(t *testing.T) {
+	t.Setenv("MQ_WIRE_FORMAT", "binary")
+	cfg := DefaultStreamerConfig()
+	if cfg.MQ.WireFormat != "binary" {
+		t.Fatalf("expected the binary wire format, got %q", cfg.MQ.WireFormat)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.MQ.WireFormat = "protobuf"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "mq.wire_format") {
+		t.Errorf("expected an mq.wire_format error, got %v", err)
+	}
+}
+
+func TestStreamerConfigCSVColumns(t *testing.T) {
+	t.Setenv("CSV_COLUMNS", "hostname, gpu_id")
+	cfg := DefaultStreamerConfig()
+	if len(cfg.CSVColumns) != 2 || cfg.CSVColumns[1] != "gpu_id" {
+		t.Fatalf("expected columns [hostname gpu_id], got %v", cfg.CSVColumns)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	cfg.CSVColumns = append(cfg.CSVColumns, "rack")
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown column "rack"`) {
+		t.Errorf("expected an unknown column error, got %v", err)
+	}
+}
+
+func TestStreamerConfigSource(t *testing.T) {
+	t.Setenv("STREAMER_SOURCE", "fabric")
+	t.Setenv("STREAMER_SOURCE_OPTIONS", "switch=leaf-1,port=8443")
+	t.Setenv("STREAMER_SOURCE_PLUGINS", "/plugins/fabric.so")
+	t.Setenv("CSV_PATH", "")
+	cfg := DefaultStreamerConfig()
+	if cfg.Source != "fabric" || cfg.SourceOptions["switch"] != "leaf-1" || len(cfg.SourcePlugins) != 1 {
+		t.Fatalf("unexpected source config %q %v %v", cfg.Source, cfg.SourceOptions, cfg.SourcePlugins)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a site-specific source to need no file, got %v", err)
+	}
+
+	cfg.Source = "fabric counters"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "source") {
+		t.Errorf("expected an invalid source name error, got %v", err)
+	}
+}
+
+func TestStreamerConfigValidateUDP(t *testing.T) {
+	cfg := DefaultStreamerConfig()
+	if cfg.UDP.Enabled() {
+		t.Error("expected the UDP listener to be disabled by default")
+	}
+
+	cfg.InputFormat = "none"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "udp.port") {
+		t.Errorf("expected UDP-only input to need a port, got %v", err)
+	}
+
+	cfg.UDP.Port = 8125
+	cfg.CSVPath = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid UDP-only streamer, got %v", err)
+	}
+
+	cfg.UDP.MaxDatagramSize = 70000
+	cfg.UDP.MaxBuffered = 0
+	err := cfg.Validate()
+	for _, want := range []string{"udp.max_datagram_size", "udp.max_buffered"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+}
+
+func TestAPIConfigValidateCacheSource(t *testing.T) {
+	cfg := DefaultAPIConfig()
+	cfg.CacheSource = "redis"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown cache source")
+	}
+
+	cfg.CacheSource = "off"
+	cfg.CacheWindow = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("disabled cache should skip cache settings, got %v", err)
+	}
+}
+
+func TestAPIConfigValidateScheduler(t *testing.T) {
+	cfg := DefaultAPIConfig()
+	cfg.Scheduler.Tick = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("disabled scheduler should skip scheduler settings, got %v", err)
+	}
+
+	cfg.Scheduler.Enabled = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for non-positive scheduler tick")
+	}
+
+	cfg.Scheduler.Tick = 30 * time.Second
+	cfg.Scheduler.SMTPHost = "smtp.example.com"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for smtp_host without smtp_from")
+	}
+
+	cfg.Scheduler.SMTPFrom = "reports@example.com"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid scheduler config, got %v", err)
+	}
+}
+
+func TestAPIConfigAlertNotifiers(t *testing.T) {
+	t.Setenv("ALERTS_ENABLED", "true")
+	t.Setenv("ALERT_NOTIFIERS", "oncall,ops")
+	t.Setenv("ALERT_NOTIFIER_ONCALL_TYPE", "pagerduty")
+	t.Setenv("ALERT_NOTIFIER_ONCALL_ROUTING_KEY", "routing-key")
+	t.Setenv("ALERT_NOTIFIER_OPS_TYPE", "email")
+	t.Setenv("ALERT_NOTIFIER_OPS_TO", "ops@example.com, gpu@example.com")
+	t.Setenv("ALERT_ESCALATE_AFTER", "30m")
+	t.Setenv("ALERT_ESCALATE_TO", "oncall")
+	t.Setenv("ALERT_LEADER_ELECTION", "false")
+
+	cfg := DefaultAPIConfig()
+	if len(cfg.Alerts.Notifiers) != 2 {
+		t.Fatalf("expected 2 alert notifiers, got %d", len(cfg.Alerts.Notifiers))
+	}
+	if ops := cfg.Alerts.Notifiers[1]; ops.Type != "email" || len(ops.To) != 2 {
+		t.Errorf("unexpected ops notifier: %+v", ops)
+	}
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "smtp_host") {
+		t.Errorf("expected email notifier to require smtp_host, got %v", err)
+	}
+
+	cfg.Scheduler.SMTPHost = "smtp.example.com"
+	cfg.Scheduler.SMTPFrom = "alerts@example.com"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid alert config, got %v", err)
+	}
+
+	cfg.Alerts.EscalateTo = []string{"manager"}
+	cfg.Alerts.Notifiers[0].RoutingKey = ""
+	cfg.Alerts.Notifiers[1].Type = "sms"
+	cfg.CacheSource = "off"
+	err = cfg.Validate()
+	for _, want := range []string{"unknown notifier \"manager\"", "oncall.routing_key", "ops.type", "cache_source"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got %v", want, err)
+		}
+	}
+}
+
+func TestAlertMaintenanceKinds(t *testing.T) {
+	if kinds := DefaultAlertConfig().MaintenanceKinds; len(kinds) != 2 || kinds[0] != "maintenance" || kinds[1] != "driver_upgrade" {
+		t.Errorf("unexpected default maintenance kinds %v", kinds)
+	}
+
+	t.Setenv("ALERT_MAINTENANCE_KINDS", "none")
+	if kinds := DefaultAlertConfig().MaintenanceKinds; kinds != nil {
+		t.Errorf("expected none to disable suppression, got %v", kinds)
+	}
+
+	t.Setenv("ALERT_MAINTENANCE_KINDS", "maintenance,outage")
+	cfg := DefaultAPIConfig()
+	cfg.Alerts.Enabled = true
+	cfg.Alerts.LeaderElection = false
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), `"outage" is not an annotation kind`) {
+		t.Errorf("expected an unknown kind error, got %v", err)
+	}
+}
+
+func TestWebhookConfigValidate(t *testing.T) {
+	cfg := DefaultCollectorConfig()
+	cfg.Webhooks.QueueSize = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("webhooks without URLs should skip webhook settings, got %v", err)
+	}
+
+	cfg.Webhooks.URLs = []string{"https://hooks.example.com/gpu", "hooks.example.com"}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected webhook validation errors")
+	}
+	for _, want := range []string{`"hooks.example.com"`, "queue_size"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestDefaultWebhookConfigEnvList(t *testing.T) {
+	t.Setenv("WEBHOOK_URLS", " http://a.example.com , ,http://b.example.com")
+	cfg := DefaultWebhookConfig()
+	if len(cfg.URLs) != 2 || cfg.URLs[1] != "http://b.example.com" {
+		t.Errorf("expected two trimmed URLs, got %q", cfg.URLs)
+	}
+	if cfg.Events != nil {
+		t.Errorf("expected no event filter by default, got %q", cfg.Events)
+	}
+}
+
+func TestMQServerConfigValidatePortClash(t *testing.T) {
+	cfg := DefaultMQServerConfig()
+	cfg.HTTPPort = cfg.TCPPort
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when TCP and HTTP ports clash")
+	}
+}
+
+func TestMQServerConfigWAL(t *testing.T) {
+	t.Setenv("MQ_DATA_DIR", "/var/lib/mq")
+	t.Setenv("MQ_FSYNC_POLICY", "always")
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.DataDir != "/var/lib/mq" || cfg.Queue.FsyncPolicy != "always" || cfg.Queue.SegmentBytes != 64<<20 {
+		t.Fatalf("unexpected WAL config %+v", cfg.Queue)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.Queue.FsyncPolicy = "sometimes"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "fsync_policy") {
+		t.Errorf("expected an fsync_policy error, got %v", err)
+	}
+	cfg.Queue.FsyncPolicy = "interval"
+	cfg.Queue.FsyncInterval = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "fsync_interval") {
+		t.Errorf("expected an fsync_interval error, got %v", err)
+	}
+	cfg.Queue.FsyncInterval = time.Second
+	cfg.Queue.SegmentBytes = 1024
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "segment_bytes") {
+		t.Errorf("expected a segment_bytes error, got %v", err)
+	}
+}
+
+func TestMQServerConfigRetention(t *testing.T) {
+	t.Setenv("MQ_RETENTION_MESSAGES", "500000")
+	t.Setenv("MQ_RETENTION_AGE", "24h")
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.RetentionMessages != 500000 || cfg.Queue.RetentionAge != 24*time.Hour || cfg.Queue.RetentionBytes != 1<<30 {
+		t.Fatalf("unexpected retention config %+v", cfg.Queue)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.Queue.RetentionBytes = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "retention_bytes") {
+		t.Errorf("expected a retention_bytes error, got %v", err)
+	}
+	cfg.Queue.RetentionBytes = 0
+	cfg.Queue.RetentionInterval = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "retention_interval") {
+		t.Errorf("expected a retention_interval error, got %v", err)
+	}
+}
+
+func TestMQServerConfigOverflow(t *testing.T) {
+	t.Setenv("MQ_MAX_MESSAGES", "1000000")
+	t.Setenv("MQ_OVERFLOW_POLICY", "block")
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.MaxMessages != 1000000 || cfg.Queue.MaxBytes != 0 || cfg.Queue.OverflowPolicy != "block" {
+		t.Fatalf("unexpected overflow config %+v", cfg.Queue)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.Queue.RetentionBytes = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "needs a retention limit") {
+		t.Errorf("expected blocking without retention to be rejected, got %v", err)
+	}
+	cfg.Queue.OverflowPolicy = "spill"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "overflow_policy") {
+		t.Errorf("expected an overflow_policy error, got %v", err)
+	}
+	cfg.Queue.OverflowPolicy = "drop_oldest"
+	cfg.Queue.MaxBytes = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "max_bytes") {
+		t.Errorf("expected a max_bytes error, got %v", err)
+	}
+}
+
+func TestMQServerConfigDedup(t *testing.T) {
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.DedupWindow != 5*time.Minute || cfg.Queue.DedupMaxKeys != 100000 {
+		t.Fatalf("unexpected dedup config %v %d", cfg.Queue.DedupWindow, cfg.Queue.DedupMaxKeys)
+	}
+
+	t.Setenv("MQ_DEDUP_WINDOW", "-1s")
+	cfg = DefaultMQServerConfig()
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dedup_window") {
+		t.Errorf("expected a dedup_window error, got %v", err)
+	}
+}
+
+func TestMQServerConfigDeadLetters(t *testing.T) {
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.MaxDeadLetters != 1000 {
+		t.Fatalf("expected 1000 dead letters kept by default, got %d", cfg.Queue.MaxDeadLetters)
+	}
+
+	t.Setenv("MQ_MAX_DEAD_LETTERS", "-1")
+	cfg = DefaultMQServerConfig()
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "max_dead_letters") {
+		t.Errorf("expected a max_dead_letters error, got %v", err)
+	}
+}
+
+func TestMQServerConfigWebSocketOrigins(t *testing.T) {
+	if cfg := DefaultMQServerConfig(); len(cfg.WebSocketOrigins) != 0 {
+		t.Fatalf("expected no origins allowed beyond the server's own, got %v", cfg.WebSocketOrigins)
+	}
+	t.Setenv("MQ_WS_ORIGINS", "https://dash.example.com, https://grafana.example.com")
+	cfg := DefaultMQServerConfig()
+	if len(cfg.WebSocketOrigins) != 2 || cfg.WebSocketOrigins[1] != "https://grafana.example.com" {
+		t.Errorf("unexpected origins %v", cfg.WebSocketOrigins)
+	}
+}
+
+func TestEncryptionConfig(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	t.Setenv("PAYLOAD_ENCRYPTION_KEYS", "k2:"+key+",k1:"+key)
+	t.Setenv("PAYLOAD_ENCRYPTION_REQUIRED", "true")
+	cfg := DefaultCollectorConfig()
+	if !cfg.Encryption.Enabled() || len(cfg.Encryption.Keys) != 2 || !cfg.Encryption.Required {
+		t.Fatalf("unexpected encryption config %+v", cfg.Encryption)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	for _, tc := range []struct {
+		enc  EncryptionConfig
+		want string
+	}{
+		{EncryptionConfig{Keys: []string{"k1:" + key}, KeysFile: "/etc/keys"}, "only one of"},
+		{EncryptionConfig{Keys: []string{"k1"}}, "ID:KEY"},
+		{EncryptionConfig{Keys: []string{"k1:" + base64.StdEncoding.EncodeToString(make([]byte, 20))}}, "16, 24 or 32 bytes"},
+		{EncryptionConfig{Keys: []string{"k1:" + key, "k1:" + key}}, "more than once"},
+		{EncryptionConfig{Required: true}, "encryption.required"},
+	} {
+		cfg.Encryption = tc.enc
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: expected an error containing %q, got %v", tc.enc, tc.want, err)
+		}
+	}
+}
+
+func TestSigningConfig(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	t.Setenv("PRODUCER_SIGNING_KEY", seed)
+	t.Setenv("PRODUCER_VERIFY_KEYS", "streamer-0:"+seed)
+	streamer := DefaultStreamerConfig()
+	if !streamer.Signing.Enabled() || streamer.Validate() != nil {
+		t.Fatalf("expected a valid signing config, got %+v: %v", streamer.Signing, streamer.Validate())
+	}
+	collector := DefaultCollectorConfig()
+	if !collector.Verification.Enabled() || collector.Verification.Policy != SignaturePolicyReject || collector.Validate() != nil {
+		t.Fatalf("expected a valid verification config, got %+v: %v", collector.Verification, collector.Validate())
+	}
+
+	streamer.Signing = SigningConfig{Key: base64.StdEncoding.EncodeToString(make([]byte, 16))}
+	if err := streamer.Validate(); err == nil || !strings.Contains(err.Error(), "32 or 64 bytes") {
+		t.Errorf("expected a key size error, got %v", err)
+	}
+	for _, tc := range []struct {
+		verify VerificationConfig
+		want   string
+	}{
+		{VerificationConfig{Policy: "warn"}, "verification.policy"},
+		{VerificationConfig{Keys: []string{"s:" + seed}, KeysFile: "/etc/producers", Policy: SignaturePolicyFlag}, "only one of"},
+		{VerificationConfig{Keys: []string{"s:" + seed, "s:" + seed}, Policy: SignaturePolicyFlag}, "more than once"},
+		{VerificationConfig{Keys: []string{"s:" + base64.StdEncoding.EncodeToString(make([]byte, 64))}, Policy: SignaturePolicyFlag}, "32 bytes"},
+	} {
+		collector.Verification = tc.verify
+		if err := collector.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: expected an error containing %q, got %v", tc.verify, tc.want, err)
+		}
+	}
+}
+
+func TestMQServerConfigSubscriberBudget(t *testing.T) {
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.SubscriberMaxPendingBytes != 64<<20 || cfg.Queue.SubscriberOverflowPolicy != "pause" {
+		t.Fatalf("unexpected subscriber budget %d %q", cfg.Queue.SubscriberMaxPendingBytes, cfg.Queue.SubscriberOverflowPolicy)
+	}
+
+	t.Setenv("MQ_SUBSCRIBER_OVERFLOW_POLICY", "drop")
+	cfg = DefaultMQServerConfig()
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "subscriber_overflow_policy") {
+		t.Errorf("expected a subscriber_overflow_policy error, got %v", err)
+	}
+}
+
+func TestMQServerConfigPublishLimits(t *testing.T) {
+	t.Setenv("MQ_CONN_PUBLISH_RATE", "50")
+	t.Setenv("MQ_GLOBAL_PUBLISH_BYTES_RATE", "1048576")
+	cfg := DefaultMQServerConfig()
+	if l := cfg.PublishLimits; l.ConnMessages != 50 || l.ConnBytes != 0 || l.GlobalMessages != 0 || l.GlobalBytes != 1<<20 {
+		t.Fatalf("unexpected publish limits %+v", l)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.PublishLimits.ConnBytes = -1
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "publish_limits.conn_bytes_per_sec") {
+		t.Errorf("expected a conn_bytes_per_sec error, got %v", err)
+	}
+}
+
+func TestPartitionsConfig(t *testing.T) {
+	t.Setenv("MQ_PARTITIONS", "4")
+	t.Setenv("COLLECTOR_PARTITIONS", "0, 2")
+	mqCfg := DefaultMQServerConfig()
+	if mqCfg.Queue.Partitions != 4 {
+		t.Fatalf("expected 4 partitions, got %d", mqCfg.Queue.Partitions)
+	}
+	mqCfg.Queue.Partitions = 0
+	if err := mqCfg.Validate(); err == nil || !strings.Contains(err.Error(), "queue.partitions") {
+		t.Errorf("expected a queue.partitions error, got %v", err)
+	}
+
+	cfg := DefaultCollectorConfig()
+	if len(cfg.SubscribePartitions) != 2 || cfg.SubscribePartitions[1] != 2 {
+		t.Fatalf("expected partitions [0 2], got %v", cfg.SubscribePartitions)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	cfg.SubscribePartitions = []int{1, 1}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Errorf("expected a duplicate partition error, got %v", err)
+	}
+	cfg.SubscribePartitions = []int{1}
+	cfg.Group = "collectors"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "group assigns them") {
+		t.Errorf("expected listed partitions to be rejected for a group member, got %v", err)
+	}
+}
+
+func TestAutoCommitIntervalConfig(t *testing.T) {
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.AutoCommitInterval != 0 {
+		t.Fatalf("expected auto-commit to be off by default, got %v", cfg.Queue.AutoCommitInterval)
+	}
+
+	t.Setenv("MQ_AUTO_COMMIT_INTERVAL", "5s")
+	cfg = DefaultMQServerConfig()
+	if cfg.Queue.AutoCommitInterval != 5*time.Second {
+		t.Fatalf("expected a 5s auto-commit interval, got %v", cfg.Queue.AutoCommitInterval)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	cfg.Queue.AutoCommitInterval = -time.Second
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "queue.auto_commit_interval") {
+		t.Errorf("expected a queue.auto_commit_interval error, got %v", err)
+	}
+}
+
+func TestAckTimeoutConfig(t *testing.T) {
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.AckTimeout != 30*time.Second {
+		t.Fatalf("expected a 30s ack timeout by default, got %v", cfg.Queue.AckTimeout)
+	}
+
+	t.Setenv("MQ_ACK_TIMEOUT", "0s")
+	cfg = DefaultMQServerConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected untracked acks to be valid, got %v", err)
+	}
+	cfg.Queue.AckTimeout = -time.Second
+	cfg.Queue.MaxRetries = -1
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "queue.ack_timeout") || !strings.Contains(err.Error(), "queue.max_retries") {
+		t.Errorf("expected queue.ack_timeout and queue.max_retries errors, got %v", err)
+	}
+}
+
+func TestMQServerConfigMemoryBytes(t *testing.T) {
+	t.Setenv("MQ_MEMORY_BYTES", "1048576")
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.MemoryBytes != 1<<20 {
+		t.Fatalf("expected a 1MiB memory budget, got %d", cfg.Queue.MemoryBytes)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "queue.memory_bytes needs queue.data_dir") {
+		t.Errorf("expected a memory budget without a data dir refused, got %v", err)
+	}
+	cfg.Queue.DataDir = t.TempDir()
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a memory budget with a data dir to be valid, got %v", err)
+	}
+}
+
+func TestMQServerConfigRetryBackoff(t *testing.T) {
+	t.Setenv("MQ_RETRY_MULTIPLIER", "3")
+	t.Setenv("MQ_RETRY_MAX_DELAY", "1m")
+	cfg := DefaultMQServerConfig()
+	if cfg.Queue.RetryMultiplier != 3 || cfg.Queue.RetryMaxDelay != time.Minute || cfg.Queue.RetryJitter != 0.2 {
+		t.Fatalf("unexpected retry backoff %+v", cfg.Queue)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Queue.RetryMultiplier = 0.5
+	cfg.Queue.RetryJitter = 2
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "queue.retry_multiplier") || !strings.Contains(err.Error(), "queue.retry_jitter") {
+		t.Errorf("expected queue.retry_multiplier and queue.retry_jitter errors, got %v", err)
+	}
+}
+
+func TestMQServerConfigClientQuotas(t *testing.T) {
+	t.Setenv("MQ_CLIENT_MAX_IN_FLIGHT", "100")
+	t.Setenv("MQ_CLIENT_MAX_PUBLISH_BYTES", "65536")
+	t.Setenv("MQ_CLIENT_MAX_SUBSCRIPTIONS", "4")
+	cfg := DefaultMQServerConfig()
+	if q := cfg.Quotas; q.MaxInFlight != 100 || q.MaxPublishBytes != 65536 || q.MaxSubscriptions != 4 {
+		t.Fatalf("unexpected quotas %+v", q)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Quotas.MaxSubscriptions = -1
+	cfg.Queue.AckTimeout = 0
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "quotas.max_subscriptions") || !strings.Contains(err.Error(), "quotas.max_in_flight requires") {
+		t.Errorf("expected quotas.max_subscriptions and quotas.max_in_flight errors, got %v", err)
+	}
+}
+
+func TestMQServerConfigLogging(t *testing.T) {
+	t.Setenv("MQ_LOG_LEVEL", "debug")
+	t.Setenv("MQ_ADMIN_TOKEN", "0123456789abcdef")
+	cfg := DefaultMQServerConfig()
+	if cfg.LogLevel != "debug" || cfg.AdminToken != "0123456789abcdef" {
+		t.Fatalf("unexpected logging config %q/%q", cfg.LogLevel, cfg.AdminToken)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.LogLevel = "verbose"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "log_level") {
+		t.Errorf("expected a log_level error, got %v", err)
+	}
+	cfg.LogLevel = "info"
+	cfg.AdminToken = "short"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for short admin token")
+	}
+}
+
+func TestOTLPReceiverConfigValidate(t *testing.T) {
+	cfg := DefaultOTLPReceiverConfig()
+	if cfg.GRPCPort != 4317 || cfg.HTTPPort != 4318 {
+		t.Errorf("expected OTLP default ports 4317/4318, got %d/%d", cfg.GRPCPort, cfg.HTTPPort)
+	}
+
+	cfg.HTTPPort = cfg.GRPCPort
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when gRPC and HTTP ports clash")
+	}
+
+	cfg = DefaultOTLPReceiverConfig()
+	cfg.MaxRequestBytes = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for non-positive max_request_bytes")
+	}
+}
+
+func TestCheckPortConflicts(t *testing.T) {
+	if err := CheckPortConflicts(map[string]int{"a": 1, "b": 2}); err != nil {
+		t.Errorf("expected no conflict, got %v", err)
+	}
+
+	err := CheckPortConflicts(map[string]int{"api.port": 8080, "mq.http_port": 8080, "mq.tcp_port": 9000})
+	if err == nil {
+		t.Fatal("expected conflict")
+	}
+	if err.Error() != "port 8080 is used by api.port, mq.http_port" {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestDefaultRetryConfigEnvOverrides(t *testing.T) {
+	os.Setenv("TEST_RETRY_MAX_ATTEMPTS", "7")
+	os.Setenv("TEST_RETRY_INITIAL_BACKOFF", "250ms")
+	os.Setenv("TEST_RETRY_JITTER", "0.5")
+	defer func() {
+		os.Unsetenv("TEST_RETRY_MAX_ATTEMPTS")
+		os.Unsetenv("TEST_RETRY_INITIAL_BACKOFF")
+		os.Unsetenv("TEST_RETRY_JITTER")
+	}()
+
+	cfg := DefaultRetryConfig("TEST", RetryConfig{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second, Multiplier: 2})
+	if cfg.MaxAttempts != 7 {
+		t.Errorf("expected 7 attempts, got %d", cfg.MaxAttempts)
+	}
+	if cfg.InitialBackoff != 250*time.Millisecond {
+		t.Errorf("expected 250ms backoff, got %v", cfg.InitialBackoff)
+	}
+	if cfg.Jitter != 0.5 {
+		t.Errorf("expected jitter 0.5, got %v", cfg.Jitter)
+	}
+	if cfg.Multiplier != 2 {
+		t.Errorf("expected default multiplier 2, got %v", cfg.Multiplier)
+	}
+}
+
+func TestRetryConfigValidate(t *testing.T) {
+	cfg := DefaultStreamerConfig()
+	cfg.PublishRetry.Jitter = 2
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for jitter above 1")
+	}
+}
+
+func TestAPIConfigValidateAdminToken(t *testing.T) {
+	cfg := DefaultAPIConfig()
+	cfg.AdminToken = "short"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for short admin token")
+	}
+
+	cfg.AdminToken = "0123456789abcdef"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid admin token, got %v", err)
+	}
+
+	cfg.BundleKey = "short"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for short bundle key")
+	}
+}
+
+func TestAPIConfigTenantTokens(t *testing.T) {
+	t.Setenv("API_TENANT_TOKENS", "acme=acme-0123456789ab, globex = globex-0123456789,broken")
+	cfg := DefaultAPIConfig()
+	if len(cfg.TenantTokens) != 2 || cfg.TenantTokens["globex"] != "globex-0123456789" {
+		t.Fatalf("expected two trimmed tenant tokens, got %v", cfg.TenantTokens)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid tenant tokens, got %v", err)
+	}
+
+	cfg.TenantTokens = map[string]string{"anonymous": "0123456789abcdef"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("expected reserved tenant name error, got %v", err)
+	}
+	cfg.TenantTokens = map[string]string{"acme": "short"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for short tenant token")
+	}
+	cfg.AdminToken = "0123456789abcdef"
+	cfg.TenantTokens = map[string]string{"acme": cfg.AdminToken}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for a tenant token equal to the admin token")
+	}
+}
+
+func TestAPIConfigStatus(t *testing.T) {
+	t.Setenv("API_STATUS_ENABLED", "true")
+	t.Setenv("API_STATUS_METRICS", "DCGM_FI_DEV_GPU_TEMP")
+	cfg := DefaultAPIConfig()
+	if !cfg.Status.Enabled || len(cfg.Status.Metrics) != 1 || cfg.Status.Interval != 30*time.Second {
+		t.Fatalf("unexpected status config %+v", cfg.Status)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	cfg.CacheSource = "off"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for status without the latest-values cache")
+	}
+	cfg.CacheSource = "storage"
+	cfg.Status.StaleAfter = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for a zero stale_after")
+	}
+}
+
+func TestAPIConfigSummary(t *testing.T) {
+	cfg := DefaultAPIConfig()
+	if !cfg.Summary.Enabled || cfg.Summary.Refresh != 10*time.Minute || cfg.Summary.Days != 30 {
+		t.Fatalf("unexpected summary config %+v", cfg.Summary)
+	}
+
+	t.Setenv("API_SUMMARY_DAYS", "0")
+	cfg = DefaultAPIConfig()
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for zero summary days")
+	}
+	cfg.Summary.Enabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a disabled summary not to be validated, got %v", err)
+	}
+}
+
+func TestStateConfig(t *testing.T) {
+	cfg := DefaultAPIConfig()
+	if cfg.State.Backend != "" || cfg.State.RedisAddr != "localhost:6379" || cfg.State.RedisPrefix != "gpu-telemetry:" {
+		t.Fatalf("unexpected state config %+v", cfg.State)
+	}
+
+	t.Setenv("STATE_BACKEND", "file")
+	streamer := DefaultStreamerConfig()
+	if err := streamer.Validate(); err == nil || !strings.Contains(err.Error(), "state.dir") {
+		t.Errorf("expected a state.dir error, got %v", err)
+	}
+	t.Setenv("STATE_DIR", t.TempDir())
+	streamer = DefaultStreamerConfig()
+	if err := streamer.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	t.Setenv("STATE_BACKEND", "etcd")
+	cfg = DefaultAPIConfig()
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "state.backend") {
+		t.Errorf("expected a state.backend error, got %v", err)
+	}
+}
+
+func TestAPIConfigEnvironments(t *testing.T) {
+	t.Setenv("API_DEFAULT_ENVIRONMENT", "prod")
+	t.Setenv("API_ENVIRONMENTS", "dev,stage")
+	t.Setenv("API_ENV_DEV_BUCKET", "gpu_telemetry_dev")
+	t.Setenv("API_ENV_STAGE_BUCKET", "gpu_telemetry_stage")
+	t.Setenv("API_ENV_STAGE_URL", "http://influx-stage:8086")
+	t.Setenv("API_ENV_STAGE_TENANTS", "acme")
+	t.Setenv("API_TENANT_TOKENS", "acme=acme-token-0123456789")
+	cfg := DefaultAPIConfig()
+
+	if cfg.DefaultEnvironment != "prod" || len(cfg.Environments) != 2 {
+		t.Fatalf("unexpected environments %q %+v", cfg.DefaultEnvironment, cfg.Environments)
+	}
+	dev, stage := cfg.Environments[0], cfg.Environments[1]
+	if dev.Bucket != "gpu_telemetry_dev" || dev.URL != "http://localhost:8086" || len(dev.Tenants) != 0 {
+		t.Errorf("unexpected dev environment %+v", dev)
+	}
+	if stage.URL != "http://influx-stage:8086" || len(stage.Tenants) != 1 || stage.Tenants[0] != "acme" {
+		t.Errorf("unexpected stage environment %+v", stage)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg.Environments[0].Bucket = ""
+	cfg.Environments[1].Tenants = []string{"globex"}
+	cfg.Environments = append(cfg.Environments, EnvironmentConfig{Name: "prod", URL: "http://x", Bucket: "b"})
+	err := cfg.Validate()
+	for _, want := range []string{"environments.dev.bucket", "tenant \"globex\"", "\"prod\" is listed more than once"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected an error mentioning %s, got %v", want, err)
+		}
+	}
+}
+
+func TestAPIConfigSLO(t *testing.T) {
+	t.Setenv("API_SLO_ENABLED", "true")
+	t.Setenv("API_SLO_OBJECTIVE", "0.99")
+	cfg := DefaultAPIConfig()
+	if !cfg.SLO.Enabled || cfg.SLO.Objective != 0.99 || cfg.SLO.Latency != 30*time.Second || cfg.SLO.Window != 24*time.Hour {
+		t.Fatalf("unexpected slo config %+v", cfg.SLO)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	cfg.SLO.Objective = 1
+	cfg.SLO.LongWindow = 48 * time.Hour
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "slo.objective") || !strings.Contains(err.Error(), "slo windows") {
+		t.Errorf("expected objective and window errors, got %v", err)
+	}
+}
+
+func TestCollectorConfigCardinality(t *testing.T) {
+	t.Setenv("COLLECTOR_CARDINALITY_BUDGET", "50000")
+	t.Setenv("COLLECTOR_CARDINALITY_DROP", "true")
+	cfg := DefaultCollectorConfig()
+	if cfg.Cardinality.Budget != 50000 || !cfg.Cardinality.Drop || cfg.Cardinality.Window != time.Hour {
+		t.Fatalf("unexpected cardinality config %+v", cfg.Cardinality)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	cfg.Cardinality.Budget = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when dropping without a budget")
+	}
+}
+
+func TestCollectorConfigLateData(t *testing.T) {
+	t.Setenv("COLLECTOR_LATE_THRESHOLD", "6h")
+	t.Setenv("COLLECTOR_LATE_POLICY", "reroute")
+	cfg := DefaultCollectorConfig()
+	if cfg.LateData.Threshold != 6*time.Hour || cfg.LateData.Policy != LatePolicyReroute {
+		t.Fatalf("unexpected late data config %+v", cfg.LateData)
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when rerouting without a bucket")
+	}
+
+	cfg.LateData.Bucket = cfg.InfluxBucket
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error when rerouting to the telemetry bucket")
+	}
+	cfg.LateData.Bucket = "gpu_telemetry_late"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	cfg.LateData.Policy = "ignore"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for an unknown policy")
+	}
+}
+
+func TestCollectorConfigClockSkew(t *testing.T) {
+	cfg := DefaultCollectorConfig()
+	if cfg.ClockSkew.Threshold != 30*time.Second || cfg.ClockSkew.Policy != SkewPolicyAccept {
+		t.Fatalf("unexpected clock skew defaults %+v", cfg.ClockSkew)
+	}
+
+	t.Setenv("COLLECTOR_SKEW_THRESHOLD", "0s")
+	t.Setenv("COLLECTOR_SKEW_POLICY", "ignore")
+	err := DefaultCollectorConfig().Validate()
+	if err == nil || !strings.Contains(err.Error(), "clock_skew.threshold") || !strings.Contains(err.Error(), "clock_skew.policy") {
+		t.Errorf("expected threshold and policy errors, got %v", err)
+	}
+}
+
+func TestCollectorConfigHostAggregates(t *testing.T) {
+	t.Setenv("COLLECTOR_HOST_AGGREGATES", "sum:DCGM_FI_DEV_POWER_USAGE, mean:DCGM_FI_DEV_GPU_UTIL")
+	cfg := DefaultCollectorConfig()
+	want := []HostAggregate{{AggregateSum, "DCGM_FI_DEV_POWER_USAGE"}, {AggregateMean, "DCGM_FI_DEV_GPU_UTIL"}}
+	if !slices.Equal(cfg.HostAggregates, want) {
+		t.Fatalf("expected %v, got %v", want, cfg.HostAggregates)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	for _, bad := range []string{"DCGM_FI_DEV_POWER_USAGE", "median:DCGM_FI_DEV_POWER_USAGE", "sum:"} {
+		t.Setenv("COLLECTOR_HOST_AGGREGATES", bad)
+		if err := DefaultCollectorConfig().Validate(); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestAPIConfigPredict(t *testing.T) {
+	cfg := DefaultAPIConfig()
+	if cfg.Predict.Enabled || cfg.Predict.Interval != 15*time.Minute || cfg.Predict.Window != 6*time.Hour || cfg.Predict.Samples != 72 || cfg.Predict.Model != "rules" {
+		t.Fatalf("unexpected predict config %+v", cfg.Predict)
+	}
+
+	t.Setenv("API_PREDICT_ENABLED", "true")
+	t.Setenv("API_PREDICT_LEADER_ELECTION", "false")
+	t.Setenv("API_PREDICT_MODEL", "logistic")
+	cfg = DefaultAPIConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	cfg.Predict.Samples = 1
+	cfg.Predict.Model = ""
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "predict.samples") || !strings.Contains(err.Error(), "predict.model") {
+		t.Errorf("expected samples and model errors, got %v", err)
+	}
+	cfg.Predict.Samples = 72
+	cfg.Predict.Model = "rules"
+	cfg.Predict.Window = time.Minute
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "predict.window") {
+		t.Errorf("expected a window error, got %v", err)
+	}
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Components that read their configuration from the environment.
+const (
+	ComponentAPI          = "api"
+	ComponentCollector    = "collector"
+	ComponentStreamer     = "streamer"
+	ComponentMQServer     = "mq-server"
+	ComponentOTLPReceiver = "otlp-receiver"
+)
+
+// Components lists every component, for tools that migrate all of them.
+var Components = []string{ComponentAPI, ComponentCollector, ComponentStreamer, ComponentMQServer, ComponentOTLPReceiver}
+
+// Deprecation is an environment variable a component no longer reads.
+type Deprecation struct {
+	// Env is the deprecated variable
+	Env string
+
+	// Components lists the components it was read by
+	Components []string
+
+	// Replacements are the variables that took its place, in the order
+	// migrate returns them; empty when the setting was removed
+	Replacements []string
+
+	// Note says what to do instead when there is no replacement
+	Note string
+
+	// migrate maps the deprecated value to the replacements' values; nil
+	// copies it to the single replacement
+	migrate func(value string) (map[string]string, error)
+}
+
+// Deprecations lists the deprecated variables, oldest first.
+var Deprecations = []Deprecation{
+	{Env: "PORT", Components: []string{ComponentAPI}, Replacements: []string{"API_PORT"}},
+	{Env: "MAX_PAGE_SIZE", Components: []string{ComponentAPI}, Replacements: []string{"MAX_LIMIT"}},
+	{Env: "ENABLE_SWAGGER", Components: []string{ComponentAPI},
+		Note: "the Swagger UI is always served at /swagger/"},
+	{Env: "GIN_MODE", Components: []string{ComponentAPI},
+		Note: "the API no longer uses Gin; set API_LOG_LEVEL for debug logging"},
+	{Env: "STORAGE_TYPE", Components: []string{ComponentAPI, ComponentCollector},
+		Note: "telemetry is always stored in InfluxDB; set INFLUXDB_URL and INFLUXDB_BUCKET"},
+	{Env: "INSTANCE_ID", Components: []string{ComponentStreamer}, Replacements: []string{"STREAMER_ID"}},
+	{Env: "MQ_TOPIC", Components: []string{ComponentStreamer, ComponentCollector},
+		Note: "the MQ has a single log; split it with MQ_PARTITIONS or filter subscribers instead"},
+	{Env: "TCP_ADDR", Components: []string{ComponentMQServer}, Replacements: []string{"TCP_HOST", "TCP_PORT"},
+		migrate: splitAddr("TCP_HOST", "TCP_PORT")},
+	{Env: "HTTP_ADDR", Components: []string{ComponentMQServer}, Replacements: []string{"HTTP_HOST", "HTTP_PORT"},
+		migrate: splitAddr("HTTP_HOST", "HTTP_PORT")},
+	{Env: "BUFFER_SIZE", Components: []string{ComponentMQServer}, Replacements: []string{"MQ_BUFFER_SIZE"}},
+
+	// MQConfig was split into MQClientConfig and MQQueueConfig; clients
+	// still read the combined config but ignore the queue's settings
+	{Env: "MQ_BUFFER_SIZE", Components: []string{ComponentAPI, ComponentCollector, ComponentStreamer, ComponentOTLPReceiver},
+		Note: "it sizes the MQ server's queue and has no effect on clients; set it on the mq-server only"},
+}
+
+// splitAddr returns a migration of a host:port address to separate host and
+// port variables. An empty host listens on all interfaces.
+func splitAddr(hostEnv, portEnv string) func(string) (map[string]string, error) {
+	return func(value string) (map[string]string, error) {
+		host, port, err := net.SplitHostPort(value)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("port %q is not a number", port)
+		}
+		if host == "" {
+			host = "0.0.0.0"
+		}
+		return map[string]string{hostEnv: host, portEnv: port}, nil
+	}
+}
+
+// appliesTo reports whether component read the deprecated variable; an
+// empty component matches every one.
+func (d Deprecation) appliesTo(component string) bool {
+	if component == "" {
+		return true
+	}
+	for _, c := range d.Components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// Warning reports a deprecated variable found in a configuration.
+type Warning struct {
+	// Setting is the deprecated variable and Value its value
+	Setting string `json:"setting"`
+	Value   string `json:"value"`
+
+	// Replacement holds the variables to set instead, with their values;
+	// empty when the setting was removed or its value could not be mapped
+	Replacement map[string]string `json:"replacement,omitempty"`
+
+	// Applied is true when the replacement was set from the deprecated
+	// value; otherwise the deprecated value is ignored
+	Applied bool `json:"applied"`
+
+	// Message says what to do, naming the exact replacement
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("deprecated setting %s=%s: %s", w.Setting, w.Value, w.Message)
+}
+
+// MigrateEnv finds the deprecated variables component reads in env and
+// maps each to its replacement. It returns the replacements to set and a
+// warning for each deprecated variable, in the order of Deprecations. A
+// replacement already in env wins over the deprecated variable. An empty
+// component checks the deprecations of every component.
+func MigrateEnv(component string, env map[string]string) (map[string]string, []Warning) {
+	set := make(map[string]string)
+	var warnings []Warning
+	for _, d := range Deprecations {
+		value, ok := env[d.Env]
+		if !ok || !d.appliesTo(component) {
+			continue
+		}
+		w := Warning{Setting: d.Env, Value: value}
+		if len(d.Replacements) == 0 {
+			w.Message = "no longer read; " + d.Note
+			warnings = append(warnings, w)
+			continue
+		}
+
+		var already []string
+		for _, name := range d.Replacements {
+			if existing, ok := env[name]; ok {
+				already = append(already, name+"="+existing)
+			}
+		}
+		if len(already) > 0 {
+			w.Message = fmt.Sprintf("ignored because %s is set; remove %s", strings.Join(already, " and "), d.Env)
+			warnings = append(warnings, w)
+			continue
+		}
+
+		replacement := map[string]string{d.Replacements[0]: value}
+		if d.migrate != nil {
+			var err error
+			if replacement, err = d.migrate(value); err != nil {
+				w.Message = fmt.Sprintf("ignored because it cannot be mapped to %s (%v)", strings.Join(d.Replacements, " and "), err)
+				warnings = append(warnings, w)
+				continue
+			}
+		}
+		assignments := make([]string, 0, len(d.Replacements))
+		for _, name := range d.Replacements {
+			set[name] = replacement[name]
+			assignments = append(assignments, name+"="+replacement[name])
+		}
+		w.Replacement = replacement
+		w.Applied = true
+		w.Message = "set " + strings.Join(assignments, " and ") + " instead"
+		warnings = append(warnings, w)
+	}
+	return set, warnings
+}
+
+// Migrate maps the deprecated variables component reads to their
+// replacements in the process environment, so that the Default*Config
+// functions called after it see them, and returns a warning for each.
+func Migrate(component string) []Warning {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+	set, warnings := MigrateEnv(component, env)
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		os.Setenv(name, set[name])
+	}
+	return warnings
+}
+
+// MigrateEnvFile rewrites an env file (KEY=VALUE lines, optionally
+// prefixed by export, as read by docker --env-file or a shell) for
+// component. Each migrated variable is replaced by its replacements, in
+// the same quoting; other deprecated variables are commented out with the
+// reason. Comments, blank lines and other variables are kept as they are.
+func MigrateEnvFile(component string, data []byte) ([]byte, []Warning, error) {
+	type entry struct {
+		export, name, value string
+		quote               byte
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	entries := make([]*entry, len(lines))
+	env := make(map[string]string)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		e := &entry{}
+		if rest, ok := strings.CutPrefix(trimmed, "export "); ok {
+			e.export, trimmed = "export ", strings.TrimSpace(rest)
+		}
+		name, value, ok := strings.Cut(trimmed, "=")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, nil, fmt.Errorf("line %d: expected KEY=VALUE, got %q", i+1, trimmed)
+		}
+		if n := len(value); n >= 2 && (value[0] == '"' || value[0] == '\'') && value[n-1] == value[0] {
+			e.quote, value = value[0], value[1:n-1]
+		}
+		e.name, e.value = name, value
+		entries[i] = e
+		env[name] = value
+	}
+
+	_, warnings := MigrateEnv(component, env)
+	bySetting := make(map[string]Warning, len(warnings))
+	for _, w := range warnings {
+		bySetting[w.Setting] = w
+	}
+	replacements := make(map[string][]string, len(Deprecations))
+	for _, d := range Deprecations {
+		replacements[d.Env] = d.Replacements
+	}
+
+	var out strings.Builder
+	for i, line := range lines {
+		e := entries[i]
+		var w Warning
+		var deprecated bool
+		if e != nil {
+			w, deprecated = bySetting[e.name]
+		}
+		if !deprecated {
+			out.WriteString(line)
+			continue
+		}
+		newline := ""
+		if strings.HasSuffix(line, "\n") {
+			newline = "\n"
+		}
+		if !w.Applied {
+			fmt.Fprintf(&out, "# %s is deprecated: %s\n# %s%s", e.name, w.Message, strings.TrimSpace(line), newline)
+			continue
+		}
+		for j, name := range replacements[e.name] {
+			value := w.Replacement[name]
+			if e.quote != 0 {
+				value = string(e.quote) + value + string(e.quote)
+			}
+			fmt.Fprintf(&out, "%s%s=%s", e.export, name, value)
+			if j < len(replacements[e.name])-1 {
+				out.WriteString("\n")
+			}
+		}
+		out.WriteString(newline)
+	}
+	return []byte(out.String()), warnings, nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrateEnv(t *testing.T) {
+	env := map[string]string{
+		"PORT":           "9090",
+		"MAX_PAGE_SIZE":  "500",
+		"MAX_LIMIT":      "2000",
+		"STORAGE_TYPE":   "influxdb",
+		"TCP_ADDR":       ":9000",
+		"MQ_BUFFER_SIZE": "500",
+	}
+
+	set, warnings := MigrateEnv(ComponentAPI, env)
+	if len(set) != 1 || set["API_PORT"] != "9090" {
+		t.Errorf("expected only API_PORT=9090 to be set, got %v", set)
+	}
+	want := []string{
+		"deprecated setting PORT=9090: set API_PORT=9090 instead",
+		"deprecated setting MAX_PAGE_SIZE=500: ignored because MAX_LIMIT=2000 is set; remove MAX_PAGE_SIZE",
+		"deprecated setting STORAGE_TYPE=influxdb: no longer read; telemetry is always stored in InfluxDB; set INFLUXDB_URL and INFLUXDB_BUCKET",
+		"deprecated setting MQ_BUFFER_SIZE=500: no longer read; it sizes the MQ server's queue and has no effect on clients; set it on the mq-server only",
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("expected %d warnings, got %v", len(want), warnings)
+	}
+	for i, w := range warnings {
+		if w.String() != want[i] {
+			t.Errorf("warning %d = %q, want %q", i, w, want[i])
+		}
+	}
+	if !warnings[0].Applied || warnings[1].Applied {
+		t.Errorf("expected only the PORT migration to be applied, got %+v", warnings)
+	}
+
+	// The MQ server reads TCP_ADDR, split into host and port, and its own MQ_BUFFER_SIZE
+	set, warnings = MigrateEnv(ComponentMQServer, env)
+	if len(warnings) != 1 || set["TCP_HOST"] != "0.0.0.0" || set["TCP_PORT"] != "9000" {
+		t.Errorf("expected TCP_ADDR split into TCP_HOST and TCP_PORT, got %v %v", set, warnings)
+	}
+	_, warnings = MigrateEnv(ComponentMQServer, map[string]string{"HTTP_ADDR": "9001"})
+	if len(warnings) != 1 || warnings[0].Applied || !strings.Contains(warnings[0].Message, "cannot be mapped to HTTP_HOST and HTTP_PORT") {
+		t.Errorf("expected a malformed address to be reported, got %v", warnings)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Setenv("INSTANCE_ID", "streamer-7")
+	t.Setenv("STREAMER_ID", "")
+	os.Unsetenv("STREAMER_ID")
+
+	warnings := Migrate(ComponentStreamer)
+	if len(warnings) != 1 || !warnings[0].Applied {
+		t.Fatalf("expected INSTANCE_ID to be migrated, got %v", warnings)
+	}
+	if cfg := DefaultStreamerConfig(); cfg.InstanceID != "streamer-7" {
+		t.Errorf("expected the migrated instance ID, got %q", cfg.InstanceID)
+	}
+}
+
+func TestMigrateEnvFile(t *testing.T) {
+	in := `# MQ server
+export TCP_ADDR=":9000"
+HTTP_ADDR=127.0.0.1:9001
+BUFFER_SIZE=20000
+
+MQ_TOPIC=telemetry.metrics
+MQ_RETENTION_AGE=24h
+`
+	out, warnings, err := MigrateEnvFile("", []byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# MQ server
+export TCP_HOST="0.0.0.0"
+export TCP_PORT="9000"
+HTTP_HOST=127.0.0.1
+HTTP_PORT=9001
+MQ_BUFFER_SIZE=20000
+
+# MQ_TOPIC is deprecated: no longer read; the MQ has a single log; split it with MQ_PARTITIONS or filter subscribers instead
+# MQ_TOPIC=telemetry.metrics
+MQ_RETENTION_AGE=24h
+`
+	if string(out) != want {
+		t.Errorf("unexpected rewrite:\n%s\nwant:\n%s", out, want)
+	}
+	if len(warnings) != 4 {
+		t.Errorf("expected 4 warnings, got %v", warnings)
+	}
+
+	if _, _, err := MigrateEnvFile("", []byte("not a setting\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected a parse error on line 1, got %v", err)
+	}
+}
@@ -0,0 +1,1094 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/logging"
+)
+
+// csvColumns are the telemetry CSV columns the streamer can parse.
+var csvColumns = []string{
+	"timestamp", "metric_name", "gpu_id", "device", "uuid", "modelname",
+	"hostname", "container", "pod", "namespace", "value", "labels_raw",
+}
+
+// Validate checks the streamer configuration for values that would prevent it from running.
+func (c StreamerConfig) Validate() error {
+	var errs []error
+	if c.Source != "file" {
+		// Site-specific sources read no file, so the input format does not apply
+		if c.Source == "" || strings.TrimLeft(strings.ToLower(c.Source), "abcdefghijklmnopqrstuvwxyz0123456789_-") != "" {
+			errs = append(errs, fmt.Errorf("source must be file or a registered source name, got %q", c.Source))
+		}
+	} else {
+		switch c.InputFormat {
+		case "auto", "csv", "prometheus":
+			if c.CSVPath == "" {
+				errs = append(errs, errors.New("csv_path must be set"))
+			}
+		case "none":
+			if !c.UDP.Enabled() {
+				errs = append(errs, errors.New("input_format none needs the UDP listener (udp.port) enabled"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("input_format must be auto, csv, prometheus or none, got %q", c.InputFormat))
+		}
+		for _, col := range c.CSVColumns {
+			if !slices.Contains(csvColumns, strings.ToLower(col)) {
+				errs = append(errs, fmt.Errorf("csv_columns: unknown column %q (want one of %s)", col, strings.Join(csvColumns, ", ")))
+			}
+		}
+	}
+	if c.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("batch_size must be positive, got %d", c.BatchSize))
+	}
+	if c.CollectInterval <= 0 {
+		errs = append(errs, fmt.Errorf("collect_interval must be positive, got %v", c.CollectInterval))
+	}
+	if c.StreamInterval <= 0 {
+		errs = append(errs, fmt.Errorf("stream_interval must be positive, got %v", c.StreamInterval))
+	}
+	errs = append(errs, validateMQEndpoint(c.MQ))
+	errs = append(errs, c.PublishRetry.validate("publish_retry"))
+	if c.UDP.Enabled() {
+		errs = append(errs, c.UDP.validate())
+	}
+	if c.HealthPort != 0 {
+		errs = append(errs, validatePort("health_port", c.HealthPort))
+	}
+	errs = append(errs, c.State.validate())
+	errs = append(errs, c.Encryption.validate())
+	errs = append(errs, c.Signing.validate())
+	return errors.Join(errs...)
+}
+
+// validate checks the UDP listener settings.
+func (c UDPIngestConfig) validate() error {
+	var errs []error
+	errs = append(errs, validatePort("udp.port", c.Port))
+	// 65507 is the largest UDP payload over IPv4
+	if c.MaxDatagramSize <= 0 || c.MaxDatagramSize > 65507 {
+		errs = append(errs, fmt.Errorf("udp.max_datagram_size must be between 1 and 65507, got %d", c.MaxDatagramSize))
+	}
+	if c.ReadBuffer < 0 {
+		errs = append(errs, fmt.Errorf("udp.read_buffer must not be negative, got %d", c.ReadBuffer))
+	}
+	if c.MaxBuffered <= 0 {
+		errs = append(errs, fmt.Errorf("udp.max_buffered must be positive, got %d", c.MaxBuffered))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the collector configuration, including the relationship
+// between the retention period and the flush interval.
+func (c CollectorConfig) Validate() error {
+	var errs []error
+	if c.InstanceID == "" {
+		errs = append(errs, errors.New("instance_id must be set"))
+	}
+	switch c.Source {
+	case "mq":
+		errs = append(errs, validateMQEndpoint(c.MQ))
+		if c.CommitInterval <= 0 {
+			errs = append(errs, fmt.Errorf("commit_interval must be positive, got %v", c.CommitInterval))
+		}
+		if c.Workers < 0 {
+			errs = append(errs, fmt.Errorf("workers must not be negative, got %d", c.Workers))
+		}
+	case "kafka":
+		errs = append(errs, c.Kafka.validate())
+	default:
+		errs = append(errs, fmt.Errorf("source must be mq or kafka, got %q", c.Source))
+	}
+	if c.IngestStatsInterval < 0 {
+		errs = append(errs, fmt.Errorf("ingest_stats_interval must not be negative, got %v", c.IngestStatsInterval))
+	}
+	errs = append(errs, validateInfluxURL(c.InfluxURL))
+	if c.InfluxOrg == "" {
+		errs = append(errs, errors.New("influx_org must be set"))
+	}
+	if c.InfluxBucket == "" {
+		errs = append(errs, errors.New("influx_bucket must be set"))
+	}
+	if c.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("flush_interval must be positive, got %v", c.FlushInterval))
+	}
+	seen := make(map[int]bool)
+	for _, p := range c.SubscribePartitions {
+		if p < 0 {
+			errs = append(errs, fmt.Errorf("subscribe_partitions must not be negative, got %d", p))
+		} else if seen[p] {
+			errs = append(errs, fmt.Errorf("subscribe_partitions lists partition %d more than once", p))
+		}
+		seen[p] = true
+	}
+	if c.Group != "" && len(c.SubscribePartitions) > 0 {
+		errs = append(errs, errors.New("subscribe_partitions cannot be set for a group member, the group assigns them"))
+	}
+	if c.RetentionPeriod <= 0 {
+		errs = append(errs, fmt.Errorf("retention_period must be positive, got %v", c.RetentionPeriod))
+	} else if c.RetentionPeriod < c.FlushInterval {
+		errs = append(errs, fmt.Errorf("retention_period (%v) is shorter than flush_interval (%v); data would expire before it is written",
+			c.RetentionPeriod, c.FlushInterval))
+	}
+	errs = append(errs, c.StoreRetry.validate("store_retry"))
+	errs = append(errs, c.Webhooks.validate())
+	errs = append(errs, c.Cardinality.validate())
+	errs = append(errs, c.LateData.validate(c.InfluxBucket))
+	errs = append(errs, c.ClockSkew.validate())
+	for _, a := range c.HostAggregates {
+		errs = append(errs, a.validate())
+	}
+	if c.HealthPort != 0 {
+		errs = append(errs, validatePort("health_port", c.HealthPort))
+	}
+	names := make(map[string]bool)
+	for _, sink := range c.Forward {
+		if names[sink.Name] {
+			errs = append(errs, fmt.Errorf("forward sink %q is listed more than once", sink.Name))
+		}
+		names[sink.Name] = true
+		errs = append(errs, sink.validate())
+	}
+	errs = append(errs, c.Encryption.validate())
+	errs = append(errs, c.Verification.validate())
+	return errors.Join(errs...)
+}
+
+// Validate checks the API configuration.
+func (c APIConfig) Validate() error {
+	var errs []error
+	errs = append(errs, validatePort("port", c.Port))
+	if c.DefaultLimit <= 0 {
+		errs = append(errs, fmt.Errorf("default_limit must be positive, got %d", c.DefaultLimit))
+	}
+	if c.MaxLimit < c.DefaultLimit {
+		errs = append(errs, fmt.Errorf("max_limit (%d) is lower than default_limit (%d)", c.MaxLimit, c.DefaultLimit))
+	}
+	switch c.CacheSource {
+	case "off":
+	case "storage", "mq":
+		if c.CacheRefreshInterval <= 0 {
+			errs = append(errs, fmt.Errorf("cache_refresh_interval must be positive, got %v", c.CacheRefreshInterval))
+		}
+		if c.CacheWindow <= 0 {
+			errs = append(errs, fmt.Errorf("cache_window must be positive, got %v", c.CacheWindow))
+		}
+		if c.CacheSource == "mq" {
+			errs = append(errs, validateMQEndpoint(c.MQ))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("cache_source must be storage, mq or off, got %q", c.CacheSource))
+	}
+	if c.Scheduler.Enabled {
+		errs = append(errs, c.Scheduler.validate())
+		if c.Scheduler.LeaderElection && c.CacheSource != "mq" {
+			errs = append(errs, validateMQEndpoint(c.MQ))
+		}
+	}
+	errs = append(errs, c.Webhooks.validate())
+	if c.Alerts.Enabled {
+		errs = append(errs, c.Alerts.validate())
+		if c.CacheSource == "off" {
+			errs = append(errs, errors.New("alerts require cache_source storage or mq"))
+		}
+		if c.Alerts.LeaderElection && c.CacheSource != "mq" {
+			errs = append(errs, validateMQEndpoint(c.MQ))
+		}
+		for _, n := range c.Alerts.Notifiers {
+			if n.Type == "email" && c.Scheduler.SMTPHost == "" {
+				errs = append(errs, fmt.Errorf("alerts.notifiers.%s: email notifiers require smtp_host", n.Name))
+			}
+		}
+	}
+	if c.Baselines.Enabled {
+		errs = append(errs, c.Baselines.validate())
+	}
+	if c.Summary.Enabled {
+		errs = append(errs, c.Summary.validate())
+	}
+	if c.Status.Enabled {
+		errs = append(errs, c.Status.validate())
+		if c.CacheSource == "off" {
+			errs = append(errs, errors.New("status requires cache_source storage or mq"))
+		}
+		if !c.Alerts.Enabled && c.Status.LeaderElection && c.CacheSource != "mq" {
+			errs = append(errs, validateMQEndpoint(c.MQ))
+		}
+	}
+	if c.DefaultEnvironment == "" {
+		errs = append(errs, errors.New("default_environment must be set"))
+	}
+	envs := map[string]bool{c.DefaultEnvironment: true}
+	for _, e := range c.Environments {
+		if envs[e.Name] {
+			errs = append(errs, fmt.Errorf("environment %q is listed more than once", e.Name))
+		}
+		envs[e.Name] = true
+		errs = append(errs, e.validate(c.TenantTokens))
+	}
+	if c.SLO.Enabled {
+		errs = append(errs, c.SLO.validate())
+		if c.SLO.LeaderElection && c.CacheSource != "mq" {
+			errs = append(errs, validateMQEndpoint(c.MQ))
+		}
+	}
+	if c.Predict.Enabled {
+		errs = append(errs, c.Predict.validate())
+		if c.Predict.LeaderElection && c.CacheSource != "mq" {
+			errs = append(errs, validateMQEndpoint(c.MQ))
+		}
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < minAdminTokenLength {
+		errs = append(errs, fmt.Errorf("admin_token must be at least %d characters", minAdminTokenLength))
+	}
+	if c.BundleKey != "" && len(c.BundleKey) < minAdminTokenLength {
+		errs = append(errs, fmt.Errorf("bundle_key must be at least %d characters", minAdminTokenLength))
+	}
+	errs = append(errs, validateLogLevel(c.LogLevel))
+	for tenant, token := range c.TenantTokens {
+		switch {
+		case tenant == "admin" || tenant == "anonymous":
+			errs = append(errs, fmt.Errorf("tenant_tokens: tenant name %q is reserved", tenant))
+		case len(token) < minAdminTokenLength:
+			errs = append(errs, fmt.Errorf("tenant_tokens.%s must be at least %d characters", tenant, minAdminTokenLength))
+		case token == c.AdminToken:
+			errs = append(errs, fmt.Errorf("tenant_tokens.%s must differ from admin_token", tenant))
+		}
+	}
+	errs = append(errs, c.Usage.validate())
+	errs = append(errs, c.State.validate())
+	errs = append(errs, c.Encryption.validate())
+	return errors.Join(errs...)
+}
+
+// validate checks the GPU status settings.
+func (c StatusConfig) validate() error {
+	var errs []error
+	if c.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("status.interval must be positive, got %v", c.Interval))
+	}
+	if c.StaleAfter <= 0 {
+		errs = append(errs, fmt.Errorf("status.stale_after must be positive, got %v", c.StaleAfter))
+	}
+	if len(c.Metrics) == 0 {
+		errs = append(errs, errors.New("status.metrics must name at least one metric"))
+	}
+	if c.LeaderElection && c.LeaseTTL < 3*time.Second {
+		errs = append(errs, fmt.Errorf("status.lease_ttl must be at least 3s, got %v", c.LeaseTTL))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the ingest-latency objective settings.
+func (c SLOConfig) validate() error {
+	var errs []error
+	if c.Objective <= 0 || c.Objective >= 1 {
+		errs = append(errs, fmt.Errorf("slo.objective must be between 0 and 1, got %v", c.Objective))
+	}
+	if c.Latency <= 0 {
+		errs = append(errs, fmt.Errorf("slo.latency must be positive, got %v", c.Latency))
+	}
+	if c.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("slo.interval must be positive, got %v", c.Interval))
+	}
+	if c.BurnRate <= 0 {
+		errs = append(errs, fmt.Errorf("slo.burn_rate must be positive, got %v", c.BurnRate))
+	}
+	if c.ShortWindow < time.Minute || c.LongWindow <= c.ShortWindow || c.Window < c.LongWindow {
+		errs = append(errs, fmt.Errorf("slo windows must satisfy 1m <= short_window < long_window <= window, got %v, %v and %v",
+			c.ShortWindow, c.LongWindow, c.Window))
+	}
+	if c.LeaderElection && c.LeaseTTL < 3*time.Second {
+		errs = append(errs, fmt.Errorf("slo.lease_ttl must be at least 3s, got %v", c.LeaseTTL))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the usage metering settings.
+func (c UsageConfig) validate() error {
+	var errs []error
+	if c.File != "" && c.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("usage.flush_interval must be positive, got %v", c.FlushInterval))
+	}
+	if c.QuotaRequests < 0 || c.QuotaRows < 0 || c.QuotaExportBytes < 0 {
+		errs = append(errs, errors.New("usage quotas must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// minAdminTokenLength rejects admin and tenant tokens and bundle keys short
+// enough to guess.
+const minAdminTokenLength = 16
+
+// validate checks the cardinality budget.
+func (c CardinalityConfig) validate() error {
+	var errs []error
+	if c.Budget < 0 {
+		errs = append(errs, fmt.Errorf("cardinality.budget must not be negative, got %d", c.Budget))
+	}
+	if c.Window <= 0 {
+		errs = append(errs, fmt.Errorf("cardinality.window must be positive, got %v", c.Window))
+	}
+	if c.Drop && c.Budget == 0 {
+		errs = append(errs, errors.New("cardinality.drop requires a budget"))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the late-data settings; the reroute bucket must differ
+// from bucket, the one on-time telemetry is written to.
+func (c LateDataConfig) validate(bucket string) error {
+	var errs []error
+	if c.Threshold <= 0 {
+		errs = append(errs, fmt.Errorf("late_data.threshold must be positive, got %v", c.Threshold))
+	}
+	switch c.Policy {
+	case LatePolicyAccept, LatePolicyTag, LatePolicyDrop:
+	case LatePolicyReroute:
+		if c.Bucket == "" {
+			errs = append(errs, errors.New("late_data.bucket must be set for the reroute policy"))
+		} else if c.Bucket == bucket {
+			errs = append(errs, fmt.Errorf("late_data.bucket must differ from the telemetry bucket %q", bucket))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("late_data.policy must be accept, tag, reroute or drop, got %q", c.Policy))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the clock-skew settings.
+func (c ClockSkewConfig) validate() error {
+	var errs []error
+	if c.Threshold <= 0 {
+		errs = append(errs, fmt.Errorf("clock_skew.threshold must be positive, got %v", c.Threshold))
+	}
+	switch c.Policy {
+	case SkewPolicyAccept, SkewPolicyCorrect, SkewPolicyFlag, SkewPolicyReject:
+	default:
+		errs = append(errs, fmt.Errorf("clock_skew.policy must be accept, correct, flag or reject, got %q", c.Policy))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks a host aggregate names a known function and a metric.
+func (a HostAggregate) validate() error {
+	switch {
+	case a.Metric == "":
+		return fmt.Errorf("host_aggregates: %q must be func:metric", a.Func)
+	case a.Func != AggregateSum && a.Func != AggregateMean && a.Func != AggregateMin && a.Func != AggregateMax:
+		return fmt.Errorf("host_aggregates: %s:%s must aggregate with sum, mean, min or max", a.Func, a.Metric)
+	}
+	return nil
+}
+
+// validate checks the webhook settings. Nothing is checked when no URLs are
+// configured, since webhooks are then disabled.
+func (c WebhookConfig) validate() error {
+	if len(c.URLs) == 0 {
+		return nil
+	}
+	var errs []error
+	for _, raw := range c.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("webhooks.urls: %q is not an http(s) URL", raw))
+		}
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.timeout must be positive, got %v", c.Timeout))
+	}
+	if c.QueueSize <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.queue_size must be positive, got %d", c.QueueSize))
+	}
+	if c.LogSize <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.log_size must be positive, got %d", c.LogSize))
+	}
+	if c.SilenceAfter <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.silence_after must be positive, got %v", c.SilenceAfter))
+	}
+	if c.LagThreshold <= 0 {
+		errs = append(errs, fmt.Errorf("webhooks.lag_threshold must be positive, got %d", c.LagThreshold))
+	}
+	errs = append(errs, c.Retry.validate("webhooks.retry"))
+	return errors.Join(errs...)
+}
+
+// validate checks one environment, whose tenants must be among those
+// identified by tenantTokens.
+func (c EnvironmentConfig) validate(tenantTokens map[string]string) error {
+	name := "environments." + c.Name
+	var errs []error
+	if c.Name == "" || strings.TrimLeft(strings.ToLower(c.Name), "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+		errs = append(errs, fmt.Errorf("environment name %q must be letters, digits and underscores", c.Name))
+	}
+	if c.URL == "" {
+		errs = append(errs, fmt.Errorf("%s.url must be set", name))
+	}
+	if c.Bucket == "" {
+		errs = append(errs, fmt.Errorf("%s.bucket must be set", name))
+	}
+	for _, tenant := range c.Tenants {
+		if _, ok := tenantTokens[tenant]; !ok {
+			errs = append(errs, fmt.Errorf("%s.tenants: tenant %q has no token in tenant_tokens", name, tenant))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks one forwarding sink. The filter expression is checked when
+// the forwarder is created, since it is parsed by the MQ package.
+func (c ForwardSinkConfig) validate() error {
+	name := "forward." + c.Name
+	var errs []error
+	if c.Name == "" || strings.TrimLeft(strings.ToLower(c.Name), "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+		errs = append(errs, fmt.Errorf("forward sink name %q must be letters, digits and underscores", c.Name))
+	}
+	switch c.Type {
+	case "prometheus", "otlp":
+		if c.URL == "" {
+			errs = append(errs, fmt.Errorf("%s.url must be set", name))
+		}
+	case "datadog":
+		if c.APIKey == "" {
+			errs = append(errs, fmt.Errorf("%s.api_key must be set", name))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.type must be prometheus, datadog or otlp, got %q", name, c.Type))
+	}
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.url: %q is not an http(s) URL", name, c.URL))
+		}
+	}
+	for _, h := range c.Headers {
+		if key, _, ok := strings.Cut(h, "="); !ok || strings.TrimSpace(key) == "" {
+			errs = append(errs, fmt.Errorf("%s.headers: %q must be Name=value", name, h))
+		}
+	}
+	if c.BatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("%s.batch_size must be positive, got %d", name, c.BatchSize))
+	}
+	if c.QueueSize < c.BatchSize {
+		errs = append(errs, fmt.Errorf("%s.queue_size (%d) is lower than batch_size (%d)", name, c.QueueSize, c.BatchSize))
+	}
+	if c.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("%s.flush_interval must be positive, got %v", name, c.FlushInterval))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("%s.timeout must be positive, got %v", name, c.Timeout))
+	}
+	errs = append(errs, c.Retry.validate(name+".retry"))
+	return errors.Join(errs...)
+}
+
+// validate checks the alerting settings. Rule files and templates are checked
+// when the evaluator and notifiers are created.
+func (c AlertConfig) validate() error {
+	var errs []error
+	if c.EvalInterval <= 0 {
+		errs = append(errs, fmt.Errorf("alerts.eval_interval must be positive, got %v", c.EvalInterval))
+	}
+	if c.RepeatInterval <= 0 {
+		errs = append(errs, fmt.Errorf("alerts.repeat_interval must be positive, got %v", c.RepeatInterval))
+	}
+	if c.EscalateAfter < 0 {
+		errs = append(errs, fmt.Errorf("alerts.escalate_after must not be negative, got %v", c.EscalateAfter))
+	}
+	if c.EscalateAfter > 0 && len(c.EscalateTo) == 0 {
+		errs = append(errs, errors.New("alerts.escalate_to must be set when escalate_after is"))
+	}
+	if c.LeaderElection && c.LeaseTTL < 3*time.Second {
+		errs = append(errs, fmt.Errorf("alerts.lease_ttl must be at least 3s, got %v", c.LeaseTTL))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("alerts.timeout must be positive, got %v", c.Timeout))
+	}
+	if c.QueueSize <= 0 {
+		errs = append(errs, fmt.Errorf("alerts.queue_size must be positive, got %d", c.QueueSize))
+	}
+	errs = append(errs, c.Retry.validate("alerts.retry"))
+
+	names := make(map[string]bool, len(c.Notifiers))
+	for _, n := range c.Notifiers {
+		if names[n.Name] {
+			errs = append(errs, fmt.Errorf("alerts.notifiers: duplicate notifier name %q", n.Name))
+		}
+		names[n.Name] = true
+		errs = append(errs, n.validate())
+	}
+	for _, name := range c.EscalateTo {
+		if !names[name] {
+			errs = append(errs, fmt.Errorf("alerts.escalate_to: unknown notifier %q", name))
+		}
+	}
+	for _, kind := range c.MaintenanceKinds {
+		switch kind {
+		case "maintenance", "driver_upgrade", "job_launch", "other":
+		default:
+			errs = append(errs, fmt.Errorf("alerts.maintenance_kinds: %q is not an annotation kind (maintenance, driver_upgrade, job_launch, other) or none", kind))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the baseline settings.
+func (c BaselineConfig) validate() error {
+	var errs []error
+	if len(c.Metrics) == 0 {
+		errs = append(errs, errors.New("baselines.metrics must name at least one metric"))
+	}
+	if c.Window <= 0 {
+		errs = append(errs, fmt.Errorf("baselines.window must be positive, got %v", c.Window))
+	}
+	if c.Refresh <= 0 {
+		errs = append(errs, fmt.Errorf("baselines.refresh must be positive, got %v", c.Refresh))
+	}
+	if c.MinSamples <= 0 {
+		errs = append(errs, fmt.Errorf("baselines.min_samples must be positive, got %d", c.MinSamples))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the fleet summary settings.
+func (c SummaryConfig) validate() error {
+	var errs []error
+	if c.Refresh <= 0 {
+		errs = append(errs, fmt.Errorf("summary.refresh must be positive, got %v", c.Refresh))
+	}
+	if c.Days <= 0 {
+		errs = append(errs, fmt.Errorf("summary.days must be positive, got %d", c.Days))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the failure prediction settings.
+func (c PredictConfig) validate() error {
+	var errs []error
+	if c.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("predict.interval must be positive, got %v", c.Interval))
+	}
+	if c.Samples < 2 {
+		errs = append(errs, fmt.Errorf("predict.samples must be at least 2, got %d", c.Samples))
+	} else if c.Window < time.Duration(c.Samples)*time.Second {
+		errs = append(errs, fmt.Errorf("predict.window must leave at least 1s between its %d samples, got %v", c.Samples, c.Window))
+	}
+	if c.Model == "" {
+		errs = append(errs, errors.New("predict.model must be set"))
+	}
+	if c.LeaderElection && c.LeaseTTL < 3*time.Second {
+		errs = append(errs, fmt.Errorf("predict.lease_ttl must be at least 3s, got %v", c.LeaseTTL))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the state store settings.
+func (c StateConfig) validate() error {
+	switch c.Backend {
+	case "":
+	case "file":
+		if c.Dir == "" {
+			return errors.New("state.dir must be set for the file backend")
+		}
+	case "redis":
+		if c.RedisAddr == "" {
+			return errors.New("state.redis_addr must be set for the redis backend")
+		}
+		if c.RedisDB < 0 {
+			return fmt.Errorf("state.redis_db must not be negative, got %d", c.RedisDB)
+		}
+	default:
+		return fmt.Errorf("state.backend must be file, redis or empty, got %q", c.Backend)
+	}
+	return nil
+}
+
+// ParseEncryptionKey parses an ID:KEY pair as written in
+// EncryptionConfig.Keys.
+func ParseEncryptionKey(s string) (string, []byte, error) {
+	id, encoded, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || id == "" {
+		return "", nil, errors.New("key must be written ID:KEY")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("key %s is not base64: %w", id, err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return "", nil, fmt.Errorf("key %s must be 16, 24 or 32 bytes, got %d", id, len(key))
+	}
+	return id, key, nil
+}
+
+// validate checks that at most one key source is set and that the inline
+// keys parse.
+func (c EncryptionConfig) validate() error {
+	var errs []error
+	sources := 0
+	for _, set := range []bool{len(c.Keys) > 0, c.KeysFile != "", c.KeyCommand != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		errs = append(errs, errors.New("encryption: set only one of keys, keys_file and key_command"))
+	}
+	ids := make(map[string]bool)
+	for _, k := range c.Keys {
+		id, _, err := ParseEncryptionKey(k)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("encryption.keys: %w", err))
+			continue
+		}
+		if ids[id] {
+			errs = append(errs, fmt.Errorf("encryption.keys lists key %s more than once", id))
+		}
+		ids[id] = true
+	}
+	if c.Required && sources == 0 {
+		errs = append(errs, errors.New("encryption.required needs keys, keys_file or key_command"))
+	}
+	return errors.Join(errs...)
+}
+
+// ParseSigningKey parses an Ed25519 private key as written in
+// SigningConfig.Key: its base64-encoded seed or full private key.
+func ParseSigningKey(s string) (ed25519.PrivateKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("signing key is not base64: %w", err)
+	}
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(key), nil
+	}
+	return nil, fmt.Errorf("signing key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(key))
+}
+
+// ParseVerifyKey parses a producer's public key as written in
+// VerificationConfig.Keys, ID:KEY.
+func ParseVerifyKey(s string) (string, ed25519.PublicKey, error) {
+	id, encoded, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || id == "" {
+		return "", nil, errors.New("key must be written ID:KEY")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("key %s is not base64: %w", id, err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return "", nil, fmt.Errorf("key %s must be %d bytes, got %d", id, ed25519.PublicKeySize, len(key))
+	}
+	return id, ed25519.PublicKey(key), nil
+}
+
+// validate checks that at most one key source is set and that an inline
+// key parses.
+func (c SigningConfig) validate() error {
+	if c.Key != "" && c.KeyFile != "" {
+		return errors.New("signing: set only one of key and key_file")
+	}
+	if c.Key != "" {
+		if _, err := ParseSigningKey(c.Key); err != nil {
+			return fmt.Errorf("signing.key: %w", err)
+		}
+	}
+	return nil
+}
+
+// validate checks the policy, that at most one registry source is set and
+// that the inline keys parse.
+func (c VerificationConfig) validate() error {
+	var errs []error
+	switch c.Policy {
+	case SignaturePolicyReject, SignaturePolicyFlag:
+	default:
+		errs = append(errs, fmt.Errorf("verification.policy must be reject or flag, got %q", c.Policy))
+	}
+	if len(c.Keys) > 0 && c.KeysFile != "" {
+		errs = append(errs, errors.New("verification: set only one of keys and keys_file"))
+	}
+	ids := make(map[string]bool)
+	for _, k := range c.Keys {
+		id, _, err := ParseVerifyKey(k)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("verification.keys: %w", err))
+			continue
+		}
+		if ids[id] {
+			errs = append(errs, fmt.Errorf("verification.keys lists producer %s more than once", id))
+		}
+		ids[id] = true
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks one alert notifier.
+func (c AlertNotifierConfig) validate() error {
+	name := "alerts.notifiers." + c.Name
+	var errs []error
+	if c.Name == "" || strings.TrimLeft(strings.ToLower(c.Name), "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+		errs = append(errs, fmt.Errorf("alert notifier name %q must be letters, digits and underscores", c.Name))
+	}
+	switch c.Type {
+	case "email":
+		if len(c.To) == 0 {
+			errs = append(errs, fmt.Errorf("%s.to must be set", name))
+		}
+	case "slack":
+		if c.URL == "" {
+			errs = append(errs, fmt.Errorf("%s.url must be set", name))
+		}
+	case "pagerduty":
+		if c.RoutingKey == "" {
+			errs = append(errs, fmt.Errorf("%s.routing_key must be set", name))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.type must be email, slack or pagerduty, got %q", name, c.Type))
+	}
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.url is not an http(s) URL", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the Kafka source settings.
+func (c KafkaConfig) validate() error {
+	var errs []error
+	if len(c.Brokers) == 0 {
+		errs = append(errs, errors.New("kafka.brokers must be set"))
+	}
+	for _, addr := range c.Brokers {
+		if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+			errs = append(errs, fmt.Errorf("kafka.brokers: %q is not a host:port address", addr))
+		}
+	}
+	if c.Topic == "" {
+		errs = append(errs, errors.New("kafka.topic must be set"))
+	}
+	switch c.Format {
+	case "json", "protobuf", "line":
+	default:
+		errs = append(errs, fmt.Errorf("kafka.format must be json, protobuf or line, got %q", c.Format))
+	}
+	switch c.StartOffset {
+	case "earliest", "latest":
+	default:
+		errs = append(errs, fmt.Errorf("kafka.start_offset must be earliest or latest, got %q", c.StartOffset))
+	}
+	if c.MaxWait <= 0 {
+		errs = append(errs, fmt.Errorf("kafka.max_wait must be positive, got %v", c.MaxWait))
+	}
+	if c.FetchMaxBytes <= 0 {
+		errs = append(errs, fmt.Errorf("kafka.fetch_max_bytes must be positive, got %d", c.FetchMaxBytes))
+	}
+	if c.CommitInterval <= 0 {
+		errs = append(errs, fmt.Errorf("kafka.commit_interval must be positive, got %v", c.CommitInterval))
+	}
+	if c.TLSCAFile != "" && !c.TLS {
+		errs = append(errs, errors.New("kafka.tls_ca_file requires kafka.tls"))
+	}
+	switch c.SASLMechanism {
+	case "":
+	case "plain", "scram-sha-256", "scram-sha-512":
+		if c.SASLUsername == "" {
+			errs = append(errs, fmt.Errorf("kafka.sasl_username must be set for sasl_mechanism %s", c.SASLMechanism))
+		}
+		if c.SASLMechanism == "plain" && !c.TLS {
+			errs = append(errs, errors.New("kafka.sasl_mechanism plain sends the password in the clear and requires kafka.tls"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("kafka.sasl_mechanism must be plain, scram-sha-256 or scram-sha-512, got %q", c.SASLMechanism))
+	}
+	errs = append(errs, c.Retry.validate("kafka.retry"))
+	return errors.Join(errs...)
+}
+
+// validate checks the scheduler settings.
+func (c SchedulerConfig) validate() error {
+	var errs []error
+	if c.Tick <= 0 {
+		errs = append(errs, fmt.Errorf("scheduler.tick must be positive, got %v", c.Tick))
+	}
+	if c.LeaderElection && c.LeaseTTL < 3*time.Second {
+		errs = append(errs, fmt.Errorf("scheduler.lease_ttl must be at least 3s, got %v", c.LeaseTTL))
+	}
+	if c.DeliveryTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("scheduler.delivery_timeout must be positive, got %v", c.DeliveryTimeout))
+	}
+	if c.SMTPHost != "" {
+		errs = append(errs, validatePort("scheduler.smtp_port", c.SMTPPort))
+		if c.SMTPFrom == "" {
+			errs = append(errs, errors.New("scheduler.smtp_from is required when smtp_host is set"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the MQ server configuration, including that the TCP and
+// HTTP listeners do not share a port.
+func (c MQServerConfig) Validate() error {
+	var errs []error
+	errs = append(errs, validatePort("tcp_port", c.TCPPort))
+	errs = append(errs, validatePort("http_port", c.HTTPPort))
+	errs = append(errs, CheckPortConflicts(map[string]int{
+		"tcp_port":  c.TCPPort,
+		"http_port": c.HTTPPort,
+	}))
+	if c.Queue.BufferSize <= 0 {
+		errs = append(errs, fmt.Errorf("queue.buffer_size must be positive, got %d", c.Queue.BufferSize))
+	}
+	if c.Queue.ProbeInterval < 0 {
+		errs = append(errs, fmt.Errorf("queue.probe_interval must not be negative, got %v", c.Queue.ProbeInterval))
+	}
+	if c.Queue.DataDir != "" {
+		switch c.Queue.FsyncPolicy {
+		case "always", "never":
+		case "interval":
+			if c.Queue.FsyncInterval <= 0 {
+				errs = append(errs, fmt.Errorf("queue.fsync_interval must be positive, got %v", c.Queue.FsyncInterval))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("queue.fsync_policy must be always, interval or never, got %q", c.Queue.FsyncPolicy))
+		}
+		if c.Queue.SegmentBytes < 1<<20 {
+			errs = append(errs, fmt.Errorf("queue.segment_bytes must be at least 1MiB, got %d", c.Queue.SegmentBytes))
+		}
+	} else if c.Queue.MemoryBytes > 0 {
+		errs = append(errs, errors.New("queue.memory_bytes needs queue.data_dir to spill the log to"))
+	}
+	if c.Queue.MemoryBytes < 0 {
+		errs = append(errs, fmt.Errorf("queue.memory_bytes must not be negative, got %d", c.Queue.MemoryBytes))
+	}
+	if c.Queue.RetentionMessages < 0 {
+		errs = append(errs, fmt.Errorf("queue.retention_messages must not be negative, got %d", c.Queue.RetentionMessages))
+	}
+	if c.Queue.RetentionBytes < 0 {
+		errs = append(errs, fmt.Errorf("queue.retention_bytes must not be negative, got %d", c.Queue.RetentionBytes))
+	}
+	if c.Queue.RetentionAge < 0 {
+		errs = append(errs, fmt.Errorf("queue.retention_age must not be negative, got %v", c.Queue.RetentionAge))
+	}
+	if c.Queue.RetentionInterval <= 0 {
+		errs = append(errs, fmt.Errorf("queue.retention_interval must be positive, got %v", c.Queue.RetentionInterval))
+	}
+	if c.Queue.MaxMessages < 0 {
+		errs = append(errs, fmt.Errorf("queue.max_messages must not be negative, got %d", c.Queue.MaxMessages))
+	}
+	if c.Queue.MaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("queue.max_bytes must not be negative, got %d", c.Queue.MaxBytes))
+	}
+	switch c.Queue.OverflowPolicy {
+	case "reject", "drop_oldest":
+	case "block":
+		// Only retention makes room for a blocked publish
+		if (c.Queue.MaxMessages > 0 || c.Queue.MaxBytes > 0) &&
+			c.Queue.RetentionMessages == 0 && c.Queue.RetentionBytes == 0 && c.Queue.RetentionAge == 0 {
+			errs = append(errs, errors.New("queue.overflow_policy block needs a retention limit to make room in the log"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("queue.overflow_policy must be reject, block or drop_oldest, got %q", c.Queue.OverflowPolicy))
+	}
+	if c.Queue.Partitions < 1 {
+		errs = append(errs, fmt.Errorf("queue.partitions must be at least 1, got %d", c.Queue.Partitions))
+	}
+	if c.Queue.DedupWindow < 0 {
+		errs = append(errs, fmt.Errorf("queue.dedup_window must not be negative, got %v", c.Queue.DedupWindow))
+	}
+	if c.Queue.DedupMaxKeys < 0 {
+		errs = append(errs, fmt.Errorf("queue.dedup_max_keys must not be negative, got %d", c.Queue.DedupMaxKeys))
+	}
+	if c.Queue.MaxDeadLetters < 0 {
+		errs = append(errs, fmt.Errorf("queue.max_dead_letters must not be negative, got %d", c.Queue.MaxDeadLetters))
+	}
+	if c.Queue.SubscriberMaxPendingBytes < 0 {
+		errs = append(errs, fmt.Errorf("queue.subscriber_max_pending_bytes must not be negative, got %d", c.Queue.SubscriberMaxPendingBytes))
+	}
+	switch c.Queue.SubscriberOverflowPolicy {
+	case "pause", "skip_ahead", "disconnect":
+	default:
+		errs = append(errs, fmt.Errorf("queue.subscriber_overflow_policy must be pause, skip_ahead or disconnect, got %q", c.Queue.SubscriberOverflowPolicy))
+	}
+	if c.Queue.AutoCommitInterval < 0 {
+		errs = append(errs, fmt.Errorf("queue.auto_commit_interval must not be negative, got %v", c.Queue.AutoCommitInterval))
+	}
+	if c.Queue.AckTimeout < 0 {
+		errs = append(errs, fmt.Errorf("queue.ack_timeout must not be negative, got %v", c.Queue.AckTimeout))
+	}
+	if c.Queue.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("queue.max_retries must not be negative, got %d", c.Queue.MaxRetries))
+	}
+	if c.Queue.RetryMultiplier < 1 {
+		errs = append(errs, fmt.Errorf("queue.retry_multiplier must be at least 1, got %v", c.Queue.RetryMultiplier))
+	}
+	if c.Queue.RetryMaxDelay < 0 {
+		errs = append(errs, fmt.Errorf("queue.retry_max_delay must not be negative, got %v", c.Queue.RetryMaxDelay))
+	}
+	if c.Queue.RetryJitter < 0 || c.Queue.RetryJitter > 1 {
+		errs = append(errs, fmt.Errorf("queue.retry_jitter must be between 0 and 1, got %v", c.Queue.RetryJitter))
+	}
+	limits := c.PublishLimits
+	for _, l := range []struct {
+		name string
+		rate float64
+	}{
+		{"conn_messages_per_sec", limits.ConnMessages},
+		{"conn_bytes_per_sec", limits.ConnBytes},
+		{"global_messages_per_sec", limits.GlobalMessages},
+		{"global_bytes_per_sec", limits.GlobalBytes},
+	} {
+		if l.rate < 0 {
+			errs = append(errs, fmt.Errorf("publish_limits.%s must not be negative, got %g", l.name, l.rate))
+		}
+	}
+	quotas := c.Quotas
+	for _, q := range []struct {
+		name  string
+		limit int
+	}{
+		{"max_in_flight", quotas.MaxInFlight},
+		{"max_publish_bytes", quotas.MaxPublishBytes},
+		{"max_subscriptions", quotas.MaxSubscriptions},
+	} {
+		if q.limit < 0 {
+			errs = append(errs, fmt.Errorf("quotas.%s must not be negative, got %d", q.name, q.limit))
+		}
+	}
+	if quotas.MaxInFlight > 0 && c.Queue.AckTimeout <= 0 {
+		errs = append(errs, errors.New("quotas.max_in_flight requires queue.ack_timeout"))
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < minAdminTokenLength {
+		errs = append(errs, fmt.Errorf("admin_token must be at least %d characters", minAdminTokenLength))
+	}
+	if c.ReplicaOf != "" {
+		errs = append(errs, validateHostPort("replica_of", c.ReplicaOf))
+	}
+	if c.PromoteAfter < 0 {
+		errs = append(errs, fmt.Errorf("promote_after must not be negative, got %v", c.PromoteAfter))
+	}
+	errs = append(errs, validateLogLevel(c.LogLevel))
+	return errors.Join(errs...)
+}
+
+// Validate checks the OTLP receiver configuration, including that the gRPC
+// and HTTP listeners do not share a port.
+func (c OTLPReceiverConfig) Validate() error {
+	var errs []error
+	if c.InstanceID == "" {
+		errs = append(errs, errors.New("instance_id must be set"))
+	}
+	errs = append(errs, validatePort("grpc_port", c.GRPCPort))
+	errs = append(errs, validatePort("http_port", c.HTTPPort))
+	errs = append(errs, CheckPortConflicts(map[string]int{
+		"grpc_port": c.GRPCPort,
+		"http_port": c.HTTPPort,
+	}))
+	if c.MaxRequestBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max_request_bytes must be positive, got %d", c.MaxRequestBytes))
+	}
+	errs = append(errs, validateMQEndpoint(c.MQ))
+	errs = append(errs, c.PublishRetry.validate("publish_retry"))
+	return errors.Join(errs...)
+}
+
+// CheckPortConflicts reports listeners that are configured on the same port.
+// Keys name the listener (e.g., "mq.tcp_port") and are used in the error message.
+func CheckPortConflicts(ports map[string]int) error {
+	byPort := make(map[int][]string)
+	for name, port := range ports {
+		byPort[port] = append(byPort[port], name)
+	}
+
+	var errs []error
+	for port, names := range byPort {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		errs = append(errs, fmt.Errorf("port %d is used by %s", port, strings.Join(names, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks a retry policy; name prefixes the field names in errors.
+func (r RetryConfig) validate(name string) error {
+	var errs []error
+	if r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		errs = append(errs, fmt.Errorf("%s backoff must not be negative", name))
+	}
+	if r.MaxBackoff > 0 && r.MaxBackoff < r.InitialBackoff {
+		errs = append(errs, fmt.Errorf("%s.max_backoff (%v) is lower than initial_backoff (%v)", name, r.MaxBackoff, r.InitialBackoff))
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("%s.jitter must be between 0 and 1, got %v", name, r.Jitter))
+	}
+	return errors.Join(errs...)
+}
+
+func validateMQEndpoint(mq MQConfig) error {
+	if mq.Host == "" {
+		return errors.New("mq.host must be set")
+	}
+	errs := []error{validatePort("mq.port", mq.Port)}
+	for _, addr := range mq.Failover {
+		errs = append(errs, validateHostPort("mq.failover", addr))
+	}
+	switch mq.WireFormat {
+	case "json", "binary":
+	default:
+		errs = append(errs, fmt.Errorf("mq.wire_format must be json or binary, got %q", mq.WireFormat))
+	}
+	return errors.Join(errs...)
+}
+
+func validateHostPort(name, addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return fmt.Errorf("%s %q must be host:port", name, addr)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return fmt.Errorf("%s %q must be host:port", name, addr)
+	}
+	return validatePort(name+" port", n)
+}
+
+func validateLogLevel(level string) error {
+	if _, err := logging.ParseLevel(level); err != nil {
+		return fmt.Errorf("log_level: %w", err)
+	}
+	return nil
+}
+
+func validatePort(name string, port int) error {
+	if port <= 0 || port > 65535 {
+		return fmt.Errorf("%s must be between 1 and 65535, got %d", name, port)
+	}
+	return nil
+}
+
+func validateInfluxURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("influx_url %q must be an http(s) URL", raw)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Package errors provides a shared error taxonomy for pipeline components.
+//
+// Errors are classified by Kind so that retry loops can tell transient
+// failures (worth retrying) from permanent ones, and API handlers can map
+// failures to HTTP status codes. Classification survives wrapping with
+// fmt.Errorf("...: %w", err).
+package errors
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net"
+	"net/http"
+)
+
+// Kind classifies an error for retry and reporting decisions.
+type Kind int
+
+const (
+	// KindUnknown is an unclassified error. Retry loops treat it as retryable.
+	KindUnknown Kind = iota
+	// KindTransient is a temporary failure (timeouts, unavailable dependencies).
+	KindTransient
+	// KindPermanent is a failure that will not succeed on retry.
+	KindPermanent
+	// KindValidation is a rejected input or configuration.
+	KindValidation
+	// KindNotFound is a missing resource.
+	KindNotFound
+)
+
+// String returns the lowercase name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindTransient:
+		return "transient"
+	case KindPermanent:
+		return "permanent"
+	case KindValidation:
+		return "validation"
+	case KindNotFound:
+		return "not_found"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseKind returns the Kind named by s, or KindUnknown.
+func ParseKind(s string) Kind {
+	for _, k := range []Kind{KindTransient, KindPermanent, KindValidation, KindNotFound} {
+		if k.String() == s {
+			return k
+		}
+	}
+	return KindUnknown
+}
+
+// Error is a classified error.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// ErrorKind implements Classifier.
+func (e *Error) ErrorKind() Kind { return e.Kind }
+
+// Classifier is implemented by errors that carry their own Kind,
+// such as errors decoded from a remote peer.
+type Classifier interface {
+	ErrorKind() Kind
+}
+
+// New returns an error with the given kind and message.
+func New(kind Kind, message string) error {
+	return &Error{Kind: kind, Err: stderrors.New(message)}
+}
+
+// Wrap classifies err with kind. It returns nil if err is nil.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Transient marks err as a temporary failure.
+func Transient(err error) error { return Wrap(KindTransient, err) }
+
+// Permanent marks err as a failure that will not succeed on retry.
+func Permanent(err error) error { return Wrap(KindPermanent, err) }
+
+// Validation marks err as a rejected input.
+func Validation(err error) error { return Wrap(KindValidation, err) }
+
+// NotFound marks err as a missing resource.
+func NotFound(err error) error { return Wrap(KindNotFound, err) }
+
+// KindOf returns the kind of the outermost classified error in err's chain.
+// Unclassified context and network timeouts are reported as transient, and
+// context cancellation as permanent, since the caller has given up.
+func KindOf(err error) Kind {
+	if err == nil {
+		return KindUnknown
+	}
+
+	var c Classifier
+	if stderrors.As(err, &c) {
+		return c.ErrorKind()
+	}
+
+	if stderrors.Is(err, context.Canceled) {
+		return KindPermanent
+	}
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return KindTransient
+	}
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		return KindTransient
+	}
+	return KindUnknown
+}
+
+// IsTransient reports whether err is classified as transient.
+func IsTransient(err error) bool { return KindOf(err) == KindTransient }
+
+// IsPermanent reports whether err is classified as permanent.
+func IsPermanent(err error) bool { return KindOf(err) == KindPermanent }
+
+// IsValidation reports whether err is classified as a validation error.
+func IsValidation(err error) bool { return KindOf(err) == KindValidation }
+
+// IsNotFound reports whether err is classified as not found.
+func IsNotFound(err error) bool { return KindOf(err) == KindNotFound }
+
+// IsRetryable reports whether a retry loop should try again after err.
+// Transient and unclassified errors are retryable; everything else is not.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch KindOf(err) {
+	case KindTransient, KindUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Codes that errors carry to API clients when their Kind is too coarse for
+// a client to act on. The API's error catalog documents them.
+const (
+	// CodeInvalidRange is a malformed time, or a reversed or empty range.
+	CodeInvalidRange = "invalid_range"
+	// CodeQueryTooBroad is a query over more time, points or series than allowed.
+	CodeQueryTooBroad = "query_too_broad"
+)
+
+// codedError attaches a machine-readable code to an error, keeping its Kind.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// WithCode attaches code to err, which keeps its message and Kind. It
+// returns nil if err is nil.
+func WithCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// CodeOf returns the code of the first coded error in err's chain, or "".
+func CodeOf(err error) string {
+	var c *codedError
+	if stderrors.As(err, &c) {
+		return c.code
+	}
+	return ""
+}
+
+// HTTPStatus maps err to an HTTP status code.
+func HTTPStatus(err error) int {
+	switch KindOf(err) {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindTransient:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// WriteHTTP writes the API's standard error body, carrying code and a
+// human-readable message, with status. Handlers and middleware outside the
+// API handlers package use it so every error response has the same shape.
+func WriteHTTP(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "message": message})
+}
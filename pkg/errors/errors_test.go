@@ -0,0 +1,107 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKindOfSurvivesWrapping(t *testing.T) {
+	base := NotFound(stderrors.New("gpu missing"))
+	wrapped := fmt.Errorf("lookup: %w", base)
+
+	if KindOf(wrapped) != KindNotFound {
+		t.Errorf("expected not_found, got %s", KindOf(wrapped))
+	}
+	if !IsNotFound(wrapped) {
+		t.Error("expected IsNotFound to be true")
+	}
+	if wrapped.Error() != "lookup: gpu missing" {
+		t.Errorf("unexpected message: %s", wrapped.Error())
+	}
+}
+
+func TestWrapNil(t *testing.T) {
+	if Transient(nil) != nil {
+		t.Error("expected wrapping nil to return nil")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{stderrors.New("unclassified"), true},
+		{Transient(stderrors.New("timeout")), true},
+		{Permanent(stderrors.New("bad schema")), false},
+		{Validation(stderrors.New("bad input")), false},
+		{NotFound(stderrors.New("missing")), false},
+		{context.Canceled, false},
+		{fmt.Errorf("wait: %w", context.DeadlineExceeded), true},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := map[error]int{
+		Validation(stderrors.New("x")): http.StatusBadRequest,
+		NotFound(stderrors.New("x")):   http.StatusNotFound,
+		Transient(stderrors.New("x")):  http.StatusServiceUnavailable,
+		Permanent(stderrors.New("x")):  http.StatusInternalServerError,
+		stderrors.New("x"):             http.StatusInternalServerError,
+	}
+	for err, want := range tests {
+		if got := HTTPStatus(err); got != want {
+			t.Errorf("HTTPStatus(%s) = %d, want %d", KindOf(err), got, want)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for _, k := range []Kind{KindTransient, KindPermanent, KindValidation, KindNotFound} {
+		if ParseKind(k.String()) != k {
+			t.Errorf("ParseKind(%q) did not round-trip", k.String())
+		}
+	}
+	if ParseKind("bogus") != KindUnknown {
+		t.Error("expected unknown kind for bogus input")
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	err := WithCode(CodeInvalidRange, stderrors.New("end must be after start"))
+	wrapped := Validation(fmt.Errorf("query: %w", stderrors.Join(stderrors.New("bad step"), err)))
+	if got := CodeOf(wrapped); got != CodeInvalidRange {
+		t.Errorf("CodeOf = %q, want %q", got, CodeInvalidRange)
+	}
+	if !IsValidation(wrapped) || wrapped.Error() != "query: bad step\nend must be after start" {
+		t.Errorf("expected the kind and message to be kept, got %s %q", KindOf(wrapped), wrapped.Error())
+	}
+	if !IsNotFound(WithCode(CodeQueryTooBroad, NotFound(stderrors.New("x")))) {
+		t.Error("expected WithCode to keep the wrapped kind")
+	}
+	if CodeOf(stderrors.New("x")) != "" || WithCode(CodeInvalidRange, nil) != nil {
+		t.Error("expected no code for uncoded or nil errors")
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, http.StatusForbidden, "forbidden", "Token does not grant the admin role")
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("got %d %q, want 403 application/json", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if body := rec.Body.String(); body != `{"error":"forbidden","message":"Token does not grant the admin role"}`+"\n" {
+		t.Errorf("body = %q", body)
+	}
+}
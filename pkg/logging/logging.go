@@ -0,0 +1,180 @@
+// Package logging lets a component's log level and debug output be changed
+// while it runs, so incidents can be debugged without a restart.
+//
+// A Runtime wraps the component's logger. Everything the component logs
+// directly is info level; lines logged with Debugf appear only while the
+// level is debug. Toggles switch optional, usually expensive debug output
+// such as wire-protocol frame dumps, whatever the level.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Level is the minimum severity of lines that are logged.
+type Level int32
+
+// Levels from most to least verbose.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+)
+
+var levelNames = []string{"debug", "info"}
+
+// String returns the level's name.
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelInfo {
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name such as "debug", case-insensitively.
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (want one of %s)", s, strings.Join(levelNames, ", "))
+}
+
+// Toggle switches one kind of debug output. A nil Toggle is always off, so
+// code can check toggles that were never registered.
+type Toggle struct {
+	description string
+	logger      *log.Logger
+	on          atomic.Bool
+}
+
+// Enabled reports whether the toggle is on. Callers check it before
+// building expensive output.
+func (t *Toggle) Enabled() bool {
+	return t != nil && t.on.Load()
+}
+
+// Printf logs a debug line while the toggle is on, whatever the level.
+func (t *Toggle) Printf(format string, args ...any) {
+	if t.Enabled() {
+		t.logger.Output(2, "DEBUG "+fmt.Sprintf(format, args...))
+	}
+}
+
+// Runtime holds a component's log level and debug toggles.
+type Runtime struct {
+	logger *log.Logger
+	level  atomic.Int32
+
+	mu      sync.RWMutex
+	toggles map[string]*Toggle
+}
+
+// New wraps logger, logging at level until it is changed.
+func New(logger *log.Logger, level Level) *Runtime {
+	r := &Runtime{logger: logger, toggles: make(map[string]*Toggle)}
+	r.level.Store(int32(level))
+	return r
+}
+
+// Level returns the current level.
+func (r *Runtime) Level() Level {
+	return Level(r.level.Load())
+}
+
+// SetLevel changes the level, logging the change.
+func (r *Runtime) SetLevel(level Level) {
+	if previous := Level(r.level.Swap(int32(level))); previous != level {
+		r.logger.Printf("Log level changed from %s to %s", previous, level)
+	}
+}
+
+// Debugf logs a line while the level is debug. A nil Runtime logs nothing,
+// so parts can take one optionally.
+func (r *Runtime) Debugf(format string, args ...any) {
+	if r != nil && r.Level() <= LevelDebug {
+		r.logger.Output(2, "DEBUG "+fmt.Sprintf(format, args...))
+	}
+}
+
+// Toggle registers a debug toggle, initially off, or returns the one
+// already registered under name.
+func (r *Runtime) Toggle(name, description string) *Toggle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.toggles[name]; ok {
+		return t
+	}
+	t := &Toggle{description: description, logger: r.logger}
+	r.toggles[name] = t
+	return t
+}
+
+// ToggleState describes a debug toggle.
+type ToggleState struct {
+	Enabled     bool   `json:"enabled" example:"false"`
+	Description string `json:"description" example:"Log the Flux text and duration of every query"`
+}
+
+// State is the current level and every registered toggle.
+type State struct {
+	Level   string                 `json:"level" example:"info"`
+	Toggles map[string]ToggleState `json:"toggles"`
+}
+
+// State returns the current level and toggles.
+func (r *Runtime) State() State {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	state := State{Level: r.Level().String(), Toggles: make(map[string]ToggleState, len(r.toggles))}
+	for name, t := range r.toggles {
+		state.Toggles[name] = ToggleState{Enabled: t.Enabled(), Description: t.description}
+	}
+	return state
+}
+
+// Update changes the level and toggles; omitted fields are left unchanged.
+type Update struct {
+	Level   string          `json:"level,omitempty" example:"debug"`
+	Toggles map[string]bool `json:"toggles,omitempty"`
+}
+
+// Apply validates the whole update, then applies it and logs each change.
+// An unknown level or toggle is a validation error and changes nothing.
+func (r *Runtime) Apply(u Update) error {
+	level := r.Level()
+	if u.Level != "" {
+		parsed, err := ParseLevel(u.Level)
+		if err != nil {
+			return perrors.Validation(err)
+		}
+		level = parsed
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(u.Toggles))
+	for name := range u.Toggles {
+		if _, ok := r.toggles[name]; !ok {
+			return perrors.Validation(fmt.Errorf("unknown debug toggle %q", name))
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r.SetLevel(level)
+	for _, name := range names {
+		on := u.Toggles[name]
+		if r.toggles[name].on.Swap(on) != on {
+			r.logger.Printf("Debug toggle %s %s", name, map[bool]string{true: "enabled", false: "disabled"}[on])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+func newTestRuntime(level Level) (*Runtime, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return New(log.New(&buf, "[TEST] ", 0), level), &buf
+}
+
+func TestLevelGating(t *testing.T) {
+	r, buf := newTestRuntime(LevelInfo)
+
+	r.Debugf("detail")
+	if buf.Len() != 0 {
+		t.Fatalf("at info, got %q", buf.String())
+	}
+
+	r.SetLevel(LevelDebug)
+	r.Debugf("detail %d", 1)
+	if got := buf.String(); got != "[TEST] Log level changed from info to debug\n[TEST] DEBUG detail 1\n" {
+		t.Fatalf("at debug, got %q", got)
+	}
+
+	var none *Runtime
+	none.Debugf("must not panic")
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, "Debug": LevelDebug} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestToggle(t *testing.T) {
+	r, buf := newTestRuntime(LevelInfo)
+	frames := r.Toggle("mq.frames", "Log every frame")
+	if r.Toggle("mq.frames", "ignored") != frames {
+		t.Fatal("registering a toggle twice must return the same toggle")
+	}
+
+	frames.Printf("off")
+	if buf.Len() != 0 {
+		t.Fatalf("a disabled toggle logged %q", buf.String())
+	}
+
+	if err := r.Apply(Update{Toggles: map[string]bool{"mq.frames": true}}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	frames.Printf("frame %d", 7)
+	if got := buf.String(); got != "[TEST] Debug toggle mq.frames enabled\n[TEST] DEBUG frame 7\n" {
+		t.Fatalf("toggle output ignores the level, got %q", got)
+	}
+
+	var unregistered *Toggle
+	if unregistered.Enabled() {
+		t.Error("a nil toggle must be off")
+	}
+	unregistered.Printf("must not panic")
+}
+
+func TestApplyIsAllOrNothing(t *testing.T) {
+	r, _ := newTestRuntime(LevelInfo)
+	r.Toggle("flux.queries", "Log every query")
+
+	for _, u := range []Update{
+		{Level: "loud", Toggles: map[string]bool{"flux.queries": true}},
+		{Level: "debug", Toggles: map[string]bool{"flux.queries": true, "missing": true}},
+	} {
+		if err := r.Apply(u); !perrors.IsValidation(err) {
+			t.Errorf("Apply(%+v) = %v, want a validation error", u, err)
+		}
+	}
+
+	state := r.State()
+	if state.Level != "info" || state.Toggles["flux.queries"].Enabled {
+		t.Errorf("a rejected update changed the state: %+v", state)
+	}
+	if state.Toggles["flux.queries"].Description != "Log every query" {
+		t.Errorf("unexpected description %q", state.Toggles["flux.queries"].Description)
+	}
+}
@@ -0,0 +1,278 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Alert severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Alert states. A pending alert's condition holds but has not yet held for
+// the rule's For duration; only firing and resolved alerts are notified.
+const (
+	AlertPending  = "pending"
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertRule raises an alert for every GPU whose latest value of a metric
+// crosses a threshold. The threshold is fixed, or computed from the baseline
+// of each GPU's model.
+type AlertRule struct {
+	// ID uniquely identifies the rule
+	ID string `json:"id"`
+
+	// Name is a human-readable label shown in notifications
+	Name string `json:"name"`
+
+	// Metric is the metric the rule watches (e.g., DCGM_FI_DEV_GPU_TEMP)
+	Metric string `json:"metric"`
+
+	// Op compares the value to Threshold: >, >=, <, <=, == or !=
+	Op string `json:"op"`
+
+	// Threshold is the value the metric is compared against
+	Threshold float64 `json:"threshold"`
+
+	// ThresholdExpr replaces Threshold with a value computed from the
+	// metric's baseline for each GPU's model, e.g. "model_p99" or
+	// "model_mean + 3 * model_stddev"; see ThresholdExpr (optional)
+	ThresholdExpr string `json:"threshold_expr,omitempty"`
+
+	// For is how long the condition must hold before the alert fires, as a
+	// Go duration; empty fires on the first breaching evaluation
+	For string `json:"for,omitempty"`
+
+	// Severity is info, warning or critical
+	Severity string `json:"severity"`
+
+	// Hostname and UUID limit the rule to matching GPUs; a trailing '*'
+	// matches a prefix (optional)
+	Hostname string `json:"hostname,omitempty"`
+	UUID     string `json:"uuid,omitempty"`
+
+	// Summary is a text/template rendered against the Alert for notifications (optional)
+	Summary string `json:"summary,omitempty"`
+
+	// Notifiers names the notifiers the alert is sent to; empty sends to all
+	Notifiers []string `json:"notifiers,omitempty"`
+
+	// Enabled pauses evaluation when false
+	Enabled bool `json:"enabled"`
+
+	// Source is where the rule is defined: "api" for rules managed through
+	// the API, "file" for rules loaded from the rules file
+	Source string `json:"source,omitempty"`
+
+	// CreatedAt and UpdatedAt track changes to API-managed rules
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Alert rule sources.
+const (
+	RuleSourceAPI  = "api"
+	RuleSourceFile = "file"
+)
+
+// Validate checks that the rule can be evaluated.
+func (r *AlertRule) Validate() error {
+	var errs []error
+	if r.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if r.Metric == "" {
+		errs = append(errs, errors.New("metric is required"))
+	}
+	switch r.Op {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		errs = append(errs, fmt.Errorf("op must be one of >, >=, <, <=, ==, !=, got %q", r.Op))
+	}
+	if r.For != "" {
+		if d, err := time.ParseDuration(r.For); err != nil || d < 0 {
+			errs = append(errs, fmt.Errorf("for must be a non-negative duration, got %q", r.For))
+		}
+	}
+	if r.ThresholdExpr != "" {
+		if _, err := ParseThresholdExpr(r.ThresholdExpr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !IsSeverity(r.Severity) {
+		errs = append(errs, fmt.Errorf("severity must be info, warning or critical, got %q", r.Severity))
+	}
+	return errors.Join(errs...)
+}
+
+// ForDuration returns how long the condition must hold before firing.
+func (r *AlertRule) ForDuration() time.Duration {
+	d, _ := time.ParseDuration(r.For)
+	return d
+}
+
+// Breached reports whether value crosses the rule's fixed threshold.
+func (r *AlertRule) Breached(value float64) bool {
+	return r.Compare(value, r.Threshold)
+}
+
+// Compare applies the rule's operator to value and threshold.
+func (r *AlertRule) Compare(value, threshold float64) bool {
+	switch r.Op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// AppliesTo reports whether the rule watches the GPU with the given identity.
+func (r *AlertRule) AppliesTo(hostname, uuid string) bool {
+	return MatchPattern(r.Hostname, hostname) && MatchPattern(r.UUID, uuid)
+}
+
+// IsSeverity reports whether s is a known severity.
+func IsSeverity(s string) bool {
+	return s == SeverityInfo || s == SeverityWarning || s == SeverityCritical
+}
+
+// MatchPattern reports whether value matches pattern: an empty pattern
+// matches anything, a trailing '*' matches a prefix, otherwise the match
+// is exact.
+func MatchPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
+
+// Alert is one rule's alert for one GPU.
+type Alert struct {
+	// ID is stable for a rule and GPU, so notifiers can deduplicate
+	ID string `json:"id"`
+
+	RuleID   string `json:"rule_id"`
+	RuleName string `json:"rule_name"`
+	Severity string `json:"severity"`
+
+	// State is pending, firing or resolved
+	State string `json:"state"`
+
+	UUID      string `json:"uuid"`
+	GPUID     int    `json:"gpu_id"`
+	Hostname  string `json:"hostname"`
+	ModelName string `json:"model_name,omitempty"`
+
+	// Metric, Op and Threshold restate the rule's condition; Value is the
+	// latest value evaluated
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+	Op        string  `json:"op"`
+	Threshold float64 `json:"threshold"`
+
+	// Summary is the rule's rendered summary
+	Summary string `json:"summary,omitempty"`
+
+	// ActiveAt is when the condition started to hold
+	ActiveAt time.Time `json:"active_at"`
+
+	// FiredAt is when the alert started firing
+	FiredAt time.Time `json:"fired_at,omitempty"`
+
+	// ResolvedAt is when the condition stopped holding after firing
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+
+	// SuppressedBy is the ID of the maintenance annotation whose window
+	// holds back the alert's notifications, if any
+	SuppressedBy string `json:"suppressed_by,omitempty"`
+}
+
+// AlertID returns the alert ID for a rule and GPU.
+func AlertID(ruleID, uuid string) string {
+	return ruleID + "/" + uuid
+}
+
+// SilenceMatchers select the alerts a silence mutes. Every non-empty matcher
+// must match; a trailing '*' matches a prefix.
+type SilenceMatchers struct {
+	RuleName string `json:"rule_name,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	UUID     string `json:"uuid,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// Silence mutes notifications for matching alerts during a time window.
+// Silenced alerts are still evaluated and tracked.
+type Silence struct {
+	// ID uniquely identifies the silence
+	ID string `json:"id"`
+
+	// Matchers select the silenced alerts
+	Matchers SilenceMatchers `json:"matchers"`
+
+	// Comment says why the alerts are silenced
+	Comment string `json:"comment"`
+
+	// CreatedBy names who created the silence (optional)
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// StartsAt and EndsAt bound the window
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+
+	// CreatedAt is when the silence was recorded
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the silence has a window, a reason and at least one matcher.
+func (s *Silence) Validate() error {
+	var errs []error
+	if s.Matchers == (SilenceMatchers{}) {
+		errs = append(errs, errors.New("at least one matcher is required"))
+	}
+	if sev := s.Matchers.Severity; sev != "" && !strings.HasSuffix(sev, "*") && !IsSeverity(sev) {
+		errs = append(errs, fmt.Errorf("matchers.severity must be info, warning or critical, got %q", s.Matchers.Severity))
+	}
+	if s.Comment == "" {
+		errs = append(errs, errors.New("comment is required"))
+	}
+	if s.EndsAt.IsZero() {
+		errs = append(errs, errors.New("ends_at is required"))
+	} else if !s.EndsAt.After(s.StartsAt) {
+		errs = append(errs, perrors.WithCode(perrors.CodeInvalidRange, errors.New("ends_at must be after starts_at")))
+	}
+	return errors.Join(errs...)
+}
+
+// Active reports whether the silence is in effect at t.
+func (s *Silence) Active(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// Matches reports whether the silence mutes the alert.
+func (s *Silence) Matches(a *Alert) bool {
+	m := s.Matchers
+	return MatchPattern(m.RuleName, a.RuleName) && MatchPattern(m.Hostname, a.Hostname) &&
+		MatchPattern(m.UUID, a.UUID) && MatchPattern(m.Severity, a.Severity)
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertRuleValidate(t *testing.T) {
+	valid := AlertRule{Name: "hot", Metric: "DCGM_FI_DEV_GPU_TEMP", Op: ">=", Threshold: 85, For: "5m", Severity: SeverityWarning}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid rule, got %v", err)
+	}
+
+	invalid := AlertRule{Op: "=>", For: "-1m", Severity: "page"}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"name", "metric", "op", "for", "severity"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestAlertRuleBreached(t *testing.T) {
+	tests := []struct {
+		op    string
+		value float64
+		want  bool
+	}{
+		{">", 86, true}, {">", 85, false},
+		{">=", 85, true}, {"<", 84, true},
+		{"<=", 86, false}, {"==", 85, true},
+		{"!=", 85, false}, {"~", 100, false},
+	}
+	for _, tt := range tests {
+		r := AlertRule{Op: tt.op, Threshold: 85}
+		if got := r.Breached(tt.value); got != tt.want {
+			t.Errorf("%v %s 85: expected %t, got %t", tt.value, tt.op, tt.want, got)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"", "host-001", true},
+		{"host-001", "host-001", true},
+		{"host-001", "host-0010", false},
+		{"host-0*", "host-001", true},
+		{"host-1*", "host-001", false},
+		{"*", "", true},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q): expected %t, got %t", tt.pattern, tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestSilence(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := Silence{
+		Matchers: SilenceMatchers{Hostname: "host-0*", Severity: SeverityWarning},
+		Comment:  "driver upgrade",
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("expected valid silence, got %v", err)
+	}
+	if !s.Active(now) || s.Active(now.Add(time.Hour)) || s.Active(now.Add(-time.Second)) {
+		t.Error("expected the silence to be active in [starts_at, ends_at) only")
+	}
+	if !s.Matches(&Alert{Hostname: "host-001", Severity: SeverityWarning}) {
+		t.Error("expected the silence to match")
+	}
+	if s.Matches(&Alert{Hostname: "host-001", Severity: SeverityCritical}) {
+		t.Error("expected every matcher to be required")
+	}
+
+	invalid := Silence{Matchers: SilenceMatchers{Severity: "page"}, StartsAt: now, EndsAt: now}
+	err := invalid.Validate()
+	for _, want := range []string{"matchers.severity", "comment", "ends_at"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+	if err := (&Silence{Comment: "x", EndsAt: now}).Validate(); err == nil || !strings.Contains(err.Error(), "matcher") {
+		t.Errorf("expected a missing matcher error, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "sort"
+
+// MetricAliases maps alternate metric names, such as those emitted by other
+// exporter versions, to the canonical name telemetry is stored under.
+type MetricAliases map[string]string
+
+// Canonical returns the name metric is stored under.
+func (a MetricAliases) Canonical(metric string) string {
+	if canonical, ok := a[metric]; ok {
+		return canonical
+	}
+	return metric
+}
+
+// Names returns every name of metric's series: its canonical name, then
+// the aliases of that name in order.
+func (a MetricAliases) Names(metric string) []string {
+	canonical := a.Canonical(metric)
+	names := []string{canonical}
+	for alias, c := range a {
+		if c == canonical {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
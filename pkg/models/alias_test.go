@@ -0,0 +1,27 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMetricAliases(t *testing.T) {
+	aliases := MetricAliases{"gpu_temperature": "DCGM_FI_DEV_GPU_TEMP", "gpu_temp": "DCGM_FI_DEV_GPU_TEMP"}
+
+	if got := aliases.Canonical("gpu_temperature"); got != "DCGM_FI_DEV_GPU_TEMP" {
+		t.Errorf("expected the alias resolved, got %s", got)
+	}
+	if got := aliases.Canonical("DCGM_FI_DEV_SM_CLOCK"); got != "DCGM_FI_DEV_SM_CLOCK" {
+		t.Errorf("expected an unaliased name kept, got %s", got)
+	}
+
+	want := []string{"DCGM_FI_DEV_GPU_TEMP", "gpu_temp", "gpu_temperature"}
+	for _, name := range want {
+		if got := aliases.Names(name); !slices.Equal(got, want) {
+			t.Errorf("Names(%s) = %v, want %v", name, got, want)
+		}
+	}
+	if got := MetricAliases(nil).Names("gpu_temp"); !slices.Equal(got, []string{"gpu_temp"}) {
+		t.Errorf("expected only the name itself without aliases, got %v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Annotation kinds.
+const (
+	AnnotationMaintenance   = "maintenance"
+	AnnotationDriverUpgrade = "driver_upgrade"
+	AnnotationJobLaunch     = "job_launch"
+	AnnotationOther         = "other"
+)
+
+// Annotation marks an operational event on a time range so charts can show
+// context next to telemetry. Hostname and UUID narrow its scope; when both are
+// empty it applies to every GPU.
+type Annotation struct {
+	// ID uniquely identifies the annotation
+	ID string `json:"id"`
+
+	// Kind is one of maintenance, driver_upgrade, job_launch or other
+	Kind string `json:"kind"`
+
+	// Title is a short label for the event
+	Title string `json:"title"`
+
+	// Description holds optional free-form details
+	Description string `json:"description,omitempty"`
+
+	// Hostname limits the annotation to one host (optional)
+	Hostname string `json:"hostname,omitempty"`
+
+	// UUID limits the annotation to one GPU (optional)
+	UUID string `json:"uuid,omitempty"`
+
+	// Start is when the event began
+	Start time.Time `json:"start"`
+
+	// End is when the event finished; zero for a point-in-time event
+	End time.Time `json:"end,omitempty"`
+
+	// CreatedAt is when the annotation was recorded
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt is when the annotation was last changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks that the annotation has the fields storage requires.
+func (a *Annotation) Validate() error {
+	var errs []error
+	switch a.Kind {
+	case AnnotationMaintenance, AnnotationDriverUpgrade, AnnotationJobLaunch, AnnotationOther:
+	default:
+		errs = append(errs, fmt.Errorf("kind must be maintenance, driver_upgrade, job_launch or other, got %q", a.Kind))
+	}
+	if a.Title == "" {
+		errs = append(errs, errors.New("title is required"))
+	}
+	if a.Start.IsZero() {
+		errs = append(errs, errors.New("start is required"))
+	}
+	if !a.End.IsZero() && a.End.Before(a.Start) {
+		errs = append(errs, perrors.WithCode(perrors.CodeInvalidRange, fmt.Errorf("end (%s) is before start (%s)", a.End.Format(time.RFC3339), a.Start.Format(time.RFC3339))))
+	}
+	return errors.Join(errs...)
+}
+
+// Overlaps reports whether the annotation intersects [start, end]. A zero
+// start or end leaves that side of the range open.
+func (a *Annotation) Overlaps(start, end time.Time) bool {
+	annEnd := a.End
+	if annEnd.IsZero() {
+		annEnd = a.Start
+	}
+	if !end.IsZero() && a.Start.After(end) {
+		return false
+	}
+	if !start.IsZero() && annEnd.Before(start) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo reports whether the annotation's scope covers the given GPU.
+// An empty hostname or uuid argument matches any annotation scope.
+func (a *Annotation) AppliesTo(hostname, uuid string) bool {
+	if a.Hostname != "" && hostname != "" && a.Hostname != hostname {
+		return false
+	}
+	if a.UUID != "" && uuid != "" && a.UUID != uuid {
+		return false
+	}
+	return true
+}
+
+// AnnotationQuery selects annotations by scope and time range.
+type AnnotationQuery struct {
+	// Hostname returns annotations for this host plus global ones
+	Hostname string `json:"hostname,omitempty"`
+
+	// UUID returns annotations for this GPU plus global ones
+	UUID string `json:"uuid,omitempty"`
+
+	// Kind filters by annotation kind
+	Kind string `json:"kind,omitempty"`
+
+	// StartTime is the inclusive start of the time window
+	StartTime *time.Time `json:"start_time,omitempty"`
+
+	// EndTime is the inclusive end of the time window
+	EndTime *time.Time `json:"end_time,omitempty"`
+}
+
+// Matches reports whether the annotation satisfies the query.
+func (q *AnnotationQuery) Matches(a *Annotation) bool {
+	if q.Kind != "" && a.Kind != q.Kind {
+		return false
+	}
+	if !a.AppliesTo(q.Hostname, q.UUID) {
+		return false
+	}
+	var start, end time.Time
+	if q.StartTime != nil {
+		start = *q.StartTime
+	}
+	if q.EndTime != nil {
+		end = *q.EndTime
+	}
+	return a.Overlaps(start, end)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnnotationValidate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	valid := Annotation{Kind: AnnotationMaintenance, Title: "Firmware update", Start: start, End: start.Add(time.Hour)}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid annotation, got %v", err)
+	}
+
+	invalid := Annotation{Kind: "outage", Start: start, End: start.Add(-time.Hour)}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"kind", "title", "end"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestAnnotationOverlaps(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := Annotation{Start: start, End: start.Add(time.Hour)}
+	point := Annotation{Start: start}
+
+	tests := []struct {
+		name       string
+		annotation Annotation
+		from, to   time.Time
+		want       bool
+	}{
+		{"window inside range", window, start.Add(-time.Hour), start.Add(2 * time.Hour), true},
+		{"window straddles range start", window, start.Add(30 * time.Minute), start.Add(2 * time.Hour), true},
+		{"window before range", window, start.Add(2 * time.Hour), start.Add(3 * time.Hour), false},
+		{"window after range", window, start.Add(-2 * time.Hour), start.Add(-time.Hour), false},
+		{"point on range edge", point, start, start.Add(time.Hour), true},
+		{"open range", point, time.Time{}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.annotation.Overlaps(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAnnotationQueryMatchesScope(t *testing.T) {
+	fleet := &Annotation{Kind: AnnotationDriverUpgrade, Start: time.Now()}
+	host := &Annotation{Kind: AnnotationMaintenance, Hostname: "host-001", Start: time.Now()}
+	gpu := &Annotation{Kind: AnnotationJobLaunch, Hostname: "host-001", UUID: "GPU-1", Start: time.Now()}
+
+	q := &AnnotationQuery{Hostname: "host-001", UUID: "GPU-2"}
+	if !q.Matches(fleet) || !q.Matches(host) {
+		t.Error("expected fleet and host annotations to apply to GPU-2 on host-001")
+	}
+	if q.Matches(gpu) {
+		t.Error("expected GPU-1 annotation not to apply to GPU-2")
+	}
+
+	q = &AnnotationQuery{Hostname: "host-002"}
+	if q.Matches(host) {
+		t.Error("expected host-001 annotation not to apply to host-002")
+	}
+
+	q = &AnnotationQuery{Kind: AnnotationJobLaunch}
+	if q.Matches(fleet) || !q.Matches(gpu) {
+		t.Error("expected kind filter to select job launches only")
+	}
+}
@@ -0,0 +1,190 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Baseline is the normal range of one metric across every GPU of one model,
+// learned from fleet history.
+type Baseline struct {
+	// ModelName is the GPU model (e.g., NVIDIA H100 80GB HBM3)
+	ModelName string `json:"model_name"`
+
+	// Metric is the metric profiled (e.g., DCGM_FI_DEV_GPU_TEMP)
+	Metric string `json:"metric"`
+
+	// Samples and GPUs count the data the baseline was learned from
+	Samples int `json:"samples"`
+	GPUs    int `json:"gpus"`
+
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	P1     float64 `json:"p1"`
+	P5     float64 `json:"p5"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+
+	// Start and End bound the history the baseline was learned from
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// BaselineStats are the statistics a threshold expression can reference as
+// model_<stat>.
+var BaselineStats = []string{"mean", "stddev", "min", "max", "p1", "p5", "p50", "p95", "p99"}
+
+// Stat returns the named statistic.
+func (b *Baseline) Stat(name string) (float64, bool) {
+	switch name {
+	case "mean":
+		return b.Mean, true
+	case "stddev":
+		return b.StdDev, true
+	case "min":
+		return b.Min, true
+	case "max":
+		return b.Max, true
+	case "p1":
+		return b.P1, true
+	case "p5":
+		return b.P5, true
+	case "p50":
+		return b.P50, true
+	case "p95":
+		return b.P95, true
+	case "p99":
+		return b.P99, true
+	}
+	return 0, false
+}
+
+// SetStat sets the named statistic, reporting whether the name is known.
+func (b *Baseline) SetStat(name string, value float64) bool {
+	switch name {
+	case "mean":
+		b.Mean = value
+	case "stddev":
+		b.StdDev = value
+	case "min":
+		b.Min = value
+	case "max":
+		b.Max = value
+	case "p1":
+		b.P1 = value
+	case "p5":
+		b.P5 = value
+	case "p50":
+		b.P50 = value
+	case "p95":
+		b.P95 = value
+	case "p99":
+		b.P99 = value
+	default:
+		return false
+	}
+	return true
+}
+
+// thresholdTerm is coef, or coef times a baseline statistic.
+type thresholdTerm struct {
+	coef float64
+	stat string
+}
+
+// ThresholdExpr is a threshold computed from a GPU model's baseline, such as
+// "model_p99", "model_p99 + 5" or "model_mean + 3 * model_stddev": a sum of
+// numbers and model_<stat> references, each optionally multiplied by a
+// number.
+type ThresholdExpr []thresholdTerm
+
+// baselinePrefix marks a baseline statistic in a threshold expression.
+const baselinePrefix = "model_"
+
+// ParseThresholdExpr parses a threshold expression. It must reference at
+// least one baseline statistic.
+func ParseThresholdExpr(s string) (ThresholdExpr, error) {
+	src := strings.ReplaceAll(s, " ", "")
+	if src == "" {
+		return nil, errors.New("empty threshold expression")
+	}
+
+	var expr ThresholdExpr
+	var refs bool
+	for src != "" {
+		sign := 1.0
+		switch src[0] {
+		case '+':
+			src = src[1:]
+		case '-':
+			sign, src = -1, src[1:]
+		default:
+			if expr != nil {
+				return nil, fmt.Errorf("threshold expression %q: expected + or - before %q", s, src)
+			}
+		}
+		end := strings.IndexAny(src, "+-")
+		if end < 0 {
+			end = len(src)
+		}
+		term, err := parseThresholdTerm(src[:end])
+		if err != nil {
+			return nil, fmt.Errorf("threshold expression %q: %w", s, err)
+		}
+		term.coef *= sign
+		refs = refs || term.stat != ""
+		expr = append(expr, term)
+		src = src[end:]
+	}
+	if !refs {
+		return nil, fmt.Errorf("threshold expression %q references no model_<stat>; use threshold for a fixed value", s)
+	}
+	return expr, nil
+}
+
+// parseThresholdTerm parses a number, a model_<stat> reference, or their product.
+func parseThresholdTerm(s string) (thresholdTerm, error) {
+	term := thresholdTerm{coef: 1}
+	factors := strings.Split(s, "*")
+	if len(factors) > 2 {
+		return term, fmt.Errorf("term %q multiplies more than two factors", s)
+	}
+	for _, f := range factors {
+		if stat, ok := strings.CutPrefix(f, baselinePrefix); ok {
+			if term.stat != "" {
+				return term, fmt.Errorf("term %q multiplies two statistics", s)
+			}
+			if _, known := (&Baseline{}).Stat(stat); !known {
+				return term, fmt.Errorf("unknown statistic %q (known: %s)", f, strings.Join(BaselineStats, ", "))
+			}
+			term.stat = stat
+			continue
+		}
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return term, fmt.Errorf("%q is neither a number nor model_<stat>", f)
+		}
+		term.coef *= v
+	}
+	return term, nil
+}
+
+// Eval computes the threshold from a baseline.
+func (e ThresholdExpr) Eval(b *Baseline) float64 {
+	var total float64
+	for _, term := range e {
+		if term.stat == "" {
+			total += term.coef
+			continue
+		}
+		v, _ := b.Stat(term.stat)
+		total += term.coef * v
+	}
+	return total
+}
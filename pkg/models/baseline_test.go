@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestParseThresholdExpr(t *testing.T) {
+	b := &Baseline{Mean: 60, StdDev: 4, P99: 80}
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"model_p99", 80},
+		{"model_p99 + 5", 85},
+		{"model_mean + 3 * model_stddev", 72},
+		{"model_mean - 2*model_stddev", 52},
+		{"-1 + model_p99", 79},
+		{"model_stddev * 0.5 + model_mean", 62},
+	}
+	for _, tt := range tests {
+		expr, err := ParseThresholdExpr(tt.expr)
+		if err != nil {
+			t.Errorf("ParseThresholdExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Eval(b); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "85", "model_p42", "model_mean * model_stddev", "2 * 3 * model_p99", "model_p99 +", "p99", "model_p99 5"} {
+		if _, err := ParseThresholdExpr(bad); err == nil {
+			t.Errorf("ParseThresholdExpr(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// ConfigBundleVersion is the bundle format version this build writes and reads.
+const ConfigBundleVersion = 1
+
+// ConfigBundle is a deployment's runtime configuration, exported from one
+// deployment and imported into another to promote it between
+// environments. It is signed with a key the deployments share, so an
+// import only accepts bundles exported by a trusted deployment and not
+// edited since.
+type ConfigBundle struct {
+	// Version is the bundle format version
+	Version int `json:"version" example:"1"`
+
+	// CreatedAt is when the bundle was exported
+	CreatedAt time.Time `json:"created_at"`
+
+	// Retention is how long telemetry is kept, as a Go duration ("0s" keeps
+	// it forever); empty when the exporting backend does not manage retention
+	Retention string `json:"retention,omitempty" example:"720h0m0s"`
+
+	// AlertRules are the rules managed through the API; rules from the rules
+	// file belong to the deployment's files and are not included
+	AlertRules []*AlertRule `json:"alert_rules"`
+
+	// SavedQueries are the scheduled saved queries
+	SavedQueries []*SavedQuery `json:"saved_queries"`
+
+	// Signature is the HMAC-SHA256 of the rest of the bundle, hex-encoded
+	Signature string `json:"signature,omitempty"`
+}
+
+// BundleChanges lists by name the items an import creates, updates and
+// leaves as they are.
+type BundleChanges struct {
+	Created   []string `json:"created,omitempty"`
+	Updated   []string `json:"updated,omitempty"`
+	Unchanged []string `json:"unchanged,omitempty"`
+}
+
+// BundleImportResult describes what importing a bundle changed, or would
+// change in a dry run.
+type BundleImportResult struct {
+	DryRun bool `json:"dry_run"`
+
+	// Retention and PreviousRetention are set when the retention changes
+	Retention         string `json:"retention,omitempty" example:"720h0m0s"`
+	PreviousRetention string `json:"previous_retention,omitempty" example:"168h0m0s"`
+
+	AlertRules   BundleChanges `json:"alert_rules"`
+	SavedQueries BundleChanges `json:"saved_queries"`
+}
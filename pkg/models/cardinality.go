@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// CardinalityReport counts the telemetry series written over a window. A
+// series is one metric with one set of tag values; each is a separate series
+// in InfluxDB, so a tag taking a new value per workload multiplies them.
+type CardinalityReport struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Series is the number of distinct series written
+	Series int `json:"series" example:"2048"`
+
+	// Metrics counts the series of the metrics with the most, highest first
+	Metrics []CardinalityCount `json:"metrics"`
+
+	// Tags counts the distinct values of each tag, highest first. A tag
+	// with far more values than there are GPUs is usually the culprit.
+	Tags []CardinalityCount `json:"tags"`
+}
+
+// CardinalityCount is the count for one metric or tag in a CardinalityReport.
+type CardinalityCount struct {
+	Name  string `json:"name" example:"pod"`
+	Count int    `json:"count" example:"1536"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Cleanup history record kinds.
+const (
+	CleanupDelete    = "delete"
+	CleanupRetention = "retention"
+)
+
+// Cleanup outcomes.
+const (
+	CleanupSucceeded = "succeeded"
+	CleanupFailed    = "failed"
+)
+
+// CleanupRequest asks for telemetry in a time range to be deleted, optionally
+// only for some GPUs.
+type CleanupRequest struct {
+	// Start and End bound the deleted range [Start, End)
+	Start time.Time `json:"start" example:"2024-01-01T00:00:00Z"`
+	End   time.Time `json:"end" example:"2024-01-02T00:00:00Z"`
+
+	// UUIDs limits deletion to these GPUs (empty means all GPUs)
+	UUIDs []string `json:"uuids,omitempty"`
+}
+
+// Validate checks the range and GPU UUIDs.
+func (r *CleanupRequest) Validate() error {
+	var errs []error
+	if r.Start.IsZero() || r.End.IsZero() {
+		errs = append(errs, errors.New("start and end are required"))
+	} else if !r.End.After(r.Start) {
+		errs = append(errs, perrors.WithCode(perrors.CodeInvalidRange, fmt.Errorf("end (%s) must be after start (%s)", r.End.Format(time.RFC3339), r.Start.Format(time.RFC3339))))
+	}
+	for _, id := range r.UUIDs {
+		// UUIDs are embedded in storage delete predicates
+		if id == "" || strings.ContainsAny(id, `"\`) {
+			errs = append(errs, fmt.Errorf("invalid uuid %q", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CleanupRecord is an entry in the admin cleanup history: either an
+// on-demand deletion or a retention change.
+type CleanupRecord struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+
+	// Start, End and UUIDs describe a deletion
+	Start   *time.Time `json:"start,omitempty"`
+	End     *time.Time `json:"end,omitempty"`
+	UUIDs   []string   `json:"uuids,omitempty"`
+	Metrics []string   `json:"metrics,omitempty"`
+
+	// Holds lists the retention holds that spared data in the deleted range
+	Holds []string `json:"holds,omitempty"`
+
+	// Retention and PreviousRetention describe a retention change
+	// (Go durations; "0s" means data is kept forever)
+	Retention         string `json:"retention,omitempty"`
+	PreviousRetention string `json:"previous_retention,omitempty"`
+
+	RequestedBy string    `json:"requested_by"`
+	RequestedAt time.Time `json:"requested_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Environment is a named data set the API serves, such as dev or prod.
+type Environment struct {
+	Name string `json:"name" example:"prod"`
+
+	// Default reports whether requests naming no environment are served from it
+	Default bool `json:"default"`
+
+	// Restricted reports whether only some tenants may read it
+	Restricted bool `json:"restricted"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Pipeline event types delivered to webhooks.
+const (
+	// EventGPUDiscovered fires the first time a GPU reports telemetry
+	EventGPUDiscovered = "gpu.discovered"
+
+	// EventGPUSilent fires when a known GPU stops reporting
+	EventGPUSilent = "gpu.silent"
+
+	// EventAlertFired and EventAlertResolved track alert state changes
+	EventAlertFired    = "alert.fired"
+	EventAlertResolved = "alert.resolved"
+
+	// EventExportCompleted fires when a scheduled saved-query export finishes
+	EventExportCompleted = "export.completed"
+
+	// EventCollectorLag fires when a collector's consumer lag crosses its
+	// threshold, in either direction
+	EventCollectorLag = "collector.lag"
+
+	// EventSLOBurn fires when the ingest-latency objective's error budget
+	// starts burning too fast, and again when it recovers
+	EventSLOBurn = "slo.burn"
+)
+
+// EventTypes lists every event type, in documentation order.
+var EventTypes = []string{
+	EventGPUDiscovered,
+	EventGPUSilent,
+	EventAlertFired,
+	EventAlertResolved,
+	EventExportCompleted,
+	EventCollectorLag,
+	EventSLOBurn,
+}
+
+// Event is a notable pipeline occurrence pushed to subscribers.
+type Event struct {
+	// ID uniquely identifies the event; receivers can use it to deduplicate retries
+	ID string `json:"id"`
+
+	// Type is one of the Event* constants
+	Type string `json:"type"`
+
+	// Time is when the event was raised
+	Time time.Time `json:"time"`
+
+	// Source is the component instance that raised the event (e.g., collector-1)
+	Source string `json:"source"`
+
+	// Data carries event-specific fields
+	Data map[string]interface{} `json:"data"`
+}
+
+// IsEventType reports whether t is a known event type.
+func IsEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
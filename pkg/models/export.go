@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Export job states.
+const (
+	ExportQueued    = "queued"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+	ExportExpired   = "expired"
+)
+
+// ExportSpec selects the telemetry an export job writes.
+type ExportSpec struct {
+	UUID       string `json:"uuid,omitempty"`
+	Hostname   string `json:"hostname,omitempty"`
+	GPUID      *int   `json:"gpu_id,omitempty"`
+	MetricName string `json:"metric_name,omitempty"`
+
+	// Start and End bound the exported time range
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Format is the artifact format: csv (default) or json
+	Format string `json:"format,omitempty"`
+}
+
+// Validate checks the spec and defaults its format.
+func (s *ExportSpec) Validate() error {
+	var errs []error
+	if s.Start.IsZero() || s.End.IsZero() {
+		errs = append(errs, errors.New("start and end are required"))
+	} else if !s.End.After(s.Start) {
+		errs = append(errs, perrors.WithCode(perrors.CodeInvalidRange, errors.New("end must be after start")))
+	}
+	if s.Format == "" {
+		s.Format = "csv"
+	}
+	if s.Format != "json" && s.Format != "csv" {
+		errs = append(errs, fmt.Errorf("format must be json or csv, got %q", s.Format))
+	}
+	return errors.Join(errs...)
+}
+
+// TelemetryQuery returns the query for one page of the export.
+func (s *ExportSpec) TelemetryQuery(limit, offset int) *TelemetryQuery {
+	start, end := s.Start, s.End
+	return &TelemetryQuery{
+		UUID:       s.UUID,
+		Hostname:   s.Hostname,
+		GPUID:      s.GPUID,
+		MetricName: s.MetricName,
+		StartTime:  &start,
+		EndTime:    &end,
+		Limit:      limit,
+		Offset:     offset,
+	}
+}
+
+// ExportJob is a telemetry export run in the background, whose artifact is
+// downloaded once it completes.
+type ExportJob struct {
+	// ID uniquely identifies the job
+	ID string `json:"id"`
+
+	// Spec is what the job exports
+	Spec ExportSpec `json:"spec"`
+
+	// Status is queued, running, completed, failed or expired
+	Status string `json:"status"`
+
+	// Rows is how many data points the artifact holds
+	Rows int `json:"rows"`
+
+	// Size is the artifact's size in bytes
+	Size int64 `json:"size"`
+
+	// Truncated is set when the export stopped at the row limit
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Error says why the job failed or its artifact was removed early
+	Error string `json:"error,omitempty"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	// ExpiresAt is when the artifact is deleted
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Filename is the artifact's download name.
+func (j *ExportJob) Filename() string {
+	return fmt.Sprintf("telemetry-export-%s.%s", j.ID, j.Spec.Format)
+}
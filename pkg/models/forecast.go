@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// FleetPoint summarizes one metric across the fleet over one interval: each
+// GPU's values are averaged, then combined across GPUs.
+type FleetPoint struct {
+	// Time is the start of the interval
+	Time time.Time `json:"time"`
+
+	// Mean is the average of the per-GPU means
+	Mean float64 `json:"mean"`
+
+	// Sum is the total of the per-GPU means (e.g., fleet power draw in watts)
+	Sum float64 `json:"sum"`
+
+	// GPUs is the number of GPUs that reported in the interval
+	GPUs int `json:"gpus"`
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// RetentionHold pins telemetry so that no cleanup deletes it: on-demand
+// deletions and collector expiry skip the data it covers until the hold is
+// removed. Used for legal holds and for keeping evidence of failures.
+type RetentionHold struct {
+	// ID uniquely identifies the hold
+	ID string `json:"id"`
+
+	// Reason says why the data is kept
+	Reason string `json:"reason" example:"RMA 4411: ECC errors on node-7"`
+
+	// Start and End bound the held range [Start, End); a missing Start holds
+	// everything before End and a missing End holds data still to be written
+	Start *time.Time `json:"start,omitempty" example:"2024-01-01T00:00:00Z"`
+	End   *time.Time `json:"end,omitempty" example:"2024-01-08T00:00:00Z"`
+
+	// UUIDs limits the hold to these GPUs (empty means all GPUs)
+	UUIDs []string `json:"uuids,omitempty"`
+
+	// CreatedBy names who placed the hold
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// CreatedAt is when the hold was recorded
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate checks that the hold has a reason and is bounded by time, GPUs
+// or both. A hold on everything forever is refused as almost certainly a
+// mistake; set retention to forever instead.
+func (h *RetentionHold) Validate() error {
+	var errs []error
+	if h.Reason == "" {
+		errs = append(errs, errors.New("reason is required"))
+	}
+	if h.Start == nil && h.End == nil && len(h.UUIDs) == 0 {
+		errs = append(errs, errors.New("a hold needs start, end or uuids"))
+	}
+	if h.Start != nil && h.End != nil && !h.End.After(*h.Start) {
+		errs = append(errs, perrors.WithCode(perrors.CodeInvalidRange, fmt.Errorf("end (%s) must be after start (%s)", h.End.Format(time.RFC3339), h.Start.Format(time.RFC3339))))
+	}
+	for _, id := range h.UUIDs {
+		// UUIDs are embedded in storage delete predicates
+		if id == "" || strings.ContainsAny(id, `"\`) {
+			errs = append(errs, fmt.Errorf("invalid uuid %q", id))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Overlaps reports whether the hold covers any time in [start, end).
+func (h *RetentionHold) Overlaps(start, end time.Time) bool {
+	return (h.Start == nil || h.Start.Before(end)) && (h.End == nil || h.End.After(start))
+}
+
+// HoldIDs returns the IDs of the holds covering any time in [start, end).
+func HoldIDs(holds []*RetentionHold, start, end time.Time) []string {
+	var ids []string
+	for _, h := range holds {
+		if h.Overlaps(start, end) {
+			ids = append(ids, h.ID)
+		}
+	}
+	return ids
+}
+
+// PlanCleanup splits a deletion into the parts that spare held data. The
+// range is cut wherever a hold starts or ends; a piece under a fleet-wide
+// hold is dropped, and a piece under GPU holds loses those GPUs. When the
+// request covers every GPU, gpus lists the GPUs with data in a piece so the
+// unheld ones can be deleted one by one. The parts are in time order and
+// delete nothing when none are returned.
+func PlanCleanup(req *CleanupRequest, holds []*RetentionHold, gpus func(start, end time.Time) ([]string, error)) ([]CleanupRequest, error) {
+	var active []*RetentionHold
+	for _, h := range holds {
+		if h.Overlaps(req.Start, req.End) {
+			active = append(active, h)
+		}
+	}
+	if len(active) == 0 {
+		return []CleanupRequest{*req}, nil
+	}
+
+	cuts := []time.Time{req.Start, req.End}
+	for _, h := range active {
+		for _, t := range []*time.Time{h.Start, h.End} {
+			if t != nil && t.After(req.Start) && t.Before(req.End) {
+				cuts = append(cuts, *t)
+			}
+		}
+	}
+	sort.Slice(cuts, func(i, j int) bool { return cuts[i].Before(cuts[j]) })
+	cuts = slices.CompactFunc(cuts, func(a, b time.Time) bool { return a.Equal(b) })
+
+	var parts []CleanupRequest
+	for i := 0; i+1 < len(cuts); i++ {
+		start, end := cuts[i], cuts[i+1]
+
+		// Each hold covers a piece entirely or not at all
+		held := make(map[string]bool)
+		fleet := false
+		for _, h := range active {
+			if !h.Overlaps(start, end) {
+				continue
+			}
+			if len(h.UUIDs) == 0 {
+				fleet = true
+				break
+			}
+			for _, id := range h.UUIDs {
+				held[id] = true
+			}
+		}
+		if fleet {
+			continue
+		}
+		if len(held) == 0 {
+			parts = append(parts, CleanupRequest{Start: start, End: end, UUIDs: req.UUIDs})
+			continue
+		}
+
+		candidates := req.UUIDs
+		if len(candidates) == 0 {
+			var err error
+			if candidates, err = gpus(start, end); err != nil {
+				return nil, err
+			}
+		}
+		var uuids []string
+		for _, id := range candidates {
+			if !held[id] {
+				uuids = append(uuids, id)
+			}
+		}
+		if len(uuids) > 0 {
+			parts = append(parts, CleanupRequest{Start: start, End: end, UUIDs: uuids})
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionHoldValidate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	valid := RetentionHold{Reason: "RMA 4411", Start: &start, End: &end}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid hold, got %v", err)
+	}
+	pinned := RetentionHold{Reason: "RMA 4411", UUIDs: []string{"GPU-1"}}
+	if err := pinned.Validate(); err != nil {
+		t.Errorf("expected a GPU-only hold to be valid, got %v", err)
+	}
+
+	err := (&RetentionHold{Start: &end, End: &start, UUIDs: []string{`GPU"1`}}).Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"reason", "end", "uuid"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+	if err := (&RetentionHold{Reason: "everything"}).Validate(); err == nil {
+		t.Error("expected an unbounded hold to be refused")
+	}
+}
+
+func TestPlanCleanup(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2024, 1, 1, h, 0, 0, 0, time.UTC) }
+	ptr := func(h int) *time.Time { t := at(h); return &t }
+	gpus := func(start, end time.Time) ([]string, error) { return []string{"GPU-1", "GPU-2"}, nil }
+	req := &CleanupRequest{Start: at(0), End: at(10)}
+
+	tests := []struct {
+		name  string
+		req   *CleanupRequest
+		holds []*RetentionHold
+		want  []CleanupRequest
+	}{
+		{
+			name: "no holds",
+			req:  req,
+			want: []CleanupRequest{*req},
+		},
+		{
+			name:  "hold outside the range",
+			req:   req,
+			holds: []*RetentionHold{{ID: "h1", Start: ptr(10), End: ptr(12)}},
+			want:  []CleanupRequest{*req},
+		},
+		{
+			name:  "fleet hold in the middle",
+			req:   req,
+			holds: []*RetentionHold{{ID: "h1", Start: ptr(3), End: ptr(5)}},
+			want:  []CleanupRequest{{Start: at(0), End: at(3)}, {Start: at(5), End: at(10)}},
+		},
+		{
+			name:  "open-ended hold",
+			req:   req,
+			holds: []*RetentionHold{{ID: "h1", Start: ptr(4)}},
+			want:  []CleanupRequest{{Start: at(0), End: at(4)}},
+		},
+		{
+			name:  "GPU hold on a fleet-wide delete",
+			req:   req,
+			holds: []*RetentionHold{{ID: "h1", End: ptr(2), UUIDs: []string{"GPU-1"}}},
+			want:  []CleanupRequest{{Start: at(0), End: at(2), UUIDs: []string{"GPU-2"}}, {Start: at(2), End: at(10)}},
+		},
+		{
+			name:  "GPU hold on a GPU delete",
+			req:   &CleanupRequest{Start: at(0), End: at(10), UUIDs: []string{"GPU-1"}},
+			holds: []*RetentionHold{{ID: "h1", UUIDs: []string{"GPU-1", "GPU-3"}}},
+			want:  nil,
+		},
+		{
+			name: "overlapping holds",
+			req:  req,
+			holds: []*RetentionHold{
+				{ID: "h1", Start: ptr(2), End: ptr(6), UUIDs: []string{"GPU-2"}},
+				{ID: "h2", Start: ptr(4), End: ptr(8)},
+			},
+			want: []CleanupRequest{
+				{Start: at(0), End: at(2)},
+				{Start: at(2), End: at(4), UUIDs: []string{"GPU-1"}},
+				{Start: at(8), End: at(10)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlanCleanup(tt.req, tt.holds, gpus)
+			if err != nil {
+				t.Fatalf("PlanCleanup failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	boom := errors.New("query failed")
+	holds := []*RetentionHold{{ID: "h1", UUIDs: []string{"GPU-1"}}}
+	if _, err := PlanCleanup(req, holds, func(time.Time, time.Time) ([]string, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Errorf("expected the GPU lookup error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// identityField names the GPUMetric field an identity label fills.
+type identityField int
+
+const (
+	identityUUID identityField = iota + 1
+	identityGPU
+	identityDevice
+	identityModel
+	identityHostname
+	identityPod
+	identityNamespace
+	identityContainer
+)
+
+// identityLabels maps label names, lowercased, to GPU identity. It covers
+// the dcgm-exporter label names, the pipeline's own InfluxDB tags, Telegraf's
+// host tag, and the OpenTelemetry semantic conventions, so telemetry from any
+// of those sources lands in the same fields.
+var identityLabels = map[string]identityField{
+	"uuid":     identityUUID,
+	"gpu.uuid": identityUUID,
+	"gpu_uuid": identityUUID,
+
+	"gpu":       identityGPU,
+	"gpu_id":    identityGPU,
+	"gpu.id":    identityGPU,
+	"gpu.index": identityGPU,
+
+	"device":     identityDevice,
+	"gpu.device": identityDevice,
+
+	"modelname":  identityModel,
+	"model_name": identityModel,
+	"model":      identityModel,
+	"gpu.model":  identityModel,
+	"gpu.name":   identityModel,
+
+	"hostname":  identityHostname,
+	"host":      identityHostname,
+	"host.name": identityHostname,
+
+	"pod":          identityPod,
+	"k8s.pod.name": identityPod,
+
+	"namespace":          identityNamespace,
+	"k8s.namespace.name": identityNamespace,
+
+	"container":          identityContainer,
+	"k8s.container.name": identityContainer,
+}
+
+// SetIdentity sets the identity field a label names (matched
+// case-insensitively) and reports whether it did. Labels that are not
+// identity, and GPU indexes that are not numeric, report false so the caller
+// can keep them as ordinary labels.
+func (m *GPUMetric) SetIdentity(key, value string) bool {
+	switch identityLabels[strings.ToLower(key)] {
+	case identityUUID:
+		m.UUID = value
+	case identityGPU:
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return false
+		}
+		m.GPUID = id
+	case identityDevice:
+		m.Device = value
+	case identityModel:
+		m.ModelName = value
+	case identityHostname:
+		m.Hostname = value
+	case identityPod:
+		m.Pod = value
+	case identityNamespace:
+		m.Namespace = value
+	case identityContainer:
+		m.Container = value
+	default:
+		return false
+	}
+	return true
+}
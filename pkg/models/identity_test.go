@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestGPUMetricSetIdentity(t *testing.T) {
+	var m GPUMetric
+	for _, kv := range [][2]string{
+		{"UUID", "GPU-1"},
+		{"gpu", "3"},
+		{"modelName", "H100"},
+		{"host.name", "node-1"},
+		{"k8s.pod.name", "trainer-0"},
+		{"namespace", "ml"},
+		{"container", "worker"},
+		{"device", "nvidia3"},
+	} {
+		if !m.SetIdentity(kv[0], kv[1]) {
+			t.Errorf("expected %s to be an identity label", kv[0])
+		}
+	}
+	want := GPUMetric{UUID: "GPU-1", GPUID: 3, ModelName: "H100", Hostname: "node-1",
+		Pod: "trainer-0", Namespace: "ml", Container: "worker", Device: "nvidia3"}
+	if m.UUID != want.UUID || m.GPUID != want.GPUID || m.ModelName != want.ModelName || m.Hostname != want.Hostname ||
+		m.Pod != want.Pod || m.Namespace != want.Namespace || m.Container != want.Container || m.Device != want.Device {
+		t.Errorf("got %+v, want %+v", m, want)
+	}
+
+	if m.SetIdentity("pci_bus_id", "0000:01") {
+		t.Error("pci_bus_id is not an identity label")
+	}
+	if m.SetIdentity("gpu", "all") || m.GPUID != 3 {
+		t.Error("a non-numeric GPU index should be rejected and leave GPUID unchanged")
+	}
+}
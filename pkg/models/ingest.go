@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// IngestUnknownSource stands for the streamer of batches that could not be
+// decoded, whose source is unknown.
+const IngestUnknownSource = "unknown"
+
+// IngestStats counts what the collectors ingested from one streamer
+// instance in one hour, by when they received it.
+type IngestStats struct {
+	// Source is the streamer instance, as named in its batches
+	Source string `json:"source" example:"streamer-0"`
+
+	// Hour is the start of the hour counted
+	Hour time.Time `json:"hour"`
+
+	// Batches and Metrics count the batches accepted and the metrics in
+	// them, and Bytes the size of those batches as received
+	Batches int64 `json:"batches" example:"360"`
+	Metrics int64 `json:"metrics" example:"36000"`
+	Bytes   int64 `json:"bytes" example:"7340032"`
+
+	// RejectedBatches counts batches refused whole, because they could
+	// not be decoded or the producer's clock was skewed
+	RejectedBatches int64 `json:"rejected_batches"`
+
+	// DroppedMetrics counts metrics of accepted batches that were not
+	// stored: undecodable rows, late data and metrics past the
+	// cardinality budget
+	DroppedMetrics int64 `json:"dropped_metrics"`
+
+	// AvgBatchSize is the mean number of metrics per accepted batch
+	AvgBatchSize float64 `json:"avg_batch_size" example:"100"`
+}
+
+// Add adds other's counts to s and updates the average batch size.
+func (s *IngestStats) Add(other *IngestStats) {
+	s.Batches += other.Batches
+	s.Metrics += other.Metrics
+	s.Bytes += other.Bytes
+	s.RejectedBatches += other.RejectedBatches
+	s.DroppedMetrics += other.DroppedMetrics
+	s.AvgBatchSize = 0
+	if s.Batches > 0 {
+		s.AvgBatchSize = float64(s.Metrics) / float64(s.Batches)
+	}
+}
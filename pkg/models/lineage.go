@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// LineRange is an inclusive span of 1-based line numbers in a source file.
+// A streamer that loops over its file may wrap within one batch, in which
+// case First is greater than Last.
+type LineRange struct {
+	First int `json:"first" example:"2"`
+	Last  int `json:"last" example:"101"`
+}
+
+// BatchLineage records where a stored batch came from and when it moved
+// through the pipeline, so suspicious telemetry can be traced back to the
+// streamer and file that produced it.
+type BatchLineage struct {
+	BatchID string `json:"batch_id" example:"3f0c8a4e-5b7d-4c1a-9e2f-8d6b7a5c4e3f"`
+
+	// Source is the streamer instance that published the batch
+	Source      string     `json:"source" example:"streamer-0"`
+	SourceFile  string     `json:"source_file,omitempty" example:"/data/dcgm_metrics.csv"`
+	SourceLines *LineRange `json:"source_lines,omitempty"`
+
+	// Collector is the collector instance that stored the batch
+	Collector string `json:"collector" example:"collector-0"`
+	MQOffset  int64  `json:"mq_offset" example:"1024"`
+
+	// Kafka is set instead of MQOffset when the collector consumed the
+	// batch from Kafka
+	Kafka *KafkaPosition `json:"kafka,omitempty"`
+
+	MetricCount int      `json:"metric_count" example:"100"`
+	UUIDs       []string `json:"uuids"`
+	Hostnames   []string `json:"hostnames"`
+	MetricNames []string `json:"metric_names"`
+
+	// FirstTimestamp and LastTimestamp bound the metric timestamps in the batch
+	FirstTimestamp time.Time `json:"first_timestamp"`
+	LastTimestamp  time.Time `json:"last_timestamp"`
+
+	// Ingest timestamps: created by the streamer, accepted by the MQ,
+	// received and stored by the collector
+	CollectedAt time.Time `json:"collected_at"`
+	PublishedAt time.Time `json:"published_at"`
+	ReceivedAt  time.Time `json:"received_at"`
+	StoredAt    time.Time `json:"stored_at"`
+
+	// Replayed marks lineage recorded when the batch was re-ingested
+	Replayed bool `json:"replayed,omitempty"`
+
+	// Signer is the producer whose signature the collector verified, and
+	// Unverified why a batch stored under the flag policy failed verification
+	Signer     string `json:"signer,omitempty" example:"streamer-0"`
+	Unverified string `json:"unverified,omitempty"`
+}
+
+// KafkaPosition is the record a batch was consumed from.
+type KafkaPosition struct {
+	Topic     string `json:"topic" example:"gpu-telemetry"`
+	Partition int32  `json:"partition" example:"0"`
+	Offset    int64  `json:"offset" example:"1024"`
+}
+
+// NewBatchLineage describes batch from its contents. The caller fills in the
+// collector, MQ or Kafka position and ingest timestamps.
+func NewBatchLineage(batch *MetricBatch) *BatchLineage {
+	l := &BatchLineage{
+		BatchID:     batch.BatchID,
+		Source:      batch.Source,
+		SourceFile:  batch.SourceFile,
+		SourceLines: batch.SourceLines,
+		MetricCount: len(batch.Metrics),
+		UUIDs:       batch.UUIDs(),
+		Hostnames:   batch.Hostnames(),
+		MetricNames: batch.MetricNames(),
+		CollectedAt: batch.CollectedAt,
+	}
+	for i := range batch.Metrics {
+		ts := batch.Metrics[i].Timestamp
+		if l.FirstTimestamp.IsZero() || ts.Before(l.FirstTimestamp) {
+			l.FirstTimestamp = ts
+		}
+		if ts.After(l.LastTimestamp) {
+			l.LastTimestamp = ts
+		}
+	}
+	return l
+}
+
+// ReplayRequest selects stored batches to re-ingest, either by batch ID or by
+// when the collector received them.
+type ReplayRequest struct {
+	BatchIDs []string `json:"batch_ids,omitempty"`
+
+	// Start and End select batches received in [Start, End) when BatchIDs is empty
+	Start *time.Time `json:"start,omitempty" example:"2024-01-01T00:00:00Z"`
+	End   *time.Time `json:"end,omitempty" example:"2024-01-01T01:00:00Z"`
+
+	// Limit caps how many batches a time range selects
+	Limit int `json:"limit,omitempty" example:"100"`
+}
+
+// Validate checks that exactly one selector is given.
+func (r *ReplayRequest) Validate() error {
+	hasRange := r.Start != nil || r.End != nil
+	switch {
+	case len(r.BatchIDs) > 0 && hasRange:
+		return errors.New("give either batch_ids or start/end, not both")
+	case len(r.BatchIDs) > 0:
+		return nil
+	case r.Start == nil || r.End == nil:
+		return errors.New("batch_ids or both start and end are required")
+	case !r.End.After(*r.Start):
+		return perrors.WithCode(perrors.CodeInvalidRange, errors.New("end must be after start"))
+	case r.Limit < 0:
+		return errors.New("limit must not be negative")
+	}
+	return nil
+}
+
+// ReplaySkip is a selected batch that could not be re-ingested.
+type ReplaySkip struct {
+	BatchID string `json:"batch_id"`
+	Reason  string `json:"reason"`
+}
+
+// ReplayResult reports the outcome of a re-ingestion.
+type ReplayResult struct {
+	Replayed []string     `json:"replayed"`
+	Skipped  []ReplaySkip `json:"skipped"`
+	Metrics  int          `json:"metrics" example:"1200"`
+}
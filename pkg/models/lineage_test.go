@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewBatchLineage(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	batch := &MetricBatch{
+		BatchID:     "batch-1",
+		Source:      "streamer-0",
+		CollectedAt: base.Add(time.Minute),
+		SourceFile:  "/data/dcgm_metrics.csv",
+		SourceLines: &LineRange{First: 2, Last: 4},
+		Metrics: []GPUMetric{
+			{Timestamp: base.Add(2 * time.Second), MetricName: MetricGPUUtil, UUID: "GPU-2", Hostname: "host-001"},
+			{Timestamp: base, MetricName: MetricTemperature, UUID: "GPU-1", Hostname: "host-001"},
+			{Timestamp: base.Add(time.Second), MetricName: MetricGPUUtil, UUID: "GPU-1", Hostname: "host-002"},
+		},
+	}
+
+	l := NewBatchLineage(batch)
+	if l.BatchID != "batch-1" || l.Source != "streamer-0" || l.SourceFile != "/data/dcgm_metrics.csv" {
+		t.Errorf("unexpected provenance: %+v", l)
+	}
+	if l.SourceLines == nil || l.SourceLines.First != 2 || l.SourceLines.Last != 4 {
+		t.Errorf("unexpected source lines: %+v", l.SourceLines)
+	}
+	if l.MetricCount != 3 {
+		t.Errorf("expected 3 metrics, got %d", l.MetricCount)
+	}
+	if len(l.UUIDs) != 2 || l.UUIDs[0] != "GPU-1" {
+		t.Errorf("expected sorted unique uuids, got %v", l.UUIDs)
+	}
+	if len(l.Hostnames) != 2 || len(l.MetricNames) != 2 {
+		t.Errorf("unexpected hostnames %v or metric names %v", l.Hostnames, l.MetricNames)
+	}
+	if !l.FirstTimestamp.Equal(base) || !l.LastTimestamp.Equal(base.Add(2*time.Second)) {
+		t.Errorf("unexpected data range %v - %v", l.FirstTimestamp, l.LastTimestamp)
+	}
+	if !l.CollectedAt.Equal(batch.CollectedAt) {
+		t.Errorf("expected collected_at from batch, got %v", l.CollectedAt)
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Failure risk levels, from a prediction's score.
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Prediction is a GPU's risk of failing soon, scored by a model over a
+// window of the GPU's recent telemetry.
+type Prediction struct {
+	UUID     string `json:"uuid"`
+	Hostname string `json:"hostname"`
+
+	// Model names the model that scored the GPU
+	Model string `json:"model" example:"rules"`
+
+	// Score runs from 0 (no sign of trouble) to 1
+	Score float64 `json:"score" example:"0.65"`
+
+	// Risk is the score's level: low, medium or high
+	Risk string `json:"risk" example:"medium"`
+
+	// Reasons lists what raised the score, most significant first
+	Reasons []string `json:"reasons,omitempty"`
+
+	// WindowStart is the start of the telemetry window scored, which ends
+	// at ScoredAt
+	WindowStart time.Time `json:"window_start"`
+	ScoredAt    time.Time `json:"scored_at"`
+}
+
+// RiskLevel returns the risk level of a score: high from 0.7, medium from
+// 0.3 and low below.
+func RiskLevel(score float64) string {
+	switch {
+	case score >= 0.7:
+		return RiskHigh
+	case score >= 0.3:
+		return RiskMedium
+	default:
+		return RiskLow
+	}
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilterLabels are the label keys a saved filter can match, the series
+// tags telemetry is stored with besides UUID and hostname.
+var FilterLabels = []string{"gpu_id", "device", "model", "container", "pod", "namespace"}
+
+// filterNamePattern keeps saved filter names short and safe in URLs.
+var filterNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// SavedFilter is a named set of GPUs, hosts, metrics and labels that
+// telemetry queries reference by name, so dashboard URLs stay short and
+// every panel selects the same data.
+type SavedFilter struct {
+	// Name identifies the filter in URLs (?filter=name)
+	Name string `json:"name"`
+
+	// Description says what the filter selects
+	Description string `json:"description,omitempty"`
+
+	// UUIDs, Hostnames and Metrics each match any of their values; an empty
+	// list matches everything
+	UUIDs     []string `json:"uuids,omitempty"`
+	Hostnames []string `json:"hostnames,omitempty"`
+	Metrics   []string `json:"metrics,omitempty"`
+
+	// Labels must all match, keyed by one of FilterLabels
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// CreatedAt is when the filter was first saved
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt is when the filter was last changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the name, that the filter selects something, and that
+// every value can be embedded in a query.
+func (f *SavedFilter) Validate() error {
+	var errs []error
+	if !filterNamePattern.MatchString(f.Name) {
+		errs = append(errs, fmt.Errorf("name must be 1-64 letters, digits, '.', '_' or '-', got %q", f.Name))
+	}
+	if len(f.UUIDs) == 0 && len(f.Hostnames) == 0 && len(f.Metrics) == 0 && len(f.Labels) == 0 {
+		errs = append(errs, errors.New("at least one of uuids, hostnames, metrics or labels is required"))
+	}
+	for field, values := range map[string][]string{"uuids": f.UUIDs, "hostnames": f.Hostnames, "metrics": f.Metrics} {
+		for _, v := range values {
+			errs = append(errs, validateFilterValue(field, v))
+		}
+	}
+	for key, v := range f.Labels {
+		if !slices.Contains(FilterLabels, key) {
+			errs = append(errs, fmt.Errorf("labels: unknown label %q (want one of %s)", key, strings.Join(FilterLabels, ", ")))
+		}
+		errs = append(errs, validateFilterValue("labels."+key, v))
+	}
+	return errors.Join(errs...)
+}
+
+// validateFilterValue rejects empty values and characters that would need
+// escaping inside a query string.
+func validateFilterValue(field, v string) error {
+	if v == "" {
+		return fmt.Errorf("%s: values must not be empty", field)
+	}
+	if strings.ContainsAny(v, "\"\\") || strings.ContainsFunc(v, func(r rune) bool { return r < ' ' }) {
+		return fmt.Errorf("%s: %q contains a quote, backslash or control character", field, v)
+	}
+	return nil
+}
+
+// MatchesGPU reports whether a GPU's UUID and hostname pass the filter.
+func (f *SavedFilter) MatchesGPU(uuid, hostname string) bool {
+	return matchesAny(f.UUIDs, uuid) && matchesAny(f.Hostnames, hostname)
+}
+
+// Matches reports whether a metric passes every part of the filter.
+func (f *SavedFilter) Matches(m *GPUMetric) bool {
+	if !f.MatchesGPU(m.UUID, m.Hostname) || !matchesAny(f.Metrics, m.MetricName) {
+		return false
+	}
+	for key, want := range f.Labels {
+		if labelValue(m, key) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply narrows query to the filter's hosts, metrics and labels. It returns
+// false when the query cannot match: the filter excludes the query's GPU,
+// host or metric.
+func (f *SavedFilter) Apply(query *TelemetryQuery) bool {
+	if query.UUID != "" && !matchesAny(f.UUIDs, query.UUID) {
+		return false
+	}
+	if query.UUID == "" {
+		query.UUIDs = f.UUIDs
+	}
+	if query.Hostname != "" && !matchesAny(f.Hostnames, query.Hostname) {
+		return false
+	}
+	if query.MetricName != "" && !matchesAny(f.Metrics, query.MetricName) {
+		return false
+	}
+	if query.GPUID != nil && f.Labels["gpu_id"] != "" && f.Labels["gpu_id"] != strconv.Itoa(*query.GPUID) {
+		return false
+	}
+	query.Hostnames = f.Hostnames
+	query.MetricNames = f.Metrics
+	query.Labels = f.Labels
+	return true
+}
+
+// MatchesSets reports whether m passes the query's UUIDs, Hostnames,
+// MetricNames and Labels, for stores that filter in memory.
+func (q *TelemetryQuery) MatchesSets(m *GPUMetric) bool {
+	f := SavedFilter{UUIDs: q.UUIDs, Hostnames: q.Hostnames, Metrics: q.MetricNames, Labels: q.Labels}
+	return f.Matches(m)
+}
+
+// matchesAny reports whether v is one of values, or values is empty.
+func matchesAny(values []string, v string) bool {
+	return len(values) == 0 || slices.Contains(values, v)
+}
+
+// labelValue returns the value metric carries for one of FilterLabels.
+func labelValue(m *GPUMetric, key string) string {
+	switch key {
+	case "gpu_id":
+		return strconv.Itoa(m.GPUID)
+	case "device":
+		return m.Device
+	case "model":
+		return m.ModelName
+	case "container":
+		return m.Container
+	case "pod":
+		return m.Pod
+	case "namespace":
+		return m.Namespace
+	}
+	return ""
+}
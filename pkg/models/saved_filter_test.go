@@ -0,0 +1,76 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSavedFilterValidate(t *testing.T) {
+	valid := SavedFilter{Name: "rack-4", Hostnames: []string{"host-001"}, Labels: map[string]string{"namespace": "training"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid filter, got %v", err)
+	}
+
+	invalid := SavedFilter{Name: "rack 4", Labels: map[string]string{"rack": "4"}, Metrics: []string{""}, UUIDs: []string{`GPU-1" or true`}}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"name", "labels: unknown label", "metrics", "uuids"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+
+	if err := (&SavedFilter{Name: "everything"}).Validate(); err == nil || !strings.Contains(err.Error(), "at least one") {
+		t.Errorf("expected an empty filter to be rejected, got %v", err)
+	}
+}
+
+func TestSavedFilterMatches(t *testing.T) {
+	f := SavedFilter{
+		Hostnames: []string{"host-001", "host-002"},
+		Metrics:   []string{"DCGM_FI_DEV_GPU_UTIL"},
+		Labels:    map[string]string{"gpu_id": "0", "pod": "train-0"},
+	}
+	m := &GPUMetric{Hostname: "host-002", MetricName: "DCGM_FI_DEV_GPU_UTIL", GPUID: 0, Pod: "train-0"}
+	if !f.Matches(m) {
+		t.Error("expected metric to match")
+	}
+	for name, change := range map[string]func(*GPUMetric){
+		"host":   func(m *GPUMetric) { m.Hostname = "host-003" },
+		"metric": func(m *GPUMetric) { m.MetricName = "DCGM_FI_DEV_GPU_TEMP" },
+		"gpu":    func(m *GPUMetric) { m.GPUID = 1 },
+		"pod":    func(m *GPUMetric) { m.Pod = "" },
+	} {
+		other := *m
+		change(&other)
+		if f.Matches(&other) {
+			t.Errorf("%s: expected no match", name)
+		}
+	}
+}
+
+func TestSavedFilterApply(t *testing.T) {
+	f := SavedFilter{UUIDs: []string{"GPU-1"}, Metrics: []string{"DCGM_FI_DEV_GPU_UTIL"}, Labels: map[string]string{"gpu_id": "0"}}
+
+	q := &TelemetryQuery{}
+	if !f.Apply(q) || len(q.UUIDs) != 1 || len(q.MetricNames) != 1 || q.Labels["gpu_id"] != "0" {
+		t.Errorf("expected the filter's sets on the query, got %+v", q)
+	}
+	q = &TelemetryQuery{UUID: "GPU-1"}
+	if !f.Apply(q) || q.UUIDs != nil {
+		t.Errorf("expected a query for one of the filter's GPUs to keep its UUID only, got %+v", q)
+	}
+
+	one := 1
+	for name, q := range map[string]*TelemetryQuery{
+		"uuid":   {UUID: "GPU-2"},
+		"metric": {MetricName: "DCGM_FI_DEV_GPU_TEMP"},
+		"gpu_id": {GPUID: &one},
+	} {
+		if f.Apply(q) {
+			t.Errorf("%s: expected the filter to exclude the query", name)
+		}
+	}
+}
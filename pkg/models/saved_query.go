@@ -0,0 +1,220 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Delivery target types for saved query reports.
+const (
+	DeliveryWebhook = "webhook"
+	DeliveryEmail   = "email"
+	DeliveryS3      = "s3"
+)
+
+// Saved query run outcomes.
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// MinScheduleInterval is the shortest schedule a saved query may use.
+const MinScheduleInterval = time.Minute
+
+// SavedQuery is a telemetry query that the API runs on a schedule and
+// delivers to a target.
+type SavedQuery struct {
+	// ID uniquely identifies the saved query
+	ID string `json:"id"`
+
+	// Name is a human-readable label
+	Name string `json:"name"`
+
+	// Query selects the telemetry to report
+	Query SavedQuerySpec `json:"query"`
+
+	// Schedule is how often to run, as a Go duration (e.g., 1h, 24h)
+	Schedule string `json:"schedule"`
+
+	// Format is the report format: json or csv
+	Format string `json:"format"`
+
+	// Target is where reports are delivered
+	Target DeliveryTarget `json:"target"`
+
+	// Enabled pauses the schedule when false
+	Enabled bool `json:"enabled"`
+
+	// CreatedAt is when the saved query was registered
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt is when the saved query was last changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SavedQuerySpec is a telemetry query over a window relative to run time.
+type SavedQuerySpec struct {
+	UUID       string `json:"uuid,omitempty"`
+	Hostname   string `json:"hostname,omitempty"`
+	GPUID      *int   `json:"gpu_id,omitempty"`
+	MetricName string `json:"metric_name,omitempty"`
+
+	// Window is how far back from run time to query, as a Go duration
+	Window string `json:"window"`
+
+	// Limit caps the rows per report (0 = storage default)
+	Limit int `json:"limit,omitempty"`
+}
+
+// DeliveryTarget says where a report goes. Which fields apply depends on Type.
+type DeliveryTarget struct {
+	// Type is webhook, email or s3
+	Type string `json:"type"`
+
+	// URL receives an HTTP POST of the report (webhook)
+	URL string `json:"url,omitempty"`
+
+	// To lists recipient addresses (email)
+	To []string `json:"to,omitempty"`
+
+	// Bucket and Prefix locate the uploaded object (s3)
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// SavedQueryRun records one scheduled execution.
+type SavedQueryRun struct {
+	ID         string    `json:"id"`
+	QueryID    string    `json:"query_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Status     string    `json:"status"`
+	Rows       int       `json:"rows"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Interval returns the parsed schedule.
+func (q *SavedQuery) Interval() time.Duration {
+	d, _ := time.ParseDuration(q.Schedule)
+	return d
+}
+
+// TelemetryQuery resolves the spec's relative window against now.
+func (q *SavedQuery) TelemetryQuery(now time.Time) *TelemetryQuery {
+	window, _ := time.ParseDuration(q.Query.Window)
+	start := now.Add(-window)
+	return &TelemetryQuery{
+		UUID:       q.Query.UUID,
+		Hostname:   q.Query.Hostname,
+		GPUID:      q.Query.GPUID,
+		MetricName: q.Query.MetricName,
+		StartTime:  &start,
+		EndTime:    &now,
+		Limit:      q.Query.Limit,
+	}
+}
+
+// Validate checks the schedule, query window, format and delivery target.
+func (q *SavedQuery) Validate() error {
+	var errs []error
+	if q.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	// The name goes into email subjects and webhook headers
+	if strings.IndexFunc(q.Name, unicode.IsControl) >= 0 {
+		errs = append(errs, fmt.Errorf("name must not contain control characters, got %q", q.Name))
+	}
+	if d, err := time.ParseDuration(q.Schedule); err != nil || d < MinScheduleInterval {
+		errs = append(errs, fmt.Errorf("schedule must be a duration of at least %v, got %q", MinScheduleInterval, q.Schedule))
+	}
+	if d, err := time.ParseDuration(q.Query.Window); err != nil || d <= 0 {
+		errs = append(errs, fmt.Errorf("query.window must be a positive duration, got %q", q.Query.Window))
+	}
+	if q.Query.Limit < 0 {
+		errs = append(errs, fmt.Errorf("query.limit must not be negative, got %d", q.Query.Limit))
+	}
+	if q.Format != "json" && q.Format != "csv" {
+		errs = append(errs, fmt.Errorf("format must be json or csv, got %q", q.Format))
+	}
+	errs = append(errs, q.Target.validate())
+	return errors.Join(errs...)
+}
+
+// validate checks that the fields the target type needs are set.
+func (t *DeliveryTarget) validate() error {
+	switch t.Type {
+	case DeliveryWebhook:
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target.url must be an http(s) URL, got %q", t.URL)
+		}
+	case DeliveryEmail:
+		if len(t.To) == 0 {
+			return errors.New("target.to must list at least one recipient")
+		}
+		for _, to := range t.To {
+			if addr, err := mail.ParseAddress(to); err != nil || addr.Address != to {
+				return fmt.Errorf("target.to must list bare email addresses, got %q", to)
+			}
+		}
+	case DeliveryS3:
+		if t.Bucket == "" {
+			return errors.New("target.bucket is required")
+		}
+	default:
+		return fmt.Errorf("target.type must be webhook, email or s3, got %q", t.Type)
+	}
+	return nil
+}
+
+// DeliveryAllowlist limits where saved-query reports may go. An empty list
+// allows nothing of that type; "*" allows anything.
+type DeliveryAllowlist struct {
+	// WebhookHosts are the hosts webhook URLs may point at
+	WebhookHosts []string
+
+	// EmailDomains are the domains recipients may be at
+	EmailDomains []string
+
+	// S3Buckets are the buckets reports may be uploaded to
+	S3Buckets []string
+}
+
+// Check returns an error naming the first part of the target the allowlist
+// does not permit.
+func (a DeliveryAllowlist) Check(t DeliveryTarget) error {
+	switch t.Type {
+	case DeliveryWebhook:
+		u, err := url.Parse(t.URL)
+		if err != nil || !allowed(a.WebhookHosts, u.Hostname()) {
+			return fmt.Errorf("target.url host is not an allowed webhook host: %q", t.URL)
+		}
+	case DeliveryEmail:
+		for _, to := range t.To {
+			at := strings.LastIndex(to, "@")
+			if at < 0 || !allowed(a.EmailDomains, to[at+1:]) {
+				return fmt.Errorf("target.to recipient is not at an allowed domain: %q", to)
+			}
+		}
+	case DeliveryS3:
+		if !allowed(a.S3Buckets, t.Bucket) {
+			return fmt.Errorf("target.bucket is not an allowed bucket: %q", t.Bucket)
+		}
+	}
+	return nil
+}
+
+// allowed reports whether value is in list, ignoring case, or list has "*".
+func allowed(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == "*" || (value != "" && strings.EqualFold(entry, value)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSavedQueryValidate(t *testing.T) {
+	valid := SavedQuery{
+		Name:     "nightly",
+		Query:    SavedQuerySpec{Hostname: "host-001", Window: "24h"},
+		Schedule: "24h",
+		Format:   "csv",
+		Target:   DeliveryTarget{Type: DeliveryS3, Bucket: "reports"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid saved query, got %v", err)
+	}
+
+	invalid := SavedQuery{
+		Schedule: "30s",
+		Query:    SavedQuerySpec{Window: "-1h"},
+		Format:   "xml",
+		Target:   DeliveryTarget{Type: DeliveryWebhook, URL: "ftp://example.com"},
+	}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"name", "schedule", "query.window", "format", "target.url"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+}
+
+func TestSavedQueryValidateName(t *testing.T) {
+	q := SavedQuery{
+		Name:     "nightly\r\nBcc: x@evil.test",
+		Query:    SavedQuerySpec{Window: "24h"},
+		Schedule: "24h",
+		Format:   "csv",
+		Target:   DeliveryTarget{Type: DeliveryS3, Bucket: "reports"},
+	}
+	if err := q.Validate(); err == nil || !strings.Contains(err.Error(), "control characters") {
+		t.Errorf("expected control characters in the name refused, got %v", err)
+	}
+}
+
+func TestDeliveryTargetValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		target DeliveryTarget
+		valid  bool
+	}{
+		{"webhook", DeliveryTarget{Type: DeliveryWebhook, URL: "https://example.com/hook"}, true},
+		{"webhook without host", DeliveryTarget{Type: DeliveryWebhook, URL: "https://"}, false},
+		{"email", DeliveryTarget{Type: DeliveryEmail, To: []string{"ops@example.com"}}, true},
+		{"email without recipients", DeliveryTarget{Type: DeliveryEmail}, false},
+		{"email with display name", DeliveryTarget{Type: DeliveryEmail, To: []string{"Ops <ops@example.com>"}}, false},
+		{"email with header break", DeliveryTarget{Type: DeliveryEmail, To: []string{"ops@example.com\r\nBcc: x@evil.test"}}, false},
+		{"s3 without bucket", DeliveryTarget{Type: DeliveryS3}, false},
+		{"unknown type", DeliveryTarget{Type: "ftp"}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.target.validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%t, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestDeliveryAllowlistCheck(t *testing.T) {
+	allow := DeliveryAllowlist{
+		WebhookHosts: []string{"hooks.example.com"},
+		EmailDomains: []string{"example.com"},
+		S3Buckets:    []string{"reports"},
+	}
+	tests := []struct {
+		name   string
+		allow  DeliveryAllowlist
+		target DeliveryTarget
+		valid  bool
+	}{
+		{"webhook host", allow, DeliveryTarget{Type: DeliveryWebhook, URL: "https://HOOKS.example.com:8443/x"}, true},
+		{"webhook other host", allow, DeliveryTarget{Type: DeliveryWebhook, URL: "http://169.254.169.254/latest"}, false},
+		{"webhook userinfo", allow, DeliveryTarget{Type: DeliveryWebhook, URL: "https://hooks.example.com@evil.test/"}, false},
+		{"email domain", allow, DeliveryTarget{Type: DeliveryEmail, To: []string{"ops@example.com"}}, true},
+		{"email other domain", allow, DeliveryTarget{Type: DeliveryEmail, To: []string{"ops@example.com", "x@evil.test"}}, false},
+		{"email subdomain", allow, DeliveryTarget{Type: DeliveryEmail, To: []string{"ops@mail.example.com"}}, false},
+		{"s3 bucket", allow, DeliveryTarget{Type: DeliveryS3, Bucket: "reports"}, true},
+		{"s3 other bucket", allow, DeliveryTarget{Type: DeliveryS3, Bucket: "secrets"}, false},
+		{"empty allowlist", DeliveryAllowlist{}, DeliveryTarget{Type: DeliveryS3, Bucket: "reports"}, false},
+		{"wildcard", DeliveryAllowlist{WebhookHosts: []string{"*"}}, DeliveryTarget{Type: DeliveryWebhook, URL: "https://any.test/"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.allow.Check(tt.target); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%t, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestSavedQueryTelemetryQuery(t *testing.T) {
+	gpuID := 2
+	q := SavedQuery{Query: SavedQuerySpec{UUID: "GPU-1", GPUID: &gpuID, Window: "6h", Limit: 500}}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tq := q.TelemetryQuery(now)
+
+	if !tq.StartTime.Equal(now.Add(-6*time.Hour)) || !tq.EndTime.Equal(now) {
+		t.Errorf("expected window [%v, %v], got [%v, %v]", now.Add(-6*time.Hour), now, *tq.StartTime, *tq.EndTime)
+	}
+	if tq.UUID != "GPU-1" || tq.GPUID == nil || *tq.GPUID != 2 || tq.Limit != 500 {
+		t.Errorf("unexpected telemetry query %+v", tq)
+	}
+}
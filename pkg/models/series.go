@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Series aggregation functions.
+const (
+	SeriesMean = "mean"
+	SeriesMin  = "min"
+	SeriesMax  = "max"
+	SeriesLast = "last"
+)
+
+// IsSeriesFn reports whether fn is a known series aggregation function.
+func IsSeriesFn(fn string) bool {
+	return fn == SeriesMean || fn == SeriesMin || fn == SeriesMax || fn == SeriesLast
+}
+
+// SeriesQuery selects metrics per GPU, aggregated into fixed time buckets.
+type SeriesQuery struct {
+	// Metrics are the metric names to read
+	Metrics []string
+
+	// UUID and Hostname limit the query to one GPU or one host (optional)
+	UUID     string
+	Hostname string
+
+	// Start and End bound the query
+	Start time.Time
+	End   time.Time
+
+	// Every is the bucket width. Buckets are aligned to multiples of Every
+	// since the Unix epoch, so Start should be too.
+	Every time.Duration
+
+	// Fn aggregates each bucket's values; empty means mean
+	Fn string
+}
+
+// Validate checks the query is well formed.
+func (q *SeriesQuery) Validate() error {
+	var errs []error
+	if len(q.Metrics) == 0 {
+		errs = append(errs, errors.New("at least one metric is required"))
+	}
+	if !q.End.After(q.Start) {
+		errs = append(errs, perrors.WithCode(perrors.CodeInvalidRange, errors.New("end must be after start")))
+	}
+	if q.Every < time.Second {
+		errs = append(errs, fmt.Errorf("bucket width must be at least 1s, got %v", q.Every))
+	}
+	if q.Fn != "" && !IsSeriesFn(q.Fn) {
+		errs = append(errs, fmt.Errorf("aggregate must be mean, min, max or last, got %q", q.Fn))
+	}
+	return errors.Join(errs...)
+}
+
+// Series is one metric of one GPU, oldest bucket first. Buckets with no data
+// are left out.
+type Series struct {
+	Metric   string        `json:"metric"`
+	UUID     string        `json:"uuid"`
+	Hostname string        `json:"hostname"`
+	Points   []SeriesPoint `json:"points"`
+}
+
+// SeriesPoint is one bucket's aggregate, timed at the bucket's start.
+type SeriesPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeriesQueryValidate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := SeriesQuery{Metrics: []string{"DCGM_FI_DEV_GPU_TEMP"}, Start: start, End: start.Add(time.Hour), Every: time.Minute}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid query, got %v", err)
+	}
+
+	invalid := SeriesQuery{Start: start, End: start, Every: time.Millisecond, Fn: "median"}
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"metric", "end", "bucket", "aggregate"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+}
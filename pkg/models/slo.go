@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// SLOStatus reports compliance with the ingest-latency objective: the share
+// of metrics stored within the target latency of their collection.
+type SLOStatus struct {
+	// Objective is the share of metrics that must be stored in time
+	Objective float64 `json:"objective" example:"0.95"`
+
+	// Latency is the target time from collection to storage
+	Latency string `json:"latency" example:"30s"`
+
+	// Window is the sliding window compliance is computed over
+	Window string `json:"window" example:"24h0m0s"`
+
+	// Compliance is the share of the window's metrics stored in time; 1
+	// when none were stored
+	Compliance float64 `json:"compliance" example:"0.987"`
+
+	// Met reports whether compliance reaches the objective
+	Met bool `json:"met"`
+
+	// Metrics counts the window's metrics and Late those stored past the
+	// target latency. Replayed batches are not counted
+	Metrics int64 `json:"metrics" example:"1200000"`
+	Late    int64 `json:"late" example:"15600"`
+
+	// BudgetRemaining is the share of the window's error budget left; it
+	// goes below 0 once the objective is missed
+	BudgetRemaining float64 `json:"budget_remaining" example:"0.74"`
+
+	// BurnRates reports how fast the error budget is being spent over the
+	// alerting windows, as multiples of the sustainable rate
+	BurnRates []SLOBurnRate `json:"burn_rates"`
+
+	// BurnRateThreshold is the burn rate both windows must reach to alert
+	BurnRateThreshold float64 `json:"burn_rate_threshold" example:"14.4"`
+
+	// Burning reports whether the burn-rate alert is firing, and since when
+	Burning      bool      `json:"burning"`
+	BurningSince time.Time `json:"burning_since,omitempty"`
+
+	// EvaluatedAt is when the objective was last evaluated
+	EvaluatedAt time.Time `json:"evaluated_at"`
+
+	// LastError is the last evaluation's error, if it failed
+	LastError string `json:"last_error,omitempty"`
+}
+
+// SLOBurnRate is the error budget's burn rate over one alerting window.
+type SLOBurnRate struct {
+	Window   string  `json:"window" example:"1h0m0s"`
+	BurnRate float64 `json:"burn_rate" example:"2.5"`
+	Metrics  int64   `json:"metrics" example:"50000"`
+	Late     int64   `json:"late" example:"6250"`
+}
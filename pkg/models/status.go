@@ -0,0 +1,92 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// GPU health levels, from best to worst.
+const (
+	HealthHealthy  = "healthy"
+	HealthWarning  = "warning"
+	HealthCritical = "critical"
+	HealthStale    = "stale"
+)
+
+// GPUStatus is the materialized current status of one GPU: the latest
+// values of the key metrics, its alert state and a health score. The API
+// keeps one per GPU up to date in storage, so the fleet overview is a single
+// read however many GPUs there are.
+type GPUStatus struct {
+	UUID      string `json:"uuid"`
+	GPUID     int    `json:"gpu_id"`
+	Device    string `json:"device"`
+	ModelName string `json:"model_name"`
+	Hostname  string `json:"hostname"`
+
+	// LastSeen is when the GPU last reported telemetry
+	LastSeen time.Time `json:"last_seen"`
+
+	// Metrics holds the latest value of each key metric the GPU reports
+	Metrics map[string]float64 `json:"metrics"`
+
+	// Health is healthy, warning, critical or stale (no telemetry lately)
+	Health string `json:"health" example:"warning"`
+
+	// HealthScore runs from 100 (nothing wrong) down to 0; each firing
+	// alert takes off more the more severe it is, and a stale GPU scores 0
+	HealthScore int `json:"health_score" example:"80"`
+
+	// Firing and Pending count the GPU's alerts in each state
+	Firing  int `json:"firing"`
+	Pending int `json:"pending"`
+
+	// Severity is the highest severity among the firing alerts
+	Severity string `json:"severity,omitempty"`
+
+	// Rules names the rules of the firing alerts
+	Rules []string `json:"rules,omitempty"`
+
+	// UpdatedAt is when the status was computed
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// ChangedAt is when the health, health score or alerts last changed
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SameHealth reports whether two statuses of a GPU agree on its health,
+// health score and alerts, ignoring metric values and timestamps.
+func (s *GPUStatus) SameHealth(other *GPUStatus) bool {
+	return s.Health == other.Health && s.HealthScore == other.HealthScore &&
+		s.Firing == other.Firing && s.Pending == other.Pending &&
+		s.Severity == other.Severity && slices.Equal(s.Rules, other.Rules)
+}
+
+// FleetStatusSummary counts the GPUs of a fleet overview by health.
+type FleetStatusSummary struct {
+	GPUs     int            `json:"gpus"`
+	ByHealth map[string]int `json:"by_health"`
+
+	// Firing counts the firing alerts across the GPUs
+	Firing int `json:"firing"`
+
+	// UpdatedAt is when the oldest status was computed, showing how
+	// current the overview is
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// SummarizeStatuses counts statuses by health.
+func SummarizeStatuses(statuses []*GPUStatus) FleetStatusSummary {
+	summary := FleetStatusSummary{
+		GPUs:     len(statuses),
+		ByHealth: map[string]int{HealthHealthy: 0, HealthWarning: 0, HealthCritical: 0, HealthStale: 0},
+	}
+	for _, s := range statuses {
+		summary.ByHealth[s.Health]++
+		summary.Firing += s.Firing
+		if summary.UpdatedAt.IsZero() || s.UpdatedAt.Before(summary.UpdatedAt) {
+			summary.UpdatedAt = s.UpdatedAt
+		}
+	}
+	return summary
+}
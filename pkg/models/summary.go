@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// FleetSummary summarizes all the telemetry stored for the fleet.
+type FleetSummary struct {
+	// TotalGPUs is the number of distinct GPUs with stored telemetry
+	TotalGPUs int `json:"total_gpus" example:"64"`
+
+	// TotalMetrics is the number of stored telemetry points
+	TotalMetrics int64 `json:"total_metrics" example:"1250000"`
+
+	// GPUsByModel counts the GPUs of each model. GPUs reporting no model
+	// are left out.
+	GPUsByModel map[string]int `json:"gpus_by_model"`
+
+	// Daily counts the points stored per UTC day, oldest first. Days
+	// without points are left out.
+	Daily []DailyVolume `json:"daily"`
+
+	// OldestMetric and NewestMetric are the timestamps of the oldest and
+	// newest stored points; zero when nothing is stored
+	OldestMetric time.Time `json:"oldest_metric,omitempty"`
+	NewestMetric time.Time `json:"newest_metric,omitempty"`
+}
+
+// DailyVolume is the number of points stored for one UTC day.
+type DailyVolume struct {
+	Day     time.Time `json:"day"`
+	Metrics int64     `json:"metrics" example:"86400"`
+}
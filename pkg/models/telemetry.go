@@ -3,6 +3,10 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
 	"time"
 )
 
@@ -44,6 +48,17 @@ type GPUMetric struct {
 
 	// Labels contains additional key-value metadata from the original telemetry
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// BatchID is the batch the metric was ingested in; see /api/v1/batches/{id}
+	BatchID string `json:"batch_id,omitempty"`
+
+	// Late marks a metric the collector received past its late-data
+	// threshold while tagging late data
+	Late bool `json:"late,omitempty"`
+
+	// Skewed marks a metric from a batch whose producer clock was off by
+	// more than the collector's clock-skew threshold while flagging skew
+	Skewed bool `json:"skewed,omitempty"`
 }
 
 // GPUInfo represents summary information about a GPU.
@@ -68,6 +83,12 @@ type MetricBatch struct {
 	// CollectedAt is when the batch was created
 	CollectedAt time.Time `json:"collected_at"`
 
+	// SourceFile is the file the streamer read the batch from (optional)
+	SourceFile string `json:"source_file,omitempty"`
+
+	// SourceLines is the span of file lines read into the batch (optional)
+	SourceLines *LineRange `json:"source_lines,omitempty"`
+
 	// Metrics is the list of GPU metrics in this batch
 	Metrics []GPUMetric `json:"metrics"`
 }
@@ -86,6 +107,14 @@ type TelemetryQuery struct {
 	// MetricName filters by metric type
 	MetricName string `json:"metric_name,omitempty"`
 
+	// UUIDs, Hostnames and MetricNames narrow the query further to any of
+	// their values, and Labels to points with all of these series tags, as
+	// a saved filter does
+	UUIDs       []string          `json:"uuids,omitempty"`
+	Hostnames   []string          `json:"hostnames,omitempty"`
+	MetricNames []string          `json:"metric_names,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+
 	// StartTime is the inclusive start of the time window
 	StartTime *time.Time `json:"start_time,omitempty"`
 
@@ -99,6 +128,12 @@ type TelemetryQuery struct {
 	Offset int `json:"offset,omitempty"`
 }
 
+// FiniteValue reports whether v can be a metric value. Batches are JSON on
+// the MQ, which cannot carry NaN or ±Inf, so parsers reject such values.
+func FiniteValue(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
 // ToJSON serializes the GPUMetric to JSON bytes.
 func (m *GPUMetric) ToJSON() ([]byte, error) {
 	return json.Marshal(m)
@@ -119,6 +154,70 @@ func (b *MetricBatch) FromJSON(data []byte) error {
 	return json.Unmarshal(data, b)
 }
 
+// WriteMetricsCSV writes metrics as CSV with a header row, in the column order
+// used by telemetry exports.
+func WriteMetricsCSV(w io.Writer, metrics []*GPUMetric) {
+	WriteMetricsCSVHeader(w)
+	WriteMetricsCSVRows(w, metrics)
+}
+
+// WriteMetricsCSVHeader writes the header line of WriteMetricsCSV, for
+// writers that add rows in several calls.
+func WriteMetricsCSVHeader(w io.Writer) {
+	fmt.Fprintf(w, "Timestamp,MetricName,GPUID,Device,UUID,ModelName,Hostname,Container,Pod,Namespace,Value\n")
+}
+
+// WriteMetricsCSVRows writes metrics as WriteMetricsCSV does, without the header.
+func WriteMetricsCSVRows(w io.Writer, metrics []*GPUMetric) {
+	for _, m := range metrics {
+		fmt.Fprintf(w, "%s,%s,%d,%s,%s,%s,%s,%s,%s,%s,%.2f\n",
+			m.Timestamp.Format(time.RFC3339),
+			m.MetricName,
+			m.GPUID,
+			m.Device,
+			m.UUID,
+			m.ModelName,
+			m.Hostname,
+			m.Container,
+			m.Pod,
+			m.Namespace,
+			m.Value,
+		)
+	}
+}
+
+// Hostnames returns the sorted, de-duplicated hostnames present in the batch.
+func (b *MetricBatch) Hostnames() []string {
+	return b.uniqueValues(func(m *GPUMetric) string { return m.Hostname })
+}
+
+// UUIDs returns the sorted, de-duplicated GPU UUIDs present in the batch.
+func (b *MetricBatch) UUIDs() []string {
+	return b.uniqueValues(func(m *GPUMetric) string { return m.UUID })
+}
+
+// MetricNames returns the sorted, de-duplicated metric names present in the batch.
+func (b *MetricBatch) MetricNames() []string {
+	return b.uniqueValues(func(m *GPUMetric) string { return m.MetricName })
+}
+
+// uniqueValues collects the distinct non-empty values of a metric field.
+func (b *MetricBatch) uniqueValues(field func(m *GPUMetric) string) []string {
+	seen := make(map[string]struct{})
+	for i := range b.Metrics {
+		if v := field(&b.Metrics[i]); v != "" {
+			seen[v] = struct{}{}
+		}
+	}
+
+	values := make([]string, 0, len(seen))
+	for v := range seen {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
 // Common DCGM metric names.
 const (
 	MetricGPUUtil     = "DCGM_FI_DEV_GPU_UTIL"
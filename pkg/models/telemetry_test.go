@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -127,6 +128,19 @@ func TestMetricBatchFromJSON(t *testing.T) {
 	}
 }
 
+func TestFiniteValue(t *testing.T) {
+	for _, v := range []float64{0, -1.5, math.MaxFloat64} {
+		if !FiniteValue(v) {
+			t.Errorf("FiniteValue(%v) = false", v)
+		}
+	}
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if FiniteValue(v) {
+			t.Errorf("FiniteValue(%v) = true", v)
+		}
+	}
+}
+
 func TestMetricUnit(t *testing.T) {
 	tests := []struct {
 		metricName string
@@ -288,3 +302,24 @@ func TestGPUMetricWithKubernetesFields(t *testing.T) {
 		t.Error("Container not preserved")
 	}
 }
+
+func TestMetricBatchHostnamesAndMetricNames(t *testing.T) {
+	batch := MetricBatch{
+		Metrics: []GPUMetric{
+			{Hostname: "host-b", MetricName: MetricGPUUtil},
+			{Hostname: "host-a", MetricName: MetricTemperature},
+			{Hostname: "host-b", MetricName: MetricGPUUtil},
+			{Hostname: "", MetricName: ""},
+		},
+	}
+
+	hosts := batch.Hostnames()
+	if len(hosts) != 2 || hosts[0] != "host-a" || hosts[1] != "host-b" {
+		t.Errorf("expected sorted unique hostnames [host-a host-b], got %v", hosts)
+	}
+
+	names := batch.MetricNames()
+	if len(names) != 2 || names[0] != MetricTemperature || names[1] != MetricGPUUtil {
+		t.Errorf("expected sorted unique metric names, got %v", names)
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Relations between topology nodes. Links follow the data: from streamer to
+// MQ, MQ to collector, collector to storage and storage to API.
+const (
+	RelationPublishes = "publishes"
+	RelationConsumes  = "consumes"
+	RelationWrites    = "writes"
+	RelationReads     = "reads"
+)
+
+// NodeKindStorage is the kind of the storage nodes in a topology; the other
+// nodes are components, of the kind they announce themselves as.
+const NodeKindStorage = "storage"
+
+// PipelineTopology is the live wiring of the deployment, assembled from the
+// heartbeats its components send the MQ server.
+type PipelineTopology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Links []TopologyLink `json:"links"`
+
+	// GeneratedAt is when the heartbeats were listed
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// TopologyNode is a running component or a storage bucket in use.
+type TopologyNode struct {
+	// ID identifies the node in links
+	ID string `json:"id" example:"collector/collector-1"`
+
+	// Kind is the component, such as "streamer", or "storage"
+	Kind string `json:"kind" example:"collector"`
+
+	// Name is the component's instance ID, or the storage bucket
+	Name string `json:"name" example:"collector-1"`
+
+	Version string `json:"version,omitempty" example:"1.2.0"`
+	Host    string `json:"host,omitempty" example:"collector-7d9f"`
+
+	// Addr is the address the component's heartbeats came from
+	Addr string `json:"addr,omitempty" example:"10.0.3.14:53122"`
+
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// LastSeen is when the component last sent a heartbeat; unset for
+	// storage
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// TopologyLink is data flowing between two nodes.
+type TopologyLink struct {
+	From     string `json:"from" example:"mq-server/0.0.0.0:9000"`
+	To       string `json:"to" example:"collector/collector-1"`
+	Relation string `json:"relation" example:"consumes"`
+}
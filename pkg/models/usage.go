@@ -0,0 +1,46 @@
+package models
+
+// AnonymousTenant meters requests made without a tenant or admin token.
+const AnonymousTenant = "anonymous"
+
+// UsageCounters is API consumption over a period.
+type UsageCounters struct {
+	// Requests is how many API requests were served
+	Requests int64 `json:"requests"`
+
+	// Rows is how many telemetry data points responses returned
+	Rows int64 `json:"rows"`
+
+	// ExportBytes is how many bytes telemetry exports and export downloads sent
+	ExportBytes int64 `json:"export_bytes"`
+}
+
+// UsageQuota is the monthly limit on each counter; 0 is unlimited.
+type UsageQuota UsageCounters
+
+// Exceeded names the first counter of usage that has reached its quota.
+func (q UsageQuota) Exceeded(usage UsageCounters) (string, bool) {
+	switch {
+	case q.Requests > 0 && usage.Requests >= q.Requests:
+		return "requests", true
+	case q.Rows > 0 && usage.Rows >= q.Rows:
+		return "rows", true
+	case q.ExportBytes > 0 && usage.ExportBytes >= q.ExportBytes:
+		return "export_bytes", true
+	}
+	return "", false
+}
+
+// TenantUsage is one tenant's API consumption in a calendar month (UTC).
+type TenantUsage struct {
+	// Tenant is the tenant name, "admin", or "anonymous"
+	Tenant string `json:"tenant" example:"acme"`
+
+	// Month is the metered month as YYYY-MM
+	Month string `json:"month" example:"2026-10"`
+
+	UsageCounters
+
+	// Quota is the tenant's monthly quota; absent when it has none
+	Quota *UsageQuota `json:"quota,omitempty"`
+}
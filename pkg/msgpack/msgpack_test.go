@@ -0,0 +1,153 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalWireFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"true", true, []byte{0xc3}},
+		{"positive fixint", 7, []byte{0x07}},
+		{"negative fixint", -3, []byte{0xfd}},
+		{"uint8", 200, []byte{0xcc, 0xc8}},
+		{"int16", -300, []byte{0xd1, 0xfe, 0xd4}},
+		{"uint32", 70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{"float64", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"fixstr", "abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{"str8", strings.Repeat("x", 40), append([]byte{0xd9, 40}, strings.Repeat("x", 40)...)},
+		{"bin", []byte{1, 2}, []byte{0xc4, 2, 1, 2}},
+		{"array", []int{1, 2}, []byte{0x92, 1, 2}},
+		{"nil slice", []int(nil), []byte{0xc0}},
+		{"sorted map", map[string]int{"b": 2, "a": 1}, []byte{0x82, 0xa1, 'a', 1, 0xa1, 'b', 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Marshal(%v) = % x, want % x", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+type inner struct {
+	Host string `json:"host"`
+}
+
+type sample struct {
+	inner
+	Name     string            `json:"name"`
+	Value    float64           `json:"value"`
+	Skipped  string            `json:"-"`
+	Optional string            `json:"optional,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	At       time.Time         `json:"at"`
+	Raw      json.RawMessage   `json:"raw"`
+	Ptr      *int              `json:"ptr"`
+	private  int
+}
+
+// TestMatchesJSON checks a value decodes to the same tree from msgpack as
+// from JSON, which is what lets clients switch formats freely.
+func TestMatchesJSON(t *testing.T) {
+	v := sample{
+		inner: inner{Host: "node-1"},
+		Name:  "DCGM_FI_DEV_GPU_UTIL",
+		Value: 87.5,
+		At:    time.Date(2025, 7, 18, 13, 42, 33, 123000000, time.UTC),
+		Raw:   json.RawMessage(`{"n": 3, "xs": [1.5, "a", null]}`),
+	}
+
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want any
+	if err := json.Unmarshal(jsonData, &want); err != nil {
+		t.Fatal(err)
+	}
+	// JSON has no integers; compare after normalizing msgpack ints to float64
+	if !equalTrees(normalize(got), want) {
+		t.Errorf("msgpack tree %v\ndiffers from JSON tree %v", got, want)
+	}
+}
+
+func normalize(v any) any {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case []any:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = normalize(v[k])
+		}
+	}
+	return v
+}
+
+func equalTrees(a, b any) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	values := []any{
+		nil, false, int64(-1), int64(-129), int64(math.MinInt64), int64(math.MaxInt64),
+		uint64(math.MaxUint64), 3.25, "", strings.Repeat("y", 70000), []byte{0xff},
+		[]any{int64(1), "two", []any{}}, map[string]any{"a": map[string]any{"b": nil}},
+	}
+	for _, v := range values {
+		data, err := Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Decode(data)
+		if err != nil {
+			t.Fatalf("Decode(Marshal(%.20v)): %v", v, err)
+		}
+		if !equalTrees(got, v) {
+			t.Errorf("round trip of %.20v gave %.20v", v, got)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, data := range [][]byte{
+		{},
+		{0xa5, 'a'},                    // string shorter than its length
+		{0xdd, 0xff, 0xff, 0xff, 0xff}, // array longer than the data
+		{0x81, 0x01, 0x02},             // integer map key
+		{0xd4, 0x01, 0x00},             // extension
+		{0x01, 0x02},                   // trailing data
+	} {
+		if _, err := Decode(data); err == nil {
+			t.Errorf("Decode(% x) succeeded", data)
+		}
+	}
+}
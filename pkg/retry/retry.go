@@ -0,0 +1,196 @@
+// Package retry provides a configurable retry policy shared by pipeline
+// components, with exponential backoff, jitter, a retryable-error predicate
+// and per-policy metrics.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/config"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+// Policy describes how an operation is retried.
+type Policy struct {
+	// Name identifies the policy in metrics (e.g., "streamer-publish")
+	Name string
+
+	// MaxAttempts is the total number of attempts, including the first.
+	// Zero or negative means retry until the context is done.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between attempts
+	MaxBackoff time.Duration
+
+	// Multiplier grows the backoff after each retry (1 for a fixed delay)
+	Multiplier float64
+
+	// Jitter randomizes each wait by up to this fraction (0 to 1) in either direction
+	Jitter float64
+
+	// Retryable decides whether an error is worth retrying.
+	// Defaults to errors.IsRetryable from pkg/errors.
+	Retryable func(error) bool
+
+	// OnRetry is called before waiting for the next attempt, typically for logging
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// FromConfig builds a policy from component configuration.
+func FromConfig(name string, cfg config.RetryConfig) Policy {
+	return Policy{
+		Name:           name,
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		MaxBackoff:     cfg.MaxBackoff,
+		Multiplier:     cfg.Multiplier,
+		Jitter:         cfg.Jitter,
+	}
+}
+
+// Backoff returns the wait before the given retry (1 for the first retry), without jitter.
+func (p Policy) Backoff(retry int) time.Duration {
+	if retry < 1 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+
+	wait := float64(p.InitialBackoff) * math.Pow(mult, float64(retry-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+	return time.Duration(wait)
+}
+
+// Wait returns the backoff before the given retry with jitter applied.
+func (p Policy) Wait(retry int) time.Duration {
+	d := p.Backoff(retry)
+	if p.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	jitter := math.Min(p.Jitter, 1)
+	return time.Duration(float64(d) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// RetryAfterError is implemented by errors that say how long to wait
+// before retrying, such as a publish the server throttled.
+type RetryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// Do runs op until it succeeds, returns a non-retryable error, the attempts
+// are exhausted, or ctx is done. It returns op's last error, or ctx.Err() if
+// the context ended while waiting between attempts. A RetryAfterError in
+// op's error waits at least as long as it says, even past MaxBackoff.
+func (p Policy) Do(ctx context.Context, op func(ctx context.Context) error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = perrors.IsRetryable
+	}
+	m := metricsFor(p.Name)
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m.attempts.Add(1)
+		err := op(ctx)
+		if err == nil {
+			m.successes.Add(1)
+			return nil
+		}
+		if !retryable(err) || (p.MaxAttempts > 0 && attempt >= p.MaxAttempts) {
+			m.failures.Add(1)
+			return err
+		}
+
+		wait := p.Wait(attempt)
+		var after RetryAfterError
+		if errors.As(err, &after) && after.RetryAfter() > wait {
+			wait = after.RetryAfter()
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		m.retries.Add(1)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			m.failures.Add(1)
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Stats is a snapshot of a policy's retry metrics.
+type Stats struct {
+	Name      string `json:"name"`
+	Attempts  int64  `json:"attempts"`
+	Retries   int64  `json:"retries"`
+	Successes int64  `json:"successes"`
+	Failures  int64  `json:"failures"`
+}
+
+// metrics holds the counters for one policy name.
+type metrics struct {
+	attempts  atomic.Int64
+	retries   atomic.Int64
+	successes atomic.Int64
+	failures  atomic.Int64
+}
+
+var registry sync.Map // policy name -> *metrics
+
+func metricsFor(name string) *metrics {
+	if m, ok := registry.Load(name); ok {
+		return m.(*metrics)
+	}
+	m, _ := registry.LoadOrStore(name, &metrics{})
+	return m.(*metrics)
+}
+
+// Snapshot returns the metrics of every policy that has run, sorted by name.
+func Snapshot() []Stats {
+	var out []Stats
+	registry.Range(func(key, value any) bool {
+		m := value.(*metrics)
+		out = append(out, Stats{
+			Name:      key.(string),
+			Attempts:  m.attempts.Load(),
+			Retries:   m.retries.Load(),
+			Successes: m.successes.Load(),
+			Failures:  m.failures.Load(),
+		})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// StatsFor returns the metrics for a single policy name.
+func StatsFor(name string) Stats {
+	for _, s := range Snapshot() {
+		if s.Name == name {
+			return s
+		}
+	}
+	return Stats{Name: name}
+}
@@ -0,0 +1,129 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+)
+
+func TestBackoff(t *testing.T) {
+	p := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2}
+
+	want := []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for retry, w := range want {
+		if got := p.Backoff(retry); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", retry, got, w)
+		}
+	}
+}
+
+func TestJitterStaysInRange(t *testing.T) {
+	p := Policy{InitialBackoff: 100 * time.Millisecond, Multiplier: 1, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := p.Wait(1); d < 50*time.Millisecond || d > 150*time.Millisecond {
+			t.Fatalf("jittered wait %v out of range", d)
+		}
+	}
+}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	p := Policy{Name: "test-transient", MaxAttempts: 5, InitialBackoff: time.Millisecond, Multiplier: 1}
+
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return perrors.Transient(errors.New("busy"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+
+	stats := StatsFor("test-transient")
+	if stats.Attempts != 3 || stats.Retries != 2 || stats.Successes != 1 || stats.Failures != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+// throttled is a transient error asking for a wait before retrying.
+type throttled struct{ wait time.Duration }
+
+func (e throttled) Error() string             { return "throttled" }
+func (e throttled) ErrorKind() perrors.Kind   { return perrors.KindTransient }
+func (e throttled) RetryAfter() time.Duration { return e.wait }
+
+func TestDoHonorsRetryAfter(t *testing.T) {
+	p := Policy{Name: "test-retry-after", MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	var waits []time.Duration
+	p.OnRetry = func(attempt int, err error, wait time.Duration) { waits = append(waits, wait) }
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("publish: %w", throttled{wait: 20 * time.Millisecond})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if len(waits) != 1 || waits[0] != 20*time.Millisecond {
+		t.Errorf("expected one 20ms wait, got %v", waits)
+	}
+}
+
+func TestDoStopsOnPermanentError(t *testing.T) {
+	p := Policy{Name: "test-permanent", MaxAttempts: 5, InitialBackoff: time.Millisecond}
+
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return perrors.Permanent(errors.New("rejected"))
+	})
+	if !perrors.IsPermanent(err) {
+		t.Errorf("expected permanent error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestDoExhaustsAttempts(t *testing.T) {
+	p := Policy{Name: "test-exhaust", MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("unclassified")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("expected 3 failed calls, got %d (err=%v)", calls, err)
+	}
+	if StatsFor("test-exhaust").Failures != 1 {
+		t.Error("expected one recorded failure")
+	}
+}
+
+func TestDoStopsWhenContextDone(t *testing.T) {
+	p := Policy{Name: "test-ctx", InitialBackoff: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := p.Do(ctx, func(ctx context.Context) error {
+		return perrors.Transient(errors.New("down"))
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+// Package schema generates JSON Schema documents from Go structs and
+// validates JSON against them, so producers written in other languages can
+// check payloads against the same definitions the pipeline decodes with.
+//
+// Schemas follow the encoding/json rules: field names come from json tags,
+// fields without omitempty are required, time.Time is an RFC 3339 string and
+// json.RawMessage accepts any value. Only the keywords the generator emits
+// are validated.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated documents.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// defsPrefix is how generated schemas refer to their shared definitions.
+const defsPrefix = "#/$defs/"
+
+// Generate returns the schema of v's type, which must be a struct or a
+// pointer to one. Named structs it contains are shared through $defs.
+func Generate(v any, id string) *Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	g := &generator{defs: make(map[string]*Schema)}
+	root := g.object(t)
+	root.Schema, root.ID, root.Title = Draft, id, t.Name()
+	if len(g.defs) > 0 {
+		root.Defs = g.defs
+	}
+	return root
+}
+
+// generator accumulates the definitions of named structs.
+type generator struct {
+	defs map[string]*Schema
+}
+
+// of returns the schema of a type.
+func (g *generator) of(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.of(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.object(t)
+		}
+		return &Schema{Ref: defsPrefix + t.Name()}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Type: "array", Items: g.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.of(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	return &Schema{}
+}
+
+// object returns the schema of a struct's JSON fields.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+	sort.Strings(s.Required)
+	return s
+}
+
+// fields adds a struct's JSON fields to s, flattening embedded structs as
+// encoding/json does.
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.fields(ft, s)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.of(f.Type)
+		if !strings.Contains(","+opts+",", ",omitempty,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// maxErrors bounds the violations reported for one document.
+const maxErrors = 20
+
+// Validate checks that data is JSON matching the schema. Every violation is
+// reported, up to a limit, with its path (e.g. $.metrics[3].value).
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	vd := &validator{root: s}
+	vd.check(s, v, "$")
+	if vd.dropped > 0 {
+		vd.errs = append(vd.errs, fmt.Errorf("and %d more", vd.dropped))
+	}
+	return errors.Join(vd.errs...)
+}
+
+// validator collects the violations found in one document.
+type validator struct {
+	root    *Schema
+	errs    []error
+	dropped int
+}
+
+func (vd *validator) fail(path, format string, args ...any) {
+	if len(vd.errs) >= maxErrors {
+		vd.dropped++
+		return
+	}
+	vd.errs = append(vd.errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (vd *validator) check(s *Schema, v any, path string) {
+	if s.Ref != "" {
+		def, ok := vd.root.Defs[strings.TrimPrefix(s.Ref, defsPrefix)]
+		if !ok {
+			vd.fail(path, "unresolved reference %s", s.Ref)
+			return
+		}
+		s = def
+	}
+
+	switch s.Type {
+	case "":
+		return
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			vd.fail(path, "must be an object, got %s", kind(v))
+			return
+		}
+		for _, name := range s.Required {
+			if value, ok := obj[name]; !ok || value == nil {
+				vd.fail(path, "missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := obj[name]
+			prop, ok := s.Properties[name]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			// Unknown properties are ignored, as encoding/json does, and an
+			// optional null decodes to the zero value
+			if prop == nil || value == nil {
+				continue
+			}
+			vd.check(prop, value, path+"."+name)
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			vd.fail(path, "must be an array, got %s", kind(v))
+			return
+		}
+		for i, item := range arr {
+			vd.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			vd.fail(path, "must be a string, got %s", kind(v))
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				vd.fail(path, "must be an RFC 3339 date-time, got %q", str)
+			}
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			vd.fail(path, "must be an integer, got %s", kind(v))
+			return
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			vd.fail(path, "must be an integer, got %s", n)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			vd.fail(path, "must be a number, got %s", kind(v))
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			vd.fail(path, "must be a boolean, got %s", kind(v))
+		}
+	}
+}
+
+// kind names a decoded JSON value's type for error messages.
+func kind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", v)
+}
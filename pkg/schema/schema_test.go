@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Name string `json:"name"`
+}
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type sample struct {
+	base
+	At      time.Time         `json:"at"`
+	Count   int               `json:"count"`
+	Ratio   float64           `json:"ratio,omitempty"`
+	On      bool              `json:"on,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Items   []inner           `json:"items"`
+	Parent  *inner            `json:"parent,omitempty"`
+	Raw     json.RawMessage   `json:"raw,omitempty"`
+	Skipped string            `json:"-"`
+	hidden  string
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(&sample{}, "https://example.com/sample.json")
+	if s.Schema != Draft || s.Title != "sample" || s.Type != "object" {
+		t.Errorf("root = %+v", s)
+	}
+	if got := strings.Join(s.Required, ","); got != "at,count,id,items" {
+		t.Errorf("required = %s", got)
+	}
+	if _, ok := s.Properties["Skipped"]; ok {
+		t.Error("json:\"-\" field is in the schema")
+	}
+	if p := s.Properties["at"]; p.Type != "string" || p.Format != "date-time" {
+		t.Errorf("time property = %+v", p)
+	}
+	if p := s.Properties["items"]; p.Type != "array" || p.Items.Ref != "#/$defs/inner" {
+		t.Errorf("items property = %+v", p)
+	}
+	if s.Defs["inner"] == nil || s.Properties["parent"].Ref != "#/$defs/inner" {
+		t.Errorf("defs = %+v", s.Defs)
+	}
+	if p := s.Properties["tags"]; p.AdditionalProperties == nil || p.AdditionalProperties.Type != "string" {
+		t.Errorf("map property = %+v", p)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	s := Generate(sample{}, "")
+
+	valid := `{"id": "a", "at": "2024-01-01T00:00:00.123456789Z", "count": 3, "ratio": 1,
+		"items": [{"name": "x"}], "parent": null, "raw": {"any": [1]}, "extra": true}`
+	if err := s.Validate([]byte(valid)); err != nil {
+		t.Errorf("Validate(valid) = %v", err)
+	}
+
+	invalid := `{"at": "yesterday", "count": 1.5, "ratio": "high", "on": 1,
+		"tags": {"k": 2}, "items": [{}, {"name": 7}]}`
+	err := s.Validate([]byte(invalid))
+	if err == nil {
+		t.Fatal("Validate(invalid) succeeded")
+	}
+	for _, want := range []string{
+		`$: missing required property "id"`,
+		"$.at: must be an RFC 3339 date-time",
+		"$.count: must be an integer, got 1.5",
+		"$.ratio: must be a number, got string",
+		"$.on: must be a boolean",
+		"$.tags.k: must be a string",
+		`$.items[0]: missing required property "name"`,
+		"$.items[1].name: must be a string, got number",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %q:\n%v", want, err)
+		}
+	}
+
+	if err := s.Validate([]byte(`{"id": `)); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("Validate(truncated) = %v", err)
+	}
+}
@@ -0,0 +1,470 @@
+// Package sdk helps programs other than the streamer publish GPU metrics to
+// the pipeline. A Producer takes metrics one at a time and handles what the
+// streamer does with them: it batches them by size and interval, publishes
+// each batch with retries and backoff, keeps batches while the MQ is
+// unreachable, spilling them to disk once memory is full, and flushes on
+// Close.
+//
+//	client := mq.NewClient(mq.DefaultClientConfig())
+//	if err := client.Connect(); err != nil {
+//		log.Fatal(err)
+//	}
+//	cfg := sdk.DefaultConfig("node-exporter-bridge")
+//	cfg.SpillDir = "/var/lib/node-exporter-bridge/spill"
+//	producer, err := sdk.NewProducer(client, cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer producer.Close(context.Background())
+//
+//	producer.Add(models.GPUMetric{UUID: "GPU-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: 45, Timestamp: time.Now()})
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/retry"
+)
+
+var (
+	// ErrClosed is returned by Add after Close.
+	ErrClosed = errors.New("sdk: producer is closed")
+
+	// ErrBufferFull is returned by Add while MaxBuffered metrics are waiting
+	// to be published and there is no spill directory to take more.
+	ErrBufferFull = errors.New("sdk: buffer full")
+)
+
+// Publisher publishes a payload and returns the log offset the MQ assigned
+// it. *mq.Client is a Publisher.
+type Publisher interface {
+	PublishConfirmed(ctx context.Context, payload []byte, metadata map[string]string) (mq.Offset, error)
+}
+
+// Config configures a Producer.
+type Config struct {
+	// Source identifies the producer in each batch, like a streamer's instance ID
+	Source string
+
+	// MaxBatchSize publishes a batch as soon as it holds this many metrics
+	MaxBatchSize int
+
+	// FlushInterval publishes a partial batch at least this often
+	FlushInterval time.Duration
+
+	// PublishTimeout bounds each publish attempt
+	PublishTimeout time.Duration
+
+	// Retry governs each round of publish attempts. A batch that still fails
+	// with a retryable error is kept and tried again after FlushInterval;
+	// any other error drops it.
+	Retry retry.Policy
+
+	// MaxBuffered caps the metrics held in memory, published or not
+	MaxBuffered int
+
+	// SpillDir holds batches beyond MaxBuffered, and those still unpublished
+	// at Close, until they are published, by this producer or a later one
+	// using the same directory. Empty makes Add fail once memory is full.
+	SpillDir string
+
+	// Clock paces flushes and stamps batches (default clock.Real)
+	Clock clock.Clock
+
+	// Logger reports retried, spilled and dropped batches (default log.Default())
+	Logger *log.Logger
+}
+
+// DefaultConfig returns the streamer's batching and retry settings for a
+// producer identified by source.
+func DefaultConfig(source string) Config {
+	return Config{
+		Source:         source,
+		MaxBatchSize:   1000,
+		FlushInterval:  5 * time.Second,
+		PublishTimeout: 5 * time.Second,
+		Retry: retry.Policy{
+			Name:           "sdk-publish",
+			MaxAttempts:    5,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     10 * time.Second,
+			Multiplier:     2,
+			Jitter:         0.2,
+		},
+		MaxBuffered: 100000,
+	}
+}
+
+func (c Config) validate() error {
+	var errs []error
+	if c.Source == "" {
+		errs = append(errs, errors.New("source must be set"))
+	}
+	if c.MaxBatchSize <= 0 {
+		errs = append(errs, fmt.Errorf("max_batch_size must be positive, got %d", c.MaxBatchSize))
+	}
+	if c.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("flush_interval must be positive, got %v", c.FlushInterval))
+	}
+	if c.PublishTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("publish_timeout must be positive, got %v", c.PublishTimeout))
+	}
+	if c.MaxBuffered < c.MaxBatchSize {
+		errs = append(errs, fmt.Errorf("max_buffered (%d) must be at least max_batch_size (%d)", c.MaxBuffered, c.MaxBatchSize))
+	}
+	return errors.Join(errs...)
+}
+
+// Stats counts what a producer has done.
+type Stats struct {
+	Added            int64 `json:"added"`
+	Rejected         int64 `json:"rejected"`
+	BatchesPublished int64 `json:"batches_published"`
+	MetricsPublished int64 `json:"metrics_published"`
+	BatchesDropped   int64 `json:"batches_dropped"`
+	MetricsDropped   int64 `json:"metrics_dropped"`
+
+	// Buffered is the number of metrics in memory waiting to be published
+	Buffered int `json:"buffered"`
+
+	// Spilled is the number of batches on disk waiting to be published
+	Spilled int `json:"spilled"`
+
+	// LastOffset is the MQ offset of the most recently published batch
+	LastOffset mq.Offset `json:"last_offset"`
+}
+
+// sealed is a batch waiting in memory, with its place in publish order.
+type sealed struct {
+	seq   uint64
+	batch *models.MetricBatch
+}
+
+// Producer batches metrics and publishes them in the background. It is safe
+// for concurrent use.
+type Producer struct {
+	pub    Publisher
+	cfg    Config
+	logger *log.Logger
+
+	mu      sync.Mutex
+	current []models.GPUMetric // the batch being filled
+	queue   []sealed           // sealed batches in memory, oldest first
+	queued  int                // metrics in queue
+	spill   *spill             // nil without SpillDir
+	seq     uint64             // sequence number of the last sealed batch
+	closed  bool
+	idle    []chan struct{} // closed once nothing is left to publish
+	stats   Stats
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewProducer starts a producer publishing through pub. Batches spilled to
+// cfg.SpillDir by an earlier producer are published first.
+func NewProducer(pub Publisher, cfg Config) (*Producer, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("sdk: invalid config: %w", err)
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.Default()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Producer{
+		pub:    pub,
+		cfg:    cfg,
+		logger: cfg.Logger,
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if cfg.SpillDir != "" {
+		s, last, err := openSpill(cfg.SpillDir)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		p.spill, p.seq = s, last
+		if n := s.len(); n > 0 {
+			p.logger.Printf("Producer %s: publishing %d batches spilled by an earlier run", cfg.Source, n)
+			p.signal()
+		}
+	}
+
+	go p.run()
+	return p, nil
+}
+
+// Add buffers a metric for publishing. It never waits for the MQ. It
+// returns ErrBufferFull when memory is full and there is no spill
+// directory, and ErrClosed after Close.
+func (p *Producer) Add(m models.GPUMetric) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if p.spill == nil && p.queued+len(p.current) >= p.cfg.MaxBuffered {
+		p.stats.Rejected++
+		return ErrBufferFull
+	}
+
+	p.current = append(p.current, m)
+	p.stats.Added++
+	if len(p.current) >= p.cfg.MaxBatchSize {
+		p.sealLocked()
+		p.signal()
+	}
+	return nil
+}
+
+// Flush publishes everything added so far, including spilled batches, and
+// waits until it is published or ctx is done.
+func (p *Producer) Flush(ctx context.Context) error {
+	p.mu.Lock()
+	p.sealLocked()
+	if p.emptyLocked() {
+		p.mu.Unlock()
+		return nil
+	}
+	idle := make(chan struct{})
+	p.idle = append(p.idle, idle)
+	p.mu.Unlock()
+	p.signal()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting metrics and publishes what is left until ctx is
+// done. Batches still unpublished then are spilled for the next producer
+// using the spill directory, or dropped without one; either way Close
+// reports them.
+func (p *Producer) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	flushErr := p.Flush(ctx)
+	p.cancel()
+	<-p.done
+	if flushErr == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	left, metrics := len(p.queue), p.queued
+	for _, s := range p.queue {
+		if p.spill == nil {
+			p.dropLocked(s.batch, flushErr)
+		} else if err := p.spill.push(s.seq, s.batch); err != nil {
+			p.dropLocked(s.batch, err)
+		}
+	}
+	p.queue, p.queued = nil, 0
+
+	if p.spill == nil {
+		return fmt.Errorf("sdk: dropped %d unpublished batches (%d metrics): %w", left, metrics, flushErr)
+	}
+	return fmt.Errorf("sdk: %d batches left unpublished in %s: %w", p.spill.len(), p.cfg.SpillDir, flushErr)
+}
+
+// Stats returns the producer's counters.
+func (p *Producer) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := p.stats
+	st.Buffered = p.queued + len(p.current)
+	st.Spilled = p.spill.len()
+	return st
+}
+
+// signal wakes the publishing goroutine.
+func (p *Producer) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sealLocked closes the batch being filled and queues it for publishing.
+func (p *Producer) sealLocked() {
+	if len(p.current) == 0 {
+		return
+	}
+	p.seq++
+	batch := &models.MetricBatch{
+		BatchID:     uuid.New().String(),
+		Source:      p.cfg.Source,
+		CollectedAt: p.cfg.Clock.Now(),
+		Metrics:     p.current,
+	}
+	p.current = nil
+
+	// While older batches wait on disk, newer ones follow them there so they
+	// are published in order
+	if p.spill != nil && (p.spill.len() > 0 || p.queued+len(batch.Metrics) > p.cfg.MaxBuffered) {
+		if err := p.spill.push(p.seq, batch); err != nil {
+			p.dropLocked(batch, err)
+		}
+		return
+	}
+	p.queue = append(p.queue, sealed{seq: p.seq, batch: batch})
+	p.queued += len(batch.Metrics)
+}
+
+// emptyLocked reports whether nothing is waiting to be published.
+func (p *Producer) emptyLocked() bool {
+	return len(p.current) == 0 && len(p.queue) == 0 && p.spill.len() == 0
+}
+
+// dropLocked counts and logs a batch that will never be published.
+func (p *Producer) dropLocked(batch *models.MetricBatch, err error) {
+	p.stats.BatchesDropped++
+	p.stats.MetricsDropped += int64(len(batch.Metrics))
+	p.logger.Printf("Producer %s: data loss: dropped batch %s (%d metrics): %v", p.cfg.Source, batch.BatchID, len(batch.Metrics), err)
+}
+
+// run seals a batch every FlushInterval and publishes whatever is queued.
+func (p *Producer) run() {
+	defer close(p.done)
+	ticker := p.cfg.Clock.NewTicker(p.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C():
+			p.mu.Lock()
+			p.sealLocked()
+			p.mu.Unlock()
+		case <-p.wake:
+		}
+		p.drain()
+	}
+}
+
+// drain publishes queued batches, memory before disk and so oldest first,
+// until none are left or the oldest cannot be published yet.
+func (p *Producer) drain() {
+	retryable := p.cfg.Retry.Retryable
+	if retryable == nil {
+		retryable = perrors.IsRetryable
+	}
+
+	for {
+		p.mu.Lock()
+		var batch *models.MetricBatch
+		fromSpill := len(p.queue) == 0
+		if !fromSpill {
+			batch = p.queue[0].batch
+		} else if p.spill.len() > 0 {
+			var err error
+			if batch, err = p.spill.peek(); err != nil {
+				p.stats.BatchesDropped++
+				p.logger.Printf("Producer %s: data loss: %v", p.cfg.Source, err)
+				p.spill.pop()
+				p.mu.Unlock()
+				continue
+			}
+		}
+		if batch == nil {
+			if len(p.current) == 0 {
+				for _, idle := range p.idle {
+					close(idle)
+				}
+				p.idle = nil
+			}
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		offset, err := p.publish(batch)
+
+		p.mu.Lock()
+		if err != nil && (p.ctx.Err() != nil || retryable(err)) {
+			// Keep the batch at the head of the queue for the next round
+			p.mu.Unlock()
+			if p.ctx.Err() == nil {
+				p.logger.Printf("Producer %s: failed to publish batch %s, keeping it to retry: %v", p.cfg.Source, batch.BatchID, err)
+			}
+			return
+		}
+
+		if fromSpill {
+			if popErr := p.spill.pop(); popErr != nil {
+				p.logger.Printf("Producer %s: %v", p.cfg.Source, popErr)
+			}
+		} else {
+			p.queue = p.queue[1:]
+			p.queued -= len(batch.Metrics)
+		}
+		if err != nil {
+			p.dropLocked(batch, err)
+		} else {
+			p.stats.BatchesPublished++
+			p.stats.MetricsPublished += int64(len(batch.Metrics))
+			p.stats.LastOffset = offset
+		}
+		p.mu.Unlock()
+	}
+}
+
+// publish sends a batch with the routing metadata the MQ filters on,
+// retrying per the policy.
+func (p *Producer) publish(batch *models.MetricBatch) (mq.Offset, error) {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return 0, perrors.Permanent(err)
+	}
+	metadata := map[string]string{
+		mq.MetaHostname:    mq.JoinMetadataSet(batch.Hostnames()),
+		mq.MetaMetricName:  mq.JoinMetadataSet(batch.MetricNames()),
+		mq.MetaRecordCount: strconv.Itoa(len(batch.Metrics)),
+
+		// Retries keep the batch ID, so the MQ drops those it already appended
+		mq.MetaIdempotencyKey: batch.BatchID,
+	}
+
+	var offset mq.Offset
+	err = p.cfg.Retry.Do(p.ctx, func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, p.cfg.PublishTimeout)
+		defer cancel()
+		var err error
+		offset, err = p.pub.PublishConfirmed(attemptCtx, payload, metadata)
+		return err
+	})
+	return offset, err
+}
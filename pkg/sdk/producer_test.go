@@ -0,0 +1,306 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/internal/mq"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/clock"
+	perrors "github.com/cisco/gpu-telemetry-pipeline/pkg/errors"
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakePublisher records published batches, failing while fail returns an error.
+type fakePublisher struct {
+	mu       sync.Mutex
+	batches  []models.MetricBatch
+	metadata []map[string]string
+	attempts int
+	fail     func(attempt int) error
+}
+
+func (f *fakePublisher) PublishConfirmed(ctx context.Context, payload []byte, metadata map[string]string) (mq.Offset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.fail != nil {
+		if err := f.fail(f.attempts); err != nil {
+			return 0, err
+		}
+	}
+	var batch models.MetricBatch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return 0, err
+	}
+	f.batches = append(f.batches, batch)
+	f.metadata = append(f.metadata, metadata)
+	return mq.Offset(len(f.batches)), nil
+}
+
+func (f *fakePublisher) setFail(fail func(attempt int) error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+// values returns the value of every published metric, in publish order.
+func (f *fakePublisher) values() []float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var values []float64
+	for _, b := range f.batches {
+		for _, m := range b.Metrics {
+			values = append(values, m.Value)
+		}
+	}
+	return values
+}
+
+func testConfig(sim *clock.Simulated) Config {
+	cfg := DefaultConfig("test-producer")
+	cfg.MaxBatchSize = 3
+	cfg.MaxBuffered = 100
+	cfg.Retry.InitialBackoff = time.Millisecond
+	cfg.Retry.MaxBackoff = time.Millisecond
+	cfg.Retry.MaxAttempts = 3
+	cfg.Clock = sim
+	cfg.Logger = log.New(io.Discard, "", 0)
+	return cfg
+}
+
+func metric(v int) models.GPUMetric {
+	return models.GPUMetric{UUID: "GPU-1", Hostname: "node-1", MetricName: "DCGM_FI_DEV_GPU_TEMP", Value: float64(v), Timestamp: start}
+}
+
+func addAll(t *testing.T, p *Producer, from, to int) {
+	t.Helper()
+	for v := from; v < to; v++ {
+		if err := p.Add(metric(v)); err != nil {
+			t.Fatalf("Add(%d) failed: %v", v, err)
+		}
+	}
+}
+
+func flush(t *testing.T, p *Producer) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v (stats %+v)", err, p.Stats())
+	}
+}
+
+func assertValues(t *testing.T, pub *fakePublisher, n int) {
+	t.Helper()
+	values := pub.values()
+	if len(values) != n {
+		t.Fatalf("expected %d metrics published, got %d: %v", n, len(values), values)
+	}
+	for i, v := range values {
+		if v != float64(i) {
+			t.Fatalf("metrics published out of order: %v", values)
+		}
+	}
+}
+
+func TestProducerBatchesBySize(t *testing.T) {
+	pub := &fakePublisher{}
+	p, err := NewProducer(pub, testConfig(clock.NewSimulated(start)))
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+
+	addAll(t, p, 0, 7)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	assertValues(t, pub, 7)
+	if len(pub.batches) != 3 || len(pub.batches[2].Metrics) != 1 {
+		t.Errorf("expected batches of 3, 3 and 1, got %d batches", len(pub.batches))
+	}
+	b := pub.batches[0]
+	if b.Source != "test-producer" || b.BatchID == "" || !b.CollectedAt.Equal(start) {
+		t.Errorf("batch not stamped: %+v", b)
+	}
+	if md := pub.metadata[0]; md[mq.MetaRecordCount] != "3" || md[mq.MetaHostname] != "node-1" {
+		t.Errorf("unexpected metadata %v", md)
+	}
+
+	st := p.Stats()
+	if st.Added != 7 || st.BatchesPublished != 3 || st.MetricsPublished != 7 || st.LastOffset != 3 || st.Buffered != 0 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+	if err := p.Add(metric(7)); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestProducerFlushesOnInterval(t *testing.T) {
+	sim := clock.NewSimulated(start)
+	pub := &fakePublisher{}
+	p, err := NewProducer(pub, testConfig(sim))
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	addAll(t, p, 0, 2)
+	sim.BlockUntil(1)
+	sim.Advance(5 * time.Second)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(pub.values()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("partial batch not published on the flush interval: %+v", p.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assertValues(t, pub, 2)
+}
+
+func TestProducerRetries(t *testing.T) {
+	pub := &fakePublisher{fail: func(attempt int) error {
+		if attempt <= 2 {
+			return perrors.Transient(errors.New("connection reset"))
+		}
+		return nil
+	}}
+	p, err := NewProducer(pub, testConfig(clock.NewSimulated(start)))
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	addAll(t, p, 0, 3)
+	flush(t, p)
+	assertValues(t, pub, 3)
+	if pub.attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", pub.attempts)
+	}
+}
+
+func TestProducerDropsOnPermanentError(t *testing.T) {
+	pub := &fakePublisher{fail: func(attempt int) error {
+		if attempt == 1 {
+			return perrors.Validation(errors.New("payload does not match schema"))
+		}
+		return nil
+	}}
+	p, err := NewProducer(pub, testConfig(clock.NewSimulated(start)))
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	addAll(t, p, 0, 6)
+	flush(t, p)
+	if st := p.Stats(); st.BatchesDropped != 1 || st.MetricsDropped != 3 || st.BatchesPublished != 1 {
+		t.Errorf("expected the rejected batch dropped and the next published, got %+v", st)
+	}
+}
+
+func TestProducerBufferFull(t *testing.T) {
+	pub := &fakePublisher{fail: func(int) error { return perrors.Transient(errors.New("mq down")) }}
+	cfg := testConfig(clock.NewSimulated(start))
+	cfg.MaxBuffered = 6
+	p, err := NewProducer(pub, cfg)
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+
+	addAll(t, p, 0, 6)
+	if err := p.Add(metric(6)); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Close(ctx); err == nil {
+		t.Fatal("expected Close to report unpublished batches")
+	}
+	if st := p.Stats(); st.Rejected != 1 || st.MetricsDropped != 6 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestProducerSpillsAndResumes(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig(clock.NewSimulated(start))
+	cfg.MaxBuffered = 6
+	cfg.SpillDir = dir
+
+	down := &fakePublisher{fail: func(int) error { return perrors.Transient(errors.New("mq down")) }}
+	p, err := NewProducer(down, cfg)
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+
+	// Memory holds two batches; the rest go to disk
+	addAll(t, p, 0, 20)
+	if st := p.Stats(); st.Rejected != 0 || st.Spilled < 4 || st.Buffered > cfg.MaxBuffered+cfg.MaxBatchSize {
+		t.Fatalf("expected overflow spilled to disk, got %+v", p.Stats())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Close(ctx); err == nil {
+		t.Fatal("expected Close to report unpublished batches")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 7 {
+		t.Fatalf("expected all 7 batches on disk after Close, got %d files", len(entries))
+	}
+
+	// A later producer publishes them first, in their original order
+	up := &fakePublisher{}
+	p, err = NewProducer(up, cfg)
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+	addAll(t, p, 20, 22)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	assertValues(t, up, 22)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("expected the spill directory emptied, got %d files", len(entries))
+	}
+}
+
+func TestProducerResumesAfterOutage(t *testing.T) {
+	cfg := testConfig(clock.NewSimulated(start))
+	cfg.MaxBuffered = 6
+	cfg.SpillDir = t.TempDir()
+	pub := &fakePublisher{fail: func(int) error { return perrors.Transient(errors.New("mq down")) }}
+	p, err := NewProducer(pub, cfg)
+	if err != nil {
+		t.Fatalf("NewProducer failed: %v", err)
+	}
+	defer p.Close(context.Background())
+
+	addAll(t, p, 0, 15)
+	pub.setFail(nil)
+	flush(t, p)
+	assertValues(t, pub, 15)
+	if st := p.Stats(); st.Spilled != 0 || st.Buffered != 0 {
+		t.Errorf("expected nothing left after the outage, got %+v", st)
+	}
+}
+
+func TestNewProducerValidates(t *testing.T) {
+	cfg := DefaultConfig("")
+	cfg.MaxBuffered = 10
+	if _, err := NewProducer(&fakePublisher{}, cfg); err == nil {
+		t.Error("expected an error without a source and with max_buffered below max_batch_size")
+	}
+}
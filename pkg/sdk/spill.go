@@ -0,0 +1,114 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/models"
+)
+
+// spillExt names spilled batch files. Each holds one JSON batch and is
+// named by the batch's zero-padded sequence number, so sorting the names
+// restores publish order.
+const spillExt = ".batch.json"
+
+// spill keeps sealed batches on disk while the memory buffer is full or the
+// producer shuts down before publishing them. It is not safe for concurrent
+// use; the producer guards it.
+type spill struct {
+	dir   string
+	files []string // oldest first
+}
+
+// openSpill opens dir, creating it if needed, and picks up batches spilled
+// by an earlier producer. It returns the highest sequence number found.
+func openSpill(dir string) (*spill, uint64, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, fmt.Errorf("sdk: failed to create spill directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("sdk: failed to read spill directory: %w", err)
+	}
+
+	s := &spill{dir: dir}
+	var last uint64
+	for _, e := range entries {
+		name := e.Name()
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spillExt), 10, 64)
+		if e.IsDir() || !strings.HasSuffix(name, spillExt) || err != nil {
+			continue
+		}
+		s.files = append(s.files, name)
+		last = max(last, seq)
+	}
+	sort.Strings(s.files)
+	return s, last, nil
+}
+
+// push writes a batch under its sequence number. The file is renamed into
+// place so a crash never leaves a partial batch behind.
+func (s *spill) push(seq uint64, batch *models.MetricBatch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%020d%s", seq, spillExt)
+	tmp := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("sdk: failed to spill batch %s: %w", batch.BatchID, err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("sdk: failed to spill batch %s: %w", batch.BatchID, err)
+	}
+
+	// Batches are usually spilled in order; those put back at shutdown are older
+	s.files = append(s.files, name)
+	if n := len(s.files); n > 1 && s.files[n-2] > name {
+		sort.Strings(s.files)
+	}
+	return nil
+}
+
+// peek reads the oldest spilled batch, or returns nil if there is none.
+func (s *spill) peek() (*models.MetricBatch, error) {
+	if len(s.files) == 0 {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, s.files[0]))
+	if err != nil {
+		return nil, fmt.Errorf("sdk: failed to read spilled batch %s: %w", s.files[0], err)
+	}
+	var batch models.MetricBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, fmt.Errorf("sdk: corrupt spilled batch %s: %w", s.files[0], err)
+	}
+	return &batch, nil
+}
+
+// pop removes the oldest spilled batch.
+func (s *spill) pop() error {
+	if len(s.files) == 0 {
+		return nil
+	}
+	name := s.files[0]
+	s.files = s.files[1:]
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("sdk: failed to remove spilled batch %s: %w", name, err)
+	}
+	return nil
+}
+
+// len returns the number of spilled batches.
+func (s *spill) len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.files)
+}
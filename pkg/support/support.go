@@ -0,0 +1,220 @@
+// Package support gathers what a bug report needs from a running
+// component: its build, its configuration with secrets redacted, its recent
+// log lines, its own statistics and a dump of its goroutines. Each
+// component serves a Report over HTTP, and pipelinectl support collects
+// them all into one tarball.
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cisco/gpu-telemetry-pipeline/pkg/buildinfo"
+)
+
+// DefaultLogLines is how many recent log lines components keep for reports.
+const DefaultLogLines = 1000
+
+// Redacted replaces the values of secret configuration fields.
+const Redacted = "[redacted]"
+
+// secretKeys are substrings of configuration keys whose values are redacted.
+var secretKeys = []string{"token", "secret", "password", "key", "headers"}
+
+// Report is one component's support report.
+type Report struct {
+	Build       buildinfo.Info `json:"build"`
+	CollectedAt time.Time      `json:"collected_at"`
+
+	// Config is the component's configuration, with secrets redacted
+	Config json.RawMessage `json:"config"`
+
+	// Stats holds the component's statistics by name, such as queue or storage
+	Stats map[string]any `json:"stats"`
+
+	// Logs are the most recent log lines, oldest first
+	Logs []string `json:"logs"`
+
+	// Goroutines is a dump of every goroutine's stack
+	Goroutines string `json:"goroutines"`
+}
+
+// Logs keeps the most recent lines written to it. Use it as a logger's
+// output alongside stdout. It is safe for concurrent use.
+type Logs struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int // where the next line goes once lines is full
+	partial []byte
+}
+
+// NewLogs creates a buffer keeping the last n lines.
+func NewLogs(n int) *Logs {
+	if n <= 0 {
+		n = DefaultLogLines
+	}
+	return &Logs{lines: make([]string, 0, n)}
+}
+
+// Write records the complete lines in p, keeping a trailing partial line
+// until the rest of it is written.
+func (l *Logs) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data := append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		l.add(string(data[:i]))
+		data = data[i+1:]
+	}
+	l.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+func (l *Logs) add(line string) {
+	if len(l.lines) < cap(l.lines) {
+		l.lines = append(l.lines, line)
+		return
+	}
+	l.lines[l.next] = line
+	l.next = (l.next + 1) % len(l.lines)
+}
+
+// Lines returns the lines kept, oldest first.
+func (l *Logs) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := make([]string, 0, len(l.lines))
+	lines = append(lines, l.lines[l.next:]...)
+	return append(lines, l.lines[:l.next]...)
+}
+
+// Source builds the support reports of one component.
+type Source struct {
+	component string
+	config    any
+	logs      *Logs
+
+	mu    sync.Mutex
+	stats map[string]func() any
+}
+
+// NewSource creates a source reporting component's config, redacted, and
+// the lines kept by logs, which may be nil.
+func NewSource(component string, config any, logs *Logs) *Source {
+	return &Source{component: component, config: config, logs: logs, stats: make(map[string]func() any)}
+}
+
+// AddStats includes the value fn returns, at the time of each report,
+// under name in the report's stats.
+func (s *Source) AddStats(name string, fn func() any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[name] = fn
+}
+
+// Report collects the component's report now.
+func (s *Source) Report() Report {
+	r := Report{
+		Build:       buildinfo.Get(s.component),
+		CollectedAt: time.Now().UTC(),
+		Stats:       make(map[string]any),
+		Logs:        []string{},
+	}
+
+	config, err := Redact(s.config)
+	if err != nil {
+		config, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	r.Config = config
+
+	s.mu.Lock()
+	for name, fn := range s.stats {
+		r.Stats[name] = fn()
+	}
+	s.mu.Unlock()
+
+	if s.logs != nil {
+		r.Logs = s.logs.Lines()
+	}
+
+	var dump bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&dump, 2)
+	r.Goroutines = dump.String()
+	return r
+}
+
+// Handler serves the component's report as JSON.
+func (s *Source) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Report())
+	}
+}
+
+// Redact returns config as JSON with the values of fields whose names
+// contain token, secret, password, key or headers replaced by Redacted.
+// Fields already left out of JSON, like most secrets, stay out.
+func Redact(config any) (json.RawMessage, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redact(v))
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if isSecret(k) && !isEmpty(val) {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = redact(val)
+		}
+	case []any:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
+
+func isSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmpty reports whether a value is unset, so unset secrets stay visibly unset.
+func isEmpty(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package support
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogsKeepsRecentLines(t *testing.T) {
+	logs := NewLogs(3)
+	logger := log.New(logs, "", 0)
+	for i := 1; i <= 5; i++ {
+		logger.Printf("line %d", i)
+	}
+
+	got := logs.Lines()
+	if len(got) != 3 || got[0] != "line 3" || got[2] != "line 5" {
+		t.Errorf("expected lines 3 to 5, got %q", got)
+	}
+
+	// A partial line waits for the rest of it
+	fmt.Fprint(logs, "line ")
+	fmt.Fprint(logs, "6\n")
+	if got := logs.Lines(); got[2] != "line 6" {
+		t.Errorf("expected the joined line, got %q", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	config := struct {
+		URL         string            `json:"url"`
+		InfluxToken string            `json:"influx_token"`
+		EmptyToken  string            `json:"empty_token"`
+		AdminToken  string            `json:"-"`
+		Sinks       []map[string]any  `json:"sinks"`
+		Labels      map[string]string `json:"labels"`
+	}{
+		URL:         "http://influxdb:8086",
+		InfluxToken: "s3cret",
+		AdminToken:  "hidden",
+		Sinks:       []map[string]any{{"name": "dd", "api_key": "k3y", "headers": []string{"Authorization: Bearer x"}}},
+		Labels:      map[string]string{"team": "gpu"},
+	}
+
+	data, err := Redact(config)
+	if err != nil {
+		t.Fatalf("Redact failed: %v", err)
+	}
+	out := string(data)
+	for _, secret := range []string{"s3cret", "hidden", "k3y", "Bearer"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %q redacted, got %s", secret, out)
+		}
+	}
+	for _, kept := range []string{`"url":"http://influxdb:8086"`, `"empty_token":""`, `"name":"dd"`, `"team":"gpu"`} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %s kept, got %s", kept, out)
+		}
+	}
+}
+
+func TestSourceHandler(t *testing.T) {
+	logs := NewLogs(10)
+	log.New(logs, "", 0).Print("started")
+	src := NewSource("collector", map[string]string{"instance_id": "c-1"}, logs)
+	src.AddStats("queue", func() any { return map[string]int{"lag": 4} })
+
+	w := httptest.NewRecorder()
+	src.Handler()(w, httptest.NewRequest(http.MethodGet, "/debug/support", nil))
+
+	var report Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("invalid report: %v", err)
+	}
+	if report.Build.Component != "collector" || string(report.Config) != `{"instance_id":"c-1"}` {
+		t.Errorf("unexpected build or config: %+v %s", report.Build, report.Config)
+	}
+	if len(report.Logs) != 1 || report.Logs[0] != "started" || report.Stats["queue"] == nil {
+		t.Errorf("unexpected logs or stats: %q %v", report.Logs, report.Stats)
+	}
+	if !strings.Contains(report.Goroutines, "goroutine ") {
+		t.Errorf("expected a goroutine dump, got %q", report.Goroutines)
+	}
+}